@@ -0,0 +1,94 @@
+// Package main is the cluster agent. It runs inside a workload cluster, opens
+// an outbound connection to the platform's NATS and carries out the
+// Kubernetes operations the orchestrator sends for that cluster against the
+// local API server, so clusters behind firewalls or NAT are managed without
+// exposing their API server.
+//
+// The agent is configured with NFOSS_AGENT_CLUSTER_ID, the platform ID of
+// the cluster, NFOSS_NATS_URL and NFOSS_NATS_CREDENTIALS_FILE, the .creds
+// file issued for the cluster by POST /clusters/:id/agent-credentials. Those
+// credentials only reach agent.<cluster ID>.> and the inboxes of the requests
+// the agent answers, so an agent cannot act on other clusters and only the
+// platform can send it operations. It authenticates to the API server with
+// its service account.
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/adapters/kubernetes"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/internal/workers"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/client-go/rest"
+)
+
+func main() {
+	clusterID := flag.String("cluster-id", os.Getenv("NFOSS_AGENT_CLUSTER_ID"), "Platform ID of the cluster (NFOSS_AGENT_CLUSTER_ID)")
+	natsURL := flag.String("nats-url", os.Getenv("NFOSS_NATS_URL"), "NATS server of the platform (NFOSS_NATS_URL)")
+	credentialsFile := flag.String("nats-credentials", os.Getenv("NFOSS_NATS_CREDENTIALS_FILE"), "NATS credentials of the cluster's agent (NFOSS_NATS_CREDENTIALS_FILE)")
+	logLevel := flag.String("log-level", envOr("NFOSS_AGENT_LOG_LEVEL", "info"), "Log level (NFOSS_AGENT_LOG_LEVEL)")
+	flag.Parse()
+
+	log := logger.New(*logLevel, "json", os.Stdout)
+
+	id, err := uuid.Parse(*clusterID)
+	if err != nil {
+		log.Fatal().Err(err).Msg("-cluster-id must be the platform ID of the cluster")
+	}
+
+	if *credentialsFile == "" {
+		log.Fatal().Msg("-nats-credentials must be the agent credentials issued for the cluster")
+	}
+
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		log.Fatal().Err(err).Msg("The agent must run inside the cluster it manages")
+	}
+	kube, err := kubernetes.NewClient(restConfig, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create Kubernetes client")
+	}
+
+	bus, err := eventbus.NewNATSEventBus(&config.NATSConfig{
+		URL:             *natsURL,
+		ClientID:        "agent-" + id.String(),
+		CredentialsFile: *credentialsFile,
+		MaxReconnects:   -1, // Keep reconnecting; the agent is useless without NATS
+		ReconnectWait:   2 * time.Second,
+		TLSEnabled:      os.Getenv("NFOSS_NATS_TLS_CA_FILE") != "",
+		TLSCAFile:       os.Getenv("NFOSS_NATS_TLS_CA_FILE"),
+	}, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to NATS")
+	}
+	defer bus.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := workers.NewAgent(bus, kube, id, log).Run(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to start cluster agent")
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info().Msg("Cluster agent stopped")
+}
+
+// envOr returns an environment variable, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,367 @@
+// Package main is nfoss, the command line client of the platform API.
+//
+// The API is taken from NFOSS_API_URL and authenticated with NFOSS_TOKEN, a
+// JWT or an API key.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// command is a subcommand of nfoss
+type command struct {
+	summary string
+	run     func(args []string) error
+}
+
+var commands = map[string]command{
+	"apply":          {"Converge a project to its northstack.yaml", apply},
+	"export":         {"Write a project out as a Helm chart or Kustomize directory", exportProject},
+	"import":         {"Create a project from a Heroku app or a Northflank project", importProject},
+	"import-compose": {"Create a project's services from a docker-compose.yml", importCompose},
+}
+
+func main() {
+	if len(os.Args) < 2 || commands[os.Args[1]].run == nil {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := commands[os.Args[1]].run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "nfoss:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: nfoss <command> [flags]\n\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", name, commands[name].summary)
+	}
+}
+
+// apply sends a manifest to POST /projects/:id/apply and prints the changes
+func apply(args []string) error {
+	flags := flag.NewFlagSet("apply", flag.ExitOnError)
+	file := flags.String("f", "northstack.yaml", "Manifest to apply")
+	project := flags.String("project", os.Getenv("NFOSS_PROJECT"), "ID of the project (NFOSS_PROJECT)")
+	dryRun := flags.Bool("dry-run", false, "Print the changes without making them")
+	prune := flags.Bool("prune", false, "Delete the services the manifest created that it no longer declares")
+	flags.Parse(args)
+
+	if *project == "" {
+		return fmt.Errorf("-project is required")
+	}
+	body, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("dry_run", fmt.Sprint(*dryRun))
+	query.Set("prune", fmt.Sprint(*prune))
+
+	var result applyResult
+	if err := call(http.MethodPost, "/projects/"+url.PathEscape(*project)+"/apply?"+query.Encode(), "application/yaml", body, &result); err != nil {
+		return err
+	}
+
+	printChanges(result)
+	return nil
+}
+
+// importCompose sends a compose file to POST /projects/:id/import/compose,
+// prints the changes and what could not be imported, and writes the manifest
+// the services were created from
+func importCompose(args []string) error {
+	flags := flag.NewFlagSet("import-compose", flag.ExitOnError)
+	file := flags.String("f", "docker-compose.yml", "Compose file to import")
+	project := flags.String("project", os.Getenv("NFOSS_PROJECT"), "ID of the project (NFOSS_PROJECT)")
+	repository := flags.String("repository", "", "Git repository holding the build contexts of the compose file")
+	branch := flags.String("branch", "", "Branch of the repository")
+	output := flags.String("o", "northstack.yaml", "Where to write the generated manifest; empty to skip")
+	dryRun := flags.Bool("dry-run", false, "Print the changes without making them")
+	flags.Parse(args)
+
+	if *project == "" {
+		return fmt.Errorf("-project is required")
+	}
+	body, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("dry_run", fmt.Sprint(*dryRun))
+	if *repository != "" {
+		query.Set("repository", *repository)
+		query.Set("branch", *branch)
+	}
+
+	var imported struct {
+		Manifest    json.RawMessage `json:"manifest"`
+		Unsupported []unsupported   `json:"unsupported"`
+		Result      applyResult     `json:"result"`
+	}
+	if err := call(http.MethodPost, "/projects/"+url.PathEscape(*project)+"/import/compose?"+query.Encode(), "application/yaml", body, &imported); err != nil {
+		return err
+	}
+
+	printChanges(imported.Result)
+	printUnsupported("Not imported", imported.Unsupported)
+
+	if *output != "" {
+		manifest, err := yaml.JSONToYAML(imported.Manifest)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*output, manifest, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("\nWrote %s; run nfoss apply with it after editing\n", *output)
+	}
+	return nil
+}
+
+// importProject sends a Heroku app or Northflank project to POST
+// /projects/import and prints what was, or would be, created. The token of the
+// source is taken from HEROKU_API_KEY or NORTHFLANK_TOKEN.
+func importProject(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	from := flags.String("from", "", "Platform to import from: heroku or northflank")
+	app := flags.String("app", "", "Heroku app to import")
+	sourceProject := flags.String("source-project", "", "ID of the Northflank project to import")
+	repository := flags.String("repository", "", "Git repository holding the code of the Heroku app")
+	branch := flags.String("branch", "", "Branch of the repository")
+	project := flags.String("project", "", "ID of an existing project to import into; by default one is created")
+	dryRun := flags.Bool("dry-run", false, "Print what would be created without creating it")
+	flags.Parse(args)
+
+	req := map[string]interface{}{"source": *from, "dry_run": *dryRun}
+	switch *from {
+	case "heroku":
+		req["token"] = os.Getenv("HEROKU_API_KEY")
+		req["app"] = *app
+		req["repository"] = *repository
+		req["branch"] = *branch
+	case "northflank":
+		req["token"] = os.Getenv("NORTHFLANK_TOKEN")
+		req["project"] = *sourceProject
+	default:
+		return fmt.Errorf("-from must be heroku or northflank")
+	}
+	if req["token"] == "" {
+		return fmt.Errorf("set HEROKU_API_KEY or NORTHFLANK_TOKEN to the %s token", *from)
+	}
+	if *project != "" {
+		req["project_id"] = *project
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	var report struct {
+		ProjectAction string `json:"project_action"`
+		Project       struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"project"`
+		Secrets []struct {
+			Action    string   `json:"action"`
+			Name      string   `json:"name"`
+			VaultPath string   `json:"vault_path"`
+			Keys      []string `json:"keys"`
+		} `json:"secrets"`
+		Services    applyResult   `json:"services"`
+		Unsupported []unsupported `json:"unsupported"`
+	}
+	if err := call(http.MethodPost, "/projects/import", "application/json", body, &report); err != nil {
+		return err
+	}
+
+	fmt.Printf("%-10s project/%s (%s)\n", report.ProjectAction, report.Project.Name, report.Project.ID)
+	for _, secret := range report.Secrets {
+		fmt.Printf("%-10s secret/%s: %d keys in %s\n", secret.Action, secret.Name, len(secret.Keys), secret.VaultPath)
+	}
+	printChanges(report.Services)
+	printUnsupported("Not imported", report.Unsupported)
+	return nil
+}
+
+// exportProject fetches GET /projects/:id/export and writes the bundle's
+// files under a directory
+func exportProject(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	project := flags.String("project", os.Getenv("NFOSS_PROJECT"), "ID of the project (NFOSS_PROJECT)")
+	format := flags.String("format", "helm", "Layout to write: helm or kustomize")
+	output := flags.String("o", "", "Directory to write; by default the project's slug")
+	flags.Parse(args)
+
+	if *project == "" {
+		return fmt.Errorf("-project is required")
+	}
+
+	var bundle struct {
+		Name  string `json:"name"`
+		Files []struct {
+			Path    string `json:"path"`
+			Content string `json:"content"`
+		} `json:"files"`
+		Unsupported []unsupported `json:"unsupported"`
+	}
+	query := url.Values{"format": {*format}}
+	if err := call(http.MethodGet, "/projects/"+url.PathEscape(*project)+"/export?"+query.Encode(), "application/json", nil, &bundle); err != nil {
+		return err
+	}
+
+	dir := *output
+	if dir == "" {
+		dir = bundle.Name
+	}
+	for _, f := range bundle.Files {
+		path := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0o644); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Wrote %d files to %s\n", len(bundle.Files), dir)
+	printUnsupported("Not exported", bundle.Unsupported)
+	return nil
+}
+
+// unsupported is a setting an import or export could not carry over
+type unsupported struct {
+	Service string `json:"service"`
+	Key     string `json:"key"`
+	Reason  string `json:"reason"`
+}
+
+// printUnsupported prints what an import or export left behind
+func printUnsupported(title string, items []unsupported) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	for _, u := range items {
+		key := u.Key
+		if u.Service != "" {
+			key = u.Service + ": " + key
+		}
+		fmt.Printf("  %s (%s)\n", key, u.Reason)
+	}
+}
+
+// applyResult is the response of an apply
+type applyResult struct {
+	DryRun  bool `json:"dry_run"`
+	Changes []struct {
+		Action  string   `json:"action"`
+		Kind    string   `json:"kind"`
+		Name    string   `json:"name"`
+		Service string   `json:"service"`
+		Fields  []string `json:"fields"`
+	} `json:"changes"`
+}
+
+// printChanges prints one line per change of an apply
+func printChanges(result applyResult) {
+	changed := 0
+	for _, change := range result.Changes {
+		name := change.Kind + "/" + change.Name
+		if change.Service != "" {
+			name += " (" + change.Service + ")"
+		}
+		line := fmt.Sprintf("%-10s %s", change.Action, name)
+		if len(change.Fields) > 0 {
+			line += ": " + strings.Join(change.Fields, ", ")
+		}
+		fmt.Println(line)
+		if change.Action != "unchanged" {
+			changed++
+		}
+	}
+	if result.DryRun {
+		fmt.Printf("%d changes planned (dry run)\n", changed)
+	} else {
+		fmt.Printf("%d changes applied\n", changed)
+	}
+}
+
+// call makes an API request and decodes the JSON response into out
+func call(method, path, contentType string, body []byte, out interface{}) error {
+	base := os.Getenv("NFOSS_API_URL")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(base, "/")+"/api/v1"+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	if token := os.Getenv("NFOSS_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := (&http.Client{Timeout: 2 * time.Minute}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return apiError(resp.StatusCode, data)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// apiError describes an error response, problem details or not
+func apiError(status int, data []byte) error {
+	var problem struct {
+		Detail string `json:"detail"`
+		Errors []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(data, &problem) != nil || problem.Detail == "" {
+		return fmt.Errorf("API answered %d: %s", status, strings.TrimSpace(string(data)))
+	}
+
+	msg := problem.Detail
+	for _, e := range problem.Errors {
+		msg += "\n  " + e.Field + " " + e.Message
+	}
+	return fmt.Errorf("%s", msg)
+}
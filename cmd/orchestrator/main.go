@@ -12,16 +12,90 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/northstack/platform/internal/activity"
+	"github.com/northstack/platform/internal/adapters/aks"
 	"github.com/northstack/platform/internal/adapters/argocd"
 	"github.com/northstack/platform/internal/adapters/coolify"
+	"github.com/northstack/platform/internal/adapters/eks"
+	"github.com/northstack/platform/internal/adapters/email"
+	"github.com/northstack/platform/internal/adapters/gke"
+	"github.com/northstack/platform/internal/adapters/grafana"
+	"github.com/northstack/platform/internal/adapters/loki"
+	"github.com/northstack/platform/internal/adapters/opsgenie"
+	"github.com/northstack/platform/internal/adapters/pagerduty"
+	"github.com/northstack/platform/internal/adapters/prometheus"
+	"github.com/northstack/platform/internal/adapters/providers"
 	"github.com/northstack/platform/internal/adapters/rancher"
+	"github.com/northstack/platform/internal/adapters/s3"
+	"github.com/northstack/platform/internal/adapters/slack"
+	"github.com/northstack/platform/internal/adapters/vault"
+	"github.com/northstack/platform/internal/alerting"
+	"github.com/northstack/platform/internal/anomaly"
 	"github.com/northstack/platform/internal/api"
+	"github.com/northstack/platform/internal/approvals"
+	"github.com/northstack/platform/internal/artifacts"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/autoscaling"
+	"github.com/northstack/platform/internal/autotls"
+	"github.com/northstack/platform/internal/availability"
+	"github.com/northstack/platform/internal/backstage"
+	"github.com/northstack/platform/internal/buildanalytics"
+	"github.com/northstack/platform/internal/buildtracker"
+	"github.com/northstack/platform/internal/cache"
+	"github.com/northstack/platform/internal/catalog"
+	"github.com/northstack/platform/internal/clusterhealth"
+	"github.com/northstack/platform/internal/clusterupgrade"
 	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/cronjobs"
+	"github.com/northstack/platform/internal/customdomains"
+	"github.com/northstack/platform/internal/dbupgrade"
+	"github.com/northstack/platform/internal/devcluster"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/dora"
+	"github.com/northstack/platform/internal/egress"
+	"github.com/northstack/platform/internal/envlifecycle"
+	"github.com/northstack/platform/internal/escalation"
 	"github.com/northstack/platform/internal/eventbus"
-	"github.com/northstack/platform/internal/repository"
+	"github.com/northstack/platform/internal/ingressroutes"
+	"github.com/northstack/platform/internal/internalnet"
+	"github.com/northstack/platform/internal/jobs"
+	"github.com/northstack/platform/internal/keda"
+	"github.com/northstack/platform/internal/kubeconfigs"
+	"github.com/northstack/platform/internal/kubeevents"
+	"github.com/northstack/platform/internal/livefeed"
+	"github.com/northstack/platform/internal/logs"
+	"github.com/northstack/platform/internal/maintenance"
+	"github.com/northstack/platform/internal/metering"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/natsauth"
+	"github.com/northstack/platform/internal/notifications"
+	"github.com/northstack/platform/internal/placement"
+	"github.com/northstack/platform/internal/prepull"
+	"github.com/northstack/platform/internal/previewdb"
+	"github.com/northstack/platform/internal/queuetime"
+	"github.com/northstack/platform/internal/releasehealth"
+	"github.com/northstack/platform/internal/residency"
+	"github.com/northstack/platform/internal/rightsizing"
+	"github.com/northstack/platform/internal/rke2"
+	"github.com/northstack/platform/internal/scheduledscaling"
+	"github.com/northstack/platform/internal/secretsync"
+	"github.com/northstack/platform/internal/signing"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/internal/tunnel"
+	"github.com/northstack/platform/internal/uptime"
+	"github.com/northstack/platform/internal/volumes"
+	"github.com/northstack/platform/internal/warmpool"
+	"github.com/northstack/platform/internal/webhooks"
+	"github.com/northstack/platform/internal/workers"
 	"github.com/northstack/platform/internal/workflow"
+	dragonflycache "github.com/northstack/platform/pkg/dragonfly"
 	"github.com/northstack/platform/pkg/logger"
+	"github.com/northstack/platform/pkg/messaging"
+	"github.com/northstack/platform/pkg/minio"
+	"github.com/northstack/platform/pkg/postgres"
+	"github.com/northstack/platform/pkg/yugabytedb"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
 )
 
 var (
@@ -35,6 +109,12 @@ func main() {
 	configPath := flag.String("config", "", "Path to configuration file")
 	showVersion := flag.Bool("version", false, "Show version information")
 	migrate := flag.Bool("migrate", false, "Run database migrations")
+	migrateTo := flag.Int("migrate-to", -1, "Migrate the database schema up or down to the given version and exit")
+	replayStream := flag.String("replay-stream", "", "Re-publish the events a JetStream stream stored in the -replay-from/-replay-to range and exit")
+	replayFrom := flag.String("replay-from", "", "Start of the replay range (RFC 3339)")
+	replayTo := flag.String("replay-to", "", "End of the replay range (RFC 3339), default now")
+	replaySubject := flag.String("replay-subject", "", "Only replay events matching this subject, e.g. deploy.>")
+	worker := flag.Bool("worker", false, "Run as an adapter worker, executing the cluster and build operations API replicas send over NATS")
 	flag.Parse()
 
 	if *showVersion {
@@ -67,17 +147,63 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Initialize metrics
+	var metricsSrv *http.Server
+	if cfg.Observability.Metrics.Enabled {
+		metrics.Init(&cfg.Observability.Metrics)
+		if port := cfg.Observability.Metrics.Port; port != 0 && port != cfg.Server.Port {
+			mux := http.NewServeMux()
+			mux.Handle(cfg.Observability.Metrics.Path, metrics.Handler())
+			metricsSrv = &http.Server{
+				Addr:              fmt.Sprintf("%s:%d", cfg.Server.Host, port),
+				Handler:           mux,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			go func() {
+				log.Info().Str("address", metricsSrv.Addr).Msg("Starting metrics server")
+				if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Error().Err(err).Msg("Metrics server failed")
+				}
+			}()
+		}
+	}
+
+	// Initialize tracing
+	var shutdownTracing func(context.Context) error
+	if cfg.Observability.Tracing.Enabled {
+		shutdownTracing, err = tracing.Init(ctx, &cfg.Observability.Tracing, version)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to initialize tracing")
+		}
+		log.Info().
+			Str("exporter", cfg.Observability.Tracing.Exporter).
+			Float64("sample_rate", cfg.Observability.Tracing.SampleRate).
+			Msg("Tracing enabled")
+	}
+
 	// Initialize database
-	db, err := repository.NewPostgresDB(ctx, &cfg.Database, log)
+	db, err := openStore(ctx, &cfg.Database, log)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to connect to database")
 	}
-	defer db.Close()
+	defer db.close()
+
+	// Roll the schema forward or back to a specific version
+	if *migrateTo >= 0 {
+		if db.migrateTo == nil {
+			log.Fatal().Str("driver", cfg.Database.Driver).Msg("The database driver does not support versioned migrations")
+		}
+		log.Info().Int("version", *migrateTo).Msg("Migrating database schema...")
+		if err := db.migrateTo(ctx, uint(*migrateTo)); err != nil {
+			log.Fatal().Err(err).Msg("Failed to migrate database schema")
+		}
+		os.Exit(0)
+	}
 
 	// Run migrations if requested
 	if *migrate {
 		log.Info().Msg("Running database migrations...")
-		if err := db.Migrate(ctx); err != nil {
+		if err := db.migrate(ctx); err != nil {
 			log.Fatal().Err(err).Msg("Failed to run migrations")
 		}
 		log.Info().Msg("Migrations completed successfully")
@@ -87,19 +213,168 @@ func main() {
 	}
 
 	// Initialize repositories
-	projectRepo := repository.NewProjectRepository(db)
-	serviceRepo := repository.NewServiceRepository(db)
+	projectRepo := db.projects
+	serviceRepo := db.services
+	buildRepo := db.builds
+	deployRepo := db.deployments
+	artifactRepo := db.artifacts
+	alertRepo := db.alerts
+	usageRepo := db.usage
+	clusterRepo := db.clusters
+	environmentRepo := db.environments
+	ingressRepo := db.ingresses
+	probeRepo := db.probes
+	notificationRepo := db.notifications
+	preferenceRepo := db.preferences
+	auditLogRepo := db.auditLogs
+	secretRepo := db.secrets
+	teamRepo := db.teams
 
-	// Initialize event bus
-	bus, err := eventbus.NewNATSEventBus(&cfg.NATS, log)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to connect to NATS")
+	var routerOpts []api.Option
+
+	// Initialize cache
+	var dragonfly *cache.DragonflyDB
+	if cfg.DragonflyDB.Enabled {
+		dragonfly, err = cache.NewDragonflyDB(cfg.DragonflyDB)
+		if err != nil {
+			if cfg.EventBus.Driver == "dragonfly" {
+				log.Fatal().Err(err).Msg("Failed to connect to DragonflyDB")
+			}
+			log.Warn().Err(err).Msg("Failed to connect to DragonflyDB, using in-process rate limiting and idempotency keys")
+		} else {
+			defer dragonfly.Close()
+			routerOpts = append(routerOpts, api.WithRateLimitStore(dragonfly))
+			routerOpts = append(routerOpts, api.WithIdempotencyStore(dragonfly))
+			routerOpts = append(routerOpts, api.WithHealthCheck("dragonfly", dragonfly.Health))
+		}
+	}
+
+	// Initialize event bus. The NATS bus also backs event replay, the
+	// dead-letter queue and the activity feed; natsBus is nil without it.
+	var bus eventBus
+	var natsBus *eventbus.NATSEventBus
+	switch cfg.EventBus.Driver {
+	case "dragonfly":
+		bus = eventbus.NewStreamsEventBus(dragonfly.Client(), dragonfly.KeyPrefix(), &cfg.EventBus.Streams, log)
+	default:
+		natsBus, err = eventbus.NewNATSEventBus(&cfg.NATS, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to connect to NATS")
+		}
+		bus = natsBus
 	}
 	defer bus.Close()
 
+	// Re-publish a time range of a stream, e.g. after fixing a consumer bug
+	if *replayStream != "" {
+		if natsBus == nil {
+			log.Fatal().Msg("-replay-stream requires the nats event bus driver")
+		}
+		replayEvents(ctx, natsBus, *replayStream, *replaySubject, *replayFrom, *replayTo, log)
+		return
+	}
+
+	if *worker {
+		if natsBus == nil {
+			log.Fatal().Msg("-worker requires the nats event bus driver")
+		}
+		runWorker(ctx, natsBus, cfg, log)
+		return
+	}
+
+	// Versioned payload schemas of published events
+	if schemas, err := eventbus.NewSchemaRegistry(); err != nil {
+		log.Warn().Err(err).Msg("Failed to load event schemas")
+	} else {
+		routerOpts = append(routerOpts, api.WithEventSchemas(schemas))
+	}
+
+	// Components reported by the admin health endpoint; the dragonfly
+	// driver is covered by the cache's check
+	routerOpts = append(routerOpts, api.WithHealthCheck("database", db.health))
+	if natsBus != nil {
+		routerOpts = append(routerOpts, api.WithHealthCheck("nats", natsBus.Health))
+	}
+
+	// Mirror project events to per-tenant subjects and let tenants subscribe to them
+	if cfg.NATS.Tenants.Enabled && natsBus != nil {
+		issuer, err := natsauth.NewIssuer(cfg.NATS.Tenants.AccountSeed, cfg.NATS.Tenants.Account)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load the NATS tenant account key")
+		}
+		tenants := eventbus.NewProjectTenants(projectRepo)
+		if err := tenants.Watch(ctx, bus); err != nil {
+			log.Fatal().Err(err).Msg("Failed to watch project changes for event tenants")
+		}
+		natsBus.UseTenants(cfg.NATS.Tenants.Prefix, tenants)
+		routerOpts = append(routerOpts, api.WithEventCredentials(issuer))
+	}
+
+	var vaultClient *vault.Client
+	if cfg.Integrations.Vault.Enabled {
+		vaultClient = vault.NewClient(&cfg.Integrations.Vault, log)
+		routerOpts = append(routerOpts, api.WithSecretWriter(vaultClient))
+	}
+
+	// Sign published events with per-organization keys in Vault transit
+	var signer *signing.Signer
+	if cfg.Integrations.Signing.Enabled && vaultClient != nil {
+		signer = signing.NewSigner(&cfg.Integrations.Signing, vaultClient, projectRepo, log)
+		// A project moved to another team is signed with that team's key
+		if err := eventbus.WatchProjectChanges(ctx, bus, signer.Forget); err != nil {
+			log.Fatal().Err(err).Msg("Failed to watch project changes for signing")
+		}
+		bus.UseSigner(signer)
+		routerOpts = append(routerOpts, api.WithSigner(signer))
+		go signer.Run(ctx)
+	}
+
 	// Initialize adapters
-	coolifyAdapter := coolify.NewAdapter(&cfg.Integrations.Coolify, log)
-	rancherAdapter := rancher.NewAdapter(&cfg.Integrations.Rancher, log)
+	var metricsCollector domain.MetricsCollector
+	if cfg.Integrations.Prometheus.Enabled {
+		metricsCollector = prometheus.NewCollector(&cfg.Integrations.Prometheus, log)
+		routerOpts = append(routerOpts, api.WithMetricsCollector(metricsCollector))
+	}
+
+	if cfg.Integrations.Loki.Enabled {
+		routerOpts = append(routerOpts, api.WithLogStore(loki.NewStore(&cfg.Integrations.Loki, log)))
+	}
+
+	// Builds and cluster provisioning run in adapter workers when enabled
+	var ciAdapter domain.CIAdapter = coolify.NewAdapter(&cfg.Integrations.Coolify, log)
+	clusterManager := newClusterManager(cfg, vaultClient, log)
+	if cfg.Workers.Enabled {
+		ciAdapter = workers.NewCIAdapter(bus, &cfg.Workers)
+		clusterManager = workers.NewClusterManager(bus, &cfg.Workers)
+	}
+
+	// Kubernetes operations run by the agent each workload cluster connects
+	// out with; without agents, features that need them stay off
+	var kubeClient domain.KubernetesClient
+	if cfg.Agents.Enabled {
+		kubeClient = workers.NewKubernetesClient(bus, &cfg.Agents)
+	}
+
+	// NATS credentials for each agent, limited to its own cluster's subjects
+	if cfg.Agents.Enabled && cfg.Agents.AccountSeed != "" {
+		issuer, err := natsauth.NewIssuer(cfg.Agents.AccountSeed, cfg.Agents.Account)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load the NATS agent account key")
+		}
+		routerOpts = append(routerOpts, api.WithAgentCredentials(issuer))
+	}
+
+	// Live service logs read from pods through the agents
+	if kubeClient != nil {
+		routerOpts = append(routerOpts, api.WithLogStreamer(logs.NewStreamer(kubeClient, log)))
+	}
+
+	// Kubernetes events and OOM kills of platform workloads, kept per service
+	if kubeClient != nil {
+		kubeEvents := kubeevents.NewStore()
+		go kubeevents.NewWatcher(kubeClient, clusterRepo, kubeEvents, log).Run(ctx)
+		routerOpts = append(routerOpts, api.WithKubeEventStore(kubeEvents))
+	}
 	argocdAdapter := argocd.NewAdapter(&cfg.Integrations.ArgoCD, log)
 
 	// Authenticate with ArgoCD if configured
@@ -109,8 +384,154 @@ func main() {
 		}
 	}
 
-	// Initialize workflow engine
-	stateMachine := workflow.NewStateMachine(coolifyAdapter, argocdAdapter, bus, serviceRepo, log)
+	// Clusters, environments and ingresses; Rancher or the cloud APIs provision clusters and environment namespaces
+	routerOpts = append(routerOpts,
+		api.WithClusterRepository(clusterRepo),
+		api.WithEnvironmentRepository(environmentRepo),
+		api.WithIngressRepository(ingressRepo),
+	)
+	var upgrader *clusterupgrade.Upgrader
+	if clusterManagement(cfg) {
+		routerOpts = append(routerOpts, api.WithClusterManager(clusterManager))
+
+		// Kubernetes version upgrades, which hold deploys to the cluster
+		upgrader = clusterupgrade.NewUpgrader(clusterManager, clusterRepo, bus, log)
+		if err := upgrader.Recover(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to recover interrupted cluster upgrades")
+		}
+		routerOpts = append(routerOpts, api.WithClusterUpgrades(upgrader))
+
+		// Admin kubeconfigs cached encrypted in Vault transit and rotated before they expire
+		if cfg.Integrations.Kubeconfigs.Enabled && vaultClient != nil {
+			kubeconfigManager := kubeconfigs.NewManager(&cfg.Integrations.Kubeconfigs, clusterManager, vaultClient, clusterRepo, environmentRepo, projectRepo, teamRepo, log)
+			routerOpts = append(routerOpts, api.WithKubeconfigs(kubeconfigManager))
+			go kubeconfigManager.Run(ctx)
+		}
+	}
+
+	// Node drains, evicting through the agent of each cluster so that
+	// PodDisruptionBudgets hold
+	if kubeClient != nil {
+		routerOpts = append(routerOpts, api.WithDrainer(maintenance.NewDrainer(kubeClient, serviceRepo, bus, log)))
+	}
+
+	// RKE2 and k3s dev clusters installed over SSH, registered in the Rancher
+	// integrations.rke2 names or else the platform's own
+	var registrar rke2.Registrar
+	rancherCfg := cfg.Integrations.Rancher
+	if cfg.Integrations.RKE2.RancherURL != "" {
+		rancherCfg.URL = cfg.Integrations.RKE2.RancherURL
+		rancherCfg.Token = cfg.Integrations.RKE2.RancherToken
+		rancherCfg.Enabled = true
+	}
+	if rancherCfg.Enabled {
+		registrar = rancher.NewAdapter(&rancherCfg, log)
+	}
+	sshRunner := rke2.NewSSHRunner(&cfg.Integrations.RKE2, log)
+	if cfg.Integrations.RKE2.Enabled {
+		provisioner := rke2.NewProvisioner(&cfg.Integrations.RKE2, clusterRepo, sshRunner, registrar, bus, log)
+		if err := provisioner.Recover(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to recover interrupted RKE2 provisioning")
+		}
+		routerOpts = append(routerOpts, api.WithRKE2Provisioner(provisioner))
+	}
+	if cfg.Integrations.DevClusters.Enabled {
+		bootstrapper := devcluster.NewBootstrapper(&cfg.Integrations.DevClusters, clusterRepo, sshRunner, registrar, bus, log)
+		routerOpts = append(routerOpts, api.WithDevClusters(bootstrapper))
+	}
+
+	// Persist builds and follow them in Coolify until they finish
+	routerOpts = append(routerOpts, api.WithBuildRepository(buildRepo))
+	buildTracker := buildtracker.NewTracker(ciAdapter, buildRepo, serviceRepo, bus, log)
+	go buildTracker.Run(ctx)
+
+	// Binary build artifacts in S3-compatible object storage
+	if cfg.Integrations.S3.Enabled {
+		objectStore, err := s3.NewStore(&cfg.Integrations.S3, log)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to configure object storage")
+		}
+		artifactManager := artifacts.NewManager(&cfg.Integrations.Artifacts, objectStore, artifactRepo, log)
+		routerOpts = append(routerOpts, api.WithArtifactManager(artifactManager))
+		go artifactManager.Run(ctx)
+	}
+
+	// Build time analytics over the persisted build history
+	buildAnalyzer := buildanalytics.NewAnalyzer(buildRepo, serviceRepo, bus, log)
+	routerOpts = append(routerOpts, api.WithBuildAnalyzer(buildAnalyzer))
+	if err := buildAnalyzer.Watch(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to start build time analyzer")
+	}
+
+	// Data residency of projects; backups default to the object storage region
+	residencyConfig := cfg.Integrations.Residency
+	if residencyConfig.BackupRegion == "" && cfg.Integrations.S3.Enabled {
+		residencyConfig.BackupRegion = cfg.Integrations.S3.Region
+	}
+	residencyChecker := residency.NewChecker(&residencyConfig, projectRepo, clusterRepo, environmentRepo, serviceRepo)
+	routerOpts = append(routerOpts, api.WithResidency(residencyChecker))
+
+	// Services without a target cluster are placed on a cluster of their project
+	if cfg.Integrations.Placement.Enabled {
+		scheduler := placement.NewScheduler(&cfg.Integrations.Placement, clusterRepo, environmentRepo, projectRepo, clusterManager, residencyChecker, log)
+		routerOpts = append(routerOpts, api.WithPlacement(scheduler))
+	}
+
+	// Notification preferences decide which events reach each channel
+	preferences := notifications.NewPreferences(preferenceRepo, log)
+	routerOpts = append(routerOpts, api.WithNotificationPreferences(preferenceRepo))
+
+	// Slack notifications of builds, deploys and alerts, routed per project
+	var notifiers notifications.Fanout
+	if cfg.Integrations.Slack.Enabled {
+		slackNotifier := slack.NewNotifier(&cfg.Integrations.Slack, projectRepo, serviceRepo, log)
+		notifiers = append(notifiers, notifications.NewGate(domain.NotificationChannelSlack, slackNotifier, preferences))
+		routerOpts = append(routerOpts, api.WithSlack(slackNotifier))
+	}
+
+	// Emails of failed deploys and firing alerts, plus weekly project digests.
+	// Project owners are only emailed once there is a user repository.
+	if emailCfg := &cfg.Notifications.Email; emailCfg.Enabled {
+		emailNotifier := email.NewNotifier(emailCfg, projectRepo, serviceRepo, nil, log)
+		emailNotifier.UsePolicy(preferences)
+		notifiers = append(notifiers, emailNotifier)
+		if emailCfg.Digest.Enabled {
+			// Digests cover alerts only when alerting is enabled
+			var digestAlerts domain.AlertRepository
+			if cfg.Observability.Alerting.Enabled {
+				digestAlerts = alertRepo
+			}
+			digester := email.NewDigester(&emailCfg.Digest, emailNotifier, projectRepo, serviceRepo, deployRepo, digestAlerts, log)
+			go digester.Run(ctx)
+		}
+	}
+
+	// On-call incidents for critical alerts and failed production deploys
+	if pdCfg := &cfg.Integrations.PagerDuty; pdCfg.Enabled {
+		pagerDuty := escalation.NewNotifier(pagerduty.NewClient(pdCfg, log), environmentRepo, serviceRepo, pdCfg.ConsoleURL, log)
+		notifiers = append(notifiers, notifications.NewGate(domain.NotificationChannelPagerDuty, pagerDuty, preferences))
+	}
+	if ogCfg := &cfg.Integrations.Opsgenie; ogCfg.Enabled {
+		opsGenie := escalation.NewNotifier(opsgenie.NewClient(ogCfg, log), environmentRepo, serviceRepo, ogCfg.ConsoleURL, log)
+		notifiers = append(notifiers, notifications.NewGate(domain.NotificationChannelOpsgenie, opsGenie, preferences))
+	}
+
+	var notifier domain.Notifier
+	if len(notifiers) > 0 {
+		notifier = notifiers
+	}
+
+	// Initialize workflow engine, pre-pulling release images onto the target
+	// nodes before rollouts when workload clusters are reachable
+	var prePuller *prepull.Puller
+	if kubeClient != nil && cfg.Integrations.PrePull.Enabled {
+		prePuller = prepull.NewPuller(kubeClient, &cfg.Integrations.PrePull, log)
+	}
+	stateMachine := workflow.NewStateMachine(ciAdapter, argocdAdapter, bus, serviceRepo, buildRepo, deployRepo, prePuller, log)
+	stateMachine.UseResidency(residencyChecker)
+	stateMachine.UseUpgrades(upgrader)
+	stateMachine.UseNotifier(notifier)
+	routerOpts = append(routerOpts, api.WithStateMachine(stateMachine), api.WithDeploymentRepository(deployRepo))
 
 	// Start workflow cleanup goroutine
 	go func() {
@@ -129,6 +550,347 @@ func main() {
 	// Subscribe to events for workflow processing
 	setupEventSubscriptions(ctx, bus, stateMachine, log)
 
+	// Watch deployments for post-deploy regressions
+	if cfg.Observability.AnomalyDetection.Enabled && metricsCollector != nil {
+		detector := anomaly.NewDetector(&cfg.Observability.AnomalyDetection, metricsCollector, deployRepo, stateMachine, bus, log)
+		if err := detector.Watch(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to start anomaly detector")
+		}
+	}
+
+	// Alerting: Alertmanager receiver plus built-in rules
+	var alertManager *alerting.Manager
+	if cfg.Observability.Alerting.Enabled {
+		alertManager = alerting.NewManager(alertRepo, bus, notifier, log)
+		routerOpts = append(routerOpts, api.WithAlertManager(alertManager))
+
+		ruleEngine := alerting.NewEngine(&cfg.Observability.Alerting, alertManager, projectRepo, serviceRepo, clusterRepo, kubeClient, metricsCollector, log)
+		go ruleEngine.Run(ctx)
+
+		// Time builds and deployments spend queued, against their SLAs
+		if cfg.Observability.QueueSLA.Enabled {
+			queueMonitor := queuetime.NewMonitor(&cfg.Observability.QueueSLA, projectRepo, serviceRepo, buildRepo, deployRepo, alertManager, log)
+			routerOpts = append(routerOpts, api.WithQueueTimeMonitor(queueMonitor))
+			go queueMonitor.Run(ctx)
+		}
+	}
+
+	// Service catalog; scorecards check for alerts only when alerting is enabled
+	var catalogAlerts domain.AlertRepository
+	if alertManager != nil {
+		catalogAlerts = alertRepo
+	}
+	routerOpts = append(routerOpts, api.WithCatalog(catalog.NewCatalog(projectRepo, serviceRepo, catalogAlerts, log)))
+
+	// Backstage catalog feed of projects and services
+	if cfg.Integrations.Backstage.Enabled {
+		routerOpts = append(routerOpts, api.WithBackstageProvider(backstage.NewProvider(&cfg.Integrations.Backstage, projectRepo, serviceRepo, log)))
+	}
+
+	// Managed databases, caches, brokers and object storage, created in the
+	// orchestrator's cluster
+	if cfg.Integrations.Databases.Enabled || cfg.Integrations.Caches.Enabled || cfg.Integrations.Brokers.Enabled || cfg.Integrations.ObjectStorage.Enabled {
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Managed databases, caches, brokers and object storage need to run inside Kubernetes")
+		}
+		dynClient, err := dynamic.NewForConfig(restConfig)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create Kubernetes client")
+		}
+		if cfg.Integrations.Databases.Enabled {
+			namespace := cfg.Integrations.Databases.Namespace
+			var pg *postgres.Service
+			if cfg.Integrations.Databases.Postgres {
+				pg = postgres.NewService(dynClient, namespace)
+			}
+			// Backups go to the artifacts bucket, under their own prefix
+			var backups *yugabytedb.BackupConfig
+			if backupCfg := cfg.Integrations.Databases.Backup; backupCfg.Enabled {
+				s3Cfg := cfg.Integrations.S3
+				backups = &yugabytedb.BackupConfig{
+					Endpoint:            s3Cfg.Endpoint,
+					Bucket:              s3Cfg.Bucket,
+					Prefix:              backupCfg.Prefix,
+					AccessKeyID:         s3Cfg.AccessKeyID,
+					SecretAccessKey:     s3Cfg.SecretAccessKey,
+					PathStyle:           s3Cfg.PathStyle,
+					Schedule:            backupCfg.Schedule,
+					IncrementalInterval: backupCfg.IncrementalInterval,
+					Retention:           backupCfg.Retention,
+				}
+			}
+			yugabyte := yugabytedb.NewDatabaseService(dynClient, namespace, backups)
+			if err := yugabyte.EnsureStorageConfig(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to configure database backup storage")
+			}
+			routerOpts = append(routerOpts, api.WithDatabases(yugabyte, pg))
+
+			// Upgrades take a backup first; without backups they are refused
+			dbUpgrader := dbupgrade.NewUpgrader(yugabyte, cfg.Integrations.Databases.UpgradeVersions, bus, log)
+			if err := dbUpgrader.Recover(ctx); err != nil {
+				log.Warn().Err(err).Msg("Failed to recover interrupted database upgrades")
+			}
+			go dbUpgrader.Run(ctx)
+			routerOpts = append(routerOpts, api.WithDatabaseUpgrades(dbUpgrader))
+
+			// Clones of databases are deleted with their preview environment
+			go previewdb.NewReaper(yugabyte, pg, environmentRepo, bus, log).Run(ctx)
+		}
+		if cfg.Integrations.Caches.Enabled {
+			caches := dragonflycache.NewCacheService(dynClient, cfg.Integrations.Caches.Namespace, cfg.Integrations.Caches.TLSIssuer)
+			routerOpts = append(routerOpts, api.WithCaches(caches))
+		}
+		if cfg.Integrations.Brokers.Enabled {
+			brokers := messaging.NewBrokerService(dynClient, cfg.Integrations.Brokers.Namespace, cfg.Integrations.Brokers.Kafka)
+			routerOpts = append(routerOpts, api.WithBrokers(brokers))
+		}
+		// Validation ensures Vault is configured for access keys
+		if cfg.Integrations.ObjectStorage.Enabled {
+			tenants := minio.NewTenantService(dynClient, &cfg.Integrations.ObjectStorage)
+			routerOpts = append(routerOpts, api.WithObjectStorage(tenants, vaultClient))
+		}
+	}
+
+	// Vault secrets, replicated into every cluster a service is deployed to.
+	// Without a Kubernetes client for workload clusters the replicator only
+	// renders manifests for GitOps.
+	routerOpts = append(routerOpts, api.WithSecretRepository(secretRepo))
+	if cfg.Integrations.SecretReplication.Enabled && vaultClient != nil {
+		replicator := secretsync.NewReplicator(
+			&cfg.Integrations.SecretReplication,
+			&cfg.Integrations.Vault,
+			vaultClient,
+			projectRepo, serviceRepo, secretRepo, deployRepo, clusterRepo, environmentRepo, db.replications,
+			kubeClient, alertManager, log,
+		)
+		routerOpts = append(routerOpts, api.WithSecretReplicator(replicator))
+		if err := replicator.Watch(ctx, bus); err != nil {
+			log.Warn().Err(err).Msg("Failed to start secret replication watcher")
+		}
+		go replicator.Run(ctx)
+	}
+
+	// Static egress IPs per environment
+	if cfg.Integrations.Egress.Enabled {
+		egressManager := egress.NewManager(&cfg.Integrations.Egress, kubeClient, clusterRepo, environmentRepo, bus, log)
+		routerOpts = append(routerOpts, api.WithEgressManager(egressManager))
+		if err := egressManager.Watch(ctx, bus); err != nil {
+			log.Warn().Err(err).Msg("Failed to start egress policy watcher")
+		}
+		go egressManager.Run(ctx)
+	}
+
+	// Expiry and idle hibernation of preview and development environments
+	if cfg.Integrations.EnvLifecycle.Enabled {
+		var namespaces domain.ClusterManagerAdapter
+		if clusterManagement(cfg) {
+			namespaces = clusterManager
+		}
+		requests, _ := metricsCollector.(envlifecycle.Querier)
+		lifecycleManager := envlifecycle.NewManager(&cfg.Integrations.EnvLifecycle, kubeClient, environmentRepo, projectRepo, clusterRepo, namespaces, requests, bus, log)
+		routerOpts = append(routerOpts, api.WithEnvLifecycle(lifecycleManager))
+		go lifecycleManager.Run(ctx)
+	}
+
+	// Kubernetes CronJobs of cron job services, with their runs and logs
+	if cfg.Integrations.CronJobs.Enabled {
+		cronJobManager := cronjobs.NewManager(&cfg.Integrations.CronJobs, kubeClient, serviceRepo, environmentRepo, projectRepo, log)
+		routerOpts = append(routerOpts, api.WithCronJobs(cronJobManager))
+		if err := cronJobManager.Watch(ctx, bus); err != nil {
+			log.Warn().Err(err).Msg("Failed to start cron job watcher")
+		}
+		go cronJobManager.Run(ctx)
+	}
+
+	// Warm standby replicas, kept idle above demand by the scaling schedule
+	// reconciler, and images pre-warmed on every node as services deploy. The
+	// autoscalers raise their minimum to the same floor.
+	var warmPool *warmpool.Pool
+	if kubeClient != nil {
+		warmPool = warmpool.NewPool(kubeClient, metricsCollector, serviceRepo, &cfg.Observability.Metering, log)
+		routerOpts = append(routerOpts, api.WithWarmPool(warmPool))
+		if err := warmPool.Watch(ctx, bus); err != nil {
+			log.Warn().Err(err).Msg("Failed to start image pre-warming watcher")
+		}
+		go scheduledscaling.NewScheduler(kubeClient, projectRepo, serviceRepo, bus, warmPool, log).Run(ctx)
+	}
+
+	// Autoscalers of services, with their replica counts recorded on them
+	if cfg.Integrations.Autoscaling.Enabled {
+		kedaManager := keda.NewManager(kubeClient, &cfg.Integrations.KEDA, warmPool, log)
+		reconciler := autoscaling.NewReconciler(&cfg.Integrations.Autoscaling, kubeClient, kedaManager, warmPool, serviceRepo, projectRepo, bus, log)
+		routerOpts = append(routerOpts, api.WithKEDAManager(kedaManager), api.WithAutoscaling(reconciler))
+		if err := reconciler.Watch(ctx, bus); err != nil {
+			log.Warn().Err(err).Msg("Failed to start autoscaling watcher")
+		}
+		go reconciler.Run(ctx)
+	}
+
+	// Disruption budgets of services with availability settings
+	if cfg.Integrations.Availability.Enabled {
+		availabilityManager := availability.NewManager(&cfg.Integrations.Availability, kubeClient, serviceRepo, projectRepo, log)
+		if err := availabilityManager.Watch(ctx, bus); err != nil {
+			log.Warn().Err(err).Msg("Failed to start disruption budget watcher")
+		}
+		go availabilityManager.Run(ctx)
+	}
+
+	// Persistent volumes of services, with their snapshots
+	if cfg.Integrations.Volumes.Enabled {
+		volumeManager := volumes.NewManager(&cfg.Integrations.Volumes, kubeClient, serviceRepo, environmentRepo, projectRepo, log)
+		routerOpts = append(routerOpts, api.WithVolumes(volumeManager))
+		if err := volumeManager.Watch(ctx, bus); err != nil {
+			log.Warn().Err(err).Msg("Failed to start volume watcher")
+		}
+		go volumeManager.Run(ctx)
+	}
+
+	// One-off commands run against a service's image as Kubernetes Jobs
+	if cfg.Integrations.Jobs.Enabled {
+		routerOpts = append(routerOpts, api.WithJobs(jobs.NewRunner(&cfg.Integrations.Jobs, kubeClient, log)))
+	}
+
+	// Custom domains ingresses may route once their DNS proves the project controls them
+	if cfg.Integrations.Domains.Enabled {
+		verifier := customdomains.NewVerifier(&cfg.Integrations.Domains, db.domains, bus, log)
+		routerOpts = append(routerOpts, api.WithCustomDomains(db.domains, verifier))
+		go verifier.Run(ctx)
+	}
+
+	// Internal DNS names and network policies of services with internal exposure
+	if cfg.Integrations.InternalNetwork.Enabled {
+		internalNet := internalnet.NewManager(&cfg.Integrations.InternalNetwork, kubeClient, serviceRepo, projectRepo, ingressRepo, log)
+		routerOpts = append(routerOpts, api.WithInternalNetwork(internalNet))
+		go internalNet.Run(ctx)
+	}
+
+	// Ingress controller resources rendered from ingresses, in each cluster's dialect
+	if cfg.Integrations.IngressRoutes.Enabled {
+		routeManager := ingressroutes.NewManager(&cfg.Integrations.IngressRoutes, kubeClient, ingressRepo, serviceRepo, clusterRepo, projectRepo, log)
+		routerOpts = append(routerOpts, api.WithIngressRoutes(routeManager))
+		go routeManager.Run(ctx)
+	}
+
+	// Certificates for auto TLS ingresses, issued by cert-manager in workload clusters
+	if cfg.Integrations.AutoTLS.Enabled {
+		certManager := autotls.NewManager(&cfg.Integrations.AutoTLS, kubeClient, ingressRepo, serviceRepo, projectRepo, bus, log)
+		routerOpts = append(routerOpts, api.WithAutoTLS(certManager))
+		go certManager.Run(ctx)
+	}
+
+	// Uptime probes against every ingress; alerts only when alerting is enabled
+	if cfg.Observability.Uptime.Enabled {
+		prober := uptime.NewProber(&cfg.Observability.Uptime, projectRepo, ingressRepo, probeRepo, alertManager, log)
+		routerOpts = append(routerOpts, api.WithUptimeProber(prober))
+		go prober.Run(ctx)
+	}
+
+	// Stored cluster statuses follow the health the cluster manager reports
+	if cfg.Observability.ClusterHealth.Enabled && clusterManagement(cfg) {
+		reconciler := clusterhealth.NewReconciler(&cfg.Observability.ClusterHealth, clusterManager, clusterRepo, bus, alertManager, log)
+		go reconciler.Run(ctx)
+	}
+
+	// Release health scores for each deployment
+	if cfg.Observability.ReleaseHealth.Enabled {
+		scorer := releasehealth.NewScorer(&cfg.Observability.ReleaseHealth, kubeClient, metricsCollector, deployRepo, alertRepo, bus, log)
+		routerOpts = append(routerOpts, api.WithReleaseHealthScorer(scorer))
+		if err := scorer.Watch(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to start release health scorer")
+		}
+	}
+
+	// Right-sizing advice from usage metrics and, through the agents,
+	// recommendation-only VPAs installed as services deploy
+	if cfg.Observability.Rightsizing.Enabled {
+		advisor := rightsizing.NewAdvisor(kubeClient, metricsCollector, serviceRepo, &cfg.Observability.Metering, log)
+		routerOpts = append(routerOpts, api.WithRightsizingAdvisor(advisor))
+		if err := advisor.Watch(ctx, bus); err != nil {
+			log.Warn().Err(err).Msg("Failed to start right-sizing advisor")
+		}
+	}
+
+	// DORA delivery metrics from the build and deployment history
+	routerOpts = append(routerOpts, api.WithDORAReporter(dora.NewReporter(serviceRepo, deployRepo, buildRepo, log)))
+
+	// Hourly per-service usage rollup for chargeback
+	if cfg.Observability.Metering.Enabled && metricsCollector != nil {
+		meter := metering.NewMeter(&cfg.Observability.Metering, usageRepo, projectRepo, serviceRepo, metricsCollector, log)
+		routerOpts = append(routerOpts, api.WithMeter(meter))
+		go meter.Run(ctx)
+	}
+
+	// Project activity timeline read back from the JetStream streams
+	if natsBus != nil && cfg.NATS.JetStreamEnabled {
+		routerOpts = append(routerOpts, api.WithActivityFeed(activity.NewFeed(natsBus, log)))
+		routerOpts = append(routerOpts, api.WithDeadLetterQueue(natsBus))
+	}
+
+	// In-product notification inbox with live delivery to open sessions
+	notificationCenter := notifications.NewCenter(notificationRepo, projectRepo, serviceRepo, deployRepo, bus, log)
+	notificationCenter.UsePolicy(preferences)
+	routerOpts = append(routerOpts, api.WithNotificationCenter(notificationCenter))
+	if err := notificationCenter.Watch(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to start notification center")
+	}
+
+	// Outbound webhooks subscribed by projects, queued as events arrive and
+	// sent from the queue
+	webhookDispatcher := webhooks.NewDispatcher(db.webhooks, bus, signer, &cfg.Integrations.Webhooks, log)
+	routerOpts = append(routerOpts, api.WithWebhooks(db.webhooks, webhookDispatcher))
+	if err := webhookDispatcher.Watch(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to start webhook dispatcher")
+	}
+	go webhookDispatcher.Run(ctx)
+
+	// User-defined project templates, alongside the built-in ones
+	routerOpts = append(routerOpts, api.WithTemplateRepository(db.templates))
+
+	// Teams, for the admin API and the membership checks of team resources
+	routerOpts = append(routerOpts, api.WithTeamRepository(teamRepo))
+
+	// Port-forward tunnels to private services, recorded in the audit trail
+	if cfg.Integrations.Tunnel.Enabled {
+		tunnelManager := tunnel.NewManager(&cfg.Integrations.Tunnel, kubeClient, audit.NewLogger(auditLogRepo, bus, log), log)
+		routerOpts = append(routerOpts, api.WithTunnelManager(tunnelManager))
+	}
+
+	// Deploys to production wait for the project owner, or a user they
+	// delegated to, and escalate to the team owner when left pending
+	if cfg.Integrations.Approvals.Enabled {
+		approvalGate := approvals.NewGate(&cfg.Integrations.Approvals, db.approvals, db.delegations, environmentRepo, projectRepo, teamRepo, audit.NewLogger(auditLogRepo, bus, log), log)
+		approvalGate.UseInbox(notificationCenter)
+		approvalGate.OnApproved(func(ctx context.Context, approval *domain.Approval) {
+			if err := stateMachine.ProcessEvent(ctx, approval.WorkflowID, workflow.EventTriggerDeploy, map[string]interface{}{"version": approval.Version}); err != nil {
+				log.Warn().Err(err).Str("approval_id", approval.ID.String()).Msg("Failed to resume approved deploy")
+			}
+		})
+		stateMachine.UseApprovals(approvalGate)
+		routerOpts = append(routerOpts, api.WithApprovals(approvalGate))
+		go approvalGate.Run(ctx)
+	}
+
+	// Live event stream for the dashboard
+	eventHub := livefeed.NewHub(bus, log)
+	if err := eventHub.Watch(ctx); err != nil {
+		log.Warn().Err(err).Msg("Failed to start live event stream")
+	} else {
+		routerOpts = append(routerOpts, api.WithEventHub(eventHub))
+	}
+
+	// Audit trail, readable by administrators
+	routerOpts = append(routerOpts, api.WithAuditLogRepository(auditLogRepo))
+
+	// Provision Grafana dashboards for projects and services
+	if cfg.Integrations.Grafana.Enabled {
+		grafanaAdapter := grafana.NewAdapter(&cfg.Integrations.Grafana, log)
+		provisioner := grafana.NewProvisioner(grafanaAdapter, projectRepo, log)
+		if err := provisioner.Subscribe(ctx, bus); err != nil {
+			log.Warn().Err(err).Msg("Failed to subscribe Grafana provisioner")
+		}
+	}
+
 	// Initialize API router
 	router := api.NewRouter(
 		cfg,
@@ -137,11 +899,10 @@ func main() {
 		serviceRepo,
 		nil, // userRepo - implement as needed
 		bus,
-		coolifyAdapter,
+		ciAdapter,
+		routerOpts...,
 	)
 
-	// Suppress unused warning
-	_ = rancherAdapter
 	engine := router.Setup()
 
 	// Create HTTP server
@@ -180,32 +941,142 @@ func main() {
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		log.Error().Err(err).Msg("Server forced to shutdown")
 	}
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(shutdownCtx)
+	}
+	if shutdownTracing != nil {
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			log.Error().Err(err).Msg("Failed to flush traces")
+		}
+	}
 
 	cancel() // Cancel root context to stop background goroutines
 
 	log.Info().Msg("Server stopped")
 }
 
-// setupEventSubscriptions sets up event subscriptions for workflow processing
-func setupEventSubscriptions(ctx context.Context, bus *eventbus.NATSEventBus, sm *workflow.StateMachine, log *logger.Logger) {
-	// Subscribe to build events
-	bus.Subscribe(ctx, "build.>", func(event *domain.Event) error {
-		log.Debug().Str("type", event.Type).Interface("data", event.Data).Msg("Received build event")
-		// Process build events and update workflow state
-		return nil
-	})
-
-	// Subscribe to deployment events
-	bus.Subscribe(ctx, "deploy.>", func(event *domain.Event) error {
-		log.Debug().Str("type", event.Type).Interface("data", event.Data).Msg("Received deploy event")
-		// Process deploy events and update workflow state
-		return nil
-	})
-
-	// Subscribe to webhook events
-	bus.Subscribe(ctx, "webhook.>", func(event *domain.Event) error {
-		log.Debug().Str("type", event.Type).Interface("data", event.Data).Msg("Received webhook event")
-		// Process webhook events
-		return nil
-	})
+// eventBus is the event bus API of both drivers
+type eventBus interface {
+	domain.EventBus
+	DurableSubscribe(ctx context.Context, subject, durable string, handler domain.EventHandler) (domain.Subscription, error)
+	UseSigner(signer eventbus.EventSigner)
+}
+
+// setupEventSubscriptions sets up event subscriptions for workflow processing.
+// They use durable consumers, so events published while the orchestrator is
+// down are processed once it is back, and failed handlers are retried.
+func setupEventSubscriptions(ctx context.Context, bus eventBus, sm *workflow.StateMachine, log *logger.Logger) {
+	subscriptions := []struct {
+		subject string
+		durable string
+		handler domain.EventHandler
+	}{
+		{
+			// Build events update workflow state
+			subject: "build.>",
+			durable: "orchestrator-builds",
+			handler: func(event *domain.Event) error {
+				log.Debug().Str("type", event.Type).Interface("data", event.Data).Msg("Received build event")
+				return nil
+			},
+		},
+		{
+			// Deploy events update workflow state
+			subject: "deploy.>",
+			durable: "orchestrator-deploys",
+			handler: func(event *domain.Event) error {
+				log.Debug().Str("type", event.Type).Interface("data", event.Data).Msg("Received deploy event")
+				return nil
+			},
+		},
+		{
+			subject: "webhook.>",
+			durable: "orchestrator-webhooks",
+			handler: func(event *domain.Event) error {
+				log.Debug().Str("type", event.Type).Interface("data", event.Data).Msg("Received webhook event")
+				return nil
+			},
+		},
+	}
+
+	for _, s := range subscriptions {
+		if _, err := bus.DurableSubscribe(ctx, s.subject, s.durable, s.handler); err != nil {
+			log.Error().Err(err).Str("subject", s.subject).Msg("Failed to subscribe to workflow events")
+		}
+	}
+}
+
+// runWorker executes the adapter calls of API replicas until the process is
+// signalled to stop
+func runWorker(ctx context.Context, bus *eventbus.NATSEventBus, cfg *config.Config, log *logger.Logger) {
+	var clusters domain.ClusterManagerAdapter
+	if clusterManagement(cfg) {
+		var vaultClient *vault.Client
+		if cfg.Integrations.Vault.Enabled {
+			vaultClient = vault.NewClient(&cfg.Integrations.Vault, log)
+		}
+		clusters = newClusterManager(cfg, vaultClient, log)
+	}
+	worker := workers.NewWorker(bus, coolify.NewAdapter(&cfg.Integrations.Coolify, log), clusters, &cfg.Workers, log)
+	if err := worker.Run(ctx); err != nil {
+		log.Fatal().Err(err).Msg("Failed to start adapter worker")
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info().Msg("Adapter worker stopped")
+}
+
+// clusterManagement reports whether any cluster manager is enabled
+func clusterManagement(cfg *config.Config) bool {
+	i := cfg.Integrations
+	return i.Rancher.Enabled || i.EKS.Enabled || i.GKE.Enabled || i.AKS.Enabled
+}
+
+// newClusterManager returns the Rancher adapter, or, when clusters of some
+// providers are provisioned through their cloud APIs directly, a router over
+// those adapters and Rancher. The direct adapters read their credentials
+// from Vault, which configuration validation requires for them.
+func newClusterManager(cfg *config.Config, vaultClient *vault.Client, log *logger.Logger) domain.ClusterManagerAdapter {
+	rancherAdapter := rancher.NewAdapter(&cfg.Integrations.Rancher, log)
+	var direct []providers.Direct
+	if cfg.Integrations.EKS.Enabled {
+		direct = append(direct, providers.Direct{Provider: domain.ClusterProviderAWS, Prefix: eks.ExternalIDPrefix, Adapter: eks.NewAdapter(&cfg.Integrations.EKS, vaultClient, log)})
+	}
+	if cfg.Integrations.GKE.Enabled {
+		direct = append(direct, providers.Direct{Provider: domain.ClusterProviderGCP, Prefix: gke.ExternalIDPrefix, Adapter: gke.NewAdapter(&cfg.Integrations.GKE, vaultClient, log)})
+	}
+	if cfg.Integrations.AKS.Enabled {
+		direct = append(direct, providers.Direct{Provider: domain.ClusterProviderAzure, Prefix: aks.ExternalIDPrefix, Adapter: aks.NewAdapter(&cfg.Integrations.AKS, vaultClient, log)})
+	}
+	if len(direct) == 0 {
+		return rancherAdapter
+	}
+	if !cfg.Integrations.Rancher.Enabled {
+		return providers.NewRouter(nil, direct...)
+	}
+	return providers.NewRouter(rancherAdapter, direct...)
+}
+
+// replayEvents re-publishes the events stream stored between from and to on
+// their original subjects
+func replayEvents(ctx context.Context, bus *eventbus.NATSEventBus, stream, subject, from, to string, log *logger.Logger) {
+	start, err := time.Parse(time.RFC3339, from)
+	if err != nil {
+		log.Fatal().Err(err).Msg("-replay-from must be an RFC 3339 time")
+	}
+	var end time.Time
+	if to != "" {
+		if end, err = time.Parse(time.RFC3339, to); err != nil {
+			log.Fatal().Err(err).Msg("-replay-to must be an RFC 3339 time")
+		}
+	}
+
+	replayed, err := bus.Replay(ctx, stream, subject, start, end)
+	if err != nil {
+		log.Fatal().Err(err).Int("replayed", replayed).Str("stream", stream).Msg("Failed to replay events")
+	}
+	log.Info().Int("replayed", replayed).Str("stream", stream).Msg("Events replayed")
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/repository"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// store is the persistence layer the orchestrator is wired with
+type store struct {
+	projects      domain.ProjectRepository
+	services      domain.ServiceRepository
+	builds        domain.BuildRepository
+	deployments   domain.DeploymentRepository
+	artifacts     domain.ArtifactRepository
+	alerts        domain.AlertRepository
+	usage         domain.UsageRepository
+	clusters      domain.ClusterRepository
+	environments  domain.EnvironmentRepository
+	ingresses     domain.IngressRepository
+	probes        domain.ProbeRepository
+	notifications domain.NotificationRepository
+	preferences   domain.NotificationPreferenceRepository
+	auditLogs     domain.AuditLogRepository
+	secrets       domain.SecretRepository
+	replications  domain.SecretReplicationRepository
+	webhooks      domain.WebhookRepository
+	templates     domain.TemplateRepository
+	domains       domain.DomainRepository
+	teams         domain.TeamRepository
+	approvals     domain.ApprovalRepository
+	delegations   domain.ApprovalDelegationRepository
+
+	migrate   func(ctx context.Context) error
+	migrateTo func(ctx context.Context, version uint) error // nil if the backend has no versioned migrations
+	health    func(ctx context.Context) error
+	close     func()
+}
+
+// openStore connects to the configured database backend
+func openStore(ctx context.Context, cfg *config.DatabaseConfig, log *logger.Logger) (*store, error) {
+	switch cfg.Driver {
+	case "", "postgres":
+		db, err := repository.NewPostgresDB(ctx, cfg, log)
+		if err != nil {
+			return nil, err
+		}
+		return &store{
+			projects:      repository.NewProjectRepository(db),
+			services:      repository.NewServiceRepository(db),
+			builds:        repository.NewBuildRepository(db),
+			deployments:   repository.NewDeploymentRepository(db),
+			artifacts:     repository.NewArtifactRepository(db),
+			alerts:        repository.NewAlertRepository(db),
+			usage:         repository.NewUsageRepository(db),
+			clusters:      repository.NewClusterRepository(db),
+			environments:  repository.NewEnvironmentRepository(db),
+			ingresses:     repository.NewIngressRepository(db),
+			probes:        repository.NewProbeRepository(db),
+			notifications: repository.NewNotificationRepository(db),
+			preferences:   repository.NewNotificationPreferenceRepository(db),
+			auditLogs:     repository.NewAuditLogRepository(db),
+			secrets:       repository.NewSecretRepository(db),
+			replications:  repository.NewSecretReplicationRepository(db),
+			webhooks:      repository.NewWebhookRepository(db),
+			templates:     repository.NewTemplateRepository(db),
+			domains:       repository.NewDomainRepository(db),
+			teams:         repository.NewTeamRepository(db),
+			approvals:     repository.NewApprovalRepository(db),
+			delegations:   repository.NewApprovalDelegationRepository(db),
+			migrate:       db.Migrate,
+			migrateTo:     db.MigrateTo,
+			health:        db.Health,
+			close:         db.Close,
+		}, nil
+	case "sqlite":
+		return openSQLiteStore(ctx, cfg, log)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !sqlite
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+func openSQLiteStore(ctx context.Context, cfg *config.DatabaseConfig, log *logger.Logger) (*store, error) {
+	return nil, fmt.Errorf("this binary was built without SQLite support; rebuild with -tags sqlite")
+}
@@ -0,0 +1,52 @@
+//go:build sqlite
+
+package main
+
+import (
+	"context"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/repository/sqlite"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// openSQLiteStore opens the embedded SQLite database. Its schema is created on
+// every start since there is no separate database to migrate ahead of time.
+func openSQLiteStore(ctx context.Context, cfg *config.DatabaseConfig, log *logger.Logger) (*store, error) {
+	db, err := sqlite.Open(ctx, cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &store{
+		projects:      sqlite.NewProjectRepository(db),
+		services:      sqlite.NewServiceRepository(db),
+		builds:        sqlite.NewBuildRepository(db),
+		deployments:   sqlite.NewDeploymentRepository(db),
+		artifacts:     sqlite.NewArtifactRepository(db),
+		alerts:        sqlite.NewAlertRepository(db),
+		usage:         sqlite.NewUsageRepository(db),
+		clusters:      sqlite.NewClusterRepository(db),
+		environments:  sqlite.NewEnvironmentRepository(db),
+		ingresses:     sqlite.NewIngressRepository(db),
+		probes:        sqlite.NewProbeRepository(db),
+		notifications: sqlite.NewNotificationRepository(db),
+		preferences:   sqlite.NewNotificationPreferenceRepository(db),
+		auditLogs:     sqlite.NewAuditLogRepository(db),
+		secrets:       sqlite.NewSecretRepository(db),
+		replications:  sqlite.NewSecretReplicationRepository(db),
+		webhooks:      sqlite.NewWebhookRepository(db),
+		templates:     sqlite.NewTemplateRepository(db),
+		domains:       sqlite.NewDomainRepository(db),
+		teams:         sqlite.NewTeamRepository(db),
+		approvals:     sqlite.NewApprovalRepository(db),
+		delegations:   sqlite.NewApprovalDelegationRepository(db),
+		migrate:       db.Migrate,
+		health:        db.Health,
+		close:         db.Close,
+	}, nil
+}
@@ -37,7 +37,7 @@ const handleResponse = async <T>(response: Response): Promise<ApiResponse<T>> =>
 
     if (!response.ok) {
         throw new ApiError(
-            data?.message || `HTTP error ${response.status}`,
+            data?.detail || data?.message || `HTTP error ${response.status}`,
             response.status,
             data
         );
@@ -0,0 +1,128 @@
+// Package activity builds a project's activity timeline from the domain
+// events retained in the JetStream streams (builds, deploys, service changes
+// such as scalings, and secret changes), newest first, with cursor pagination.
+package activity
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// readWindow is how many messages are read from a stream at a time
+	readWindow = 250
+	// maxScan bounds the messages scanned per stream for one page, so projects
+	// with little activity on a busy platform get short pages instead of slow ones
+	maxScan = 2000
+)
+
+// streams maps activity categories to the streams holding their events
+var streams = map[string]string{
+	"build":   "BUILDS",
+	"deploy":  "DEPLOYMENTS",
+	"service": "SERVICES",
+	"secret":  "SECRETS",
+}
+
+// Categories lists the supported activity categories
+var Categories = []string{"build", "deploy", "service", "secret"}
+
+// Feed reads project activity from the event streams
+type Feed struct {
+	store  domain.EventStore
+	logger *logger.Logger
+}
+
+// NewFeed creates a new Feed
+func NewFeed(store domain.EventStore, log *logger.Logger) *Feed {
+	return &Feed{
+		store:  store,
+		logger: log,
+	}
+}
+
+// List returns up to limit entries of a project's activity older than the
+// cursor, restricted to the given categories (all when empty). Pages may be
+// short while more activity remains; iteration ends when NextCursor is empty.
+func (f *Feed) List(ctx context.Context, projectID uuid.UUID, categories []string, after string, limit int) (*Page, error) {
+	if len(categories) == 0 {
+		categories = Categories
+	}
+
+	var bounds cursor
+	if after != "" {
+		var err error
+		if bounds, err = decodeCursor(after); err != nil {
+			return nil, errors.BadRequest("invalid cursor")
+		}
+	}
+
+	scans := make([]*scan, 0, len(categories))
+	for _, category := range categories {
+		stream, ok := streams[category]
+		if !ok {
+			return nil, errors.BadRequest(fmt.Sprintf("unknown category %q", category))
+		}
+
+		before, ok := bounds[stream]
+		if bounds != nil && !ok {
+			continue // Exhausted on an earlier page
+		}
+
+		s, err := f.scan(ctx, stream, projectID, before, limit)
+		if err != nil {
+			return nil, errors.DependencyFailed("nats", err)
+		}
+		scans = append(scans, s)
+	}
+
+	return paginate(scans, limit), nil
+}
+
+// scan reads a stream backwards from before (exclusive; the end of the
+// stream when zero) until it finds limit events of the project or hits maxScan
+func (f *Feed) scan(ctx context.Context, stream string, projectID uuid.UUID, before uint64, limit int) (*scan, error) {
+	first, last, err := f.store.StreamBounds(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &scan{stream: stream, before: before}
+	if before == 0 || before > last+1 {
+		s.before = last + 1
+	}
+	if last == 0 || last < first {
+		s.exhausted = true
+		return s, nil
+	}
+
+	next := s.before
+	project := projectID.String()
+	for next > first && len(s.matched) < limit && len(s.messages) < maxScan {
+		from := first
+		if next-first > readWindow {
+			from = next - readWindow
+		}
+
+		events, err := f.store.ReadStream(ctx, stream, from, next-1)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(events) - 1; i >= 0; i-- {
+			msg := events[i]
+			s.messages = append(s.messages, msg)
+			if msg.Event != nil && fmt.Sprint(msg.Event.Data["project_id"]) == project {
+				s.matched = append(s.matched, msg)
+			}
+		}
+		next = from
+	}
+	s.exhausted = next <= first
+
+	return s, nil
+}
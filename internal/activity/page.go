@@ -0,0 +1,144 @@
+package activity
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+// Entry is one item of the activity timeline
+type Entry struct {
+	ID        string                 `json:"id"`
+	Category  string                 `json:"category"`
+	Type      string                 `json:"type"`
+	Source    string                 `json:"source,omitempty"`
+	ServiceID string                 `json:"service_id,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Page is a page of activity, newest first
+type Page struct {
+	Entries    []*Entry `json:"entries"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// cursor holds, per stream, the sequence the next page reads backwards from
+// (exclusive). Streams that are fully read are left out.
+type cursor map[string]uint64
+
+func decodeCursor(v string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, err
+	}
+	var c cursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	if c == nil {
+		c = cursor{}
+	}
+	return c, nil
+}
+
+func (c cursor) encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// scan is what was read from one stream for a page
+type scan struct {
+	stream    string
+	before    uint64
+	messages  []*domain.StoredEvent // Newest first
+	matched   []*domain.StoredEvent // Newest first
+	exhausted bool
+}
+
+// paginate merges the matches of all streams newest first. Entries are only
+// taken down to the horizon of the streams that were not read to their
+// start, since those may still hold older activity that sorts in between.
+func paginate(scans []*scan, limit int) *Page {
+	var horizon time.Time
+	for _, s := range scans {
+		if !s.exhausted && len(s.messages) > 0 {
+			if oldest := s.messages[len(s.messages)-1].Stored; oldest.After(horizon) {
+				horizon = oldest
+			}
+		}
+	}
+
+	var candidates []*domain.StoredEvent
+	for _, s := range scans {
+		for _, msg := range s.matched {
+			if !msg.Stored.Before(horizon) {
+				candidates = append(candidates, msg)
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].Stored.Equal(candidates[j].Stored) {
+			return candidates[i].Stored.After(candidates[j].Stored)
+		}
+		return candidates[i].Sequence > candidates[j].Sequence
+	})
+
+	cutoff := horizon
+	if len(candidates) >= limit {
+		candidates = candidates[:limit]
+		cutoff = candidates[len(candidates)-1].Stored
+	}
+
+	page := &Page{Entries: make([]*Entry, 0, len(candidates))}
+	taken := make(map[*domain.StoredEvent]bool, len(candidates))
+	for _, msg := range candidates {
+		taken[msg] = true
+		page.Entries = append(page.Entries, toEntry(msg))
+	}
+
+	next := cursor{}
+	for _, s := range scans {
+		before := s.before
+		for _, msg := range s.messages {
+			if taken[msg] || msg.Stored.After(cutoff) {
+				before = msg.Sequence
+			}
+		}
+
+		consumed := len(s.messages) == 0 || before == s.messages[len(s.messages)-1].Sequence
+		if !(s.exhausted && consumed) {
+			next[s.stream] = before
+		}
+	}
+	if len(next) > 0 {
+		page.NextCursor = next.encode()
+	}
+
+	return page
+}
+
+func toEntry(msg *domain.StoredEvent) *Entry {
+	entry := &Entry{
+		ID:        msg.Event.ID,
+		Type:      msg.Event.Type,
+		Source:    msg.Event.Source,
+		Timestamp: msg.Stored.UTC(),
+		Data:      msg.Event.Data,
+	}
+	if entry.Type == "" {
+		entry.Type = msg.Event.Subject
+	}
+	for category, stream := range streams {
+		if stream == msg.Stream {
+			entry.Category = category
+		}
+	}
+	if id, ok := msg.Event.Data["service_id"].(string); ok {
+		entry.ServiceID = id
+	}
+	return entry
+}
@@ -0,0 +1,71 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stored(stream string, seq uint64, at time.Time, project string) *domain.StoredEvent {
+	return &domain.StoredEvent{
+		Stream:   stream,
+		Sequence: seq,
+		Stored:   at,
+		Event:    &domain.Event{Type: "x", Data: map[string]interface{}{"project_id": project}},
+	}
+}
+
+func TestPaginateMergesNewestFirst(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	builds := &scan{stream: "BUILDS", before: 4, exhausted: true}
+	deploys := &scan{stream: "DEPLOYMENTS", before: 3, exhausted: true}
+	for seq := uint64(3); seq >= 1; seq-- {
+		msg := stored("BUILDS", seq, base.Add(time.Duration(seq)*2*time.Minute), "p")
+		builds.messages = append(builds.messages, msg)
+		builds.matched = append(builds.matched, msg)
+	}
+	for seq := uint64(2); seq >= 1; seq-- {
+		msg := stored("DEPLOYMENTS", seq, base.Add(time.Duration(seq)*3*time.Minute), "p")
+		deploys.messages = append(deploys.messages, msg)
+		deploys.matched = append(deploys.matched, msg)
+	}
+
+	page := paginate([]*scan{builds, deploys}, 3)
+	require.Len(t, page.Entries, 3)
+	assert.Equal(t, "build", page.Entries[0].Category)  // 6m
+	assert.Equal(t, "deploy", page.Entries[1].Category) // 6m, lower sequence
+	assert.Equal(t, "build", page.Entries[2].Category)  // 4m
+	require.NotEmpty(t, page.NextCursor)
+
+	next, err := decodeCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, cursor{"BUILDS": 2, "DEPLOYMENTS": 2}, next)
+}
+
+func TestPaginateStopsAtHorizon(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Only the newest part of the busy stream was scanned, and none of it matched
+	busy := &scan{stream: "SERVICES", before: 101, messages: []*domain.StoredEvent{
+		stored("SERVICES", 100, base.Add(10*time.Minute), "other"),
+		stored("SERVICES", 99, base.Add(9*time.Minute), "other"),
+	}}
+	old := stored("SECRETS", 1, base, "p")
+	quiet := &scan{stream: "SECRETS", before: 2, exhausted: true, messages: []*domain.StoredEvent{old}, matched: []*domain.StoredEvent{old}}
+
+	page := paginate([]*scan{busy, quiet}, 10)
+	assert.Empty(t, page.Entries)
+
+	next, err := decodeCursor(page.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, cursor{"SERVICES": 100, "SECRETS": 2}, next)
+}
+
+func TestPaginateLastPage(t *testing.T) {
+	msg := stored("BUILDS", 1, time.Now(), "p")
+	page := paginate([]*scan{{stream: "BUILDS", before: 2, exhausted: true, messages: []*domain.StoredEvent{msg}, matched: []*domain.StoredEvent{msg}}}, 10)
+	assert.Len(t, page.Entries, 1)
+	assert.Empty(t, page.NextCursor)
+}
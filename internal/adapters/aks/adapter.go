@@ -0,0 +1,464 @@
+// Package aks provisions Kubernetes clusters through the Azure Resource
+// Manager API for AKS directly, for installations that do not run Rancher.
+// Operations inside a cluster go to its API server with the cluster's admin
+// credentials.
+package aks
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/northstack/platform/internal/adapters/kubeapi"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// ExternalIDPrefix starts the external ID of every cluster the adapter
+// manages: aks:<location>:<name>
+const ExternalIDPrefix = "aks:"
+
+// tagManaged marks the clusters the adapter creates
+const tagManaged = "northstack-managed"
+
+// defaultAgentPool is the system node pool created with a cluster
+const defaultAgentPool = "default"
+
+// apiVersion is the version of the Microsoft.ContainerService API called
+const apiVersion = "2024-02-01"
+
+// Secrets reads the service principal credentials
+type Secrets interface {
+	GetSecret(ctx context.Context, path string) (map[string][]byte, error)
+}
+
+// Adapter implements the ClusterManagerAdapter interface for AKS
+type Adapter struct {
+	config     *config.AKSConfig
+	secrets    Secrets
+	httpClient *http.Client
+	logger     *logger.Logger
+	baseURL    string
+	loginURL   string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAdapter creates a new AKS adapter
+func NewAdapter(cfg *config.AKSConfig, secrets Secrets, log *logger.Logger) *Adapter {
+	return &Adapter{
+		config:     cfg,
+		secrets:    secrets,
+		httpClient: &http.Client{Timeout: cfg.Timeout, Transport: tracing.Transport(nil)},
+		logger:     log,
+		baseURL:    "https://management.azure.com",
+		loginURL:   "https://login.microsoftonline.com",
+	}
+}
+
+// managedCluster is a cluster as the AKS API describes it
+type managedCluster struct {
+	Name       string            `json:"name"`
+	Location   string            `json:"location"`
+	Tags       map[string]string `json:"tags"`
+	Properties struct {
+		ProvisioningState        string `json:"provisioningState"`
+		CurrentKubernetesVersion string `json:"currentKubernetesVersion"`
+		FQDN                     string `json:"fqdn"`
+		PowerState               struct {
+			Code string `json:"code"`
+		} `json:"powerState"`
+		AgentPoolProfiles []agentPool `json:"agentPoolProfiles"`
+	} `json:"properties"`
+}
+
+// agentPool is a node pool as the AKS API describes it, either as a profile
+// of its cluster or as a resource of its own
+type agentPool struct {
+	Name              string `json:"name,omitempty"`
+	Count             int32  `json:"count"`
+	VMSize            string `json:"vmSize,omitempty"`
+	Mode              string `json:"mode,omitempty"`
+	Type              string `json:"type,omitempty"`
+	OSType            string `json:"osType,omitempty"`
+	EnableAutoScaling bool   `json:"enableAutoScaling"`
+	MinCount          *int32 `json:"minCount,omitempty"`
+	MaxCount          *int32 `json:"maxCount,omitempty"`
+	// OrchestratorVersion is the Kubernetes version of the pool's nodes
+	OrchestratorVersion string `json:"orchestratorVersion,omitempty"`
+}
+
+// principal is the service principal the adapter authenticates as
+type principal struct {
+	TenantID       string
+	ClientID       string
+	ClientSecret   string
+	SubscriptionID string
+}
+
+// CreateCluster creates a cluster with its default system node pool in the
+// configured resource group
+func (a *Adapter) CreateCluster(ctx context.Context, cluster *domain.Cluster) (string, error) {
+	if cluster.Region == "" {
+		return "", errors.BadRequest("an Azure location is required")
+	}
+	nodeCount := cluster.NodeCount
+	if nodeCount < 1 {
+		nodeCount = 1
+	}
+
+	pool := toAgentPool(&domain.NodePool{Name: defaultAgentPool, InstanceType: a.config.VMSize, NodeCount: nodeCount, MinCount: 1, MaxCount: nodeCount * 2, AutoScaling: true})
+	pool.Mode = "System"
+	properties := map[string]interface{}{
+		"dnsPrefix":         cluster.Slug,
+		"agentPoolProfiles": []agentPool{pool},
+	}
+	if cluster.KubeVersion != "" {
+		properties["kubernetesVersion"] = cluster.KubeVersion
+	}
+	body := map[string]interface{}{
+		"location":   cluster.Region,
+		"tags":       map[string]string{tagManaged: "true"},
+		"identity":   map[string]string{"type": "SystemAssigned"},
+		"properties": properties,
+	}
+	if err := a.doRequest(ctx, http.MethodPut, "/"+cluster.Slug, body, nil); err != nil {
+		return "", err
+	}
+
+	id := ExternalIDPrefix + cluster.Region + ":" + cluster.Slug
+	a.logger.Info().
+		Str("external_id", id).
+		Str("cluster_name", cluster.Name).
+		Msg("Created cluster in AKS")
+	return id, nil
+}
+
+// GetCluster retrieves a cluster
+func (a *Adapter) GetCluster(ctx context.Context, externalID string) (*domain.Cluster, error) {
+	mc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return toDomain(mc), nil
+}
+
+// UpdateCluster upgrades the cluster's control plane and node pools to its
+// Kubernetes version and resizes the default node pool to its node count,
+// when set
+func (a *Adapter) UpdateCluster(ctx context.Context, cluster *domain.Cluster) error {
+	mc, err := a.getCluster(ctx, cluster.RancherClusterID)
+	if err != nil {
+		return err
+	}
+
+	if cluster.KubeVersion != "" && cluster.KubeVersion != mc.Properties.CurrentKubernetesVersion {
+		// A PUT of the current resource with a new version upgrades the
+		// control plane, then every node pool
+		var raw map[string]interface{}
+		if err := a.doRequest(ctx, http.MethodGet, "/"+mc.Name, nil, &raw); err != nil {
+			return err
+		}
+		properties, _ := raw["properties"].(map[string]interface{})
+		if properties == nil {
+			return errors.Internal("AKS returned a cluster without properties")
+		}
+		properties["kubernetesVersion"] = cluster.KubeVersion
+		if pools, ok := properties["agentPoolProfiles"].([]interface{}); ok {
+			for _, p := range pools {
+				if pool, ok := p.(map[string]interface{}); ok {
+					pool["orchestratorVersion"] = cluster.KubeVersion
+				}
+			}
+		}
+		if err := a.doRequest(ctx, http.MethodPut, "/"+mc.Name, raw, nil); err != nil {
+			return err
+		}
+	}
+	if cluster.NodeCount > 0 {
+		pool := &domain.NodePool{Name: defaultAgentPool, NodeCount: cluster.NodeCount, MinCount: 1, MaxCount: cluster.NodeCount * 2, AutoScaling: true}
+		if err := a.UpdateNodePool(ctx, cluster.RancherClusterID, pool); err != nil {
+			return err
+		}
+	}
+
+	a.logger.Info().
+		Str("external_id", cluster.RancherClusterID).
+		Str("cluster_name", cluster.Name).
+		Msg("Updated cluster in AKS")
+	return nil
+}
+
+// DeleteCluster deletes a cluster and its node pools
+func (a *Adapter) DeleteCluster(ctx context.Context, externalID string) error {
+	mc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if err := a.doRequest(ctx, http.MethodDelete, "/"+mc.Name, nil, nil); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Msg("Deleted cluster from AKS")
+	return nil
+}
+
+// GetKubeConfig returns the cluster's admin kubeconfig
+func (a *Adapter) GetKubeConfig(ctx context.Context, externalID string) ([]byte, error) {
+	mc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Kubeconfigs []struct {
+			Value string `json:"value"`
+		} `json:"kubeconfigs"`
+	}
+	if err := a.doRequest(ctx, http.MethodPost, "/"+mc.Name+"/listClusterAdminCredential", nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Kubeconfigs) == 0 {
+		return nil, errors.NotFound("kubeconfig", externalID)
+	}
+	kubeconfig, err := base64.StdEncoding.DecodeString(result.Kubeconfigs[0].Value)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid kubeconfig")
+	}
+	return kubeconfig, nil
+}
+
+// ListClusters lists the managed clusters of the resource group
+func (a *Adapter) ListClusters(ctx context.Context) ([]*domain.Cluster, error) {
+	var list struct {
+		Value []managedCluster `json:"value"`
+	}
+	if err := a.doRequest(ctx, http.MethodGet, "", nil, &list); err != nil {
+		return nil, err
+	}
+
+	clusters := make([]*domain.Cluster, 0, len(list.Value))
+	for i := range list.Value {
+		if list.Value[i].Tags[tagManaged] == "true" {
+			clusters = append(clusters, toDomain(&list.Value[i]))
+		}
+	}
+	return clusters, nil
+}
+
+// GetClusterHealth reads the health of a cluster from its API server
+func (a *Adapter) GetClusterHealth(ctx context.Context, externalID string) (*domain.ClusterHealth, error) {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return kubeapi.Health(ctx, e)
+}
+
+// GetClusterCapacity reads the capacity of a cluster from its API server
+func (a *Adapter) GetClusterCapacity(ctx context.Context, externalID string) (*domain.ClusterCapacity, error) {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return kubeapi.Capacity(ctx, e)
+}
+
+// CreateNamespace creates a namespace on a cluster
+func (a *Adapter) CreateNamespace(ctx context.Context, externalID, namespace string, labels map[string]string) error {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	return kubeapi.CreateNamespace(ctx, e, namespace, labels)
+}
+
+// DeleteNamespace deletes a namespace from a cluster
+func (a *Adapter) DeleteNamespace(ctx context.Context, externalID, namespace string) error {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	return kubeapi.DeleteNamespace(ctx, e, namespace)
+}
+
+// IssueKubeConfig is not offered for AKS clusters yet
+func (a *Adapter) IssueKubeConfig(ctx context.Context, externalID string, grant *domain.KubeConfigGrant) (*domain.IssuedKubeConfig, error) {
+	return nil, errors.NotImplemented("scoped kubeconfigs are not available for AKS clusters")
+}
+
+// RevokeKubeConfig cannot revoke admin credentials short of rotating the
+// certificates of the whole cluster
+func (a *Adapter) RevokeKubeConfig(ctx context.Context, externalID string, kubeconfig []byte) error {
+	return errors.NotImplemented("AKS admin credentials cannot be revoked individually")
+}
+
+// getCluster reads a cluster the adapter manages
+func (a *Adapter) getCluster(ctx context.Context, externalID string) (*managedCluster, error) {
+	name, err := parseExternalID(externalID)
+	if err != nil {
+		return nil, err
+	}
+	var mc managedCluster
+	if err := a.doRequest(ctx, http.MethodGet, "/"+name, nil, &mc); err != nil {
+		return nil, err
+	}
+	if mc.Tags[tagManaged] != "true" {
+		return nil, errors.NotFound("cluster", externalID)
+	}
+	return &mc, nil
+}
+
+// kubeEndpoint returns the API server of a cluster with its admin credentials
+func (a *Adapter) kubeEndpoint(ctx context.Context, externalID string) (*kubeapi.Endpoint, error) {
+	kubeconfig, err := a.GetKubeConfig(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return kubeapi.ParseKubeConfig(kubeconfig)
+}
+
+// doRequest sends an authorized request for a managed cluster of the
+// resource group, or for the group's managed clusters when path is empty,
+// encoding body and decoding the response into out unless they are nil
+func (a *Adapter) doRequest(ctx context.Context, method, path string, body, out interface{}) error {
+	p, err := a.principal(ctx)
+	if err != nil {
+		return err
+	}
+	token, err := a.accessToken(ctx, p)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal request")
+		}
+		reader = bytes.NewReader(data)
+	}
+	url := fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters%s?api-version=%s",
+		a.baseURL, p.SubscriptionID, a.config.ResourceGroup, path, apiVersion)
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	resp, err := a.httpClient.Do(req)
+	metrics.ObserveAdapterCall("aks", method, start, resp, err)
+	if err != nil {
+		return errors.DependencyFailed("aks", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return handleError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+	return nil
+}
+
+// handleError extracts error information from a response
+func handleError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var errResp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Description string `json:"error_description"`
+	}
+	json.Unmarshal(body, &errResp)
+	msg := errResp.Error.Message
+	if msg == "" {
+		msg = errResp.Description
+	}
+	if msg == "" {
+		msg = strings.TrimSpace(string(body))
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return errors.NotFound("aks resource", msg)
+	case http.StatusConflict:
+		return errors.NewError(errors.CodeConflict, msg, http.StatusConflict)
+	case http.StatusBadRequest:
+		return errors.BadRequest(msg)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errors.DependencyFailed("aks", fmt.Errorf("access denied: %s", msg))
+	default:
+		return errors.DependencyFailed("aks", fmt.Errorf("AKS API error (%d): %s", resp.StatusCode, msg))
+	}
+}
+
+// parseExternalID returns the name of a cluster from aks:<location>:<name>
+func parseExternalID(externalID string) (string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(externalID, ExternalIDPrefix), ":", 2)
+	if !strings.HasPrefix(externalID, ExternalIDPrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", errors.NotFound("cluster", externalID)
+	}
+	return parts[1], nil
+}
+
+func toDomain(mc *managedCluster) *domain.Cluster {
+	cluster := &domain.Cluster{
+		Name:             mc.Name,
+		Slug:             mc.Name,
+		Provider:         domain.ClusterProviderAzure,
+		Region:           mc.Location,
+		Status:           mapStatus(mc.Properties.ProvisioningState),
+		KubeVersion:      mc.Properties.CurrentKubernetesVersion,
+		RancherClusterID: ExternalIDPrefix + mc.Location + ":" + mc.Name,
+	}
+	if mc.Properties.FQDN != "" {
+		cluster.APIEndpoint = "https://" + mc.Properties.FQDN
+	}
+	if cluster.Status == domain.ClusterStatusActive && mc.Properties.PowerState.Code == "Stopped" {
+		cluster.Status = domain.ClusterStatusUnhealthy
+	}
+	for _, pool := range mc.Properties.AgentPoolProfiles {
+		cluster.NodeCount += pool.Count
+	}
+	return cluster
+}
+
+// mapStatus maps the provisioning state of an AKS cluster to a domain status
+func mapStatus(state string) domain.ClusterStatus {
+	switch state {
+	case "Succeeded":
+		return domain.ClusterStatusActive
+	case "Upgrading", "Updating", "Scaling":
+		return domain.ClusterStatusUpgrading
+	case "Deleting":
+		return domain.ClusterStatusDeleting
+	case "Failed", "Canceled":
+		return domain.ClusterStatusUnhealthy
+	default:
+		return domain.ClusterStatusProvisioning
+	}
+}
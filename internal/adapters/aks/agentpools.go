@@ -0,0 +1,197 @@
+package aks
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/northstack/platform/internal/adapters/kubeapi"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ListNodePools lists the agent pools of a cluster
+func (a *Adapter) ListNodePools(ctx context.Context, externalID string) ([]*domain.NodePool, error) {
+	mc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	pools := make([]*domain.NodePool, len(mc.Properties.AgentPoolProfiles))
+	for i := range mc.Properties.AgentPoolProfiles {
+		pools[i] = fromAgentPool(&mc.Properties.AgentPoolProfiles[i])
+	}
+	return pools, nil
+}
+
+// CreateNodePool adds a user agent pool to a cluster
+func (a *Adapter) CreateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	mc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if findPool(mc, pool.Name) != nil {
+		return errors.Conflict("node pool " + pool.Name)
+	}
+
+	ap := toAgentPool(pool)
+	ap.Mode = "User"
+	if ap.VMSize == "" {
+		ap.VMSize = a.config.VMSize
+	}
+	if err := a.doRequest(ctx, http.MethodPut, "/"+mc.Name+"/agentPools/"+pool.Name, map[string]interface{}{"properties": ap}, nil); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", pool.Name).
+		Int("nodes", int(pool.NodeCount)).
+		Msg("Created node pool")
+	return nil
+}
+
+// UpdateNodePool resizes an agent pool and moves it to the pool's version.
+// When it shrinks, its newest surplus nodes are drained first. AKS cannot
+// change the VM size of an agent pool.
+func (a *Adapter) UpdateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	mc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	current := findPool(mc, pool.Name)
+	if current == nil {
+		return errors.NotFound("node pool", pool.Name)
+	}
+	if pool.InstanceType != "" && pool.InstanceType != current.VMSize {
+		return errors.BadRequest("AKS cannot change the VM size of an agent pool; create a new pool instead")
+	}
+
+	if surplus := int(current.Count - pool.NodeCount); surplus > 0 {
+		e, err := a.kubeEndpoint(ctx, externalID)
+		if err != nil {
+			return err
+		}
+		if _, err := kubeapi.DrainPool(ctx, e, kubeapi.LabelAKSAgentPool, pool.Name, surplus); err != nil {
+			return err
+		}
+	}
+
+	ap := toAgentPool(pool)
+	ap.Name = ""
+	ap.VMSize = current.VMSize
+	ap.Mode = current.Mode
+	if ap.OrchestratorVersion == "" {
+		ap.OrchestratorVersion = current.OrchestratorVersion
+	}
+	if err := a.doRequest(ctx, http.MethodPut, "/"+mc.Name+"/agentPools/"+pool.Name, map[string]interface{}{"properties": ap}, nil); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", pool.Name).
+		Int("nodes", int(pool.NodeCount)).
+		Msg("Updated node pool")
+	return nil
+}
+
+// DeleteNodePool drains every node of an agent pool, then deletes it. A
+// cluster's last pool cannot be deleted.
+func (a *Adapter) DeleteNodePool(ctx context.Context, externalID, name string) error {
+	mc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if findPool(mc, name) == nil {
+		return errors.NotFound("node pool", name)
+	}
+	if len(mc.Properties.AgentPoolProfiles) == 1 {
+		return errors.NewError(errors.CodeConflict, "the last node pool of a cluster cannot be deleted", http.StatusConflict)
+	}
+
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if _, err := kubeapi.DrainPool(ctx, e, kubeapi.LabelAKSAgentPool, name, -1); err != nil {
+		return err
+	}
+	if err := a.doRequest(ctx, http.MethodDelete, "/"+mc.Name+"/agentPools/"+name, nil, nil); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", name).
+		Msg("Deleted node pool")
+	return nil
+}
+
+// ListKubernetesVersions lists the versions AKS offers to upgrade a
+// cluster's control plane to
+func (a *Adapter) ListKubernetesVersions(ctx context.Context, externalID string) ([]string, error) {
+	mc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile struct {
+		Properties struct {
+			ControlPlaneProfile struct {
+				Upgrades []struct {
+					KubernetesVersion string `json:"kubernetesVersion"`
+				} `json:"upgrades"`
+			} `json:"controlPlaneProfile"`
+		} `json:"properties"`
+	}
+	if err := a.doRequest(ctx, http.MethodGet, "/"+mc.Name+"/upgradeProfiles/default", nil, &profile); err != nil {
+		return nil, err
+	}
+	candidates := make([]string, len(profile.Properties.ControlPlaneProfile.Upgrades))
+	for i, u := range profile.Properties.ControlPlaneProfile.Upgrades {
+		candidates[i] = u.KubernetesVersion
+	}
+	return kubeapi.UpgradeTargets(mc.Properties.CurrentKubernetesVersion, candidates), nil
+}
+
+func findPool(mc *managedCluster, name string) *agentPool {
+	for i := range mc.Properties.AgentPoolProfiles {
+		if mc.Properties.AgentPoolProfiles[i].Name == name {
+			return &mc.Properties.AgentPoolProfiles[i]
+		}
+	}
+	return nil
+}
+
+func fromAgentPool(ap *agentPool) *domain.NodePool {
+	pool := &domain.NodePool{
+		Name:         ap.Name,
+		InstanceType: ap.VMSize,
+		NodeCount:    ap.Count,
+		AutoScaling:  ap.EnableAutoScaling,
+		Version:      ap.OrchestratorVersion,
+	}
+	if ap.MinCount != nil {
+		pool.MinCount = *ap.MinCount
+	}
+	if ap.MaxCount != nil {
+		pool.MaxCount = *ap.MaxCount
+	}
+	return pool
+}
+
+func toAgentPool(pool *domain.NodePool) agentPool {
+	ap := agentPool{
+		Name:                pool.Name,
+		Count:               pool.NodeCount,
+		VMSize:              pool.InstanceType,
+		Type:                "VirtualMachineScaleSets",
+		OSType:              "Linux",
+		EnableAutoScaling:   pool.AutoScaling,
+		OrchestratorVersion: pool.Version,
+	}
+	if pool.AutoScaling {
+		min, max := pool.MinCount, pool.MaxCount
+		ap.MinCount, ap.MaxCount = &min, &max
+	}
+	return ap
+}
@@ -0,0 +1,78 @@
+package aks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// managementScope is the OAuth scope of Azure Resource Manager
+const managementScope = "https://management.azure.com/.default"
+
+// principal reads the service principal from the secrets store
+func (a *Adapter) principal(ctx context.Context) (*principal, error) {
+	secret, err := a.secrets.GetSecret(ctx, a.config.CredentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	p := &principal{
+		TenantID:       string(secret["tenant_id"]),
+		ClientID:       string(secret["client_id"]),
+		ClientSecret:   string(secret["client_secret"]),
+		SubscriptionID: string(secret["subscription_id"]),
+	}
+	if p.TenantID == "" || p.ClientID == "" || p.ClientSecret == "" || p.SubscriptionID == "" {
+		return nil, errors.Internal("the Azure credentials secret needs tenant_id, client_id, client_secret and subscription_id")
+	}
+	return p, nil
+}
+
+// accessToken returns a Resource Manager token of the service principal,
+// cached until shortly before it expires
+func (a *Adapter) accessToken(ctx context.Context, p *principal) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Now().Before(a.expires) {
+		return a.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"scope":         {managementScope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.loginURL+"/"+p.TenantID+"/oauth2/v2.0/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	start := time.Now()
+	resp, err := a.httpClient.Do(req)
+	metrics.ObserveAdapterCall("aks", http.MethodPost, start, resp, err)
+	if err != nil {
+		return "", errors.DependencyFailed("aks", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", handleError(resp)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", errors.Wrap(err, "failed to decode token response")
+	}
+	a.token = result.AccessToken
+	a.expires = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return a.token, nil
+}
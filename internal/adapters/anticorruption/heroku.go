@@ -0,0 +1,208 @@
+package anticorruption
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/manifest"
+)
+
+// HerokuAPIURL is the Heroku Platform API
+const HerokuAPIURL = "https://api.heroku.com"
+
+// herokuPort is the port web dynos are told to listen on through $PORT
+const herokuPort = 5000
+
+// herokuSizes are the memory (MB) and CPU (millicores) requested for a dyno size
+var herokuSizes = map[string][2]int{
+	"free":              {512, 250},
+	"eco":               {512, 250},
+	"hobby":             {512, 250},
+	"basic":             {512, 250},
+	"standard-1x":       {512, 250},
+	"standard-2x":       {1024, 500},
+	"performance-m":     {2560, 1000},
+	"performance-l":     {14336, 4000},
+	"performance-l-ram": {30720, 2000},
+	"performance-xl":    {63488, 4000},
+	"performance-2xl":   {129024, 8000},
+}
+
+// HerokuSource reads a Heroku app: its dynos become services built from a
+// repository with buildpacks and its config vars a secret they all read
+type HerokuSource struct {
+	baseURL    string
+	token      string
+	app        string
+	repository string
+	branch     string
+	httpClient *http.Client
+}
+
+// NewHerokuSource creates a source for an app. Heroku does not expose the
+// code of an app, so repository, with the app's code, is required.
+func NewHerokuSource(baseURL, token, app, repository, branch string) *HerokuSource {
+	if baseURL == "" {
+		baseURL = HerokuAPIURL
+	}
+	return &HerokuSource{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		app:        app,
+		repository: repository,
+		branch:     branch,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name implements Source
+func (s *HerokuSource) Name() string {
+	return "heroku"
+}
+
+// Fetch implements Source
+func (s *HerokuSource) Fetch(ctx context.Context) (*Snapshot, error) {
+	var app struct {
+		ID        string    `json:"id"`
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+		Region    struct {
+			Name string `json:"name"`
+		} `json:"region"`
+		Stack struct {
+			Name string `json:"name"`
+		} `json:"stack"`
+	}
+	if err := s.get(ctx, "/apps/"+url.PathEscape(s.app), &app); err != nil {
+		return nil, err
+	}
+
+	var configVars map[string]*string
+	if err := s.get(ctx, "/apps/"+url.PathEscape(s.app)+"/config-vars", &configVars); err != nil {
+		return nil, err
+	}
+	var formation []struct {
+		Type     string `json:"type"`
+		Quantity int    `json:"quantity"`
+		Size     string `json:"size"`
+		Command  string `json:"command"`
+	}
+	if err := s.get(ctx, "/apps/"+url.PathEscape(s.app)+"/formation", &formation); err != nil {
+		return nil, err
+	}
+	var addons []struct {
+		Name         string `json:"name"`
+		AddonService struct {
+			Name string `json:"name"`
+		} `json:"addon_service"`
+		Plan struct {
+			Name string `json:"name"`
+		} `json:"plan"`
+		ConfigVars []string `json:"config_vars"`
+	}
+	if err := s.get(ctx, "/apps/"+url.PathEscape(s.app)+"/addons", &addons); err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Project: LegacyProjectDTO{
+			ProjectID:   app.ID,
+			ProjectName: app.Name,
+			Desc:        "Imported from the Heroku app " + app.Name,
+			CreatedTime: app.CreatedAt.UnixMilli(),
+			UpdatedTime: app.UpdatedAt.UnixMilli(),
+			Settings:    map[string]interface{}{"heroku_region": app.Region.Name, "heroku_stack": app.Stack.Name},
+		},
+	}
+
+	config := SecretValues{
+		LegacySecretDTO: LegacySecretDTO{Name: app.Name + "-config", SecretType: "environment"},
+		Values:          map[string]string{},
+	}
+	for key, value := range configVars {
+		if value != nil {
+			config.Values[key] = *value
+		}
+	}
+	if len(config.Values) > 0 {
+		snapshot.Secrets = append(snapshot.Secrets, config)
+	}
+
+	sort.Slice(formation, func(i, j int) bool { return formation[i].Type < formation[j].Type })
+	for _, process := range formation {
+		if process.Type == "release" {
+			snapshot.Unsupported = append(snapshot.Unsupported, manifest.Unsupported{
+				Service: process.Type,
+				Key:     "release",
+				Reason:  "release phase commands are not supported; run them as a deploy hook",
+			})
+			continue
+		}
+		if s.repository == "" {
+			snapshot.Unsupported = append(snapshot.Unsupported, manifest.Unsupported{
+				Service: process.Type,
+				Key:     "repository",
+				Reason:  "Heroku does not expose the app's code; import again with the repository holding it",
+			})
+			continue
+		}
+
+		size, ok := herokuSizes[strings.ToLower(process.Size)]
+		if !ok {
+			size = herokuSizes["standard-1x"]
+			snapshot.Unsupported = append(snapshot.Unsupported, manifest.Unsupported{
+				Service: process.Type,
+				Key:     "size",
+				Reason:  fmt.Sprintf("unknown dyno size %s; sized like standard-1x", process.Size),
+			})
+		}
+		service := LegacyServiceDTO{
+			Name:       process.Type,
+			Type:       "worker",
+			RepoURL:    s.repository,
+			BranchName: s.branch,
+			BuildType:  "buildpack",
+			Instances:  process.Quantity,
+			MemoryMB:   size[0],
+			CPUShares:  size[1],
+		}
+		if len(config.Values) > 0 {
+			service.SecretRefs = []string{config.Name}
+		}
+		if process.Type == "web" {
+			service.Type = "web"
+			service.ContainerPort = herokuPort
+			service.PublicPort = true
+			service.EnvVars = map[string]string{"PORT": fmt.Sprint(herokuPort)}
+		} else {
+			snapshot.Unsupported = append(snapshot.Unsupported, manifest.Unsupported{
+				Service: process.Type,
+				Key:     "command",
+				Reason:  fmt.Sprintf("the service runs the image's default command, not %q; set it in the Procfile's web entry or the image", process.Command),
+			})
+		}
+		snapshot.Services = append(snapshot.Services, service)
+	}
+
+	for _, addon := range addons {
+		snapshot.Unsupported = append(snapshot.Unsupported, manifest.Unsupported{
+			Key: "addons." + addon.Name,
+			Reason: fmt.Sprintf("%s (%s) is not migrated; its config vars (%s) were imported as they are and still point at Heroku",
+				addon.AddonService.Name, addon.Plan.Name, strings.Join(addon.ConfigVars, ", ")),
+		})
+	}
+	return snapshot, nil
+}
+
+func (s *HerokuSource) get(ctx context.Context, path string, out interface{}) error {
+	return getJSON(ctx, s.httpClient, "heroku", s.baseURL+path, map[string]string{
+		"Accept":        "application/vnd.heroku+json; version=3",
+		"Authorization": "Bearer " + s.token,
+	}, out)
+}
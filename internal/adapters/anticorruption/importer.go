@@ -0,0 +1,341 @@
+package anticorruption
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/manifest"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Source reads a project from another platform into legacy DTOs
+type Source interface {
+	// Name identifies the platform, e.g. heroku
+	Name() string
+	Fetch(ctx context.Context) (*Snapshot, error)
+}
+
+// Snapshot is a project as another platform describes it
+type Snapshot struct {
+	Project     LegacyProjectDTO
+	Services    []LegacyServiceDTO
+	Secrets     []SecretValues
+	Unsupported []manifest.Unsupported // Addons and settings that cannot be carried over
+}
+
+// SecretValues is a secret group with its values, which the importer writes
+// to Vault. The VaultPath of the DTO is set by the importer.
+type SecretValues struct {
+	LegacySecretDTO
+	Values map[string]string
+}
+
+// SecretWriter stores secret values, usually in Vault
+type SecretWriter interface {
+	WriteSecret(ctx context.Context, path string, data map[string]string) (int, error)
+}
+
+// ImportOptions tune an import
+type ImportOptions struct {
+	ProjectID *uuid.UUID // Import into this project instead of creating one
+	OwnerID   uuid.UUID  // Owner of a created project
+	DryRun    bool       // Report what would be created without changing anything
+}
+
+// SecretImport is a secret group an import registers
+type SecretImport struct {
+	Action    string   `json:"action"` // create or update
+	Name      string   `json:"name"`
+	VaultPath string   `json:"vault_path"`
+	Keys      []string `json:"keys"`
+}
+
+// ImportReport describes an import, or what it would do on a dry run
+type ImportReport struct {
+	DryRun        bool                   `json:"dry_run"`
+	Source        string                 `json:"source"`
+	ProjectAction string                 `json:"project_action"` // create or existing
+	Project       *domain.Project        `json:"project"`
+	Secrets       []SecretImport         `json:"secrets"`
+	Services      *manifest.Result       `json:"services"`
+	Unsupported   []manifest.Unsupported `json:"unsupported"`
+}
+
+// Importer creates projects, services and secrets from the projects of other
+// platforms. Services are converged like a northstack.yaml, so importing again
+// updates what an earlier import created.
+type Importer struct {
+	projects *ProjectTranslator
+	services *ServiceTranslator
+	secrets  *SecretTranslator
+
+	projectRepo domain.ProjectRepository
+	secretRepo  domain.SecretRepository
+	applier     *manifest.Applier
+	vault       SecretWriter
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewImporter creates a new Importer. vault may be nil, in which case secret
+// values are not imported and are reported instead.
+func NewImporter(projectRepo domain.ProjectRepository, secretRepo domain.SecretRepository, applier *manifest.Applier, vault SecretWriter, eventBus domain.EventBus, log *logger.Logger) *Importer {
+	return &Importer{
+		projects:    NewProjectTranslator(),
+		services:    NewServiceTranslator(),
+		secrets:     NewSecretTranslator(),
+		projectRepo: projectRepo,
+		secretRepo:  secretRepo,
+		applier:     applier,
+		vault:       vault,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Import fetches a project from a source and recreates it here. Secret values
+// go to Vault under imported/<project slug>/<secret name>.
+func (i *Importer) Import(ctx context.Context, source Source, opts ImportOptions) (*ImportReport, error) {
+	snapshot, err := source.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{
+		DryRun:      opts.DryRun,
+		Source:      source.Name(),
+		Secrets:     []SecretImport{},
+		Unsupported: append([]manifest.Unsupported{}, snapshot.Unsupported...),
+	}
+
+	project, err := i.project(ctx, snapshot, opts, report)
+	if err != nil {
+		return nil, err
+	}
+	report.Project = project
+
+	imported := make(map[string]bool, len(snapshot.Secrets))
+	for idx := range snapshot.Secrets {
+		name, err := i.importSecret(ctx, project, &snapshot.Secrets[idx], opts.DryRun, report)
+		if err != nil {
+			return nil, err
+		}
+		if name != "" {
+			imported[name] = true
+		}
+	}
+
+	m := &manifest.Manifest{Version: manifest.Version}
+	for idx := range snapshot.Services {
+		service, err := i.services.FromLegacy(&snapshot.Services[idx])
+		if err != nil {
+			return nil, err
+		}
+		spec := serviceSpec(service)
+		// Secrets whose values were not imported would leave the service without them
+		spec.Secrets = nil
+		for _, name := range service.SecretRefs {
+			if imported[name] {
+				spec.Secrets = append(spec.Secrets, name)
+			}
+		}
+		m.Services = append(m.Services, spec)
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	if report.Services, err = i.applier.Apply(ctx, project.ID, m, manifest.Options{DryRun: opts.DryRun}); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(report.Unsupported, func(a, b int) bool {
+		return report.Unsupported[a].Service < report.Unsupported[b].Service
+	})
+	if !opts.DryRun {
+		i.logger.Info().
+			Str("source", source.Name()).
+			Str("project_id", project.ID.String()).
+			Int("services", len(m.Services)).
+			Int("secrets", len(report.Secrets)).
+			Msg("Project imported")
+	}
+	return report, nil
+}
+
+// project returns the project to import into, creating it unless the import
+// targets an existing one or is a dry run
+func (i *Importer) project(ctx context.Context, snapshot *Snapshot, opts ImportOptions, report *ImportReport) (*domain.Project, error) {
+	if opts.ProjectID != nil {
+		report.ProjectAction = "existing"
+		return i.projectRepo.GetByID(ctx, *opts.ProjectID)
+	}
+
+	project, err := i.projects.FromLegacy(&snapshot.Project)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	project.ID = uuid.New()
+	project.OwnerID = opts.OwnerID
+	project.CreatedAt = now
+	project.UpdatedAt = now
+	if project.Labels == nil {
+		project.Labels = map[string]string{}
+	}
+	project.Labels["northstack.io/imported-from"] = report.Source
+
+	if existing, err := i.projectRepo.GetBySlug(ctx, project.Slug); err == nil && existing != nil {
+		return nil, errors.Conflict(fmt.Sprintf("project %s (import into it with project_id)", project.Slug))
+	}
+
+	report.ProjectAction = "create"
+	if opts.DryRun {
+		return project, nil
+	}
+	if err := i.projectRepo.Create(ctx, project); err != nil {
+		return nil, err
+	}
+	i.publish(ctx, "project.created", map[string]interface{}{
+		"project_id": project.ID.String(),
+		"name":       project.Name,
+		"owner_id":   project.OwnerID.String(),
+	})
+	return project, nil
+}
+
+// importSecret writes a secret group's values to Vault and registers it with
+// the project. It returns the secret's name, or "" when it was not imported.
+func (i *Importer) importSecret(ctx context.Context, project *domain.Project, values *SecretValues, dryRun bool, report *ImportReport) (string, error) {
+	keys := make([]string, 0, len(values.Values))
+	for key := range values.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if i.vault == nil {
+		report.Unsupported = append(report.Unsupported, manifest.Unsupported{
+			Key:    "secrets." + values.Name,
+			Reason: "Vault is not configured, so secret values cannot be imported; copy them manually",
+		})
+		return "", nil
+	}
+
+	dto := values.LegacySecretDTO
+	dto.ProjectRef = project.ID.String()
+	dto.Keys = keys
+	dto.VaultPath = "imported/" + project.Slug + "/" + generateSlug(dto.Name)
+	secret, err := i.secrets.FromLegacy(&dto)
+	if err != nil {
+		return "", err
+	}
+
+	change := SecretImport{Action: "create", Name: secret.Name, VaultPath: secret.VaultPath, Keys: keys}
+	existing, err := i.secretRepo.GetByName(ctx, project.ID, secret.Name)
+	if err == nil && existing != nil {
+		change.Action = "update"
+		secret = existing
+		secret.Keys = keys
+	}
+	report.Secrets = append(report.Secrets, change)
+	if dryRun {
+		return secret.Name, nil
+	}
+
+	version, err := i.vault.WriteSecret(ctx, secret.VaultPath, values.Values)
+	if err != nil {
+		return "", err
+	}
+	secret.Version = version
+	secret.UpdatedAt = time.Now()
+	if change.Action == "create" {
+		err = i.secretRepo.Create(ctx, secret)
+	} else {
+		err = i.secretRepo.Update(ctx, secret)
+	}
+	if err != nil {
+		return "", err
+	}
+	return secret.Name, nil
+}
+
+// serviceSpec returns the manifest entry of a translated service
+func serviceSpec(service *domain.Service) manifest.ServiceSpec {
+	spec := manifest.ServiceSpec{
+		Name:   service.Name,
+		Slug:   service.Slug,
+		Type:   string(service.Type),
+		Build:  service.BuildSource,
+		Env:    service.EnvVars,
+		Labels: service.Labels,
+		Resources: &manifest.ResourcesSpec{
+			CPURequest:    service.Resources.CPURequest,
+			CPULimit:      service.Resources.CPULimit,
+			MemoryRequest: service.Resources.MemoryRequest,
+			MemoryLimit:   service.Resources.MemoryLimit,
+		},
+		Scaling: &manifest.ScalingSpec{
+			MinReplicas: service.Scaling.MinReplicas,
+			MaxReplicas: service.Scaling.MaxReplicas,
+		},
+	}
+	for _, p := range service.Ports {
+		spec.Ports = append(spec.Ports, manifest.PortSpec{
+			Name:       p.Name,
+			Port:       p.Port,
+			TargetPort: p.TargetPort,
+			Protocol:   p.Protocol,
+			Public:     p.Public,
+		})
+	}
+	return spec
+}
+
+func (i *Importer) publish(ctx context.Context, eventType string, data map[string]interface{}) {
+	if i.eventBus == nil {
+		return
+	}
+	event := &domain.Event{Type: eventType, Source: "import", Data: data}
+	if err := i.eventBus.Publish(ctx, eventType, event); err != nil {
+		i.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// getJSON fetches a resource from another platform's API
+func getJSON(ctx context.Context, client *http.Client, platform, url string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.DependencyFailed(platform, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return errors.Unauthorized(platform + " rejected the credentials")
+	case resp.StatusCode == http.StatusNotFound:
+		return errors.NotFound(platform+" resource", req.URL.Path)
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.DependencyFailed(platform, fmt.Errorf("GET %s answered %d: %s", req.URL.Path, resp.StatusCode, body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.DependencyFailed(platform, fmt.Errorf("failed to decode %s: %w", req.URL.Path, err))
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package anticorruption
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/manifest"
+)
+
+// NorthflankAPIURL is the Northflank API
+const NorthflankAPIURL = "https://api.northflank.com"
+
+// northflankMemory is the memory (MB) of compute plans that do not name it,
+// by their vCPU share in hundredths
+var northflankMemory = map[int]int{10: 256, 20: 512, 50: 1024, 100: 2048, 200: 4096, 400: 8192, 800: 16384, 1600: 32768}
+
+// NorthflankSource reads a Northflank project: its deployment and combined
+// services, their runtime environments and the project's secret groups
+type NorthflankSource struct {
+	baseURL    string
+	token      string
+	project    string
+	httpClient *http.Client
+}
+
+// NewNorthflankSource creates a source for a project, by its Northflank ID
+func NewNorthflankSource(baseURL, token, project string) *NorthflankSource {
+	if baseURL == "" {
+		baseURL = NorthflankAPIURL
+	}
+	return &NorthflankSource{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		project:    project,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Name implements Source
+func (s *NorthflankSource) Name() string {
+	return "northflank"
+}
+
+type northflankService struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	ServiceType string `json:"serviceType"` // combined, deployment or build
+	Deployment  struct {
+		Instances int `json:"instances"`
+		External  *struct {
+			ImagePath string `json:"imagePath"`
+		} `json:"external"`
+	} `json:"deployment"`
+	Billing struct {
+		DeploymentPlan string `json:"deploymentPlan"`
+	} `json:"billing"`
+	Ports []struct {
+		Name         string `json:"name"`
+		InternalPort int    `json:"internalPort"`
+		Public       bool   `json:"public"`
+		Protocol     string `json:"protocol"`
+	} `json:"ports"`
+	VCSData *struct {
+		ProjectURL    string `json:"projectUrl"`
+		ProjectBranch string `json:"projectBranch"`
+	} `json:"vcsData"`
+	BuildSettings struct {
+		Dockerfile *struct {
+			DockerFilePath string `json:"dockerFilePath"`
+		} `json:"dockerfile"`
+	} `json:"buildSettings"`
+}
+
+// Fetch implements Source
+func (s *NorthflankSource) Fetch(ctx context.Context) (*Snapshot, error) {
+	base := "/v1/projects/" + url.PathEscape(s.project)
+
+	var project struct {
+		Data struct {
+			ID          string `json:"id"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+		} `json:"data"`
+	}
+	if err := s.get(ctx, base, &project); err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{
+		Project: LegacyProjectDTO{
+			ProjectID:   project.Data.ID,
+			ProjectName: project.Data.Name,
+			Desc:        project.Data.Description,
+			CreatedTime: time.Now().UnixMilli(),
+			UpdatedTime: time.Now().UnixMilli(),
+		},
+	}
+
+	// Secret groups apply to every service unless restricted to some
+	var groups struct {
+		Data struct {
+			Secrets []struct {
+				ID           string `json:"id"`
+				Name         string `json:"name"`
+				SecretType   string `json:"secretType"`
+				Restrictions struct {
+					Restricted bool `json:"restricted"`
+					NFObjects  []struct {
+						ID string `json:"id"`
+					} `json:"nfObjects"`
+				} `json:"restrictions"`
+			} `json:"secrets"`
+		} `json:"data"`
+	}
+	if err := s.get(ctx, base+"/secrets?per_page=100", &groups); err != nil {
+		return nil, err
+	}
+	groupsOf := map[string][]string{} // Service ID, or "" for all, to group names
+	for _, group := range groups.Data.Secrets {
+		var details struct {
+			Data struct {
+				Secrets struct {
+					Variables map[string]string      `json:"variables"`
+					Files     map[string]interface{} `json:"files"`
+				} `json:"secrets"`
+			} `json:"data"`
+		}
+		if err := s.get(ctx, base+"/secrets/"+url.PathEscape(group.ID)+"/details", &details); err != nil {
+			return nil, err
+		}
+		for file := range details.Data.Secrets.Files {
+			snapshot.Unsupported = append(snapshot.Unsupported, manifest.Unsupported{
+				Key:    "secrets." + group.Name + ".files." + file,
+				Reason: "secret files are not supported; store the file as a variable",
+			})
+		}
+		if group.SecretType == "arguments" {
+			snapshot.Unsupported = append(snapshot.Unsupported, manifest.Unsupported{
+				Key:    "secrets." + group.Name,
+				Reason: "build arguments are not supported",
+			})
+			continue
+		}
+		if len(details.Data.Secrets.Variables) == 0 {
+			continue
+		}
+		snapshot.Secrets = append(snapshot.Secrets, SecretValues{
+			LegacySecretDTO: LegacySecretDTO{SecretID: group.ID, Name: group.Name, SecretType: "environment"},
+			Values:          details.Data.Secrets.Variables,
+		})
+		if !group.Restrictions.Restricted {
+			groupsOf[""] = append(groupsOf[""], group.Name)
+		}
+		for _, object := range group.Restrictions.NFObjects {
+			groupsOf[object.ID] = append(groupsOf[object.ID], group.Name)
+		}
+	}
+
+	var list struct {
+		Data struct {
+			Services []struct {
+				ID string `json:"id"`
+			} `json:"services"`
+		} `json:"data"`
+	}
+	if err := s.get(ctx, base+"/services?per_page=100", &list); err != nil {
+		return nil, err
+	}
+	for _, item := range list.Data.Services {
+		var details struct {
+			Data northflankService `json:"data"`
+		}
+		if err := s.get(ctx, base+"/services/"+url.PathEscape(item.ID), &details); err != nil {
+			return nil, err
+		}
+		service := details.Data
+		if service.ServiceType == "build" {
+			snapshot.Unsupported = append(snapshot.Unsupported, manifest.Unsupported{
+				Service: service.Name,
+				Key:     "serviceType",
+				Reason:  "build services are not imported; services build their own repositories",
+			})
+			continue
+		}
+
+		var runtime struct {
+			Data struct {
+				RuntimeEnvironment map[string]string `json:"runtimeEnvironment"`
+			} `json:"data"`
+		}
+		if err := s.get(ctx, base+"/services/"+url.PathEscape(service.ID)+"/runtime-environment", &runtime); err != nil {
+			return nil, err
+		}
+
+		dto, unsupported, ok := northflankServiceDTO(service)
+		snapshot.Unsupported = append(snapshot.Unsupported, unsupported...)
+		if !ok {
+			continue
+		}
+		dto.SecretRefs = append(append(dto.SecretRefs, groupsOf[""]...), groupsOf[service.ID]...)
+		if len(runtime.Data.RuntimeEnvironment) > 0 {
+			// Runtime variables are stored encrypted on Northflank; keep them secret here too
+			name := service.Name + "-env"
+			snapshot.Secrets = append(snapshot.Secrets, SecretValues{
+				LegacySecretDTO: LegacySecretDTO{Name: name, SecretType: "environment"},
+				Values:          runtime.Data.RuntimeEnvironment,
+			})
+			dto.SecretRefs = append(dto.SecretRefs, name)
+		}
+		snapshot.Services = append(snapshot.Services, dto)
+	}
+
+	var addons struct {
+		Data struct {
+			Addons []struct {
+				Name string `json:"name"`
+				Spec struct {
+					Type string `json:"type"`
+				} `json:"spec"`
+			} `json:"addons"`
+		} `json:"data"`
+	}
+	if err := s.get(ctx, base+"/addons?per_page=100", &addons); err != nil {
+		return nil, err
+	}
+	for _, addon := range addons.Data.Addons {
+		snapshot.Unsupported = append(snapshot.Unsupported, manifest.Unsupported{
+			Key:    "addons." + addon.Name,
+			Reason: fmt.Sprintf("the %s addon is not migrated; services still read its connection details from Northflank", addon.Spec.Type),
+		})
+	}
+	return snapshot, nil
+}
+
+// northflankServiceDTO translates a Northflank service into a legacy DTO and
+// reports what it drops. It returns false for services that cannot be built.
+func northflankServiceDTO(service northflankService) (LegacyServiceDTO, []manifest.Unsupported, bool) {
+	var unsupported []manifest.Unsupported
+	cpu, memory := northflankPlan(service.Billing.DeploymentPlan)
+	dto := LegacyServiceDTO{
+		ServiceID: service.ID,
+		Name:      service.Name,
+		Type:      "worker",
+		Instances: service.Deployment.Instances,
+		CPUShares: cpu,
+		MemoryMB:  memory,
+	}
+
+	switch {
+	case service.Deployment.External != nil && service.Deployment.External.ImagePath != "":
+		dto.Image = service.Deployment.External.ImagePath
+	case service.VCSData != nil:
+		dto.RepoURL = service.VCSData.ProjectURL
+		dto.BranchName = service.VCSData.ProjectBranch
+		dto.BuildType = "buildpack"
+		if service.BuildSettings.Dockerfile != nil {
+			dto.BuildType = "docker"
+			dto.Dockerfile = strings.TrimPrefix(service.BuildSettings.Dockerfile.DockerFilePath, "/")
+		}
+	default:
+		unsupported = append(unsupported, manifest.Unsupported{
+			Service: service.Name,
+			Key:     "deployment",
+			Reason:  "the service deploys the image of a build service; import again after pointing it at a repository or image",
+		})
+		return dto, unsupported, false
+	}
+
+	for i, port := range service.Ports {
+		if i == 0 && strings.ToUpper(port.Protocol) != "UDP" {
+			dto.Type = "combined"
+			dto.ContainerPort = port.InternalPort
+			dto.PublicPort = port.Public
+			continue
+		}
+		unsupported = append(unsupported, manifest.Unsupported{
+			Service: service.Name,
+			Key:     "ports." + port.Name,
+			Reason:  fmt.Sprintf("only the first TCP port is imported; add port %d after the import", port.InternalPort),
+		})
+	}
+	return dto, unsupported, true
+}
+
+// northflankPlan returns the millicores and memory (MB) of a compute plan,
+// e.g. nf-compute-20 or nf-compute-100-4, or zero for unknown plans
+func northflankPlan(plan string) (cpu, memory int) {
+	parts := strings.Split(strings.TrimPrefix(plan, "nf-compute-"), "-")
+	if !strings.HasPrefix(plan, "nf-compute-") || len(parts) > 2 {
+		return 0, 0
+	}
+	share, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0
+	}
+	memory = northflankMemory[share]
+	if len(parts) == 2 {
+		if gb, err := strconv.Atoi(parts[1]); err == nil {
+			memory = gb * 1024
+		}
+	}
+	return share * 10, memory
+}
+
+func (s *NorthflankSource) get(ctx context.Context, path string, out interface{}) error {
+	return getJSON(ctx, s.httpClient, "northflank", s.baseURL+path, map[string]string{
+		"Accept":        "application/json",
+		"Authorization": "Bearer " + s.token,
+	}, out)
+}
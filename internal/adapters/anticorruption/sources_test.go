@@ -0,0 +1,134 @@
+package anticorruption
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI answers GET requests with fixed JSON bodies by path
+func fakeAPI(t *testing.T, responses map[string]interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHerokuSource(t *testing.T) {
+	server := fakeAPI(t, map[string]interface{}{
+		"/apps/shop":             map[string]interface{}{"id": "0a1b", "name": "shop", "region": map[string]string{"name": "eu"}},
+		"/apps/shop/config-vars": map[string]interface{}{"SECRET_KEY": "s3cret", "DATABASE_URL": "postgres://", "UNSET": nil},
+		"/apps/shop/formation": []map[string]interface{}{
+			{"type": "web", "quantity": 2, "size": "standard-2x", "command": "npm start"},
+			{"type": "worker", "quantity": 1, "size": "basic", "command": "npm run jobs"},
+			{"type": "release", "quantity": 0, "size": "basic", "command": "npm run migrate"},
+		},
+		"/apps/shop/addons": []map[string]interface{}{
+			{"name": "postgresql-1", "addon_service": map[string]string{"name": "heroku-postgresql"}, "plan": map[string]string{"name": "essential-0"}, "config_vars": []string{"DATABASE_URL"}},
+		},
+	})
+
+	snapshot, err := NewHerokuSource(server.URL, "token", "shop", "https://github.com/acme/shop", "main").Fetch(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "shop", snapshot.Project.ProjectName)
+	require.Len(t, snapshot.Secrets, 1)
+	assert.Equal(t, "shop-config", snapshot.Secrets[0].Name)
+	assert.Equal(t, map[string]string{"SECRET_KEY": "s3cret", "DATABASE_URL": "postgres://"}, snapshot.Secrets[0].Values)
+
+	require.Len(t, snapshot.Services, 2)
+	web := snapshot.Services[0]
+	assert.Equal(t, "web", web.Name)
+	assert.Equal(t, 5000, web.ContainerPort)
+	assert.True(t, web.PublicPort)
+	assert.Equal(t, 1024, web.MemoryMB)
+	assert.Equal(t, "buildpack", web.BuildType)
+	assert.Equal(t, []string{"shop-config"}, web.SecretRefs)
+
+	service, err := NewServiceTranslator().FromLegacy(&web)
+	require.NoError(t, err)
+	assert.Equal(t, "buildpack", service.BuildSource.Type)
+	assert.Equal(t, int32(2), service.Scaling.MinReplicas)
+
+	keys := map[string]bool{}
+	for _, u := range snapshot.Unsupported {
+		keys[u.Service+"/"+u.Key] = true
+	}
+	assert.Equal(t, map[string]bool{"release/release": true, "worker/command": true, "/addons.postgresql-1": true}, keys)
+}
+
+func TestHerokuSourceRejectedCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := NewHerokuSource(server.URL, "token", "shop", "", "").Fetch(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "heroku rejected the credentials")
+}
+
+func TestNorthflankSource(t *testing.T) {
+	server := fakeAPI(t, map[string]interface{}{
+		"/v1/projects/shop": map[string]interface{}{"data": map[string]string{"id": "shop", "name": "Shop"}},
+		"/v1/projects/shop/secrets": map[string]interface{}{"data": map[string]interface{}{"secrets": []map[string]interface{}{
+			{"id": "shared", "name": "shared", "secretType": "environment-arguments"},
+			{"id": "api-only", "name": "api-only", "secretType": "environment", "restrictions": map[string]interface{}{"restricted": true, "nfObjects": []map[string]string{{"id": "api"}}}},
+		}}},
+		"/v1/projects/shop/secrets/shared/details":   map[string]interface{}{"data": map[string]interface{}{"secrets": map[string]interface{}{"variables": map[string]string{"SENTRY_DSN": "https://"}}}},
+		"/v1/projects/shop/secrets/api-only/details": map[string]interface{}{"data": map[string]interface{}{"secrets": map[string]interface{}{"variables": map[string]string{"STRIPE_KEY": "sk"}}}},
+		"/v1/projects/shop/services":                 map[string]interface{}{"data": map[string]interface{}{"services": []map[string]string{{"id": "api"}, {"id": "builder"}}}},
+		"/v1/projects/shop/services/api": map[string]interface{}{"data": map[string]interface{}{
+			"id": "api", "name": "api", "serviceType": "combined",
+			"deployment":    map[string]interface{}{"instances": 2},
+			"billing":       map[string]string{"deploymentPlan": "nf-compute-100-4"},
+			"ports":         []map[string]interface{}{{"name": "p01", "internalPort": 8080, "public": true, "protocol": "HTTP"}, {"name": "metrics", "internalPort": 9090, "protocol": "HTTP"}},
+			"vcsData":       map[string]string{"projectUrl": "https://github.com/acme/api", "projectBranch": "main"},
+			"buildSettings": map[string]interface{}{"dockerfile": map[string]string{"dockerFilePath": "/Dockerfile"}},
+		}},
+		"/v1/projects/shop/services/api/runtime-environment": map[string]interface{}{"data": map[string]interface{}{"runtimeEnvironment": map[string]string{"LOG_LEVEL": "info"}}},
+		"/v1/projects/shop/services/builder":                 map[string]interface{}{"data": map[string]string{"id": "builder", "name": "builder", "serviceType": "build"}},
+		"/v1/projects/shop/addons":                           map[string]interface{}{"data": map[string]interface{}{"addons": []map[string]interface{}{{"name": "db", "spec": map[string]string{"type": "postgresql"}}}}},
+	})
+
+	snapshot, err := NewNorthflankSource(server.URL, "token", "shop").Fetch(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, snapshot.Services, 1)
+	api := snapshot.Services[0]
+	assert.Equal(t, "docker", api.BuildType)
+	assert.Equal(t, "Dockerfile", api.Dockerfile)
+	assert.Equal(t, 8080, api.ContainerPort)
+	assert.Equal(t, 1000, api.CPUShares)
+	assert.Equal(t, 4096, api.MemoryMB)
+	assert.Equal(t, []string{"shared", "api-only", "api-env"}, api.SecretRefs)
+	assert.Len(t, snapshot.Secrets, 3)
+
+	keys := map[string]bool{}
+	for _, u := range snapshot.Unsupported {
+		keys[u.Service+"/"+u.Key] = true
+	}
+	assert.Equal(t, map[string]bool{"api/ports.metrics": true, "builder/serviceType": true, "/addons.db": true}, keys)
+}
+
+func TestNorthflankPlan(t *testing.T) {
+	cpu, memory := northflankPlan("nf-compute-20")
+	assert.Equal(t, 200, cpu)
+	assert.Equal(t, 512, memory)
+
+	cpu, memory = northflankPlan("custom")
+	assert.Zero(t, cpu)
+	assert.Zero(t, memory)
+}
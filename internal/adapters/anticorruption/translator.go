@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,14 +35,33 @@ type LegacyServiceDTO struct {
 	ProjectRef    string            `json:"projectId"`
 	RepoURL       string            `json:"gitUrl"`
 	BranchName    string            `json:"branch"`
+	BuildType     string            `json:"buildType,omitempty"` // git (default), buildpack or docker
+	Dockerfile    string            `json:"dockerfile,omitempty"`
+	Image         string            `json:"image,omitempty"` // Deploys the image instead of building the repository
 	ContainerPort int               `json:"port"`
+	PublicPort    bool              `json:"public,omitempty"`
 	EnvVars       map[string]string `json:"env"`
+	SecretRefs    []string          `json:"secrets,omitempty"` // Names of the secret groups the service reads
 	Instances     int               `json:"replicas"`
 	CPUShares     int               `json:"cpu"`
 	MemoryMB      int               `json:"memory"`
 	StatusStr     string            `json:"status"`
 }
 
+// LegacySecretDTO represents a secret group in legacy format. Values are
+// never part of it; they stay in Vault under VaultPath.
+type LegacySecretDTO struct {
+	SecretID    string   `json:"secretId"`
+	Name        string   `json:"name"`
+	ProjectRef  string   `json:"projectId"`
+	SecretType  string   `json:"secretType"`
+	Keys        []string `json:"keys"`
+	VaultPath   string   `json:"vaultPath"`
+	Version     int      `json:"version"`
+	CreatedTime int64    `json:"createdAt"`
+	UpdatedTime int64    `json:"updatedAt"`
+}
+
 // ProjectTranslator translates between legacy and domain project representations
 type ProjectTranslator struct{}
 
@@ -121,39 +141,72 @@ func (t *ServiceTranslator) FromLegacy(legacy *LegacyServiceDTO) (*domain.Servic
 	// Map legacy status to domain ServiceStatus
 	status := mapLegacyServiceStatus(legacy.StatusStr)
 
-	return &domain.Service{
-		ID:        id,
-		ProjectID: projectID,
-		Name:      legacy.Name,
-		Slug:      generateSlug(legacy.Name),
-		Type:      serviceType,
-		Status:    status,
-		BuildSource: domain.BuildSource{
-			Type:       "git",
-			Repository: legacy.RepoURL,
-			Branch:     legacy.BranchName,
-		},
-		Resources: domain.ResourceLimits{
-			CPURequest:    fmt.Sprintf("%dm", legacy.CPUShares),
-			CPULimit:      fmt.Sprintf("%dm", legacy.CPUShares*2),
-			MemoryRequest: fmt.Sprintf("%dMi", legacy.MemoryMB),
-			MemoryLimit:   fmt.Sprintf("%dMi", legacy.MemoryMB*2),
-		},
-		Scaling: domain.ScalingConfig{
-			MinReplicas: int32(legacy.Instances),
-			MaxReplicas: int32(legacy.Instances * 2),
-		},
-		Ports: []domain.ServicePort{
+	// Legacy payloads often leave sizing out; fall back to the platform defaults
+	cpu, memory, instances := legacy.CPUShares, legacy.MemoryMB, legacy.Instances
+	if cpu <= 0 {
+		cpu = 100
+	}
+	if memory <= 0 {
+		memory = 128
+	}
+	if instances <= 0 {
+		instances = 1
+	}
+
+	var ports []domain.ServicePort
+	if legacy.ContainerPort > 0 {
+		ports = []domain.ServicePort{
 			{
 				Name:       "http",
 				Port:       int32(legacy.ContainerPort),
 				TargetPort: int32(legacy.ContainerPort),
 				Protocol:   "TCP",
+				Public:     legacy.PublicPort,
 			},
+		}
+	}
+
+	build := domain.BuildSource{
+		Type:       "git",
+		Repository: legacy.RepoURL,
+		Branch:     legacy.BranchName,
+		Dockerfile: legacy.Dockerfile,
+	}
+	switch {
+	case legacy.Image != "":
+		build = domain.BuildSource{Type: "docker", Image: legacy.Image}
+	case legacy.BuildType != "":
+		build.Type = legacy.BuildType
+	}
+
+	var secretRefs []string
+	for _, name := range legacy.SecretRefs {
+		secretRefs = append(secretRefs, generateSlug(name))
+	}
+
+	return &domain.Service{
+		ID:          id,
+		ProjectID:   projectID,
+		Name:        legacy.Name,
+		Slug:        generateSlug(legacy.Name),
+		Type:        serviceType,
+		Status:      status,
+		BuildSource: build,
+		Resources: domain.ResourceLimits{
+			CPURequest:    fmt.Sprintf("%dm", cpu),
+			CPULimit:      fmt.Sprintf("%dm", cpu*2),
+			MemoryRequest: fmt.Sprintf("%dMi", memory),
+			MemoryLimit:   fmt.Sprintf("%dMi", memory*2),
 		},
-		EnvVars:   legacy.EnvVars,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		Scaling: domain.ScalingConfig{
+			MinReplicas: int32(instances),
+			MaxReplicas: int32(instances * 2),
+		},
+		Ports:      ports,
+		EnvVars:    legacy.EnvVars,
+		SecretRefs: secretRefs,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
 	}, nil
 }
 
@@ -171,13 +224,67 @@ func (t *ServiceTranslator) ToLegacy(service *domain.Service) *LegacyServiceDTO
 		ProjectRef:    service.ProjectID.String(),
 		RepoURL:       service.BuildSource.Repository,
 		BranchName:    service.BuildSource.Branch,
+		BuildType:     service.BuildSource.Type,
+		Dockerfile:    service.BuildSource.Dockerfile,
+		Image:         service.BuildSource.Image,
 		ContainerPort: port,
 		EnvVars:       service.EnvVars,
+		SecretRefs:    service.SecretRefs,
 		Instances:     int(service.Scaling.MinReplicas),
+		CPUShares:     parseQuantity(service.Resources.CPURequest, "m"),
+		MemoryMB:      parseQuantity(service.Resources.MemoryRequest, "Mi"),
 		StatusStr:     mapDomainServiceStatus(service.Status),
 	}
 }
 
+// SecretTranslator translates between legacy and domain secret representations
+type SecretTranslator struct{}
+
+// NewSecretTranslator creates a new translator
+func NewSecretTranslator() *SecretTranslator {
+	return &SecretTranslator{}
+}
+
+// FromLegacy converts a legacy secret DTO to domain Secret
+func (t *SecretTranslator) FromLegacy(legacy *LegacySecretDTO) (*domain.Secret, error) {
+	if legacy.VaultPath == "" {
+		return nil, fmt.Errorf("secret %q has no vault path; values must be stored in Vault", legacy.Name)
+	}
+
+	id, _ := uuid.Parse(legacy.SecretID)
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+	projectID, _ := uuid.Parse(legacy.ProjectRef)
+
+	return &domain.Secret{
+		ID:        id,
+		ProjectID: projectID,
+		Name:      generateSlug(legacy.Name),
+		Type:      mapLegacySecretType(legacy.SecretType),
+		Keys:      legacy.Keys,
+		VaultPath: strings.Trim(legacy.VaultPath, "/"),
+		Version:   1,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}, nil
+}
+
+// ToLegacy converts a domain Secret to legacy DTO format
+func (t *SecretTranslator) ToLegacy(secret *domain.Secret) *LegacySecretDTO {
+	return &LegacySecretDTO{
+		SecretID:    secret.ID.String(),
+		Name:        secret.Name,
+		ProjectRef:  secret.ProjectID.String(),
+		SecretType:  mapDomainSecretType(secret.Type),
+		Keys:        secret.Keys,
+		VaultPath:   secret.VaultPath,
+		Version:     secret.Version,
+		CreatedTime: secret.CreatedAt.UnixMilli(),
+		UpdatedTime: secret.UpdatedAt.UnixMilli(),
+	}
+}
+
 // LegacySystemFacade provides a unified interface to legacy systems
 type LegacySystemFacade struct {
 	projectTranslator *ProjectTranslator
@@ -282,6 +389,39 @@ func mapDomainServiceType(serviceType domain.ServiceType) string {
 	}
 }
 
+func mapLegacySecretType(legacyType string) domain.SecretType {
+	switch strings.ToLower(legacyType) {
+	case "tls", "certificate":
+		return domain.SecretTypeTLS
+	case "docker", "registry", "docker-config":
+		return domain.SecretTypeDockerConfig
+	default:
+		// Environment and build argument groups are plain key-value secrets
+		return domain.SecretTypeOpaque
+	}
+}
+
+func mapDomainSecretType(secretType domain.SecretType) string {
+	switch secretType {
+	case domain.SecretTypeTLS:
+		return "tls"
+	case domain.SecretTypeDockerConfig:
+		return "docker-config"
+	default:
+		return "environment"
+	}
+}
+
+// parseQuantity reads a Kubernetes quantity in the given unit, e.g. 250m or
+// 512Mi, returning 0 for anything else
+func parseQuantity(quantity, unit string) int {
+	n, err := strconv.Atoi(strings.TrimSuffix(quantity, unit))
+	if err != nil || !strings.HasSuffix(quantity, unit) {
+		return 0
+	}
+	return n
+}
+
 func mapDomainServiceStatus(status domain.ServiceStatus) string {
 	switch status {
 	case domain.ServiceStatusRunning:
@@ -0,0 +1,55 @@
+package anticorruption
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceTranslatorRoundTrip(t *testing.T) {
+	translator := NewServiceTranslator()
+
+	service, err := translator.FromLegacy(&LegacyServiceDTO{
+		Name:      "Web API",
+		Type:      "combined",
+		RepoURL:   "https://github.com/acme/api",
+		CPUShares: 250,
+		MemoryMB:  512,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "web-api", service.Slug)
+	assert.Equal(t, domain.ServiceTypeWebApp, service.Type)
+	assert.Equal(t, int32(1), service.Scaling.MinReplicas)
+	assert.Empty(t, service.Ports)
+
+	legacy := translator.ToLegacy(service)
+	assert.Equal(t, "combined", legacy.Type)
+	assert.Equal(t, 250, legacy.CPUShares)
+	assert.Equal(t, 512, legacy.MemoryMB)
+	assert.Equal(t, 1, legacy.Instances)
+}
+
+func TestSecretTranslatorRequiresVaultPath(t *testing.T) {
+	translator := NewSecretTranslator()
+
+	_, err := translator.FromLegacy(&LegacySecretDTO{Name: "db", SecretType: "environment"})
+	assert.Error(t, err)
+
+	projectID := uuid.New()
+	secret, err := translator.FromLegacy(&LegacySecretDTO{
+		Name:       "Registry Auth",
+		ProjectRef: projectID.String(),
+		SecretType: "docker-config",
+		Keys:       []string{".dockerconfigjson"},
+		VaultPath:  "/secret/acme/registry/",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "registry-auth", secret.Name)
+	assert.Equal(t, domain.SecretTypeDockerConfig, secret.Type)
+	assert.Equal(t, "secret/acme/registry", secret.VaultPath)
+	assert.Equal(t, projectID, secret.ProjectID)
+	assert.Equal(t, "docker-config", translator.ToLegacy(secret).SecretType)
+}
@@ -14,8 +14,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/availability"
 	"github.com/northstack/platform/internal/config"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/dualstack"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/internal/volumes"
 	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
 )
@@ -40,7 +45,7 @@ func NewAdapter(cfg *config.ArgoCDConfig, log *logger.Logger) *Adapter {
 		config: cfg,
 		httpClient: &http.Client{
 			Timeout:   cfg.Timeout,
-			Transport: transport,
+			Transport: tracing.Transport(transport),
 		},
 		logger:    log,
 		authToken: cfg.Token,
@@ -95,7 +100,20 @@ type argoHelmParam struct {
 
 // argoKustomize represents Kustomize-specific configuration
 type argoKustomize struct {
-	Images []string `json:"images,omitempty"`
+	Images  []string             `json:"images,omitempty"`
+	Patches []argoKustomizePatch `json:"patches,omitempty"`
+}
+
+// argoKustomizePatch represents a Kustomize patch applied to the rendered manifests
+type argoKustomizePatch struct {
+	Target argoPatchTarget `json:"target"`
+	Patch  string          `json:"patch"`
+}
+
+// argoPatchTarget selects the resources a Kustomize patch applies to
+type argoPatchTarget struct {
+	Kind          string `json:"kind"`
+	LabelSelector string `json:"labelSelector,omitempty"`
 }
 
 // argoDestination represents the deployment destination
@@ -238,9 +256,9 @@ func (a *Adapter) CreateApplication(ctx context.Context, service *domain.Service
 			Name:      appName,
 			Namespace: "argocd",
 			Labels: map[string]string{
-				"openpaas.io/service-id":     service.ID.String(),
-				"openpaas.io/project-id":     service.ProjectID.String(),
-				"openpaas.io/environment-id": environment.ID.String(),
+				domain.LabelServiceID:     service.ID.String(),
+				domain.LabelProjectID:     service.ProjectID.String(),
+				domain.LabelEnvironmentID: environment.ID.String(),
 			},
 			Annotations: service.Annotations,
 		},
@@ -287,6 +305,22 @@ func (a *Adapter) CreateApplication(ctx context.Context, service *domain.Service
 		}
 	}
 
+	// Give the service's Kubernetes Service the requested IP families
+	if patch := dualstack.Patch(service.Networking); patch != "" {
+		if app.Spec.Source.Kustomize == nil {
+			app.Spec.Source.Kustomize = &argoKustomize{}
+		}
+		setPatch(app.Spec.Source.Kustomize, "Service", service.ID, patch)
+	}
+
+	// Mount the service's volumes and spread its replicas across zones or nodes
+	if workloadPatch(service, "Deployment") != "" {
+		if app.Spec.Source.Kustomize == nil {
+			app.Spec.Source.Kustomize = &argoKustomize{}
+		}
+		setWorkloadPatches(app.Spec.Source.Kustomize, service)
+	}
+
 	body, err := json.Marshal(app)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to marshal application")
@@ -336,6 +370,23 @@ func (a *Adapter) UpdateApplication(ctx context.Context, service *domain.Service
 		}
 	}
 
+	// Update or drop the IP family patch
+	patch := dualstack.Patch(service.Networking)
+	if patch != "" && existing.Spec.Source.Kustomize == nil {
+		existing.Spec.Source.Kustomize = &argoKustomize{}
+	}
+	if existing.Spec.Source.Kustomize != nil {
+		setPatch(existing.Spec.Source.Kustomize, "Service", service.ID, patch)
+	}
+
+	// Update or drop the volume and topology spread patches
+	if workloadPatch(service, "Deployment") != "" && existing.Spec.Source.Kustomize == nil {
+		existing.Spec.Source.Kustomize = &argoKustomize{}
+	}
+	if existing.Spec.Source.Kustomize != nil {
+		setWorkloadPatches(existing.Spec.Source.Kustomize, service)
+	}
+
 	// Update labels
 	existing.Metadata.Labels["openpaas.io/version"] = service.CurrentVersion
 
@@ -556,7 +607,10 @@ func (a *Adapter) doRequest(ctx context.Context, method, path string, body []byt
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
-	return a.httpClient.Do(req)
+	start := time.Now()
+	resp, err := a.httpClient.Do(req)
+	metrics.ObserveAdapterCall("argocd", method, start, resp, err)
+	return resp, err
 }
 
 // handleError extracts error information from a response
@@ -590,3 +644,61 @@ func (a *Adapter) handleError(resp *http.Response) error {
 		return errors.Internal(fmt.Sprintf("ArgoCD API error (%d): %s", resp.StatusCode, msg))
 	}
 }
+
+// workloadPatch renders a strategic merge patch of a service's workload of
+// the given kind, mounting its volumes into the container named after the
+// service and spreading its replicas; empty when there is nothing to patch
+func workloadPatch(service *domain.Service, kind string) string {
+	podSpec := map[string]interface{}{}
+	selector := map[string]interface{}{domain.LabelServiceID: service.ID.String()}
+	if constraints := availability.Constraints(service.Availability, selector); len(constraints) > 0 {
+		podSpec["topologySpreadConstraints"] = constraints
+	}
+	if len(service.Volumes) > 0 {
+		podSpec["volumes"] = volumes.PodVolumes(service)
+		podSpec["containers"] = []interface{}{map[string]interface{}{
+			"name":         service.Slug,
+			"volumeMounts": volumes.Mounts(service),
+		}}
+	}
+	if len(podSpec) == 0 {
+		return ""
+	}
+
+	out, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": service.Slug},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{"spec": podSpec},
+		},
+	})
+	return string(out)
+}
+
+// setWorkloadPatches replaces the patches of a service's Deployment and
+// StatefulSet, whichever it runs as
+func setWorkloadPatches(k *argoKustomize, service *domain.Service) {
+	for _, kind := range []string{"Deployment", "StatefulSet"} {
+		setPatch(k, kind, service.ID, workloadPatch(service, kind))
+	}
+}
+
+// setPatch replaces the patch of a service's object of the given kind,
+// keeping any other patches; an empty patch removes it
+func setPatch(k *argoKustomize, kind string, serviceID uuid.UUID, patch string) {
+	target := argoPatchTarget{
+		Kind:          kind,
+		LabelSelector: fmt.Sprintf("%s=%s", domain.LabelServiceID, serviceID),
+	}
+	patches := k.Patches[:0]
+	for _, p := range k.Patches {
+		if p.Target != target {
+			patches = append(patches, p)
+		}
+	}
+	if patch != "" {
+		patches = append(patches, argoKustomizePatch{Target: target, Patch: patch})
+	}
+	k.Patches = patches
+}
@@ -10,11 +10,14 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/northstack/platform/internal/config"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
 	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
 )
@@ -31,7 +34,8 @@ func NewAdapter(cfg *config.CoolifyConfig, log *logger.Logger) *Adapter {
 	return &Adapter{
 		config: cfg,
 		httpClient: &http.Client{
-			Timeout: cfg.Timeout,
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
 		},
 		logger: log,
 	}
@@ -64,16 +68,23 @@ type coolifyApplication struct {
 
 // coolifyBuild represents a build in Coolify's API
 type coolifyBuild struct {
-	ID          string `json:"id"`
-	Status      string `json:"status"`
-	Logs        string `json:"logs,omitempty"`
-	Duration    int64  `json:"duration,omitempty"`
-	CreatedAt   string `json:"created_at"`
-	StartedAt   string `json:"started_at,omitempty"`
-	CompletedAt string `json:"completed_at,omitempty"`
-	ImageTag    string `json:"image_tag,omitempty"`
-	ImageDigest string `json:"image_digest,omitempty"`
-	Error       string `json:"error,omitempty"`
+	ID          string              `json:"id"`
+	Status      string              `json:"status"`
+	Logs        string              `json:"logs,omitempty"`
+	Duration    int64               `json:"duration,omitempty"`
+	CreatedAt   string              `json:"created_at"`
+	StartedAt   string              `json:"started_at,omitempty"`
+	CompletedAt string              `json:"completed_at,omitempty"`
+	ImageTag    string              `json:"image_tag,omitempty"`
+	ImageDigest string              `json:"image_digest,omitempty"`
+	Error       string              `json:"error,omitempty"`
+	Stages      []coolifyBuildStage `json:"stages,omitempty"`
+}
+
+// coolifyBuildStage is a build phase timing, reported by newer Coolify versions
+type coolifyBuildStage struct {
+	Name     string `json:"name"`
+	Duration int64  `json:"duration"`
 }
 
 // CreateProject creates a project in Coolify
@@ -223,6 +234,13 @@ func (a *Adapter) GetBuildStatus(ctx context.Context, buildID string) (*domain.B
 		},
 	}
 
+	for _, stage := range result.Stages {
+		build.Stages = append(build.Stages, domain.BuildStage{
+			Name:     mapCoolifyBuildStage(stage.Name),
+			Duration: stage.Duration,
+		})
+	}
+
 	if result.StartedAt != "" {
 		if t, err := time.Parse(time.RFC3339, result.StartedAt); err == nil {
 			build.StartedAt = &t
@@ -430,7 +448,10 @@ func (a *Adapter) doRequest(ctx context.Context, method, path string, body []byt
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
-	return a.httpClient.Do(req)
+	start := time.Now()
+	resp, err := a.httpClient.Do(req)
+	metrics.ObserveAdapterCall("coolify", method, start, resp, err)
+	return resp, err
 }
 
 // handleError extracts error information from a response
@@ -482,3 +503,18 @@ func mapCoolifyBuildStatus(status string) domain.BuildStatus {
 		return domain.BuildStatusQueued
 	}
 }
+
+func mapCoolifyBuildStage(name string) string {
+	switch strings.ToLower(name) {
+	case "clone", "git", "checkout":
+		return domain.BuildStageClone
+	case "install", "dependencies", "deps":
+		return domain.BuildStageDependencies
+	case "build", "compile":
+		return domain.BuildStageCompile
+	case "push", "image_push", "registry":
+		return domain.BuildStageImagePush
+	default:
+		return strings.ToLower(name)
+	}
+}
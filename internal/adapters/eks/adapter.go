@@ -0,0 +1,533 @@
+// Package eks provisions Kubernetes clusters through the Amazon EKS API
+// directly, for installations that do not run Rancher. Operations inside a
+// cluster go to its API server with a token signed by the same credentials.
+package eks
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/adapters/kubeapi"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// ExternalIDPrefix starts the external ID of every cluster the adapter
+// manages: eks:<region>:<name>
+const ExternalIDPrefix = "eks:"
+
+// Tags of the clusters the adapter creates
+const (
+	tagManaged = "northstack.io/managed"
+	// tagNodeCount holds the size of the default node group until it is
+	// created; EKS only accepts node groups once the control plane is active
+	tagNodeCount = "northstack.io/node-count"
+)
+
+// defaultNodeGroup is the node group created with a cluster
+const defaultNodeGroup = "default"
+
+// deleteTimeout bounds how long a deleted cluster waits for its node groups
+// to go before the control plane is deleted
+const deleteTimeout = 30 * time.Minute
+
+// deletePollInterval is the wait between checks of a deleted cluster's node groups
+var deletePollInterval = 15 * time.Second
+
+// Secrets reads the AWS credentials
+type Secrets interface {
+	GetSecret(ctx context.Context, path string) (map[string][]byte, error)
+}
+
+// Adapter implements the ClusterManagerAdapter interface for EKS
+type Adapter struct {
+	config     *config.EKSConfig
+	secrets    Secrets
+	httpClient *http.Client
+	logger     *logger.Logger
+	now        func() time.Time
+	endpoint   func(region string) string
+}
+
+// NewAdapter creates a new EKS adapter
+func NewAdapter(cfg *config.EKSConfig, secrets Secrets, log *logger.Logger) *Adapter {
+	return &Adapter{
+		config:     cfg,
+		secrets:    secrets,
+		httpClient: &http.Client{Timeout: cfg.Timeout, Transport: tracing.Transport(nil)},
+		logger:     log,
+		now:        time.Now,
+		endpoint: func(region string) string {
+			return fmt.Sprintf("https://eks.%s.amazonaws.com", region)
+		},
+	}
+}
+
+// eksCluster is a cluster as the EKS API describes it
+type eksCluster struct {
+	Name                 string            `json:"name"`
+	Arn                  string            `json:"arn"`
+	Version              string            `json:"version"`
+	Endpoint             string            `json:"endpoint"`
+	Status               string            `json:"status"`
+	Tags                 map[string]string `json:"tags"`
+	CreatedAt            float64           `json:"createdAt"`
+	CertificateAuthority struct {
+		Data string `json:"data"`
+	} `json:"certificateAuthority"`
+}
+
+// eksNodeGroup is a managed node group as the EKS API describes it
+type eksNodeGroup struct {
+	Name          string   `json:"nodegroupName"`
+	Version       string   `json:"version"`
+	Status        string   `json:"status"`
+	InstanceTypes []string `json:"instanceTypes"`
+	ScalingConfig struct {
+		MinSize     int32 `json:"minSize"`
+		MaxSize     int32 `json:"maxSize"`
+		DesiredSize int32 `json:"desiredSize"`
+	} `json:"scalingConfig"`
+}
+
+// CreateCluster creates the control plane of a cluster in its region. The
+// default node group follows once the control plane is active, when the
+// cluster is next read.
+func (a *Adapter) CreateCluster(ctx context.Context, cluster *domain.Cluster) (string, error) {
+	if cluster.Region == "" {
+		return "", errors.BadRequest("an AWS region is required")
+	}
+	nodeCount := cluster.NodeCount
+	if nodeCount < 1 {
+		nodeCount = 1
+	}
+
+	body := map[string]interface{}{
+		"name":    cluster.Slug,
+		"roleArn": a.config.ClusterRoleARN,
+		"resourcesVpcConfig": map[string]interface{}{
+			"subnetIds":        a.config.SubnetIDs,
+			"securityGroupIds": a.config.SecurityGroupIDs,
+		},
+		"tags": map[string]string{
+			tagManaged:   "true",
+			tagNodeCount: strconv.Itoa(int(nodeCount)),
+		},
+	}
+	if cluster.KubeVersion != "" {
+		body["version"] = cluster.KubeVersion
+	}
+	if err := a.doRequest(ctx, cluster.Region, http.MethodPost, "/clusters", body, nil); err != nil {
+		return "", err
+	}
+
+	id := ExternalIDPrefix + cluster.Region + ":" + cluster.Slug
+	a.logger.Info().
+		Str("external_id", id).
+		Str("cluster_name", cluster.Name).
+		Msg("Created cluster in EKS")
+	return id, nil
+}
+
+// GetCluster retrieves a cluster. Reading an active cluster creates its
+// default node group when that is still pending, and moves node groups to
+// the version of the control plane after an upgrade.
+func (a *Adapter) GetCluster(ctx context.Context, externalID string) (*domain.Cluster, error) {
+	region, name, err := parseExternalID(externalID)
+	if err != nil {
+		return nil, err
+	}
+	ec, err := a.describeCluster(ctx, region, name)
+	if err != nil {
+		return nil, err
+	}
+	cluster := toDomain(region, ec)
+	if ec.Status != "ACTIVE" {
+		return cluster, nil
+	}
+
+	if pending, ok := ec.Tags[tagNodeCount]; ok {
+		count, _ := strconv.Atoi(pending)
+		pool := &domain.NodePool{Name: defaultNodeGroup, InstanceType: a.config.InstanceType, NodeCount: int32(count), MinCount: 1, MaxCount: int32(count) * 2, AutoScaling: true}
+		if err := a.createNodeGroup(ctx, region, name, pool); err != nil && !isConflict(err) {
+			return nil, err
+		}
+		path := "/tags/" + url.PathEscape(ec.Arn) + "?tagKeys=" + url.QueryEscape(tagNodeCount)
+		if err := a.doRequest(ctx, region, http.MethodDelete, path, nil, nil); err != nil {
+			return nil, err
+		}
+		cluster.Status = domain.ClusterStatusProvisioning
+		cluster.NodeCount = int32(count)
+		return cluster, nil
+	}
+
+	groups, err := a.nodeGroups(ctx, region, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		cluster.NodeCount += group.ScalingConfig.DesiredSize
+		switch {
+		case group.Status != "ACTIVE":
+			cluster.Status = domain.ClusterStatusUpgrading
+		case group.Version != ec.Version:
+			path := fmt.Sprintf("/clusters/%s/node-groups/%s/update-version", name, group.Name)
+			if err := a.doRequest(ctx, region, http.MethodPost, path, map[string]string{"version": ec.Version}, nil); err != nil {
+				return nil, err
+			}
+			cluster.Status = domain.ClusterStatusUpgrading
+		}
+	}
+	return cluster, nil
+}
+
+// UpdateCluster upgrades the control plane to the cluster's Kubernetes
+// version and resizes the default node group to its node count, when set
+func (a *Adapter) UpdateCluster(ctx context.Context, cluster *domain.Cluster) error {
+	region, name, err := parseExternalID(cluster.RancherClusterID)
+	if err != nil {
+		return err
+	}
+	ec, err := a.describeCluster(ctx, region, name)
+	if err != nil {
+		return err
+	}
+
+	if cluster.KubeVersion != "" && cluster.KubeVersion != ec.Version {
+		path := fmt.Sprintf("/clusters/%s/updates", name)
+		if err := a.doRequest(ctx, region, http.MethodPost, path, map[string]string{"version": cluster.KubeVersion}, nil); err != nil {
+			return err
+		}
+	}
+	if cluster.NodeCount > 0 {
+		pool := &domain.NodePool{Name: defaultNodeGroup, NodeCount: cluster.NodeCount, MinCount: 1, MaxCount: cluster.NodeCount * 2, AutoScaling: true}
+		if err := a.UpdateNodePool(ctx, cluster.RancherClusterID, pool); err != nil {
+			return err
+		}
+	}
+
+	a.logger.Info().
+		Str("external_id", cluster.RancherClusterID).
+		Str("cluster_name", cluster.Name).
+		Msg("Updated cluster in EKS")
+	return nil
+}
+
+// DeleteCluster deletes the node groups of a cluster, then its control
+// plane, which EKS only allows once the node groups are gone. The control
+// plane is deleted in the background.
+func (a *Adapter) DeleteCluster(ctx context.Context, externalID string) error {
+	region, name, err := parseExternalID(externalID)
+	if err != nil {
+		return err
+	}
+	names, err := a.nodeGroupNames(ctx, region, name)
+	if err != nil {
+		return err
+	}
+	for _, group := range names {
+		path := fmt.Sprintf("/clusters/%s/node-groups/%s", name, group)
+		if err := a.doRequest(ctx, region, http.MethodDelete, path, nil, nil); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	go a.deleteControlPlane(region, name)
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Msg("Deleting cluster from EKS")
+	return nil
+}
+
+// deleteControlPlane waits for a cluster's node groups to be deleted, then
+// deletes the cluster
+func (a *Adapter) deleteControlPlane(region, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), deleteTimeout)
+	defer cancel()
+
+	for {
+		names, err := a.nodeGroupNames(ctx, region, name)
+		if err == nil && len(names) == 0 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			a.logger.Error().Str("cluster", name).Str("region", region).Msg("Timed out waiting for EKS node groups to be deleted")
+			return
+		case <-time.After(deletePollInterval):
+		}
+	}
+
+	if err := a.doRequest(ctx, region, http.MethodDelete, "/clusters/"+name, nil, nil); err != nil && !errors.IsNotFound(err) {
+		a.logger.Error().Err(err).Str("cluster", name).Str("region", region).Msg("Failed to delete EKS cluster")
+	}
+}
+
+// GetKubeConfig returns a kubeconfig whose token EKS accepts for 15 minutes
+func (a *Adapter) GetKubeConfig(ctx context.Context, externalID string) ([]byte, error) {
+	_, name, err := parseExternalID(externalID)
+	if err != nil {
+		return nil, err
+	}
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return kubeapi.KubeConfig(name, e)
+}
+
+// ListClusters lists the managed clusters of the configured regions
+func (a *Adapter) ListClusters(ctx context.Context) ([]*domain.Cluster, error) {
+	var clusters []*domain.Cluster
+	for _, region := range a.config.Regions {
+		var list struct {
+			Clusters []string `json:"clusters"`
+		}
+		if err := a.doRequest(ctx, region, http.MethodGet, "/clusters", nil, &list); err != nil {
+			return nil, err
+		}
+		for _, name := range list.Clusters {
+			ec, err := a.describeCluster(ctx, region, name)
+			if err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+			clusters = append(clusters, toDomain(region, ec))
+		}
+	}
+	return clusters, nil
+}
+
+// GetClusterHealth reads the health of a cluster from its API server
+func (a *Adapter) GetClusterHealth(ctx context.Context, externalID string) (*domain.ClusterHealth, error) {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return kubeapi.Health(ctx, e)
+}
+
+// GetClusterCapacity reads the capacity of a cluster from its API server
+func (a *Adapter) GetClusterCapacity(ctx context.Context, externalID string) (*domain.ClusterCapacity, error) {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return kubeapi.Capacity(ctx, e)
+}
+
+// CreateNamespace creates a namespace on a cluster
+func (a *Adapter) CreateNamespace(ctx context.Context, externalID, namespace string, labels map[string]string) error {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	return kubeapi.CreateNamespace(ctx, e, namespace, labels)
+}
+
+// DeleteNamespace deletes a namespace from a cluster
+func (a *Adapter) DeleteNamespace(ctx context.Context, externalID, namespace string) error {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	return kubeapi.DeleteNamespace(ctx, e, namespace)
+}
+
+// IssueKubeConfig is not offered for EKS clusters, whose API servers only
+// trust IAM identities
+func (a *Adapter) IssueKubeConfig(ctx context.Context, externalID string, grant *domain.KubeConfigGrant) (*domain.IssuedKubeConfig, error) {
+	return nil, errors.NotImplemented("scoped kubeconfigs are not available for EKS clusters")
+}
+
+// RevokeKubeConfig has nothing to revoke: EKS tokens expire on their own
+func (a *Adapter) RevokeKubeConfig(ctx context.Context, externalID string, kubeconfig []byte) error {
+	return nil
+}
+
+// describeCluster reads a cluster the adapter manages
+func (a *Adapter) describeCluster(ctx context.Context, region, name string) (*eksCluster, error) {
+	var result struct {
+		Cluster eksCluster `json:"cluster"`
+	}
+	if err := a.doRequest(ctx, region, http.MethodGet, "/clusters/"+name, nil, &result); err != nil {
+		return nil, err
+	}
+	if result.Cluster.Tags[tagManaged] != "true" {
+		return nil, errors.NotFound("cluster", ExternalIDPrefix+region+":"+name)
+	}
+	return &result.Cluster, nil
+}
+
+// kubeEndpoint returns the API server of a cluster with a fresh token
+func (a *Adapter) kubeEndpoint(ctx context.Context, externalID string) (*kubeapi.Endpoint, error) {
+	region, name, err := parseExternalID(externalID)
+	if err != nil {
+		return nil, err
+	}
+	ec, err := a.describeCluster(ctx, region, name)
+	if err != nil {
+		return nil, err
+	}
+	if ec.Endpoint == "" {
+		return nil, errors.NewError(errors.CodeConflict, "cluster "+name+" has no API server yet", http.StatusConflict)
+	}
+	ca, err := base64.StdEncoding.DecodeString(ec.CertificateAuthority.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cluster CA certificate")
+	}
+	creds, err := a.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &kubeapi.Endpoint{Server: ec.Endpoint, CAData: ca, Token: creds.token(region, name, a.now())}, nil
+}
+
+// credentials reads the AWS access keys from the secrets store
+func (a *Adapter) credentials(ctx context.Context) (credentials, error) {
+	secret, err := a.secrets.GetSecret(ctx, a.config.CredentialsPath)
+	if err != nil {
+		return credentials{}, err
+	}
+	creds := credentials{
+		AccessKeyID:     string(secret["access_key_id"]),
+		SecretAccessKey: string(secret["secret_access_key"]),
+		SessionToken:    string(secret["session_token"]),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return credentials{}, errors.Internal("the AWS credentials secret needs access_key_id and secret_access_key")
+	}
+	return creds, nil
+}
+
+// doRequest sends a signed request to the EKS API of a region, encoding body
+// and decoding the response into out unless they are nil
+func (a *Adapter) doRequest(ctx context.Context, region, method, path string, body, out interface{}) error {
+	creds, err := a.credentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return errors.Wrap(err, "failed to marshal request")
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.endpoint(region)+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	creds.sign(req, region, "eks", hashHex(payload), a.now())
+
+	start := time.Now()
+	resp, err := a.httpClient.Do(req)
+	metrics.ObserveAdapterCall("eks", method, start, resp, err)
+	if err != nil {
+		return errors.DependencyFailed("eks", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return handleError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+	return nil
+}
+
+// handleError extracts error information from a response
+func handleError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	json.Unmarshal(body, &errResp)
+	msg := errResp.Message
+	if msg == "" {
+		msg = strings.TrimSpace(string(body))
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return errors.NotFound("eks resource", msg)
+	case http.StatusConflict:
+		return errors.NewError(errors.CodeConflict, msg, http.StatusConflict)
+	case http.StatusBadRequest:
+		return errors.BadRequest(msg)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errors.DependencyFailed("eks", fmt.Errorf("access denied: %s", msg))
+	default:
+		return errors.DependencyFailed("eks", fmt.Errorf("EKS API error (%d): %s", resp.StatusCode, msg))
+	}
+}
+
+// parseExternalID splits eks:<region>:<name>
+func parseExternalID(externalID string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(externalID, ExternalIDPrefix), ":", 2)
+	if !strings.HasPrefix(externalID, ExternalIDPrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.NotFound("cluster", externalID)
+	}
+	return parts[0], parts[1], nil
+}
+
+func toDomain(region string, ec *eksCluster) *domain.Cluster {
+	cluster := &domain.Cluster{
+		Name:             ec.Name,
+		Slug:             ec.Name,
+		Provider:         domain.ClusterProviderAWS,
+		Region:           region,
+		Status:           mapStatus(ec.Status),
+		KubeVersion:      ec.Version,
+		APIEndpoint:      ec.Endpoint,
+		RancherClusterID: ExternalIDPrefix + region + ":" + ec.Name,
+	}
+	if ec.CreatedAt > 0 {
+		cluster.CreatedAt = time.Unix(int64(ec.CreatedAt), 0).UTC()
+	}
+	return cluster
+}
+
+// mapStatus maps the status of an EKS cluster to a domain status
+func mapStatus(status string) domain.ClusterStatus {
+	switch status {
+	case "ACTIVE":
+		return domain.ClusterStatusActive
+	case "UPDATING":
+		return domain.ClusterStatusUpgrading
+	case "DELETING":
+		return domain.ClusterStatusDeleting
+	case "FAILED":
+		return domain.ClusterStatusUnhealthy
+	default:
+		return domain.ClusterStatusProvisioning
+	}
+}
+
+func isConflict(err error) bool {
+	appErr, ok := err.(*errors.AppError)
+	return ok && appErr.Code == errors.CodeConflict
+}
@@ -0,0 +1,247 @@
+package eks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/northstack/platform/internal/adapters/kubeapi"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ListNodePools lists the managed node groups of a cluster
+func (a *Adapter) ListNodePools(ctx context.Context, externalID string) ([]*domain.NodePool, error) {
+	region, name, err := parseExternalID(externalID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := a.nodeGroups(ctx, region, name)
+	if err != nil {
+		return nil, err
+	}
+
+	pools := make([]*domain.NodePool, len(groups))
+	for i, group := range groups {
+		pools[i] = &domain.NodePool{
+			Name:        group.Name,
+			NodeCount:   group.ScalingConfig.DesiredSize,
+			MinCount:    group.ScalingConfig.MinSize,
+			MaxCount:    group.ScalingConfig.MaxSize,
+			AutoScaling: group.ScalingConfig.MinSize != group.ScalingConfig.MaxSize,
+			Version:     group.Version,
+		}
+		if len(group.InstanceTypes) > 0 {
+			pools[i].InstanceType = group.InstanceTypes[0]
+		}
+	}
+	return pools, nil
+}
+
+// CreateNodePool adds a managed node group to a cluster
+func (a *Adapter) CreateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	region, name, err := parseExternalID(externalID)
+	if err != nil {
+		return err
+	}
+	if err := a.createNodeGroup(ctx, region, name, pool); err != nil {
+		if isConflict(err) {
+			return errors.Conflict("node pool " + pool.Name)
+		}
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", pool.Name).
+		Int("nodes", int(pool.NodeCount)).
+		Msg("Created node pool")
+	return nil
+}
+
+// UpdateNodePool resizes a node group and moves it to the pool's version.
+// When it shrinks, its newest surplus nodes are drained first. EKS cannot
+// change the instance type of a node group.
+func (a *Adapter) UpdateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	region, name, err := parseExternalID(externalID)
+	if err != nil {
+		return err
+	}
+	current, err := a.describeNodeGroup(ctx, region, name, pool.Name)
+	if err != nil {
+		return err
+	}
+	if pool.InstanceType != "" && (len(current.InstanceTypes) == 0 || current.InstanceTypes[0] != pool.InstanceType) {
+		return errors.BadRequest("EKS cannot change the instance type of a node group; create a new pool instead")
+	}
+
+	if surplus := int(current.ScalingConfig.DesiredSize - pool.NodeCount); surplus > 0 {
+		e, err := a.kubeEndpoint(ctx, externalID)
+		if err != nil {
+			return err
+		}
+		if _, err := kubeapi.DrainPool(ctx, e, kubeapi.LabelEKSNodeGroup, pool.Name, surplus); err != nil {
+			return err
+		}
+	}
+
+	path := fmt.Sprintf("/clusters/%s/node-groups/%s/update-config", name, pool.Name)
+	if err := a.doRequest(ctx, region, http.MethodPost, path, map[string]interface{}{"scalingConfig": scalingConfig(pool)}, nil); err != nil {
+		return err
+	}
+	if pool.Version != "" && pool.Version != current.Version {
+		path := fmt.Sprintf("/clusters/%s/node-groups/%s/update-version", name, pool.Name)
+		if err := a.doRequest(ctx, region, http.MethodPost, path, map[string]string{"version": pool.Version}, nil); err != nil {
+			return err
+		}
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", pool.Name).
+		Int("nodes", int(pool.NodeCount)).
+		Msg("Updated node pool")
+	return nil
+}
+
+// DeleteNodePool drains every node of a node group, then deletes it. A
+// cluster's last node group cannot be deleted.
+func (a *Adapter) DeleteNodePool(ctx context.Context, externalID, poolName string) error {
+	region, name, err := parseExternalID(externalID)
+	if err != nil {
+		return err
+	}
+	names, err := a.nodeGroupNames(ctx, region, name)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, n := range names {
+		found = found || n == poolName
+	}
+	if !found {
+		return errors.NotFound("node pool", poolName)
+	}
+	if len(names) == 1 {
+		return errors.NewError(errors.CodeConflict, "the last node pool of a cluster cannot be deleted", http.StatusConflict)
+	}
+
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if _, err := kubeapi.DrainPool(ctx, e, kubeapi.LabelEKSNodeGroup, poolName, -1); err != nil {
+		return err
+	}
+	if err := a.doRequest(ctx, region, http.MethodDelete, fmt.Sprintf("/clusters/%s/node-groups/%s", name, poolName), nil, nil); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", poolName).
+		Msg("Deleted node pool")
+	return nil
+}
+
+// ListKubernetesVersions lists the versions EKS offers that a cluster can be
+// upgraded to
+func (a *Adapter) ListKubernetesVersions(ctx context.Context, externalID string) ([]string, error) {
+	region, name, err := parseExternalID(externalID)
+	if err != nil {
+		return nil, err
+	}
+	ec, err := a.describeCluster(ctx, region, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		ClusterVersions []struct {
+			ClusterVersion string `json:"clusterVersion"`
+		} `json:"clusterVersions"`
+	}
+	if err := a.doRequest(ctx, region, http.MethodGet, "/cluster-versions", nil, &result); err != nil {
+		return nil, err
+	}
+	candidates := make([]string, len(result.ClusterVersions))
+	for i, v := range result.ClusterVersions {
+		candidates[i] = v.ClusterVersion
+	}
+	return kubeapi.UpgradeTargets(ec.Version, candidates), nil
+}
+
+// createNodeGroup creates a managed node group in the configured subnets
+func (a *Adapter) createNodeGroup(ctx context.Context, region, cluster string, pool *domain.NodePool) error {
+	instanceType := pool.InstanceType
+	if instanceType == "" {
+		instanceType = a.config.InstanceType
+	}
+	body := map[string]interface{}{
+		"nodegroupName": pool.Name,
+		"nodeRole":      a.config.NodeRoleARN,
+		"subnets":       a.config.SubnetIDs,
+		"instanceTypes": []string{instanceType},
+		"scalingConfig": scalingConfig(pool),
+		"tags":          map[string]string{tagManaged: "true"},
+	}
+	if pool.Version != "" {
+		body["version"] = pool.Version
+	}
+	return a.doRequest(ctx, region, http.MethodPost, fmt.Sprintf("/clusters/%s/node-groups", cluster), body, nil)
+}
+
+func (a *Adapter) describeNodeGroup(ctx context.Context, region, cluster, name string) (*eksNodeGroup, error) {
+	var result struct {
+		NodeGroup eksNodeGroup `json:"nodegroup"`
+	}
+	if err := a.doRequest(ctx, region, http.MethodGet, fmt.Sprintf("/clusters/%s/node-groups/%s", cluster, name), nil, &result); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, errors.NotFound("node pool", name)
+		}
+		return nil, err
+	}
+	return &result.NodeGroup, nil
+}
+
+func (a *Adapter) nodeGroupNames(ctx context.Context, region, cluster string) ([]string, error) {
+	var result struct {
+		NodeGroups []string `json:"nodegroups"`
+	}
+	if err := a.doRequest(ctx, region, http.MethodGet, fmt.Sprintf("/clusters/%s/node-groups", cluster), nil, &result); err != nil {
+		return nil, err
+	}
+	return result.NodeGroups, nil
+}
+
+func (a *Adapter) nodeGroups(ctx context.Context, region, cluster string) ([]*eksNodeGroup, error) {
+	names, err := a.nodeGroupNames(ctx, region, cluster)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*eksNodeGroup, 0, len(names))
+	for _, name := range names {
+		group, err := a.describeNodeGroup(ctx, region, cluster, name)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// scalingConfig sizes a node group; a pool without autoscaling is pinned to
+// its node count
+func scalingConfig(pool *domain.NodePool) map[string]int32 {
+	min, max := pool.MinCount, pool.MaxCount
+	if !pool.AutoScaling {
+		min, max = pool.NodeCount, pool.NodeCount
+	}
+	if max < pool.NodeCount {
+		max = pool.NodeCount
+	}
+	if max < 1 {
+		max = 1
+	}
+	return map[string]int32{"minSize": min, "maxSize": max, "desiredSize": pool.NodeCount}
+}
@@ -0,0 +1,185 @@
+package eks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// credentials are the AWS access keys the adapter signs requests with
+type credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+const (
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat  = "20060102T150405Z"
+
+	// tokenPrefix marks the bearer tokens the EKS authenticator accepts
+	tokenPrefix = "k8s-aws-v1."
+	// clusterIDHeader binds a token to the cluster it was issued for
+	clusterIDHeader = "x-k8s-aws-id"
+)
+
+// sign adds a Signature Version 4 Authorization header to a request whose
+// payload hashes to payloadHash. Only the host, date and session token
+// headers are signed.
+func (c credentials) sign(req *http.Request, region, service, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host, "x-amz-date": amzDate}
+	if c.SessionToken != "" {
+		headers["x-amz-security-token"] = c.SessionToken
+	}
+	canonicalHeaders, signedHeaders := canonicalizeHeaders(headers)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath(req.URL),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope, signature := c.signature(canonicalRequest, region, service, now)
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// token returns a bearer token for the API server of an EKS cluster: a
+// presigned STS GetCallerIdentity URL the cluster's authenticator calls to
+// learn who the bearer is. EKS accepts it for 15 minutes.
+func (c credentials) token(region, cluster string, now time.Time) string {
+	host := fmt.Sprintf("sts.%s.amazonaws.com", region)
+	amzDate := now.UTC().Format(amzDateFormat)
+	scope := fmt.Sprintf("%s/%s/sts/aws4_request", amzDate[:8], region)
+
+	query := url.Values{
+		"Action":              {"GetCallerIdentity"},
+		"Version":             {"2011-06-15"},
+		"X-Amz-Algorithm":     {sigV4Algorithm},
+		"X-Amz-Credential":    {c.AccessKeyID + "/" + scope},
+		"X-Amz-Date":          {amzDate},
+		"X-Amz-Expires":       {"60"},
+		"X-Amz-SignedHeaders": {"host;" + clusterIDHeader},
+	}
+	if c.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+	canonicalHeaders, signedHeaders := canonicalizeHeaders(map[string]string{"host": host, clusterIDHeader: cluster})
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		"/",
+		canonicalQuery(query),
+		canonicalHeaders,
+		signedHeaders,
+		hashHex(nil),
+	}, "\n")
+	_, signature := c.signature(canonicalRequest, region, "sts", now)
+	query.Set("X-Amz-Signature", signature)
+
+	presigned := fmt.Sprintf("https://%s/?%s", host, canonicalQuery(query))
+	return tokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(presigned))
+}
+
+// signature returns the credential scope and the signature of a canonical
+// request
+func (c credentials) signature(canonicalRequest, region, service string, now time.Time) (string, string) {
+	amzDate := now.UTC().Format(amzDateFormat)
+	date := amzDate[:8]
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, region, service)
+
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	return scope, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalizeHeaders returns the canonical header block and the signed
+// header list of lower-cased headers
+func canonicalizeHeaders(headers map[string]string) (string, string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var block strings.Builder
+	for _, name := range names {
+		block.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	return block.String(), strings.Join(names, ";")
+}
+
+// canonicalPath URI-encodes each segment of a path
+func canonicalPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			unescaped = segment
+		}
+		segments[i] = uriEncode(unescaped)
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery sorts and URI-encodes query parameters
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode percent-encodes every byte but the unreserved characters, as
+// Signature Version 4 requires
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,55 @@
+package eks
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCredentials = credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+// TestSign checks the get-vanilla case of the AWS Signature Version 4 test suite
+func TestSign(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	require.NoError(t, err)
+
+	testCredentials.sign(req, "us-east-1", "service", hashHex(nil), time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestToken(t *testing.T) {
+	token := testCredentials.token("eu-west-1", "prod", time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC))
+	require.True(t, strings.HasPrefix(token, tokenPrefix))
+
+	presigned, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, tokenPrefix))
+	require.NoError(t, err)
+	u, err := url.Parse(string(presigned))
+	require.NoError(t, err)
+
+	assert.Equal(t, "sts.eu-west-1.amazonaws.com", u.Host)
+	query := u.Query()
+	assert.Equal(t, "GetCallerIdentity", query.Get("Action"))
+	assert.Equal(t, "host;x-k8s-aws-id", query.Get("X-Amz-SignedHeaders"))
+	assert.Equal(t, "AKIDEXAMPLE/20240501/eu-west-1/sts/aws4_request", query.Get("X-Amz-Credential"))
+	assert.Len(t, query.Get("X-Amz-Signature"), 64)
+}
+
+func TestParseExternalID(t *testing.T) {
+	region, name, err := parseExternalID("eks:eu-west-1:prod")
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", region)
+	assert.Equal(t, "prod", name)
+
+	_, _, err = parseExternalID("c-x8k2p")
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+package email
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// digestDeploys is how many of each service's latest deployments a digest reads
+	digestDeploys = 200
+	// digestAlerts is how many of a project's latest alerts a digest reads
+	digestAlerts = 500
+	// digestFiring is how many alerts still firing a digest lists
+	digestFiring = 10
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Digest summarises a project's deploys and alerts over a week
+type Digest struct {
+	From           time.Time
+	To             time.Time
+	Deploys        int
+	FailedDeploys  int
+	Rollbacks      int
+	AlertsFired    int
+	CriticalAlerts int
+	Services       []ServiceDigest // Services deployed in the week
+	Firing         []*domain.Alert // Alerts still firing, however long ago they started
+}
+
+// ServiceDigest summarises a service's deploys over a week
+type ServiceDigest struct {
+	Name          string
+	Deploys       int
+	Failed        int
+	LatestVersion string // Latest version deployed successfully, in the week or before
+}
+
+// Empty reports whether the project was quiet all week
+func (d *Digest) Empty() bool {
+	return d.Deploys == 0 && d.AlertsFired == 0 && len(d.Firing) == 0
+}
+
+// Digester emails every project's weekly digest
+type Digester struct {
+	config      *config.EmailDigestConfig
+	notifier    *Notifier
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	deployRepo  domain.DeploymentRepository
+	alertRepo   domain.AlertRepository
+
+	mu       sync.Mutex
+	lastSent time.Time
+
+	logger *logger.Logger
+}
+
+// NewDigester creates a new Digester. alertRepo may be nil, in which case
+// digests only cover deploys.
+func NewDigester(
+	cfg *config.EmailDigestConfig,
+	notifier *Notifier,
+	projectRepo domain.ProjectRepository,
+	serviceRepo domain.ServiceRepository,
+	deployRepo domain.DeploymentRepository,
+	alertRepo domain.AlertRepository,
+	log *logger.Logger,
+) *Digester {
+	return &Digester{
+		config:      cfg,
+		notifier:    notifier,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		deployRepo:  deployRepo,
+		alertRepo:   alertRepo,
+		logger:      log,
+	}
+}
+
+// Run sends the digests once a week, in the configured hour, until ctx is done
+func (d *Digester) Run(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if d.due(now) {
+				d.SendAll(ctx, now)
+			}
+		}
+	}
+}
+
+// due reports whether the digests should go out at now: in the configured
+// hour, and not already sent in it
+func (d *Digester) due(now time.Time) bool {
+	now = now.UTC()
+	if now.Weekday() != weekdays[d.config.Day] || now.Hour() != d.config.Hour {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return now.Sub(d.lastSent) > 24*time.Hour
+}
+
+// SendAll emails the digest of the week up to now for every project that
+// was not quiet
+func (d *Digester) SendAll(ctx context.Context, now time.Time) {
+	d.mu.Lock()
+	d.lastSent = now.UTC()
+	d.mu.Unlock()
+
+	projects, err := d.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		d.logger.Warn().Err(err).Msg("Failed to list projects for digests")
+		return
+	}
+
+	from := now.Add(-7 * 24 * time.Hour)
+	sent := 0
+	for _, project := range projects {
+		digest, err := d.Collect(ctx, project, from, now)
+		if err != nil {
+			d.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to collect project digest")
+			continue
+		}
+		if digest.Empty() {
+			continue
+		}
+		if err := d.notifier.SendDigest(ctx, project, digest); err != nil {
+			d.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to send project digest")
+			continue
+		}
+		sent++
+	}
+
+	d.logger.Info().Int("projects", len(projects)).Int("sent", sent).Msg("Weekly digests sent")
+}
+
+// Collect summarises a project's deploys and alerts between from and to
+func (d *Digester) Collect(ctx context.Context, project *domain.Project, from, to time.Time) (*Digest, error) {
+	digest := &Digest{From: from, To: to}
+
+	services, err := d.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range services {
+		deployments, err := d.deployRepo.ListByService(ctx, svc.ID, digestDeploys)
+		if err != nil {
+			return nil, err
+		}
+		summary := ServiceDigest{Name: svc.Name}
+		for _, deployment := range deployments {
+			if summary.LatestVersion == "" && deployment.Status == domain.DeploymentStatusSucceeded && !deployment.CreatedAt.After(to) {
+				summary.LatestVersion = deployment.Version
+			}
+			if deployment.CreatedAt.Before(from) || deployment.CreatedAt.After(to) {
+				continue
+			}
+			summary.Deploys++
+			switch deployment.Status {
+			case domain.DeploymentStatusFailed:
+				summary.Failed++
+			case domain.DeploymentStatusRolledBack:
+				digest.Rollbacks++
+			}
+		}
+		if summary.Deploys > 0 {
+			digest.Deploys += summary.Deploys
+			digest.FailedDeploys += summary.Failed
+			digest.Services = append(digest.Services, summary)
+		}
+	}
+
+	if d.alertRepo == nil {
+		return digest, nil
+	}
+	alerts, err := d.alertRepo.List(ctx, domain.AlertFilter{ProjectID: &project.ID, Limit: digestAlerts})
+	if err != nil {
+		return nil, err
+	}
+	for _, alert := range alerts {
+		started := time.Unix(alert.StartsAt, 0)
+		if started.Before(from) || started.After(to) {
+			continue
+		}
+		digest.AlertsFired++
+		if alert.Severity == "critical" {
+			digest.CriticalAlerts++
+		}
+	}
+	firing, err := d.alertRepo.List(ctx, domain.AlertFilter{ProjectID: &project.ID, Status: domain.AlertStatusFiring, Limit: digestFiring})
+	if err != nil {
+		return nil, err
+	}
+	digest.Firing = firing
+
+	return digest, nil
+}
@@ -0,0 +1,101 @@
+package email
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDeployments struct {
+	domain.DeploymentRepository
+	deployments map[uuid.UUID][]*domain.Deployment
+}
+
+func (f fakeDeployments) ListByService(_ context.Context, serviceID uuid.UUID, _ int) ([]*domain.Deployment, error) {
+	return f.deployments[serviceID], nil
+}
+
+type fakeAlerts struct {
+	domain.AlertRepository
+	alerts []*domain.Alert
+}
+
+func (f fakeAlerts) List(_ context.Context, filter domain.AlertFilter) ([]*domain.Alert, error) {
+	var alerts []*domain.Alert
+	for _, a := range f.alerts {
+		if filter.Status == "" || a.Status == filter.Status {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+func TestCollect(t *testing.T) {
+	now := time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC)
+	from := now.Add(-7 * 24 * time.Hour)
+	project := &domain.Project{ID: uuid.New(), Name: "Shop"}
+	api := &domain.Service{ID: uuid.New(), ProjectID: project.ID, Name: "api"}
+	worker := &domain.Service{ID: uuid.New(), ProjectID: project.ID, Name: "worker"}
+
+	deployments := fakeDeployments{deployments: map[uuid.UUID][]*domain.Deployment{
+		// Newest first, as repositories list them
+		api.ID: {
+			{Status: domain.DeploymentStatusFailed, Version: "v9", CreatedAt: now.Add(-time.Hour)},
+			{Status: domain.DeploymentStatusSucceeded, Version: "v8", CreatedAt: now.Add(-48 * time.Hour)},
+			{Status: domain.DeploymentStatusRolledBack, Version: "v7", CreatedAt: now.Add(-72 * time.Hour)},
+			{Status: domain.DeploymentStatusSucceeded, Version: "v6", CreatedAt: from.Add(-time.Hour)},
+		},
+		// Not deployed this week
+		worker.ID: {
+			{Status: domain.DeploymentStatusSucceeded, Version: "v2", CreatedAt: from.Add(-24 * time.Hour)},
+		},
+	}}
+	alerts := fakeAlerts{alerts: []*domain.Alert{
+		{Name: "HighErrorRate", Severity: "critical", Status: domain.AlertStatusFiring, StartsAt: now.Add(-2 * time.Hour).Unix()},
+		{Name: "HighLatency", Severity: "warning", Status: domain.AlertStatusResolved, StartsAt: now.Add(-50 * time.Hour).Unix()},
+		{Name: "DiskFilling", Severity: "warning", Status: domain.AlertStatusFiring, StartsAt: from.Add(-time.Hour).Unix()},
+	}}
+
+	n, sent := testNotifier([]*domain.Project{project}, []*domain.Service{api, worker}, nil)
+	d := NewDigester(&config.EmailDigestConfig{Day: "mon", Hour: 9}, n, fakeProjects{projects: []*domain.Project{project}}, fakeServices{services: []*domain.Service{api, worker}}, deployments, alerts, logger.New("error", "json", io.Discard))
+
+	digest, err := d.Collect(context.Background(), project, from, now)
+	require.NoError(t, err)
+	assert.Equal(t, 3, digest.Deploys)
+	assert.Equal(t, 1, digest.FailedDeploys)
+	assert.Equal(t, 1, digest.Rollbacks)
+	assert.Equal(t, []ServiceDigest{{Name: "api", Deploys: 3, Failed: 1, LatestVersion: "v8"}}, digest.Services)
+	assert.Equal(t, 2, digest.AlertsFired)
+	assert.Equal(t, 1, digest.CriticalAlerts)
+	// Alerts still firing are listed however long ago they started
+	assert.Len(t, digest.Firing, 2)
+
+	d.SendAll(context.Background(), now)
+	require.Len(t, *sent, 1)
+	assert.Contains(t, (*sent)[0].Data, "Subject: Weekly digest of Shop\r\n")
+	body := (*sent)[0].body(t)
+	assert.Contains(t, body, "from Mar 2 to Mar 9, 2026")
+	assert.Contains(t, body, "DiskFilling")
+}
+
+func TestDue(t *testing.T) {
+	d := &Digester{config: &config.EmailDigestConfig{Day: "mon", Hour: 9}}
+	monday := time.Date(2026, 3, 9, 9, 15, 0, 0, time.UTC)
+
+	assert.True(t, d.due(monday))
+	assert.False(t, d.due(monday.Add(time.Hour)))
+	assert.False(t, d.due(monday.Add(24*time.Hour)))
+
+	// Once sent, not again in the same hour
+	d.lastSent = monday
+	assert.False(t, d.due(monday.Add(30*time.Minute)))
+	assert.True(t, d.due(monday.Add(7*24*time.Hour)))
+}
@@ -0,0 +1,221 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/quotedprintable"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// pages are the email templates by name, each parsed into its own copy of
+// the layout since they all define "content"
+var pages = parsePages("deploy_failed", "alert_fired", "digest", "notification")
+
+func parsePages(names ...string) map[string]*template.Template {
+	layout := template.Must(template.ParseFS(templateFS, "templates/layout.html"))
+	parsed := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		page := template.Must(layout.Clone())
+		parsed[name] = template.Must(page.ParseFS(templateFS, "templates/"+name+".html"))
+	}
+	return parsed
+}
+
+// message is an HTML email to one or more recipients
+type message struct {
+	To      []string
+	Subject string
+	HTML    string
+}
+
+// page holds what the layout of every email shows
+type page struct {
+	Title      string
+	ConsoleURL string
+}
+
+type deployFailedPage struct {
+	page
+	Project         string
+	Service         string
+	Version         string
+	PreviousVersion string
+	TriggeredBy     string
+	Error           string
+	Link            string
+}
+
+type label struct {
+	Name  string
+	Value string
+}
+
+type alertPage struct {
+	page
+	Project  string
+	Severity string
+	Source   string
+	Since    string
+	Message  string
+	Labels   []label
+	Link     string
+}
+
+type digestPage struct {
+	page
+	*Digest
+	Project string
+	From    string
+	To      string
+	Link    string
+}
+
+type notificationPage struct {
+	page
+	Severity string
+	Message  string
+}
+
+// render executes a page's template
+func render(name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := pages[name].Execute(&buf, data); err != nil {
+		return "", errors.Wrap(err, "failed to render "+name+" email")
+	}
+	return buf.String(), nil
+}
+
+func deployFailedMessage(project *domain.Project, serviceName string, deployment *domain.Deployment, consoleURL string) (*message, error) {
+	title := fmt.Sprintf("Deploy of %s failed", serviceName)
+	if deployment.Version != "" {
+		title = fmt.Sprintf("Deploy of %s %s failed", serviceName, deployment.Version)
+	}
+	html, err := render("deploy_failed", deployFailedPage{
+		page:            page{Title: title, ConsoleURL: consoleURL},
+		Project:         project.Name,
+		Service:         serviceName,
+		Version:         deployment.Version,
+		PreviousVersion: deployment.PreviousVersion,
+		TriggeredBy:     deployment.TriggeredBy,
+		Error:           deployment.ErrorMessage,
+		Link:            link(consoleURL, "/services/"+deployment.ServiceID.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &message{Subject: fmt.Sprintf("[%s] %s", project.Name, title), HTML: html}, nil
+}
+
+// alertMessage builds the email of a firing alert. project is nil for alerts
+// of no project.
+func alertMessage(project *domain.Project, alert *domain.Alert, consoleURL string) (*message, error) {
+	title := fmt.Sprintf("%s is firing", alert.Name)
+	data := alertPage{
+		page:     page{Title: title, ConsoleURL: consoleURL},
+		Severity: alert.Severity,
+		Source:   alert.Source,
+		Message:  alert.Message,
+		Labels:   sortedLabels(alert.Labels),
+	}
+	if alert.StartsAt > 0 {
+		data.Since = time.Unix(alert.StartsAt, 0).UTC().Format("Jan 2, 15:04 MST")
+	}
+	if alert.ServiceID != nil {
+		data.Link = link(consoleURL, "/services/"+alert.ServiceID.String())
+	}
+
+	subject := title
+	if alert.Severity != "" {
+		subject = fmt.Sprintf("[%s] %s", strings.ToUpper(alert.Severity), title)
+	}
+	if project != nil {
+		data.Project = project.Name
+		subject = fmt.Sprintf("[%s] %s", project.Name, subject)
+	}
+
+	html, err := render("alert_fired", data)
+	if err != nil {
+		return nil, err
+	}
+	return &message{Subject: subject, HTML: html}, nil
+}
+
+func digestMessage(project *domain.Project, digest *Digest, consoleURL string) (*message, error) {
+	title := fmt.Sprintf("Weekly digest of %s", project.Name)
+	html, err := render("digest", digestPage{
+		page:    page{Title: title, ConsoleURL: consoleURL},
+		Digest:  digest,
+		Project: project.Name,
+		From:    digest.From.UTC().Format("Jan 2"),
+		To:      digest.To.UTC().Format("Jan 2, 2006"),
+		Link:    link(consoleURL, "/projects/"+project.ID.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &message{Subject: title, HTML: html}, nil
+}
+
+func notificationMessage(notification *domain.Notification, consoleURL string) (*message, error) {
+	html, err := render("notification", notificationPage{
+		page:     page{Title: notification.Title, ConsoleURL: consoleURL},
+		Severity: notification.Severity,
+		Message:  notification.Message,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &message{Subject: notification.Title, HTML: html}, nil
+}
+
+// link joins the console URL and a path, or returns nothing without a console URL
+func link(consoleURL, path string) string {
+	if consoleURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(consoleURL, "/") + path
+}
+
+func sortedLabels(labels map[string]string) []label {
+	sorted := make([]label, 0, len(labels))
+	for name, value := range labels {
+		if name == "alertname" || name == "severity" {
+			continue // Already shown
+		}
+		sorted = append(sorted, label{Name: name, Value: value})
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// encode renders the message as RFC 5322 data, its HTML body quoted-printable
+func (m *message) encode(from string, date time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(m.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(m.HTML)); err != nil {
+		return nil, errors.Wrap(err, "failed to encode email")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to encode email")
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,238 @@
+// Package email implements domain.Notifier over SMTP. Failed deploys and
+// firing alerts are emailed as HTML to the people of their project, and the
+// package sends weekly project digests.
+package email
+
+import (
+	"context"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Notifier sends notifications as HTML emails
+type Notifier struct {
+	config      *config.EmailConfig
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	userRepo    domain.UserRepository
+	policy      domain.NotificationPolicy
+	send        sendFunc
+	logger      *logger.Logger
+}
+
+// NewNotifier creates a new Notifier. serviceRepo may be nil, in which case
+// emails name services by ID. Without userRepo, emails only reach the
+// configured recipients rather than project owners and deployers.
+func NewNotifier(cfg *config.EmailConfig, projectRepo domain.ProjectRepository, serviceRepo domain.ServiceRepository, userRepo domain.UserRepository, log *logger.Logger) *Notifier {
+	return &Notifier{
+		config:      cfg,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		userRepo:    userRepo,
+		send:        smtpSender(cfg),
+		logger:      log,
+	}
+}
+
+var _ domain.Notifier = (*Notifier)(nil)
+
+// UsePolicy makes the notifier email users only the events their
+// notification preferences let reach email. The configured recipients are
+// not users and get what the project's preferences let through.
+func (n *Notifier) UsePolicy(policy domain.NotificationPolicy) {
+	n.policy = policy
+}
+
+// SendNotification emails a notification to its recipient address, or the
+// configured recipients when it names none. Notifications for other
+// channels are ignored.
+func (n *Notifier) SendNotification(ctx context.Context, notification *domain.Notification) error {
+	if notification.Channel != "" && notification.Channel != "email" {
+		return nil
+	}
+	var to []string
+	if notification.Recipient != "" {
+		address, err := mail.ParseAddress(notification.Recipient)
+		if err != nil {
+			return errors.BadRequest("invalid email recipient " + notification.Recipient)
+		}
+		to = []string{address.Address}
+	} else {
+		to = n.recipients(ctx, nil, notification.Type, notification.Severity)
+	}
+	if len(to) == 0 {
+		return errors.BadRequest("notification has no email recipient")
+	}
+
+	msg, err := notificationMessage(notification, n.config.ConsoleURL)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return n.deliver(ctx, msg)
+}
+
+// SendBuildNotification does nothing; builds are not emailed
+func (n *Notifier) SendBuildNotification(ctx context.Context, build *domain.Build) error {
+	return nil
+}
+
+// SendDeploymentNotification emails a failed deploy to the project owner,
+// whoever triggered it and the configured recipients. Other outcomes are not
+// emailed.
+func (n *Notifier) SendDeploymentNotification(ctx context.Context, deployment *domain.Deployment) error {
+	if deployment.Status != domain.DeploymentStatusFailed {
+		return nil
+	}
+
+	project, err := n.projectRepo.GetByID(ctx, deployment.ProjectID)
+	if err != nil {
+		return err
+	}
+	users := []uuid.UUID{project.OwnerID}
+	if deployer, err := uuid.Parse(deployment.TriggeredBy); err == nil {
+		users = append(users, deployer)
+	}
+	to := n.recipients(ctx, &project.ID, domain.NotificationEventDeployFailed, "", users...)
+	if len(to) == 0 {
+		return nil
+	}
+
+	msg, err := deployFailedMessage(project, n.serviceName(ctx, deployment.ServiceID), deployment, n.config.ConsoleURL)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return n.deliver(ctx, msg)
+}
+
+// SendAlertNotification emails an alert that fired to its project's owner and
+// the configured recipients. Alerts of no project go to the configured
+// recipients only, and resolved alerts are not emailed.
+func (n *Notifier) SendAlertNotification(ctx context.Context, alert *domain.Alert) error {
+	if alert.Status == domain.AlertStatusResolved {
+		return nil
+	}
+
+	var project *domain.Project
+	var to []string
+	if alert.ProjectID == nil {
+		to = n.recipients(ctx, nil, domain.NotificationEventAlertFired, alert.Severity)
+	} else {
+		var err error
+		if project, err = n.projectRepo.GetByID(ctx, *alert.ProjectID); err != nil {
+			return err
+		}
+		to = n.recipients(ctx, &project.ID, domain.NotificationEventAlertFired, alert.Severity, project.OwnerID)
+	}
+	if len(to) == 0 {
+		return nil
+	}
+
+	msg, err := alertMessage(project, alert, n.config.ConsoleURL)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return n.deliver(ctx, msg)
+}
+
+// SendDigest emails a project's weekly digest to its owner and the
+// configured recipients
+func (n *Notifier) SendDigest(ctx context.Context, project *domain.Project, digest *Digest) error {
+	to := n.recipients(ctx, &project.ID, domain.NotificationEventDigest, "", project.OwnerID)
+	if len(to) == 0 {
+		return nil
+	}
+
+	msg, err := digestMessage(project, digest, n.config.ConsoleURL)
+	if err != nil {
+		return err
+	}
+	msg.To = to
+	return n.deliver(ctx, msg)
+}
+
+// recipients returns the addresses of the active users among userIDs, and
+// of the configured recipients, whom the event may reach, without duplicates
+func (n *Notifier) recipients(ctx context.Context, projectID *uuid.UUID, eventType, severity string, userIDs ...uuid.UUID) []string {
+	var to []string
+	seen := make(map[string]bool)
+	add := func(address string) {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return
+		}
+		key := strings.ToLower(parsed.Address)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		to = append(to, parsed.Address)
+	}
+
+	if n.userRepo != nil {
+		for _, id := range userIDs {
+			if id == uuid.Nil {
+				continue
+			}
+			if n.policy != nil && !n.policy.Allows(ctx, domain.NotificationChannelEmail, &id, projectID, eventType, severity) {
+				continue
+			}
+			user, err := n.userRepo.GetByID(ctx, id)
+			if err != nil {
+				if !errors.IsNotFound(err) {
+					n.logger.Warn().Err(err).Str("user_id", id.String()).Msg("Failed to load email recipient")
+				}
+				continue
+			}
+			if user.IsActive {
+				add(user.Email)
+			}
+		}
+	}
+	if n.policy != nil && !n.policy.Allows(ctx, domain.NotificationChannelEmail, nil, projectID, eventType, severity) {
+		return to
+	}
+	for _, address := range n.config.Recipients {
+		add(address)
+	}
+	return to
+}
+
+// serviceName names a service in emails, by its ID when it cannot be loaded
+func (n *Notifier) serviceName(ctx context.Context, serviceID uuid.UUID) string {
+	if n.serviceRepo == nil {
+		return serviceID.String()
+	}
+	service, err := n.serviceRepo.GetByID(ctx, serviceID)
+	if err != nil {
+		return serviceID.String()
+	}
+	return service.Name
+}
+
+// deliver encodes a message and sends it to all its recipients at once
+func (n *Notifier) deliver(ctx context.Context, msg *message) error {
+	sender := n.config.From
+	if address, err := mail.ParseAddress(n.config.From); err == nil {
+		sender = address.Address
+	}
+
+	data, err := msg.encode(n.config.From, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := n.send(ctx, sender, msg.To, data); err != nil {
+		n.logger.Warn().Err(err).Str("subject", msg.Subject).Int("recipients", len(msg.To)).Msg("Failed to send email")
+		return err
+	}
+	return nil
+}
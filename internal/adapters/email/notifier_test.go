@@ -0,0 +1,212 @@
+package email
+
+import (
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProjects struct {
+	domain.ProjectRepository
+	projects []*domain.Project
+}
+
+func (f fakeProjects) GetByID(_ context.Context, id uuid.UUID) (*domain.Project, error) {
+	for _, p := range f.projects {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return nil, errors.NotFound("project", id.String())
+}
+
+func (f fakeProjects) List(context.Context, domain.ProjectFilter) ([]*domain.Project, error) {
+	return f.projects, nil
+}
+
+type fakeServices struct {
+	domain.ServiceRepository
+	services []*domain.Service
+}
+
+func (f fakeServices) GetByID(_ context.Context, id uuid.UUID) (*domain.Service, error) {
+	for _, s := range f.services {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, errors.NotFound("service", id.String())
+}
+
+func (f fakeServices) ListByProject(_ context.Context, projectID uuid.UUID, _ domain.ServiceFilter) ([]*domain.Service, error) {
+	var services []*domain.Service
+	for _, s := range f.services {
+		if s.ProjectID == projectID {
+			services = append(services, s)
+		}
+	}
+	return services, nil
+}
+
+type fakeUsers struct {
+	domain.UserRepository
+	users []*domain.User
+}
+
+func (f fakeUsers) GetByID(_ context.Context, id uuid.UUID) (*domain.User, error) {
+	for _, u := range f.users {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+	return nil, errors.NotFound("user", id.String())
+}
+
+// sentEmail is an email captured instead of relayed
+type sentEmail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// body decodes the HTML body of the email
+func (e sentEmail) body(t *testing.T) string {
+	_, encoded, ok := strings.Cut(e.Data, "\r\n\r\n")
+	require.True(t, ok)
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(encoded)))
+	require.NoError(t, err)
+	return string(decoded)
+}
+
+func testNotifier(projects []*domain.Project, services []*domain.Service, users domain.UserRepository) (*Notifier, *[]sentEmail) {
+	cfg := &config.EmailConfig{
+		From:       "NorthStack <noreply@example.com>",
+		Recipients: []string{"Platform <platform@example.com>"},
+		ConsoleURL: "https://console.example.com",
+		Timeout:    time.Second,
+	}
+	n := NewNotifier(cfg, fakeProjects{projects: projects}, fakeServices{services: services}, users, logger.New("error", "json", io.Discard))
+
+	var sent []sentEmail
+	n.send = func(_ context.Context, from string, to []string, data []byte) error {
+		sent = append(sent, sentEmail{From: from, To: to, Data: string(data)})
+		return nil
+	}
+	return n, &sent
+}
+
+func TestSendDeploymentNotification(t *testing.T) {
+	owner := &domain.User{ID: uuid.New(), Email: "owner@example.com", IsActive: true}
+	deployer := &domain.User{ID: uuid.New(), Email: "dev@example.com", IsActive: true}
+	project := &domain.Project{ID: uuid.New(), Name: "Shop", OwnerID: owner.ID}
+	service := &domain.Service{ID: uuid.New(), ProjectID: project.ID, Name: "api"}
+	n, sent := testNotifier([]*domain.Project{project}, []*domain.Service{service}, fakeUsers{users: []*domain.User{owner, deployer}})
+
+	deployment := &domain.Deployment{
+		ServiceID:    service.ID,
+		ProjectID:    project.ID,
+		Status:       domain.DeploymentStatusFailed,
+		Version:      "v7",
+		TriggeredBy:  deployer.ID.String(),
+		ErrorMessage: "rollout timed out <readiness>",
+	}
+	require.NoError(t, n.SendDeploymentNotification(context.Background(), deployment))
+	require.Len(t, *sent, 1)
+	email := (*sent)[0]
+	assert.Equal(t, "noreply@example.com", email.From)
+	assert.Equal(t, []string{"owner@example.com", "dev@example.com", "platform@example.com"}, email.To)
+	assert.Contains(t, email.Data, "Subject: [Shop] Deploy of api v7 failed\r\n")
+	body := email.body(t)
+	assert.Contains(t, body, "rollout timed out &lt;readiness&gt;")
+	assert.Contains(t, body, "https://console.example.com/services/"+service.ID.String())
+
+	// Only failures are emailed
+	deployment.Status = domain.DeploymentStatusSucceeded
+	require.NoError(t, n.SendDeploymentNotification(context.Background(), deployment))
+	assert.Len(t, *sent, 1)
+}
+
+func TestSendAlertNotification(t *testing.T) {
+	project := &domain.Project{ID: uuid.New(), Name: "Shop", OwnerID: uuid.New()}
+	// Without a user repository only the configured recipients are emailed
+	n, sent := testNotifier([]*domain.Project{project}, nil, nil)
+
+	alert := &domain.Alert{
+		Name:      "HighErrorRate",
+		Severity:  "critical",
+		Status:    domain.AlertStatusFiring,
+		Message:   "5xx > 5%",
+		Labels:    map[string]string{"alertname": "HighErrorRate", "route": "/checkout"},
+		ProjectID: &project.ID,
+		StartsAt:  time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC).Unix(),
+	}
+	require.NoError(t, n.SendAlertNotification(context.Background(), alert))
+	require.Len(t, *sent, 1)
+	email := (*sent)[0]
+	assert.Equal(t, []string{"platform@example.com"}, email.To)
+	assert.Contains(t, email.Data, "Subject: [Shop] [CRITICAL] HighErrorRate is firing\r\n")
+	body := email.body(t)
+	assert.Contains(t, body, "/checkout")
+	assert.Contains(t, body, "Mar 2, 09:30 UTC")
+
+	alert.Status = domain.AlertStatusResolved
+	require.NoError(t, n.SendAlertNotification(context.Background(), alert))
+	assert.Len(t, *sent, 1)
+}
+
+func TestSendNotification(t *testing.T) {
+	n, sent := testNotifier(nil, nil, nil)
+
+	// Notifications for other channels are not emailed
+	require.NoError(t, n.SendNotification(context.Background(), &domain.Notification{Channel: "slack", Title: "Hi"}))
+	assert.Empty(t, *sent)
+
+	require.NoError(t, n.SendNotification(context.Background(), &domain.Notification{Channel: "email", Recipient: "ops@example.com", Title: "Héllo", Message: "Test"}))
+	require.Len(t, *sent, 1)
+	assert.Equal(t, []string{"ops@example.com"}, (*sent)[0].To)
+	assert.Contains(t, (*sent)[0].Data, "Subject: =?utf-8?q?H=C3=A9llo?=\r\n")
+}
+
+// denyUser lets nothing reach one user, and every event reach everyone else
+type denyUser struct {
+	userID  uuid.UUID
+	project bool // Whether the project's own preferences let nothing through either
+}
+
+func (d denyUser) Allows(_ context.Context, channel string, userID, _ *uuid.UUID, _, _ string) bool {
+	if channel != domain.NotificationChannelEmail {
+		return true
+	}
+	if userID == nil {
+		return !d.project
+	}
+	return *userID != d.userID
+}
+
+func TestRecipientsPolicy(t *testing.T) {
+	owner := &domain.User{ID: uuid.New(), Email: "owner@example.com", IsActive: true}
+	deployer := &domain.User{ID: uuid.New(), Email: "dev@example.com", IsActive: true}
+	project := &domain.Project{ID: uuid.New(), Name: "Shop", OwnerID: owner.ID}
+	n, _ := testNotifier([]*domain.Project{project}, nil, fakeUsers{users: []*domain.User{owner, deployer}})
+	ctx := context.Background()
+
+	n.UsePolicy(denyUser{userID: owner.ID})
+	to := n.recipients(ctx, &project.ID, domain.NotificationEventDeployFailed, "", owner.ID, deployer.ID)
+	assert.Equal(t, []string{"dev@example.com", "platform@example.com"}, to)
+
+	// The configured recipients follow the project's preferences
+	n.UsePolicy(denyUser{userID: owner.ID, project: true})
+	to = n.recipients(ctx, &project.ID, domain.NotificationEventDeployFailed, "", owner.ID, deployer.ID)
+	assert.Equal(t, []string{"dev@example.com"}, to)
+}
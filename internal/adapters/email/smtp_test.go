@@ -0,0 +1,107 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpServer accepts one session without TLS or authentication, sending the
+// commands and message data it received on the returned channel
+func smtpServer(t *testing.T, rejectRcpt string) (string, int, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		var lines []string
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				received <- lines
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case strings.HasPrefix(line, "RCPT") && rejectRcpt != "" && strings.Contains(line, rejectRcpt):
+				reply("550 no such user")
+			case line == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil || data == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(data, "\r\n"))
+				}
+				reply("250 queued")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return host, portNumber, received
+}
+
+func TestRelay(t *testing.T) {
+	host, port, received := smtpServer(t, "")
+	cfg := &config.EmailConfig{Host: host, Port: port, TLS: "none", Timeout: 5 * time.Second}
+
+	err := relay(context.Background(), cfg, "noreply@example.com", []string{"a@example.com", "b@example.com"}, []byte("Subject: Hi\r\n\r\nHello\r\n"))
+	require.NoError(t, err)
+
+	lines := <-received
+	assert.Contains(t, lines, "MAIL FROM:<noreply@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<a@example.com>")
+	assert.Contains(t, lines, "RCPT TO:<b@example.com>")
+	assert.Contains(t, lines, "Subject: Hi")
+}
+
+func TestRelayRequiresStartTLS(t *testing.T) {
+	host, port, _ := smtpServer(t, "")
+	cfg := &config.EmailConfig{Host: host, Port: port, TLS: "starttls", Timeout: 5 * time.Second}
+
+	// The server does not offer STARTTLS, so nothing is sent in the clear
+	err := relay(context.Background(), cfg, "noreply@example.com", []string{"a@example.com"}, []byte("Subject: Hi\r\n\r\nHello\r\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not offer STARTTLS")
+}
+
+func TestRelayRejectedRecipient(t *testing.T) {
+	host, port, _ := smtpServer(t, "gone@example.com")
+	cfg := &config.EmailConfig{Host: host, Port: port, TLS: "none", Timeout: 5 * time.Second}
+
+	err := relay(context.Background(), cfg, "noreply@example.com", []string{"gone@example.com"}, []byte("Subject: Hi\r\n\r\nHello\r\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "recipient gone@example.com")
+}
@@ -0,0 +1,443 @@
+// Package gke provisions Kubernetes clusters through the Google Kubernetes
+// Engine API directly, for installations that do not run Rancher. Operations
+// inside a cluster go to its API server with the service account's token.
+package gke
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/adapters/kubeapi"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// ExternalIDPrefix starts the external ID of every cluster the adapter
+// manages: gke:<location>:<name>
+const ExternalIDPrefix = "gke:"
+
+// labelManaged marks the clusters the adapter creates; GKE label keys take
+// neither dots nor slashes
+const labelManaged = "northstack-managed"
+
+// defaultNodePool is the node pool created with a cluster
+const defaultNodePool = "default"
+
+// Secrets reads the service account key
+type Secrets interface {
+	GetSecret(ctx context.Context, path string) (map[string][]byte, error)
+}
+
+// Adapter implements the ClusterManagerAdapter interface for GKE
+type Adapter struct {
+	config     *config.GKEConfig
+	secrets    Secrets
+	httpClient *http.Client
+	logger     *logger.Logger
+	baseURL    string
+	tokens     tokenSource
+}
+
+// NewAdapter creates a new GKE adapter
+func NewAdapter(cfg *config.GKEConfig, secrets Secrets, log *logger.Logger) *Adapter {
+	httpClient := &http.Client{Timeout: cfg.Timeout, Transport: tracing.Transport(nil)}
+	return &Adapter{
+		config:     cfg,
+		secrets:    secrets,
+		httpClient: httpClient,
+		logger:     log,
+		baseURL:    "https://container.googleapis.com/v1",
+		tokens:     tokenSource{httpClient: httpClient},
+	}
+}
+
+// gkeCluster is a cluster as the GKE API describes it
+type gkeCluster struct {
+	Name                 string            `json:"name"`
+	Location             string            `json:"location"`
+	CurrentMasterVersion string            `json:"currentMasterVersion"`
+	Endpoint             string            `json:"endpoint"`
+	Status               string            `json:"status"`
+	ResourceLabels       map[string]string `json:"resourceLabels"`
+	CreateTime           time.Time         `json:"createTime"`
+	CurrentNodeCount     int32             `json:"currentNodeCount"`
+	NodePools            []gkeNodePool     `json:"nodePools"`
+	MasterAuth           struct {
+		ClusterCACertificate string `json:"clusterCaCertificate"`
+	} `json:"masterAuth"`
+}
+
+// gkeNodePool is a node pool as the GKE API describes it
+type gkeNodePool struct {
+	Name             string `json:"name"`
+	Version          string `json:"version,omitempty"`
+	Status           string `json:"status,omitempty"`
+	InitialNodeCount int32  `json:"initialNodeCount,omitempty"`
+	Config           struct {
+		MachineType string `json:"machineType,omitempty"`
+	} `json:"config"`
+	Autoscaling *gkeAutoscaling `json:"autoscaling,omitempty"`
+}
+
+type gkeAutoscaling struct {
+	Enabled      bool  `json:"enabled"`
+	MinNodeCount int32 `json:"minNodeCount,omitempty"`
+	MaxNodeCount int32 `json:"maxNodeCount,omitempty"`
+}
+
+// CreateCluster creates a cluster with its default node pool in the
+// cluster's region or zone
+func (a *Adapter) CreateCluster(ctx context.Context, cluster *domain.Cluster) (string, error) {
+	if cluster.Region == "" {
+		return "", errors.BadRequest("a GCP region or zone is required")
+	}
+	project, err := a.project(ctx)
+	if err != nil {
+		return "", err
+	}
+	nodeCount := cluster.NodeCount
+	if nodeCount < 1 {
+		nodeCount = 1
+	}
+
+	pool := toNodePool(&domain.NodePool{Name: defaultNodePool, InstanceType: a.config.MachineType, NodeCount: nodeCount, MinCount: 1, MaxCount: nodeCount * 2, AutoScaling: true})
+	gc := map[string]interface{}{
+		"name":           cluster.Slug,
+		"resourceLabels": map[string]string{labelManaged: "true"},
+		"nodePools":      []gkeNodePool{pool},
+	}
+	if cluster.KubeVersion != "" {
+		gc["initialClusterVersion"] = cluster.KubeVersion
+	}
+	if a.config.Network != "" {
+		gc["network"] = a.config.Network
+	}
+	path := fmt.Sprintf("/projects/%s/locations/%s/clusters", project, cluster.Region)
+	if err := a.doRequest(ctx, http.MethodPost, path, map[string]interface{}{"cluster": gc}, nil); err != nil {
+		return "", err
+	}
+
+	id := ExternalIDPrefix + cluster.Region + ":" + cluster.Slug
+	a.logger.Info().
+		Str("external_id", id).
+		Str("cluster_name", cluster.Name).
+		Msg("Created cluster in GKE")
+	return id, nil
+}
+
+// GetCluster retrieves a cluster
+func (a *Adapter) GetCluster(ctx context.Context, externalID string) (*domain.Cluster, error) {
+	gc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return toDomain(gc), nil
+}
+
+// UpdateCluster upgrades the control plane to the cluster's Kubernetes
+// version and resizes the default node pool to its node count, when set.
+// Node pools follow the control plane through GKE's node auto-upgrade.
+func (a *Adapter) UpdateCluster(ctx context.Context, cluster *domain.Cluster) error {
+	gc, err := a.getCluster(ctx, cluster.RancherClusterID)
+	if err != nil {
+		return err
+	}
+	path, err := a.clusterPath(ctx, cluster.RancherClusterID)
+	if err != nil {
+		return err
+	}
+
+	if cluster.KubeVersion != "" && cluster.KubeVersion != gc.CurrentMasterVersion {
+		update := map[string]interface{}{"update": map[string]string{"desiredMasterVersion": cluster.KubeVersion}}
+		if err := a.doRequest(ctx, http.MethodPut, path, update, nil); err != nil {
+			return err
+		}
+	}
+	if cluster.NodeCount > 0 {
+		pool := &domain.NodePool{Name: defaultNodePool, NodeCount: cluster.NodeCount, MinCount: 1, MaxCount: cluster.NodeCount * 2, AutoScaling: true}
+		if err := a.UpdateNodePool(ctx, cluster.RancherClusterID, pool); err != nil {
+			return err
+		}
+	}
+
+	a.logger.Info().
+		Str("external_id", cluster.RancherClusterID).
+		Str("cluster_name", cluster.Name).
+		Msg("Updated cluster in GKE")
+	return nil
+}
+
+// DeleteCluster deletes a cluster and its node pools
+func (a *Adapter) DeleteCluster(ctx context.Context, externalID string) error {
+	if _, err := a.getCluster(ctx, externalID); err != nil {
+		return err
+	}
+	path, err := a.clusterPath(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if err := a.doRequest(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Msg("Deleted cluster from GKE")
+	return nil
+}
+
+// GetKubeConfig returns a kubeconfig carrying the service account's access
+// token, which expires within the hour
+func (a *Adapter) GetKubeConfig(ctx context.Context, externalID string) ([]byte, error) {
+	_, name, err := parseExternalID(externalID)
+	if err != nil {
+		return nil, err
+	}
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return kubeapi.KubeConfig(name, e)
+}
+
+// ListClusters lists the managed clusters of every location of the project
+func (a *Adapter) ListClusters(ctx context.Context) ([]*domain.Cluster, error) {
+	project, err := a.project(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Clusters []gkeCluster `json:"clusters"`
+	}
+	if err := a.doRequest(ctx, http.MethodGet, fmt.Sprintf("/projects/%s/locations/-/clusters", project), nil, &list); err != nil {
+		return nil, err
+	}
+
+	clusters := make([]*domain.Cluster, 0, len(list.Clusters))
+	for i := range list.Clusters {
+		if list.Clusters[i].ResourceLabels[labelManaged] == "true" {
+			clusters = append(clusters, toDomain(&list.Clusters[i]))
+		}
+	}
+	return clusters, nil
+}
+
+// GetClusterHealth reads the health of a cluster from its API server
+func (a *Adapter) GetClusterHealth(ctx context.Context, externalID string) (*domain.ClusterHealth, error) {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return kubeapi.Health(ctx, e)
+}
+
+// GetClusterCapacity reads the capacity of a cluster from its API server
+func (a *Adapter) GetClusterCapacity(ctx context.Context, externalID string) (*domain.ClusterCapacity, error) {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return kubeapi.Capacity(ctx, e)
+}
+
+// CreateNamespace creates a namespace on a cluster
+func (a *Adapter) CreateNamespace(ctx context.Context, externalID, namespace string, labels map[string]string) error {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	return kubeapi.CreateNamespace(ctx, e, namespace, labels)
+}
+
+// DeleteNamespace deletes a namespace from a cluster
+func (a *Adapter) DeleteNamespace(ctx context.Context, externalID, namespace string) error {
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	return kubeapi.DeleteNamespace(ctx, e, namespace)
+}
+
+// IssueKubeConfig is not offered for GKE clusters, whose API servers only
+// trust Google identities
+func (a *Adapter) IssueKubeConfig(ctx context.Context, externalID string, grant *domain.KubeConfigGrant) (*domain.IssuedKubeConfig, error) {
+	return nil, errors.NotImplemented("scoped kubeconfigs are not available for GKE clusters")
+}
+
+// RevokeKubeConfig has nothing to revoke: access tokens expire on their own
+func (a *Adapter) RevokeKubeConfig(ctx context.Context, externalID string, kubeconfig []byte) error {
+	return nil
+}
+
+// getCluster reads a cluster the adapter manages
+func (a *Adapter) getCluster(ctx context.Context, externalID string) (*gkeCluster, error) {
+	path, err := a.clusterPath(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	var gc gkeCluster
+	if err := a.doRequest(ctx, http.MethodGet, path, nil, &gc); err != nil {
+		return nil, err
+	}
+	if gc.ResourceLabels[labelManaged] != "true" {
+		return nil, errors.NotFound("cluster", externalID)
+	}
+	return &gc, nil
+}
+
+// clusterPath returns the API path of a cluster
+func (a *Adapter) clusterPath(ctx context.Context, externalID string) (string, error) {
+	location, name, err := parseExternalID(externalID)
+	if err != nil {
+		return "", err
+	}
+	project, err := a.project(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/projects/%s/locations/%s/clusters/%s", project, location, name), nil
+}
+
+// kubeEndpoint returns the API server of a cluster with a fresh token
+func (a *Adapter) kubeEndpoint(ctx context.Context, externalID string) (*kubeapi.Endpoint, error) {
+	gc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	if gc.Endpoint == "" {
+		return nil, errors.NewError(errors.CodeConflict, "cluster "+gc.Name+" has no API server yet", http.StatusConflict)
+	}
+	ca, err := base64.StdEncoding.DecodeString(gc.MasterAuth.ClusterCACertificate)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cluster CA certificate")
+	}
+	token, err := a.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &kubeapi.Endpoint{Server: "https://" + gc.Endpoint, CAData: ca, Token: token}, nil
+}
+
+// doRequest sends an authorized request to the GKE API, encoding body and
+// decoding the response into out unless they are nil
+func (a *Adapter) doRequest(ctx context.Context, method, path string, body, out interface{}) error {
+	token, err := a.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal request")
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	resp, err := a.httpClient.Do(req)
+	metrics.ObserveAdapterCall("gke", method, start, resp, err)
+	if err != nil {
+		return errors.DependencyFailed("gke", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return handleError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+	return nil
+}
+
+// handleError extracts error information from a response
+func handleError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var errResp struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal(body, &errResp)
+	msg := errResp.Error.Message
+	if msg == "" {
+		msg = strings.TrimSpace(string(body))
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return errors.NotFound("gke resource", msg)
+	case http.StatusConflict:
+		return errors.NewError(errors.CodeConflict, msg, http.StatusConflict)
+	case http.StatusBadRequest:
+		return errors.BadRequest(msg)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errors.DependencyFailed("gke", fmt.Errorf("access denied: %s", msg))
+	default:
+		return errors.DependencyFailed("gke", fmt.Errorf("GKE API error (%d): %s", resp.StatusCode, msg))
+	}
+}
+
+// parseExternalID splits gke:<location>:<name>
+func parseExternalID(externalID string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(externalID, ExternalIDPrefix), ":", 2)
+	if !strings.HasPrefix(externalID, ExternalIDPrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.NotFound("cluster", externalID)
+	}
+	return parts[0], parts[1], nil
+}
+
+func toDomain(gc *gkeCluster) *domain.Cluster {
+	return &domain.Cluster{
+		Name:             gc.Name,
+		Slug:             gc.Name,
+		Provider:         domain.ClusterProviderGCP,
+		Region:           gc.Location,
+		Status:           mapStatus(gc.Status),
+		KubeVersion:      gc.CurrentMasterVersion,
+		APIEndpoint:      gc.Endpoint,
+		NodeCount:        gc.CurrentNodeCount,
+		RancherClusterID: ExternalIDPrefix + gc.Location + ":" + gc.Name,
+		CreatedAt:        gc.CreateTime,
+	}
+}
+
+// mapStatus maps the status of a GKE cluster to a domain status
+func mapStatus(status string) domain.ClusterStatus {
+	switch status {
+	case "RUNNING":
+		return domain.ClusterStatusActive
+	case "RECONCILING":
+		return domain.ClusterStatusUpgrading
+	case "STOPPING":
+		return domain.ClusterStatusDeleting
+	case "ERROR", "DEGRADED":
+		return domain.ClusterStatusUnhealthy
+	default:
+		return domain.ClusterStatusProvisioning
+	}
+}
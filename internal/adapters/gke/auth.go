@@ -0,0 +1,135 @@
+package gke
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// cloudPlatformScope is the OAuth scope of the access tokens the adapter uses
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// serviceAccountKey is the part of a service account's JSON key the adapter reads
+type serviceAccountKey struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// tokenSource caches the access token of a service account until shortly
+// before it expires
+type tokenSource struct {
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// project returns the project of the service account, which holds the clusters
+func (a *Adapter) project(ctx context.Context) (string, error) {
+	key, err := a.key(ctx)
+	if err != nil {
+		return "", err
+	}
+	return key.ProjectID, nil
+}
+
+// token returns an access token of the service account
+func (a *Adapter) token(ctx context.Context) (string, error) {
+	a.tokens.mu.Lock()
+	defer a.tokens.mu.Unlock()
+	if a.tokens.token != "" && time.Now().Before(a.tokens.expires) {
+		return a.tokens.token, nil
+	}
+
+	key, err := a.key(ctx)
+	if err != nil {
+		return "", err
+	}
+	token, lifetime, err := a.tokens.exchange(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	a.tokens.token = token
+	a.tokens.expires = time.Now().Add(lifetime - time.Minute)
+	return token, nil
+}
+
+// key reads the service account key from the secrets store
+func (a *Adapter) key(ctx context.Context) (*serviceAccountKey, error) {
+	secret, err := a.secrets.GetSecret(ctx, a.config.CredentialsPath)
+	if err != nil {
+		return nil, err
+	}
+	var key serviceAccountKey
+	if err := json.Unmarshal(secret["credentials"], &key); err != nil {
+		return nil, errors.Wrap(err, "invalid GCP service account key")
+	}
+	if key.ProjectID == "" || key.ClientEmail == "" || key.PrivateKey == "" {
+		return nil, errors.Internal("the GCP credentials secret needs a service account key with project_id, client_email and private_key")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &key, nil
+}
+
+// exchange trades a JWT signed with the service account's key for an access
+// token and its lifetime
+func (s *tokenSource) exchange(ctx context.Context, key *serviceAccountKey) (string, time.Duration, error) {
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(key.PrivateKey))
+	if err != nil {
+		return "", 0, errors.Wrap(err, "invalid GCP service account private key")
+	}
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   key.ClientEmail,
+		"scope": cloudPlatformScope,
+		"aud":   key.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(privateKey)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "failed to sign token request")
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, key.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	start := time.Now()
+	resp, err := s.httpClient.Do(req)
+	metrics.ObserveAdapterCall("gke", http.MethodPost, start, resp, err)
+	if err != nil {
+		return "", 0, errors.DependencyFailed("gke", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, handleError(resp)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", 0, errors.Wrap(err, "failed to decode token response")
+	}
+	return result.AccessToken, time.Duration(result.ExpiresIn) * time.Second, nil
+}
@@ -0,0 +1,222 @@
+package gke
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/northstack/platform/internal/adapters/kubeapi"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ListNodePools lists the node pools of a cluster
+func (a *Adapter) ListNodePools(ctx context.Context, externalID string) ([]*domain.NodePool, error) {
+	gc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	pools := make([]*domain.NodePool, len(gc.NodePools))
+	for i := range gc.NodePools {
+		pools[i] = fromNodePool(&gc.NodePools[i])
+	}
+	return pools, nil
+}
+
+// CreateNodePool adds a node pool to a cluster
+func (a *Adapter) CreateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	gc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if findPool(gc, pool.Name) != nil {
+		return errors.Conflict("node pool " + pool.Name)
+	}
+	path, err := a.clusterPath(ctx, externalID)
+	if err != nil {
+		return err
+	}
+
+	np := toNodePool(pool)
+	if np.Config.MachineType == "" {
+		np.Config.MachineType = a.config.MachineType
+	}
+	if err := a.doRequest(ctx, http.MethodPost, path+"/nodePools", map[string]interface{}{"nodePool": np}, nil); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", pool.Name).
+		Int("nodes", int(pool.NodeCount)).
+		Msg("Created node pool")
+	return nil
+}
+
+// UpdateNodePool resizes a node pool, changes its machine type and moves it
+// to the pool's version. When it shrinks, its newest surplus nodes are
+// drained first.
+func (a *Adapter) UpdateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	gc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	current := findPool(gc, pool.Name)
+	if current == nil {
+		return errors.NotFound("node pool", pool.Name)
+	}
+	path, err := a.clusterPath(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	path += "/nodePools/" + pool.Name
+
+	// GKE does not report the size of a pool, so its nodes are counted
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	size, err := kubeapi.PoolSize(ctx, e, kubeapi.LabelGKENodePool, pool.Name)
+	if err != nil {
+		return err
+	}
+	if surplus := size - int(pool.NodeCount); surplus > 0 {
+		if _, err := kubeapi.DrainPool(ctx, e, kubeapi.LabelGKENodePool, pool.Name, surplus); err != nil {
+			return err
+		}
+	}
+
+	np := toNodePool(pool)
+	if err := a.doRequest(ctx, http.MethodPost, path+":setAutoscaling", map[string]interface{}{"autoscaling": np.Autoscaling}, nil); err != nil {
+		return err
+	}
+	if err := a.doRequest(ctx, http.MethodPost, path+":setSize", map[string]int32{"nodeCount": pool.NodeCount}, nil); err != nil {
+		return err
+	}
+	update := map[string]string{}
+	if pool.InstanceType != "" && pool.InstanceType != current.Config.MachineType {
+		update["machineType"] = pool.InstanceType
+	}
+	if pool.Version != "" && pool.Version != current.Version {
+		update["nodeVersion"] = pool.Version
+	}
+	if len(update) > 0 {
+		if err := a.doRequest(ctx, http.MethodPut, path, update, nil); err != nil {
+			return err
+		}
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", pool.Name).
+		Int("nodes", int(pool.NodeCount)).
+		Str("instance_type", pool.InstanceType).
+		Msg("Updated node pool")
+	return nil
+}
+
+// DeleteNodePool drains every node of a pool, then deletes it. A cluster's
+// last pool cannot be deleted.
+func (a *Adapter) DeleteNodePool(ctx context.Context, externalID, name string) error {
+	gc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if findPool(gc, name) == nil {
+		return errors.NotFound("node pool", name)
+	}
+	if len(gc.NodePools) == 1 {
+		return errors.NewError(errors.CodeConflict, "the last node pool of a cluster cannot be deleted", http.StatusConflict)
+	}
+	path, err := a.clusterPath(ctx, externalID)
+	if err != nil {
+		return err
+	}
+
+	e, err := a.kubeEndpoint(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	if _, err := kubeapi.DrainPool(ctx, e, kubeapi.LabelGKENodePool, name, -1); err != nil {
+		return err
+	}
+	if err := a.doRequest(ctx, http.MethodDelete, path+"/nodePools/"+name, nil, nil); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", name).
+		Msg("Deleted node pool")
+	return nil
+}
+
+// ListKubernetesVersions lists the control plane versions GKE offers in a
+// cluster's location that the cluster can be upgraded to
+func (a *Adapter) ListKubernetesVersions(ctx context.Context, externalID string) ([]string, error) {
+	gc, err := a.getCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	project, err := a.project(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var serverConfig struct {
+		ValidMasterVersions []string `json:"validMasterVersions"`
+	}
+	path := fmt.Sprintf("/projects/%s/locations/%s/serverConfig", project, gc.Location)
+	if err := a.doRequest(ctx, http.MethodGet, path, nil, &serverConfig); err != nil {
+		return nil, err
+	}
+	return kubeapi.UpgradeTargets(gc.CurrentMasterVersion, serverConfig.ValidMasterVersions), nil
+}
+
+func findPool(gc *gkeCluster, name string) *gkeNodePool {
+	for i := range gc.NodePools {
+		if gc.NodePools[i].Name == name {
+			return &gc.NodePools[i]
+		}
+	}
+	return nil
+}
+
+// nodeCount estimates the size of a pool GKE reports no count for: its
+// autoscaling minimum, or its initial size
+func nodeCount(np *gkeNodePool) int32 {
+	if np.Autoscaling != nil && np.Autoscaling.Enabled {
+		return np.Autoscaling.MinNodeCount
+	}
+	return np.InitialNodeCount
+}
+
+func fromNodePool(np *gkeNodePool) *domain.NodePool {
+	pool := &domain.NodePool{
+		Name:         np.Name,
+		InstanceType: np.Config.MachineType,
+		NodeCount:    nodeCount(np),
+		Version:      np.Version,
+	}
+	if np.Autoscaling != nil && np.Autoscaling.Enabled {
+		pool.AutoScaling = true
+		pool.MinCount = np.Autoscaling.MinNodeCount
+		pool.MaxCount = np.Autoscaling.MaxNodeCount
+	}
+	return pool
+}
+
+func toNodePool(pool *domain.NodePool) gkeNodePool {
+	np := gkeNodePool{
+		Name:             pool.Name,
+		Version:          pool.Version,
+		InitialNodeCount: pool.NodeCount,
+		Autoscaling:      &gkeAutoscaling{Enabled: pool.AutoScaling},
+	}
+	np.Config.MachineType = pool.InstanceType
+	if pool.AutoScaling {
+		np.Autoscaling.MinNodeCount = pool.MinCount
+		np.Autoscaling.MaxNodeCount = pool.MaxCount
+	}
+	return np
+}
@@ -0,0 +1,180 @@
+// Package grafana provides integration with Grafana for observability dashboards.
+// Every project gets a Grafana folder and every service a templated dashboard
+// bound to the service's metric labels, so teams get dashboards without setup.
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Adapter manages folders and dashboards through the Grafana HTTP API
+type Adapter struct {
+	config     *config.GrafanaConfig
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewAdapter creates a new Grafana adapter
+func NewAdapter(cfg *config.GrafanaConfig, log *logger.Logger) *Adapter {
+	return &Adapter{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
+		},
+		logger: log,
+	}
+}
+
+// FolderUID returns the Grafana folder UID for a project
+func FolderUID(projectID uuid.UUID) string {
+	return "prj-" + projectID.String()
+}
+
+// ServiceDashboardUID returns the Grafana dashboard UID for a service
+func ServiceDashboardUID(serviceID uuid.UUID) string {
+	return "svc-" + serviceID.String()
+}
+
+// ProjectDashboardUID returns the Grafana overview dashboard UID for a project
+func ProjectDashboardUID(projectID uuid.UUID) string {
+	return "ovw-" + projectID.String()
+}
+
+// EnsureFolder creates the project folder if it does not exist
+func (a *Adapter) EnsureFolder(ctx context.Context, projectID uuid.UUID, title string) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"uid":   FolderUID(projectID),
+		"title": title,
+	})
+
+	resp, err := a.doRequest(ctx, http.MethodPost, "/api/folders", body)
+	if err != nil {
+		return errors.DependencyFailed("grafana", err)
+	}
+	defer resp.Body.Close()
+
+	// Grafana answers 409/412 when the folder already exists
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusConflict, http.StatusPreconditionFailed:
+		return nil
+	default:
+		return a.handleError(resp)
+	}
+}
+
+// DeleteFolder removes the project folder and every dashboard in it
+func (a *Adapter) DeleteFolder(ctx context.Context, projectID uuid.UUID) error {
+	resp, err := a.doRequest(ctx, http.MethodDelete, "/api/folders/"+FolderUID(projectID)+"?forceDeleteRules=true", nil)
+	if err != nil {
+		return errors.DependencyFailed("grafana", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return a.handleError(resp)
+	}
+	return nil
+}
+
+// UpsertDashboard creates or replaces a dashboard in the project folder
+func (a *Adapter) UpsertDashboard(ctx context.Context, projectID uuid.UUID, dashboard map[string]interface{}) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"dashboard": dashboard,
+		"folderUid": FolderUID(projectID),
+		"overwrite": true,
+		"message":   "Provisioned by platform orchestrator",
+	})
+
+	resp, err := a.doRequest(ctx, http.MethodPost, "/api/dashboards/db", body)
+	if err != nil {
+		return errors.DependencyFailed("grafana", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return a.handleError(resp)
+	}
+	return nil
+}
+
+// DeleteDashboard removes a dashboard by UID
+func (a *Adapter) DeleteDashboard(ctx context.Context, uid string) error {
+	resp, err := a.doRequest(ctx, http.MethodDelete, "/api/dashboards/uid/"+uid, nil)
+	if err != nil {
+		return errors.DependencyFailed("grafana", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return a.handleError(resp)
+	}
+	return nil
+}
+
+// doRequest performs an HTTP request to the Grafana API
+func (a *Adapter) doRequest(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.config.URL+path, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+a.config.APIToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if a.config.OrgID > 0 {
+		req.Header.Set("X-Grafana-Org-Id", strconv.Itoa(a.config.OrgID))
+	}
+
+	start := time.Now()
+	resp, err := a.httpClient.Do(req)
+	metrics.ObserveAdapterCall("grafana", method, start, resp, err)
+	return resp, err
+}
+
+// handleError extracts error information from a response
+func (a *Adapter) handleError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	json.Unmarshal(body, &errResp)
+
+	msg := errResp.Message
+	if msg == "" {
+		msg = string(body)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return errors.NotFound("grafana resource", msg)
+	case http.StatusUnauthorized:
+		return errors.Unauthorized("invalid Grafana credentials")
+	case http.StatusForbidden:
+		return errors.Forbidden("access denied to Grafana resource")
+	case http.StatusBadRequest:
+		return errors.BadRequest(msg)
+	default:
+		return errors.Internal(fmt.Sprintf("Grafana API error (%d): %s", resp.StatusCode, msg))
+	}
+}
@@ -0,0 +1,115 @@
+package grafana
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+)
+
+// panelQuery is a single time series panel on a templated dashboard
+type panelQuery struct {
+	title string
+	unit  string
+	expr  string
+}
+
+// servicePanels are the panels on every service dashboard.
+// %s is replaced with the label matcher for the service.
+var servicePanels = []panelQuery{
+	{"CPU usage", "short", `sum(rate(container_cpu_usage_seconds_total{%s}[5m]))`},
+	{"Memory usage", "bytes", `sum(container_memory_working_set_bytes{%s})`},
+	{"Request rate", "reqps", `sum(rate(http_requests_total{%s}[5m]))`},
+	{"Error rate", "percentunit", `sum(rate(http_requests_total{%[1]s,status=~"5.."}[5m])) / sum(rate(http_requests_total{%[1]s}[5m]))`},
+	{"Latency p99", "s", `histogram_quantile(0.99, sum by (le) (rate(http_request_duration_seconds_bucket{%s}[5m])))`},
+	{"Replicas", "short", `count(kube_pod_status_ready{condition="true",%s})`},
+}
+
+// projectPanels are the panels on the project overview dashboard, broken down by service
+var projectPanels = []panelQuery{
+	{"CPU usage by service", "short", `sum by (` + domain.MetricLabelServiceID + `) (rate(container_cpu_usage_seconds_total{%s}[5m]))`},
+	{"Memory usage by service", "bytes", `sum by (` + domain.MetricLabelServiceID + `) (container_memory_working_set_bytes{%s})`},
+	{"Request rate by service", "reqps", `sum by (` + domain.MetricLabelServiceID + `) (rate(http_requests_total{%s}[5m]))`},
+	{"5xx rate by service", "reqps", `sum by (` + domain.MetricLabelServiceID + `) (rate(http_requests_total{%s,status=~"5.."}[5m]))`},
+}
+
+// ServiceDashboard builds the templated dashboard for a service
+func (a *Adapter) ServiceDashboard(serviceID, projectID uuid.UUID, name string) map[string]interface{} {
+	matcher := fmt.Sprintf(`%s="%s"`, domain.MetricLabelServiceID, serviceID)
+	return a.dashboard(ServiceDashboardUID(serviceID), name, servicePanels, matcher, map[string]string{
+		domain.MetricLabelServiceID: serviceID.String(),
+		domain.MetricLabelProjectID: projectID.String(),
+	})
+}
+
+// ProjectDashboard builds the overview dashboard for a project
+func (a *Adapter) ProjectDashboard(projectID uuid.UUID, name string) map[string]interface{} {
+	matcher := fmt.Sprintf(`%s="%s"`, domain.MetricLabelProjectID, projectID)
+	return a.dashboard(ProjectDashboardUID(projectID), name+" overview", projectPanels, matcher, map[string]string{
+		domain.MetricLabelProjectID: projectID.String(),
+	})
+}
+
+func (a *Adapter) dashboard(uid, title string, queries []panelQuery, matcher string, constants map[string]string) map[string]interface{} {
+	datasource := map[string]interface{}{
+		"type": "prometheus",
+		"uid":  a.config.DatasourceUID,
+	}
+
+	panels := make([]interface{}, 0, len(queries))
+	for i, q := range queries {
+		panels = append(panels, map[string]interface{}{
+			"id":         i + 1,
+			"type":       "timeseries",
+			"title":      q.title,
+			"datasource": datasource,
+			"gridPos": map[string]interface{}{
+				"h": 8,
+				"w": 12,
+				"x": (i % 2) * 12,
+				"y": (i / 2) * 8,
+			},
+			"fieldConfig": map[string]interface{}{
+				"defaults":  map[string]interface{}{"unit": q.unit},
+				"overrides": []interface{}{},
+			},
+			"targets": []interface{}{
+				map[string]interface{}{
+					"refId":      "A",
+					"datasource": datasource,
+					"expr":       fmt.Sprintf(q.expr, matcher),
+				},
+			},
+		})
+	}
+
+	// Constant variables make the bound labels visible and reusable in ad-hoc panels
+	names := make([]string, 0, len(constants))
+	for name := range constants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	variables := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		variables = append(variables, map[string]interface{}{
+			"name":  name,
+			"type":  "constant",
+			"query": constants[name],
+			"hide":  2,
+		})
+	}
+
+	return map[string]interface{}{
+		"uid":           uid,
+		"title":         title,
+		"tags":          []string{domain.ManagedByValue},
+		"timezone":      "browser",
+		"schemaVersion": 39,
+		"refresh":       "30s",
+		"time":          map[string]interface{}{"from": "now-6h", "to": "now"},
+		"templating":    map[string]interface{}{"list": variables},
+		"panels":        panels,
+	}
+}
@@ -0,0 +1,139 @@
+package grafana
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Provisioner keeps Grafana folders and dashboards in step with projects and services
+type Provisioner struct {
+	adapter     *Adapter
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewProvisioner creates a new Provisioner
+func NewProvisioner(adapter *Adapter, projectRepo domain.ProjectRepository, log *logger.Logger) *Provisioner {
+	return &Provisioner{
+		adapter:     adapter,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// Subscribe registers the provisioner on project and service lifecycle
+// events, through durable consumers so that changes made while no replica
+// runs are provisioned too, and failures are retried. Provisioning is
+// idempotent.
+func (p *Provisioner) Subscribe(ctx context.Context, bus domain.EventBus) error {
+	handlers := map[string]domain.EventHandler{
+		"project.created": p.onProjectCreated,
+		"project.deleted": p.onProjectDeleted,
+		"service.created": p.onServiceCreated,
+		"service.deleted": p.onServiceDeleted,
+	}
+	for subject, handler := range handlers {
+		if _, err := eventbus.SubscribeDurable(ctx, bus, subject, eventbus.DurableName("grafana", subject), handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProvisionProject creates the project folder and overview dashboard
+func (p *Provisioner) ProvisionProject(ctx context.Context, project *domain.Project) error {
+	if err := p.adapter.EnsureFolder(ctx, project.ID, project.Name); err != nil {
+		return err
+	}
+	return p.adapter.UpsertDashboard(ctx, project.ID, p.adapter.ProjectDashboard(project.ID, project.Name))
+}
+
+// ProvisionService creates the service dashboard, creating the project folder if needed
+func (p *Provisioner) ProvisionService(ctx context.Context, serviceID, projectID uuid.UUID, name string) error {
+	project, err := p.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if err := p.adapter.EnsureFolder(ctx, project.ID, project.Name); err != nil {
+		return err
+	}
+	return p.adapter.UpsertDashboard(ctx, projectID, p.adapter.ServiceDashboard(serviceID, projectID, name))
+}
+
+func (p *Provisioner) onProjectCreated(event *domain.Event) error {
+	projectID, ok := eventID(event, "project_id")
+	if !ok {
+		return nil
+	}
+
+	ctx := context.Background()
+	project, err := p.projectRepo.GetByID(ctx, projectID)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		p.logger.Warn().Err(err).Str("project_id", projectID.String()).Msg("Failed to load project for Grafana provisioning")
+		return err
+	}
+
+	if err := p.ProvisionProject(ctx, project); err != nil {
+		p.logger.Error().Err(err).Str("project_id", projectID.String()).Msg("Failed to provision Grafana folder")
+		return err
+	}
+	return nil
+}
+
+func (p *Provisioner) onProjectDeleted(event *domain.Event) error {
+	projectID, ok := eventID(event, "project_id")
+	if !ok {
+		return nil
+	}
+
+	if err := p.adapter.DeleteFolder(context.Background(), projectID); err != nil {
+		p.logger.Error().Err(err).Str("project_id", projectID.String()).Msg("Failed to delete Grafana folder")
+		return err
+	}
+	return nil
+}
+
+func (p *Provisioner) onServiceCreated(event *domain.Event) error {
+	serviceID, ok := eventID(event, "service_id")
+	if !ok {
+		return nil
+	}
+	projectID, ok := eventID(event, "project_id")
+	if !ok {
+		return nil
+	}
+	name, _ := event.Data["name"].(string)
+
+	if err := p.ProvisionService(context.Background(), serviceID, projectID, name); err != nil {
+		p.logger.Error().Err(err).Str("service_id", serviceID.String()).Msg("Failed to provision Grafana dashboard")
+		return err
+	}
+	return nil
+}
+
+func (p *Provisioner) onServiceDeleted(event *domain.Event) error {
+	serviceID, ok := eventID(event, "service_id")
+	if !ok {
+		return nil
+	}
+
+	if err := p.adapter.DeleteDashboard(context.Background(), ServiceDashboardUID(serviceID)); err != nil {
+		p.logger.Error().Err(err).Str("service_id", serviceID.String()).Msg("Failed to delete Grafana dashboard")
+		return err
+	}
+	return nil
+}
+
+func eventID(event *domain.Event, key string) (uuid.UUID, bool) {
+	raw, _ := event.Data[key].(string)
+	id, err := uuid.Parse(raw)
+	return id, err == nil
+}
@@ -0,0 +1,178 @@
+package kubeapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// Node labels naming the pool of a node, per hosted provider
+const (
+	LabelEKSNodeGroup = "eks.amazonaws.com/nodegroup"
+	LabelGKENodePool  = "cloud.google.com/gke-nodepool"
+	LabelAKSAgentPool = "kubernetes.azure.com/agentpool"
+)
+
+// drainTimeout bounds how long the pods of removed nodes get to be evicted
+const drainTimeout = 10 * time.Minute
+
+// drainPollInterval is the wait between eviction passes
+var drainPollInterval = 5 * time.Second
+
+// DrainPool cordons the newest count nodes of the pool whose nodes carry
+// label=pool, or all of them when count is negative, and evicts their pods
+// until none is left. Evictions honour PodDisruptionBudgets; pods still
+// blocked at the timeout fail the drain. It returns the drained nodes.
+func DrainPool(ctx context.Context, e *Endpoint, label, pool string, count int) ([]string, error) {
+	nodes, err := poolNodes(ctx, e, label, pool)
+	if err != nil {
+		return nil, err
+	}
+	if count >= 0 && count < len(nodes) {
+		nodes = nodes[:count]
+	}
+
+	for _, node := range nodes {
+		patch := map[string]interface{}{"spec": map[string]interface{}{"unschedulable": true}}
+		if err := e.do(ctx, http.MethodPatch, "/api/v1/nodes/"+node, patch, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline := time.Now().Add(drainTimeout)
+	for {
+		// Pods stay listed while they terminate, or while a budget blocks them
+		var remaining []string
+		for _, node := range nodes {
+			pods, err := evictablePods(ctx, e, node)
+			if err != nil {
+				return nil, err
+			}
+			for _, pod := range pods {
+				if err := evict(ctx, e, pod.namespace, pod.name); err != nil {
+					return nil, err
+				}
+				remaining = append(remaining, pod.namespace+"/"+pod.name)
+			}
+		}
+		if len(remaining) == 0 {
+			return nodes, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errors.NewError(errors.CodeConflict,
+				fmt.Sprintf("draining node pool %s timed out; pods still running: %s", pool, strings.Join(remaining, ", ")), http.StatusConflict)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(drainPollInterval):
+		}
+	}
+}
+
+// PoolSize counts the nodes of the pool whose nodes carry label=pool
+func PoolSize(ctx context.Context, e *Endpoint, label, pool string) (int, error) {
+	nodes, err := poolNodes(ctx, e, label, pool)
+	return len(nodes), err
+}
+
+// poolNodes lists the names of a pool's nodes, newest first
+func poolNodes(ctx context.Context, e *Endpoint, label, pool string) ([]string, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name              string    `json:"name"`
+				CreationTimestamp time.Time `json:"creationTimestamp"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	path := "/api/v1/nodes?labelSelector=" + url.QueryEscape(label+"="+pool)
+	if err := e.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Metadata.CreationTimestamp.After(list.Items[j].Metadata.CreationTimestamp)
+	})
+	names := make([]string, len(list.Items))
+	for i, item := range list.Items {
+		names[i] = item.Metadata.Name
+	}
+	return names, nil
+}
+
+type podRef struct {
+	namespace string
+	name      string
+}
+
+// evictablePods lists the pods on a node a drain moves: DaemonSet and
+// mirror pods stay
+func evictablePods(ctx context.Context, e *Endpoint, node string) ([]podRef, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name            string            `json:"name"`
+				Namespace       string            `json:"namespace"`
+				Annotations     map[string]string `json:"annotations"`
+				OwnerReferences []struct {
+					Kind string `json:"kind"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	path := "/api/v1/pods?fieldSelector=" + url.QueryEscape("spec.nodeName="+node)
+	if err := e.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+
+	var pods []podRef
+	for _, item := range list.Items {
+		if item.Status.Phase == "Succeeded" || item.Status.Phase == "Failed" {
+			continue
+		}
+		if _, mirror := item.Metadata.Annotations["kubernetes.io/config.mirror"]; mirror {
+			continue
+		}
+		daemon := false
+		for _, owner := range item.Metadata.OwnerReferences {
+			if owner.Kind == "DaemonSet" {
+				daemon = true
+			}
+		}
+		if !daemon {
+			pods = append(pods, podRef{namespace: item.Metadata.Namespace, name: item.Metadata.Name})
+		}
+	}
+	return pods, nil
+}
+
+// evict asks the API server to evict a pod. Evictions a PodDisruptionBudget
+// does not allow yet are retried by the next pass.
+func evict(ctx context.Context, e *Endpoint, namespace, name string) error {
+	resp, err := e.send(ctx, http.MethodPost, fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/eviction", namespace, name), map[string]interface{}{
+		"apiVersion": "policy/v1",
+		"kind":       "Eviction",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNotFound, http.StatusTooManyRequests:
+		return nil
+	default:
+		return errors.DependencyFailed("kubernetes", fmt.Errorf("evicting pod %s/%s: %s", namespace, name, resp.Status))
+	}
+}
@@ -0,0 +1,303 @@
+// Package kubeapi talks to the API server of a managed cluster directly. The
+// cluster managers that provision clusters through cloud APIs, without
+// Rancher and its Kubernetes proxy, use it for the operations that act inside
+// a cluster: health, capacity and namespaces.
+package kubeapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// requestTimeout bounds each call to an API server
+const requestTimeout = 30 * time.Second
+
+// Endpoint is the API server of a cluster and the credentials to call it
+// with: a bearer token or a client certificate
+type Endpoint struct {
+	Server     string
+	CAData     []byte // PEM; the system roots are used when empty
+	Token      string
+	ClientCert []byte // PEM
+	ClientKey  []byte // PEM
+}
+
+// Health counts the ready nodes of a cluster and how much of its allocatable
+// CPU and memory pods request. A cluster with nodes that are not ready is
+// unhealthy.
+func Health(ctx context.Context, e *Endpoint) (*domain.ClusterHealth, error) {
+	nodes, err := listNodes(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := capacityOf(ctx, e, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &domain.ClusterHealth{
+		Status:      domain.ClusterStatusActive,
+		NodeCount:   int32(len(nodes)),
+		CPUUsage:    fraction(capacity.CPU),
+		MemoryUsage: fraction(capacity.Memory),
+		Conditions:  []domain.ClusterCondition{},
+	}
+	for _, node := range nodes {
+		if node.ready() {
+			health.ReadyNodes++
+			continue
+		}
+		health.Conditions = append(health.Conditions, domain.ClusterCondition{
+			Type:    "NodeReady",
+			Status:  "False",
+			Message: fmt.Sprintf("node %s is not ready", node.Metadata.Name),
+		})
+	}
+	if health.NodeCount == 0 || health.ReadyNodes < health.NodeCount {
+		health.Status = domain.ClusterStatusUnhealthy
+	}
+	return health, nil
+}
+
+// Capacity sums the allocatable resources of the nodes of a cluster and the
+// requests of the pods that have not finished, per namespace
+func Capacity(ctx context.Context, e *Endpoint) (*domain.ClusterCapacity, error) {
+	nodes, err := listNodes(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+	return capacityOf(ctx, e, nodes)
+}
+
+func capacityOf(ctx context.Context, e *Endpoint, nodes []node) (*domain.ClusterCapacity, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				Containers []struct {
+					Resources struct {
+						Requests map[string]string `json:"requests"`
+					} `json:"resources"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	selector := url.QueryEscape("status.phase!=Succeeded,status.phase!=Failed")
+	if err := e.do(ctx, http.MethodGet, "/api/v1/pods?fieldSelector="+selector, nil, &list); err != nil {
+		return nil, err
+	}
+
+	capacity := &domain.ClusterCapacity{}
+	for _, node := range nodes {
+		capacity.CPU.Allocatable += quantity(node.Status.Allocatable["cpu"])
+		capacity.Memory.Allocatable += quantity(node.Status.Allocatable["memory"])
+		capacity.Pods.Allocatable += quantity(node.Status.Allocatable["pods"])
+	}
+
+	byNamespace := make(map[string]*domain.NamespaceAllocation)
+	for _, pod := range list.Items {
+		ns := byNamespace[pod.Metadata.Namespace]
+		if ns == nil {
+			ns = &domain.NamespaceAllocation{Namespace: pod.Metadata.Namespace}
+			byNamespace[pod.Metadata.Namespace] = ns
+		}
+		ns.Pods++
+		for _, c := range pod.Spec.Containers {
+			ns.CPURequest += quantity(c.Resources.Requests["cpu"])
+			ns.MemoryRequest += quantity(c.Resources.Requests["memory"])
+		}
+	}
+
+	capacity.Namespaces = make([]domain.NamespaceAllocation, 0, len(byNamespace))
+	for _, ns := range byNamespace {
+		capacity.CPU.Requested += ns.CPURequest
+		capacity.Memory.Requested += ns.MemoryRequest
+		capacity.Pods.Requested += float64(ns.Pods)
+		capacity.Namespaces = append(capacity.Namespaces, *ns)
+	}
+	sort.Slice(capacity.Namespaces, func(i, j int) bool {
+		return capacity.Namespaces[i].Namespace < capacity.Namespaces[j].Namespace
+	})
+	return capacity, nil
+}
+
+// CreateNamespace creates a namespace; an existing namespace is not an error
+func CreateNamespace(ctx context.Context, e *Endpoint, namespace string, labels map[string]string) error {
+	err := e.do(ctx, http.MethodPost, "/api/v1/namespaces", map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": namespace, "labels": labels},
+	}, nil)
+	if err != nil && !isConflict(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteNamespace deletes a namespace; a missing namespace is not an error
+func DeleteNamespace(ctx context.Context, e *Endpoint, namespace string) error {
+	err := e.do(ctx, http.MethodDelete, "/api/v1/namespaces/"+namespace, nil, nil)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// node is the part of a node the package reads
+type node struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Status struct {
+		Allocatable map[string]string `json:"allocatable"`
+		Conditions  []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+func (n node) ready() bool {
+	for _, c := range n.Status.Conditions {
+		if c.Type == "Ready" {
+			return c.Status == "True"
+		}
+	}
+	return false
+}
+
+func listNodes(ctx context.Context, e *Endpoint) ([]node, error) {
+	var list struct {
+		Items []node `json:"items"`
+	}
+	if err := e.do(ctx, http.MethodGet, "/api/v1/nodes", nil, &list); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// do sends a request to the API server, encoding body and decoding the
+// response into out unless they are nil
+func (e *Endpoint) do(ctx context.Context, method, path string, body, out interface{}) error {
+	resp, err := e.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return errors.NotFound(path)
+	case resp.StatusCode == http.StatusConflict:
+		return errors.Conflict(path)
+	case resp.StatusCode >= 300:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return errors.DependencyFailed("kubernetes", fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(message)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+	return nil
+}
+
+// send sends a request to the API server and returns its response whatever
+// the status. PATCH bodies are merge patches.
+func (e *Endpoint) send(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	client, err := e.client()
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, e.Server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case method == http.MethodPatch:
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	case body != nil:
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if e.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.Token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	return resp, nil
+}
+
+// client returns an HTTP client trusting the endpoint's CA and presenting its
+// client certificate
+func (e *Endpoint) client() (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(e.CAData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(e.CAData) {
+			return nil, errors.Internal("invalid cluster CA certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if len(e.ClientCert) > 0 {
+		cert, err := tls.X509KeyPair(e.ClientCert, e.ClientKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid cluster client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+	}, nil
+}
+
+func isConflict(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Code == errors.CodeConflict
+}
+
+func fraction(c domain.ResourceCapacity) float64 {
+	if c.Allocatable == 0 {
+		return 0
+	}
+	return c.Requested / c.Allocatable
+}
+
+// quantity parses a Kubernetes resource quantity, or returns 0
+func quantity(s string) float64 {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0
+	}
+	return q.AsApproximateFloat64()
+}
@@ -0,0 +1,64 @@
+package kubeapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthAndCapacity(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/nodes":
+			io.WriteString(w, `{"items":[
+				{"metadata":{"name":"a"},"status":{"allocatable":{"cpu":"4","memory":"8Gi","pods":"110"},"conditions":[{"type":"Ready","status":"True"}]}},
+				{"metadata":{"name":"b"},"status":{"allocatable":{"cpu":"4","memory":"8Gi","pods":"110"},"conditions":[{"type":"Ready","status":"False"}]}}]}`)
+		case "/api/v1/pods":
+			io.WriteString(w, `{"items":[
+				{"metadata":{"namespace":"shop"},"spec":{"containers":[{"resources":{"requests":{"cpu":"500m","memory":"1Gi"}}}]}},
+				{"metadata":{"namespace":"shop"},"spec":{"containers":[{"resources":{"requests":{"cpu":"1500m"}}}]}}]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	e := &Endpoint{Server: srv.URL, Token: "secret"}
+
+	health, err := Health(context.Background(), e)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ClusterStatusUnhealthy, health.Status)
+	assert.Equal(t, int32(2), health.NodeCount)
+	assert.Equal(t, int32(1), health.ReadyNodes)
+	assert.InDelta(t, 0.25, health.CPUUsage, 0.001)
+
+	capacity, err := Capacity(context.Background(), e)
+	require.NoError(t, err)
+	assert.Equal(t, 8.0, capacity.CPU.Allocatable)
+	require.Len(t, capacity.Namespaces, 1)
+	assert.Equal(t, 2, capacity.Namespaces[0].Pods)
+	assert.InDelta(t, 2.0, capacity.Namespaces[0].CPURequest, 0.001)
+}
+
+func TestKubeConfigRoundTrip(t *testing.T) {
+	e := &Endpoint{Server: "https://prod.example.com", CAData: []byte("-----BEGIN CERTIFICATE-----\n"), Token: "secret"}
+
+	data, err := KubeConfig("prod", e)
+	require.NoError(t, err)
+	parsed, err := ParseKubeConfig(data)
+	require.NoError(t, err)
+	assert.Equal(t, e.Server, parsed.Server)
+	assert.Equal(t, e.CAData, parsed.CAData)
+	assert.Equal(t, e.Token, parsed.Token)
+	assert.Empty(t, parsed.ClientCert)
+
+	_, err = ParseKubeConfig([]byte("apiVersion: v1\nkind: Config\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package kubeapi
+
+import (
+	"encoding/base64"
+
+	"github.com/northstack/platform/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// kubeConfig is the part of a kubeconfig file the package reads and writes
+type kubeConfig struct {
+	APIVersion     string         `json:"apiVersion"`
+	Kind           string         `json:"kind"`
+	Clusters       []namedCluster `json:"clusters"`
+	Users          []namedUser    `json:"users"`
+	Contexts       []namedContext `json:"contexts"`
+	CurrentContext string         `json:"current-context"`
+}
+
+type namedCluster struct {
+	Name    string `json:"name"`
+	Cluster struct {
+		Server                   string `json:"server"`
+		CertificateAuthorityData string `json:"certificate-authority-data,omitempty"`
+	} `json:"cluster"`
+}
+
+type namedUser struct {
+	Name string `json:"name"`
+	User struct {
+		Token                 string `json:"token,omitempty"`
+		ClientCertificateData string `json:"client-certificate-data,omitempty"`
+		ClientKeyData         string `json:"client-key-data,omitempty"`
+	} `json:"user"`
+}
+
+type namedContext struct {
+	Name    string `json:"name"`
+	Context struct {
+		Cluster string `json:"cluster"`
+		User    string `json:"user"`
+	} `json:"context"`
+}
+
+// KubeConfig renders a kubeconfig that calls an endpoint with its token
+func KubeConfig(name string, e *Endpoint) ([]byte, error) {
+	config := kubeConfig{
+		APIVersion:     "v1",
+		Kind:           "Config",
+		Clusters:       []namedCluster{{Name: name}},
+		Users:          []namedUser{{Name: name}},
+		Contexts:       []namedContext{{Name: name}},
+		CurrentContext: name,
+	}
+	config.Clusters[0].Cluster.Server = e.Server
+	config.Clusters[0].Cluster.CertificateAuthorityData = base64.StdEncoding.EncodeToString(e.CAData)
+	config.Users[0].User.Token = e.Token
+	config.Contexts[0].Context.Cluster = name
+	config.Contexts[0].Context.User = name
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render kubeconfig")
+	}
+	return data, nil
+}
+
+// ParseKubeConfig returns the endpoint of the current context of a
+// kubeconfig with embedded credentials
+func ParseKubeConfig(data []byte) (*Endpoint, error) {
+	var config kubeConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, errors.Wrap(err, "invalid kubeconfig")
+	}
+
+	clusterName, userName := "", ""
+	for _, c := range config.Contexts {
+		if c.Name == config.CurrentContext || config.CurrentContext == "" {
+			clusterName, userName = c.Context.Cluster, c.Context.User
+			break
+		}
+	}
+
+	e := &Endpoint{}
+	for _, c := range config.Clusters {
+		if c.Name == clusterName {
+			e.Server = c.Cluster.Server
+			e.CAData, _ = base64.StdEncoding.DecodeString(c.Cluster.CertificateAuthorityData)
+		}
+	}
+	for _, u := range config.Users {
+		if u.Name == userName {
+			e.Token = u.User.Token
+			e.ClientCert, _ = base64.StdEncoding.DecodeString(u.User.ClientCertificateData)
+			e.ClientKey, _ = base64.StdEncoding.DecodeString(u.User.ClientKeyData)
+		}
+	}
+	if e.Server == "" {
+		return nil, errors.Internal("kubeconfig has no cluster for its current context")
+	}
+	return e, nil
+}
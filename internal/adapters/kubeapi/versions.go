@@ -0,0 +1,68 @@
+package kubeapi
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UpgradeTargets returns the candidates a cluster at current can be upgraded
+// to, oldest first: newer than current and at most one minor version ahead,
+// as Kubernetes only supports upgrading one minor version at a time
+func UpgradeTargets(current string, candidates []string) []string {
+	from, ok := ParseVersion(current)
+	if !ok {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	targets := []string{}
+	for _, candidate := range candidates {
+		v, ok := ParseVersion(candidate)
+		if !ok || seen[candidate] || v[0] != from[0] || v[1] > from[1]+1 || compareVersions(v, from) <= 0 {
+			continue
+		}
+		seen[candidate] = true
+		targets = append(targets, candidate)
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		vi, _ := ParseVersion(targets[i])
+		vj, _ := ParseVersion(targets[j])
+		return compareVersions(vi, vj) < 0
+	})
+	return targets
+}
+
+// ParseVersion parses the major, minor and patch numbers of versions such as
+// 1.29, v1.29.3 and 1.29.3-gke.1200; a missing patch number is 0
+func ParseVersion(version string) ([3]int, bool) {
+	var v [3]int
+	version = strings.TrimPrefix(version, "v")
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return v, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
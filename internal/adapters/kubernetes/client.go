@@ -0,0 +1,452 @@
+// Package kubernetes implements domain.KubernetesClient against the API server
+// of the cluster the process runs in. The cluster agent uses it to carry out
+// the operations the orchestrator sends it; since there is only one cluster,
+// the cluster ID of each call is ignored.
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/yaml"
+)
+
+// FieldManager owns the fields of the objects applied through the client
+const FieldManager = "northstack-agent"
+
+// Client is a domain.KubernetesClient for the local cluster
+type Client struct {
+	config     *rest.Config
+	dynamic    dynamic.Interface
+	httpClient *http.Client
+	logger     *logger.Logger
+
+	mu    sync.Mutex
+	kinds map[string]kindInfo // Discovered resources by kind
+}
+
+// kindInfo locates the resource of a kind
+type kindInfo struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+// NewClient creates a new Client from a REST config, usually
+// rest.InClusterConfig
+func NewClient(cfg *rest.Config, log *logger.Logger) (*Client, error) {
+	dyn, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	httpClient, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	return &Client{
+		config:     cfg,
+		dynamic:    dyn,
+		httpClient: httpClient,
+		logger:     log,
+	}, nil
+}
+
+// ApplyManifest server-side applies each document of a YAML or JSON manifest.
+// Namespaced objects without a namespace go to the default namespace.
+func (c *Client) ApplyManifest(ctx context.Context, clusterID uuid.UUID, manifest []byte) error {
+	for _, doc := range splitDocuments(manifest) {
+		data, err := yaml.YAMLToJSON(doc)
+		if err != nil {
+			return errors.BadRequest("invalid manifest: " + err.Error())
+		}
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(data); err != nil {
+			return errors.BadRequest("invalid manifest: " + err.Error())
+		}
+
+		info, err := c.resolve(ctx, obj.GetKind(), obj.GetAPIVersion())
+		if err != nil {
+			return err
+		}
+		resource := c.dynamic.Resource(info.gvr)
+		var target dynamic.ResourceInterface = resource
+		if info.namespaced {
+			namespace := obj.GetNamespace()
+			if namespace == "" {
+				namespace = metav1.NamespaceDefault
+			}
+			target = resource.Namespace(namespace)
+		}
+
+		force := true
+		if _, err := target.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+			FieldManager: FieldManager,
+			Force:        &force,
+		}); err != nil {
+			return apiError(err, obj.GetKind(), obj.GetName())
+		}
+	}
+	return nil
+}
+
+// PatchResource applies a strategic merge patch to an object. Custom
+// resources do not support strategic merge patches.
+func (c *Client) PatchResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string, patch []byte) error {
+	target, err := c.target(ctx, kind, namespace)
+	if err != nil {
+		return err
+	}
+	if _, err := target.Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{
+		FieldManager: FieldManager,
+	}); err != nil {
+		return apiError(err, kind, name)
+	}
+	return nil
+}
+
+// DeleteResource deletes an object
+func (c *Client) DeleteResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) error {
+	target, err := c.target(ctx, kind, namespace)
+	if err != nil {
+		return err
+	}
+	if err := target.Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+		return apiError(err, kind, name)
+	}
+	return nil
+}
+
+// EvictPod creates a policy/v1 Eviction for a pod. The API server refuses it
+// with 429 Too Many Requests while a PodDisruptionBudget of the pod does not
+// allow the disruption.
+func (c *Client) EvictPod(ctx context.Context, clusterID uuid.UUID, namespace, name string) error {
+	eviction := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "policy/v1",
+		"kind":       "Eviction",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+	}}
+	pods := c.dynamic.Resource(schema.GroupVersionResource{Version: "v1", Resource: "pods"}).Namespace(namespace)
+	if _, err := pods.Create(ctx, eviction, metav1.CreateOptions{}, "eviction"); err != nil {
+		if apierrors.IsTooManyRequests(err) {
+			return errors.NewError(errors.CodeRateLimited, fmt.Sprintf("eviction of pod %s is blocked by a disruption budget", name), http.StatusTooManyRequests).WithError(err)
+		}
+		return apiError(err, "Pod", name)
+	}
+	return nil
+}
+
+// GetResource returns an object
+func (c *Client) GetResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) (map[string]interface{}, error) {
+	target, err := c.target(ctx, kind, namespace)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := target.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, apiError(err, kind, name)
+	}
+	return obj.Object, nil
+}
+
+// ListResources lists the objects of a kind carrying all the labels, in one
+// namespace or, when namespace is empty, in all of them
+func (c *Client) ListResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string, labels map[string]string) ([]map[string]interface{}, error) {
+	target, err := c.target(ctx, kind, namespace)
+	if err != nil {
+		return nil, err
+	}
+	list, err := target.List(ctx, metav1.ListOptions{LabelSelector: labelSelector(labels)})
+	if err != nil {
+		return nil, apiError(err, kind, "")
+	}
+
+	items := make([]map[string]interface{}, 0, len(list.Items))
+	for _, item := range list.Items {
+		items = append(items, item.Object)
+	}
+	return items, nil
+}
+
+// GetPodLogs returns the last lines logged by a container of a pod
+func (c *Client) GetPodLogs(ctx context.Context, clusterID uuid.UUID, namespace, podName, container string, tailLines int64) (string, error) {
+	return c.podLogs(ctx, namespace, podName, container, tailLines, url.Values{})
+}
+
+// GetTimestampedPodLogs returns the last lines logged by a container of a
+// pod, each prefixed by the time it was logged at. With since set, lines
+// logged before its second are left out.
+func (c *Client) GetTimestampedPodLogs(ctx context.Context, clusterID uuid.UUID, namespace, podName, container string, since time.Time, tailLines int64) (string, error) {
+	query := url.Values{}
+	query.Set("timestamps", "true")
+	if !since.IsZero() {
+		query.Set("sinceTime", since.UTC().Format(time.RFC3339))
+	}
+	return c.podLogs(ctx, namespace, podName, container, tailLines, query)
+}
+
+// podLogs reads the logs of a container of a pod with the given query
+func (c *Client) podLogs(ctx context.Context, namespace, podName, container string, tailLines int64, query url.Values) (string, error) {
+	if container != "" {
+		query.Set("container", container)
+	}
+	if tailLines > 0 {
+		query.Set("tailLines", fmt.Sprint(tailLines))
+	}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/log?%s", namespace, podName, query.Encode())
+
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	logs, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.DependencyFailed("kubernetes", err)
+	}
+	return string(logs), nil
+}
+
+// WatchResource calls handler with each change to the objects of a kind until
+// ctx is cancelled or the API server ends the watch
+func (c *Client) WatchResource(ctx context.Context, clusterID uuid.UUID, kind, namespace string, handler func(eventType string, obj map[string]interface{})) error {
+	target, err := c.target(ctx, kind, namespace)
+	if err != nil {
+		return err
+	}
+	watcher, err := target.Watch(ctx, metav1.ListOptions{})
+	if err != nil {
+		return apiError(err, kind, "")
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+			if obj, ok := event.Object.(*unstructured.Unstructured); ok {
+				handler(string(event.Type), obj.Object)
+			}
+		}
+	}
+}
+
+// PortForward is not supported; the agent only serves operations that fit a
+// request and its reply
+func (c *Client) PortForward(ctx context.Context, clusterID uuid.UUID, namespace, podName string, port int32) (io.ReadWriteCloser, error) {
+	return nil, errors.NotImplemented("port forwarding is not supported by the cluster agent")
+}
+
+// target returns the resource interface for the objects of a kind in a
+// namespace; namespace is ignored for cluster-scoped kinds
+func (c *Client) target(ctx context.Context, kind, namespace string) (dynamic.ResourceInterface, error) {
+	info, err := c.resolve(ctx, kind, "")
+	if err != nil {
+		return nil, err
+	}
+	resource := c.dynamic.Resource(info.gvr)
+	if info.namespaced && namespace != "" {
+		return resource.Namespace(namespace), nil
+	}
+	return resource, nil
+}
+
+// resolve finds the resource of a kind, within apiVersion when it is given.
+// Resources are discovered on first use and rediscovered when a kind is
+// missing, so that CRDs installed later are found.
+func (c *Client) resolve(ctx context.Context, kind, apiVersion string) (kindInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := kindKey(kind, apiVersion)
+	if info, ok := c.kinds[key]; ok {
+		return info, nil
+	}
+
+	kinds, err := c.discover(ctx)
+	if err != nil {
+		return kindInfo{}, err
+	}
+	c.kinds = kinds
+	if info, ok := c.kinds[key]; ok {
+		return info, nil
+	}
+	return kindInfo{}, errors.BadRequest(fmt.Sprintf("unknown kind %s", strings.TrimPrefix(key, "/")))
+}
+
+// kindKey indexes a kind alone, or within an API version
+func kindKey(kind, apiVersion string) string {
+	return apiVersion + "/" + kind
+}
+
+// discover lists the resources the API server serves. Each kind is indexed
+// under its group version, and alone under the preferred version of its
+// group; the core group wins over others serving the same kind.
+func (c *Client) discover(ctx context.Context) (map[string]kindInfo, error) {
+	type groupVersion struct {
+		GroupVersion string `json:"groupVersion"`
+	}
+	var groups struct {
+		Groups []struct {
+			Versions         []groupVersion `json:"versions"`
+			PreferredVersion groupVersion   `json:"preferredVersion"`
+		} `json:"groups"`
+	}
+	if err := c.getJSON(ctx, "/apis", &groups); err != nil {
+		return nil, err
+	}
+
+	kinds := make(map[string]kindInfo)
+	add := func(groupVersion string, preferred bool) error {
+		path := "/apis/" + groupVersion
+		if groupVersion == "v1" {
+			path = "/api/v1"
+		}
+		var list struct {
+			Resources []struct {
+				Name       string `json:"name"`
+				Kind       string `json:"kind"`
+				Namespaced bool   `json:"namespaced"`
+			} `json:"resources"`
+		}
+		if err := c.getJSON(ctx, path, &list); err != nil {
+			return err
+		}
+
+		gv, err := schema.ParseGroupVersion(groupVersion)
+		if err != nil {
+			return err
+		}
+		for _, r := range list.Resources {
+			if strings.Contains(r.Name, "/") {
+				continue // Subresource
+			}
+			info := kindInfo{gvr: gv.WithResource(r.Name), namespaced: r.Namespaced}
+			kinds[kindKey(r.Kind, groupVersion)] = info
+			if _, taken := kinds[kindKey(r.Kind, "")]; preferred && !taken {
+				kinds[kindKey(r.Kind, "")] = info
+			}
+		}
+		return nil
+	}
+
+	if err := add("v1", true); err != nil {
+		return nil, err
+	}
+	for _, group := range groups.Groups {
+		for _, version := range group.Versions {
+			if err := add(version.GroupVersion, version.GroupVersion == group.PreferredVersion.GroupVersion); err != nil {
+				// An aggregated API that is down must not hide the others
+				c.logger.Warn().Err(err).Str("group_version", version.GroupVersion).Msg("Failed to discover API resources")
+			}
+		}
+	}
+	return kinds, nil
+}
+
+// get sends a GET request to the API server and checks its status
+func (c *Client) get(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.config.Host, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, errors.NotFound(path)
+		}
+		return nil, errors.DependencyFailed("kubernetes", fmt.Errorf("GET %s: %s: %s", path, resp.Status, bytes.TrimSpace(body)))
+	}
+	return resp, nil
+}
+
+// getJSON sends a GET request to the API server and decodes its response
+func (c *Client) getJSON(ctx context.Context, path string, out interface{}) error {
+	resp, err := c.get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+	return nil
+}
+
+// apiError converts an error of the API server to a platform error
+func apiError(err error, kind, name string) error {
+	switch {
+	case apierrors.IsNotFound(err):
+		return errors.NotFound(kind, name)
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return errors.Conflict(kind)
+	case apierrors.IsForbidden(err):
+		return errors.Forbidden(err.Error())
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return errors.BadRequest(err.Error())
+	}
+	return errors.DependencyFailed("kubernetes", err)
+}
+
+// labelSelector formats labels as an equality-based selector
+func labelSelector(labels map[string]string) string {
+	selector := make([]string, 0, len(labels))
+	for key, value := range labels {
+		selector = append(selector, key+"="+value)
+	}
+	return strings.Join(selector, ",")
+}
+
+// splitDocuments splits a multi-document YAML manifest at its "---" lines,
+// dropping empty documents
+func splitDocuments(manifest []byte) [][]byte {
+	var docs [][]byte
+	var current []byte
+	flush := func() {
+		if len(bytes.TrimSpace(current)) > 0 {
+			docs = append(docs, current)
+		}
+		current = nil
+	}
+
+	for _, line := range bytes.SplitAfter(manifest, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("---")) {
+			flush()
+			continue
+		}
+		current = append(current, line...)
+	}
+	flush()
+	return docs
+}
@@ -0,0 +1,134 @@
+package kubernetes
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+// apiServer serves discovery, one deployment and one pod's logs
+func apiServer(t *testing.T) *httptest.Server {
+	responses := map[string]string{
+		"/apis":         `{"groups":[{"versions":[{"groupVersion":"apps/v1"}],"preferredVersion":{"groupVersion":"apps/v1"}}]}`,
+		"/api/v1":       `{"resources":[{"name":"pods","kind":"Pod","namespaced":true},{"name":"pods/log","kind":"Pod","namespaced":true},{"name":"namespaces","kind":"Namespace","namespaced":false}]}`,
+		"/apis/apps/v1": `{"resources":[{"name":"deployments","kind":"Deployment","namespaced":true}]}`,
+		"/apis/apps/v1/namespaces/shop/deployments/web": `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"shop"}}`,
+		"/api/v1/namespaces/shop/pods/web-1/log":        "started\n",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+			return
+		}
+		if r.URL.Path == "/api/v1/namespaces/shop/pods/web-1/log" {
+			assert.Equal(t, "10", r.URL.Query().Get("tailLines"))
+			if r.URL.Query().Get("timestamps") == "true" {
+				assert.Equal(t, "2026-03-01T10:00:00Z", r.URL.Query().Get("sinceTime"))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := apiServer(t)
+	client, err := NewClient(&rest.Config{Host: srv.URL}, logger.New("error", "json", io.Discard))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	obj, err := client.GetResource(ctx, uuid.Nil, "Deployment", "shop", "web")
+	require.NoError(t, err)
+	assert.Equal(t, "apps/v1", obj["apiVersion"])
+
+	_, err = client.GetResource(ctx, uuid.Nil, "Deployment", "shop", "api")
+	assert.True(t, errors.IsNotFound(err))
+
+	info, err := client.resolve(ctx, "Namespace", "")
+	require.NoError(t, err)
+	assert.False(t, info.namespaced)
+
+	_, err = client.resolve(ctx, "Deployment", "apps/v1beta1")
+	assert.Error(t, err, "kinds are looked up within the manifest's API version")
+
+	logs, err := client.GetPodLogs(ctx, uuid.Nil, "shop", "web-1", "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, "started\n", logs)
+
+	_, err = client.GetTimestampedPodLogs(ctx, uuid.Nil, "shop", "web-1", "", time.Date(2026, 3, 1, 10, 0, 0, 500, time.UTC), 10)
+	require.NoError(t, err)
+}
+
+func TestClientPatchesAndEvicts(t *testing.T) {
+	discovery := apiServer(t)
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			resp, err := http.Get(discovery.URL + r.URL.Path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(resp.StatusCode)
+			io.Copy(w, resp.Body)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("Content-Type")+" "+string(body))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/v1/namespaces/shop/pods/web-2/eviction":
+			w.WriteHeader(http.StatusTooManyRequests)
+			io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"TooManyRequests","code":429}`)
+		case "/api/v1/namespaces/shop/pods/web-1/eviction":
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"kind":"Status","apiVersion":"v1","status":"Success","code":201}`)
+		default:
+			io.WriteString(w, `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"web","namespace":"shop"}}`)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := NewClient(&rest.Config{Host: srv.URL}, logger.New("error", "json", io.Discard))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, client.PatchResource(ctx, uuid.Nil, "Deployment", "shop", "web", []byte(`{"spec":{"replicas":2}}`)))
+	require.NoError(t, client.EvictPod(ctx, uuid.Nil, "shop", "web-1"))
+
+	err = client.EvictPod(ctx, uuid.Nil, "shop", "web-2")
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, errors.CodeRateLimited, appErr.Code, "a disruption budget blocks the eviction")
+
+	require.Len(t, requests, 3)
+	assert.Equal(t, `PATCH /apis/apps/v1/namespaces/shop/deployments/web application/strategic-merge-patch+json {"spec":{"replicas":2}}`, requests[0])
+	assert.Contains(t, requests[1], "POST /api/v1/namespaces/shop/pods/web-1/eviction")
+	assert.Contains(t, requests[1], `"kind":"Eviction"`)
+	assert.Contains(t, requests[1], `"apiVersion":"policy/v1"`)
+}
+
+func TestSplitDocuments(t *testing.T) {
+	manifest := "---\napiVersion: v1\nkind: Namespace\n--- # web\napiVersion: apps/v1\nkind: Deployment\n---\n\n"
+	docs := splitDocuments([]byte(manifest))
+	require.Len(t, docs, 2)
+	assert.Equal(t, "apiVersion: v1\nkind: Namespace\n", string(docs[0]))
+	assert.Equal(t, "apiVersion: apps/v1\nkind: Deployment\n", string(docs[1]))
+
+	assert.Len(t, splitDocuments([]byte(`{"apiVersion":"v1","kind":"Namespace"}`)), 1)
+}
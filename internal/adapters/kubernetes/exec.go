@@ -0,0 +1,129 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/northstack/platform/pkg/errors"
+	"k8s.io/client-go/rest"
+)
+
+// Channels of the Kubernetes websocket exec protocol; each message starts
+// with the byte of its channel
+const (
+	execProtocol = "v4.channel.k8s.io"
+
+	channelStdout = 1
+	channelStderr = 2
+	channelError  = 3
+)
+
+// ExecInPod runs a command in a container of a pod and returns its output,
+// stdout and stderr interleaved as they were written
+func (c *Client) ExecInPod(ctx context.Context, clusterID uuid.UUID, namespace, podName, container string, command []string) (string, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(c.config.Host, "/"))
+	if err != nil {
+		return "", err
+	}
+	switch endpoint.Scheme {
+	case "https":
+		endpoint.Scheme = "wss"
+	case "http":
+		endpoint.Scheme = "ws"
+	}
+	endpoint.Path = fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/exec", namespace, podName)
+	query := url.Values{"command": command, "stdout": {"true"}, "stderr": {"true"}}
+	if container != "" {
+		query.Set("container", container)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	tlsConfig, err := rest.TLSConfigFor(c.config)
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{}
+	if token, err := c.bearerToken(); err != nil {
+		return "", err
+	} else if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	dialer := websocket.Dialer{
+		TLSClientConfig: tlsConfig,
+		Subprotocols:    []string{execProtocol},
+		Proxy:           http.ProxyFromEnvironment,
+	}
+	conn, resp, err := dialer.DialContext(ctx, endpoint.String(), header)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", errors.NotFound("pod", podName)
+		}
+		return "", errors.DependencyFailed("kubernetes", err)
+	}
+	defer conn.Close()
+
+	// Unblock the read when ctx is cancelled
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var output bytes.Buffer
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return output.String(), nil
+			}
+			if ctx.Err() != nil {
+				return output.String(), ctx.Err()
+			}
+			return output.String(), errors.DependencyFailed("kubernetes", err)
+		}
+		if len(msg) == 0 {
+			continue
+		}
+
+		switch msg[0] {
+		case channelStdout, channelStderr:
+			output.Write(msg[1:])
+		case channelError:
+			if err := execStatus(msg[1:]); err != nil {
+				return output.String(), err
+			}
+		}
+	}
+}
+
+// execStatus returns the error reported on the error channel of an exec, or
+// nil when the command succeeded
+func execStatus(data []byte) error {
+	var status struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil || status.Status == "Success" {
+		return nil
+	}
+	return errors.DependencyFailed("kubernetes", fmt.Errorf("command failed: %s", status.Message))
+}
+
+// bearerToken returns the token the client authenticates with, reading the
+// token file on each call since in-cluster tokens are rotated
+func (c *Client) bearerToken() (string, error) {
+	if c.config.BearerTokenFile != "" {
+		token, err := os.ReadFile(c.config.BearerTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account token: %w", err)
+		}
+		return strings.TrimSpace(string(token)), nil
+	}
+	return c.config.BearerToken, nil
+}
@@ -0,0 +1,198 @@
+// Package loki implements domain.LogStore against the Grafana Loki HTTP API.
+// Service log streams are selected by the service_id label attached by the
+// platform's log shipping pipeline.
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	defaultLimit = 500
+	maxLimit     = 5000
+)
+
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Store queries Loki for historical logs
+type Store struct {
+	config     *config.LokiConfig
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewStore creates a new Loki-backed LogStore
+func NewStore(cfg *config.LokiConfig, log *logger.Logger) *Store {
+	return &Store{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
+		},
+		logger: log,
+	}
+}
+
+// queryResponse is the Loki query_range response for stream results
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// SearchServiceLogs searches historical logs for a service
+func (s *Store) SearchServiceLogs(ctx context.Context, serviceID uuid.UUID, query domain.LogQuery) ([]domain.LogEntry, error) {
+	expr, err := buildQuery(serviceID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	direction := query.Direction
+	if direction != "forward" {
+		direction = "backward"
+	}
+
+	params := url.Values{}
+	params.Set("query", expr)
+	params.Set("start", strconv.FormatInt(query.Start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(query.End.UnixNano(), 10))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("direction", direction)
+
+	resp, err := s.do(ctx, "/loki/api/v1/query_range", params)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []domain.LogEntry{}
+	for _, stream := range resp.Data.Result {
+		for _, v := range stream.Values {
+			ns, err := strconv.ParseInt(v[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			entries = append(entries, domain.LogEntry{
+				Timestamp: time.Unix(0, ns).UTC(),
+				Labels:    stream.Stream,
+				Message:   v[1],
+			})
+		}
+	}
+
+	// Loki returns entries grouped by stream; interleave them by time
+	sort.SliceStable(entries, func(i, j int) bool {
+		if direction == "forward" {
+			return entries[i].Timestamp.Before(entries[j].Timestamp)
+		}
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	return entries, nil
+}
+
+// buildQuery renders the LogQL expression for a service search
+func buildQuery(serviceID uuid.UUID, query domain.LogQuery) (string, error) {
+	matchers := []string{fmt.Sprintf("%s=%q", domain.MetricLabelServiceID, serviceID.String())}
+
+	names := make([]string, 0, len(query.Labels))
+	for name := range query.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !labelName.MatchString(name) {
+			return "", errors.BadRequest(fmt.Sprintf("invalid label name: %s", name))
+		}
+		if name == domain.MetricLabelServiceID {
+			continue // Always scoped to the requested service
+		}
+		matchers = append(matchers, fmt.Sprintf("%s=%s", name, strconv.Quote(query.Labels[name])))
+	}
+
+	expr := "{" + strings.Join(matchers, ",") + "}"
+	if query.Regex != "" {
+		if _, err := regexp.Compile(query.Regex); err != nil {
+			return "", errors.BadRequest(fmt.Sprintf("invalid regex: %v", err))
+		}
+		expr += " |~ " + strconv.Quote(query.Regex)
+	}
+	return expr, nil
+}
+
+func (s *Store) do(ctx context.Context, path string, params url.Values) (*queryResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.URL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build Loki request")
+	}
+	if s.config.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.config.TenantID)
+	}
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	httpResp, err := s.httpClient.Do(req)
+	metrics.ObserveAdapterCall("loki", http.MethodGet, start, httpResp, err)
+	if err != nil {
+		return nil, errors.DependencyFailed("loki", err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, errors.DependencyFailed("loki", err)
+	}
+
+	if httpResp.StatusCode == http.StatusBadRequest {
+		return nil, errors.BadRequest(strings.TrimSpace(string(body)))
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, errors.DependencyFailed("loki", fmt.Errorf("unexpected status %d: %s", httpResp.StatusCode, body))
+	}
+
+	var resp queryResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, errors.DependencyFailed("loki", err)
+	}
+	if resp.Status != "success" {
+		return nil, errors.DependencyFailed("loki", fmt.Errorf("query failed: %s", resp.Error))
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,142 @@
+// Package opsgenie opens and closes Opsgenie alerts through the Alert API,
+// as a backend of the escalation notifier. The dedup key is the alert's
+// alias, which Opsgenie deduplicates open alerts by.
+package opsgenie
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/escalation"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Limits of the Alert API
+const (
+	maxMessage = 130
+	maxAlias   = 512
+)
+
+// source identifies NorthStack as the creator and closer of alerts
+const source = "NorthStack"
+
+// Client creates and closes Opsgenie alerts
+type Client struct {
+	config     *config.OpsgenieConfig
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewClient creates a new Client
+func NewClient(cfg *config.OpsgenieConfig, log *logger.Logger) *Client {
+	return &Client{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
+		},
+		logger: log,
+	}
+}
+
+var _ escalation.Backend = (*Client)(nil)
+
+type alertRequest struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Responders  []responder       `json:"responders,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority"`
+}
+
+type responder struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type closeRequest struct {
+	Source string `json:"source"`
+	Note   string `json:"note"`
+}
+
+// Trigger creates an alert. Opsgenie adds to the open alert of the same
+// alias instead of creating another.
+func (c *Client) Trigger(ctx context.Context, incident *escalation.Incident) error {
+	req := &alertRequest{
+		Message:  truncate(incident.Summary, maxMessage),
+		Alias:    truncate(incident.DedupKey, maxAlias),
+		Details:  incident.Details,
+		Entity:   incident.Source,
+		Source:   source,
+		Priority: c.config.Priority,
+	}
+	if len(incident.Summary) > maxMessage || incident.Link != "" {
+		req.Description = incident.Summary
+		if incident.Link != "" {
+			req.Description += "\n\n" + incident.Link
+		}
+	}
+	if c.config.Team != "" {
+		req.Responders = []responder{{Type: "team", Name: c.config.Team}}
+	}
+	return c.post(ctx, "/v2/alerts", req)
+}
+
+// Resolve closes the open alert of a dedup key. Opsgenie processes requests
+// asynchronously, so closing an alias with no open alert is not an error.
+func (c *Client) Resolve(ctx context.Context, dedupKey string) error {
+	path := "/v2/alerts/" + url.PathEscape(truncate(dedupKey, maxAlias)) + "/close?identifierType=alias"
+	return c.post(ctx, path, &closeRequest{Source: source, Note: "Resolved in NorthStack"})
+}
+
+// post sends a request, which Opsgenie accepts with 202
+func (c *Client) post(ctx context.Context, path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode Opsgenie request")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.config.APIURL, "/")+path, bytes.NewReader(data))
+	if err != nil {
+		return errors.Wrap(err, "failed to create Opsgenie request")
+	}
+	req.Header.Set("Authorization", "GenieKey "+c.config.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	metrics.ObserveAdapterCall("opsgenie", http.MethodPost, start, resp, err)
+	if err != nil {
+		return errors.DependencyFailed("opsgenie", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return errors.DependencyFailed("opsgenie", fmt.Errorf("POST %s returned %d: %s", strings.SplitN(path, "?", 2)[0], resp.StatusCode, strings.TrimSpace(string(respBody))))
+	}
+
+	c.logger.Debug().Str("path", path).Msg("Opsgenie request accepted")
+	return nil
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
@@ -0,0 +1,70 @@
+package opsgenie
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/escalation"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type request struct {
+	Path  string
+	Query string
+	Body  map[string]interface{}
+}
+
+// alertAPI records the requests sent to it
+func alertAPI(t *testing.T) (*httptest.Server, *[]request) {
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GenieKey key-1", r.Header.Get("Authorization"))
+		req := request{Path: r.URL.EscapedPath(), Query: r.URL.RawQuery}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req.Body))
+		requests = append(requests, req)
+		w.WriteHeader(http.StatusAccepted)
+		io.WriteString(w, `{"result": "Request will be processed", "requestId": "r1"}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestTriggerAndResolve(t *testing.T) {
+	server, requests := alertAPI(t)
+	cfg := &config.OpsgenieConfig{APIKey: "key-1", APIURL: server.URL, Team: "platform-oncall", Priority: "P1", Timeout: time.Second}
+	c := NewClient(cfg, logger.New("error", "json", io.Discard))
+
+	incident := &escalation.Incident{
+		DedupKey: "deploy/s1/c1",
+		Summary:  "Production deploy of api v7 failed",
+		Source:   "api",
+		Details:  map[string]string{"error": "rollout timed out"},
+		Link:     "https://console.example.com/services/s1",
+	}
+	require.NoError(t, c.Trigger(context.Background(), incident))
+	require.NoError(t, c.Resolve(context.Background(), incident.DedupKey))
+
+	require.Len(t, *requests, 2)
+	create := (*requests)[0]
+	assert.Equal(t, "/v2/alerts", create.Path)
+	assert.Equal(t, "deploy/s1/c1", create.Body["alias"])
+	assert.Equal(t, "Production deploy of api v7 failed", create.Body["message"])
+	assert.Equal(t, "P1", create.Body["priority"])
+	assert.Equal(t, "api", create.Body["entity"])
+	assert.Contains(t, create.Body["description"], "https://console.example.com/services/s1")
+	assert.Equal(t, []interface{}{map[string]interface{}{"type": "team", "name": "platform-oncall"}}, create.Body["responders"])
+
+	// Alerts are closed by their alias, escaped in the path
+	closeReq := (*requests)[1]
+	assert.Equal(t, "/v2/alerts/deploy%2Fs1%2Fc1/close", closeReq.Path)
+	assert.Equal(t, "identifierType=alias", closeReq.Query)
+	assert.Equal(t, "NorthStack", closeReq.Body["source"])
+}
@@ -0,0 +1,137 @@
+// Package pagerduty opens and resolves PagerDuty incidents through the
+// Events API v2, as a backend of the escalation notifier
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/escalation"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// maxSummary is the longest summary PagerDuty accepts
+const maxSummary = 1024
+
+// Client sends events to a PagerDuty service
+type Client struct {
+	config     *config.PagerDutyConfig
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewClient creates a new Client
+func NewClient(cfg *config.PagerDutyConfig, log *logger.Logger) *Client {
+	return &Client{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
+		},
+		logger: log,
+	}
+}
+
+var _ escalation.Backend = (*Client)(nil)
+
+type event struct {
+	RoutingKey  string   `json:"routing_key"`
+	EventAction string   `json:"event_action"` // trigger or resolve
+	DedupKey    string   `json:"dedup_key"`
+	Payload     *payload `json:"payload,omitempty"`
+	Links       []link   `json:"links,omitempty"`
+}
+
+type payload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type link struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+// Trigger opens an incident, or adds to the open incident of its dedup key
+func (c *Client) Trigger(ctx context.Context, incident *escalation.Incident) error {
+	source := incident.Source
+	if source == "" {
+		source = "northstack"
+	}
+	summary := incident.Summary
+	if len(summary) > maxSummary {
+		summary = summary[:maxSummary-3] + "..."
+	}
+
+	ev := &event{
+		RoutingKey:  c.config.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    incident.DedupKey,
+		Payload: &payload{
+			Summary:       summary,
+			Source:        source,
+			Severity:      escalation.SeverityCritical,
+			CustomDetails: incident.Details,
+		},
+	}
+	if incident.Link != "" {
+		ev.Links = []link{{Href: incident.Link, Text: "Open in NorthStack"}}
+	}
+	return c.enqueue(ctx, ev)
+}
+
+// Resolve resolves the open incident of a dedup key. PagerDuty ignores keys
+// with no open incident.
+func (c *Client) Resolve(ctx context.Context, dedupKey string) error {
+	return c.enqueue(ctx, &event{
+		RoutingKey:  c.config.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    dedupKey,
+	})
+}
+
+// enqueue sends an event, which PagerDuty accepts with 202 and processes
+// asynchronously
+func (c *Client) enqueue(ctx context.Context, ev *event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode PagerDuty event")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.config.APIURL, "/")+"/v2/enqueue", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create PagerDuty request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	metrics.ObserveAdapterCall("pagerduty", http.MethodPost, start, resp, err)
+	if err != nil {
+		return errors.DependencyFailed("pagerduty", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return errors.DependencyFailed("pagerduty", fmt.Errorf("%s event returned %d: %s", ev.EventAction, resp.StatusCode, strings.TrimSpace(string(data))))
+	}
+
+	c.logger.Debug().
+		Str("dedup_key", ev.DedupKey).
+		Str("action", ev.EventAction).
+		Msg("PagerDuty event sent")
+	return nil
+}
@@ -0,0 +1,79 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/escalation"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventsAPI records the events sent to it, answering with status
+func eventsAPI(t *testing.T, status int) (*httptest.Server, *[]event) {
+	var events []event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/enqueue", r.URL.Path)
+		var ev event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&ev))
+		events = append(events, ev)
+		w.WriteHeader(status)
+		if status == http.StatusAccepted {
+			io.WriteString(w, `{"status": "success", "dedup_key": "`+ev.DedupKey+`"}`)
+		} else {
+			io.WriteString(w, `{"status": "invalid event", "message": "Event object is invalid"}`)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &events
+}
+
+func testClient(url string) *Client {
+	cfg := &config.PagerDutyConfig{RoutingKey: "R0UT1NG", APIURL: url, Timeout: time.Second}
+	return NewClient(cfg, logger.New("error", "json", io.Discard))
+}
+
+func TestTriggerAndResolve(t *testing.T) {
+	server, events := eventsAPI(t, http.StatusAccepted)
+	c := testClient(server.URL)
+
+	incident := &escalation.Incident{
+		DedupKey: "alert/ClusterUnhealthy/c1",
+		Summary:  strings.Repeat("x", 2000),
+		Details:  map[string]string{"cluster_id": "c1"},
+		Link:     "https://console.example.com/clusters/c1",
+	}
+	require.NoError(t, c.Trigger(context.Background(), incident))
+	require.NoError(t, c.Resolve(context.Background(), incident.DedupKey))
+
+	require.Len(t, *events, 2)
+	trigger := (*events)[0]
+	assert.Equal(t, "R0UT1NG", trigger.RoutingKey)
+	assert.Equal(t, "trigger", trigger.EventAction)
+	assert.Equal(t, "alert/ClusterUnhealthy/c1", trigger.DedupKey)
+	assert.Equal(t, "critical", trigger.Payload.Severity)
+	assert.Equal(t, "northstack", trigger.Payload.Source)
+	assert.Len(t, trigger.Payload.Summary, maxSummary)
+	assert.Equal(t, "https://console.example.com/clusters/c1", trigger.Links[0].Href)
+
+	resolve := (*events)[1]
+	assert.Equal(t, "resolve", resolve.EventAction)
+	assert.Equal(t, "alert/ClusterUnhealthy/c1", resolve.DedupKey)
+	assert.Nil(t, resolve.Payload)
+}
+
+func TestTriggerRejected(t *testing.T) {
+	server, _ := eventsAPI(t, http.StatusBadRequest)
+	c := testClient(server.URL)
+
+	err := c.Trigger(context.Background(), &escalation.Incident{DedupKey: "k", Summary: "s"})
+	require.Error(t, err)
+}
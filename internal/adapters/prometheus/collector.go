@@ -0,0 +1,281 @@
+// Package prometheus implements domain.MetricsCollector against the Prometheus HTTP API.
+// It works with a single Prometheus or a Thanos Query frontend; series are selected by
+// the service, project and cluster labels added by the platform's scrape configuration.
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// PromQL templates; %s is replaced with the label matcher for the queried entity
+const (
+	queryCPU          = `sum(rate(container_cpu_usage_seconds_total{%s}[5m]))`
+	queryMemory       = `sum(container_memory_working_set_bytes{%s})`
+	queryEgress       = `sum(rate(container_network_transmit_bytes_total{%s}[5m]))`
+	queryRequests     = `sum(rate(http_requests_total{%s}[5m]))`
+	queryErrors       = `sum(rate(http_requests_total{%s,status=~"5.."}[5m]))`
+	queryErrorRate    = `sum(rate(http_requests_total{%[1]s,status=~"5.."}[5m])) / sum(rate(http_requests_total{%[1]s}[5m]))`
+	queryLatency      = `histogram_quantile(%[2]s, sum by (le) (rate(http_request_duration_seconds_bucket{%[1]s}[5m])))`
+	queryReplicas     = `count(kube_pod_status_ready{condition="true",%s})`
+	queryPods         = `count(kube_pod_info{%s})`
+	queryDisk         = `sum(node_filesystem_size_bytes{%[1]s,mountpoint="/"}) - sum(node_filesystem_avail_bytes{%[1]s,mountpoint="/"})`
+	queryNodes        = `count(kube_node_info{%s})`
+	queryServiceCount = `count(count by (` + domain.MetricLabelServiceID + `) (container_memory_working_set_bytes{%s}))`
+)
+
+// defaultStep is used when the caller does not specify a resolution
+const defaultStep = 60
+
+// Collector queries Prometheus for platform metrics
+type Collector struct {
+	config     *config.PrometheusConfig
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewCollector creates a new Prometheus-backed MetricsCollector
+func NewCollector(cfg *config.PrometheusConfig, log *logger.Logger) *Collector {
+	return &Collector{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
+		},
+		logger: log,
+	}
+}
+
+// apiResponse is the Prometheus HTTP API envelope
+type apiResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]interface{}  `json:"values,omitempty"`
+			Value  [2]interface{}    `json:"value,omitempty"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// GetServiceMetrics retrieves metrics for a service
+func (c *Collector) GetServiceMetrics(ctx context.Context, serviceID uuid.UUID, timeRange domain.TimeRange) (*domain.ServiceMetrics, error) {
+	m := matcher(domain.MetricLabelServiceID, serviceID)
+	result := &domain.ServiceMetrics{ServiceID: serviceID}
+
+	queries := []struct {
+		expr string
+		dst  *[]domain.MetricPoint
+	}{
+		{fmt.Sprintf(queryCPU, m), &result.CPUUsage},
+		{fmt.Sprintf(queryMemory, m), &result.MemoryUsage},
+		{fmt.Sprintf(queryEgress, m), &result.NetworkEgress},
+		{fmt.Sprintf(queryRequests, m), &result.RequestCount},
+		{fmt.Sprintf(queryErrorRate, m), &result.ErrorRate},
+		{fmt.Sprintf(queryLatency, m, "0.5"), &result.Latency.P50},
+		{fmt.Sprintf(queryLatency, m, "0.9"), &result.Latency.P90},
+		{fmt.Sprintf(queryLatency, m, "0.99"), &result.Latency.P99},
+		{fmt.Sprintf(queryReplicas, m), &result.Replicas},
+	}
+	for _, q := range queries {
+		points, err := c.queryRange(ctx, q.expr, timeRange)
+		if err != nil {
+			return nil, err
+		}
+		*q.dst = points
+	}
+
+	return result, nil
+}
+
+// GetClusterMetrics retrieves metrics for a cluster
+func (c *Collector) GetClusterMetrics(ctx context.Context, clusterID uuid.UUID, timeRange domain.TimeRange) (*domain.ClusterMetrics, error) {
+	m := matcher(domain.MetricLabelClusterID, clusterID)
+	result := &domain.ClusterMetrics{ClusterID: clusterID}
+
+	queries := []struct {
+		expr string
+		dst  *[]domain.MetricPoint
+	}{
+		{fmt.Sprintf(queryCPU, m), &result.CPUUsage},
+		{fmt.Sprintf(queryMemory, m), &result.MemoryUsage},
+		{fmt.Sprintf(queryPods, m), &result.PodCount},
+		{fmt.Sprintf(queryDisk, m), &result.DiskUsage},
+	}
+	for _, q := range queries {
+		points, err := c.queryRange(ctx, q.expr, timeRange)
+		if err != nil {
+			return nil, err
+		}
+		*q.dst = points
+	}
+
+	nodes, err := c.query(ctx, fmt.Sprintf(queryNodes, m))
+	if err != nil {
+		return nil, err
+	}
+	result.NodeCount = int32(nodes)
+
+	return result, nil
+}
+
+// GetProjectMetrics retrieves aggregated metrics for a project
+func (c *Collector) GetProjectMetrics(ctx context.Context, projectID uuid.UUID, timeRange domain.TimeRange) (*domain.ProjectMetrics, error) {
+	m := matcher(domain.MetricLabelProjectID, projectID)
+	result := &domain.ProjectMetrics{ProjectID: projectID}
+
+	queries := []struct {
+		expr string
+		dst  *[]domain.MetricPoint
+	}{
+		{fmt.Sprintf(queryCPU, m), &result.TotalCPU},
+		{fmt.Sprintf(queryMemory, m), &result.TotalMemory},
+		{fmt.Sprintf(queryRequests, m), &result.TotalRequests},
+		{fmt.Sprintf(queryErrors, m), &result.TotalErrors},
+	}
+	for _, q := range queries {
+		points, err := c.queryRange(ctx, q.expr, timeRange)
+		if err != nil {
+			return nil, err
+		}
+		*q.dst = points
+	}
+
+	services, err := c.query(ctx, fmt.Sprintf(queryServiceCount, m))
+	if err != nil {
+		return nil, err
+	}
+	result.ServiceCount = int(services)
+
+	return result, nil
+}
+
+// queryRange runs a range query and returns the first series
+func (c *Collector) queryRange(ctx context.Context, expr string, timeRange domain.TimeRange) ([]domain.MetricPoint, error) {
+	step := timeRange.Step
+	if step <= 0 {
+		step = defaultStep
+	}
+
+	params := url.Values{}
+	params.Set("query", expr)
+	params.Set("start", strconv.FormatInt(timeRange.Start, 10))
+	params.Set("end", strconv.FormatInt(timeRange.End, 10))
+	params.Set("step", strconv.FormatInt(step, 10))
+
+	resp, err := c.do(ctx, "/api/v1/query_range", params)
+	if err != nil {
+		return nil, err
+	}
+
+	points := []domain.MetricPoint{}
+	if len(resp.Data.Result) == 0 {
+		return points, nil
+	}
+	for _, v := range resp.Data.Result[0].Values {
+		if p, ok := parseSample(v); ok {
+			points = append(points, p)
+		}
+	}
+	return points, nil
+}
+
+// Query runs an instant PromQL query and returns the value of its first
+// series, or 0 when it matches none
+func (c *Collector) Query(ctx context.Context, expr string) (float64, error) {
+	return c.query(ctx, expr)
+}
+
+// query runs an instant query and returns the scalar value of the first series
+func (c *Collector) query(ctx context.Context, expr string) (float64, error) {
+	params := url.Values{}
+	params.Set("query", expr)
+
+	resp, err := c.do(ctx, "/api/v1/query", params)
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Data.Result) == 0 {
+		return 0, nil
+	}
+
+	p, _ := parseSample(resp.Data.Result[0].Value)
+	return p.Value, nil
+}
+
+func (c *Collector) do(ctx context.Context, path string, params url.Values) (*apiResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.URL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build Prometheus request")
+	}
+	if c.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.BearerToken)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	httpResp, err := c.httpClient.Do(req)
+	metrics.ObserveAdapterCall("prometheus", http.MethodGet, start, httpResp, err)
+	if err != nil {
+		return nil, errors.DependencyFailed("prometheus", err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, errors.DependencyFailed("prometheus", err)
+	}
+
+	var resp apiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, errors.DependencyFailed("prometheus", fmt.Errorf("unexpected response (%d): %s", httpResp.StatusCode, body))
+	}
+	if resp.Status != "success" {
+		if httpResp.StatusCode == http.StatusBadRequest {
+			return nil, errors.BadRequest(resp.Error)
+		}
+		return nil, errors.DependencyFailed("prometheus", fmt.Errorf("%s: %s", resp.ErrorType, resp.Error))
+	}
+
+	return &resp, nil
+}
+
+// parseSample converts a [timestamp, "value"] pair into a MetricPoint
+func parseSample(sample [2]interface{}) (domain.MetricPoint, bool) {
+	ts, ok := sample[0].(float64)
+	if !ok {
+		return domain.MetricPoint{}, false
+	}
+	raw, ok := sample[1].(string)
+	if !ok {
+		return domain.MetricPoint{}, false
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	// NaN and Inf (e.g. error rate with no traffic) cannot be encoded as JSON
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return domain.MetricPoint{}, false
+	}
+	return domain.MetricPoint{Timestamp: int64(ts), Value: value}, true
+}
+
+func matcher(label string, id uuid.UUID) string {
+	return fmt.Sprintf(`%s="%s"`, label, id)
+}
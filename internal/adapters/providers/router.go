@@ -0,0 +1,231 @@
+// Package providers routes cluster management to the adapter of each
+// cluster's provider: EKS, GKE and AKS clusters provisioned through their
+// cloud APIs directly, and every other cluster through Rancher.
+package providers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// Direct is a cluster manager that provisions the clusters of one cloud
+// provider, whose external IDs all start with the same prefix
+type Direct struct {
+	Provider domain.ClusterProvider
+	Prefix   string
+	Adapter  domain.ClusterManagerAdapter
+}
+
+// aliases maps the provider names the clusters API accepts to the cloud
+// providers they provision on
+var aliases = map[domain.ClusterProvider]domain.ClusterProvider{
+	"eks": domain.ClusterProviderAWS,
+	"gke": domain.ClusterProviderGCP,
+	"aks": domain.ClusterProviderAzure,
+}
+
+// Router implements ClusterManagerAdapter over several cluster managers
+type Router struct {
+	direct  []Direct
+	rancher domain.ClusterManagerAdapter
+}
+
+// NewRouter creates a router; rancher, which manages every cluster no
+// direct adapter does, may be nil
+func NewRouter(rancher domain.ClusterManagerAdapter, direct ...Direct) *Router {
+	return &Router{direct: direct, rancher: rancher}
+}
+
+// CreateCluster provisions a cluster with the adapter of its provider
+func (r *Router) CreateCluster(ctx context.Context, cluster *domain.Cluster) (string, error) {
+	provider := cluster.Provider
+	if cloud, ok := aliases[provider]; ok {
+		provider = cloud
+	}
+	for _, d := range r.direct {
+		if d.Provider == provider {
+			return d.Adapter.CreateCluster(ctx, cluster)
+		}
+	}
+	if r.rancher == nil {
+		return "", errors.BadRequest(fmt.Sprintf("no cluster manager provisions %s clusters", cluster.Provider))
+	}
+	return r.rancher.CreateCluster(ctx, cluster)
+}
+
+// GetCluster retrieves cluster information
+func (r *Router) GetCluster(ctx context.Context, externalID string) (*domain.Cluster, error) {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return nil, err
+	}
+	return a.GetCluster(ctx, externalID)
+}
+
+// UpdateCluster updates cluster configuration
+func (r *Router) UpdateCluster(ctx context.Context, cluster *domain.Cluster) error {
+	a, err := r.adapter(cluster.RancherClusterID)
+	if err != nil {
+		return err
+	}
+	return a.UpdateCluster(ctx, cluster)
+}
+
+// DeleteCluster deprovisions a cluster
+func (r *Router) DeleteCluster(ctx context.Context, externalID string) error {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return err
+	}
+	return a.DeleteCluster(ctx, externalID)
+}
+
+// GetKubeConfig retrieves the kubeconfig for a cluster
+func (r *Router) GetKubeConfig(ctx context.Context, externalID string) ([]byte, error) {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return nil, err
+	}
+	return a.GetKubeConfig(ctx, externalID)
+}
+
+// ListClusters lists the clusters of every adapter
+func (r *Router) ListClusters(ctx context.Context) ([]*domain.Cluster, error) {
+	var clusters []*domain.Cluster
+	for _, a := range r.adapters() {
+		listed, err := a.ListClusters(ctx)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, listed...)
+	}
+	return clusters, nil
+}
+
+// GetClusterHealth retrieves health status of a cluster
+func (r *Router) GetClusterHealth(ctx context.Context, externalID string) (*domain.ClusterHealth, error) {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return nil, err
+	}
+	return a.GetClusterHealth(ctx, externalID)
+}
+
+// CreateNamespace creates a namespace on a cluster
+func (r *Router) CreateNamespace(ctx context.Context, externalID, namespace string, labels map[string]string) error {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return err
+	}
+	return a.CreateNamespace(ctx, externalID, namespace, labels)
+}
+
+// DeleteNamespace deletes a namespace from a cluster
+func (r *Router) DeleteNamespace(ctx context.Context, externalID, namespace string) error {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return err
+	}
+	return a.DeleteNamespace(ctx, externalID, namespace)
+}
+
+// ListNodePools lists the node pools of a cluster
+func (r *Router) ListNodePools(ctx context.Context, externalID string) ([]*domain.NodePool, error) {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return nil, err
+	}
+	return a.ListNodePools(ctx, externalID)
+}
+
+// CreateNodePool adds a node pool to a cluster
+func (r *Router) CreateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return err
+	}
+	return a.CreateNodePool(ctx, externalID, pool)
+}
+
+// UpdateNodePool resizes a node pool or changes its instance type
+func (r *Router) UpdateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return err
+	}
+	return a.UpdateNodePool(ctx, externalID, pool)
+}
+
+// DeleteNodePool removes a node pool from a cluster
+func (r *Router) DeleteNodePool(ctx context.Context, externalID, name string) error {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return err
+	}
+	return a.DeleteNodePool(ctx, externalID, name)
+}
+
+// ListKubernetesVersions lists the Kubernetes versions a cluster can be
+// upgraded to
+func (r *Router) ListKubernetesVersions(ctx context.Context, externalID string) ([]string, error) {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return nil, err
+	}
+	return a.ListKubernetesVersions(ctx, externalID)
+}
+
+// GetClusterCapacity returns the capacity of a cluster
+func (r *Router) GetClusterCapacity(ctx context.Context, externalID string) (*domain.ClusterCapacity, error) {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return nil, err
+	}
+	return a.GetClusterCapacity(ctx, externalID)
+}
+
+// IssueKubeConfig returns a kubeconfig carrying only a grant's access
+func (r *Router) IssueKubeConfig(ctx context.Context, externalID string, grant *domain.KubeConfigGrant) (*domain.IssuedKubeConfig, error) {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return nil, err
+	}
+	return a.IssueKubeConfig(ctx, externalID, grant)
+}
+
+// RevokeKubeConfig invalidates the credentials of a kubeconfig
+func (r *Router) RevokeKubeConfig(ctx context.Context, externalID string, kubeconfig []byte) error {
+	a, err := r.adapter(externalID)
+	if err != nil {
+		return err
+	}
+	return a.RevokeKubeConfig(ctx, externalID, kubeconfig)
+}
+
+// adapter returns the adapter that manages the cluster with an external ID
+func (r *Router) adapter(externalID string) (domain.ClusterManagerAdapter, error) {
+	for _, d := range r.direct {
+		if strings.HasPrefix(externalID, d.Prefix) {
+			return d.Adapter, nil
+		}
+	}
+	if r.rancher == nil {
+		return nil, errors.NotFound("cluster", externalID)
+	}
+	return r.rancher, nil
+}
+
+func (r *Router) adapters() []domain.ClusterManagerAdapter {
+	adapters := make([]domain.ClusterManagerAdapter, 0, len(r.direct)+1)
+	for _, d := range r.direct {
+		adapters = append(adapters, d.Adapter)
+	}
+	if r.rancher != nil {
+		adapters = append(adapters, r.rancher)
+	}
+	return adapters
+}
@@ -0,0 +1,72 @@
+package providers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeManager records the clusters it is asked about
+type fakeManager struct {
+	domain.ClusterManagerAdapter
+	name    string
+	created []string
+	read    []string
+}
+
+func (f *fakeManager) CreateCluster(_ context.Context, cluster *domain.Cluster) (string, error) {
+	f.created = append(f.created, cluster.Slug)
+	return f.name + ":" + cluster.Slug, nil
+}
+
+func (f *fakeManager) GetCluster(_ context.Context, externalID string) (*domain.Cluster, error) {
+	f.read = append(f.read, externalID)
+	return &domain.Cluster{RancherClusterID: externalID}, nil
+}
+
+func (f *fakeManager) ListClusters(context.Context) ([]*domain.Cluster, error) {
+	return []*domain.Cluster{{Name: f.name}}, nil
+}
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	eks := &fakeManager{name: "eks"}
+	rancher := &fakeManager{name: "c-rancher"}
+	router := NewRouter(rancher, Direct{Provider: domain.ClusterProviderAWS, Prefix: "eks:", Adapter: eks})
+
+	id, err := router.CreateCluster(ctx, &domain.Cluster{Slug: "prod", Provider: domain.ClusterProviderAWS})
+	require.NoError(t, err)
+	assert.Equal(t, "eks:prod", id)
+	_, err = router.CreateCluster(ctx, &domain.Cluster{Slug: "staging", Provider: "eks"})
+	require.NoError(t, err)
+	_, err = router.CreateCluster(ctx, &domain.Cluster{Slug: "edge", Provider: domain.ClusterProviderRKE2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"prod", "staging"}, eks.created)
+	assert.Equal(t, []string{"edge"}, rancher.created)
+
+	_, err = router.GetCluster(ctx, "eks:us-east-1:prod")
+	require.NoError(t, err)
+	_, err = router.GetCluster(ctx, "c-x8k2p")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eks:us-east-1:prod"}, eks.read)
+	assert.Equal(t, []string{"c-x8k2p"}, rancher.read)
+
+	clusters, err := router.ListClusters(ctx)
+	require.NoError(t, err)
+	assert.Len(t, clusters, 2)
+}
+
+func TestRouterWithoutRancher(t *testing.T) {
+	ctx := context.Background()
+	router := NewRouter(nil, Direct{Provider: domain.ClusterProviderAWS, Prefix: "eks:", Adapter: &fakeManager{name: "eks"}})
+
+	_, err := router.CreateCluster(ctx, &domain.Cluster{Slug: "edge", Provider: domain.ClusterProviderGCP})
+	assert.Error(t, err)
+
+	_, err = router.GetCluster(ctx, "c-x8k2p")
+	assert.True(t, errors.IsNotFound(err))
+}
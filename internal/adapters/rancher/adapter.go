@@ -10,10 +10,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/northstack/platform/internal/config"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
 	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
 )
@@ -37,7 +42,7 @@ func NewAdapter(cfg *config.RancherConfig, log *logger.Logger) *Adapter {
 		config: cfg,
 		httpClient: &http.Client{
 			Timeout:   cfg.Timeout,
-			Transport: transport,
+			Transport: tracing.Transport(transport),
 		},
 		logger: log,
 	}
@@ -55,6 +60,7 @@ type rancherCluster struct {
 	Transitioning            string                 `json:"transitioning,omitempty"`
 	TransitioningMessage     string                 `json:"transitioningMessage,omitempty"`
 	APIEndpoint              string                 `json:"apiEndpoint,omitempty"`
+	CACert                   string                 `json:"caCert,omitempty"` // Base64 PEM of the API server's CA
 	Labels                   map[string]string      `json:"labels,omitempty"`
 	Annotations              map[string]string      `json:"annotations,omitempty"`
 	RancherKubernetesEngineConfig *rkeConfig        `json:"rancherKubernetesEngineConfig,omitempty"`
@@ -91,6 +97,7 @@ type eksConfig struct {
 	Region                  string   `json:"region"`
 	KubernetesVersion       string   `json:"kubernetesVersion,omitempty"`
 	NodeGroups              []nodeGroup `json:"nodeGroups,omitempty"`
+	AmazonCredentialSecret  string   `json:"amazonCredentialSecret,omitempty"`
 	VPC                     string   `json:"vpc,omitempty"`
 	Subnets                 []string `json:"subnets,omitempty"`
 }
@@ -101,6 +108,8 @@ type gkeConfig struct {
 	Region                  string   `json:"region"`
 	KubernetesVersion       string   `json:"kubernetesVersion,omitempty"`
 	NodePools               []nodePool `json:"nodePools,omitempty"`
+	GoogleCredentialSecret  string   `json:"googleCredentialSecret,omitempty"`
+	ProjectID               string   `json:"projectID,omitempty"`
 	Network                 string   `json:"network,omitempty"`
 	Subnetwork              string   `json:"subnetwork,omitempty"`
 }
@@ -110,6 +119,7 @@ type aksConfig struct {
 	Location                string   `json:"location"`
 	KubernetesVersion       string   `json:"kubernetesVersion,omitempty"`
 	NodePools               []nodePool `json:"nodePools,omitempty"`
+	AzureCredentialSecret   string   `json:"azureCredentialSecret,omitempty"`
 	ResourceGroup           string   `json:"resourceGroup,omitempty"`
 	VirtualNetwork          string   `json:"virtualNetwork,omitempty"`
 }
@@ -121,6 +131,7 @@ type nodeGroup struct {
 	DesiredSize  int32  `json:"desiredSize,omitempty"`
 	MinSize      int32  `json:"minSize,omitempty"`
 	MaxSize      int32  `json:"maxSize,omitempty"`
+	Version      string `json:"version,omitempty"`
 }
 
 // nodePool represents a node pool for GKE/AKS
@@ -131,6 +142,8 @@ type nodePool struct {
 	MinNodeCount      int32  `json:"minNodeCount,omitempty"`
 	MaxNodeCount      int32  `json:"maxNodeCount,omitempty"`
 	AutoScaling       bool   `json:"autoScaling,omitempty"`
+	Version           string `json:"version,omitempty"`             // GKE
+	OrchestratorVersion string `json:"orchestratorVersion,omitempty"` // AKS
 }
 
 // clusterCondition represents a cluster condition
@@ -197,23 +210,23 @@ func (a *Adapter) GetCluster(ctx context.Context, externalID string) (*domain.Cl
 	return a.rancherToDomain(&rCluster), nil
 }
 
-// UpdateCluster updates cluster configuration
+// UpdateCluster updates a cluster's name, labels and Kubernetes version, and
+// resizes clusters with a single node pool. The node pools of the cluster are
+// otherwise kept.
 func (a *Adapter) UpdateCluster(ctx context.Context, cluster *domain.Cluster) error {
-	rCluster := a.domainToRancher(cluster)
-
-	body, err := json.Marshal(rCluster)
+	rCluster, err := a.getRancherCluster(ctx, cluster.RancherClusterID)
 	if err != nil {
-		return errors.Wrap(err, "failed to marshal cluster")
+		return err
 	}
-
-	resp, err := a.doRequest(ctx, "PUT", fmt.Sprintf("/v3/clusters/%s", cluster.RancherClusterID), body)
-	if err != nil {
-		return errors.DependencyFailed("rancher", err)
+	rCluster.Name = cluster.Name
+	rCluster.Labels = cluster.Labels
+	setKubernetesVersion(rCluster, cluster.KubeVersion)
+	if err := a.resize(ctx, rCluster, cluster.NodeCount); err != nil {
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return a.handleError(resp)
+	if err := a.putRancherCluster(ctx, rCluster); err != nil {
+		return err
 	}
 
 	a.logger.Info().
@@ -269,6 +282,59 @@ func (a *Adapter) GetKubeConfig(ctx context.Context, externalID string) ([]byte,
 	return []byte(result.Config), nil
 }
 
+// RegisterCluster creates an imported cluster for one provisioned outside
+// Rancher and returns its ID and the URL of the agent manifest that, applied
+// to the cluster, connects it to Rancher
+func (a *Adapter) RegisterCluster(ctx context.Context, cluster *domain.Cluster) (string, string, error) {
+	body, err := json.Marshal(rancherCluster{
+		Name:        cluster.Slug,
+		Description: fmt.Sprintf("Managed by OpenPaaS - %s", cluster.Slug),
+		Labels:      cluster.Labels,
+	})
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to marshal cluster")
+	}
+
+	resp, err := a.doRequest(ctx, "POST", "/v3/clusters", body)
+	if err != nil {
+		return "", "", errors.DependencyFailed("rancher", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", "", a.handleError(resp)
+	}
+	var created rancherCluster
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", "", errors.Wrap(err, "failed to decode response")
+	}
+
+	body, _ = json.Marshal(map[string]string{"type": "clusterRegistrationToken", "clusterId": created.ID})
+	tokenResp, err := a.doRequest(ctx, "POST", "/v3/clusterregistrationtokens", body)
+	if err != nil {
+		return "", "", errors.DependencyFailed("rancher", err)
+	}
+	defer tokenResp.Body.Close()
+	if tokenResp.StatusCode != http.StatusOK && tokenResp.StatusCode != http.StatusCreated {
+		return "", "", a.handleError(tokenResp)
+	}
+	var token struct {
+		ManifestURL string `json:"manifestUrl"`
+	}
+	if err := json.NewDecoder(tokenResp.Body).Decode(&token); err != nil {
+		return "", "", errors.Wrap(err, "failed to decode response")
+	}
+	if token.ManifestURL == "" {
+		return "", "", errors.DependencyFailed("rancher", fmt.Errorf("registration token of cluster %s has no manifest URL", created.ID))
+	}
+
+	a.logger.Info().
+		Str("cluster_id", created.ID).
+		Str("cluster_name", cluster.Name).
+		Msg("Registered cluster in Rancher")
+
+	return created.ID, token.ManifestURL, nil
+}
+
 // ListClusters lists all managed clusters
 func (a *Adapter) ListClusters(ctx context.Context) ([]*domain.Cluster, error) {
 	resp, err := a.doRequest(ctx, "GET", "/v3/clusters", nil)
@@ -319,16 +385,25 @@ func (a *Adapter) GetClusterHealth(ctx context.Context, externalID string) (*dom
 		Conditions       []clusterCondition `json:"conditions"`
 		Allocatable      map[string]string  `json:"allocatable"`
 		Requested        map[string]string  `json:"requested"`
+		RKEConfig        *struct {
+			Services struct {
+				KubeAPI struct {
+					ServiceClusterIPRange string `json:"serviceClusterIpRange"`
+				} `json:"kubeApi"`
+			} `json:"services"`
+		} `json:"rancherKubernetesEngineConfig"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&rCluster); err != nil {
 		return nil, errors.Wrap(err, "failed to decode response")
 	}
 
 	health := &domain.ClusterHealth{
-		Status:     mapRancherClusterStatus(rCluster.State),
-		NodeCount:  rCluster.NodeCount,
-		ReadyNodes: rCluster.NodeCount, // Simplified; would need more API calls for accurate count
-		Conditions: make([]domain.ClusterCondition, len(rCluster.Conditions)),
+		Status:      mapRancherClusterStatus(rCluster.State),
+		NodeCount:   rCluster.NodeCount,
+		ReadyNodes:  rCluster.NodeCount, // Simplified; would need more API calls for accurate count
+		CPUUsage:    requestedFraction(rCluster.Requested["cpu"], rCluster.Allocatable["cpu"]),
+		MemoryUsage: requestedFraction(rCluster.Requested["memory"], rCluster.Allocatable["memory"]),
+		Conditions:  make([]domain.ClusterCondition, len(rCluster.Conditions)),
 	}
 
 	for i, c := range rCluster.Conditions {
@@ -339,9 +414,101 @@ func (a *Adapter) GetClusterHealth(ctx context.Context, externalID string) (*dom
 		}
 	}
 
+	if rCluster.RKEConfig != nil {
+		health.IPFamilies = serviceIPFamilies(rCluster.RKEConfig.Services.KubeAPI.ServiceClusterIPRange)
+	}
+
 	return health, nil
 }
 
+// requestedFraction returns the fraction of an allocatable resource quantity
+// that pods request, or 0 when either quantity is unknown
+func requestedFraction(requested, allocatable string) float64 {
+	alloc := quantity(allocatable)
+	if alloc == 0 {
+		return 0
+	}
+	return quantity(requested) / alloc
+}
+
+// serviceIPFamilies returns the IP families of a comma-separated list of
+// service CIDRs; a dual-stack cluster has one CIDR per family
+func serviceIPFamilies(cidrs string) []domain.IPFamily {
+	var families []domain.IPFamily
+	for _, cidr := range strings.Split(cidrs, ",") {
+		ip, _, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			continue
+		}
+		family := domain.IPFamilyIPv6
+		if ip.To4() != nil {
+			family = domain.IPFamilyIPv4
+		}
+		if len(families) == 0 || families[0] != family {
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+// CreateNamespace creates a namespace on a downstream cluster through the
+// Rancher Kubernetes API proxy
+func (a *Adapter) CreateNamespace(ctx context.Context, externalID, namespace string, labels map[string]string) error {
+	payload := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata": map[string]interface{}{
+			"name":   namespace,
+			"labels": labels,
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal namespace")
+	}
+
+	resp, err := a.doRequest(ctx, "POST", fmt.Sprintf("/k8s/clusters/%s/api/v1/namespaces", externalID), body)
+	if err != nil {
+		return errors.DependencyFailed("rancher", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusConflict:
+	default:
+		return a.handleError(resp)
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("namespace", namespace).
+		Msg("Created namespace")
+
+	return nil
+}
+
+// DeleteNamespace deletes a namespace from a downstream cluster
+func (a *Adapter) DeleteNamespace(ctx context.Context, externalID, namespace string) error {
+	resp, err := a.doRequest(ctx, "DELETE", fmt.Sprintf("/k8s/clusters/%s/api/v1/namespaces/%s", externalID, namespace), nil)
+	if err != nil {
+		return errors.DependencyFailed("rancher", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNotFound:
+	default:
+		return a.handleError(resp)
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("namespace", namespace).
+		Msg("Deleted namespace")
+
+	return nil
+}
+
 // domainToRancher converts a domain cluster to Rancher format
 func (a *Adapter) domainToRancher(cluster *domain.Cluster) *rancherCluster {
 	rc := &rancherCluster{
@@ -439,7 +606,16 @@ func (a *Adapter) rancherToDomain(rc *rancherCluster) *domain.Cluster {
 
 // doRequest performs an HTTP request to the Rancher API
 func (a *Adapter) doRequest(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	return a.doRequestAs(ctx, method, path, "", body)
+}
+
+// doRequestAs performs an HTTP request with a body of the given content type,
+// JSON when empty
+func (a *Adapter) doRequestAs(ctx context.Context, method, path, contentType string, body []byte) (*http.Response, error) {
 	url := a.config.URL + path
+	if contentType == "" {
+		contentType = "application/json"
+	}
 
 	var bodyReader io.Reader
 	if body != nil {
@@ -458,10 +634,13 @@ func (a *Adapter) doRequest(ctx context.Context, method, path string, body []byt
 		req.SetBasicAuth(a.config.AccessKey, a.config.SecretKey)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("Accept", "application/json")
 
-	return a.httpClient.Do(req)
+	start := time.Now()
+	resp, err := a.httpClient.Do(req)
+	metrics.ObserveAdapterCall("rancher", method, start, resp, err)
+	return resp, err
 }
 
 // handleError extracts error information from a response
@@ -0,0 +1,96 @@
+package rancher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/northstack/platform/internal/domain"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// GetClusterCapacity returns the allocatable and requested CPU, memory and
+// pods Rancher reports for a cluster, and the requests of the running pods
+// of each namespace
+func (a *Adapter) GetClusterCapacity(ctx context.Context, externalID string) (*domain.ClusterCapacity, error) {
+	var rc struct {
+		Allocatable map[string]string `json:"allocatable"`
+		Requested   map[string]string `json:"requested"`
+	}
+	if err := a.kubeRequest(ctx, "GET", fmt.Sprintf("/v3/clusters/%s", externalID), "", nil, &rc); err != nil {
+		return nil, err
+	}
+
+	capacity := &domain.ClusterCapacity{
+		CPU:    resourceCapacity(rc.Allocatable["cpu"], rc.Requested["cpu"]),
+		Memory: resourceCapacity(rc.Allocatable["memory"], rc.Requested["memory"]),
+		Pods:   resourceCapacity(rc.Allocatable["pods"], rc.Requested["pods"]),
+	}
+
+	namespaces, err := a.namespaceAllocations(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	capacity.Namespaces = namespaces
+	return capacity, nil
+}
+
+// namespaceAllocations sums the pods and container requests of the pods that
+// have not finished, per namespace
+func (a *Adapter) namespaceAllocations(ctx context.Context, externalID string) ([]domain.NamespaceAllocation, error) {
+	type requests struct {
+		Resources struct {
+			Requests map[string]string `json:"requests"`
+		} `json:"resources"`
+	}
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				Containers []requests `json:"containers"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	selector := url.QueryEscape("status.phase!=Succeeded,status.phase!=Failed")
+	path := fmt.Sprintf("/k8s/clusters/%s/api/v1/pods?fieldSelector=%s", externalID, selector)
+	if err := a.kubeRequest(ctx, "GET", path, "", nil, &list); err != nil {
+		return nil, err
+	}
+
+	byNamespace := make(map[string]*domain.NamespaceAllocation)
+	for _, pod := range list.Items {
+		ns := byNamespace[pod.Metadata.Namespace]
+		if ns == nil {
+			ns = &domain.NamespaceAllocation{Namespace: pod.Metadata.Namespace}
+			byNamespace[pod.Metadata.Namespace] = ns
+		}
+		ns.Pods++
+		for _, c := range pod.Spec.Containers {
+			ns.CPURequest += quantity(c.Resources.Requests["cpu"])
+			ns.MemoryRequest += quantity(c.Resources.Requests["memory"])
+		}
+	}
+
+	allocations := make([]domain.NamespaceAllocation, 0, len(byNamespace))
+	for _, ns := range byNamespace {
+		allocations = append(allocations, *ns)
+	}
+	sort.Slice(allocations, func(i, j int) bool { return allocations[i].Namespace < allocations[j].Namespace })
+	return allocations, nil
+}
+
+func resourceCapacity(allocatable, requested string) domain.ResourceCapacity {
+	return domain.ResourceCapacity{Allocatable: quantity(allocatable), Requested: quantity(requested)}
+}
+
+// quantity parses a Kubernetes resource quantity, or returns 0
+func quantity(s string) float64 {
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return 0
+	}
+	return q.AsApproximateFloat64()
+}
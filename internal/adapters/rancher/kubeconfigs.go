@@ -0,0 +1,279 @@
+package rancher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// accessNamespace holds the service accounts of issued kubeconfigs
+const accessNamespace = "northstack-access"
+
+// labelAccessSubject marks the bindings of an issued kubeconfig's subject
+const labelAccessSubject = "northstack.io/access-subject"
+
+// kubeConfig is the part of a kubeconfig file the adapter reads and writes
+type kubeConfig struct {
+	APIVersion     string             `json:"apiVersion"`
+	Kind           string             `json:"kind"`
+	Clusters       []namedKubeCluster `json:"clusters"`
+	Users          []namedKubeUser    `json:"users"`
+	Contexts       []namedKubeContext `json:"contexts"`
+	CurrentContext string             `json:"current-context"`
+}
+
+type namedKubeCluster struct {
+	Name    string `json:"name"`
+	Cluster struct {
+		Server                   string `json:"server"`
+		CertificateAuthorityData string `json:"certificate-authority-data,omitempty"`
+	} `json:"cluster"`
+}
+
+type namedKubeUser struct {
+	Name string `json:"name"`
+	User struct {
+		Token string `json:"token,omitempty"`
+	} `json:"user"`
+}
+
+type namedKubeContext struct {
+	Name    string `json:"name"`
+	Context struct {
+		Cluster   string `json:"cluster"`
+		User      string `json:"user"`
+		Namespace string `json:"namespace,omitempty"`
+	} `json:"context"`
+}
+
+// IssueKubeConfig returns a kubeconfig for a service account of the cluster
+// bound to the grant's cluster role, with a token from the TokenRequest API
+// that expires after the grant's TTL. The kubeconfig talks to the cluster's
+// API server directly, as Rancher's proxy only accepts Rancher tokens.
+// Bindings of the subject the grant no longer covers are removed.
+func (a *Adapter) IssueKubeConfig(ctx context.Context, externalID string, grant *domain.KubeConfigGrant) (*domain.IssuedKubeConfig, error) {
+	rc, err := a.getRancherCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	if rc.APIEndpoint == "" {
+		return nil, errors.BadRequest("the cluster's API endpoint is not known yet")
+	}
+
+	base := fmt.Sprintf("/k8s/clusters/%s", externalID)
+	labels := map[string]string{labelAccessSubject: grant.Subject}
+	if err := a.kubeCreate(ctx, base+"/api/v1/namespaces", map[string]interface{}{
+		"metadata": map[string]interface{}{"name": accessNamespace},
+	}); err != nil {
+		return nil, err
+	}
+	if err := a.kubeCreate(ctx, base+"/api/v1/namespaces/"+accessNamespace+"/serviceaccounts", map[string]interface{}{
+		"metadata": map[string]interface{}{"name": grant.Subject, "labels": labels},
+	}); err != nil {
+		return nil, err
+	}
+	if err := a.bindSubject(ctx, base, grant); err != nil {
+		return nil, err
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "authentication.k8s.io/v1",
+		"kind":       "TokenRequest",
+		"spec":       map[string]interface{}{"expirationSeconds": int64(grant.TTL / time.Second)},
+	})
+	var token struct {
+		Status struct {
+			Token               string    `json:"token"`
+			ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+		} `json:"status"`
+	}
+	path := fmt.Sprintf("%s/api/v1/namespaces/%s/serviceaccounts/%s/token", base, accessNamespace, grant.Subject)
+	if err := a.kubeRequest(ctx, "POST", path, "", body, &token); err != nil {
+		return nil, err
+	}
+
+	name := rc.Name
+	var config kubeConfig
+	config.APIVersion = "v1"
+	config.Kind = "Config"
+	config.Clusters = []namedKubeCluster{{Name: name}}
+	config.Clusters[0].Cluster.Server = rc.APIEndpoint
+	config.Clusters[0].Cluster.CertificateAuthorityData = rc.CACert
+	config.Users = []namedKubeUser{{Name: grant.Subject}}
+	config.Users[0].User.Token = token.Status.Token
+	config.Contexts = []namedKubeContext{{Name: name}}
+	config.Contexts[0].Context.Cluster = name
+	config.Contexts[0].Context.User = grant.Subject
+	if len(grant.Namespaces) > 0 {
+		config.Contexts[0].Context.Namespace = grant.Namespaces[0]
+	}
+	config.CurrentContext = name
+
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render kubeconfig")
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("subject", grant.Subject).
+		Str("cluster_role", grant.ClusterRole).
+		Int("namespaces", len(grant.Namespaces)).
+		Msg("Issued kubeconfig")
+	return &domain.IssuedKubeConfig{KubeConfig: data, ExpiresAt: token.Status.ExpirationTimestamp}, nil
+}
+
+// bindSubject binds the grant's cluster role to its service account in each
+// of its namespaces, or cluster-wide, and removes the subject's other bindings
+func (a *Adapter) bindSubject(ctx context.Context, base string, grant *domain.KubeConfigGrant) error {
+	rbac := base + "/apis/rbac.authorization.k8s.io/v1"
+	bindingName := "northstack-access-" + grant.Subject
+	selector := "?labelSelector=" + url.QueryEscape(labelAccessSubject+"="+grant.Subject)
+
+	type binding struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		RoleRef struct {
+			Name string `json:"name"`
+		} `json:"roleRef"`
+	}
+	var roleBindings, clusterBindings struct {
+		Items []binding `json:"items"`
+	}
+	if err := a.kubeRequest(ctx, "GET", rbac+"/rolebindings"+selector, "", nil, &roleBindings); err != nil {
+		return err
+	}
+	if err := a.kubeRequest(ctx, "GET", rbac+"/clusterrolebindings"+selector, "", nil, &clusterBindings); err != nil {
+		return err
+	}
+
+	wanted := make(map[string]bool, len(grant.Namespaces))
+	for _, ns := range grant.Namespaces {
+		wanted[ns] = true
+	}
+	clusterWide := len(grant.Namespaces) == 0
+
+	// Role references cannot change, so outdated bindings are replaced
+	existing := make(map[string]bool)
+	for _, b := range roleBindings.Items {
+		if wanted[b.Metadata.Namespace] && b.RoleRef.Name == grant.ClusterRole {
+			existing[b.Metadata.Namespace] = true
+			continue
+		}
+		if err := a.kubeDelete(ctx, fmt.Sprintf("%s/namespaces/%s/rolebindings/%s", rbac, b.Metadata.Namespace, b.Metadata.Name)); err != nil {
+			return err
+		}
+	}
+	hasClusterBinding := false
+	for _, b := range clusterBindings.Items {
+		if clusterWide && b.RoleRef.Name == grant.ClusterRole {
+			hasClusterBinding = true
+			continue
+		}
+		if err := a.kubeDelete(ctx, fmt.Sprintf("%s/clusterrolebindings/%s", rbac, b.Metadata.Name)); err != nil {
+			return err
+		}
+	}
+
+	newBinding := func(kind, namespace string) map[string]interface{} {
+		metadata := map[string]interface{}{"name": bindingName, "labels": map[string]string{labelAccessSubject: grant.Subject}}
+		if namespace != "" {
+			metadata["namespace"] = namespace
+		}
+		return map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       kind,
+			"metadata":   metadata,
+			"roleRef":    map[string]string{"apiGroup": "rbac.authorization.k8s.io", "kind": "ClusterRole", "name": grant.ClusterRole},
+			"subjects":   []map[string]string{{"kind": "ServiceAccount", "name": grant.Subject, "namespace": accessNamespace}},
+		}
+	}
+	if clusterWide && !hasClusterBinding {
+		if err := a.kubeCreate(ctx, rbac+"/clusterrolebindings", newBinding("ClusterRoleBinding", "")); err != nil {
+			return err
+		}
+	}
+	for _, ns := range grant.Namespaces {
+		if existing[ns] {
+			continue
+		}
+		if err := a.kubeCreate(ctx, fmt.Sprintf("%s/namespaces/%s/rolebindings", rbac, ns), newBinding("RoleBinding", ns)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevokeKubeConfig deletes the Rancher token of a kubeconfig generated by
+// GetKubeConfig. Tokens that are already gone are ignored.
+func (a *Adapter) RevokeKubeConfig(ctx context.Context, externalID string, kubeconfig []byte) error {
+	var config kubeConfig
+	if err := yaml.Unmarshal(kubeconfig, &config); err != nil {
+		return errors.Wrap(err, "failed to parse kubeconfig")
+	}
+
+	for _, user := range config.Users {
+		// Rancher tokens are <name>:<secret>
+		name, _, ok := strings.Cut(user.User.Token, ":")
+		if !ok || name == "" {
+			continue
+		}
+		resp, err := a.doRequest(ctx, "DELETE", "/v3/tokens/"+url.PathEscape(name), nil)
+		if err != nil {
+			return errors.DependencyFailed("rancher", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+			return errors.Internal(fmt.Sprintf("failed to revoke Rancher token %s (%d)", name, resp.StatusCode))
+		}
+		a.logger.Info().Str("external_id", externalID).Str("token", name).Msg("Revoked kubeconfig token")
+	}
+	return nil
+}
+
+// kubeCreate creates an object in a downstream cluster; objects that already
+// exist are left as they are
+func (a *Adapter) kubeCreate(ctx context.Context, path string, obj interface{}) error {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal object")
+	}
+	resp, err := a.doRequest(ctx, "POST", path, body)
+	if err != nil {
+		return errors.DependencyFailed("rancher", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusConflict:
+		return nil
+	default:
+		return a.handleError(resp)
+	}
+}
+
+// kubeDelete deletes an object of a downstream cluster, if it exists
+func (a *Adapter) kubeDelete(ctx context.Context, path string) error {
+	resp, err := a.doRequest(ctx, "DELETE", path, nil)
+	if err != nil {
+		return errors.DependencyFailed("rancher", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNotFound:
+		return nil
+	default:
+		return a.handleError(resp)
+	}
+}
@@ -0,0 +1,529 @@
+package rancher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// Node labels naming the pool of a node, per hosted provider
+const (
+	labelEKSNodeGroup = "eks.amazonaws.com/nodegroup"
+	labelGKENodePool  = "cloud.google.com/gke-nodepool"
+	labelAKSAgentPool = "kubernetes.azure.com/agentpool"
+)
+
+// drainTimeout bounds how long the pods of removed nodes get to be evicted
+const drainTimeout = 10 * time.Minute
+
+// drainPollInterval is the wait between eviction passes
+var drainPollInterval = 5 * time.Second
+
+// ListNodePools lists the node pools of a hosted cluster
+func (a *Adapter) ListNodePools(ctx context.Context, externalID string) ([]*domain.NodePool, error) {
+	rc, err := a.getRancherCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	return nodePools(rc)
+}
+
+// CreateNodePool adds a node pool to a hosted cluster
+func (a *Adapter) CreateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	rc, err := a.getRancherCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	pools, err := nodePools(rc)
+	if err != nil {
+		return err
+	}
+	if findPool(pools, pool.Name) != nil {
+		return errors.Conflict("node pool " + pool.Name)
+	}
+
+	if err := setNodePools(rc, append(pools, pool)); err != nil {
+		return err
+	}
+	if err := a.putRancherCluster(ctx, rc); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", pool.Name).
+		Int("nodes", int(pool.NodeCount)).
+		Msg("Created node pool")
+	return nil
+}
+
+// UpdateNodePool changes a node pool. When it shrinks, its newest surplus
+// nodes are cordoned and drained first; the provider's scale-in usually
+// removes those.
+func (a *Adapter) UpdateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	rc, err := a.getRancherCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	pools, err := nodePools(rc)
+	if err != nil {
+		return err
+	}
+	current := findPool(pools, pool.Name)
+	if current == nil {
+		return errors.NotFound("node pool", pool.Name)
+	}
+
+	if surplus := int(current.NodeCount - pool.NodeCount); surplus > 0 {
+		if err := a.drainPool(ctx, externalID, poolLabel(rc), pool.Name, surplus); err != nil {
+			return err
+		}
+	}
+
+	*current = *pool
+	if err := setNodePools(rc, pools); err != nil {
+		return err
+	}
+	if err := a.putRancherCluster(ctx, rc); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", pool.Name).
+		Int("nodes", int(pool.NodeCount)).
+		Str("instance_type", pool.InstanceType).
+		Msg("Updated node pool")
+	return nil
+}
+
+// DeleteNodePool drains every node of a pool, then removes it. A cluster's
+// last pool cannot be removed.
+func (a *Adapter) DeleteNodePool(ctx context.Context, externalID, name string) error {
+	rc, err := a.getRancherCluster(ctx, externalID)
+	if err != nil {
+		return err
+	}
+	pools, err := nodePools(rc)
+	if err != nil {
+		return err
+	}
+	if findPool(pools, name) == nil {
+		return errors.NotFound("node pool", name)
+	}
+	if len(pools) == 1 {
+		return errors.NewError(errors.CodeConflict, "the last node pool of a cluster cannot be deleted", http.StatusConflict)
+	}
+
+	if err := a.drainPool(ctx, externalID, poolLabel(rc), name, -1); err != nil {
+		return err
+	}
+
+	remaining := make([]*domain.NodePool, 0, len(pools)-1)
+	for _, p := range pools {
+		if p.Name != name {
+			remaining = append(remaining, p)
+		}
+	}
+	if err := setNodePools(rc, remaining); err != nil {
+		return err
+	}
+	if err := a.putRancherCluster(ctx, rc); err != nil {
+		return err
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", name).
+		Msg("Deleted node pool")
+	return nil
+}
+
+// drainPool cordons the newest count nodes of a pool, or all of them when
+// count is negative, and evicts their pods until none is left. Evictions
+// honour PodDisruptionBudgets; pods still blocked at the timeout fail the drain.
+func (a *Adapter) drainPool(ctx context.Context, externalID, label, pool string, count int) error {
+	nodes, err := a.poolNodes(ctx, externalID, label, pool)
+	if err != nil {
+		return err
+	}
+	if count >= 0 && count < len(nodes) {
+		nodes = nodes[:count]
+	}
+
+	for _, node := range nodes {
+		patch := []byte(`{"spec":{"unschedulable":true}}`)
+		path := fmt.Sprintf("/k8s/clusters/%s/api/v1/nodes/%s", externalID, node)
+		if err := a.kubeRequest(ctx, "PATCH", path, "application/merge-patch+json", patch, nil); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(drainTimeout)
+	for {
+		// Pods stay listed while they terminate, or while a budget blocks them
+		var remaining []string
+		for _, node := range nodes {
+			pods, err := a.evictablePods(ctx, externalID, node)
+			if err != nil {
+				return err
+			}
+			for _, pod := range pods {
+				if err := a.evict(ctx, externalID, pod.namespace, pod.name); err != nil {
+					return err
+				}
+				remaining = append(remaining, pod.namespace+"/"+pod.name)
+			}
+		}
+		if len(remaining) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return errors.NewError(errors.CodeConflict,
+				fmt.Sprintf("draining node pool %s timed out; pods still running: %s", pool, strings.Join(remaining, ", ")), http.StatusConflict)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(drainPollInterval):
+		}
+	}
+
+	a.logger.Info().
+		Str("external_id", externalID).
+		Str("pool", pool).
+		Int("nodes", len(nodes)).
+		Msg("Drained node pool")
+	return nil
+}
+
+// poolNodes lists the names of a pool's nodes, newest first
+func (a *Adapter) poolNodes(ctx context.Context, externalID, label, pool string) ([]string, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name              string    `json:"name"`
+				CreationTimestamp time.Time `json:"creationTimestamp"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	path := fmt.Sprintf("/k8s/clusters/%s/api/v1/nodes?labelSelector=%s", externalID, url.QueryEscape(label+"="+pool))
+	if err := a.kubeRequest(ctx, "GET", path, "", nil, &list); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Metadata.CreationTimestamp.After(list.Items[j].Metadata.CreationTimestamp)
+	})
+	names := make([]string, len(list.Items))
+	for i, item := range list.Items {
+		names[i] = item.Metadata.Name
+	}
+	return names, nil
+}
+
+type podRef struct {
+	namespace string
+	name      string
+}
+
+// evictablePods lists the pods on a node a drain moves: DaemonSet and
+// mirror pods stay
+func (a *Adapter) evictablePods(ctx context.Context, externalID, node string) ([]podRef, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name            string            `json:"name"`
+				Namespace       string            `json:"namespace"`
+				Annotations     map[string]string `json:"annotations"`
+				OwnerReferences []struct {
+					Kind string `json:"kind"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+			Status struct {
+				Phase string `json:"phase"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	path := fmt.Sprintf("/k8s/clusters/%s/api/v1/pods?fieldSelector=%s", externalID, url.QueryEscape("spec.nodeName="+node))
+	if err := a.kubeRequest(ctx, "GET", path, "", nil, &list); err != nil {
+		return nil, err
+	}
+
+	var pods []podRef
+	for _, item := range list.Items {
+		if item.Status.Phase == "Succeeded" || item.Status.Phase == "Failed" {
+			continue
+		}
+		if _, mirror := item.Metadata.Annotations["kubernetes.io/config.mirror"]; mirror {
+			continue
+		}
+		daemon := false
+		for _, owner := range item.Metadata.OwnerReferences {
+			if owner.Kind == "DaemonSet" {
+				daemon = true
+			}
+		}
+		if !daemon {
+			pods = append(pods, podRef{namespace: item.Metadata.Namespace, name: item.Metadata.Name})
+		}
+	}
+	return pods, nil
+}
+
+// evict asks the API server to evict a pod. Evictions a PodDisruptionBudget
+// does not allow yet are retried by the next pass.
+func (a *Adapter) evict(ctx context.Context, externalID, namespace, name string) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "policy/v1",
+		"kind":       "Eviction",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	})
+	path := fmt.Sprintf("/k8s/clusters/%s/api/v1/namespaces/%s/pods/%s/eviction", externalID, namespace, name)
+	resp, err := a.doRequest(ctx, "POST", path, body)
+	if err != nil {
+		return errors.DependencyFailed("rancher", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNotFound, http.StatusTooManyRequests:
+		return nil
+	default:
+		return a.handleError(resp)
+	}
+}
+
+// kubeRequest calls the Rancher API, or the Kubernetes API of a downstream
+// cluster through Rancher's proxy, and decodes the response into out, unless
+// out is nil
+func (a *Adapter) kubeRequest(ctx context.Context, method, path, contentType string, body []byte, out interface{}) error {
+	resp, err := a.doRequestAs(ctx, method, path, contentType, body)
+	if err != nil {
+		return errors.DependencyFailed("rancher", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return a.handleError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode response")
+	}
+	return nil
+}
+
+// getRancherCluster fetches a cluster as Rancher stores it
+func (a *Adapter) getRancherCluster(ctx context.Context, externalID string) (*rancherCluster, error) {
+	resp, err := a.doRequest(ctx, "GET", fmt.Sprintf("/v3/clusters/%s", externalID), nil)
+	if err != nil {
+		return nil, errors.DependencyFailed("rancher", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.NotFound("cluster", externalID)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, a.handleError(resp)
+	}
+
+	var rc rancherCluster
+	if err := json.NewDecoder(resp.Body).Decode(&rc); err != nil {
+		return nil, errors.Wrap(err, "failed to decode response")
+	}
+	return &rc, nil
+}
+
+// putRancherCluster replaces a cluster's configuration in Rancher
+func (a *Adapter) putRancherCluster(ctx context.Context, rc *rancherCluster) error {
+	body, err := json.Marshal(rc)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cluster")
+	}
+
+	resp, err := a.doRequest(ctx, "PUT", fmt.Sprintf("/v3/clusters/%s", rc.ID), body)
+	if err != nil {
+		return errors.DependencyFailed("rancher", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return a.handleError(resp)
+	}
+	return nil
+}
+
+// nodePools reads the node pools of a hosted cluster's configuration
+func nodePools(rc *rancherCluster) ([]*domain.NodePool, error) {
+	var pools []*domain.NodePool
+	switch {
+	case rc.AmazonElasticContainerServiceConfig != nil:
+		for _, g := range rc.AmazonElasticContainerServiceConfig.NodeGroups {
+			pools = append(pools, &domain.NodePool{
+				Name:         g.Name,
+				InstanceType: g.InstanceType,
+				NodeCount:    g.DesiredSize,
+				MinCount:     g.MinSize,
+				MaxCount:     g.MaxSize,
+				AutoScaling:  g.MinSize != g.MaxSize,
+				Version:      g.Version,
+			})
+		}
+	case rc.GoogleKubernetesEngineConfig != nil:
+		pools = fromNodePools(rc.GoogleKubernetesEngineConfig.NodePools)
+	case rc.AzureKubernetesServiceConfig != nil:
+		pools = fromNodePools(rc.AzureKubernetesServiceConfig.NodePools)
+		for i, p := range rc.AzureKubernetesServiceConfig.NodePools {
+			pools[i].Version = p.OrchestratorVersion
+		}
+	default:
+		return nil, errors.BadRequest("node pools are only managed for EKS, GKE and AKS clusters")
+	}
+	return pools, nil
+}
+
+func fromNodePools(in []nodePool) []*domain.NodePool {
+	pools := make([]*domain.NodePool, len(in))
+	for i, p := range in {
+		pools[i] = &domain.NodePool{
+			Name:         p.Name,
+			InstanceType: p.MachineType,
+			NodeCount:    p.InitialNodeCount,
+			MinCount:     p.MinNodeCount,
+			MaxCount:     p.MaxNodeCount,
+			AutoScaling:  p.AutoScaling,
+			Version:      p.Version,
+		}
+	}
+	return pools
+}
+
+// setNodePools writes node pools back to a hosted cluster's configuration
+func setNodePools(rc *rancherCluster, pools []*domain.NodePool) error {
+	switch {
+	case rc.AmazonElasticContainerServiceConfig != nil:
+		groups := make([]nodeGroup, len(pools))
+		for i, p := range pools {
+			min, max := p.MinCount, p.MaxCount
+			if !p.AutoScaling {
+				min, max = p.NodeCount, p.NodeCount
+			}
+			groups[i] = nodeGroup{Name: p.Name, InstanceType: p.InstanceType, DesiredSize: p.NodeCount, MinSize: min, MaxSize: max, Version: p.Version}
+		}
+		rc.AmazonElasticContainerServiceConfig.NodeGroups = groups
+	case rc.GoogleKubernetesEngineConfig != nil:
+		rc.GoogleKubernetesEngineConfig.NodePools = toNodePools(pools)
+	case rc.AzureKubernetesServiceConfig != nil:
+		aksPools := toNodePools(pools)
+		for i := range aksPools {
+			aksPools[i].OrchestratorVersion, aksPools[i].Version = aksPools[i].Version, ""
+		}
+		rc.AzureKubernetesServiceConfig.NodePools = aksPools
+	default:
+		return errors.BadRequest("node pools are only managed for EKS, GKE and AKS clusters")
+	}
+	return nil
+}
+
+func toNodePools(pools []*domain.NodePool) []nodePool {
+	out := make([]nodePool, len(pools))
+	for i, p := range pools {
+		out[i] = nodePool{
+			Name:             p.Name,
+			MachineType:      p.InstanceType,
+			InitialNodeCount: p.NodeCount,
+			MinNodeCount:     p.MinCount,
+			MaxNodeCount:     p.MaxCount,
+			AutoScaling:      p.AutoScaling,
+			Version:          p.Version,
+		}
+	}
+	return out
+}
+
+// poolLabel returns the node label naming a node's pool on a hosted cluster
+func poolLabel(rc *rancherCluster) string {
+	switch {
+	case rc.AmazonElasticContainerServiceConfig != nil:
+		return labelEKSNodeGroup
+	case rc.GoogleKubernetesEngineConfig != nil:
+		return labelGKENodePool
+	default:
+		return labelAKSAgentPool
+	}
+}
+
+func findPool(pools []*domain.NodePool, name string) *domain.NodePool {
+	for _, p := range pools {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// resize sets the size of a hosted cluster with a single node pool. The
+// pools of clusters with several are resized one by one instead.
+func (a *Adapter) resize(ctx context.Context, rc *rancherCluster, count int32) error {
+	pools, err := nodePools(rc)
+	if err != nil || count <= 0 {
+		// Only hosted clusters have pools to resize
+		return nil
+	}
+	var total int32
+	for _, p := range pools {
+		total += p.NodeCount
+	}
+	if total == count {
+		return nil
+	}
+	if len(pools) != 1 {
+		return errors.BadRequest("the cluster has several node pools; resize them through its node pools")
+	}
+
+	pool := pools[0]
+	if surplus := int(pool.NodeCount - count); surplus > 0 {
+		if err := a.drainPool(ctx, rc.ID, poolLabel(rc), pool.Name, surplus); err != nil {
+			return err
+		}
+	}
+	pool.NodeCount = count
+	if pool.MaxCount < count {
+		pool.MaxCount = count
+	}
+	if pool.MinCount > count {
+		pool.MinCount = count
+	}
+	return setNodePools(rc, pools)
+}
+
+// setKubernetesVersion sets the Kubernetes version of a cluster and of its
+// provider configuration
+func setKubernetesVersion(rc *rancherCluster, version string) {
+	if version == "" {
+		return
+	}
+	rc.KubernetesVersion = version
+	switch {
+	case rc.AmazonElasticContainerServiceConfig != nil:
+		rc.AmazonElasticContainerServiceConfig.KubernetesVersion = version
+	case rc.GoogleKubernetesEngineConfig != nil:
+		rc.GoogleKubernetesEngineConfig.KubernetesVersion = version
+	case rc.AzureKubernetesServiceConfig != nil:
+		rc.AzureKubernetesServiceConfig.KubernetesVersion = version
+	case rc.RancherKubernetesEngineConfig != nil:
+		rc.RancherKubernetesEngineConfig.KubernetesVersion = version
+	}
+}
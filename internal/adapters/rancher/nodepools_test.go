@@ -0,0 +1,131 @@
+package rancher
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRancher serves an EKS cluster with two node groups and the nodes and
+// pods of its "batch" group
+type fakeRancher struct {
+	mu      sync.Mutex
+	cluster rancherCluster
+	calls   []string
+	evicted map[string]bool
+}
+
+func (f *fakeRancher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.URL.Path == "/v3/clusters/c-1" && r.Method == "GET":
+		json.NewEncoder(w).Encode(f.cluster)
+	case r.URL.Path == "/v3/clusters/c-1" && r.Method == "PUT":
+		json.NewDecoder(r.Body).Decode(&f.cluster)
+		json.NewEncoder(w).Encode(f.cluster)
+	case r.URL.Path == "/k8s/clusters/c-1/api/v1/nodes":
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": []interface{}{
+			map[string]interface{}{"metadata": map[string]interface{}{"name": "old", "creationTimestamp": "2026-01-01T00:00:00Z"}},
+			map[string]interface{}{"metadata": map[string]interface{}{"name": "new", "creationTimestamp": "2026-02-01T00:00:00Z"}},
+		}})
+	case strings.HasPrefix(r.URL.Path, "/k8s/clusters/c-1/api/v1/nodes/"):
+		w.WriteHeader(http.StatusOK)
+	case r.URL.Path == "/k8s/clusters/c-1/api/v1/pods":
+		var items []interface{}
+		if r.URL.Query().Get("fieldSelector") == "spec.nodeName=new" && !f.evicted["worker"] {
+			items = append(items,
+				map[string]interface{}{"metadata": map[string]interface{}{"name": "worker", "namespace": "shop"}},
+				map[string]interface{}{"metadata": map[string]interface{}{"name": "agent", "namespace": "kube-system",
+					"ownerReferences": []interface{}{map[string]interface{}{"kind": "DaemonSet"}}}},
+			)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case strings.HasSuffix(r.URL.Path, "/eviction"):
+		f.evicted[strings.Split(r.URL.Path, "/")[9]] = true
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeRancher(t *testing.T) (*Adapter, *fakeRancher) {
+	fake := &fakeRancher{evicted: map[string]bool{}, cluster: rancherCluster{
+		ID:   "c-1",
+		Name: "prod",
+		AmazonElasticContainerServiceConfig: &eksConfig{Region: "eu-west-1", NodeGroups: []nodeGroup{
+			{Name: "default", InstanceType: "m6i.large", DesiredSize: 3, MinSize: 1, MaxSize: 6},
+			{Name: "batch", InstanceType: "c6i.xlarge", DesiredSize: 2, MinSize: 2, MaxSize: 2},
+		}},
+	}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	drainPollInterval = time.Millisecond
+	adapter := NewAdapter(&config.RancherConfig{URL: server.URL, Timeout: 5 * time.Second}, logger.New("error", "json", io.Discard))
+	return adapter, fake
+}
+
+func TestNodePools(t *testing.T) {
+	adapter, fake := newFakeRancher(t)
+	ctx := context.Background()
+
+	pools, err := adapter.ListNodePools(ctx, "c-1")
+	require.NoError(t, err)
+	require.Len(t, pools, 2)
+	assert.Equal(t, &domain.NodePool{Name: "default", InstanceType: "m6i.large", NodeCount: 3, MinCount: 1, MaxCount: 6, AutoScaling: true}, pools[0])
+	assert.False(t, pools[1].AutoScaling)
+
+	require.NoError(t, adapter.CreateNodePool(ctx, "c-1", &domain.NodePool{Name: "gpu", InstanceType: "g5.xlarge", NodeCount: 1}))
+	assert.Len(t, fake.cluster.AmazonElasticContainerServiceConfig.NodeGroups, 3)
+	assert.Error(t, adapter.CreateNodePool(ctx, "c-1", &domain.NodePool{Name: "gpu", NodeCount: 1}))
+
+	// Shrinking drains the newest node, leaving DaemonSet pods alone
+	require.NoError(t, adapter.UpdateNodePool(ctx, "c-1", &domain.NodePool{Name: "batch", InstanceType: "c6i.xlarge", NodeCount: 1}))
+	assert.Contains(t, fake.calls, "PATCH /k8s/clusters/c-1/api/v1/nodes/new")
+	assert.NotContains(t, fake.calls, "PATCH /k8s/clusters/c-1/api/v1/nodes/old")
+	assert.Equal(t, map[string]bool{"worker": true}, fake.evicted)
+	batch := fake.cluster.AmazonElasticContainerServiceConfig.NodeGroups[1]
+	assert.Equal(t, nodeGroup{Name: "batch", InstanceType: "c6i.xlarge", DesiredSize: 1, MinSize: 1, MaxSize: 1}, batch)
+
+	require.NoError(t, adapter.DeleteNodePool(ctx, "c-1", "gpu"))
+	assert.Len(t, fake.cluster.AmazonElasticContainerServiceConfig.NodeGroups, 2)
+}
+
+func TestUpdateClusterKeepsNodePools(t *testing.T) {
+	adapter, fake := newFakeRancher(t)
+
+	err := adapter.UpdateCluster(context.Background(), &domain.Cluster{RancherClusterID: "c-1", Name: "prod", KubeVersion: "1.29", NodeCount: 6})
+	assert.Error(t, err, "clusters with several pools are resized pool by pool")
+
+	require.NoError(t, adapter.UpdateCluster(context.Background(), &domain.Cluster{RancherClusterID: "c-1", Name: "prod", KubeVersion: "1.29", NodeCount: 5}))
+	assert.Equal(t, "1.29", fake.cluster.AmazonElasticContainerServiceConfig.KubernetesVersion)
+	assert.Len(t, fake.cluster.AmazonElasticContainerServiceConfig.NodeGroups, 2)
+}
+
+func TestUpgradeTargets(t *testing.T) {
+	assert.Equal(t, []string{"1.28.9", "1.29.0-gke.100", "1.29.4"},
+		upgradeTargets("1.28.5", []string{"1.29.4", "1.27.9", "1.28.5", "1.30.1", "1.28.9", "1.29.0-gke.100"}))
+	assert.Empty(t, upgradeTargets("unknown", []string{"1.29"}))
+
+	// EKS clusters are offered the next minor version
+	adapter, fake := newFakeRancher(t)
+	fake.cluster.AmazonElasticContainerServiceConfig.KubernetesVersion = "1.28"
+	versions, err := adapter.ListKubernetesVersions(context.Background(), "c-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.29"}, versions)
+}
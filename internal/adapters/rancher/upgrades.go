@@ -0,0 +1,77 @@
+package rancher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/northstack/platform/internal/adapters/kubeapi"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ListKubernetesVersions lists the versions a hosted cluster can be upgraded
+// to: newer than its current version and at most one minor version ahead, as
+// Kubernetes only supports upgrading one minor version at a time. Rancher has
+// no version list for EKS, so the next minor version is offered; EKS refuses
+// it until it supports it.
+func (a *Adapter) ListKubernetesVersions(ctx context.Context, externalID string) ([]string, error) {
+	rc, err := a.getRancherCluster(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	current := currentVersion(rc)
+
+	var candidates []string
+	switch {
+	case rc.AmazonElasticContainerServiceConfig != nil:
+		v, ok := kubeapi.ParseVersion(current)
+		if !ok {
+			return nil, errors.Internal(fmt.Sprintf("cluster has an unknown Kubernetes version %q", current))
+		}
+		candidates = []string{fmt.Sprintf("%d.%d", v[0], v[1]+1)}
+	case rc.GoogleKubernetesEngineConfig != nil:
+		gke := rc.GoogleKubernetesEngineConfig
+		query := url.Values{"cloudCredentialId": {gke.GoogleCredentialSecret}, "projectId": {gke.ProjectID}}
+		if gke.Zone != "" {
+			query.Set("zone", gke.Zone)
+		} else {
+			query.Set("region", gke.Region)
+		}
+		var config struct {
+			ValidMasterVersions []string `json:"validMasterVersions"`
+		}
+		if err := a.kubeRequest(ctx, "GET", "/meta/gkeVersions?"+query.Encode(), "", nil, &config); err != nil {
+			return nil, err
+		}
+		candidates = config.ValidMasterVersions
+	case rc.AzureKubernetesServiceConfig != nil:
+		aks := rc.AzureKubernetesServiceConfig
+		query := url.Values{"cloudCredentialId": {aks.AzureCredentialSecret}, "region": {aks.Location}}
+		if err := a.kubeRequest(ctx, "GET", "/meta/aksVersions?"+query.Encode(), "", nil, &candidates); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.BadRequest("Kubernetes upgrades are only orchestrated for EKS, GKE and AKS clusters")
+	}
+
+	return upgradeTargets(current, candidates), nil
+}
+
+// currentVersion returns the Kubernetes version of a cluster's control plane
+func currentVersion(rc *rancherCluster) string {
+	switch {
+	case rc.AmazonElasticContainerServiceConfig != nil && rc.AmazonElasticContainerServiceConfig.KubernetesVersion != "":
+		return rc.AmazonElasticContainerServiceConfig.KubernetesVersion
+	case rc.GoogleKubernetesEngineConfig != nil && rc.GoogleKubernetesEngineConfig.KubernetesVersion != "":
+		return rc.GoogleKubernetesEngineConfig.KubernetesVersion
+	case rc.AzureKubernetesServiceConfig != nil && rc.AzureKubernetesServiceConfig.KubernetesVersion != "":
+		return rc.AzureKubernetesServiceConfig.KubernetesVersion
+	}
+	return rc.KubernetesVersion
+}
+
+// upgradeTargets returns the candidates a cluster at current can be upgraded
+// to, oldest first
+func upgradeTargets(current string, candidates []string) []string {
+	return kubeapi.UpgradeTargets(current, candidates)
+}
@@ -0,0 +1,136 @@
+// Package s3 implements domain.ObjectStore against the S3 REST API, which is
+// also served by MinIO and Ceph RGW. Requests are signed with SigV4; uploads
+// are streamed with an unsigned payload.
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// maxPresignExpiry is the longest validity SigV4 allows for a presigned URL
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// Store reads and writes objects in a single bucket
+type Store struct {
+	config     *config.S3Config
+	endpoint   *url.URL
+	signer     signer
+	httpClient *http.Client
+	logger     *logger.Logger
+}
+
+// NewStore creates a new S3-backed ObjectStore
+func NewStore(cfg *config.S3Config, log *logger.Logger) (*Store, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, errors.InvalidInput("s3 endpoint must be an absolute URL")
+	}
+
+	return &Store{
+		config:   cfg,
+		endpoint: endpoint,
+		signer: signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			region:          cfg.Region,
+		},
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
+		},
+		logger: log,
+	}, nil
+}
+
+// PutObject stores size bytes read from body under key
+func (s *Store) PutObject(ctx context.Context, key string, body io.Reader, size int64, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), body)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return s.do(req, http.StatusOK)
+}
+
+// DeleteObject removes the object stored under key. Deleting a missing object
+// is not an error.
+func (s *Store) DeleteObject(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+
+	return s.do(req, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+}
+
+// PresignGet returns a URL that downloads the object under key until expiry
+func (s *Store) PresignGet(key, filename string, expiry time.Duration) (string, error) {
+	if expiry <= 0 || expiry > maxPresignExpiry {
+		return "", errors.InvalidInput("presigned URL expiry must be between 1s and 7 days")
+	}
+
+	u := s.objectURL(key)
+	if filename != "" {
+		query := u.Query()
+		query.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		u.RawQuery = query.Encode()
+	}
+
+	return s.signer.presign(http.MethodGet, u, expiry, time.Now()), nil
+}
+
+// objectURL addresses key in the bucket, path-style or virtual-hosted-style
+func (s *Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	path := "/" + strings.TrimPrefix(key, "/")
+	if s.config.PathStyle {
+		path = "/" + s.config.Bucket + path
+	} else {
+		u.Host = s.config.Bucket + "." + u.Host
+	}
+	u.Path = u.Path + path
+	u.RawPath = uriEncode(u.Path, false)
+	return &u
+}
+
+func (s *Store) do(req *http.Request, expected ...int) error {
+	s.signer.sign(req, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return errors.DependencyFailed("s3", err)
+	}
+	defer resp.Body.Close()
+
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			io.Copy(io.Discard, resp.Body)
+			return nil
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	s.logger.Warn().
+		Int("status", resp.StatusCode).
+		Str("method", req.Method).
+		Str("body", string(body)).
+		Msg("S3 request failed")
+
+	return errors.DependencyFailed("s3", fmt.Errorf("%s %s: status %d", req.Method, req.URL.Path, resp.StatusCode))
+}
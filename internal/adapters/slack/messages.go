@@ -0,0 +1,288 @@
+package slack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// Event types notifications are routed by, named after the platform events
+// they report
+const (
+	EventBuildCompleted    = domain.NotificationEventBuildCompleted
+	EventBuildFailed       = domain.NotificationEventBuildFailed
+	EventDeployCompleted   = domain.NotificationEventDeployCompleted
+	EventDeployFailed      = domain.NotificationEventDeployFailed
+	EventRollbackCompleted = domain.NotificationEventRollbackCompleted
+	EventAlertFired        = domain.NotificationEventAlertFired
+	EventAlertResolved     = domain.NotificationEventAlertResolved
+)
+
+var eventTypes = map[string]bool{
+	"*":                    true,
+	EventBuildCompleted:    true,
+	EventBuildFailed:       true,
+	EventDeployCompleted:   true,
+	EventDeployFailed:      true,
+	EventRollbackCompleted: true,
+	EventAlertFired:        true,
+	EventAlertResolved:     true,
+}
+
+type object = map[string]interface{}
+
+// message is a chat.postMessage request. Text is shown in notifications and
+// by clients that cannot render blocks.
+type message struct {
+	Channel string        `json:"channel"`
+	Text    string        `json:"text"`
+	Blocks  []interface{} `json:"blocks"`
+}
+
+// Validate checks a project's Slack settings
+func Validate(s *domain.SlackSettings) error {
+	if s.Channel == "" && len(s.Routes) == 0 {
+		return errors.BadRequest("set a channel or at least one route")
+	}
+	if s.Channel != "" && !validChannel(s.Channel) {
+		return errors.BadRequest(fmt.Sprintf("invalid Slack channel %q", s.Channel))
+	}
+	for i, route := range s.Routes {
+		if !validChannel(route.Channel) {
+			return errors.BadRequest(fmt.Sprintf("route %d: invalid Slack channel %q", i, route.Channel))
+		}
+		if len(route.Events) == 0 {
+			return errors.BadRequest(fmt.Sprintf("route %d takes no events", i))
+		}
+		for _, t := range route.Events {
+			if !eventTypes[t] {
+				return errors.BadRequest(fmt.Sprintf("route %d: unknown event type %q", i, t))
+			}
+		}
+	}
+	return nil
+}
+
+// validChannel accepts a channel name, with or without #, or a channel ID
+func validChannel(channel string) bool {
+	name := strings.TrimPrefix(channel, "#")
+	return name != "" && len(name) <= 80 && !strings.ContainsAny(name, " ,#")
+}
+
+// Channels returns the channels a project's event goes to: those of every
+// route that takes it, or else the project's channel
+func Channels(s *domain.SlackSettings, eventType, severity string) []string {
+	if s == nil {
+		return nil
+	}
+	var channels []string
+	seen := make(map[string]bool)
+	for _, route := range s.Routes {
+		if !route.Matches(eventType, severity) || seen[route.Channel] {
+			continue
+		}
+		seen[route.Channel] = true
+		channels = append(channels, route.Channel)
+	}
+	if len(channels) == 0 && s.Channel != "" {
+		channels = append(channels, s.Channel)
+	}
+	return channels
+}
+
+// buildMessage reports the outcome of a build
+func buildMessage(project *domain.Project, serviceName string, build *domain.Build, consoleURL string) message {
+	title := fmt.Sprintf(":white_check_mark: Build of %s succeeded", serviceName)
+	if build.Status == domain.BuildStatusFailed {
+		title = fmt.Sprintf(":x: Build of %s failed", serviceName)
+	}
+
+	fields := []string{field("Project", project.Name), field("Service", serviceName)}
+	if build.ImageTag != "" {
+		fields = append(fields, field("Image", "`"+build.ImageTag+"`"))
+	}
+	if build.Duration > 0 {
+		fields = append(fields, field("Duration", (time.Duration(build.Duration)*time.Second).String()))
+	}
+	if build.Source.Branch != "" {
+		fields = append(fields, field("Branch", build.Source.Branch))
+	}
+	if build.Source.CommitSHA != "" {
+		fields = append(fields, field("Commit", "`"+shortSHA(build.Source.CommitSHA)+"`"))
+	}
+
+	blocks := []interface{}{header(title), fieldsSection(fields)}
+	if build.ErrorMessage != "" {
+		blocks = append(blocks, errorSection(build.ErrorMessage))
+	}
+	if build.TriggeredBy != "" {
+		blocks = append(blocks, contextBlock("Triggered by "+escape(build.TriggeredBy)))
+	}
+	blocks = append(blocks, linkButton(consoleURL, "/services/"+build.ServiceID.String(), "View service")...)
+	return message{Text: title, Blocks: blocks}
+}
+
+// deploymentMessage reports the outcome of a deployment
+func deploymentMessage(project *domain.Project, serviceName string, deployment *domain.Deployment, consoleURL string) message {
+	var title string
+	switch deployment.Status {
+	case domain.DeploymentStatusFailed:
+		title = fmt.Sprintf(":x: Deploy of %s %s failed", serviceName, deployment.Version)
+	case domain.DeploymentStatusRolledBack:
+		title = fmt.Sprintf(":rewind: %s rolled back to %s", serviceName, deployment.Version)
+	default:
+		title = fmt.Sprintf(":rocket: %s %s deployed", serviceName, deployment.Version)
+	}
+
+	fields := []string{field("Project", project.Name), field("Service", serviceName)}
+	if deployment.Version != "" {
+		fields = append(fields, field("Version", "`"+deployment.Version+"`"))
+	}
+	if deployment.PreviousVersion != "" {
+		fields = append(fields, field("Previous version", "`"+deployment.PreviousVersion+"`"))
+	}
+	if deployment.Strategy != "" {
+		fields = append(fields, field("Strategy", string(deployment.Strategy)))
+	}
+	if deployment.Replicas > 0 {
+		fields = append(fields, field("Replicas", fmt.Sprintf("%d/%d ready", deployment.ReadyReplicas, deployment.Replicas)))
+	}
+
+	blocks := []interface{}{header(title), fieldsSection(fields)}
+	if deployment.ErrorMessage != "" {
+		blocks = append(blocks, errorSection(deployment.ErrorMessage))
+	}
+	if deployment.TriggeredBy != "" {
+		blocks = append(blocks, contextBlock("Triggered by "+escape(deployment.TriggeredBy)))
+	}
+	blocks = append(blocks, linkButton(consoleURL, "/services/"+deployment.ServiceID.String(), "View service")...)
+	return message{Text: title, Blocks: blocks}
+}
+
+// alertMessage reports an alert firing or resolving. project is nil for
+// alerts of no project.
+func alertMessage(project *domain.Project, alert *domain.Alert, consoleURL string) message {
+	title := fmt.Sprintf(":rotating_light: [%s] %s", strings.ToUpper(alert.Severity), alert.Name)
+	if alert.Status == domain.AlertStatusResolved {
+		title = fmt.Sprintf(":large_green_circle: Resolved: %s", alert.Name)
+	}
+
+	var fields []string
+	if project != nil {
+		fields = append(fields, field("Project", project.Name))
+	}
+	if alert.Severity != "" {
+		fields = append(fields, field("Severity", alert.Severity))
+	}
+	if alert.Source != "" {
+		fields = append(fields, field("Source", alert.Source))
+	}
+	if alert.StartsAt > 0 {
+		// Rendered in the reader's time zone
+		since := time.Unix(alert.StartsAt, 0).UTC().Format(time.RFC3339)
+		fields = append(fields, fmt.Sprintf("*Since*\n<!date^%d^{date_short_pretty} {time}|%s>", alert.StartsAt, since))
+	}
+
+	blocks := []interface{}{header(title)}
+	if alert.Message != "" {
+		blocks = append(blocks, section(escape(alert.Message)))
+	}
+	if len(fields) > 0 {
+		blocks = append(blocks, fieldsSection(fields))
+	}
+	if alert.ServiceID != nil {
+		blocks = append(blocks, linkButton(consoleURL, "/services/"+alert.ServiceID.String(), "View service")...)
+	}
+	return message{Text: title, Blocks: blocks}
+}
+
+// notificationMessage renders a free-form notification
+func notificationMessage(n *domain.Notification) message {
+	title := n.Title
+	if title == "" {
+		title = n.Type
+	}
+	blocks := []interface{}{header(title)}
+	if n.Message != "" {
+		blocks = append(blocks, section(escape(n.Message)))
+	}
+	if n.Severity != "" {
+		blocks = append(blocks, contextBlock("Severity: "+escape(n.Severity)))
+	}
+	return message{Text: title, Blocks: blocks}
+}
+
+// header is a header block, whose plain text Slack limits to 150 characters
+func header(text string) object {
+	if len(text) > 150 {
+		text = text[:147] + "..."
+	}
+	return object{"type": "header", "text": object{"type": "plain_text", "text": text, "emoji": true}}
+}
+
+func section(text string) object {
+	return object{"type": "section", "text": object{"type": "mrkdwn", "text": truncate(text)}}
+}
+
+// fieldsSection lays fields out in two columns; Slack takes at most 10
+func fieldsSection(fields []string) object {
+	if len(fields) > 10 {
+		fields = fields[:10]
+	}
+	out := make([]interface{}, 0, len(fields))
+	for _, f := range fields {
+		out = append(out, object{"type": "mrkdwn", "text": f})
+	}
+	return object{"type": "section", "fields": out}
+}
+
+func errorSection(msg string) object {
+	return section("```" + strings.ReplaceAll(truncate(msg), "```", "'''") + "```")
+}
+
+func contextBlock(text string) object {
+	return object{"type": "context", "elements": []interface{}{object{"type": "mrkdwn", "text": text}}}
+}
+
+// linkButton is an actions block with a button to a console page, none when
+// no console URL is configured
+func linkButton(consoleURL, path, label string) []interface{} {
+	if consoleURL == "" {
+		return nil
+	}
+	return []interface{}{object{
+		"type": "actions",
+		"elements": []interface{}{object{
+			"type": "button",
+			"text": object{"type": "plain_text", "text": label},
+			"url":  strings.TrimSuffix(consoleURL, "/") + path,
+		}},
+	}}
+}
+
+func field(name, value string) string {
+	return "*" + name + "*\n" + escape(value)
+}
+
+// escape escapes the characters Slack's mrkdwn treats as control sequences
+func escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// truncate keeps text within the 3000 characters of a section
+func truncate(text string) string {
+	if len(text) > 2900 {
+		return text[:2900] + "\n..."
+	}
+	return text
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
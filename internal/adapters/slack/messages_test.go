@@ -0,0 +1,58 @@
+package slack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       domain.SlackSettings
+		wantErr bool
+	}{
+		{"channel", domain.SlackSettings{Channel: "#deploys"}, false},
+		{"channel ID", domain.SlackSettings{Channel: "C0123456789"}, false},
+		{"routes", domain.SlackSettings{Routes: []domain.SlackRoute{{Events: []string{EventDeployFailed, EventBuildFailed}, Channel: "#oncall"}}}, false},
+		{"empty", domain.SlackSettings{}, true},
+		{"bad channel", domain.SlackSettings{Channel: "#two words"}, true},
+		{"route without events", domain.SlackSettings{Routes: []domain.SlackRoute{{Channel: "#oncall"}}}, true},
+		{"unknown event", domain.SlackSettings{Routes: []domain.SlackRoute{{Events: []string{"deploy.exploded"}, Channel: "#oncall"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&tt.s)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestChannels(t *testing.T) {
+	s := &domain.SlackSettings{
+		Channel: "#general",
+		Routes: []domain.SlackRoute{
+			{Events: []string{EventDeployFailed, EventBuildFailed}, Channel: "#oncall"},
+			{Events: []string{EventAlertFired}, Severities: []string{"critical"}, Channel: "#oncall"},
+			{Events: []string{"*"}, Severities: []string{"warning"}, Channel: "#alerts"},
+		},
+	}
+
+	assert.Equal(t, []string{"#oncall"}, Channels(s, EventDeployFailed, ""))
+	assert.Equal(t, []string{"#general"}, Channels(s, EventDeployCompleted, ""))
+	assert.Equal(t, []string{"#oncall"}, Channels(s, EventAlertFired, "critical"))
+	assert.Equal(t, []string{"#alerts"}, Channels(s, EventAlertResolved, "warning"))
+	assert.Nil(t, Channels(nil, EventDeployFailed, ""))
+	assert.Empty(t, Channels(&domain.SlackSettings{}, EventDeployFailed, ""))
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, "a &lt;b&gt; &amp; c", escape("a <b> & c"))
+	assert.True(t, strings.HasSuffix(truncate(strings.Repeat("x", 4000)), "..."))
+}
@@ -0,0 +1,213 @@
+// Package slack implements domain.Notifier for Slack. Build, deploy and alert
+// notifications are posted as Block Kit messages by a Slack app's bot, to the
+// channels each project routes them to.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Notifier posts notifications to Slack through the Web API
+type Notifier struct {
+	config      *config.SlackConfig
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	httpClient  *http.Client
+	logger      *logger.Logger
+}
+
+// NewNotifier creates a new Notifier. serviceRepo may be nil, in which case
+// messages name services by ID.
+func NewNotifier(cfg *config.SlackConfig, projectRepo domain.ProjectRepository, serviceRepo domain.ServiceRepository, log *logger.Logger) *Notifier {
+	return &Notifier{
+		config:      cfg,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
+		},
+		logger: log,
+	}
+}
+
+var _ domain.Notifier = (*Notifier)(nil)
+
+// SendNotification posts a notification to its recipient channel, or the
+// default channel when it names none. Notifications for other channels are
+// ignored.
+func (n *Notifier) SendNotification(ctx context.Context, notification *domain.Notification) error {
+	if notification.Channel != "" && notification.Channel != "slack" {
+		return nil
+	}
+	channel := notification.Recipient
+	if channel == "" {
+		channel = n.config.DefaultChannel
+	}
+	if channel == "" {
+		return errors.BadRequest("notification has no Slack channel")
+	}
+	return n.post(ctx, []string{channel}, notificationMessage(notification))
+}
+
+// SendBuildNotification posts a finished build to the channels its project
+// routes it to
+func (n *Notifier) SendBuildNotification(ctx context.Context, build *domain.Build) error {
+	var eventType string
+	switch build.Status {
+	case domain.BuildStatusSucceeded:
+		eventType = EventBuildCompleted
+	case domain.BuildStatusFailed:
+		eventType = EventBuildFailed
+	default:
+		return nil
+	}
+
+	project, err := n.projectRepo.GetByID(ctx, build.ProjectID)
+	if err != nil {
+		return err
+	}
+	channels := Channels(project.Slack, eventType, "")
+	if len(channels) == 0 {
+		return nil
+	}
+	return n.post(ctx, channels, buildMessage(project, n.serviceName(ctx, build.ServiceID), build, n.config.ConsoleURL))
+}
+
+// SendDeploymentNotification posts a finished deployment to the channels
+// its project routes it to
+func (n *Notifier) SendDeploymentNotification(ctx context.Context, deployment *domain.Deployment) error {
+	var eventType string
+	switch deployment.Status {
+	case domain.DeploymentStatusSucceeded:
+		eventType = EventDeployCompleted
+	case domain.DeploymentStatusFailed:
+		eventType = EventDeployFailed
+	case domain.DeploymentStatusRolledBack:
+		eventType = EventRollbackCompleted
+	default:
+		return nil
+	}
+
+	project, err := n.projectRepo.GetByID(ctx, deployment.ProjectID)
+	if err != nil {
+		return err
+	}
+	channels := Channels(project.Slack, eventType, "")
+	if len(channels) == 0 {
+		return nil
+	}
+	return n.post(ctx, channels, deploymentMessage(project, n.serviceName(ctx, deployment.ServiceID), deployment, n.config.ConsoleURL))
+}
+
+// SendAlertNotification posts an alert that fired or resolved to the
+// channels its project routes it to. Alerts of no project go to the default
+// channel.
+func (n *Notifier) SendAlertNotification(ctx context.Context, alert *domain.Alert) error {
+	eventType := EventAlertFired
+	if alert.Status == domain.AlertStatusResolved {
+		eventType = EventAlertResolved
+	}
+
+	if alert.ProjectID == nil {
+		if n.config.DefaultChannel == "" {
+			return nil
+		}
+		return n.post(ctx, []string{n.config.DefaultChannel}, alertMessage(nil, alert, n.config.ConsoleURL))
+	}
+
+	project, err := n.projectRepo.GetByID(ctx, *alert.ProjectID)
+	if err != nil {
+		return err
+	}
+	channels := Channels(project.Slack, eventType, alert.Severity)
+	if len(channels) == 0 {
+		return nil
+	}
+	return n.post(ctx, channels, alertMessage(project, alert, n.config.ConsoleURL))
+}
+
+// serviceName names a service in messages, by its ID when it cannot be loaded
+func (n *Notifier) serviceName(ctx context.Context, serviceID uuid.UUID) string {
+	if n.serviceRepo == nil {
+		return serviceID.String()
+	}
+	service, err := n.serviceRepo.GetByID(ctx, serviceID)
+	if err != nil {
+		return serviceID.String()
+	}
+	return service.Name
+}
+
+// post sends a message to each channel, returning the first failure after
+// trying them all
+func (n *Notifier) post(ctx context.Context, channels []string, msg message) error {
+	var firstErr error
+	for _, channel := range channels {
+		msg.Channel = strings.TrimPrefix(channel, "#")
+		if err := n.postMessage(ctx, msg); err != nil {
+			n.logger.Warn().Err(err).Str("channel", channel).Msg("Failed to post Slack message")
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// postMessage calls chat.postMessage. Slack answers 200 with ok false for
+// most failures, such as a channel the bot is not a member of.
+func (n *Notifier) postMessage(ctx context.Context, msg message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode Slack message")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(n.config.APIURL, "/")+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to create Slack request")
+	}
+	req.Header.Set("Authorization", "Bearer "+n.config.BotToken)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	start := time.Now()
+	resp, err := n.httpClient.Do(req)
+	metrics.ObserveAdapterCall("slack", http.MethodPost, start, resp, err)
+	if err != nil {
+		return errors.DependencyFailed("slack", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode != http.StatusOK {
+		return errors.DependencyFailed("slack", fmt.Errorf("chat.postMessage returned %d: %s", resp.StatusCode, strings.TrimSpace(string(data))))
+	}
+
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return errors.DependencyFailed("slack", fmt.Errorf("invalid chat.postMessage response: %w", err))
+	}
+	if !result.OK {
+		// Slack's error names what to fix, such as not_in_channel
+		return errors.NewError(errors.CodeDependencyFailed, fmt.Sprintf("Slack refused the message to %s: %s", msg.Channel, result.Error), http.StatusBadGateway)
+	}
+	return nil
+}
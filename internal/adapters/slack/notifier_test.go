@@ -0,0 +1,130 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProjects struct {
+	domain.ProjectRepository
+	project *domain.Project
+}
+
+func (f fakeProjects) GetByID(context.Context, uuid.UUID) (*domain.Project, error) {
+	return f.project, nil
+}
+
+type fakeServices struct {
+	domain.ServiceRepository
+	service *domain.Service
+}
+
+func (f fakeServices) GetByID(context.Context, uuid.UUID) (*domain.Service, error) {
+	return f.service, nil
+}
+
+// slackAPI records the messages posted to it, answering with error for
+// channels in failing
+func slackAPI(t *testing.T, failing ...string) (*httptest.Server, *[]message) {
+	var posted []message
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat.postMessage", r.URL.Path)
+		assert.Equal(t, "Bearer xoxb-test", r.Header.Get("Authorization"))
+		var msg message
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+		posted = append(posted, msg)
+		for _, channel := range failing {
+			if msg.Channel == channel {
+				io.WriteString(w, `{"ok": false, "error": "not_in_channel"}`)
+				return
+			}
+		}
+		io.WriteString(w, `{"ok": true}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &posted
+}
+
+func testNotifier(url string, project *domain.Project) *Notifier {
+	cfg := &config.SlackConfig{BotToken: "xoxb-test", APIURL: url, DefaultChannel: "#platform", ConsoleURL: "https://console.example.com", Timeout: time.Second}
+	service := &domain.Service{Name: "api"}
+	return NewNotifier(cfg, fakeProjects{project: project}, fakeServices{service: service}, logger.New("error", "json", io.Discard))
+}
+
+func TestSendDeploymentNotification(t *testing.T) {
+	server, posted := slackAPI(t)
+	project := &domain.Project{ID: uuid.New(), Name: "Shop", Slack: &domain.SlackSettings{
+		Channel: "#shop",
+		Routes:  []domain.SlackRoute{{Events: []string{EventDeployFailed}, Channel: "#shop-oncall"}},
+	}}
+	n := testNotifier(server.URL, project)
+
+	deployment := &domain.Deployment{ServiceID: uuid.New(), ProjectID: project.ID, Status: domain.DeploymentStatusFailed, Version: "v7", ErrorMessage: "rollout timed out"}
+	require.NoError(t, n.SendDeploymentNotification(context.Background(), deployment))
+	require.Len(t, *posted, 1)
+	msg := (*posted)[0]
+	assert.Equal(t, "shop-oncall", msg.Channel)
+	assert.Equal(t, ":x: Deploy of api v7 failed", msg.Text)
+	assert.Contains(t, string(mustJSON(t, msg.Blocks)), "rollout timed out")
+	assert.Contains(t, string(mustJSON(t, msg.Blocks)), "https://console.example.com/services/"+deployment.ServiceID.String())
+
+	deployment.Status = domain.DeploymentStatusSucceeded
+	require.NoError(t, n.SendDeploymentNotification(context.Background(), deployment))
+	require.Len(t, *posted, 2)
+	assert.Equal(t, "shop", (*posted)[1].Channel)
+
+	// Deployments still in progress are not reported
+	deployment.Status = domain.DeploymentStatusInProgress
+	require.NoError(t, n.SendDeploymentNotification(context.Background(), deployment))
+	assert.Len(t, *posted, 2)
+}
+
+func TestSendAlertNotification(t *testing.T) {
+	server, posted := slackAPI(t, "shop-pager")
+	project := &domain.Project{ID: uuid.New(), Name: "Shop", Slack: &domain.SlackSettings{
+		Routes: []domain.SlackRoute{
+			{Events: []string{EventAlertFired}, Severities: []string{"critical"}, Channel: "#shop-pager"},
+			{Events: []string{"*"}, Channel: "#shop"},
+		},
+	}}
+	n := testNotifier(server.URL, project)
+
+	alert := &domain.Alert{Name: "HighErrorRate", Severity: "critical", Status: domain.AlertStatusFiring, Message: "5xx > 5%", ProjectID: &project.ID}
+	err := n.SendAlertNotification(context.Background(), alert)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not_in_channel")
+	// The failing channel does not keep the others from being posted to
+	require.Len(t, *posted, 2)
+	assert.Equal(t, "shop", (*posted)[1].Channel)
+
+	alert.ProjectID = nil
+	require.NoError(t, n.SendAlertNotification(context.Background(), alert))
+	assert.Equal(t, "platform", (*posted)[2].Channel)
+}
+
+func TestSendBuildNotificationUnrouted(t *testing.T) {
+	server, posted := slackAPI(t)
+	n := testNotifier(server.URL, &domain.Project{ID: uuid.New()})
+
+	build := &domain.Build{Status: domain.BuildStatusFailed}
+	require.NoError(t, n.SendBuildNotification(context.Background(), build))
+	assert.Empty(t, *posted)
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,331 @@
+// Package vault provides integration with HashiCorp Vault, the source of
+// truth for secret values. The platform reads KV v2 metadata and only writes
+// values when importing projects from other platforms and for the
+// credentials of managed addons; values are delivered into clusters by
+// External Secrets Operator.
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// serviceAccountTokenPath is where the orchestrator's own service account token is mounted
+const serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Client reads secret metadata through the Vault HTTP API
+type Client struct {
+	config     *config.VaultConfig
+	httpClient *http.Client
+	logger     *logger.Logger
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time // Zero for tokens that do not expire
+}
+
+// Client is a domain.SecretsAdapter for KV v2 secrets
+var _ domain.SecretsAdapter = (*Client)(nil)
+
+// NewClient creates a new Vault client
+func NewClient(cfg *config.VaultConfig, log *logger.Logger) *Client {
+	return &Client{
+		config: cfg,
+		httpClient: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
+		},
+		logger: log,
+	}
+}
+
+// CurrentVersion returns the current version of a KV v2 secret. path is
+// relative to the configured mount.
+func (c *Client) CurrentVersion(ctx context.Context, path string) (int, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "/v1/"+c.config.MountPath+"/metadata/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return 0, errors.DependencyFailed("vault", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden {
+		// Vault also answers 403 for expired tokens; log in again next time
+		c.mu.Lock()
+		c.token = ""
+		c.mu.Unlock()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, c.handleError(resp, path)
+	}
+
+	var metadata struct {
+		Data struct {
+			CurrentVersion int `json:"current_version"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return 0, errors.Wrap(err, "failed to decode Vault metadata")
+	}
+	return metadata.Data.CurrentVersion, nil
+}
+
+// WriteSecret stores a new version of a KV v2 secret and returns its version.
+// path is relative to the configured mount.
+func (c *Client) WriteSecret(ctx context.Context, path string, data map[string]string) (int, error) {
+	body, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.doRequest(ctx, http.MethodPost, "/v1/"+c.config.MountPath+"/data/"+strings.TrimPrefix(path, "/"), body)
+	if err != nil {
+		return 0, errors.DependencyFailed("vault", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, c.handleError(resp, path)
+	}
+
+	var written struct {
+		Data struct {
+			Version int `json:"version"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&written); err != nil {
+		return 0, errors.Wrap(err, "failed to decode Vault response")
+	}
+	return written.Data.Version, nil
+}
+
+// GetSecret reads the current version of a KV v2 secret, as
+// domain.SecretsAdapter does. path is relative to the configured mount.
+func (c *Client) GetSecret(ctx context.Context, path string) (map[string][]byte, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "/v1/"+c.config.MountPath+"/data/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, errors.DependencyFailed("vault", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError(resp, path)
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return nil, errors.Wrap(err, "failed to decode Vault secret")
+	}
+
+	data := make(map[string][]byte, len(secret.Data.Data))
+	for key, value := range secret.Data.Data {
+		data[key] = []byte(value)
+	}
+	return data, nil
+}
+
+// CreateSecret stores the values of a secret at its Vault path and sets the
+// secret's version
+func (c *Client) CreateSecret(ctx context.Context, secret *domain.Secret, data map[string][]byte) error {
+	values := make(map[string]string, len(data))
+	for key, value := range data {
+		values[key] = string(value)
+	}
+	version, err := c.WriteSecret(ctx, secret.VaultPath, values)
+	if err != nil {
+		return err
+	}
+	secret.Version = version
+	return nil
+}
+
+// UpdateSecret stores a new version of the values of a secret
+func (c *Client) UpdateSecret(ctx context.Context, secret *domain.Secret, data map[string][]byte) error {
+	return c.CreateSecret(ctx, secret, data)
+}
+
+// DeleteSecret deletes every version of a KV v2 secret. path is relative to
+// the configured mount.
+func (c *Client) DeleteSecret(ctx context.Context, path string) error {
+	resp, err := c.doRequest(ctx, http.MethodDelete, "/v1/"+c.config.MountPath+"/metadata/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return errors.DependencyFailed("vault", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return c.handleError(resp, path)
+	}
+	return nil
+}
+
+// ListSecrets lists the keys under a KV v2 path; folders end with a slash. An
+// empty or missing path has no keys.
+func (c *Client) ListSecrets(ctx context.Context, path string) ([]string, error) {
+	resp, err := c.doRequest(ctx, "LIST", "/v1/"+c.config.MountPath+"/metadata/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, errors.DependencyFailed("vault", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return []string{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.handleError(resp, path)
+	}
+
+	var list struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, errors.Wrap(err, "failed to decode Vault list")
+	}
+	return list.Data.Keys, nil
+}
+
+// CreateDynamicSecret is not supported; the platform only uses KV secrets
+func (c *Client) CreateDynamicSecret(ctx context.Context, name string, config map[string]interface{}) error {
+	return errors.NotImplemented("dynamic Vault secrets are not supported")
+}
+
+// doRequest performs an authenticated request to the Vault API
+func (c *Client) doRequest(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	token, err := c.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.config.Address+path, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	metrics.ObserveAdapterCall("vault", method, start, resp, err)
+	return resp, err
+}
+
+// authenticate returns a client token, logging in again shortly before the
+// previous one expires
+func (c *Client) authenticate(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && (c.expires.IsZero() || time.Until(c.expires) > time.Minute) {
+		return c.token, nil
+	}
+
+	var path string
+	var payload map[string]string
+	switch c.config.AuthMethod {
+	case "", "token":
+		c.token = c.config.Token
+		return c.token, nil
+	case "kubernetes":
+		jwt, err := os.ReadFile(serviceAccountTokenPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read service account token: %w", err)
+		}
+		mount := c.config.K8sAuthPath
+		if mount == "" {
+			mount = "kubernetes"
+		}
+		path = "/v1/auth/" + strings.Trim(mount, "/") + "/login"
+		payload = map[string]string{"role": c.config.K8sRole, "jwt": strings.TrimSpace(string(jwt))}
+	case "approle":
+		path = "/v1/auth/approle/login"
+		payload = map[string]string{"role_id": c.config.RoleID, "secret_id": c.config.SecretID}
+	default:
+		return "", fmt.Errorf("unsupported Vault auth method: %s", c.config.AuthMethod)
+	}
+
+	body, _ := json.Marshal(payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.Address+path, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	metrics.ObserveAdapterCall("vault", http.MethodPost, start, resp, err)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.handleError(resp, path)
+	}
+
+	var login struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&login); err != nil {
+		return "", fmt.Errorf("failed to decode Vault login: %w", err)
+	}
+
+	c.token = login.Auth.ClientToken
+	c.expires = time.Time{}
+	if login.Auth.LeaseDuration > 0 {
+		c.expires = time.Now().Add(time.Duration(login.Auth.LeaseDuration) * time.Second)
+	}
+	c.logger.Debug().Str("auth_method", c.config.AuthMethod).Msg("Authenticated with Vault")
+
+	return c.token, nil
+}
+
+// handleError extracts error information from a response
+func (c *Client) handleError(resp *http.Response, path string) error {
+	body, _ := io.ReadAll(resp.Body)
+
+	var errResp struct {
+		Errors []string `json:"errors"`
+	}
+	json.Unmarshal(body, &errResp)
+
+	msg := strings.Join(errResp.Errors, "; ")
+	if msg == "" {
+		msg = string(body)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return errors.NotFound("vault secret", path)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errors.Forbidden("access denied to Vault path " + path)
+	default:
+		return errors.Internal(fmt.Sprintf("Vault API error (%d): %s", resp.StatusCode, msg))
+	}
+}
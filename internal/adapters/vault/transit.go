@@ -0,0 +1,218 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// TransitKeyVersion is one version of an asymmetric transit key
+type TransitKeyVersion struct {
+	PublicKey string    `json:"public_key"` // PEM
+	CreatedAt time.Time `json:"creation_time"`
+}
+
+// TransitKey is an asymmetric transit key and its versions
+type TransitKey struct {
+	Name          string
+	Type          string
+	LatestVersion int
+	Versions      map[int]TransitKeyVersion
+}
+
+// CreateTransitKey creates an ECDSA P-256 signing key; creating an existing
+// key leaves it unchanged
+func (c *Client) CreateTransitKey(ctx context.Context, name string) error {
+	body, _ := json.Marshal(map[string]string{"type": "ecdsa-p256"})
+	return c.transitWrite(ctx, "/keys/"+name, body, name)
+}
+
+// CreateEncryptionKey creates an AES-256-GCM encryption key; creating an
+// existing key leaves it unchanged
+func (c *Client) CreateEncryptionKey(ctx context.Context, name string) error {
+	body, _ := json.Marshal(map[string]string{"type": "aes256-gcm96"})
+	return c.transitWrite(ctx, "/keys/"+name, body, name)
+}
+
+// RotateTransitKey adds a new version to a key, which becomes the one used to sign
+func (c *Client) RotateTransitKey(ctx context.Context, name string) error {
+	return c.transitWrite(ctx, "/keys/"+name+"/rotate", nil, name)
+}
+
+// TransitKey returns a key with the public half of every version
+func (c *Client) TransitKey(ctx context.Context, name string) (*TransitKey, error) {
+	var resp struct {
+		Data struct {
+			Name          string                     `json:"name"`
+			Type          string                     `json:"type"`
+			LatestVersion int                        `json:"latest_version"`
+			Keys          map[string]json.RawMessage `json:"keys"`
+		} `json:"data"`
+	}
+	if err := c.transitRead(ctx, "/keys/"+name, name, &resp); err != nil {
+		return nil, err
+	}
+
+	key := &TransitKey{
+		Name:          resp.Data.Name,
+		Type:          resp.Data.Type,
+		LatestVersion: resp.Data.LatestVersion,
+		Versions:      make(map[int]TransitKeyVersion, len(resp.Data.Keys)),
+	}
+	for raw, data := range resp.Data.Keys {
+		version, err := strconv.Atoi(raw)
+		if err != nil {
+			continue
+		}
+		// Symmetric keys only carry a creation timestamp here
+		var v TransitKeyVersion
+		if json.Unmarshal(data, &v) == nil {
+			key.Versions[version] = v
+		}
+	}
+	return key, nil
+}
+
+// ListTransitKeys returns the names of all transit keys
+func (c *Client) ListTransitKeys(ctx context.Context) ([]string, error) {
+	var resp struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err := c.transitRead(ctx, "/keys?list=true", "keys", &resp)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.Data.Keys, nil
+}
+
+// TransitSign signs input with a version of a key. The signature is the raw
+// JWS encoding (base64url of r||s) so it can be used in a JWS directly.
+func (c *Client) TransitSign(ctx context.Context, name string, version int, input []byte) (string, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(input),
+		"key_version":          version,
+		"hash_algorithm":       "sha2-256",
+		"marshaling_algorithm": "jws",
+	})
+
+	resp, err := c.doRequest(ctx, http.MethodPost, c.transitPath("/sign/"+name), body)
+	if err != nil {
+		return "", errors.DependencyFailed("vault", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", c.handleError(resp, name)
+	}
+
+	var signed struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&signed); err != nil {
+		return "", errors.Wrap(err, "failed to decode Vault signature")
+	}
+
+	// vault:v<version>:<signature>
+	signature := signed.Data.Signature
+	return signature[strings.LastIndex(signature, ":")+1:], nil
+}
+
+// TransitEncrypt encrypts plaintext with the latest version of a key. The
+// ciphertext names the key version, so it stays decryptable after rotations.
+func (c *Client) TransitEncrypt(ctx context.Context, name string, plaintext []byte) (string, error) {
+	body, _ := json.Marshal(map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)})
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := c.transitCall(ctx, "/encrypt/"+name, body, name, &resp); err != nil {
+		return "", err
+	}
+	return resp.Data.Ciphertext, nil
+}
+
+// TransitDecrypt decrypts a ciphertext TransitEncrypt returned
+func (c *Client) TransitDecrypt(ctx context.Context, name, ciphertext string) ([]byte, error) {
+	body, _ := json.Marshal(map[string]string{"ciphertext": ciphertext})
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := c.transitCall(ctx, "/decrypt/"+name, body, name, &resp); err != nil {
+		return nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode Vault plaintext")
+	}
+	return plaintext, nil
+}
+
+func (c *Client) transitPath(path string) string {
+	mount := c.config.TransitMount
+	if mount == "" {
+		mount = "transit"
+	}
+	return "/v1/" + strings.Trim(mount, "/") + path
+}
+
+func (c *Client) transitRead(ctx context.Context, path, name string, out interface{}) error {
+	resp, err := c.doRequest(ctx, http.MethodGet, c.transitPath(path), nil)
+	if err != nil {
+		return errors.DependencyFailed("vault", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.handleError(resp, name)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode Vault response")
+	}
+	return nil
+}
+
+func (c *Client) transitWrite(ctx context.Context, path string, body []byte, name string) error {
+	resp, err := c.doRequest(ctx, http.MethodPost, c.transitPath(path), body)
+	if err != nil {
+		return errors.DependencyFailed("vault", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return c.handleError(resp, name)
+	}
+	return nil
+}
+
+// transitCall posts to the transit engine and decodes the response into out
+func (c *Client) transitCall(ctx context.Context, path string, body []byte, name string, out interface{}) error {
+	resp, err := c.doRequest(ctx, http.MethodPost, c.transitPath(path), body)
+	if err != nil {
+		return errors.DependencyFailed("vault", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.handleError(resp, name)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode Vault response")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package alerting
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+)
+
+// WebhookPayload is the Alertmanager webhook payload (version 4)
+type WebhookPayload struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []WebhookAlert    `json:"alerts"`
+}
+
+// WebhookAlert is a single alert in an Alertmanager webhook payload
+type WebhookAlert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// IngestResult summarizes a processed webhook
+type IngestResult struct {
+	Fired    int `json:"fired"`
+	Resolved int `json:"resolved"`
+}
+
+// Ingest processes an Alertmanager webhook payload
+func (m *Manager) Ingest(ctx context.Context, payload *WebhookPayload) (*IngestResult, error) {
+	result := &IngestResult{}
+	for _, wa := range payload.Alerts {
+		alert := FromWebhook(wa)
+
+		if wa.Status == domain.AlertStatusResolved {
+			resolved, err := m.Resolve(ctx, alert.Fingerprint, wa.EndsAt)
+			if err != nil {
+				return nil, err
+			}
+			if resolved != nil {
+				result.Resolved++
+			}
+			continue
+		}
+
+		if _, err := m.Fire(ctx, alert); err != nil {
+			return nil, err
+		}
+		result.Fired++
+	}
+	return result, nil
+}
+
+// FromWebhook converts an Alertmanager alert, attributing it to platform
+// resources through the service_id, project_id and cluster_id labels
+func FromWebhook(wa WebhookAlert) *domain.Alert {
+	severity := wa.Labels["severity"]
+	if severity == "" {
+		severity = "warning"
+	}
+
+	message := wa.Annotations["summary"]
+	if message == "" {
+		message = wa.Annotations["description"]
+	}
+
+	fingerprint := wa.Fingerprint
+	if fingerprint == "" {
+		fingerprint = labelsFingerprint(wa.Labels)
+	}
+
+	alert := &domain.Alert{
+		Fingerprint: SourceAlertmanager + ":" + fingerprint,
+		Name:        wa.Labels["alertname"],
+		Severity:    severity,
+		Source:      SourceAlertmanager,
+		Message:     message,
+		Labels:      wa.Labels,
+		Annotations: wa.Annotations,
+		ServiceID:   labelID(wa.Labels, domain.MetricLabelServiceID),
+		ProjectID:   labelID(wa.Labels, domain.MetricLabelProjectID),
+		ClusterID:   labelID(wa.Labels, domain.MetricLabelClusterID),
+	}
+	if !wa.StartsAt.IsZero() {
+		alert.StartsAt = wa.StartsAt.Unix()
+	}
+	return alert
+}
+
+// labelsFingerprint hashes a label set in a stable order
+func labelsFingerprint(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(labels[name])
+		b.WriteByte(0)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+func labelID(labels map[string]string, key string) *uuid.UUID {
+	id, err := uuid.Parse(labels[key])
+	if err != nil {
+		return nil
+	}
+	return &id
+}
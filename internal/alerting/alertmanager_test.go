@@ -0,0 +1,60 @@
+package alerting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromWebhook(t *testing.T) {
+	serviceID := uuid.New()
+	startsAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	alert := FromWebhook(WebhookAlert{
+		Status: "firing",
+		Labels: map[string]string{
+			"alertname":                 "HighLatency",
+			domain.MetricLabelServiceID: serviceID.String(),
+		},
+		Annotations: map[string]string{"description": "p99 above 2s"},
+		StartsAt:    startsAt,
+	})
+
+	assert.Equal(t, "HighLatency", alert.Name)
+	assert.Equal(t, "warning", alert.Severity)
+	assert.Equal(t, "p99 above 2s", alert.Message)
+	assert.Equal(t, SourceAlertmanager, alert.Source)
+	assert.Equal(t, startsAt.Unix(), alert.StartsAt)
+	assert.Equal(t, &serviceID, alert.ServiceID)
+	assert.Nil(t, alert.ProjectID)
+
+	// Fingerprints fall back to a stable hash of the label set
+	again := FromWebhook(WebhookAlert{Labels: map[string]string{
+		domain.MetricLabelServiceID: serviceID.String(),
+		"alertname":                 "HighLatency",
+	}})
+	assert.Equal(t, alert.Fingerprint, again.Fingerprint)
+}
+
+func TestIsCrashLooping(t *testing.T) {
+	pod := func(reason string) map[string]interface{} {
+		return map[string]interface{}{
+			"status": map[string]interface{}{
+				"containerStatuses": []interface{}{
+					map[string]interface{}{
+						"state": map[string]interface{}{
+							"waiting": map[string]interface{}{"reason": reason},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	assert.True(t, isCrashLooping(pod("CrashLoopBackOff")))
+	assert.False(t, isCrashLooping(pod("ContainerCreating")))
+	assert.False(t, isCrashLooping(map[string]interface{}{}))
+}
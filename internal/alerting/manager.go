@@ -0,0 +1,156 @@
+// Package alerting records alerts raised by the platform's own rule engine or
+// received from Alertmanager, deduplicates them by fingerprint, publishes
+// alert.fired / alert.resolved events and forwards them to the Notifier.
+package alerting
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Alert sources
+const (
+	SourcePlatform     = "platform"
+	SourceAlertmanager = "alertmanager"
+)
+
+// Manager tracks alert lifecycle
+type Manager struct {
+	alertRepo domain.AlertRepository
+	eventBus  domain.EventBus
+	notifier  domain.Notifier
+	logger    *logger.Logger
+}
+
+// NewManager creates a new Manager. notifier may be nil.
+func NewManager(alertRepo domain.AlertRepository, eventBus domain.EventBus, notifier domain.Notifier, log *logger.Logger) *Manager {
+	return &Manager{
+		alertRepo: alertRepo,
+		eventBus:  eventBus,
+		notifier:  notifier,
+		logger:    log,
+	}
+}
+
+// Fire records a firing alert. If an alert with the same fingerprint is
+// already firing it is refreshed instead and no new event is published.
+func (m *Manager) Fire(ctx context.Context, alert *domain.Alert) (*domain.Alert, error) {
+	existing, err := m.alertRepo.GetFiringByFingerprint(ctx, alert.Fingerprint)
+	if err == nil {
+		existing.Severity = alert.Severity
+		existing.Message = alert.Message
+		existing.Labels = alert.Labels
+		existing.Annotations = alert.Annotations
+		if err := m.alertRepo.Update(ctx, existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	alert.ID = uuid.New().String()
+	alert.Status = domain.AlertStatusFiring
+	alert.EndsAt = 0
+	if alert.StartsAt == 0 {
+		alert.StartsAt = time.Now().Unix()
+	}
+	if err := m.alertRepo.Create(ctx, alert); err != nil {
+		return nil, err
+	}
+
+	m.logger.Info().
+		Str("alert_id", alert.ID).
+		Str("name", alert.Name).
+		Str("severity", alert.Severity).
+		Str("source", alert.Source).
+		Msg("Alert fired")
+
+	m.publish(ctx, "alert.fired", alert)
+	m.notify(ctx, alert)
+
+	return alert, nil
+}
+
+// Resolve resolves the firing alert with the given fingerprint, if any
+func (m *Manager) Resolve(ctx context.Context, fingerprint string, endsAt time.Time) (*domain.Alert, error) {
+	alert, err := m.alertRepo.GetFiringByFingerprint(ctx, fingerprint)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if endsAt.IsZero() {
+		endsAt = time.Now()
+	}
+	alert.Status = domain.AlertStatusResolved
+	alert.EndsAt = endsAt.Unix()
+	if err := m.alertRepo.Update(ctx, alert); err != nil {
+		return nil, err
+	}
+
+	m.logger.Info().
+		Str("alert_id", alert.ID).
+		Str("name", alert.Name).
+		Msg("Alert resolved")
+
+	m.publish(ctx, "alert.resolved", alert)
+	m.notify(ctx, alert)
+
+	return alert, nil
+}
+
+// List lists alerts
+func (m *Manager) List(ctx context.Context, filter domain.AlertFilter) ([]*domain.Alert, error) {
+	return m.alertRepo.List(ctx, filter)
+}
+
+// Get retrieves an alert by ID
+func (m *Manager) Get(ctx context.Context, id string) (*domain.Alert, error) {
+	return m.alertRepo.GetByID(ctx, id)
+}
+
+func (m *Manager) publish(ctx context.Context, eventType string, alert *domain.Alert) {
+	data := map[string]interface{}{
+		"alert_id":    alert.ID,
+		"fingerprint": alert.Fingerprint,
+		"name":        alert.Name,
+		"severity":    alert.Severity,
+		"source":      alert.Source,
+		"message":     alert.Message,
+	}
+	if alert.ServiceID != nil {
+		data["service_id"] = alert.ServiceID.String()
+	}
+	if alert.ProjectID != nil {
+		data["project_id"] = alert.ProjectID.String()
+	}
+	if alert.ClusterID != nil {
+		data["cluster_id"] = alert.ClusterID.String()
+	}
+
+	if err := m.eventBus.Publish(ctx, eventType, &domain.Event{
+		Type:   eventType,
+		Source: "alerting",
+		Data:   data,
+	}); err != nil {
+		m.logger.Error().Err(err).Str("alert_id", alert.ID).Msg("Failed to publish alert event")
+	}
+}
+
+func (m *Manager) notify(ctx context.Context, alert *domain.Alert) {
+	if m.notifier == nil {
+		return
+	}
+	if err := m.notifier.SendAlertNotification(ctx, alert); err != nil {
+		m.logger.Warn().Err(err).Str("alert_id", alert.ID).Msg("Failed to send alert notification")
+	}
+}
@@ -0,0 +1,288 @@
+package alerting
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// errorRateWindow is the lookback used by the error rate rule
+const errorRateWindow = 5 * time.Minute
+
+// Built-in rule names
+const (
+	RuleServiceErrorRate = "ServiceErrorRate"
+	RuleCrashLoop        = "CrashLoopBackOff"
+	RuleClusterUnhealthy = "ClusterUnhealthy"
+)
+
+// rule evaluates to the set of alerts that should currently be firing
+type rule struct {
+	name string
+	eval func(ctx context.Context) ([]*domain.Alert, error)
+}
+
+// Engine periodically evaluates the built-in alert rules
+type Engine struct {
+	config      *config.AlertingConfig
+	manager     *Manager
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	clusterRepo domain.ClusterRepository
+	kube        domain.KubernetesClient
+	metrics     domain.MetricsCollector
+	logger      *logger.Logger
+}
+
+// NewEngine creates a new Engine. Rules whose dependencies are nil are skipped.
+func NewEngine(
+	cfg *config.AlertingConfig,
+	manager *Manager,
+	projectRepo domain.ProjectRepository,
+	serviceRepo domain.ServiceRepository,
+	clusterRepo domain.ClusterRepository,
+	kube domain.KubernetesClient,
+	metrics domain.MetricsCollector,
+	log *logger.Logger,
+) *Engine {
+	return &Engine{
+		config:      cfg,
+		manager:     manager,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		clusterRepo: clusterRepo,
+		kube:        kube,
+		metrics:     metrics,
+		logger:      log,
+	}
+}
+
+// Run evaluates the rules every EvaluationInterval until ctx is cancelled
+func (e *Engine) Run(ctx context.Context) {
+	rules := e.rules()
+	if len(rules) == 0 {
+		e.logger.Info().Msg("No alert rules have their dependencies configured, rule engine idle")
+		return
+	}
+
+	ticker := time.NewTicker(e.config.EvaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		e.evaluate(ctx, rules)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// evaluate runs the rules once, firing new alerts and resolving alerts whose
+// condition has cleared. Alerts of a rule that failed to evaluate are left as is.
+func (e *Engine) evaluate(ctx context.Context, rules []rule) {
+	active := make(map[string]bool)
+	evaluated := make(map[string]bool)
+
+	for _, r := range rules {
+		alerts, err := r.eval(ctx)
+		if err != nil {
+			e.logger.Warn().Err(err).Str("rule", r.name).Msg("Alert rule evaluation failed")
+			continue
+		}
+		evaluated[r.name] = true
+
+		for _, alert := range alerts {
+			alert.Name = r.name
+			alert.Source = SourcePlatform
+			alert.Fingerprint = ruleFingerprint(r.name, alert.Fingerprint)
+			active[alert.Fingerprint] = true
+			if _, err := e.manager.Fire(ctx, alert); err != nil {
+				e.logger.Error().Err(err).Str("rule", r.name).Msg("Failed to fire alert")
+			}
+		}
+	}
+
+	firing, err := e.manager.List(ctx, domain.AlertFilter{Status: domain.AlertStatusFiring, Source: SourcePlatform})
+	if err != nil {
+		e.logger.Error().Err(err).Msg("Failed to list firing alerts")
+		return
+	}
+	for _, alert := range firing {
+		if !evaluated[alert.Name] || active[alert.Fingerprint] {
+			continue
+		}
+		if _, err := e.manager.Resolve(ctx, alert.Fingerprint, time.Now()); err != nil {
+			e.logger.Error().Err(err).Str("alert_id", alert.ID).Msg("Failed to resolve alert")
+		}
+	}
+}
+
+func (e *Engine) rules() []rule {
+	var rules []rule
+	if e.metrics != nil && e.projectRepo != nil && e.serviceRepo != nil {
+		rules = append(rules, rule{name: RuleServiceErrorRate, eval: e.serviceErrorRate})
+	}
+	if e.kube != nil && e.clusterRepo != nil {
+		rules = append(rules, rule{name: RuleCrashLoop, eval: e.crashLoops})
+	}
+	if e.clusterRepo != nil {
+		rules = append(rules, rule{name: RuleClusterUnhealthy, eval: e.unhealthyClusters})
+	}
+	return rules
+}
+
+// serviceErrorRate fires for services whose mean error rate over the last
+// window exceeds the threshold, and escalates to critical at twice the threshold
+func (e *Engine) serviceErrorRate(ctx context.Context) ([]*domain.Alert, error) {
+	projects, err := e.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	end := time.Now()
+	window := domain.TimeRange{Start: end.Add(-errorRateWindow).Unix(), End: end.Unix(), Step: 60}
+
+	var alerts []*domain.Alert
+	for _, project := range projects {
+		services, err := e.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, svc := range services {
+			m, err := e.metrics.GetServiceMetrics(ctx, svc.ID, window)
+			if err != nil {
+				return nil, err
+			}
+
+			rate := meanValue(m.ErrorRate)
+			if rate <= e.config.ErrorRateThreshold {
+				continue
+			}
+
+			severity := "warning"
+			if rate > 2*e.config.ErrorRateThreshold {
+				severity = "critical"
+			}
+
+			serviceID, projectID := svc.ID, project.ID
+			alerts = append(alerts, &domain.Alert{
+				Fingerprint: svc.ID.String(),
+				Severity:    severity,
+				Message:     fmt.Sprintf("Service %s error rate is %.1f%% (threshold %.1f%%)", svc.Name, rate*100, e.config.ErrorRateThreshold*100),
+				Labels:      map[string]string{"service": svc.Name, "project": project.Name},
+				ServiceID:   &serviceID,
+				ProjectID:   &projectID,
+			})
+		}
+	}
+	return alerts, nil
+}
+
+// crashLoops fires for services with at least one container in CrashLoopBackOff
+func (e *Engine) crashLoops(ctx context.Context) ([]*domain.Alert, error) {
+	clusters, err := e.clusterRepo.List(ctx, domain.ClusterFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []*domain.Alert
+	for _, cluster := range clusters {
+		pods, err := e.kube.ListResources(ctx, cluster.ID, "Pod", "", map[string]string{
+			domain.LabelManagedBy: domain.ManagedByValue,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		crashing := make(map[string][]string) // service ID -> pod names
+		for _, pod := range pods {
+			serviceID, _, _ := unstructured.NestedString(pod, "metadata", "labels", domain.LabelServiceID)
+			if serviceID == "" || !isCrashLooping(pod) {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(pod, "metadata", "name")
+			crashing[serviceID] = append(crashing[serviceID], name)
+		}
+
+		for serviceID, names := range crashing {
+			sort.Strings(names)
+			alert := &domain.Alert{
+				Fingerprint: cluster.ID.String() + "/" + serviceID,
+				Severity:    "critical",
+				Message:     fmt.Sprintf("%d instance(s) in CrashLoopBackOff: %s", len(names), strings.Join(names, ", ")),
+				Labels:      map[string]string{"cluster": cluster.Name},
+			}
+			clusterID := cluster.ID
+			alert.ClusterID = &clusterID
+			if id, err := uuid.Parse(serviceID); err == nil {
+				alert.ServiceID = &id
+			}
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts, nil
+}
+
+// unhealthyClusters fires for clusters reported unhealthy
+func (e *Engine) unhealthyClusters(ctx context.Context) ([]*domain.Alert, error) {
+	clusters, err := e.clusterRepo.List(ctx, domain.ClusterFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []*domain.Alert
+	for _, cluster := range clusters {
+		if cluster.Status != domain.ClusterStatusUnhealthy {
+			continue
+		}
+		clusterID := cluster.ID
+		alerts = append(alerts, &domain.Alert{
+			Fingerprint: cluster.ID.String(),
+			Severity:    "critical",
+			Message:     fmt.Sprintf("Cluster %s is unhealthy", cluster.Name),
+			Labels:      map[string]string{"cluster": cluster.Name, "provider": string(cluster.Provider)},
+			ClusterID:   &clusterID,
+		})
+	}
+	return alerts, nil
+}
+
+func isCrashLooping(pod map[string]interface{}) bool {
+	statuses, _, _ := unstructured.NestedSlice(pod, "status", "containerStatuses")
+	for _, st := range statuses {
+		m, ok := st.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if reason, _, _ := unstructured.NestedString(m, "state", "waiting", "reason"); reason == "CrashLoopBackOff" {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleFingerprint(rule, subject string) string {
+	return SourcePlatform + ":" + rule + ":" + subject
+}
+
+func meanValue(points []domain.MetricPoint) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, p := range points {
+		sum += p.Value
+	}
+	return sum / float64(len(points))
+}
@@ -0,0 +1,236 @@
+// Package anomaly detects post-deploy regressions without user-defined thresholds.
+// After a deployment completes, the error rate and p99 latency observed after the
+// rollout are compared to a pre-deploy baseline of the same service.
+package anomaly
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/internal/workflow"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Signal names
+const (
+	SignalErrorRate  = "error_rate"
+	SignalLatencyP99 = "latency_p99"
+)
+
+// Report is the outcome of comparing a deployment to its baseline
+type Report struct {
+	DeploymentID *uuid.UUID       `json:"deployment_id,omitempty"`
+	ServiceID    uuid.UUID        `json:"service_id"`
+	Regressed    bool             `json:"regressed"`
+	Signals      []SignalResult   `json:"signals"`
+	RolledBack   bool             `json:"rolled_back"`
+	AnalyzedAt   time.Time        `json:"analyzed_at"`
+	Window       domain.TimeRange `json:"window"`
+}
+
+// Detector watches completed deployments for regressions
+type Detector struct {
+	config       *config.AnomalyDetectionConfig
+	metrics      domain.MetricsCollector
+	deployRepo   domain.DeploymentRepository
+	stateMachine *workflow.StateMachine
+	eventBus     domain.EventBus
+	logger       *logger.Logger
+
+	mu sync.Mutex
+	// scheduled holds the IDs of the events whose analysis is pending, so a
+	// redelivered event does not schedule a second one
+	scheduled map[string]bool
+}
+
+// NewDetector creates a new Detector. deployRepo and stateMachine may be nil,
+// in which case regressions are only published as events.
+func NewDetector(
+	cfg *config.AnomalyDetectionConfig,
+	metrics domain.MetricsCollector,
+	deployRepo domain.DeploymentRepository,
+	stateMachine *workflow.StateMachine,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *Detector {
+	return &Detector{
+		config:       cfg,
+		metrics:      metrics,
+		deployRepo:   deployRepo,
+		stateMachine: stateMachine,
+		eventBus:     eventBus,
+		logger:       log,
+		scheduled:    make(map[string]bool),
+	}
+}
+
+// Watch schedules an analysis for every completed deployment. Events go
+// through a durable consumer, so one replica analyzes each deployment.
+func (d *Detector) Watch(ctx context.Context) error {
+	_, err := eventbus.SubscribeDurable(ctx, d.eventBus, "deploy.completed", eventbus.DurableName("anomaly", "deploy.completed"), func(event *domain.Event) error {
+		raw, _ := event.Data["service_id"].(string)
+		serviceID, err := uuid.Parse(raw)
+		if err != nil {
+			return nil
+		}
+
+		var deploymentID, workflowID *uuid.UUID
+		if id, err := uuid.Parse(stringField(event.Data, "deployment_id")); err == nil {
+			deploymentID = &id
+		}
+		if id, err := uuid.Parse(stringField(event.Data, "workflow_id")); err == nil {
+			workflowID = &id
+		}
+
+		deployedAt := time.Now()
+		if event.Timestamp > 0 {
+			deployedAt = time.Unix(0, event.Timestamp)
+		}
+
+		if !d.schedule(event.ID) {
+			return nil
+		}
+		go func() {
+			defer d.done(event.ID)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Until(deployedAt.Add(d.config.ObservationWindow))):
+			}
+			d.analyzeAndAct(ctx, serviceID, deploymentID, workflowID, deployedAt)
+		}()
+		return nil
+	})
+	return err
+}
+
+// schedule records that the analysis of an event is pending, and reports
+// false if it already was
+func (d *Detector) schedule(eventID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.scheduled[eventID] {
+		return false
+	}
+	d.scheduled[eventID] = true
+	return true
+}
+
+// done forgets an event once its analysis ran
+func (d *Detector) done(eventID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.scheduled, eventID)
+}
+
+// Analyze compares the observation window after deployedAt to the preceding baseline
+func (d *Detector) Analyze(ctx context.Context, serviceID uuid.UUID, deployedAt time.Time) (*Report, error) {
+	window := domain.TimeRange{
+		Start: deployedAt.Add(-d.config.BaselineWindow).Unix(),
+		End:   deployedAt.Add(d.config.ObservationWindow).Unix(),
+		Step:  30,
+	}
+
+	metrics, err := d.metrics.GetServiceMetrics(ctx, serviceID, window)
+	if err != nil {
+		return nil, err
+	}
+
+	split := deployedAt.Unix()
+	report := &Report{
+		ServiceID:  serviceID,
+		AnalyzedAt: time.Now().UTC(),
+		Window:     window,
+		Signals: []SignalResult{
+			compare(SignalErrorRate, metrics.ErrorRate, split, errorRateFloor),
+			compare(SignalLatencyP99, metrics.Latency.P99, split, latencyFloor),
+		},
+	}
+	for _, s := range report.Signals {
+		if s.Regressed {
+			report.Regressed = true
+		}
+	}
+	return report, nil
+}
+
+func (d *Detector) analyzeAndAct(ctx context.Context, serviceID uuid.UUID, deploymentID, workflowID *uuid.UUID, deployedAt time.Time) {
+	report, err := d.Analyze(ctx, serviceID, deployedAt)
+	if err != nil {
+		d.logger.Warn().Err(err).Str("service_id", serviceID.String()).Msg("Post-deploy anomaly analysis failed")
+		return
+	}
+	report.DeploymentID = deploymentID
+
+	if !report.Regressed {
+		d.record(ctx, report)
+		return
+	}
+
+	d.logger.Warn().
+		Str("service_id", serviceID.String()).
+		Interface("signals", report.Signals).
+		Msg("Post-deploy regression detected")
+
+	if d.config.AutoRollback && d.stateMachine != nil && workflowID != nil {
+		if err := d.stateMachine.ProcessEvent(ctx, *workflowID, workflow.EventTriggerRollback, map[string]interface{}{
+			"error": "automatic rollback: post-deploy regression detected",
+		}); err != nil {
+			d.logger.Error().Err(err).Str("workflow_id", workflowID.String()).Msg("Failed to trigger automatic rollback")
+		} else {
+			report.RolledBack = true
+		}
+	}
+
+	d.record(ctx, report)
+
+	data := map[string]interface{}{
+		"service_id":  serviceID.String(),
+		"signals":     report.Signals,
+		"rolled_back": report.RolledBack,
+	}
+	if deploymentID != nil {
+		data["deployment_id"] = deploymentID.String()
+	}
+	if err := d.eventBus.Publish(ctx, "deploy.anomaly_detected", &domain.Event{
+		Type:   "deploy.anomaly_detected",
+		Source: "anomaly-detector",
+		Data:   data,
+	}); err != nil {
+		d.logger.Error().Err(err).Msg("Failed to publish anomaly event")
+	}
+}
+
+// record stores the report on the deployment record
+func (d *Detector) record(ctx context.Context, report *Report) {
+	if d.deployRepo == nil || report.DeploymentID == nil {
+		return
+	}
+
+	deployment, err := d.deployRepo.GetByID(ctx, *report.DeploymentID)
+	if err != nil {
+		d.logger.Warn().Err(err).Str("deployment_id", report.DeploymentID.String()).Msg("Failed to load deployment for anomaly report")
+		return
+	}
+	if deployment.Metadata == nil {
+		deployment.Metadata = make(map[string]interface{})
+	}
+	deployment.Metadata["anomaly"] = report
+	if report.RolledBack {
+		deployment.Status = domain.DeploymentStatusRolledBack
+	}
+
+	if err := d.deployRepo.Update(ctx, deployment); err != nil {
+		d.logger.Warn().Err(err).Str("deployment_id", report.DeploymentID.String()).Msg("Failed to record anomaly report")
+	}
+}
+
+func stringField(data map[string]interface{}, key string) string {
+	s, _ := data[key].(string)
+	return s
+}
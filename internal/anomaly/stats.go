@@ -0,0 +1,77 @@
+package anomaly
+
+import (
+	"math"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+const (
+	// zThreshold is how many baseline standard deviations count as anomalous
+	zThreshold = 3.0
+	// minRelativeIncrease ignores statistically significant but tiny shifts
+	minRelativeIncrease = 0.2
+	// minSamples is the number of points needed on each side of the split
+	minSamples = 5
+
+	// Absolute floors below which a change is never a regression
+	errorRateFloor = 0.01 // one percentage point
+	latencyFloor   = 0.05 // 50ms
+)
+
+// SignalResult is the comparison of one signal before and after a deployment
+type SignalResult struct {
+	Name         string  `json:"name"`
+	BaselineMean float64 `json:"baseline_mean"`
+	BaselineStd  float64 `json:"baseline_std"`
+	CurrentMean  float64 `json:"current_mean"`
+	ZScore       float64 `json:"z_score"`
+	Regressed    bool    `json:"regressed"`
+	Insufficient bool    `json:"insufficient_data,omitempty"`
+}
+
+// compare splits the series at split (unix seconds) and tests whether the
+// post-split mean is significantly above the baseline
+func compare(name string, points []domain.MetricPoint, split int64, floor float64) SignalResult {
+	var baseline, current []float64
+	for _, p := range points {
+		if p.Timestamp < split {
+			baseline = append(baseline, p.Value)
+		} else {
+			current = append(current, p.Value)
+		}
+	}
+
+	result := SignalResult{Name: name}
+	if len(baseline) < minSamples || len(current) < minSamples {
+		result.Insufficient = true
+		return result
+	}
+
+	result.BaselineMean, result.BaselineStd = meanStd(baseline)
+	result.CurrentMean, _ = meanStd(current)
+
+	// A perfectly flat baseline would make any change infinitely significant
+	std := math.Max(result.BaselineStd, math.Max(result.BaselineMean*0.05, 1e-9))
+	result.ZScore = (result.CurrentMean - result.BaselineMean) / std
+
+	increase := result.CurrentMean - result.BaselineMean
+	relative := increase / math.Max(result.BaselineMean, 1e-9)
+	result.Regressed = result.ZScore >= zThreshold && increase >= floor && relative >= minRelativeIncrease
+
+	return result
+}
+
+func meanStd(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
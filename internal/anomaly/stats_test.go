@@ -0,0 +1,55 @@
+package anomaly
+
+import (
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func series(start int64, values ...float64) []domain.MetricPoint {
+	points := make([]domain.MetricPoint, len(values))
+	for i, v := range values {
+		points[i] = domain.MetricPoint{Timestamp: start + int64(i)*30, Value: v}
+	}
+	return points
+}
+
+func TestCompare(t *testing.T) {
+	split := int64(300)
+
+	t.Run("error rate regression", func(t *testing.T) {
+		points := append(
+			series(0, 0.01, 0.012, 0.009, 0.011, 0.01, 0.01, 0.011, 0.009, 0.01, 0.01),
+			series(split, 0.08, 0.09, 0.1, 0.085, 0.095)...,
+		)
+		result := compare(SignalErrorRate, points, split, errorRateFloor)
+		assert.True(t, result.Regressed)
+		assert.False(t, result.Insufficient)
+	})
+
+	t.Run("noise within baseline", func(t *testing.T) {
+		points := append(
+			series(0, 0.2, 0.25, 0.18, 0.22, 0.3, 0.19, 0.21, 0.24, 0.2, 0.23),
+			series(split, 0.22, 0.24, 0.21, 0.25, 0.2)...,
+		)
+		result := compare(SignalLatencyP99, points, split, latencyFloor)
+		assert.False(t, result.Regressed)
+	})
+
+	t.Run("small absolute change is ignored", func(t *testing.T) {
+		points := append(
+			series(0, 0, 0, 0, 0, 0, 0),
+			series(split, 0.001, 0.002, 0.001, 0.001, 0.002)...,
+		)
+		result := compare(SignalErrorRate, points, split, errorRateFloor)
+		assert.False(t, result.Regressed)
+	})
+
+	t.Run("insufficient data", func(t *testing.T) {
+		points := append(series(0, 0.1, 0.1), series(split, 0.5)...)
+		result := compare(SignalErrorRate, points, split, errorRateFloor)
+		assert.True(t, result.Insufficient)
+		assert.False(t, result.Regressed)
+	})
+}
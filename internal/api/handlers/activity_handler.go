@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/activity"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// ActivityHandler handles the project activity feed endpoint
+type ActivityHandler struct {
+	feed        *activity.Feed
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewActivityHandler creates a new ActivityHandler
+func NewActivityHandler(feed *activity.Feed, projectRepo domain.ProjectRepository, log *logger.Logger) *ActivityHandler {
+	return &ActivityHandler{
+		feed:        feed,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// List handles GET /projects/:id/activity
+// Query: category (comma-separated: build, deploy, service, secret), cursor, limit (default 50, max 100)
+func (h *ActivityHandler) List(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	var categories []string
+	if v := c.Query("category"); v != "" {
+		categories = strings.Split(v, ",")
+	}
+
+	limit := parseIntQuery(c, "limit", 50)
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	if _, err := h.projectRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	page, err := h.feed.List(c.Request.Context(), id, categories, c.Query("cursor"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
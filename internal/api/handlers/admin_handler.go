@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/queuetime"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// healthCheckTimeout bounds each component check of GET /admin/health
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck reports whether a platform component, e.g. the database, is up
+type HealthCheck func(ctx context.Context) error
+
+// AdminHandler serves the platform operator API under /admin. Unlike the
+// tenant-scoped API it sees every tenant's users, teams and projects.
+type AdminHandler struct {
+	userRepo     domain.UserRepository
+	teamRepo     domain.TeamRepository
+	projectRepo  domain.ProjectRepository
+	serviceRepo  domain.ServiceRepository
+	queueMonitor *queuetime.Monitor
+	checks       map[string]HealthCheck
+	auditLogger  *audit.Logger
+	eventBus     domain.EventBus
+	logger       *logger.Logger
+}
+
+// NewAdminHandler creates a new AdminHandler. userRepo, teamRepo and
+// queueMonitor may be nil, in which case their endpoints answer 501;
+// auditLogger may be nil, in which case operator actions are only logged.
+func NewAdminHandler(
+	userRepo domain.UserRepository,
+	teamRepo domain.TeamRepository,
+	projectRepo domain.ProjectRepository,
+	serviceRepo domain.ServiceRepository,
+	queueMonitor *queuetime.Monitor,
+	checks map[string]HealthCheck,
+	auditLogger *audit.Logger,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *AdminHandler {
+	return &AdminHandler{
+		userRepo:     userRepo,
+		teamRepo:     teamRepo,
+		projectRepo:  projectRepo,
+		serviceRepo:  serviceRepo,
+		queueMonitor: queueMonitor,
+		checks:       checks,
+		auditLogger:  auditLogger,
+		eventBus:     eventBus,
+		logger:       log,
+	}
+}
+
+// ComponentHealth is the state of one component in GET /admin/health
+type ComponentHealth struct {
+	Status    string  `json:"status"` // ok or down
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// PlatformHealth is the response body of GET /admin/health
+type PlatformHealth struct {
+	Status     string                     `json:"status"` // ok, or degraded when a component is down
+	Components map[string]ComponentHealth `json:"components"`
+	CheckedAt  time.Time                  `json:"checked_at"`
+}
+
+// ListUsers handles GET /admin/users
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	if h.userRepo == nil {
+		NotImplemented(c, "user management is not configured")
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", 50)
+	offset := parseIntQuery(c, "offset", 0)
+	users, err := h.userRepo.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   users,
+		"count":  len(users),
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// DisableUser handles POST /admin/users/:id/disable. Disabled users can
+// neither log in nor use tokens they already hold.
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	h.setUserActive(c, false)
+}
+
+// EnableUser handles POST /admin/users/:id/enable
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	h.setUserActive(c, true)
+}
+
+func (h *AdminHandler) setUserActive(c *gin.Context, active bool) {
+	if h.userRepo == nil {
+		NotImplemented(c, "user management is not configured")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid user ID"))
+		return
+	}
+	operatorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if !active && id == operatorID {
+		respondError(c, errors.BadRequest("administrators cannot disable their own account"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.userRepo.GetByID(ctx, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	wasActive := user.IsActive
+	user.IsActive = active
+	user.Status = domain.UserStatusActive
+	if !active {
+		user.Status = domain.UserStatusSuspended
+	}
+	user.UpdatedAt = time.Now()
+	if err := h.userRepo.Update(ctx, user); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.audit(c, audit.LogOptions{
+		UserID:       operatorID,
+		Action:       domain.AuditActionUpdate,
+		ResourceType: "user",
+		ResourceID:   user.ID,
+		ResourceName: user.Email,
+		OldValue:     map[string]interface{}{"is_active": wasActive},
+		NewValue:     map[string]interface{}{"is_active": active},
+	})
+
+	h.logger.Info().
+		Str("user_id", user.ID.String()).
+		Str("operator_id", operatorID.String()).
+		Bool("active", active).
+		Msg("User account status changed by operator")
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ListTeams handles GET /admin/teams
+func (h *AdminHandler) ListTeams(c *gin.Context) {
+	if h.teamRepo == nil {
+		NotImplemented(c, "team management is not configured")
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", 50)
+	offset := parseIntQuery(c, "offset", 0)
+	teams, err := h.teamRepo.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   teams,
+		"count":  len(teams),
+		"offset": offset,
+		"limit":  limit,
+	})
+}
+
+// ListProjects handles GET /admin/projects, across all tenants
+func (h *AdminHandler) ListProjects(c *gin.Context) {
+	filter := domain.ProjectFilter{
+		Search: c.Query("search"),
+		Limit:  parseIntQuery(c, "limit", 50),
+		Offset: parseIntQuery(c, "offset", 0),
+	}
+	if ownerID, err := uuid.Parse(c.Query("owner_id")); err == nil {
+		filter.OwnerID = &ownerID
+	}
+	if teamID, err := uuid.Parse(c.Query("team_id")); err == nil {
+		filter.TeamID = &teamID
+	}
+	if status := c.Query("status"); status != "" {
+		s := domain.ProjectStatus(status)
+		filter.Status = &s
+	}
+
+	projects, err := h.projectRepo.List(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	responses := make([]ProjectResponse, len(projects))
+	for i, p := range projects {
+		responses[i] = projectToResponse(p)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   responses,
+		"count":  len(responses),
+		"offset": filter.Offset,
+		"limit":  filter.Limit,
+	})
+}
+
+// ForceDeleteProject handles DELETE /admin/projects/:id. The project's rows
+// cascade in the database; service.deleted is published for each service
+// first so that the workloads are torn down as well.
+func (h *AdminHandler) ForceDeleteProject(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+	operatorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	project, err := h.projectRepo.GetByID(ctx, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	services, err := h.serviceRepo.ListByProject(ctx, id, domain.ServiceFilter{})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.projectRepo.Delete(ctx, id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	for _, service := range services {
+		h.publishServiceDeleted(ctx, service)
+	}
+	h.eventBus.Publish(ctx, "project.deleted", &domain.Event{
+		Type:   "project.deleted",
+		Source: "admin",
+		Data: map[string]interface{}{
+			"project_id": project.ID.String(),
+			"name":       project.Name,
+			"forced":     true,
+		},
+	})
+
+	h.audit(c, audit.LogOptions{
+		UserID:       operatorID,
+		Action:       domain.AuditActionDelete,
+		ResourceType: "project",
+		ResourceID:   project.ID,
+		ResourceName: project.Name,
+		ProjectID:    &project.ID,
+		Metadata:     map[string]interface{}{"forced": true, "services": len(services)},
+	})
+
+	h.logger.Warn().
+		Str("project_id", project.ID.String()).
+		Str("operator_id", operatorID.String()).
+		Int("services", len(services)).
+		Msg("Project force-deleted by operator")
+
+	c.Status(http.StatusNoContent)
+}
+
+// ForceDeleteService handles DELETE /admin/services/:id
+func (h *AdminHandler) ForceDeleteService(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+	operatorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	service, err := h.serviceRepo.GetByID(ctx, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.serviceRepo.Delete(ctx, id); err != nil {
+		respondError(c, err)
+		return
+	}
+	h.publishServiceDeleted(ctx, service)
+
+	h.audit(c, audit.LogOptions{
+		UserID:       operatorID,
+		Action:       domain.AuditActionDelete,
+		ResourceType: "service",
+		ResourceID:   service.ID,
+		ResourceName: service.Name,
+		ProjectID:    &service.ProjectID,
+		Metadata:     map[string]interface{}{"forced": true},
+	})
+
+	h.logger.Warn().
+		Str("service_id", service.ID.String()).
+		Str("operator_id", operatorID.String()).
+		Msg("Service force-deleted by operator")
+
+	c.Status(http.StatusNoContent)
+}
+
+// Health handles GET /admin/health. Components are checked concurrently; the
+// response is 200 either way so that the breakdown is always readable.
+func (h *AdminHandler) Health(c *gin.Context) {
+	health := PlatformHealth{
+		Status:     "ok",
+		Components: make(map[string]ComponentHealth, len(h.checks)),
+		CheckedAt:  time.Now().UTC(),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range h.checks {
+		wg.Add(1)
+		go func(name string, check HealthCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(ctx)
+			component := ComponentHealth{
+				Status:    "ok",
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				component.Status = "down"
+				component.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			health.Components[name] = component
+			if err != nil {
+				health.Status = "degraded"
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, health)
+}
+
+// Queues handles GET /admin/queues: how many builds and deployments are
+// waiting platform-wide and how long they have waited
+func (h *AdminHandler) Queues(c *gin.Context) {
+	if h.queueMonitor == nil {
+		NotImplemented(c, "queue time monitoring is not configured")
+		return
+	}
+
+	stats, err := h.queueMonitor.PlatformStats(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Kind < stats[j].Kind })
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  stats,
+		"count": len(stats),
+	})
+}
+
+func (h *AdminHandler) publishServiceDeleted(ctx context.Context, service *domain.Service) {
+	h.eventBus.Publish(ctx, "service.deleted", &domain.Event{
+		Type:   "service.deleted",
+		Source: "admin",
+		Data: map[string]interface{}{
+			"service_id": service.ID.String(),
+			"project_id": service.ProjectID.String(),
+			"name":       service.Name,
+			"forced":     true,
+		},
+	})
+}
+
+// audit records an operator action; failures are logged by the audit logger
+func (h *AdminHandler) audit(c *gin.Context, opts audit.LogOptions) {
+	if h.auditLogger == nil {
+		return
+	}
+	opts.IPAddress = c.ClientIP()
+	opts.UserAgent = c.Request.UserAgent()
+	_ = h.auditLogger.Log(c.Request.Context(), opts)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHealthReportsDegradedComponents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewAdminHandler(nil, nil, nil, nil, nil, map[string]HealthCheck{
+		"database": func(ctx context.Context) error { return nil },
+		"nats":     func(ctx context.Context) error { return fmt.Errorf("nats connection is RECONNECTING") },
+	}, nil, nil, logger.New("error", "json", io.Discard))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/health", nil)
+	h.Health(c)
+
+	var health PlatformHealth
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "degraded", health.Status)
+	assert.Equal(t, "ok", health.Components["database"].Status)
+	assert.Equal(t, "down", health.Components["nats"].Status)
+	assert.Equal(t, "nats connection is RECONNECTING", health.Components["nats"].Error)
+}
+
+// stubUserRepo fails the test with a nil dereference if it is used
+type stubUserRepo struct{ domain.UserRepository }
+
+func TestAdminCannotDisableOwnAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewAdminHandler(stubUserRepo{}, nil, nil, nil, nil, nil, nil, nil, logger.New("error", "json", io.Discard))
+
+	operatorID := uuid.New()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/"+operatorID.String()+"/disable", nil)
+	c.Params = gin.Params{{Key: "id", Value: operatorID.String()}}
+	c.Set("user_id", operatorID)
+	h.DisableUser(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/natsauth"
+	"github.com/northstack/platform/internal/workers"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// AgentCredentialsHandler issues the NATS credentials of cluster agents,
+// limited to the subjects of their own cluster
+type AgentCredentialsHandler struct {
+	issuer      *natsauth.Issuer
+	config      *config.AgentsConfig
+	clusterRepo domain.ClusterRepository
+	auditLogger *audit.Logger
+	logger      *logger.Logger
+}
+
+// NewAgentCredentialsHandler creates a new AgentCredentialsHandler.
+// auditLogger may be nil.
+func NewAgentCredentialsHandler(issuer *natsauth.Issuer, cfg *config.AgentsConfig, clusterRepo domain.ClusterRepository, auditLogger *audit.Logger, log *logger.Logger) *AgentCredentialsHandler {
+	return &AgentCredentialsHandler{
+		issuer:      issuer,
+		config:      cfg,
+		clusterRepo: clusterRepo,
+		auditLogger: auditLogger,
+		logger:      log,
+	}
+}
+
+// Issue handles POST /clusters/:id/agent-credentials. The credentials answer
+// requests on agent.<cluster ID>.> and nothing else, so an agent cannot act
+// on, or for, another cluster.
+func (h *AgentCredentialsHandler) Issue(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid cluster ID"))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	cluster, err := h.clusterRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	subjects := workers.AgentSubjects(cluster.ID)
+	creds, err := h.issuer.Responder("agent-"+cluster.ID.String(), subjects, h.config.CredentialsTTL)
+	if err != nil {
+		respondError(c, errors.Wrap(err, "failed to issue agent credentials"))
+		return
+	}
+
+	if h.auditLogger != nil {
+		_ = h.auditLogger.Log(c.Request.Context(), audit.LogOptions{
+			UserID:       userID,
+			Action:       domain.AuditActionIssueCredentials,
+			ResourceType: "cluster",
+			ResourceID:   cluster.ID,
+			ResourceName: cluster.Name,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+			Metadata:     map[string]interface{}{"subjects": subjects, "kind": "nats_agent"},
+		})
+	}
+
+	h.logger.Info().
+		Str("user_id", userID.String()).
+		Str("cluster_id", cluster.ID.String()).
+		Msg("Agent credentials issued")
+
+	response := EventCredentialsResponse{
+		Credentials: creds.File(),
+		Subjects:    subjects,
+		URL:         h.config.URL,
+	}
+	if !creds.ExpiresAt.IsZero() {
+		response.ExpiresAt = &creds.ExpiresAt
+	}
+	c.JSON(http.StatusCreated, response)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/alerting"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// AlertsHandler handles alert endpoints
+type AlertsHandler struct {
+	manager      *alerting.Manager
+	webhookToken string
+	logger       *logger.Logger
+}
+
+// NewAlertsHandler creates a new AlertsHandler. The Alertmanager receiver
+// refuses every request while webhookToken is empty.
+func NewAlertsHandler(manager *alerting.Manager, webhookToken string, log *logger.Logger) *AlertsHandler {
+	return &AlertsHandler{
+		manager:      manager,
+		webhookToken: webhookToken,
+		logger:       log,
+	}
+}
+
+// Webhook handles POST /alerts/webhook (Alertmanager webhook receiver)
+func (h *AlertsHandler) Webhook(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if h.webhookToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.webhookToken)) != 1 {
+		respondError(c, errors.Unauthorized("invalid webhook token"))
+		return
+	}
+
+	var payload alerting.WebhookPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		respondError(c, errors.BadRequest("invalid Alertmanager payload"))
+		return
+	}
+
+	result, err := h.manager.Ingest(c.Request.Context(), &payload)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Debug().
+		Str("receiver", payload.Receiver).
+		Int("fired", result.Fired).
+		Int("resolved", result.Resolved).
+		Msg("Alertmanager webhook processed")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// List handles GET /alerts
+// Query: status (firing|resolved), source, service_id, project_id, cluster_id, limit, offset
+func (h *AlertsHandler) List(c *gin.Context) {
+	filter := domain.AlertFilter{
+		Status: c.Query("status"),
+		Source: c.Query("source"),
+		Limit:  parseIntQuery(c, "limit", 50),
+		Offset: parseIntQuery(c, "offset", 0),
+	}
+
+	for key, target := range map[string]**uuid.UUID{
+		"service_id": &filter.ServiceID,
+		"project_id": &filter.ProjectID,
+		"cluster_id": &filter.ClusterID,
+	} {
+		v := c.Query(key)
+		if v == "" {
+			continue
+		}
+		id, err := uuid.Parse(v)
+		if err != nil {
+			respondError(c, errors.BadRequest("invalid "+key))
+			return
+		}
+		*target = &id
+	}
+
+	alerts, err := h.manager.List(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+// Get handles GET /alerts/:id
+func (h *AlertsHandler) Get(c *gin.Context) {
+	alert, err := h.manager.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
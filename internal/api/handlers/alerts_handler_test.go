@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertsWebhookRequiresToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	post := func(h *AlertsHandler, authorization string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/alerts/webhook", strings.NewReader(`not json`))
+		if authorization != "" {
+			c.Request.Header.Set("Authorization", authorization)
+		}
+		h.Webhook(c)
+		return w.Code
+	}
+
+	// Without a configured token nothing gets in, not even an empty one
+	open := NewAlertsHandler(nil, "", logger.New("error", "json", io.Discard))
+	assert.Equal(t, http.StatusUnauthorized, post(open, ""))
+	assert.Equal(t, http.StatusUnauthorized, post(open, "Bearer "))
+
+	h := NewAlertsHandler(nil, "s3cret", logger.New("error", "json", io.Discard))
+	assert.Equal(t, http.StatusUnauthorized, post(h, "Bearer wrong"))
+	// The right token gets as far as decoding the payload
+	assert.Equal(t, http.StatusBadRequest, post(h, "Bearer s3cret"))
+}
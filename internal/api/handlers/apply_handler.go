@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/manifest"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// maxManifestBody bounds the size of an applied manifest
+const maxManifestBody = 1 << 20
+
+// ApplyHandler converges projects to northstack.yaml manifests
+type ApplyHandler struct {
+	applier     *manifest.Applier
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewApplyHandler creates a new ApplyHandler
+func NewApplyHandler(applier *manifest.Applier, projectRepo domain.ProjectRepository, log *logger.Logger) *ApplyHandler {
+	return &ApplyHandler{
+		applier:     applier,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// Apply handles POST /projects/:id/apply?dry_run=&prune=. The body is the
+// manifest, in YAML or JSON; the response lists the changes made, or that
+// would be made on a dry run.
+func (h *ApplyHandler) Apply(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxManifestBody))
+	if err != nil {
+		respondError(c, errors.BadRequest("failed to read manifest: "+err.Error()))
+		return
+	}
+	m, err := manifest.Parse(body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.projectRepo.GetByID(ctx, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	result, err := h.applier.Apply(ctx, projectID, m, manifest.Options{
+		DryRun: parseBoolQuery(c, "dry_run", false),
+		Prune:  parseBoolQuery(c, "prune", false),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ComposeImportResponse is the outcome of a compose import
+type ComposeImportResponse struct {
+	Manifest    *manifest.Manifest     `json:"manifest"`    // Check it in as northstack.yaml to keep applying it
+	Unsupported []manifest.Unsupported `json:"unsupported"` // Settings that were not carried over
+	Result      *manifest.Result       `json:"result"`
+}
+
+// ImportCompose handles POST /projects/:id/import/compose?dry_run=&repository=&branch=.
+// The body is a docker-compose.yml; its services are converted to a manifest
+// and applied. repository holds the code of services compose builds locally.
+func (h *ApplyHandler) ImportCompose(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxManifestBody))
+	if err != nil {
+		respondError(c, errors.BadRequest("failed to read compose file: "+err.Error()))
+		return
+	}
+	imported, err := manifest.FromCompose(body, manifest.ComposeOptions{
+		Repository:  c.Query("repository"),
+		Branch:      c.Query("branch"),
+		StorageSize: c.Query("storage_size"),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.projectRepo.GetByID(ctx, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	result, err := h.applier.Apply(ctx, projectID, imported.Manifest, manifest.Options{
+		DryRun: parseBoolQuery(c, "dry_run", false),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ComposeImportResponse{
+		Manifest:    imported.Manifest,
+		Unsupported: imported.Unsupported,
+		Result:      result,
+	})
+}
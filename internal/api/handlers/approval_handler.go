@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/approvals"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// ApprovalHandler handles deploy approvals and approval delegations
+type ApprovalHandler struct {
+	gate   *approvals.Gate
+	logger *logger.Logger
+}
+
+// NewApprovalHandler creates a new ApprovalHandler
+func NewApprovalHandler(gate *approvals.Gate, log *logger.Logger) *ApprovalHandler {
+	return &ApprovalHandler{
+		gate:   gate,
+		logger: log,
+	}
+}
+
+// DecideApprovalRequest represents the request body for approving or rejecting a deploy
+type DecideApprovalRequest struct {
+	Comment string `json:"comment"`
+}
+
+// CreateApprovalDelegationRequest represents the request body for delegating approvals
+type CreateApprovalDelegationRequest struct {
+	DelegateID uuid.UUID  `json:"delegate_id" binding:"required"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     time.Time  `json:"ends_at" binding:"required"`
+	Reason     string     `json:"reason"`
+}
+
+// List handles GET /approvals
+// Lists the approvals the user may decide, including those of users who
+// delegated approvals to them.
+// Query: status, project_id, limit (default 50, max 100), cursor
+func (h *ApprovalHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	filter := domain.ApprovalFilter{Status: domain.ApprovalStatus(c.Query("status"))}
+	switch filter.Status {
+	case "", domain.ApprovalStatusPending, domain.ApprovalStatusApproved, domain.ApprovalStatusRejected:
+	default:
+		respondError(c, errors.BadRequest("status must be pending, approved or rejected"))
+		return
+	}
+	if v := c.Query("project_id"); v != "" {
+		projectID, err := uuid.Parse(v)
+		if err != nil {
+			respondError(c, errors.BadRequest("invalid project ID"))
+			return
+		}
+		filter.ProjectID = &projectID
+	}
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+	filter.Limit = limit
+	filter.After = after
+
+	list, err := h.gate.List(c.Request.Context(), userID, filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        list,
+		"count":       len(list),
+		"limit":       limit,
+		"next_cursor": nextCursor(list, limit, approvalCursor),
+	})
+}
+
+// Get handles GET /approvals/:id
+func (h *ApprovalHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid approval ID"))
+		return
+	}
+
+	approval, err := h.gate.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// Approve handles POST /approvals/:id/approve
+// The approved deploy resumes.
+func (h *ApprovalHandler) Approve(c *gin.Context) {
+	h.decide(c, h.gate.Approve)
+}
+
+// Reject handles POST /approvals/:id/reject
+func (h *ApprovalHandler) Reject(c *gin.Context) {
+	h.decide(c, h.gate.Reject)
+}
+
+func (h *ApprovalHandler) decide(c *gin.Context, decide func(ctx context.Context, id uuid.UUID, actor approvals.Actor, comment string) (*domain.Approval, error)) {
+	actor, ok := approvalActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid approval ID"))
+		return
+	}
+
+	var req DecideApprovalRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, bindError(err))
+			return
+		}
+	}
+
+	approval, err := decide(c.Request.Context(), id, actor, req.Comment)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// CreateDelegation handles POST /approval-delegations
+// Lets another user decide the user's approvals from starts_at, or now,
+// until ends_at.
+func (h *ApprovalHandler) CreateDelegation(c *gin.Context) {
+	actor, ok := approvalActor(c)
+	if !ok {
+		return
+	}
+
+	var req CreateApprovalDelegationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	delegation := &domain.ApprovalDelegation{
+		DelegateID: req.DelegateID,
+		EndsAt:     req.EndsAt,
+		Reason:     req.Reason,
+	}
+	if req.StartsAt != nil {
+		delegation.StartsAt = *req.StartsAt
+	}
+	if err := h.gate.Delegate(c.Request.Context(), actor, delegation); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, delegation)
+}
+
+// ListDelegations handles GET /approval-delegations
+// Lists the delegations the user gave or received.
+func (h *ApprovalHandler) ListDelegations(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.gate.Delegations(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  list,
+		"count": len(list),
+	})
+}
+
+// DeleteDelegation handles DELETE /approval-delegations/:id
+func (h *ApprovalHandler) DeleteDelegation(c *gin.Context) {
+	actor, ok := approvalActor(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid delegation ID"))
+		return
+	}
+
+	if err := h.gate.Revoke(c.Request.Context(), actor, id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// approvalActor returns the authenticated user acting on approvals, for the audit log
+func approvalActor(c *gin.Context) (approvals.Actor, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return approvals.Actor{}, false
+	}
+	return approvals.Actor{
+		UserID:    userID,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}, true
+}
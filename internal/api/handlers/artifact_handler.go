@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/artifacts"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// ArtifactHandler handles build artifact endpoints
+type ArtifactHandler struct {
+	manager     *artifacts.Manager
+	buildRepo   domain.BuildRepository
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewArtifactHandler creates a new ArtifactHandler
+func NewArtifactHandler(manager *artifacts.Manager, buildRepo domain.BuildRepository, serviceRepo domain.ServiceRepository, log *logger.Logger) *ArtifactHandler {
+	return &ArtifactHandler{
+		manager:     manager,
+		buildRepo:   buildRepo,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// ArtifactResponse is an artifact with a signed download URL
+type ArtifactResponse struct {
+	*domain.Artifact
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+// Upload handles PUT /builds/:id/artifacts/:name. The request body is the
+// raw file; Content-Length is required, and X-Checksum-SHA256 is verified
+// when present.
+func (h *ArtifactHandler) Upload(c *gin.Context) {
+	build, ok := h.build(c)
+	if !ok {
+		return
+	}
+
+	retentionDays := parseIntQuery(c, "retention_days", 0)
+	if retentionDays < 0 {
+		respondError(c, errors.BadRequest("retention_days must not be negative"))
+		return
+	}
+
+	up := artifacts.Upload{
+		Name:        c.Param("name"),
+		ContentType: c.ContentType(),
+		Size:        c.Request.ContentLength,
+		Body:        c.Request.Body,
+		SHA256:      c.GetHeader("X-Checksum-SHA256"),
+		Retention:   time.Duration(retentionDays) * 24 * time.Hour,
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		up.UploadedBy = fmt.Sprint(userID)
+	}
+
+	artifact, err := h.manager.Upload(c.Request.Context(), build, up)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ArtifactResponse{Artifact: artifact})
+}
+
+// Get handles GET /builds/:id/artifacts/:name
+func (h *ArtifactHandler) Get(c *gin.Context) {
+	build, ok := h.build(c)
+	if !ok {
+		return
+	}
+
+	artifact, err := h.manager.Get(c.Request.Context(), build.ID, c.Param("name"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	url, expires, err := h.manager.DownloadURL(artifact)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, ArtifactResponse{
+		Artifact:             artifact,
+		DownloadURL:          url,
+		DownloadURLExpiresAt: &expires,
+	})
+}
+
+// ListByBuild handles GET /builds/:id/artifacts
+func (h *ArtifactHandler) ListByBuild(c *gin.Context) {
+	build, ok := h.build(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.manager.ListByBuild(c.Request.Context(), build.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  list,
+		"count": len(list),
+	})
+}
+
+// ListByService handles GET /services/:id/artifacts
+func (h *ArtifactHandler) ListByService(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", 50)
+	if limit < 1 || limit > 200 {
+		respondError(c, errors.BadRequest("limit must be between 1 and 200"))
+		return
+	}
+
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.manager.ListByServiceAfter(c.Request.Context(), id, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        list,
+		"count":       len(list),
+		"limit":       limit,
+		"next_cursor": nextCursor(list, limit, artifactCursor),
+	})
+}
+
+// Delete handles DELETE /builds/:id/artifacts/:name
+func (h *ArtifactHandler) Delete(c *gin.Context) {
+	build, ok := h.build(c)
+	if !ok {
+		return
+	}
+
+	artifact, err := h.manager.Get(c.Request.Context(), build.ID, c.Param("name"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.manager.Delete(c.Request.Context(), artifact); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("artifact_id", artifact.ID.String()).
+		Str("build_id", build.ID.String()).
+		Msg("Artifact deleted")
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ArtifactHandler) build(c *gin.Context) (*domain.Build, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid build ID"))
+		return nil, false
+	}
+
+	build, err := h.buildRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return build, true
+}
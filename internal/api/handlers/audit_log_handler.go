@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// AuditLogHandler handles audit log endpoints
+type AuditLogHandler struct {
+	repo   domain.AuditLogRepository
+	logger *logger.Logger
+}
+
+// NewAuditLogHandler creates a new AuditLogHandler
+func NewAuditLogHandler(repo domain.AuditLogRepository, log *logger.Logger) *AuditLogHandler {
+	return &AuditLogHandler{
+		repo:   repo,
+		logger: log,
+	}
+}
+
+// List handles GET /audit-logs
+// Query: user_id, project_id, resource_type, resource_id, action, start_time, end_time (unix seconds), limit, offset, cursor
+func (h *AuditLogHandler) List(c *gin.Context) {
+	filter := domain.AuditLogFilter{
+		ResourceType: c.Query("resource_type"),
+		Limit:        parseIntQuery(c, "limit", 50),
+		Offset:       parseIntQuery(c, "offset", 0),
+	}
+
+	if filter.Limit < 1 || filter.Limit > 500 {
+		respondError(c, errors.BadRequest("limit must be between 1 and 500"))
+		return
+	}
+
+	for key, target := range map[string]**uuid.UUID{
+		"user_id":     &filter.UserID,
+		"project_id":  &filter.ProjectID,
+		"resource_id": &filter.ResourceID,
+	} {
+		v := c.Query(key)
+		if v == "" {
+			continue
+		}
+		id, err := uuid.Parse(v)
+		if err != nil {
+			respondError(c, errors.BadRequest("invalid "+key))
+			return
+		}
+		*target = &id
+	}
+
+	for key, target := range map[string]**int64{
+		"start_time": &filter.StartTime,
+		"end_time":   &filter.EndTime,
+	} {
+		v := c.Query(key)
+		if v == "" {
+			continue
+		}
+		t, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			respondError(c, errors.BadRequest(key+" must be a unix timestamp"))
+			return
+		}
+		*target = &t
+	}
+
+	if action := c.Query("action"); action != "" {
+		a := domain.AuditAction(action)
+		filter.Action = &a
+	}
+
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+	filter.After = after
+
+	logs, err := h.repo.List(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        logs,
+		"count":       len(logs),
+		"offset":      filter.Offset,
+		"limit":       filter.Limit,
+		"next_cursor": nextCursor(logs, filter.Limit, auditLogCursor),
+	})
+}
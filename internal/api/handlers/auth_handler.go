@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/northstack/platform/internal/config"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -54,20 +55,24 @@ type AuthResponse struct {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, bindError(err))
 		return
 	}
 
 	// Find user by email
 	user, err := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		respondError(c, errors.Unauthorized("Invalid credentials"))
 		return
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		respondError(c, errors.Unauthorized("Invalid credentials"))
+		return
+	}
+	if !user.IsActive {
+		respondError(c, errors.Unauthorized("User account is disabled"))
 		return
 	}
 
@@ -75,7 +80,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	token, refreshToken, expiresAt, err := h.generateTokens(user)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to generate tokens")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		respondError(c, errors.Internal("Failed to generate tokens"))
 		return
 	}
 
@@ -95,21 +100,21 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, bindError(err))
 		return
 	}
 
 	// Check if user exists
 	existing, _ := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
 	if existing != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		respondError(c, errors.NewError(errors.CodeConflict, "Email already registered", http.StatusConflict))
 		return
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		respondError(c, errors.Internal("Failed to process password"))
 		return
 	}
 
@@ -127,14 +132,14 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	if err := h.userRepo.Create(c.Request.Context(), user); err != nil {
 		h.logger.Error().Err(err).Msg("Failed to create user")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		respondError(c, errors.Internal("Failed to create user"))
 		return
 	}
 
 	// Generate tokens
 	token, refreshToken, expiresAt, err := h.generateTokens(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		respondError(c, errors.Internal("Failed to generate tokens"))
 		return
 	}
 
@@ -152,7 +157,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		RefreshToken string `json:"refresh_token" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, bindError(err))
 		return
 	}
 
@@ -161,33 +166,37 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return []byte(h.config.JWTSecret), nil
 	})
 	if err != nil || !token.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		respondError(c, errors.Unauthorized("Invalid refresh token"))
 		return
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		respondError(c, errors.Unauthorized("Invalid token claims"))
 		return
 	}
 
 	// Get user
 	userID, err := uuid.Parse(claims["sub"].(string))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		respondError(c, errors.Unauthorized("Invalid user ID"))
 		return
 	}
 
 	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		respondError(c, errors.Unauthorized("User not found"))
+		return
+	}
+	if !user.IsActive {
+		respondError(c, errors.Unauthorized("User account is disabled"))
 		return
 	}
 
 	// Generate new tokens
 	newToken, newRefreshToken, expiresAt, err := h.generateTokens(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate tokens"})
+		respondError(c, errors.Internal("Failed to generate tokens"))
 		return
 	}
 
@@ -210,13 +219,13 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		respondError(c, errors.Unauthorized("Not authenticated"))
 		return
 	}
 
 	user, err := h.userRepo.GetByID(c.Request.Context(), userID.(uuid.UUID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		respondError(c, errors.NotFound("user"))
 		return
 	}
 
@@ -227,7 +236,7 @@ func (h *AuthHandler) GetCurrentUser(c *gin.Context) {
 func (h *AuthHandler) UpdateCurrentUser(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		respondError(c, errors.Unauthorized("Not authenticated"))
 		return
 	}
 
@@ -235,13 +244,13 @@ func (h *AuthHandler) UpdateCurrentUser(c *gin.Context) {
 		Name string `json:"name"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, bindError(err))
 		return
 	}
 
 	user, err := h.userRepo.GetByID(c.Request.Context(), userID.(uuid.UUID))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		respondError(c, errors.NotFound("user"))
 		return
 	}
 
@@ -251,7 +260,7 @@ func (h *AuthHandler) UpdateCurrentUser(c *gin.Context) {
 	user.UpdatedAt = time.Now()
 
 	if err := h.userRepo.Update(c.Request.Context(), user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		respondError(c, errors.Internal("Failed to update user"))
 		return
 	}
 
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/autoscaling"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/keda"
+	"github.com/northstack/platform/internal/warmpool"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// AutoscalingHandler handles autoscaling configuration endpoints
+type AutoscalingHandler struct {
+	manager     *keda.Manager
+	reconciler  *autoscaling.Reconciler
+	serviceRepo domain.ServiceRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewAutoscalingHandler creates a new AutoscalingHandler. reconciler may be
+// nil, in which case only KEDA ScaledObjects are applied.
+func NewAutoscalingHandler(manager *keda.Manager, reconciler *autoscaling.Reconciler, serviceRepo domain.ServiceRepository, eventBus domain.EventBus, log *logger.Logger) *AutoscalingHandler {
+	return &AutoscalingHandler{
+		manager:     manager,
+		reconciler:  reconciler,
+		serviceRepo: serviceRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Update handles PUT /services/:id/autoscaling
+// Replaces the replica range, resource targets and triggers; scaling schedules and warm standby are kept.
+func (h *AutoscalingHandler) Update(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	var req ScalingConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	if req.MinReplicas < 0 || req.MaxReplicas < 1 || req.MaxReplicas < req.MinReplicas {
+		respondError(c, errors.BadRequest("replicas must satisfy 0 <= min_replicas <= max_replicas and max_replicas >= 1"))
+		return
+	}
+	if err := keda.ValidateTriggers(req.Triggers); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if service.TargetClusterID != nil {
+		if err := h.manager.CheckAvailability(c.Request.Context(), *service.TargetClusterID, req.Triggers); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	service.Scaling.MinReplicas = req.MinReplicas
+	service.Scaling.MaxReplicas = req.MaxReplicas
+	service.Scaling.TargetCPU = req.TargetCPU
+	service.Scaling.TargetMemory = req.TargetMemory
+	service.Scaling.Triggers = req.Triggers
+	if err := warmpool.Validate(service.Scaling.WarmStandby, service.Scaling); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.serviceRepo.Update(c.Request.Context(), service); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Services that are not deployed yet get their autoscaler on first deploy
+	if h.reconciler != nil {
+		err = h.reconciler.Reconcile(c.Request.Context(), service)
+	} else {
+		err = h.manager.Apply(c.Request.Context(), service)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		respondError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(c.Request.Context(), "service.updated", &domain.Event{
+		Type:   "service.updated",
+		Source: "api",
+		Data: map[string]interface{}{
+			"service_id": service.ID.String(),
+			"project_id": service.ProjectID.String(),
+		},
+	})
+
+	h.logger.Info().
+		Str("service_id", service.ID.String()).
+		Int("triggers", len(req.Triggers)).
+		Msg("Autoscaling updated")
+
+	c.JSON(http.StatusOK, service.Scaling)
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/backstage"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// BackstageHandler serves the Backstage catalog feed and entity provider endpoints
+type BackstageHandler struct {
+	provider *backstage.Provider
+	logger   *logger.Logger
+}
+
+// NewBackstageHandler creates a new BackstageHandler
+func NewBackstageHandler(provider *backstage.Provider, log *logger.Logger) *BackstageHandler {
+	return &BackstageHandler{
+		provider: provider,
+		logger:   log,
+	}
+}
+
+// Authenticate checks the feed token, given as a bearer token or, for
+// Backstage URL locations that cannot set headers, a token query parameter
+func (h *BackstageHandler) Authenticate(c *gin.Context) {
+	want := h.provider.Token()
+	if want == "" {
+		c.Next()
+		return
+	}
+
+	token := c.Query("token")
+	if header := c.GetHeader("Authorization"); header != "" {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+		respondError(c, errors.Unauthorized("invalid Backstage token"))
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// CatalogInfo handles GET /backstage/catalog-info.yaml, for a Backstage URL location
+func (h *BackstageHandler) CatalogInfo(c *gin.Context) {
+	entities, err := h.provider.Entities(c.Request.Context(), h.location(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	doc, err := backstage.MarshalYAML(entities)
+	if err != nil {
+		respondError(c, errors.Wrap(err, "failed to encode catalog"))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/yaml", doc)
+}
+
+// Entities handles GET /backstage/entities, polled by a Backstage entity provider
+func (h *BackstageHandler) Entities(c *gin.Context) {
+	entities, err := h.provider.Entities(c.Request.Context(), h.location(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": entities})
+}
+
+// Entity handles GET /backstage/entities/by-name/:kind/:namespace/:name
+func (h *BackstageHandler) Entity(c *gin.Context) {
+	entity, err := h.provider.Entity(c.Request.Context(), c.Param("kind"), c.Param("namespace"), c.Param("name"), h.location(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, entity)
+}
+
+// location is the feed URL recorded as the entities' managing location. It
+// must be the same for every endpoint, or Backstage sees conflicting owners.
+func (h *BackstageHandler) location(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/v1/backstage/catalog-info.yaml"
+}
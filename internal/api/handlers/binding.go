@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+func init() {
+	// Report fields by their JSON names rather than their Go names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// bindError converts an error from binding a request body into a
+// validation error with one entry per failing field
+func bindError(err error) error {
+	var validationErrs validator.ValidationErrors
+	if stderrors.As(err, &validationErrs) {
+		fields := make([]errors.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, errors.FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Message: ruleMessage(fe),
+			})
+		}
+		return errors.Validation(fields)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if stderrors.As(err, &typeErr) && typeErr.Field != "" {
+		return errors.Validation([]errors.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: "must be " + jsonType(typeErr.Type),
+		}})
+	}
+
+	var syntaxErr *json.SyntaxError
+	if stderrors.As(err, &syntaxErr) {
+		return errors.BadRequest("request body is not valid JSON: " + syntaxErr.Error())
+	}
+	return errors.BadRequest(err.Error())
+}
+
+// fieldPath drops the struct name a validator namespace starts with
+func fieldPath(namespace string) string {
+	if i := strings.IndexByte(namespace, '.'); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+func ruleMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min":
+		return "must be at least " + fe.Param() + sizeUnit(fe.Kind())
+	case "max":
+		return "must be at most " + fe.Param() + sizeUnit(fe.Kind())
+	case "email":
+		return "must be an email address"
+	case "url":
+		return "must be a URL"
+	case "uuid":
+		return "must be a UUID"
+	default:
+		if fe.Param() != "" {
+			return "must satisfy " + fe.Tag() + "=" + fe.Param()
+		}
+		return "must satisfy " + fe.Tag()
+	}
+}
+
+// sizeUnit names what min and max count for a kind of field
+func sizeUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
+
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
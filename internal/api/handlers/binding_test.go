@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindingRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Type     string `json:"type" binding:"required,oneof=web worker"`
+	Replicas int    `json:"replicas" binding:"max=10"`
+}
+
+func bind(t *testing.T, body string) *errors.Problem {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/services", strings.NewReader(body))
+
+	var req bindingRequest
+	err := c.ShouldBindJSON(&req)
+	require.Error(t, err)
+	respondError(c, bindError(err))
+
+	assert.Equal(t, errors.ProblemContentType, w.Header().Get("Content-Type"))
+	problem := &errors.Problem{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), problem))
+	return problem
+}
+
+func TestBindErrorReportsFields(t *testing.T) {
+	problem := bind(t, `{"type":"cron","replicas":20}`)
+
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, "validation_failed", problem.Code)
+	assert.Equal(t, "/api/v1/services", problem.Instance)
+	assert.Equal(t, []errors.FieldError{
+		{Field: "name", Rule: "required", Message: "is required"},
+		{Field: "type", Rule: "oneof", Message: "must be one of web, worker"},
+		{Field: "replicas", Rule: "max", Message: "must be at most 10"},
+	}, problem.Errors)
+}
+
+func TestBindErrorReportsTypeMismatch(t *testing.T) {
+	problem := bind(t, `{"name":"api","type":"web","replicas":"two"}`)
+
+	assert.Equal(t, "validation_failed", problem.Code)
+	assert.Equal(t, "replicas must be an integer", problem.Detail)
+	require.Len(t, problem.Errors, 1)
+	assert.Equal(t, "replicas", problem.Errors[0].Field)
+}
+
+func TestRespondErrorUsesStableCodes(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/services/42", nil)
+
+	respondError(c, errors.NotFound("service", "42"))
+
+	problem := &errors.Problem{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), problem))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "not_found", problem.Code)
+	assert.Equal(t, errors.ProblemTypeBase+"not-found", problem.Type)
+	assert.Equal(t, "Not Found", problem.Title)
+	assert.Equal(t, "service not found: 42", problem.Detail)
+}
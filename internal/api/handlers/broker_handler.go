@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/adapters/anticorruption"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/northstack/platform/pkg/messaging"
+)
+
+// BrokerHandler handles managed message broker endpoints. Like caches, the
+// credentials of a broker are stored in Vault as a project secret, which
+// services bind through their secret references.
+type BrokerHandler struct {
+	brokers      *messaging.BrokerService
+	projectRepo  domain.ProjectRepository
+	serviceRepo  domain.ServiceRepository
+	secretRepo   domain.SecretRepository
+	secretWriter anticorruption.SecretWriter
+	eventBus     domain.EventBus
+	logger       *logger.Logger
+}
+
+// NewBrokerHandler creates a new BrokerHandler. Without a secret repository
+// and writer, brokers have no project secret and cannot be bound to services.
+func NewBrokerHandler(brokers *messaging.BrokerService, projectRepo domain.ProjectRepository, serviceRepo domain.ServiceRepository, secretRepo domain.SecretRepository, secretWriter anticorruption.SecretWriter, eventBus domain.EventBus, log *logger.Logger) *BrokerHandler {
+	return &BrokerHandler{
+		brokers:      brokers,
+		projectRepo:  projectRepo,
+		serviceRepo:  serviceRepo,
+		secretRepo:   secretRepo,
+		secretWriter: secretWriter,
+		eventBus:     eventBus,
+		logger:       log,
+	}
+}
+
+// CreateBrokerRequest represents a broker creation request
+type CreateBrokerRequest struct {
+	Name      string `json:"name" binding:"required,hostname_rfc1123,max=30"`
+	Engine    string `json:"engine" binding:"omitempty,oneof=kafka nats"` // Defaults to nats
+	Size      string `json:"size" binding:"required,oneof=small medium large"`
+	StorageGB int    `json:"storage_gb" binding:"gte=0,max=1000"` // Defaults to 10
+	Version   string `json:"version"`
+}
+
+// BrokerResponse is a broker with the project secret holding its credentials
+type BrokerResponse struct {
+	*messaging.BrokerInfo
+	SecretRef string `json:"secret_ref,omitempty"`
+}
+
+// CreateBroker handles POST /projects/:project_id/brokers
+func (h *BrokerHandler) CreateBroker(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	var req CreateBrokerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	if req.Engine == messaging.EngineKafka && !h.brokers.KafkaAvailable() {
+		respondError(c, errors.BadRequest("Kafka brokers are not available"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	project, err := h.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	teamID := ""
+	if tid, exists := c.Get("team_id"); exists {
+		teamID = tid.(string)
+	}
+
+	broker, err := h.brokers.CreateBroker(ctx, &messaging.CreateBrokerInput{
+		Name:      strings.ToLower(req.Name),
+		ProjectID: projectID.String(),
+		TeamID:    teamID,
+		Engine:    req.Engine,
+		Size:      req.Size,
+		StorageGB: req.StorageGB,
+		Version:   req.Version,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response := &BrokerResponse{BrokerInfo: broker}
+	if h.secretRepo != nil && h.secretWriter != nil {
+		secret, err := h.registerSecret(ctx, project, broker)
+		if err != nil {
+			// The broker works without it; binding retries the registration
+			h.logger.Warn().Err(err).Str("broker_id", broker.ID).Msg("Failed to register broker credentials secret")
+		} else {
+			response.SecretRef = secret.Name
+		}
+	}
+
+	h.publishEvent(ctx, "broker.created", map[string]interface{}{
+		"broker_id":  broker.ID,
+		"project_id": projectID.String(),
+		"name":       broker.Name,
+		"engine":     broker.Engine,
+	})
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListBrokers handles GET /projects/:project_id/brokers
+func (h *BrokerHandler) ListBrokers(c *gin.Context) {
+	brokers, err := h.brokers.ListBrokers(c.Request.Context(), c.Param("project_id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	responses := make([]*BrokerResponse, 0, len(brokers))
+	for _, broker := range brokers {
+		responses = append(responses, h.response(broker))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"brokers": responses,
+		"total":   len(responses),
+	})
+}
+
+// GetBroker handles GET /brokers/:id
+func (h *BrokerHandler) GetBroker(c *gin.Context) {
+	broker, err := h.brokers.GetBroker(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.response(broker))
+}
+
+// DeleteBroker handles DELETE /brokers/:id. The project secret is
+// unregistered; its values stay in Vault.
+func (h *BrokerHandler) DeleteBroker(c *gin.Context) {
+	ctx := c.Request.Context()
+	brokerID := c.Param("id")
+	broker, err := h.brokers.GetBroker(ctx, brokerID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.brokers.DeleteBroker(ctx, brokerID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if projectID, err := uuid.Parse(broker.ProjectID); err == nil && h.secretRepo != nil {
+		secret, err := h.secretRepo.GetByName(ctx, projectID, brokerSecretName(broker))
+		if err == nil {
+			err = h.secretRepo.Delete(ctx, secret.ID)
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			h.logger.Warn().Err(err).Str("broker_id", brokerID).Msg("Failed to unregister broker credentials secret")
+		}
+	}
+
+	h.publishEvent(ctx, "broker.deleted", map[string]interface{}{
+		"broker_id": brokerID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Broker deleted"})
+}
+
+// GetConnectionInfo handles GET /brokers/:id/connection
+func (h *BrokerHandler) GetConnectionInfo(c *gin.Context) {
+	broker, err := h.brokers.GetBroker(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response := h.response(broker)
+	c.JSON(http.StatusOK, gin.H{
+		"engine":      broker.Engine,
+		"endpoint":    broker.Endpoint,
+		"port":        broker.Port,
+		"secret_name": broker.SecretName,
+		"secret_ref":  response.SecretRef,
+	})
+}
+
+// BindBrokerRequest names the service that reads a broker's credentials
+type BindBrokerRequest struct {
+	ServiceID string `json:"service_id" binding:"required,uuid"`
+}
+
+// BindBroker handles POST /brokers/:id/bind. The broker's project secret is
+// added to the secret references of a service of the same project, which
+// receives the KAFKA_* or NATS_* variables on its next deployment.
+func (h *BrokerHandler) BindBroker(c *gin.Context) {
+	if h.secretRepo == nil || h.secretWriter == nil {
+		respondError(c, errors.BadRequest("binding brokers needs Vault secrets"))
+		return
+	}
+
+	var req BindBrokerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	broker, err := h.brokers.GetBroker(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	service, err := h.serviceRepo.GetByID(ctx, uuid.MustParse(req.ServiceID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if broker.ProjectID != service.ProjectID.String() {
+		respondError(c, errors.BadRequest("the service is not in the broker's project"))
+		return
+	}
+
+	secret, err := h.secretRepo.GetByName(ctx, service.ProjectID, brokerSecretName(broker))
+	if errors.IsNotFound(err) {
+		var project *domain.Project
+		if project, err = h.projectRepo.GetByID(ctx, service.ProjectID); err == nil {
+			secret, err = h.registerSecret(ctx, project, broker)
+		}
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("broker_id", broker.ID).Msg("Failed to register broker credentials secret")
+		respondError(c, errors.Internal("Failed to bind broker"))
+		return
+	}
+
+	bound := false
+	for _, ref := range service.SecretRefs {
+		if ref == secret.Name {
+			bound = true
+			break
+		}
+	}
+	if !bound {
+		service.SecretRefs = append(service.SecretRefs, secret.Name)
+		service.UpdatedAt = time.Now()
+		if err := h.serviceRepo.Update(ctx, service); err != nil {
+			respondError(c, err)
+			return
+		}
+		h.publishEvent(ctx, "broker.bound", map[string]interface{}{
+			"broker_id":  broker.ID,
+			"service_id": service.ID.String(),
+			"secret_ref": secret.Name,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_id":  service.ID,
+		"secret_refs": service.SecretRefs,
+	})
+}
+
+// CreateTopicRequest creates a Kafka topic or a JetStream stream
+type CreateTopicRequest struct {
+	Name           string   `json:"name" binding:"required,hostname_rfc1123,max=100"`
+	Partitions     int      `json:"partitions" binding:"gte=0,max=100"` // Kafka only
+	Subjects       []string `json:"subjects" binding:"dive,required"`   // NATS only
+	RetentionHours int      `json:"retention_hours" binding:"gte=0"`
+}
+
+// CreateTopic handles POST /brokers/:id/topics
+func (h *BrokerHandler) CreateTopic(c *gin.Context) {
+	var req CreateTopicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	brokerID := c.Param("id")
+	topic, err := h.brokers.CreateTopic(ctx, brokerID, &messaging.CreateTopicInput{
+		Name:           strings.ToLower(req.Name),
+		Partitions:     req.Partitions,
+		Subjects:       req.Subjects,
+		RetentionHours: req.RetentionHours,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.publishEvent(ctx, "broker.topic.created", map[string]interface{}{
+		"broker_id": brokerID,
+		"topic":     topic.Name,
+	})
+
+	c.JSON(http.StatusCreated, topic)
+}
+
+// ListTopics handles GET /brokers/:id/topics
+func (h *BrokerHandler) ListTopics(c *gin.Context) {
+	topics, err := h.brokers.ListTopics(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"topics": topics,
+		"total":  len(topics),
+	})
+}
+
+// DeleteTopic handles DELETE /brokers/:id/topics/:topic
+func (h *BrokerHandler) DeleteTopic(c *gin.Context) {
+	ctx := c.Request.Context()
+	brokerID := c.Param("id")
+	if err := h.brokers.DeleteTopic(ctx, brokerID, c.Param("topic")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.publishEvent(ctx, "broker.topic.deleted", map[string]interface{}{
+		"broker_id": brokerID,
+		"topic":     c.Param("topic"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Topic deleted"})
+}
+
+// registerSecret writes the credentials of a broker to Vault and registers
+// them as a project secret
+func (h *BrokerHandler) registerSecret(ctx context.Context, project *domain.Project, broker *messaging.BrokerInfo) (*domain.Secret, error) {
+	values, err := h.brokers.Connection(ctx, broker.ID)
+	if err != nil {
+		return nil, err
+	}
+	path := "brokers/" + project.Slug + "/" + broker.Name
+	version, err := h.secretWriter.WriteSecret(ctx, path, values)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	now := time.Now()
+	secret := &domain.Secret{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		Name:      brokerSecretName(broker),
+		Type:      domain.SecretTypeOpaque,
+		Keys:      keys,
+		VaultPath: path,
+		Version:   version,
+		Labels:    map[string]string{"northstack.io/broker": broker.ID},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.secretRepo.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// response adds the name of the project secret of a broker, when there is one
+func (h *BrokerHandler) response(broker *messaging.BrokerInfo) *BrokerResponse {
+	response := &BrokerResponse{BrokerInfo: broker}
+	if h.secretRepo != nil && h.secretWriter != nil {
+		response.SecretRef = brokerSecretName(broker)
+	}
+	return response
+}
+
+func (h *BrokerHandler) publishEvent(ctx context.Context, eventType string, data map[string]interface{}) {
+	event := &domain.Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+	if err := h.eventBus.Publish(ctx, eventType, event); err != nil {
+		h.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// brokerSecretName is the name of the project secret of a broker
+func brokerSecretName(broker *messaging.BrokerInfo) string {
+	return "broker-" + broker.Name
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/northstack/platform/pkg/minio"
+)
+
+// accessKeysPath is the path segment of the access key endpoints, which a
+// bucket cannot be named after
+const accessKeysPath = "access-keys"
+
+// BucketHandler handles the object storage endpoints of projects: buckets in
+// the project's MinIO tenant, their quotas and access keys. Access keys are
+// stored in Vault through the secrets adapter.
+type BucketHandler struct {
+	storage     *minio.TenantService
+	projectRepo domain.ProjectRepository
+	secrets     domain.SecretsAdapter
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewBucketHandler creates a new BucketHandler
+func NewBucketHandler(storage *minio.TenantService, projectRepo domain.ProjectRepository, secrets domain.SecretsAdapter, eventBus domain.EventBus, log *logger.Logger) *BucketHandler {
+	return &BucketHandler{
+		storage:     storage,
+		projectRepo: projectRepo,
+		secrets:     secrets,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// CreateBucketRequest represents a bucket creation request
+type CreateBucketRequest struct {
+	Name    string `json:"name" binding:"required,hostname_rfc1123,min=3,max=63"`
+	QuotaGB int64  `json:"quota_gb" binding:"gte=0"` // 0 for no quota
+}
+
+// SetBucketQuotaRequest sets the quota of a bucket
+type SetBucketQuotaRequest struct {
+	QuotaGB int64 `json:"quota_gb" binding:"gte=0"` // 0 removes the quota
+}
+
+// List handles GET /projects/:id/buckets. A project without buckets has no
+// tenant yet.
+func (h *BucketHandler) List(c *gin.Context) {
+	project, err := h.project(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenant, err := h.storage.GetTenant(ctx, project.ID.String())
+	if errors.IsNotFound(err) {
+		c.JSON(http.StatusOK, gin.H{"tenant": nil, "buckets": []*minio.Bucket{}, "total": 0})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	buckets := []*minio.Bucket{}
+	if tenant.Ready {
+		if buckets, err = h.storage.ListBuckets(ctx, project.ID.String()); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tenant":  tenant,
+		"buckets": buckets,
+		"total":   len(buckets),
+	})
+}
+
+// Create handles POST /projects/:id/buckets. The first bucket of a project
+// provisions its tenant; until the tenant is ready, creating buckets fails
+// with 503 and a Retry-After header.
+func (h *BucketHandler) Create(c *gin.Context) {
+	project, err := h.project(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req CreateBucketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	name := strings.ToLower(req.Name)
+	if name == accessKeysPath {
+		respondError(c, errors.BadRequest("the bucket name "+accessKeysPath+" is reserved"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	projectID := project.ID.String()
+	if err := h.ready(c, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.storage.CreateBucket(ctx, projectID, name); err != nil {
+		respondError(c, err)
+		return
+	}
+	if req.QuotaGB > 0 {
+		if err := h.storage.SetQuota(ctx, projectID, name, req.QuotaGB<<30); err != nil {
+			h.logger.Warn().Err(err).Str("project_id", projectID).Str("bucket", name).Msg("Failed to set bucket quota")
+			respondError(c, err)
+			return
+		}
+	}
+
+	h.publishEvent(ctx, "bucket.created", map[string]interface{}{
+		"project_id": projectID,
+		"bucket":     name,
+		"quota_gb":   req.QuotaGB,
+	})
+
+	c.JSON(http.StatusCreated, &minio.Bucket{Name: name, QuotaBytes: req.QuotaGB << 30, CreatedAt: time.Now()})
+}
+
+// Delete handles DELETE /projects/:id/buckets/:bucket. Only empty buckets can
+// be deleted.
+func (h *BucketHandler) Delete(c *gin.Context) {
+	project, err := h.project(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	bucket := c.Param("bucket")
+	if err := h.storage.DeleteBucket(ctx, project.ID.String(), bucket); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.publishEvent(ctx, "bucket.deleted", map[string]interface{}{
+		"project_id": project.ID.String(),
+		"bucket":     bucket,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bucket deleted"})
+}
+
+// SetQuota handles PUT /projects/:id/buckets/:bucket/quota
+func (h *BucketHandler) SetQuota(c *gin.Context) {
+	project, err := h.project(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req SetBucketQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	bucket := c.Param("bucket")
+	if err := h.storage.SetQuota(c.Request.Context(), project.ID.String(), bucket, req.QuotaGB<<30); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &minio.Bucket{Name: bucket, QuotaBytes: req.QuotaGB << 30})
+}
+
+// AccessKeyResponse is an issued access key. The secret key is only returned
+// once; it stays available in Vault at vault_path.
+type AccessKeyResponse struct {
+	*minio.AccessKey
+	Endpoint  string `json:"endpoint"`
+	VaultPath string `json:"vault_path"`
+}
+
+// IssueAccessKey handles POST /projects/:id/buckets/access-keys. The key has
+// access to every bucket of the project.
+func (h *BucketHandler) IssueAccessKey(c *gin.Context) {
+	project, err := h.project(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	projectID := project.ID.String()
+	if err := h.ready(c, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	key, err := h.storage.IssueAccessKey(ctx, projectID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	tenant, err := h.storage.GetTenant(ctx, projectID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	secret := &domain.Secret{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		Name:      "storage-" + strings.ToLower(key.AccessKey),
+		Type:      domain.SecretTypeOpaque,
+		Keys:      []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_ENDPOINT_URL"},
+		VaultPath: accessKeyPath(project, key.AccessKey),
+	}
+	err = h.secrets.CreateSecret(ctx, secret, map[string][]byte{
+		"AWS_ACCESS_KEY_ID":     []byte(key.AccessKey),
+		"AWS_SECRET_ACCESS_KEY": []byte(key.SecretKey),
+		"AWS_ENDPOINT_URL":      []byte(tenant.Endpoint),
+	})
+	if err != nil {
+		// An access key nobody can read again is useless
+		if revokeErr := h.storage.RevokeAccessKey(ctx, projectID, key.AccessKey); revokeErr != nil {
+			h.logger.Error().Err(revokeErr).Str("project_id", projectID).Msg("Failed to revoke unstored access key")
+		}
+		respondError(c, err)
+		return
+	}
+
+	h.publishEvent(ctx, "bucket.access_key.issued", map[string]interface{}{
+		"project_id": projectID,
+		"access_key": key.AccessKey,
+	})
+
+	c.JSON(http.StatusCreated, &AccessKeyResponse{
+		AccessKey: key,
+		Endpoint:  tenant.Endpoint,
+		VaultPath: secret.VaultPath,
+	})
+}
+
+// ListAccessKeys handles GET /projects/:id/buckets/access-keys
+func (h *BucketHandler) ListAccessKeys(c *gin.Context) {
+	project, err := h.project(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	keys, err := h.storage.ListAccessKeys(c.Request.Context(), project.ID.String())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"access_keys": keys, "total": len(keys)})
+}
+
+// RevokeAccessKey handles DELETE /projects/:id/buckets/access-keys/:key and
+// deletes the key from Vault
+func (h *BucketHandler) RevokeAccessKey(c *gin.Context) {
+	project, err := h.project(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	accessKey := c.Param("key")
+	if err := h.storage.RevokeAccessKey(ctx, project.ID.String(), accessKey); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.secrets.DeleteSecret(ctx, accessKeyPath(project, accessKey)); err != nil && !errors.IsNotFound(err) {
+		h.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to delete revoked access key from Vault")
+	}
+
+	h.publishEvent(ctx, "bucket.access_key.revoked", map[string]interface{}{
+		"project_id": project.ID.String(),
+		"access_key": accessKey,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Access key revoked"})
+}
+
+// project returns the project of the request
+func (h *BucketHandler) project(c *gin.Context) (*domain.Project, error) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		return nil, errors.BadRequest("invalid project ID")
+	}
+	return h.projectRepo.GetByID(c.Request.Context(), id)
+}
+
+// ready provisions the tenant of a project if needed and fails with 503 until
+// it is ready
+func (h *BucketHandler) ready(c *gin.Context, projectID string) error {
+	tenant, err := h.storage.EnsureTenant(c.Request.Context(), projectID)
+	if err != nil {
+		return err
+	}
+	if !tenant.Ready {
+		c.Header("Retry-After", "30")
+		return errors.NewError(errors.CodeServiceUnavailable, "object storage is being provisioned; retry shortly", http.StatusServiceUnavailable)
+	}
+	return nil
+}
+
+func (h *BucketHandler) publishEvent(ctx context.Context, eventType string, data map[string]interface{}) {
+	event := &domain.Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+	if err := h.eventBus.Publish(ctx, eventType, event); err != nil {
+		h.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// accessKeyPath is the Vault path of an access key
+func accessKeyPath(project *domain.Project, accessKey string) string {
+	return "storage/" + project.Slug + "/" + accessKey
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/buildanalytics"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	defaultBuildAnalyticsWeeks = 8
+	maxBuildAnalyticsWeeks     = 26
+)
+
+// BuildAnalyticsHandler handles build time analytics endpoints
+type BuildAnalyticsHandler struct {
+	analyzer    *buildanalytics.Analyzer
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewBuildAnalyticsHandler creates a new BuildAnalyticsHandler
+func NewBuildAnalyticsHandler(analyzer *buildanalytics.Analyzer, projectRepo domain.ProjectRepository, serviceRepo domain.ServiceRepository, log *logger.Logger) *BuildAnalyticsHandler {
+	return &BuildAnalyticsHandler{
+		analyzer:    analyzer,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// Service handles GET /services/:id/build-analytics
+// Query: weeks (default 8, max 26)
+func (h *BuildAnalyticsHandler) Service(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	weeks := parseIntQuery(c, "weeks", defaultBuildAnalyticsWeeks)
+	if weeks < 2 || weeks > maxBuildAnalyticsWeeks {
+		respondError(c, errors.BadRequest("weeks must be between 2 and 26"))
+		return
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	report, err := h.analyzer.ServiceReport(c.Request.Context(), id, weeks)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Regressions handles GET /projects/:id/build-analytics/regressions
+func (h *BuildAnalyticsHandler) Regressions(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	if _, err := h.projectRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	reports, err := h.analyzer.Regressions(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"services": reports})
+}
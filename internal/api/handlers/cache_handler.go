@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/adapters/anticorruption"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/dragonfly"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// CacheHandler handles managed cache endpoints. The connection details of a
+// cache are also stored in Vault as a project secret, which services bind
+// through their secret references.
+type CacheHandler struct {
+	caches       *dragonfly.CacheService
+	projectRepo  domain.ProjectRepository
+	serviceRepo  domain.ServiceRepository
+	secretRepo   domain.SecretRepository
+	secretWriter anticorruption.SecretWriter
+	eventBus     domain.EventBus
+	logger       *logger.Logger
+}
+
+// NewCacheHandler creates a new CacheHandler. Without a secret repository and
+// writer, caches have no project secret and cannot be bound to services.
+func NewCacheHandler(caches *dragonfly.CacheService, projectRepo domain.ProjectRepository, serviceRepo domain.ServiceRepository, secretRepo domain.SecretRepository, secretWriter anticorruption.SecretWriter, eventBus domain.EventBus, log *logger.Logger) *CacheHandler {
+	return &CacheHandler{
+		caches:       caches,
+		projectRepo:  projectRepo,
+		serviceRepo:  serviceRepo,
+		secretRepo:   secretRepo,
+		secretWriter: secretWriter,
+		eventBus:     eventBus,
+		logger:       log,
+	}
+}
+
+// CreateCacheRequest represents a cache creation request. Names are short as
+// the cache's Kubernetes objects are named after the project ID and the name.
+type CreateCacheRequest struct {
+	Name        string `json:"name" binding:"required,hostname_rfc1123,max=15"`
+	Engine      string `json:"engine" binding:"omitempty,oneof=dragonfly redis"` // Defaults to dragonfly
+	Size        string `json:"size" binding:"required,oneof=small medium large xlarge"`
+	Persistence bool   `json:"persistence"`
+	StorageGB   int    `json:"storage_gb" binding:"required_if=Persistence true,gte=0,max=500"`
+	TLSEnabled  bool   `json:"tls_enabled"`
+	Version     string `json:"version"`
+}
+
+// CacheResponse is a cache with the project secret holding its connection
+type CacheResponse struct {
+	*dragonfly.CacheInfo
+	SecretRef string `json:"secret_ref,omitempty"`
+}
+
+// CreateCache handles POST /projects/:project_id/caches
+func (h *CacheHandler) CreateCache(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	var req CreateCacheRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	if req.TLSEnabled && !h.caches.TLSAvailable() {
+		respondError(c, errors.BadRequest("TLS is not available for caches"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	project, err := h.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	teamID := ""
+	if tid, exists := c.Get("team_id"); exists {
+		teamID = tid.(string)
+	}
+
+	cache, err := h.caches.CreateCache(ctx, &dragonfly.CreateCacheInput{
+		Name:        req.Name,
+		ProjectID:   projectID.String(),
+		TeamID:      teamID,
+		Engine:      req.Engine,
+		Size:        req.Size,
+		Persistence: req.Persistence,
+		StorageGB:   req.StorageGB,
+		TLSEnabled:  req.TLSEnabled,
+		Version:     req.Version,
+	})
+	if apierrors.IsAlreadyExists(err) {
+		respondError(c, errors.Conflict("a cache named "+req.Name+" already exists"))
+		return
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to create cache")
+		respondError(c, errors.Internal("Failed to create cache"))
+		return
+	}
+
+	response := &CacheResponse{CacheInfo: cache}
+	if h.secretRepo != nil && h.secretWriter != nil {
+		secret, err := h.registerSecret(ctx, project, cache)
+		if err != nil {
+			// The cache works without it; binding retries the registration
+			h.logger.Warn().Err(err).Str("cache_id", cache.ID).Msg("Failed to register cache connection secret")
+		} else {
+			response.SecretRef = secret.Name
+		}
+	}
+
+	h.publishEvent(ctx, "cache.created", map[string]interface{}{
+		"cache_id":   cache.ID,
+		"project_id": projectID.String(),
+		"name":       req.Name,
+		"engine":     cache.Engine,
+	})
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListCaches handles GET /projects/:project_id/caches
+func (h *CacheHandler) ListCaches(c *gin.Context) {
+	caches, err := h.caches.ListCaches(c.Request.Context(), c.Param("project_id"))
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to list caches")
+		respondError(c, errors.Internal("Failed to list caches"))
+		return
+	}
+
+	responses := make([]*CacheResponse, 0, len(caches))
+	for _, cache := range caches {
+		responses = append(responses, h.response(cache))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"caches": responses,
+		"total":  len(responses),
+	})
+}
+
+// GetCache handles GET /caches/:id
+func (h *CacheHandler) GetCache(c *gin.Context) {
+	cache, err := h.getCache(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, h.response(cache))
+}
+
+// DeleteCache handles DELETE /caches/:id. The project secret is unregistered;
+// its values stay in Vault.
+func (h *CacheHandler) DeleteCache(c *gin.Context) {
+	ctx := c.Request.Context()
+	cacheID := c.Param("id")
+	cache, err := h.getCache(ctx, cacheID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.caches.DeleteCache(ctx, cacheID); err != nil {
+		h.logger.Error().Err(err).Msg("Failed to delete cache")
+		respondError(c, errors.Internal("Failed to delete cache"))
+		return
+	}
+
+	if projectID, ok := cacheProject(cache); ok && h.secretRepo != nil {
+		secret, err := h.secretRepo.GetByName(ctx, projectID, cacheSecretName(cache))
+		if err == nil {
+			err = h.secretRepo.Delete(ctx, secret.ID)
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			h.logger.Warn().Err(err).Str("cache_id", cacheID).Msg("Failed to unregister cache connection secret")
+		}
+	}
+
+	h.publishEvent(ctx, "cache.deleted", map[string]interface{}{
+		"cache_id": cacheID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cache deleted"})
+}
+
+// GetConnectionInfo handles GET /caches/:id/connection
+func (h *CacheHandler) GetConnectionInfo(c *gin.Context) {
+	cache, err := h.getCache(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	response := h.response(cache)
+	c.JSON(http.StatusOK, gin.H{
+		"endpoint":          cache.Endpoint,
+		"port":              cache.Port,
+		"tls_enabled":       cache.TLSEnabled,
+		"secret_name":       cache.SecretName,
+		"secret_ref":        response.SecretRef,
+		"connection_string": "View the REDIS_URL key of the secret",
+	})
+}
+
+// BindCacheRequest names the service that reads a cache's connection
+type BindCacheRequest struct {
+	ServiceID string `json:"service_id" binding:"required,uuid"`
+}
+
+// BindCache handles POST /caches/:id/bind. The cache's project secret is added
+// to the secret references of a service of the same project, which receives
+// REDIS_HOST, REDIS_PORT, REDIS_PASSWORD and REDIS_URL on its next deployment.
+func (h *CacheHandler) BindCache(c *gin.Context) {
+	if h.secretRepo == nil || h.secretWriter == nil {
+		respondError(c, errors.BadRequest("binding caches needs Vault secrets"))
+		return
+	}
+
+	var req BindCacheRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cache, err := h.getCache(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	service, err := h.serviceRepo.GetByID(ctx, uuid.MustParse(req.ServiceID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if projectID, ok := cacheProject(cache); !ok || projectID != service.ProjectID {
+		respondError(c, errors.BadRequest("the service is not in the cache's project"))
+		return
+	}
+
+	secret, err := h.secretRepo.GetByName(ctx, service.ProjectID, cacheSecretName(cache))
+	if errors.IsNotFound(err) {
+		var project *domain.Project
+		if project, err = h.projectRepo.GetByID(ctx, service.ProjectID); err == nil {
+			secret, err = h.registerSecret(ctx, project, cache)
+		}
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("cache_id", cache.ID).Msg("Failed to register cache connection secret")
+		respondError(c, errors.Internal("Failed to bind cache"))
+		return
+	}
+
+	bound := false
+	for _, ref := range service.SecretRefs {
+		if ref == secret.Name {
+			bound = true
+			break
+		}
+	}
+	if !bound {
+		service.SecretRefs = append(service.SecretRefs, secret.Name)
+		service.UpdatedAt = time.Now()
+		if err := h.serviceRepo.Update(ctx, service); err != nil {
+			respondError(c, err)
+			return
+		}
+		h.publishEvent(ctx, "cache.bound", map[string]interface{}{
+			"cache_id":   cache.ID,
+			"service_id": service.ID.String(),
+			"secret_ref": secret.Name,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_id":  service.ID,
+		"secret_refs": service.SecretRefs,
+	})
+}
+
+// getCache returns a cache, or a not found error
+func (h *CacheHandler) getCache(ctx context.Context, cacheID string) (*dragonfly.CacheInfo, error) {
+	cache, err := h.caches.GetCache(ctx, cacheID)
+	if apierrors.IsNotFound(err) {
+		return nil, errors.NotFound("cache", cacheID)
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("cache_id", cacheID).Msg("Failed to get cache")
+		return nil, errors.Internal("Failed to get cache")
+	}
+	return cache, nil
+}
+
+// registerSecret writes the connection of a cache to Vault and registers it as
+// a project secret
+func (h *CacheHandler) registerSecret(ctx context.Context, project *domain.Project, cache *dragonfly.CacheInfo) (*domain.Secret, error) {
+	values, err := h.caches.Connection(ctx, cache.ID)
+	if err != nil {
+		return nil, err
+	}
+	path := "caches/" + project.Slug + "/" + cache.Name
+	version, err := h.secretWriter.WriteSecret(ctx, path, values)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	now := time.Now()
+	secret := &domain.Secret{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		Name:      cacheSecretName(cache),
+		Type:      domain.SecretTypeOpaque,
+		Keys:      keys,
+		VaultPath: path,
+		Version:   version,
+		Labels:    map[string]string{"northstack.io/cache": cache.ID},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.secretRepo.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// response adds the name of the project secret of a cache, when there is one
+func (h *CacheHandler) response(cache *dragonfly.CacheInfo) *CacheResponse {
+	response := &CacheResponse{CacheInfo: cache}
+	if h.secretRepo != nil && h.secretWriter != nil {
+		response.SecretRef = cacheSecretName(cache)
+	}
+	return response
+}
+
+func (h *CacheHandler) publishEvent(ctx context.Context, eventType string, data map[string]interface{}) {
+	event := &domain.Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+	if err := h.eventBus.Publish(ctx, eventType, event); err != nil {
+		h.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// cacheSecretName is the name of the project secret of a cache
+func cacheSecretName(cache *dragonfly.CacheInfo) string {
+	return "cache-" + cache.Name
+}
+
+// cacheProject returns the project of a cache
+func cacheProject(cache *dragonfly.CacheInfo) (uuid.UUID, bool) {
+	id, err := uuid.Parse(cache.ProjectID)
+	return id, err == nil
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/capacity"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// CapacityHandler handles cluster capacity endpoints
+type CapacityHandler struct {
+	reporter    *capacity.Reporter
+	clusterRepo domain.ClusterRepository
+	logger      *logger.Logger
+}
+
+// NewCapacityHandler creates a new CapacityHandler
+func NewCapacityHandler(reporter *capacity.Reporter, clusterRepo domain.ClusterRepository, log *logger.Logger) *CapacityHandler {
+	return &CapacityHandler{
+		reporter:    reporter,
+		clusterRepo: clusterRepo,
+		logger:      log,
+	}
+}
+
+// Get handles GET /clusters/:id/capacity
+func (h *CapacityHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid cluster ID"))
+		return
+	}
+
+	cluster, err := h.clusterRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	report, err := h.reporter.Report(c.Request.Context(), cluster)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
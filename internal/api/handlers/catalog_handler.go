@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/catalog"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// CatalogHandler handles service catalog endpoints
+type CatalogHandler struct {
+	catalog *catalog.Catalog
+	logger  *logger.Logger
+}
+
+// NewCatalogHandler creates a new CatalogHandler
+func NewCatalogHandler(c *catalog.Catalog, log *logger.Logger) *CatalogHandler {
+	return &CatalogHandler{
+		catalog: c,
+		logger:  log,
+	}
+}
+
+// List handles GET /catalog
+// Query: project_id, tier, lifecycle, owner
+func (h *CatalogHandler) List(c *gin.Context) {
+	filter := catalog.Filter{
+		Tier:      domain.ServiceTier(c.Query("tier")),
+		Lifecycle: domain.ServiceLifecycle(c.Query("lifecycle")),
+		Owner:     c.Query("owner"),
+	}
+
+	if v := c.Query("project_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			respondError(c, errors.BadRequest("invalid project_id"))
+			return
+		}
+		filter.ProjectID = &id
+	}
+
+	entries, err := h.catalog.List(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  entries,
+		"count": len(entries),
+	})
+}
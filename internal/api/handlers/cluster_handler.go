@@ -2,105 +2,242 @@ package handlers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/clusterupgrade"
+	"github.com/northstack/platform/internal/devcluster"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/egress"
+	"github.com/northstack/platform/internal/ingressroutes"
+	"github.com/northstack/platform/internal/rke2"
+	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
 )
 
 // ClusterHandler handles Kubernetes cluster management endpoints
 type ClusterHandler struct {
-	clusterRepo domain.ClusterRepository
-	eventBus    domain.EventBus
-	logger      *logger.Logger
+	clusterRepo    domain.ClusterRepository
+	envRepo        domain.EnvironmentRepository
+	clusterManager domain.ClusterManagerAdapter
+	provisioner    *rke2.Provisioner
+	devClusters    *devcluster.Bootstrapper
+	eventBus       domain.EventBus
+	logger         *logger.Logger
 }
 
-// NewClusterHandler creates a new ClusterHandler
-func NewClusterHandler(clusterRepo domain.ClusterRepository, eventBus domain.EventBus, log *logger.Logger) *ClusterHandler {
+// NewClusterHandler creates a new ClusterHandler. Without a cluster manager
+// clusters are only recorded; they are not provisioned or deprovisioned.
+// Without a provisioner RKE2 clusters cannot be installed on nodes, and
+// without a bootstrapper dev clusters cannot be created.
+func NewClusterHandler(clusterRepo domain.ClusterRepository, envRepo domain.EnvironmentRepository, clusterManager domain.ClusterManagerAdapter, provisioner *rke2.Provisioner, devClusters *devcluster.Bootstrapper, eventBus domain.EventBus, log *logger.Logger) *ClusterHandler {
 	return &ClusterHandler{
-		clusterRepo: clusterRepo,
-		eventBus:    eventBus,
-		logger:      log,
+		clusterRepo:    clusterRepo,
+		envRepo:        envRepo,
+		clusterManager: clusterManager,
+		provisioner:    provisioner,
+		devClusters:    devClusters,
+		eventBus:       eventBus,
+		logger:         log,
 	}
 }
 
 // CreateClusterRequest represents a cluster creation request
 type CreateClusterRequest struct {
-	Name        string            `json:"name" binding:"required"`
-	Provider    string            `json:"provider" binding:"required,oneof=rancher rke2 k3s eks gke aks"`
-	Region      string            `json:"region" binding:"required"`
-	KubeVersion string            `json:"kube_version"`
-	NodeCount   int32             `json:"node_count" binding:"required,min=1"`
-	Labels      map[string]string `json:"labels"`
+	Name              string            `json:"name" binding:"required"`
+	Slug              string            `json:"slug"`
+	Provider          string            `json:"provider" binding:"required,oneof=rancher rke2 k3s eks gke aks"`
+	Region            string            `json:"region" binding:"required"`
+	KubeVersion       string            `json:"kube_version"`
+	NodeCount         int32             `json:"node_count" binding:"required_without=Nodes,omitempty,min=1"`
+	Labels            map[string]string `json:"labels"`
+	EgressIPs         []string          `json:"egress_ips,omitempty" binding:"omitempty,dive,ip"`                     // Public IPs of the cloud NAT in front of the cluster
+	Nodes             []rke2.Node       `json:"nodes,omitempty" binding:"omitempty,dive"`                             // Machines to install an rke2 cluster on over SSH
+	IngressController string            `json:"ingress_controller,omitempty" binding:"omitempty,oneof=nginx traefik"` // Dialect of the cluster's ingresses; the platform default when empty
+}
+
+// UpdateClusterRequest represents a cluster update request
+type UpdateClusterRequest struct {
+	Name              *string           `json:"name,omitempty"`
+	KubeVersion       *string           `json:"kube_version,omitempty"`
+	NodeCount         *int32            `json:"node_count,omitempty" binding:"omitempty,min=1"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	EgressIPs         []string          `json:"egress_ips,omitempty" binding:"omitempty,dive,ip"`
+	IngressController *string           `json:"ingress_controller,omitempty" binding:"omitempty,oneof=nginx traefik"` // Empty restores the platform default
 }
 
 // ClusterResponse represents a cluster in API responses
 type ClusterResponse struct {
-	ID          uuid.UUID         `json:"id"`
-	Name        string            `json:"name"`
-	Slug        string            `json:"slug"`
-	Provider    string            `json:"provider"`
-	Region      string            `json:"region"`
-	KubeVersion string            `json:"kube_version"`
-	Status      string            `json:"status"`
-	Endpoint    string            `json:"endpoint,omitempty"`
-	NodeCount   int32             `json:"node_count"`
-	Labels      map[string]string `json:"labels,omitempty"`
-	CreatedAt   time.Time         `json:"created_at"`
-	UpdatedAt   time.Time         `json:"updated_at"`
-}
-
-// CreateCluster creates a new Kubernetes cluster
+	ID                uuid.UUID                `json:"id"`
+	Name              string                   `json:"name"`
+	Slug              string                   `json:"slug"`
+	Provider          string                   `json:"provider"`
+	Region            string                   `json:"region"`
+	KubeVersion       string                   `json:"kube_version"`
+	Status            string                   `json:"status"`
+	Endpoint          string                   `json:"endpoint,omitempty"`
+	NodeCount         int32                    `json:"node_count"`
+	Labels            map[string]string        `json:"labels,omitempty"`
+	EgressIPs         []string                 `json:"egress_ips,omitempty"`
+	IngressController string                   `json:"ingress_controller,omitempty"` // Set when the cluster overrides the platform default
+	Provisioning      *rke2.Progress           `json:"provisioning,omitempty"`       // Node progress of rke2 clusters installed by the platform
+	DevCluster        *devcluster.Info         `json:"dev_cluster,omitempty"`        // Set on single-node dev clusters, which only host non-production environments
+	Upgrade           *clusterupgrade.Progress `json:"upgrade,omitempty"`            // Latest Kubernetes version upgrade
+	CreatedAt         time.Time                `json:"created_at"`
+	UpdatedAt         time.Time                `json:"updated_at"`
+}
+
+// CreateCluster handles POST /clusters
 func (h *ClusterHandler) CreateCluster(c *gin.Context) {
 	var req CreateClusterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, bindError(err))
+		return
+	}
+
+	slug := req.Slug
+	if slug == "" {
+		slug = slugify(req.Name)
+	}
+	if !isDNSLabel(slug) {
+		respondError(c, errors.BadRequest("slug must be a lowercase DNS label of at most 63 characters"))
 		return
 	}
 
+	ctx := c.Request.Context()
+	now := time.Now()
 	cluster := &domain.Cluster{
 		ID:          uuid.New(),
 		Name:        req.Name,
-		Slug:        req.Name, // Simplify for now
+		Slug:        slug,
 		Provider:    domain.ClusterProvider(req.Provider),
 		Region:      req.Region,
 		KubeVersion: req.KubeVersion,
 		Status:      domain.ClusterStatusProvisioning,
 		NodeCount:   req.NodeCount,
 		Labels:      req.Labels,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	egress.SetClusterNATIPs(cluster, req.EgressIPs)
+	ingressroutes.SetClusterController(cluster, req.IngressController)
+
+	install := len(req.Nodes) > 0
+	if install {
+		if cluster.Provider != domain.ClusterProviderRKE2 {
+			respondError(c, errors.BadRequest("nodes can only be given for rke2 clusters"))
+			return
+		}
+		if h.provisioner == nil {
+			respondError(c, errors.BadRequest("RKE2 provisioning is not enabled"))
+			return
+		}
+		if err := h.provisioner.Prepare(cluster, req.Nodes); err != nil {
+			respondError(c, err)
+			return
+		}
+	} else if h.clusterManager != nil {
+		externalID, err := h.clusterManager.CreateCluster(ctx, cluster)
+		if err != nil {
+			h.logger.Error().Err(err).Str("slug", slug).Msg("Failed to provision cluster")
+			respondError(c, err)
+			return
+		}
+		cluster.RancherClusterID = externalID
 	}
 
-	if err := h.clusterRepo.Create(c.Request.Context(), cluster); err != nil {
-		h.logger.Error().Err(err).Msg("Failed to create cluster")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cluster"})
+	if err := h.clusterRepo.Create(ctx, cluster); err != nil {
+		h.logger.Error().Err(err).Str("slug", slug).Msg("Failed to create cluster")
+		if cluster.RancherClusterID != "" {
+			if derr := h.clusterManager.DeleteCluster(ctx, cluster.RancherClusterID); derr != nil {
+				h.logger.Warn().Err(derr).Str("external_id", cluster.RancherClusterID).Msg("Failed to roll back provisioned cluster")
+			}
+		}
+		respondError(c, err)
 		return
 	}
 
-	// Publish event
-	h.publishEvent(c.Request.Context(), "cluster.created", map[string]interface{}{
+	h.publishEvent(ctx, "cluster.created", map[string]interface{}{
 		"cluster_id": cluster.ID.String(),
 		"name":       cluster.Name,
 		"provider":   string(cluster.Provider),
 	})
 
+	if install {
+		h.provisioner.Start(cluster)
+	}
+
 	c.JSON(http.StatusCreated, h.toResponse(cluster))
 }
 
-// ListClusters lists all clusters
+// CreateDevCluster handles POST /clusters/dev. The cluster is returned as
+// provisioning while k3s is installed.
+func (h *ClusterHandler) CreateDevCluster(c *gin.Context) {
+	if h.devClusters == nil {
+		respondError(c, errors.BadRequest("dev clusters are not enabled"))
+		return
+	}
+
+	var req devcluster.Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cluster, err := h.devClusters.Create(ctx, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.publishEvent(ctx, "cluster.created", map[string]interface{}{
+		"cluster_id": cluster.ID.String(),
+		"name":       cluster.Name,
+		"provider":   string(cluster.Provider),
+		"dev":        true,
+	})
+
+	c.JSON(http.StatusCreated, h.toResponse(cluster))
+}
+
+// ListClusters handles GET /clusters
 func (h *ClusterHandler) ListClusters(c *gin.Context) {
 	filter := domain.ClusterFilter{
-		Limit: 100,
+		Region: c.Query("region"),
+		Limit:  parseIntQuery(c, "limit", 50),
+		Offset: parseIntQuery(c, "offset", 0),
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		respondError(c, errors.BadRequest("limit must be between 1 and 100"))
+		return
+	}
+	if provider := c.Query("provider"); provider != "" {
+		p := domain.ClusterProvider(provider)
+		filter.Provider = &p
 	}
+	if status := c.Query("status"); status != "" {
+		s := domain.ClusterStatus(status)
+		filter.Status = &s
+	}
+	labels, ok := parseLabelSelector(c)
+	if !ok {
+		return
+	}
+	filter.Labels = labels
+
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+	filter.After = after
 
 	clusters, err := h.clusterRepo.List(c.Request.Context(), filter)
 	if err != nil {
-		h.logger.Error().Err(err).Msg("Failed to list clusters")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list clusters"})
+		respondError(c, err)
 		return
 	}
 
@@ -110,101 +247,425 @@ func (h *ClusterHandler) ListClusters(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"clusters": responses,
-		"total":    len(responses),
+		"data":        responses,
+		"count":       len(responses),
+		"offset":      filter.Offset,
+		"limit":       filter.Limit,
+		"next_cursor": nextCursor(clusters, filter.Limit, clusterCursor),
 	})
 }
 
-// GetCluster retrieves a specific cluster
+// GetCluster handles GET /clusters/:id
 func (h *ClusterHandler) GetCluster(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cluster ID"})
+	cluster, ok := h.cluster(c)
+	if !ok {
 		return
 	}
 
-	cluster, err := h.clusterRepo.GetByID(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cluster not found"})
+	c.JSON(http.StatusOK, h.toResponse(cluster))
+}
+
+// UpdateCluster handles PATCH /clusters/:id
+func (h *ClusterHandler) UpdateCluster(c *gin.Context) {
+	var req UpdateClusterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	cluster, ok := h.cluster(c)
+	if !ok {
 		return
 	}
 
+	resized := false
+	if req.Name != nil {
+		cluster.Name = *req.Name
+	}
+	if req.KubeVersion != nil && *req.KubeVersion != cluster.KubeVersion {
+		cluster.KubeVersion = *req.KubeVersion
+		resized = true
+	}
+	if req.NodeCount != nil && *req.NodeCount != cluster.NodeCount {
+		cluster.NodeCount = *req.NodeCount
+		resized = true
+	}
+	if req.Labels != nil {
+		cluster.Labels = req.Labels
+	}
+	if req.EgressIPs != nil {
+		egress.SetClusterNATIPs(cluster, req.EgressIPs)
+	}
+	if req.IngressController != nil {
+		ingressroutes.SetClusterController(cluster, *req.IngressController)
+	}
+
+	if resized && cluster.Status == domain.ClusterStatusUpgrading {
+		respondError(c, upgradingError())
+		return
+	}
+
+	ctx := c.Request.Context()
+	if resized && h.clusterManager != nil && cluster.RancherClusterID != "" {
+		if err := h.clusterManager.UpdateCluster(ctx, cluster); err != nil {
+			h.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to update cluster")
+			respondError(c, err)
+			return
+		}
+	}
+
+	if err := h.clusterRepo.Update(ctx, cluster); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.publishEvent(ctx, "cluster.updated", map[string]interface{}{
+		"cluster_id": cluster.ID.String(),
+		"name":       cluster.Name,
+	})
+
 	c.JSON(http.StatusOK, h.toResponse(cluster))
 }
 
-// DeleteCluster deletes a cluster
+// DeleteCluster handles DELETE /clusters/:id. Clusters that still host
+// environments cannot be deleted.
 func (h *ClusterHandler) DeleteCluster(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cluster ID"})
+	cluster, ok := h.cluster(c)
+	if !ok {
 		return
 	}
 
-	cluster, err := h.clusterRepo.GetByID(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cluster not found"})
-		return
+	ctx := c.Request.Context()
+	if h.envRepo != nil {
+		environments, err := h.envRepo.ListByCluster(ctx, cluster.ID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if len(environments) > 0 {
+			respondError(c, errors.NewError(errors.CodeConflict,
+				fmt.Sprintf("cluster still hosts %d environment(s)", len(environments)), http.StatusConflict))
+			return
+		}
 	}
 
-	// Mark as deleting
-	cluster.Status = domain.ClusterStatusDeleting
-	if err := h.clusterRepo.Update(c.Request.Context(), cluster); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cluster"})
+	if h.clusterManager != nil && cluster.RancherClusterID != "" {
+		cluster.Status = domain.ClusterStatusDeleting
+		if err := h.clusterRepo.Update(ctx, cluster); err != nil {
+			respondError(c, err)
+			return
+		}
+		if err := h.clusterManager.DeleteCluster(ctx, cluster.RancherClusterID); err != nil && !errors.IsNotFound(err) {
+			h.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to deprovision cluster")
+			respondError(c, err)
+			return
+		}
+	}
+
+	if h.devClusters != nil {
+		if err := h.devClusters.Delete(ctx, cluster); err != nil {
+			h.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to delete dev cluster")
+			respondError(c, err)
+			return
+		}
+	}
+
+	if err := h.clusterRepo.Delete(ctx, cluster.ID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	// Publish event
-	h.publishEvent(c.Request.Context(), "cluster.deleted", map[string]interface{}{
+	h.publishEvent(ctx, "cluster.deleted", map[string]interface{}{
 		"cluster_id": cluster.ID.String(),
 		"name":       cluster.Name,
 	})
 
-	c.JSON(http.StatusOK, gin.H{"message": "Cluster deletion initiated"})
+	h.logger.Info().
+		Str("cluster_id", cluster.ID.String()).
+		Msg("Cluster deleted")
+
+	c.Status(http.StatusNoContent)
 }
 
-// GetClusterKubeconfig retrieves the kubeconfig for a cluster
+// GetClusterKubeconfig handles GET /clusters/:id/kubeconfig
 func (h *ClusterHandler) GetClusterKubeconfig(c *gin.Context) {
-	idStr := c.Param("id")
-	id, err := uuid.Parse(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cluster ID"})
+	cluster, ok := h.cluster(c)
+	if !ok {
 		return
 	}
 
-	cluster, err := h.clusterRepo.GetByID(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cluster not found"})
+	if cluster.Status != domain.ClusterStatusActive {
+		respondError(c, errors.BadRequest("cluster is not ready"))
+		return
+	}
+	if h.clusterManager == nil || cluster.RancherClusterID == "" {
+		respondError(c, errors.BadRequest("cluster is not managed by the platform"))
 		return
 	}
 
-	if cluster.Status != domain.ClusterStatusActive {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cluster is not ready"})
+	kubeconfig, err := h.clusterManager.GetKubeConfig(c.Request.Context(), cluster.RancherClusterID)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"kubeconfig": "# Kubeconfig retrieved from cluster provider",
+		"kubeconfig": string(kubeconfig),
 		"cluster_id": cluster.ID,
 		"endpoint":   cluster.APIEndpoint,
 	})
 }
 
+// CreateNodePoolRequest represents a node pool creation request
+type CreateNodePoolRequest struct {
+	Name         string `json:"name" binding:"required"`
+	InstanceType string `json:"instance_type"`
+	NodeCount    int32  `json:"node_count" binding:"required,min=1"`
+	MinCount     int32  `json:"min_count" binding:"omitempty,min=0"`
+	MaxCount     int32  `json:"max_count" binding:"omitempty,min=1"`
+	AutoScaling  bool   `json:"autoscaling"`
+}
+
+// UpdateNodePoolRequest represents a node pool update request
+type UpdateNodePoolRequest struct {
+	InstanceType *string `json:"instance_type,omitempty"`
+	NodeCount    *int32  `json:"node_count,omitempty" binding:"omitempty,min=1"`
+	MinCount     *int32  `json:"min_count,omitempty" binding:"omitempty,min=0"`
+	MaxCount     *int32  `json:"max_count,omitempty" binding:"omitempty,min=1"`
+	AutoScaling  *bool   `json:"autoscaling,omitempty"`
+}
+
+// ListNodePools handles GET /clusters/:id/nodepools
+func (h *ClusterHandler) ListNodePools(c *gin.Context) {
+	cluster, ok := h.managedCluster(c)
+	if !ok {
+		return
+	}
+
+	pools, err := h.clusterManager.ListNodePools(c.Request.Context(), cluster.RancherClusterID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": pools, "count": len(pools)})
+}
+
+// CreateNodePool handles POST /clusters/:id/nodepools
+func (h *ClusterHandler) CreateNodePool(c *gin.Context) {
+	var req CreateNodePoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	if !isDNSLabel(req.Name) {
+		respondError(c, errors.BadRequest("name must be a lowercase DNS label of at most 63 characters"))
+		return
+	}
+
+	cluster, ok := h.managedCluster(c)
+	if !ok {
+		return
+	}
+
+	pool := &domain.NodePool{
+		Name:         req.Name,
+		InstanceType: req.InstanceType,
+		NodeCount:    req.NodeCount,
+		MinCount:     req.MinCount,
+		MaxCount:     req.MaxCount,
+		AutoScaling:  req.AutoScaling,
+	}
+	if err := validateNodePool(pool); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.clusterManager.CreateNodePool(ctx, cluster.RancherClusterID, pool); err != nil {
+		h.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Str("pool", pool.Name).Msg("Failed to create node pool")
+		respondError(c, err)
+		return
+	}
+	h.nodePoolsChanged(ctx, cluster, "cluster.nodepool.created", pool.Name)
+
+	c.JSON(http.StatusCreated, pool)
+}
+
+// UpdateNodePool handles PATCH /clusters/:id/nodepools/:name. Shrinking a
+// pool cordons and drains the nodes to be removed first.
+func (h *ClusterHandler) UpdateNodePool(c *gin.Context) {
+	var req UpdateNodePoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	cluster, ok := h.managedCluster(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	pool, err := h.nodePool(ctx, cluster, c.Param("name"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if req.InstanceType != nil {
+		pool.InstanceType = *req.InstanceType
+	}
+	if req.NodeCount != nil {
+		pool.NodeCount = *req.NodeCount
+	}
+	if req.MinCount != nil {
+		pool.MinCount = *req.MinCount
+	}
+	if req.MaxCount != nil {
+		pool.MaxCount = *req.MaxCount
+	}
+	if req.AutoScaling != nil {
+		pool.AutoScaling = *req.AutoScaling
+	}
+	if err := validateNodePool(pool); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.clusterManager.UpdateNodePool(ctx, cluster.RancherClusterID, pool); err != nil {
+		h.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Str("pool", pool.Name).Msg("Failed to update node pool")
+		respondError(c, err)
+		return
+	}
+	h.nodePoolsChanged(ctx, cluster, "cluster.nodepool.updated", pool.Name)
+
+	c.JSON(http.StatusOK, pool)
+}
+
+// DeleteNodePool handles DELETE /clusters/:id/nodepools/:name. The pool's
+// nodes are cordoned and drained first.
+func (h *ClusterHandler) DeleteNodePool(c *gin.Context) {
+	cluster, ok := h.managedCluster(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	name := c.Param("name")
+	if err := h.clusterManager.DeleteNodePool(ctx, cluster.RancherClusterID, name); err != nil {
+		h.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Str("pool", name).Msg("Failed to delete node pool")
+		respondError(c, err)
+		return
+	}
+	h.nodePoolsChanged(ctx, cluster, "cluster.nodepool.deleted", name)
+
+	c.Status(http.StatusNoContent)
+}
+
+// managedCluster loads a cluster whose node pools the cluster manager manages
+func (h *ClusterHandler) managedCluster(c *gin.Context) (*domain.Cluster, bool) {
+	cluster, ok := h.cluster(c)
+	if !ok {
+		return nil, false
+	}
+	if h.clusterManager == nil || cluster.RancherClusterID == "" {
+		respondError(c, errors.BadRequest("cluster is not managed by the platform"))
+		return nil, false
+	}
+	if cluster.Status == domain.ClusterStatusUpgrading && c.Request.Method != http.MethodGet {
+		respondError(c, upgradingError())
+		return nil, false
+	}
+	return cluster, true
+}
+
+// upgradingError refuses changes to the nodes or version of a cluster mid-upgrade
+func upgradingError() error {
+	return errors.NewError(errors.CodeConflict, "cluster is being upgraded; its nodes cannot change until the upgrade finishes", http.StatusConflict)
+}
+
+func (h *ClusterHandler) nodePool(ctx context.Context, cluster *domain.Cluster, name string) (*domain.NodePool, error) {
+	pools, err := h.clusterManager.ListNodePools(ctx, cluster.RancherClusterID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range pools {
+		if pool.Name == name {
+			return pool, nil
+		}
+	}
+	return nil, errors.NotFound("node pool", name)
+}
+
+// nodePoolsChanged records the cluster's new size and publishes the change
+func (h *ClusterHandler) nodePoolsChanged(ctx context.Context, cluster *domain.Cluster, eventType, pool string) {
+	if pools, err := h.clusterManager.ListNodePools(ctx, cluster.RancherClusterID); err == nil {
+		var count int32
+		for _, p := range pools {
+			count += p.NodeCount
+		}
+		cluster.NodeCount = count
+		cluster.UpdatedAt = time.Now()
+		if err := h.clusterRepo.Update(ctx, cluster); err != nil {
+			h.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to record cluster size")
+		}
+	}
+
+	h.publishEvent(ctx, eventType, map[string]interface{}{
+		"cluster_id": cluster.ID.String(),
+		"pool":       pool,
+	})
+}
+
+// validateNodePool checks a pool's size against its bounds
+func validateNodePool(pool *domain.NodePool) error {
+	if pool.MaxCount > 0 && pool.MinCount > pool.MaxCount {
+		return errors.BadRequest("min_count must not exceed max_count")
+	}
+	if pool.AutoScaling && pool.MaxCount == 0 {
+		return errors.BadRequest("max_count is required when autoscaling")
+	}
+	if pool.MaxCount > 0 && (pool.NodeCount < pool.MinCount || pool.NodeCount > pool.MaxCount) {
+		return errors.BadRequest("node_count must be between min_count and max_count")
+	}
+	return nil
+}
+
+func (h *ClusterHandler) cluster(c *gin.Context) (*domain.Cluster, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid cluster ID"))
+		return nil, false
+	}
+
+	cluster, err := h.clusterRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return cluster, true
+}
+
 func (h *ClusterHandler) toResponse(cluster *domain.Cluster) ClusterResponse {
 	return ClusterResponse{
-		ID:          cluster.ID,
-		Name:        cluster.Name,
-		Slug:        cluster.Slug,
-		Provider:    string(cluster.Provider),
-		Region:      cluster.Region,
-		KubeVersion: cluster.KubeVersion,
-		Status:      string(cluster.Status),
-		Endpoint:    cluster.APIEndpoint,
-		NodeCount:   cluster.NodeCount,
-		Labels:      cluster.Labels,
-		CreatedAt:   cluster.CreatedAt,
-		UpdatedAt:   cluster.UpdatedAt,
+		ID:                cluster.ID,
+		Name:              cluster.Name,
+		Slug:              cluster.Slug,
+		Provider:          string(cluster.Provider),
+		Region:            cluster.Region,
+		KubeVersion:       cluster.KubeVersion,
+		Status:            string(cluster.Status),
+		Endpoint:          cluster.APIEndpoint,
+		NodeCount:         cluster.NodeCount,
+		Labels:            cluster.Labels,
+		EgressIPs:         egress.ClusterNATIPs(cluster),
+		IngressController: string(ingressroutes.ClusterController(cluster, "")),
+		Provisioning:      rke2.GetProgress(cluster),
+		DevCluster:        devcluster.GetInfo(cluster),
+		Upgrade:           clusterupgrade.GetProgress(cluster),
+		CreatedAt:         cluster.CreatedAt,
+		UpdatedAt:         cluster.UpdatedAt,
 	}
 }
 
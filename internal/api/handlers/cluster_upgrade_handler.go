@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/clusterupgrade"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// ClusterUpgradeHandler handles Kubernetes version upgrade endpoints
+type ClusterUpgradeHandler struct {
+	upgrader    *clusterupgrade.Upgrader
+	clusterRepo domain.ClusterRepository
+	logger      *logger.Logger
+}
+
+// NewClusterUpgradeHandler creates a new ClusterUpgradeHandler
+func NewClusterUpgradeHandler(upgrader *clusterupgrade.Upgrader, clusterRepo domain.ClusterRepository, log *logger.Logger) *ClusterUpgradeHandler {
+	return &ClusterUpgradeHandler{
+		upgrader:    upgrader,
+		clusterRepo: clusterRepo,
+		logger:      log,
+	}
+}
+
+// StartUpgradeRequest represents the request body for upgrading a cluster
+type StartUpgradeRequest struct {
+	Version  string `json:"version" binding:"required"`
+	MaxSurge int32  `json:"max_surge" binding:"omitempty,min=0,max=100"` // Extra nodes each pool gets while it is upgraded
+}
+
+// UpgradeStatusResponse lists a cluster's upgrade options and latest upgrade
+type UpgradeStatusResponse struct {
+	CurrentVersion    string                   `json:"current_version"`
+	AvailableVersions []string                 `json:"available_versions"`
+	Upgrade           *clusterupgrade.Progress `json:"upgrade,omitempty"`
+}
+
+// Status handles GET /clusters/:id/upgrade
+func (h *ClusterUpgradeHandler) Status(c *gin.Context) {
+	cluster, ok := h.loadCluster(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.upgrader.Versions(c.Request.Context(), cluster)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, UpgradeStatusResponse{
+		CurrentVersion:    cluster.KubeVersion,
+		AvailableVersions: versions,
+		Upgrade:           clusterupgrade.GetProgress(cluster),
+	})
+}
+
+// Start handles POST /clusters/:id/upgrade
+func (h *ClusterUpgradeHandler) Start(c *gin.Context) {
+	var req StartUpgradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	cluster, ok := h.loadCluster(c)
+	if !ok {
+		return
+	}
+
+	progress, err := h.upgrader.Start(c.Request.Context(), cluster.ID, req.Version, req.MaxSurge)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, progress)
+}
+
+func (h *ClusterUpgradeHandler) loadCluster(c *gin.Context) (*domain.Cluster, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid cluster ID"))
+		return nil, false
+	}
+
+	cluster, err := h.clusterRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return cluster, true
+}
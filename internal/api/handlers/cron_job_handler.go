@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/cronjobs"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// CronJobHandler handles the schedule and run endpoints of cron job services
+type CronJobHandler struct {
+	manager     *cronjobs.Manager
+	serviceRepo domain.ServiceRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewCronJobHandler creates a new CronJobHandler
+func NewCronJobHandler(manager *cronjobs.Manager, serviceRepo domain.ServiceRepository, eventBus domain.EventBus, log *logger.Logger) *CronJobHandler {
+	return &CronJobHandler{
+		manager:     manager,
+		serviceRepo: serviceRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Get handles GET /services/:id/cron-job
+func (h *CronJobHandler) Get(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+	if service.CronJob == nil {
+		respondError(c, errors.NotFound("cron job schedule", service.ID.String()))
+		return
+	}
+
+	c.JSON(http.StatusOK, service.CronJob)
+}
+
+// Update handles PUT /services/:id/cron-job
+func (h *CronJobHandler) Update(c *gin.Context) {
+	var req domain.CronJobConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	if err := cronjobs.Validate(&req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	service.CronJob = &req
+	h.save(c, service)
+}
+
+// Suspend handles POST /services/:id/cron-job/suspend. Runs in progress
+// finish; no new ones are scheduled.
+func (h *CronJobHandler) Suspend(c *gin.Context) {
+	h.setSuspend(c, true)
+}
+
+// Resume handles POST /services/:id/cron-job/resume
+func (h *CronJobHandler) Resume(c *gin.Context) {
+	h.setSuspend(c, false)
+}
+
+// RunNow handles POST /services/:id/cron-job/runs
+func (h *CronJobHandler) RunNow(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.manager.RunNow(c.Request.Context(), service)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, run)
+}
+
+// ListRuns handles GET /services/:id/cron-job/runs
+func (h *CronJobHandler) ListRuns(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	runs, err := h.manager.Runs(c.Request.Context(), service)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  runs,
+		"count": len(runs),
+	})
+}
+
+// RunLogs handles GET /services/:id/cron-job/runs/:run/logs
+// Query: tail, the number of lines to return
+func (h *CronJobHandler) RunLogs(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	run := c.Param("run")
+	logs, err := h.manager.Logs(c.Request.Context(), service, run, int64(parseIntQuery(c, "tail", 0)))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"run":  run,
+		"logs": logs,
+	})
+}
+
+func (h *CronJobHandler) setSuspend(c *gin.Context, suspend bool) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+	if service.CronJob == nil {
+		respondError(c, errors.BadRequest("the service has no schedule; set one first"))
+		return
+	}
+
+	service.CronJob.Suspend = suspend
+	h.save(c, service)
+}
+
+func (h *CronJobHandler) save(c *gin.Context, service *domain.Service) {
+	if err := h.serviceRepo.Update(c.Request.Context(), service); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Services that are not deployed yet get their CronJob on first deploy
+	if err := h.manager.Apply(c.Request.Context(), service); err != nil && !errors.IsNotFound(err) {
+		respondError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(c.Request.Context(), "service.updated", &domain.Event{
+		Type:   "service.updated",
+		Source: "api",
+		Data: map[string]interface{}{
+			"service_id": service.ID.String(),
+			"project_id": service.ProjectID.String(),
+		},
+	})
+
+	h.logger.Info().
+		Str("service_id", service.ID.String()).
+		Str("schedule", service.CronJob.Schedule).
+		Bool("suspended", service.CronJob.Suspend).
+		Msg("Cron job updated")
+
+	c.JSON(http.StatusOK, service.CronJob)
+}
+
+func (h *CronJobHandler) loadService(c *gin.Context) (*domain.Service, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return nil, false
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	if service.Type != domain.ServiceTypeCronJob {
+		respondError(c, errors.BadRequest("the service is not a cron job"))
+		return nil, false
+	}
+
+	return service, true
+}
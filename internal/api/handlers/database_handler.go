@@ -2,54 +2,113 @@ package handlers
 
 import (
 	"context"
+	stderrors "errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/dbupgrade"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/residency"
+	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
+	"github.com/northstack/platform/pkg/postgres"
 	"github.com/northstack/platform/pkg/yugabytedb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Database engines
+const (
+	EngineYugabyteDB = "yugabytedb"
+	EnginePostgres   = "postgres"
 )
 
 // DatabaseHandler handles database management endpoints
 type DatabaseHandler struct {
-	dbService *yugabytedb.DatabaseService
-	eventBus  domain.EventBus
-	logger    *logger.Logger
+	dbService   *yugabytedb.DatabaseService
+	postgres    *postgres.Service
+	projectRepo domain.ProjectRepository
+	envRepo     domain.EnvironmentRepository
+	residency   *residency.Checker
+	upgrader    *dbupgrade.Upgrader
+	eventBus    domain.EventBus
+	logger      *logger.Logger
 }
 
-// NewDatabaseHandler creates a new DatabaseHandler
-func NewDatabaseHandler(dbService *yugabytedb.DatabaseService, eventBus domain.EventBus, log *logger.Logger) *DatabaseHandler {
+// NewDatabaseHandler creates a new DatabaseHandler. Without a PostgreSQL
+// service, only YugabyteDB databases can be created. With a residency checker,
+// databases and their backups must stay in the project's residency region.
+// With an upgrader, databases being upgraded cannot be scaled. Databases are
+// cloned into the environments of envRepo.
+func NewDatabaseHandler(dbService *yugabytedb.DatabaseService, pgService *postgres.Service, projectRepo domain.ProjectRepository, envRepo domain.EnvironmentRepository, residency *residency.Checker, upgrader *dbupgrade.Upgrader, eventBus domain.EventBus, log *logger.Logger) *DatabaseHandler {
 	return &DatabaseHandler{
-		dbService: dbService,
-		eventBus:  eventBus,
-		logger:    log,
+		dbService:   dbService,
+		postgres:    pgService,
+		projectRepo: projectRepo,
+		envRepo:     envRepo,
+		residency:   residency,
+		upgrader:    upgrader,
+		eventBus:    eventBus,
+		logger:      log,
 	}
 }
 
 // CreateDatabaseRequest represents a database creation request
 type CreateDatabaseRequest struct {
 	Name             string `json:"name" binding:"required"`
+	Type             string `json:"type" binding:"omitempty,oneof=yugabytedb postgres"` // Defaults to yugabytedb
 	Size             string `json:"size" binding:"required,oneof=small medium large xlarge"`
 	StorageGB        int    `json:"storage_gb" binding:"required,min=10,max=1000"`
 	HighAvailability bool   `json:"high_availability"`
 	BackupEnabled    bool   `json:"backup_enabled"`
 	TLSEnabled       bool   `json:"tls_enabled"`
 	Version          string `json:"version"`
+	// Pooler deploys PgBouncer in front of the database; its endpoint becomes
+	// the default one
+	Pooler *PoolerRequest `json:"pooler"`
+}
+
+// PoolerRequest configures the connection pooler of a database
+type PoolerRequest struct {
+	Mode                 string `json:"mode" binding:"omitempty,oneof=session transaction statement"` // Defaults to transaction
+	PoolSize             int    `json:"pool_size" binding:"gte=0,max=500"`                            // Defaults to 20
+	MaxClientConnections int    `json:"max_client_connections" binding:"gte=0,max=10000"`             // Defaults to 1000
 }
 
-// CreateDatabase creates a new YugabyteDB cluster
+// CreateDatabase creates a new YugabyteDB or PostgreSQL cluster
 func (h *DatabaseHandler) CreateDatabase(c *gin.Context) {
 	projectID := c.Param("project_id")
 	if projectID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Project ID required"})
+		respondError(c, errors.BadRequest("Project ID required"))
 		return
 	}
 
 	var req CreateDatabaseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, bindError(err))
+		return
+	}
+
+	if req.Type == "" {
+		req.Type = EngineYugabyteDB
+	}
+	if req.Type == EnginePostgres {
+		if h.postgres == nil {
+			respondError(c, errors.BadRequest("PostgreSQL databases are not enabled"))
+			return
+		}
+		if req.BackupEnabled {
+			respondError(c, errors.BadRequest("backups are not supported for PostgreSQL databases"))
+			return
+		}
+	} else if req.BackupEnabled && !h.dbService.BackupsEnabled() {
+		respondError(c, errors.BadRequest("database backups are not enabled"))
+		return
+	}
+
+	if err := h.checkResidency(c.Request.Context(), projectID, req); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -59,6 +118,11 @@ func (h *DatabaseHandler) CreateDatabase(c *gin.Context) {
 		teamID = tid.(string)
 	}
 
+	if req.Type == EnginePostgres {
+		h.createPostgres(c, projectID, teamID, req)
+		return
+	}
+
 	input := &yugabytedb.CreateDatabaseInput{
 		Name:             req.Name,
 		ProjectID:        projectID,
@@ -70,11 +134,18 @@ func (h *DatabaseHandler) CreateDatabase(c *gin.Context) {
 		TLSEnabled:       req.TLSEnabled,
 		Version:          req.Version,
 	}
+	if req.Pooler != nil {
+		input.Pooler = &yugabytedb.PoolerInput{
+			Mode:                 req.Pooler.Mode,
+			PoolSize:             req.Pooler.PoolSize,
+			MaxClientConnections: req.Pooler.MaxClientConnections,
+		}
+	}
 
 	db, err := h.dbService.CreateDatabase(c.Request.Context(), input)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to create database")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create database"})
+		respondError(c, errors.Internal("Failed to create database"))
 		return
 	}
 
@@ -83,21 +154,73 @@ func (h *DatabaseHandler) CreateDatabase(c *gin.Context) {
 		"database_id": db.ID,
 		"project_id":  projectID,
 		"name":        req.Name,
+		"type":        req.Type,
 	})
 
 	c.JSON(http.StatusCreated, db)
 }
 
-// ListDatabases lists all databases for a project
+// createPostgres creates a PostgreSQL cluster; TLS is always on
+func (h *DatabaseHandler) createPostgres(c *gin.Context, projectID, teamID string, req CreateDatabaseRequest) {
+	input := &postgres.CreateDatabaseInput{
+		Name:             req.Name,
+		ProjectID:        projectID,
+		TeamID:           teamID,
+		Size:             req.Size,
+		StorageGB:        req.StorageGB,
+		HighAvailability: req.HighAvailability,
+		Version:          req.Version,
+	}
+	if req.Pooler != nil {
+		input.Pooler = &postgres.PoolerInput{
+			Mode:                 req.Pooler.Mode,
+			PoolSize:             req.Pooler.PoolSize,
+			MaxClientConnections: req.Pooler.MaxClientConnections,
+		}
+	}
+	db, err := h.postgres.CreateDatabase(c.Request.Context(), input)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Failed to create database")
+		respondError(c, errors.Internal("Failed to create database"))
+		return
+	}
+
+	h.publishEvent(c.Request.Context(), "database.created", map[string]interface{}{
+		"database_id": db.ID,
+		"project_id":  projectID,
+		"name":        req.Name,
+		"type":        req.Type,
+	})
+
+	c.JSON(http.StatusCreated, db)
+}
+
+// ListDatabases lists all databases for a project, of every engine
 func (h *DatabaseHandler) ListDatabases(c *gin.Context) {
 	projectID := c.Param("project_id")
 
-	databases, err := h.dbService.ListDatabases(c.Request.Context(), projectID)
+	yugabyte, err := h.dbService.ListDatabases(c.Request.Context(), projectID)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to list databases")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list databases"})
+		respondError(c, errors.Internal("Failed to list databases"))
 		return
 	}
+	databases := make([]interface{}, 0, len(yugabyte))
+	for _, db := range yugabyte {
+		databases = append(databases, db)
+	}
+
+	if h.postgres != nil {
+		pg, err := h.postgres.ListDatabases(c.Request.Context(), projectID)
+		if err != nil {
+			h.logger.Error().Err(err).Msg("Failed to list databases")
+			respondError(c, errors.Internal("Failed to list databases"))
+			return
+		}
+		for _, db := range pg {
+			databases = append(databases, db)
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"databases": databases,
@@ -109,13 +232,21 @@ func (h *DatabaseHandler) ListDatabases(c *gin.Context) {
 func (h *DatabaseHandler) GetDatabase(c *gin.Context) {
 	databaseID := c.Param("id")
 	if databaseID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Database ID required"})
+		respondError(c, errors.BadRequest("Database ID required"))
+		return
+	}
+
+	if pg, err := h.getPostgres(c.Request.Context(), databaseID); err != nil {
+		respondError(c, err)
+		return
+	} else if pg != nil {
+		c.JSON(http.StatusOK, pg)
 		return
 	}
 
 	db, err := h.dbService.GetDatabase(c.Request.Context(), databaseID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Database not found"})
+		respondError(c, errors.NotFound("database", databaseID))
 		return
 	}
 
@@ -126,13 +257,20 @@ func (h *DatabaseHandler) GetDatabase(c *gin.Context) {
 func (h *DatabaseHandler) DeleteDatabase(c *gin.Context) {
 	databaseID := c.Param("id")
 	if databaseID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Database ID required"})
+		respondError(c, errors.BadRequest("Database ID required"))
 		return
 	}
 
-	if err := h.dbService.DeleteDatabase(c.Request.Context(), databaseID); err != nil {
+	var err error
+	if h.postgres != nil {
+		err = h.postgres.DeleteDatabase(c.Request.Context(), databaseID)
+	}
+	if h.postgres == nil || apierrors.IsNotFound(err) {
+		err = h.dbService.DeleteDatabase(c.Request.Context(), databaseID)
+	}
+	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to delete database")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete database"})
+		respondError(c, errors.Internal("Failed to delete database"))
 		return
 	}
 
@@ -153,19 +291,31 @@ type ScaleDatabaseRequest struct {
 func (h *DatabaseHandler) ScaleDatabase(c *gin.Context) {
 	databaseID := c.Param("id")
 	if databaseID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Database ID required"})
+		respondError(c, errors.BadRequest("Database ID required"))
 		return
 	}
 
 	var req ScaleDatabaseRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, bindError(err))
+		return
+	}
+
+	if err := h.upgrader.CheckChange(c.Request.Context(), databaseID); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	if err := h.dbService.ScaleDatabase(c.Request.Context(), databaseID, req.Replicas); err != nil {
+	var err error
+	if h.postgres != nil {
+		err = h.postgres.ScaleDatabase(c.Request.Context(), databaseID, req.Replicas)
+	}
+	if h.postgres == nil || apierrors.IsNotFound(err) {
+		err = h.dbService.ScaleDatabase(c.Request.Context(), databaseID, req.Replicas)
+	}
+	if err != nil {
 		h.logger.Error().Err(err).Msg("Failed to scale database")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scale database"})
+		respondError(c, errors.Internal("Failed to scale database"))
 		return
 	}
 
@@ -185,26 +335,684 @@ func (h *DatabaseHandler) ScaleDatabase(c *gin.Context) {
 func (h *DatabaseHandler) GetConnectionInfo(c *gin.Context) {
 	databaseID := c.Param("id")
 	if databaseID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Database ID required"})
+		respondError(c, errors.BadRequest("Database ID required"))
+		return
+	}
+
+	if pg, err := h.getPostgres(c.Request.Context(), databaseID); err != nil {
+		respondError(c, err)
+		return
+	} else if pg != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"endpoint":           pg.Endpoint,
+			"direct_endpoint":    pg.DirectEndpoint,
+			"read_only_endpoint": pg.ReadOnlyEndpoint,
+			"pool_mode":          pg.PoolMode,
+			"port":               pg.Port,
+			"database":           pg.Database,
+			"username":           pg.Username,
+			"secret_name":        pg.SecretName,
+			"connection_string":  "View the uri key of the secret",
+		})
 		return
 	}
 
 	db, err := h.dbService.GetDatabase(c.Request.Context(), databaseID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Database not found"})
+		respondError(c, errors.NotFound("database", databaseID))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
+		"endpoint":          db.Endpoint,
 		"ysql_endpoint":     db.YSQLEndpoint,
 		"ycql_endpoint":     db.YCQLEndpoint,
 		"port":              db.Port,
+		"pool_mode":         db.PoolMode,
 		"database":          db.Database,
 		"secret_name":       db.SecretName,
-		"connection_string": "View secret for full connection string",
+		"connection_string": "View the uri key of the secret",
+	})
+}
+
+// GetMetrics handles GET /databases/:id/metrics: connections, query rate,
+// replication lag and disk usage
+func (h *DatabaseHandler) GetMetrics(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+
+	pg, err := h.getPostgres(ctx, databaseID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	var metrics interface{}
+	if pg != nil {
+		metrics, err = h.postgres.GetMetrics(ctx, databaseID)
+	} else {
+		metrics, err = h.dbService.GetMetrics(ctx, databaseID)
+	}
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to get database metrics"))
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// SlowQueriesRequest filters the statements of a database
+type SlowQueriesRequest struct {
+	MinMeanMs float64 `form:"min_mean_ms,default=100" binding:"gte=0"`
+	Limit     int     `form:"limit,default=50" binding:"gte=1,max=500"`
+}
+
+// SlowQueries handles GET /databases/:id/slow-queries, the statements with
+// the highest mean time from pg_stat_statements
+func (h *DatabaseHandler) SlowQueries(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+
+	var req SlowQueriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	pg, err := h.getPostgres(ctx, databaseID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	var queries interface{}
+	total := 0
+	if pg != nil {
+		var pgQueries []*postgres.SlowQuery
+		pgQueries, err = h.postgres.SlowQueries(ctx, databaseID, req.MinMeanMs, req.Limit)
+		queries, total = pgQueries, len(pgQueries)
+	} else {
+		var ybQueries []*yugabytedb.SlowQuery
+		ybQueries, err = h.dbService.SlowQueries(ctx, databaseID, req.MinMeanMs, req.Limit)
+		queries, total = ybQueries, len(ybQueries)
+	}
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to get slow queries"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"queries":     queries,
+		"total":       total,
+		"min_mean_ms": req.MinMeanMs,
+	})
+}
+
+// ListBackups handles GET /databases/:id/backups
+func (h *DatabaseHandler) ListBackups(c *gin.Context) {
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(c.Request.Context(), databaseID, "backups"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	backups, err := h.dbService.ListBackups(c.Request.Context(), databaseID)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to list backups"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backups": backups,
+		"total":   len(backups),
+	})
+}
+
+// CreateBackup handles POST /databases/:id/backups, starting an on-demand
+// full backup
+func (h *DatabaseHandler) CreateBackup(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "backups"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	db, err := h.dbService.GetDatabase(ctx, databaseID)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to get database"))
+		return
+	}
+	if err := h.checkResidency(ctx, db.ProjectID, CreateDatabaseRequest{Name: db.Database, BackupEnabled: true}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	backup, err := h.dbService.CreateBackup(ctx, databaseID)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to create backup"))
+		return
+	}
+
+	h.publishEvent(ctx, "database.backup.created", map[string]interface{}{
+		"database_id": databaseID,
+		"backup_id":   backup.ID,
+	})
+
+	c.JSON(http.StatusAccepted, backup)
+}
+
+// RestoreDatabaseRequest restores a backup into a new database. Without a
+// backup ID, the latest backup completed at or before restore_time is
+// restored, or the latest one.
+type RestoreDatabaseRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	BackupID    string     `json:"backup_id"`
+	RestoreTime *time.Time `json:"restore_time"`
+	Size        string     `json:"size" binding:"required,oneof=small medium large xlarge"`
+	StorageGB   int        `json:"storage_gb" binding:"required,min=10,max=1000"`
+}
+
+// RestoreDatabase handles POST /databases/:id/restore. The source database is
+// left untouched.
+func (h *DatabaseHandler) RestoreDatabase(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "backups"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req RestoreDatabaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	source, err := h.dbService.GetDatabase(ctx, databaseID)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to get database"))
+		return
+	}
+	if err := h.checkResidency(ctx, source.ProjectID, CreateDatabaseRequest{Name: req.Name}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	teamID := ""
+	if tid, exists := c.Get("team_id"); exists {
+		teamID = tid.(string)
+	}
+
+	db, backup, err := h.dbService.RestoreDatabase(ctx, &yugabytedb.RestoreDatabaseInput{
+		SourceID:    databaseID,
+		BackupID:    req.BackupID,
+		RestoreTime: req.RestoreTime,
+		Name:        req.Name,
+		ProjectID:   source.ProjectID,
+		TeamID:      teamID,
+		Size:        req.Size,
+		StorageGB:   req.StorageGB,
+	})
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to restore database"))
+		return
+	}
+
+	h.publishEvent(ctx, "database.restored", map[string]interface{}{
+		"database_id": db.ID,
+		"source_id":   databaseID,
+		"backup_id":   backup.ID,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"database": db,
+		"backup":   backup,
 	})
 }
 
+// CloneDatabaseRequest copies a database into an environment
+type CloneDatabaseRequest struct {
+	Name          string    `json:"name" binding:"required"`
+	EnvironmentID uuid.UUID `json:"environment_id" binding:"required"`
+	Size          string    `json:"size" binding:"required,oneof=small medium large xlarge"`
+	StorageGB     int       `json:"storage_gb" binding:"required,min=10,max=1000"`
+}
+
+// CloneDatabase handles POST /databases/:id/clone. The clone is a new
+// database of the source's project that belongs to a preview environment and
+// is deleted with it. PostgreSQL clones copy the source's data files from its
+// primary; YugabyteDB clones restore its latest backup. The source is only
+// read from.
+func (h *DatabaseHandler) CloneDatabase(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+
+	var req CloneDatabaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	env, err := h.envRepo.GetByID(ctx, req.EnvironmentID)
+	if errors.IsNotFound(err) {
+		respondError(c, errors.BadRequest("environment not found"))
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if env.Type != domain.EnvironmentTypePreview {
+		respondError(c, errors.BadRequest("databases can only be cloned into preview environments"))
+		return
+	}
+
+	teamID := ""
+	if tid, exists := c.Get("team_id"); exists {
+		teamID = tid.(string)
+	}
+
+	pg, err := h.getPostgres(ctx, databaseID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	projectID := ""
+	if pg != nil {
+		projectID = pg.ProjectID
+	} else {
+		source, err := h.dbService.GetDatabase(ctx, databaseID)
+		if err != nil {
+			respondError(c, h.yugabyteError(err, databaseID, "Failed to get database"))
+			return
+		}
+		projectID = source.ProjectID
+	}
+	if env.ProjectID.String() != projectID {
+		respondError(c, errors.BadRequest("the environment belongs to another project than the database"))
+		return
+	}
+	if err := h.checkResidency(ctx, projectID, CreateDatabaseRequest{Name: req.Name}); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var clone interface{}
+	var cloneID string
+	if pg != nil {
+		db, err := h.postgres.CloneDatabase(ctx, &postgres.CloneDatabaseInput{
+			SourceID:      databaseID,
+			Name:          req.Name,
+			TeamID:        teamID,
+			EnvironmentID: env.ID.String(),
+			Size:          req.Size,
+			StorageGB:     req.StorageGB,
+		})
+		if err != nil {
+			respondError(c, h.cloneError(err, databaseID))
+			return
+		}
+		clone, cloneID = db, db.ID
+	} else {
+		db, _, err := h.dbService.RestoreDatabase(ctx, &yugabytedb.RestoreDatabaseInput{
+			SourceID:      databaseID,
+			Name:          req.Name,
+			ProjectID:     projectID,
+			TeamID:        teamID,
+			Size:          req.Size,
+			StorageGB:     req.StorageGB,
+			EnvironmentID: env.ID.String(),
+		})
+		if err != nil {
+			respondError(c, h.cloneError(err, databaseID))
+			return
+		}
+		clone, cloneID = db, db.ID
+	}
+
+	h.publishEvent(ctx, "database.cloned", map[string]interface{}{
+		"database_id":    cloneID,
+		"source_id":      databaseID,
+		"environment_id": env.ID.String(),
+	})
+
+	c.JSON(http.StatusCreated, clone)
+}
+
+// cloneError maps the errors of cloning a database
+func (h *DatabaseHandler) cloneError(err error, databaseID string) error {
+	if apierrors.IsAlreadyExists(err) {
+		return errors.Conflict("database")
+	}
+	return h.yugabyteError(err, databaseID, "Failed to clone database")
+}
+
+// CreateLogicalDatabaseRequest adds a database to a cluster
+type CreateLogicalDatabaseRequest struct {
+	Name string `json:"name" binding:"required,max=63"`
+}
+
+// ListLogicalDatabases handles GET /databases/:id/logical-databases
+func (h *DatabaseHandler) ListLogicalDatabases(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "logical databases"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	databases, err := h.dbService.ListLogicalDatabases(ctx, databaseID)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to list logical databases"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"databases": databases,
+		"total":     len(databases),
+	})
+}
+
+// CreateLogicalDatabase handles POST /databases/:id/logical-databases. The
+// database is owned by the owner of the cluster; other roles need a grant.
+func (h *DatabaseHandler) CreateLogicalDatabase(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "logical databases"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req CreateLogicalDatabaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	db, err := h.dbService.CreateLogicalDatabase(ctx, databaseID, req.Name)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to create logical database"))
+		return
+	}
+
+	h.publishEvent(ctx, "database.logical_database.created", map[string]interface{}{
+		"database_id": databaseID,
+		"name":        db.Name,
+	})
+
+	c.JSON(http.StatusCreated, db)
+}
+
+// DeleteLogicalDatabase handles DELETE /databases/:id/logical-databases/:name
+func (h *DatabaseHandler) DeleteLogicalDatabase(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "logical databases"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	name := c.Param("name")
+	if err := h.dbService.DeleteLogicalDatabase(ctx, databaseID, name); err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to delete logical database"))
+		return
+	}
+
+	h.publishEvent(ctx, "database.logical_database.deleted", map[string]interface{}{
+		"database_id": databaseID,
+		"name":        name,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logical database deleted"})
+}
+
+// CreateRoleRequest creates a role with access to logical databases
+type CreateRoleRequest struct {
+	Name   string         `json:"name" binding:"required,max=63"`
+	Grants []GrantRequest `json:"grants" binding:"required,min=1,dive"`
+}
+
+// GrantRequest is the access of a role to a logical database
+type GrantRequest struct {
+	Database string `json:"database" binding:"required"`
+	Access   string `json:"access" binding:"omitempty,oneof=read_only read_write owner"` // Defaults to read_write
+}
+
+// ListRoles handles GET /databases/:id/roles
+func (h *DatabaseHandler) ListRoles(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "roles"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	roles, err := h.dbService.ListRoles(ctx, databaseID)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to list roles"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"roles": roles,
+		"total": len(roles),
+	})
+}
+
+// CreateRole handles POST /databases/:id/roles. The password is only in the
+// response and the role's secret.
+func (h *DatabaseHandler) CreateRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "roles"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	input := &yugabytedb.CreateRoleInput{Name: req.Name}
+	for _, grant := range req.Grants {
+		input.Grants = append(input.Grants, &yugabytedb.Grant{Database: grant.Database, Access: grant.Access})
+	}
+
+	creds, err := h.dbService.CreateRole(ctx, databaseID, input)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to create role"))
+		return
+	}
+
+	h.publishEvent(ctx, "database.role.created", map[string]interface{}{
+		"database_id": databaseID,
+		"role":        creds.Name,
+		"grants":      creds.Grants,
+	})
+
+	c.JSON(http.StatusCreated, creds)
+}
+
+// ResetRolePassword handles POST /databases/:id/roles/:role/reset-password
+func (h *DatabaseHandler) ResetRolePassword(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "roles"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	creds, err := h.dbService.ResetRolePassword(ctx, databaseID, c.Param("role"))
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to reset role password"))
+		return
+	}
+
+	h.publishEvent(ctx, "database.role.password_reset", map[string]interface{}{
+		"database_id": databaseID,
+		"role":        creds.Name,
+	})
+
+	c.JSON(http.StatusOK, creds)
+}
+
+// DeleteRole handles DELETE /databases/:id/roles/:role. The objects the role
+// owns are dropped with it.
+func (h *DatabaseHandler) DeleteRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "roles"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	role := c.Param("role")
+	if err := h.dbService.DeleteRole(ctx, databaseID, role); err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to delete role"))
+		return
+	}
+
+	h.publishEvent(ctx, "database.role.deleted", map[string]interface{}{
+		"database_id": databaseID,
+		"role":        role,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
+
+// UpgradeDatabaseRequest upgrades a database to a newer version of its
+// release series
+type UpgradeDatabaseRequest struct {
+	Version string `json:"version" binding:"required"`
+	// MaintenanceWindow delays the upgrade until the window next opens;
+	// without one it starts right away
+	MaintenanceWindow *dbupgrade.Window `json:"maintenance_window"`
+}
+
+// DatabaseUpgradeResponse lists a database's upgrade options and latest
+// upgrade
+type DatabaseUpgradeResponse struct {
+	CurrentVersion    string              `json:"current_version"`
+	AvailableVersions []string            `json:"available_versions"`
+	Upgrade           *dbupgrade.Progress `json:"upgrade,omitempty"`
+}
+
+// UpgradeStatus handles GET /databases/:id/upgrade
+func (h *DatabaseHandler) UpgradeStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "upgrades"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	current, versions, err := h.upgrader.Versions(ctx, databaseID)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to list database versions"))
+		return
+	}
+	progress, err := h.upgrader.Progress(ctx, databaseID)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to get database upgrade"))
+		return
+	}
+
+	c.JSON(http.StatusOK, DatabaseUpgradeResponse{
+		CurrentVersion:    current,
+		AvailableVersions: versions,
+		Upgrade:           progress,
+	})
+}
+
+// UpgradeDatabase handles POST /databases/:id/upgrade. A full backup is taken
+// before the masters and then the tablet servers are restarted one at a time
+// on the new version.
+func (h *DatabaseHandler) UpgradeDatabase(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "upgrades"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	var req UpgradeDatabaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	progress, err := h.upgrader.Start(ctx, databaseID, req.Version, req.MaintenanceWindow)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to start database upgrade"))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, progress)
+}
+
+// CancelUpgrade handles DELETE /databases/:id/upgrade. Only upgrades waiting
+// for their maintenance window can be cancelled.
+func (h *DatabaseHandler) CancelUpgrade(c *gin.Context) {
+	ctx := c.Request.Context()
+	databaseID := c.Param("id")
+	if err := h.requireYugabyte(ctx, databaseID, "upgrades"); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	progress, err := h.upgrader.Cancel(ctx, databaseID)
+	if err != nil {
+		respondError(c, h.yugabyteError(err, databaseID, "Failed to cancel database upgrade"))
+		return
+	}
+
+	c.JSON(http.StatusOK, progress)
+}
+
+// requireYugabyte rejects requests for a feature of YugabyteDB databases made
+// for PostgreSQL ones
+func (h *DatabaseHandler) requireYugabyte(ctx context.Context, databaseID, feature string) error {
+	pg, err := h.getPostgres(ctx, databaseID)
+	if err != nil {
+		return err
+	}
+	if pg != nil {
+		return errors.BadRequest(feature + " are only available for YugabyteDB databases")
+	}
+	return nil
+}
+
+// yugabyteError passes on the errors of the database services meant for
+// clients, and hides the others
+func (h *DatabaseHandler) yugabyteError(err error, databaseID, message string) error {
+	var appErr *errors.AppError
+	switch {
+	case stderrors.As(err, &appErr):
+		return appErr
+	case apierrors.IsNotFound(err):
+		return errors.NotFound("database", databaseID)
+	}
+	h.logger.Error().Err(err).Str("database_id", databaseID).Msg(message)
+	return errors.Internal(message)
+}
+
+// getPostgres returns the PostgreSQL cluster with an ID, or nil when there is
+// none and the database may be a YugabyteDB cluster
+func (h *DatabaseHandler) getPostgres(ctx context.Context, databaseID string) (*postgres.DatabaseInfo, error) {
+	if h.postgres == nil {
+		return nil, nil
+	}
+	db, err := h.postgres.GetDatabase(ctx, databaseID)
+	switch {
+	case apierrors.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		h.logger.Error().Err(err).Str("database_id", databaseID).Msg("Failed to get database")
+		return nil, errors.Internal("Failed to get database")
+	}
+	return db, nil
+}
+
 func (h *DatabaseHandler) publishEvent(ctx context.Context, eventType string, data map[string]interface{}) {
 	event := &domain.Event{
 		ID:        uuid.New().String(),
@@ -216,3 +1024,26 @@ func (h *DatabaseHandler) publishEvent(ctx context.Context, eventType string, da
 		h.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
 	}
 }
+
+// checkResidency rejects a database, or its backups, outside the project's
+// data residency
+func (h *DatabaseHandler) checkResidency(ctx context.Context, projectID string, req CreateDatabaseRequest) error {
+	if h.residency == nil {
+		return nil
+	}
+	id, err := uuid.Parse(projectID)
+	if err != nil {
+		return errors.BadRequest("invalid project ID")
+	}
+	project, err := h.projectRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := h.residency.CheckDatabase(project, req.Name); err != nil {
+		return err
+	}
+	if req.BackupEnabled {
+		return h.residency.CheckBackups(project, req.Name)
+	}
+	return nil
+}
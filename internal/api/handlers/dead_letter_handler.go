@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// DeadLetterHandler lets platform operators inspect, re-drive and discard
+// events whose handlers failed on every attempt
+type DeadLetterHandler struct {
+	dlq         domain.DeadLetterQueue
+	auditLogger *audit.Logger
+	logger      *logger.Logger
+}
+
+// NewDeadLetterHandler creates a new DeadLetterHandler. dlq may be nil, in
+// which case its endpoints answer 501; auditLogger may be nil.
+func NewDeadLetterHandler(dlq domain.DeadLetterQueue, auditLogger *audit.Logger, log *logger.Logger) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		dlq:         dlq,
+		auditLogger: auditLogger,
+		logger:      log,
+	}
+}
+
+// List handles GET /admin/dead-letters?after=&limit=
+func (h *DeadLetterHandler) List(c *gin.Context) {
+	if h.dlq == nil {
+		NotImplemented(c, "the dead-letter queue requires JetStream")
+		return
+	}
+
+	var after uint64
+	if raw := c.Query("after"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			respondError(c, errors.InvalidInput("after must be a sequence number"))
+			return
+		}
+		after = parsed
+	}
+	limit := parseIntQuery(c, "limit", 50)
+
+	letters, err := h.dlq.ListDeadLetters(c.Request.Context(), after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	resp := gin.H{
+		"data":  letters,
+		"count": len(letters),
+	}
+	if len(letters) > 0 {
+		resp["next_after"] = letters[len(letters)-1].Sequence
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Get handles GET /admin/dead-letters/:seq
+func (h *DeadLetterHandler) Get(c *gin.Context) {
+	seq, ok := h.sequence(c)
+	if !ok {
+		return
+	}
+
+	letter, err := h.dlq.GetDeadLetter(c.Request.Context(), seq)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, letter)
+}
+
+// Redrive handles POST /admin/dead-letters/:seq/redrive, publishing the event
+// on its original subject again
+func (h *DeadLetterHandler) Redrive(c *gin.Context) {
+	seq, ok := h.sequence(c)
+	if !ok {
+		return
+	}
+
+	operatorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.dlq.Redrive(c.Request.Context(), seq); err != nil {
+		respondError(c, err)
+		return
+	}
+	h.audit(c, operatorID, domain.AuditActionUpdate, "redrive", seq)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Dead letter re-driven"})
+}
+
+// Discard handles DELETE /admin/dead-letters/:seq
+func (h *DeadLetterHandler) Discard(c *gin.Context) {
+	seq, ok := h.sequence(c)
+	if !ok {
+		return
+	}
+
+	operatorID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.dlq.DiscardDeadLetter(c.Request.Context(), seq); err != nil {
+		respondError(c, err)
+		return
+	}
+	h.audit(c, operatorID, domain.AuditActionDelete, "discard", seq)
+	c.Status(http.StatusNoContent)
+}
+
+// sequence parses the :seq parameter, answering the request itself when the
+// queue is not configured or the sequence is invalid
+func (h *DeadLetterHandler) sequence(c *gin.Context) (uint64, bool) {
+	if h.dlq == nil {
+		NotImplemented(c, "the dead-letter queue requires JetStream")
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(c.Param("seq"), 10, 64)
+	if err != nil || seq == 0 {
+		respondError(c, errors.InvalidInput("invalid dead letter sequence"))
+		return 0, false
+	}
+	return seq, true
+}
+
+// audit records an operator action on a dead letter
+func (h *DeadLetterHandler) audit(c *gin.Context, operatorID uuid.UUID, action domain.AuditAction, operation string, seq uint64) {
+	h.logger.Info().
+		Str("operation", operation).
+		Int64("sequence", int64(seq)).
+		Str("operator_id", operatorID.String()).
+		Msg("Dead letter handled by operator")
+
+	if h.auditLogger == nil {
+		return
+	}
+	_ = h.auditLogger.Log(c.Request.Context(), audit.LogOptions{
+		UserID:       operatorID,
+		Action:       action,
+		ResourceType: "dead_letter",
+		ResourceName: strconv.FormatUint(seq, 10),
+		IPAddress:    c.ClientIP(),
+		UserAgent:    c.Request.UserAgent(),
+		Metadata:     map[string]interface{}{"operation": operation},
+	})
+}
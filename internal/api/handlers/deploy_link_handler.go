@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/deploylinks"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// deployButton is the "Deploy to NFOSS" badge READMEs link to a deploy link with
+const deployButton = `<svg xmlns="http://www.w3.org/2000/svg" width="148" height="32" role="img" aria-label="Deploy to NFOSS">` +
+	`<title>Deploy to NFOSS</title>` +
+	`<rect width="148" height="32" rx="6" fill="#111827"/>` +
+	`<path d="M14 22 L22 10 L30 22 Z" fill="#6366f1"/>` +
+	`<text x="40" y="21" fill="#ffffff" font-family="Verdana,DejaVu Sans,sans-serif" font-size="13">Deploy to NFOSS</text>` +
+	`</svg>`
+
+// DeployLinkHandler serves one-click deploy links
+type DeployLinkHandler struct {
+	linker *deploylinks.Linker
+	logger *logger.Logger
+}
+
+// NewDeployLinkHandler creates a new DeployLinkHandler
+func NewDeployLinkHandler(linker *deploylinks.Linker, log *logger.Logger) *DeployLinkHandler {
+	return &DeployLinkHandler{
+		linker: linker,
+		logger: log,
+	}
+}
+
+// DeployLinkRequest is the body of deploying a link
+type DeployLinkRequest struct {
+	Repository  string            `json:"repository" binding:"required"`
+	Branch      string            `json:"branch"`
+	Template    string            `json:"template"` // Catalog template; the repository's northstack.yaml if empty
+	Name        string            `json:"name" binding:"required,max=255"`
+	Slug        string            `json:"slug" binding:"required,max=63"`
+	Description string            `json:"description"`
+	TeamID      *uuid.UUID        `json:"team_id"`
+	Variables   map[string]string `json:"variables"`
+	GitToken    string            `json:"git_token"` // Reads private repositories and registers the webhook; not stored
+	DryRun      bool              `json:"dry_run"`
+}
+
+// Plan handles GET /deploy?repository=&branch=&template=, the target of
+// deploy buttons. Browsers are sent on to the console, which signs the user in
+// and walks them through the deploy; other clients get what the link deploys.
+func (h *DeployLinkHandler) Plan(c *gin.Context) {
+	var link deploylinks.Link
+	if err := c.ShouldBindQuery(&link); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		if consoleURL := h.linker.ConsoleURL(link); consoleURL != "" {
+			c.Redirect(http.StatusFound, consoleURL)
+			return
+		}
+	}
+
+	plan, err := h.linker.Plan(c.Request.Context(), link, "")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// Button handles GET /deploy/button.svg
+func (h *DeployLinkHandler) Button(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/svg+xml", []byte(deployButton))
+}
+
+// Deploy handles POST /deploy. It creates the project a link describes and
+// registers the repository's webhook, answering 201 unless it is a dry run.
+func (h *DeployLinkHandler) Deploy(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req DeployLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	link := deploylinks.Link{Repository: req.Repository, Branch: req.Branch, Template: req.Template}
+	result, err := h.linker.Deploy(c.Request.Context(), link, deploylinks.DeployRequest{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		TeamID:      req.TeamID,
+		OwnerID:     userID,
+		Variables:   req.Variables,
+		GitToken:    req.GitToken,
+		DryRun:      req.DryRun,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if result.DryRun {
+		status = http.StatusOK
+	} else {
+		h.logger.Info().Str("repository", req.Repository).Str("webhook", result.Webhook.Status).Msg("Project deployed from link")
+	}
+	c.JSON(status, result)
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/dora"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// DeploymentHandler handles deployment history endpoints
+type DeploymentHandler struct {
+	deployRepo  domain.DeploymentRepository
+	serviceRepo domain.ServiceRepository
+	envRepo     domain.EnvironmentRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewDeploymentHandler creates a new DeploymentHandler. envRepo may be nil, in
+// which case external deployments record the environment by name only.
+func NewDeploymentHandler(deployRepo domain.DeploymentRepository, serviceRepo domain.ServiceRepository, envRepo domain.EnvironmentRepository, eventBus domain.EventBus, log *logger.Logger) *DeploymentHandler {
+	return &DeploymentHandler{
+		deployRepo:  deployRepo,
+		serviceRepo: serviceRepo,
+		envRepo:     envRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// externalLookback bounds the history searched for a previously registered
+// external deployment and for the previous version
+const externalLookback = 100
+
+// ExternalDeploymentRequest registers a deployment performed by an external CD tool
+type ExternalDeploymentRequest struct {
+	ServiceID       uuid.UUID  `json:"service_id" binding:"required"`
+	Version         string     `json:"version" binding:"required,max=255"`
+	Environment     string     `json:"environment,omitempty"` // Environment ID or slug within the service's project
+	Status          string     `json:"status,omitempty" binding:"omitempty,oneof=succeeded failed rolled_back"`
+	Strategy        string     `json:"strategy,omitempty" binding:"omitempty,oneof=rolling_update blue_green canary recreate"`
+	Source          string     `json:"source" binding:"required,max=100"` // e.g. "argocd", "spinnaker", "jenkins"
+	ExternalID      string     `json:"external_id,omitempty"`             // Makes registration idempotent per source
+	URL             string     `json:"url,omitempty" binding:"omitempty,url"`
+	CommitSHA       string     `json:"commit_sha,omitempty"`
+	CommitTimestamp *time.Time `json:"commit_timestamp,omitempty"`
+	TriggeredBy     string     `json:"triggered_by,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	Timestamp       *time.Time `json:"timestamp,omitempty"` // When the deployment finished; defaults to now
+}
+
+// RegisterExternal handles POST /deployments/external. Re-registering the same
+// source and external_id returns the existing record.
+func (h *DeploymentHandler) RegisterExternal(c *gin.Context) {
+	var req ExternalDeploymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	service, err := h.serviceRepo.GetByID(ctx, req.ServiceID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	history, err := h.deployRepo.ListByService(ctx, service.ID, externalLookback)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if existing := findExternal(history, req.Source, req.ExternalID); existing != nil {
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+
+	var env *domain.Environment
+	if req.Environment != "" {
+		if env, err = h.environment(c, service.ProjectID, req.Environment); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	triggeredBy := req.Source
+	if userID, exists := c.Get("user_id"); exists {
+		triggeredBy = fmt.Sprint(userID)
+	}
+	deployment, err := externalDeployment(&req, service, env, history, triggeredBy, time.Now())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.deployRepo.Create(ctx, deployment); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.publishEvent(c, deployment)
+
+	h.logger.Info().
+		Str("deployment_id", deployment.ID.String()).
+		Str("service_id", service.ID.String()).
+		Str("source", req.Source).
+		Str("version", req.Version).
+		Msg("External deployment registered")
+
+	c.JSON(http.StatusCreated, deployment)
+}
+
+// Get handles GET /deployments/:id
+func (h *DeploymentHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid deployment ID"))
+		return
+	}
+
+	deployment, err := h.deployRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deployment)
+}
+
+// ListByService handles GET /services/:id/deployments
+func (h *DeploymentHandler) ListByService(c *gin.Context) {
+	id, ok := h.service(c)
+	if !ok {
+		return
+	}
+
+	limit, ok := deploymentLimit(c)
+	if !ok {
+		return
+	}
+
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	deployments, err := h.deployRepo.ListByServiceAfter(c.Request.Context(), id, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        deployments,
+		"count":       len(deployments),
+		"limit":       limit,
+		"next_cursor": nextCursor(deployments, limit, deploymentCursor),
+	})
+}
+
+// LatestByService handles GET /services/:id/deployments/latest
+func (h *DeploymentHandler) LatestByService(c *gin.Context) {
+	id, ok := h.service(c)
+	if !ok {
+		return
+	}
+
+	deployment, err := h.deployRepo.GetLatestByService(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deployment)
+}
+
+// ListByCluster handles GET /clusters/:id/deployments
+func (h *DeploymentHandler) ListByCluster(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid cluster ID"))
+		return
+	}
+
+	limit, ok := deploymentLimit(c)
+	if !ok {
+		return
+	}
+
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	deployments, err := h.deployRepo.ListByClusterAfter(c.Request.Context(), id, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        deployments,
+		"count":       len(deployments),
+		"limit":       limit,
+		"next_cursor": nextCursor(deployments, limit, deploymentCursor),
+	})
+}
+
+// LatestByCluster handles GET /clusters/:id/deployments/latest
+func (h *DeploymentHandler) LatestByCluster(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid cluster ID"))
+		return
+	}
+
+	deployments, err := h.deployRepo.ListByCluster(c.Request.Context(), id, 1)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if len(deployments) == 0 {
+		respondError(c, errors.NotFound("deployment for cluster", id.String()))
+		return
+	}
+
+	c.JSON(http.StatusOK, deployments[0])
+}
+
+// environment resolves an environment ID or slug; unknown environments are
+// recorded by name only
+func (h *DeploymentHandler) environment(c *gin.Context, projectID uuid.UUID, ref string) (*domain.Environment, error) {
+	if h.envRepo == nil {
+		return nil, nil
+	}
+
+	var env *domain.Environment
+	var err error
+	if id, perr := uuid.Parse(ref); perr == nil {
+		env, err = h.envRepo.GetByID(c.Request.Context(), id)
+	} else {
+		env, err = h.envRepo.GetBySlug(c.Request.Context(), projectID, ref)
+	}
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if env.ProjectID != projectID {
+		return nil, errors.BadRequest("environment does not belong to the service's project")
+	}
+	return env, nil
+}
+
+// publishEvent announces finished external deployments so release health
+// scoring and anomaly detection cover them too
+func (h *DeploymentHandler) publishEvent(c *gin.Context, deployment *domain.Deployment) {
+	if h.eventBus == nil {
+		return
+	}
+
+	eventType := "deploy.completed"
+	if deployment.Status != domain.DeploymentStatusSucceeded {
+		eventType = "deploy.failed"
+	}
+
+	event := &domain.Event{
+		Type:   eventType,
+		Source: "external",
+		Data: map[string]interface{}{
+			"deployment_id": deployment.ID.String(),
+			"service_id":    deployment.ServiceID.String(),
+			"project_id":    deployment.ProjectID.String(),
+			"version":       deployment.Version,
+			"source":        deployment.Metadata["source"],
+		},
+		Timestamp: deployment.CompletedAt.UnixNano(),
+	}
+	if deployment.ErrorMessage != "" {
+		event.Data["error"] = deployment.ErrorMessage
+	}
+
+	if err := h.eventBus.Publish(c.Request.Context(), eventType, event); err != nil {
+		h.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+func (h *DeploymentHandler) service(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return uuid.Nil, false
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return uuid.Nil, false
+	}
+
+	return id, true
+}
+
+func deploymentLimit(c *gin.Context) (int, bool) {
+	limit := parseIntQuery(c, "limit", 20)
+	if limit < 1 || limit > 100 {
+		respondError(c, errors.BadRequest("limit must be between 1 and 100"))
+		return 0, false
+	}
+	return limit, true
+}
+
+// findExternal returns the deployment already registered for an external ID
+func findExternal(history []*domain.Deployment, source, externalID string) *domain.Deployment {
+	if externalID == "" {
+		return nil
+	}
+	for _, d := range history {
+		if d.Metadata["source"] == source && d.Metadata["external_id"] == externalID {
+			return d
+		}
+	}
+	return nil
+}
+
+// externalDeployment builds the record for an externally performed deployment.
+// CreatedAt is backdated to when the deployment ran so it sorts correctly in
+// the service's history.
+func externalDeployment(req *ExternalDeploymentRequest, service *domain.Service, env *domain.Environment, history []*domain.Deployment, triggeredBy string, now time.Time) (*domain.Deployment, error) {
+	completedAt := now
+	if req.Timestamp != nil {
+		completedAt = req.Timestamp.UTC()
+	}
+	if completedAt.After(now.Add(time.Minute)) {
+		return nil, errors.BadRequest("timestamp must not be in the future")
+	}
+	startedAt := completedAt
+	if req.StartedAt != nil {
+		startedAt = req.StartedAt.UTC()
+	}
+	if startedAt.After(completedAt) {
+		return nil, errors.BadRequest("started_at must not be after timestamp")
+	}
+
+	deployment := &domain.Deployment{
+		ID:          uuid.New(),
+		ServiceID:   service.ID,
+		ProjectID:   service.ProjectID,
+		Status:      domain.DeploymentStatusSucceeded,
+		Strategy:    domain.DeploymentStrategyRollingUpdate,
+		Version:     req.Version,
+		Replicas:    service.Scaling.MinReplicas,
+		TriggeredBy: req.TriggeredBy,
+		Metadata: map[string]interface{}{
+			"source": req.Source,
+		},
+		StartedAt:   &startedAt,
+		CompletedAt: &completedAt,
+		CreatedAt:   startedAt,
+	}
+	if req.Status != "" {
+		deployment.Status = domain.DeploymentStatus(req.Status)
+	}
+	if deployment.Status == domain.DeploymentStatusSucceeded {
+		deployment.ReadyReplicas = deployment.Replicas
+	} else {
+		deployment.ErrorMessage = req.Error
+	}
+	if req.Strategy != "" {
+		deployment.Strategy = domain.DeploymentStrategy(req.Strategy)
+	}
+	if deployment.TriggeredBy == "" {
+		deployment.TriggeredBy = triggeredBy
+	}
+
+	if env != nil {
+		deployment.ClusterID = env.ClusterID
+		deployment.Metadata["environment_id"] = env.ID.String()
+		deployment.Metadata["environment"] = env.Slug
+	} else if req.Environment != "" {
+		deployment.Metadata["environment"] = req.Environment
+	}
+
+	optional := map[string]string{
+		"external_id": req.ExternalID,
+		"url":         req.URL,
+		"commit_sha":  req.CommitSHA,
+	}
+	for key, value := range optional {
+		if value != "" {
+			deployment.Metadata[key] = value
+		}
+	}
+	if req.CommitTimestamp != nil {
+		deployment.Metadata[dora.CommitTimestampKey] = req.CommitTimestamp.UTC().Format(time.RFC3339)
+	}
+
+	// History is newest first
+	for _, d := range history {
+		if d.Status == domain.DeploymentStatusSucceeded && d.CreatedAt.Before(deployment.CreatedAt) {
+			deployment.PreviousVersion = d.Version
+			break
+		}
+	}
+
+	return deployment, nil
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/openapi"
+	"github.com/northstack/platform/pkg/logger"
+	"sigs.k8s.io/yaml"
+)
+
+// swaggerUI loads Swagger UI from a CDN and points it at the JSON document
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>NorthStack API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/api/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// DocsHandler serves the OpenAPI document of the running server
+type DocsHandler struct {
+	builder *openapi.Builder
+	routes  func() gin.RoutesInfo
+	logger  *logger.Logger
+
+	once sync.Once
+	doc  *openapi.Document
+}
+
+// NewDocsHandler creates a new DocsHandler. routes returns the engine's
+// routes; they are read on the first request, once every route is registered.
+func NewDocsHandler(builder *openapi.Builder, routes func() gin.RoutesInfo, log *logger.Logger) *DocsHandler {
+	return &DocsHandler{
+		builder: builder,
+		routes:  routes,
+		logger:  log,
+	}
+}
+
+func (h *DocsHandler) document() *openapi.Document {
+	h.once.Do(func() {
+		h.doc = h.builder.Build(h.routes())
+	})
+	return h.doc
+}
+
+// JSON handles GET /api/openapi.json
+func (h *DocsHandler) JSON(c *gin.Context) {
+	c.JSON(http.StatusOK, h.document())
+}
+
+// YAML handles GET /api/openapi.yaml
+func (h *DocsHandler) YAML(c *gin.Context) {
+	out, err := yaml.Marshal(h.document())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml", out)
+}
+
+// UI handles GET /api/docs
+func (h *DocsHandler) UI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUI))
+}
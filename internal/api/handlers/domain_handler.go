@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/customdomains"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// DomainHandler handles the custom domains of projects and their verification
+type DomainHandler struct {
+	repo        domain.DomainRepository
+	verifier    *customdomains.Verifier
+	projectRepo domain.ProjectRepository
+	ingressRepo domain.IngressRepository
+	logger      *logger.Logger
+}
+
+// NewDomainHandler creates a new DomainHandler
+func NewDomainHandler(repo domain.DomainRepository, verifier *customdomains.Verifier, projectRepo domain.ProjectRepository, ingressRepo domain.IngressRepository, log *logger.Logger) *DomainHandler {
+	return &DomainHandler{
+		repo:        repo,
+		verifier:    verifier,
+		projectRepo: projectRepo,
+		ingressRepo: ingressRepo,
+		logger:      log,
+	}
+}
+
+// CreateDomainRequest represents the request body for claiming a custom domain
+type CreateDomainRequest struct {
+	Name   string `json:"name" binding:"required,fqdn"`
+	Method string `json:"method" binding:"omitempty,oneof=txt cname"` // txt by default
+}
+
+// Create handles POST /projects/:project_id/domains. The response carries the
+// challenge record to publish.
+func (h *DomainHandler) Create(c *gin.Context) {
+	var req CreateDomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.projectRepo.GetByID(ctx, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	d, err := h.verifier.Claim(ctx, projectID, userID, req.Name, domain.DomainVerificationMethod(req.Method))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("domain", d.Name).
+		Str("project_id", projectID.String()).
+		Str("method", string(d.Method)).
+		Msg("Custom domain claimed")
+
+	c.JSON(http.StatusCreated, d)
+}
+
+// ListByProject handles GET /projects/:project_id/domains
+func (h *DomainHandler) ListByProject(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.projectRepo.GetByID(ctx, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	domains, err := h.repo.ListByProjectAfter(ctx, projectID, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	for _, d := range domains {
+		d.Record = h.verifier.Challenge(d)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        domains,
+		"count":       len(domains),
+		"limit":       limit,
+		"next_cursor": nextCursor(domains, limit, domainCursor),
+	})
+}
+
+// Get handles GET /domains/:id
+func (h *DomainHandler) Get(c *gin.Context) {
+	d, ok := h.domain(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, d)
+}
+
+// Verify handles POST /domains/:id/verify, checking the challenge record now
+// instead of waiting for the background check. Domains that failed to verify
+// in time can still be verified this way.
+func (h *DomainHandler) Verify(c *gin.Context) {
+	d, ok := h.domain(c)
+	if !ok {
+		return
+	}
+
+	if err := h.verifier.Verify(c.Request.Context(), d); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, d)
+}
+
+// Delete handles DELETE /domains/:id. A domain still routed by one of the
+// project's ingresses, and not covered by another of its verified domains,
+// cannot be removed.
+func (h *DomainHandler) Delete(c *gin.Context) {
+	d, ok := h.domain(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.checkUnused(ctx, d); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.repo.Delete(ctx, d.ID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("domain", d.Name).
+		Str("project_id", d.ProjectID.String()).
+		Msg("Custom domain removed")
+
+	c.Status(http.StatusNoContent)
+}
+
+// checkUnused fails with 409 when removing a verified domain would leave an
+// ingress routing a domain its project no longer controls
+func (h *DomainHandler) checkUnused(ctx context.Context, d *domain.CustomDomain) error {
+	if d.Status != domain.DomainStatusVerified || h.ingressRepo == nil {
+		return nil
+	}
+	ingresses, err := h.ingressRepo.ListByProject(ctx, d.ProjectID)
+	if err != nil {
+		return err
+	}
+	domains, err := h.repo.ListByProject(ctx, d.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	for _, ingress := range ingresses {
+		if !d.Covers(ingress.Domain) {
+			continue
+		}
+		covered := false
+		for _, other := range domains {
+			if other.ID != d.ID && other.Covers(ingress.Domain) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return errors.NewError(errors.CodeConflict, "the domain is used by ingress "+ingress.Domain+"; delete the ingress first", http.StatusConflict)
+		}
+	}
+	return nil
+}
+
+func (h *DomainHandler) domain(c *gin.Context) (*domain.CustomDomain, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid domain ID"))
+		return nil, false
+	}
+
+	d, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	d.Record = h.verifier.Challenge(d)
+
+	return d, true
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/dora"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	defaultDORAWindow = "90d"
+	maxDORAWindow     = 365 * 24 * time.Hour
+)
+
+// DORAHandler handles delivery performance reporting endpoints
+type DORAHandler struct {
+	reporter    *dora.Reporter
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewDORAHandler creates a new DORAHandler
+func NewDORAHandler(reporter *dora.Reporter, projectRepo domain.ProjectRepository, log *logger.Logger) *DORAHandler {
+	return &DORAHandler{
+		reporter:    reporter,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// Get handles GET /projects/:id/dora
+// Query: window (e.g. 30d, 12w, 720h; default 90d)
+func (h *DORAHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	label := c.DefaultQuery("window", defaultDORAWindow)
+	window, err := parseWindow(label)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if _, err := h.projectRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	report, err := h.reporter.Report(c.Request.Context(), id, window, label)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseWindow accepts day (d) and week (w) suffixes in addition to Go durations
+func parseWindow(v string) (time.Duration, error) {
+	var d time.Duration
+	switch {
+	case strings.HasSuffix(v, "d"), strings.HasSuffix(v, "w"):
+		n, err := strconv.Atoi(v[:len(v)-1])
+		if err != nil {
+			return 0, errors.BadRequest("invalid window")
+		}
+		d = time.Duration(n) * 24 * time.Hour
+		if strings.HasSuffix(v, "w") {
+			d *= 7
+		}
+	default:
+		var err error
+		if d, err = time.ParseDuration(v); err != nil {
+			return 0, errors.BadRequest("invalid window")
+		}
+	}
+
+	if d < 24*time.Hour || d > maxDORAWindow {
+		return 0, errors.BadRequest("window must be between 1d and 365d")
+	}
+	return d, nil
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/maintenance"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// DrainHandler handles node maintenance endpoints
+type DrainHandler struct {
+	drainer     *maintenance.Drainer
+	clusterRepo domain.ClusterRepository
+	logger      *logger.Logger
+}
+
+// NewDrainHandler creates a new DrainHandler
+func NewDrainHandler(drainer *maintenance.Drainer, clusterRepo domain.ClusterRepository, log *logger.Logger) *DrainHandler {
+	return &DrainHandler{
+		drainer:     drainer,
+		clusterRepo: clusterRepo,
+		logger:      log,
+	}
+}
+
+// DrainNodeRequest represents the request body for draining a node
+type DrainNodeRequest struct {
+	DryRun         bool `json:"dry_run"`
+	TimeoutSeconds int  `json:"timeout_seconds" binding:"omitempty,min=30,max=86400"`
+}
+
+// Drain handles POST /clusters/:id/nodes/:node/drain
+func (h *DrainHandler) Drain(c *gin.Context) {
+	cluster, ok := h.loadCluster(c)
+	if !ok {
+		return
+	}
+
+	var req DrainNodeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, bindError(err))
+			return
+		}
+	}
+
+	node := c.Param("node")
+	op, err := h.drainer.Start(c.Request.Context(), cluster.ID, node, maintenance.DrainOptions{
+		DryRun:  req.DryRun,
+		Timeout: time.Duration(req.TimeoutSeconds) * time.Second,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if req.DryRun {
+		c.JSON(http.StatusOK, op)
+		return
+	}
+
+	h.logger.Info().
+		Str("cluster_id", cluster.ID.String()).
+		Str("node", node).
+		Int("pods", op.TotalPods).
+		Msg("Node drain started")
+
+	c.JSON(http.StatusAccepted, op)
+}
+
+// Status handles GET /clusters/:id/nodes/:node/drain
+func (h *DrainHandler) Status(c *gin.Context) {
+	cluster, ok := h.loadCluster(c)
+	if !ok {
+		return
+	}
+
+	op, err := h.drainer.Status(cluster.ID, c.Param("node"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, op)
+}
+
+func (h *DrainHandler) loadCluster(c *gin.Context) (*domain.Cluster, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid cluster ID"))
+		return nil, false
+	}
+
+	cluster, err := h.clusterRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return cluster, true
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/egress"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// EgressHandler handles the static egress IP endpoints of environments
+type EgressHandler struct {
+	manager *egress.Manager
+	envRepo domain.EnvironmentRepository
+	logger  *logger.Logger
+}
+
+// NewEgressHandler creates a new EgressHandler
+func NewEgressHandler(manager *egress.Manager, envRepo domain.EnvironmentRepository, log *logger.Logger) *EgressHandler {
+	return &EgressHandler{
+		manager: manager,
+		envRepo: envRepo,
+		logger:  log,
+	}
+}
+
+// EgressRequest represents the request body for configuring egress IPs
+type EgressRequest struct {
+	Mode string `json:"mode" binding:"required,oneof=gateway cluster_nat"`
+	Pool string `json:"pool,omitempty"` // Gateway node pool; defaults to the configured pool
+}
+
+// Get handles GET /environments/:id/egress
+func (h *EgressHandler) Get(c *gin.Context) {
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+	if env.Egress == nil {
+		respondError(c, errors.NotFound("egress configuration", env.ID.String()))
+		return
+	}
+
+	c.JSON(http.StatusOK, env.Egress)
+}
+
+// Configure handles PUT /environments/:id/egress
+func (h *EgressHandler) Configure(c *gin.Context) {
+	var req EgressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.manager.Configure(c.Request.Context(), env, domain.EgressMode(req.Mode), req.Pool)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("environment_id", env.ID.String()).
+		Str("mode", req.Mode).
+		Str("status", string(result.Status)).
+		Msg("Egress configured")
+
+	c.JSON(http.StatusOK, result)
+}
+
+// Disable handles DELETE /environments/:id/egress
+func (h *EgressHandler) Disable(c *gin.Context) {
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.manager.Disable(c.Request.Context(), env); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *EgressHandler) environment(c *gin.Context) (*domain.Environment, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid environment ID"))
+		return nil, false
+	}
+
+	env, err := h.envRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return env, true
+}
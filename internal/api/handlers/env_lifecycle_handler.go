@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/envlifecycle"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// EnvLifecycleHandler handles the expiry and hibernation endpoints of environments
+type EnvLifecycleHandler struct {
+	manager *envlifecycle.Manager
+	envRepo domain.EnvironmentRepository
+	logger  *logger.Logger
+}
+
+// NewEnvLifecycleHandler creates a new EnvLifecycleHandler
+func NewEnvLifecycleHandler(manager *envlifecycle.Manager, envRepo domain.EnvironmentRepository, log *logger.Logger) *EnvLifecycleHandler {
+	return &EnvLifecycleHandler{
+		manager: manager,
+		envRepo: envRepo,
+		logger:  log,
+	}
+}
+
+// LifecycleRequest represents the request body for setting an environment's lifecycle
+type LifecycleRequest struct {
+	TTLHours      int  `json:"ttl_hours" binding:"min=0"`
+	IdleHours     int  `json:"idle_hours" binding:"min=0"`
+	WakeOnRequest bool `json:"wake_on_request"`
+}
+
+// Get handles GET /environments/:id/lifecycle. Preview environments without
+// a lifecycle of their own report the configured defaults.
+func (h *EnvLifecycleHandler) Get(c *gin.Context) {
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+	lifecycle := h.manager.Effective(env)
+	if lifecycle == nil {
+		respondError(c, errors.NotFound("environment lifecycle", env.ID.String()))
+		return
+	}
+
+	c.JSON(http.StatusOK, lifecycle)
+}
+
+// Configure handles PUT /environments/:id/lifecycle
+func (h *EnvLifecycleHandler) Configure(c *gin.Context) {
+	var req LifecycleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+
+	lifecycle, err := h.manager.Configure(c.Request.Context(), env, domain.EnvironmentLifecycle{
+		TTLHours:      req.TTLHours,
+		IdleHours:     req.IdleHours,
+		WakeOnRequest: req.WakeOnRequest,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("environment_id", env.ID.String()).
+		Int("ttl_hours", req.TTLHours).
+		Int("idle_hours", req.IdleHours).
+		Msg("Environment lifecycle configured")
+
+	c.JSON(http.StatusOK, lifecycle)
+}
+
+// Disable handles DELETE /environments/:id/lifecycle
+func (h *EnvLifecycleHandler) Disable(c *gin.Context) {
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.manager.Disable(c.Request.Context(), env); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Hibernate handles POST /environments/:id/hibernate
+func (h *EnvLifecycleHandler) Hibernate(c *gin.Context) {
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+	if env.IsDefault || (env.Type != domain.EnvironmentTypePreview && env.Type != domain.EnvironmentTypeDevelopment) {
+		respondError(c, errors.BadRequest("only preview and development environments that are not the project default can hibernate"))
+		return
+	}
+
+	if err := h.manager.Hibernate(c.Request.Context(), env); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, env)
+}
+
+// Wake handles POST /environments/:id/wake
+func (h *EnvLifecycleHandler) Wake(c *gin.Context) {
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+
+	if err := h.manager.Wake(c.Request.Context(), env); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, env)
+}
+
+func (h *EnvLifecycleHandler) environment(c *gin.Context) (*domain.Environment, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid environment ID"))
+		return nil, false
+	}
+
+	env, err := h.envRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return env, true
+}
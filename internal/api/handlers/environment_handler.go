@@ -0,0 +1,407 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/devcluster"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/residency"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// EnvironmentHandler handles project environment endpoints
+type EnvironmentHandler struct {
+	envRepo        domain.EnvironmentRepository
+	clusterRepo    domain.ClusterRepository
+	projectRepo    domain.ProjectRepository
+	clusterManager domain.ClusterManagerAdapter
+	residency      *residency.Checker
+	eventBus       domain.EventBus
+	logger         *logger.Logger
+}
+
+// NewEnvironmentHandler creates a new EnvironmentHandler. Without a cluster
+// manager, namespaces are recorded but not created on the cluster.
+func NewEnvironmentHandler(
+	envRepo domain.EnvironmentRepository,
+	clusterRepo domain.ClusterRepository,
+	projectRepo domain.ProjectRepository,
+	clusterManager domain.ClusterManagerAdapter,
+	residency *residency.Checker,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *EnvironmentHandler {
+	return &EnvironmentHandler{
+		envRepo:        envRepo,
+		clusterRepo:    clusterRepo,
+		projectRepo:    projectRepo,
+		clusterManager: clusterManager,
+		residency:      residency,
+		eventBus:       eventBus,
+		logger:         log,
+	}
+}
+
+// CreateEnvironmentRequest represents the request body for creating an environment
+type CreateEnvironmentRequest struct {
+	Name      string            `json:"name" binding:"required,min=1,max=255"`
+	Slug      string            `json:"slug"`
+	Type      string            `json:"type" binding:"required,oneof=development staging production preview"`
+	ClusterID uuid.UUID         `json:"cluster_id" binding:"required"`
+	Namespace string            `json:"namespace"`
+	IsDefault bool              `json:"is_default"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// UpdateEnvironmentRequest represents the request body for updating an environment
+type UpdateEnvironmentRequest struct {
+	Name      *string           `json:"name,omitempty"`
+	Type      *string           `json:"type,omitempty" binding:"omitempty,oneof=development staging production preview"`
+	IsDefault *bool             `json:"is_default,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// Create handles POST /projects/:project_id/environments
+func (h *EnvironmentHandler) Create(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	var req CreateEnvironmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	slug := req.Slug
+	if slug == "" {
+		slug = slugify(req.Name)
+	}
+	if !isDNSLabel(slug) {
+		respondError(c, errors.BadRequest("slug must be a lowercase DNS label of at most 63 characters"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	project, err := h.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	cluster, err := h.clusterRepo.GetByID(ctx, req.ClusterID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if cluster.Status == domain.ClusterStatusDeleting {
+		respondError(c, errors.BadRequest("cluster is being deleted"))
+		return
+	}
+	if err := h.residency.CheckCluster(project, cluster); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := devcluster.CheckEnvironment(cluster, domain.EnvironmentType(req.Type)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = environmentNamespace(project.Slug, slug)
+	}
+	if !isDNSLabel(namespace) {
+		respondError(c, errors.BadRequest("namespace must be a lowercase DNS label of at most 63 characters"))
+		return
+	}
+
+	if _, err := h.envRepo.GetBySlug(ctx, project.ID, slug); err == nil {
+		respondError(c, errors.Conflict("environment "+slug))
+		return
+	} else if !errors.IsNotFound(err) {
+		respondError(c, err)
+		return
+	}
+	inUse, err := h.namespaceInUse(ctx, cluster.ID, namespace)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if inUse {
+		respondError(c, errors.Conflict("namespace "+namespace))
+		return
+	}
+
+	now := time.Now()
+	env := &domain.Environment{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		ClusterID: cluster.ID,
+		Name:      req.Name,
+		Slug:      slug,
+		Type:      domain.EnvironmentType(req.Type),
+		Namespace: namespace,
+		IsDefault: req.IsDefault,
+		Labels:    req.Labels,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if h.clusterManager != nil && cluster.RancherClusterID != "" {
+		if err := h.clusterManager.CreateNamespace(ctx, cluster.RancherClusterID, namespace, namespaceLabels(env)); err != nil {
+			h.logger.Error().Err(err).
+				Str("cluster_id", cluster.ID.String()).
+				Str("namespace", namespace).
+				Msg("Failed to create namespace")
+			respondError(c, err)
+			return
+		}
+	}
+
+	if err := h.envRepo.Create(ctx, env); err != nil {
+		// The namespace is left in place; creating it again on retry is a no-op
+		h.logger.Error().Err(err).Str("slug", slug).Msg("Failed to create environment")
+		respondError(c, err)
+		return
+	}
+
+	if env.IsDefault {
+		h.clearOtherDefaults(ctx, env)
+	}
+
+	h.publishEvent(ctx, "project.environment.created", env)
+
+	h.logger.Info().
+		Str("environment_id", env.ID.String()).
+		Str("namespace", env.Namespace).
+		Msg("Environment created")
+
+	c.JSON(http.StatusCreated, env)
+}
+
+// ListByProject handles GET /projects/:project_id/environments
+func (h *EnvironmentHandler) ListByProject(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.projectRepo.GetByID(ctx, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	environments, err := h.envRepo.ListByProjectAfter(ctx, projectID, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        environments,
+		"count":       len(environments),
+		"limit":       limit,
+		"next_cursor": nextCursor(environments, limit, environmentCursor),
+	})
+}
+
+// Get handles GET /environments/:id
+func (h *EnvironmentHandler) Get(c *gin.Context) {
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, env)
+}
+
+// Update handles PATCH /environments/:id. The cluster and namespace of an
+// environment cannot be changed.
+func (h *EnvironmentHandler) Update(c *gin.Context) {
+	var req UpdateEnvironmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		env.Name = *req.Name
+	}
+	ctx := c.Request.Context()
+	if req.Type != nil && domain.EnvironmentType(*req.Type) != env.Type {
+		cluster, err := h.clusterRepo.GetByID(ctx, env.ClusterID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if err := devcluster.CheckEnvironment(cluster, domain.EnvironmentType(*req.Type)); err != nil {
+			respondError(c, err)
+			return
+		}
+		env.Type = domain.EnvironmentType(*req.Type)
+	}
+	if req.IsDefault != nil {
+		env.IsDefault = *req.IsDefault
+	}
+	if req.Labels != nil {
+		env.Labels = req.Labels
+	}
+
+	if err := h.envRepo.Update(ctx, env); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if env.IsDefault {
+		h.clearOtherDefaults(ctx, env)
+	}
+
+	h.publishEvent(ctx, "project.environment.updated", env)
+
+	c.JSON(http.StatusOK, env)
+}
+
+// Delete handles DELETE /environments/:id and removes its namespace
+func (h *EnvironmentHandler) Delete(c *gin.Context) {
+	env, ok := h.environment(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if h.clusterManager != nil {
+		cluster, err := h.clusterRepo.GetByID(ctx, env.ClusterID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if cluster.RancherClusterID != "" {
+			if err := h.clusterManager.DeleteNamespace(ctx, cluster.RancherClusterID, env.Namespace); err != nil {
+				h.logger.Error().Err(err).
+					Str("environment_id", env.ID.String()).
+					Str("namespace", env.Namespace).
+					Msg("Failed to delete namespace")
+				respondError(c, err)
+				return
+			}
+		}
+	}
+
+	if err := h.envRepo.Delete(ctx, env.ID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.publishEvent(ctx, "project.environment.deleted", env)
+
+	h.logger.Info().
+		Str("environment_id", env.ID.String()).
+		Msg("Environment deleted")
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *EnvironmentHandler) environment(c *gin.Context) (*domain.Environment, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid environment ID"))
+		return nil, false
+	}
+
+	env, err := h.envRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return env, true
+}
+
+// namespaceInUse reports whether an environment already uses the namespace on the cluster
+func (h *EnvironmentHandler) namespaceInUse(ctx context.Context, clusterID uuid.UUID, namespace string) (bool, error) {
+	environments, err := h.envRepo.ListByCluster(ctx, clusterID)
+	if err != nil {
+		return false, err
+	}
+	for _, env := range environments {
+		if env.Namespace == namespace {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// clearOtherDefaults keeps a single default environment per project
+func (h *EnvironmentHandler) clearOtherDefaults(ctx context.Context, env *domain.Environment) {
+	environments, err := h.envRepo.ListByProject(ctx, env.ProjectID)
+	if err != nil {
+		h.logger.Warn().Err(err).Str("project_id", env.ProjectID.String()).Msg("Failed to list environments")
+		return
+	}
+	for _, other := range environments {
+		if other.ID == env.ID || !other.IsDefault {
+			continue
+		}
+		other.IsDefault = false
+		if err := h.envRepo.Update(ctx, other); err != nil {
+			h.logger.Warn().Err(err).Str("environment_id", other.ID.String()).Msg("Failed to clear default environment")
+		}
+	}
+}
+
+func (h *EnvironmentHandler) publishEvent(ctx context.Context, eventType string, env *domain.Environment) {
+	event := &domain.Event{
+		Type:   eventType,
+		Source: "api",
+		Data: map[string]interface{}{
+			"environment_id": env.ID.String(),
+			"project_id":     env.ProjectID.String(),
+			"cluster_id":     env.ClusterID.String(),
+			"namespace":      env.Namespace,
+		},
+	}
+	if err := h.eventBus.Publish(ctx, eventType, event); err != nil {
+		h.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// environmentNamespace derives the default namespace of an environment
+func environmentNamespace(projectSlug, envSlug string) string {
+	return slugify(projectSlug + "-" + envSlug)
+}
+
+// namespaceLabels returns the labels applied to an environment's namespace
+func namespaceLabels(env *domain.Environment) map[string]string {
+	labels := make(map[string]string, len(env.Labels)+3)
+	for k, v := range env.Labels {
+		labels[k] = v
+	}
+	labels[domain.LabelProjectID] = env.ProjectID.String()
+	labels[domain.LabelEnvironmentID] = env.ID.String()
+	labels[domain.LabelManagedBy] = domain.ManagedByValue
+	return labels
+}
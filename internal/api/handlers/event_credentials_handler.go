@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/internal/natsauth"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// EventCredentialsHandler issues NATS credentials that can subscribe to the
+// events of one tenant, mirrored to its subjects by the event bus
+type EventCredentialsHandler struct {
+	issuer      *natsauth.Issuer
+	config      *config.NATSTenantsConfig
+	projectRepo domain.ProjectRepository
+	teamRepo    domain.TeamRepository
+	auditLogger *audit.Logger
+	logger      *logger.Logger
+}
+
+// NewEventCredentialsHandler creates a new EventCredentialsHandler. teamRepo
+// and auditLogger may be nil; without teamRepo only project owners and
+// admins get credentials.
+func NewEventCredentialsHandler(issuer *natsauth.Issuer, cfg *config.NATSTenantsConfig, projectRepo domain.ProjectRepository, teamRepo domain.TeamRepository, auditLogger *audit.Logger, log *logger.Logger) *EventCredentialsHandler {
+	return &EventCredentialsHandler{
+		issuer:      issuer,
+		config:      cfg,
+		projectRepo: projectRepo,
+		teamRepo:    teamRepo,
+		auditLogger: auditLogger,
+		logger:      log,
+	}
+}
+
+// EventCredentialsResponse is a .creds file and what it grants
+type EventCredentialsResponse struct {
+	Credentials string     `json:"credentials"`
+	Subjects    []string   `json:"subjects"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	URL         string     `json:"url,omitempty"`
+}
+
+// ForProject handles POST /projects/:project_id/event-credentials, issuing
+// credentials that subscribe to the events of one project
+func (h *EventCredentialsHandler) ForProject(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectRepo.GetByID(c.Request.Context(), projectID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	tenant := eventbus.ProjectTenant(project)
+	allowed := project.OwnerID == userID
+	if !allowed && project.TeamID != nil {
+		if allowed, err = h.isMember(c, *project.TeamID, userID); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+	if !allowed && !isAdmin(c) {
+		respondError(c, errors.Forbidden("no access to project "+projectID.String()))
+		return
+	}
+
+	h.issue(c, userID, "project", project.ID, project.Name, eventbus.TenantSubjects(h.config.Prefix, tenant, &project.ID))
+}
+
+// ForTeam handles POST /teams/:id/event-credentials, issuing credentials that
+// subscribe to the events of every project of a team
+func (h *EventCredentialsHandler) ForTeam(c *gin.Context) {
+	teamID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid team ID"))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	team, err := h.teamRepo.GetByID(c.Request.Context(), teamID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	allowed := team.OwnerID == userID
+	if !allowed {
+		if allowed, err = h.isMember(c, team.ID, userID); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+	if !allowed && !isAdmin(c) {
+		respondError(c, errors.Forbidden("no access to team "+teamID.String()))
+		return
+	}
+
+	h.issue(c, userID, "team", team.ID, team.Name, eventbus.TenantSubjects(h.config.Prefix, team.ID, nil))
+}
+
+// issue signs credentials for the subjects and audit-logs them
+func (h *EventCredentialsHandler) issue(c *gin.Context, userID uuid.UUID, resourceType string, resourceID uuid.UUID, resourceName string, subjects []string) {
+	creds, err := h.issuer.Subscriber(userID.String(), subjects, h.config.CredentialsTTL)
+	if err != nil {
+		respondError(c, errors.Wrap(err, "failed to issue event credentials"))
+		return
+	}
+
+	if h.auditLogger != nil {
+		opts := audit.LogOptions{
+			UserID:       userID,
+			Action:       domain.AuditActionIssueCredentials,
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			ResourceName: resourceName,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+			Metadata:     map[string]interface{}{"subjects": subjects, "kind": "nats"},
+		}
+		if resourceType == "project" {
+			opts.ProjectID = &resourceID
+		}
+		_ = h.auditLogger.Log(c.Request.Context(), opts)
+	}
+
+	h.logger.Info().
+		Str("user_id", userID.String()).
+		Str(resourceType+"_id", resourceID.String()).
+		Interface("subjects", subjects).
+		Msg("Event credentials issued")
+
+	response := EventCredentialsResponse{
+		Credentials: creds.File(),
+		Subjects:    subjects,
+		URL:         h.config.URL,
+	}
+	if !creds.ExpiresAt.IsZero() {
+		response.ExpiresAt = &creds.ExpiresAt
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// isMember reports whether a user belongs to a team
+func (h *EventCredentialsHandler) isMember(c *gin.Context, teamID, userID uuid.UUID) (bool, error) {
+	if h.teamRepo == nil {
+		return false, nil
+	}
+	members, err := h.teamRepo.GetMembers(c.Request.Context(), teamID)
+	if err != nil {
+		return false, err
+	}
+	for _, member := range members {
+		if member.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// isAdmin reports whether the caller is a platform admin
+func isAdmin(c *gin.Context) bool {
+	role, _ := c.Get("user_role")
+	return role == domain.UserRoleAdmin
+}
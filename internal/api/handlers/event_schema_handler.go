@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/domain"
+)
+
+// EventSchemaHandler serves the JSON schemas of published events, so that
+// consumers can check the payloads they depend on
+type EventSchemaHandler struct {
+	registry domain.EventSchemaRegistry
+}
+
+// NewEventSchemaHandler creates a new EventSchemaHandler
+func NewEventSchemaHandler(registry domain.EventSchemaRegistry) *EventSchemaHandler {
+	return &EventSchemaHandler{registry: registry}
+}
+
+// List handles GET /events/schemas?subject=, returning every version of the
+// schemas that apply to subject, or all schemas
+func (h *EventSchemaHandler) List(c *gin.Context) {
+	schemas := h.registry.Schemas(c.Query("subject"))
+	c.JSON(http.StatusOK, gin.H{
+		"data":  schemas,
+		"count": len(schemas),
+	})
+}
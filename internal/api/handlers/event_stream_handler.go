@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/livefeed"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// eventStreamPing is how often idle connections are pinged, so proxies
+	// keep them open and dead clients are noticed
+	eventStreamPing = 30 * time.Second
+	// eventStreamPongWait is how long a client may go without answering
+	eventStreamPongWait = 2 * eventStreamPing
+	// eventStreamWriteWait bounds a single write to the client
+	eventStreamWriteWait = 10 * time.Second
+)
+
+// EventStreamHandler streams live build, deploy and service events to the
+// dashboard over WebSocket
+type EventStreamHandler struct {
+	hub         *livefeed.Hub
+	projectRepo domain.ProjectRepository
+	upgrader    websocket.Upgrader
+	logger      *logger.Logger
+}
+
+// NewEventStreamHandler creates a new EventStreamHandler. Upgrades are
+// accepted from the same origin and from allowedOrigins; "*" accepts any.
+func NewEventStreamHandler(hub *livefeed.Hub, projectRepo domain.ProjectRepository, allowedOrigins []string, log *logger.Logger) *EventStreamHandler {
+	return &EventStreamHandler{
+		hub:         hub,
+		projectRepo: projectRepo,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 4096,
+			CheckOrigin:     checkOrigin(allowedOrigins),
+		},
+		logger: log,
+	}
+}
+
+// Stream handles GET /events/ws
+// The connection receives every event of the caller's projects as a JSON
+// message; administrators receive the events of all projects. The optional
+// project_id query parameter, repeatable, narrows the stream further.
+// Browsers cannot set headers on WebSocket requests, so the token may be
+// passed in the token query parameter instead.
+func (h *EventStreamHandler) Stream(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	projects, err := h.projects(c, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already responded
+		h.logger.Debug().Err(err).Msg("Event stream upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	events, cancel := h.hub.Subscribe(projects)
+	defer cancel()
+
+	// The client only sends control frames; reading processes them and
+	// notices when the connection goes away
+	closed := make(chan struct{})
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(eventStreamPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(eventStreamPongWait))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(eventStreamPing)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-c.Request.Context().Done():
+			return
+		case event := <-events:
+			conn.SetWriteDeadline(time.Now().Add(eventStreamWriteWait))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventStreamWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// projects returns the projects the caller's stream covers, nil meaning all
+func (h *EventStreamHandler) projects(c *gin.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	var requested []uuid.UUID
+	for _, value := range c.QueryArray("project_id") {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, errors.BadRequest("invalid project ID: " + value)
+		}
+		requested = append(requested, id)
+	}
+
+	if role, _ := c.Get("user_role"); role == domain.UserRoleAdmin {
+		return requested, nil
+	}
+
+	owned, err := h.projectRepo.List(c.Request.Context(), domain.ProjectFilter{OwnerID: &userID})
+	if err != nil {
+		return nil, err
+	}
+	allowed := make([]uuid.UUID, 0, len(owned))
+	for _, project := range owned {
+		allowed = append(allowed, project.ID)
+	}
+	if requested == nil {
+		return allowed, nil
+	}
+
+	for _, id := range requested {
+		if !containsID(allowed, id) {
+			return nil, errors.Forbidden("no access to project " + id.String())
+		}
+	}
+	return requested, nil
+}
+
+// checkOrigin accepts requests without an Origin, from the host serving the
+// API, and from the allowed origins
+func checkOrigin(allowedOrigins []string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		host := origin
+		if i := strings.Index(host, "://"); i >= 0 {
+			host = host[i+3:]
+		}
+		if strings.EqualFold(host, r.Host) {
+			return true
+		}
+		for _, allowed := range allowedOrigins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
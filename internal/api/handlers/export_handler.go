@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/export"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// mimeGzip is the media type of exported archives
+const mimeGzip = "application/gzip"
+
+// ExportHandler exports projects to Helm charts and Kustomize directories
+type ExportHandler struct {
+	exporter *export.Exporter
+	logger   *logger.Logger
+}
+
+// NewExportHandler creates a new ExportHandler
+func NewExportHandler(exporter *export.Exporter, log *logger.Logger) *ExportHandler {
+	return &ExportHandler{
+		exporter: exporter,
+		logger:   log,
+	}
+}
+
+// Export handles GET /projects/:id/export?format=helm|kustomize. It answers a
+// gzipped tarball of the bundle's directory, or the bundle's files as JSON to
+// clients that accept only JSON.
+func (h *ExportHandler) Export(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	format := export.Format(c.DefaultQuery("format", string(export.FormatHelm)))
+	bundle, err := h.exporter.Export(c.Request.Context(), projectID, format)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if c.NegotiateFormat(mimeGzip, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(http.StatusOK, bundle)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.tar.gz"`, bundle.Name, bundle.Format))
+	c.Header("Content-Type", mimeGzip)
+	c.Status(http.StatusOK)
+	if err := bundle.WriteArchive(c.Writer); err != nil {
+		h.logger.Error().Err(err).Str("project_id", projectID.String()).Msg("Failed to write export archive")
+	}
+}
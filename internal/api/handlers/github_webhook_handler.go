@@ -8,6 +8,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
 )
 
@@ -30,14 +31,14 @@ func (h *GitHubWebhookHandler) HandleWebhook(c *gin.Context) {
 	// Read body
 	body, err := c.GetRawData()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		respondError(c, errors.BadRequest("Failed to read body"))
 		return
 	}
 
 	// Verify signature
 	signature := c.GetHeader("X-Hub-Signature-256")
 	if !h.verifySignature(body, signature) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+		respondError(c, errors.Unauthorized("Invalid signature"))
 		return
 	}
 
@@ -80,7 +81,7 @@ type PullRequestEvent struct {
 func (h *GitHubWebhookHandler) handlePullRequest(c *gin.Context, body []byte) {
 	var event PullRequestEvent
 	if err := json.Unmarshal(body, &event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		respondError(c, errors.BadRequest("Invalid payload"))
 		return
 	}
 
@@ -120,7 +121,7 @@ func (h *GitHubWebhookHandler) handlePush(c *gin.Context, body []byte) {
 	}
 
 	if err := json.Unmarshal(body, &event); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		respondError(c, errors.BadRequest("Invalid payload"))
 		return
 	}
 
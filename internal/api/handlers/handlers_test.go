@@ -3,12 +3,15 @@ package handlers
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"bytes"
 	"encoding/json"
 	"net/http/httptest"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -310,3 +313,91 @@ func TestDatabaseCreationValidation(t *testing.T) {
 		})
 	}
 }
+
+// TestEnvironmentNamespace tests default namespace derivation
+func TestEnvironmentNamespace(t *testing.T) {
+	assert.Equal(t, "shop-staging", environmentNamespace("Shop", "staging"))
+	assert.Equal(t, "my-app-pr-42", environmentNamespace("My App", "PR #42"))
+
+	long := environmentNamespace("a-very-long-project-slug-that-keeps-going-and-going", "preview-environment")
+	assert.True(t, isDNSLabel(long))
+	assert.Len(t, long, 63)
+}
+
+// TestIngressPath tests ingress path normalization
+func TestIngressPath(t *testing.T) {
+	path, err := ingressPath("")
+	assert.NoError(t, err)
+	assert.Equal(t, "/", path)
+
+	path, err = ingressPath("/api")
+	assert.NoError(t, err)
+	assert.Equal(t, "/api", path)
+
+	_, err = ingressPath("api")
+	assert.Error(t, err)
+	_, err = ingressPath("/api?x=1")
+	assert.Error(t, err)
+}
+
+// TestExternalDeployment tests building records for externally performed deployments
+func TestExternalDeployment(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	service := &domain.Service{ID: uuid.New(), ProjectID: uuid.New()}
+	service.Scaling.MinReplicas = 2
+	env := &domain.Environment{ID: uuid.New(), ClusterID: uuid.New(), Slug: "production"}
+	history := []*domain.Deployment{
+		{Version: "v3", Status: domain.DeploymentStatusFailed, CreatedAt: now.Add(-time.Hour)},
+		{Version: "v2", Status: domain.DeploymentStatusSucceeded, CreatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	finished := now.Add(-30 * time.Minute)
+	committed := now.Add(-3 * time.Hour)
+	req := &ExternalDeploymentRequest{
+		Version:         "v4",
+		Source:          "spinnaker",
+		ExternalID:      "exec-17",
+		CommitTimestamp: &committed,
+		Timestamp:       &finished,
+	}
+
+	d, err := externalDeployment(req, service, env, history, "spinnaker", now)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DeploymentStatusSucceeded, d.Status)
+	assert.Equal(t, env.ClusterID, d.ClusterID)
+	assert.Equal(t, finished, d.CreatedAt)
+	assert.Equal(t, "v2", d.PreviousVersion)
+	assert.Equal(t, int32(2), d.ReadyReplicas)
+	assert.Equal(t, committed.Format(time.RFC3339), d.Metadata["commit_timestamp"])
+	assert.Same(t, d, findExternal([]*domain.Deployment{d}, "spinnaker", "exec-17"))
+	assert.Nil(t, findExternal([]*domain.Deployment{d}, "argocd", "exec-17"))
+
+	future := now.Add(time.Hour)
+	req.Timestamp = &future
+	_, err = externalDeployment(req, service, nil, nil, "spinnaker", now)
+	assert.Error(t, err)
+}
+
+// TestParseLabelSelector tests parsing of label=key=value query parameters
+func TestParseLabelSelector(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	parse := func(query string) (map[string]string, bool, int) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/services?"+query, nil)
+		labels, ok := parseLabelSelector(c)
+		return labels, ok, w.Code
+	}
+
+	labels, ok, _ := parse("label=team=payments&label=tier=tier-1=gold")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"team": "payments", "tier": "tier-1=gold"}, labels)
+
+	labels, ok, _ = parse("")
+	assert.True(t, ok)
+	assert.Nil(t, labels)
+
+	_, ok, code := parse("label=team")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -2,30 +2,30 @@ package handlers
 
 import (
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/northstack/platform/pkg/errors"
 )
 
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Code    string                 `json:"code"`
-	Message string                 `json:"message"`
-	Details string                 `json:"details,omitempty"`
-	Meta    map[string]interface{} `json:"meta,omitempty"`
+// respondError sends an error to the client as RFC 7807 problem details
+func respondError(c *gin.Context, err error) {
+	problem := errors.NewProblem(err, c.Request.URL.Path)
+
+	c.Header("Content-Type", errors.ProblemContentType)
+	c.JSON(problem.Status, problem)
 }
 
-// respondError sends an error response to the client
-func respondError(c *gin.Context, err error) {
-	pe := errors.GetPlatformError(err)
+// NoRoute handles requests for routes that do not exist
+func NoRoute(c *gin.Context) {
+	respondError(c, errors.NotFound("route", c.Request.Method+" "+c.Request.URL.Path))
+}
 
-	c.JSON(pe.HTTPStatus, ErrorResponse{
-		Code:    pe.Code,
-		Message: pe.Message,
-		Details: pe.Details,
-		Meta:    pe.Metadata,
-	})
+// NotImplemented responds that an endpoint is not available in this deployment
+func NotImplemented(c *gin.Context, message string) {
+	respondError(c, errors.NotImplemented(message))
 }
 
 // parseIntQuery parses an integer query parameter with a default value
@@ -43,6 +43,34 @@ func parseIntQuery(c *gin.Context, key string, defaultValue int) int {
 	return intValue
 }
 
+// dnsLabelPattern matches a Kubernetes DNS label (RFC 1123)
+var dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// isDNSLabel reports whether s can be used as a namespace or resource name
+func isDNSLabel(s string) bool {
+	return len(s) <= 63 && dnsLabelPattern.MatchString(s)
+}
+
+// slugify converts a display name into a DNS label
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > 63 {
+		slug = slug[:63]
+	}
+	return strings.TrimRight(slug, "-")
+}
+
 // parseBoolQuery parses a boolean query parameter with a default value
 func parseBoolQuery(c *gin.Context, key string, defaultValue bool) bool {
 	value := c.Query(key)
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/adapters/anticorruption"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// ImportHandler imports projects from Heroku and Northflank
+type ImportHandler struct {
+	importer *anticorruption.Importer
+	logger   *logger.Logger
+}
+
+// NewImportHandler creates a new ImportHandler
+func NewImportHandler(importer *anticorruption.Importer, log *logger.Logger) *ImportHandler {
+	return &ImportHandler{
+		importer: importer,
+		logger:   log,
+	}
+}
+
+// ImportRequest names the project to import and the credentials to read it
+// with. Credentials are used for the import only and are not stored.
+type ImportRequest struct {
+	Source     string     `json:"source" binding:"required,oneof=heroku northflank"`
+	Token      string     `json:"token" binding:"required"`
+	App        string     `json:"app" binding:"required_if=Source heroku"`         // Heroku app name
+	Project    string     `json:"project" binding:"required_if=Source northflank"` // Northflank project ID
+	Repository string     `json:"repository"`                                      // Code of a Heroku app
+	Branch     string     `json:"branch"`
+	ProjectID  *uuid.UUID `json:"project_id"` // Import into this project instead of creating one
+	DryRun     bool       `json:"dry_run"`
+}
+
+// Import handles POST /projects/import. It creates a project with the
+// services and secrets of the source, or reports what it would create on a
+// dry run, along with what could not be carried over.
+func (h *ImportHandler) Import(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req ImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	var source anticorruption.Source
+	switch req.Source {
+	case "heroku":
+		source = anticorruption.NewHerokuSource("", req.Token, req.App, req.Repository, req.Branch)
+	case "northflank":
+		source = anticorruption.NewNorthflankSource("", req.Token, req.Project)
+	default:
+		respondError(c, errors.BadRequest("unknown source "+req.Source))
+		return
+	}
+
+	report, err := h.importer.Import(c.Request.Context(), source, anticorruption.ImportOptions{
+		ProjectID: req.ProjectID,
+		OwnerID:   userID,
+		DryRun:    req.DryRun,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
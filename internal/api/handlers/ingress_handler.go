@@ -0,0 +1,516 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/autotls"
+	"github.com/northstack/platform/internal/customdomains"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/dualstack"
+	"github.com/northstack/platform/internal/ingressroutes"
+	"github.com/northstack/platform/internal/internalnet"
+	"github.com/northstack/platform/internal/secretsync"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// IngressHandler handles service ingress endpoints
+type IngressHandler struct {
+	ingressRepo domain.IngressRepository
+	serviceRepo domain.ServiceRepository
+	projectRepo domain.ProjectRepository
+	networking  *dualstack.Checker
+	domains     *customdomains.Verifier
+	routes      *ingressroutes.Manager
+	certs       *autotls.Manager
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewIngressHandler creates a new IngressHandler. Without a domain verifier,
+// ingresses may route any domain; without a route manager, ingresses are not
+// applied to clusters; without a certificate manager, auto TLS is only
+// recorded on the ingress.
+func NewIngressHandler(ingressRepo domain.IngressRepository, serviceRepo domain.ServiceRepository, projectRepo domain.ProjectRepository, networking *dualstack.Checker, domains *customdomains.Verifier, routes *ingressroutes.Manager, certs *autotls.Manager, eventBus domain.EventBus, log *logger.Logger) *IngressHandler {
+	return &IngressHandler{
+		ingressRepo: ingressRepo,
+		serviceRepo: serviceRepo,
+		projectRepo: projectRepo,
+		networking:  networking,
+		domains:     domains,
+		routes:      routes,
+		certs:       certs,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// CreateIngressRequest represents the request body for creating an ingress
+type CreateIngressRequest struct {
+	Domain      string                 `json:"domain" binding:"required,hostname_rfc1123"`
+	Path        string                 `json:"path"`
+	Type        string                 `json:"type" binding:"omitempty,oneof=http grpc tcp"`
+	TLS         *domain.TLSConfig      `json:"tls,omitempty"`
+	Annotations map[string]string      `json:"annotations,omitempty"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	IPFamilies  []domain.IPFamily      `json:"ip_families,omitempty"` // DNS record families: IPv4 (A), IPv6 (AAAA)
+	Routing     *domain.IngressRouting `json:"routing,omitempty"`     // Path rewrite, body size limit and sticky sessions
+}
+
+// UpdateIngressRequest represents the request body for updating an ingress
+type UpdateIngressRequest struct {
+	Domain      *string                `json:"domain,omitempty" binding:"omitempty,hostname_rfc1123"`
+	Path        *string                `json:"path,omitempty"`
+	Type        *string                `json:"type,omitempty" binding:"omitempty,oneof=http grpc tcp"`
+	TLS         *domain.TLSConfig      `json:"tls,omitempty"`
+	Annotations map[string]string      `json:"annotations,omitempty"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	IPFamilies  []domain.IPFamily      `json:"ip_families,omitempty"`
+	Routing     *domain.IngressRouting `json:"routing,omitempty"` // Replaces all routing options
+}
+
+// Create handles POST /services/:id/ingresses
+func (h *IngressHandler) Create(c *gin.Context) {
+	var req CreateIngressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	service, ok := h.service(c)
+	if !ok {
+		return
+	}
+	if internalnet.Internal(service) {
+		respondError(c, errors.BadRequest("internal services cannot be routed by ingresses; set the service's networking.exposure to public first"))
+		return
+	}
+
+	path, err := ingressPath(req.Path)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.domains.Check(c.Request.Context(), service.ProjectID, req.Domain); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.checkFamilies(c, service, req.IPFamilies); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ingressType := domain.IngressTypeHTTP
+	if req.Type != "" {
+		ingressType = domain.IngressType(req.Type)
+	}
+
+	now := time.Now()
+	ingress := &domain.Ingress{
+		ID:          uuid.New(),
+		ServiceID:   service.ID,
+		ProjectID:   service.ProjectID,
+		Domain:      strings.ToLower(req.Domain),
+		Path:        path,
+		Type:        ingressType,
+		Annotations: req.Annotations,
+		Labels:      req.Labels,
+		IPFamilies:  req.IPFamilies,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if req.TLS != nil {
+		ingress.TLS = *req.TLS
+		ingress.TLS.Certificate = nil
+	}
+	if req.Routing != nil {
+		ingress.Routing = *req.Routing
+	}
+	if err := h.checkRouting(c.Request.Context(), nil, ingress); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if h.certs != nil && ingress.TLS.AutoTLS {
+		if err := h.certs.Validate(&ingress.TLS); err != nil {
+			respondError(c, err)
+			return
+		}
+		if err := h.certs.Apply(ctx, ingress); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	if err := h.ingressRepo.Create(ctx, ingress); err != nil {
+		h.removeCertificate(ctx, ingress)
+		respondError(c, err)
+		return
+	}
+
+	h.applyRoutes(ctx, ingress)
+	h.publishEvent(ctx, "service.ingress.created", ingress)
+
+	h.logger.Info().
+		Str("ingress_id", ingress.ID.String()).
+		Str("domain", ingress.Domain).
+		Msg("Ingress created")
+
+	c.JSON(http.StatusCreated, ingress)
+}
+
+// ListByService handles GET /services/:id/ingresses
+func (h *IngressHandler) ListByService(c *gin.Context) {
+	service, ok := h.service(c)
+	if !ok {
+		return
+	}
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	ingresses, err := h.ingressRepo.ListByServiceAfter(c.Request.Context(), service.ID, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        ingresses,
+		"count":       len(ingresses),
+		"limit":       limit,
+		"next_cursor": nextCursor(ingresses, limit, ingressCursor),
+	})
+}
+
+// ListByProject handles GET /projects/:project_id/ingresses
+func (h *IngressHandler) ListByProject(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.projectRepo.GetByID(ctx, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	ingresses, err := h.ingressRepo.ListByProjectAfter(ctx, projectID, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        ingresses,
+		"count":       len(ingresses),
+		"limit":       limit,
+		"next_cursor": nextCursor(ingresses, limit, ingressCursor),
+	})
+}
+
+// Get handles GET /ingresses/:id
+func (h *IngressHandler) Get(c *gin.Context) {
+	ingress, ok := h.ingress(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, ingress)
+}
+
+// Update handles PATCH /ingresses/:id
+func (h *IngressHandler) Update(c *gin.Context) {
+	var req UpdateIngressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	ingress, ok := h.ingress(c)
+	if !ok {
+		return
+	}
+
+	previous := *ingress
+	if req.Domain != nil && strings.ToLower(*req.Domain) != ingress.Domain {
+		if err := h.domains.Check(c.Request.Context(), ingress.ProjectID, *req.Domain); err != nil {
+			respondError(c, err)
+			return
+		}
+		ingress.Domain = strings.ToLower(*req.Domain)
+	}
+	if req.Path != nil {
+		path, err := ingressPath(*req.Path)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		ingress.Path = path
+	}
+	if req.Type != nil {
+		ingress.Type = domain.IngressType(*req.Type)
+	}
+	if req.TLS != nil {
+		ingress.TLS = *req.TLS
+		ingress.TLS.Certificate = previous.TLS.Certificate
+	}
+	if req.Annotations != nil {
+		ingress.Annotations = req.Annotations
+	}
+	if req.Labels != nil {
+		ingress.Labels = req.Labels
+	}
+	if req.IPFamilies != nil {
+		service, err := h.serviceRepo.GetByID(c.Request.Context(), ingress.ServiceID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if err := h.checkFamilies(c, service, req.IPFamilies); err != nil {
+			respondError(c, err)
+			return
+		}
+		ingress.IPFamilies = req.IPFamilies
+	}
+	if req.Routing != nil {
+		ingress.Routing = *req.Routing
+	}
+	if err := h.checkRouting(c.Request.Context(), &previous, ingress); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.updateCertificate(ctx, &previous, ingress); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.ingressRepo.Update(ctx, ingress); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.applyRoutes(ctx, ingress)
+	h.publishEvent(ctx, "service.ingress.updated", ingress)
+
+	c.JSON(http.StatusOK, ingress)
+}
+
+// Delete handles DELETE /ingresses/:id
+func (h *IngressHandler) Delete(c *gin.Context) {
+	ingress, ok := h.ingress(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.ingressRepo.Delete(ctx, ingress.ID); err != nil {
+		respondError(c, err)
+		return
+	}
+	if h.routes != nil {
+		if err := h.routes.Remove(ctx, ingress); err != nil {
+			h.logger.Warn().Err(err).Str("ingress_id", ingress.ID.String()).Msg("Failed to remove ingress resources")
+		}
+	}
+	h.removeCertificate(ctx, ingress)
+
+	h.publishEvent(ctx, "service.ingress.deleted", ingress)
+
+	h.logger.Info().
+		Str("ingress_id", ingress.ID.String()).
+		Msg("Ingress deleted")
+
+	c.Status(http.StatusNoContent)
+}
+
+// Manifests handles GET /ingresses/:id/manifests, rendering the ingress
+// controller resources of an ingress as YAML for the cluster of its service
+func (h *IngressHandler) Manifests(c *gin.Context) {
+	ingress, ok := h.ingress(c)
+	if !ok {
+		return
+	}
+
+	manifests, err := h.routes.Manifests(c.Request.Context(), ingress)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	out, err := secretsync.MarshalYAML(manifests)
+	if err != nil {
+		respondError(c, errors.Wrap(err, "failed to encode ingress resources"))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/yaml", out)
+}
+
+func (h *IngressHandler) service(c *gin.Context) (*domain.Service, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return nil, false
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return service, true
+}
+
+func (h *IngressHandler) ingress(c *gin.Context) (*domain.Ingress, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid ingress ID"))
+		return nil, false
+	}
+
+	ingress, err := h.ingressRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return ingress, true
+}
+
+// updateCertificate requests a new certificate when an auto TLS ingress
+// changed its hosts or TLS settings, and removes it when auto TLS was
+// turned off
+func (h *IngressHandler) updateCertificate(ctx context.Context, previous, ingress *domain.Ingress) error {
+	if h.certs == nil {
+		return nil
+	}
+	if !ingress.TLS.AutoTLS {
+		if previous.TLS.Certificate != nil {
+			h.removeCertificate(ctx, previous)
+			ingress.TLS.Certificate = nil
+		}
+		return nil
+	}
+
+	if err := h.certs.Validate(&ingress.TLS); err != nil {
+		return err
+	}
+	ingress.TLS.Enabled = true
+	ingress.TLS.SecretName = autotls.SecretName(ingress)
+	if ingress.TLS.Certificate != nil && strings.Join(ingress.Hosts(), ",") == strings.Join(previous.Hosts(), ",") &&
+		ingress.TLS.Challenge == previous.TLS.Challenge && ingress.TLS.SecretName == previous.TLS.SecretName {
+		return nil
+	}
+	return h.certs.Apply(ctx, ingress)
+}
+
+// checkRouting validates the routing options of an ingress, that hosts newly
+// redirected by its rules belong to the project, and that the ingress
+// controller of its cluster supports them
+func (h *IngressHandler) checkRouting(ctx context.Context, previous, ingress *domain.Ingress) error {
+	if err := ingressroutes.Validate(ingress); err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	if previous != nil {
+		for _, host := range previous.Hosts() {
+			known[host] = true
+		}
+	}
+	for _, host := range ingress.Hosts()[1:] {
+		if known[host] {
+			continue
+		}
+		if err := h.domains.Check(ctx, ingress.ProjectID, host); err != nil {
+			return err
+		}
+	}
+	if h.routes == nil {
+		return nil
+	}
+	return h.routes.Check(ctx, ingress)
+}
+
+// applyRoutes applies the ingress controller resources of an ingress. The
+// ingress is saved either way; the reconcile loop retries failures.
+func (h *IngressHandler) applyRoutes(ctx context.Context, ingress *domain.Ingress) {
+	if h.routes == nil {
+		return
+	}
+	if err := h.routes.Apply(ctx, ingress); err != nil {
+		h.logger.Warn().Err(err).Str("ingress_id", ingress.ID.String()).Msg("Failed to apply ingress resources")
+	}
+}
+
+// removeCertificate deletes the certificate of an ingress from its cluster
+func (h *IngressHandler) removeCertificate(ctx context.Context, ingress *domain.Ingress) {
+	if h.certs == nil {
+		return
+	}
+	if err := h.certs.Remove(ctx, ingress); err != nil {
+		h.logger.Warn().Err(err).Str("ingress_id", ingress.ID.String()).Msg("Failed to remove ingress certificate")
+	}
+}
+
+func (h *IngressHandler) publishEvent(ctx context.Context, eventType string, ingress *domain.Ingress) {
+	event := &domain.Event{
+		Type:   eventType,
+		Source: "api",
+		Data: map[string]interface{}{
+			"ingress_id":   ingress.ID.String(),
+			"service_id":   ingress.ServiceID.String(),
+			"project_id":   ingress.ProjectID.String(),
+			"domain":       ingress.Domain,
+			"path":         ingress.Path,
+			"record_types": dualstack.RecordTypes(ingress.IPFamilies),
+		},
+	}
+	if err := h.eventBus.Publish(ctx, eventType, event); err != nil {
+		h.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// checkFamilies validates the IP families of an ingress against the clusters
+// serving its service
+func (h *IngressHandler) checkFamilies(c *gin.Context, service *domain.Service, families []domain.IPFamily) error {
+	if err := dualstack.ValidateFamilies(families); err != nil {
+		return err
+	}
+	return h.networking.Check(c.Request.Context(), service, dualstack.ForIngress(families))
+}
+
+// ingressPath normalizes an ingress path, defaulting to the root
+func ingressPath(path string) (string, error) {
+	if path == "" {
+		return "/", nil
+	}
+	if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, " ?#") {
+		return "", errors.BadRequest("path must start with / and must not contain spaces, queries or fragments")
+	}
+	return path, nil
+}
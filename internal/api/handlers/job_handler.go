@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/jobs"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// JobHandler handles the one-off job endpoints of services
+type JobHandler struct {
+	runner      *jobs.Runner
+	serviceRepo domain.ServiceRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewJobHandler creates a new JobHandler
+func NewJobHandler(runner *jobs.Runner, serviceRepo domain.ServiceRepository, eventBus domain.EventBus, log *logger.Logger) *JobHandler {
+	return &JobHandler{
+		runner:      runner,
+		serviceRepo: serviceRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// RunJobRequest represents a request to run a one-off job
+type RunJobRequest struct {
+	Command        []string `json:"command" binding:"required"`
+	TimeoutSeconds int64    `json:"timeout_seconds"`
+	Retries        int32    `json:"retries"`
+}
+
+// Run handles POST /services/:id/jobs
+func (h *JobHandler) Run(c *gin.Context) {
+	var req RunJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	jobReq := &jobs.Request{
+		Command:        req.Command,
+		TimeoutSeconds: req.TimeoutSeconds,
+		Retries:        req.Retries,
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		jobReq.TriggeredBy = fmt.Sprint(userID)
+	}
+
+	run, err := h.runner.Run(c.Request.Context(), service, jobReq)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(c.Request.Context(), "service.job_started", &domain.Event{
+		Type:   "service.job_started",
+		Source: "api",
+		Data: map[string]interface{}{
+			"service_id": service.ID.String(),
+			"project_id": service.ProjectID.String(),
+			"job":        run.Name,
+		},
+	})
+
+	c.JSON(http.StatusCreated, run)
+}
+
+// List handles GET /services/:id/jobs
+func (h *JobHandler) List(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	runs, err := h.runner.List(c.Request.Context(), service)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  runs,
+		"count": len(runs),
+	})
+}
+
+// Get handles GET /services/:id/jobs/:job
+func (h *JobHandler) Get(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.runner.Get(c.Request.Context(), service, c.Param("job"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// Logs handles GET /services/:id/jobs/:job/logs
+// Query: tail, the number of lines to return
+func (h *JobHandler) Logs(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	job := c.Param("job")
+	logs, err := h.runner.Logs(c.Request.Context(), service, job, int64(parseIntQuery(c, "tail", 0)))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"job":  job,
+		"logs": logs,
+	})
+}
+
+// Cancel handles POST /services/:id/jobs/:job/cancel
+func (h *JobHandler) Cancel(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	run, err := h.runner.Cancel(c.Request.Context(), service, c.Param("job"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+func (h *JobHandler) loadService(c *gin.Context) (*domain.Service, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return nil, false
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return service, true
+}
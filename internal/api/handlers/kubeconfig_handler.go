@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/kubeconfigs"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// KubeconfigHandler serves cached admin kubeconfigs and issues short-lived
+// kubeconfigs to users with access to a cluster
+type KubeconfigHandler struct {
+	manager     *kubeconfigs.Manager
+	clusterRepo domain.ClusterRepository
+	auditLogger *audit.Logger
+	logger      *logger.Logger
+}
+
+// NewKubeconfigHandler creates a new KubeconfigHandler. auditLogger may be nil.
+func NewKubeconfigHandler(manager *kubeconfigs.Manager, clusterRepo domain.ClusterRepository, auditLogger *audit.Logger, log *logger.Logger) *KubeconfigHandler {
+	return &KubeconfigHandler{
+		manager:     manager,
+		clusterRepo: clusterRepo,
+		auditLogger: auditLogger,
+		logger:      log,
+	}
+}
+
+// IssueKubeconfigRequest represents the request body for a user kubeconfig
+type IssueKubeconfigRequest struct {
+	TTLSeconds int `json:"ttl_seconds" binding:"omitempty,min=60"`
+}
+
+// Admin handles GET /clusters/:id/kubeconfig
+func (h *KubeconfigHandler) Admin(c *gin.Context) {
+	cluster, ok := h.loadCluster(c)
+	if !ok {
+		return
+	}
+	if cluster.Status != domain.ClusterStatusActive {
+		respondError(c, errors.BadRequest("cluster is not ready"))
+		return
+	}
+
+	kubeconfig, err := h.manager.Admin(c.Request.Context(), cluster)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"kubeconfig": string(kubeconfig),
+		"cluster_id": cluster.ID,
+		"endpoint":   cluster.APIEndpoint,
+	})
+}
+
+// Issue handles POST /clusters/:id/kubeconfig
+func (h *KubeconfigHandler) Issue(c *gin.Context) {
+	var req IssueKubeconfigRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, bindError(err))
+			return
+		}
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	cluster, ok := h.loadCluster(c)
+	if !ok {
+		return
+	}
+
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	issued, grant, err := h.manager.Issue(c.Request.Context(), cluster, userID, isAdmin(c), ttl)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if h.auditLogger != nil {
+		_ = h.auditLogger.Log(c.Request.Context(), audit.LogOptions{
+			UserID:       userID,
+			Action:       domain.AuditActionIssueCredentials,
+			ResourceType: "cluster",
+			ResourceID:   cluster.ID,
+			ResourceName: cluster.Name,
+			IPAddress:    c.ClientIP(),
+			UserAgent:    c.Request.UserAgent(),
+			Metadata: map[string]interface{}{
+				"kind":         "kubeconfig",
+				"cluster_role": grant.ClusterRole,
+				"namespaces":   grant.Namespaces,
+				"expires_at":   issued.ExpiresAt,
+			},
+		})
+	}
+
+	h.logger.Info().
+		Str("user_id", userID.String()).
+		Str("cluster_id", cluster.ID.String()).
+		Str("cluster_role", grant.ClusterRole).
+		Msg("Kubeconfig issued")
+
+	c.JSON(http.StatusOK, gin.H{
+		"kubeconfig":   string(issued.KubeConfig),
+		"cluster_id":   cluster.ID,
+		"cluster_role": grant.ClusterRole,
+		"namespaces":   grant.Namespaces,
+		"expires_at":   issued.ExpiresAt,
+	})
+}
+
+func (h *KubeconfigHandler) loadCluster(c *gin.Context) (*domain.Cluster, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid cluster ID"))
+		return nil, false
+	}
+
+	cluster, err := h.clusterRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return cluster, true
+}
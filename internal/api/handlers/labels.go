@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// parseLabelSelector reads repeated label=key=value query parameters into the
+// labels a listed resource must all carry
+func parseLabelSelector(c *gin.Context) (map[string]string, bool) {
+	selectors := c.QueryArray("label")
+	if len(selectors) == 0 {
+		return nil, true
+	}
+
+	labels := make(map[string]string, len(selectors))
+	for _, selector := range selectors {
+		key, value, ok := strings.Cut(selector, "=")
+		if !ok || key == "" {
+			respondError(c, errors.BadRequest("label selector must be key=value: "+selector))
+			return nil, false
+		}
+		labels[key] = value
+	}
+
+	return labels, true
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/logs"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	defaultLogSearchRange = time.Hour
+	maxLogSearchRange     = 30 * 24 * time.Hour
+)
+
+// LogsHandler handles service log endpoints
+type LogsHandler struct {
+	streamer    *logs.Streamer
+	store       domain.LogStore
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewLogsHandler creates a new LogsHandler. Either streamer or store may be nil
+// if live tailing or historical search is not available.
+func NewLogsHandler(streamer *logs.Streamer, store domain.LogStore, serviceRepo domain.ServiceRepository, log *logger.Logger) *LogsHandler {
+	return &LogsHandler{
+		streamer:    streamer,
+		store:       store,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// Stream handles GET /services/:id/logs
+// Query: follow, tail, container, instance. With follow=true the response is an
+// SSE stream of "log" events; otherwise a JSON list of lines.
+func (h *LogsHandler) Stream(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	opts := logs.StreamOptions{
+		Follow:    parseBoolQuery(c, "follow", false),
+		Tail:      int64(parseIntQuery(c, "tail", 0)),
+		Container: c.Query("container"),
+		Instance:  c.Query("instance"),
+	}
+
+	if !opts.Follow {
+		lines := []logs.Line{}
+		err := h.streamer.Stream(c.Request.Context(), service, opts, func(l logs.Line) error {
+			lines = append(lines, l)
+			return nil
+		})
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"lines": lines})
+		return
+	}
+
+	// Validate the selection before switching to SSE so errors are plain JSON
+	if _, err := h.streamer.Instances(c.Request.Context(), service); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	err := h.streamer.Stream(c.Request.Context(), service, opts, func(l logs.Line) error {
+		c.SSEvent("log", l)
+		c.Writer.Flush()
+		return c.Request.Context().Err()
+	})
+	if err != nil && c.Request.Context().Err() == nil {
+		c.SSEvent("error", errors.NewProblem(err, c.Request.URL.Path))
+		c.Writer.Flush()
+	}
+}
+
+// Instances handles GET /services/:id/instances
+func (h *LogsHandler) Instances(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	instances, err := h.streamer.Instances(c.Request.Context(), service)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"instances": instances})
+}
+
+// Search handles GET /services/:id/logs/search
+// Query: start/end (RFC3339 or unix seconds) or since (e.g. 6h), regex,
+// label (repeatable, name=value), limit, direction (backward|forward)
+func (h *LogsHandler) Search(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	query, err := parseLogQuery(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	entries, err := h.store.SearchServiceLogs(c.Request.Context(), service.ID, query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"query":   query,
+	})
+}
+
+func (h *LogsHandler) loadService(c *gin.Context) (*domain.Service, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return nil, false
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return service, true
+}
+
+func parseLogQuery(c *gin.Context) (domain.LogQuery, error) {
+	end := time.Now()
+	start := end.Add(-defaultLogSearchRange)
+
+	if since := c.Query("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return domain.LogQuery{}, errors.BadRequest("invalid since")
+		}
+		start = end.Add(-d)
+	}
+	if v := c.Query("start"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			return domain.LogQuery{}, errors.BadRequest("invalid start")
+		}
+		start = t
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			return domain.LogQuery{}, errors.BadRequest("invalid end")
+		}
+		end = t
+	}
+
+	span := end.Sub(start)
+	if span <= 0 {
+		return domain.LogQuery{}, errors.BadRequest("start must be before end")
+	}
+	if span > maxLogSearchRange {
+		return domain.LogQuery{}, errors.BadRequest("range must not exceed 30 days")
+	}
+
+	labels := make(map[string]string)
+	for _, l := range c.QueryArray("label") {
+		name, value, ok := strings.Cut(l, "=")
+		if !ok || name == "" {
+			return domain.LogQuery{}, errors.BadRequest("label filters must be name=value")
+		}
+		labels[name] = value
+	}
+
+	direction := c.DefaultQuery("direction", "backward")
+	if direction != "backward" && direction != "forward" {
+		return domain.LogQuery{}, errors.BadRequest("direction must be backward or forward")
+	}
+
+	return domain.LogQuery{
+		Start:     start,
+		End:       end,
+		Regex:     c.Query("regex"),
+		Labels:    labels,
+		Limit:     parseIntQuery(c, "limit", 0),
+		Direction: direction,
+	}, nil
+}
+
+// parseTimeParam accepts RFC3339 or unix seconds
+func parseTimeParam(v string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	defaultMetricsRange = time.Hour
+	maxMetricsRange     = 30 * 24 * time.Hour
+	// metricsResolution is the target number of points per series
+	metricsResolution = 300
+)
+
+// MetricsHandler serves time series for dashboard charts
+type MetricsHandler struct {
+	collector   domain.MetricsCollector
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewMetricsHandler creates a new MetricsHandler
+func NewMetricsHandler(collector domain.MetricsCollector, serviceRepo domain.ServiceRepository, log *logger.Logger) *MetricsHandler {
+	return &MetricsHandler{
+		collector:   collector,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// GetServiceMetrics handles GET /services/:id/metrics
+// Query: range (e.g. 6h) or start/end (unix seconds), optional step (seconds)
+func (h *MetricsHandler) GetServiceMetrics(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	timeRange, err := parseTimeRange(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	metrics, err := h.collector.GetServiceMetrics(c.Request.Context(), id, timeRange)
+	if err != nil {
+		h.logger.Error().Err(err).Str("service_id", id.String()).Msg("Failed to query service metrics")
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// parseTimeRange reads range/start/end/step query parameters
+func parseTimeRange(c *gin.Context) (domain.TimeRange, error) {
+	end := time.Now()
+	start := end.Add(-defaultMetricsRange)
+
+	if r := c.Query("range"); r != "" {
+		d, err := time.ParseDuration(r)
+		if err != nil || d <= 0 {
+			return domain.TimeRange{}, errors.BadRequest("invalid range")
+		}
+		start = end.Add(-d)
+	}
+	if s := c.Query("start"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return domain.TimeRange{}, errors.BadRequest("invalid start")
+		}
+		start = time.Unix(v, 0)
+	}
+	if e := c.Query("end"); e != "" {
+		v, err := strconv.ParseInt(e, 10, 64)
+		if err != nil {
+			return domain.TimeRange{}, errors.BadRequest("invalid end")
+		}
+		end = time.Unix(v, 0)
+	}
+
+	span := end.Sub(start)
+	if span <= 0 {
+		return domain.TimeRange{}, errors.BadRequest("start must be before end")
+	}
+	if span > maxMetricsRange {
+		return domain.TimeRange{}, errors.BadRequest("range must not exceed 30 days")
+	}
+
+	step := int64(parseIntQuery(c, "step", 0))
+	if step <= 0 {
+		step = int64(span.Seconds()) / metricsResolution
+		if step < 15 {
+			step = 15
+		}
+	}
+
+	return domain.TimeRange{
+		Start: start.Unix(),
+		End:   end.Unix(),
+		Step:  step,
+	}, nil
+}
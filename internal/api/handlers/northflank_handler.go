@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/adapters/anticorruption"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// northflankMaxPerPage caps the per_page query parameter of list endpoints
+const northflankMaxPerPage = 100
+
+// NorthflankHandler serves a Northflank-compatible façade under /northflank/v1
+// so that CLIs and scripts written against Northflank keep working during a
+// migration. Bodies use the legacy DTOs of the anticorruption package, wrapped
+// in Northflank's {"data": ...} envelope.
+type NorthflankHandler struct {
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	secretRepo  domain.SecretRepository
+	projects    *anticorruption.ProjectTranslator
+	services    *anticorruption.ServiceTranslator
+	secrets     *anticorruption.SecretTranslator
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewNorthflankHandler creates a new NorthflankHandler. secretRepo may be nil,
+// in which case the secret endpoints answer 501.
+func NewNorthflankHandler(
+	projectRepo domain.ProjectRepository,
+	serviceRepo domain.ServiceRepository,
+	secretRepo domain.SecretRepository,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *NorthflankHandler {
+	return &NorthflankHandler{
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		secretRepo:  secretRepo,
+		projects:    anticorruption.NewProjectTranslator(),
+		services:    anticorruption.NewServiceTranslator(),
+		secrets:     anticorruption.NewSecretTranslator(),
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// northflankPagination is the pagination block of Northflank list responses
+type northflankPagination struct {
+	HasNextPage bool `json:"hasNextPage"`
+	Count       int  `json:"count"`
+}
+
+// ListProjects handles GET /northflank/v1/projects
+func (h *NorthflankHandler) ListProjects(c *gin.Context) {
+	limit, offset := northflankPage(c)
+	projects, err := h.projectRepo.List(c.Request.Context(), domain.ProjectFilter{Limit: limit + 1, Offset: offset})
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	hasNext := len(projects) > limit
+	if hasNext {
+		projects = projects[:limit]
+	}
+	dtos := make([]*anticorruption.LegacyProjectDTO, len(projects))
+	for i, p := range projects {
+		dtos[i] = h.projects.ToLegacy(p)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       gin.H{"projects": dtos},
+		"pagination": northflankPagination{HasNextPage: hasNext, Count: len(dtos)},
+	})
+}
+
+// CreateProject handles POST /northflank/v1/projects
+func (h *NorthflankHandler) CreateProject(c *gin.Context) {
+	var req anticorruption.LegacyProjectDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondNorthflankError(c, bindError(err))
+		return
+	}
+	if req.ProjectName == "" {
+		respondNorthflankError(c, errors.Validation([]errors.FieldError{{Field: "name", Rule: "required", Message: "is required"}}))
+		return
+	}
+
+	userID, ok := c.Value("user_id").(uuid.UUID)
+	if !ok {
+		respondNorthflankError(c, errors.Unauthorized("user not authenticated"))
+		return
+	}
+
+	project, err := h.projects.FromLegacy(&req)
+	if err != nil {
+		respondNorthflankError(c, errors.BadRequest(err.Error()))
+		return
+	}
+	now := time.Now()
+	project.OwnerID = userID
+	project.CreatedAt = now
+	project.UpdatedAt = now
+
+	ctx := c.Request.Context()
+	if err := h.projectRepo.Create(ctx, project); err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(ctx, "project.created", &domain.Event{
+		Type:   "project.created",
+		Source: "api",
+		Data: map[string]interface{}{
+			"project_id": project.ID.String(),
+			"name":       project.Name,
+			"owner_id":   project.OwnerID.String(),
+		},
+	})
+
+	h.logger.Info().
+		Str("project_id", project.ID.String()).
+		Str("slug", project.Slug).
+		Msg("Project created through Northflank API")
+
+	c.JSON(http.StatusCreated, gin.H{"data": h.projects.ToLegacy(project)})
+}
+
+// GetProject handles GET /northflank/v1/projects/:projectId
+func (h *NorthflankHandler) GetProject(c *gin.Context) {
+	project, err := h.project(c.Request.Context(), c.Param("projectId"))
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.projects.ToLegacy(project)})
+}
+
+// DeleteProject handles DELETE /northflank/v1/projects/:projectId
+func (h *NorthflankHandler) DeleteProject(c *gin.Context) {
+	ctx := c.Request.Context()
+	project, err := h.project(ctx, c.Param("projectId"))
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	if err := h.projectRepo.Delete(ctx, project.ID); err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(ctx, "project.deleted", &domain.Event{
+		Type:   "project.deleted",
+		Source: "api",
+		Data: map[string]interface{}{
+			"project_id": project.ID.String(),
+			"name":       project.Name,
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{}})
+}
+
+// ListServices handles GET /northflank/v1/projects/:projectId/services
+func (h *NorthflankHandler) ListServices(c *gin.Context) {
+	ctx := c.Request.Context()
+	project, err := h.project(ctx, c.Param("projectId"))
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	limit, offset := northflankPage(c)
+	services, err := h.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{Limit: limit + 1, Offset: offset})
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	hasNext := len(services) > limit
+	if hasNext {
+		services = services[:limit]
+	}
+	dtos := make([]*anticorruption.LegacyServiceDTO, len(services))
+	for i, s := range services {
+		dtos[i] = h.services.ToLegacy(s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       gin.H{"services": dtos},
+		"pagination": northflankPagination{HasNextPage: hasNext, Count: len(dtos)},
+	})
+}
+
+// CreateService handles POST /northflank/v1/projects/:projectId/services and
+// the kind-specific POST .../services/:kind routes, e.g. services/combined
+func (h *NorthflankHandler) CreateService(c *gin.Context) {
+	var req anticorruption.LegacyServiceDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondNorthflankError(c, bindError(err))
+		return
+	}
+	if req.Name == "" {
+		respondNorthflankError(c, errors.Validation([]errors.FieldError{{Field: "name", Rule: "required", Message: "is required"}}))
+		return
+	}
+	if kind := c.Param("kind"); kind != "" {
+		req.Type = kind
+	}
+
+	ctx := c.Request.Context()
+	project, err := h.project(ctx, c.Param("projectId"))
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	service, err := h.services.FromLegacy(&req)
+	if err != nil {
+		respondNorthflankError(c, errors.BadRequest(err.Error()))
+		return
+	}
+	service.ProjectID = project.ID
+	service.Status = domain.ServiceStatusPending
+
+	if err := h.serviceRepo.Create(ctx, service); err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(ctx, "service.created", &domain.Event{
+		Type:   "service.created",
+		Source: "api",
+		Data: map[string]interface{}{
+			"service_id": service.ID.String(),
+			"project_id": project.ID.String(),
+			"name":       service.Name,
+			"type":       string(service.Type),
+		},
+	})
+
+	h.logger.Info().
+		Str("service_id", service.ID.String()).
+		Str("project_id", project.ID.String()).
+		Msg("Service created through Northflank API")
+
+	c.JSON(http.StatusCreated, gin.H{"data": h.services.ToLegacy(service)})
+}
+
+// GetService handles GET /northflank/v1/projects/:projectId/services/:serviceId
+func (h *NorthflankHandler) GetService(c *gin.Context) {
+	service, err := h.service(c)
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.services.ToLegacy(service)})
+}
+
+// DeleteService handles DELETE /northflank/v1/projects/:projectId/services/:serviceId
+func (h *NorthflankHandler) DeleteService(c *gin.Context) {
+	service, err := h.service(c)
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.serviceRepo.Delete(ctx, service.ID); err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(ctx, "service.deleted", &domain.Event{
+		Type:   "service.deleted",
+		Source: "api",
+		Data: map[string]interface{}{
+			"service_id": service.ID.String(),
+			"project_id": service.ProjectID.String(),
+			"name":       service.Name,
+		},
+	})
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{}})
+}
+
+// ListSecrets handles GET /northflank/v1/projects/:projectId/secrets
+func (h *NorthflankHandler) ListSecrets(c *gin.Context) {
+	if h.secretRepo == nil {
+		respondNorthflankError(c, errors.NotImplemented("secrets are not configured"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	project, err := h.project(ctx, c.Param("projectId"))
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	secrets, err := h.secretRepo.ListByProject(ctx, project.ID)
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	dtos := make([]*anticorruption.LegacySecretDTO, len(secrets))
+	for i, s := range secrets {
+		dtos[i] = h.secrets.ToLegacy(s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       gin.H{"secrets": dtos},
+		"pagination": northflankPagination{Count: len(dtos)},
+	})
+}
+
+// CreateSecret handles POST /northflank/v1/projects/:projectId/secrets. The
+// secret must already be in Vault; the body references it by vaultPath.
+func (h *NorthflankHandler) CreateSecret(c *gin.Context) {
+	if h.secretRepo == nil {
+		respondNorthflankError(c, errors.NotImplemented("secrets are not configured"))
+		return
+	}
+
+	var req anticorruption.LegacySecretDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondNorthflankError(c, bindError(err))
+		return
+	}
+	if req.Name == "" {
+		respondNorthflankError(c, errors.Validation([]errors.FieldError{{Field: "name", Rule: "required", Message: "is required"}}))
+		return
+	}
+
+	ctx := c.Request.Context()
+	project, err := h.project(ctx, c.Param("projectId"))
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	secret, err := h.secrets.FromLegacy(&req)
+	if err != nil {
+		respondNorthflankError(c, errors.Validation([]errors.FieldError{{Field: "vaultPath", Rule: "required", Message: err.Error()}}))
+		return
+	}
+	secret.ProjectID = project.ID
+
+	if err := h.secretRepo.Create(ctx, secret); err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("secret_id", secret.ID.String()).
+		Str("name", secret.Name).
+		Msg("Secret registered through Northflank API")
+
+	c.JSON(http.StatusCreated, gin.H{"data": h.secrets.ToLegacy(secret)})
+}
+
+// GetSecret handles GET /northflank/v1/projects/:projectId/secrets/:secretId
+func (h *NorthflankHandler) GetSecret(c *gin.Context) {
+	secret, err := h.secret(c)
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": h.secrets.ToLegacy(secret)})
+}
+
+// DeleteSecret handles DELETE /northflank/v1/projects/:projectId/secrets/:secretId.
+// The value stays in Vault.
+func (h *NorthflankHandler) DeleteSecret(c *gin.Context) {
+	secret, err := h.secret(c)
+	if err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	if err := h.secretRepo.Delete(c.Request.Context(), secret.ID); err != nil {
+		respondNorthflankError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{}})
+}
+
+// project resolves a Northflank project reference, which is either an ID or,
+// as Northflank's own IDs are, a slug
+func (h *NorthflankHandler) project(ctx context.Context, ref string) (*domain.Project, error) {
+	if id, err := uuid.Parse(ref); err == nil {
+		return h.projectRepo.GetByID(ctx, id)
+	}
+	return h.projectRepo.GetBySlug(ctx, ref)
+}
+
+// service resolves the service reference of a request within its project
+func (h *NorthflankHandler) service(c *gin.Context) (*domain.Service, error) {
+	ctx := c.Request.Context()
+	project, err := h.project(ctx, c.Param("projectId"))
+	if err != nil {
+		return nil, err
+	}
+
+	ref := c.Param("serviceId")
+	if id, err := uuid.Parse(ref); err == nil {
+		service, err := h.serviceRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if service.ProjectID != project.ID {
+			return nil, errors.NotFound("service", ref)
+		}
+		return service, nil
+	}
+	return h.serviceRepo.GetBySlug(ctx, project.ID, ref)
+}
+
+// secret resolves the secret reference of a request within its project
+func (h *NorthflankHandler) secret(c *gin.Context) (*domain.Secret, error) {
+	if h.secretRepo == nil {
+		return nil, errors.NotImplemented("secrets are not configured")
+	}
+
+	ctx := c.Request.Context()
+	project, err := h.project(ctx, c.Param("projectId"))
+	if err != nil {
+		return nil, err
+	}
+
+	ref := c.Param("secretId")
+	if id, err := uuid.Parse(ref); err == nil {
+		secret, err := h.secretRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if secret.ProjectID != project.ID {
+			return nil, errors.NotFound("secret", ref)
+		}
+		return secret, nil
+	}
+	return h.secretRepo.GetByName(ctx, project.ID, ref)
+}
+
+// northflankPage reads Northflank's 1-based page and per_page parameters
+func northflankPage(c *gin.Context) (limit, offset int) {
+	limit = parseIntQuery(c, "per_page", 50)
+	if limit <= 0 || limit > northflankMaxPerPage {
+		limit = northflankMaxPerPage
+	}
+	page := parseIntQuery(c, "page", 1)
+	if page < 1 {
+		page = 1
+	}
+	return limit, (page - 1) * limit
+}
+
+// respondNorthflankError sends an error in Northflank's
+// {"error": {"status", "message"}} shape, carrying the stable code as well
+func respondNorthflankError(c *gin.Context, err error) {
+	problem := errors.NewProblem(err, c.Request.URL.Path)
+	body := gin.H{
+		"status":  problem.Status,
+		"message": problem.Detail,
+		"code":    problem.Code,
+	}
+	if len(problem.Errors) > 0 {
+		body["details"] = problem.Errors
+	}
+	c.JSON(problem.Status, gin.H{"error": body})
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/notifications"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// notificationKeepAlive is how often an idle notification stream sends a comment
+// so that proxies do not close it
+const notificationKeepAlive = 30 * time.Second
+
+// NotificationHandler handles the authenticated user's notification inbox
+type NotificationHandler struct {
+	center *notifications.Center
+	logger *logger.Logger
+}
+
+// NewNotificationHandler creates a new NotificationHandler
+func NewNotificationHandler(center *notifications.Center, log *logger.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		center: center,
+		logger: log,
+	}
+}
+
+// List handles GET /notifications
+// Query: unread (only unread notifications), limit (default 20, max 100), offset or cursor
+func (h *NotificationHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := parseIntQuery(c, "offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	list, unread, err := h.center.List(c.Request.Context(), userID, domain.NotificationFilter{
+		UnreadOnly: parseBoolQuery(c, "unread", false),
+		Limit:      limit,
+		Offset:     offset,
+		After:      after,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":         list,
+		"count":        len(list),
+		"offset":       offset,
+		"limit":        limit,
+		"unread_count": unread,
+		"next_cursor":  nextCursor(list, limit, notificationCursor),
+	})
+}
+
+// UnreadCount handles GET /notifications/unread-count
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	unread, err := h.center.UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread_count": unread})
+}
+
+// MarkRead handles POST /notifications/:id/read
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid notification ID"))
+		return
+	}
+
+	if err := h.center.MarkRead(c.Request.Context(), userID, id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// MarkAllRead handles POST /notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	n, err := h.center.MarkAllRead(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": n})
+}
+
+// Stream handles GET /notifications/stream
+// The response is an SSE stream that starts with a "read" event carrying the
+// unread count, followed by "notification" events for new notifications and
+// "read" events when notifications are marked read in another session.
+func (h *NotificationHandler) Stream(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	// Subscribe before counting so that nothing created in between is missed
+	messages, cancel := h.center.Subscribe(userID)
+	defer cancel()
+
+	unread, err := h.center.UnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.SSEvent(notifications.EventRead, notifications.ReadUpdate{UnreadCount: unread})
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(notificationKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case msg := <-messages:
+			c.SSEvent(msg.Event, msg.Data)
+			c.Writer.Flush()
+		case <-keepAlive.C:
+			if _, err := c.Writer.WriteString(": keep-alive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+// currentUserID returns the authenticated user's ID, responding with an error if there is none
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get("user_id")
+	userID, ok := value.(uuid.UUID)
+	if !exists || !ok {
+		respondError(c, errors.Unauthorized("user not authenticated"))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/notifications"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// NotificationPreferenceHandler handles the notification preferences of
+// projects and of the authenticated user
+type NotificationPreferenceHandler struct {
+	repo        domain.NotificationPreferenceRepository
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewNotificationPreferenceHandler creates a new NotificationPreferenceHandler
+func NewNotificationPreferenceHandler(repo domain.NotificationPreferenceRepository, projectRepo domain.ProjectRepository, log *logger.Logger) *NotificationPreferenceHandler {
+	return &NotificationPreferenceHandler{
+		repo:        repo,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// CreateNotificationPreferenceRequest represents the request body for a
+// notification preference. ProjectID only applies to the user's own
+// preferences, narrowing them to one project.
+type CreateNotificationPreferenceRequest struct {
+	Channel    string              `json:"channel" binding:"required"`
+	ProjectID  *uuid.UUID          `json:"project_id,omitempty"`
+	Events     []string            `json:"events,omitempty"`
+	Severities []string            `json:"severities,omitempty"`
+	Muted      bool                `json:"muted,omitempty"`
+	Mute       []domain.MuteWindow `json:"mute,omitempty"`
+}
+
+// UpdateNotificationPreferenceRequest represents the request body for
+// changing what a preference lets through; its scope and channel are fixed
+type UpdateNotificationPreferenceRequest struct {
+	Events     []string            `json:"events,omitempty"`
+	Severities []string            `json:"severities,omitempty"`
+	Muted      *bool               `json:"muted,omitempty"`
+	Mute       []domain.MuteWindow `json:"mute,omitempty"`
+}
+
+// CreateForProject handles POST /projects/:project_id/notification-preferences
+func (h *NotificationPreferenceHandler) CreateForProject(c *gin.Context) {
+	var req CreateNotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+	if _, err := h.projectRepo.GetByID(c.Request.Context(), projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.create(c, nil, &projectID, &req)
+}
+
+// ListByProject handles GET /projects/:project_id/notification-preferences
+func (h *NotificationPreferenceHandler) ListByProject(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.projectRepo.GetByID(ctx, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	preferences, err := h.repo.ListByProject(ctx, projectID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  preferences,
+		"count": len(preferences),
+	})
+}
+
+// CreateForUser handles POST /users/me/notification-preferences
+func (h *NotificationPreferenceHandler) CreateForUser(c *gin.Context) {
+	var req CreateNotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	if req.ProjectID != nil {
+		if _, err := h.projectRepo.GetByID(c.Request.Context(), *req.ProjectID); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	h.create(c, &userID, req.ProjectID, &req)
+}
+
+// ListByUser handles GET /users/me/notification-preferences
+func (h *NotificationPreferenceHandler) ListByUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	preferences, err := h.repo.ListByUser(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  preferences,
+		"count": len(preferences),
+	})
+}
+
+// Get handles GET /notification-preferences/:id
+func (h *NotificationPreferenceHandler) Get(c *gin.Context) {
+	preference, ok := h.preference(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, preference)
+}
+
+// Update handles PATCH /notification-preferences/:id
+func (h *NotificationPreferenceHandler) Update(c *gin.Context) {
+	var req UpdateNotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	preference, ok := h.preference(c)
+	if !ok {
+		return
+	}
+
+	if req.Events != nil {
+		preference.Events = req.Events
+	}
+	if req.Severities != nil {
+		preference.Severities = req.Severities
+	}
+	if req.Muted != nil {
+		preference.Muted = *req.Muted
+	}
+	if req.Mute != nil {
+		preference.Mute = req.Mute
+	}
+	if err := notifications.ValidatePreference(preference); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	preference.UpdatedAt = time.Now()
+	if err := h.repo.Update(c.Request.Context(), preference); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, preference)
+}
+
+// Delete handles DELETE /notification-preferences/:id
+func (h *NotificationPreferenceHandler) Delete(c *gin.Context) {
+	preference, ok := h.preference(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), preference.ID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().Str("preference_id", preference.ID.String()).Msg("Notification preference deleted")
+	c.Status(http.StatusNoContent)
+}
+
+func (h *NotificationPreferenceHandler) create(c *gin.Context, userID, projectID *uuid.UUID, req *CreateNotificationPreferenceRequest) {
+	now := time.Now()
+	preference := &domain.NotificationPreference{
+		ID:         uuid.New(),
+		UserID:     userID,
+		ProjectID:  projectID,
+		Channel:    req.Channel,
+		Events:     req.Events,
+		Severities: req.Severities,
+		Muted:      req.Muted,
+		Mute:       req.Mute,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := notifications.ValidatePreference(preference); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.repo.Create(c.Request.Context(), preference); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("preference_id", preference.ID.String()).
+		Str("channel", preference.Channel).
+		Msg("Notification preference created")
+
+	c.JSON(http.StatusCreated, preference)
+}
+
+// preference loads the preference named in the path. Users only see their
+// own preferences; those of other users are reported as not found.
+func (h *NotificationPreferenceHandler) preference(c *gin.Context) (*domain.NotificationPreference, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid notification preference ID"))
+		return nil, false
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return nil, false
+	}
+
+	preference, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	if preference.UserID != nil && *preference.UserID != userID {
+		respondError(c, errors.NotFound("notification preference", id.String()))
+		return nil, false
+	}
+	return preference, true
+}
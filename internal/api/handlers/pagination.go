@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// parseCursor reads the cursor query parameter of a list endpoint. A cursor
+// continues from the page it was returned with, so it cannot be combined
+// with an offset.
+func parseCursor(c *gin.Context) (*domain.Cursor, bool) {
+	v := c.Query("cursor")
+	if v == "" {
+		return nil, true
+	}
+
+	if c.Query("offset") != "" {
+		respondError(c, errors.BadRequest("cursor and offset cannot be combined"))
+		return nil, false
+	}
+
+	cursor, err := domain.DecodeCursor(v)
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid cursor"))
+		return nil, false
+	}
+
+	return cursor, true
+}
+
+// pageLimit reads the limit query parameter of a list endpoint, 50 by
+// default and at most 100
+func pageLimit(c *gin.Context) (int, bool) {
+	limit := parseIntQuery(c, "limit", 50)
+	if limit < 1 || limit > 100 {
+		respondError(c, errors.BadRequest("limit must be between 1 and 100"))
+		return 0, false
+	}
+	return limit, true
+}
+
+// nextCursor returns the cursor of the page following items, or nil when
+// items did not fill the page and so was the last one
+func nextCursor[T any](items []T, limit int, position func(T) domain.Cursor) *string {
+	if limit <= 0 || len(items) < limit {
+		return nil
+	}
+
+	next := position(items[len(items)-1]).Encode()
+	return &next
+}
+
+func projectCursor(p *domain.Project) domain.Cursor {
+	return domain.Cursor{CreatedAt: p.CreatedAt, ID: p.ID}
+}
+
+func serviceCursor(s *domain.Service) domain.Cursor {
+	return domain.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+}
+
+func buildCursor(b *domain.Build) domain.Cursor {
+	return domain.Cursor{CreatedAt: b.CreatedAt, ID: b.ID}
+}
+
+func deploymentCursor(d *domain.Deployment) domain.Cursor {
+	return domain.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+}
+
+func auditLogCursor(l *domain.AuditLog) domain.Cursor {
+	return domain.Cursor{CreatedAt: l.CreatedAt, ID: l.ID}
+}
+
+func clusterCursor(c *domain.Cluster) domain.Cursor {
+	return domain.Cursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+func environmentCursor(e *domain.Environment) domain.Cursor {
+	return domain.Cursor{CreatedAt: e.CreatedAt, ID: e.ID}
+}
+
+func ingressCursor(i *domain.Ingress) domain.Cursor {
+	return domain.Cursor{CreatedAt: i.CreatedAt, ID: i.ID}
+}
+
+func secretCursor(s *domain.Secret) domain.Cursor {
+	return domain.Cursor{CreatedAt: s.CreatedAt, ID: s.ID}
+}
+
+func domainCursor(d *domain.CustomDomain) domain.Cursor {
+	return domain.Cursor{CreatedAt: d.CreatedAt, ID: d.ID}
+}
+
+func webhookCursor(w *domain.WebhookSubscription) domain.Cursor {
+	return domain.Cursor{CreatedAt: w.CreatedAt, ID: w.ID}
+}
+
+func artifactCursor(a *domain.Artifact) domain.Cursor {
+	return domain.Cursor{CreatedAt: a.CreatedAt, ID: a.ID}
+}
+
+func notificationCursor(n *domain.UserNotification) domain.Cursor {
+	return domain.Cursor{CreatedAt: n.CreatedAt, ID: n.ID}
+}
+
+func approvalCursor(a *domain.Approval) domain.Cursor {
+	return domain.Cursor{CreatedAt: a.CreatedAt, ID: a.ID}
+}
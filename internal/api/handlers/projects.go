@@ -8,62 +8,69 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/residency"
 	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
 )
 
 // ProjectHandler handles project-related HTTP requests
 type ProjectHandler struct {
-	repo     domain.ProjectRepository
-	eventBus domain.EventBus
-	logger   *logger.Logger
+	repo      domain.ProjectRepository
+	residency *residency.Checker
+	eventBus  domain.EventBus
+	logger    *logger.Logger
 }
 
 // NewProjectHandler creates a new ProjectHandler
-func NewProjectHandler(repo domain.ProjectRepository, eventBus domain.EventBus, log *logger.Logger) *ProjectHandler {
+func NewProjectHandler(repo domain.ProjectRepository, residency *residency.Checker, eventBus domain.EventBus, log *logger.Logger) *ProjectHandler {
 	return &ProjectHandler{
-		repo:     repo,
-		eventBus: eventBus,
-		logger:   log,
+		repo:      repo,
+		residency: residency,
+		eventBus:  eventBus,
+		logger:    log,
 	}
 }
 
 // CreateProjectRequest represents the request body for creating a project
 type CreateProjectRequest struct {
-	Name        string            `json:"name" binding:"required,min=1,max=255"`
-	Slug        string            `json:"slug" binding:"required,min=1,max=255,alphanum"`
-	Description string            `json:"description,omitempty"`
-	TeamID      *uuid.UUID        `json:"team_id,omitempty"`
-	Labels      map[string]string `json:"labels,omitempty"`
+	Name          string            `json:"name" binding:"required,min=1,max=255"`
+	Slug          string            `json:"slug" binding:"required,min=1,max=255,alphanum"`
+	Description   string            `json:"description,omitempty"`
+	TeamID        *uuid.UUID        `json:"team_id,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	DataResidency string            `json:"data_residency,omitempty"` // Residency region, e.g. eu
 }
 
 // UpdateProjectRequest represents the request body for updating a project
 type UpdateProjectRequest struct {
-	Name        *string           `json:"name,omitempty"`
-	Description *string           `json:"description,omitempty"`
-	TeamID      *uuid.UUID        `json:"team_id,omitempty"`
-	Labels      map[string]string `json:"labels,omitempty"`
+	Name          *string           `json:"name,omitempty"`
+	Description   *string           `json:"description,omitempty"`
+	TeamID        *uuid.UUID        `json:"team_id,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	DataResidency *string           `json:"data_residency,omitempty"` // Empty clears the residency
 }
 
 // ProjectResponse represents the response body for a project
 type ProjectResponse struct {
-	ID          uuid.UUID         `json:"id"`
-	Name        string            `json:"name"`
-	Slug        string            `json:"slug"`
-	Description string            `json:"description,omitempty"`
-	Status      string            `json:"status"`
-	OwnerID     uuid.UUID         `json:"owner_id"`
-	TeamID      *uuid.UUID        `json:"team_id,omitempty"`
-	Labels      map[string]string `json:"labels,omitempty"`
-	CreatedAt   time.Time         `json:"created_at"`
-	UpdatedAt   time.Time         `json:"updated_at"`
+	ID            uuid.UUID             `json:"id"`
+	Name          string                `json:"name"`
+	Slug          string                `json:"slug"`
+	Description   string                `json:"description,omitempty"`
+	Status        string                `json:"status"`
+	OwnerID       uuid.UUID             `json:"owner_id"`
+	TeamID        *uuid.UUID            `json:"team_id,omitempty"`
+	Labels        map[string]string     `json:"labels,omitempty"`
+	DataResidency string                `json:"data_residency,omitempty"`
+	Slack         *domain.SlackSettings `json:"slack,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
 }
 
 // Create handles POST /projects
 func (h *ProjectHandler) Create(c *gin.Context) {
 	var req CreateProjectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, errors.BadRequest(err.Error()))
+		respondError(c, bindError(err))
 		return
 	}
 
@@ -74,17 +81,23 @@ func (h *ProjectHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if err := h.residency.Validate(req.DataResidency); err != nil {
+		respondError(c, err)
+		return
+	}
+
 	project := &domain.Project{
-		ID:          uuid.New(),
-		Name:        req.Name,
-		Slug:        req.Slug,
-		Description: req.Description,
-		Status:      domain.ProjectStatusActive,
-		OwnerID:     userID.(uuid.UUID),
-		TeamID:      req.TeamID,
-		Labels:      req.Labels,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:            uuid.New(),
+		Name:          req.Name,
+		Slug:          req.Slug,
+		Description:   req.Description,
+		Status:        domain.ProjectStatusActive,
+		OwnerID:       userID.(uuid.UUID),
+		TeamID:        req.TeamID,
+		Labels:        req.Labels,
+		DataResidency: req.DataResidency,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}
 
 	if err := h.repo.Create(c.Request.Context(), project); err != nil {
@@ -169,6 +182,18 @@ func (h *ProjectHandler) List(c *gin.Context) {
 	filter.Limit = parseIntQuery(c, "limit", 50)
 	filter.Offset = parseIntQuery(c, "offset", 0)
 
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+	filter.After = after
+
+	labels, ok := parseLabelSelector(c)
+	if !ok {
+		return
+	}
+	filter.Labels = labels
+
 	projects, err := h.repo.List(c.Request.Context(), filter)
 	if err != nil {
 		respondError(c, err)
@@ -181,10 +206,11 @@ func (h *ProjectHandler) List(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":   responses,
-		"count":  len(responses),
-		"offset": filter.Offset,
-		"limit":  filter.Limit,
+		"data":        responses,
+		"count":       len(responses),
+		"offset":      filter.Offset,
+		"limit":       filter.Limit,
+		"next_cursor": nextCursor(projects, filter.Limit, projectCursor),
 	})
 }
 
@@ -199,7 +225,7 @@ func (h *ProjectHandler) Update(c *gin.Context) {
 
 	var req UpdateProjectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, errors.BadRequest(err.Error()))
+		respondError(c, bindError(err))
 		return
 	}
 
@@ -222,12 +248,34 @@ func (h *ProjectHandler) Update(c *gin.Context) {
 	if req.Labels != nil {
 		project.Labels = req.Labels
 	}
+	if req.DataResidency != nil && *req.DataResidency != project.DataResidency {
+		project.DataResidency = *req.DataResidency
+		if err := h.residency.Validate(project.DataResidency); err != nil {
+			respondError(c, err)
+			return
+		}
+		// Declaring a residency must not leave existing placements in breach
+		if err := h.residency.CheckProject(c.Request.Context(), project); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
 
 	if err := h.repo.Update(c.Request.Context(), project); err != nil {
 		respondError(c, err)
 		return
 	}
 
+	// Caches of the project's team, such as its event tenant, are evicted on this
+	h.eventBus.Publish(c.Request.Context(), "project.updated", &domain.Event{
+		Type:   "project.updated",
+		Source: "api",
+		Data: map[string]interface{}{
+			"project_id": project.ID.String(),
+			"name":       project.Name,
+		},
+	})
+
 	h.logger.Info().
 		Str("project_id", project.ID.String()).
 		Msg("Project updated")
@@ -275,15 +323,17 @@ func (h *ProjectHandler) Delete(c *gin.Context) {
 
 func projectToResponse(p *domain.Project) ProjectResponse {
 	return ProjectResponse{
-		ID:          p.ID,
-		Name:        p.Name,
-		Slug:        p.Slug,
-		Description: p.Description,
-		Status:      string(p.Status),
-		OwnerID:     p.OwnerID,
-		TeamID:      p.TeamID,
-		Labels:      p.Labels,
-		CreatedAt:   p.CreatedAt,
-		UpdatedAt:   p.UpdatedAt,
+		ID:            p.ID,
+		Name:          p.Name,
+		Slug:          p.Slug,
+		Description:   p.Description,
+		Status:        string(p.Status),
+		OwnerID:       p.OwnerID,
+		TeamID:        p.TeamID,
+		Labels:        p.Labels,
+		DataResidency: p.DataResidency,
+		Slack:         p.Slack,
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
 	}
 }
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/queuetime"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const defaultQueueTimeWindow = "7d"
+
+// QueueTimeHandler handles queue time reporting endpoints
+type QueueTimeHandler struct {
+	monitor     *queuetime.Monitor
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewQueueTimeHandler creates a new QueueTimeHandler
+func NewQueueTimeHandler(monitor *queuetime.Monitor, projectRepo domain.ProjectRepository, log *logger.Logger) *QueueTimeHandler {
+	return &QueueTimeHandler{
+		monitor:     monitor,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// Get handles GET /projects/:id/queue-times
+// Query: window (e.g. 1d, 2w, 72h; default 7d)
+func (h *QueueTimeHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	label := c.DefaultQuery("window", defaultQueueTimeWindow)
+	window, err := parseWindow(label)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if _, err := h.projectRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	report, err := h.monitor.ProjectReport(c.Request.Context(), id, window, label)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
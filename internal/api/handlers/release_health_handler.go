@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/releasehealth"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	defaultReleaseHealthLimit = 20
+	maxReleaseHealthLimit     = 100
+)
+
+// ReleaseHealthHandler handles release health endpoints
+type ReleaseHealthHandler struct {
+	scorer      *releasehealth.Scorer
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewReleaseHealthHandler creates a new ReleaseHealthHandler
+func NewReleaseHealthHandler(scorer *releasehealth.Scorer, serviceRepo domain.ServiceRepository, log *logger.Logger) *ReleaseHealthHandler {
+	return &ReleaseHealthHandler{
+		scorer:      scorer,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// Summary handles GET /services/:id/release-health
+// Query: limit (number of most recent deployments, default 20)
+func (h *ReleaseHealthHandler) Summary(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", defaultReleaseHealthLimit)
+	if limit <= 0 || limit > maxReleaseHealthLimit {
+		respondError(c, errors.BadRequest("limit must be between 1 and 100"))
+		return
+	}
+
+	summary, err := h.scorer.Summarize(c.Request.Context(), id, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
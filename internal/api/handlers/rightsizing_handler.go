@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/rightsizing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// RightsizingHandler handles resource right-sizing endpoints
+type RightsizingHandler struct {
+	advisor     *rightsizing.Advisor
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewRightsizingHandler creates a new RightsizingHandler
+func NewRightsizingHandler(advisor *rightsizing.Advisor, serviceRepo domain.ServiceRepository, log *logger.Logger) *RightsizingHandler {
+	return &RightsizingHandler{
+		advisor:     advisor,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// Get handles GET /services/:id/rightsizing
+func (h *RightsizingHandler) Get(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	rec, err := h.advisor.Recommend(c.Request.Context(), service)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rec)
+}
+
+// EnableVPA handles POST /services/:id/rightsizing/vpa
+func (h *RightsizingHandler) EnableVPA(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	if err := h.advisor.EnsureVPA(c.Request.Context(), service); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().Str("service_id", service.ID.String()).Msg("VPA recommender enabled")
+
+	c.JSON(http.StatusOK, gin.H{"message": "VPA recommender enabled"})
+}
+
+func (h *RightsizingHandler) loadService(c *gin.Context) (*domain.Service, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return nil, false
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return service, true
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/workflow"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// RolloutHandler handles deployment workflow status endpoints
+type RolloutHandler struct {
+	stateMachine *workflow.StateMachine
+	serviceRepo  domain.ServiceRepository
+	logger       *logger.Logger
+}
+
+// NewRolloutHandler creates a new RolloutHandler
+func NewRolloutHandler(stateMachine *workflow.StateMachine, serviceRepo domain.ServiceRepository, log *logger.Logger) *RolloutHandler {
+	return &RolloutHandler{
+		stateMachine: stateMachine,
+		serviceRepo:  serviceRepo,
+		logger:       log,
+	}
+}
+
+// RolloutResponse represents the status of a service's latest deployment workflow
+type RolloutResponse struct {
+	WorkflowID   uuid.UUID             `json:"workflow_id"`
+	State        string                `json:"state"`
+	Version      string                `json:"version,omitempty"`
+	Image        string                `json:"image,omitempty"`
+	BuildID      *uuid.UUID            `json:"build_id,omitempty"`
+	DeploymentID *uuid.UUID            `json:"deployment_id,omitempty"`
+	PrePull      *domain.PrePullStatus `json:"pre_pull,omitempty"`
+	Error        string                `json:"error,omitempty"`
+	StartedAt    time.Time             `json:"started_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+// Get handles GET /services/:id/rollout
+func (h *RolloutHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	wf, ok := h.stateMachine.LatestWorkflowByService(id)
+	if !ok {
+		respondError(c, errors.NotFound("rollout for service", id.String()))
+		return
+	}
+
+	c.JSON(http.StatusOK, RolloutResponse{
+		WorkflowID:   wf.ID,
+		State:        string(wf.State),
+		Version:      wf.Version,
+		Image:        wf.Image,
+		BuildID:      wf.BuildID,
+		DeploymentID: wf.DeploymentID,
+		PrePull:      wf.PrePull,
+		Error:        wf.Error,
+		StartedAt:    wf.StartedAt,
+		UpdatedAt:    wf.UpdatedAt,
+	})
+}
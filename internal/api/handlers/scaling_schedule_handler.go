@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/scheduledscaling"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// ScalingScheduleHandler handles time-based scaling schedule endpoints
+type ScalingScheduleHandler struct {
+	serviceRepo domain.ServiceRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewScalingScheduleHandler creates a new ScalingScheduleHandler
+func NewScalingScheduleHandler(serviceRepo domain.ServiceRepository, eventBus domain.EventBus, log *logger.Logger) *ScalingScheduleHandler {
+	return &ScalingScheduleHandler{
+		serviceRepo: serviceRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// ScalingSchedulesRequest represents the request body for replacing a service's schedules
+type ScalingSchedulesRequest struct {
+	Schedules []domain.ScalingSchedule `json:"schedules"`
+}
+
+// ScalingSchedulesResponse represents a service's schedules and the range currently in effect
+type ScalingSchedulesResponse struct {
+	Schedules   []domain.ScalingSchedule `json:"schedules"`
+	Active      string                   `json:"active,omitempty"`
+	MinReplicas int32                    `json:"min_replicas"`
+	MaxReplicas int32                    `json:"max_replicas"`
+}
+
+// Get handles GET /services/:id/scaling-schedules
+func (h *ScalingScheduleHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, schedulesToResponse(service.Scaling))
+}
+
+// Replace handles PUT /services/:id/scaling-schedules
+func (h *ScalingScheduleHandler) Replace(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	var req ScalingSchedulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	if err := scheduledscaling.Validate(req.Schedules); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	service.Scaling.Schedules = req.Schedules
+	if err := h.serviceRepo.Update(c.Request.Context(), service); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(c.Request.Context(), "service.updated", &domain.Event{
+		Type:   "service.updated",
+		Source: "api",
+		Data: map[string]interface{}{
+			"service_id": service.ID.String(),
+			"project_id": service.ProjectID.String(),
+		},
+	})
+
+	h.logger.Info().
+		Str("service_id", service.ID.String()).
+		Int("schedules", len(req.Schedules)).
+		Msg("Scaling schedules updated")
+
+	c.JSON(http.StatusOK, schedulesToResponse(service.Scaling))
+}
+
+func schedulesToResponse(scaling domain.ScalingConfig) ScalingSchedulesResponse {
+	min, max, active := scheduledscaling.Desired(scaling, time.Now())
+	resp := ScalingSchedulesResponse{
+		Schedules:   scaling.Schedules,
+		MinReplicas: min,
+		MaxReplicas: max,
+	}
+	if resp.Schedules == nil {
+		resp.Schedules = []domain.ScalingSchedule{}
+	}
+	if active != nil {
+		resp.Active = active.Name
+	}
+	return resp
+}
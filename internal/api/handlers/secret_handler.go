@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// SecretHandler handles project secret endpoints. Values live in Vault; the
+// platform only records where each secret is and which keys it holds.
+type SecretHandler struct {
+	secretRepo  domain.SecretRepository
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewSecretHandler creates a new SecretHandler
+func NewSecretHandler(secretRepo domain.SecretRepository, projectRepo domain.ProjectRepository, log *logger.Logger) *SecretHandler {
+	return &SecretHandler{
+		secretRepo:  secretRepo,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// CreateSecretRequest represents the request body for registering a Vault secret
+type CreateSecretRequest struct {
+	Name      string            `json:"name" binding:"required,hostname_rfc1123"`
+	Type      string            `json:"type" binding:"omitempty,oneof=opaque tls docker_config ssh_auth basic_auth"`
+	Keys      []string          `json:"keys,omitempty"`
+	VaultPath string            `json:"vault_path" binding:"required"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// Create handles POST /projects/:project_id/secrets
+func (h *SecretHandler) Create(c *gin.Context) {
+	var req CreateSecretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.projectRepo.GetByID(ctx, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	secretType := domain.SecretTypeOpaque
+	if req.Type != "" {
+		secretType = domain.SecretType(req.Type)
+	}
+
+	now := time.Now()
+	secret := &domain.Secret{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		Name:      strings.ToLower(req.Name),
+		Type:      secretType,
+		Keys:      req.Keys,
+		VaultPath: strings.Trim(req.VaultPath, "/"),
+		Version:   1,
+		Labels:    req.Labels,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.secretRepo.Create(ctx, secret); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("secret_id", secret.ID.String()).
+		Str("name", secret.Name).
+		Msg("Secret registered")
+
+	c.JSON(http.StatusCreated, secret)
+}
+
+// ListByProject handles GET /projects/:project_id/secrets
+func (h *SecretHandler) ListByProject(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.projectRepo.GetByID(ctx, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	secrets, err := h.secretRepo.ListByProjectAfter(ctx, projectID, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        secrets,
+		"count":       len(secrets),
+		"limit":       limit,
+		"next_cursor": nextCursor(secrets, limit, secretCursor),
+	})
+}
+
+// Get handles GET /secrets/:id
+func (h *SecretHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid secret ID"))
+		return
+	}
+
+	secret, err := h.secretRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, secret)
+}
+
+// Delete handles DELETE /secrets/:id. The value stays in Vault.
+func (h *SecretHandler) Delete(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid secret ID"))
+		return
+	}
+
+	if err := h.secretRepo.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().Str("secret_id", id.String()).Msg("Secret deleted")
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/secretsync"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// SecretReplicationHandler handles the replication of a service's secrets into its clusters
+type SecretReplicationHandler struct {
+	replicator  *secretsync.Replicator
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewSecretReplicationHandler creates a new SecretReplicationHandler
+func NewSecretReplicationHandler(replicator *secretsync.Replicator, serviceRepo domain.ServiceRepository, log *logger.Logger) *SecretReplicationHandler {
+	return &SecretReplicationHandler{
+		replicator:  replicator,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// Status handles GET /services/:id/secret-replication
+func (h *SecretReplicationHandler) Status(c *gin.Context) {
+	service, ok := h.service(c)
+	if !ok {
+		return
+	}
+
+	replications, err := h.replicator.Status(c.Request.Context(), service)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  replications,
+		"count": len(replications),
+	})
+}
+
+// Sync handles POST /services/:id/secret-replication/sync
+func (h *SecretReplicationHandler) Sync(c *gin.Context) {
+	service, ok := h.service(c)
+	if !ok {
+		return
+	}
+
+	replications, err := h.replicator.Reconcile(c.Request.Context(), service)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  replications,
+		"count": len(replications),
+	})
+}
+
+// Manifests handles GET /services/:id/secret-replication/manifests?cluster_id=
+func (h *SecretReplicationHandler) Manifests(c *gin.Context) {
+	clusterID, err := uuid.Parse(c.Query("cluster_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("cluster_id must be a cluster ID"))
+		return
+	}
+
+	service, ok := h.service(c)
+	if !ok {
+		return
+	}
+
+	manifests, err := h.replicator.Manifests(c.Request.Context(), service, clusterID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	doc, err := secretsync.MarshalYAML(manifests)
+	if err != nil {
+		respondError(c, errors.Wrap(err, "failed to encode manifests"))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/yaml", doc)
+}
+
+func (h *SecretReplicationHandler) service(c *gin.Context) (*domain.Service, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return nil, false
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return service, true
+}
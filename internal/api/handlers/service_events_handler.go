@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/kubeevents"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	defaultServiceEventsLimit = 50
+	maxServiceEventsLimit     = 200
+)
+
+// ServiceEventsHandler handles Kubernetes event endpoints for services
+type ServiceEventsHandler struct {
+	store       *kubeevents.Store
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewServiceEventsHandler creates a new ServiceEventsHandler
+func NewServiceEventsHandler(store *kubeevents.Store, serviceRepo domain.ServiceRepository, log *logger.Logger) *ServiceEventsHandler {
+	return &ServiceEventsHandler{
+		store:       store,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// List handles GET /services/:id/events
+// Query: category (oom_killed, failed_scheduling, image_pull, crash_loop, other), limit (default 50, max 200)
+func (h *ServiceEventsHandler) List(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	category := c.Query("category")
+	switch category {
+	case "", kubeevents.CategoryOOMKilled, kubeevents.CategoryFailedScheduling,
+		kubeevents.CategoryImagePull, kubeevents.CategoryCrashLoop, kubeevents.CategoryOther:
+	default:
+		respondError(c, errors.BadRequest("invalid category"))
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", defaultServiceEventsLimit)
+	if limit < 1 || limit > maxServiceEventsLimit {
+		respondError(c, errors.BadRequest("limit must be between 1 and 200"))
+		return
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	events := h.store.List(id, category, limit)
+	c.JSON(http.StatusOK, gin.H{
+		"events": events,
+		"total":  len(events),
+	})
+}
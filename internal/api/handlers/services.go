@@ -1,12 +1,22 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/availability"
+	"github.com/northstack/platform/internal/catalog"
+	"github.com/northstack/platform/internal/cronjobs"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/dualstack"
+	"github.com/northstack/platform/internal/internalnet"
+	"github.com/northstack/platform/internal/keda"
+	"github.com/northstack/platform/internal/placement"
+	"github.com/northstack/platform/internal/warmpool"
 	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
 )
@@ -16,15 +26,28 @@ type ServiceHandler struct {
 	serviceRepo domain.ServiceRepository
 	projectRepo domain.ProjectRepository
 	ciAdapter   domain.CIAdapter
+	buildRepo   domain.BuildRepository
+	networking  *dualstack.Checker
+	scheduler   *placement.Scheduler
+	internal    *internalnet.Manager
 	eventBus    domain.EventBus
 	logger      *logger.Logger
 }
 
-// NewServiceHandler creates a new ServiceHandler
+// NewServiceHandler creates a new ServiceHandler. buildRepo may be nil, in
+// which case triggered builds are not persisted; networking may be nil, in
+// which case dual-stack options are not checked against clusters; scheduler
+// may be nil, in which case services without a target cluster stay unplaced;
+// internal may be nil, in which case internal services get no DNS names or
+// network policies.
 func NewServiceHandler(
 	serviceRepo domain.ServiceRepository,
 	projectRepo domain.ProjectRepository,
 	ciAdapter domain.CIAdapter,
+	buildRepo domain.BuildRepository,
+	networking *dualstack.Checker,
+	scheduler *placement.Scheduler,
+	internal *internalnet.Manager,
 	eventBus domain.EventBus,
 	log *logger.Logger,
 ) *ServiceHandler {
@@ -32,6 +55,10 @@ func NewServiceHandler(
 		serviceRepo: serviceRepo,
 		projectRepo: projectRepo,
 		ciAdapter:   ciAdapter,
+		buildRepo:   buildRepo,
+		networking:  networking,
+		scheduler:   scheduler,
+		internal:    internal,
 		eventBus:    eventBus,
 		logger:      log,
 	}
@@ -39,17 +66,25 @@ func NewServiceHandler(
 
 // CreateServiceRequest represents the request body for creating a service
 type CreateServiceRequest struct {
-	Name        string                 `json:"name" binding:"required,min=1,max=255"`
-	Slug        string                 `json:"slug" binding:"required,min=1,max=255"`
-	Type        string                 `json:"type" binding:"required,oneof=webapp worker cronjob stateful_db stateless"`
-	BuildSource BuildSourceRequest     `json:"build_source" binding:"required"`
-	Resources   *ResourceLimitsRequest `json:"resources,omitempty"`
-	Scaling     *ScalingConfigRequest  `json:"scaling,omitempty"`
-	HealthCheck *HealthCheckRequest    `json:"health_check,omitempty"`
-	EnvVars     map[string]string      `json:"env_vars,omitempty"`
-	SecretRefs  []string               `json:"secret_refs,omitempty"`
-	Ports       []PortRequest          `json:"ports,omitempty"`
-	Labels      map[string]string      `json:"labels,omitempty"`
+	Name        string                    `json:"name" binding:"required,min=1,max=255"`
+	Slug        string                    `json:"slug" binding:"required,min=1,max=255"`
+	Type        string                    `json:"type" binding:"required,oneof=webapp worker cronjob stateful_db stateless"`
+	BuildSource BuildSourceRequest        `json:"build_source" binding:"required"`
+	Resources   *ResourceLimitsRequest    `json:"resources,omitempty"`
+	Scaling     *ScalingConfigRequest     `json:"scaling,omitempty"`
+	HealthCheck *HealthCheckRequest       `json:"health_check,omitempty"`
+	EnvVars     map[string]string         `json:"env_vars,omitempty"`
+	SecretRefs  []string                  `json:"secret_refs,omitempty"`
+	Ports       []PortRequest             `json:"ports,omitempty"`
+	Labels      map[string]string         `json:"labels,omitempty"`
+	Catalog     *domain.ServiceCatalog    `json:"catalog,omitempty"`
+	Networking  *domain.ServiceNetworking `json:"networking,omitempty"`
+	CronJob     *domain.CronJobConfig     `json:"cron_job,omitempty"` // Required for cron job services
+
+	Availability *domain.ServiceAvailability `json:"availability,omitempty"`
+
+	TargetClusterID *uuid.UUID               `json:"target_cluster_id,omitempty"` // Skips placement
+	Placement       *domain.ServicePlacement `json:"placement,omitempty"`
 }
 
 // BuildSourceRequest represents build source configuration
@@ -77,6 +112,9 @@ type ScalingConfigRequest struct {
 	MaxReplicas  int32 `json:"max_replicas"`
 	TargetCPU    int32 `json:"target_cpu,omitempty"`
 	TargetMemory int32 `json:"target_memory,omitempty"`
+
+	Triggers    []domain.ScalingTrigger `json:"triggers,omitempty"`
+	WarmStandby *domain.WarmStandby     `json:"warm_standby,omitempty"`
 }
 
 // HealthCheckRequest represents health check configuration
@@ -102,23 +140,31 @@ type PortRequest struct {
 
 // ServiceResponse represents the response body for a service
 type ServiceResponse struct {
-	ID             uuid.UUID             `json:"id"`
-	ProjectID      uuid.UUID             `json:"project_id"`
-	Name           string                `json:"name"`
-	Slug           string                `json:"slug"`
-	Type           string                `json:"type"`
-	Status         string                `json:"status"`
-	BuildSource    domain.BuildSource    `json:"build_source"`
-	Resources      domain.ResourceLimits `json:"resources"`
-	Scaling        domain.ScalingConfig  `json:"scaling"`
-	HealthCheck    *domain.HealthCheck   `json:"health_check,omitempty"`
-	EnvVars        map[string]string     `json:"env_vars,omitempty"`
-	SecretRefs     []string              `json:"secret_refs,omitempty"`
-	Ports          []domain.ServicePort  `json:"ports,omitempty"`
-	Labels         map[string]string     `json:"labels,omitempty"`
-	CurrentVersion string                `json:"current_version,omitempty"`
-	CreatedAt      time.Time             `json:"created_at"`
-	UpdatedAt      time.Time             `json:"updated_at"`
+	ID             uuid.UUID                 `json:"id"`
+	ProjectID      uuid.UUID                 `json:"project_id"`
+	Name           string                    `json:"name"`
+	Slug           string                    `json:"slug"`
+	Type           string                    `json:"type"`
+	Status         string                    `json:"status"`
+	BuildSource    domain.BuildSource        `json:"build_source"`
+	Resources      domain.ResourceLimits     `json:"resources"`
+	Scaling        domain.ScalingConfig      `json:"scaling"`
+	HealthCheck    *domain.HealthCheck       `json:"health_check,omitempty"`
+	EnvVars        map[string]string         `json:"env_vars,omitempty"`
+	SecretRefs     []string                  `json:"secret_refs,omitempty"`
+	Ports          []domain.ServicePort      `json:"ports,omitempty"`
+	Labels         map[string]string         `json:"labels,omitempty"`
+	Catalog        domain.ServiceCatalog     `json:"catalog"`
+	Networking     *domain.ServiceNetworking `json:"networking,omitempty"`
+	TargetClusterID *uuid.UUID               `json:"target_cluster_id,omitempty"`
+	Placement      *domain.ServicePlacement  `json:"placement,omitempty"`
+	CronJob        *domain.CronJobConfig     `json:"cron_job,omitempty"`
+	Availability   *domain.ServiceAvailability `json:"availability,omitempty"`
+	Volumes        []domain.Volume           `json:"volumes,omitempty"`
+	Replicas       *domain.ServiceReplicas   `json:"replicas,omitempty"`
+	CurrentVersion string                    `json:"current_version,omitempty"`
+	CreatedAt      time.Time                 `json:"created_at"`
+	UpdatedAt      time.Time                 `json:"updated_at"`
 }
 
 // Create handles POST /projects/:project_id/services
@@ -131,7 +177,7 @@ func (h *ServiceHandler) Create(c *gin.Context) {
 
 	var req CreateServiceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, errors.BadRequest(err.Error()))
+		respondError(c, bindError(err))
 		return
 	}
 
@@ -164,13 +210,75 @@ func (h *ServiceHandler) Create(c *gin.Context) {
 		UpdatedAt:  time.Now(),
 	}
 
+	if req.Catalog != nil {
+		if err := catalog.Validate(*req.Catalog); err != nil {
+			respondError(c, err)
+			return
+		}
+		service.Catalog = *req.Catalog
+	}
+
+	if req.Networking != nil {
+		if err := internalnet.Validate(req.Networking); err != nil {
+			respondError(c, err)
+			return
+		}
+		if err := h.networking.Check(c.Request.Context(), service, req.Networking); err != nil {
+			respondError(c, err)
+			return
+		}
+		service.Networking = req.Networking
+		if err := h.prepareInternal(c, service); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	if service.Type == domain.ServiceTypeCronJob {
+		if err := cronjobs.Validate(req.CronJob); err != nil {
+			respondError(c, err)
+			return
+		}
+		service.CronJob = req.CronJob
+	} else if req.CronJob != nil {
+		respondError(c, errors.BadRequest("cron_job only applies to cron job services"))
+		return
+	}
+
+	if err := placement.Validate(req.Placement); err != nil {
+		respondError(c, err)
+		return
+	}
+	service.Placement = req.Placement
+	service.TargetClusterID = req.TargetClusterID
+	if service.TargetClusterID == nil && h.scheduler != nil {
+		decision, err := h.scheduler.Place(c.Request.Context(), service)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if decision != nil {
+			service.TargetClusterID = &decision.ClusterID
+		}
+	}
+
 	// Set defaults for scaling
 	if req.Scaling != nil {
+		if err := keda.ValidateTriggers(req.Scaling.Triggers); err != nil {
+			respondError(c, err)
+			return
+		}
 		service.Scaling = domain.ScalingConfig{
 			MinReplicas:  req.Scaling.MinReplicas,
 			MaxReplicas:  req.Scaling.MaxReplicas,
 			TargetCPU:    req.Scaling.TargetCPU,
 			TargetMemory: req.Scaling.TargetMemory,
+			Triggers:     req.Scaling.Triggers,
+			WarmStandby:  req.Scaling.WarmStandby,
+		}
+		if err := warmpool.Validate(service.Scaling.WarmStandby, service.Scaling); err != nil {
+			respondError(c, err)
+			return
 		}
 	} else {
 		service.Scaling = domain.ScalingConfig{
@@ -180,6 +288,12 @@ func (h *ServiceHandler) Create(c *gin.Context) {
 		}
 	}
 
+	if err := availability.Validate(req.Availability, service); err != nil {
+		respondError(c, err)
+		return
+	}
+	service.Availability = req.Availability
+
 	// Set resources with defaults
 	if req.Resources != nil {
 		service.Resources = domain.ResourceLimits{
@@ -239,6 +353,7 @@ func (h *ServiceHandler) Create(c *gin.Context) {
 		respondError(c, err)
 		return
 	}
+	h.applyInternal(c, service)
 
 	// Publish event
 	h.eventBus.Publish(c.Request.Context(), "service.created", &domain.Event{
@@ -303,6 +418,18 @@ func (h *ServiceHandler) ListByProject(c *gin.Context) {
 	filter.Limit = parseIntQuery(c, "limit", 50)
 	filter.Offset = parseIntQuery(c, "offset", 0)
 
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+	filter.After = after
+
+	labels, ok := parseLabelSelector(c)
+	if !ok {
+		return
+	}
+	filter.Labels = labels
+
 	services, err := h.serviceRepo.ListByProject(c.Request.Context(), projectID, filter)
 	if err != nil {
 		respondError(c, err)
@@ -315,10 +442,11 @@ func (h *ServiceHandler) ListByProject(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":   responses,
-		"count":  len(responses),
-		"offset": filter.Offset,
-		"limit":  filter.Limit,
+		"data":        responses,
+		"count":       len(responses),
+		"offset":      filter.Offset,
+		"limit":       filter.Limit,
+		"next_cursor": nextCursor(services, filter.Limit, serviceCursor),
 	})
 }
 
@@ -333,7 +461,7 @@ func (h *ServiceHandler) Update(c *gin.Context) {
 
 	var req map[string]interface{}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, errors.BadRequest(err.Error()))
+		respondError(c, bindError(err))
 		return
 	}
 
@@ -363,11 +491,80 @@ func (h *ServiceHandler) Update(c *gin.Context) {
 			}
 		}
 	}
+	if raw, ok := req["catalog"]; ok {
+		var meta domain.ServiceCatalog
+		data, _ := json.Marshal(raw)
+		if err := json.Unmarshal(data, &meta); err != nil {
+			respondError(c, errors.BadRequest("invalid catalog"))
+			return
+		}
+		if err := catalog.Validate(meta); err != nil {
+			respondError(c, err)
+			return
+		}
+		service.Catalog = meta
+	}
+	if raw, ok := req["networking"]; ok {
+		var networking *domain.ServiceNetworking
+		data, _ := json.Marshal(raw)
+		if err := json.Unmarshal(data, &networking); err != nil {
+			respondError(c, errors.BadRequest("invalid networking"))
+			return
+		}
+		if err := internalnet.Validate(networking); err != nil {
+			respondError(c, err)
+			return
+		}
+		if err := h.networking.Check(c.Request.Context(), service, networking); err != nil {
+			respondError(c, err)
+			return
+		}
+		service.Networking = networking
+		if err := h.prepareInternal(c, service); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
+	if raw, ok := req["cron_job"]; ok {
+		var cronJob *domain.CronJobConfig
+		data, _ := json.Marshal(raw)
+		if err := json.Unmarshal(data, &cronJob); err != nil {
+			respondError(c, errors.BadRequest("invalid cron_job"))
+			return
+		}
+		if service.Type != domain.ServiceTypeCronJob {
+			respondError(c, errors.BadRequest("cron_job only applies to cron job services"))
+			return
+		}
+		if err := cronjobs.Validate(cronJob); err != nil {
+			respondError(c, err)
+			return
+		}
+		service.CronJob = cronJob
+	}
+
+	if raw, ok := req["availability"]; ok {
+		var settings *domain.ServiceAvailability
+		data, _ := json.Marshal(raw)
+		if err := json.Unmarshal(data, &settings); err != nil {
+			respondError(c, errors.BadRequest("invalid availability"))
+			return
+		}
+		if err := availability.Validate(settings, service); err != nil {
+			respondError(c, err)
+			return
+		}
+		service.Availability = settings
+	}
 
 	if err := h.serviceRepo.Update(c.Request.Context(), service); err != nil {
 		respondError(c, err)
 		return
 	}
+	if _, ok := req["networking"]; ok {
+		h.applyInternal(c, service)
+	}
 
 	// Publish event
 	h.eventBus.Publish(c.Request.Context(), "service.updated", &domain.Event{
@@ -405,6 +602,11 @@ func (h *ServiceHandler) Delete(c *gin.Context) {
 		respondError(c, err)
 		return
 	}
+	if h.internal != nil {
+		if err := h.internal.Remove(c.Request.Context(), service); err != nil {
+			h.logger.Warn().Err(err).Str("service_id", id.String()).Msg("Failed to remove internal networking")
+		}
+	}
 
 	// Publish event
 	h.eventBus.Publish(c.Request.Context(), "service.deleted", &domain.Event{
@@ -459,9 +661,31 @@ func (h *ServiceHandler) TriggerBuild(c *gin.Context) {
 		return
 	}
 
+	if h.buildRepo != nil {
+		if userID, exists := c.Get("user_id"); exists {
+			build.TriggeredBy = fmt.Sprint(userID)
+		}
+		if err := h.buildRepo.Create(c.Request.Context(), build); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+
 	// Update service status
 	h.serviceRepo.UpdateStatus(c.Request.Context(), id, domain.ServiceStatusBuilding)
 
+	// Publish event
+	h.eventBus.Publish(c.Request.Context(), "build.started", &domain.Event{
+		Type:   "build.started",
+		Source: "api",
+		Data: map[string]interface{}{
+			"build_id":   build.ID.String(),
+			"service_id": service.ID.String(),
+			"project_id": service.ProjectID.String(),
+			"status":     string(build.Status),
+		},
+	})
+
 	h.logger.Info().
 		Str("service_id", id.String()).
 		Str("build_id", build.ID.String()).
@@ -474,6 +698,49 @@ func (h *ServiceHandler) TriggerBuild(c *gin.Context) {
 	})
 }
 
+// ListBuilds handles GET /services/:id/builds
+func (h *ServiceHandler) ListBuilds(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", 20)
+	if limit < 1 || limit > 100 {
+		respondError(c, errors.BadRequest("limit must be between 1 and 100"))
+		return
+	}
+
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	builds, err := h.buildRepo.ListByServiceAfter(c.Request.Context(), id, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Logs can be large; they are served by the build logs endpoints
+	for _, build := range builds {
+		build.BuildLogs = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        builds,
+		"count":       len(builds),
+		"limit":       limit,
+		"next_cursor": nextCursor(builds, limit, buildCursor),
+	})
+}
+
 // Scale handles POST /services/:id/scale
 func (h *ServiceHandler) Scale(c *gin.Context) {
 	idStr := c.Param("id")
@@ -487,7 +754,7 @@ func (h *ServiceHandler) Scale(c *gin.Context) {
 		Replicas int32 `json:"replicas" binding:"required,min=0,max=100"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		respondError(c, errors.BadRequest(err.Error()))
+		respondError(c, bindError(err))
 		return
 	}
 
@@ -524,6 +791,72 @@ func (h *ServiceHandler) Scale(c *gin.Context) {
 	})
 }
 
+// PlaceServiceRequest represents the request body for placing a service
+type PlaceServiceRequest struct {
+	Placement *domain.ServicePlacement `json:"placement,omitempty"` // Replaces the service's constraints when set
+}
+
+// Place handles POST /services/:id/placement. It schedules the service onto
+// a cluster again, replacing its target cluster.
+func (h *ServiceHandler) Place(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	var req PlaceServiceRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, bindError(err))
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	service, err := h.serviceRepo.GetByID(ctx, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if req.Placement != nil {
+		service.Placement = req.Placement
+	}
+
+	decision, err := h.scheduler.Place(ctx, service)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if decision == nil {
+		respondError(c, errors.BadRequest("the service's project has no environments to place it in"))
+		return
+	}
+
+	service.TargetClusterID = &decision.ClusterID
+	if err := h.serviceRepo.Update(ctx, service); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(ctx, "service.updated", &domain.Event{
+		Type:   "service.updated",
+		Source: "api",
+		Data: map[string]interface{}{
+			"service_id":        service.ID.String(),
+			"project_id":        service.ProjectID.String(),
+			"target_cluster_id": decision.ClusterID.String(),
+		},
+	})
+
+	h.logger.Info().
+		Str("service_id", service.ID.String()).
+		Str("cluster_id", decision.ClusterID.String()).
+		Msg("Service placed")
+
+	c.JSON(http.StatusOK, decision)
+}
+
 func serviceToResponse(s *domain.Service) ServiceResponse {
 	return ServiceResponse{
 		ID:             s.ID,
@@ -540,8 +873,36 @@ func serviceToResponse(s *domain.Service) ServiceResponse {
 		SecretRefs:     s.SecretRefs,
 		Ports:          s.Ports,
 		Labels:         s.Labels,
+		Catalog:        s.Catalog,
+		Networking:     s.Networking,
+		TargetClusterID: s.TargetClusterID,
+		Placement:      s.Placement,
+		CronJob:        s.CronJob,
+		Availability:   s.Availability,
+		Volumes:        s.Volumes,
+		Replicas:       s.Replicas,
 		CurrentVersion: s.CurrentVersion,
 		CreatedAt:      s.CreatedAt,
 		UpdatedAt:      s.UpdatedAt,
 	}
 }
+
+// prepareInternal records the internal DNS name of a service, checking its
+// internal networking against the project
+func (h *ServiceHandler) prepareInternal(c *gin.Context, service *domain.Service) error {
+	if h.internal == nil {
+		return nil
+	}
+	return h.internal.Prepare(c.Request.Context(), service)
+}
+
+// applyInternal applies the network policy and internal name of a service. The
+// service is saved either way; the reconcile loop retries failures.
+func (h *ServiceHandler) applyInternal(c *gin.Context, service *domain.Service) {
+	if h.internal == nil {
+		return
+	}
+	if err := h.internal.Apply(c.Request.Context(), service); err != nil {
+		h.logger.Warn().Err(err).Str("service_id", service.ID.String()).Msg("Failed to apply internal networking")
+	}
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/sharelink"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// sharedDeploymentLimit bounds the deployment history shown on a shared service view
+const sharedDeploymentLimit = 10
+
+// ShareHandler issues share links and serves the read-only views behind them
+type ShareHandler struct {
+	signer      *sharelink.Signer
+	serviceRepo domain.ServiceRepository
+	deployRepo  domain.DeploymentRepository
+	logStore    domain.LogStore
+	logger      *logger.Logger
+}
+
+// NewShareHandler creates a new ShareHandler. deployRepo and logStore may be
+// nil; deployment links and shared logs are then unavailable.
+func NewShareHandler(signer *sharelink.Signer, serviceRepo domain.ServiceRepository, deployRepo domain.DeploymentRepository, logStore domain.LogStore, log *logger.Logger) *ShareHandler {
+	return &ShareHandler{
+		signer:      signer,
+		serviceRepo: serviceRepo,
+		deployRepo:  deployRepo,
+		logStore:    logStore,
+		logger:      log,
+	}
+}
+
+// CreateShareLinkRequest represents the request body for creating a share link
+type CreateShareLinkRequest struct {
+	ExpiresIn   string `json:"expires_in,omitempty"` // Go duration, e.g. "72h"; defaults to the configured TTL
+	IncludeLogs bool   `json:"include_logs"`
+}
+
+// ShareLinkResponse represents an issued share link
+type ShareLinkResponse struct {
+	Token       string             `json:"token"`
+	URL         string             `json:"url,omitempty"`
+	Resource    sharelink.Resource `json:"resource"`
+	ResourceID  uuid.UUID          `json:"resource_id"`
+	IncludeLogs bool               `json:"include_logs"`
+	ExpiresAt   time.Time          `json:"expires_at"`
+}
+
+// SharedService is the subset of a service visible through a share link;
+// configuration such as environment variables and secrets is never exposed
+type SharedService struct {
+	ID             uuid.UUID            `json:"id"`
+	Name           string               `json:"name"`
+	Type           domain.ServiceType   `json:"type"`
+	Status         domain.ServiceStatus `json:"status"`
+	CurrentVersion string               `json:"current_version,omitempty"`
+	UpdatedAt      time.Time            `json:"updated_at"`
+}
+
+// SharedDeployment is the subset of a deployment visible through a share link
+type SharedDeployment struct {
+	ID              uuid.UUID                 `json:"id"`
+	Status          domain.DeploymentStatus   `json:"status"`
+	Strategy        domain.DeploymentStrategy `json:"strategy"`
+	Version         string                    `json:"version"`
+	PreviousVersion string                    `json:"previous_version,omitempty"`
+	Replicas        int32                     `json:"replicas"`
+	ReadyReplicas   int32                     `json:"ready_replicas"`
+	ErrorMessage    string                    `json:"error_message,omitempty"`
+	Health          *domain.ReleaseHealth     `json:"health,omitempty"`
+	StartedAt       *time.Time                `json:"started_at,omitempty"`
+	CompletedAt     *time.Time                `json:"completed_at,omitempty"`
+	CreatedAt       time.Time                 `json:"created_at"`
+}
+
+// ShareService handles POST /services/:id/share-links
+func (h *ShareHandler) ShareService(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.issue(c, sharelink.ResourceService, id)
+}
+
+// ShareDeployment handles POST /deployments/:id/share-links
+func (h *ShareHandler) ShareDeployment(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid deployment ID"))
+		return
+	}
+
+	if _, err := h.deployRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.issue(c, sharelink.ResourceDeployment, id)
+}
+
+// View handles GET /shared/:token without authentication
+func (h *ShareHandler) View(c *gin.Context) {
+	claims, ok := h.verify(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	response := gin.H{
+		"resource":     claims.Resource,
+		"include_logs": claims.IncludeLogs && h.logStore != nil,
+		"expires_at":   claims.Expiry(),
+	}
+
+	switch claims.Resource {
+	case sharelink.ResourceService:
+		service, err := h.serviceRepo.GetByID(ctx, claims.ID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		response["service"] = sharedService(service)
+
+		if h.deployRepo != nil {
+			deployments, err := h.deployRepo.ListByService(ctx, service.ID, sharedDeploymentLimit)
+			if err != nil {
+				respondError(c, err)
+				return
+			}
+			shared := make([]SharedDeployment, len(deployments))
+			for i, d := range deployments {
+				shared[i] = sharedDeployment(d)
+			}
+			response["deployments"] = shared
+		}
+
+	case sharelink.ResourceDeployment:
+		deployment, service, ok := h.deployment(c, claims.ID)
+		if !ok {
+			return
+		}
+		response["service"] = sharedService(service)
+		response["deployment"] = sharedDeployment(deployment)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Logs handles GET /shared/:token/logs for links issued with include_logs.
+// Deployment links default to the logs from the deployment onwards.
+func (h *ShareHandler) Logs(c *gin.Context) {
+	claims, ok := h.verify(c)
+	if !ok {
+		return
+	}
+	if !claims.IncludeLogs || h.logStore == nil {
+		respondError(c, errors.Forbidden("this share link does not include logs"))
+		return
+	}
+
+	query, err := parseLogQuery(c)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	serviceID := claims.ID
+	if claims.Resource == sharelink.ResourceDeployment {
+		deployment, _, ok := h.deployment(c, claims.ID)
+		if !ok {
+			return
+		}
+		serviceID = deployment.ServiceID
+		if c.Query("since") == "" && c.Query("start") == "" {
+			query.Start = deployment.CreatedAt
+		}
+	}
+
+	entries, err := h.logStore.SearchServiceLogs(c.Request.Context(), serviceID, query)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"query":   query,
+	})
+}
+
+func (h *ShareHandler) issue(c *gin.Context, resource sharelink.Resource, id uuid.UUID) {
+	// The body is optional
+	var req CreateShareLinkRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, bindError(err))
+			return
+		}
+	}
+
+	var requested time.Duration
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil {
+			respondError(c, errors.BadRequest("invalid expires_in"))
+			return
+		}
+		requested = d
+	}
+	ttl, err := h.signer.TTL(requested)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	claims := sharelink.Claims{
+		Resource:    resource,
+		ID:          id,
+		IncludeLogs: req.IncludeLogs,
+		ExpiresAt:   time.Now().Add(ttl).Unix(),
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		claims.IssuedBy = fmt.Sprint(userID)
+	}
+
+	token, err := h.signer.Sign(claims)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("resource", string(resource)).
+		Str("resource_id", id.String()).
+		Str("issued_by", claims.IssuedBy).
+		Time("expires_at", claims.Expiry()).
+		Msg("Share link issued")
+
+	c.JSON(http.StatusCreated, ShareLinkResponse{
+		Token:       token,
+		URL:         h.signer.URL(token),
+		Resource:    resource,
+		ResourceID:  id,
+		IncludeLogs: req.IncludeLogs,
+		ExpiresAt:   claims.Expiry(),
+	})
+}
+
+func (h *ShareHandler) verify(c *gin.Context) (*sharelink.Claims, bool) {
+	claims, err := h.signer.Verify(c.Param("token"), time.Now())
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	if claims.Resource == sharelink.ResourceDeployment && h.deployRepo == nil {
+		respondError(c, errors.NotFound("deployment", claims.ID.String()))
+		return nil, false
+	}
+	return claims, true
+}
+
+func (h *ShareHandler) deployment(c *gin.Context, id uuid.UUID) (*domain.Deployment, *domain.Service, bool) {
+	deployment, err := h.deployRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, nil, false
+	}
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), deployment.ServiceID)
+	if err != nil {
+		respondError(c, err)
+		return nil, nil, false
+	}
+	return deployment, service, true
+}
+
+func sharedService(s *domain.Service) SharedService {
+	return SharedService{
+		ID:             s.ID,
+		Name:           s.Name,
+		Type:           s.Type,
+		Status:         s.Status,
+		CurrentVersion: s.CurrentVersion,
+		UpdatedAt:      s.UpdatedAt,
+	}
+}
+
+func sharedDeployment(d *domain.Deployment) SharedDeployment {
+	return SharedDeployment{
+		ID:              d.ID,
+		Status:          d.Status,
+		Strategy:        d.Strategy,
+		Version:         d.Version,
+		PreviousVersion: d.PreviousVersion,
+		Replicas:        d.Replicas,
+		ReadyReplicas:   d.ReadyReplicas,
+		ErrorMessage:    d.ErrorMessage,
+		Health:          d.Health,
+		StartedAt:       d.StartedAt,
+		CompletedAt:     d.CompletedAt,
+		CreatedAt:       d.CreatedAt,
+	}
+}
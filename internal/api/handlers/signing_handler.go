@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/signing"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// SigningHandler serves the keys webhook and event signatures verify against
+type SigningHandler struct {
+	signer *signing.Signer
+	logger *logger.Logger
+}
+
+// NewSigningHandler creates a new SigningHandler
+func NewSigningHandler(signer *signing.Signer, log *logger.Logger) *SigningHandler {
+	return &SigningHandler{
+		signer: signer,
+		logger: log,
+	}
+}
+
+// JWKS handles GET /.well-known/jwks.json
+func (h *SigningHandler) JWKS(c *gin.Context) {
+	set, err := h.signer.JWKS(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Short enough that consumers pick up a rotated key well within the overlap
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/adapters/slack"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// SlackHandler handles the Slack notification settings of projects
+type SlackHandler struct {
+	notifier    *slack.Notifier
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewSlackHandler creates a new SlackHandler
+func NewSlackHandler(notifier *slack.Notifier, projectRepo domain.ProjectRepository, log *logger.Logger) *SlackHandler {
+	return &SlackHandler{
+		notifier:    notifier,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// TestSlackRequest represents a request to post a test message
+type TestSlackRequest struct {
+	Channel string `json:"channel"` // The project's channel when empty
+}
+
+// Get handles GET /projects/:id/slack
+func (h *SlackHandler) Get(c *gin.Context) {
+	project, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+	if project.Slack == nil {
+		respondError(c, errors.NotFound("slack settings", project.ID.String()))
+		return
+	}
+
+	c.JSON(http.StatusOK, project.Slack)
+}
+
+// Update handles PUT /projects/:id/slack
+func (h *SlackHandler) Update(c *gin.Context) {
+	var req domain.SlackSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	if err := slack.Validate(&req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	project, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+
+	project.Slack = &req
+	if err := h.projectRepo.Update(c.Request.Context(), project); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("project_id", project.ID.String()).
+		Int("routes", len(req.Routes)).
+		Msg("Slack settings updated")
+
+	c.JSON(http.StatusOK, project.Slack)
+}
+
+// Delete handles DELETE /projects/:id/slack, stopping the project's Slack notifications
+func (h *SlackHandler) Delete(c *gin.Context) {
+	project, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+
+	project.Slack = nil
+	if err := h.projectRepo.Update(c.Request.Context(), project); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("project_id", project.ID.String()).
+		Msg("Slack settings removed")
+
+	c.Status(http.StatusNoContent)
+}
+
+// Test handles POST /projects/:id/slack/test, posting a message to check
+// that the bot can reach a channel
+func (h *SlackHandler) Test(c *gin.Context) {
+	var req TestSlackRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondError(c, bindError(err))
+			return
+		}
+	}
+
+	project, ok := h.loadProject(c)
+	if !ok {
+		return
+	}
+
+	channel := req.Channel
+	if channel == "" && project.Slack != nil {
+		channel = project.Slack.Channel
+	}
+	if channel == "" {
+		respondError(c, errors.BadRequest("the project has no Slack channel; name one to test"))
+		return
+	}
+
+	err := h.notifier.SendNotification(c.Request.Context(), &domain.Notification{
+		Type:      "test",
+		Channel:   "slack",
+		Recipient: channel,
+		Title:     "Test notification",
+		Message:   fmt.Sprintf("Notifications of project %s can reach this channel.", project.Name),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"channel": channel, "delivered": true})
+}
+
+func (h *SlackHandler) loadProject(c *gin.Context) (*domain.Project, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return nil, false
+	}
+
+	project, err := h.projectRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return project, true
+}
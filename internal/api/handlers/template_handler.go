@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/templates"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// TemplateHandler serves the project templates catalog
+type TemplateHandler struct {
+	catalog *templates.Catalog
+	logger  *logger.Logger
+}
+
+// NewTemplateHandler creates a new TemplateHandler
+func NewTemplateHandler(catalog *templates.Catalog, log *logger.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		catalog: catalog,
+		logger:  log,
+	}
+}
+
+// TemplateRequest is the body of creating or replacing a template
+type TemplateRequest struct {
+	Slug        string                    `json:"slug"` // Taken from the path on updates
+	Name        string                    `json:"name" binding:"required,max=255"`
+	Description string                    `json:"description"`
+	Category    string                    `json:"category" binding:"max=63"`
+	Tags        []string                  `json:"tags"`
+	Manifest    string                    `json:"manifest" binding:"required"` // northstack.yaml with ${VARIABLE} placeholders
+	Variables   []domain.TemplateVariable `json:"variables"`
+}
+
+// InstantiateTemplateRequest is the body of instantiating a template
+type InstantiateTemplateRequest struct {
+	Name        string            `json:"name" binding:"required_without=ProjectID,max=255"`
+	Slug        string            `json:"slug" binding:"required_without=ProjectID,omitempty,max=63"`
+	Description string            `json:"description"`
+	TeamID      *uuid.UUID        `json:"team_id"`
+	ProjectID   *uuid.UUID        `json:"project_id"` // Add the services to this project instead of creating one
+	Variables   map[string]string `json:"variables"`
+	DryRun      bool              `json:"dry_run"`
+}
+
+// List handles GET /templates
+func (h *TemplateHandler) List(c *gin.Context) {
+	list, err := h.catalog.List(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": list})
+}
+
+// Get handles GET /templates/:slug
+func (h *TemplateHandler) Get(c *gin.Context) {
+	template, err := h.catalog.Get(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// Create handles POST /templates
+func (h *TemplateHandler) Create(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	template := &domain.Template{
+		Slug:        req.Slug,
+		Name:        req.Name,
+		Description: req.Description,
+		Category:    req.Category,
+		Tags:        req.Tags,
+		Manifest:    req.Manifest,
+		Variables:   req.Variables,
+		CreatedBy:   &userID,
+	}
+	if err := h.catalog.Create(c.Request.Context(), template); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().Str("template", template.Slug).Msg("Template created")
+	c.JSON(http.StatusCreated, template)
+}
+
+// Update handles PUT /templates/:slug. Only the creator of a template and
+// admins can change it.
+func (h *TemplateHandler) Update(c *gin.Context) {
+	template, ok := h.editable(c)
+	if !ok {
+		return
+	}
+
+	var req TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	template.Name = req.Name
+	template.Description = req.Description
+	template.Category = req.Category
+	template.Tags = req.Tags
+	template.Manifest = req.Manifest
+	template.Variables = req.Variables
+	if err := h.catalog.Update(c.Request.Context(), template); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// Delete handles DELETE /templates/:slug. Projects created from the template
+// are kept.
+func (h *TemplateHandler) Delete(c *gin.Context) {
+	template, ok := h.editable(c)
+	if !ok {
+		return
+	}
+
+	if err := h.catalog.Delete(c.Request.Context(), template); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().Str("template", template.Slug).Msg("Template deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// Instantiate handles POST /templates/:slug/instantiate. It creates a project
+// with the template's services, or adds them to project_id, and answers 201
+// when a project was created.
+func (h *TemplateHandler) Instantiate(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req InstantiateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	instance, err := h.catalog.Instantiate(c.Request.Context(), c.Param("slug"), templates.InstantiateRequest{
+		ProjectID:   req.ProjectID,
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		TeamID:      req.TeamID,
+		OwnerID:     userID,
+		Variables:   req.Variables,
+		DryRun:      req.DryRun,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if instance.ProjectAction == "create" && !instance.DryRun {
+		status = http.StatusCreated
+	}
+	c.JSON(status, instance)
+}
+
+// editable returns the template of the request if the caller may change it,
+// writing the error response otherwise
+func (h *TemplateHandler) editable(c *gin.Context) (*domain.Template, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return nil, false
+	}
+
+	template, err := h.catalog.Get(c.Request.Context(), c.Param("slug"))
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	if template.Builtin {
+		respondError(c, errors.Forbidden("built-in templates cannot be changed"))
+		return nil, false
+	}
+	if (template.CreatedBy == nil || *template.CreatedBy != userID) && !isAdmin(c) {
+		respondError(c, errors.Forbidden("only the creator of a template can change it"))
+		return nil, false
+	}
+	return template, true
+}
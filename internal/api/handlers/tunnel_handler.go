@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/tunnel"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// tunnelCheckInterval is how often tunnels are pinged and checked against
+// their idle and duration limits
+const tunnelCheckInterval = 15 * time.Second
+
+// TunnelHandler relays port-forward tunnels to private services over WebSocket
+type TunnelHandler struct {
+	manager     *tunnel.Manager
+	serviceRepo domain.ServiceRepository
+	projectRepo domain.ProjectRepository
+	upgrader    websocket.Upgrader
+	logger      *logger.Logger
+}
+
+// NewTunnelHandler creates a new TunnelHandler
+func NewTunnelHandler(
+	manager *tunnel.Manager,
+	serviceRepo domain.ServiceRepository,
+	projectRepo domain.ProjectRepository,
+	allowedOrigins []string,
+	log *logger.Logger,
+) *TunnelHandler {
+	return &TunnelHandler{
+		manager:     manager,
+		serviceRepo: serviceRepo,
+		projectRepo: projectRepo,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  32 * 1024,
+			WriteBufferSize: 32 * 1024,
+			CheckOrigin:     checkOrigin(allowedOrigins),
+		},
+		logger: log,
+	}
+}
+
+// PortForward handles GET /services/:id/port-forward?port=<port>
+// The connection is upgraded to a WebSocket whose binary messages carry the
+// raw TCP stream to and from the service port. Only the owner of the
+// service's project and administrators may open tunnels.
+func (h *TunnelHandler) PortForward(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+	port, err := strconv.ParseInt(c.Query("port"), 10, 32)
+	if err != nil || port <= 0 {
+		respondError(c, errors.BadRequest("port query parameter must be a service port"))
+		return
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if role, _ := c.Get("user_role"); role != domain.UserRoleAdmin {
+		project, err := h.projectRepo.GetByID(c.Request.Context(), service.ProjectID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if project.OwnerID != userID {
+			respondError(c, errors.Forbidden("only the project owner can open tunnels to its services"))
+			return
+		}
+	}
+
+	// Open before upgrading so that failures are reported as API errors
+	session, err := h.manager.Open(c.Request.Context(), service, tunnel.Request{
+		UserID:    userID,
+		Port:      int32(port),
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer session.Close()
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already responded
+		return
+	}
+	defer conn.Close()
+
+	h.relay(conn, session)
+}
+
+// List handles GET /tunnels
+func (h *TunnelHandler) List(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions := h.manager.Sessions(userID)
+	c.JSON(http.StatusOK, gin.H{
+		"data":  sessions,
+		"total": len(sessions),
+	})
+}
+
+// relay copies between the WebSocket and the tunnel until either side ends
+// or the tunnel exceeds its idle or duration limit
+func (h *TunnelHandler) relay(conn *websocket.Conn, session *tunnel.Session) {
+	done := make(chan struct{})
+	var once sync.Once
+	finish := func() { once.Do(func() { close(done) }) }
+
+	var lastActive atomic.Int64
+	lastActive.Store(time.Now().UnixNano())
+
+	// Pod to client
+	go func() {
+		defer finish()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := session.Read(buf)
+			if n > 0 {
+				lastActive.Store(time.Now().UnixNano())
+				if werr := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Client to pod
+	go func() {
+		defer finish()
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if kind != websocket.BinaryMessage {
+				continue
+			}
+			lastActive.Store(time.Now().UnixNano())
+			if _, err := session.Write(data); err != nil {
+				return
+			}
+		}
+	}()
+
+	check := time.NewTicker(tunnelCheckInterval)
+	defer check.Stop()
+
+	reason := ""
+	for reason == "" {
+		select {
+		case <-done:
+			return
+		case now := <-check.C:
+			idle := now.Sub(time.Unix(0, lastActive.Load()))
+			switch {
+			case h.manager.MaxDuration() > 0 && now.Sub(session.StartedAt) >= h.manager.MaxDuration():
+				reason = "tunnel reached its maximum duration"
+			case h.manager.IdleTimeout() > 0 && idle >= h.manager.IdleTimeout():
+				reason = "tunnel was idle for too long"
+			default:
+				if err := conn.WriteControl(websocket.PingMessage, nil, now.Add(tunnelCheckInterval)); err != nil {
+					return
+				}
+			}
+		}
+	}
+
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, reason), time.Now().Add(time.Second))
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/uptime"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	defaultUptimeChecksLimit = 100
+	maxUptimeChecksLimit     = 1000
+)
+
+// UptimeHandler handles ingress uptime endpoints
+type UptimeHandler struct {
+	prober      *uptime.Prober
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewUptimeHandler creates a new UptimeHandler
+func NewUptimeHandler(prober *uptime.Prober, serviceRepo domain.ServiceRepository, log *logger.Logger) *UptimeHandler {
+	return &UptimeHandler{
+		prober:      prober,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// Summary handles GET /services/:id/uptime
+func (h *UptimeHandler) Summary(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	if _, err := h.serviceRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	ingresses, err := h.prober.ServiceUptime(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_id": id,
+		"ingresses":  ingresses,
+	})
+}
+
+// Checks handles GET /services/:id/uptime/checks
+// Query: ingress_id (required), limit (default 100, max 1000)
+func (h *UptimeHandler) Checks(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return
+	}
+
+	ingressID, err := uuid.Parse(c.Query("ingress_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid ingress ID"))
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", defaultUptimeChecksLimit)
+	if limit < 1 || limit > maxUptimeChecksLimit {
+		respondError(c, errors.BadRequest("limit must be between 1 and 1000"))
+		return
+	}
+
+	checks, err := h.prober.History(c.Request.Context(), id, ingressID, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"checks": checks,
+		"total":  len(checks),
+	})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metering"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const maxUsagePeriod = 366 * 24 * time.Hour
+
+// UsageHandler handles resource usage reporting endpoints
+type UsageHandler struct {
+	meter       *metering.Meter
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewUsageHandler creates a new UsageHandler
+func NewUsageHandler(meter *metering.Meter, projectRepo domain.ProjectRepository, log *logger.Logger) *UsageHandler {
+	return &UsageHandler{
+		meter:       meter,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// Get handles GET /projects/:id/usage
+// Query: from, to (RFC 3339 or YYYY-MM-DD; default the current month to date)
+func (h *UsageHandler) Get(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	now := time.Now().UTC()
+	from, err := parseTimeQuery(c, "from", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	to, err := parseTimeQuery(c, "to", now)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !to.After(from) || to.Sub(from) > maxUsagePeriod {
+		respondError(c, errors.BadRequest("to must be after from and at most 366 days later"))
+		return
+	}
+
+	if _, err := h.projectRepo.GetByID(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	report, err := h.meter.Report(c.Request.Context(), id, from, to)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseTimeQuery parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseTimeQuery(c *gin.Context, key string, defaultValue time.Time) (time.Time, error) {
+	v := c.Query(key)
+	if v == "" {
+		return defaultValue, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.BadRequest("invalid " + key + ": expected RFC 3339 or YYYY-MM-DD")
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/volumes"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// VolumeHandler handles the persistent volume endpoints of services
+type VolumeHandler struct {
+	manager     *volumes.Manager
+	serviceRepo domain.ServiceRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewVolumeHandler creates a new VolumeHandler
+func NewVolumeHandler(manager *volumes.Manager, serviceRepo domain.ServiceRepository, eventBus domain.EventBus, log *logger.Logger) *VolumeHandler {
+	return &VolumeHandler{
+		manager:     manager,
+		serviceRepo: serviceRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// CreateVolumeRequest represents a request to add a volume to a service
+type CreateVolumeRequest struct {
+	Name         string                  `json:"name" binding:"required"`
+	Size         string                  `json:"size" binding:"required"`
+	StorageClass string                  `json:"storage_class"`
+	MountPath    string                  `json:"mount_path" binding:"required"`
+	AccessMode   domain.VolumeAccessMode `json:"access_mode"`
+}
+
+// UpdateVolumeRequest represents a request to resize or remount a volume
+type UpdateVolumeRequest struct {
+	Size      *string `json:"size"`
+	MountPath *string `json:"mount_path"`
+}
+
+// Create handles POST /services/:id/volumes
+func (h *VolumeHandler) Create(c *gin.Context) {
+	var req CreateVolumeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	volume := domain.Volume{
+		Name:         req.Name,
+		Size:         req.Size,
+		StorageClass: req.StorageClass,
+		MountPath:    req.MountPath,
+		AccessMode:   req.AccessMode,
+	}
+	if _, exists := volumes.Find(service, volume.Name); exists {
+		respondError(c, errors.NewError(errors.CodeConflict, fmt.Sprintf("service already has a volume named %s", volume.Name), http.StatusConflict))
+		return
+	}
+	if err := volumes.Validate(service, volume); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	service.Volumes = append(service.Volumes, volume)
+	h.save(c, service, volume, http.StatusCreated)
+}
+
+// List handles GET /services/:id/volumes
+func (h *VolumeHandler) List(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	statuses, err := h.manager.Status(c.Request.Context(), service)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  statuses,
+		"count": len(statuses),
+	})
+}
+
+// Get handles GET /services/:id/volumes/:volume
+func (h *VolumeHandler) Get(c *gin.Context) {
+	service, volume, ok := h.loadVolume(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.manager.VolumeStatus(c.Request.Context(), service, volume)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// Update handles PATCH /services/:id/volumes/:volume
+// A larger size expands the claim in place, while pods keep running, when its
+// storage class allows it.
+func (h *VolumeHandler) Update(c *gin.Context) {
+	var req UpdateVolumeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	service, old, ok := h.loadVolume(c)
+	if !ok {
+		return
+	}
+
+	volume := old
+	if req.Size != nil {
+		volume.Size = *req.Size
+	}
+	if req.MountPath != nil {
+		volume.MountPath = *req.MountPath
+	}
+	if err := volumes.Validate(service, volume); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := volumes.ValidateChange(old, volume); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.manager.CheckResize(c.Request.Context(), service, old, volume); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	for i := range service.Volumes {
+		if service.Volumes[i].Name == volume.Name {
+			service.Volumes[i] = volume
+		}
+	}
+	h.save(c, service, volume, http.StatusOK)
+}
+
+// Delete handles DELETE /services/:id/volumes/:volume
+// The volume's data is deleted with its claim. Volumes with snapshots are
+// refused with 409 until the snapshots are deleted.
+func (h *VolumeHandler) Delete(c *gin.Context) {
+	service, volume, ok := h.loadVolume(c)
+	if !ok {
+		return
+	}
+
+	if err := h.manager.Delete(c.Request.Context(), service, volume); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	remaining := make([]domain.Volume, 0, len(service.Volumes))
+	for _, v := range service.Volumes {
+		if v.Name != volume.Name {
+			remaining = append(remaining, v)
+		}
+	}
+	service.Volumes = remaining
+	if err := h.serviceRepo.Update(c.Request.Context(), service); err != nil {
+		respondError(c, err)
+		return
+	}
+	h.publishUpdated(c, service)
+
+	h.logger.Info().
+		Str("service_id", service.ID.String()).
+		Str("volume", volume.Name).
+		Msg("Volume deleted")
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListSnapshots handles GET /services/:id/volumes/:volume/snapshots
+func (h *VolumeHandler) ListSnapshots(c *gin.Context) {
+	service, volume, ok := h.loadVolume(c)
+	if !ok {
+		return
+	}
+
+	snapshots, err := h.manager.Snapshots(c.Request.Context(), service, volume)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  snapshots,
+		"count": len(snapshots),
+	})
+}
+
+// CreateSnapshot handles POST /services/:id/volumes/:volume/snapshots
+func (h *VolumeHandler) CreateSnapshot(c *gin.Context) {
+	service, volume, ok := h.loadVolume(c)
+	if !ok {
+		return
+	}
+
+	snapshot, err := h.manager.CreateSnapshot(c.Request.Context(), service, volume)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("service_id", service.ID.String()).
+		Str("volume", volume.Name).
+		Str("snapshot", snapshot.Name).
+		Msg("Volume snapshot created")
+
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+// DeleteSnapshot handles DELETE /services/:id/volumes/:volume/snapshots/:snapshot
+func (h *VolumeHandler) DeleteSnapshot(c *gin.Context) {
+	service, volume, ok := h.loadVolume(c)
+	if !ok {
+		return
+	}
+
+	if err := h.manager.DeleteSnapshot(c.Request.Context(), service, volume, c.Param("snapshot")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *VolumeHandler) save(c *gin.Context, service *domain.Service, volume domain.Volume, status int) {
+	if err := h.serviceRepo.Update(c.Request.Context(), service); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Services that are not deployed yet get their claims on first deploy
+	if err := h.manager.Apply(c.Request.Context(), service); err != nil {
+		respondError(c, err)
+		return
+	}
+	h.publishUpdated(c, service)
+
+	h.logger.Info().
+		Str("service_id", service.ID.String()).
+		Str("volume", volume.Name).
+		Str("size", volume.Size).
+		Msg("Volume saved")
+
+	result, err := h.manager.VolumeStatus(c.Request.Context(), service, volume)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(status, result)
+}
+
+func (h *VolumeHandler) publishUpdated(c *gin.Context, service *domain.Service) {
+	h.eventBus.Publish(c.Request.Context(), "service.updated", &domain.Event{
+		Type:   "service.updated",
+		Source: "api",
+		Data: map[string]interface{}{
+			"service_id": service.ID.String(),
+			"project_id": service.ProjectID.String(),
+		},
+	})
+}
+
+func (h *VolumeHandler) loadVolume(c *gin.Context) (*domain.Service, domain.Volume, bool) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return nil, domain.Volume{}, false
+	}
+
+	volume, exists := volumes.Find(service, c.Param("volume"))
+	if !exists {
+		respondError(c, errors.NotFound("volume", c.Param("volume")))
+		return nil, domain.Volume{}, false
+	}
+
+	return service, volume, true
+}
+
+func (h *VolumeHandler) loadService(c *gin.Context) (*domain.Service, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return nil, false
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return service, true
+}
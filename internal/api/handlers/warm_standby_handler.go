@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/warmpool"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// WarmStandbyHandler handles warm standby capacity endpoints
+type WarmStandbyHandler struct {
+	pool        *warmpool.Pool
+	serviceRepo domain.ServiceRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewWarmStandbyHandler creates a new WarmStandbyHandler
+func NewWarmStandbyHandler(pool *warmpool.Pool, serviceRepo domain.ServiceRepository, eventBus domain.EventBus, log *logger.Logger) *WarmStandbyHandler {
+	return &WarmStandbyHandler{
+		pool:        pool,
+		serviceRepo: serviceRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// WarmStandbyResponse represents a service's warm standby and its cost at the current requests
+type WarmStandbyResponse struct {
+	WarmStandby *domain.WarmStandby `json:"warm_standby"`
+	Cost        *warmpool.Cost      `json:"cost,omitempty"`
+}
+
+// Get handles GET /services/:id/warm-standby
+func (h *WarmStandbyHandler) Get(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, h.response(service))
+}
+
+// Update handles PUT /services/:id/warm-standby
+// A body of {"replicas": 0} without prewarm_image is rejected; use DELETE to disable.
+func (h *WarmStandbyHandler) Update(c *gin.Context) {
+	var req domain.WarmStandby
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+	if err := warmpool.Validate(&req, service.Scaling); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	service.Scaling.WarmStandby = &req
+	h.save(c, service)
+}
+
+// Delete handles DELETE /services/:id/warm-standby
+func (h *WarmStandbyHandler) Delete(c *gin.Context) {
+	service, ok := h.loadService(c)
+	if !ok {
+		return
+	}
+
+	service.Scaling.WarmStandby = nil
+	h.save(c, service)
+}
+
+func (h *WarmStandbyHandler) save(c *gin.Context, service *domain.Service) {
+	if err := h.serviceRepo.Update(c.Request.Context(), service); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// Services that are not deployed yet get their pre-warming on first deploy
+	if err := h.pool.EnsurePrewarm(c.Request.Context(), service); err != nil && !errors.IsNotFound(err) {
+		respondError(c, err)
+		return
+	}
+
+	h.eventBus.Publish(c.Request.Context(), "service.updated", &domain.Event{
+		Type:   "service.updated",
+		Source: "api",
+		Data: map[string]interface{}{
+			"service_id": service.ID.String(),
+			"project_id": service.ProjectID.String(),
+		},
+	})
+
+	h.logger.Info().
+		Str("service_id", service.ID.String()).
+		Bool("enabled", service.Scaling.WarmStandby != nil).
+		Msg("Warm standby updated")
+
+	c.JSON(http.StatusOK, h.response(service))
+}
+
+func (h *WarmStandbyHandler) response(service *domain.Service) WarmStandbyResponse {
+	return WarmStandbyResponse{
+		WarmStandby: service.Scaling.WarmStandby,
+		Cost:        h.pool.Estimate(service, service.Resources),
+	}
+}
+
+func (h *WarmStandbyHandler) loadService(c *gin.Context) (*domain.Service, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid service ID"))
+		return nil, false
+	}
+
+	service, err := h.serviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+
+	return service, true
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/webhooks"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// WebhookSubscriptionHandler handles the outbound webhook subscriptions of projects
+type WebhookSubscriptionHandler struct {
+	repo        domain.WebhookRepository
+	projectRepo domain.ProjectRepository
+	dispatcher  *webhooks.Dispatcher
+	logger      *logger.Logger
+}
+
+// NewWebhookSubscriptionHandler creates a new WebhookSubscriptionHandler
+func NewWebhookSubscriptionHandler(repo domain.WebhookRepository, projectRepo domain.ProjectRepository, dispatcher *webhooks.Dispatcher, log *logger.Logger) *WebhookSubscriptionHandler {
+	return &WebhookSubscriptionHandler{
+		repo:        repo,
+		projectRepo: projectRepo,
+		dispatcher:  dispatcher,
+		logger:      log,
+	}
+}
+
+// CreateWebhookSubscriptionRequest represents the request body for subscribing an endpoint
+type CreateWebhookSubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required,url"`
+	EventTypes  []string `json:"event_types" binding:"required,min=1"`
+	Description string   `json:"description,omitempty"`
+}
+
+// UpdateWebhookSubscriptionRequest represents the request body for changing a subscription
+type UpdateWebhookSubscriptionRequest struct {
+	URL         *string  `json:"url,omitempty" binding:"omitempty,url"`
+	EventTypes  []string `json:"event_types,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// Create handles POST /projects/:project_id/webhook-subscriptions. The
+// response is the only one that includes the signing secret.
+func (h *WebhookSubscriptionHandler) Create(c *gin.Context) {
+	var req CreateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+	if err := validateWebhook(req.URL, req.EventTypes); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.authorize(c, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		respondError(c, errors.Wrap(err, "failed to generate webhook secret"))
+		return
+	}
+
+	now := time.Now()
+	subscription := &domain.WebhookSubscription{
+		ID:          uuid.New(),
+		ProjectID:   projectID,
+		URL:         req.URL,
+		EventTypes:  req.EventTypes,
+		Secret:      secret,
+		Description: req.Description,
+		Active:      true,
+		CreatedBy:   userID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := h.repo.Create(ctx, subscription); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().
+		Str("subscription_id", subscription.ID.String()).
+		Str("project_id", projectID.String()).
+		Interface("event_types", subscription.EventTypes).
+		Msg("Webhook subscription created")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"subscription": subscription,
+		"secret":       secret,
+	})
+}
+
+// ListByProject handles GET /projects/:project_id/webhook-subscriptions
+func (h *WebhookSubscriptionHandler) ListByProject(c *gin.Context) {
+	projectID, err := uuid.Parse(c.Param("project_id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid project ID"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.authorize(c, projectID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+	after, ok := parseCursor(c)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := h.repo.ListByProjectAfter(ctx, projectID, after, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":        subscriptions,
+		"count":       len(subscriptions),
+		"limit":       limit,
+		"next_cursor": nextCursor(subscriptions, limit, webhookCursor),
+	})
+}
+
+// Get handles GET /webhook-subscriptions/:id
+func (h *WebhookSubscriptionHandler) Get(c *gin.Context) {
+	subscription, ok := h.subscription(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, subscription)
+}
+
+// Update handles PATCH /webhook-subscriptions/:id
+func (h *WebhookSubscriptionHandler) Update(c *gin.Context) {
+	var req UpdateWebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, bindError(err))
+		return
+	}
+
+	subscription, ok := h.subscription(c)
+	if !ok {
+		return
+	}
+
+	if req.URL != nil {
+		subscription.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		subscription.EventTypes = req.EventTypes
+	}
+	if req.Description != nil {
+		subscription.Description = *req.Description
+	}
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+	if err := validateWebhook(subscription.URL, subscription.EventTypes); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	if err := h.repo.Update(c.Request.Context(), subscription); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
+
+// Delete handles DELETE /webhook-subscriptions/:id
+func (h *WebhookSubscriptionHandler) Delete(c *gin.Context) {
+	subscription, ok := h.subscription(c)
+	if !ok {
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), subscription.ID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.logger.Info().Str("subscription_id", subscription.ID.String()).Msg("Webhook subscription deleted")
+	c.Status(http.StatusNoContent)
+}
+
+// Deliveries handles GET /webhook-subscriptions/:id/deliveries?limit=
+func (h *WebhookSubscriptionHandler) Deliveries(c *gin.Context) {
+	subscription, ok := h.subscription(c)
+	if !ok {
+		return
+	}
+
+	limit := parseIntQuery(c, "limit", 50)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	deliveries, err := h.repo.ListDeliveries(c.Request.Context(), subscription.ID, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  deliveries,
+		"count": len(deliveries),
+	})
+}
+
+// Test handles POST /webhook-subscriptions/:id/test, sending a webhook.test
+// event once, whether or not the subscription is active
+func (h *WebhookSubscriptionHandler) Test(c *gin.Context) {
+	subscription, ok := h.subscription(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.dispatcher.Test(c.Request.Context(), subscription)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func (h *WebhookSubscriptionHandler) subscription(c *gin.Context) (*domain.WebhookSubscription, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, errors.BadRequest("invalid webhook subscription ID"))
+		return nil, false
+	}
+
+	subscription, err := h.repo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	if err := h.authorize(c, subscription.ProjectID); err != nil {
+		respondError(c, err)
+		return nil, false
+	}
+	return subscription, true
+}
+
+// authorize lets the owner of a project and admins manage its subscriptions
+func (h *WebhookSubscriptionHandler) authorize(c *gin.Context, projectID uuid.UUID) error {
+	project, err := h.projectRepo.GetByID(c.Request.Context(), projectID)
+	if err != nil {
+		return err
+	}
+	if userID, _ := c.Get("user_id"); userID != project.OwnerID && !isAdmin(c) {
+		return errors.Forbidden("no access to project " + projectID.String())
+	}
+	return nil
+}
+
+// validateWebhook checks that a subscription posts to a public HTTPS
+// endpoint and names known event types. Hostnames are checked again when
+// deliveries resolve them.
+func validateWebhook(endpoint string, eventTypes []string) error {
+	var fields []errors.FieldError
+	if u, err := url.Parse(endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+		fields = append(fields, errors.FieldError{Field: "url", Message: "must be an https URL"})
+	} else if privateHost(u.Hostname()) {
+		fields = append(fields, errors.FieldError{Field: "url", Message: "must be a public host"})
+	}
+	if len(eventTypes) == 0 {
+		fields = append(fields, errors.FieldError{Field: "event_types", Message: "must name at least one event type"})
+	}
+	for _, eventType := range eventTypes {
+		if !webhooks.ValidEventType(eventType) {
+			fields = append(fields, errors.FieldError{
+				Field:   "event_types",
+				Message: "unknown event type " + eventType + ", expected * or one of " + strings.Join(webhooks.EventTypes, ", "),
+			})
+		}
+	}
+	if len(fields) > 0 {
+		return errors.Validation(fields)
+	}
+	return nil
+}
+
+// privateHost reports whether a URL host is localhost or a private address
+func privateHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	addr, err := netip.ParseAddr(host)
+	return err == nil && webhooks.PrivateAddress(addr)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+type webhookProjects struct {
+	domain.ProjectRepository
+	project *domain.Project
+}
+
+func (f webhookProjects) GetByID(context.Context, uuid.UUID) (*domain.Project, error) {
+	return f.project, nil
+}
+
+type webhookSubscriptions struct {
+	domain.WebhookRepository
+	subscription *domain.WebhookSubscription
+}
+
+func (f webhookSubscriptions) GetByID(context.Context, uuid.UUID) (*domain.WebhookSubscription, error) {
+	return f.subscription, nil
+}
+
+func TestWebhookSubscriptionOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	project := &domain.Project{ID: uuid.New(), OwnerID: uuid.New()}
+	subscription := &domain.WebhookSubscription{ID: uuid.New(), ProjectID: project.ID, URL: "https://hooks.example.com"}
+	h := NewWebhookSubscriptionHandler(webhookSubscriptions{subscription: subscription}, webhookProjects{project: project}, nil, logger.New("error", "json", io.Discard))
+
+	get := func(userID uuid.UUID, role domain.UserRole) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/webhook-subscriptions/"+subscription.ID.String(), nil)
+		c.Params = gin.Params{{Key: "id", Value: subscription.ID.String()}}
+		c.Set("user_id", userID)
+		c.Set("user_role", role)
+		h.Get(c)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get(project.OwnerID, domain.UserRoleMember))
+	assert.Equal(t, http.StatusForbidden, get(uuid.New(), domain.UserRoleMember))
+	assert.Equal(t, http.StatusOK, get(uuid.New(), domain.UserRoleAdmin))
+}
+
+func TestValidateWebhookRejectsPrivateHosts(t *testing.T) {
+	events := []string{"deploy.completed"}
+	assert.NoError(t, validateWebhook("https://hooks.example.com/northstack", events))
+	for _, endpoint := range []string{
+		"http://hooks.example.com",
+		"https://localhost:8443/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.0.0.5/hook",
+		"https://[::1]/hook",
+	} {
+		assert.Error(t, validateWebhook(endpoint, events), endpoint)
+	}
+}
@@ -37,10 +37,7 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := extractToken(c)
 		if token == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"code":    errors.CodeUnauthorized,
-				"message": "authentication required",
-			})
+			abortWithError(c, errors.Unauthorized("authentication required"))
 			return
 		}
 
@@ -60,10 +57,7 @@ func (m *AuthMiddleware) RequireRole(roles ...domain.UserRole) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("user_role")
 		if !exists {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-				"code":    errors.CodeForbidden,
-				"message": "access denied",
-			})
+			abortWithError(c, errors.Forbidden("access denied"))
 			return
 		}
 
@@ -75,10 +69,7 @@ func (m *AuthMiddleware) RequireRole(roles ...domain.UserRole) gin.HandlerFunc {
 			}
 		}
 
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
-			"code":    errors.CodeForbidden,
-			"message": "insufficient permissions",
-		})
+		abortWithError(c, errors.Forbidden("insufficient permissions"))
 	}
 }
 
@@ -97,27 +88,18 @@ func (m *AuthMiddleware) validateJWT(c *gin.Context, token string) {
 	// This is a placeholder - implement proper JWT validation
 	userID, err := uuid.Parse(token) // This is wrong - just for demonstration
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"code":    errors.CodeUnauthorized,
-			"message": "invalid token",
-		})
+		abortWithError(c, errors.Unauthorized("invalid token"))
 		return
 	}
 
 	user, err := m.userRepo.GetByID(context.Background(), userID)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"code":    errors.CodeUnauthorized,
-			"message": "user not found",
-		})
+		abortWithError(c, errors.Unauthorized("user not found"))
 		return
 	}
 
 	if !user.IsActive {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"code":    errors.CodeUnauthorized,
-			"message": "user account is disabled",
-		})
+		abortWithError(c, errors.Unauthorized("user account is disabled"))
 		return
 	}
 
@@ -132,10 +114,7 @@ func (m *AuthMiddleware) validateJWT(c *gin.Context, token string) {
 // validateAPIKey validates an API key
 func (m *AuthMiddleware) validateAPIKey(c *gin.Context, apiKey string) {
 	if !m.config.APIKeyEnabled {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"code":    errors.CodeUnauthorized,
-			"message": "API key authentication is disabled",
-		})
+		abortWithError(c, errors.Unauthorized("API key authentication is disabled"))
 		return
 	}
 
@@ -150,10 +129,7 @@ func (m *AuthMiddleware) validateAPIKey(c *gin.Context, apiKey string) {
 	// Extract user ID from key (placeholder implementation)
 	keyParts := strings.Split(apiKey, "_")
 	if len(keyParts) < 2 {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-			"code":    errors.CodeUnauthorized,
-			"message": "invalid API key format",
-		})
+		abortWithError(c, errors.Unauthorized("invalid API key format"))
 		return
 	}
 
@@ -206,63 +182,6 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-// RateLimitMiddleware handles rate limiting
-type RateLimitMiddleware struct {
-	config *config.AuthConfig
-	logger *logger.Logger
-	// In production, use Redis or similar for distributed rate limiting
-}
-
-// NewRateLimitMiddleware creates a new RateLimitMiddleware
-func NewRateLimitMiddleware(cfg *config.AuthConfig, log *logger.Logger) *RateLimitMiddleware {
-	return &RateLimitMiddleware{
-		config: cfg,
-		logger: log,
-	}
-}
-
-// RateLimit returns a middleware that enforces rate limiting
-func (m *RateLimitMiddleware) RateLimit() gin.HandlerFunc {
-	if !m.config.RateLimitEnabled {
-		return func(c *gin.Context) {
-			c.Next()
-		}
-	}
-
-	// Simple in-memory rate limiter
-	// In production, use a distributed rate limiter
-	type clientInfo struct {
-		requests int
-		window   time.Time
-	}
-	clients := make(map[string]*clientInfo)
-
-	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
-
-		info, exists := clients[clientIP]
-		if !exists || time.Since(info.window) > m.config.RateLimitWindow {
-			clients[clientIP] = &clientInfo{
-				requests: 1,
-				window:   time.Now(),
-			}
-			c.Next()
-			return
-		}
-
-		info.requests++
-		if info.requests > m.config.RateLimitRequests {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"code":    errors.CodeRateLimited,
-				"message": "rate limit exceeded",
-			})
-			return
-		}
-
-		c.Next()
-	}
-}
-
 // CORSMiddleware handles CORS
 func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,301 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/cache"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// IdempotencyKeyHeader names the header clients set to make a POST safe to retry
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// githubDeliveryHeader identifies a GitHub webhook delivery; redeliveries reuse it
+	githubDeliveryHeader = "X-GitHub-Delivery"
+
+	maxIdempotencyKeyLength = 255
+	// maxIdempotentBody bounds the request and response bodies that are
+	// remembered; larger requests are processed without idempotency
+	maxIdempotentBody = 1 << 20
+)
+
+// replayedHeaders are the response headers stored with a response
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// IdempotencyStore remembers the responses of requests made with an
+// Idempotency-Key. cache.DragonflyDB implements it for distributed deployments.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*cache.IdempotentResponse, error)
+	Complete(ctx context.Context, key string, response *cache.IdempotentResponse, ttl time.Duration) error
+	Release(ctx context.Context, key string) error
+}
+
+// IdempotencyMiddleware replays the response of a POST retried with the same
+// Idempotency-Key instead of processing it again
+type IdempotencyMiddleware struct {
+	store  IdempotencyStore
+	ttl    time.Duration
+	logger *logger.Logger
+}
+
+// NewIdempotencyMiddleware creates a new IdempotencyMiddleware that remembers
+// responses for ttl. If store is nil, an in-process store is used.
+func NewIdempotencyMiddleware(store IdempotencyStore, ttl time.Duration, log *logger.Logger) *IdempotencyMiddleware {
+	if store == nil {
+		store = NewMemoryIdempotencyStore()
+	}
+	return &IdempotencyMiddleware{
+		store:  store,
+		ttl:    ttl,
+		logger: log,
+	}
+}
+
+// Idempotency returns the middleware. Keys are scoped to the credential of
+// the request, or to the client IP without one. Responses with a 5xx status
+// are not remembered, so that the retry is processed again.
+func (m *IdempotencyMiddleware) Idempotency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.handle(c, c.GetHeader(IdempotencyKeyHeader), IdempotencyKeyHeader)
+	}
+}
+
+// GitHubDeliveries returns the middleware of the GitHub webhook route, which
+// uses the delivery ID as the key when the request has no Idempotency-Key, so
+// that redeliveries are answered once. Keys are scoped like Idempotency's.
+func (m *IdempotencyMiddleware) GitHubDeliveries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(IdempotencyKeyHeader) != "" {
+			// Already handled by Idempotency
+			c.Next()
+			return
+		}
+		m.handle(c, c.GetHeader(githubDeliveryHeader), githubDeliveryHeader)
+	}
+}
+
+// handle replays the response remembered for idempotencyKey, read from
+// header, or processes the request and remembers its response
+func (m *IdempotencyMiddleware) handle(c *gin.Context, idempotencyKey, header string) {
+	if c.Request.Method != http.MethodPost || m.ttl <= 0 || idempotencyKey == "" {
+		c.Next()
+		return
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		abortWithError(c, errors.BadRequest(header+" must be at most 255 characters"))
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentBody+1))
+	if err != nil {
+		abortWithError(c, errors.BadRequest("failed to read request body"))
+		return
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if len(body) > maxIdempotentBody {
+		c.Next()
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := idempotencyScope(c) + ":" + idempotencyKey
+	if header == githubDeliveryHeader {
+		key = idempotencyScope(c) + ":github:" + idempotencyKey
+	}
+	fingerprint := requestFingerprint(c.Request.Method, c.Request.URL.Path, body)
+
+	stored, err := m.store.Reserve(ctx, key, fingerprint, m.ttl)
+	if err != nil {
+		// Fail open like the rate limiter: the store must not take the API down
+		m.logger.Warn().Err(err).Msg("Idempotency check failed")
+		c.Next()
+		return
+	}
+	if stored != nil {
+		m.replay(c, stored, fingerprint)
+		return
+	}
+
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	completed := false
+	defer func() {
+		// Release on panics too, so the request can be retried
+		if !completed {
+			if err := m.store.Release(context.Background(), key); err != nil {
+				m.logger.Warn().Err(err).Msg("Failed to release idempotency key")
+			}
+		}
+	}()
+
+	c.Next()
+
+	status := recorder.Status()
+	if status >= http.StatusInternalServerError || recorder.overflow {
+		return
+	}
+	response := &cache.IdempotentResponse{
+		Fingerprint: fingerprint,
+		Completed:   true,
+		Status:      status,
+		Header:      make(map[string]string),
+		Body:        recorder.body.Bytes(),
+	}
+	for _, name := range replayedHeaders {
+		if value := recorder.Header().Get(name); value != "" {
+			response.Header[name] = value
+		}
+	}
+	if err := m.store.Complete(context.Background(), key, response, m.ttl); err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to store idempotent response")
+		return
+	}
+	completed = true
+}
+
+// replay answers a request whose key was used before
+func (m *IdempotencyMiddleware) replay(c *gin.Context, stored *cache.IdempotentResponse, fingerprint string) {
+	switch {
+	case stored.Fingerprint != fingerprint:
+		abortWithError(c, errors.NewError(errors.CodeInvalidInput, "Idempotency-Key was already used for a different request", http.StatusUnprocessableEntity))
+	case !stored.Completed:
+		c.Header("Retry-After", "1")
+		abortWithError(c, errors.NewError(errors.CodeConflict, "a request with this Idempotency-Key is still being processed", http.StatusConflict))
+	default:
+		for name, value := range stored.Header {
+			c.Header(name, value)
+		}
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Status(stored.Status)
+		c.Writer.Write(stored.Body)
+		c.Abort()
+	}
+}
+
+// idempotencyScope keeps clients from seeing each other's responses
+func idempotencyScope(c *gin.Context) string {
+	if token := extractToken(c); token != "" {
+		sum := sha256.Sum256([]byte(token))
+		return "idempotency:token:" + hex.EncodeToString(sum[:])
+	}
+	return "idempotency:ip:" + c.ClientIP()
+}
+
+func requestFingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder copies what a handler writes so that it can be stored
+type responseRecorder struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool // The body was too large to store
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.record(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.record([]byte(s))
+	return r.ResponseWriter.WriteString(s)
+}
+
+func (r *responseRecorder) record(data []byte) {
+	if r.overflow {
+		return
+	}
+	if r.body.Len()+len(data) > maxIdempotentBody {
+		r.overflow = true
+		r.body.Reset()
+		return
+	}
+	r.body.Write(data)
+}
+
+// memoryIdempotencySweepInterval is how often MemoryIdempotencyStore drops
+// expired entries
+const memoryIdempotencySweepInterval = time.Minute
+
+// MemoryIdempotencyStore is an in-process store for single-instance deployments
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+type memoryIdempotencyEntry struct {
+	response  cache.IdempotentResponse
+	expiresAt time.Time
+}
+
+// NewMemoryIdempotencyStore creates a new MemoryIdempotencyStore
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		entries:   make(map[string]memoryIdempotencyEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// sweep drops expired entries, so keys that are never retried do not pile up
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// Reserve claims key for a request with the given fingerprint, or returns the
+// record of the request that claimed it
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*cache.IdempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= memoryIdempotencySweepInterval {
+		s.sweep(now)
+	}
+
+	if entry, ok := s.entries[key]; ok && !now.After(entry.expiresAt) {
+		response := entry.response
+		return &response, nil
+	}
+	s.entries[key] = memoryIdempotencyEntry{
+		response:  cache.IdempotentResponse{Fingerprint: fingerprint},
+		expiresAt: now.Add(ttl),
+	}
+	return nil, nil
+}
+
+// Complete stores the response of a reserved key
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, response *cache.IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryIdempotencyEntry{response: *response, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Release drops a reserved key
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
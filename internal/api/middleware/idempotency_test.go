@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func idempotentRouter(calls *int, status int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	m := NewIdempotencyMiddleware(nil, time.Hour, logger.New("error", "json", io.Discard))
+	router := gin.New()
+	router.Use(m.Idempotency())
+	router.POST("/services", func(c *gin.Context) {
+		*calls++
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Header("Location", "/services/1")
+		c.JSON(status, gin.H{"call": *calls, "body": string(body)})
+	})
+	return router
+}
+
+func post(router *gin.Engine, key, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/services", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	calls := 0
+	router := idempotentRouter(&calls, http.StatusCreated)
+
+	first := post(router, "abc", "alice", `{"name":"api"}`)
+	second := post(router, "abc", "alice", `{"name":"api"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "/services/1", second.Header().Get("Location"))
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	// Keys are scoped to the credential
+	post(router, "abc", "bob", `{"name":"api"}`)
+	assert.Equal(t, 2, calls)
+
+	// Requests without a key are always processed
+	post(router, "", "alice", `{"name":"api"}`)
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotencyRejectsReuseForDifferentRequest(t *testing.T) {
+	calls := 0
+	router := idempotentRouter(&calls, http.StatusCreated)
+
+	post(router, "abc", "alice", `{"name":"api"}`)
+	w := post(router, "abc", "alice", `{"name":"web"}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyRetriesServerErrors(t *testing.T) {
+	calls := 0
+	router := idempotentRouter(&calls, http.StatusBadGateway)
+
+	post(router, "abc", "alice", `{}`)
+	post(router, "abc", "alice", `{}`)
+
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeysGitHubDeliveriesOnTheirRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := NewIdempotencyMiddleware(nil, time.Hour, logger.New("error", "json", io.Discard))
+	router := gin.New()
+	router.Use(m.Idempotency())
+	calls := map[string]int{}
+	handler := func(c *gin.Context) {
+		calls[c.FullPath()]++
+		c.Status(http.StatusAccepted)
+	}
+	router.POST("/webhooks/github", m.GitHubDeliveries(), handler)
+	router.POST("/services", handler)
+
+	deliver := func(path string) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"action":"opened"}`))
+		req.Header.Set(githubDeliveryHeader, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	deliver("/webhooks/github")
+	deliver("/webhooks/github")
+	deliver("/services")
+	deliver("/services")
+
+	assert.Equal(t, 1, calls["/webhooks/github"], "redeliveries are answered once")
+	assert.Equal(t, 2, calls["/services"], "the delivery header is not a key elsewhere")
+}
+
+func TestMemoryIdempotencyStoreSweepsExpiredEntries(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	ctx := context.Background()
+
+	_, err := store.Reserve(ctx, "idempotency:ip:192.0.2.1:a", "f", 10*time.Millisecond)
+	require.NoError(t, err)
+	_, err = store.Reserve(ctx, "idempotency:ip:192.0.2.2:b", "f", time.Hour)
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	// An expired key is free again before it is swept
+	stored, err := store.Reserve(ctx, "idempotency:ip:192.0.2.1:a", "g", 10*time.Millisecond)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	time.Sleep(20 * time.Millisecond)
+	store.lastSweep = time.Now().Add(-memoryIdempotencySweepInterval)
+	_, err = store.Reserve(ctx, "idempotency:ip:192.0.2.3:c", "f", time.Hour)
+	require.NoError(t, err)
+
+	assert.NotContains(t, store.entries, "idempotency:ip:192.0.2.1:a")
+	assert.Contains(t, store.entries, "idempotency:ip:192.0.2.2:b")
+	assert.Contains(t, store.entries, "idempotency:ip:192.0.2.3:c")
+}
@@ -0,0 +1,14 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// abortWithError stops the request with err as RFC 7807 problem details
+func abortWithError(c *gin.Context, err error) {
+	problem := errors.NewProblem(err, c.Request.URL.Path)
+
+	c.Header("Content-Type", errors.ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
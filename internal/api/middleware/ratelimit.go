@@ -2,11 +2,19 @@
 package middleware
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/cache"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
 	"golang.org/x/time/rate"
 )
 
@@ -117,10 +125,7 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 			c.Header("X-RateLimit-Limit", string(rune(rl.config.RequestsPerSecond)))
 			c.Header("X-RateLimit-Remaining", "0")
 			c.Header("Retry-After", "1")
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error":   "rate_limit_exceeded",
-				"message": "Too many requests. Please slow down.",
-			})
+			abortWithError(c, errors.NewError(errors.CodeRateLimitExceeded, "Too many requests. Please slow down.", http.StatusTooManyRequests))
 			return
 		}
 
@@ -144,3 +149,194 @@ func IPRateLimiter(requestsPerSecond int) gin.HandlerFunc {
 	})
 	return rl.Middleware()
 }
+
+// RateLimitStore records requests against a rate limit window.
+// cache.DragonflyDB implements it for distributed deployments.
+type RateLimitStore interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (*cache.RateLimitResult, error)
+}
+
+// RateLimitMiddleware enforces AuthConfig rate limits per IP and per token
+type RateLimitMiddleware struct {
+	config *config.AuthConfig
+	store  RateLimitStore
+	logger *logger.Logger
+}
+
+// NewRateLimitMiddleware creates a new RateLimitMiddleware.
+// If store is nil, an in-process store is used.
+func NewRateLimitMiddleware(cfg *config.AuthConfig, store RateLimitStore, log *logger.Logger) *RateLimitMiddleware {
+	if store == nil {
+		store = NewMemoryRateLimitStore()
+	}
+	return &RateLimitMiddleware{
+		config: cfg,
+		store:  store,
+		logger: log,
+	}
+}
+
+// RateLimit returns a middleware that limits requests per client IP.
+// Requests carrying credentials get the authenticated limit, others the anonymous one.
+// Nothing checks the credentials at this point, so routes that must hold
+// anonymous clients to the anonymous limit also use RateLimitAnonymous.
+func (m *RateLimitMiddleware) RateLimit() gin.HandlerFunc {
+	if !m.config.RateLimitEnabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		limit := m.config.RateLimitAnonymousRequests
+		if extractToken(c) != "" {
+			limit = m.config.RateLimitRequests
+		}
+		m.enforce(c, "ratelimit:ip:"+c.ClientIP(), limit)
+	}
+}
+
+// RateLimitAnonymous returns a middleware that limits requests per client IP
+// with the anonymous limit, whatever credentials they carry. It guards the
+// routes that authenticate, such as login, where a made-up Authorization
+// header must not buy the authenticated limit.
+func (m *RateLimitMiddleware) RateLimitAnonymous() gin.HandlerFunc {
+	if !m.config.RateLimitEnabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		m.enforce(c, "ratelimit:anonymous:"+c.ClientIP(), m.config.RateLimitAnonymousRequests)
+	}
+}
+
+// RateLimitToken returns a middleware that limits requests per credential.
+// It must run after RequireAuth.
+func (m *RateLimitMiddleware) RateLimitToken() gin.HandlerFunc {
+	if !m.config.RateLimitEnabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		token := extractToken(c)
+		if token == "" {
+			c.Next()
+			return
+		}
+		sum := sha256.Sum256([]byte(token))
+		m.enforce(c, "ratelimit:token:"+hex.EncodeToString(sum[:]), m.config.RateLimitRequests)
+	}
+}
+
+// enforce checks the limit for key and writes the standard rate limit headers
+func (m *RateLimitMiddleware) enforce(c *gin.Context, key string, limit int) {
+	if limit <= 0 {
+		c.Next()
+		return
+	}
+
+	result, err := m.store.Allow(c.Request.Context(), key, limit, m.config.RateLimitWindow)
+	if err != nil {
+		// Fail open: an unavailable limiter must not take the API down
+		m.logger.Warn().Err(err).Msg("Rate limit check failed")
+		c.Next()
+		return
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+
+	if !result.Allowed {
+		retryAfter := int(time.Until(result.ResetAt).Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		abortWithError(c, errors.NewError(errors.CodeRateLimited, "rate limit exceeded", http.StatusTooManyRequests))
+		return
+	}
+
+	c.Next()
+}
+
+// memoryRateLimitSweepInterval is how often MemoryRateLimitStore drops the
+// windows of keys that have gone idle
+const memoryRateLimitSweepInterval = time.Minute
+
+// MemoryRateLimitStore is an in-process sliding window store for single-instance deployments
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	lastSweep time.Time
+}
+
+// memoryWindow holds the recent requests of one key
+type memoryWindow struct {
+	hits   []time.Time
+	window time.Duration
+}
+
+// NewMemoryRateLimitStore creates a new MemoryRateLimitStore
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		windows:   make(map[string]*memoryWindow),
+		lastSweep: time.Now(),
+	}
+}
+
+// sweep drops the keys whose last request has left their window, so keys
+// that are never seen again, such as past client IPs, do not pile up
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	for key, w := range s.windows {
+		if len(w.hits) == 0 || !w.hits[len(w.hits)-1].After(now.Add(-w.window)) {
+			delete(s.windows, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// Allow records a request against a sliding window rate limit
+func (s *MemoryRateLimitStore) Allow(ctx context.Context, key string, limit int, window time.Duration) (*cache.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= memoryRateLimitSweepInterval {
+		s.sweep(now)
+	}
+	cutoff := now.Add(-window)
+
+	var hits []time.Time
+	if w, ok := s.windows[key]; ok {
+		hits = w.hits
+	}
+	i := 0
+	for i < len(hits) && !hits[i].After(cutoff) {
+		i++
+	}
+	hits = hits[i:]
+
+	allowed := len(hits) < limit
+	if allowed {
+		hits = append(hits, now)
+	}
+	if len(hits) == 0 {
+		delete(s.windows, key)
+	} else {
+		s.windows[key] = &memoryWindow{hits: hits, window: window}
+	}
+
+	resetAt := now.Add(window)
+	if len(hits) > 0 {
+		resetAt = hits[0].Add(window)
+	}
+
+	return &cache.RateLimitResult{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: limit - len(hits),
+		ResetAt:   resetAt,
+	}, nil
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rateLimitedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	m := NewRateLimitMiddleware(&config.AuthConfig{
+		RateLimitEnabled:           true,
+		RateLimitRequests:          10,
+		RateLimitAnonymousRequests: 2,
+		RateLimitWindow:            time.Minute,
+	}, nil, logger.New("error", "json", io.Discard))
+	router := gin.New()
+	router.Use(m.RateLimit())
+	router.POST("/auth/login", m.RateLimitAnonymous(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestLoginStaysOnAnonymousLimitWithMadeUpCredentials(t *testing.T) {
+	router := rateLimitedRouter()
+
+	codes := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		req.Header.Set("Authorization", "Bearer made-up")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+}
+
+func TestMemoryRateLimitStoreSweepsIdleKeys(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	ctx := context.Background()
+
+	_, err := store.Allow(ctx, "ratelimit:ip:192.0.2.1", 5, 10*time.Millisecond)
+	require.NoError(t, err)
+	_, err = store.Allow(ctx, "ratelimit:ip:192.0.2.2", 5, time.Hour)
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+	store.lastSweep = time.Now().Add(-memoryRateLimitSweepInterval)
+	_, err = store.Allow(ctx, "ratelimit:ip:192.0.2.3", 5, time.Hour)
+	require.NoError(t, err)
+
+	assert.NotContains(t, store.windows, "ratelimit:ip:192.0.2.1")
+	assert.Contains(t, store.windows, "ratelimit:ip:192.0.2.2")
+	assert.Contains(t, store.windows, "ratelimit:ip:192.0.2.3")
+}
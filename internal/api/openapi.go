@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/northstack/platform/internal/api/handlers"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/openapi"
+)
+
+// apiVersion is the version of the API document
+const apiVersion = "1.0.0"
+
+// operations documents the bodies of the core resources. Routes missing here
+// are still in the document, with their parameters but without bodies.
+var operations = []openapi.Annotation{
+	// Auth
+	{Method: http.MethodPost, Path: "/api/v1/auth/login", Summary: "Log in", Request: handlers.LoginRequest{}, Response: handlers.AuthResponse{}},
+	{Method: http.MethodPost, Path: "/api/v1/auth/register", Summary: "Register a user", Request: handlers.RegisterRequest{}, Response: handlers.AuthResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/api/v1/auth/refresh", Summary: "Exchange a refresh token", Request: struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}{}, Response: handlers.AuthResponse{}},
+	{Method: http.MethodGet, Path: "/api/v1/users/me", Summary: "Get the current user", Response: domain.User{}},
+	{Method: http.MethodPatch, Path: "/api/v1/users/me", Summary: "Update the current user", Request: struct {
+		Name string `json:"name"`
+	}{}, Response: domain.User{}},
+
+	// Projects
+	{Method: http.MethodPost, Path: "/api/v1/projects", Summary: "Create a project", Request: handlers.CreateProjectRequest{}, Response: handlers.ProjectResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/v1/projects", Summary: "List projects", Response: handlers.ProjectResponse{}, List: true},
+	{Method: http.MethodGet, Path: "/api/v1/projects/:id", Summary: "Get a project", Response: handlers.ProjectResponse{}},
+	{Method: http.MethodGet, Path: "/api/v1/projects/slug/:slug", Summary: "Get a project by slug", Response: handlers.ProjectResponse{}},
+	{Method: http.MethodPatch, Path: "/api/v1/projects/:id", Summary: "Update a project", Request: handlers.UpdateProjectRequest{}, Response: handlers.ProjectResponse{}},
+	{Method: http.MethodDelete, Path: "/api/v1/projects/:id", Summary: "Delete a project", Status: http.StatusNoContent},
+
+	// Services
+	{Method: http.MethodPost, Path: "/api/v1/projects/:project_id/services", Summary: "Create a service", Request: handlers.CreateServiceRequest{}, Response: handlers.ServiceResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/v1/projects/:project_id/services", Summary: "List a project's services", Response: handlers.ServiceResponse{}, List: true},
+	{Method: http.MethodGet, Path: "/api/v1/services/:id", Summary: "Get a service", Response: handlers.ServiceResponse{}},
+	{Method: http.MethodPatch, Path: "/api/v1/services/:id", Summary: "Update a service", Request: map[string]interface{}{}, Response: handlers.ServiceResponse{}},
+	{Method: http.MethodDelete, Path: "/api/v1/services/:id", Summary: "Delete a service", Status: http.StatusNoContent},
+	{Method: http.MethodPost, Path: "/api/v1/services/:id/builds", Summary: "Trigger a build", Status: http.StatusAccepted},
+	{Method: http.MethodGet, Path: "/api/v1/services/:id/builds", Summary: "List a service's builds", Response: domain.Build{}, List: true},
+	{Method: http.MethodPut, Path: "/api/v1/services/:id/autoscaling", Summary: "Set a service's autoscaling", Request: handlers.ScalingConfigRequest{}, Response: domain.ScalingConfig{}},
+	{Method: http.MethodGet, Path: "/api/v1/services/:id/cron-job", Summary: "Get a cron job's schedule", Response: domain.CronJobConfig{}},
+	{Method: http.MethodPut, Path: "/api/v1/services/:id/cron-job", Summary: "Set a cron job's schedule", Request: domain.CronJobConfig{}, Response: domain.CronJobConfig{}},
+	{Method: http.MethodPost, Path: "/api/v1/services/:id/cron-job/suspend", Summary: "Stop scheduling a cron job's runs", Response: domain.CronJobConfig{}},
+	{Method: http.MethodPost, Path: "/api/v1/services/:id/cron-job/resume", Summary: "Resume scheduling a cron job's runs", Response: domain.CronJobConfig{}},
+	{Method: http.MethodPost, Path: "/api/v1/services/:id/cron-job/runs", Summary: "Start a cron job run now", Response: domain.CronJobRun{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/v1/services/:id/cron-job/runs", Summary: "List a cron job's runs", Response: domain.CronJobRun{}, List: true},
+	{Method: http.MethodPost, Path: "/api/v1/services/:id/jobs", Summary: "Run a one-off job with a service's image", Request: handlers.RunJobRequest{}, Response: domain.JobRun{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/v1/services/:id/jobs", Summary: "List a service's one-off jobs", Response: domain.JobRun{}, List: true},
+	{Method: http.MethodGet, Path: "/api/v1/services/:id/jobs/:job", Summary: "Get a one-off job", Response: domain.JobRun{}},
+	{Method: http.MethodPost, Path: "/api/v1/services/:id/jobs/:job/cancel", Summary: "Cancel a one-off job", Response: domain.JobRun{}},
+
+	// Deployments
+	{Method: http.MethodGet, Path: "/api/v1/services/:id/deployments", Summary: "List a service's deployments", Response: domain.Deployment{}, List: true},
+	{Method: http.MethodGet, Path: "/api/v1/deployments/:id", Summary: "Get a deployment", Response: domain.Deployment{}},
+	{Method: http.MethodPost, Path: "/api/v1/deployments/external", Summary: "Register an external deployment", Request: handlers.ExternalDeploymentRequest{}, Response: domain.Deployment{}, Status: http.StatusCreated},
+
+	// Environments
+	{Method: http.MethodPost, Path: "/api/v1/projects/:project_id/environments", Summary: "Create an environment", Request: handlers.CreateEnvironmentRequest{}, Response: domain.Environment{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/v1/projects/:project_id/environments", Summary: "List a project's environments", Response: domain.Environment{}, List: true},
+	{Method: http.MethodGet, Path: "/api/v1/environments/:id", Summary: "Get an environment", Response: domain.Environment{}},
+	{Method: http.MethodPatch, Path: "/api/v1/environments/:id", Summary: "Update an environment", Request: handlers.UpdateEnvironmentRequest{}, Response: domain.Environment{}},
+	{Method: http.MethodDelete, Path: "/api/v1/environments/:id", Summary: "Delete an environment", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/api/v1/environments/:id/egress", Summary: "Get an environment's egress IPs", Response: domain.Egress{}},
+	{Method: http.MethodPut, Path: "/api/v1/environments/:id/egress", Summary: "Configure static egress IPs", Request: handlers.EgressRequest{}, Response: domain.Egress{}},
+	{Method: http.MethodDelete, Path: "/api/v1/environments/:id/egress", Summary: "Remove static egress IPs", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/api/v1/environments/:id/lifecycle", Summary: "Get an environment's expiry and hibernation policy", Response: domain.EnvironmentLifecycle{}},
+	{Method: http.MethodPut, Path: "/api/v1/environments/:id/lifecycle", Summary: "Set an environment's expiry and hibernation policy", Request: handlers.LifecycleRequest{}, Response: domain.EnvironmentLifecycle{}},
+	{Method: http.MethodDelete, Path: "/api/v1/environments/:id/lifecycle", Summary: "Remove an environment's expiry and hibernation policy", Status: http.StatusNoContent},
+	{Method: http.MethodPost, Path: "/api/v1/environments/:id/hibernate", Summary: "Scale an environment's workloads to zero", Response: domain.Environment{}},
+	{Method: http.MethodPost, Path: "/api/v1/environments/:id/wake", Summary: "Scale a hibernated environment back up", Response: domain.Environment{}},
+
+	// Ingresses
+	{Method: http.MethodPost, Path: "/api/v1/services/:id/ingresses", Summary: "Create an ingress", Request: handlers.CreateIngressRequest{}, Response: domain.Ingress{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/v1/services/:id/ingresses", Summary: "List a service's ingresses", Response: domain.Ingress{}, List: true},
+	{Method: http.MethodGet, Path: "/api/v1/projects/:project_id/ingresses", Summary: "List a project's ingresses", Response: domain.Ingress{}, List: true},
+	{Method: http.MethodGet, Path: "/api/v1/ingresses/:id", Summary: "Get an ingress", Response: domain.Ingress{}},
+	{Method: http.MethodPatch, Path: "/api/v1/ingresses/:id", Summary: "Update an ingress", Request: handlers.UpdateIngressRequest{}, Response: domain.Ingress{}},
+	{Method: http.MethodDelete, Path: "/api/v1/ingresses/:id", Summary: "Delete an ingress", Status: http.StatusNoContent},
+
+	// Secrets
+	{Method: http.MethodPost, Path: "/api/v1/projects/:project_id/secrets", Summary: "Bind a Vault secret", Request: handlers.CreateSecretRequest{}, Response: domain.Secret{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/v1/projects/:project_id/secrets", Summary: "List a project's secrets", Response: domain.Secret{}, List: true},
+	{Method: http.MethodGet, Path: "/api/v1/secrets/:id", Summary: "Get a secret", Response: domain.Secret{}},
+	{Method: http.MethodDelete, Path: "/api/v1/secrets/:id", Summary: "Delete a secret", Status: http.StatusNoContent},
+
+	// Clusters
+	{Method: http.MethodPost, Path: "/api/v1/clusters", Summary: "Register a cluster", Request: handlers.CreateClusterRequest{}, Response: handlers.ClusterResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api/v1/clusters", Summary: "List clusters", Response: handlers.ClusterResponse{}, List: true},
+	{Method: http.MethodGet, Path: "/api/v1/clusters/:id", Summary: "Get a cluster", Response: handlers.ClusterResponse{}},
+	{Method: http.MethodPatch, Path: "/api/v1/clusters/:id", Summary: "Update a cluster", Request: handlers.UpdateClusterRequest{}, Response: handlers.ClusterResponse{}},
+	{Method: http.MethodDelete, Path: "/api/v1/clusters/:id", Summary: "Delete a cluster", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/api/v1/audit-logs", Summary: "List audit logs", Response: domain.AuditLog{}, List: true},
+}
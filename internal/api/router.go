@@ -2,41 +2,76 @@
 package api
 
 import (
-	"fmt"
-	"time"
-
 	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/activity"
+	"github.com/northstack/platform/internal/adapters/anticorruption"
+	"github.com/northstack/platform/internal/adapters/slack"
+	"github.com/northstack/platform/internal/alerting"
 	"github.com/northstack/platform/internal/api/handlers"
 	"github.com/northstack/platform/internal/api/middleware"
+	"github.com/northstack/platform/internal/approvals"
+	"github.com/northstack/platform/internal/artifacts"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/autoscaling"
+	"github.com/northstack/platform/internal/autotls"
+	"github.com/northstack/platform/internal/backstage"
+	"github.com/northstack/platform/internal/buildanalytics"
+	"github.com/northstack/platform/internal/capacity"
+	"github.com/northstack/platform/internal/catalog"
+	"github.com/northstack/platform/internal/clusterupgrade"
 	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/cronjobs"
+	"github.com/northstack/platform/internal/customdomains"
+	"github.com/northstack/platform/internal/dbupgrade"
+	"github.com/northstack/platform/internal/deploylinks"
+	"github.com/northstack/platform/internal/devcluster"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/dora"
+	"github.com/northstack/platform/internal/dualstack"
+	"github.com/northstack/platform/internal/egress"
+	"github.com/northstack/platform/internal/envlifecycle"
+	"github.com/northstack/platform/internal/export"
+	"github.com/northstack/platform/internal/ingressroutes"
+	"github.com/northstack/platform/internal/internalnet"
+	"github.com/northstack/platform/internal/jobs"
+	"github.com/northstack/platform/internal/keda"
+	"github.com/northstack/platform/internal/kubeconfigs"
+	"github.com/northstack/platform/internal/kubeevents"
+	"github.com/northstack/platform/internal/livefeed"
+	"github.com/northstack/platform/internal/logs"
+	"github.com/northstack/platform/internal/maintenance"
+	"github.com/northstack/platform/internal/manifest"
+	"github.com/northstack/platform/internal/metering"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/natsauth"
+	"github.com/northstack/platform/internal/notifications"
+	"github.com/northstack/platform/internal/openapi"
+	"github.com/northstack/platform/internal/placement"
+	"github.com/northstack/platform/internal/queuetime"
+	"github.com/northstack/platform/internal/releasehealth"
+	"github.com/northstack/platform/internal/residency"
+	"github.com/northstack/platform/internal/rightsizing"
+	"github.com/northstack/platform/internal/rke2"
+	"github.com/northstack/platform/internal/secretsync"
+	"github.com/northstack/platform/internal/sharelink"
+	"github.com/northstack/platform/internal/signing"
+	"github.com/northstack/platform/internal/templates"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/internal/tunnel"
+	"github.com/northstack/platform/internal/uptime"
+	"github.com/northstack/platform/internal/volumes"
+	"github.com/northstack/platform/internal/warmpool"
+	"github.com/northstack/platform/internal/webhooks"
+	"github.com/northstack/platform/internal/workflow"
+	"github.com/northstack/platform/pkg/dragonfly"
+	"github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-)
-
-var (
-	httpRequests = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Total number of HTTP requests",
-		},
-		[]string{"path", "method", "status"},
-	)
-	httpDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name: "http_request_duration_seconds",
-			Help: "Duration of HTTP requests in seconds",
-		},
-		[]string{"path", "method"},
-	)
+	"github.com/northstack/platform/pkg/messaging"
+	"github.com/northstack/platform/pkg/minio"
+	"github.com/northstack/platform/pkg/postgres"
+	"github.com/northstack/platform/pkg/yugabytedb"
 )
 
-func init() {
-	prometheus.MustRegister(httpRequests)
-	prometheus.MustRegister(httpDuration)
-}
-
 // Router holds all the dependencies for the API router
 type Router struct {
 	config      *config.Config
@@ -46,6 +81,451 @@ type Router struct {
 	userRepo    domain.UserRepository
 	eventBus    domain.EventBus
 	ciAdapter   domain.CIAdapter
+
+	// Optional dependencies, set via Option
+	clusterRepo    domain.ClusterRepository
+	envRepo        domain.EnvironmentRepository
+	ingressRepo    domain.IngressRepository
+	clusterManager domain.ClusterManagerAdapter
+	provisioner    *rke2.Provisioner
+	devClusters    *devcluster.Bootstrapper
+	drainer        *maintenance.Drainer
+	upgrader       *clusterupgrade.Upgrader
+	dbUpgrader     *dbupgrade.Upgrader
+	kubeconfigs    *kubeconfigs.Manager
+	rateLimitStore middleware.RateLimitStore
+	idempotency    middleware.IdempotencyStore
+	advisor        *rightsizing.Advisor
+	metrics        domain.MetricsCollector
+	logStreamer    *logs.Streamer
+	logStore       domain.LogStore
+	releaseHealth  *releasehealth.Scorer
+	doraReporter   *dora.Reporter
+	alertManager   *alerting.Manager
+	buildAnalyzer  *buildanalytics.Analyzer
+	buildRepo      domain.BuildRepository
+	deployRepo     domain.DeploymentRepository
+	kubeEvents     *kubeevents.Store
+	queueMonitor   *queuetime.Monitor
+	uptimeProber   *uptime.Prober
+	kedaManager    *keda.Manager
+	autoscaling    *autoscaling.Reconciler
+	meter          *metering.Meter
+	activityFeed   *activity.Feed
+	warmPool       *warmpool.Pool
+	stateMachine   *workflow.StateMachine
+	artifacts      *artifacts.Manager
+	notifications  *notifications.Center
+	preferenceRepo domain.NotificationPreferenceRepository
+	auditLogRepo   domain.AuditLogRepository
+	catalog        *catalog.Catalog
+	backstage      *backstage.Provider
+	secretRepo     domain.SecretRepository
+	replicator     *secretsync.Replicator
+	signer         *signing.Signer
+	egress         *egress.Manager
+	envLifecycle   *envlifecycle.Manager
+	cronJobs       *cronjobs.Manager
+	jobRunner      *jobs.Runner
+	volumes        *volumes.Manager
+	certs          *autotls.Manager
+	ingressRoutes  *ingressroutes.Manager
+	domainRepo     domain.DomainRepository
+	domains        *customdomains.Verifier
+	residency      *residency.Checker
+	scheduler      *placement.Scheduler
+	internalNet    *internalnet.Manager
+	eventHub       *livefeed.Hub
+	tunnels        *tunnel.Manager
+	approvals      *approvals.Gate
+	teamRepo       domain.TeamRepository
+	health         map[string]handlers.HealthCheck
+	deadLetters    domain.DeadLetterQueue
+	webhookRepo    domain.WebhookRepository
+	webhooks       *webhooks.Dispatcher
+	slack          *slack.Notifier
+	eventSchemas   domain.EventSchemaRegistry
+	eventCreds     *natsauth.Issuer
+	agentCreds     *natsauth.Issuer
+	secretWriter   anticorruption.SecretWriter
+	templateRepo   domain.TemplateRepository
+	databases      *yugabytedb.DatabaseService
+	postgres       *postgres.Service
+	caches         *dragonfly.CacheService
+	brokers        *messaging.BrokerService
+	storage        *minio.TenantService
+	storageSecrets domain.SecretsAdapter
+}
+
+// Option configures an optional Router dependency
+type Option func(*Router)
+
+// WithClusterRepository sets the cluster repository
+func WithClusterRepository(repo domain.ClusterRepository) Option {
+	return func(r *Router) { r.clusterRepo = repo }
+}
+
+// WithEnvironmentRepository enables the environment endpoints; they also require WithClusterRepository
+func WithEnvironmentRepository(repo domain.EnvironmentRepository) Option {
+	return func(r *Router) { r.envRepo = repo }
+}
+
+// WithIngressRepository enables the ingress endpoints
+func WithIngressRepository(repo domain.IngressRepository) Option {
+	return func(r *Router) { r.ingressRepo = repo }
+}
+
+// WithClusterManager provisions clusters and environment namespaces through the cluster manager
+func WithClusterManager(manager domain.ClusterManagerAdapter) Option {
+	return func(r *Router) { r.clusterManager = manager }
+}
+
+// WithRKE2Provisioner installs rke2 clusters created with nodes
+func WithRKE2Provisioner(provisioner *rke2.Provisioner) Option {
+	return func(r *Router) { r.provisioner = provisioner }
+}
+
+// WithDevClusters enables creating single-node k3s dev clusters
+func WithDevClusters(bootstrapper *devcluster.Bootstrapper) Option {
+	return func(r *Router) { r.devClusters = bootstrapper }
+}
+
+// WithClusterUpgrades enables the Kubernetes version upgrade endpoints
+func WithClusterUpgrades(upgrader *clusterupgrade.Upgrader) Option {
+	return func(r *Router) { r.upgrader = upgrader }
+}
+
+// WithKubeconfigs caches admin kubeconfigs and enables issuing short-lived
+// kubeconfigs to users with access to a cluster
+func WithKubeconfigs(manager *kubeconfigs.Manager) Option {
+	return func(r *Router) { r.kubeconfigs = manager }
+}
+
+// WithDrainer enables the node drain endpoints
+func WithDrainer(drainer *maintenance.Drainer) Option {
+	return func(r *Router) { r.drainer = drainer }
+}
+
+// WithRateLimitStore sets a shared store (e.g. DragonflyDB) for rate limiting
+func WithRateLimitStore(store middleware.RateLimitStore) Option {
+	return func(r *Router) { r.rateLimitStore = store }
+}
+
+// WithIdempotencyStore shares Idempotency-Key responses across replicas
+func WithIdempotencyStore(store middleware.IdempotencyStore) Option {
+	return func(r *Router) { r.idempotency = store }
+}
+
+// WithRightsizingAdvisor enables the right-sizing endpoints
+func WithRightsizingAdvisor(advisor *rightsizing.Advisor) Option {
+	return func(r *Router) { r.advisor = advisor }
+}
+
+// WithMetricsCollector enables the service metrics endpoints
+func WithMetricsCollector(collector domain.MetricsCollector) Option {
+	return func(r *Router) { r.metrics = collector }
+}
+
+// WithLogStreamer enables the service log endpoints
+func WithLogStreamer(streamer *logs.Streamer) Option {
+	return func(r *Router) { r.logStreamer = streamer }
+}
+
+// WithLogStore enables historical log search
+func WithLogStore(store domain.LogStore) Option {
+	return func(r *Router) { r.logStore = store }
+}
+
+// WithReleaseHealthScorer enables the release health endpoints
+func WithReleaseHealthScorer(scorer *releasehealth.Scorer) Option {
+	return func(r *Router) { r.releaseHealth = scorer }
+}
+
+// WithDORAReporter enables the delivery performance reporting endpoints
+func WithDORAReporter(reporter *dora.Reporter) Option {
+	return func(r *Router) { r.doraReporter = reporter }
+}
+
+// WithAlertManager enables the alert endpoints and Alertmanager receiver
+func WithAlertManager(manager *alerting.Manager) Option {
+	return func(r *Router) { r.alertManager = manager }
+}
+
+// WithBuildRepository persists triggered builds and enables the build history endpoint
+func WithBuildRepository(repo domain.BuildRepository) Option {
+	return func(r *Router) { r.buildRepo = repo }
+}
+
+// WithDeploymentRepository enables the deployment history endpoints
+func WithDeploymentRepository(repo domain.DeploymentRepository) Option {
+	return func(r *Router) { r.deployRepo = repo }
+}
+
+// WithBuildAnalyzer enables the build time analytics endpoints
+func WithBuildAnalyzer(analyzer *buildanalytics.Analyzer) Option {
+	return func(r *Router) { r.buildAnalyzer = analyzer }
+}
+
+// WithKubeEventStore enables the per-service Kubernetes events endpoint
+func WithKubeEventStore(store *kubeevents.Store) Option {
+	return func(r *Router) { r.kubeEvents = store }
+}
+
+// WithQueueTimeMonitor enables the queue time reporting endpoint
+func WithQueueTimeMonitor(monitor *queuetime.Monitor) Option {
+	return func(r *Router) { r.queueMonitor = monitor }
+}
+
+// WithUptimeProber enables the ingress uptime endpoints
+func WithUptimeProber(prober *uptime.Prober) Option {
+	return func(r *Router) { r.uptimeProber = prober }
+}
+
+// WithKEDAManager enables configuring event-driven autoscaling triggers
+func WithKEDAManager(manager *keda.Manager) Option {
+	return func(r *Router) { r.kedaManager = manager }
+}
+
+// WithAutoscaling applies autoscaling changes through the reconciler, which
+// also renders HorizontalPodAutoscalers for CPU and memory targets
+func WithAutoscaling(reconciler *autoscaling.Reconciler) Option {
+	return func(r *Router) { r.autoscaling = reconciler }
+}
+
+// WithMeter enables the usage reporting endpoint
+func WithMeter(meter *metering.Meter) Option {
+	return func(r *Router) { r.meter = meter }
+}
+
+// WithActivityFeed enables the project activity feed endpoint
+func WithActivityFeed(feed *activity.Feed) Option {
+	return func(r *Router) { r.activityFeed = feed }
+}
+
+// WithWarmPool enables the warm standby endpoints
+func WithWarmPool(pool *warmpool.Pool) Option {
+	return func(r *Router) { r.warmPool = pool }
+}
+
+// WithStateMachine enables the deployment rollout status endpoint
+func WithStateMachine(sm *workflow.StateMachine) Option {
+	return func(r *Router) { r.stateMachine = sm }
+}
+
+// WithArtifactManager enables the build artifact endpoints; they also require WithBuildRepository
+func WithArtifactManager(manager *artifacts.Manager) Option {
+	return func(r *Router) { r.artifacts = manager }
+}
+
+// WithNotificationCenter enables the notification inbox endpoints
+func WithNotificationCenter(center *notifications.Center) Option {
+	return func(r *Router) { r.notifications = center }
+}
+
+// WithNotificationPreferences enables the notification preference endpoints
+func WithNotificationPreferences(repo domain.NotificationPreferenceRepository) Option {
+	return func(r *Router) { r.preferenceRepo = repo }
+}
+
+// WithAuditLogRepository enables the audit log endpoint
+func WithAuditLogRepository(repo domain.AuditLogRepository) Option {
+	return func(r *Router) { r.auditLogRepo = repo }
+}
+
+// WithCatalog enables the service catalog endpoint
+func WithCatalog(c *catalog.Catalog) Option {
+	return func(r *Router) { r.catalog = c }
+}
+
+// WithBackstageProvider enables the Backstage catalog feed and entity provider endpoints
+func WithBackstageProvider(provider *backstage.Provider) Option {
+	return func(r *Router) { r.backstage = provider }
+}
+
+// WithSecretRepository enables the project secret endpoints
+func WithSecretRepository(repo domain.SecretRepository) Option {
+	return func(r *Router) { r.secretRepo = repo }
+}
+
+// WithSecretReplicator enables the secret replication endpoints
+func WithSecretReplicator(replicator *secretsync.Replicator) Option {
+	return func(r *Router) { r.replicator = replicator }
+}
+
+// WithSigner enables the JWKS endpoint for verifying webhook and event signatures
+func WithSigner(signer *signing.Signer) Option {
+	return func(r *Router) { r.signer = signer }
+}
+
+// WithAutoTLS issues the certificates of auto TLS ingresses
+func WithAutoTLS(manager *autotls.Manager) Option {
+	return func(r *Router) { r.certs = manager }
+}
+
+// WithInternalNetwork gives internal services DNS names and network policies
+func WithInternalNetwork(manager *internalnet.Manager) Option {
+	return func(r *Router) { r.internalNet = manager }
+}
+
+// WithIngressRoutes applies the ingress controller resources of ingresses to
+// workload clusters
+func WithIngressRoutes(manager *ingressroutes.Manager) Option {
+	return func(r *Router) { r.ingressRoutes = manager }
+}
+
+// WithCustomDomains enables custom domain verification; ingresses may then
+// only route verified domains
+func WithCustomDomains(repo domain.DomainRepository, verifier *customdomains.Verifier) Option {
+	return func(r *Router) {
+		r.domainRepo = repo
+		r.domains = verifier
+	}
+}
+
+// WithEgressManager enables the environment egress IP endpoints
+func WithEgressManager(manager *egress.Manager) Option {
+	return func(r *Router) { r.egress = manager }
+}
+
+// WithEnvLifecycle enables the environment expiry and hibernation endpoints
+func WithEnvLifecycle(manager *envlifecycle.Manager) Option {
+	return func(r *Router) { r.envLifecycle = manager }
+}
+
+// WithCronJobs enables the schedule and run endpoints of cron job services
+func WithCronJobs(manager *cronjobs.Manager) Option {
+	return func(r *Router) { r.cronJobs = manager }
+}
+
+// WithJobs enables the one-off job endpoints of services
+func WithJobs(runner *jobs.Runner) Option {
+	return func(r *Router) { r.jobRunner = runner }
+}
+
+// WithVolumes enables the persistent volume endpoints of services
+func WithVolumes(manager *volumes.Manager) Option {
+	return func(r *Router) { r.volumes = manager }
+}
+
+// WithResidency enforces the data residency of projects on their placements
+func WithResidency(checker *residency.Checker) Option {
+	return func(r *Router) { r.residency = checker }
+}
+
+// WithPlacement schedules services created without a target cluster onto a
+// cluster of their project
+func WithPlacement(scheduler *placement.Scheduler) Option {
+	return func(r *Router) { r.scheduler = scheduler }
+}
+
+// WithEventHub enables the live event stream for the dashboard
+func WithEventHub(hub *livefeed.Hub) Option {
+	return func(r *Router) { r.eventHub = hub }
+}
+
+// WithTunnelManager enables port-forward tunnels to service ports
+func WithTunnelManager(manager *tunnel.Manager) Option {
+	return func(r *Router) { r.tunnels = manager }
+}
+
+// WithApprovals enables the deploy approval and approval delegation endpoints
+func WithApprovals(gate *approvals.Gate) Option {
+	return func(r *Router) { r.approvals = gate }
+}
+
+// WithTeamRepository enables listing teams in the admin API, team event
+// credentials, and the team membership checks of project event credentials
+func WithTeamRepository(repo domain.TeamRepository) Option {
+	return func(r *Router) { r.teamRepo = repo }
+}
+
+// WithHealthCheck adds a component to the admin health report
+func WithHealthCheck(name string, check handlers.HealthCheck) Option {
+	return func(r *Router) {
+		if r.health == nil {
+			r.health = make(map[string]handlers.HealthCheck)
+		}
+		r.health[name] = check
+	}
+}
+
+// WithDeadLetterQueue enables the admin dead-letter endpoints
+func WithDeadLetterQueue(dlq domain.DeadLetterQueue) Option {
+	return func(r *Router) { r.deadLetters = dlq }
+}
+
+// WithEventSchemas serves the schemas of published events
+func WithEventSchemas(registry domain.EventSchemaRegistry) Option {
+	return func(r *Router) { r.eventSchemas = registry }
+}
+
+// WithEventCredentials enables issuing NATS credentials scoped to the events
+// of a tenant
+func WithEventCredentials(issuer *natsauth.Issuer) Option {
+	return func(r *Router) { r.eventCreds = issuer }
+}
+
+// WithAgentCredentials enables issuing NATS credentials for cluster agents,
+// scoped to the subjects of their cluster
+func WithAgentCredentials(issuer *natsauth.Issuer) Option {
+	return func(r *Router) { r.agentCreds = issuer }
+}
+
+// WithSlack enables the Slack notification settings of projects
+func WithSlack(notifier *slack.Notifier) Option {
+	return func(r *Router) { r.slack = notifier }
+}
+
+// WithWebhooks enables outbound webhook subscriptions
+func WithWebhooks(repo domain.WebhookRepository, dispatcher *webhooks.Dispatcher) Option {
+	return func(r *Router) {
+		r.webhookRepo = repo
+		r.webhooks = dispatcher
+	}
+}
+
+// WithSecretWriter lets project imports store secret values, usually in Vault
+func WithSecretWriter(writer anticorruption.SecretWriter) Option {
+	return func(r *Router) { r.secretWriter = writer }
+}
+
+// WithTemplateRepository lets users add their own project templates to the
+// built-in ones
+func WithTemplateRepository(repo domain.TemplateRepository) Option {
+	return func(r *Router) { r.templateRepo = repo }
+}
+
+// WithDatabases enables managed databases: YugabyteDB clusters and, with a
+// PostgreSQL service, CloudNativePG clusters
+func WithDatabases(yugabyte *yugabytedb.DatabaseService, pg *postgres.Service) Option {
+	return func(r *Router) {
+		r.databases = yugabyte
+		r.postgres = pg
+	}
+}
+
+// WithDatabaseUpgrades enables the database version upgrade endpoints
+func WithDatabaseUpgrades(upgrader *dbupgrade.Upgrader) Option {
+	return func(r *Router) { r.dbUpgrader = upgrader }
+}
+
+// WithCaches enables managed Dragonfly and Redis caches
+func WithCaches(caches *dragonfly.CacheService) Option {
+	return func(r *Router) { r.caches = caches }
+}
+
+// WithBrokers enables managed Kafka and NATS message brokers
+func WithBrokers(brokers *messaging.BrokerService) Option {
+	return func(r *Router) { r.brokers = brokers }
+}
+
+// WithObjectStorage enables per-project MinIO buckets, storing their access
+// keys through the secrets adapter
+func WithObjectStorage(tenants *minio.TenantService, secrets domain.SecretsAdapter) Option {
+	return func(r *Router) {
+		r.storage = tenants
+		r.storageSecrets = secrets
+	}
 }
 
 // NewRouter creates a new Router
@@ -57,8 +537,9 @@ func NewRouter(
 	userRepo domain.UserRepository,
 	eventBus domain.EventBus,
 	ciAdapter domain.CIAdapter,
+	opts ...Option,
 ) *Router {
-	return &Router{
+	r := &Router{
 		config:      cfg,
 		logger:      log,
 		projectRepo: projectRepo,
@@ -67,6 +548,10 @@ func NewRouter(
 		eventBus:    eventBus,
 		ciAdapter:   ciAdapter,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // Setup configures and returns the Gin router
@@ -80,6 +565,9 @@ func (r *Router) Setup() *gin.Engine {
 	// Global middleware
 	router.Use(gin.Recovery())
 	router.Use(middleware.RequestIDMiddleware())
+	if r.config.Observability.Tracing.Enabled {
+		router.Use(tracing.HTTPMiddleware())
+	}
 	// Add logging middleware
 	if r.config.Observability.Logging.Level != "" {
 		router.Use(middleware.LoggingMiddleware(r.logger))
@@ -90,40 +578,49 @@ func (r *Router) Setup() *gin.Engine {
 	}
 
 	// Rate limiting
-	rateLimiter := middleware.NewRateLimitMiddleware(&r.config.Auth, r.logger)
+	rateLimiter := middleware.NewRateLimitMiddleware(&r.config.Auth, r.rateLimitStore, r.logger)
 	router.Use(rateLimiter.RateLimit())
 
+	// Replay retried POSTs that carry an Idempotency-Key
+	idempotent := middleware.NewIdempotencyMiddleware(r.idempotency, r.config.Server.IdempotencyTTL, r.logger)
+	router.Use(idempotent.Idempotency())
+
+	// Unknown routes answer with problem details like every other error
+	router.NoRoute(handlers.NoRoute)
+
 	// Health checks (no auth required)
 	healthHandler := handlers.NewHealthHandler("1.0.0", "production")
 	router.GET("/health", healthHandler.Live)
 	router.GET("/health/live", healthHandler.Live)
 	router.GET("/health/ready", healthHandler.Ready)
 
-	// Metrics endpoint
+	// Metrics
 	if r.config.Observability.Metrics.Enabled {
-		// Expose Prometheus metrics at the configured path
-		router.GET(r.config.Observability.Metrics.Path, gin.WrapH(promhttp.HandlerFor(
-			prometheus.DefaultGatherer,
-			promhttp.HandlerOpts{
-				EnableOpenMetrics: true,
-			},
-		)))
-		// Add middleware to record metrics for all requests
-		router.Use(func(c *gin.Context) {
-			start := time.Now()
-			c.Next() // Process request
-
-			path := c.FullPath()
-			if path == "" {
-				path = "unknown" // Fallback for routes without a full path (e.g., 404s)
-			}
+		router.Use(metrics.HTTPMiddleware())
+		// Served here only when no dedicated metrics listener is configured
+		if port := r.config.Observability.Metrics.Port; port == 0 || port == r.config.Server.Port {
+			router.GET(r.config.Observability.Metrics.Path, gin.WrapH(metrics.Handler()))
+		}
+	}
 
-			duration := time.Since(start).Seconds()
-			status := fmt.Sprintf("%d", c.Writer.Status())
+	// Public keys of webhook and event signatures
+	if r.signer != nil {
+		signingHandler := handlers.NewSigningHandler(r.signer, r.logger)
+		router.GET("/.well-known/jwks.json", signingHandler.JWKS)
+	}
 
-			httpRequests.WithLabelValues(path, c.Request.Method, status).Inc()
-			httpDuration.WithLabelValues(path, c.Request.Method).Observe(duration)
-		})
+	// OpenAPI document, generated from the routes registered below
+	spec := openapi.NewBuilder(openapi.Info{
+		Title:       "NorthStack Platform API",
+		Description: "Projects, services, deployments and the clusters they run on.",
+		Version:     apiVersion,
+	}, errors.Problem{})
+	spec.Annotate(operations...)
+	if r.config.Server.DocsEnabled {
+		docsHandler := handlers.NewDocsHandler(spec, router.Routes, r.logger)
+		router.GET("/api/openapi.json", docsHandler.JSON)
+		router.GET("/api/openapi.yaml", docsHandler.YAML)
+		router.GET("/api/docs", docsHandler.UI)
 	}
 
 	// API v1 routes
@@ -134,21 +631,68 @@ func (r *Router) Setup() *gin.Engine {
 
 	// Auth handler (public routes)
 	authHandler := handlers.NewAuthHandler(r.userRepo, &r.config.Auth, r.logger)
-	v1.POST("/auth/login", authHandler.Login)
-	v1.POST("/auth/register", authHandler.Register)
-	v1.POST("/auth/refresh", authHandler.RefreshToken)
+	anonymous := rateLimiter.RateLimitAnonymous()
+	v1.POST("/auth/login", anonymous, authHandler.Login)
+	v1.POST("/auth/register", anonymous, authHandler.Register)
+	v1.POST("/auth/refresh", anonymous, authHandler.RefreshToken)
 	v1.POST("/webhooks/:source", r.handleWebhook)
 
+	// Alertmanager receiver
+	var alertsHandler *handlers.AlertsHandler
+	if r.alertManager != nil {
+		alertsHandler = handlers.NewAlertsHandler(r.alertManager, r.config.Observability.Alerting.WebhookToken, r.logger)
+		// The receiver is outside the protected group, so it only exists with a token
+		if r.config.Observability.Alerting.WebhookToken != "" {
+			v1.POST("/alerts/webhook", alertsHandler.Webhook)
+		} else {
+			r.logger.Warn().Msg("Alertmanager receiver disabled: observability.alerting.webhook_token is not set")
+		}
+	}
+
+	// Backstage catalog feed; authenticated by its own token so that Backstage
+	// needs no user account
+	if r.backstage != nil {
+		backstageHandler := handlers.NewBackstageHandler(r.backstage, r.logger)
+		feed := v1.Group("/backstage", backstageHandler.Authenticate)
+		feed.GET("/catalog-info.yaml", backstageHandler.CatalogInfo)
+		feed.GET("/entities", backstageHandler.Entities)
+		feed.GET("/entities/by-name/:kind/:namespace/:name", backstageHandler.Entity)
+	}
+
+	// Read-only share links; the views themselves need no account
+	var shareHandler *handlers.ShareHandler
+	if r.config.Auth.ShareLinks.Enabled {
+		signer := sharelink.NewSigner(&r.config.Auth.ShareLinks, r.config.Auth.JWTSecret)
+		shareHandler = handlers.NewShareHandler(signer, r.serviceRepo, r.deployRepo, r.logStore, r.logger)
+		v1.GET("/shared/:token", shareHandler.View)
+		v1.GET("/shared/:token/logs", shareHandler.Logs)
+	}
+
 	// GitHub webhook handler
 	githubWebhook := handlers.NewGitHubWebhookHandler(r.config.Integrations.Coolify.WebhookSecret, r.logger)
-	v1.POST("/webhooks/github", githubWebhook.HandleWebhook)
+	v1.POST("/webhooks/github", idempotent.GitHubDeliveries(), githubWebhook.HandleWebhook)
+
+	// Project templates: built-in ones, plus user-defined ones when stored
+	templateCatalog := templates.NewCatalog(r.templateRepo, r.projectRepo,
+		manifest.NewApplier(r.serviceRepo, r.ingressRepo, r.eventBus, r.logger), r.eventBus, r.logger)
+
+	// One-click deploy links; anyone can see what a link deploys
+	var deployLinkHandler *handlers.DeployLinkHandler
+	if r.config.Integrations.DeployLinks.Enabled {
+		linker := deploylinks.NewLinker(&r.config.Integrations.DeployLinks,
+			r.config.Integrations.Coolify.WebhookSecret, templateCatalog, r.logger)
+		deployLinkHandler = handlers.NewDeployLinkHandler(linker, r.logger)
+		v1.GET("/deploy", deployLinkHandler.Plan)
+		v1.GET("/deploy/button.svg", deployLinkHandler.Button)
+	}
 
 	// Protected routes
+	spec.Mark(router.Routes(), openapi.Public)
 	protected := v1.Group("")
-	protected.Use(authMiddleware.RequireAuth())
+	protected.Use(authMiddleware.RequireAuth(), rateLimiter.RateLimitToken())
 	{
 		// Projects
-		projectHandler := handlers.NewProjectHandler(r.projectRepo, r.eventBus, r.logger)
+		projectHandler := handlers.NewProjectHandler(r.projectRepo, r.residency, r.eventBus, r.logger)
 		protected.POST("/projects", projectHandler.Create)
 		protected.GET("/projects", projectHandler.List)
 		protected.GET("/projects/:id", projectHandler.Get)
@@ -156,39 +700,568 @@ func (r *Router) Setup() *gin.Engine {
 		protected.PATCH("/projects/:id", projectHandler.Update)
 		protected.DELETE("/projects/:id", projectHandler.Delete)
 
+		// Dual-stack options are checked against the clusters services deploy to
+		var networking *dualstack.Checker
+		if r.clusterRepo != nil && r.envRepo != nil {
+			networking = dualstack.NewChecker(r.clusterRepo, r.envRepo, r.clusterManager, r.logger)
+		}
+
 		// Services
-		serviceHandler := handlers.NewServiceHandler(r.serviceRepo, r.projectRepo, r.ciAdapter, r.eventBus, r.logger)
+		serviceHandler := handlers.NewServiceHandler(r.serviceRepo, r.projectRepo, r.ciAdapter, r.buildRepo, networking, r.scheduler, r.internalNet, r.eventBus, r.logger)
 		protected.POST("/projects/:project_id/services", serviceHandler.Create)
 		protected.GET("/projects/:project_id/services", serviceHandler.ListByProject)
 		protected.GET("/services/:id", serviceHandler.Get)
 		protected.PATCH("/services/:id", serviceHandler.Update)
 		protected.DELETE("/services/:id", serviceHandler.Delete)
 		protected.POST("/services/:id/builds", serviceHandler.TriggerBuild)
+		if r.buildRepo != nil {
+			protected.GET("/services/:id/builds", serviceHandler.ListBuilds)
+		}
 		protected.POST("/services/:id/scale", serviceHandler.Scale)
+		if r.scheduler != nil {
+			protected.POST("/services/:id/placement", serviceHandler.Place)
+		}
+
+		// Declarative northstack.yaml manifests, and docker-compose files converted to them
+		applyHandler := handlers.NewApplyHandler(manifest.NewApplier(r.serviceRepo, r.ingressRepo, r.eventBus, r.logger), r.projectRepo, r.logger)
+		protected.POST("/projects/:id/apply", applyHandler.Apply)
+		protected.POST("/projects/:id/import/compose", applyHandler.ImportCompose)
+
+		// Imports from Heroku and Northflank; secret values need Vault and the secret store
+		secretWriter := r.secretWriter
+		if r.secretRepo == nil {
+			secretWriter = nil
+		}
+		importHandler := handlers.NewImportHandler(anticorruption.NewImporter(
+			r.projectRepo, r.secretRepo,
+			manifest.NewApplier(r.serviceRepo, r.ingressRepo, r.eventBus, r.logger),
+			secretWriter, r.eventBus, r.logger,
+		), r.logger)
+		protected.POST("/projects/import", importHandler.Import)
+
+		// Ejecting to a plain Helm chart or Kustomize directory
+		exportHandler := handlers.NewExportHandler(export.NewExporter(r.projectRepo, r.serviceRepo, r.ingressRepo, r.secretRepo, r.logger), r.logger)
+		protected.GET("/projects/:id/export", exportHandler.Export)
+
+		// Project templates
+		templateHandler := handlers.NewTemplateHandler(templateCatalog, r.logger)
+		protected.GET("/templates", templateHandler.List)
+		protected.GET("/templates/:slug", templateHandler.Get)
+		protected.POST("/templates/:slug/instantiate", templateHandler.Instantiate)
+		if templateCatalog.Editable() {
+			protected.POST("/templates", templateHandler.Create)
+			protected.PUT("/templates/:slug", templateHandler.Update)
+			protected.DELETE("/templates/:slug", templateHandler.Delete)
+		}
+		if deployLinkHandler != nil {
+			protected.POST("/deploy", deployLinkHandler.Deploy)
+		}
+
+		var deploymentHandler *handlers.DeploymentHandler
+		if r.deployRepo != nil {
+			deploymentHandler = handlers.NewDeploymentHandler(r.deployRepo, r.serviceRepo, r.envRepo, r.eventBus, r.logger)
+			protected.GET("/services/:id/deployments", deploymentHandler.ListByService)
+			protected.GET("/services/:id/deployments/latest", deploymentHandler.LatestByService)
+			protected.GET("/deployments/:id", deploymentHandler.Get)
+			protected.POST("/deployments/external", deploymentHandler.RegisterExternal)
+		}
+
+		if shareHandler != nil {
+			protected.POST("/services/:id/share-links", shareHandler.ShareService)
+			if r.deployRepo != nil {
+				protected.POST("/deployments/:id/share-links", shareHandler.ShareDeployment)
+			}
+		}
+
+		if r.stateMachine != nil {
+			rolloutHandler := handlers.NewRolloutHandler(r.stateMachine, r.serviceRepo, r.logger)
+			protected.GET("/services/:id/rollout", rolloutHandler.Get)
+		}
+
+		scalingScheduleHandler := handlers.NewScalingScheduleHandler(r.serviceRepo, r.eventBus, r.logger)
+		protected.GET("/services/:id/scaling-schedules", scalingScheduleHandler.Get)
+		protected.PUT("/services/:id/scaling-schedules", scalingScheduleHandler.Replace)
+
+		if r.kedaManager != nil {
+			autoscalingHandler := handlers.NewAutoscalingHandler(r.kedaManager, r.autoscaling, r.serviceRepo, r.eventBus, r.logger)
+			protected.PUT("/services/:id/autoscaling", autoscalingHandler.Update)
+		}
+		if r.cronJobs != nil {
+			cronJobHandler := handlers.NewCronJobHandler(r.cronJobs, r.serviceRepo, r.eventBus, r.logger)
+			protected.GET("/services/:id/cron-job", cronJobHandler.Get)
+			protected.PUT("/services/:id/cron-job", cronJobHandler.Update)
+			protected.POST("/services/:id/cron-job/suspend", cronJobHandler.Suspend)
+			protected.POST("/services/:id/cron-job/resume", cronJobHandler.Resume)
+			protected.POST("/services/:id/cron-job/runs", cronJobHandler.RunNow)
+			protected.GET("/services/:id/cron-job/runs", cronJobHandler.ListRuns)
+			protected.GET("/services/:id/cron-job/runs/:run/logs", cronJobHandler.RunLogs)
+		}
+		if r.jobRunner != nil {
+			jobHandler := handlers.NewJobHandler(r.jobRunner, r.serviceRepo, r.eventBus, r.logger)
+			protected.POST("/services/:id/jobs", jobHandler.Run)
+			protected.GET("/services/:id/jobs", jobHandler.List)
+			protected.GET("/services/:id/jobs/:job", jobHandler.Get)
+			protected.GET("/services/:id/jobs/:job/logs", jobHandler.Logs)
+			protected.POST("/services/:id/jobs/:job/cancel", jobHandler.Cancel)
+		}
+		if r.volumes != nil {
+			volumeHandler := handlers.NewVolumeHandler(r.volumes, r.serviceRepo, r.eventBus, r.logger)
+			protected.POST("/services/:id/volumes", volumeHandler.Create)
+			protected.GET("/services/:id/volumes", volumeHandler.List)
+			protected.GET("/services/:id/volumes/:volume", volumeHandler.Get)
+			protected.PATCH("/services/:id/volumes/:volume", volumeHandler.Update)
+			protected.DELETE("/services/:id/volumes/:volume", volumeHandler.Delete)
+			protected.GET("/services/:id/volumes/:volume/snapshots", volumeHandler.ListSnapshots)
+			protected.POST("/services/:id/volumes/:volume/snapshots", volumeHandler.CreateSnapshot)
+			protected.DELETE("/services/:id/volumes/:volume/snapshots/:snapshot", volumeHandler.DeleteSnapshot)
+		}
+		if r.warmPool != nil {
+			warmStandbyHandler := handlers.NewWarmStandbyHandler(r.warmPool, r.serviceRepo, r.eventBus, r.logger)
+			protected.GET("/services/:id/warm-standby", warmStandbyHandler.Get)
+			protected.PUT("/services/:id/warm-standby", warmStandbyHandler.Update)
+			protected.DELETE("/services/:id/warm-standby", warmStandbyHandler.Delete)
+		}
+
+		// Metrics
+		if r.metrics != nil {
+			metricsHandler := handlers.NewMetricsHandler(r.metrics, r.serviceRepo, r.logger)
+			protected.GET("/services/:id/metrics", metricsHandler.GetServiceMetrics)
+		}
+
+		// Logs
+		logsHandler := handlers.NewLogsHandler(r.logStreamer, r.logStore, r.serviceRepo, r.logger)
+		if r.logStreamer != nil {
+			protected.GET("/services/:id/logs", logsHandler.Stream)
+			protected.GET("/services/:id/instances", logsHandler.Instances)
+		}
+		if r.logStore != nil {
+			protected.GET("/services/:id/logs/search", logsHandler.Search)
+		}
+
+		// Port-forward tunnels to private service ports
+		if r.tunnels != nil {
+			tunnelHandler := handlers.NewTunnelHandler(r.tunnels, r.serviceRepo, r.projectRepo, r.config.Server.CORSOrigins, r.logger)
+			protected.GET("/services/:id/port-forward", tunnelHandler.PortForward)
+			protected.GET("/tunnels", tunnelHandler.List)
+		}
+
+		// Deploy approvals
+		if r.approvals != nil {
+			approvalHandler := handlers.NewApprovalHandler(r.approvals, r.logger)
+			protected.GET("/approvals", approvalHandler.List)
+			protected.GET("/approvals/:id", approvalHandler.Get)
+			protected.POST("/approvals/:id/approve", approvalHandler.Approve)
+			protected.POST("/approvals/:id/reject", approvalHandler.Reject)
+			protected.GET("/approval-delegations", approvalHandler.ListDelegations)
+			protected.POST("/approval-delegations", approvalHandler.CreateDelegation)
+			protected.DELETE("/approval-delegations/:id", approvalHandler.DeleteDelegation)
+		}
+
+		// Kubernetes events
+		if r.kubeEvents != nil {
+			serviceEventsHandler := handlers.NewServiceEventsHandler(r.kubeEvents, r.serviceRepo, r.logger)
+			protected.GET("/services/:id/events", serviceEventsHandler.List)
+		}
+
+		// Right-sizing
+		if r.advisor != nil {
+			rightsizingHandler := handlers.NewRightsizingHandler(r.advisor, r.serviceRepo, r.logger)
+			protected.GET("/services/:id/rightsizing", rightsizingHandler.Get)
+			protected.POST("/services/:id/rightsizing/vpa", rightsizingHandler.EnableVPA)
+		}
+
+		// Release health
+		if r.releaseHealth != nil {
+			releaseHealthHandler := handlers.NewReleaseHealthHandler(r.releaseHealth, r.serviceRepo, r.logger)
+			protected.GET("/services/:id/release-health", releaseHealthHandler.Summary)
+		}
+
+		// Uptime
+		if r.uptimeProber != nil {
+			uptimeHandler := handlers.NewUptimeHandler(r.uptimeProber, r.serviceRepo, r.logger)
+			protected.GET("/services/:id/uptime", uptimeHandler.Summary)
+			protected.GET("/services/:id/uptime/checks", uptimeHandler.Checks)
+		}
+
+		// Alerts
+		if alertsHandler != nil {
+			protected.GET("/alerts", alertsHandler.List)
+			protected.GET("/alerts/:id", alertsHandler.Get)
+		}
+
+		// Build time analytics
+		if r.buildAnalyzer != nil {
+			buildAnalyticsHandler := handlers.NewBuildAnalyticsHandler(r.buildAnalyzer, r.projectRepo, r.serviceRepo, r.logger)
+			protected.GET("/services/:id/build-analytics", buildAnalyticsHandler.Service)
+			protected.GET("/projects/:id/build-analytics/regressions", buildAnalyticsHandler.Regressions)
+		}
+
+		// Queue times
+		if r.queueMonitor != nil {
+			queueTimeHandler := handlers.NewQueueTimeHandler(r.queueMonitor, r.projectRepo, r.logger)
+			protected.GET("/projects/:id/queue-times", queueTimeHandler.Get)
+		}
+
+		// Resource usage
+		if r.meter != nil {
+			usageHandler := handlers.NewUsageHandler(r.meter, r.projectRepo, r.logger)
+			protected.GET("/projects/:id/usage", usageHandler.Get)
+		}
+
+		// Notification inbox of the authenticated user
+		if r.notifications != nil {
+			notificationHandler := handlers.NewNotificationHandler(r.notifications, r.logger)
+			protected.GET("/notifications", notificationHandler.List)
+			protected.GET("/notifications/unread-count", notificationHandler.UnreadCount)
+			protected.GET("/notifications/stream", notificationHandler.Stream)
+			protected.POST("/notifications/read-all", notificationHandler.MarkAllRead)
+			protected.POST("/notifications/:id/read", notificationHandler.MarkRead)
+		}
+
+		// Which events reach which notification channels
+		if r.preferenceRepo != nil {
+			preferenceHandler := handlers.NewNotificationPreferenceHandler(r.preferenceRepo, r.projectRepo, r.logger)
+			protected.POST("/projects/:project_id/notification-preferences", preferenceHandler.CreateForProject)
+			protected.GET("/projects/:project_id/notification-preferences", preferenceHandler.ListByProject)
+			protected.POST("/users/me/notification-preferences", preferenceHandler.CreateForUser)
+			protected.GET("/users/me/notification-preferences", preferenceHandler.ListByUser)
+			protected.GET("/notification-preferences/:id", preferenceHandler.Get)
+			protected.PATCH("/notification-preferences/:id", preferenceHandler.Update)
+			protected.DELETE("/notification-preferences/:id", preferenceHandler.Delete)
+		}
+
+		// Live build, deploy and service events for the dashboard
+		if r.eventHub != nil {
+			eventStreamHandler := handlers.NewEventStreamHandler(r.eventHub, r.projectRepo, r.config.Server.CORSOrigins, r.logger)
+			protected.GET("/events/ws", eventStreamHandler.Stream)
+		}
+
+		// Versioned payload schemas of published events
+		if r.eventSchemas != nil {
+			eventSchemaHandler := handlers.NewEventSchemaHandler(r.eventSchemas)
+			protected.GET("/events/schemas", eventSchemaHandler.List)
+		}
+
+		// NATS credentials for tenants to subscribe to their own events
+		if r.eventCreds != nil {
+			var auditLogger *audit.Logger
+			if r.auditLogRepo != nil {
+				auditLogger = audit.NewLogger(r.auditLogRepo, r.eventBus, r.logger)
+			}
+			eventCredentialsHandler := handlers.NewEventCredentialsHandler(r.eventCreds, &r.config.NATS.Tenants, r.projectRepo, r.teamRepo, auditLogger, r.logger)
+			protected.POST("/projects/:project_id/event-credentials", eventCredentialsHandler.ForProject)
+			if r.teamRepo != nil {
+				protected.POST("/teams/:id/event-credentials", eventCredentialsHandler.ForTeam)
+			}
+		}
+
+		// Service catalog with scorecards
+		if r.catalog != nil {
+			catalogHandler := handlers.NewCatalogHandler(r.catalog, r.logger)
+			protected.GET("/catalog", catalogHandler.List)
+		}
+
+		// Activity feed
+		if r.activityFeed != nil {
+			activityHandler := handlers.NewActivityHandler(r.activityFeed, r.projectRepo, r.logger)
+			protected.GET("/projects/:id/activity", activityHandler.List)
+		}
+
+		// Environments
+		if r.envRepo != nil && r.clusterRepo != nil {
+			environmentHandler := handlers.NewEnvironmentHandler(r.envRepo, r.clusterRepo, r.projectRepo, r.clusterManager, r.residency, r.eventBus, r.logger)
+			protected.POST("/projects/:project_id/environments", environmentHandler.Create)
+			protected.GET("/projects/:project_id/environments", environmentHandler.ListByProject)
+			protected.GET("/environments/:id", environmentHandler.Get)
+			protected.PATCH("/environments/:id", environmentHandler.Update)
+			protected.DELETE("/environments/:id", environmentHandler.Delete)
+
+			if r.egress != nil {
+				egressHandler := handlers.NewEgressHandler(r.egress, r.envRepo, r.logger)
+				protected.GET("/environments/:id/egress", egressHandler.Get)
+				protected.PUT("/environments/:id/egress", egressHandler.Configure)
+				protected.DELETE("/environments/:id/egress", egressHandler.Disable)
+			}
+
+			if r.envLifecycle != nil {
+				lifecycleHandler := handlers.NewEnvLifecycleHandler(r.envLifecycle, r.envRepo, r.logger)
+				protected.GET("/environments/:id/lifecycle", lifecycleHandler.Get)
+				protected.PUT("/environments/:id/lifecycle", lifecycleHandler.Configure)
+				protected.DELETE("/environments/:id/lifecycle", lifecycleHandler.Disable)
+				protected.POST("/environments/:id/hibernate", lifecycleHandler.Hibernate)
+				protected.POST("/environments/:id/wake", lifecycleHandler.Wake)
+			}
+		}
+
+		// Ingresses
+		if r.ingressRepo != nil {
+			ingressHandler := handlers.NewIngressHandler(r.ingressRepo, r.serviceRepo, r.projectRepo, networking, r.domains, r.ingressRoutes, r.certs, r.eventBus, r.logger)
+			protected.POST("/services/:id/ingresses", ingressHandler.Create)
+			protected.GET("/services/:id/ingresses", ingressHandler.ListByService)
+			protected.GET("/projects/:project_id/ingresses", ingressHandler.ListByProject)
+			protected.GET("/ingresses/:id", ingressHandler.Get)
+			protected.PATCH("/ingresses/:id", ingressHandler.Update)
+			protected.DELETE("/ingresses/:id", ingressHandler.Delete)
+			if r.ingressRoutes != nil {
+				protected.GET("/ingresses/:id/manifests", ingressHandler.Manifests)
+			}
+		}
+
+		// Custom domains and their DNS verification
+		if r.domains != nil {
+			domainHandler := handlers.NewDomainHandler(r.domainRepo, r.domains, r.projectRepo, r.ingressRepo, r.logger)
+			protected.POST("/projects/:project_id/domains", domainHandler.Create)
+			protected.GET("/projects/:project_id/domains", domainHandler.ListByProject)
+			protected.GET("/domains/:id", domainHandler.Get)
+			protected.POST("/domains/:id/verify", domainHandler.Verify)
+			protected.DELETE("/domains/:id", domainHandler.Delete)
+		}
+
+		// Vault secrets and their replication into the clusters services run on
+		if r.secretRepo != nil {
+			secretHandler := handlers.NewSecretHandler(r.secretRepo, r.projectRepo, r.logger)
+			protected.POST("/projects/:project_id/secrets", secretHandler.Create)
+			protected.GET("/projects/:project_id/secrets", secretHandler.ListByProject)
+			protected.GET("/secrets/:id", secretHandler.Get)
+			protected.DELETE("/secrets/:id", secretHandler.Delete)
+		}
+
+		// Object storage of projects
+		if r.storage != nil {
+			bucketHandler := handlers.NewBucketHandler(r.storage, r.projectRepo, r.storageSecrets, r.eventBus, r.logger)
+			protected.GET("/projects/:id/buckets", bucketHandler.List)
+			protected.POST("/projects/:id/buckets", bucketHandler.Create)
+			protected.DELETE("/projects/:id/buckets/:bucket", bucketHandler.Delete)
+			protected.PUT("/projects/:id/buckets/:bucket/quota", bucketHandler.SetQuota)
+			protected.GET("/projects/:id/buckets/access-keys", bucketHandler.ListAccessKeys)
+			protected.POST("/projects/:id/buckets/access-keys", bucketHandler.IssueAccessKey)
+			protected.DELETE("/projects/:id/buckets/access-keys/:key", bucketHandler.RevokeAccessKey)
+		}
+		// Outbound webhooks for platform events
+		if r.webhooks != nil {
+			webhookHandler := handlers.NewWebhookSubscriptionHandler(r.webhookRepo, r.projectRepo, r.webhooks, r.logger)
+			protected.POST("/projects/:project_id/webhook-subscriptions", webhookHandler.Create)
+			protected.GET("/projects/:project_id/webhook-subscriptions", webhookHandler.ListByProject)
+			protected.GET("/webhook-subscriptions/:id", webhookHandler.Get)
+			protected.PATCH("/webhook-subscriptions/:id", webhookHandler.Update)
+			protected.DELETE("/webhook-subscriptions/:id", webhookHandler.Delete)
+			protected.GET("/webhook-subscriptions/:id/deliveries", webhookHandler.Deliveries)
+			protected.POST("/webhook-subscriptions/:id/test", webhookHandler.Test)
+		}
+		// Slack notifications of builds, deploys and alerts
+		if r.slack != nil {
+			slackHandler := handlers.NewSlackHandler(r.slack, r.projectRepo, r.logger)
+			protected.GET("/projects/:id/slack", slackHandler.Get)
+			protected.PUT("/projects/:id/slack", slackHandler.Update)
+			protected.DELETE("/projects/:id/slack", slackHandler.Delete)
+			protected.POST("/projects/:id/slack/test", slackHandler.Test)
+		}
+		if r.replicator != nil {
+			replicationHandler := handlers.NewSecretReplicationHandler(r.replicator, r.serviceRepo, r.logger)
+			protected.GET("/services/:id/secret-replication", replicationHandler.Status)
+			protected.POST("/services/:id/secret-replication/sync", replicationHandler.Sync)
+			protected.GET("/services/:id/secret-replication/manifests", replicationHandler.Manifests)
+		}
+
+		// Build artifacts
+		if r.artifacts != nil && r.buildRepo != nil {
+			artifactHandler := handlers.NewArtifactHandler(r.artifacts, r.buildRepo, r.serviceRepo, r.logger)
+			protected.GET("/builds/:id/artifacts", artifactHandler.ListByBuild)
+			protected.PUT("/builds/:id/artifacts/:name", artifactHandler.Upload)
+			protected.GET("/builds/:id/artifacts/:name", artifactHandler.Get)
+			protected.DELETE("/builds/:id/artifacts/:name", artifactHandler.Delete)
+			protected.GET("/services/:id/artifacts", artifactHandler.ListByService)
+		}
+
+		// Delivery performance
+		if r.doraReporter != nil {
+			doraHandler := handlers.NewDORAHandler(r.doraReporter, r.projectRepo, r.logger)
+			protected.GET("/projects/:id/dora", doraHandler.Get)
+		}
 
 		// User management
 		protected.GET("/users/me", authHandler.GetCurrentUser)
 		protected.PATCH("/users/me", authHandler.UpdateCurrentUser)
 		protected.POST("/auth/logout", authHandler.Logout)
 
+		// Northflank-compatible façade for CLIs and scripts being migrated
+		northflankHandler := handlers.NewNorthflankHandler(r.projectRepo, r.serviceRepo, r.secretRepo, r.eventBus, r.logger)
+		northflank := router.Group("/northflank/v1")
+		northflank.Use(authMiddleware.RequireAuth(), rateLimiter.RateLimitToken())
+		northflank.GET("/projects", northflankHandler.ListProjects)
+		northflank.POST("/projects", northflankHandler.CreateProject)
+		northflank.GET("/projects/:projectId", northflankHandler.GetProject)
+		northflank.DELETE("/projects/:projectId", northflankHandler.DeleteProject)
+		northflank.GET("/projects/:projectId/services", northflankHandler.ListServices)
+		northflank.POST("/projects/:projectId/services", northflankHandler.CreateService)
+		northflank.POST("/projects/:projectId/services/:kind", northflankHandler.CreateService)
+		northflank.GET("/projects/:projectId/services/:serviceId", northflankHandler.GetService)
+		northflank.DELETE("/projects/:projectId/services/:serviceId", northflankHandler.DeleteService)
+		northflank.GET("/projects/:projectId/secrets", northflankHandler.ListSecrets)
+		northflank.POST("/projects/:projectId/secrets", northflankHandler.CreateSecret)
+		northflank.GET("/projects/:projectId/secrets/:secretId", northflankHandler.GetSecret)
+		northflank.DELETE("/projects/:projectId/secrets/:secretId", northflankHandler.DeleteSecret)
+
+		// Short-lived kubeconfigs for users with access to a cluster
+		var kubeconfigHandler *handlers.KubeconfigHandler
+		if r.kubeconfigs != nil && r.clusterRepo != nil {
+			var auditLogger *audit.Logger
+			if r.auditLogRepo != nil {
+				auditLogger = audit.NewLogger(r.auditLogRepo, r.eventBus, r.logger)
+			}
+			kubeconfigHandler = handlers.NewKubeconfigHandler(r.kubeconfigs, r.clusterRepo, auditLogger, r.logger)
+			protected.POST("/clusters/:id/kubeconfig", kubeconfigHandler.Issue)
+		}
+
 		// Clusters (admin only)
+		spec.Mark(router.Routes(), openapi.Authenticated)
 		adminOnly := protected.Group("")
 		adminOnly.Use(authMiddleware.RequireRole(domain.UserRoleAdmin))
 		{
-			adminOnly.POST("/clusters", r.handleCreateCluster)
-			adminOnly.GET("/clusters", r.handleListClusters)
-			adminOnly.GET("/clusters/:id", r.handleGetCluster)
-			adminOnly.DELETE("/clusters/:id", r.handleDeleteCluster)
-			adminOnly.GET("/clusters/:id/kubeconfig", r.handleGetClusterKubeconfig)
+			if r.clusterRepo != nil {
+				clusterHandler := handlers.NewClusterHandler(r.clusterRepo, r.envRepo, r.clusterManager, r.provisioner, r.devClusters, r.eventBus, r.logger)
+				adminOnly.POST("/clusters", clusterHandler.CreateCluster)
+				adminOnly.POST("/clusters/dev", clusterHandler.CreateDevCluster)
+				adminOnly.GET("/clusters", clusterHandler.ListClusters)
+				adminOnly.GET("/clusters/:id", clusterHandler.GetCluster)
+				adminOnly.PATCH("/clusters/:id", clusterHandler.UpdateCluster)
+				adminOnly.DELETE("/clusters/:id", clusterHandler.DeleteCluster)
+				if r.kubeconfigs != nil {
+					adminOnly.GET("/clusters/:id/kubeconfig", kubeconfigHandler.Admin)
+				} else {
+					adminOnly.GET("/clusters/:id/kubeconfig", clusterHandler.GetClusterKubeconfig)
+				}
+				if r.agentCreds != nil {
+					var auditLogger *audit.Logger
+					if r.auditLogRepo != nil {
+						auditLogger = audit.NewLogger(r.auditLogRepo, r.eventBus, r.logger)
+					}
+					agentCredentialsHandler := handlers.NewAgentCredentialsHandler(r.agentCreds, &r.config.Agents, r.clusterRepo, auditLogger, r.logger)
+					adminOnly.POST("/clusters/:id/agent-credentials", agentCredentialsHandler.Issue)
+				}
+				adminOnly.GET("/clusters/:id/nodepools", clusterHandler.ListNodePools)
+				adminOnly.POST("/clusters/:id/nodepools", clusterHandler.CreateNodePool)
+				adminOnly.PATCH("/clusters/:id/nodepools/:name", clusterHandler.UpdateNodePool)
+				adminOnly.DELETE("/clusters/:id/nodepools/:name", clusterHandler.DeleteNodePool)
+				if r.clusterManager != nil {
+					capacityHandler := handlers.NewCapacityHandler(capacity.NewReporter(&r.config.Observability.Capacity, r.clusterManager, r.metrics, r.logger), r.clusterRepo, r.logger)
+					adminOnly.GET("/clusters/:id/capacity", capacityHandler.Get)
+				}
+			} else {
+				adminOnly.POST("/clusters", r.handleCreateCluster)
+				adminOnly.GET("/clusters", r.handleListClusters)
+				adminOnly.GET("/clusters/:id", r.handleGetCluster)
+				adminOnly.DELETE("/clusters/:id", r.handleDeleteCluster)
+				adminOnly.GET("/clusters/:id/kubeconfig", r.handleGetClusterKubeconfig)
+			}
+			if deploymentHandler != nil {
+				adminOnly.GET("/clusters/:id/deployments", deploymentHandler.ListByCluster)
+				adminOnly.GET("/clusters/:id/deployments/latest", deploymentHandler.LatestByCluster)
+			}
+
+			// Node maintenance
+			if r.drainer != nil && r.clusterRepo != nil {
+				drainHandler := handlers.NewDrainHandler(r.drainer, r.clusterRepo, r.logger)
+				adminOnly.POST("/clusters/:id/nodes/:node/drain", drainHandler.Drain)
+				adminOnly.GET("/clusters/:id/nodes/:node/drain", drainHandler.Status)
+			}
+			if r.upgrader != nil && r.clusterRepo != nil {
+				upgradeHandler := handlers.NewClusterUpgradeHandler(r.upgrader, r.clusterRepo, r.logger)
+				adminOnly.GET("/clusters/:id/upgrade", upgradeHandler.Status)
+				adminOnly.POST("/clusters/:id/upgrade", upgradeHandler.Start)
+			}
+
+			if r.auditLogRepo != nil {
+				auditLogHandler := handlers.NewAuditLogHandler(r.auditLogRepo, r.logger)
+				adminOnly.GET("/audit-logs", auditLogHandler.List)
+			}
 
 			// Database management
-			adminOnly.POST("/projects/:project_id/databases", r.handleCreateDatabase)
-			adminOnly.GET("/projects/:project_id/databases", r.handleListDatabases)
-			adminOnly.GET("/databases/:id", r.handleGetDatabase)
-			adminOnly.DELETE("/databases/:id", r.handleDeleteDatabase)
-			adminOnly.POST("/databases/:id/scale", r.handleScaleDatabase)
+			if r.databases != nil {
+				databaseHandler := handlers.NewDatabaseHandler(r.databases, r.postgres, r.projectRepo, r.envRepo, r.residency, r.dbUpgrader, r.eventBus, r.logger)
+				adminOnly.POST("/projects/:project_id/databases", databaseHandler.CreateDatabase)
+				adminOnly.GET("/projects/:project_id/databases", databaseHandler.ListDatabases)
+				adminOnly.GET("/databases/:id", databaseHandler.GetDatabase)
+				adminOnly.DELETE("/databases/:id", databaseHandler.DeleteDatabase)
+				adminOnly.POST("/databases/:id/scale", databaseHandler.ScaleDatabase)
+				adminOnly.GET("/databases/:id/connection", databaseHandler.GetConnectionInfo)
+				adminOnly.GET("/databases/:id/backups", databaseHandler.ListBackups)
+				adminOnly.POST("/databases/:id/backups", databaseHandler.CreateBackup)
+				adminOnly.POST("/databases/:id/restore", databaseHandler.RestoreDatabase)
+				if r.envRepo != nil {
+					adminOnly.POST("/databases/:id/clone", databaseHandler.CloneDatabase)
+				}
+				adminOnly.GET("/databases/:id/metrics", databaseHandler.GetMetrics)
+				adminOnly.GET("/databases/:id/slow-queries", databaseHandler.SlowQueries)
+				adminOnly.GET("/databases/:id/logical-databases", databaseHandler.ListLogicalDatabases)
+				adminOnly.POST("/databases/:id/logical-databases", databaseHandler.CreateLogicalDatabase)
+				adminOnly.DELETE("/databases/:id/logical-databases/:name", databaseHandler.DeleteLogicalDatabase)
+				adminOnly.GET("/databases/:id/roles", databaseHandler.ListRoles)
+				adminOnly.POST("/databases/:id/roles", databaseHandler.CreateRole)
+				adminOnly.POST("/databases/:id/roles/:role/reset-password", databaseHandler.ResetRolePassword)
+				adminOnly.DELETE("/databases/:id/roles/:role", databaseHandler.DeleteRole)
+				if r.dbUpgrader != nil {
+					adminOnly.GET("/databases/:id/upgrade", databaseHandler.UpgradeStatus)
+					adminOnly.POST("/databases/:id/upgrade", databaseHandler.UpgradeDatabase)
+					adminOnly.DELETE("/databases/:id/upgrade", databaseHandler.CancelUpgrade)
+				}
+			} else {
+				adminOnly.POST("/projects/:project_id/databases", r.handleCreateDatabase)
+				adminOnly.GET("/projects/:project_id/databases", r.handleListDatabases)
+				adminOnly.GET("/databases/:id", r.handleGetDatabase)
+				adminOnly.DELETE("/databases/:id", r.handleDeleteDatabase)
+				adminOnly.POST("/databases/:id/scale", r.handleScaleDatabase)
+			}
+
+			// Managed caches; binding them to services needs the secret store
+			if r.caches != nil {
+				cacheHandler := handlers.NewCacheHandler(r.caches, r.projectRepo, r.serviceRepo, r.secretRepo, r.secretWriter, r.eventBus, r.logger)
+				adminOnly.POST("/projects/:project_id/caches", cacheHandler.CreateCache)
+				adminOnly.GET("/projects/:project_id/caches", cacheHandler.ListCaches)
+				adminOnly.GET("/caches/:id", cacheHandler.GetCache)
+				adminOnly.DELETE("/caches/:id", cacheHandler.DeleteCache)
+				adminOnly.GET("/caches/:id/connection", cacheHandler.GetConnectionInfo)
+				adminOnly.POST("/caches/:id/bind", cacheHandler.BindCache)
+			}
+
+			// Managed message brokers, bound to services like caches
+			if r.brokers != nil {
+				brokerHandler := handlers.NewBrokerHandler(r.brokers, r.projectRepo, r.serviceRepo, r.secretRepo, r.secretWriter, r.eventBus, r.logger)
+				adminOnly.POST("/projects/:project_id/brokers", brokerHandler.CreateBroker)
+				adminOnly.GET("/projects/:project_id/brokers", brokerHandler.ListBrokers)
+				adminOnly.GET("/brokers/:id", brokerHandler.GetBroker)
+				adminOnly.DELETE("/brokers/:id", brokerHandler.DeleteBroker)
+				adminOnly.GET("/brokers/:id/connection", brokerHandler.GetConnectionInfo)
+				adminOnly.POST("/brokers/:id/bind", brokerHandler.BindBroker)
+				adminOnly.POST("/brokers/:id/topics", brokerHandler.CreateTopic)
+				adminOnly.GET("/brokers/:id/topics", brokerHandler.ListTopics)
+				adminOnly.DELETE("/brokers/:id/topics/:topic", brokerHandler.DeleteTopic)
+			}
+
+			// Platform operator API, across all tenants
+			var auditLogger *audit.Logger
+			if r.auditLogRepo != nil {
+				auditLogger = audit.NewLogger(r.auditLogRepo, r.eventBus, r.logger)
+			}
+			adminHandler := handlers.NewAdminHandler(r.userRepo, r.teamRepo, r.projectRepo, r.serviceRepo, r.queueMonitor, r.health, auditLogger, r.eventBus, r.logger)
+			adminOnly.GET("/admin/users", adminHandler.ListUsers)
+			adminOnly.POST("/admin/users/:id/disable", adminHandler.DisableUser)
+			adminOnly.POST("/admin/users/:id/enable", adminHandler.EnableUser)
+			adminOnly.GET("/admin/teams", adminHandler.ListTeams)
+			adminOnly.GET("/admin/projects", adminHandler.ListProjects)
+			adminOnly.DELETE("/admin/projects/:id", adminHandler.ForceDeleteProject)
+			adminOnly.DELETE("/admin/services/:id", adminHandler.ForceDeleteService)
+			adminOnly.GET("/admin/health", adminHandler.Health)
+			adminOnly.GET("/admin/queues", adminHandler.Queues)
+
+			deadLetterHandler := handlers.NewDeadLetterHandler(r.deadLetters, auditLogger, r.logger)
+			adminOnly.GET("/admin/dead-letters", deadLetterHandler.List)
+			adminOnly.GET("/admin/dead-letters/:seq", deadLetterHandler.Get)
+			adminOnly.POST("/admin/dead-letters/:seq/redrive", deadLetterHandler.Redrive)
+			adminOnly.DELETE("/admin/dead-letters/:seq", deadLetterHandler.Discard)
 		}
 	}
+	spec.Mark(router.Routes(), openapi.Admin)
 
 	return router
 }
@@ -196,32 +1269,32 @@ func (r *Router) Setup() *gin.Engine {
 // Placeholder handlers for cluster and database - will be injected via DI
 func (r *Router) handleWebhook(c *gin.Context) { c.JSON(200, gin.H{"message": "webhook received"}) }
 func (r *Router) handleCreateCluster(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented - inject ClusterHandler"})
+	handlers.NotImplemented(c, "cluster management is not configured")
 }
 func (r *Router) handleListClusters(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented - inject ClusterHandler"})
+	handlers.NotImplemented(c, "cluster management is not configured")
 }
 func (r *Router) handleGetCluster(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented - inject ClusterHandler"})
+	handlers.NotImplemented(c, "cluster management is not configured")
 }
 func (r *Router) handleDeleteCluster(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented - inject ClusterHandler"})
+	handlers.NotImplemented(c, "cluster management is not configured")
 }
 func (r *Router) handleGetClusterKubeconfig(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented"})
+	handlers.NotImplemented(c, "kubeconfig download is not implemented")
 }
 func (r *Router) handleCreateDatabase(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented - inject DatabaseHandler"})
+	handlers.NotImplemented(c, "managed databases are not configured")
 }
 func (r *Router) handleListDatabases(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented - inject DatabaseHandler"})
+	handlers.NotImplemented(c, "managed databases are not configured")
 }
 func (r *Router) handleGetDatabase(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented - inject DatabaseHandler"})
+	handlers.NotImplemented(c, "managed databases are not configured")
 }
 func (r *Router) handleDeleteDatabase(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented - inject DatabaseHandler"})
+	handlers.NotImplemented(c, "managed databases are not configured")
 }
 func (r *Router) handleScaleDatabase(c *gin.Context) {
-	c.JSON(501, gin.H{"error": "Not implemented - inject DatabaseHandler"})
+	handlers.NotImplemented(c, "managed databases are not configured")
 }
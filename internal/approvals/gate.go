@@ -0,0 +1,521 @@
+// Package approvals gates deploys to the environments that need a sign-off,
+// such as production. A deploy to one of them waits until the project owner
+// approves it, or a user the owner delegated approvals to for a window of
+// time, and goes to the owner of the project's team once it has been pending
+// for too long. Requests, decisions, delegations and escalations are all
+// recorded in the audit log; those the orchestrator makes on its own, such as
+// escalations, are recorded with no user.
+package approvals
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/notifications"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Deploy identifies a deploy of a deployment workflow
+type Deploy struct {
+	WorkflowID uuid.UUID
+	ServiceID  uuid.UUID
+	ProjectID  uuid.UUID
+	ClusterID  uuid.UUID
+	Version    string
+}
+
+// Actor identifies who acts on an approval or a delegation, for auditing
+type Actor struct {
+	UserID    uuid.UUID
+	IPAddress string
+	UserAgent string
+}
+
+// Gate holds deploys to gated environments until they are approved
+type Gate struct {
+	config      *config.ApprovalsConfig
+	repo        domain.ApprovalRepository
+	delegations domain.ApprovalDelegationRepository
+	envRepo     domain.EnvironmentRepository
+	projectRepo domain.ProjectRepository
+	teamRepo    domain.TeamRepository
+	audit       *audit.Logger
+	inbox       *notifications.Center
+	onApproved  func(ctx context.Context, approval *domain.Approval)
+	logger      *logger.Logger
+	now         func() time.Time
+
+	// mu serializes changes of approvals, so that a deploy is not requested
+	// twice and a decision does not race an escalation
+	mu sync.Mutex
+}
+
+// NewGate creates a new Gate. teamRepo may be nil, in which case pending
+// approvals are never escalated; auditLogger may be nil, in which case
+// approvals are only logged.
+func NewGate(
+	cfg *config.ApprovalsConfig,
+	repo domain.ApprovalRepository,
+	delegations domain.ApprovalDelegationRepository,
+	envRepo domain.EnvironmentRepository,
+	projectRepo domain.ProjectRepository,
+	teamRepo domain.TeamRepository,
+	auditLogger *audit.Logger,
+	log *logger.Logger,
+) *Gate {
+	return &Gate{
+		config:      cfg,
+		repo:        repo,
+		delegations: delegations,
+		envRepo:     envRepo,
+		projectRepo: projectRepo,
+		teamRepo:    teamRepo,
+		audit:       auditLogger,
+		logger:      log,
+		now:         time.Now,
+	}
+}
+
+// UseInbox makes the gate tell approvers, and the users they delegated
+// approvals to, in their inbox when an approval awaits them
+func (g *Gate) UseInbox(center *notifications.Center) {
+	g.inbox = center
+}
+
+// OnApproved sets what resumes a deploy once it is approved
+func (g *Gate) OnApproved(fn func(ctx context.Context, approval *domain.Approval)) {
+	g.onApproved = fn
+}
+
+// CheckDeploy holds a deploy to a gated environment until it is approved.
+// The first check requests an approval and later ones fail until the
+// approval is decided; a rejected deploy is requested again when it is
+// triggered again.
+func (g *Gate) CheckDeploy(ctx context.Context, d Deploy) error {
+	if g == nil {
+		return nil
+	}
+	env, err := g.environment(ctx, d.ProjectID, d.ClusterID)
+	if err != nil || env == nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	latest, err := g.repo.LatestByWorkflow(ctx, d.WorkflowID)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if latest != nil && latest.Version == d.Version {
+		switch latest.Status {
+		case domain.ApprovalStatusApproved:
+			return nil
+		case domain.ApprovalStatusPending:
+			return awaiting(env, latest)
+		}
+	}
+
+	approval, err := g.request(ctx, d, env)
+	if err != nil {
+		return err
+	}
+	return awaiting(env, approval)
+}
+
+// environment returns the project's environment on the cluster when its
+// deploys need approval, and nil otherwise
+func (g *Gate) environment(ctx context.Context, projectID, clusterID uuid.UUID) (*domain.Environment, error) {
+	environments, err := g.envRepo.ListByCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	for _, env := range environments {
+		if env.ProjectID != projectID {
+			continue
+		}
+		for _, t := range g.config.EnvironmentTypes {
+			if string(env.Type) == t {
+				return env, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// request asks the project owner to approve a deploy
+func (g *Gate) request(ctx context.Context, d Deploy, env *domain.Environment) (*domain.Approval, error) {
+	project, err := g.projectRepo.GetByID(ctx, d.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := g.now()
+	approval := &domain.Approval{
+		ID:            uuid.New(),
+		ProjectID:     d.ProjectID,
+		ServiceID:     d.ServiceID,
+		EnvironmentID: env.ID,
+		WorkflowID:    d.WorkflowID,
+		Version:       d.Version,
+		Status:        domain.ApprovalStatusPending,
+		ApproverID:    project.OwnerID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := g.repo.Create(ctx, approval); err != nil {
+		return nil, err
+	}
+
+	g.record(ctx, Actor{}, domain.AuditActionCreate, "approval", approval.ID, &approval.ProjectID, map[string]interface{}{
+		"approver_id":    approval.ApproverID.String(),
+		"environment_id": env.ID.String(),
+		"service_id":     approval.ServiceID.String(),
+		"version":        approval.Version,
+	})
+	g.logger.Info().
+		Str("approval_id", approval.ID.String()).
+		Str("environment_id", env.ID.String()).
+		Str("approver_id", approval.ApproverID.String()).
+		Msg("Deploy approval requested")
+
+	g.notify(ctx, approval, approval.ApproverID, fmt.Sprintf("A deploy to %s awaits your approval", env.Name))
+	return approval, nil
+}
+
+// awaiting is the error of a deploy held until an approval is approved
+func awaiting(env *domain.Environment, approval *domain.Approval) error {
+	return errors.NewError(errors.CodeConflict,
+		fmt.Sprintf("deploys to %s need approval; the deploy resumes once approval %s is approved", env.Name, approval.ID),
+		http.StatusConflict).WithDetails(map[string]interface{}{"approval_id": approval.ID.String()})
+}
+
+// Get returns an approval
+func (g *Gate) Get(ctx context.Context, id uuid.UUID) (*domain.Approval, error) {
+	return g.repo.GetByID(ctx, id)
+}
+
+// List returns a page of the approvals a user may decide: those asked of
+// them and of the users who delegated approvals to them for now
+func (g *Gate) List(ctx context.Context, userID uuid.UUID, filter domain.ApprovalFilter) ([]*domain.Approval, error) {
+	delegations, err := g.delegations.ListActive(ctx, userID, g.now())
+	if err != nil {
+		return nil, err
+	}
+
+	filter.ApproverIDs = []uuid.UUID{userID}
+	for _, d := range delegations {
+		filter.ApproverIDs = append(filter.ApproverIDs, d.DelegatorID)
+	}
+	return g.repo.List(ctx, filter)
+}
+
+// Approve approves a pending deploy, which then resumes
+func (g *Gate) Approve(ctx context.Context, id uuid.UUID, actor Actor, comment string) (*domain.Approval, error) {
+	approval, err := g.decide(ctx, id, actor, domain.ApprovalStatusApproved, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	// Outside the lock: resuming the deploy checks it again
+	if g.onApproved != nil {
+		g.onApproved(context.WithoutCancel(ctx), approval)
+	}
+	return approval, nil
+}
+
+// Reject rejects a pending deploy
+func (g *Gate) Reject(ctx context.Context, id uuid.UUID, actor Actor, comment string) (*domain.Approval, error) {
+	return g.decide(ctx, id, actor, domain.ApprovalStatusRejected, comment)
+}
+
+// decide records the actor's decision on a pending approval. Only the
+// approver and the users they delegated approvals to for now may decide.
+func (g *Gate) decide(ctx context.Context, id uuid.UUID, actor Actor, status domain.ApprovalStatus, comment string) (*domain.Approval, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	approval, err := g.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if approval.Status != domain.ApprovalStatusPending {
+		return nil, errors.NewError(errors.CodeConflict, fmt.Sprintf("approval %s is already %s", approval.ID, approval.Status), http.StatusConflict)
+	}
+
+	var delegation *domain.ApprovalDelegation
+	if actor.UserID != approval.ApproverID {
+		if delegation, err = g.delegationFor(ctx, approval, actor.UserID); err != nil {
+			return nil, err
+		}
+		if delegation == nil {
+			return nil, errors.Forbidden("only the approver, or a user they delegated approvals to, can decide this approval")
+		}
+	}
+
+	now := g.now()
+	approval.Status = status
+	approval.DecidedBy = &actor.UserID
+	approval.DecidedAt = &now
+	approval.Comment = comment
+	approval.UpdatedAt = now
+	if err := g.repo.Update(ctx, approval); err != nil {
+		return nil, err
+	}
+
+	action := domain.AuditActionApprove
+	if status == domain.ApprovalStatusRejected {
+		action = domain.AuditActionReject
+	}
+	metadata := map[string]interface{}{"version": approval.Version}
+	if comment != "" {
+		metadata["comment"] = comment
+	}
+	if delegation != nil {
+		metadata["on_behalf_of"] = approval.ApproverID.String()
+		metadata["delegation_id"] = delegation.ID.String()
+	}
+	g.record(ctx, actor, action, "approval", approval.ID, &approval.ProjectID, metadata)
+	g.logger.Info().
+		Str("approval_id", approval.ID.String()).
+		Str("user_id", actor.UserID.String()).
+		Str("status", string(status)).
+		Msg("Deploy approval decided")
+
+	return approval, nil
+}
+
+// delegationFor returns the delegation that lets a user decide an approval
+// asked of someone else, or nil when there is none
+func (g *Gate) delegationFor(ctx context.Context, approval *domain.Approval, userID uuid.UUID) (*domain.ApprovalDelegation, error) {
+	delegations, err := g.delegations.ListActive(ctx, userID, g.now())
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range delegations {
+		if d.DelegatorID == approval.ApproverID {
+			return d, nil
+		}
+	}
+	return nil, nil
+}
+
+// Delegate lets another user decide the approvals asked of the actor, from
+// the delegation's start, or now when it has none, until its end
+func (g *Gate) Delegate(ctx context.Context, actor Actor, delegation *domain.ApprovalDelegation) error {
+	now := g.now()
+	if delegation.StartsAt.IsZero() {
+		delegation.StartsAt = now
+	}
+	switch {
+	case delegation.DelegateID == uuid.Nil:
+		return errors.BadRequest("delegate_id is required")
+	case delegation.DelegateID == actor.UserID:
+		return errors.BadRequest("approvals cannot be delegated to yourself")
+	case !delegation.EndsAt.After(delegation.StartsAt):
+		return errors.BadRequest("ends_at must be after starts_at")
+	case !delegation.EndsAt.After(now):
+		return errors.BadRequest("ends_at must be in the future")
+	}
+
+	delegation.ID = uuid.New()
+	delegation.DelegatorID = actor.UserID
+	delegation.CreatedAt = now
+	if err := g.delegations.Create(ctx, delegation); err != nil {
+		return err
+	}
+
+	g.record(ctx, actor, domain.AuditActionDelegate, "approval_delegation", delegation.ID, nil, map[string]interface{}{
+		"delegate_id": delegation.DelegateID.String(),
+		"starts_at":   delegation.StartsAt.UTC().Format(time.RFC3339),
+		"ends_at":     delegation.EndsAt.UTC().Format(time.RFC3339),
+		"reason":      delegation.Reason,
+	})
+	g.logger.Info().
+		Str("delegation_id", delegation.ID.String()).
+		Str("delegator_id", delegation.DelegatorID.String()).
+		Str("delegate_id", delegation.DelegateID.String()).
+		Msg("Approvals delegated")
+	return nil
+}
+
+// Delegations returns the delegations a user gave or received
+func (g *Gate) Delegations(ctx context.Context, userID uuid.UUID) ([]*domain.ApprovalDelegation, error) {
+	return g.delegations.ListByUser(ctx, userID)
+}
+
+// Revoke ends a delegation the actor gave
+func (g *Gate) Revoke(ctx context.Context, actor Actor, id uuid.UUID) error {
+	delegation, err := g.delegations.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if delegation.DelegatorID != actor.UserID {
+		return errors.Forbidden("only the user who delegated approvals can revoke the delegation")
+	}
+	if err := g.delegations.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	g.record(ctx, actor, domain.AuditActionDelete, "approval_delegation", delegation.ID, nil, map[string]interface{}{
+		"delegate_id": delegation.DelegateID.String(),
+	})
+	g.logger.Info().Str("delegation_id", delegation.ID.String()).Msg("Approval delegation revoked")
+	return nil
+}
+
+// Run escalates approvals left pending for too long until ctx is done. It
+// returns at once when approvals never escalate.
+func (g *Gate) Run(ctx context.Context) {
+	if g.config.EscalateAfter <= 0 || g.teamRepo == nil {
+		return
+	}
+
+	ticker := time.NewTicker(g.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		g.escalateAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *Gate) escalateAll(ctx context.Context) {
+	cutoff := g.now().Add(-g.config.EscalateAfter)
+	pending, err := g.repo.List(ctx, domain.ApprovalFilter{
+		Status:        domain.ApprovalStatusPending,
+		CreatedBefore: &cutoff,
+		Unescalated:   true,
+	})
+	if err != nil {
+		g.logger.Warn().Err(err).Msg("Failed to list pending approvals to escalate")
+		return
+	}
+	for _, approval := range pending {
+		if err := g.escalate(ctx, approval.ID); err != nil {
+			g.logger.Warn().Err(err).Str("approval_id", approval.ID.String()).Msg("Failed to escalate approval")
+		}
+	}
+}
+
+// escalate hands a pending approval to the owner of its project's team.
+// Approvals of projects without a team stay with their approver.
+func (g *Gate) escalate(ctx context.Context, id uuid.UUID) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// Decided meanwhile, or escalated by another replica
+	approval, err := g.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if approval.Status != domain.ApprovalStatusPending || approval.EscalatedAt != nil {
+		return nil
+	}
+
+	project, err := g.projectRepo.GetByID(ctx, approval.ProjectID)
+	if err != nil {
+		return err
+	}
+	if project.TeamID == nil {
+		return nil
+	}
+	team, err := g.teamRepo.GetByID(ctx, *project.TeamID)
+	if err != nil {
+		return err
+	}
+
+	now := g.now()
+	from := approval.ApproverID
+	approval.ApproverID = team.OwnerID
+	approval.EscalatedAt = &now
+	approval.UpdatedAt = now
+	if err := g.repo.Update(ctx, approval); err != nil {
+		return err
+	}
+
+	g.record(ctx, Actor{}, domain.AuditActionEscalate, "approval", approval.ID, &approval.ProjectID, map[string]interface{}{
+		"from":        from.String(),
+		"to":          team.OwnerID.String(),
+		"pending_for": now.Sub(approval.CreatedAt).Round(time.Minute).String(),
+	})
+	g.logger.Info().
+		Str("approval_id", approval.ID.String()).
+		Str("approver_id", team.OwnerID.String()).
+		Msg("Deploy approval escalated to the team owner")
+
+	if team.OwnerID != from {
+		g.notify(ctx, approval, team.OwnerID, fmt.Sprintf("A deploy of %s was escalated to you for approval", project.Name))
+	}
+	return nil
+}
+
+// record adds an action to the audit log. Audit failures are logged by the
+// audit logger and do not undo the action.
+func (g *Gate) record(ctx context.Context, actor Actor, action domain.AuditAction, resourceType string, resourceID uuid.UUID, projectID *uuid.UUID, metadata map[string]interface{}) {
+	if g.audit == nil {
+		return
+	}
+	_ = g.audit.Log(ctx, audit.LogOptions{
+		UserID:       actor.UserID,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ProjectID:    projectID,
+		IPAddress:    actor.IPAddress,
+		UserAgent:    actor.UserAgent,
+		Metadata:     metadata,
+	})
+}
+
+// notify tells a user, and the users they delegated approvals to for now,
+// that an approval awaits them
+func (g *Gate) notify(ctx context.Context, approval *domain.Approval, userID uuid.UUID, title string) {
+	if g.inbox == nil {
+		return
+	}
+
+	recipients := []uuid.UUID{userID}
+	delegations, err := g.delegations.ListByUser(ctx, userID)
+	if err != nil {
+		g.logger.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to list approval delegations to notify")
+	}
+	now := g.now()
+	for _, d := range delegations {
+		if d.DelegatorID == userID && !now.Before(d.StartsAt) && now.Before(d.EndsAt) {
+			recipients = append(recipients, d.DelegateID)
+		}
+	}
+
+	for _, recipient := range recipients {
+		n := &domain.UserNotification{
+			UserID:    recipient,
+			Type:      domain.NotificationApprovalRequested,
+			Title:     title,
+			Link:      "/approvals/" + approval.ID.String(),
+			ProjectID: &approval.ProjectID,
+			ServiceID: &approval.ServiceID,
+			Data:      map[string]interface{}{"approval_id": approval.ID.String()},
+		}
+		if approval.Version != "" {
+			n.Body = fmt.Sprintf("Version %s is waiting to be deployed.", approval.Version)
+			n.Data["version"] = approval.Version
+		}
+		if err := g.inbox.Notify(ctx, n); err != nil {
+			g.logger.Warn().Err(err).Str("user_id", recipient.String()).Msg("Failed to notify approver")
+		}
+	}
+}
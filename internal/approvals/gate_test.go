@@ -0,0 +1,404 @@
+package approvals
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/notifications"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type approvalRepo struct {
+	domain.ApprovalRepository
+	approvals map[uuid.UUID]*domain.Approval
+}
+
+func (r *approvalRepo) Create(ctx context.Context, approval *domain.Approval) error {
+	copied := *approval
+	r.approvals[approval.ID] = &copied
+	return nil
+}
+
+func (r *approvalRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Approval, error) {
+	approval, ok := r.approvals[id]
+	if !ok {
+		return nil, errors.NotFound("approval", id.String())
+	}
+	copied := *approval
+	return &copied, nil
+}
+
+func (r *approvalRepo) LatestByWorkflow(ctx context.Context, workflowID uuid.UUID) (*domain.Approval, error) {
+	var latest *domain.Approval
+	for _, approval := range r.approvals {
+		if approval.WorkflowID == workflowID && (latest == nil || approval.CreatedAt.After(latest.CreatedAt)) {
+			latest = approval
+		}
+	}
+	if latest == nil {
+		return nil, errors.NotFound("approval of workflow", workflowID.String())
+	}
+	copied := *latest
+	return &copied, nil
+}
+
+func (r *approvalRepo) List(ctx context.Context, filter domain.ApprovalFilter) ([]*domain.Approval, error) {
+	approvals := []*domain.Approval{}
+	for _, approval := range r.approvals {
+		if filter.ApproverIDs != nil && !contains(filter.ApproverIDs, approval.ApproverID) {
+			continue
+		}
+		if filter.Status != "" && approval.Status != filter.Status {
+			continue
+		}
+		if filter.CreatedBefore != nil && !approval.CreatedAt.Before(*filter.CreatedBefore) {
+			continue
+		}
+		if filter.Unescalated && approval.EscalatedAt != nil {
+			continue
+		}
+		copied := *approval
+		approvals = append(approvals, &copied)
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].CreatedAt.After(approvals[j].CreatedAt) })
+	return approvals, nil
+}
+
+func (r *approvalRepo) Update(ctx context.Context, approval *domain.Approval) error {
+	copied := *approval
+	r.approvals[approval.ID] = &copied
+	return nil
+}
+
+func contains(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+type delegationRepo struct {
+	domain.ApprovalDelegationRepository
+	delegations map[uuid.UUID]*domain.ApprovalDelegation
+}
+
+func (r *delegationRepo) Create(ctx context.Context, delegation *domain.ApprovalDelegation) error {
+	r.delegations[delegation.ID] = delegation
+	return nil
+}
+
+func (r *delegationRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.ApprovalDelegation, error) {
+	delegation, ok := r.delegations[id]
+	if !ok {
+		return nil, errors.NotFound("approval delegation", id.String())
+	}
+	return delegation, nil
+}
+
+func (r *delegationRepo) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.ApprovalDelegation, error) {
+	delegations := []*domain.ApprovalDelegation{}
+	for _, d := range r.delegations {
+		if d.DelegatorID == userID || d.DelegateID == userID {
+			delegations = append(delegations, d)
+		}
+	}
+	return delegations, nil
+}
+
+func (r *delegationRepo) ListActive(ctx context.Context, delegateID uuid.UUID, at time.Time) ([]*domain.ApprovalDelegation, error) {
+	delegations := []*domain.ApprovalDelegation{}
+	for _, d := range r.delegations {
+		if d.DelegateID == delegateID && !at.Before(d.StartsAt) && at.Before(d.EndsAt) {
+			delegations = append(delegations, d)
+		}
+	}
+	return delegations, nil
+}
+
+func (r *delegationRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(r.delegations, id)
+	return nil
+}
+
+type envRepo struct {
+	domain.EnvironmentRepository
+	environments []*domain.Environment
+}
+
+func (r *envRepo) ListByCluster(ctx context.Context, clusterID uuid.UUID) ([]*domain.Environment, error) {
+	environments := []*domain.Environment{}
+	for _, env := range r.environments {
+		if env.ClusterID == clusterID {
+			environments = append(environments, env)
+		}
+	}
+	return environments, nil
+}
+
+type projectRepo struct {
+	domain.ProjectRepository
+	project *domain.Project
+}
+
+func (r *projectRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Project, error) {
+	return r.project, nil
+}
+
+type teamRepo struct {
+	domain.TeamRepository
+	team *domain.Team
+}
+
+func (r *teamRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Team, error) {
+	return r.team, nil
+}
+
+type auditRepo struct {
+	domain.AuditLogRepository
+	entries []*domain.AuditLog
+}
+
+func (r *auditRepo) Create(ctx context.Context, log *domain.AuditLog) error {
+	r.entries = append(r.entries, log)
+	return nil
+}
+
+func (r *auditRepo) actions() []domain.AuditAction {
+	actions := []domain.AuditAction{}
+	for _, entry := range r.entries {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+type inboxRepo struct {
+	domain.NotificationRepository
+	notifications []*domain.UserNotification
+}
+
+func (r *inboxRepo) Create(ctx context.Context, n *domain.UserNotification) error {
+	r.notifications = append(r.notifications, n)
+	return nil
+}
+
+type fixture struct {
+	gate        *Gate
+	approvals   *approvalRepo
+	delegations *delegationRepo
+	audit       *auditRepo
+	inbox       *inboxRepo
+	project     *domain.Project
+	team        *domain.Team
+	deploy      Deploy
+	now         time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	log := logger.New("error", "json", io.Discard)
+
+	teamID := uuid.New()
+	project := &domain.Project{ID: uuid.New(), Name: "shop", OwnerID: uuid.New(), TeamID: &teamID}
+	team := &domain.Team{ID: teamID, OwnerID: uuid.New()}
+	clusterID := uuid.New()
+	environments := &envRepo{environments: []*domain.Environment{
+		{ID: uuid.New(), ProjectID: project.ID, ClusterID: clusterID, Name: "Production", Type: domain.EnvironmentTypeProduction},
+		{ID: uuid.New(), ProjectID: project.ID, ClusterID: uuid.New(), Name: "Staging", Type: domain.EnvironmentTypeStaging},
+	}}
+
+	f := &fixture{
+		approvals:   &approvalRepo{approvals: map[uuid.UUID]*domain.Approval{}},
+		delegations: &delegationRepo{delegations: map[uuid.UUID]*domain.ApprovalDelegation{}},
+		audit:       &auditRepo{},
+		inbox:       &inboxRepo{},
+		project:     project,
+		team:        team,
+		deploy:      Deploy{WorkflowID: uuid.New(), ServiceID: uuid.New(), ProjectID: project.ID, ClusterID: clusterID, Version: "v1"},
+		now:         time.Date(2026, 8, 3, 9, 0, 0, 0, time.UTC),
+	}
+	cfg := &config.ApprovalsConfig{Enabled: true, EnvironmentTypes: []string{"production"}, EscalateAfter: 4 * time.Hour, Interval: time.Minute}
+	f.gate = NewGate(cfg, f.approvals, f.delegations, environments, &projectRepo{project: project}, &teamRepo{team: team}, audit.NewLogger(f.audit, nil, log), log)
+	f.gate.UseInbox(notifications.NewCenter(f.inbox, nil, nil, nil, nil, log))
+	f.gate.now = func() time.Time { return f.now }
+	return f
+}
+
+// requestApproval triggers the fixture's deploy and returns the approval it awaits
+func (f *fixture) requestApproval(t *testing.T) *domain.Approval {
+	t.Helper()
+	err := f.gate.CheckDeploy(context.Background(), f.deploy)
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, http.StatusConflict, appErr.HTTPStatus)
+
+	id, err := uuid.Parse(appErr.Details.(map[string]interface{})["approval_id"].(string))
+	require.NoError(t, err)
+	approval, err := f.gate.Get(context.Background(), id)
+	require.NoError(t, err)
+	return approval
+}
+
+func TestCheckDeployWaitsForApproval(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	var resumed []*domain.Approval
+	f.gate.OnApproved(func(ctx context.Context, approval *domain.Approval) {
+		resumed = append(resumed, approval)
+		assert.NoError(t, f.gate.CheckDeploy(ctx, f.deploy), "approved deploys pass")
+	})
+
+	approval := f.requestApproval(t)
+	assert.Equal(t, domain.ApprovalStatusPending, approval.Status)
+	assert.Equal(t, f.project.OwnerID, approval.ApproverID)
+	require.Len(t, f.inbox.notifications, 1)
+	assert.Equal(t, f.project.OwnerID, f.inbox.notifications[0].UserID)
+
+	// Checking again waits on the same approval
+	assert.Equal(t, approval.ID, f.requestApproval(t).ID)
+	assert.Len(t, f.approvals.approvals, 1)
+
+	// Other environments are not gated
+	staging := f.deploy
+	staging.ClusterID = uuid.New()
+	assert.NoError(t, f.gate.CheckDeploy(ctx, staging))
+
+	_, err := f.gate.Approve(ctx, approval.ID, Actor{UserID: uuid.New()}, "")
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusForbidden, appErr.HTTPStatus)
+
+	approved, err := f.gate.Approve(ctx, approval.ID, Actor{UserID: f.project.OwnerID, IPAddress: "10.0.0.1"}, "ship it")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ApprovalStatusApproved, approved.Status)
+	assert.Equal(t, f.project.OwnerID, *approved.DecidedBy)
+	require.Len(t, resumed, 1)
+	assert.Equal(t, approval.ID, resumed[0].ID)
+
+	_, err = f.gate.Reject(ctx, approval.ID, Actor{UserID: f.project.OwnerID}, "")
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusConflict, appErr.HTTPStatus, "decided approvals stay decided")
+
+	// A new version needs its own approval
+	f.deploy.Version = "v2"
+	assert.NotEqual(t, approval.ID, f.requestApproval(t).ID)
+
+	assert.Equal(t, []domain.AuditAction{domain.AuditActionCreate, domain.AuditActionApprove, domain.AuditActionCreate}, f.audit.actions())
+	assert.Equal(t, uuid.Nil, f.audit.entries[0].UserID, "the orchestrator requests approvals")
+	assert.Equal(t, f.project.OwnerID, f.audit.entries[1].UserID)
+	assert.Equal(t, "10.0.0.1", f.audit.entries[1].IPAddress)
+	assert.Equal(t, "ship it", f.audit.entries[1].Metadata["comment"])
+}
+
+func TestRejectedDeploysAreRequestedAgain(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	approval := f.requestApproval(t)
+	rejected, err := f.gate.Reject(ctx, approval.ID, Actor{UserID: f.project.OwnerID}, "not during the sale")
+	require.NoError(t, err)
+	assert.Equal(t, domain.ApprovalStatusRejected, rejected.Status)
+
+	f.now = f.now.Add(time.Hour)
+	again := f.requestApproval(t)
+	assert.NotEqual(t, approval.ID, again.ID)
+	assert.Equal(t, domain.ApprovalStatusPending, again.Status)
+}
+
+func TestDelegatesDecideWithinTheirWindow(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	owner := Actor{UserID: f.project.OwnerID}
+	deputy := uuid.New()
+
+	assert.Error(t, f.gate.Delegate(ctx, owner, &domain.ApprovalDelegation{DelegateID: owner.UserID, EndsAt: f.now.Add(time.Hour)}), "not to yourself")
+	assert.Error(t, f.gate.Delegate(ctx, owner, &domain.ApprovalDelegation{DelegateID: deputy, EndsAt: f.now.Add(-time.Hour)}), "not in the past")
+	assert.Error(t, f.gate.Delegate(ctx, owner, &domain.ApprovalDelegation{DelegateID: deputy, StartsAt: f.now.Add(2 * time.Hour), EndsAt: f.now.Add(time.Hour)}), "ends after it starts")
+
+	vacation := &domain.ApprovalDelegation{DelegateID: deputy, EndsAt: f.now.Add(7 * 24 * time.Hour), Reason: "vacation"}
+	require.NoError(t, f.gate.Delegate(ctx, owner, vacation))
+	assert.Equal(t, owner.UserID, vacation.DelegatorID)
+	assert.Equal(t, f.now, vacation.StartsAt, "windows start now by default")
+
+	// Both the owner and their delegate are told, and the delegate sees the approval
+	approval := f.requestApproval(t)
+	require.Len(t, f.inbox.notifications, 2)
+	assert.Equal(t, deputy, f.inbox.notifications[1].UserID)
+	listed, err := f.gate.List(ctx, deputy, domain.ApprovalFilter{})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, approval.ID, listed[0].ID)
+
+	// Only the delegator revokes
+	var appErr *errors.AppError
+	require.ErrorAs(t, f.gate.Revoke(ctx, Actor{UserID: deputy}, vacation.ID), &appErr)
+	assert.Equal(t, http.StatusForbidden, appErr.HTTPStatus)
+
+	approved, err := f.gate.Approve(ctx, approval.ID, Actor{UserID: deputy}, "")
+	require.NoError(t, err)
+	assert.Equal(t, deputy, *approved.DecidedBy)
+
+	decided := f.audit.entries[len(f.audit.entries)-1]
+	assert.Equal(t, domain.AuditActionApprove, decided.Action)
+	assert.Equal(t, deputy, decided.UserID)
+	assert.Equal(t, owner.UserID.String(), decided.Metadata["on_behalf_of"])
+	assert.Equal(t, vacation.ID.String(), decided.Metadata["delegation_id"])
+
+	// Once revoked, the delegate no longer decides
+	require.NoError(t, f.gate.Revoke(ctx, owner, vacation.ID))
+	f.deploy.Version = "v2"
+	next := f.requestApproval(t)
+	_, err = f.gate.Approve(ctx, next.ID, Actor{UserID: deputy}, "")
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusForbidden, appErr.HTTPStatus)
+
+	assert.Equal(t, []domain.AuditAction{
+		domain.AuditActionDelegate, domain.AuditActionCreate, domain.AuditActionApprove, domain.AuditActionDelete, domain.AuditActionCreate,
+	}, f.audit.actions())
+}
+
+func TestPendingApprovalsEscalateToTheTeamOwner(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+
+	approval := f.requestApproval(t)
+
+	f.now = f.now.Add(3 * time.Hour)
+	f.gate.escalateAll(ctx)
+	got, err := f.gate.Get(ctx, approval.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.EscalatedAt, "not pending long enough")
+
+	f.now = f.now.Add(2 * time.Hour)
+	f.gate.escalateAll(ctx)
+	got, err = f.gate.Get(ctx, approval.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.EscalatedAt)
+	assert.Equal(t, f.team.OwnerID, got.ApproverID)
+
+	escalated := f.audit.entries[len(f.audit.entries)-1]
+	assert.Equal(t, domain.AuditActionEscalate, escalated.Action)
+	assert.Equal(t, uuid.Nil, escalated.UserID)
+	assert.Equal(t, f.project.OwnerID.String(), escalated.Metadata["from"])
+	assert.Equal(t, f.team.OwnerID.String(), escalated.Metadata["to"])
+	assert.Equal(t, "5h0m0s", escalated.Metadata["pending_for"])
+	assert.Equal(t, f.team.OwnerID, f.inbox.notifications[len(f.inbox.notifications)-1].UserID)
+
+	// Escalations happen once, and the team owner now decides
+	f.gate.escalateAll(ctx)
+	assert.Len(t, f.audit.entries, 2)
+	_, err = f.gate.Approve(ctx, approval.ID, Actor{UserID: f.team.OwnerID}, "")
+	assert.NoError(t, err)
+}
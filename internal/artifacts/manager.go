@@ -0,0 +1,248 @@
+// Package artifacts hosts the non-container outputs of builds, such as CLI
+// binaries and reports. Files are streamed to object storage with their
+// SHA-256 recorded, expire after a retention period, and are downloaded
+// through short-lived signed URLs.
+package artifacts
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// sweepInterval is how often expired artifacts are removed
+	sweepInterval = time.Hour
+	// sweepBatch bounds the number of artifacts removed per sweep
+	sweepBatch = 500
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,254}$`)
+
+// Upload describes an artifact being uploaded
+type Upload struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Body        io.Reader
+	SHA256      string        // Optional; the upload is rejected when the content does not match
+	Retention   time.Duration // Zero for the configured default
+	UploadedBy  string
+}
+
+// Manager stores and serves build artifacts
+type Manager struct {
+	config  *config.ArtifactsConfig
+	objects domain.ObjectStore
+	repo    domain.ArtifactRepository
+	logger  *logger.Logger
+}
+
+// NewManager creates a new Manager
+func NewManager(cfg *config.ArtifactsConfig, objects domain.ObjectStore, repo domain.ArtifactRepository, log *logger.Logger) *Manager {
+	return &Manager{
+		config:  cfg,
+		objects: objects,
+		repo:    repo,
+		logger:  log,
+	}
+}
+
+// Upload streams an artifact of build to object storage and records it
+func (m *Manager) Upload(ctx context.Context, build *domain.Build, up Upload) (*domain.Artifact, error) {
+	if err := ValidateName(up.Name); err != nil {
+		return nil, err
+	}
+	if up.Size <= 0 {
+		return nil, errors.BadRequest("artifact size must be known in advance; set Content-Length")
+	}
+	if m.config.MaxSize > 0 && up.Size > m.config.MaxSize {
+		return nil, errors.BadRequest(fmt.Sprintf("artifact exceeds the maximum size of %d bytes", m.config.MaxSize))
+	}
+	expected := strings.ToLower(up.SHA256)
+	if expected != "" && !isSHA256(expected) {
+		return nil, errors.BadRequest("sha256 must be 64 hex characters")
+	}
+
+	now := time.Now().UTC()
+	expiresAt, err := expiry(up.Retention, m.config, now)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := up.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	artifact := &domain.Artifact{
+		ID:          uuid.New(),
+		BuildID:     build.ID,
+		ServiceID:   build.ServiceID,
+		ProjectID:   build.ProjectID,
+		Name:        up.Name,
+		ContentType: contentType,
+		Size:        up.Size,
+		UploadedBy:  up.UploadedBy,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   now,
+	}
+	// Keyed by ID rather than name so a rejected duplicate never overwrites the original
+	artifact.StorageKey = fmt.Sprintf("artifacts/%s/%s/%s/%s", build.ProjectID, build.ServiceID, build.ID, artifact.ID)
+
+	hash := sha256.New()
+	if err := m.objects.PutObject(ctx, artifact.StorageKey, io.TeeReader(up.Body, hash), up.Size, contentType); err != nil {
+		return nil, err
+	}
+	artifact.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if expected != "" && expected != artifact.SHA256 {
+		m.discard(artifact)
+		return nil, errors.BadRequest(fmt.Sprintf("checksum mismatch: expected %s, received %s", expected, artifact.SHA256))
+	}
+
+	if err := m.repo.Create(ctx, artifact); err != nil {
+		m.discard(artifact)
+		return nil, err
+	}
+
+	m.logger.Info().
+		Str("artifact_id", artifact.ID.String()).
+		Str("build_id", build.ID.String()).
+		Str("name", artifact.Name).
+		Int64("size", artifact.Size).
+		Msg("Artifact uploaded")
+
+	return artifact, nil
+}
+
+// Get returns the artifact of a build by name
+func (m *Manager) Get(ctx context.Context, buildID uuid.UUID, name string) (*domain.Artifact, error) {
+	return m.repo.GetByName(ctx, buildID, name)
+}
+
+// ListByBuild returns the artifacts of a build
+func (m *Manager) ListByBuild(ctx context.Context, buildID uuid.UUID) ([]*domain.Artifact, error) {
+	return m.repo.ListByBuild(ctx, buildID)
+}
+
+// ListByService returns the most recent artifacts of a service
+func (m *Manager) ListByService(ctx context.Context, serviceID uuid.UUID, limit int) ([]*domain.Artifact, error) {
+	return m.repo.ListByService(ctx, serviceID, limit)
+}
+
+// ListByServiceAfter returns the page of a service's artifacts after a cursor
+func (m *Manager) ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Artifact, error) {
+	return m.repo.ListByServiceAfter(ctx, serviceID, after, limit)
+}
+
+// DownloadURL returns a signed URL for the artifact and the time it stops working
+func (m *Manager) DownloadURL(artifact *domain.Artifact) (string, time.Time, error) {
+	expires := time.Now().Add(m.config.URLExpiry)
+	url, err := m.objects.PresignGet(artifact.StorageKey, artifact.Name, m.config.URLExpiry)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return url, expires, nil
+}
+
+// Delete removes an artifact and its stored content
+func (m *Manager) Delete(ctx context.Context, artifact *domain.Artifact) error {
+	if err := m.objects.DeleteObject(ctx, artifact.StorageKey); err != nil {
+		return err
+	}
+	return m.repo.Delete(ctx, artifact.ID)
+}
+
+// Run removes artifacts once their retention ends, until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		m.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) sweep(ctx context.Context) {
+	expired, err := m.repo.ListExpired(ctx, time.Now(), sweepBatch)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list expired artifacts")
+		return
+	}
+
+	removed := 0
+	for _, artifact := range expired {
+		if err := m.Delete(ctx, artifact); err != nil {
+			m.logger.Warn().Err(err).Str("artifact_id", artifact.ID.String()).Msg("Failed to remove expired artifact")
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		m.logger.Info().Int("count", removed).Msg("Removed expired artifacts")
+	}
+}
+
+// discard removes the stored content of an upload that was not recorded
+func (m *Manager) discard(artifact *domain.Artifact) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := m.objects.DeleteObject(ctx, artifact.StorageKey); err != nil {
+		m.logger.Warn().Err(err).Str("key", artifact.StorageKey).Msg("Failed to discard artifact content")
+	}
+}
+
+// ValidateName checks that an artifact name is a plain file name
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return errors.BadRequest("artifact name must be 1-255 letters, digits, '.', '_' or '-', starting with a letter or digit")
+	}
+	return nil
+}
+
+// expiry resolves the requested retention against the configured default
+// and maximum. A nil result means the artifact never expires.
+func expiry(retention time.Duration, cfg *config.ArtifactsConfig, now time.Time) (*time.Time, error) {
+	if retention < 0 {
+		return nil, errors.BadRequest("retention must not be negative")
+	}
+	if retention == 0 {
+		retention = cfg.DefaultRetention
+	}
+	if cfg.MaxRetention > 0 && (retention == 0 || retention > cfg.MaxRetention) {
+		return nil, errors.BadRequest(fmt.Sprintf("retention must not exceed %s", cfg.MaxRetention))
+	}
+	if retention == 0 {
+		return nil, nil
+	}
+
+	expiresAt := now.Add(retention)
+	return &expiresAt, nil
+}
+
+func isSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
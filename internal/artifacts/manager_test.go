@@ -0,0 +1,39 @@
+package artifacts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateName(t *testing.T) {
+	assert.NoError(t, ValidateName("cli-linux-amd64.tar.gz"))
+	assert.NoError(t, ValidateName("coverage_report.html"))
+	assert.Error(t, ValidateName(""))
+	assert.Error(t, ValidateName(".env"))
+	assert.Error(t, ValidateName("../secrets"))
+	assert.Error(t, ValidateName("dist/cli"))
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := &config.ArtifactsConfig{DefaultRetention: 30 * 24 * time.Hour, MaxRetention: 365 * 24 * time.Hour}
+
+	expiresAt, err := expiry(0, cfg, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(30*24*time.Hour), *expiresAt)
+
+	expiresAt, err = expiry(time.Hour, cfg, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(time.Hour), *expiresAt)
+
+	_, err = expiry(400*24*time.Hour, cfg, now)
+	assert.Error(t, err)
+
+	// Without a default or maximum, artifacts are kept until deleted
+	expiresAt, err = expiry(0, &config.ArtifactsConfig{}, now)
+	assert.NoError(t, err)
+	assert.Nil(t, expiresAt)
+}
@@ -0,0 +1,142 @@
+package autoscaling
+
+import (
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/scheduledscaling"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// kedaHPAPrefix prefixes the HorizontalPodAutoscalers KEDA creates for its
+// ScaledObjects
+const kedaHPAPrefix = "keda-hpa-"
+
+type object = map[string]interface{}
+
+// workload is the Deployment or StatefulSet running a service
+type workload struct {
+	kind      string
+	name      string
+	namespace string
+	uid       string
+	obj       map[string]interface{}
+}
+
+// Autoscaler is the autoscaler a service's scaling calls for: KEDA for
+// event-driven triggers, a HorizontalPodAutoscaler for a replica range to
+// move in by CPU or memory, or none
+func Autoscaler(s domain.ScalingConfig) string {
+	switch {
+	case len(s.Triggers) > 0:
+		return domain.AutoscalerKEDA
+	case s.MaxReplicas > minReplicas(s.MinReplicas) && (s.TargetCPU > 0 || s.TargetMemory > 0):
+		return domain.AutoscalerHPA
+	}
+	return ""
+}
+
+// HPA renders the HorizontalPodAutoscaler of a service's workload for a
+// replica range. It is owned by the workload, so it is deleted with it.
+func HPA(svc *domain.Service, w *workload, r scheduledscaling.Range) object {
+	s := svc.Scaling
+	var metrics []interface{}
+	for _, m := range []struct {
+		resource string
+		target   int32
+	}{{"cpu", s.TargetCPU}, {"memory", s.TargetMemory}} {
+		if m.target > 0 {
+			metrics = append(metrics, object{
+				"type": "Resource",
+				"resource": object{
+					"name":   m.resource,
+					"target": object{"type": "Utilization", "averageUtilization": int64(m.target)},
+				},
+			})
+		}
+	}
+
+	min, max := minReplicas(r.Min), r.Max
+	if max < min {
+		max = min
+	}
+	spec := object{
+		"scaleTargetRef": object{"apiVersion": "apps/v1", "kind": w.kind, "name": w.name},
+		"minReplicas":    int64(min),
+		"maxReplicas":    int64(max),
+		"metrics":        metrics,
+	}
+	behavior := object{}
+	if s.ScaleDownDelay > 0 {
+		behavior["scaleDown"] = object{"stabilizationWindowSeconds": int64(s.ScaleDownDelay)}
+	}
+	if s.ScaleUpStabilization > 0 {
+		behavior["scaleUp"] = object{"stabilizationWindowSeconds": int64(s.ScaleUpStabilization)}
+	}
+	if len(behavior) > 0 {
+		spec["behavior"] = behavior
+	}
+
+	metadata := object{
+		"name":      w.name,
+		"namespace": w.namespace,
+		"labels": object{
+			domain.LabelServiceID: svc.ID.String(),
+			domain.LabelProjectID: svc.ProjectID.String(),
+			domain.LabelManagedBy: domain.ManagedByValue,
+		},
+	}
+	if w.uid != "" {
+		metadata["ownerReferences"] = []interface{}{object{
+			"apiVersion": "apps/v1",
+			"kind":       w.kind,
+			"name":       w.name,
+			"uid":        w.uid,
+		}}
+	}
+	if annotations := r.Annotations(); annotations != nil {
+		metadata["annotations"] = annotations
+	}
+
+	return object{
+		"apiVersion": "autoscaling/v2",
+		"kind":       "HorizontalPodAutoscaler",
+		"metadata":   metadata,
+		"spec":       spec,
+	}
+}
+
+// Observe reads the replica counts of a workload and, when the service is
+// autoscaled, the count its HorizontalPodAutoscaler asks for
+func Observe(w map[string]interface{}, hpa map[string]interface{}, autoscaler string) *domain.ServiceReplicas {
+	desired, _, _ := unstructured.NestedInt64(w, "spec", "replicas")
+	current, _, _ := unstructured.NestedInt64(w, "status", "replicas")
+	ready, _, _ := unstructured.NestedInt64(w, "status", "readyReplicas")
+
+	replicas := &domain.ServiceReplicas{
+		Desired:    int32(desired),
+		Current:    int32(current),
+		Ready:      int32(ready),
+		Autoscaler: autoscaler,
+	}
+	if hpa != nil {
+		if d, ok, _ := unstructured.NestedInt64(hpa, "status", "desiredReplicas"); ok {
+			replicas.Desired = int32(d)
+		}
+		if raw, _, _ := unstructured.NestedString(hpa, "status", "lastScaleTime"); raw != "" {
+			if t, err := time.Parse(time.RFC3339, raw); err == nil {
+				replicas.LastScaleTime = &t
+			}
+		}
+	}
+	return replicas
+}
+
+// minReplicas is the lowest minimum a HorizontalPodAutoscaler accepts;
+// scaling to zero needs KEDA
+func minReplicas(min int32) int32 {
+	if min < 1 {
+		return 1
+	}
+	return min
+}
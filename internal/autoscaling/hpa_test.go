@@ -0,0 +1,85 @@
+package autoscaling
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/scheduledscaling"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoscaler(t *testing.T) {
+	tests := []struct {
+		name    string
+		scaling domain.ScalingConfig
+		want    string
+	}{
+		{"fixed replicas", domain.ScalingConfig{MinReplicas: 2, MaxReplicas: 2, TargetCPU: 70}, ""},
+		{"no target", domain.ScalingConfig{MinReplicas: 1, MaxReplicas: 5}, ""},
+		{"cpu target", domain.ScalingConfig{MinReplicas: 1, MaxReplicas: 5, TargetCPU: 70}, domain.AutoscalerHPA},
+		{"zero minimum", domain.ScalingConfig{MinReplicas: 0, MaxReplicas: 1, TargetMemory: 80}, ""},
+		{"triggers", domain.ScalingConfig{MinReplicas: 0, MaxReplicas: 5, Triggers: []domain.ScalingTrigger{{Type: domain.ScalingTriggerHTTPRPS, Target: 100}}}, domain.AutoscalerKEDA},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Autoscaler(tt.scaling))
+		})
+	}
+}
+
+func TestHPA(t *testing.T) {
+	svc := &domain.Service{
+		ID:        uuid.New(),
+		ProjectID: uuid.New(),
+		Scaling: domain.ScalingConfig{
+			MinReplicas:    0,
+			MaxReplicas:    6,
+			TargetCPU:      70,
+			ScaleDownDelay: 300,
+		},
+	}
+	w := &workload{kind: "Deployment", name: "api", namespace: "shop-production", uid: "uid-1"}
+
+	obj := HPA(svc, w, scheduledscaling.Range{Min: 0, Max: 6, Schedule: "business-hours"})
+	metadata := obj["metadata"].(object)
+	assert.Equal(t, "api", metadata["name"])
+	assert.Equal(t, "shop-production", metadata["namespace"])
+	assert.Equal(t, domain.ManagedByValue, metadata["labels"].(object)[domain.LabelManagedBy])
+	assert.Equal(t, "business-hours", metadata["annotations"].(object)[scheduledscaling.AnnotationSchedule])
+	owner := metadata["ownerReferences"].([]interface{})[0].(object)
+	assert.Equal(t, "uid-1", owner["uid"])
+
+	spec := obj["spec"].(object)
+	assert.Equal(t, object{"apiVersion": "apps/v1", "kind": "Deployment", "name": "api"}, spec["scaleTargetRef"])
+	assert.Equal(t, int64(1), spec["minReplicas"])
+	assert.Equal(t, int64(6), spec["maxReplicas"])
+	require.Len(t, spec["metrics"], 1)
+	metric := spec["metrics"].([]interface{})[0].(object)
+	assert.Equal(t, "cpu", metric["resource"].(object)["name"])
+	assert.Equal(t, object{"scaleDown": object{"stabilizationWindowSeconds": int64(300)}}, spec["behavior"])
+
+	// A warm standby floor raises the minimum and is recorded, as the
+	// scheduler applies it under the same field manager
+	obj = HPA(svc, w, scheduledscaling.Range{Min: 3, Max: 6, Warm: "3"})
+	assert.Equal(t, map[string]interface{}{scheduledscaling.AnnotationWarmFloor: "3"}, obj["metadata"].(object)["annotations"])
+	assert.Equal(t, int64(3), obj["spec"].(object)["minReplicas"])
+}
+
+func TestObserve(t *testing.T) {
+	deployment := object{
+		"spec":   object{"replicas": int64(3)},
+		"status": object{"replicas": int64(3), "readyReplicas": int64(2)},
+	}
+
+	replicas := Observe(deployment, nil, "")
+	assert.Equal(t, &domain.ServiceReplicas{Desired: 3, Current: 3, Ready: 2}, replicas)
+
+	hpa := object{"status": object{"desiredReplicas": int64(5), "lastScaleTime": "2026-06-02T10:00:00Z"}}
+	replicas = Observe(deployment, hpa, domain.AutoscalerHPA)
+	assert.Equal(t, int32(5), replicas.Desired)
+	assert.Equal(t, domain.AutoscalerHPA, replicas.Autoscaler)
+	require.NotNil(t, replicas.LastScaleTime)
+	assert.Equal(t, 2026, replicas.LastScaleTime.Year())
+}
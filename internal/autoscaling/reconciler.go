@@ -0,0 +1,291 @@
+// Package autoscaling gives services the autoscaler their scaling calls for:
+// a HorizontalPodAutoscaler for CPU and memory targets, or a KEDA
+// ScaledObject once they have event-driven triggers, which then owns the
+// HorizontalPodAutoscaler itself. It keeps them in step with the service as
+// it changes, and records the replica counts of each service's workload on
+// the service.
+package autoscaling
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/envlifecycle"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/internal/keda"
+	"github.com/northstack/platform/internal/scheduledscaling"
+	"github.com/northstack/platform/internal/warmpool"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Reconciler applies the autoscalers of services and observes their replicas
+type Reconciler struct {
+	config      *config.AutoscalingConfig
+	kube        domain.KubernetesClient
+	keda        *keda.Manager
+	warmPool    *warmpool.Pool
+	serviceRepo domain.ServiceRepository
+	projectRepo domain.ProjectRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewReconciler creates a new Reconciler. kube may be nil, in which case
+// autoscaling is unavailable, and warmPool may be nil, in which case warm
+// standby replicas do not raise the minimum.
+func NewReconciler(
+	cfg *config.AutoscalingConfig,
+	kube domain.KubernetesClient,
+	kedaManager *keda.Manager,
+	warmPool *warmpool.Pool,
+	serviceRepo domain.ServiceRepository,
+	projectRepo domain.ProjectRepository,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *Reconciler {
+	return &Reconciler{
+		config:      cfg,
+		kube:        kube,
+		keda:        kedaManager,
+		warmPool:    warmPool,
+		serviceRepo: serviceRepo,
+		projectRepo: projectRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Reconcile applies the autoscaler a service calls for, removes the ones it
+// no longer does, and records the replica counts of its workload. Services
+// that are not deployed yet are skipped.
+func (r *Reconciler) Reconcile(ctx context.Context, svc *domain.Service) error {
+	if svc.TargetClusterID == nil || svc.Type == domain.ServiceTypeCronJob {
+		return nil
+	}
+	if r.kube == nil {
+		return errors.ErrNoKubernetes
+	}
+	w, err := r.findWorkload(ctx, svc)
+	if err != nil || w == nil {
+		return err
+	}
+
+	autoscaler := Autoscaler(svc.Scaling)
+	// Hibernation pauses the ScaledObject; applying it again would wake it
+	annotations, _, _ := unstructured.NestedStringMap(w.obj, "metadata", "annotations")
+	if annotations[envlifecycle.AnnotationReplicas] == "" {
+		if err := r.applyAutoscaler(ctx, svc, w, autoscaler); err != nil {
+			return err
+		}
+	}
+	return r.observe(ctx, svc, w, autoscaler)
+}
+
+func (r *Reconciler) applyAutoscaler(ctx context.Context, svc *domain.Service, w *workload, autoscaler string) error {
+	switch autoscaler {
+	case domain.AutoscalerKEDA:
+		// KEDA refuses to scale a workload another HorizontalPodAutoscaler targets
+		if err := r.deleteHPA(ctx, svc, w); err != nil {
+			return err
+		}
+		return r.keda.Apply(ctx, svc)
+
+	case domain.AutoscalerHPA:
+		// Removes the ScaledObject left from triggers; KEDA deletes its HPA with it
+		if err := r.keda.Apply(ctx, svc); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		// Applied under the scheduler's field manager, so the scheduled
+		// range and warm standby floor are kept rather than reset
+		manifest, err := json.Marshal(HPA(svc, w, scheduledscaling.Resolve(ctx, svc, time.Now(), r.warmPool)))
+		if err != nil {
+			return errors.Wrap(err, "failed to encode HorizontalPodAutoscaler")
+		}
+		if err := r.kube.ApplyManifest(ctx, *svc.TargetClusterID, manifest); err != nil {
+			return errors.DependencyFailed("kubernetes", err)
+		}
+		return nil
+
+	default:
+		if err := r.keda.Apply(ctx, svc); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return r.deleteHPA(ctx, svc, w)
+	}
+}
+
+// observe records the replica counts of a workload on its service, and
+// publishes service.scaled when the count asked for changes
+func (r *Reconciler) observe(ctx context.Context, svc *domain.Service, w *workload, autoscaler string) error {
+	var hpa map[string]interface{}
+	if autoscaler != "" {
+		name := w.name
+		if autoscaler == domain.AutoscalerKEDA {
+			name = kedaHPAPrefix + w.name
+		}
+		obj, err := r.kube.GetResource(ctx, *svc.TargetClusterID, "HorizontalPodAutoscaler", w.namespace, name)
+		if err != nil && !errors.IsNotFound(err) {
+			return errors.DependencyFailed("kubernetes", err)
+		}
+		hpa = obj
+	}
+
+	replicas := Observe(w.obj, hpa, autoscaler)
+	replicas.ObservedAt = time.Now().UTC()
+	if err := r.serviceRepo.UpdateReplicas(ctx, svc.ID, replicas); err != nil {
+		return err
+	}
+
+	previous := svc.Replicas
+	svc.Replicas = replicas
+	if previous == nil || previous.Desired == replicas.Desired {
+		return nil
+	}
+
+	r.logger.Info().
+		Str("service_id", svc.ID.String()).
+		Int("from", int(previous.Desired)).
+		Int("to", int(replicas.Desired)).
+		Str("autoscaler", autoscaler).
+		Msg("Service scaled")
+
+	r.eventBus.Publish(ctx, "service.scaled", &domain.Event{
+		Type:   "service.scaled",
+		Source: "autoscaling",
+		Data: map[string]interface{}{
+			"service_id":        svc.ID.String(),
+			"project_id":        svc.ProjectID.String(),
+			"replicas":          replicas.Desired,
+			"previous_replicas": previous.Desired,
+			"autoscaler":        autoscaler,
+		},
+	})
+	return nil
+}
+
+// deleteHPA removes the HorizontalPodAutoscaler the platform rendered for a
+// workload, leaving any other alone
+func (r *Reconciler) deleteHPA(ctx context.Context, svc *domain.Service, w *workload) error {
+	obj, err := r.kube.GetResource(ctx, *svc.TargetClusterID, "HorizontalPodAutoscaler", w.namespace, w.name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	if managedBy, _, _ := unstructured.NestedString(obj, "metadata", "labels", domain.LabelManagedBy); managedBy != domain.ManagedByValue {
+		return nil
+	}
+	if err := r.kube.DeleteResource(ctx, *svc.TargetClusterID, "HorizontalPodAutoscaler", w.namespace, w.name); err != nil && !errors.IsNotFound(err) {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	r.logger.Info().Str("service_id", svc.ID.String()).Msg("Removed HorizontalPodAutoscaler")
+	return nil
+}
+
+// findWorkload locates the Deployment or StatefulSet running a service on
+// its target cluster, nil when there is none yet
+func (r *Reconciler) findWorkload(ctx context.Context, svc *domain.Service) (*workload, error) {
+	selector := map[string]string{domain.LabelServiceID: svc.ID.String()}
+	for _, kind := range []string{"Deployment", "StatefulSet"} {
+		objects, err := r.kube.ListResources(ctx, *svc.TargetClusterID, kind, "", selector)
+		if err != nil {
+			return nil, errors.DependencyFailed("kubernetes", err)
+		}
+		if len(objects) == 0 {
+			continue
+		}
+		obj := objects[0]
+		w := &workload{kind: kind, obj: obj}
+		w.name, _, _ = unstructured.NestedString(obj, "metadata", "name")
+		w.namespace, _, _ = unstructured.NestedString(obj, "metadata", "namespace")
+		w.uid, _, _ = unstructured.NestedString(obj, "metadata", "uid")
+		return w, nil
+	}
+	return nil, nil
+}
+
+// Run reconciles every service until ctx is cancelled, which also keeps
+// their replica counts current
+func (r *Reconciler) Run(ctx context.Context) {
+	if r.kube == nil {
+		r.logger.Warn().Msg("No Kubernetes client for workload clusters, autoscalers are not reconciled")
+		return
+	}
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.reconcileAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Watch reconciles a service as it is created, changed or deployed. Events
+// go through durable consumers, so one replica handles each and failures are
+// retried; reconciling is idempotent.
+func (r *Reconciler) Watch(ctx context.Context, bus domain.EventBus) error {
+	if r.kube == nil {
+		return nil
+	}
+	for _, subject := range []string{"service.created", "service.updated", "deploy.completed"} {
+		if _, err := eventbus.SubscribeDurable(ctx, bus, subject, eventbus.DurableName("autoscaling", subject), func(event *domain.Event) error {
+			return r.reconcileEvent(ctx, event)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) reconcileEvent(ctx context.Context, event *domain.Event) error {
+	raw, _ := event.Data["service_id"].(string)
+	serviceID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	svc, err := r.serviceRepo.GetByID(ctx, serviceID)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		r.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to load service for autoscaling")
+		return err
+	}
+	if err := r.Reconcile(ctx, svc); err != nil {
+		r.logger.Warn().Err(err).Str("service_id", raw).Msg("Autoscaling reconcile failed")
+		return err
+	}
+	return nil
+}
+
+func (r *Reconciler) reconcileAll(ctx context.Context) {
+	projects, err := r.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Failed to list projects for autoscaling")
+		return
+	}
+	for _, project := range projects {
+		services, err := r.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			r.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list services for autoscaling")
+			continue
+		}
+		for _, svc := range services {
+			if err := r.Reconcile(ctx, svc); err != nil {
+				r.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Autoscaling reconcile failed")
+			}
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package autoscaling
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcilerWithoutKubernetes(t *testing.T) {
+	clusterID := uuid.New()
+	svc := &domain.Service{ID: uuid.New(), Type: domain.ServiceTypeWebApp, TargetClusterID: &clusterID}
+	r := NewReconciler(&config.AutoscalingConfig{}, nil, nil, nil, nil, nil, nil, logger.New("error", "json", io.Discard))
+	ctx := context.Background()
+
+	// Agents are disabled: the API reports autoscaling as unavailable
+	assert.ErrorIs(t, r.Reconcile(ctx, svc), errors.ErrNoKubernetes)
+
+	// and nothing is reconciled in the background
+	assert.NoError(t, r.Watch(ctx, nil))
+	r.Run(ctx)
+}
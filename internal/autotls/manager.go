@@ -0,0 +1,330 @@
+// Package autotls issues certificates for ingresses with auto TLS through
+// cert-manager in the workload cluster serving the ingress's service. Each
+// namespace gets an ACME Issuer per challenge type (HTTP-01 through the
+// platform's ingress class, DNS-01 through the configured DNS provider) and
+// each ingress a Certificate. The readiness of the certificate, and why its
+// issuance failed, is tracked on the ingress record.
+package autotls
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Manager issues and tracks the certificates of auto TLS ingresses
+type Manager struct {
+	config      *config.AutoTLSConfig
+	kube        domain.KubernetesClient
+	ingressRepo domain.IngressRepository
+	serviceRepo domain.ServiceRepository
+	projectRepo domain.ProjectRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewManager creates a new Manager. Without a Kubernetes client, certificates
+// stay pending.
+func NewManager(
+	cfg *config.AutoTLSConfig,
+	kube domain.KubernetesClient,
+	ingressRepo domain.IngressRepository,
+	serviceRepo domain.ServiceRepository,
+	projectRepo domain.ProjectRepository,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *Manager {
+	return &Manager{
+		config:      cfg,
+		kube:        kube,
+		ingressRepo: ingressRepo,
+		serviceRepo: serviceRepo,
+		projectRepo: projectRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Validate checks that the platform can solve the challenge of an auto TLS
+// configuration
+func (m *Manager) Validate(tls *domain.TLSConfig) error {
+	if !tls.AutoTLS {
+		return nil
+	}
+	switch m.challenge(tls) {
+	case domain.ACMEChallengeHTTP01:
+		return nil
+	case domain.ACMEChallengeDNS01:
+		if m.config.DNS01.Provider == "" {
+			return errors.BadRequest("no DNS provider is configured for dns01 challenges")
+		}
+		return nil
+	}
+	return errors.BadRequest(fmt.Sprintf("tls challenge must be %s or %s", domain.ACMEChallengeHTTP01, domain.ACMEChallengeDNS01))
+}
+
+// Apply requests the certificate of an auto TLS ingress and records it on
+// ingress.TLS. Problems in the cluster are recorded as the certificate's
+// status rather than returned, since cert-manager or the workload may still
+// show up; the refresh loop retries them.
+func (m *Manager) Apply(ctx context.Context, ingress *domain.Ingress) error {
+	previous := ingress.TLS.Certificate
+	challenge := m.challenge(&ingress.TLS)
+	cert := &domain.Certificate{
+		Status:    domain.CertificateStatusPending,
+		Challenge: challenge,
+		UpdatedAt: time.Now(),
+	}
+	ingress.TLS.Enabled = true
+	ingress.TLS.SecretName = SecretName(ingress)
+	ingress.TLS.Certificate = cert
+
+	if m.kube == nil {
+		cert.Message = "no Kubernetes client for workload clusters; the certificate was not requested"
+		return nil
+	}
+
+	svc, err := m.serviceRepo.GetByID(ctx, ingress.ServiceID)
+	if err != nil {
+		return err
+	}
+	if svc.TargetClusterID == nil {
+		cert.Message = "the service is not deployed to a cluster yet"
+		return nil
+	}
+	clusterID := *svc.TargetClusterID
+	namespace, err := m.namespace(ctx, clusterID, svc.ID)
+	if err != nil {
+		return err
+	}
+	if namespace == "" {
+		cert.Message = "the service has no workload in its cluster yet"
+		return nil
+	}
+	cert.ClusterID = &clusterID
+	cert.Namespace = namespace
+
+	// The service moved; its old certificate would otherwise be renewed forever
+	if previous != nil && previous.ClusterID != nil && (*previous.ClusterID != clusterID || previous.Namespace != namespace) {
+		if err := m.delete(ctx, previous, ingress); err != nil {
+			m.logger.Warn().Err(err).Str("ingress_id", ingress.ID.String()).Msg("Failed to remove moved certificate")
+		}
+	}
+
+	if !m.exists(ctx, clusterID, "CustomResourceDefinition", "", crdName) {
+		cert.Status = domain.CertificateStatusFailed
+		cert.Message = "cert-manager is not installed in the target cluster"
+		return nil
+	}
+
+	manifests := []map[string]interface{}{}
+	if challenge == domain.ACMEChallengeDNS01 {
+		if secret := DNS01Secret(&m.config.DNS01, namespace); secret != nil {
+			manifests = append(manifests, secret)
+		}
+	}
+	manifests = append(manifests, Issuer(m.config, challenge, namespace), Certificate(ingress, challenge, namespace))
+	for _, obj := range manifests {
+		manifest, err := json.Marshal(obj)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode cert-manager resources")
+		}
+		if err := m.kube.ApplyManifest(ctx, clusterID, manifest); err != nil {
+			cert.Status = domain.CertificateStatusFailed
+			cert.Message = fmt.Sprintf("failed to apply %s: %s", obj["kind"], err.Error())
+			return nil
+		}
+	}
+
+	m.logger.Info().
+		Str("ingress_id", ingress.ID.String()).
+		Str("domain", ingress.Domain).
+		Str("challenge", string(challenge)).
+		Msg("Requested ingress certificate")
+	return nil
+}
+
+// Remove deletes the certificate of an ingress and the secret it was stored in
+func (m *Manager) Remove(ctx context.Context, ingress *domain.Ingress) error {
+	cert := ingress.TLS.Certificate
+	if cert == nil || cert.ClusterID == nil || m.kube == nil {
+		return nil
+	}
+	return m.delete(ctx, cert, ingress)
+}
+
+// Refresh reads the status of an ingress's certificate, requesting it again
+// when it was never applied or has disappeared, and stores any change
+func (m *Manager) Refresh(ctx context.Context, ingress *domain.Ingress) error {
+	if !ingress.TLS.AutoTLS {
+		return nil
+	}
+
+	var before domain.Certificate
+	if ingress.TLS.Certificate != nil {
+		before = *ingress.TLS.Certificate
+	}
+
+	cert := ingress.TLS.Certificate
+	var obj map[string]interface{}
+	if cert != nil && cert.ClusterID != nil && m.kube != nil {
+		obj, _ = m.kube.GetResource(ctx, *cert.ClusterID, "Certificate", cert.Namespace, CertificateName(ingress))
+	}
+	if obj != nil {
+		ReadStatus(obj, cert)
+	} else if err := m.Apply(ctx, ingress); err != nil {
+		return err
+	}
+
+	after := ingress.TLS.Certificate
+	if unchanged(&before, after) {
+		return nil
+	}
+	after.UpdatedAt = time.Now()
+	if err := m.ingressRepo.Update(ctx, ingress); err != nil {
+		return err
+	}
+	if after.Status != before.Status {
+		m.publish(ctx, ingress)
+	}
+	return nil
+}
+
+// Run refreshes the certificates of every auto TLS ingress until ctx is
+// cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.refreshAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) refreshAll(ctx context.Context) {
+	projects, err := m.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list projects for certificate refresh")
+		return
+	}
+	for _, project := range projects {
+		ingresses, err := m.ingressRepo.ListByProject(ctx, project.ID)
+		if err != nil {
+			m.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list ingresses for certificate refresh")
+			continue
+		}
+		for _, ingress := range ingresses {
+			if err := m.Refresh(ctx, ingress); err != nil {
+				m.logger.Warn().Err(err).Str("ingress_id", ingress.ID.String()).Msg("Failed to refresh ingress certificate")
+			}
+		}
+	}
+}
+
+// challenge is the challenge of a TLS configuration, or the platform default
+func (m *Manager) challenge(tls *domain.TLSConfig) domain.ACMEChallenge {
+	if tls.Challenge != "" {
+		return tls.Challenge
+	}
+	return domain.ACMEChallenge(m.config.Challenge)
+}
+
+// namespace is the namespace of the Deployment running a service, or empty
+// when it has none yet
+func (m *Manager) namespace(ctx context.Context, clusterID, serviceID uuid.UUID) (string, error) {
+	objects, err := m.kube.ListResources(ctx, clusterID, "Deployment", "", map[string]string{
+		domain.LabelServiceID: serviceID.String(),
+	})
+	if err != nil {
+		return "", errors.DependencyFailed("kubernetes", err)
+	}
+	if len(objects) == 0 {
+		return "", nil
+	}
+	namespace, _, _ := unstructured.NestedString(objects[0], "metadata", "namespace")
+	return namespace, nil
+}
+
+func (m *Manager) delete(ctx context.Context, cert *domain.Certificate, ingress *domain.Ingress) error {
+	if err := m.kube.DeleteResource(ctx, *cert.ClusterID, "Certificate", cert.Namespace, CertificateName(ingress)); err != nil && !errors.IsNotFound(err) {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	// cert-manager leaves the issued secret behind
+	if err := m.kube.DeleteResource(ctx, *cert.ClusterID, "Secret", cert.Namespace, SecretName(ingress)); err != nil && !errors.IsNotFound(err) {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+func (m *Manager) exists(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) bool {
+	obj, err := m.kube.GetResource(ctx, clusterID, kind, namespace, name)
+	return err == nil && obj != nil
+}
+
+// publish announces that a certificate became ready or failed
+func (m *Manager) publish(ctx context.Context, ingress *domain.Ingress) {
+	cert := ingress.TLS.Certificate
+	var eventType string
+	switch cert.Status {
+	case domain.CertificateStatusReady:
+		eventType = "service.ingress.certificate_ready"
+	case domain.CertificateStatusFailed:
+		eventType = "service.ingress.certificate_failed"
+	default:
+		return
+	}
+
+	m.logger.Info().
+		Str("ingress_id", ingress.ID.String()).
+		Str("status", string(cert.Status)).
+		Str("message", cert.Message).
+		Msg("Ingress certificate status changed")
+
+	if m.eventBus == nil {
+		return
+	}
+	event := &domain.Event{
+		Type:   eventType,
+		Source: "autotls",
+		Data: map[string]interface{}{
+			"ingress_id": ingress.ID.String(),
+			"service_id": ingress.ServiceID.String(),
+			"project_id": ingress.ProjectID.String(),
+			"domain":     ingress.Domain,
+			"message":    cert.Message,
+		},
+	}
+	if err := m.eventBus.Publish(ctx, eventType, event); err != nil {
+		m.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// unchanged reports whether a refresh left a certificate as it was
+func unchanged(before, after *domain.Certificate) bool {
+	return before.Status == after.Status &&
+		before.Message == after.Message &&
+		before.Namespace == after.Namespace &&
+		sameTime(before.NotAfter, after.NotAfter) &&
+		sameTime(before.RenewalTime, after.RenewalTime)
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
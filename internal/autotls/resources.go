@@ -0,0 +1,216 @@
+package autotls
+
+import (
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	apiVersion = "cert-manager.io/v1"
+
+	// crdName is the Certificate CRD installed by cert-manager
+	crdName = "certificates.cert-manager.io"
+
+	// dns01SecretName holds the DNS provider credentials of the DNS-01 issuer
+	dns01SecretName = "northstack-acme-dns01"
+)
+
+// IssuerName is the name of the namespaced Issuer solving a challenge
+func IssuerName(challenge domain.ACMEChallenge) string {
+	return "northstack-acme-" + string(challenge)
+}
+
+// CertificateName is the name of the Certificate of an ingress
+func CertificateName(ingress *domain.Ingress) string {
+	return "ingress-" + ingress.ID.String()
+}
+
+// SecretName is the TLS secret the certificate of an ingress is stored in
+func SecretName(ingress *domain.Ingress) string {
+	if ingress.TLS.SecretName != "" {
+		return ingress.TLS.SecretName
+	}
+	return CertificateName(ingress) + "-tls"
+}
+
+// Issuer renders the ACME Issuer solving a challenge in a namespace. HTTP-01
+// challenges are served through the platform's ingress class; DNS-01
+// challenges create TXT records with the configured DNS provider.
+func Issuer(cfg *config.AutoTLSConfig, challenge domain.ACMEChallenge, namespace string) map[string]interface{} {
+	var solver map[string]interface{}
+	if challenge == domain.ACMEChallengeDNS01 {
+		solver = map[string]interface{}{"dns01": dns01Solver(&cfg.DNS01)}
+	} else {
+		solver = map[string]interface{}{
+			"http01": map[string]interface{}{
+				"ingress": map[string]interface{}{"ingressClassName": cfg.IngressClass},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "Issuer",
+		"metadata": map[string]interface{}{
+			"name":      IssuerName(challenge),
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				domain.LabelManagedBy: domain.ManagedByValue,
+			},
+		},
+		"spec": map[string]interface{}{
+			"acme": map[string]interface{}{
+				"email":  cfg.Email,
+				"server": cfg.Server,
+				"privateKeySecretRef": map[string]interface{}{
+					"name": IssuerName(challenge) + "-account",
+				},
+				"solvers": []interface{}{solver},
+			},
+		},
+	}
+}
+
+func dns01Solver(cfg *config.DNS01Config) map[string]interface{} {
+	if cfg.Provider == "cloudflare" {
+		return map[string]interface{}{
+			"cloudflare": map[string]interface{}{
+				"apiTokenSecretRef": map[string]interface{}{"name": dns01SecretName, "key": "api-token"},
+			},
+		}
+	}
+
+	route53 := map[string]interface{}{"region": cfg.Route53Region}
+	if cfg.Route53AccessKeyID != "" {
+		route53["accessKeyIDSecretRef"] = map[string]interface{}{"name": dns01SecretName, "key": "access-key-id"}
+		route53["secretAccessKeySecretRef"] = map[string]interface{}{"name": dns01SecretName, "key": "secret-access-key"}
+	}
+	return map[string]interface{}{"route53": route53}
+}
+
+// DNS01Secret renders the secret holding the DNS provider credentials, or nil
+// when the provider uses the ambient credentials of cert-manager
+func DNS01Secret(cfg *config.DNS01Config, namespace string) map[string]interface{} {
+	data := map[string]interface{}{}
+	switch cfg.Provider {
+	case "cloudflare":
+		data["api-token"] = cfg.CloudflareAPIToken
+	case "route53":
+		if cfg.Route53AccessKeyID == "" {
+			return nil
+		}
+		data["access-key-id"] = cfg.Route53AccessKeyID
+		data["secret-access-key"] = cfg.Route53SecretAccessKey
+	default:
+		return nil
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"type":       "Opaque",
+		"metadata": map[string]interface{}{
+			"name":      dns01SecretName,
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				domain.LabelManagedBy: domain.ManagedByValue,
+			},
+		},
+		"stringData": data,
+	}
+}
+
+// Certificate renders the Certificate of an ingress's domain
+func Certificate(ingress *domain.Ingress, challenge domain.ACMEChallenge, namespace string) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "Certificate",
+		"metadata": map[string]interface{}{
+			"name":      CertificateName(ingress),
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				domain.LabelServiceID: ingress.ServiceID.String(),
+				domain.LabelProjectID: ingress.ProjectID.String(),
+				domain.LabelManagedBy: domain.ManagedByValue,
+			},
+		},
+		"spec": map[string]interface{}{
+			"secretName": SecretName(ingress),
+			"dnsNames":   dnsNames(ingress),
+			"issuerRef": map[string]interface{}{
+				"name":  IssuerName(challenge),
+				"kind":  "Issuer",
+				"group": "cert-manager.io",
+			},
+		},
+	}
+}
+
+// dnsNames are the names the certificate of an ingress covers: its domain and
+// the hosts it redirects
+func dnsNames(ingress *domain.Ingress) []interface{} {
+	hosts := ingress.Hosts()
+	names := make([]interface{}, len(hosts))
+	for i, host := range hosts {
+		names[i] = host
+	}
+	return names
+}
+
+// ReadStatus reads the readiness of a Certificate into cert. A certificate
+// that is not ready has failed once cert-manager records a failed issuance;
+// cert-manager keeps retrying with backoff, so it may still become ready.
+func ReadStatus(obj map[string]interface{}, cert *domain.Certificate) {
+	cert.Status = domain.CertificateStatusPending
+	cert.Message = ""
+	cert.NotAfter = timestamp(obj, "status", "notAfter")
+	cert.RenewalTime = timestamp(obj, "status", "renewalTime")
+
+	ready, readyMessage := condition(obj, "Ready")
+	if ready == "True" {
+		cert.Status = domain.CertificateStatusReady
+		return
+	}
+
+	_, issuingMessage := condition(obj, "Issuing")
+	message := issuingMessage
+	if message == "" {
+		message = readyMessage
+	}
+	if timestamp(obj, "status", "lastFailureTime") != nil {
+		cert.Status = domain.CertificateStatusFailed
+		if message == "" {
+			message = "certificate issuance failed"
+		}
+	}
+	cert.Message = message
+}
+
+func condition(obj map[string]interface{}, conditionType string) (string, string) {
+	conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != conditionType {
+			continue
+		}
+		status, _ := cond["status"].(string)
+		message, _ := cond["message"].(string)
+		return status, message
+	}
+	return "", ""
+}
+
+func timestamp(obj map[string]interface{}, fields ...string) *time.Time {
+	raw, _, _ := unstructured.NestedString(obj, fields...)
+	if raw == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
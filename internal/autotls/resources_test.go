@@ -0,0 +1,87 @@
+package autotls
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestIssuer(t *testing.T) {
+	cfg := &config.AutoTLSConfig{
+		Email:        "ops@example.com",
+		Server:       "https://acme-staging-v02.api.letsencrypt.org/directory",
+		IngressClass: "nginx",
+		DNS01:        config.DNS01Config{Provider: "route53", Route53Region: "eu-west-1"},
+	}
+
+	issuer := Issuer(cfg, domain.ACMEChallengeHTTP01, "team-a")
+	assert.Equal(t, "northstack-acme-http01", issuer["metadata"].(map[string]interface{})["name"])
+	solvers, _, _ := unstructured.NestedSlice(issuer, "spec", "acme", "solvers")
+	class, _, _ := unstructured.NestedString(solvers[0].(map[string]interface{}), "http01", "ingress", "ingressClassName")
+	assert.Equal(t, "nginx", class)
+
+	issuer = Issuer(cfg, domain.ACMEChallengeDNS01, "team-a")
+	solvers, _, _ = unstructured.NestedSlice(issuer, "spec", "acme", "solvers")
+	region, _, _ := unstructured.NestedString(solvers[0].(map[string]interface{}), "dns01", "route53", "region")
+	assert.Equal(t, "eu-west-1", region)
+	_, found, _ := unstructured.NestedMap(solvers[0].(map[string]interface{}), "dns01", "route53", "accessKeyIDSecretRef")
+	assert.False(t, found) // Ambient credentials
+	assert.Nil(t, DNS01Secret(&cfg.DNS01, "team-a"))
+
+	cfg.DNS01 = config.DNS01Config{Provider: "cloudflare", CloudflareAPIToken: "token"}
+	secret := DNS01Secret(&cfg.DNS01, "team-a")
+	token, _, _ := unstructured.NestedString(secret, "stringData", "api-token")
+	assert.Equal(t, "token", token)
+}
+
+func TestCertificate(t *testing.T) {
+	ingress := &domain.Ingress{ID: uuid.New(), ServiceID: uuid.New(), ProjectID: uuid.New(), Domain: "app.example.com"}
+
+	cert := Certificate(ingress, domain.ACMEChallengeDNS01, "team-a")
+	secretName, _, _ := unstructured.NestedString(cert, "spec", "secretName")
+	assert.Equal(t, "ingress-"+ingress.ID.String()+"-tls", secretName)
+	issuer, _, _ := unstructured.NestedString(cert, "spec", "issuerRef", "name")
+	assert.Equal(t, "northstack-acme-dns01", issuer)
+	names, _, _ := unstructured.NestedStringSlice(cert, "spec", "dnsNames")
+	assert.Equal(t, []string{"app.example.com"}, names)
+
+	ingress.TLS.SecretName = "app-tls"
+	assert.Equal(t, "app-tls", SecretName(ingress))
+}
+
+func TestReadStatus(t *testing.T) {
+	var cert domain.Certificate
+	ReadStatus(map[string]interface{}{}, &cert)
+	assert.Equal(t, domain.CertificateStatusPending, cert.Status)
+
+	ReadStatus(map[string]interface{}{
+		"status": map[string]interface{}{
+			"notAfter":    "2026-12-01T00:00:00Z",
+			"renewalTime": "2026-11-01T00:00:00Z",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True", "message": "Certificate is up to date and has not expired"},
+			},
+		},
+	}, &cert)
+	assert.Equal(t, domain.CertificateStatusReady, cert.Status)
+	assert.Empty(t, cert.Message)
+	assert.Equal(t, 2026, cert.NotAfter.Year())
+	assert.NotNil(t, cert.RenewalTime)
+
+	ReadStatus(map[string]interface{}{
+		"status": map[string]interface{}{
+			"lastFailureTime": "2026-10-01T00:00:00Z",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "False", "message": "Issuing certificate as Secret does not exist"},
+				map[string]interface{}{"type": "Issuing", "status": "False", "message": "The certificate request has failed to complete and will be retried: 403 urn:ietf:params:acme:error:unauthorized"},
+			},
+		},
+	}, &cert)
+	assert.Equal(t, domain.CertificateStatusFailed, cert.Status)
+	assert.Contains(t, cert.Message, "unauthorized")
+	assert.Nil(t, cert.NotAfter)
+}
@@ -0,0 +1,174 @@
+// Package availability keeps services serving through node drains and
+// failures. A service's disruption budget becomes a PodDisruptionBudget in its
+// target cluster, so evictions wait while too few replicas are ready, and its
+// spread becomes topology spread constraints in the pod template of its
+// workload, so replicas do not share a zone or node.
+package availability
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+type object = map[string]interface{}
+
+// topologyKeys are the node labels of each failure domain
+var topologyKeys = map[domain.TopologyDomain]string{
+	domain.TopologyZone: "topology.kubernetes.io/zone",
+	domain.TopologyNode: "kubernetes.io/hostname",
+}
+
+// Validate checks a service's availability settings against its type and
+// scaling. A budget that could never be met is rejected, as it would stop
+// nodes from being drained at all.
+func Validate(a *domain.ServiceAvailability, service *domain.Service) error {
+	if a == nil {
+		return nil
+	}
+	if service.Type == domain.ServiceTypeCronJob {
+		return errors.BadRequest("availability does not apply to cron job services")
+	}
+	if a.MinAvailable != "" && a.MaxUnavailable != "" {
+		return errors.BadRequest("set min_available or max_unavailable, not both")
+	}
+
+	if a.MinAvailable != "" {
+		count, percent, err := parseBudget(a.MinAvailable)
+		if err != nil {
+			return errors.BadRequest("invalid min_available: " + err.Error())
+		}
+		replicas := service.Scaling.MinReplicas
+		if replicas < 1 {
+			replicas = 1
+		}
+		if (percent && count >= 100) || (!percent && count >= int(replicas)) {
+			return errors.BadRequest(fmt.Sprintf("min_available must leave at least one of the service's %d minimum replicas free to be evicted", replicas))
+		}
+	}
+	if a.MaxUnavailable != "" {
+		count, _, err := parseBudget(a.MaxUnavailable)
+		if err != nil {
+			return errors.BadRequest("invalid max_unavailable: " + err.Error())
+		}
+		if count == 0 {
+			return errors.BadRequest("max_unavailable must allow at least one replica to be evicted")
+		}
+	}
+
+	seen := make(map[domain.TopologyDomain]bool)
+	for _, s := range a.Spread {
+		if _, ok := topologyKeys[s.Topology]; !ok {
+			return errors.BadRequest(fmt.Sprintf("unknown spread topology %q: use zone or node", s.Topology))
+		}
+		if seen[s.Topology] {
+			return errors.BadRequest(fmt.Sprintf("spread topology %s is listed twice", s.Topology))
+		}
+		seen[s.Topology] = true
+		if s.MaxSkew < 0 {
+			return errors.BadRequest("spread max_skew must be positive")
+		}
+	}
+	return nil
+}
+
+// parseBudget reads a budget as a count of replicas or a percentage of them
+func parseBudget(raw string) (int, bool, error) {
+	value, percent := strings.CutSuffix(raw, "%")
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("%q is not a replica count or percentage", raw)
+	}
+	if percent && n > 100 {
+		return 0, false, fmt.Errorf("%q is over 100%%", raw)
+	}
+	return n, percent, nil
+}
+
+// Budget is the minAvailable or maxUnavailable of a PodDisruptionBudget for a
+// service's settings, nil when they set neither
+func Budget(a *domain.ServiceAvailability) object {
+	switch {
+	case a == nil:
+		return nil
+	case a.MinAvailable != "":
+		return object{"minAvailable": intOrPercent(a.MinAvailable)}
+	case a.MaxUnavailable != "":
+		return object{"maxUnavailable": intOrPercent(a.MaxUnavailable)}
+	}
+	return nil
+}
+
+// Constraints renders the topology spread constraints of a service's pods,
+// counting the pods matched by matchLabels
+func Constraints(a *domain.ServiceAvailability, matchLabels map[string]interface{}) []interface{} {
+	if a == nil {
+		return nil
+	}
+	var constraints []interface{}
+	for _, s := range a.Spread {
+		skew := s.MaxSkew
+		if skew < 1 {
+			skew = 1
+		}
+		whenUnsatisfiable := "ScheduleAnyway"
+		if s.Required {
+			whenUnsatisfiable = "DoNotSchedule"
+		}
+		constraints = append(constraints, object{
+			"maxSkew":           int64(skew),
+			"topologyKey":       topologyKeys[s.Topology],
+			"whenUnsatisfiable": whenUnsatisfiable,
+			"labelSelector":     object{"matchLabels": matchLabels},
+		})
+	}
+	return constraints
+}
+
+// PDB renders the PodDisruptionBudget of a service's workload. It is owned by
+// the workload, so it is deleted with it.
+func PDB(svc *domain.Service, w *workload) object {
+	spec := Budget(svc.Availability)
+	spec["selector"] = object{"matchLabels": object{domain.LabelServiceID: svc.ID.String()}}
+	// Pods that are not ready do not serve anyway; keeping them from being
+	// evicted would only hold up drains
+	spec["unhealthyPodEvictionPolicy"] = "AlwaysAllow"
+
+	metadata := object{
+		"name":      w.name,
+		"namespace": w.namespace,
+		"labels": object{
+			domain.LabelServiceID: svc.ID.String(),
+			domain.LabelProjectID: svc.ProjectID.String(),
+			domain.LabelManagedBy: domain.ManagedByValue,
+		},
+	}
+	if w.uid != "" {
+		metadata["ownerReferences"] = []interface{}{object{
+			"apiVersion": "apps/v1",
+			"kind":       w.kind,
+			"name":       w.name,
+			"uid":        w.uid,
+		}}
+	}
+
+	return object{
+		"apiVersion": "policy/v1",
+		"kind":       "PodDisruptionBudget",
+		"metadata":   metadata,
+		"spec":       spec,
+	}
+}
+
+// intOrPercent is a budget as Kubernetes takes it: a number for a count, a
+// string for a percentage
+func intOrPercent(raw string) interface{} {
+	if strings.HasSuffix(raw, "%") {
+		return raw
+	}
+	n, _ := strconv.Atoi(raw)
+	return int64(n)
+}
@@ -0,0 +1,96 @@
+package availability
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	service := &domain.Service{Type: domain.ServiceTypeWebApp, Scaling: domain.ScalingConfig{MinReplicas: 3, MaxReplicas: 6}}
+
+	tests := []struct {
+		name    string
+		a       *domain.ServiceAvailability
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"min available count", &domain.ServiceAvailability{MinAvailable: "2"}, false},
+		{"min available percentage", &domain.ServiceAvailability{MinAvailable: "50%"}, false},
+		{"max unavailable", &domain.ServiceAvailability{MaxUnavailable: "1"}, false},
+		{"spread", &domain.ServiceAvailability{Spread: []domain.TopologySpread{{Topology: domain.TopologyZone}, {Topology: domain.TopologyNode, Required: true}}}, false},
+		{"both budgets", &domain.ServiceAvailability{MinAvailable: "1", MaxUnavailable: "1"}, true},
+		{"min available of every replica", &domain.ServiceAvailability{MinAvailable: "3"}, true},
+		{"min available of all", &domain.ServiceAvailability{MinAvailable: "100%"}, true},
+		{"max unavailable none", &domain.ServiceAvailability{MaxUnavailable: "0%"}, true},
+		{"not a count", &domain.ServiceAvailability{MinAvailable: "two"}, true},
+		{"over 100 percent", &domain.ServiceAvailability{MaxUnavailable: "150%"}, true},
+		{"unknown topology", &domain.ServiceAvailability{Spread: []domain.TopologySpread{{Topology: "rack"}}}, true},
+		{"duplicate topology", &domain.ServiceAvailability{Spread: []domain.TopologySpread{{Topology: domain.TopologyZone}, {Topology: domain.TopologyZone}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.a, service)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	cronJob := &domain.Service{Type: domain.ServiceTypeCronJob}
+	assert.Error(t, Validate(&domain.ServiceAvailability{MinAvailable: "1"}, cronJob))
+}
+
+func TestConstraints(t *testing.T) {
+	assert.Empty(t, Constraints(nil, nil))
+	assert.Empty(t, Constraints(&domain.ServiceAvailability{MinAvailable: "1"}, nil))
+
+	a := &domain.ServiceAvailability{Spread: []domain.TopologySpread{
+		{Topology: domain.TopologyZone},
+		{Topology: domain.TopologyNode, MaxSkew: 2, Required: true},
+	}}
+	selector := map[string]interface{}{domain.LabelServiceID: "svc-1"}
+	constraints := Constraints(a, selector)
+	require.Len(t, constraints, 2)
+
+	zone := constraints[0].(object)
+	assert.Equal(t, "topology.kubernetes.io/zone", zone["topologyKey"])
+	assert.Equal(t, int64(1), zone["maxSkew"])
+	assert.Equal(t, "ScheduleAnyway", zone["whenUnsatisfiable"])
+	assert.Equal(t, object{"matchLabels": selector}, zone["labelSelector"])
+	node := constraints[1].(object)
+	assert.Equal(t, "kubernetes.io/hostname", node["topologyKey"])
+	assert.Equal(t, int64(2), node["maxSkew"])
+	assert.Equal(t, "DoNotSchedule", node["whenUnsatisfiable"])
+}
+
+func TestPDB(t *testing.T) {
+	svc := &domain.Service{
+		ID:           uuid.New(),
+		ProjectID:    uuid.New(),
+		Availability: &domain.ServiceAvailability{MinAvailable: "2"},
+	}
+	w := &workload{kind: "StatefulSet", name: "db", namespace: "shop-production", uid: "uid-1"}
+
+	obj := PDB(svc, w)
+	assert.Equal(t, "policy/v1", obj["apiVersion"])
+	metadata := obj["metadata"].(object)
+	assert.Equal(t, "db", metadata["name"])
+	assert.Equal(t, "shop-production", metadata["namespace"])
+	assert.Equal(t, domain.ManagedByValue, metadata["labels"].(object)[domain.LabelManagedBy])
+	owner := metadata["ownerReferences"].([]interface{})[0].(object)
+	assert.Equal(t, "StatefulSet", owner["kind"])
+
+	spec := obj["spec"].(object)
+	assert.Equal(t, int64(2), spec["minAvailable"])
+	assert.NotContains(t, spec, "maxUnavailable")
+	assert.Equal(t, object{"matchLabels": object{domain.LabelServiceID: svc.ID.String()}}, spec["selector"])
+
+	svc.Availability = &domain.ServiceAvailability{MaxUnavailable: "25%"}
+	assert.Equal(t, "25%", PDB(svc, w)["spec"].(object)["maxUnavailable"])
+}
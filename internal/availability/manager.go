@@ -0,0 +1,198 @@
+package availability
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// workload is the Deployment or StatefulSet running a service
+type workload struct {
+	kind      string
+	name      string
+	namespace string
+	uid       string
+}
+
+// Manager applies the PodDisruptionBudgets of services
+type Manager struct {
+	config      *config.AvailabilityConfig
+	kube        domain.KubernetesClient
+	serviceRepo domain.ServiceRepository
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewManager creates a new Manager. kube may be nil, in which case
+// disruption budgets are unavailable.
+func NewManager(
+	cfg *config.AvailabilityConfig,
+	kube domain.KubernetesClient,
+	serviceRepo domain.ServiceRepository,
+	projectRepo domain.ProjectRepository,
+	log *logger.Logger,
+) *Manager {
+	return &Manager{
+		config:      cfg,
+		kube:        kube,
+		serviceRepo: serviceRepo,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// Apply applies the PodDisruptionBudget of a service's workload, or removes
+// the one the platform applied once the service no longer sets a budget.
+// Services that are not deployed yet are skipped.
+func (m *Manager) Apply(ctx context.Context, svc *domain.Service) error {
+	if svc.TargetClusterID == nil || svc.Type == domain.ServiceTypeCronJob {
+		return nil
+	}
+	if m.kube == nil {
+		return errors.ErrNoKubernetes
+	}
+	w, err := m.findWorkload(ctx, svc)
+	if err != nil || w == nil {
+		return err
+	}
+
+	if Budget(svc.Availability) == nil {
+		return m.deletePDB(ctx, svc, w)
+	}
+	manifest, err := json.Marshal(PDB(svc, w))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode PodDisruptionBudget")
+	}
+	if err := m.kube.ApplyManifest(ctx, *svc.TargetClusterID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+// deletePDB removes the PodDisruptionBudget the platform applied for a
+// workload, leaving any other alone
+func (m *Manager) deletePDB(ctx context.Context, svc *domain.Service, w *workload) error {
+	obj, err := m.kube.GetResource(ctx, *svc.TargetClusterID, "PodDisruptionBudget", w.namespace, w.name)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	if managedBy, _, _ := unstructured.NestedString(obj, "metadata", "labels", domain.LabelManagedBy); managedBy != domain.ManagedByValue {
+		return nil
+	}
+	if err := m.kube.DeleteResource(ctx, *svc.TargetClusterID, "PodDisruptionBudget", w.namespace, w.name); err != nil && !errors.IsNotFound(err) {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	m.logger.Info().Str("service_id", svc.ID.String()).Msg("Removed PodDisruptionBudget")
+	return nil
+}
+
+// findWorkload locates the Deployment or StatefulSet running a service on
+// its target cluster, nil when there is none yet
+func (m *Manager) findWorkload(ctx context.Context, svc *domain.Service) (*workload, error) {
+	selector := map[string]string{domain.LabelServiceID: svc.ID.String()}
+	for _, kind := range []string{"Deployment", "StatefulSet"} {
+		objects, err := m.kube.ListResources(ctx, *svc.TargetClusterID, kind, "", selector)
+		if err != nil {
+			return nil, errors.DependencyFailed("kubernetes", err)
+		}
+		if len(objects) == 0 {
+			continue
+		}
+		w := &workload{kind: kind}
+		w.name, _, _ = unstructured.NestedString(objects[0], "metadata", "name")
+		w.namespace, _, _ = unstructured.NestedString(objects[0], "metadata", "namespace")
+		w.uid, _, _ = unstructured.NestedString(objects[0], "metadata", "uid")
+		return w, nil
+	}
+	return nil, nil
+}
+
+// Run applies the disruption budget of every service until ctx is
+// cancelled, restoring any removed from a cluster by hand
+func (m *Manager) Run(ctx context.Context) {
+	if m.kube == nil {
+		m.logger.Warn().Msg("No Kubernetes client for workload clusters, disruption budgets are not applied")
+		return
+	}
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.applyAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Watch applies a service's disruption budget as it is created, changed or
+// deployed. Events go through durable consumers, so one replica handles each
+// and failures are retried; applying is idempotent.
+func (m *Manager) Watch(ctx context.Context, bus domain.EventBus) error {
+	if m.kube == nil {
+		return nil
+	}
+	for _, subject := range []string{"service.created", "service.updated", "deploy.completed"} {
+		if _, err := eventbus.SubscribeDurable(ctx, bus, subject, eventbus.DurableName("availability", subject), func(event *domain.Event) error {
+			return m.applyEvent(ctx, event)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Manager) applyEvent(ctx context.Context, event *domain.Event) error {
+	raw, _ := event.Data["service_id"].(string)
+	serviceID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	svc, err := m.serviceRepo.GetByID(ctx, serviceID)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		m.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to load service for its disruption budget")
+		return err
+	}
+	if err := m.Apply(ctx, svc); err != nil {
+		m.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to apply disruption budget")
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) applyAll(ctx context.Context) {
+	projects, err := m.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list projects for disruption budgets")
+		return
+	}
+	for _, project := range projects {
+		services, err := m.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			m.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list services for disruption budgets")
+			continue
+		}
+		for _, svc := range services {
+			if err := m.Apply(ctx, svc); err != nil {
+				m.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Failed to apply disruption budget")
+			}
+		}
+	}
+}
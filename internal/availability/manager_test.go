@@ -0,0 +1,28 @@
+package availability
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagerWithoutKubernetes(t *testing.T) {
+	clusterID := uuid.New()
+	svc := &domain.Service{ID: uuid.New(), Type: domain.ServiceTypeWebApp, TargetClusterID: &clusterID}
+	m := NewManager(&config.AvailabilityConfig{}, nil, nil, nil, logger.New("error", "json", io.Discard))
+	ctx := context.Background()
+
+	// Agents are disabled: disruption budgets are reported as unavailable
+	assert.ErrorIs(t, m.Apply(ctx, svc), errors.ErrNoKubernetes)
+
+	// and nothing is applied in the background
+	assert.NoError(t, m.Watch(ctx, nil))
+	m.Run(ctx)
+}
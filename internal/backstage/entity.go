@@ -0,0 +1,235 @@
+// Package backstage publishes the platform's projects and services as
+// Backstage catalog entities, so organizations running Backstage can surface
+// platform state in their existing portal. Projects become Systems, databases
+// become Resources, other services become Components, and services that
+// declare an API definition also provide an API.
+package backstage
+
+import (
+	"strings"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+)
+
+// Entity kinds
+const (
+	KindSystem    = "System"
+	KindComponent = "Component"
+	KindAPI       = "API"
+	KindResource  = "Resource"
+)
+
+// Service annotations that declare the API a service provides
+const (
+	AnnotationAPIDefinition = "northstack.io/api-definition" // URL of the API definition
+	AnnotationAPIType       = "northstack.io/api-type"       // openapi (default), asyncapi, graphql or grpc
+)
+
+// Annotations set on the generated entities
+const (
+	annotationProjectID       = "northstack.io/project-id"
+	annotationServiceID       = "northstack.io/service-id"
+	annotationManagedBy       = "backstage.io/managed-by-location"
+	annotationManagedByOrigin = "backstage.io/managed-by-origin-location"
+	annotationSourceLocation  = "backstage.io/source-location"
+	annotationKubernetes      = "backstage.io/kubernetes-label-selector"
+)
+
+const apiVersion = "backstage.io/v1alpha1"
+
+// defaultLifecycle is used for services without a catalog lifecycle
+const defaultLifecycle = "production"
+
+// Entity is a Backstage catalog entity
+type Entity struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Metadata   Metadata `json:"metadata"`
+	Spec       Spec     `json:"spec"`
+}
+
+// Metadata is the metadata of a Backstage entity
+type Metadata struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Links       []Link            `json:"links,omitempty"`
+}
+
+// Link is an external link shown on an entity page
+type Link struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+}
+
+// Spec holds the spec fields of every generated kind; unused fields are omitted
+type Spec struct {
+	Type         string      `json:"type,omitempty"`
+	Lifecycle    string      `json:"lifecycle,omitempty"`
+	Owner        string      `json:"owner"`
+	System       string      `json:"system,omitempty"`
+	ProvidesAPIs []string    `json:"providesApis,omitempty"`
+	Definition   interface{} `json:"definition,omitempty"`
+}
+
+// Ref returns the entity reference, kind:namespace/name
+func (e *Entity) Ref() string {
+	return strings.ToLower(e.Kind) + ":" + e.Metadata.Namespace + "/" + e.Metadata.Name
+}
+
+// Generate builds the entities of a project and its services. location is the
+// URL the entities are served from, recorded as their managing location.
+func Generate(cfg *config.BackstageConfig, project *domain.Project, services []*domain.Service, location string) []*Entity {
+	namespace := project.Slug
+	annotations := func(extra map[string]string) map[string]string {
+		a := map[string]string{
+			annotationProjectID:       project.ID.String(),
+			annotationManagedBy:       "url:" + location,
+			annotationManagedByOrigin: "url:" + location,
+		}
+		for k, v := range extra {
+			a[k] = v
+		}
+		return a
+	}
+
+	system := &Entity{
+		APIVersion: apiVersion,
+		Kind:       KindSystem,
+		Metadata: Metadata{
+			Name:        project.Slug,
+			Namespace:   namespace,
+			Title:       project.Name,
+			Description: project.Description,
+			Annotations: annotations(nil),
+			Links:       consoleLink(cfg, "/projects/"+project.ID.String()),
+		},
+		Spec: Spec{Owner: cfg.DefaultOwner},
+	}
+	entities := []*Entity{system}
+
+	for _, service := range services {
+		owner := service.Catalog.Owner
+		if owner == "" {
+			owner = cfg.DefaultOwner
+		}
+		lifecycle := string(service.Catalog.Lifecycle)
+		if lifecycle == "" {
+			lifecycle = defaultLifecycle
+		}
+
+		extra := map[string]string{
+			annotationServiceID:  service.ID.String(),
+			annotationKubernetes: domain.LabelServiceID + "=" + service.ID.String(),
+		}
+		if service.BuildSource.Type == "git" && service.BuildSource.Repository != "" {
+			extra[annotationSourceLocation] = "url:" + service.BuildSource.Repository
+		}
+
+		metadata := Metadata{
+			Name:        service.Slug,
+			Namespace:   namespace,
+			Title:       service.Name,
+			Annotations: annotations(extra),
+			Tags:        tags(service),
+			Links:       serviceLinks(cfg, service),
+		}
+
+		if service.Type == domain.ServiceTypeStatefulDB {
+			entities = append(entities, &Entity{
+				APIVersion: apiVersion,
+				Kind:       KindResource,
+				Metadata:   metadata,
+				Spec:       Spec{Type: "database", Owner: owner, System: project.Slug},
+			})
+			continue
+		}
+
+		component := &Entity{
+			APIVersion: apiVersion,
+			Kind:       KindComponent,
+			Metadata:   metadata,
+			Spec: Spec{
+				Type:      componentType(service.Type),
+				Lifecycle: lifecycle,
+				Owner:     owner,
+				System:    project.Slug,
+			},
+		}
+		entities = append(entities, component)
+
+		definition := service.Annotations[AnnotationAPIDefinition]
+		if definition == "" {
+			continue
+		}
+		apiType := service.Annotations[AnnotationAPIType]
+		if apiType == "" {
+			apiType = "openapi"
+		}
+		api := &Entity{
+			APIVersion: apiVersion,
+			Kind:       KindAPI,
+			Metadata: Metadata{
+				Name:        service.Slug + "-api",
+				Namespace:   namespace,
+				Title:       service.Name + " API",
+				Annotations: annotations(map[string]string{annotationServiceID: service.ID.String()}),
+				Tags:        metadata.Tags,
+			},
+			Spec: Spec{
+				Type:       apiType,
+				Lifecycle:  lifecycle,
+				Owner:      owner,
+				System:     project.Slug,
+				Definition: map[string]string{"$text": definition},
+			},
+		}
+		component.Spec.ProvidesAPIs = []string{api.Metadata.Name}
+		entities = append(entities, api)
+	}
+
+	return entities
+}
+
+func componentType(t domain.ServiceType) string {
+	switch t {
+	case domain.ServiceTypeWorker:
+		return "worker"
+	case domain.ServiceTypeCronJob:
+		return "cronjob"
+	default:
+		return "service"
+	}
+}
+
+// tags returns the service type and tier as Backstage tags, which allow only
+// lowercase letters, digits and single dashes
+func tags(service *domain.Service) []string {
+	tags := []string{strings.ReplaceAll(string(service.Type), "_", "-")}
+	if service.Catalog.Tier != "" {
+		tags = append(tags, string(service.Catalog.Tier))
+	}
+	return tags
+}
+
+func serviceLinks(cfg *config.BackstageConfig, service *domain.Service) []Link {
+	links := consoleLink(cfg, "/services/"+service.ID.String())
+	if service.Catalog.DocsURL != "" {
+		links = append(links, Link{URL: service.Catalog.DocsURL, Title: "Documentation"})
+	}
+	for _, slo := range service.Catalog.SLOLinks {
+		links = append(links, Link{URL: slo, Title: "SLO"})
+	}
+	return links
+}
+
+func consoleLink(cfg *config.BackstageConfig, path string) []Link {
+	if cfg.ConsoleURL == "" {
+		return nil
+	}
+	return []Link{{URL: strings.TrimSuffix(cfg.ConsoleURL, "/") + path, Title: "NorthStack"}}
+}
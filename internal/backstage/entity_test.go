@@ -0,0 +1,69 @@
+package backstage
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	cfg := &config.BackstageConfig{DefaultOwner: "platform-team", ConsoleURL: "https://console.example.com/"}
+	project := &domain.Project{ID: uuid.New(), Name: "Payments", Slug: "payments"}
+	api := &domain.Service{
+		ID:          uuid.New(),
+		Name:        "Payments API",
+		Slug:        "api",
+		Type:        domain.ServiceTypeWebApp,
+		BuildSource: domain.BuildSource{Type: "git", Repository: "https://github.com/acme/payments"},
+		Annotations: map[string]string{AnnotationAPIDefinition: "https://api.example.com/openapi.json"},
+		Catalog: domain.ServiceCatalog{
+			Owner:     "payments-team",
+			Tier:      domain.ServiceTier1,
+			Lifecycle: domain.ServiceLifecycleExperimental,
+			DocsURL:   "https://docs.example.com/payments",
+		},
+	}
+	db := &domain.Service{ID: uuid.New(), Name: "Ledger", Slug: "ledger", Type: domain.ServiceTypeStatefulDB}
+
+	entities := Generate(cfg, project, []*domain.Service{api, db}, "https://ns.example.com/api/v1/backstage/catalog-info.yaml")
+	require.Len(t, entities, 4)
+
+	system, component, definition, resource := entities[0], entities[1], entities[2], entities[3]
+	assert.Equal(t, "system:payments/payments", system.Ref())
+	assert.Equal(t, "platform-team", system.Spec.Owner)
+
+	assert.Equal(t, KindComponent, component.Kind)
+	assert.Equal(t, "service", component.Spec.Type)
+	assert.Equal(t, "experimental", component.Spec.Lifecycle)
+	assert.Equal(t, "payments-team", component.Spec.Owner)
+	assert.Equal(t, "payments", component.Spec.System)
+	assert.Equal(t, []string{"api-api"}, component.Spec.ProvidesAPIs)
+	assert.Equal(t, []string{"webapp", "tier-1"}, component.Metadata.Tags)
+	assert.Equal(t, "url:https://github.com/acme/payments", component.Metadata.Annotations[annotationSourceLocation])
+	assert.Equal(t, "https://console.example.com/services/"+api.ID.String(), component.Metadata.Links[0].URL)
+	assert.Equal(t, "Documentation", component.Metadata.Links[1].Title)
+
+	assert.Equal(t, "api:payments/api-api", definition.Ref())
+	assert.Equal(t, "openapi", definition.Spec.Type)
+	assert.Equal(t, map[string]string{"$text": "https://api.example.com/openapi.json"}, definition.Spec.Definition)
+
+	assert.Equal(t, KindResource, resource.Kind)
+	assert.Equal(t, "database", resource.Spec.Type)
+	assert.Equal(t, "platform-team", resource.Spec.Owner)
+	assert.Equal(t, []string{"stateful-db"}, resource.Metadata.Tags)
+	assert.Empty(t, resource.Spec.Lifecycle)
+}
+
+func TestMarshalYAML(t *testing.T) {
+	doc, err := MarshalYAML([]*Entity{
+		{APIVersion: apiVersion, Kind: KindSystem, Metadata: Metadata{Name: "a"}, Spec: Spec{Owner: "o"}},
+		{APIVersion: apiVersion, Kind: KindSystem, Metadata: Metadata{Name: "b"}, Spec: Spec{Owner: "o"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "apiVersion: backstage.io/v1alpha1\nkind: System\nmetadata:\n  name: a\nspec:\n  owner: o\n---\n"+
+		"apiVersion: backstage.io/v1alpha1\nkind: System\nmetadata:\n  name: b\nspec:\n  owner: o\n", string(doc))
+}
@@ -0,0 +1,93 @@
+package backstage
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"sigs.k8s.io/yaml"
+)
+
+// Provider generates the catalog entities from the platform's current state
+type Provider struct {
+	config      *config.BackstageConfig
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	logger      *logger.Logger
+}
+
+// NewProvider creates a new Provider
+func NewProvider(cfg *config.BackstageConfig, projectRepo domain.ProjectRepository, serviceRepo domain.ServiceRepository, log *logger.Logger) *Provider {
+	return &Provider{
+		config:      cfg,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		logger:      log,
+	}
+}
+
+// Token returns the credential Backstage presents, empty if none is required
+func (p *Provider) Token() string {
+	return p.config.Token
+}
+
+// Entities returns the entities of every project and service
+func (p *Provider) Entities(ctx context.Context, location string) ([]*Entity, error) {
+	projects, err := p.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	entities := []*Entity{}
+	for _, project := range projects {
+		services, err := p.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			return nil, err
+		}
+		entities = append(entities, Generate(p.config, project, services, location)...)
+	}
+
+	return entities, nil
+}
+
+// Entity returns a single entity by kind, namespace and name. Namespaces are
+// project slugs, so only that project is loaded.
+func (p *Provider) Entity(ctx context.Context, kind, namespace, name, location string) (*Entity, error) {
+	project, err := p.projectRepo.GetBySlug(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := p.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entity := range Generate(p.config, project, services, location) {
+		if strings.EqualFold(entity.Kind, kind) && entity.Metadata.Name == name {
+			return entity, nil
+		}
+	}
+
+	return nil, errors.NotFound("entity", kind+":"+namespace+"/"+name)
+}
+
+// MarshalYAML encodes entities as a multi-document catalog-info.yaml
+func MarshalYAML(entities []*Entity) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, entity := range entities {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		doc, err := yaml.Marshal(entity)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(doc)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,139 @@
+// Package buildanalytics aggregates build durations, broken down by stage where
+// the CI adapter reports one, into weekly p50/p95 trends per service and flags
+// services whose builds slow down significantly week over week.
+package buildanalytics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// maxBuilds bounds the build history loaded for a service
+const maxBuilds = 1000
+
+// Report is the build time analysis for a service
+type Report struct {
+	ServiceID  uuid.UUID   `json:"service_id"`
+	Weeks      []Week      `json:"weeks"`
+	Bottleneck string      `json:"bottleneck,omitempty"` // Slowest stage in the most recent week
+	Regression *Regression `json:"regression,omitempty"`
+}
+
+// Analyzer computes build time analytics
+type Analyzer struct {
+	buildRepo   domain.BuildRepository
+	serviceRepo domain.ServiceRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+
+	mu      sync.Mutex
+	flagged map[uuid.UUID]time.Time // Last regression event per service
+}
+
+// NewAnalyzer creates a new Analyzer
+func NewAnalyzer(buildRepo domain.BuildRepository, serviceRepo domain.ServiceRepository, eventBus domain.EventBus, log *logger.Logger) *Analyzer {
+	return &Analyzer{
+		buildRepo:   buildRepo,
+		serviceRepo: serviceRepo,
+		eventBus:    eventBus,
+		logger:      log,
+		flagged:     make(map[uuid.UUID]time.Time),
+	}
+}
+
+// ServiceReport analyzes the last n weeks of builds for a service
+func (a *Analyzer) ServiceReport(ctx context.Context, serviceID uuid.UUID, weeks int) (*Report, error) {
+	builds, err := a.buildRepo.ListByService(ctx, serviceID, maxBuilds)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		ServiceID: serviceID,
+		Weeks:     weekly(builds, time.Now().UTC(), weeks),
+	}
+	if latest := report.Weeks[len(report.Weeks)-1]; len(latest.Stages) > 0 {
+		report.Bottleneck = latest.Stages[0].Name
+	}
+	report.Regression = detectRegression(report.Weeks)
+
+	return report, nil
+}
+
+// Regressions returns the reports of services in a project whose build times regressed
+func (a *Analyzer) Regressions(ctx context.Context, projectID uuid.UUID) ([]*Report, error) {
+	services, err := a.serviceRepo.ListByProject(ctx, projectID, domain.ServiceFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []*Report{}
+	for _, svc := range services {
+		report, err := a.ServiceReport(ctx, svc.ID, 2)
+		if err != nil {
+			return nil, err
+		}
+		if report.Regression != nil {
+			reports = append(reports, report)
+		}
+	}
+	return reports, nil
+}
+
+// Watch re-checks a service after each completed build and publishes
+// build.duration_regressed at most once a week per service. Builds go through
+// a durable consumer, so one replica checks each and failures are retried.
+func (a *Analyzer) Watch(ctx context.Context) error {
+	_, err := eventbus.SubscribeDurable(ctx, a.eventBus, "build.completed", eventbus.DurableName("buildanalytics", "build.completed"), func(event *domain.Event) error {
+		raw, _ := event.Data["service_id"].(string)
+		serviceID, err := uuid.Parse(raw)
+		if err != nil {
+			return nil
+		}
+
+		report, err := a.ServiceReport(ctx, serviceID, 2)
+		if err != nil {
+			return err
+		}
+		if report.Regression == nil || !a.shouldFlag(serviceID) {
+			return nil
+		}
+
+		a.logger.Warn().
+			Str("service_id", serviceID.String()).
+			Float64("previous_p50", report.Regression.PreviousP50).
+			Float64("current_p50", report.Regression.CurrentP50).
+			Msg("Build time regression detected")
+
+		return a.eventBus.Publish(ctx, "build.duration_regressed", &domain.Event{
+			Type:   "build.duration_regressed",
+			Source: "build-analytics",
+			Data: map[string]interface{}{
+				"service_id":           serviceID.String(),
+				"project_id":           event.Data["project_id"],
+				"previous_p50_seconds": report.Regression.PreviousP50,
+				"current_p50_seconds":  report.Regression.CurrentP50,
+				"change":               report.Regression.Change,
+				"bottleneck":           report.Bottleneck,
+			},
+		})
+	})
+	return err
+}
+
+func (a *Analyzer) shouldFlag(serviceID uuid.UUID) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if last, ok := a.flagged[serviceID]; ok && time.Since(last) < week {
+		return false
+	}
+	a.flagged[serviceID] = time.Now()
+	return true
+}
@@ -0,0 +1,145 @@
+package buildanalytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+const (
+	week = 7 * 24 * time.Hour
+
+	// regressionThreshold is the week-over-week p50 increase that counts as a regression
+	regressionThreshold = 0.25
+	// minSamples is the number of builds a week needs before it is compared
+	minSamples = 3
+)
+
+// StageStats summarizes the duration of one build stage
+type StageStats struct {
+	Name  string  `json:"name"`
+	P50   float64 `json:"p50_seconds"`
+	P95   float64 `json:"p95_seconds"`
+	Share float64 `json:"share"` // Fraction of the summed stage p50s
+}
+
+// Week summarizes successful builds in a seven-day bucket
+type Week struct {
+	Start  time.Time    `json:"start"`
+	Builds int          `json:"builds"`
+	P50    float64      `json:"p50_seconds"`
+	P95    float64      `json:"p95_seconds"`
+	Stages []StageStats `json:"stages,omitempty"`
+}
+
+// Regression describes a week-over-week build time increase
+type Regression struct {
+	PreviousP50 float64 `json:"previous_p50_seconds"`
+	CurrentP50  float64 `json:"current_p50_seconds"`
+	Change      float64 `json:"change"` // Relative increase, e.g. 0.4 for +40%
+}
+
+// weekly buckets successful builds into weeks ending at end, oldest first
+func weekly(builds []*domain.Build, end time.Time, weeks int) []Week {
+	result := make([]Week, weeks)
+	totals := make([][]float64, weeks)
+	stages := make([]map[string][]float64, weeks)
+
+	start := end.Add(-time.Duration(weeks) * week)
+	for i := range result {
+		result[i].Start = start.Add(time.Duration(i) * week)
+		stages[i] = make(map[string][]float64)
+	}
+
+	for _, b := range builds {
+		if b.Status != domain.BuildStatusSucceeded || b.CompletedAt == nil {
+			continue
+		}
+		if b.CompletedAt.Before(start) || !b.CompletedAt.Before(end) {
+			continue
+		}
+		i := int(b.CompletedAt.Sub(start) / week)
+
+		totals[i] = append(totals[i], duration(b))
+		for _, s := range b.Stages {
+			stages[i][s.Name] = append(stages[i][s.Name], float64(s.Duration))
+		}
+	}
+
+	for i := range result {
+		result[i].Builds = len(totals[i])
+		result[i].P50 = percentile(totals[i], 50)
+		result[i].P95 = percentile(totals[i], 95)
+		result[i].Stages = stageStats(stages[i])
+	}
+	return result
+}
+
+// stageStats computes per-stage percentiles ordered by p50, slowest first
+func stageStats(stages map[string][]float64) []StageStats {
+	var stats []StageStats
+	var total float64
+	for name, values := range stages {
+		s := StageStats{Name: name, P50: percentile(values, 50), P95: percentile(values, 95)}
+		total += s.P50
+		stats = append(stats, s)
+	}
+	for i := range stats {
+		if total > 0 {
+			stats[i].Share = round(stats[i].P50 / total)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].P50 != stats[j].P50 {
+			return stats[i].P50 > stats[j].P50
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// detectRegression compares the last two weeks
+func detectRegression(weeks []Week) *Regression {
+	if len(weeks) < 2 {
+		return nil
+	}
+	prev, cur := weeks[len(weeks)-2], weeks[len(weeks)-1]
+	if prev.Builds < minSamples || cur.Builds < minSamples || prev.P50 == 0 {
+		return nil
+	}
+
+	change := (cur.P50 - prev.P50) / prev.P50
+	if change < regressionThreshold {
+		return nil
+	}
+	return &Regression{PreviousP50: prev.P50, CurrentP50: cur.P50, Change: round(change)}
+}
+
+// duration returns the build's wall time in seconds
+func duration(b *domain.Build) float64 {
+	if b.StartedAt != nil && b.CompletedAt != nil {
+		return b.CompletedAt.Sub(*b.StartedAt).Seconds()
+	}
+	return float64(b.Duration)
+}
+
+// percentile uses linear interpolation between closest ranks
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	v := sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+	return round(v)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,51 @@
+package buildanalytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{10, 20, 30, 40, 50}
+	assert.Equal(t, 30.0, percentile(values, 50))
+	assert.Equal(t, 48.0, percentile(values, 95))
+	assert.Equal(t, 0.0, percentile(nil, 50))
+}
+
+func TestWeeklyRegression(t *testing.T) {
+	end := time.Date(2026, 5, 15, 0, 0, 0, 0, time.UTC)
+
+	build := func(daysAgo int, seconds int64, stages ...domain.BuildStage) *domain.Build {
+		completed := end.Add(-time.Duration(daysAgo) * 24 * time.Hour)
+		return &domain.Build{
+			Status:      domain.BuildStatusSucceeded,
+			Duration:    seconds,
+			Stages:      stages,
+			CompletedAt: &completed,
+		}
+	}
+	slow := []domain.BuildStage{{Name: domain.BuildStageCompile, Duration: 150}, {Name: domain.BuildStageClone, Duration: 10}}
+
+	builds := []*domain.Build{
+		build(10, 100), build(9, 100), build(8, 110),
+		build(3, 160, slow...), build(2, 170, slow...), build(1, 180, slow...),
+		{Status: domain.BuildStatusFailed},
+	}
+
+	weeks := weekly(builds, end, 2)
+	assert.Equal(t, 3, weeks[0].Builds)
+	assert.Equal(t, 100.0, weeks[0].P50)
+	assert.Equal(t, 170.0, weeks[1].P50)
+	assert.Equal(t, domain.BuildStageCompile, weeks[1].Stages[0].Name)
+
+	regression := detectRegression(weeks)
+	if assert.NotNil(t, regression) {
+		assert.Equal(t, 0.7, regression.Change)
+	}
+
+	// Too few samples are never compared
+	assert.Nil(t, detectRegression(weekly(builds[3:], end, 2)))
+}
@@ -0,0 +1,222 @@
+// Package buildtracker keeps persisted builds in step with the CI system:
+// queued and running builds are polled until they finish, their status,
+// image and stage timings are stored, and build.completed, build.failed or
+// build.canceled is published once they do.
+package buildtracker
+
+import (
+	"context"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// pollInterval is how often in-flight builds are checked without a webhook
+	pollInterval = 15 * time.Second
+	// maxActive bounds the number of in-flight builds checked per sync
+	maxActive = 200
+)
+
+// Tracker syncs in-flight builds from the CI system
+type Tracker struct {
+	ci          domain.CIAdapter
+	buildRepo   domain.BuildRepository
+	serviceRepo domain.ServiceRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+	wake        chan struct{}
+}
+
+// NewTracker creates a new Tracker
+func NewTracker(
+	ci domain.CIAdapter,
+	buildRepo domain.BuildRepository,
+	serviceRepo domain.ServiceRepository,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *Tracker {
+	return &Tracker{
+		ci:          ci,
+		buildRepo:   buildRepo,
+		serviceRepo: serviceRepo,
+		eventBus:    eventBus,
+		logger:      log,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Run syncs in-flight builds every pollInterval, and as soon as the CI system
+// reports progress through its webhook, until ctx is cancelled
+func (t *Tracker) Run(ctx context.Context) {
+	// Waking one replica is enough: every replica syncs the same builds, and
+	// a sync leaves builds that are up to date alone
+	_, err := eventbus.SubscribeDurable(ctx, t.eventBus, "webhook.received", eventbus.DurableName("buildtracker", "webhook.received"), func(event *domain.Event) error {
+		if event.Type == "webhook.coolify.build" {
+			select {
+			case t.wake <- struct{}{}:
+			default:
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.logger.Warn().Err(err).Msg("Failed to subscribe build tracker to CI webhooks, polling only")
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		t.sync(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-t.wake:
+		}
+	}
+}
+
+// sync refreshes every in-flight build from the CI system
+func (t *Tracker) sync(ctx context.Context) {
+	builds, err := t.buildRepo.ListActive(ctx, maxActive)
+	if err != nil {
+		t.logger.Warn().Err(err).Msg("Failed to list in-flight builds")
+		return
+	}
+
+	for _, build := range builds {
+		externalID, _ := build.Metadata["coolify_build_id"].(string)
+		if externalID == "" {
+			continue
+		}
+
+		remote, err := t.ci.GetBuildStatus(ctx, externalID)
+		if errors.IsNotFound(err) {
+			remote = &domain.Build{Status: domain.BuildStatusFailed, ErrorMessage: "build is no longer known to the CI system"}
+		} else if err != nil {
+			t.logger.Warn().Err(err).Str("build_id", build.ID.String()).Msg("Failed to get build status")
+			continue
+		}
+
+		if !merge(build, remote, time.Now()) {
+			continue
+		}
+
+		if err := t.buildRepo.Update(ctx, build); err != nil {
+			t.logger.Warn().Err(err).Str("build_id", build.ID.String()).Msg("Failed to update build")
+			continue
+		}
+
+		t.finish(ctx, build)
+	}
+}
+
+// finish publishes the outcome of a build that reached a terminal status
+func (t *Tracker) finish(ctx context.Context, build *domain.Build) {
+	var eventType string
+	switch build.Status {
+	case domain.BuildStatusSucceeded:
+		eventType = "build.completed"
+	case domain.BuildStatusFailed:
+		eventType = "build.failed"
+		if err := t.serviceRepo.UpdateStatus(ctx, build.ServiceID, domain.ServiceStatusFailed); err != nil {
+			t.logger.Warn().Err(err).Str("service_id", build.ServiceID.String()).Msg("Failed to mark service failed")
+		}
+	case domain.BuildStatusCanceled:
+		eventType = "build.canceled"
+	default:
+		return
+	}
+
+	t.logger.Info().
+		Str("build_id", build.ID.String()).
+		Str("service_id", build.ServiceID.String()).
+		Str("status", string(build.Status)).
+		Int64("duration", build.Duration).
+		Msg("Build finished")
+
+	err := t.eventBus.Publish(ctx, eventType, &domain.Event{
+		Type:   eventType,
+		Source: "platform-orchestrator",
+		Data: map[string]interface{}{
+			"build_id":   build.ID.String(),
+			"service_id": build.ServiceID.String(),
+			"project_id": build.ProjectID.String(),
+			"status":     string(build.Status),
+			"image_tag":  build.ImageTag,
+			"duration":   build.Duration,
+			"error":      build.ErrorMessage,
+		},
+	})
+	if err != nil {
+		t.logger.Warn().Err(err).Str("build_id", build.ID.String()).Msg("Failed to publish build event")
+	}
+}
+
+// merge copies the CI system's view of a build onto the stored build and
+// reports whether anything changed. Terminal builds always get a completion
+// time, falling back to now when the CI system reports none.
+func merge(build, remote *domain.Build, now time.Time) bool {
+	changed := false
+
+	if remote.Status != "" && remote.Status != build.Status {
+		build.Status = remote.Status
+		changed = true
+	}
+	if remote.ImageTag != "" && remote.ImageTag != build.ImageTag {
+		build.ImageTag = remote.ImageTag
+		changed = true
+	}
+	if remote.ImageDigest != "" && remote.ImageDigest != build.ImageDigest {
+		build.ImageDigest = remote.ImageDigest
+		changed = true
+	}
+	if remote.BuildLogs != "" && remote.BuildLogs != build.BuildLogs {
+		build.BuildLogs = remote.BuildLogs
+		changed = true
+	}
+	if remote.ErrorMessage != "" && remote.ErrorMessage != build.ErrorMessage {
+		build.ErrorMessage = remote.ErrorMessage
+		changed = true
+	}
+	if remote.Duration != 0 && remote.Duration != build.Duration {
+		build.Duration = remote.Duration
+		changed = true
+	}
+	if len(remote.Stages) > 0 && len(remote.Stages) != len(build.Stages) {
+		build.Stages = remote.Stages
+		changed = true
+	}
+	if remote.StartedAt != nil && build.StartedAt == nil {
+		build.StartedAt = remote.StartedAt
+		changed = true
+	}
+
+	if terminal(build.Status) && build.CompletedAt == nil {
+		completed := now
+		if remote.CompletedAt != nil {
+			completed = *remote.CompletedAt
+		}
+		build.CompletedAt = &completed
+		if build.Duration == 0 && build.StartedAt != nil {
+			build.Duration = int64(completed.Sub(*build.StartedAt).Seconds())
+		}
+		changed = true
+	}
+
+	return changed
+}
+
+func terminal(status domain.BuildStatus) bool {
+	switch status {
+	case domain.BuildStatusSucceeded, domain.BuildStatusFailed, domain.BuildStatusCanceled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package buildtracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	started := now.Add(-90 * time.Second)
+	build := &domain.Build{Status: domain.BuildStatusQueued}
+
+	assert.False(t, merge(build, &domain.Build{Status: domain.BuildStatusQueued}, now))
+
+	assert.True(t, merge(build, &domain.Build{Status: domain.BuildStatusRunning, StartedAt: &started}, now))
+	assert.Equal(t, domain.BuildStatusRunning, build.Status)
+	assert.Nil(t, build.CompletedAt)
+
+	// No completion time or duration from the CI system: derived from now
+	assert.True(t, merge(build, &domain.Build{
+		Status:   domain.BuildStatusSucceeded,
+		ImageTag: "registry.example.com/api:abc123",
+		Stages:   []domain.BuildStage{{Name: domain.BuildStageCompile, Duration: 60}},
+	}, now))
+	assert.Equal(t, "registry.example.com/api:abc123", build.ImageTag)
+	assert.Equal(t, now, *build.CompletedAt)
+	assert.Equal(t, int64(90), build.Duration)
+	assert.Len(t, build.Stages, 1)
+
+	assert.False(t, merge(build, &domain.Build{Status: domain.BuildStatusSucceeded}, now.Add(time.Minute)))
+}
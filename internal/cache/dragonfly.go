@@ -47,6 +47,16 @@ func NewDragonflyDB(cfg config.DragonflyDBConfig) (*DragonflyDB, error) {
 	}, nil
 }
 
+// Client returns the underlying client, e.g. for the streams event bus
+func (d *DragonflyDB) Client() redis.UniversalClient {
+	return d.client
+}
+
+// KeyPrefix returns the prefix of every key the platform stores
+func (d *DragonflyDB) KeyPrefix() string {
+	return d.config.KeyPrefix
+}
+
 func (d *DragonflyDB) Health(ctx context.Context) error {
 	return d.client.Ping(ctx).Err()
 }
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotentResponse is the stored outcome of a request made with an
+// Idempotency-Key. Until the request completes it only holds the fingerprint.
+type IdempotentResponse struct {
+	Fingerprint string            `json:"fingerprint"` // Hash of the method, path and body
+	Completed   bool              `json:"completed"`
+	Status      int               `json:"status,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// Reserve claims key for a request with the given fingerprint. If the key is
+// already claimed, the stored record is returned instead and nothing changes.
+func (d *DragonflyDB) Reserve(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotentResponse, error) {
+	data, err := json.Marshal(&IdempotentResponse{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	full := d.config.KeyPrefix + ":" + key
+	ok, err := d.client.SetNX(ctx, full, data, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if ok {
+		return nil, nil
+	}
+
+	stored, err := d.client.Get(ctx, full).Bytes()
+	if err == redis.Nil {
+		// Expired or released in between; the caller may retry
+		return nil, fmt.Errorf("idempotency key %s changed while reserving it", key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	record := &IdempotentResponse{}
+	if err := json.Unmarshal(stored, record); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency key: %w", err)
+	}
+	return record, nil
+}
+
+// Complete stores the response of a reserved key
+func (d *DragonflyDB) Complete(ctx context.Context, key string, response *IdempotentResponse, ttl time.Duration) error {
+	return d.Set(ctx, key, response, ttl)
+}
+
+// Release drops a reserved key so that the request can be retried
+func (d *DragonflyDB) Release(ctx context.Context, key string) error {
+	return d.Delete(ctx, key)
+}
@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitResult describes the outcome of a rate limit check
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	ResetAt   time.Time
+}
+
+// slidingWindowScript implements a sliding window log on a sorted set.
+// It returns {allowed, count, reset_ms}.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local member = ARGV[4]
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, member)
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = now + window
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window
+end
+return {allowed, count, reset}
+`)
+
+// Allow records a request against a sliding window rate limit
+func (d *DragonflyDB) Allow(ctx context.Context, key string, limit int, window time.Duration) (*RateLimitResult, error) {
+	now := time.Now().UnixMilli()
+	res, err := slidingWindowScript.Run(ctx, d.client,
+		[]string{d.config.KeyPrefix + ":" + key},
+		now, window.Milliseconds(), limit, fmt.Sprintf("%d-%s", now, uuid.New().String()),
+	).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate rate limit: %w", err)
+	}
+
+	remaining := limit - int(res[1])
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &RateLimitResult{
+		Allowed:   res[0] == 1,
+		Limit:     limit,
+		Remaining: remaining,
+		ResetAt:   time.UnixMilli(res[2]),
+	}, nil
+}
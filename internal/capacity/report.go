@@ -0,0 +1,158 @@
+// Package capacity reports how full managed clusters are. Allocatable and
+// requested CPU, memory and pods come from the cluster manager; when a
+// metrics collector is configured, the growth of actual usage over a
+// trailing window forecasts when each resource runs out.
+package capacity
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// maxHorizon bounds forecasts; growth that fills a cluster later than this
+// is reported without a date
+const maxHorizon = 5 * 365 * 24 * time.Hour
+
+// Report is the capacity of a cluster
+type Report struct {
+	ClusterID   uuid.UUID                    `json:"cluster_id"`
+	CPU         Resource                     `json:"cpu"`    // Cores
+	Memory      Resource                     `json:"memory"` // Bytes
+	Pods        Resource                     `json:"pods"`
+	Namespaces  []domain.NamespaceAllocation `json:"namespaces"`
+	GeneratedAt time.Time                    `json:"generated_at"`
+}
+
+// Resource is the allocation of one resource of a cluster
+type Resource struct {
+	Allocatable float64   `json:"allocatable"`
+	Requested   float64   `json:"requested"`
+	Headroom    float64   `json:"headroom"`           // Allocatable not requested yet
+	Utilization float64   `json:"utilization"`        // Fraction of allocatable requested
+	Forecast    *Forecast `json:"forecast,omitempty"` // Without metrics, no forecast is made
+}
+
+// Forecast extrapolates the usage of a resource
+type Forecast struct {
+	Usage        float64    `json:"usage"`             // Latest actual usage
+	GrowthPerDay float64    `json:"growth_per_day"`    // Trend of usage over the forecast window
+	FullAt       *time.Time `json:"full_at,omitempty"` // When usage reaches allocatable at that growth; unset when it is not growing, or not within five years
+}
+
+// Reporter builds capacity reports
+type Reporter struct {
+	config         *config.CapacityConfig
+	clusterManager domain.ClusterManagerAdapter
+	metrics        domain.MetricsCollector
+	logger         *logger.Logger
+}
+
+// NewReporter creates a new Reporter. metrics may be nil, in which case
+// reports carry no forecasts.
+func NewReporter(cfg *config.CapacityConfig, clusterManager domain.ClusterManagerAdapter, metrics domain.MetricsCollector, log *logger.Logger) *Reporter {
+	return &Reporter{
+		config:         cfg,
+		clusterManager: clusterManager,
+		metrics:        metrics,
+		logger:         log,
+	}
+}
+
+// Report returns the capacity of a cluster. Forecasts are left out when the
+// metrics cannot be read rather than failing the report.
+func (r *Reporter) Report(ctx context.Context, cluster *domain.Cluster) (*Report, error) {
+	if cluster.RancherClusterID == "" {
+		return nil, errors.BadRequest("cluster is not managed by the platform")
+	}
+
+	capacity, err := r.clusterManager.GetClusterCapacity(ctx, cluster.RancherClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	report := &Report{
+		ClusterID:   cluster.ID,
+		CPU:         resource(capacity.CPU),
+		Memory:      resource(capacity.Memory),
+		Pods:        resource(capacity.Pods),
+		Namespaces:  capacity.Namespaces,
+		GeneratedAt: now,
+	}
+	if report.Namespaces == nil {
+		report.Namespaces = []domain.NamespaceAllocation{}
+	}
+
+	if r.metrics != nil {
+		metrics, err := r.metrics.GetClusterMetrics(ctx, cluster.ID, domain.TimeRange{
+			Start: now.Add(-r.config.ForecastWindow).Unix(),
+			End:   now.Unix(),
+			Step:  int64(r.config.ForecastStep / time.Second),
+		})
+		if err != nil {
+			r.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to read cluster metrics for capacity forecast")
+		} else {
+			report.CPU.Forecast = forecast(metrics.CPUUsage, report.CPU.Allocatable)
+			report.Memory.Forecast = forecast(metrics.MemoryUsage, report.Memory.Allocatable)
+			report.Pods.Forecast = forecast(metrics.PodCount, report.Pods.Allocatable)
+		}
+	}
+
+	return report, nil
+}
+
+func resource(c domain.ResourceCapacity) Resource {
+	res := Resource{
+		Allocatable: c.Allocatable,
+		Requested:   c.Requested,
+		Headroom:    c.Allocatable - c.Requested,
+	}
+	if c.Allocatable > 0 {
+		res.Utilization = c.Requested / c.Allocatable
+	}
+	return res
+}
+
+// forecast fits a least-squares line through the usage points and, when
+// usage grows, extrapolates when it reaches allocatable. It returns nil for
+// fewer than two points.
+func forecast(points []domain.MetricPoint, allocatable float64) *Forecast {
+	if len(points) < 2 {
+		return nil
+	}
+
+	// Timestamps are offset from the first point to keep the sums small
+	t0 := points[0].Timestamp
+	var sumT, sumV, sumTT, sumTV float64
+	for _, p := range points {
+		t := float64(p.Timestamp - t0)
+		sumT += t
+		sumV += p.Value
+		sumTT += t * t
+		sumTV += t * p.Value
+	}
+	n := float64(len(points))
+	denominator := n*sumTT - sumT*sumT
+	if denominator == 0 {
+		return nil
+	}
+	slope := (n*sumTV - sumT*sumV) / denominator // Per second
+
+	last := points[len(points)-1]
+	f := &Forecast{Usage: last.Value, GrowthPerDay: slope * 86400}
+	if slope > 0 && allocatable > 0 {
+		seconds := math.Max(0, (allocatable-last.Value)/slope)
+		if seconds < maxHorizon.Seconds() {
+			fullAt := time.Unix(last.Timestamp, 0).Add(time.Duration(seconds * float64(time.Second))).UTC()
+			f.FullAt = &fullAt
+		}
+	}
+	return f
+}
@@ -0,0 +1,87 @@
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClusters struct {
+	domain.ClusterManagerAdapter
+	capacity *domain.ClusterCapacity
+}
+
+func (f fakeClusters) GetClusterCapacity(context.Context, string) (*domain.ClusterCapacity, error) {
+	return f.capacity, nil
+}
+
+type fakeMetrics struct {
+	domain.MetricsCollector
+	metrics *domain.ClusterMetrics
+	err     error
+}
+
+func (f fakeMetrics) GetClusterMetrics(context.Context, uuid.UUID, domain.TimeRange) (*domain.ClusterMetrics, error) {
+	return f.metrics, f.err
+}
+
+func TestForecast(t *testing.T) {
+	day := int64(86400)
+	growing := []domain.MetricPoint{{Timestamp: 0, Value: 4}, {Timestamp: day, Value: 5}, {Timestamp: 2 * day, Value: 6}}
+
+	f := forecast(growing, 10)
+	require.NotNil(t, f)
+	assert.Equal(t, 6.0, f.Usage)
+	assert.InDelta(t, 1.0, f.GrowthPerDay, 1e-9)
+	require.NotNil(t, f.FullAt)
+	assert.Equal(t, time.Unix(6*day, 0).UTC(), *f.FullAt)
+
+	// Shrinking usage never fills the cluster
+	shrinking := []domain.MetricPoint{{Timestamp: 0, Value: 6}, {Timestamp: day, Value: 5}}
+	f = forecast(shrinking, 10)
+	require.NotNil(t, f)
+	assert.Less(t, f.GrowthPerDay, 0.0)
+	assert.Nil(t, f.FullAt)
+
+	assert.Nil(t, forecast(growing[:1], 10))
+}
+
+func TestReport(t *testing.T) {
+	clusters := fakeClusters{capacity: &domain.ClusterCapacity{
+		CPU:    domain.ResourceCapacity{Allocatable: 8, Requested: 6},
+		Memory: domain.ResourceCapacity{Allocatable: 32e9, Requested: 8e9},
+		Pods:   domain.ResourceCapacity{Allocatable: 110, Requested: 20},
+	}}
+	cluster := &domain.Cluster{ID: uuid.New(), RancherClusterID: "c-1"}
+	cfg := &config.CapacityConfig{ForecastWindow: 7 * 24 * time.Hour, ForecastStep: time.Hour}
+	log := logger.New("error", "json", io.Discard)
+
+	report, err := NewReporter(cfg, clusters, nil, log).Report(context.Background(), cluster)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, report.CPU.Headroom)
+	assert.Equal(t, 0.75, report.CPU.Utilization)
+	assert.Equal(t, 0.25, report.Memory.Utilization)
+	assert.Nil(t, report.CPU.Forecast)
+	assert.NotNil(t, report.Namespaces)
+
+	// Metrics that cannot be read leave the forecast out
+	report, err = NewReporter(cfg, clusters, fakeMetrics{err: fmt.Errorf("prometheus down")}, log).Report(context.Background(), cluster)
+	require.NoError(t, err)
+	assert.Nil(t, report.Memory.Forecast)
+
+	metrics := &domain.ClusterMetrics{CPUUsage: []domain.MetricPoint{{Timestamp: 0, Value: 2}, {Timestamp: 3600, Value: 3}}}
+	report, err = NewReporter(cfg, clusters, fakeMetrics{metrics: metrics}, log).Report(context.Background(), cluster)
+	require.NoError(t, err)
+	require.NotNil(t, report.CPU.Forecast)
+	assert.Equal(t, time.Unix(6*3600, 0).UTC(), *report.CPU.Forecast.FullAt)
+	assert.Nil(t, report.Pods.Forecast)
+}
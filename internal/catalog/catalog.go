@@ -0,0 +1,150 @@
+// Package catalog presents the platform's services as a lightweight internal
+// developer portal: each service carries ownership, tier, lifecycle and
+// documentation metadata, and is graded by a scorecard of operational checks.
+package catalog
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Entry is a service as listed in the catalog
+type Entry struct {
+	ServiceID   uuid.UUID             `json:"service_id"`
+	ProjectID   uuid.UUID             `json:"project_id"`
+	ProjectName string                `json:"project_name"`
+	Name        string                `json:"name"`
+	Slug        string                `json:"slug"`
+	Type        domain.ServiceType    `json:"type"`
+	Status      domain.ServiceStatus  `json:"status"`
+	Catalog     domain.ServiceCatalog `json:"catalog"`
+	Scorecard   Scorecard             `json:"scorecard"`
+}
+
+// Filter narrows the catalog listing
+type Filter struct {
+	ProjectID *uuid.UUID
+	Tier      domain.ServiceTier
+	Lifecycle domain.ServiceLifecycle
+	Owner     string
+}
+
+// Catalog lists services with their catalog metadata and scorecards
+type Catalog struct {
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	alertRepo   domain.AlertRepository
+	logger      *logger.Logger
+}
+
+// NewCatalog creates a new Catalog. alertRepo may be nil, in which case
+// scorecards leave out the alerts check.
+func NewCatalog(projectRepo domain.ProjectRepository, serviceRepo domain.ServiceRepository, alertRepo domain.AlertRepository, log *logger.Logger) *Catalog {
+	return &Catalog{
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		alertRepo:   alertRepo,
+		logger:      log,
+	}
+}
+
+// List returns the catalog entries matching the filter
+func (c *Catalog) List(ctx context.Context, filter Filter) ([]*Entry, error) {
+	var projects []*domain.Project
+	if filter.ProjectID != nil {
+		project, err := c.projectRepo.GetByID(ctx, *filter.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		projects = []*domain.Project{project}
+	} else {
+		var err error
+		if projects, err = c.projectRepo.List(ctx, domain.ProjectFilter{}); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := []*Entry{}
+	for _, project := range projects {
+		services, err := c.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, service := range services {
+			if !matches(service.Catalog, filter) {
+				continue
+			}
+			entries = append(entries, &Entry{
+				ServiceID:   service.ID,
+				ProjectID:   project.ID,
+				ProjectName: project.Name,
+				Name:        service.Name,
+				Slug:        service.Slug,
+				Type:        service.Type,
+				Status:      service.Status,
+				Catalog:     service.Catalog,
+				Scorecard:   Score(service, c.signals(ctx, service)),
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+// signals gathers the scorecard inputs that live outside the service definition
+func (c *Catalog) signals(ctx context.Context, service *domain.Service) Signals {
+	if c.alertRepo == nil {
+		return Signals{}
+	}
+
+	alerts, err := c.alertRepo.List(ctx, domain.AlertFilter{ServiceID: &service.ID, Limit: 1})
+	if err != nil {
+		c.logger.Warn().Err(err).Str("service_id", service.ID.String()).Msg("Failed to list alerts for scorecard")
+		return Signals{}
+	}
+
+	return Signals{AlertsKnown: true, HasAlerts: len(alerts) > 0}
+}
+
+func matches(catalog domain.ServiceCatalog, filter Filter) bool {
+	return (filter.Tier == "" || catalog.Tier == filter.Tier) &&
+		(filter.Lifecycle == "" || catalog.Lifecycle == filter.Lifecycle) &&
+		(filter.Owner == "" || catalog.Owner == filter.Owner)
+}
+
+// Validate checks the catalog metadata of a service
+func Validate(catalog domain.ServiceCatalog) error {
+	switch catalog.Tier {
+	case "", domain.ServiceTier1, domain.ServiceTier2, domain.ServiceTier3:
+	default:
+		return errors.BadRequest("catalog tier must be one of tier-1, tier-2, tier-3")
+	}
+
+	switch catalog.Lifecycle {
+	case "", domain.ServiceLifecycleExperimental, domain.ServiceLifecycleProduction, domain.ServiceLifecycleDeprecated:
+	default:
+		return errors.BadRequest("catalog lifecycle must be one of experimental, production, deprecated")
+	}
+
+	if catalog.DocsURL != "" && !isWebURL(catalog.DocsURL) {
+		return errors.BadRequest("catalog docs_url must be an http(s) URL")
+	}
+	for _, link := range catalog.SLOLinks {
+		if !isWebURL(link) {
+			return errors.BadRequest("catalog slo_links must be http(s) URLs")
+		}
+	}
+
+	return nil
+}
+
+func isWebURL(v string) bool {
+	u, err := url.Parse(v)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
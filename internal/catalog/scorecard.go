@@ -0,0 +1,97 @@
+package catalog
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/northstack/platform/internal/domain"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Scorecard check names
+const (
+	CheckHealthCheck    = "health_check"
+	CheckOwner          = "owner"
+	CheckAlerts         = "alerts"
+	CheckResourcePolicy = "resource_policy"
+)
+
+// Check is the outcome of one scorecard check
+type Check struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"` // Why the check failed
+}
+
+// Scorecard grades a service against the platform's operational standards
+type Scorecard struct {
+	Score  int     `json:"score"` // Percentage of checks passed
+	Passed int     `json:"passed"`
+	Total  int     `json:"total"`
+	Checks []Check `json:"checks"`
+}
+
+// Signals are the facts about a service that are not part of its definition
+type Signals struct {
+	// AlertsKnown is false when alerting is not configured, in which case the
+	// alerts check is left out of the scorecard
+	AlertsKnown bool
+	HasAlerts   bool
+}
+
+// Score runs the scorecard checks against a service
+func Score(service *domain.Service, signals Signals) Scorecard {
+	checks := []Check{
+		check(CheckHealthCheck, service.HealthCheck != nil, "no health check is configured"),
+		check(CheckOwner, service.Catalog.Owner != "", "no owner is set in the catalog"),
+	}
+	if signals.AlertsKnown {
+		checks = append(checks, check(CheckAlerts, signals.HasAlerts, "no alerts have been received for the service"))
+	}
+	if err := resourcePolicy(service.Resources); err != nil {
+		checks = append(checks, Check{Name: CheckResourcePolicy, Message: err.Error()})
+	} else {
+		checks = append(checks, Check{Name: CheckResourcePolicy, Passed: true})
+	}
+
+	card := Scorecard{Total: len(checks), Checks: checks}
+	for _, c := range checks {
+		if c.Passed {
+			card.Passed++
+		}
+	}
+	card.Score = int(math.Round(float64(card.Passed) * 100 / float64(card.Total)))
+	return card
+}
+
+func check(name string, passed bool, message string) Check {
+	if passed {
+		return Check{Name: name, Passed: true}
+	}
+	return Check{Name: name, Message: message}
+}
+
+// resourcePolicy requires CPU and memory requests and limits, with each
+// request no larger than its limit
+func resourcePolicy(r domain.ResourceLimits) error {
+	for _, pair := range []struct{ name, request, limit string }{
+		{"cpu", r.CPURequest, r.CPULimit},
+		{"memory", r.MemoryRequest, r.MemoryLimit},
+	} {
+		if pair.request == "" || pair.limit == "" {
+			return fmt.Errorf("%s request and limit must both be set", pair.name)
+		}
+		request, err := resource.ParseQuantity(pair.request)
+		if err != nil {
+			return fmt.Errorf("invalid %s request %q", pair.name, pair.request)
+		}
+		limit, err := resource.ParseQuantity(pair.limit)
+		if err != nil {
+			return fmt.Errorf("invalid %s limit %q", pair.name, pair.limit)
+		}
+		if request.Cmp(limit) > 0 {
+			return fmt.Errorf("%s request %s exceeds its limit %s", pair.name, pair.request, pair.limit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	service := &domain.Service{
+		HealthCheck: &domain.HealthCheck{Type: "http", Path: "/healthz"},
+		Catalog:     domain.ServiceCatalog{Owner: "payments-team"},
+		Resources: domain.ResourceLimits{
+			CPURequest:    "100m",
+			CPULimit:      "1",
+			MemoryRequest: "128Mi",
+			MemoryLimit:   "512Mi",
+		},
+	}
+
+	t.Run("all checks pass", func(t *testing.T) {
+		card := Score(service, Signals{AlertsKnown: true, HasAlerts: true})
+		assert.Equal(t, 100, card.Score)
+		assert.Equal(t, 4, card.Total)
+	})
+
+	t.Run("alerts check is left out without alerting", func(t *testing.T) {
+		card := Score(service, Signals{})
+		assert.Equal(t, 3, card.Total)
+		for _, c := range card.Checks {
+			assert.NotEqual(t, CheckAlerts, c.Name)
+		}
+	})
+
+	t.Run("failures explain themselves", func(t *testing.T) {
+		bare := &domain.Service{Resources: domain.ResourceLimits{
+			CPURequest:    "2",
+			CPULimit:      "500m",
+			MemoryRequest: "128Mi",
+			MemoryLimit:   "512Mi",
+		}}
+		card := Score(bare, Signals{AlertsKnown: true})
+		assert.Equal(t, 0, card.Score)
+		assert.Equal(t, "cpu request 2 exceeds its limit 500m", card.Checks[3].Message)
+	})
+
+	t.Run("missing limits fail the resource policy", func(t *testing.T) {
+		assert.Error(t, resourcePolicy(domain.ResourceLimits{CPURequest: "100m", CPULimit: "1", MemoryRequest: "128Mi"}))
+	})
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(domain.ServiceCatalog{
+		Tier:      domain.ServiceTier1,
+		Lifecycle: domain.ServiceLifecycleProduction,
+		DocsURL:   "https://docs.example.com/payments",
+		SLOLinks:  []string{"https://grafana.example.com/d/slo"},
+	}))
+	assert.Error(t, Validate(domain.ServiceCatalog{Tier: "gold"}))
+	assert.Error(t, Validate(domain.ServiceCatalog{Lifecycle: "beta"}))
+	assert.Error(t, Validate(domain.ServiceCatalog{DocsURL: "docs/payments"}))
+}
@@ -0,0 +1,226 @@
+// Package clusterhealth keeps the stored status of managed clusters in line
+// with the health their cluster manager reports. Each status change is
+// persisted and published as cluster.updated, and a cluster that turns
+// unhealthy raises an alert that resolves once it recovers.
+package clusterhealth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/alerting"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// RuleClusterUnhealthy is the name of the alert raised for unhealthy clusters
+	RuleClusterUnhealthy = "ClusterUnhealthy"
+	// maxConcurrentChecks bounds the health checks in flight at once
+	maxConcurrentChecks = 8
+)
+
+// Reconciler periodically checks the health of every managed cluster
+type Reconciler struct {
+	config      *config.ClusterHealthConfig
+	clusters    domain.ClusterManagerAdapter
+	clusterRepo domain.ClusterRepository
+	eventBus    domain.EventBus
+	alerts      *alerting.Manager
+	logger      *logger.Logger
+
+	mu       sync.Mutex
+	failures map[uuid.UUID]int
+}
+
+// NewReconciler creates a new Reconciler. alerts may be nil to only track
+// statuses.
+func NewReconciler(
+	cfg *config.ClusterHealthConfig,
+	clusters domain.ClusterManagerAdapter,
+	clusterRepo domain.ClusterRepository,
+	eventBus domain.EventBus,
+	alerts *alerting.Manager,
+	log *logger.Logger,
+) *Reconciler {
+	return &Reconciler{
+		config:      cfg,
+		clusters:    clusters,
+		clusterRepo: clusterRepo,
+		eventBus:    eventBus,
+		alerts:      alerts,
+		logger:      log,
+		failures:    make(map[uuid.UUID]int),
+	}
+}
+
+// Run reconciles all clusters every Interval until ctx is cancelled
+func (r *Reconciler) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.ReconcileAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReconcileAll checks every cluster with an external ID once
+func (r *Reconciler) ReconcileAll(ctx context.Context) {
+	clusters, err := r.clusterRepo.List(ctx, domain.ClusterFilter{})
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Failed to list clusters for health reconciliation")
+		return
+	}
+
+	sem := make(chan struct{}, maxConcurrentChecks)
+	var wg sync.WaitGroup
+	for _, cluster := range clusters {
+		if cluster.RancherClusterID == "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(cluster *domain.Cluster) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.reconcile(ctx, cluster)
+		}(cluster)
+	}
+	wg.Wait()
+}
+
+// reconcile derives the status of one cluster and stores it when it changed.
+// Upgrades and deletions own the status of their cluster until they finish,
+// and clusters still provisioning only become active once their provider
+// reports them so.
+func (r *Reconciler) reconcile(ctx context.Context, cluster *domain.Cluster) {
+	var (
+		status  domain.ClusterStatus
+		message string
+	)
+	switch cluster.Status {
+	case domain.ClusterStatusUpgrading, domain.ClusterStatusDeleting:
+		return
+	case domain.ClusterStatusProvisioning:
+		current, err := r.clusters.GetCluster(ctx, cluster.RancherClusterID)
+		if err != nil || current.Status != domain.ClusterStatusActive {
+			return
+		}
+		status = domain.ClusterStatusActive
+	default:
+		status, message = r.health(ctx, cluster)
+		if status == "" {
+			return
+		}
+	}
+
+	if status == cluster.Status {
+		return
+	}
+	r.transition(ctx, cluster, status, message)
+}
+
+// health returns the status a cluster's health check reports, and why it is
+// unhealthy. Failed checks only make a cluster unhealthy once they reach
+// the failure threshold; until then the status is empty and stays as is.
+func (r *Reconciler) health(ctx context.Context, cluster *domain.Cluster) (domain.ClusterStatus, string) {
+	health, err := r.clusters.GetClusterHealth(ctx, cluster.RancherClusterID)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.failures[cluster.ID]++
+		if r.failures[cluster.ID] < r.config.FailureThreshold {
+			return "", ""
+		}
+		return domain.ClusterStatusUnhealthy, fmt.Sprintf("health check failed %d consecutive times: %v", r.failures[cluster.ID], err)
+	}
+	delete(r.failures, cluster.ID)
+
+	if health.Status != domain.ClusterStatusUnhealthy {
+		return domain.ClusterStatusActive, ""
+	}
+	reasons := make([]string, 0, len(health.Conditions))
+	for _, c := range health.Conditions {
+		if c.Message != "" {
+			reasons = append(reasons, c.Message)
+		}
+	}
+	message := fmt.Sprintf("%d of %d nodes ready", health.ReadyNodes, health.NodeCount)
+	if len(reasons) > 0 {
+		message += ": " + strings.Join(reasons, "; ")
+	}
+	return domain.ClusterStatusUnhealthy, message
+}
+
+// transition stores a cluster's new status, publishes cluster.updated and
+// fires or resolves its alert. The cluster is re-read first so a status an
+// upgrade or deletion set meanwhile is not overwritten.
+func (r *Reconciler) transition(ctx context.Context, listed *domain.Cluster, status domain.ClusterStatus, message string) {
+	cluster, err := r.clusterRepo.GetByID(ctx, listed.ID)
+	if err != nil || cluster.Status != listed.Status {
+		return
+	}
+	previous := cluster.Status
+	cluster.Status = status
+	cluster.UpdatedAt = time.Now().UTC()
+	if err := r.clusterRepo.Update(ctx, cluster); err != nil {
+		r.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to store cluster status")
+		return
+	}
+
+	r.logger.Info().
+		Str("cluster_id", cluster.ID.String()).
+		Str("from", string(previous)).
+		Str("to", string(status)).
+		Msg("Cluster status changed")
+
+	event := &domain.Event{
+		ID:   uuid.New().String(),
+		Type: "cluster.updated",
+		Data: map[string]interface{}{
+			"cluster_id":      cluster.ID.String(),
+			"name":            cluster.Name,
+			"status":          string(status),
+			"previous_status": string(previous),
+		},
+		Timestamp: time.Now().Unix(),
+	}
+	if err := r.eventBus.Publish(ctx, event.Type, event); err != nil {
+		r.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to publish cluster status change")
+	}
+
+	if r.alerts == nil {
+		return
+	}
+	fingerprint := RuleClusterUnhealthy + "/" + cluster.ID.String()
+	switch status {
+	case domain.ClusterStatusUnhealthy:
+		_, err := r.alerts.Fire(ctx, &domain.Alert{
+			Fingerprint: fingerprint,
+			Name:        RuleClusterUnhealthy,
+			Severity:    "critical",
+			Source:      alerting.SourcePlatform,
+			Message:     fmt.Sprintf("Cluster %s is unhealthy: %s", cluster.Name, message),
+			Labels:      map[string]string{"cluster": cluster.Slug, "region": cluster.Region},
+		})
+		if err != nil {
+			r.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to fire cluster health alert")
+		}
+	case domain.ClusterStatusActive:
+		if _, err := r.alerts.Resolve(ctx, fingerprint, time.Now()); err != nil {
+			r.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to resolve cluster health alert")
+		}
+	}
+}
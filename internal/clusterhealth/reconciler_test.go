@@ -0,0 +1,149 @@
+package clusterhealth
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/alerting"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClusterRepo struct {
+	domain.ClusterRepository
+	mu       sync.Mutex
+	clusters map[uuid.UUID]*domain.Cluster
+}
+
+func (r *fakeClusterRepo) List(context.Context, domain.ClusterFilter) ([]*domain.Cluster, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var clusters []*domain.Cluster
+	for _, c := range r.clusters {
+		copied := *c
+		clusters = append(clusters, &copied)
+	}
+	return clusters, nil
+}
+
+func (r *fakeClusterRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Cluster, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *r.clusters[id]
+	return &c, nil
+}
+
+func (r *fakeClusterRepo) Update(_ context.Context, cluster *domain.Cluster) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clusters[cluster.ID] = cluster
+	return nil
+}
+
+// fakeManager reports the health set per external ID; a missing entry fails
+type fakeManager struct {
+	domain.ClusterManagerAdapter
+	mu     sync.Mutex
+	health map[string]*domain.ClusterHealth
+}
+
+func (m *fakeManager) GetClusterHealth(_ context.Context, externalID string) (*domain.ClusterHealth, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	health, ok := m.health[externalID]
+	if !ok {
+		return nil, errors.DependencyFailed("rancher", io.ErrUnexpectedEOF)
+	}
+	return health, nil
+}
+
+func (m *fakeManager) GetCluster(context.Context, string) (*domain.Cluster, error) {
+	return &domain.Cluster{Status: domain.ClusterStatusActive}, nil
+}
+
+type fakeBus struct {
+	domain.EventBus
+	mu     sync.Mutex
+	events []*domain.Event
+}
+
+func (b *fakeBus) Publish(_ context.Context, _ string, event *domain.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+	return nil
+}
+
+type fakeAlertRepo struct {
+	domain.AlertRepository
+	alerts []*domain.Alert
+}
+
+func (r *fakeAlertRepo) Create(_ context.Context, alert *domain.Alert) error {
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *fakeAlertRepo) Update(context.Context, *domain.Alert) error { return nil }
+
+func (r *fakeAlertRepo) GetFiringByFingerprint(_ context.Context, fingerprint string) (*domain.Alert, error) {
+	for _, a := range r.alerts {
+		if a.Fingerprint == fingerprint && a.Status == domain.AlertStatusFiring {
+			return a, nil
+		}
+	}
+	return nil, errors.NotFound("alert", fingerprint)
+}
+
+func TestReconcile(t *testing.T) {
+	log := logger.New("error", "json", io.Discard)
+	ctx := context.Background()
+	prod := &domain.Cluster{ID: uuid.New(), Name: "prod", RancherClusterID: "c-prod", Status: domain.ClusterStatusActive}
+	edge := &domain.Cluster{ID: uuid.New(), Name: "edge", RancherClusterID: "c-edge", Status: domain.ClusterStatusProvisioning}
+	upgrading := &domain.Cluster{ID: uuid.New(), Name: "batch", RancherClusterID: "c-batch", Status: domain.ClusterStatusUpgrading}
+	repo := &fakeClusterRepo{clusters: map[uuid.UUID]*domain.Cluster{prod.ID: prod, edge.ID: edge, upgrading.ID: upgrading}}
+	manager := &fakeManager{health: map[string]*domain.ClusterHealth{
+		"c-prod": {Status: domain.ClusterStatusUnhealthy, NodeCount: 3, ReadyNodes: 2},
+	}}
+	bus := &fakeBus{}
+	alertRepo := &fakeAlertRepo{}
+	r := NewReconciler(&config.ClusterHealthConfig{FailureThreshold: 2}, manager, repo, bus, alerting.NewManager(alertRepo, bus, nil, log), log)
+
+	r.ReconcileAll(ctx)
+	stored, _ := repo.GetByID(ctx, prod.ID)
+	assert.Equal(t, domain.ClusterStatusUnhealthy, stored.Status)
+	stored, _ = repo.GetByID(ctx, edge.ID)
+	assert.Equal(t, domain.ClusterStatusActive, stored.Status, "provisioning finished")
+	stored, _ = repo.GetByID(ctx, upgrading.ID)
+	assert.Equal(t, domain.ClusterStatusUpgrading, stored.Status, "upgrades own their status")
+	require.Len(t, alertRepo.alerts, 1)
+	assert.Contains(t, alertRepo.alerts[0].Message, "2 of 3 nodes ready")
+
+	manager.health["c-prod"] = &domain.ClusterHealth{Status: domain.ClusterStatusActive, NodeCount: 3, ReadyNodes: 3}
+	r.ReconcileAll(ctx)
+	stored, _ = repo.GetByID(ctx, prod.ID)
+	assert.Equal(t, domain.ClusterStatusActive, stored.Status)
+	assert.Equal(t, domain.AlertStatusResolved, alertRepo.alerts[0].Status)
+
+	// The edge cluster's checks fail: unhealthy at the threshold only
+	stored, _ = repo.GetByID(ctx, edge.ID)
+	assert.Equal(t, domain.ClusterStatusActive, stored.Status)
+	r.ReconcileAll(ctx)
+	stored, _ = repo.GetByID(ctx, edge.ID)
+	assert.Equal(t, domain.ClusterStatusUnhealthy, stored.Status)
+
+	var updates int
+	for _, e := range bus.events {
+		if e.Type == "cluster.updated" {
+			updates++
+		}
+	}
+	assert.Equal(t, 4, updates)
+}
@@ -0,0 +1,500 @@
+// Package clusterupgrade orchestrates Kubernetes version upgrades of managed
+// clusters: the control plane goes first, then each node pool in turn, with
+// optional surge nodes keeping the pool's capacity while its nodes are
+// replaced. The cluster is upgrading until every step finished, and service
+// deploys to it are refused meanwhile.
+package clusterupgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// MetadataProgress is the cluster metadata key holding the upgrade progress
+const MetadataProgress = "upgrade"
+
+// stepTimeout bounds how long the provider gets to finish a step
+const stepTimeout = time.Hour
+
+// pollInterval is the wait between checks of a step's completion
+var pollInterval = 30 * time.Second
+
+// Step targets
+const (
+	TargetControlPlane = "control_plane"
+	TargetNodePool     = "node_pool"
+)
+
+// StepStatus is how far a step of an upgrade got
+type StepStatus string
+
+const (
+	StepPending StepStatus = "pending"
+	StepRunning StepStatus = "running"
+	StepDone    StepStatus = "done"
+	StepFailed  StepStatus = "failed"
+)
+
+// Step is the upgrade of the control plane or of one node pool
+type Step struct {
+	Target      string     `json:"target"`
+	Pool        string     `json:"pool,omitempty"`
+	Status      StepStatus `json:"status"`
+	Message     string     `json:"message,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Progress is the state of a cluster's latest upgrade
+type Progress struct {
+	FromVersion string     `json:"from_version"`
+	ToVersion   string     `json:"to_version"`
+	MaxSurge    int32      `json:"max_surge"` // Extra nodes each pool gets while it is upgraded
+	Steps       []Step     `json:"steps"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// GetProgress returns the progress of a cluster's latest upgrade, or nil
+func GetProgress(cluster *domain.Cluster) *Progress {
+	raw, ok := cluster.Metadata[MetadataProgress]
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var progress Progress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil
+	}
+	return &progress
+}
+
+func setProgress(cluster *domain.Cluster, progress *Progress) {
+	if cluster.Metadata == nil {
+		cluster.Metadata = make(map[string]interface{})
+	}
+	cluster.Metadata[MetadataProgress] = progress
+}
+
+// Upgrader upgrades managed clusters through the cluster manager
+type Upgrader struct {
+	clusters    domain.ClusterManagerAdapter
+	clusterRepo domain.ClusterRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+
+	mu sync.Mutex // Serializes upgrade starts and progress updates
+}
+
+// NewUpgrader creates a new Upgrader. Without an event bus no events are
+// published. A nil Upgrader allows every deploy.
+func NewUpgrader(clusters domain.ClusterManagerAdapter, clusterRepo domain.ClusterRepository, eventBus domain.EventBus, log *logger.Logger) *Upgrader {
+	return &Upgrader{
+		clusters:    clusters,
+		clusterRepo: clusterRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Versions lists the Kubernetes versions a cluster can be upgraded to
+func (u *Upgrader) Versions(ctx context.Context, cluster *domain.Cluster) ([]string, error) {
+	if cluster.RancherClusterID == "" {
+		return nil, errors.BadRequest("cluster is not managed by the platform")
+	}
+	return u.clusters.ListKubernetesVersions(ctx, cluster.RancherClusterID)
+}
+
+// Start upgrades a cluster to version in the background. Upgrading to the
+// version the control plane already runs resumes a failed upgrade with the
+// node pools still behind.
+func (u *Upgrader) Start(ctx context.Context, clusterID uuid.UUID, version string, maxSurge int32) (*Progress, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	cluster, err := u.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	switch cluster.Status {
+	case domain.ClusterStatusActive, domain.ClusterStatusUnhealthy:
+	default:
+		return nil, errors.NewError(errors.CodeConflict, fmt.Sprintf("cluster is %s and cannot be upgraded", cluster.Status), http.StatusConflict)
+	}
+
+	if version != cluster.KubeVersion {
+		versions, err := u.Versions(ctx, cluster)
+		if err != nil {
+			return nil, err
+		}
+		if !contains(versions, version) {
+			return nil, errors.BadRequest(fmt.Sprintf("cluster cannot be upgraded from %s to %s; available versions are %v", cluster.KubeVersion, version, versions))
+		}
+	}
+	pools, err := u.clusters.ListNodePools(ctx, cluster.RancherClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	progress := &Progress{FromVersion: cluster.KubeVersion, ToVersion: version, MaxSurge: maxSurge, StartedAt: now}
+	controlPlane := Step{Target: TargetControlPlane, Status: StepPending}
+	if version == cluster.KubeVersion {
+		controlPlane.Status = StepDone
+	}
+	progress.Steps = append(progress.Steps, controlPlane)
+	pending := controlPlane.Status == StepPending
+	for _, pool := range pools {
+		step := Step{Target: TargetNodePool, Pool: pool.Name, Status: StepPending}
+		if pool.Version == version {
+			step.Status = StepDone
+		}
+		pending = pending || step.Status == StepPending
+		progress.Steps = append(progress.Steps, step)
+	}
+	if !pending {
+		return nil, errors.BadRequest("cluster already runs Kubernetes " + version)
+	}
+
+	cluster.Status = domain.ClusterStatusUpgrading
+	cluster.UpdatedAt = now
+	setProgress(cluster, progress)
+	if err := u.clusterRepo.Update(ctx, cluster); err != nil {
+		return nil, err
+	}
+
+	u.logger.Info().
+		Str("cluster_id", cluster.ID.String()).
+		Str("from", progress.FromVersion).
+		Str("to", version).
+		Int("pools", len(pools)).
+		Msg("Cluster upgrade started")
+	u.publish(ctx, "cluster.upgrade_started", cluster, progress)
+
+	go u.run(context.Background(), cluster.ID, cluster.RancherClusterID, progress)
+	return progress, nil
+}
+
+// run performs the pending steps of an upgrade in order, stopping at the
+// first failure
+func (u *Upgrader) run(ctx context.Context, clusterID uuid.UUID, externalID string, progress *Progress) {
+	for i, step := range progress.Steps {
+		if step.Status != StepPending {
+			continue
+		}
+		u.setStep(ctx, clusterID, i, StepRunning, "")
+
+		var err error
+		if step.Target == TargetControlPlane {
+			err = u.upgradeControlPlane(ctx, clusterID, externalID, progress.ToVersion)
+		} else {
+			err = u.upgradePool(ctx, externalID, step.Pool, progress.ToVersion, progress.MaxSurge)
+		}
+		if err != nil {
+			u.logger.Error().Err(err).
+				Str("cluster_id", clusterID.String()).
+				Str("target", step.Target).
+				Str("pool", step.Pool).
+				Msg("Cluster upgrade step failed")
+			u.setStep(ctx, clusterID, i, StepFailed, err.Error())
+			u.finish(ctx, clusterID, err.Error())
+			return
+		}
+		u.setStep(ctx, clusterID, i, StepDone, "")
+	}
+	u.finish(ctx, clusterID, "")
+}
+
+// upgradeControlPlane upgrades the control plane and records the cluster's
+// new version once it is active again
+func (u *Upgrader) upgradeControlPlane(ctx context.Context, clusterID uuid.UUID, externalID, version string) error {
+	cluster, err := u.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+	target := *cluster
+	target.KubeVersion = version
+	target.NodeCount = 0 // Leave the node pools as they are
+	if err := u.clusters.UpdateCluster(ctx, &target); err != nil {
+		return err
+	}
+	if err := u.wait(ctx, externalID, nil); err != nil {
+		return err
+	}
+
+	_, err = u.update(ctx, clusterID, func(c *domain.Cluster, _ *Progress) {
+		c.KubeVersion = version
+	})
+	return err
+}
+
+// upgradePool upgrades a node pool's nodes. With a surge, the pool grows by
+// that many nodes first and shrinks back, draining, once it is upgraded.
+func (u *Upgrader) upgradePool(ctx context.Context, externalID, name, version string, maxSurge int32) error {
+	pool, err := u.pool(ctx, externalID, name)
+	if err != nil {
+		return err
+	}
+	original := *pool
+
+	if maxSurge > 0 {
+		surged := original
+		surged.NodeCount += maxSurge
+		if surged.MaxCount < surged.NodeCount {
+			surged.MaxCount = surged.NodeCount
+		}
+		if err := u.clusters.UpdateNodePool(ctx, externalID, &surged); err != nil {
+			return err
+		}
+		if err := u.wait(ctx, externalID, nil); err != nil {
+			return err
+		}
+		pool = &surged
+	}
+
+	upgraded := *pool
+	upgraded.Version = version
+	if err := u.clusters.UpdateNodePool(ctx, externalID, &upgraded); err != nil {
+		return err
+	}
+	err = u.wait(ctx, externalID, func() (bool, error) {
+		current, err := u.pool(ctx, externalID, name)
+		if err != nil {
+			return false, err
+		}
+		return current.Version == version, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if maxSurge > 0 {
+		restored := original
+		restored.Version = version
+		if err := u.clusters.UpdateNodePool(ctx, externalID, &restored); err != nil {
+			return err
+		}
+		return u.wait(ctx, externalID, nil)
+	}
+	return nil
+}
+
+// wait polls until the provider reports the cluster active again and done,
+// when set, holds, or the step times out
+func (u *Upgrader) wait(ctx context.Context, externalID string, done func() (bool, error)) error {
+	deadline := time.Now().Add(stepTimeout)
+	var lastErr error
+	for {
+		// The provider takes a moment to report the change in progress
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+
+		cluster, err := u.clusters.GetCluster(ctx, externalID)
+		if err == nil && cluster.Status == domain.ClusterStatusActive {
+			finished := true
+			if done != nil {
+				finished, err = done()
+			}
+			if err == nil && finished {
+				return nil
+			}
+		}
+		if err != nil {
+			lastErr = err
+		}
+
+		if time.Now().After(deadline) {
+			if lastErr != nil {
+				return fmt.Errorf("timed out after %s: %v", stepTimeout, lastErr)
+			}
+			return fmt.Errorf("timed out after %s", stepTimeout)
+		}
+	}
+}
+
+func (u *Upgrader) pool(ctx context.Context, externalID, name string) (*domain.NodePool, error) {
+	pools, err := u.clusters.ListNodePools(ctx, externalID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range pools {
+		if pool.Name == name {
+			return pool, nil
+		}
+	}
+	return nil, errors.NotFound("node pool", name)
+}
+
+// Recover marks upgrades interrupted by a restart failed, leaving their
+// clusters unhealthy; starting the upgrade again resumes it
+func (u *Upgrader) Recover(ctx context.Context) error {
+	status := domain.ClusterStatusUpgrading
+	clusters, err := u.clusterRepo.List(ctx, domain.ClusterFilter{Status: &status})
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		if GetProgress(cluster) == nil {
+			continue
+		}
+		_, err := u.update(ctx, cluster.ID, func(_ *domain.Cluster, progress *Progress) {
+			now := time.Now()
+			for i := range progress.Steps {
+				if progress.Steps[i].Status == StepRunning {
+					progress.Steps[i].Status = StepFailed
+					progress.Steps[i].Message = "interrupted by an orchestrator restart"
+					progress.Steps[i].CompletedAt = &now
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		u.logger.Warn().Str("cluster_id", cluster.ID.String()).Msg("Interrupted cluster upgrade marked failed")
+		u.finish(ctx, cluster.ID, "the upgrade was interrupted")
+	}
+	return nil
+}
+
+// CheckDeploy refuses deploys to a cluster that is being upgraded
+func (u *Upgrader) CheckDeploy(ctx context.Context, clusterID uuid.UUID) error {
+	if u == nil {
+		return nil
+	}
+	cluster, err := u.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if cluster.Status != domain.ClusterStatusUpgrading {
+		return nil
+	}
+
+	version := cluster.KubeVersion
+	if progress := GetProgress(cluster); progress != nil {
+		version = progress.ToVersion
+	}
+	return errors.NewError(errors.CodeConflict,
+		fmt.Sprintf("cluster %s is being upgraded to Kubernetes %s; deploys to it resume when the upgrade finishes", cluster.Name, version),
+		http.StatusConflict)
+}
+
+// setStep records the status of one step
+func (u *Upgrader) setStep(ctx context.Context, clusterID uuid.UUID, index int, status StepStatus, message string) {
+	_, err := u.update(ctx, clusterID, func(_ *domain.Cluster, progress *Progress) {
+		if index >= len(progress.Steps) {
+			return
+		}
+		now := time.Now()
+		step := &progress.Steps[index]
+		step.Status = status
+		step.Message = message
+		if status == StepRunning {
+			step.StartedAt = &now
+		} else {
+			step.CompletedAt = &now
+		}
+	})
+	if err != nil {
+		u.logger.Warn().Err(err).Str("cluster_id", clusterID.String()).Msg("Failed to record cluster upgrade step")
+	}
+}
+
+// finish ends an upgrade: the cluster is active again, or unhealthy when a
+// step failed
+func (u *Upgrader) finish(ctx context.Context, clusterID uuid.UUID, problem string) {
+	cluster, err := u.update(ctx, clusterID, func(c *domain.Cluster, progress *Progress) {
+		now := time.Now()
+		progress.CompletedAt = &now
+		progress.Error = problem
+		c.Status = domain.ClusterStatusActive
+		if problem != "" {
+			c.Status = domain.ClusterStatusUnhealthy
+		}
+	})
+	if err != nil {
+		u.logger.Error().Err(err).Str("cluster_id", clusterID.String()).Msg("Failed to record cluster upgrade result")
+		return
+	}
+
+	progress := GetProgress(cluster)
+	eventType := "cluster.upgraded"
+	if problem != "" {
+		eventType = "cluster.upgrade_failed"
+	}
+	u.logger.Info().
+		Str("cluster_id", clusterID.String()).
+		Str("version", progress.ToVersion).
+		Str("status", string(cluster.Status)).
+		Msg("Cluster upgrade finished")
+	u.publish(ctx, eventType, cluster, progress)
+}
+
+// update applies a change to the stored cluster and its upgrade progress
+func (u *Upgrader) update(ctx context.Context, clusterID uuid.UUID, apply func(*domain.Cluster, *Progress)) (*domain.Cluster, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	cluster, err := u.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	progress := GetProgress(cluster)
+	if progress == nil {
+		progress = &Progress{}
+	}
+	apply(cluster, progress)
+	setProgress(cluster, progress)
+	cluster.UpdatedAt = time.Now()
+	if err := u.clusterRepo.Update(ctx, cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+func (u *Upgrader) publish(ctx context.Context, eventType string, cluster *domain.Cluster, progress *Progress) {
+	if u.eventBus == nil {
+		return
+	}
+	err := u.eventBus.Publish(ctx, eventType, &domain.Event{
+		Type:   eventType,
+		Source: "platform-orchestrator",
+		Data: map[string]interface{}{
+			"cluster_id":   cluster.ID.String(),
+			"name":         cluster.Name,
+			"from_version": progress.FromVersion,
+			"to_version":   progress.ToVersion,
+			"error":        progress.Error,
+		},
+	})
+	if err != nil {
+		u.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to publish cluster event")
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package clusterupgrade
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClusterRepo struct {
+	domain.ClusterRepository
+	mu       sync.Mutex
+	clusters map[uuid.UUID]*domain.Cluster
+}
+
+func (r *fakeClusterRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Cluster, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *r.clusters[id]
+	return &c, nil
+}
+
+func (r *fakeClusterRepo) Update(_ context.Context, cluster *domain.Cluster) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clusters[cluster.ID] = cluster
+	return nil
+}
+
+// fakeManager records the calls of an upgrade; pools take new versions at once
+type fakeManager struct {
+	domain.ClusterManagerAdapter
+	mu    sync.Mutex
+	pools map[string]domain.NodePool
+	calls []string
+}
+
+func (m *fakeManager) ListKubernetesVersions(context.Context, string) ([]string, error) {
+	return []string{"1.29"}, nil
+}
+
+func (m *fakeManager) GetCluster(context.Context, string) (*domain.Cluster, error) {
+	return &domain.Cluster{Status: domain.ClusterStatusActive}, nil
+}
+
+func (m *fakeManager) UpdateCluster(_ context.Context, cluster *domain.Cluster) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, "control plane "+cluster.KubeVersion)
+	return nil
+}
+
+func (m *fakeManager) ListNodePools(context.Context, string) ([]*domain.NodePool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var pools []*domain.NodePool
+	for _, name := range []string{"default", "batch"} {
+		p := m.pools[name]
+		pools = append(pools, &p)
+	}
+	return pools, nil
+}
+
+func (m *fakeManager) UpdateNodePool(_ context.Context, _ string, pool *domain.NodePool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pools[pool.Name] = *pool
+	m.calls = append(m.calls, fmt.Sprintf("%s %s x%d", pool.Name, pool.Version, pool.NodeCount))
+	return nil
+}
+
+func TestUpgrade(t *testing.T) {
+	pollInterval = time.Millisecond
+	cluster := &domain.Cluster{ID: uuid.New(), Name: "prod", RancherClusterID: "c-1", KubeVersion: "1.28", Status: domain.ClusterStatusActive}
+	repo := &fakeClusterRepo{clusters: map[uuid.UUID]*domain.Cluster{cluster.ID: cluster}}
+	manager := &fakeManager{pools: map[string]domain.NodePool{
+		"default": {Name: "default", NodeCount: 3, MaxCount: 3, Version: "1.28"},
+		"batch":   {Name: "batch", NodeCount: 2, MaxCount: 4, Version: "1.28"},
+	}}
+	u := NewUpgrader(manager, repo, nil, logger.New("error", "json", io.Discard))
+	ctx := context.Background()
+
+	_, err := u.Start(ctx, cluster.ID, "1.30", 1)
+	assert.Error(t, err, "only one minor version at a time")
+
+	progress, err := u.Start(ctx, cluster.ID, "1.29", 1)
+	require.NoError(t, err)
+	assert.Len(t, progress.Steps, 3)
+	assert.Error(t, u.CheckDeploy(ctx, cluster.ID))
+	_, err = u.Start(ctx, cluster.ID, "1.29", 1)
+	assert.Error(t, err, "already upgrading")
+
+	assert.Eventually(t, func() bool {
+		c, _ := repo.GetByID(ctx, cluster.ID)
+		return c.Status != domain.ClusterStatusUpgrading
+	}, 5*time.Second, time.Millisecond)
+
+	stored, _ := repo.GetByID(ctx, cluster.ID)
+	assert.Equal(t, domain.ClusterStatusActive, stored.Status)
+	assert.Equal(t, "1.29", stored.KubeVersion)
+	assert.NoError(t, u.CheckDeploy(ctx, cluster.ID))
+	for _, step := range GetProgress(stored).Steps {
+		assert.Equal(t, StepDone, step.Status)
+	}
+
+	// Each pool surges by one node, upgrades, then shrinks back
+	assert.Equal(t, []string{
+		"control plane 1.29",
+		"default 1.28 x4", "default 1.29 x4", "default 1.29 x3",
+		"batch 1.28 x3", "batch 1.29 x3", "batch 1.29 x2",
+	}, manager.calls)
+	assert.Equal(t, int32(3), manager.pools["default"].MaxCount)
+
+	_, err = u.Start(ctx, cluster.ID, "1.29", 0)
+	assert.Error(t, err, "nothing left to upgrade")
+}
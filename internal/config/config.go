@@ -4,6 +4,7 @@ package config
 
 import (
 	"fmt"
+	"net/mail"
 	"strings"
 	"time"
 
@@ -18,7 +19,11 @@ type Config struct {
 	DragonflyDB   DragonflyDBConfig   `mapstructure:"dragonflydb"` // High-performance cache (Redis replacement)
 	Redis         RedisConfig         `mapstructure:"redis"`       // Legacy Redis (optional fallback)
 	NATS          NATSConfig          `mapstructure:"nats"`
+	EventBus      EventBusConfig      `mapstructure:"event_bus"`
+	Workers       WorkersConfig       `mapstructure:"workers"`
+	Agents        AgentsConfig        `mapstructure:"agents"`
 	Integrations  IntegrationsConfig  `mapstructure:"integrations"`
+	Notifications NotificationsConfig `mapstructure:"notifications"`
 	Auth          AuthConfig          `mapstructure:"auth"`
 	Observability ObservabilityConfig `mapstructure:"observability"`
 }
@@ -117,9 +122,13 @@ type ServerConfig struct {
 	TLSCertFile     string        `mapstructure:"tls_cert_file"`
 	TLSKeyFile      string        `mapstructure:"tls_key_file"`
 	CORSEnabled     bool          `mapstructure:"cors_enabled"`
+	DocsEnabled     bool          `mapstructure:"docs_enabled"`    // Serve the OpenAPI document and Swagger UI
+	IdempotencyTTL  time.Duration `mapstructure:"idempotency_ttl"` // How long responses to Idempotency-Key requests are replayed; 0 disables
 }
 
 type DatabaseConfig struct {
+	Driver          string        `mapstructure:"driver"` // postgres or sqlite
+	Path            string        `mapstructure:"path"`   // SQLite database file
 	Host            string        `mapstructure:"host"`
 	Port            int           `mapstructure:"port"`
 	Database        string        `mapstructure:"database"`
@@ -160,7 +169,8 @@ type NATSConfig struct {
 	URL              string        `mapstructure:"url"`
 	ClusterID        string        `mapstructure:"cluster_id"`
 	ClientID         string        `mapstructure:"client_id"`
-	Token            string        `mapstructure:"token"` // Added for compatibility
+	Token            string        `mapstructure:"token"`            // Added for compatibility
+	CredentialsFile  string        `mapstructure:"credentials_file"` // .creds file of a user JWT; used instead of token or password
 	Username         string        `mapstructure:"username"`
 	Password         string        `mapstructure:"password"`
 	MaxReconnects    int           `mapstructure:"max_reconnects"`
@@ -173,6 +183,72 @@ type NATSConfig struct {
 
 	// Stream configuration
 	Streams []StreamConfig `mapstructure:"streams"`
+
+	// Durable consumers of workflow-critical subjects
+	Consumers ConsumerConfig `mapstructure:"consumers"`
+
+	// Publish events as CloudEvents 1.0; consumers read both formats
+	CloudEvents bool `mapstructure:"cloudevents"`
+
+	// Check event payloads against their subject's schema: off, warn or enforce
+	SchemaValidation string `mapstructure:"schema_validation"`
+
+	// Per-tenant copies of events and scoped credentials to subscribe to them
+	Tenants NATSTenantsConfig `mapstructure:"tenants"`
+}
+
+// NATSTenantsConfig lets teams subscribe to the events of their own projects.
+// NATS must run in operator (JWT) mode for the issued credentials to work.
+type NATSTenantsConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Prefix         string        `mapstructure:"prefix"`          // First token of tenant subjects
+	AccountSeed    string        `mapstructure:"account_seed"`    // Seed of the account, or of one of its signing keys, that issues tenant users
+	Account        string        `mapstructure:"account"`         // Public key of the account when AccountSeed is a signing key
+	URL            string        `mapstructure:"url"`             // NATS URL handed to tenants
+	CredentialsTTL time.Duration `mapstructure:"credentials_ttl"` // Lifetime of issued credentials
+}
+
+// ConsumerConfig configures the durable JetStream consumers that deliver
+// workflow events at least once
+type ConsumerConfig struct {
+	MaxDeliver int             `mapstructure:"max_deliver"` // Attempts before an event is dead-lettered
+	AckWait    time.Duration   `mapstructure:"ack_wait"`    // Redeliver when a handler runs longer than this
+	Backoff    []time.Duration `mapstructure:"backoff"`     // Delay before each retry; the last repeats
+	BatchSize  int             `mapstructure:"batch_size"`  // Events fetched per pull
+}
+
+// EventBusConfig selects the event bus backend
+type EventBusConfig struct {
+	Driver  string             `mapstructure:"driver"` // nats, or dragonfly for DragonflyDB streams
+	Streams RedisStreamsConfig `mapstructure:"streams"`
+}
+
+// WorkersConfig moves cluster and build operations to adapter worker
+// processes, which API replicas call over NATS
+type WorkersConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Queue   string        `mapstructure:"queue"`   // Queue group the workers share
+	Timeout time.Duration `mapstructure:"timeout"` // How long a call waits for its reply
+}
+
+// AgentsConfig sends Kubernetes operations to the agents running in
+// workload clusters, which connect out to NATS. With an account seed, admins
+// get NATS credentials for each agent limited to its own cluster's subjects;
+// NATS must run in operator (JWT) mode for them to work.
+type AgentsConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Timeout        time.Duration `mapstructure:"timeout"`         // How long a call waits for the agent's reply
+	AccountSeed    string        `mapstructure:"account_seed"`    // Seed of the orchestrator's account, or of one of its signing keys, that issues agent users
+	Account        string        `mapstructure:"account"`         // Public key of the account when AccountSeed is a signing key
+	URL            string        `mapstructure:"url"`             // NATS URL handed to agents
+	CredentialsTTL time.Duration `mapstructure:"credentials_ttl"` // Lifetime of issued credentials; zero for no expiry
+}
+
+// RedisStreamsConfig configures the event bus on DragonflyDB streams
+type RedisStreamsConfig struct {
+	MaxLen     int64         `mapstructure:"max_len"`     // Approximate number of events kept per stream
+	MaxDeliver int           `mapstructure:"max_deliver"` // Attempts before a durable consumer gives up on an event
+	AckWait    time.Duration `mapstructure:"ack_wait"`    // An unacknowledged event is redelivered after this long
 }
 
 type StreamConfig struct {
@@ -186,12 +262,452 @@ type StreamConfig struct {
 }
 
 type IntegrationsConfig struct {
-	Coolify CoolifyConfig `mapstructure:"coolify"`
-	Rancher RancherConfig `mapstructure:"rancher"`
-	ArgoCD  ArgoCDConfig  `mapstructure:"argocd"`
-	Vault   VaultConfig   `mapstructure:"vault"`
-	RKE2    RKE2Config    `mapstructure:"rke2"`
-	Hasura  HasuraConfig  `mapstructure:"hasura"`
+	Coolify    CoolifyConfig    `mapstructure:"coolify"`
+	Rancher    RancherConfig    `mapstructure:"rancher"`
+	EKS        EKSConfig        `mapstructure:"eks"`
+	GKE        GKEConfig        `mapstructure:"gke"`
+	AKS        AKSConfig        `mapstructure:"aks"`
+	ArgoCD     ArgoCDConfig     `mapstructure:"argocd"`
+	Vault      VaultConfig      `mapstructure:"vault"`
+	RKE2       RKE2Config       `mapstructure:"rke2"`
+	Hasura     HasuraConfig     `mapstructure:"hasura"`
+	Grafana    GrafanaConfig    `mapstructure:"grafana"`
+	Prometheus PrometheusConfig `mapstructure:"prometheus"`
+	Loki       LokiConfig       `mapstructure:"loki"`
+	KEDA       KEDAConfig       `mapstructure:"keda"`
+	PrePull    PrePullConfig    `mapstructure:"pre_pull"`
+	S3         S3Config         `mapstructure:"s3"`
+	Artifacts  ArtifactsConfig  `mapstructure:"artifacts"`
+	Backstage  BackstageConfig  `mapstructure:"backstage"`
+	Slack      SlackConfig      `mapstructure:"slack"`
+	PagerDuty  PagerDutyConfig  `mapstructure:"pagerduty"`
+	Opsgenie   OpsgenieConfig   `mapstructure:"opsgenie"`
+
+	SecretReplication SecretReplicationConfig `mapstructure:"secret_replication"`
+	Signing           SigningConfig           `mapstructure:"signing"`
+	Egress            EgressConfig            `mapstructure:"egress"`
+	IngressRoutes     IngressRoutesConfig     `mapstructure:"ingress_routes"`
+	InternalNetwork   InternalNetworkConfig   `mapstructure:"internal_network"`
+	EnvLifecycle      EnvLifecycleConfig      `mapstructure:"environment_lifecycle"`
+	CronJobs          CronJobsConfig          `mapstructure:"cron_jobs"`
+	Jobs              JobsConfig              `mapstructure:"jobs"`
+	Autoscaling       AutoscalingConfig       `mapstructure:"autoscaling"`
+	Availability      AvailabilityConfig      `mapstructure:"availability"`
+	Volumes           VolumesConfig           `mapstructure:"volumes"`
+	AutoTLS           AutoTLSConfig           `mapstructure:"auto_tls"`
+	Domains           DomainsConfig           `mapstructure:"domains"`
+	Residency         ResidencyConfig         `mapstructure:"residency"`
+	Tunnel            TunnelConfig            `mapstructure:"tunnel"`
+	Webhooks          WebhooksConfig          `mapstructure:"webhooks"`
+	DeployLinks       DeployLinksConfig       `mapstructure:"deploy_links"`
+	Approvals         ApprovalsConfig         `mapstructure:"approvals"`
+	DevClusters       DevClustersConfig       `mapstructure:"dev_clusters"`
+	Kubeconfigs       KubeconfigsConfig       `mapstructure:"kubeconfigs"`
+	Placement         PlacementConfig         `mapstructure:"placement"`
+	Databases         DatabasesConfig         `mapstructure:"databases"`
+	Caches            CachesConfig            `mapstructure:"caches"`
+	Brokers           BrokersConfig           `mapstructure:"brokers"`
+	ObjectStorage     ObjectStorageConfig     `mapstructure:"object_storage"`
+}
+
+// DatabasesConfig controls managed databases, provisioned in the cluster the
+// orchestrator runs in by the YugabyteDB and CloudNativePG operators
+type DatabasesConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Namespace string `mapstructure:"namespace"` // Where the database clusters and their secrets are created
+	Postgres  bool   `mapstructure:"postgres"`  // Offer PostgreSQL databases; needs the CloudNativePG operator
+
+	Backup DatabaseBackupConfig `mapstructure:"backup"`
+	// UpgradeVersions are the YugabyteDB versions offered for upgrades; when
+	// empty they are discovered from Docker Hub
+	UpgradeVersions []string `mapstructure:"upgrade_versions"`
+}
+
+// DatabaseBackupConfig controls YugabyteDB backups, stored in the S3 bucket of
+// the s3 integration
+type DatabaseBackupConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	Prefix              string        `mapstructure:"prefix"`               // Key prefix of backups in the bucket
+	Schedule            string        `mapstructure:"schedule"`             // Cron expression of scheduled full backups
+	IncrementalInterval time.Duration `mapstructure:"incremental_interval"` // Incremental backups between full ones, the restore granularity; 0 disables them
+	Retention           time.Duration `mapstructure:"retention"`
+}
+
+// CachesConfig controls managed Dragonfly and Redis caches, run in the
+// cluster the orchestrator runs in
+type CachesConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Namespace string `mapstructure:"namespace"`  // Where caches and their secrets are created
+	TLSIssuer string `mapstructure:"tls_issuer"` // cert-manager ClusterIssuer of cache certificates; empty disables TLS
+}
+
+// BrokersConfig controls managed message brokers, run in the cluster the
+// orchestrator runs in. NATS brokers need no operator; Kafka needs Strimzi.
+type BrokersConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Namespace string `mapstructure:"namespace"` // Where brokers and their secrets are created
+	Kafka     bool   `mapstructure:"kafka"`     // Offer Kafka; needs the Strimzi operator
+}
+
+// ObjectStorageConfig controls per-project MinIO tenants, provisioned by the
+// MinIO Operator in the cluster the orchestrator runs in
+type ObjectStorageConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	NamespacePrefix  string        `mapstructure:"namespace_prefix"` // A project's tenant runs in <prefix><project ID>
+	Image            string        `mapstructure:"image"`
+	VolumesPerServer int           `mapstructure:"volumes_per_server"` // Erasure coding needs at least 4
+	VolumeSize       string        `mapstructure:"volume_size"`
+	StorageClass     string        `mapstructure:"storage_class"` // Empty for the cluster default
+	Region           string        `mapstructure:"region"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+}
+
+// BackstageConfig controls the Backstage catalog feed. Backstage ingests it
+// through a URL location or polls the entity provider endpoints.
+type BackstageConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	Token        string `mapstructure:"token"`         // Bearer or ?token= credential; empty disables authentication
+	DefaultOwner string `mapstructure:"default_owner"` // Entity owner for services without a catalog owner
+	ConsoleURL   string `mapstructure:"console_url"`   // Console base URL linked from each entity
+}
+
+// SlackConfig controls Slack notifications of builds, deploys and alerts,
+// posted by a Slack app's bot to the channels each project routes them to
+type SlackConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	BotToken       string        `mapstructure:"bot_token"` // Bot token of an app with the chat:write scope
+	APIURL         string        `mapstructure:"api_url"`
+	DefaultChannel string        `mapstructure:"default_channel"` // Receives alerts of no project; none when empty
+	ConsoleURL     string        `mapstructure:"console_url"`     // Console base URL messages link to
+	Timeout        time.Duration `mapstructure:"timeout"`
+}
+
+// PagerDutyConfig controls PagerDuty incidents for critical alerts and
+// failed production deploys, sent through the Events API v2
+type PagerDutyConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	RoutingKey string        `mapstructure:"routing_key"` // Integration key of the Events API v2 integration of the service paged
+	APIURL     string        `mapstructure:"api_url"`
+	ConsoleURL string        `mapstructure:"console_url"` // Console base URL incidents link to
+	Timeout    time.Duration `mapstructure:"timeout"`
+}
+
+// OpsgenieConfig controls Opsgenie alerts for critical alerts and failed
+// production deploys
+type OpsgenieConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	APIKey     string        `mapstructure:"api_key"`  // Key of an API integration
+	APIURL     string        `mapstructure:"api_url"`  // https://api.eu.opsgenie.com for EU accounts
+	Team       string        `mapstructure:"team"`     // Responder team; the integration's team when empty
+	Priority   string        `mapstructure:"priority"` // P1..P5
+	ConsoleURL string        `mapstructure:"console_url"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+}
+
+// NotificationsConfig controls notifications sent to people outside the
+// console, besides the Slack integration
+type NotificationsConfig struct {
+	Email EmailConfig `mapstructure:"email"`
+}
+
+// EmailConfig controls HTML emails sent through an SMTP relay: failed
+// deploys, firing alerts and weekly project digests
+type EmailConfig struct {
+	Enabled    bool              `mapstructure:"enabled"`
+	Host       string            `mapstructure:"host"`
+	Port       int               `mapstructure:"port"`
+	Username   string            `mapstructure:"username"` // No authentication when empty
+	Password   string            `mapstructure:"password"`
+	From       string            `mapstructure:"from"`        // e.g. NorthStack <noreply@example.com>
+	TLS        string            `mapstructure:"tls"`         // starttls, tls (implicit, usually port 465) or none
+	Recipients []string          `mapstructure:"recipients"`  // Receive every email, and alerts of no project
+	ConsoleURL string            `mapstructure:"console_url"` // Console base URL emails link to
+	Timeout    time.Duration     `mapstructure:"timeout"`
+	Digest     EmailDigestConfig `mapstructure:"digest"`
+}
+
+// EmailDigestConfig controls the weekly digest of each project's deploys and
+// alerts
+type EmailDigestConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Day     string `mapstructure:"day"`  // mon..sun
+	Hour    int    `mapstructure:"hour"` // 0-23, UTC
+}
+
+// SecretReplicationConfig controls syncing the Vault secrets bound to a
+// service into every cluster it is deployed to, through External Secrets Operator
+type SecretReplicationConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Interval        time.Duration `mapstructure:"interval"`          // Between checks of every cluster copy
+	StaleAfter      time.Duration `mapstructure:"stale_after"`       // A copy behind Vault for this long raises a drift alert
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`  // ExternalSecret refreshInterval
+	StoreName       string        `mapstructure:"store_name"`        // ClusterSecretStore created in each cluster
+	AuthMountPrefix string        `mapstructure:"auth_mount_prefix"` // Vault Kubernetes auth mount of a cluster is <prefix><cluster slug>
+	ServiceAccount  string        `mapstructure:"service_account"`   // namespace/name ESO authenticates to Vault as
+}
+
+// SigningConfig controls signing outbound webhooks and published events with
+// a key per organization (team) held in Vault transit
+type SigningConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	KeyPrefix      string        `mapstructure:"key_prefix"`      // Key of an organization is <prefix><team ID>, or <prefix>default
+	RotationPeriod time.Duration `mapstructure:"rotation_period"` // Age at which a key is rotated
+	Overlap        time.Duration `mapstructure:"overlap"`         // How long a replaced key version stays in the JWKS
+}
+
+// EgressConfig controls static egress IPs per environment, through Cilium
+// egress gateway nodes or the cluster's cloud NAT
+type EgressConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Interval      time.Duration `mapstructure:"interval"`       // Between refreshes of the assigned IPs
+	PoolLabel     string        `mapstructure:"pool_label"`     // Node label naming the gateway pool a node belongs to
+	DefaultPool   string        `mapstructure:"default_pool"`   // Pool used when a request names none
+	IPAnnotation  string        `mapstructure:"ip_annotation"`  // Node annotation with the static public IP, e.g. an elastic IP
+	ExcludedCIDRs []string      `mapstructure:"excluded_cidrs"` // Destinations that keep leaving through the pod's own node
+}
+
+// IngressRoutesConfig controls the routing resources rendered from ingresses
+// and applied to the cluster serving each ingress's service, in the dialect of
+// the cluster's ingress controller
+type IngressRoutesConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	Controller        string        `mapstructure:"controller"`          // nginx or traefik, for clusters that set none
+	NGINXClass        string        `mapstructure:"nginx_class"`         // ingressClassName of NGINX Ingresses
+	TraefikEntryPoint string        `mapstructure:"traefik_entry_point"` // Entry point of plain HTTP routes
+	TraefikTLSEntry   string        `mapstructure:"traefik_tls_entry"`   // Entry point of TLS routes
+	Interval          time.Duration `mapstructure:"interval"`            // Between reconciliations of every ingress
+}
+
+// InternalNetworkConfig controls the internal DNS names and network policies
+// of services with internal exposure. Names are served by a CoreDNS server
+// block the platform writes into each workload cluster.
+type InternalNetworkConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Domain           string        `mapstructure:"domain"`             // Zone of internal names: <service>.<project>.<domain>
+	ClusterDomain    string        `mapstructure:"cluster_domain"`     // DNS domain of the clusters' Services
+	CoreDNSNamespace string        `mapstructure:"coredns_namespace"`  // Namespace of the CoreDNS custom ConfigMap
+	CoreDNSConfigMap string        `mapstructure:"coredns_config_map"` // ConfigMap CoreDNS imports *.server files from
+	Interval         time.Duration `mapstructure:"interval"`           // Between reconciliations of every cluster
+}
+
+// EnvLifecycleConfig controls the expiry and idle hibernation of short-lived
+// environments. Idle detection and wake on request read ingress controller
+// request metrics from integrations.prometheus.
+type EnvLifecycleConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	Interval    time.Duration `mapstructure:"interval"`     // Between checks of every environment; also how fast requests wake one
+	PreviewTTL  time.Duration `mapstructure:"preview_ttl"`  // Lifetime of preview environments that set none; 0 for no limit
+	PreviewIdle time.Duration `mapstructure:"preview_idle"` // Idle time after which preview environments that set none hibernate; 0 never
+}
+
+// CronJobsConfig controls the Kubernetes CronJobs rendered for cron job
+// services in their target clusters
+type CronJobsConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Interval     time.Duration `mapstructure:"interval"`       // Between reconciliations of every cron job service
+	LogTailLines int64         `mapstructure:"log_tail_lines"` // Lines of a run's logs returned when the request sets none
+}
+
+// JobsConfig controls one-off commands run against a service's image as
+// Kubernetes Jobs
+type JobsConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	DefaultTimeout time.Duration `mapstructure:"default_timeout"` // How long a job may run when the request sets no timeout
+	MaxTimeout     time.Duration `mapstructure:"max_timeout"`     // Longest timeout a request may set
+	Retention      time.Duration `mapstructure:"retention"`       // How long finished jobs are kept with their logs
+	LogTailLines   int64         `mapstructure:"log_tail_lines"`  // Lines of a job's logs returned when the request sets none
+}
+
+// AutoscalingConfig controls the HorizontalPodAutoscalers and KEDA
+// ScaledObjects rendered for services in their target clusters
+type AutoscalingConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // Between reconciliations of every service's autoscaler and replica counts
+}
+
+// AvailabilityConfig controls the PodDisruptionBudgets applied to services
+// with availability settings in their target clusters
+type AvailabilityConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // Between reconciliations of every service's disruption budget
+}
+
+// VolumesConfig controls the PersistentVolumeClaims of service volumes in
+// their target clusters and the CSI snapshots taken of them
+type VolumesConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Interval      time.Duration `mapstructure:"interval"`       // Between reapplications of every volume's claim
+	SnapshotClass string        `mapstructure:"snapshot_class"` // VolumeSnapshotClass of snapshots; the cluster default when empty
+}
+
+// AutoTLSConfig controls the certificates cert-manager issues in workload
+// clusters for ingresses with auto_tls, from an ACME CA such as Let's Encrypt
+type AutoTLSConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Email        string        `mapstructure:"email"`         // ACME account contact, warned about expiring certificates
+	Server       string        `mapstructure:"server"`        // ACME directory URL
+	Challenge    string        `mapstructure:"challenge"`     // http01 or dns01, for ingresses that name none
+	IngressClass string        `mapstructure:"ingress_class"` // Serves HTTP-01 challenges
+	Interval     time.Duration `mapstructure:"interval"`      // Between checks of certificate statuses
+	DNS01        DNS01Config   `mapstructure:"dns01"`
+}
+
+// DNS01Config holds the DNS provider that solves DNS-01 challenges
+type DNS01Config struct {
+	Provider               string `mapstructure:"provider"`             // cloudflare or route53; empty disables DNS-01
+	CloudflareAPIToken     string `mapstructure:"cloudflare_api_token"` // Needs Zone:DNS:Edit
+	Route53Region          string `mapstructure:"route53_region"`
+	Route53AccessKeyID     string `mapstructure:"route53_access_key_id"` // Empty for the ambient credentials of cert-manager
+	Route53SecretAccessKey string `mapstructure:"route53_secret_access_key"`
+}
+
+// DomainsConfig controls custom domain verification. When enabled, an
+// ingress may only route a domain its project has proven control of in DNS.
+type DomainsConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	PlatformDomains []string      `mapstructure:"platform_domains"` // Domains the platform hands out, with their subdomains; no verification needed
+	CNAMETarget     string        `mapstructure:"cname_target"`     // Zone challenge CNAMEs point into; empty offers TXT challenges only
+	Nameserver      string        `mapstructure:"nameserver"`       // host:port queried for challenges; empty for the system resolver
+	Interval        time.Duration `mapstructure:"interval"`         // Between checks of pending domains
+	Expiry          time.Duration `mapstructure:"expiry"`           // Pending domains fail after this long
+}
+
+// ResidencyConfig defines the data residency regions projects can declare
+type ResidencyConfig struct {
+	Regions        map[string][]string `mapstructure:"regions"`         // Residency region to the cluster and storage regions it allows, e.g. eu: [eu-west-1, eu-central-1]
+	DatabaseRegion string              `mapstructure:"database_region"` // Region managed databases run in
+	BackupRegion   string              `mapstructure:"backup_region"`   // Region database backups are stored in; defaults to the S3 region
+}
+
+// TunnelConfig controls port-forward tunnels to the ports of private services
+type TunnelConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	MaxSessionsPerUser int           `mapstructure:"max_sessions_per_user"` // Open tunnels a user may hold at once
+	MaxDuration        time.Duration `mapstructure:"max_duration"`          // A tunnel is closed after this long
+	IdleTimeout        time.Duration `mapstructure:"idle_timeout"`          // A tunnel without traffic for this long is closed
+}
+
+// WebhooksConfig controls the delivery of platform events to the outbound
+// webhook subscriptions of projects
+type WebhooksConfig struct {
+	MaxAttempts    int           `mapstructure:"max_attempts"`    // Attempts per event, including the first
+	InitialBackoff time.Duration `mapstructure:"initial_backoff"` // Delay before the first retry; doubles after each
+	MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+	Timeout        time.Duration `mapstructure:"timeout"` // Per request
+}
+
+// DeployLinksConfig controls one-click deploy links, the target of "Deploy
+// to NFOSS" buttons: public links that create a project from a repository and
+// a template, after the user signs in to the console
+type DeployLinksConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	ConsoleURL string `mapstructure:"console_url"` // Console page browsers following a link are sent to
+	APIURL     string `mapstructure:"api_url"`     // Public URL of this API, which repository webhooks are delivered to
+}
+
+// ApprovalsConfig controls deploy approval gates: deploys to environments of
+// the given types wait until the project owner, or a user they delegated to,
+// approves them, and go to the team owner when left pending
+type ApprovalsConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	EnvironmentTypes []string      `mapstructure:"environment_types"` // Environments whose deploys need approval, e.g. production
+	EscalateAfter    time.Duration `mapstructure:"escalate_after"`    // Pending time after which approvals go to the team owner; 0 never
+	Interval         time.Duration `mapstructure:"interval"`          // Between checks for approvals to escalate
+}
+
+// DevClustersConfig controls single-node k3s clusters for trials and
+// preview workloads, installed on a VM over SSH with the integrations.rke2
+// SSH settings or locally with k3d. They only host non-production environments.
+type DevClustersConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	K3sVersion     string        `mapstructure:"k3s_version"`
+	InstallURL     string        `mapstructure:"install_url"` // k3s install script
+	InstallTimeout time.Duration `mapstructure:"install_timeout"`
+	K3dPath        string        `mapstructure:"k3d_path"` // k3d binary for local clusters
+}
+
+// KubeconfigsConfig controls the kubeconfigs of managed clusters: the
+// cached admin kubeconfigs, encrypted with a Vault transit key, and the
+// short-lived kubeconfigs issued to users
+type KubeconfigsConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	EncryptionKey    string        `mapstructure:"encryption_key"` // Transit key the cached kubeconfigs are encrypted with
+	MaxAge           time.Duration `mapstructure:"max_age"`        // Lifetime assumed for credentials without an expiry, below Rancher's kubeconfig token TTL
+	RotateBefore     time.Duration `mapstructure:"rotate_before"`  // Cached kubeconfigs are replaced this long before they expire
+	CheckInterval    time.Duration `mapstructure:"check_interval"`
+	UserTTL          time.Duration `mapstructure:"user_ttl"` // Default lifetime of user kubeconfigs
+	MaxUserTTL       time.Duration `mapstructure:"max_user_ttl"`
+	UserClusterRole  string        `mapstructure:"user_cluster_role"`  // Bound in the namespaces of a user's environments
+	AdminClusterRole string        `mapstructure:"admin_cluster_role"` // Bound cluster-wide for platform admins
+}
+
+// PlacementConfig controls the scheduler that picks the cluster of services
+// created without a target cluster among the clusters of their project
+type PlacementConfig struct {
+	Enabled        bool    `mapstructure:"enabled"`
+	MaxCPUUsage    float64 `mapstructure:"max_cpu_usage"`    // Clusters with more of their allocatable CPU requested are full
+	MaxMemoryUsage float64 `mapstructure:"max_memory_usage"` // Clusters with more of their allocatable memory requested are full
+}
+
+// S3Config holds the S3-compatible object storage (AWS S3, MinIO, Ceph RGW) used for build artifacts
+type S3Config struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	Endpoint        string        `mapstructure:"endpoint"` // e.g. https://s3.eu-west-1.amazonaws.com or http://minio:9000
+	Region          string        `mapstructure:"region"`
+	Bucket          string        `mapstructure:"bucket"`
+	AccessKeyID     string        `mapstructure:"access_key_id"`
+	SecretAccessKey string        `mapstructure:"secret_access_key"`
+	PathStyle       bool          `mapstructure:"path_style"` // Required by most MinIO deployments
+	Timeout         time.Duration `mapstructure:"timeout"`
+}
+
+// ArtifactsConfig controls hosting of binary build artifacts
+type ArtifactsConfig struct {
+	MaxSize          int64         `mapstructure:"max_size"`          // Bytes
+	DefaultRetention time.Duration `mapstructure:"default_retention"` // Used when an upload does not set one
+	MaxRetention     time.Duration `mapstructure:"max_retention"`
+	URLExpiry        time.Duration `mapstructure:"url_expiry"` // Lifetime of signed download URLs
+}
+
+// PrePullConfig controls pulling a release's image onto the target nodes before its rollout
+type PrePullConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`
+	Timeout      time.Duration `mapstructure:"timeout"` // After which the rollout starts anyway
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+}
+
+// KEDAConfig holds the in-cluster settings used when rendering KEDA scalers
+type KEDAConfig struct {
+	PrometheusAddress string `mapstructure:"prometheus_address"` // Prometheus reachable from inside workload clusters
+	IngressClass      string `mapstructure:"ingress_class"`      // Ingress controller whose request metrics drive RPS scaling
+}
+
+// LokiConfig holds the Loki API used for historical log search
+type LokiConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	URL      string        `mapstructure:"url"`
+	TenantID string        `mapstructure:"tenant_id"` // X-Scope-OrgID for multi-tenant Loki
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+}
+
+// PrometheusConfig holds the Prometheus (or Thanos Query) API used for service metrics
+type PrometheusConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	URL         string        `mapstructure:"url"`
+	BearerToken string        `mapstructure:"bearer_token"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+}
+
+// GrafanaConfig holds Grafana dashboard provisioning configuration
+type GrafanaConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	URL           string        `mapstructure:"url"`
+	APIToken      string        `mapstructure:"api_token"`
+	OrgID         int           `mapstructure:"org_id"`
+	DatasourceUID string        `mapstructure:"datasource_uid"` // Prometheus datasource used by templated dashboards
+	Timeout       time.Duration `mapstructure:"timeout"`
 }
 
 // RKE2Config holds RKE2 cluster provisioning configuration
@@ -205,9 +721,12 @@ type RKE2Config struct {
 	CNI               string `mapstructure:"cni"`
 
 	// SSH for bare metal provisioning
-	SSHUser    string        `mapstructure:"ssh_user"`
-	SSHKeyPath string        `mapstructure:"ssh_key_path"`
-	SSHTimeout time.Duration `mapstructure:"ssh_timeout"`
+	SSHUser        string        `mapstructure:"ssh_user"`
+	SSHKeyPath     string        `mapstructure:"ssh_key_path"`
+	SSHTimeout     time.Duration `mapstructure:"ssh_timeout"`
+	KnownHostsPath string        `mapstructure:"known_hosts_path"` // Host keys nodes must present; any key is accepted when empty
+	InstallURL     string        `mapstructure:"install_url"`      // RKE2 install script
+	InstallTimeout time.Duration `mapstructure:"install_timeout"`  // Per node
 
 	// Cloud provider
 	CloudProvider string `mapstructure:"cloud_provider"`
@@ -279,6 +798,42 @@ type RancherConfig struct {
 	Token         string          `mapstructure:"token"`
 }
 
+// EKSConfig provisions aws clusters through the EKS API directly, without
+// Rancher. The credentials secret holds access_key_id and secret_access_key.
+type EKSConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	CredentialsPath  string        `mapstructure:"credentials_path"`   // Vault KV path of the credentials
+	Regions          []string      `mapstructure:"regions"`            // Searched when listing clusters
+	ClusterRoleARN   string        `mapstructure:"cluster_role_arn"`   // IAM role of the control plane
+	NodeRoleARN      string        `mapstructure:"node_role_arn"`      // IAM role of the nodes
+	SubnetIDs        []string      `mapstructure:"subnet_ids"`         // Subnets of the control plane and nodes
+	SecurityGroupIDs []string      `mapstructure:"security_group_ids"` // Extra security groups of the control plane
+	InstanceType     string        `mapstructure:"instance_type"`      // Of the default node group
+	Timeout          time.Duration `mapstructure:"timeout"`
+}
+
+// GKEConfig provisions gcp clusters through the GKE API directly, without
+// Rancher. The credentials secret holds a service account key under
+// credentials; clusters are created in its project.
+type GKEConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	CredentialsPath string        `mapstructure:"credentials_path"` // Vault KV path of the credentials
+	Network         string        `mapstructure:"network"`          // VPC network; empty for the default network
+	MachineType     string        `mapstructure:"machine_type"`     // Of the default node pool
+	Timeout         time.Duration `mapstructure:"timeout"`
+}
+
+// AKSConfig provisions azure clusters through the AKS API directly, without
+// Rancher. The credentials secret holds tenant_id, client_id, client_secret
+// and subscription_id of a service principal.
+type AKSConfig struct {
+	Enabled         bool          `mapstructure:"enabled"`
+	CredentialsPath string        `mapstructure:"credentials_path"` // Vault KV path of the credentials
+	ResourceGroup   string        `mapstructure:"resource_group"`   // Holds the managed clusters
+	VMSize          string        `mapstructure:"vm_size"`          // Of the default node pool
+	Timeout         time.Duration `mapstructure:"timeout"`
+}
+
 type ClusterConfig struct {
 	ID      string `mapstructure:"id"`
 	Name    string `mapstructure:"name"`
@@ -327,6 +882,8 @@ type VaultConfig struct {
 	MountPath  string        `mapstructure:"mount_path"`
 	Timeout    time.Duration `mapstructure:"timeout"`
 
+	TransitMount string `mapstructure:"transit_mount"` // Transit engine holding signing keys
+
 	// Kubernetes auth
 	K8sRole     string `mapstructure:"k8s_role"`
 	K8sAuthPath string `mapstructure:"k8s_auth_path"`
@@ -353,21 +910,116 @@ type AuthConfig struct {
 	APIKeyEnabled bool   `mapstructure:"api_key_enabled"`
 
 	// Rate Limiting
-	RateLimitEnabled  bool          `mapstructure:"rate_limit_enabled"`
-	RateLimitWindow   time.Duration `mapstructure:"rate_limit_window"`
-	RateLimitRequests int           `mapstructure:"rate_limit_requests"`
+	RateLimitEnabled           bool          `mapstructure:"rate_limit_enabled"`
+	RateLimitWindow            time.Duration `mapstructure:"rate_limit_window"`
+	RateLimitRequests          int           `mapstructure:"rate_limit_requests"`           // Authenticated, per token and per IP
+	RateLimitAnonymousRequests int           `mapstructure:"rate_limit_anonymous_requests"` // Unauthenticated, per IP
 
 	// Session
 	SessionCookieName   string        `mapstructure:"session_cookie_name"`
 	SessionCookieSecure bool          `mapstructure:"session_cookie_secure"`
 	SessionMaxAge       time.Duration `mapstructure:"session_max_age"`
+
+	// Share links
+	ShareLinks ShareLinksConfig `mapstructure:"share_links"`
+}
+
+// ShareLinksConfig controls signed, expiring read-only links to service and
+// deployment views. Links are stateless; rotating the secret revokes them all.
+type ShareLinksConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Secret     string        `mapstructure:"secret"` // Defaults to a key derived from auth.jwt_secret
+	DefaultTTL time.Duration `mapstructure:"default_ttl"`
+	MaxTTL     time.Duration `mapstructure:"max_ttl"`
+	BaseURL    string        `mapstructure:"base_url"` // Console page the token is appended to
 }
 
 type ObservabilityConfig struct {
-	Metrics       MetricsConfig `mapstructure:"metrics"`
-	Logging       LoggingConfig `mapstructure:"logging"`
-	Tracing       TracingConfig `mapstructure:"tracing"`
-	MetricsConfig MetricsConfig `mapstructure:"-"` // Alias
+	Metrics          MetricsConfig          `mapstructure:"metrics"`
+	Logging          LoggingConfig          `mapstructure:"logging"`
+	Tracing          TracingConfig          `mapstructure:"tracing"`
+	AnomalyDetection AnomalyDetectionConfig `mapstructure:"anomaly_detection"`
+	ReleaseHealth    ReleaseHealthConfig    `mapstructure:"release_health"`
+	Rightsizing      RightsizingConfig      `mapstructure:"rightsizing"`
+	Alerting         AlertingConfig         `mapstructure:"alerting"`
+	QueueSLA         QueueSLAConfig         `mapstructure:"queue_sla"`
+	Uptime           UptimeConfig           `mapstructure:"uptime"`
+	ClusterHealth    ClusterHealthConfig    `mapstructure:"cluster_health"`
+	Metering         MeteringConfig         `mapstructure:"metering"`
+	Capacity         CapacityConfig         `mapstructure:"capacity"`
+	MetricsConfig    MetricsConfig          `mapstructure:"-"` // Alias
+}
+
+// CapacityConfig controls the cluster capacity reports
+type CapacityConfig struct {
+	ForecastWindow time.Duration `mapstructure:"forecast_window"` // Trailing usage the growth trend is fitted to
+	ForecastStep   time.Duration `mapstructure:"forecast_step"`
+}
+
+// ReleaseHealthConfig controls per-deployment health scoring
+type ReleaseHealthConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	EvaluationWindow time.Duration `mapstructure:"evaluation_window"` // Time after a deploy before it is scored
+	SLOTarget        float64       `mapstructure:"slo_target"`        // Availability target used for error budget burn
+}
+
+// RightsizingConfig controls the right-sizing recommendations of services,
+// drawn from usage metrics and recommendation-only VerticalPodAutoscalers
+type RightsizingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// AnomalyDetectionConfig controls post-deploy regression detection
+type AnomalyDetectionConfig struct {
+	Enabled           bool          `mapstructure:"enabled"`
+	BaselineWindow    time.Duration `mapstructure:"baseline_window"`    // Pre-deploy window used as the baseline
+	ObservationWindow time.Duration `mapstructure:"observation_window"` // Post-deploy window compared to the baseline
+	AutoRollback      bool          `mapstructure:"auto_rollback"`
+}
+
+// AlertingConfig controls the built-in alert rules and Alertmanager ingestion
+type AlertingConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	EvaluationInterval time.Duration `mapstructure:"evaluation_interval"`
+	ErrorRateThreshold float64       `mapstructure:"error_rate_threshold"` // Fraction of failed requests that fires an alert
+	WebhookToken       string        `mapstructure:"webhook_token"`        // Bearer token expected from Alertmanager; the receiver is off without one
+}
+
+// QueueSLAConfig controls queue time tracking for builds and deployments
+type QueueSLAConfig struct {
+	Enabled            bool          `mapstructure:"enabled"`
+	EvaluationInterval time.Duration `mapstructure:"evaluation_interval"`
+	Window             time.Duration `mapstructure:"window"`         // Lookback used for the platform-wide percentiles
+	BuildSLA           time.Duration `mapstructure:"build_sla"`      // Maximum acceptable p95 time in queue for builds
+	DeploymentSLA      time.Duration `mapstructure:"deployment_sla"` // Maximum acceptable p95 time in queue for deployments
+}
+
+// UptimeConfig controls HTTP probing of public ingress domains
+type UptimeConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Interval         time.Duration `mapstructure:"interval"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+	FailureThreshold int           `mapstructure:"failure_threshold"` // Consecutive failures before alerting
+	Retention        time.Duration `mapstructure:"retention"`
+}
+
+// ClusterHealthConfig controls the reconciliation of stored cluster statuses
+// with the health the cluster managers report
+type ClusterHealthConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	Interval         time.Duration `mapstructure:"interval"`
+	FailureThreshold int           `mapstructure:"failure_threshold"` // Consecutive failed health checks before a cluster is unhealthy
+}
+
+// MeteringConfig controls the hourly per-service resource usage rollup
+type MeteringConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Backfill time.Duration `mapstructure:"backfill"` // How far back missing hours are rolled up after downtime
+
+	// Unit prices used for cost estimates
+	Currency           string  `mapstructure:"currency"`
+	CPUCoreHourPrice   float64 `mapstructure:"cpu_core_hour_price"`
+	MemoryGiBHourPrice float64 `mapstructure:"memory_gib_hour_price"`
 }
 
 type MetricsConfig struct {
@@ -444,6 +1096,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.write_timeout", "30s")
 	v.SetDefault("server.shutdown_timeout", "10s")
 	v.SetDefault("server.cors_origins", []string{"*"})
+	v.SetDefault("server.docs_enabled", true)
+	v.SetDefault("server.idempotency_ttl", "24h")
 
 	// YugabyteDB defaults (primary database)
 	v.SetDefault("yugabytedb.enabled", true)
@@ -477,6 +1131,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("dragonflydb.key_prefix", "northstack")
 
 	// Legacy Database defaults (fallback to PostgreSQL)
+	v.SetDefault("database.driver", "postgres")
+	v.SetDefault("database.path", "northstack.db")
 	v.SetDefault("database.host", "localhost")
 	v.SetDefault("database.port", 5432)
 	v.SetDefault("database.database", "northflank_oss")
@@ -502,6 +1158,30 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("nats.max_reconnects", 60)
 	v.SetDefault("nats.reconnect_wait", "2s")
 	v.SetDefault("nats.jetstream_enabled", true)
+	v.SetDefault("nats.consumers.max_deliver", 5)
+	v.SetDefault("nats.consumers.ack_wait", "1m")
+	v.SetDefault("nats.consumers.backoff", []string{"1s", "10s", "1m", "5m"})
+	v.SetDefault("nats.consumers.batch_size", 10)
+	v.SetDefault("nats.cloudevents", false)
+	v.SetDefault("nats.schema_validation", "warn")
+	v.SetDefault("nats.tenants.enabled", false)
+	v.SetDefault("nats.tenants.prefix", "tenant")
+	v.SetDefault("nats.tenants.credentials_ttl", "24h")
+
+	// Event bus defaults
+	v.SetDefault("event_bus.driver", "nats")
+	v.SetDefault("event_bus.streams.max_len", 100000)
+	v.SetDefault("event_bus.streams.max_deliver", 5)
+	v.SetDefault("event_bus.streams.ack_wait", "1m")
+
+	// Adapter worker defaults
+	v.SetDefault("workers.enabled", false)
+	v.SetDefault("workers.queue", "adapter-workers")
+	v.SetDefault("workers.timeout", "2m")
+
+	// Cluster agents
+	v.SetDefault("agents.enabled", false)
+	v.SetDefault("agents.timeout", "30s")
 
 	// Integration defaults - Coolify
 	v.SetDefault("integrations.coolify.enabled", true)
@@ -512,6 +1192,20 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("integrations.rancher.enabled", true)
 	v.SetDefault("integrations.rancher.timeout", "30s")
 
+	// Direct cloud provisioning, without Rancher
+	v.SetDefault("integrations.eks.enabled", false)
+	v.SetDefault("integrations.eks.credentials_path", "platform/cloud/aws")
+	v.SetDefault("integrations.eks.instance_type", "m6i.large")
+	v.SetDefault("integrations.eks.timeout", "30s")
+	v.SetDefault("integrations.gke.enabled", false)
+	v.SetDefault("integrations.gke.credentials_path", "platform/cloud/gcp")
+	v.SetDefault("integrations.gke.machine_type", "e2-standard-4")
+	v.SetDefault("integrations.gke.timeout", "30s")
+	v.SetDefault("integrations.aks.enabled", false)
+	v.SetDefault("integrations.aks.credentials_path", "platform/cloud/azure")
+	v.SetDefault("integrations.aks.vm_size", "Standard_D4s_v5")
+	v.SetDefault("integrations.aks.timeout", "30s")
+
 	// Integration defaults - ArgoCD
 	v.SetDefault("integrations.argocd.enabled", true)
 	v.SetDefault("integrations.argocd.timeout", "30s")
@@ -526,6 +1220,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("integrations.vault.auth_method", "kubernetes")
 	v.SetDefault("integrations.vault.mount_path", "secret")
 	v.SetDefault("integrations.vault.timeout", "10s")
+	v.SetDefault("integrations.vault.transit_mount", "transit")
 
 	// Integration defaults - RKE2
 	v.SetDefault("integrations.rke2.enabled", true)
@@ -533,6 +1228,8 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("integrations.rke2.cni", "cilium")
 	v.SetDefault("integrations.rke2.ssh_user", "root")
 	v.SetDefault("integrations.rke2.ssh_timeout", "30s")
+	v.SetDefault("integrations.rke2.install_url", "https://get.rke2.io")
+	v.SetDefault("integrations.rke2.install_timeout", "20m")
 	v.SetDefault("integrations.rke2.cloud_provider", "none")
 	v.SetDefault("integrations.rke2.profile", "cis-1.23")
 	v.SetDefault("integrations.rke2.selinux", false)
@@ -549,6 +1246,229 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("integrations.hasura.enable_event_triggers", true)
 	v.SetDefault("integrations.hasura.enable_scheduled_triggers", true)
 
+	// Integration defaults - Grafana
+	v.SetDefault("integrations.grafana.enabled", false)
+	v.SetDefault("integrations.grafana.url", "http://localhost:3000")
+	v.SetDefault("integrations.grafana.org_id", 1)
+	v.SetDefault("integrations.grafana.datasource_uid", "prometheus")
+	v.SetDefault("integrations.grafana.timeout", "30s")
+
+	// Integration defaults - Prometheus
+	v.SetDefault("integrations.prometheus.enabled", false)
+	v.SetDefault("integrations.prometheus.url", "http://localhost:9090")
+	v.SetDefault("integrations.prometheus.timeout", "30s")
+
+	// Integration defaults - KEDA
+	v.SetDefault("integrations.keda.prometheus_address", "http://prometheus-server.monitoring.svc:80")
+	v.SetDefault("integrations.keda.ingress_class", "nginx")
+
+	// Integration defaults - Image pre-pull
+	v.SetDefault("integrations.pre_pull.enabled", true)
+	v.SetDefault("integrations.pre_pull.timeout", "10m")
+	v.SetDefault("integrations.pre_pull.poll_interval", "5s")
+
+	// Integration defaults - Secret replication
+	v.SetDefault("integrations.secret_replication.enabled", false)
+	v.SetDefault("integrations.secret_replication.interval", "1m")
+	v.SetDefault("integrations.secret_replication.stale_after", "10m")
+	v.SetDefault("integrations.secret_replication.refresh_interval", "1h")
+	v.SetDefault("integrations.secret_replication.store_name", "northstack-vault")
+	v.SetDefault("integrations.secret_replication.auth_mount_prefix", "kubernetes-")
+	v.SetDefault("integrations.secret_replication.service_account", "external-secrets/external-secrets")
+
+	// Integration defaults - Webhook and event signing
+	v.SetDefault("integrations.signing.enabled", false)
+	v.SetDefault("integrations.signing.key_prefix", "northstack-org-")
+	v.SetDefault("integrations.signing.rotation_period", "720h")
+	v.SetDefault("integrations.signing.overlap", "168h")
+
+	// Integration defaults - Egress IPs
+	v.SetDefault("integrations.egress.enabled", false)
+	v.SetDefault("integrations.egress.interval", "5m")
+	v.SetDefault("integrations.egress.pool_label", "egress.northstack.io/pool")
+	v.SetDefault("integrations.egress.default_pool", "default")
+	v.SetDefault("integrations.egress.ip_annotation", "egress.northstack.io/public-ip")
+	v.SetDefault("integrations.egress.excluded_cidrs", []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"})
+
+	// Integration defaults - Ingress routes
+	v.SetDefault("integrations.ingress_routes.enabled", false)
+	v.SetDefault("integrations.ingress_routes.controller", "nginx")
+	v.SetDefault("integrations.ingress_routes.nginx_class", "nginx")
+	v.SetDefault("integrations.ingress_routes.traefik_entry_point", "web")
+	v.SetDefault("integrations.ingress_routes.traefik_tls_entry", "websecure")
+	v.SetDefault("integrations.ingress_routes.interval", "5m")
+
+	// Integration defaults - Internal service networking
+	v.SetDefault("integrations.internal_network.enabled", false)
+	v.SetDefault("integrations.internal_network.domain", "internal")
+	v.SetDefault("integrations.internal_network.cluster_domain", "cluster.local")
+	v.SetDefault("integrations.internal_network.coredns_namespace", "kube-system")
+	v.SetDefault("integrations.internal_network.coredns_config_map", "coredns-custom")
+	v.SetDefault("integrations.internal_network.interval", "5m")
+
+	// Integration defaults - Environment lifecycle
+	v.SetDefault("integrations.environment_lifecycle.enabled", false)
+	v.SetDefault("integrations.environment_lifecycle.interval", "1m")
+	v.SetDefault("integrations.environment_lifecycle.preview_ttl", "0s")
+	v.SetDefault("integrations.environment_lifecycle.preview_idle", "0s")
+
+	// Integration defaults - Cron jobs
+	v.SetDefault("integrations.cron_jobs.enabled", false)
+	v.SetDefault("integrations.cron_jobs.interval", "5m")
+	v.SetDefault("integrations.cron_jobs.log_tail_lines", 500)
+
+	// Integration defaults - One-off jobs
+	v.SetDefault("integrations.jobs.enabled", false)
+	v.SetDefault("integrations.jobs.default_timeout", "1h")
+	v.SetDefault("integrations.jobs.max_timeout", "24h")
+	v.SetDefault("integrations.jobs.retention", "168h")
+	v.SetDefault("integrations.jobs.log_tail_lines", 500)
+
+	// Integration defaults - Autoscaling
+	v.SetDefault("integrations.autoscaling.enabled", false)
+	v.SetDefault("integrations.autoscaling.interval", "1m")
+
+	// Integration defaults - Availability
+	v.SetDefault("integrations.availability.enabled", false)
+	v.SetDefault("integrations.availability.interval", "5m")
+
+	// Integration defaults - Volumes
+	v.SetDefault("integrations.volumes.enabled", false)
+	v.SetDefault("integrations.volumes.interval", "5m")
+	v.SetDefault("integrations.volumes.snapshot_class", "")
+
+	// Integration defaults - Ingress certificates
+	v.SetDefault("integrations.auto_tls.enabled", false)
+	v.SetDefault("integrations.auto_tls.server", "https://acme-v02.api.letsencrypt.org/directory")
+	v.SetDefault("integrations.auto_tls.challenge", "http01")
+	v.SetDefault("integrations.auto_tls.ingress_class", "nginx")
+	v.SetDefault("integrations.auto_tls.interval", "1m")
+
+	// Integration defaults - Custom domain verification
+	v.SetDefault("integrations.domains.enabled", false)
+	v.SetDefault("integrations.domains.interval", "1m")
+	v.SetDefault("integrations.domains.expiry", "72h")
+
+	// Integration defaults - Port-forward tunnels
+	v.SetDefault("integrations.tunnel.enabled", false)
+	v.SetDefault("integrations.tunnel.max_sessions_per_user", 3)
+	v.SetDefault("integrations.tunnel.max_duration", "8h")
+	v.SetDefault("integrations.tunnel.idle_timeout", "30m")
+
+	// Integration defaults - Outbound webhooks
+	v.SetDefault("integrations.webhooks.max_attempts", 6)
+	v.SetDefault("integrations.webhooks.initial_backoff", "10s")
+	v.SetDefault("integrations.webhooks.max_backoff", "30m")
+	v.SetDefault("integrations.webhooks.timeout", "10s")
+
+	// Integration defaults - One-click deploy links
+	v.SetDefault("integrations.deploy_links.enabled", false)
+	v.SetDefault("integrations.deploy_links.console_url", "http://localhost:3000/deploy")
+	v.SetDefault("integrations.deploy_links.api_url", "http://localhost:8080")
+
+	// Integration defaults - Deploy approvals
+	v.SetDefault("integrations.approvals.enabled", false)
+	v.SetDefault("integrations.approvals.environment_types", []string{"production"})
+	v.SetDefault("integrations.approvals.escalate_after", "4h")
+	v.SetDefault("integrations.approvals.interval", "5m")
+
+	// Integration defaults - k3s dev clusters
+	v.SetDefault("integrations.dev_clusters.enabled", false)
+	v.SetDefault("integrations.dev_clusters.k3s_version", "v1.28.5+k3s1")
+	v.SetDefault("integrations.dev_clusters.install_url", "https://get.k3s.io")
+	v.SetDefault("integrations.dev_clusters.install_timeout", "10m")
+	v.SetDefault("integrations.dev_clusters.k3d_path", "k3d")
+
+	// Integration defaults - Kubeconfigs
+	v.SetDefault("integrations.kubeconfigs.enabled", false)
+	v.SetDefault("integrations.kubeconfigs.encryption_key", "kubeconfigs")
+	v.SetDefault("integrations.kubeconfigs.max_age", "168h")
+	v.SetDefault("integrations.kubeconfigs.rotate_before", "24h")
+	v.SetDefault("integrations.kubeconfigs.check_interval", "1h")
+	v.SetDefault("integrations.kubeconfigs.user_ttl", "1h")
+	v.SetDefault("integrations.kubeconfigs.max_user_ttl", "12h")
+	v.SetDefault("integrations.kubeconfigs.user_cluster_role", "edit")
+	v.SetDefault("integrations.kubeconfigs.admin_cluster_role", "cluster-admin")
+
+	// Integration defaults - Placement
+	v.SetDefault("integrations.placement.enabled", false)
+	v.SetDefault("integrations.placement.max_cpu_usage", 0.85)
+	v.SetDefault("integrations.placement.max_memory_usage", 0.85)
+
+	// Integration defaults - S3 object storage
+	v.SetDefault("integrations.s3.enabled", false)
+	v.SetDefault("integrations.s3.endpoint", "http://localhost:9000")
+	v.SetDefault("integrations.s3.region", "us-east-1")
+	v.SetDefault("integrations.s3.bucket", "northstack-artifacts")
+	v.SetDefault("integrations.s3.path_style", true)
+	v.SetDefault("integrations.s3.timeout", "5m")
+
+	// Integration defaults - Build artifacts
+	v.SetDefault("integrations.artifacts.max_size", 2<<30)
+	v.SetDefault("integrations.artifacts.default_retention", "720h")
+	v.SetDefault("integrations.artifacts.max_retention", "8760h")
+	v.SetDefault("integrations.artifacts.url_expiry", "15m")
+
+	// Integration defaults - Backstage
+	v.SetDefault("integrations.backstage.enabled", false)
+	v.SetDefault("integrations.backstage.default_owner", "platform-team")
+
+	// Integration defaults - Slack
+	v.SetDefault("integrations.slack.enabled", false)
+	v.SetDefault("integrations.slack.api_url", "https://slack.com/api")
+	v.SetDefault("integrations.slack.timeout", "10s")
+
+	// Integration defaults - PagerDuty and Opsgenie
+	v.SetDefault("integrations.pagerduty.enabled", false)
+	v.SetDefault("integrations.pagerduty.api_url", "https://events.pagerduty.com")
+	v.SetDefault("integrations.pagerduty.timeout", "10s")
+	v.SetDefault("integrations.opsgenie.enabled", false)
+	v.SetDefault("integrations.opsgenie.api_url", "https://api.opsgenie.com")
+	v.SetDefault("integrations.opsgenie.priority", "P1")
+	v.SetDefault("integrations.opsgenie.timeout", "10s")
+
+	// Notification defaults - Email
+	v.SetDefault("notifications.email.enabled", false)
+	v.SetDefault("notifications.email.port", 587)
+	v.SetDefault("notifications.email.tls", "starttls")
+	v.SetDefault("notifications.email.timeout", "10s")
+	v.SetDefault("notifications.email.digest.enabled", false)
+	v.SetDefault("notifications.email.digest.day", "mon")
+	v.SetDefault("notifications.email.digest.hour", 9)
+
+	// Integration defaults - Managed databases
+	v.SetDefault("integrations.databases.enabled", false)
+	v.SetDefault("integrations.databases.namespace", "northstack-databases")
+	v.SetDefault("integrations.databases.postgres", true)
+	v.SetDefault("integrations.databases.backup.enabled", false)
+	v.SetDefault("integrations.databases.backup.prefix", "database-backups")
+	v.SetDefault("integrations.databases.backup.schedule", "0 3 * * *")
+	v.SetDefault("integrations.databases.backup.incremental_interval", "1h")
+	v.SetDefault("integrations.databases.backup.retention", "168h")
+
+	// Integration defaults - Managed caches
+	v.SetDefault("integrations.caches.enabled", false)
+	v.SetDefault("integrations.caches.namespace", "northstack-caches")
+
+	// Integration defaults - Managed message brokers
+	v.SetDefault("integrations.brokers.enabled", false)
+	v.SetDefault("integrations.brokers.namespace", "northstack-brokers")
+	v.SetDefault("integrations.brokers.kafka", true)
+
+	// Integration defaults - Managed object storage
+	v.SetDefault("integrations.object_storage.enabled", false)
+	v.SetDefault("integrations.object_storage.namespace_prefix", "storage-")
+	v.SetDefault("integrations.object_storage.image", "quay.io/minio/minio:RELEASE.2024-10-13T13-34-11Z")
+	v.SetDefault("integrations.object_storage.volumes_per_server", 4)
+	v.SetDefault("integrations.object_storage.volume_size", "10Gi")
+	v.SetDefault("integrations.object_storage.region", "us-east-1")
+	v.SetDefault("integrations.object_storage.timeout", "30s")
+
+	// Integration defaults - Loki
+	v.SetDefault("integrations.loki.enabled", false)
+	v.SetDefault("integrations.loki.url", "http://localhost:3100")
+	v.SetDefault("integrations.loki.timeout", "30s")
+
 	// Auth defaults
 	v.SetDefault("auth.jwt_expiration", "24h")
 	v.SetDefault("auth.refresh_expiration", "168h")
@@ -556,6 +1476,12 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("auth.session_cookie_name", "nfoss_session")
 	v.SetDefault("auth.session_cookie_secure", true)
 	v.SetDefault("auth.session_max_age", "168h")
+	v.SetDefault("auth.rate_limit_window", "1m")
+	v.SetDefault("auth.rate_limit_requests", 600)
+	v.SetDefault("auth.rate_limit_anonymous_requests", 60)
+	v.SetDefault("auth.share_links.enabled", true)
+	v.SetDefault("auth.share_links.default_ttl", "24h")
+	v.SetDefault("auth.share_links.max_ttl", "720h")
 
 	// Observability defaults
 	v.SetDefault("observability.metrics.enabled", true)
@@ -568,6 +1494,44 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("observability.logging.format", "json")
 	v.SetDefault("observability.logging.output", "stdout")
 
+	v.SetDefault("observability.capacity.forecast_window", "168h")
+	v.SetDefault("observability.capacity.forecast_step", "1h")
+
+	v.SetDefault("observability.anomaly_detection.enabled", true)
+	v.SetDefault("observability.anomaly_detection.baseline_window", "1h")
+	v.SetDefault("observability.anomaly_detection.observation_window", "15m")
+	v.SetDefault("observability.anomaly_detection.auto_rollback", false)
+
+	v.SetDefault("observability.release_health.enabled", true)
+	v.SetDefault("observability.release_health.evaluation_window", "1h")
+	v.SetDefault("observability.release_health.slo_target", 0.999)
+	v.SetDefault("observability.rightsizing.enabled", false)
+
+	v.SetDefault("observability.alerting.enabled", true)
+	v.SetDefault("observability.alerting.evaluation_interval", "1m")
+	v.SetDefault("observability.alerting.error_rate_threshold", 0.05)
+
+	v.SetDefault("observability.queue_sla.enabled", true)
+	v.SetDefault("observability.queue_sla.evaluation_interval", "1m")
+	v.SetDefault("observability.queue_sla.window", "1h")
+	v.SetDefault("observability.queue_sla.build_sla", "5m")
+	v.SetDefault("observability.queue_sla.deployment_sla", "2m")
+
+	v.SetDefault("observability.uptime.enabled", true)
+	v.SetDefault("observability.uptime.interval", "1m")
+	v.SetDefault("observability.uptime.timeout", "10s")
+	v.SetDefault("observability.uptime.failure_threshold", 3)
+	v.SetDefault("observability.uptime.retention", "720h")
+	v.SetDefault("observability.cluster_health.enabled", true)
+	v.SetDefault("observability.cluster_health.interval", "1m")
+	v.SetDefault("observability.cluster_health.failure_threshold", 3)
+
+	v.SetDefault("observability.metering.enabled", true)
+	v.SetDefault("observability.metering.backfill", "24h")
+	v.SetDefault("observability.metering.currency", "USD")
+	v.SetDefault("observability.metering.cpu_core_hour_price", 0.031)
+	v.SetDefault("observability.metering.memory_gib_hour_price", 0.0042)
+
 	v.SetDefault("observability.tracing.enabled", false)
 	v.SetDefault("observability.tracing.exporter", "otlp")
 	v.SetDefault("observability.tracing.sample_rate", 0.1)
@@ -580,8 +1544,48 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
-	if c.Database.Host == "" {
-		return fmt.Errorf("database host is required")
+	switch c.Database.Driver {
+	case "", "postgres":
+		if c.Database.Host == "" {
+			return fmt.Errorf("database host is required")
+		}
+	case "sqlite":
+		if c.Database.Path == "" {
+			return fmt.Errorf("database path is required for sqlite")
+		}
+	default:
+		return fmt.Errorf("unsupported database driver: %s", c.Database.Driver)
+	}
+
+	switch c.NATS.SchemaValidation {
+	case "", "off", "warn", "enforce":
+	default:
+		return fmt.Errorf("unsupported nats schema_validation mode: %s", c.NATS.SchemaValidation)
+	}
+
+	switch c.EventBus.Driver {
+	case "", "nats":
+	case "dragonfly":
+		if !c.DragonflyDB.Enabled {
+			return fmt.Errorf("the dragonfly event bus requires dragonflydb to be enabled")
+		}
+	default:
+		return fmt.Errorf("unsupported event bus driver: %s", c.EventBus.Driver)
+	}
+
+	if c.NATS.Tenants.Enabled && c.NATS.Tenants.AccountSeed == "" {
+		return fmt.Errorf("nats tenants account_seed is required when tenants are enabled")
+	}
+	if c.NATS.Tenants.Enabled && c.EventBus.Driver == "dragonfly" {
+		return fmt.Errorf("nats tenants require the nats event bus driver")
+	}
+
+	if c.Workers.Enabled && c.EventBus.Driver == "dragonfly" {
+		return fmt.Errorf("adapter workers require the nats event bus driver")
+	}
+
+	if c.Agents.Enabled && c.EventBus.Driver == "dragonfly" {
+		return fmt.Errorf("cluster agents require the nats event bus driver")
 	}
 
 	if c.Integrations.Coolify.Enabled && c.Integrations.Coolify.BaseURL == "" {
@@ -592,10 +1596,135 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("rancher base_url is required when rancher is enabled")
 	}
 
+	if c.Integrations.Slack.Enabled && c.Integrations.Slack.BotToken == "" {
+		return fmt.Errorf("slack bot_token is required when slack is enabled")
+	}
+
+	if c.Integrations.PagerDuty.Enabled && c.Integrations.PagerDuty.RoutingKey == "" {
+		return fmt.Errorf("pagerduty routing_key is required when pagerduty is enabled")
+	}
+
+	if opsgenie := c.Integrations.Opsgenie; opsgenie.Enabled {
+		if opsgenie.APIKey == "" {
+			return fmt.Errorf("opsgenie api_key is required when opsgenie is enabled")
+		}
+		switch opsgenie.Priority {
+		case "P1", "P2", "P3", "P4", "P5":
+		default:
+			return fmt.Errorf("opsgenie priority must be one of P1..P5")
+		}
+	}
+
+	if email := c.Notifications.Email; email.Enabled {
+		if email.Host == "" || email.From == "" {
+			return fmt.Errorf("notifications email host and from are required when email is enabled")
+		}
+		if _, err := mail.ParseAddress(email.From); err != nil {
+			return fmt.Errorf("notifications email from is not an email address: %w", err)
+		}
+		for _, recipient := range email.Recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return fmt.Errorf("notifications email recipient %q is not an email address: %w", recipient, err)
+			}
+		}
+		switch email.TLS {
+		case "starttls", "tls", "none":
+		default:
+			return fmt.Errorf("notifications email tls must be starttls, tls or none")
+		}
+		if email.Digest.Enabled {
+			switch email.Digest.Day {
+			case "mon", "tue", "wed", "thu", "fri", "sat", "sun":
+			default:
+				return fmt.Errorf("notifications email digest day must be one of mon..sun")
+			}
+			if email.Digest.Hour < 0 || email.Digest.Hour > 23 {
+				return fmt.Errorf("notifications email digest hour must be between 0 and 23")
+			}
+		}
+	}
+
+	if c.Integrations.EKS.Enabled && (c.Integrations.EKS.ClusterRoleARN == "" || c.Integrations.EKS.NodeRoleARN == "" || len(c.Integrations.EKS.SubnetIDs) == 0) {
+		return fmt.Errorf("eks cluster_role_arn, node_role_arn and subnet_ids are required when eks is enabled")
+	}
+
+	if c.Integrations.AKS.Enabled && c.Integrations.AKS.ResourceGroup == "" {
+		return fmt.Errorf("aks resource_group is required when aks is enabled")
+	}
+
+	if (c.Integrations.EKS.Enabled || c.Integrations.GKE.Enabled || c.Integrations.AKS.Enabled) && !c.Integrations.Vault.Enabled {
+		return fmt.Errorf("direct cloud provisioning reads its credentials from vault, which must be enabled")
+	}
+
 	if c.Integrations.ArgoCD.Enabled && c.Integrations.ArgoCD.ServerURL == "" {
 		return fmt.Errorf("argocd server_url is required when argocd is enabled")
 	}
 
+	if c.Integrations.S3.Enabled && c.Integrations.S3.Bucket == "" {
+		return fmt.Errorf("s3 bucket is required when s3 is enabled")
+	}
+
+	if c.Integrations.Databases.Backup.Enabled && !c.Integrations.S3.Enabled {
+		return fmt.Errorf("database backups are stored in s3, which must be enabled")
+	}
+
+	if c.Integrations.ObjectStorage.Enabled && !c.Integrations.Vault.Enabled {
+		return fmt.Errorf("object storage keeps access keys in vault, which must be enabled")
+	}
+
+	if routes := c.Integrations.IngressRoutes; routes.Enabled && routes.Controller != "nginx" && routes.Controller != "traefik" {
+		return fmt.Errorf("ingress_routes controller must be nginx or traefik")
+	}
+
+	if lc := c.Integrations.EnvLifecycle; lc.Enabled && lc.PreviewIdle > 0 && !c.Integrations.Prometheus.Enabled {
+		return fmt.Errorf("environment_lifecycle preview_idle reads request metrics from prometheus, which must be enabled")
+	}
+
+	if jobs := c.Integrations.Jobs; jobs.Enabled && (jobs.DefaultTimeout <= 0 || jobs.MaxTimeout < jobs.DefaultTimeout) {
+		return fmt.Errorf("jobs default_timeout must be positive and no longer than max_timeout")
+	}
+
+	if approvals := c.Integrations.Approvals; approvals.Enabled {
+		if len(approvals.EnvironmentTypes) == 0 {
+			return fmt.Errorf("approvals environment_types is required when approvals are enabled")
+		}
+		for _, t := range approvals.EnvironmentTypes {
+			switch t {
+			case "development", "staging", "production", "preview":
+			default:
+				return fmt.Errorf("approvals environment_types must be development, staging, production or preview")
+			}
+		}
+		if approvals.EscalateAfter < 0 || approvals.Interval <= 0 {
+			return fmt.Errorf("approvals escalate_after must not be negative and interval must be positive")
+		}
+	}
+
+	if autoTLS := c.Integrations.AutoTLS; autoTLS.Enabled {
+		if autoTLS.Email == "" {
+			return fmt.Errorf("auto_tls email is required when auto_tls is enabled")
+		}
+		if autoTLS.Challenge != "http01" && autoTLS.Challenge != "dns01" {
+			return fmt.Errorf("auto_tls challenge must be http01 or dns01")
+		}
+		switch autoTLS.DNS01.Provider {
+		case "":
+			if autoTLS.Challenge == "dns01" {
+				return fmt.Errorf("auto_tls dns01 provider is required when dns01 is the default challenge")
+			}
+		case "cloudflare":
+			if autoTLS.DNS01.CloudflareAPIToken == "" {
+				return fmt.Errorf("auto_tls dns01 cloudflare_api_token is required for cloudflare")
+			}
+		case "route53":
+			if autoTLS.DNS01.Route53Region == "" {
+				return fmt.Errorf("auto_tls dns01 route53_region is required for route53")
+			}
+		default:
+			return fmt.Errorf("auto_tls dns01 provider must be cloudflare or route53")
+		}
+	}
+
 	if c.Auth.JWTSecret == "" {
 		return fmt.Errorf("auth.jwt_secret is required")
 	}
@@ -0,0 +1,327 @@
+// Package cronjobs runs cron job services as Kubernetes CronJobs in the
+// namespace of their project's environment on their target cluster, and reads
+// back their runs: the Jobs the CronJob controller starts on schedule and
+// those started on demand, with the exit codes and logs of their pods.
+package cronjobs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/internal/jobs"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Manager applies the CronJobs of cron job services and starts and reads
+// their runs
+type Manager struct {
+	config      *config.CronJobsConfig
+	kube        domain.KubernetesClient
+	serviceRepo domain.ServiceRepository
+	envRepo     domain.EnvironmentRepository
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewManager creates a new Manager. kube may be nil, in which case cron jobs
+// are unavailable.
+func NewManager(
+	cfg *config.CronJobsConfig,
+	kube domain.KubernetesClient,
+	serviceRepo domain.ServiceRepository,
+	envRepo domain.EnvironmentRepository,
+	projectRepo domain.ProjectRepository,
+	log *logger.Logger,
+) *Manager {
+	return &Manager{
+		config:      cfg,
+		kube:        kube,
+		serviceRepo: serviceRepo,
+		envRepo:     envRepo,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// Deployable reports whether a service has a CronJob to run: a schedule, a
+// target cluster and an image, either its own or one the platform built
+func Deployable(svc *domain.Service) bool {
+	return svc.Type == domain.ServiceTypeCronJob && svc.CronJob != nil && svc.TargetClusterID != nil &&
+		(svc.BuildSource.Image != "" || svc.CurrentVersion != "")
+}
+
+// Apply installs or updates the CronJob of a service. Services that cannot
+// run yet are skipped; the reconcile loop picks them up once they can.
+func (m *Manager) Apply(ctx context.Context, svc *domain.Service) error {
+	if !Deployable(svc) {
+		return nil
+	}
+	if m.kube == nil {
+		return errors.ErrNoKubernetes
+	}
+	namespace, err := m.namespace(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := json.Marshal(CronJob(svc, namespace))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode CronJob")
+	}
+	if err := m.kube.ApplyManifest(ctx, *svc.TargetClusterID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+
+	m.logger.Debug().Str("service_id", svc.ID.String()).Msg("Applied CronJob")
+	return nil
+}
+
+// RunNow starts a run of a service outside its schedule. It runs even while
+// the schedule is suspended.
+func (m *Manager) RunNow(ctx context.Context, svc *domain.Service) (*domain.CronJobRun, error) {
+	if !Deployable(svc) {
+		return nil, errors.BadRequest("the cron job is not deployed yet: it needs a schedule, a target cluster and an image")
+	}
+	if m.kube == nil {
+		return nil, errors.ErrNoKubernetes
+	}
+	namespace, err := m.namespace(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	cronJob, err := m.kube.GetResource(ctx, *svc.TargetClusterID, "CronJob", namespace, Name(svc))
+	if errors.IsNotFound(err) {
+		return nil, errors.BadRequest("the cron job is not deployed yet")
+	}
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	uid, _, _ := unstructured.NestedString(cronJob, "metadata", "uid")
+
+	name := ManualJobName(svc, time.Now())
+	manifest, err := json.Marshal(ManualJob(svc, namespace, name, uid))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode Job")
+	}
+	if err := m.kube.ApplyManifest(ctx, *svc.TargetClusterID, manifest); err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	m.logger.Info().Str("service_id", svc.ID.String()).Str("job", name).Msg("Started cron job run")
+	return &domain.CronJobRun{Name: name, Manual: true, Status: domain.JobPending}, nil
+}
+
+// Runs lists the runs of a service the cluster still keeps, newest first
+func (m *Manager) Runs(ctx context.Context, svc *domain.Service) ([]domain.CronJobRun, error) {
+	if !Deployable(svc) {
+		return []domain.CronJobRun{}, nil
+	}
+	if m.kube == nil {
+		return nil, errors.ErrNoKubernetes
+	}
+	namespace, err := m.namespace(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	selector := map[string]string{domain.LabelServiceID: svc.ID.String()}
+	jobs, err := m.kube.ListResources(ctx, *svc.TargetClusterID, "Job", namespace, selector)
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	pods, err := m.kube.ListResources(ctx, *svc.TargetClusterID, "Pod", namespace, selector)
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	return Runs(svc, jobs, pods), nil
+}
+
+// Logs returns the last lines logged by the latest attempt of a run, or the
+// configured number of lines when tailLines is 0
+func (m *Manager) Logs(ctx context.Context, svc *domain.Service, run string, tailLines int64) (string, error) {
+	if !Deployable(svc) {
+		return "", errors.NotFound("cron job run", run)
+	}
+	if m.kube == nil {
+		return "", errors.ErrNoKubernetes
+	}
+	namespace, err := m.namespace(ctx, svc)
+	if err != nil {
+		return "", err
+	}
+
+	pods, err := m.kube.ListResources(ctx, *svc.TargetClusterID, "Pod", namespace, map[string]string{
+		domain.LabelServiceID: svc.ID.String(),
+		jobs.LabelJobName:     run,
+	})
+	if err != nil {
+		return "", errors.DependencyFailed("kubernetes", err)
+	}
+	pod := jobs.LatestPod(pods)
+	if pod == nil {
+		return "", errors.NotFound("cron job run", run)
+	}
+	name, _, _ := unstructured.NestedString(pod, "metadata", "name")
+
+	if tailLines <= 0 {
+		tailLines = m.config.LogTailLines
+	}
+	logs, err := m.kube.GetPodLogs(ctx, *svc.TargetClusterID, namespace, name, svc.Slug, tailLines)
+	if err != nil {
+		return "", errors.DependencyFailed("kubernetes", err)
+	}
+	return logs, nil
+}
+
+// Run reconciles the CronJobs of every cron job service until ctx is
+// cancelled
+func (m *Manager) Run(ctx context.Context) {
+	if m.kube == nil {
+		m.logger.Warn().Msg("No Kubernetes client for workload clusters, cron jobs are not reconciled")
+		return
+	}
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.reconcile(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Watch applies the CronJob of a service as it is created, changed or
+// deployed, and removes it when the service is deleted. Events go through
+// durable consumers, so one replica handles each and failures are retried;
+// applying and removing are idempotent.
+func (m *Manager) Watch(ctx context.Context, bus domain.EventBus) error {
+	if m.kube == nil {
+		return nil
+	}
+	for _, subject := range []string{"service.created", "service.updated", "deploy.completed"} {
+		if _, err := eventbus.SubscribeDurable(ctx, bus, subject, eventbus.DurableName("cronjobs", subject), func(event *domain.Event) error {
+			return m.applyEvent(ctx, event)
+		}); err != nil {
+			return err
+		}
+	}
+	_, err := eventbus.SubscribeDurable(ctx, bus, "service.deleted", eventbus.DurableName("cronjobs", "service.deleted"), func(event *domain.Event) error {
+		return m.removeEvent(ctx, event)
+	})
+	return err
+}
+
+func (m *Manager) applyEvent(ctx context.Context, event *domain.Event) error {
+	raw, _ := event.Data["service_id"].(string)
+	serviceID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	svc, err := m.serviceRepo.GetByID(ctx, serviceID)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		m.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to load service for its CronJob")
+		return err
+	}
+	if err := m.Apply(ctx, svc); err != nil {
+		m.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to apply CronJob")
+		return err
+	}
+	return nil
+}
+
+// removeEvent deletes the CronJobs of a deleted service from the clusters of
+// its project's environments; its Jobs and their pods are garbage collected
+// with them
+func (m *Manager) removeEvent(ctx context.Context, event *domain.Event) error {
+	serviceID, _ := event.Data["service_id"].(string)
+	raw, _ := event.Data["project_id"].(string)
+	projectID, err := uuid.Parse(raw)
+	if err != nil || serviceID == "" {
+		return nil
+	}
+	environments, err := m.envRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		m.logger.Warn().Err(err).Str("service_id", serviceID).Msg("Failed to list environments to remove CronJob")
+		return err
+	}
+	var failed error
+	for _, env := range environments {
+		cronJobs, err := m.kube.ListResources(ctx, env.ClusterID, "CronJob", env.Namespace, map[string]string{
+			domain.LabelServiceID: serviceID,
+		})
+		if err != nil {
+			m.logger.Warn().Err(err).Str("service_id", serviceID).Msg("Failed to list CronJobs of deleted service")
+			failed = err
+			continue
+		}
+		for _, obj := range cronJobs {
+			name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+			if err := m.kube.DeleteResource(ctx, env.ClusterID, "CronJob", env.Namespace, name); err != nil && !errors.IsNotFound(err) {
+				m.logger.Warn().Err(err).Str("service_id", serviceID).Msg("Failed to remove CronJob")
+				failed = err
+			}
+		}
+	}
+	return failed
+}
+
+func (m *Manager) reconcile(ctx context.Context) {
+	projects, err := m.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list projects for cron jobs")
+		return
+	}
+	cronJob := domain.ServiceTypeCronJob
+	for _, project := range projects {
+		services, err := m.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{Type: &cronJob})
+		if err != nil {
+			m.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list cron job services")
+			continue
+		}
+		for _, svc := range services {
+			if err := m.Apply(ctx, svc); err != nil {
+				m.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Failed to apply CronJob")
+			}
+		}
+	}
+}
+
+// namespace is the namespace of the environment of the service's project on
+// its target cluster, the project default first
+func (m *Manager) namespace(ctx context.Context, svc *domain.Service) (string, error) {
+	environments, err := m.envRepo.ListByProject(ctx, svc.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	namespace := ""
+	for _, env := range environments {
+		if env.ClusterID != *svc.TargetClusterID {
+			continue
+		}
+		if env.IsDefault {
+			return env.Namespace, nil
+		}
+		if namespace == "" {
+			namespace = env.Namespace
+		}
+	}
+	if namespace == "" {
+		return "", errors.NotFound("environment on the target cluster of service", svc.ID.String())
+	}
+	return namespace, nil
+}
@@ -0,0 +1,41 @@
+package cronjobs
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagerWithoutKubernetes(t *testing.T) {
+	clusterID := uuid.New()
+	svc := &domain.Service{
+		ID:              uuid.New(),
+		Type:            domain.ServiceTypeCronJob,
+		CronJob:         &domain.CronJobConfig{Schedule: "0 * * * *"},
+		BuildSource:     domain.BuildSource{Image: "registry.example.com/shop/report"},
+		TargetClusterID: &clusterID,
+	}
+	m := NewManager(&config.CronJobsConfig{}, nil, nil, nil, nil, logger.New("error", "json", io.Discard))
+	ctx := context.Background()
+
+	// Agents are disabled: the API reports cron jobs as unavailable
+	for _, err := range []error{
+		m.Apply(ctx, svc),
+		func() error { _, err := m.RunNow(ctx, svc); return err }(),
+		func() error { _, err := m.Runs(ctx, svc); return err }(),
+		func() error { _, err := m.Logs(ctx, svc, "report-1", 0); return err }(),
+	} {
+		assert.ErrorIs(t, err, errors.ErrNoKubernetes)
+	}
+
+	// and nothing is reconciled in the background
+	assert.NoError(t, m.Watch(ctx, nil))
+	m.Run(ctx)
+}
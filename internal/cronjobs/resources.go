@@ -0,0 +1,235 @@
+package cronjobs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/jobs"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// annotationInstantiate marks the Jobs started on demand, as kubectl
+	// create job --from does
+	annotationInstantiate = "cronjob.kubernetes.io/instantiate"
+
+	// maxNameLength bounds the names of Jobs, which label their pods
+	maxNameLength = 63
+)
+
+type object = map[string]interface{}
+
+// Name is the name of the CronJob of a service
+func Name(svc *domain.Service) string {
+	return svc.Slug
+}
+
+// ManualJobName names a Job started on demand at now
+func ManualJobName(svc *domain.Service, now time.Time) string {
+	suffix := fmt.Sprintf("-manual-%d", now.Unix())
+	name := Name(svc)
+	if len(name)+len(suffix) > maxNameLength {
+		name = strings.TrimRight(name[:maxNameLength-len(suffix)], "-")
+	}
+	return name + suffix
+}
+
+// CronJob renders the CronJob of a cron job service. Each attempt of a run
+// gets its own pod (restartPolicy Never), so the exit code and logs of every
+// failed attempt stay readable until the run is cleaned up.
+func CronJob(svc *domain.Service, namespace string) object {
+	cfg := svc.CronJob
+	policy := cfg.ConcurrencyPolicy
+	if policy == "" {
+		policy = domain.CronConcurrencyForbid
+	}
+
+	spec := object{
+		"schedule":          strings.TrimSpace(cfg.Schedule),
+		"concurrencyPolicy": string(policy),
+		"suspend":           cfg.Suspend,
+		"jobTemplate": object{
+			"metadata": object{"labels": labels(svc)},
+			"spec":     JobSpec(svc),
+		},
+	}
+	if cfg.TimeZone != "" {
+		spec["timeZone"] = cfg.TimeZone
+	}
+	if cfg.StartingDeadlineSeconds > 0 {
+		spec["startingDeadlineSeconds"] = cfg.StartingDeadlineSeconds
+	}
+	if cfg.SuccessfulRunsKept > 0 {
+		spec["successfulJobsHistoryLimit"] = int64(cfg.SuccessfulRunsKept)
+	}
+	if cfg.FailedRunsKept > 0 {
+		spec["failedJobsHistoryLimit"] = int64(cfg.FailedRunsKept)
+	}
+
+	return object{
+		"apiVersion": "batch/v1",
+		"kind":       "CronJob",
+		"metadata": object{
+			"name":      Name(svc),
+			"namespace": namespace,
+			"labels":    labels(svc),
+		},
+		"spec": spec,
+	}
+}
+
+// JobSpec renders the spec of the Jobs of a cron job service
+func JobSpec(svc *domain.Service) object {
+	cfg := svc.CronJob
+
+	container := object{
+		"name":  svc.Slug,
+		"image": Image(svc),
+	}
+	if len(cfg.Command) > 0 {
+		container["command"] = cfg.Command
+	}
+	if len(svc.EnvVars) > 0 {
+		keys := make([]string, 0, len(svc.EnvVars))
+		for k := range svc.EnvVars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		env := make([]interface{}, 0, len(keys))
+		for _, k := range keys {
+			env = append(env, object{"name": k, "value": svc.EnvVars[k]})
+		}
+		container["env"] = env
+	}
+	if len(svc.SecretRefs) > 0 {
+		envFrom := make([]interface{}, 0, len(svc.SecretRefs))
+		for _, ref := range svc.SecretRefs {
+			envFrom = append(envFrom, object{"secretRef": object{"name": ref}})
+		}
+		container["envFrom"] = envFrom
+	}
+	if r := resources(svc.Resources); len(r) > 0 {
+		container["resources"] = r
+	}
+
+	spec := object{
+		"backoffLimit": int64(cfg.BackoffLimit),
+		"template": object{
+			"metadata": object{"labels": labels(svc)},
+			"spec": object{
+				"restartPolicy": "Never",
+				"containers":    []interface{}{container},
+			},
+		},
+	}
+	if cfg.ActiveDeadlineSeconds > 0 {
+		spec["activeDeadlineSeconds"] = cfg.ActiveDeadlineSeconds
+	}
+	return spec
+}
+
+// ManualJob renders a Job started on demand from the CronJob's template. It
+// is owned by the CronJob, which cleans it up with its scheduled runs.
+func ManualJob(svc *domain.Service, namespace, name, cronJobUID string) object {
+	metadata := object{
+		"name":        name,
+		"namespace":   namespace,
+		"labels":      labels(svc),
+		"annotations": object{annotationInstantiate: "manual"},
+	}
+	if cronJobUID != "" {
+		metadata["ownerReferences"] = []interface{}{object{
+			"apiVersion": "batch/v1",
+			"kind":       "CronJob",
+			"name":       Name(svc),
+			"uid":        cronJobUID,
+		}}
+	}
+	return object{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   metadata,
+		"spec":       JobSpec(svc),
+	}
+}
+
+// Image is the image reference cron job runs start from: the service's image
+// at its current version, or the image the platform built for it
+func Image(svc *domain.Service) string {
+	b := svc.BuildSource
+	ref := b.Image
+	if ref == "" {
+		ref = svc.Slug
+		if b.Registry != "" {
+			ref = strings.TrimRight(b.Registry, "/") + "/" + ref
+		}
+	}
+	if svc.CurrentVersion != "" && !strings.Contains(ref[strings.LastIndex(ref, "/")+1:], ":") && !strings.Contains(ref, "@") {
+		ref += ":" + svc.CurrentVersion
+	}
+	return ref
+}
+
+// Runs builds the runs of a service from its Jobs and their pods, newest
+// first. One-off jobs of the service are not runs of its schedule.
+func Runs(svc *domain.Service, jobList, pods []map[string]interface{}) []domain.CronJobRun {
+	podsByJob := jobs.PodsByJob(pods)
+
+	runs := make([]domain.CronJobRun, 0, len(jobList))
+	created := make(map[string]time.Time, len(jobList))
+	for _, job := range jobList {
+		if oneOff, _, _ := unstructured.NestedString(job, "metadata", "labels", jobs.LabelOneOff); oneOff == "true" {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(job, "metadata", "name")
+		state := jobs.Read(job, podsByJob[name], svc.Slug)
+		instantiate, _, _ := unstructured.NestedString(job, "metadata", "annotations", annotationInstantiate)
+		runs = append(runs, domain.CronJobRun{
+			Name:        state.Name,
+			Manual:      instantiate == "manual",
+			Status:      state.Status,
+			Pod:         state.Pod,
+			Attempts:    state.Attempts,
+			ExitCode:    state.ExitCode,
+			Reason:      state.Reason,
+			StartedAt:   state.StartedAt,
+			CompletedAt: state.CompletedAt,
+		})
+		created[state.Name] = state.CreatedAt
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return created[runs[i].Name].After(created[runs[j].Name])
+	})
+	return runs
+}
+
+// labels identify the objects of a service
+func labels(svc *domain.Service) object {
+	return object{
+		domain.LabelServiceID: svc.ID.String(),
+		domain.LabelProjectID: svc.ProjectID.String(),
+		domain.LabelManagedBy: domain.ManagedByValue,
+	}
+}
+
+// resources renders a service's requests and limits
+func resources(r domain.ResourceLimits) object {
+	out := object{}
+	set := func(kind, resource, value string) {
+		if value == "" {
+			return
+		}
+		if out[kind] == nil {
+			out[kind] = object{}
+		}
+		out[kind].(object)[resource] = value
+	}
+	set("requests", "cpu", r.CPURequest)
+	set("requests", "memory", r.MemoryRequest)
+	set("limits", "cpu", r.CPULimit)
+	set("limits", "memory", r.MemoryLimit)
+	return out
+}
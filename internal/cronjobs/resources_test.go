@@ -0,0 +1,135 @@
+package cronjobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/jobs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronJob(t *testing.T) {
+	svc := &domain.Service{
+		ID:             uuid.New(),
+		ProjectID:      uuid.New(),
+		Slug:           "report",
+		Type:           domain.ServiceTypeCronJob,
+		BuildSource:    domain.BuildSource{Image: "registry.example.com/shop/report"},
+		CurrentVersion: "v2",
+		EnvVars:        map[string]string{"B": "2", "A": "1"},
+		SecretRefs:     []string{"report-db"},
+		Resources:      domain.ResourceLimits{CPURequest: "100m", MemoryLimit: "256Mi"},
+		CronJob: &domain.CronJobConfig{
+			Schedule:                "0 3 * * *",
+			TimeZone:                "Europe/Berlin",
+			StartingDeadlineSeconds: 300,
+			ActiveDeadlineSeconds:   3600,
+			BackoffLimit:            2,
+			Command:                 []string{"/app/report", "--daily"},
+			FailedRunsKept:          5,
+		},
+	}
+
+	obj := CronJob(svc, "shop-production")
+	spec := obj["spec"].(object)
+	assert.Equal(t, "report", obj["metadata"].(object)["name"])
+	assert.Equal(t, "shop-production", obj["metadata"].(object)["namespace"])
+	assert.Equal(t, "0 3 * * *", spec["schedule"])
+	assert.Equal(t, "Europe/Berlin", spec["timeZone"])
+	assert.Equal(t, "Forbid", spec["concurrencyPolicy"])
+	assert.Equal(t, int64(300), spec["startingDeadlineSeconds"])
+	assert.Equal(t, int64(5), spec["failedJobsHistoryLimit"])
+	assert.NotContains(t, spec, "successfulJobsHistoryLimit")
+
+	jobSpec := spec["jobTemplate"].(object)["spec"].(object)
+	assert.Equal(t, int64(2), jobSpec["backoffLimit"])
+	assert.Equal(t, int64(3600), jobSpec["activeDeadlineSeconds"])
+	template := jobSpec["template"].(object)
+	assert.Equal(t, svc.ID.String(), template["metadata"].(object)["labels"].(object)[domain.LabelServiceID])
+	podSpec := template["spec"].(object)
+	assert.Equal(t, "Never", podSpec["restartPolicy"])
+	container := podSpec["containers"].([]interface{})[0].(object)
+	assert.Equal(t, "registry.example.com/shop/report:v2", container["image"])
+	assert.Equal(t, []string{"/app/report", "--daily"}, container["command"])
+	assert.Equal(t, []interface{}{object{"name": "A", "value": "1"}, object{"name": "B", "value": "2"}}, container["env"])
+	assert.Equal(t, []interface{}{object{"secretRef": object{"name": "report-db"}}}, container["envFrom"])
+
+	job := ManualJob(svc, "shop-production", "report-manual-1", "uid-1")
+	assert.Equal(t, "manual", job["metadata"].(object)["annotations"].(object)[annotationInstantiate])
+	assert.Equal(t, "uid-1", job["metadata"].(object)["ownerReferences"].([]interface{})[0].(object)["uid"])
+
+	svc.Slug = "a-very-long-service-name-that-almost-fills-a-kubernetes-name-x"
+	assert.LessOrEqual(t, len(ManualJobName(svc, time.Unix(1780000000, 0))), maxNameLength)
+}
+
+func TestRuns(t *testing.T) {
+	svc := &domain.Service{Slug: "report"}
+	jobList := []map[string]interface{}{
+		{
+			"metadata": map[string]interface{}{"name": "report-29000000", "creationTimestamp": "2026-06-01T03:00:00Z"},
+			"status": map[string]interface{}{
+				"startTime":      "2026-06-01T03:00:01Z",
+				"completionTime": "2026-06-01T03:02:00Z",
+				"succeeded":      int64(1),
+				"conditions":     []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}},
+			},
+		},
+		{
+			"metadata": map[string]interface{}{
+				"name":              "report-manual-1780300000",
+				"creationTimestamp": "2026-06-01T09:00:00Z",
+				"annotations":       map[string]interface{}{annotationInstantiate: "manual"},
+			},
+			"status": map[string]interface{}{
+				"startTime":  "2026-06-01T09:00:01Z",
+				"failed":     int64(2),
+				"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded", "lastTransitionTime": "2026-06-01T09:05:00Z"}},
+			},
+		},
+		{
+			"metadata": map[string]interface{}{"name": "report-29000060", "creationTimestamp": "2026-06-02T03:00:00Z"},
+			"status":   map[string]interface{}{"startTime": "2026-06-02T03:00:01Z", "active": int64(1)},
+		},
+	}
+	pod := func(name, job, created string, exitCode int64) map[string]interface{} {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "creationTimestamp": created, "labels": map[string]interface{}{jobs.LabelJobName: job}},
+			"status": map[string]interface{}{"containerStatuses": []interface{}{map[string]interface{}{
+				"name":  "report",
+				"state": map[string]interface{}{"terminated": map[string]interface{}{"exitCode": exitCode, "reason": "Error"}},
+			}}},
+		}
+	}
+	pods := []map[string]interface{}{
+		pod("report-manual-1780300000-a", "report-manual-1780300000", "2026-06-01T09:00:01Z", 1),
+		pod("report-manual-1780300000-b", "report-manual-1780300000", "2026-06-01T09:02:00Z", 137),
+		pod("report-29000000-a", "report-29000000", "2026-06-01T03:00:01Z", 0),
+	}
+
+	runs := Runs(svc, jobList, pods)
+	require.Len(t, runs, 3)
+
+	assert.Equal(t, "report-29000060", runs[0].Name)
+	assert.Equal(t, domain.JobRunning, runs[0].Status)
+	assert.Nil(t, runs[0].ExitCode)
+
+	failed := runs[1]
+	assert.True(t, failed.Manual)
+	assert.Equal(t, domain.JobFailed, failed.Status)
+	assert.Equal(t, "BackoffLimitExceeded", failed.Reason)
+	assert.Equal(t, int32(2), failed.Attempts)
+	assert.Equal(t, "report-manual-1780300000-b", failed.Pod)
+	require.NotNil(t, failed.ExitCode)
+	assert.Equal(t, int32(137), *failed.ExitCode)
+	require.NotNil(t, failed.CompletedAt)
+
+	succeeded := runs[2]
+	assert.False(t, succeeded.Manual)
+	assert.Equal(t, domain.JobSucceeded, succeeded.Status)
+	require.NotNil(t, succeeded.ExitCode)
+	assert.Equal(t, int32(0), *succeeded.ExitCode)
+	assert.Empty(t, succeeded.Reason)
+}
@@ -0,0 +1,155 @@
+package cronjobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+const (
+	// maxBackoffLimit bounds the retries of a failed run
+	maxBackoffLimit = 10
+	// maxRunsKept bounds the finished runs kept per outcome
+	maxRunsKept = 100
+)
+
+// descriptors are the schedule shorthands the CronJob controller accepts
+var descriptors = map[string]bool{
+	"@yearly":   true,
+	"@annually": true,
+	"@monthly":  true,
+	"@weekly":   true,
+	"@daily":    true,
+	"@midnight": true,
+	"@hourly":   true,
+}
+
+// field is one field of a five-field cron expression
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+	anyMark  bool // Accepts ? for any value
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31, anyMark: true},
+	{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}},
+	{name: "day of week", min: 0, max: 6, anyMark: true, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}},
+}
+
+// Validate checks the cron job settings of a service
+func Validate(cfg *domain.CronJobConfig) error {
+	if cfg == nil {
+		return errors.BadRequest("cron_job is required for cron job services")
+	}
+	if err := validateSchedule(cfg.Schedule); err != nil {
+		return errors.BadRequest("schedule: " + err.Error())
+	}
+	if cfg.TimeZone != "" {
+		if _, err := time.LoadLocation(cfg.TimeZone); err != nil {
+			return errors.BadRequest(fmt.Sprintf("unknown time_zone %q", cfg.TimeZone))
+		}
+	}
+	switch cfg.ConcurrencyPolicy {
+	case "", domain.CronConcurrencyAllow, domain.CronConcurrencyForbid, domain.CronConcurrencyReplace:
+	default:
+		return errors.BadRequest("concurrency_policy must be Allow, Forbid or Replace")
+	}
+	if cfg.StartingDeadlineSeconds < 0 || cfg.ActiveDeadlineSeconds < 0 {
+		return errors.BadRequest("deadlines cannot be negative")
+	}
+	if cfg.BackoffLimit < 0 || cfg.BackoffLimit > maxBackoffLimit {
+		return errors.BadRequest(fmt.Sprintf("backoff_limit must be between 0 and %d", maxBackoffLimit))
+	}
+	if cfg.SuccessfulRunsKept < 0 || cfg.SuccessfulRunsKept > maxRunsKept || cfg.FailedRunsKept < 0 || cfg.FailedRunsKept > maxRunsKept {
+		return errors.BadRequest(fmt.Sprintf("runs kept must be between 0 and %d", maxRunsKept))
+	}
+	return nil
+}
+
+// validateSchedule checks a standard five-field cron expression or one of
+// the @ descriptors. Time zones are set apart, as Kubernetes rejects TZ=
+// prefixes in schedules.
+func validateSchedule(schedule string) error {
+	schedule = strings.TrimSpace(schedule)
+	if schedule == "" {
+		return fmt.Errorf("is required")
+	}
+	if strings.HasPrefix(schedule, "TZ=") || strings.HasPrefix(schedule, "CRON_TZ=") {
+		return fmt.Errorf("set the time zone with time_zone instead of a TZ= prefix")
+	}
+	if strings.HasPrefix(schedule, "@") {
+		if !descriptors[schedule] {
+			return fmt.Errorf("unknown descriptor %q", schedule)
+		}
+		return nil
+	}
+
+	parts := strings.Fields(schedule)
+	if len(parts) != len(fields) {
+		return fmt.Errorf("must have %d fields: minute hour day-of-month month day-of-week", len(fields))
+	}
+	for i, part := range parts {
+		if err := fields[i].validate(part); err != nil {
+			return fmt.Errorf("%s: %w", fields[i].name, err)
+		}
+	}
+	return nil
+}
+
+// validate checks a field: a comma-separated list of *, values and ranges,
+// each optionally stepped with /n
+func (f field) validate(spec string) error {
+	for _, item := range strings.Split(spec, ",") {
+		base, step, stepped := strings.Cut(item, "/")
+		if stepped {
+			n, err := strconv.Atoi(step)
+			if err != nil || n < 1 || n > f.max {
+				return fmt.Errorf("invalid step %q", step)
+			}
+		}
+
+		if base == "*" || (base == "?" && f.anyMark) {
+			continue
+		}
+		from, to, isRange := strings.Cut(base, "-")
+		lo, err := f.value(from)
+		if err != nil {
+			return err
+		}
+		hi := lo
+		if isRange {
+			if hi, err = f.value(to); err != nil {
+				return err
+			}
+			if hi < lo {
+				return fmt.Errorf("range %q is reversed", base)
+			}
+		}
+	}
+	return nil
+}
+
+// value parses a number or name within the field's range
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%q is not between %d and %d", s, f.min, f.max)
+	}
+	return v, nil
+}
@@ -0,0 +1,34 @@
+package cronjobs
+
+import (
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	for _, schedule := range []string{"*/5 * * * *", "0 3 * * mon-fri", "30 2 1,15 * ?", "0 0 1 jan,jul *", "@hourly"} {
+		assert.NoError(t, Validate(&domain.CronJobConfig{Schedule: schedule}), schedule)
+	}
+
+	invalid := []domain.CronJobConfig{
+		{Schedule: ""},
+		{Schedule: "* * * *"},
+		{Schedule: "60 * * * *"},
+		{Schedule: "0 5-1 * * *"},
+		{Schedule: "*/0 * * * *"},
+		{Schedule: "? * * * *"},
+		{Schedule: "@every 5m"},
+		{Schedule: "TZ=UTC 0 * * * *"},
+		{Schedule: "0 * * * *", TimeZone: "Mars/Olympus"},
+		{Schedule: "0 * * * *", ConcurrencyPolicy: "Sometimes"},
+		{Schedule: "0 * * * *", BackoffLimit: 11},
+		{Schedule: "0 * * * *", ActiveDeadlineSeconds: -1},
+		{Schedule: "0 * * * *", FailedRunsKept: 101},
+	}
+	for _, cfg := range invalid {
+		assert.Error(t, Validate(&cfg), cfg.Schedule)
+	}
+	assert.Error(t, Validate(nil))
+}
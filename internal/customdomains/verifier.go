@@ -0,0 +1,309 @@
+// Package customdomains verifies that a project controls the custom domains
+// its ingresses route. A project claims a domain and gets a challenge: a TXT
+// record, or a CNAME into the platform's verification zone, under
+// _northstack-challenge.<domain>. Pending domains are checked in DNS until the
+// record appears or the claim expires. Only a domain verified by the
+// ingress's project, or one of its parents, can be routed, so a tenant cannot
+// take over a hostname that another tenant or an outside party serves.
+package customdomains
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// RecordPrefix is the label challenge records are published under
+const RecordPrefix = "_northstack-challenge"
+
+// txtPrefix starts the value of TXT challenges
+const txtPrefix = "northstack-verification="
+
+// resolver looks up challenge records; *net.Resolver in production
+type resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+}
+
+// Verifier claims custom domains for projects and verifies them in DNS
+type Verifier struct {
+	config   *config.DomainsConfig
+	repo     domain.DomainRepository
+	resolver resolver
+	eventBus domain.EventBus
+	logger   *logger.Logger
+}
+
+// NewVerifier creates a new Verifier
+func NewVerifier(cfg *config.DomainsConfig, repo domain.DomainRepository, eventBus domain.EventBus, log *logger.Logger) *Verifier {
+	r := net.DefaultResolver
+	if cfg.Nameserver != "" {
+		r = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, cfg.Nameserver)
+			},
+		}
+	}
+	return &Verifier{
+		config:   cfg,
+		repo:     repo,
+		resolver: r,
+		eventBus: eventBus,
+		logger:   log,
+	}
+}
+
+// Claim adds a pending custom domain to a project. Several projects may claim
+// the same name, each with its own token; only one can verify it.
+func (v *Verifier) Claim(ctx context.Context, projectID, userID uuid.UUID, name string, method domain.DomainVerificationMethod) (*domain.CustomDomain, error) {
+	name = Normalize(name)
+	switch method {
+	case "":
+		method = domain.DomainVerificationTXT
+	case domain.DomainVerificationTXT:
+	case domain.DomainVerificationCNAME:
+		if v.config.CNAMETarget == "" {
+			return nil, errors.BadRequest("cname verification is not available; use txt")
+		}
+	default:
+		return nil, errors.BadRequest(fmt.Sprintf("method must be %s or %s", domain.DomainVerificationTXT, domain.DomainVerificationCNAME))
+	}
+	if v.platformDomain(name) {
+		return nil, errors.BadRequest(fmt.Sprintf("%s is served by the platform and needs no verification", name))
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate verification token")
+	}
+
+	now := time.Now()
+	d := &domain.CustomDomain{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		Name:      name,
+		Method:    method,
+		Token:     token,
+		Status:    domain.DomainStatusPending,
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := v.repo.Create(ctx, d); err != nil {
+		return nil, err
+	}
+	d.Record = v.Challenge(d)
+	return d, nil
+}
+
+// Challenge is the DNS record that proves control of a domain
+func (v *Verifier) Challenge(d *domain.CustomDomain) *domain.DNSRecord {
+	name := RecordPrefix + "." + d.Name
+	if d.Method == domain.DomainVerificationCNAME {
+		return &domain.DNSRecord{Type: "CNAME", Name: name, Value: d.Token + "." + strings.TrimSuffix(v.config.CNAMETarget, ".")}
+	}
+	return &domain.DNSRecord{Type: "TXT", Name: name, Value: txtPrefix + d.Token}
+}
+
+// Verify checks the challenge of a domain in DNS now and stores the outcome.
+// A domain that is not verified keeps its status with the reason as message.
+func (v *Verifier) Verify(ctx context.Context, d *domain.CustomDomain) error {
+	if d.Status == domain.DomainStatusVerified {
+		return nil
+	}
+	now := time.Now()
+	d.CheckedAt = &now
+
+	found, err := v.lookup(ctx, d)
+	switch {
+	case err != nil:
+		d.Message = err.Error()
+	case !found:
+		record := v.Challenge(d)
+		d.Message = fmt.Sprintf("%s record %s not found", record.Type, record.Name)
+	default:
+		d.Message, err = v.claimedElsewhere(ctx, d)
+		if err != nil {
+			return err
+		}
+		if d.Message == "" {
+			d.Status = domain.DomainStatusVerified
+			d.VerifiedAt = &now
+		}
+	}
+
+	if err := v.repo.Update(ctx, d); err != nil {
+		return err
+	}
+	if d.Status == domain.DomainStatusVerified {
+		v.publish(ctx, "domain.verified", d)
+	}
+	return nil
+}
+
+// Check fails with 403 unless a project may route a host: the host is a
+// platform domain or is covered by one of the project's verified domains.
+// A nil Verifier allows every host.
+func (v *Verifier) Check(ctx context.Context, projectID uuid.UUID, host string) error {
+	if v == nil {
+		return nil
+	}
+	host = Normalize(host)
+	if v.platformDomain(host) {
+		return nil
+	}
+	domains, err := v.repo.ListByProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	for _, d := range domains {
+		if d.Covers(host) {
+			return nil
+		}
+	}
+	return errors.Forbidden(fmt.Sprintf("%s is not a verified domain of this project; add it to the project's domains and publish its challenge record", host))
+}
+
+// Run checks pending domains until ctx is cancelled. Domains still pending
+// after the configured expiry fail.
+func (v *Verifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(v.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		v.checkPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (v *Verifier) checkPending(ctx context.Context) {
+	domains, err := v.repo.ListByStatus(ctx, domain.DomainStatusPending)
+	if err != nil {
+		v.logger.Warn().Err(err).Msg("Failed to list pending domains")
+		return
+	}
+	for _, d := range domains {
+		if err := v.Verify(ctx, d); err != nil {
+			v.logger.Warn().Err(err).Str("domain", d.Name).Msg("Failed to verify domain")
+			continue
+		}
+		if d.Status == domain.DomainStatusPending && time.Since(d.CreatedAt) > v.config.Expiry {
+			d.Status = domain.DomainStatusFailed
+			if err := v.repo.Update(ctx, d); err != nil {
+				v.logger.Warn().Err(err).Str("domain", d.Name).Msg("Failed to expire domain")
+				continue
+			}
+			v.publish(ctx, "domain.verification_failed", d)
+		}
+	}
+}
+
+// lookup reports whether the challenge record of a domain is published.
+// Missing records are not errors.
+func (v *Verifier) lookup(ctx context.Context, d *domain.CustomDomain) (bool, error) {
+	record := v.Challenge(d)
+	var err error
+	if d.Method == domain.DomainVerificationCNAME {
+		var target string
+		if target, err = v.resolver.LookupCNAME(ctx, record.Name); err == nil {
+			return strings.EqualFold(strings.TrimSuffix(target, "."), record.Value), nil
+		}
+	} else {
+		var values []string
+		if values, err = v.resolver.LookupTXT(ctx, record.Name); err == nil {
+			for _, value := range values {
+				if strings.TrimSpace(value) == record.Value {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if stderrors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false, nil
+	}
+	return false, fmt.Errorf("DNS lookup of %s failed: %w", record.Name, err)
+}
+
+// claimedElsewhere explains why a domain whose record is published cannot be
+// verified: another project already verified it
+func (v *Verifier) claimedElsewhere(ctx context.Context, d *domain.CustomDomain) (string, error) {
+	claims, err := v.repo.ListByName(ctx, d.Name)
+	if err != nil {
+		return "", err
+	}
+	for _, claim := range claims {
+		if claim.ID != d.ID && claim.Status == domain.DomainStatusVerified {
+			return "the domain is verified by another project, which must remove it first", nil
+		}
+	}
+	return "", nil
+}
+
+func (v *Verifier) platformDomain(host string) bool {
+	for _, platform := range v.config.PlatformDomains {
+		platform = Normalize(platform)
+		if host == platform || strings.HasSuffix(host, "."+platform) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verifier) publish(ctx context.Context, eventType string, d *domain.CustomDomain) {
+	v.logger.Info().
+		Str("domain", d.Name).
+		Str("project_id", d.ProjectID.String()).
+		Str("status", string(d.Status)).
+		Msg("Custom domain verification finished")
+
+	if v.eventBus == nil {
+		return
+	}
+	event := &domain.Event{
+		Type:   eventType,
+		Source: "customdomains",
+		Data: map[string]interface{}{
+			"domain_id":  d.ID.String(),
+			"project_id": d.ProjectID.String(),
+			"domain":     d.Name,
+			"message":    d.Message,
+		},
+	}
+	if err := v.eventBus.Publish(ctx, eventType, event); err != nil {
+		v.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// Normalize lowercases a domain name and drops its trailing dot
+func Normalize(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,102 @@
+package customdomains
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver struct {
+	txt   map[string][]string
+	cname map[string]string
+}
+
+func (r *fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if values, ok := r.txt[name]; ok {
+		return values, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupCNAME(_ context.Context, host string) (string, error) {
+	if target, ok := r.cname[host]; ok {
+		return target, nil
+	}
+	return "", &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+type fakeRepo struct {
+	domain.DomainRepository
+	domains []*domain.CustomDomain
+}
+
+func (r *fakeRepo) ListByProject(_ context.Context, projectID uuid.UUID) ([]*domain.CustomDomain, error) {
+	var domains []*domain.CustomDomain
+	for _, d := range r.domains {
+		if d.ProjectID == projectID {
+			domains = append(domains, d)
+		}
+	}
+	return domains, nil
+}
+
+func TestChallenge(t *testing.T) {
+	v := &Verifier{config: &config.DomainsConfig{CNAMETarget: "verify.northstack.example."}}
+	d := &domain.CustomDomain{Name: "example.com", Method: domain.DomainVerificationTXT, Token: "abc"}
+
+	assert.Equal(t, &domain.DNSRecord{Type: "TXT", Name: "_northstack-challenge.example.com", Value: "northstack-verification=abc"}, v.Challenge(d))
+
+	d.Method = domain.DomainVerificationCNAME
+	assert.Equal(t, &domain.DNSRecord{Type: "CNAME", Name: "_northstack-challenge.example.com", Value: "abc.verify.northstack.example"}, v.Challenge(d))
+}
+
+func TestLookup(t *testing.T) {
+	r := &fakeResolver{
+		txt:   map[string][]string{"_northstack-challenge.example.com": {"google-site-verification=x", "northstack-verification=abc"}},
+		cname: map[string]string{"_northstack-challenge.example.org": "ABC.verify.northstack.example."},
+	}
+	v := &Verifier{config: &config.DomainsConfig{CNAMETarget: "verify.northstack.example"}, resolver: r}
+
+	found, err := v.lookup(context.Background(), &domain.CustomDomain{Name: "example.com", Method: domain.DomainVerificationTXT, Token: "abc"})
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = v.lookup(context.Background(), &domain.CustomDomain{Name: "example.com", Method: domain.DomainVerificationTXT, Token: "other"})
+	require.NoError(t, err)
+	assert.False(t, found) // Another project's token
+
+	found, err = v.lookup(context.Background(), &domain.CustomDomain{Name: "example.org", Method: domain.DomainVerificationCNAME, Token: "abc"})
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = v.lookup(context.Background(), &domain.CustomDomain{Name: "example.net", Method: domain.DomainVerificationTXT, Token: "abc"})
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestCheck(t *testing.T) {
+	projectID := uuid.New()
+	repo := &fakeRepo{domains: []*domain.CustomDomain{
+		{ProjectID: projectID, Name: "example.com", Status: domain.DomainStatusVerified},
+		{ProjectID: projectID, Name: "example.org", Status: domain.DomainStatusPending},
+		{ProjectID: uuid.New(), Name: "example.net", Status: domain.DomainStatusVerified},
+	}}
+	v := &Verifier{config: &config.DomainsConfig{PlatformDomains: []string{"apps.northstack.example"}}, repo: repo}
+	ctx := context.Background()
+
+	assert.NoError(t, v.Check(ctx, projectID, "example.com"))
+	assert.NoError(t, v.Check(ctx, projectID, "API.example.com."))
+	assert.NoError(t, v.Check(ctx, projectID, "web.apps.northstack.example"))
+	assert.Error(t, v.Check(ctx, projectID, "badexample.com"))
+	assert.Error(t, v.Check(ctx, projectID, "example.org")) // Pending
+	assert.Error(t, v.Check(ctx, projectID, "example.net")) // Another project's
+
+	var disabled *Verifier
+	assert.NoError(t, disabled.Check(ctx, projectID, "example.net"))
+}
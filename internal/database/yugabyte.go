@@ -7,6 +7,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/tracing"
 )
 
 type YugabyteDB struct {
@@ -33,6 +34,7 @@ func NewYugabyteDB(cfg config.YugabyteDBConfig) (*YugabyteDB, error) {
 	poolConfig.MinConns = cfg.MinConns
 	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
 	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
 
 	// Multi-host / HA logic could be enhanced here by parsing cfg.Host if it contains multiple IPs
 	// For standard pgx, it supports connection strings with multiple hosts
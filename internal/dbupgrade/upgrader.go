@@ -0,0 +1,565 @@
+// Package dbupgrade orchestrates minor-version upgrades of managed YugabyteDB
+// databases: a full backup is taken first, then the operator rolls the
+// masters and the tablet servers one pod at a time. Upgrades start right away
+// or when their maintenance window next opens. The state of a database's
+// latest upgrade is kept in an annotation of its cluster.
+package dbupgrade
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/northstack/platform/pkg/yugabytedb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// stepTimeout bounds how long a step gets to finish
+const stepTimeout = time.Hour
+
+// pollInterval is the wait between checks of a step's completion, and of
+// maintenance windows opening
+var pollInterval = 30 * time.Second
+
+// Steps of an upgrade, in order
+const (
+	StepBackup   = "backup"
+	StepMasters  = "masters"
+	StepTServers = "tservers"
+)
+
+// Status is how far an upgrade, or a step of it, got
+type Status string
+
+const (
+	StatusScheduled Status = "scheduled" // Waiting for its maintenance window
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusDone      Status = "done"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Step is one step of an upgrade
+type Step struct {
+	Name        string     `json:"name"`
+	Status      Status     `json:"status"`
+	Message     string     `json:"message,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Progress is the state of a database's latest upgrade
+type Progress struct {
+	FromVersion string     `json:"from_version"`
+	ToVersion   string     `json:"to_version"`
+	Status      Status     `json:"status"`
+	Window      *Window    `json:"maintenance_window,omitempty"`
+	BackupID    string     `json:"backup_id,omitempty"`
+	Steps       []Step     `json:"steps"`
+	Error       string     `json:"error,omitempty"`
+	RequestedAt time.Time  `json:"requested_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// active reports whether an upgrade is scheduled or running
+func (p *Progress) active() bool {
+	return p != nil && (p.Status == StatusScheduled || p.Status == StatusRunning)
+}
+
+// Upgrader upgrades YugabyteDB databases
+type Upgrader struct {
+	databases *yugabytedb.DatabaseService
+	versions  []string // Offered versions; discovered from the image registry when empty
+	eventBus  domain.EventBus
+	logger    *logger.Logger
+
+	mu sync.Mutex // Serializes upgrade starts and progress updates
+}
+
+// NewUpgrader creates a new Upgrader. Without versions, the versions of a
+// database's release series are discovered from Docker Hub. Without an event
+// bus no events are published. A nil Upgrader allows every change.
+func NewUpgrader(databases *yugabytedb.DatabaseService, versions []string, eventBus domain.EventBus, log *logger.Logger) *Upgrader {
+	return &Upgrader{
+		databases: databases,
+		versions:  versions,
+		eventBus:  eventBus,
+		logger:    log,
+	}
+}
+
+// Versions returns the current version of a database and the newer versions
+// of its release series it can be upgraded to, oldest first
+func (u *Upgrader) Versions(ctx context.Context, databaseID string) (string, []string, error) {
+	db, err := u.databases.GetDatabase(ctx, databaseID)
+	if err != nil {
+		return "", nil, notFound(err, databaseID)
+	}
+
+	series := yugabytedb.ReleaseSeries(db.Version)
+	candidates := u.versions
+	if len(candidates) == 0 {
+		if candidates, err = u.databases.ReleaseVersions(ctx, db.Version); err != nil {
+			return "", nil, err
+		}
+	}
+	available := []string{}
+	for _, version := range candidates {
+		if yugabytedb.ReleaseSeries(version) == series && yugabytedb.CompareVersions(version, db.Version) > 0 {
+			available = append(available, version)
+		}
+	}
+	yugabytedb.SortVersions(available)
+	return db.Version, available, nil
+}
+
+// Progress returns the latest upgrade of a database, or nil
+func (u *Upgrader) Progress(ctx context.Context, databaseID string) (*Progress, error) {
+	state, err := u.databases.UpgradeState(ctx, databaseID)
+	if err != nil {
+		return nil, notFound(err, databaseID)
+	}
+	return parseProgress(state), nil
+}
+
+// Start upgrades a database to a newer version of its release series, right
+// away or when a maintenance window next opens. Upgrades need backups, as a
+// full backup is taken first.
+func (u *Upgrader) Start(ctx context.Context, databaseID, version string, window *Window) (*Progress, error) {
+	if !u.databases.BackupsEnabled() {
+		return nil, errors.BadRequest("upgrades take a backup first and need database backups to be enabled")
+	}
+	if window != nil {
+		if err := window.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	current, err := u.Progress(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if current.active() {
+		return nil, errors.NewError(errors.CodeConflict, fmt.Sprintf("database is already being upgraded to %s", current.ToVersion), http.StatusConflict)
+	}
+	from, versions, err := u.Versions(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if !contains(versions, version) {
+		return nil, errors.BadRequest(fmt.Sprintf("database cannot be upgraded from %s to %s; available versions are %v", from, version, versions))
+	}
+
+	now := time.Now()
+	progress := &Progress{
+		FromVersion: from,
+		ToVersion:   version,
+		Status:      StatusScheduled,
+		Window:      window,
+		RequestedAt: now,
+		Steps: []Step{
+			{Name: StepBackup, Status: StatusPending},
+			{Name: StepMasters, Status: StatusPending},
+			{Name: StepTServers, Status: StatusPending},
+		},
+	}
+	start := window == nil || window.Open(now)
+	if start {
+		progress.Status = StatusRunning
+		progress.StartedAt = &now
+	}
+	if err := u.store(ctx, databaseID, progress); err != nil {
+		return nil, err
+	}
+
+	u.logger.Info().
+		Str("database_id", databaseID).
+		Str("from", from).
+		Str("to", version).
+		Str("status", string(progress.Status)).
+		Msg("Database upgrade requested")
+	if start {
+		u.publish(ctx, "database.upgrade_started", databaseID, progress)
+		go u.run(context.Background(), databaseID)
+	} else {
+		u.publish(ctx, "database.upgrade_scheduled", databaseID, progress)
+	}
+	return progress, nil
+}
+
+// Cancel cancels an upgrade still waiting for its maintenance window
+func (u *Upgrader) Cancel(ctx context.Context, databaseID string) (*Progress, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	progress, err := u.Progress(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil || progress.Status != StatusScheduled {
+		return nil, errors.BadRequest("only scheduled upgrades can be cancelled")
+	}
+	now := time.Now()
+	progress.Status = StatusCancelled
+	progress.CompletedAt = &now
+	if err := u.store(ctx, databaseID, progress); err != nil {
+		return nil, err
+	}
+	u.publish(ctx, "database.upgrade_cancelled", databaseID, progress)
+	return progress, nil
+}
+
+// Run starts scheduled upgrades when their maintenance windows open, until
+// ctx is done
+func (u *Upgrader) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			u.startScheduled(ctx, now)
+		}
+	}
+}
+
+// startScheduled starts the scheduled upgrades whose windows are open
+func (u *Upgrader) startScheduled(ctx context.Context, now time.Time) {
+	states, err := u.databases.UpgradeStates(ctx)
+	if err != nil {
+		u.logger.Warn().Err(err).Msg("Failed to list database upgrades")
+		return
+	}
+	for databaseID, state := range states {
+		progress := parseProgress(state)
+		if progress == nil || progress.Status != StatusScheduled || progress.Window == nil || !progress.Window.Open(now) {
+			continue
+		}
+		started, err := u.update(ctx, databaseID, func(p *Progress) {
+			// Cancelled since it was listed
+			if p.Status != StatusScheduled {
+				return
+			}
+			p.Status = StatusRunning
+			p.StartedAt = &now
+		})
+		if err != nil {
+			u.logger.Error().Err(err).Str("database_id", databaseID).Msg("Failed to start scheduled database upgrade")
+			continue
+		}
+		if started.Status != StatusRunning {
+			continue
+		}
+		u.logger.Info().Str("database_id", databaseID).Str("to", started.ToVersion).Msg("Maintenance window opened, database upgrade started")
+		u.publish(ctx, "database.upgrade_started", databaseID, started)
+		go u.run(context.Background(), databaseID)
+	}
+}
+
+// run performs the pending steps of an upgrade in order, stopping at the
+// first failure
+func (u *Upgrader) run(ctx context.Context, databaseID string) {
+	progress, err := u.Progress(ctx, databaseID)
+	if err != nil || progress == nil {
+		u.logger.Error().Err(err).Str("database_id", databaseID).Msg("Failed to load database upgrade")
+		return
+	}
+
+	for i, step := range progress.Steps {
+		if step.Status != StatusPending {
+			continue
+		}
+		u.setStep(ctx, databaseID, i, StatusRunning, "")
+
+		switch step.Name {
+		case StepBackup:
+			err = u.backup(ctx, databaseID)
+		case StepMasters:
+			if err = u.databases.SetVersion(ctx, databaseID, progress.ToVersion); err == nil {
+				err = u.waitRollout(ctx, databaseID, yugabytedb.ComponentMaster, progress.ToVersion)
+			}
+		case StepTServers:
+			err = u.waitRollout(ctx, databaseID, yugabytedb.ComponentTServer, progress.ToVersion)
+		}
+		if err != nil {
+			u.logger.Error().Err(err).
+				Str("database_id", databaseID).
+				Str("step", step.Name).
+				Msg("Database upgrade step failed")
+			u.setStep(ctx, databaseID, i, StatusFailed, err.Error())
+			u.finish(ctx, databaseID, err.Error())
+			return
+		}
+		u.setStep(ctx, databaseID, i, StatusDone, "")
+	}
+	u.finish(ctx, databaseID, "")
+}
+
+// backup takes a full backup and waits for it to complete
+func (u *Upgrader) backup(ctx context.Context, databaseID string) error {
+	backup, err := u.databases.CreateBackup(ctx, databaseID)
+	if err != nil {
+		return err
+	}
+	if _, err := u.update(ctx, databaseID, func(p *Progress) { p.BackupID = backup.ID }); err != nil {
+		return err
+	}
+
+	return wait(ctx, func() (bool, error) {
+		backups, err := u.databases.ListBackups(ctx, databaseID)
+		if err != nil {
+			return false, err
+		}
+		for _, b := range backups {
+			if b.ID != backup.ID {
+				continue
+			}
+			switch b.Status {
+			case yugabytedb.BackupCompleted:
+				return true, nil
+			case yugabytedb.BackupFailed:
+				return false, &failure{fmt.Sprintf("pre-upgrade backup %s failed: %s", b.ID, b.Message)}
+			}
+		}
+		return false, nil
+	})
+}
+
+// waitRollout waits until every pod of a component runs the new version and
+// is ready
+func (u *Upgrader) waitRollout(ctx context.Context, databaseID, component, version string) error {
+	return wait(ctx, func() (bool, error) {
+		return u.databases.RolledOut(ctx, databaseID, component, version)
+	})
+}
+
+// failure is an error of a step that retrying cannot fix
+type failure struct {
+	message string
+}
+
+func (f *failure) Error() string {
+	return f.message
+}
+
+// wait polls done until it holds, reports a failure or the step times out.
+// Other errors are retried.
+func wait(ctx context.Context, done func() (bool, error)) error {
+	deadline := time.Now().Add(stepTimeout)
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+
+		finished, err := done()
+		var failed *failure
+		switch {
+		case err == nil && finished:
+			return nil
+		case stderrors.As(err, &failed):
+			return err
+		case err != nil:
+			lastErr = err
+		}
+
+		if time.Now().After(deadline) {
+			if lastErr != nil {
+				return fmt.Errorf("timed out after %s: %v", stepTimeout, lastErr)
+			}
+			return fmt.Errorf("timed out after %s", stepTimeout)
+		}
+	}
+}
+
+// Recover marks upgrades interrupted by a restart failed; they can be
+// started again
+func (u *Upgrader) Recover(ctx context.Context) error {
+	states, err := u.databases.UpgradeStates(ctx)
+	if err != nil {
+		return err
+	}
+	for databaseID, state := range states {
+		if progress := parseProgress(state); progress == nil || progress.Status != StatusRunning {
+			continue
+		}
+		_, err := u.update(ctx, databaseID, func(p *Progress) {
+			now := time.Now()
+			for i := range p.Steps {
+				if p.Steps[i].Status == StatusRunning {
+					p.Steps[i].Status = StatusFailed
+					p.Steps[i].Message = "interrupted by an orchestrator restart"
+					p.Steps[i].CompletedAt = &now
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		u.logger.Warn().Str("database_id", databaseID).Msg("Interrupted database upgrade marked failed")
+		u.finish(ctx, databaseID, "the upgrade was interrupted")
+	}
+	return nil
+}
+
+// CheckChange refuses changes to a database that is being upgraded
+func (u *Upgrader) CheckChange(ctx context.Context, databaseID string) error {
+	if u == nil {
+		return nil
+	}
+	state, err := u.databases.UpgradeState(ctx, databaseID)
+	if err != nil {
+		// Not a YugabyteDB database, or left to the change to report
+		return nil
+	}
+	if progress := parseProgress(state); progress != nil && progress.Status == StatusRunning {
+		return errors.NewError(errors.CodeConflict,
+			fmt.Sprintf("database is being upgraded to %s; retry when the upgrade finishes", progress.ToVersion),
+			http.StatusConflict)
+	}
+	return nil
+}
+
+// setStep records the status of one step
+func (u *Upgrader) setStep(ctx context.Context, databaseID string, index int, status Status, message string) {
+	_, err := u.update(ctx, databaseID, func(p *Progress) {
+		if index >= len(p.Steps) {
+			return
+		}
+		now := time.Now()
+		step := &p.Steps[index]
+		step.Status = status
+		step.Message = message
+		if status == StatusRunning {
+			step.StartedAt = &now
+		} else {
+			step.CompletedAt = &now
+		}
+	})
+	if err != nil {
+		u.logger.Warn().Err(err).Str("database_id", databaseID).Msg("Failed to record database upgrade step")
+	}
+}
+
+// finish ends an upgrade, failed when there is a problem
+func (u *Upgrader) finish(ctx context.Context, databaseID, problem string) {
+	progress, err := u.update(ctx, databaseID, func(p *Progress) {
+		now := time.Now()
+		p.CompletedAt = &now
+		p.Error = problem
+		p.Status = StatusDone
+		if problem != "" {
+			p.Status = StatusFailed
+		}
+	})
+	if err != nil {
+		u.logger.Error().Err(err).Str("database_id", databaseID).Msg("Failed to record database upgrade result")
+		return
+	}
+
+	eventType := "database.upgraded"
+	if problem != "" {
+		eventType = "database.upgrade_failed"
+	}
+	u.logger.Info().
+		Str("database_id", databaseID).
+		Str("version", progress.ToVersion).
+		Str("status", string(progress.Status)).
+		Msg("Database upgrade finished")
+	u.publish(ctx, eventType, databaseID, progress)
+}
+
+// update applies a change to the stored progress of an upgrade
+func (u *Upgrader) update(ctx context.Context, databaseID string, apply func(*Progress)) (*Progress, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	progress, err := u.Progress(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		progress = &Progress{}
+	}
+	apply(progress)
+	if err := u.store(ctx, databaseID, progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
+
+func (u *Upgrader) store(ctx context.Context, databaseID string, progress *Progress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	return u.databases.SetUpgradeState(ctx, databaseID, string(data))
+}
+
+func (u *Upgrader) publish(ctx context.Context, eventType, databaseID string, progress *Progress) {
+	if u.eventBus == nil {
+		return
+	}
+	err := u.eventBus.Publish(ctx, eventType, &domain.Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Source:    "platform-orchestrator",
+		Timestamp: time.Now().Unix(),
+		Data: map[string]interface{}{
+			"database_id":  databaseID,
+			"from_version": progress.FromVersion,
+			"to_version":   progress.ToVersion,
+			"status":       progress.Status,
+			"error":        progress.Error,
+		},
+	})
+	if err != nil {
+		u.logger.Warn().Err(err).Str("database_id", databaseID).Msg("Failed to publish database event")
+	}
+}
+
+// parseProgress decodes the upgrade annotation of a cluster, nil when there
+// is none
+func parseProgress(state string) *Progress {
+	if state == "" {
+		return nil
+	}
+	var progress Progress
+	if err := json.Unmarshal([]byte(state), &progress); err != nil {
+		return nil
+	}
+	return &progress
+}
+
+// notFound maps a missing cluster to a not-found error
+func notFound(err error, databaseID string) error {
+	if apierrors.IsNotFound(err) {
+		return errors.NotFound("database", databaseID)
+	}
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
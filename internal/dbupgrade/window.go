@@ -0,0 +1,26 @@
+package dbupgrade
+
+import (
+	"time"
+
+	"github.com/northstack/platform/internal/timewindow"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// Window is a recurring maintenance window. An upgrade scheduled for a window
+// starts once it opens; its steps may run past the window's end.
+type Window timewindow.Window
+
+// Validate checks a maintenance window
+func (w *Window) Validate() error {
+	if err := timewindow.Window(*w).Validate(); err != nil {
+		return errors.BadRequest("maintenance window: " + err.Error())
+	}
+	return nil
+}
+
+// Open reports whether now falls within the window. A window spanning
+// midnight belongs to the day it starts on.
+func (w *Window) Open(now time.Time) bool {
+	return timewindow.Window(*w).Open(now)
+}
@@ -0,0 +1,38 @@
+package dbupgrade
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindowOpen(t *testing.T) {
+	weekend := Window{Days: []string{"sat", "sun"}, Start: "02:00", End: "05:00", Timezone: "Europe/Berlin"}
+	// Saturday 2026-06-06 01:30 UTC is 03:30 in Berlin
+	assert.True(t, weekend.Open(time.Date(2026, 6, 6, 1, 30, 0, 0, time.UTC)))
+	// Saturday 04:00 UTC is 06:00 in Berlin
+	assert.False(t, weekend.Open(time.Date(2026, 6, 6, 4, 0, 0, 0, time.UTC)))
+	// Wednesday 01:30 UTC
+	assert.False(t, weekend.Open(time.Date(2026, 6, 3, 1, 30, 0, 0, time.UTC)))
+
+	nightly := Window{Days: []string{"fri"}, Start: "23:00", End: "01:00"}
+	// Saturday 00:30 UTC belongs to Friday's window
+	assert.True(t, nightly.Open(time.Date(2026, 6, 6, 0, 30, 0, 0, time.UTC)))
+	// Friday 00:30 UTC belongs to Thursday
+	assert.False(t, nightly.Open(time.Date(2026, 6, 5, 0, 30, 0, 0, time.UTC)))
+}
+
+func TestWindowValidate(t *testing.T) {
+	assert.NoError(t, (&Window{Start: "02:00", End: "04:00"}).Validate())
+
+	invalid := []Window{
+		{Start: "2am", End: "04:00"},
+		{Start: "02:00", End: "02:00"},
+		{Start: "02:00", End: "04:00", Days: []string{"someday"}},
+		{Start: "02:00", End: "04:00", Timezone: "Mars/Olympus"},
+	}
+	for _, w := range invalid {
+		assert.Error(t, w.Validate(), w)
+	}
+}
@@ -0,0 +1,369 @@
+// Package deploylinks implements one-click deploy links, the targets of
+// "Deploy to NFOSS" buttons in READMEs. A link names a repository and,
+// optionally, a template; without one, the repository's own northstack.yaml is
+// deployed. Anyone can see what a link deploys; a signed-in user then creates
+// the project, and the repository's webhook is registered on their behalf.
+package deploylinks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/manifest"
+	"github.com/northstack/platform/internal/templates"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/git"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Variables a link fills in from its repository
+const (
+	VariableRepository = "REPOSITORY"
+	VariableBranch     = "BRANCH"
+)
+
+// defaultBranch is the branch deployed when a link names none
+const defaultBranch = "main"
+
+// maxManifestBytes bounds the size of a repository's manifest
+const maxManifestBytes = 1 << 20
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Link is what a deploy link names, from its query: ?repository=&branch=&template=
+type Link struct {
+	Repository string `json:"repository" form:"repository"`
+	Branch     string `json:"branch,omitempty" form:"branch"`
+	Template   string `json:"template,omitempty" form:"template"` // Catalog template; empty for the repository's northstack.yaml
+}
+
+// Repository is a hosted repository a link deploys
+type Repository struct {
+	Provider git.Provider `json:"provider"`
+	Owner    string       `json:"owner"` // User or group; GitLab groups may be nested
+	Name     string       `json:"name"`
+	URL      string       `json:"url"`
+}
+
+// Plan is what a link deploys, shown before anything is created
+type Plan struct {
+	Link       Link                   `json:"link"`
+	Repository *Repository            `json:"repository"`
+	Template   *domain.Template       `json:"template"`
+	Services   []manifest.ServiceSpec `json:"services"`    // Rendered with the variables' defaults
+	ConsoleURL string                 `json:"console_url"` // Console page that walks a user through the deploy
+}
+
+// DeployRequest is a signed-in user's go-ahead for a link
+type DeployRequest struct {
+	Name        string
+	Slug        string
+	Description string
+	TeamID      *uuid.UUID
+	OwnerID     uuid.UUID
+	Variables   map[string]string
+	GitToken    string // Allowed to read the repository and add webhooks to it; not stored
+	DryRun      bool
+}
+
+// Result is the outcome of deploying a link
+type Result struct {
+	*templates.Instance
+	Webhook WebhookResult `json:"webhook"`
+}
+
+// WebhookResult tells whether the repository's webhook was registered
+type WebhookResult struct {
+	Status string `json:"status"` // created, skipped or failed
+	ID     int64  `json:"id,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Linker resolves and deploys links
+type Linker struct {
+	cfg           *config.DeployLinksConfig
+	webhookSecret string
+	catalog       *templates.Catalog
+	providers     map[git.Provider]git.GitProvider
+	httpClient    *http.Client
+	logger        *logger.Logger
+}
+
+// NewLinker creates a new Linker. Repository webhooks are signed with
+// webhookSecret, which the webhook endpoints verify.
+func NewLinker(cfg *config.DeployLinksConfig, webhookSecret string, catalog *templates.Catalog, log *logger.Logger) *Linker {
+	return &Linker{
+		cfg:           cfg,
+		webhookSecret: webhookSecret,
+		catalog:       catalog,
+		providers: map[git.Provider]git.GitProvider{
+			git.ProviderGitHub: git.NewGitHubProvider(git.OAuthConfig{}),
+			git.ProviderGitLab: git.NewGitLabProvider(git.OAuthConfig{}, ""),
+		},
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+		logger:     log,
+	}
+}
+
+// ConsoleURL returns the console page a browser following a link is sent to,
+// or "" if none is configured
+func (l *Linker) ConsoleURL(link Link) string {
+	if l.cfg.ConsoleURL == "" {
+		return ""
+	}
+	query := url.Values{"repository": {link.Repository}}
+	if link.Branch != "" {
+		query.Set("branch", link.Branch)
+	}
+	if link.Template != "" {
+		query.Set("template", link.Template)
+	}
+	return l.cfg.ConsoleURL + "?" + query.Encode()
+}
+
+// Plan returns what a link deploys. Only public repositories can be read
+// without a token.
+func (l *Linker) Plan(ctx context.Context, link Link, token string) (*Plan, error) {
+	repo, t, err := l.resolve(ctx, link, token)
+	if err != nil {
+		return nil, err
+	}
+	m, err := templates.Preview(t)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Plan{
+		Link:       link,
+		Repository: repo,
+		Template:   t,
+		Services:   m.Services,
+		ConsoleURL: l.ConsoleURL(link),
+	}, nil
+}
+
+// Deploy creates the project a link describes and registers the repository's
+// webhook, so that pushes build it. A webhook that cannot be registered is
+// reported rather than failing the deploy.
+func (l *Linker) Deploy(ctx context.Context, link Link, req DeployRequest) (*Result, error) {
+	repo, t, err := l.resolve(ctx, link, req.GitToken)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := l.catalog.InstantiateTemplate(ctx, t, templates.InstantiateRequest{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		TeamID:      req.TeamID,
+		OwnerID:     req.OwnerID,
+		Variables:   req.Variables,
+		DryRun:      req.DryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Instance: instance}
+	if req.DryRun {
+		result.Webhook = WebhookResult{Status: "skipped", Reason: "dry run"}
+		return result, nil
+	}
+	result.Webhook = l.registerWebhook(ctx, repo, req.GitToken)
+	return result, nil
+}
+
+// resolve returns the repository of a link and the template it deploys, with
+// the repository and branch filled in
+func (l *Linker) resolve(ctx context.Context, link Link, token string) (*Repository, *domain.Template, error) {
+	repo, err := ParseRepository(link.Repository)
+	if err != nil {
+		return nil, nil, err
+	}
+	branch := link.Branch
+	if branch == "" {
+		branch = defaultBranch
+	}
+
+	if link.Template != "" {
+		base, err := l.catalog.Get(ctx, link.Template)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, withDefaults(base, map[string]string{VariableRepository: repo.URL, VariableBranch: branch}), nil
+	}
+
+	data, err := l.fetchManifest(ctx, repo, link.Branch, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := &domain.Template{
+		Slug:        strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(repo.Name), "-"), "-"),
+		Name:        repo.Owner + "/" + repo.Name,
+		Description: "Deployed from " + repo.URL,
+		Tags:        []string{},
+		Manifest:    string(data),
+		Variables: []domain.TemplateVariable{
+			{Name: VariableRepository, Description: "Git repository of the services", Default: repo.URL},
+			{Name: VariableBranch, Description: "Branch to build", Default: branch},
+		},
+	}
+	if err := templates.Validate(t); err != nil {
+		return nil, nil, err
+	}
+	return repo, t, nil
+}
+
+// fetchManifest reads the northstack.yaml of a repository's branch, or of its
+// default branch
+func (l *Linker) fetchManifest(ctx context.Context, repo *Repository, branch, token string) ([]byte, error) {
+	ref := branch
+	if ref == "" {
+		ref = "HEAD"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL(repo, ref, manifest.FileName), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		if repo.Provider == git.ProviderGitLab {
+			req.Header.Set("PRIVATE-TOKEN", token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := l.httpClient.Do(req)
+	if err != nil {
+		return nil, errors.DependencyFailed(string(repo.Provider), err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errors.NotFound(manifest.FileName, repo.URL+"@"+ref)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, errors.Forbidden("the repository is private; deploy it with a git token that can read it")
+	case resp.StatusCode != http.StatusOK:
+		return nil, errors.DependencyFailed(string(repo.Provider), fmt.Errorf("reading %s answered %d", manifest.FileName, resp.StatusCode))
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	if err != nil {
+		return nil, errors.DependencyFailed(string(repo.Provider), err)
+	}
+	if len(data) > maxManifestBytes {
+		return nil, errors.BadRequest(fmt.Sprintf("%s is larger than %d bytes", manifest.FileName, maxManifestBytes))
+	}
+	return data, nil
+}
+
+// registerWebhook adds the platform's webhook to a repository
+func (l *Linker) registerWebhook(ctx context.Context, repo *Repository, token string) WebhookResult {
+	if token == "" {
+		return WebhookResult{Status: "skipped", Reason: "no git token was given; add the webhook to the repository by hand"}
+	}
+	if l.cfg.APIURL == "" {
+		return WebhookResult{Status: "skipped", Reason: "the public API URL is not configured"}
+	}
+
+	events := []string{"push", "pull_request"}
+	if repo.Provider == git.ProviderGitLab {
+		events = []string{"push", "merge_request"}
+	}
+	hookURL := strings.TrimRight(l.cfg.APIURL, "/") + "/api/v1/webhooks/" + string(repo.Provider)
+
+	hook, err := l.providers[repo.Provider].CreateWebhook(ctx, token, repo.Owner, repo.Name, &git.Webhook{
+		URL:    hookURL,
+		Events: events,
+		Active: true,
+		Secret: l.webhookSecret,
+	})
+	if err == nil && hook.ID == 0 {
+		err = fmt.Errorf("the token may not add webhooks to the repository")
+	}
+	if err != nil {
+		l.logger.Warn().Err(err).Str("repository", repo.URL).Msg("Failed to register repository webhook")
+		return WebhookResult{Status: "failed", URL: hookURL, Reason: err.Error()}
+	}
+
+	l.logger.Info().Str("repository", repo.URL).Int64("webhook_id", hook.ID).Msg("Repository webhook registered")
+	return WebhookResult{Status: "created", ID: hook.ID, URL: hookURL}
+}
+
+// ParseRepository reads the web or clone URL of a GitHub or GitLab repository
+func ParseRepository(raw string) (*Repository, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return nil, errors.BadRequest("repository must be the https URL of a GitHub or GitLab repository")
+	}
+
+	var provider git.Provider
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	switch host {
+	case "github.com":
+		provider = git.ProviderGitHub
+	case "gitlab.com":
+		provider = git.ProviderGitLab
+	default:
+		return nil, errors.BadRequest("only repositories on github.com and gitlab.com can be deployed from links")
+	}
+
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git"), "/")
+	for i, part := range parts {
+		// GitLab web URLs continue with /-/tree/... after the project
+		if part == "-" {
+			parts = parts[:i]
+			break
+		}
+	}
+	if len(parts) < 2 || (provider == git.ProviderGitHub && len(parts) > 2) {
+		return nil, errors.BadRequest("repository must name an owner and a repository, e.g. https://github.com/acme/shop")
+	}
+	for _, part := range parts {
+		if part == "" || part == "." || part == ".." {
+			return nil, errors.BadRequest("invalid repository path")
+		}
+	}
+
+	owner, name := strings.Join(parts[:len(parts)-1], "/"), parts[len(parts)-1]
+	return &Repository{
+		Provider: provider,
+		Owner:    owner,
+		Name:     name,
+		URL:      "https://" + host + "/" + owner + "/" + name,
+	}, nil
+}
+
+// rawURL returns the URL of a file of a repository at a ref
+func rawURL(repo *Repository, ref, file string) string {
+	if repo.Provider == git.ProviderGitLab {
+		return repo.URL + "/-/raw/" + url.PathEscape(ref) + "/" + file
+	}
+	return "https://raw.githubusercontent.com/" + repo.Owner + "/" + repo.Name + "/" + url.PathEscape(ref) + "/" + file
+}
+
+// withDefaults returns a copy of a template whose named variables default to
+// the given values
+func withDefaults(base *domain.Template, defaults map[string]string) *domain.Template {
+	t := *base
+	t.Variables = make([]domain.TemplateVariable, len(base.Variables))
+	for i, v := range base.Variables {
+		if value, ok := defaults[v.Name]; ok && value != "" {
+			v.Default = value
+			v.Required = false
+		}
+		t.Variables[i] = v
+	}
+	return &t
+}
@@ -0,0 +1,154 @@
+package deploylinks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/templates"
+	"github.com/northstack/platform/pkg/git"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider records the webhooks it is asked to create
+type fakeProvider struct {
+	git.GitProvider
+	owner, repo string
+	webhook     *git.Webhook
+}
+
+func (p *fakeProvider) CreateWebhook(ctx context.Context, token, owner, repo string, webhook *git.Webhook) (*git.Webhook, error) {
+	p.owner, p.repo, p.webhook = owner, repo, webhook
+	return &git.Webhook{ID: 42, URL: webhook.URL}, nil
+}
+
+// redirect sends every request to a test server
+type redirect struct {
+	target *url.URL
+}
+
+func (r redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme, req.URL.Host = r.target.Scheme, r.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestLinker(t *testing.T) *Linker {
+	log := logger.New("error", "json", io.Discard)
+	cfg := &config.DeployLinksConfig{Enabled: true, ConsoleURL: "https://console.example.com/deploy", APIURL: "https://api.example.com/"}
+	return NewLinker(cfg, "secret", templates.NewCatalog(nil, nil, nil, nil, log), log)
+}
+
+func TestParseRepository(t *testing.T) {
+	cases := []struct {
+		raw, provider, owner, name string
+	}{
+		{"https://github.com/acme/shop", "github", "acme", "shop"},
+		{"https://github.com/acme/shop.git", "github", "acme", "shop"},
+		{"https://www.github.com/acme/shop/", "github", "acme", "shop"},
+		{"https://gitlab.com/acme/web/shop", "gitlab", "acme/web", "shop"},
+		{"https://gitlab.com/acme/shop/-/tree/main", "gitlab", "acme", "shop"},
+	}
+	for _, tc := range cases {
+		repo, err := ParseRepository(tc.raw)
+		require.NoError(t, err, tc.raw)
+		assert.Equal(t, tc.provider, string(repo.Provider), tc.raw)
+		assert.Equal(t, tc.owner, repo.Owner, tc.raw)
+		assert.Equal(t, tc.name, repo.Name, tc.raw)
+	}
+
+	for _, raw := range []string{
+		"", "http://github.com/acme/shop", "https://bitbucket.org/acme/shop",
+		"https://github.com/acme", "https://github.com/acme/shop/tree/main",
+		"https://token@github.com/acme/shop", "git@github.com:acme/shop.git",
+	} {
+		_, err := ParseRepository(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestRawURL(t *testing.T) {
+	github, _ := ParseRepository("https://github.com/acme/shop")
+	gitlab, _ := ParseRepository("https://gitlab.com/acme/web/shop")
+	assert.Equal(t, "https://raw.githubusercontent.com/acme/shop/main/northstack.yaml", rawURL(github, "main", "northstack.yaml"))
+	assert.Equal(t, "https://gitlab.com/acme/web/shop/-/raw/HEAD/northstack.yaml", rawURL(gitlab, "HEAD", "northstack.yaml"))
+}
+
+func TestPlanFillsTemplateFromLink(t *testing.T) {
+	linker := newTestLinker(t)
+	link := Link{Repository: "https://github.com/acme/site.git", Template: "static-site"}
+
+	plan, err := linker.Plan(context.Background(), link, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/acme/site", plan.Repository.URL)
+	require.Len(t, plan.Services, 1)
+	assert.Equal(t, "https://github.com/acme/site", plan.Services[0].Build.Repository)
+	assert.Equal(t, "main", plan.Services[0].Build.Branch)
+	assert.Equal(t, "https://console.example.com/deploy?repository=https%3A%2F%2Fgithub.com%2Facme%2Fsite.git&template=static-site", plan.ConsoleURL)
+
+	// The catalog's template is left as it was
+	base, err := linker.catalog.Get(context.Background(), "static-site")
+	require.NoError(t, err)
+	assert.True(t, base.Variables[0].Required)
+	assert.Empty(t, base.Variables[0].Default)
+}
+
+func TestPlanReadsRepositoryManifest(t *testing.T) {
+	var path, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		if r.URL.Path != "/acme/shop/HEAD/northstack.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`version: 1
+services:
+  - name: web
+    build:
+      type: docker
+      repository: ${REPOSITORY}
+      branch: ${BRANCH}
+`))
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	linker := newTestLinker(t)
+	linker.httpClient = &http.Client{Transport: redirect{target: target}}
+
+	plan, err := linker.Plan(context.Background(), Link{Repository: "https://github.com/acme/shop"}, "ghp_token")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer ghp_token", auth)
+	assert.Equal(t, "acme/shop", plan.Template.Name)
+	require.Len(t, plan.Services, 1)
+	assert.Equal(t, "https://github.com/acme/shop", plan.Services[0].Build.Repository)
+	assert.Equal(t, "main", plan.Services[0].Build.Branch)
+
+	_, err = linker.Plan(context.Background(), Link{Repository: "https://github.com/acme/shop", Branch: "dev"}, "")
+	assert.Error(t, err)
+	assert.Equal(t, "/acme/shop/dev/northstack.yaml", path)
+}
+
+func TestRegisterWebhook(t *testing.T) {
+	linker := newTestLinker(t)
+	provider := &fakeProvider{}
+	linker.providers[git.ProviderGitLab] = provider
+	repo, err := ParseRepository("https://gitlab.com/acme/web/shop")
+	require.NoError(t, err)
+
+	result := linker.registerWebhook(context.Background(), repo, "glpat")
+	assert.Equal(t, WebhookResult{Status: "created", ID: 42, URL: "https://api.example.com/api/v1/webhooks/gitlab"}, result)
+	assert.Equal(t, "acme/web", provider.owner)
+	assert.Equal(t, "shop", provider.repo)
+	assert.Equal(t, []string{"push", "merge_request"}, provider.webhook.Events)
+	assert.Equal(t, "secret", provider.webhook.Secret)
+
+	result = linker.registerWebhook(context.Background(), repo, "")
+	assert.Equal(t, "skipped", result.Status)
+}
@@ -0,0 +1,370 @@
+// Package devcluster bootstraps single-node k3s clusters for trials and
+// preview workloads, on a VM over SSH or on the orchestrator's host with k3d.
+// Dev clusters are registered like any other cluster but only host
+// non-production environments.
+package devcluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/rke2"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// MetadataKey is the cluster metadata key marking dev clusters
+const MetadataKey = "dev_cluster"
+
+// endpointPrefix marks the line of a local install's output holding the API
+// server URL
+const endpointPrefix = "endpoint="
+
+// Mode is where a dev cluster runs
+type Mode string
+
+const (
+	ModeSSH   Mode = "ssh"   // k3s on a VM reachable over SSH
+	ModeLocal Mode = "local" // k3d on the orchestrator's host
+)
+
+var versionPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+\+k3s\d+$`)
+
+// Request describes a dev cluster to create
+type Request struct {
+	Name        string            `json:"name" binding:"required"`
+	Slug        string            `json:"slug"`
+	Mode        Mode              `json:"mode" binding:"required,oneof=ssh local"`
+	Address     string            `json:"address"` // VM to install k3s on; required in ssh mode
+	SSHPort     int               `json:"ssh_port,omitempty"`
+	KubeVersion string            `json:"kube_version"` // k3s release, e.g. v1.28.5+k3s1
+	Labels      map[string]string `json:"labels"`
+}
+
+// Info is what the platform records about a dev cluster
+type Info struct {
+	Mode    Mode   `json:"mode"`
+	Address string `json:"address,omitempty"`
+	Error   string `json:"error,omitempty"` // Why the install failed
+}
+
+// GetInfo returns the dev cluster record of a cluster, or nil for other clusters
+func GetInfo(cluster *domain.Cluster) *Info {
+	raw, ok := cluster.Metadata[MetadataKey]
+	if !ok {
+		return nil
+	}
+	// Metadata read back from the store is decoded JSON
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil
+	}
+	return &info
+}
+
+func setInfo(cluster *domain.Cluster, info *Info) {
+	if cluster.Metadata == nil {
+		cluster.Metadata = make(map[string]interface{})
+	}
+	cluster.Metadata[MetadataKey] = info
+}
+
+// CheckEnvironment refuses production environments on dev clusters
+func CheckEnvironment(cluster *domain.Cluster, envType domain.EnvironmentType) error {
+	if envType == domain.EnvironmentTypeProduction && GetInfo(cluster) != nil {
+		return errors.BadRequest(fmt.Sprintf("cluster %s is a dev cluster and cannot host production environments", cluster.Slug))
+	}
+	return nil
+}
+
+// Bootstrapper creates dev clusters
+type Bootstrapper struct {
+	cfg         *config.DevClustersConfig
+	clusterRepo domain.ClusterRepository
+	ssh         rke2.Runner
+	local       rke2.Runner
+	registrar   rke2.Registrar
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewBootstrapper creates a new Bootstrapper. Without a registrar clusters
+// are not registered in Rancher; without an event bus no events are published.
+func NewBootstrapper(cfg *config.DevClustersConfig, clusterRepo domain.ClusterRepository, ssh rke2.Runner, registrar rke2.Registrar, eventBus domain.EventBus, log *logger.Logger) *Bootstrapper {
+	return &Bootstrapper{
+		cfg:         cfg,
+		clusterRepo: clusterRepo,
+		ssh:         ssh,
+		local:       localRunner{},
+		registrar:   registrar,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Create records a dev cluster as provisioning and installs it in the
+// background
+func (b *Bootstrapper) Create(ctx context.Context, req Request) (*domain.Cluster, error) {
+	if req.Slug == "" {
+		req.Slug = slugify(req.Name)
+	}
+	if req.KubeVersion == "" {
+		req.KubeVersion = b.cfg.K3sVersion
+	}
+	if err := validate(req); err != nil {
+		return nil, err
+	}
+	if _, err := b.clusterRepo.GetBySlug(ctx, req.Slug); err == nil {
+		return nil, errors.Conflict("cluster " + req.Slug)
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	region := "local"
+	if req.Mode == ModeSSH {
+		region = req.Address
+	}
+	now := time.Now()
+	cluster := &domain.Cluster{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Provider:    domain.ClusterProviderK3s,
+		Region:      region,
+		Status:      domain.ClusterStatusProvisioning,
+		KubeVersion: req.KubeVersion,
+		NodeCount:   1,
+		Labels:      req.Labels,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	setInfo(cluster, &Info{Mode: req.Mode, Address: req.Address})
+	if err := b.clusterRepo.Create(ctx, cluster); err != nil {
+		return nil, err
+	}
+
+	b.logger.Info().
+		Str("cluster_id", cluster.ID.String()).
+		Str("mode", string(req.Mode)).
+		Msg("Creating dev cluster")
+
+	node := rke2.Node{Address: req.Address, SSHPort: req.SSHPort, Role: rke2.RoleServer}
+	go b.install(context.Background(), cluster.ID, req.Slug, req.Mode, node, req.KubeVersion)
+	return cluster, nil
+}
+
+// install runs the install of a dev cluster and records how it went
+func (b *Bootstrapper) install(ctx context.Context, clusterID uuid.UUID, slug string, mode Mode, node rke2.Node, version string) {
+	timeout := b.cfg.InstallTimeout
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	runner, script := b.ssh, b.sshScript(node, version)
+	endpoint := "https://" + net.JoinHostPort(node.Address, "6443")
+	if mode == ModeLocal {
+		runner, script = b.local, b.localScript(slug, version)
+		endpoint = ""
+	}
+
+	var problem, rancherID string
+	output, err := runner.Run(runCtx, node, script)
+	if err != nil {
+		problem = "install failed: " + rke2.FailureMessage(err, output)
+	} else {
+		if mode == ModeLocal {
+			endpoint = parseEndpoint(output)
+		}
+		rancherID, problem = b.register(runCtx, clusterID, slug, mode, runner, node)
+	}
+
+	cluster, err := b.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		b.logger.Error().Err(err).Str("cluster_id", clusterID.String()).Msg("Failed to load dev cluster")
+		return
+	}
+	info := GetInfo(cluster)
+	if info == nil {
+		info = &Info{Mode: mode, Address: node.Address}
+	}
+	info.Error = problem
+	setInfo(cluster, info)
+	cluster.Status = domain.ClusterStatusActive
+	if problem != "" {
+		cluster.Status = domain.ClusterStatusUnhealthy
+	}
+	if endpoint != "" {
+		cluster.APIEndpoint = endpoint
+	}
+	if rancherID != "" {
+		cluster.RancherClusterID = rancherID
+	}
+	cluster.UpdatedAt = time.Now()
+	if err := b.clusterRepo.Update(ctx, cluster); err != nil {
+		b.logger.Error().Err(err).Str("cluster_id", clusterID.String()).Msg("Failed to record dev cluster")
+		return
+	}
+
+	b.logger.Info().
+		Str("cluster_id", clusterID.String()).
+		Str("status", string(cluster.Status)).
+		Msg("Dev cluster created")
+	b.publish(ctx, cluster, problem)
+}
+
+// register registers a cluster in Rancher and applies its agent with the
+// cluster's own kubectl. It returns the Rancher cluster ID and what went wrong.
+func (b *Bootstrapper) register(ctx context.Context, clusterID uuid.UUID, slug string, mode Mode, runner rke2.Runner, node rke2.Node) (string, string) {
+	if b.registrar == nil {
+		return "", ""
+	}
+	cluster, err := b.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return "", fmt.Sprintf("failed to load the cluster: %v", err)
+	}
+	rancherID, manifestURL, err := b.registrar.RegisterCluster(ctx, cluster)
+	if err != nil {
+		return "", fmt.Sprintf("failed to register the cluster in Rancher: %v", err)
+	}
+
+	kubectl := "k3s kubectl"
+	if mode == ModeLocal {
+		kubectl = "kubectl --context " + rke2.Quote("k3d-"+slug)
+	}
+	script := fmt.Sprintf("set -eu\ncurl -sfL %s | %s apply -f -\n", rke2.Quote(manifestURL), kubectl)
+	if output, err := runner.Run(ctx, node, script); err != nil {
+		return rancherID, "failed to apply the Rancher agent: " + rke2.FailureMessage(err, output)
+	}
+	return rancherID, ""
+}
+
+// sshScript installs a k3s server on a VM
+func (b *Bootstrapper) sshScript(node rke2.Node, version string) string {
+	var sb strings.Builder
+	sb.WriteString("set -eu\n")
+	fmt.Fprintf(&sb, "curl -sfL %s | INSTALL_K3S_VERSION=%s sh -s - server --tls-san %s --write-kubeconfig-mode 0600\n",
+		rke2.Quote(b.cfg.InstallURL), rke2.Quote(version), rke2.Quote(node.Address))
+	sb.WriteString("until k3s kubectl get nodes >/dev/null 2>&1; do sleep 2; done\n")
+	return sb.String()
+}
+
+// localScript creates a k3d cluster and prints its API server URL. k3d image
+// tags spell the k3s release with a dash.
+func (b *Bootstrapper) localScript(slug, version string) string {
+	var sb strings.Builder
+	sb.WriteString("set -eu\n")
+	fmt.Fprintf(&sb, "%s cluster create %s --image %s --wait\n",
+		rke2.Quote(b.cfg.K3dPath), rke2.Quote(slug), rke2.Quote("rancher/k3s:"+strings.ReplaceAll(version, "+", "-")))
+	fmt.Fprintf(&sb, "echo %s$(kubectl config view --raw -o jsonpath=%s)\n",
+		endpointPrefix, rke2.Quote(fmt.Sprintf(`{.clusters[?(@.name=="k3d-%s")].cluster.server}`, slug)))
+	return sb.String()
+}
+
+// Delete removes the k3d cluster of a local dev cluster. Clusters on VMs are
+// left installed.
+func (b *Bootstrapper) Delete(ctx context.Context, cluster *domain.Cluster) error {
+	info := GetInfo(cluster)
+	if info == nil || info.Mode != ModeLocal {
+		return nil
+	}
+	script := fmt.Sprintf("%s cluster delete %s\n", rke2.Quote(b.cfg.K3dPath), rke2.Quote(cluster.Slug))
+	if output, err := b.local.Run(ctx, rke2.Node{}, script); err != nil {
+		return errors.DependencyFailed("k3d", fmt.Errorf("%s", rke2.FailureMessage(err, output)))
+	}
+	return nil
+}
+
+func (b *Bootstrapper) publish(ctx context.Context, cluster *domain.Cluster, problem string) {
+	if b.eventBus == nil {
+		return
+	}
+	eventType := "cluster.provisioned"
+	if problem != "" {
+		eventType = "cluster.provisioning_failed"
+	}
+	err := b.eventBus.Publish(ctx, eventType, &domain.Event{
+		Type:   eventType,
+		Source: "platform-orchestrator",
+		Data: map[string]interface{}{
+			"cluster_id": cluster.ID.String(),
+			"name":       cluster.Name,
+			"status":     string(cluster.Status),
+			"dev":        true,
+			"error":      problem,
+		},
+	})
+	if err != nil {
+		b.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to publish cluster event")
+	}
+}
+
+// localRunner runs scripts on the orchestrator's host
+type localRunner struct{}
+
+func (localRunner) Run(ctx context.Context, _ rke2.Node, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-s")
+	cmd.Stdin = strings.NewReader(script)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	err := cmd.Run()
+	return output.String(), err
+}
+
+func validate(req Request) error {
+	if !dnsLabel.MatchString(req.Slug) {
+		return errors.BadRequest("slug must be a lowercase DNS label of at most 63 characters")
+	}
+	if !versionPattern.MatchString(req.KubeVersion) {
+		return errors.BadRequest("kube_version must be a k3s release, e.g. v1.28.5+k3s1")
+	}
+	switch req.Mode {
+	case ModeSSH:
+		// Reuses the node checks of RKE2 clusters
+		return rke2.ValidateNodes([]rke2.Node{{Address: req.Address, SSHPort: req.SSHPort, Role: rke2.RoleServer}})
+	case ModeLocal:
+		if req.Address != "" {
+			return errors.BadRequest("address is only used in ssh mode")
+		}
+		return nil
+	}
+	return errors.BadRequest("mode must be ssh or local")
+}
+
+var dnsLabel = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+func slugify(name string) string {
+	slug := strings.Trim(nonSlug.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 63 {
+		slug = strings.TrimRight(slug[:63], "-")
+	}
+	return slug
+}
+
+// parseEndpoint finds the API server URL in a local install's output
+func parseEndpoint(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, endpointPrefix) {
+			return strings.TrimSpace(strings.TrimPrefix(line, endpointPrefix))
+		}
+	}
+	return ""
+}
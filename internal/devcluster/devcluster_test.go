@@ -0,0 +1,89 @@
+package devcluster
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/rke2"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClusterRepo struct {
+	domain.ClusterRepository
+	clusters map[uuid.UUID]*domain.Cluster
+}
+
+func (r *fakeClusterRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Cluster, error) {
+	c := *r.clusters[id]
+	return &c, nil
+}
+
+func (r *fakeClusterRepo) Update(_ context.Context, cluster *domain.Cluster) error {
+	r.clusters[cluster.ID] = cluster
+	return nil
+}
+
+type fakeRunner struct {
+	scripts []string
+	output  string
+}
+
+func (r *fakeRunner) Run(_ context.Context, _ rke2.Node, script string) (string, error) {
+	r.scripts = append(r.scripts, script)
+	return r.output, nil
+}
+
+func testConfig() *config.DevClustersConfig {
+	return &config.DevClustersConfig{K3sVersion: "v1.28.5+k3s1", InstallURL: "https://get.k3s.io", K3dPath: "k3d"}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, validate(Request{Slug: "trial", Mode: ModeSSH, Address: "203.0.113.10", KubeVersion: "v1.28.5+k3s1"}))
+	assert.NoError(t, validate(Request{Slug: "trial", Mode: ModeLocal, KubeVersion: "v1.28.5+k3s1"}))
+	assert.Error(t, validate(Request{Slug: "trial", Mode: ModeSSH, KubeVersion: "v1.28.5+k3s1"}))
+	assert.Error(t, validate(Request{Slug: "trial", Mode: ModeLocal, KubeVersion: "v1.28.5+rke2r1"}))
+	assert.Error(t, validate(Request{Slug: "Trial", Mode: ModeLocal, KubeVersion: "v1.28.5+k3s1"}))
+}
+
+func TestCheckEnvironment(t *testing.T) {
+	dev := &domain.Cluster{Slug: "trial"}
+	setInfo(dev, &Info{Mode: ModeLocal})
+	assert.Error(t, CheckEnvironment(dev, domain.EnvironmentTypeProduction))
+	assert.NoError(t, CheckEnvironment(dev, domain.EnvironmentTypePreview))
+	assert.NoError(t, CheckEnvironment(&domain.Cluster{Slug: "prod"}, domain.EnvironmentTypeProduction))
+}
+
+func TestScripts(t *testing.T) {
+	b := NewBootstrapper(testConfig(), nil, nil, nil, nil, logger.New("error", "json", io.Discard))
+
+	ssh := b.sshScript(rke2.Node{Address: "203.0.113.10"}, "v1.28.5+k3s1")
+	assert.Contains(t, ssh, "curl -sfL 'https://get.k3s.io' | INSTALL_K3S_VERSION='v1.28.5+k3s1' sh -s - server --tls-san '203.0.113.10'")
+
+	local := b.localScript("trial", "v1.28.5+k3s1")
+	assert.Contains(t, local, "'k3d' cluster create 'trial' --image 'rancher/k3s:v1.28.5-k3s1' --wait")
+	assert.Equal(t, "https://0.0.0.0:43211", parseEndpoint("INFO[0010] Cluster 'trial' created successfully!\nendpoint=https://0.0.0.0:43211\n"))
+}
+
+func TestInstallLocal(t *testing.T) {
+	cluster := &domain.Cluster{ID: uuid.New(), Slug: "trial", Status: domain.ClusterStatusProvisioning}
+	setInfo(cluster, &Info{Mode: ModeLocal})
+	repo := &fakeClusterRepo{clusters: map[uuid.UUID]*domain.Cluster{cluster.ID: cluster}}
+	runner := &fakeRunner{output: "endpoint=https://0.0.0.0:43211\n"}
+	b := NewBootstrapper(testConfig(), repo, nil, nil, nil, logger.New("error", "json", io.Discard))
+	b.local = runner
+
+	b.install(context.Background(), cluster.ID, "trial", ModeLocal, rke2.Node{}, "v1.28.5+k3s1")
+
+	stored := repo.clusters[cluster.ID]
+	assert.Equal(t, domain.ClusterStatusActive, stored.Status)
+	assert.Equal(t, "https://0.0.0.0:43211", stored.APIEndpoint)
+	require.NotNil(t, GetInfo(stored))
+	assert.Equal(t, ModeLocal, GetInfo(stored).Mode)
+	assert.Len(t, runner.scripts, 1)
+}
@@ -4,6 +4,9 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
+	"io"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -27,6 +30,7 @@ type ProjectFilter struct {
 	Search   string
 	Limit    int
 	Offset   int
+	After    *Cursor // Keyset pagination; takes the place of Offset
 }
 
 // ServiceRepository defines the interface for service persistence
@@ -38,6 +42,7 @@ type ServiceRepository interface {
 	Update(ctx context.Context, service *Service) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status ServiceStatus) error
+	UpdateReplicas(ctx context.Context, id uuid.UUID, replicas *ServiceReplicas) error
 }
 
 // ServiceFilter defines filtering options for listing services
@@ -48,6 +53,7 @@ type ServiceFilter struct {
 	Search  string
 	Limit   int
 	Offset  int
+	After   *Cursor // Keyset pagination; takes the place of Offset
 }
 
 // BuildRepository defines the interface for build persistence
@@ -55,7 +61,9 @@ type BuildRepository interface {
 	Create(ctx context.Context, build *Build) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Build, error)
 	ListByService(ctx context.Context, serviceID uuid.UUID, limit int) ([]*Build, error)
+	ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *Cursor, limit int) ([]*Build, error)
 	ListByProject(ctx context.Context, projectID uuid.UUID, limit int) ([]*Build, error)
+	ListActive(ctx context.Context, limit int) ([]*Build, error)
 	Update(ctx context.Context, build *Build) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status BuildStatus, errorMsg string) error
 }
@@ -66,7 +74,9 @@ type DeploymentRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*Deployment, error)
 	GetLatestByService(ctx context.Context, serviceID uuid.UUID) (*Deployment, error)
 	ListByService(ctx context.Context, serviceID uuid.UUID, limit int) ([]*Deployment, error)
+	ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *Cursor, limit int) ([]*Deployment, error)
 	ListByCluster(ctx context.Context, clusterID uuid.UUID, limit int) ([]*Deployment, error)
+	ListByClusterAfter(ctx context.Context, clusterID uuid.UUID, after *Cursor, limit int) ([]*Deployment, error)
 	Update(ctx context.Context, deployment *Deployment) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status DeploymentStatus, errorMsg string) error
 }
@@ -89,6 +99,7 @@ type ClusterFilter struct {
 	Labels   map[string]string
 	Limit    int
 	Offset   int
+	After    *Cursor // Keyset pagination; takes the place of Offset
 }
 
 // EnvironmentRepository defines the interface for environment persistence
@@ -97,6 +108,7 @@ type EnvironmentRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*Environment, error)
 	GetBySlug(ctx context.Context, projectID uuid.UUID, slug string) (*Environment, error)
 	ListByProject(ctx context.Context, projectID uuid.UUID) ([]*Environment, error)
+	ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *Cursor, limit int) ([]*Environment, error)
 	ListByCluster(ctx context.Context, clusterID uuid.UUID) ([]*Environment, error)
 	Update(ctx context.Context, environment *Environment) error
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -108,17 +120,74 @@ type SecretRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*Secret, error)
 	GetByName(ctx context.Context, projectID uuid.UUID, name string) (*Secret, error)
 	ListByProject(ctx context.Context, projectID uuid.UUID) ([]*Secret, error)
+	ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *Cursor, limit int) ([]*Secret, error)
 	Update(ctx context.Context, secret *Secret) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
+// SecretReplicationRepository defines the interface for per-cluster secret replication status
+type SecretReplicationRepository interface {
+	// Upsert records the replication of a secret into a cluster namespace
+	Upsert(ctx context.Context, replication *SecretReplication) error
+	ListByProject(ctx context.Context, projectID uuid.UUID) ([]*SecretReplication, error)
+	ListBySecret(ctx context.Context, secretID uuid.UUID) ([]*SecretReplication, error)
+}
+
+// WebhookRepository defines the interface for outbound webhook subscriptions and their delivery log
+type WebhookRepository interface {
+	Create(ctx context.Context, subscription *WebhookSubscription) error
+	GetByID(ctx context.Context, id uuid.UUID) (*WebhookSubscription, error)
+	ListByProject(ctx context.Context, projectID uuid.UUID) ([]*WebhookSubscription, error)
+	ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *Cursor, limit int) ([]*WebhookSubscription, error)
+	Update(ctx context.Context, subscription *WebhookSubscription) error
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	// CreateDelivery logs or queues a delivery attempt. An attempt whose ID
+	// exists is left as it is, so queueing is idempotent.
+	CreateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// UpdateDelivery records the outcome of a queued attempt
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// ListDeliveries returns the latest delivery attempts of a subscription, newest first
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]*WebhookDelivery, error)
+	// ListDueDeliveries returns queued attempts due at now, with their bodies, oldest first
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error)
+	// ClaimDelivery postpones a queued attempt due at now to until, and reports
+	// whether this caller claimed it rather than another replica
+	ClaimDelivery(ctx context.Context, id uuid.UUID, now, until time.Time) (bool, error)
+}
+
+// TemplateRepository defines the interface for user-defined project templates
+type TemplateRepository interface {
+	Create(ctx context.Context, template *Template) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Template, error)
+	GetBySlug(ctx context.Context, slug string) (*Template, error)
+	List(ctx context.Context) ([]*Template, error)
+	Update(ctx context.Context, template *Template) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// DomainRepository defines the interface for the custom domains of projects
+type DomainRepository interface {
+	Create(ctx context.Context, d *CustomDomain) error
+	GetByID(ctx context.Context, id uuid.UUID) (*CustomDomain, error)
+	ListByProject(ctx context.Context, projectID uuid.UUID) ([]*CustomDomain, error)
+	ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *Cursor, limit int) ([]*CustomDomain, error)
+	// ListByName returns every project's claim on a domain name
+	ListByName(ctx context.Context, name string) ([]*CustomDomain, error)
+	ListByStatus(ctx context.Context, status DomainStatus) ([]*CustomDomain, error)
+	Update(ctx context.Context, d *CustomDomain) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
 // IngressRepository defines the interface for ingress persistence
 type IngressRepository interface {
 	Create(ctx context.Context, ingress *Ingress) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Ingress, error)
 	GetByDomain(ctx context.Context, domain string) (*Ingress, error)
 	ListByService(ctx context.Context, serviceID uuid.UUID) ([]*Ingress, error)
+	ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *Cursor, limit int) ([]*Ingress, error)
 	ListByProject(ctx context.Context, projectID uuid.UUID) ([]*Ingress, error)
+	ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *Cursor, limit int) ([]*Ingress, error)
 	Update(ctx context.Context, ingress *Ingress) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -174,6 +243,123 @@ type AuditLogFilter struct {
 	EndTime      *int64
 	Limit        int
 	Offset       int
+	After        *Cursor // Keyset pagination; takes the place of Offset
+}
+
+// AlertRepository defines the interface for alert persistence
+type AlertRepository interface {
+	Create(ctx context.Context, alert *Alert) error
+	GetByID(ctx context.Context, id string) (*Alert, error)
+	GetFiringByFingerprint(ctx context.Context, fingerprint string) (*Alert, error)
+	List(ctx context.Context, filter AlertFilter) ([]*Alert, error)
+	Update(ctx context.Context, alert *Alert) error
+}
+
+// AlertFilter defines filtering options for listing alerts
+type AlertFilter struct {
+	Status    string
+	Source    string
+	ServiceID *uuid.UUID
+	ProjectID *uuid.UUID
+	ClusterID *uuid.UUID
+	Limit     int
+	Offset    int
+}
+
+// ProbeRepository defines the interface for uptime probe persistence
+type ProbeRepository interface {
+	Create(ctx context.Context, result *ProbeResult) error
+	ListByIngress(ctx context.Context, ingressID uuid.UUID, limit int) ([]*ProbeResult, error)
+	Stats(ctx context.Context, ingressID uuid.UUID, since time.Time) (*ProbeStats, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// UsageRepository defines the interface for hourly resource usage persistence
+type UsageRepository interface {
+	Upsert(ctx context.Context, record *UsageRecord) error
+	ListByProject(ctx context.Context, projectID uuid.UUID, from, to time.Time) ([]*UsageRecord, error)
+	LatestHour(ctx context.Context) (*time.Time, error)
+}
+
+// ArtifactRepository defines the interface for build artifact metadata persistence
+type ArtifactRepository interface {
+	Create(ctx context.Context, artifact *Artifact) error
+	GetByName(ctx context.Context, buildID uuid.UUID, name string) (*Artifact, error)
+	ListByBuild(ctx context.Context, buildID uuid.UUID) ([]*Artifact, error)
+	ListByService(ctx context.Context, serviceID uuid.UUID, limit int) ([]*Artifact, error)
+	ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *Cursor, limit int) ([]*Artifact, error)
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]*Artifact, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// NotificationRepository defines the interface for user notification persistence
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *UserNotification) error
+	GetByID(ctx context.Context, userID, id uuid.UUID) (*UserNotification, error)
+	ListByUser(ctx context.Context, userID uuid.UUID, filter NotificationFilter) ([]*UserNotification, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int64, error)
+	MarkRead(ctx context.Context, userID, id uuid.UUID) error
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+// NotificationFilter defines filtering options for listing notifications
+type NotificationFilter struct {
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+	After      *Cursor // Keyset pagination; takes the place of Offset
+}
+
+// NotificationPreferenceRepository defines the interface for notification preference persistence
+type NotificationPreferenceRepository interface {
+	Create(ctx context.Context, preference *NotificationPreference) error
+	GetByID(ctx context.Context, id uuid.UUID) (*NotificationPreference, error)
+	// ListByUser lists a user's preferences, for every project and none
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*NotificationPreference, error)
+	// ListByProject lists a project's own preferences, not those of its users
+	ListByProject(ctx context.Context, projectID uuid.UUID) ([]*NotificationPreference, error)
+	Update(ctx context.Context, preference *NotificationPreference) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// ApprovalRepository defines the interface for deploy approval persistence
+type ApprovalRepository interface {
+	Create(ctx context.Context, approval *Approval) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Approval, error)
+	// LatestByWorkflow returns the most recent approval of a deployment workflow
+	LatestByWorkflow(ctx context.Context, workflowID uuid.UUID) (*Approval, error)
+	List(ctx context.Context, filter ApprovalFilter) ([]*Approval, error)
+	Update(ctx context.Context, approval *Approval) error
+}
+
+// ApprovalFilter defines filtering options for listing approvals, newest first
+type ApprovalFilter struct {
+	ProjectID     *uuid.UUID
+	ApproverIDs   []uuid.UUID // Approvals asked of any of these users
+	Status        ApprovalStatus
+	CreatedBefore *time.Time
+	Unescalated   bool
+	Limit         int
+	After         *Cursor
+}
+
+// ApprovalDelegationRepository defines the interface for approval delegation persistence
+type ApprovalDelegationRepository interface {
+	Create(ctx context.Context, delegation *ApprovalDelegation) error
+	GetByID(ctx context.Context, id uuid.UUID) (*ApprovalDelegation, error)
+	// ListByUser lists the delegations a user gave or received, newest first
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]*ApprovalDelegation, error)
+	// ListActive lists the delegations a user received whose window holds at
+	ListActive(ctx context.Context, delegateID uuid.UUID, at time.Time) ([]*ApprovalDelegation, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// NotificationPolicy decides whether an event reaches a notification channel
+type NotificationPolicy interface {
+	// Allows reports whether an event of a project, addressed to a user,
+	// reaches a channel. userID is nil for events addressed to no one, such
+	// as Slack posts, and projectID for events of no project.
+	Allows(ctx context.Context, channel string, userID, projectID *uuid.UUID, eventType, severity string) bool
 }
 
 // CIAdapter defines the interface for CI/Build systems (e.g., Coolify)
@@ -208,6 +394,31 @@ type ClusterManagerAdapter interface {
 	ListClusters(ctx context.Context) ([]*Cluster, error)
 	// GetClusterHealth retrieves health status of a cluster
 	GetClusterHealth(ctx context.Context, externalID string) (*ClusterHealth, error)
+	// CreateNamespace creates a namespace on a cluster; an existing namespace is not an error
+	CreateNamespace(ctx context.Context, externalID, namespace string, labels map[string]string) error
+	// DeleteNamespace deletes a namespace from a cluster; a missing namespace is not an error
+	DeleteNamespace(ctx context.Context, externalID, namespace string) error
+	// ListNodePools lists the node pools of a cluster
+	ListNodePools(ctx context.Context, externalID string) ([]*NodePool, error)
+	// CreateNodePool adds a node pool to a cluster
+	CreateNodePool(ctx context.Context, externalID string, pool *NodePool) error
+	// UpdateNodePool resizes a node pool or changes its instance type; nodes
+	// to be removed are cordoned and drained first
+	UpdateNodePool(ctx context.Context, externalID string, pool *NodePool) error
+	// DeleteNodePool cordons and drains the nodes of a pool, then removes it
+	DeleteNodePool(ctx context.Context, externalID, name string) error
+	// ListKubernetesVersions lists the Kubernetes versions a cluster can be
+	// upgraded to
+	ListKubernetesVersions(ctx context.Context, externalID string) ([]string, error)
+	// GetClusterCapacity returns the allocatable and requested resources of
+	// a cluster and the requests of its namespaces
+	GetClusterCapacity(ctx context.Context, externalID string) (*ClusterCapacity, error)
+	// IssueKubeConfig returns a kubeconfig whose short-lived credentials
+	// carry only the grant's access
+	IssueKubeConfig(ctx context.Context, externalID string, grant *KubeConfigGrant) (*IssuedKubeConfig, error)
+	// RevokeKubeConfig invalidates the credentials of a kubeconfig
+	// GetKubeConfig returned
+	RevokeKubeConfig(ctx context.Context, externalID string, kubeconfig []byte) error
 }
 
 // ClusterHealth represents the health status of a cluster
@@ -215,9 +426,10 @@ type ClusterHealth struct {
 	Status      ClusterStatus     `json:"status"`
 	NodeCount   int32             `json:"node_count"`
 	ReadyNodes  int32             `json:"ready_nodes"`
-	CPUUsage    float64           `json:"cpu_usage"`
-	MemoryUsage float64           `json:"memory_usage"`
+	CPUUsage    float64           `json:"cpu_usage"`    // Fraction of allocatable CPU requested
+	MemoryUsage float64           `json:"memory_usage"` // Fraction of allocatable memory requested
 	Conditions  []ClusterCondition `json:"conditions"`
+	IPFamilies  []IPFamily        `json:"ip_families,omitempty"` // Service IP families the cluster supports
 }
 
 // ClusterCondition represents a condition of a cluster
@@ -310,27 +522,99 @@ type Event struct {
 // EventHandler is a function that handles events
 type EventHandler func(event *Event) error
 
+// RequestHandler answers a request sent with EventBus.Request
+type RequestHandler func(ctx context.Context, request *Event) (*Event, error)
+
 // Subscription represents an event subscription
 type Subscription interface {
 	Unsubscribe() error
 }
 
+// EventStore reads back events retained in durable streams
+type EventStore interface {
+	// StreamBounds returns the first and last sequence held by a stream
+	StreamBounds(ctx context.Context, stream string) (first, last uint64, err error)
+	// ReadStream returns the events with sequences in [from, to], oldest first
+	ReadStream(ctx context.Context, stream string, from, to uint64) ([]*StoredEvent, error)
+}
+
+// StoredEvent is an event together with its position in a stream
+type StoredEvent struct {
+	Stream   string
+	Sequence uint64
+	Stored   time.Time
+	Event    *Event // Nil when the message could not be decoded
+}
+
+// DeadLetterQueue parks events whose handlers failed on every delivery
+// attempt, so that they can be inspected and re-driven once the handler is fixed
+type DeadLetterQueue interface {
+	// ListDeadLetters returns up to limit dead letters after a sequence, oldest first
+	ListDeadLetters(ctx context.Context, after uint64, limit int) ([]*DeadLetter, error)
+	// GetDeadLetter returns one dead letter
+	GetDeadLetter(ctx context.Context, sequence uint64) (*DeadLetter, error)
+	// Redrive publishes a dead letter on its original subject again and removes it
+	Redrive(ctx context.Context, sequence uint64) error
+	// DiscardDeadLetter removes a dead letter without re-driving it
+	DiscardDeadLetter(ctx context.Context, sequence uint64) error
+}
+
+// DeadLetter is an event parked in the dead-letter queue
+type DeadLetter struct {
+	Sequence       uint64    `json:"sequence"` // Position in the dead-letter stream
+	Subject        string    `json:"subject"`  // Subject the event was published on
+	Stream         string    `json:"stream"`   // Stream the event was consumed from
+	StreamSequence uint64    `json:"stream_sequence"`
+	Consumer       string    `json:"consumer"`
+	Attempts       int       `json:"attempts"`
+	Error          string    `json:"error"`
+	FailedAt       time.Time `json:"failed_at"`
+	Event          *Event    `json:"event,omitempty"` // Nil when the message could not be decoded
+}
+
+// EventSchema is one version of the JSON schema of the payloads published on
+// the subjects matching Subject
+type EventSchema struct {
+	Subject string          `json:"subject"` // May use the wildcards * and >
+	Version int             `json:"version"`
+	Schema  json.RawMessage `json:"schema"`
+}
+
+// EventSchemaRegistry serves the schemas of published events
+type EventSchemaRegistry interface {
+	// Schemas returns every version of the schemas that apply to subject, or
+	// of all schemas when subject is empty
+	Schemas(subject string) []*EventSchema
+}
+
 // KubernetesClient defines the interface for Kubernetes operations
 type KubernetesClient interface {
 	// ApplyManifest applies a Kubernetes manifest
 	ApplyManifest(ctx context.Context, clusterID uuid.UUID, manifest []byte) error
+	// PatchResource applies a strategic merge patch to a Kubernetes resource
+	PatchResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string, patch []byte) error
 	// DeleteResource deletes a Kubernetes resource
 	DeleteResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) error
+	// EvictPod evicts a pod through the Eviction API, which honours
+	// PodDisruptionBudgets; it fails with a rate limited error while a
+	// budget does not allow the disruption
+	EvictPod(ctx context.Context, clusterID uuid.UUID, namespace, name string) error
 	// GetResource retrieves a Kubernetes resource
 	GetResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) (map[string]interface{}, error)
 	// ListResources lists Kubernetes resources
 	ListResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string, labels map[string]string) ([]map[string]interface{}, error)
 	// GetPodLogs retrieves logs from a pod
 	GetPodLogs(ctx context.Context, clusterID uuid.UUID, namespace, podName, container string, tailLines int64) (string, error)
+	// GetTimestampedPodLogs retrieves logs from a pod with each line prefixed
+	// by the RFC 3339 time it was logged at, as kubectl logs --timestamps
+	// does. A non-zero since skips lines logged before its second.
+	GetTimestampedPodLogs(ctx context.Context, clusterID uuid.UUID, namespace, podName, container string, since time.Time, tailLines int64) (string, error)
 	// ExecInPod executes a command in a pod
 	ExecInPod(ctx context.Context, clusterID uuid.UUID, namespace, podName, container string, command []string) (string, error)
 	// WatchResource watches for changes to a resource
 	WatchResource(ctx context.Context, clusterID uuid.UUID, kind, namespace string, handler func(eventType string, obj map[string]interface{})) error
+	// PortForward opens a stream to a port of a pod, as kubectl port-forward does
+	PortForward(ctx context.Context, clusterID uuid.UUID, namespace, podName string, port int32) (io.ReadWriteCloser, error)
 }
 
 // MetricsCollector defines the interface for collecting metrics
@@ -343,6 +627,40 @@ type MetricsCollector interface {
 	GetProjectMetrics(ctx context.Context, projectID uuid.UUID, timeRange TimeRange) (*ProjectMetrics, error)
 }
 
+// LogStore defines the interface for querying historical logs
+type LogStore interface {
+	// SearchServiceLogs searches historical logs for a service
+	SearchServiceLogs(ctx context.Context, serviceID uuid.UUID, query LogQuery) ([]LogEntry, error)
+}
+
+// ObjectStore defines the interface for blob storage (e.g., S3, MinIO)
+type ObjectStore interface {
+	// PutObject stores size bytes read from body under key
+	PutObject(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// DeleteObject removes the object stored under key
+	DeleteObject(ctx context.Context, key string) error
+	// PresignGet returns a URL that downloads the object under key until expiry,
+	// served as an attachment named filename
+	PresignGet(key, filename string, expiry time.Duration) (string, error)
+}
+
+// LogQuery defines a historical log search
+type LogQuery struct {
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	Regex     string            `json:"regex,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Limit     int               `json:"limit"`
+	Direction string            `json:"direction"` // "backward" (newest first) or "forward"
+}
+
+// LogEntry represents a single stored log line
+type LogEntry struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Message   string            `json:"message"`
+}
+
 // TimeRange defines a time range for metrics queries
 type TimeRange struct {
 	Start int64 `json:"start"`
@@ -355,6 +673,7 @@ type ServiceMetrics struct {
 	ServiceID     uuid.UUID       `json:"service_id"`
 	CPUUsage      []MetricPoint   `json:"cpu_usage"`
 	MemoryUsage   []MetricPoint   `json:"memory_usage"`
+	NetworkEgress []MetricPoint   `json:"network_egress"` // Bytes per second
 	RequestCount  []MetricPoint   `json:"request_count"`
 	ErrorRate     []MetricPoint   `json:"error_rate"`
 	Latency       LatencyMetrics  `json:"latency"`
@@ -417,9 +736,16 @@ type Notification struct {
 	Data      map[string]interface{} `json:"data,omitempty"`
 }
 
+// Alert statuses
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+)
+
 // Alert represents an alert
 type Alert struct {
 	ID          string                 `json:"id"`
+	Fingerprint string                 `json:"fingerprint"`
 	Name        string                 `json:"name"`
 	Severity    string                 `json:"severity"`
 	Status      string                 `json:"status"`
@@ -427,6 +753,163 @@ type Alert struct {
 	Message     string                 `json:"message"`
 	Labels      map[string]string      `json:"labels"`
 	Annotations map[string]string      `json:"annotations"`
+	ServiceID   *uuid.UUID             `json:"service_id,omitempty"`
+	ProjectID   *uuid.UUID             `json:"project_id,omitempty"`
+	ClusterID   *uuid.UUID             `json:"cluster_id,omitempty"`
 	StartsAt    int64                  `json:"starts_at"`
 	EndsAt      int64                  `json:"ends_at,omitempty"`
 }
+
+// ProbeResult is the outcome of a single uptime check against an ingress
+type ProbeResult struct {
+	ID           uuid.UUID `json:"id"`
+	IngressID    uuid.UUID `json:"ingress_id"`
+	ServiceID    uuid.UUID `json:"service_id"`
+	URL          string    `json:"url"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	ResponseTime int64     `json:"response_time"` // Milliseconds
+	Success      bool      `json:"success"`
+	Error        string    `json:"error,omitempty"`
+	CheckedAt    time.Time `json:"checked_at"`
+}
+
+// ProbeStats aggregates the probe results of an ingress over a period
+type ProbeStats struct {
+	Total           int64   `json:"total"`
+	Successful      int64   `json:"successful"`
+	AvgResponseTime float64 `json:"avg_response_time"` // Milliseconds, successful checks only
+}
+
+// UsageRecord is the resource usage of a service during one hour
+type UsageRecord struct {
+	ServiceID      uuid.UUID `json:"service_id"`
+	ProjectID      uuid.UUID `json:"project_id"`
+	Hour           time.Time `json:"hour"` // Start of the hour, UTC
+	CPUCoreHours   float64   `json:"cpu_core_hours"`
+	MemoryGiBHours float64   `json:"memory_gib_hours"`
+	EgressBytes    float64   `json:"egress_bytes"`
+}
+
+// Artifact is a named file produced by a build, such as a CLI binary or a
+// report, kept in object storage until it expires
+type Artifact struct {
+	ID          uuid.UUID  `json:"id"`
+	BuildID     uuid.UUID  `json:"build_id"`
+	ServiceID   uuid.UUID  `json:"service_id"`
+	ProjectID   uuid.UUID  `json:"project_id"`
+	Name        string     `json:"name"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`   // Bytes
+	SHA256      string     `json:"sha256"` // Hex encoded
+	StorageKey  string     `json:"-"`
+	UploadedBy  string     `json:"uploaded_by,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Notification types
+const (
+	NotificationDeploySucceeded   = "deploy_succeeded"
+	NotificationDeployFailed      = "deploy_failed"
+	NotificationApprovalRequested = "approval_requested"
+	NotificationQuotaNearing      = "quota_nearing"
+)
+
+// UserNotification is an entry in a user's in-product inbox
+type UserNotification struct {
+	ID        uuid.UUID              `json:"id"`
+	UserID    uuid.UUID              `json:"user_id"`
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Body      string                 `json:"body,omitempty"`
+	Link      string                 `json:"link,omitempty"` // API path of the resource the notification is about
+	ProjectID *uuid.UUID             `json:"project_id,omitempty"`
+	ServiceID *uuid.UUID             `json:"service_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	ReadAt    *time.Time             `json:"read_at,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// Notification event types, named after the platform events they report
+const (
+	NotificationEventBuildCompleted    = "build.completed"
+	NotificationEventBuildFailed       = "build.failed"
+	NotificationEventDeployCompleted   = "deploy.completed"
+	NotificationEventDeployFailed      = "deploy.failed"
+	NotificationEventRollbackCompleted = "rollback.completed"
+	NotificationEventAlertFired        = "alert.fired"
+	NotificationEventAlertResolved     = "alert.resolved"
+	NotificationEventDigest            = "project.digest"
+)
+
+// Notification channels
+const (
+	NotificationChannelInbox     = "inbox"
+	NotificationChannelEmail     = "email"
+	NotificationChannelSlack     = "slack"
+	NotificationChannelPagerDuty = "pagerduty"
+	NotificationChannelOpsgenie  = "opsgenie"
+)
+
+// NotificationPreference decides which events reach a notification channel,
+// for a project, a user, or a user in one project
+type NotificationPreference struct {
+	ID         uuid.UUID    `json:"id"`
+	UserID     *uuid.UUID   `json:"user_id,omitempty"`
+	ProjectID  *uuid.UUID   `json:"project_id,omitempty"`
+	Channel    string       `json:"channel"`              // inbox, email, slack, pagerduty, opsgenie, or * for every channel
+	Events     []string     `json:"events,omitempty"`     // Event types that reach the channel, or *; every type when empty
+	Severities []string     `json:"severities,omitempty"` // Alert severities that reach the channel; every severity when empty
+	Muted      bool         `json:"muted"`                // Nothing reaches the channel
+	Mute       []MuteWindow `json:"mute,omitempty"`       // Recurring windows in which nothing reaches the channel
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+}
+
+// MuteWindow is a recurring window of the week in which a channel is muted
+type MuteWindow struct {
+	Days     []string `json:"days,omitempty"`     // mon..sun; empty means every day
+	Start    string   `json:"start"`              // HH:MM
+	End      string   `json:"end"`                // HH:MM; earlier than Start for windows spanning midnight
+	Timezone string   `json:"timezone,omitempty"` // IANA name; defaults to UTC
+}
+
+// ApprovalStatus represents the state of a deploy approval
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending  ApprovalStatus = "pending"
+	ApprovalStatusApproved ApprovalStatus = "approved"
+	ApprovalStatusRejected ApprovalStatus = "rejected"
+)
+
+// Approval holds a deploy to a gated environment until its approver, or a
+// user they delegated to, signs it off
+type Approval struct {
+	ID            uuid.UUID      `json:"id"`
+	ProjectID     uuid.UUID      `json:"project_id"`
+	ServiceID     uuid.UUID      `json:"service_id"`
+	EnvironmentID uuid.UUID      `json:"environment_id"`
+	WorkflowID    uuid.UUID      `json:"workflow_id"` // Deployment workflow that resumes once approved
+	Version       string         `json:"version,omitempty"`
+	Status        ApprovalStatus `json:"status"`
+	ApproverID    uuid.UUID      `json:"approver_id"` // The project owner, or the team owner once escalated
+	EscalatedAt   *time.Time     `json:"escalated_at,omitempty"`
+	DecidedBy     *uuid.UUID     `json:"decided_by,omitempty"` // The approver or their delegate
+	DecidedAt     *time.Time     `json:"decided_at,omitempty"`
+	Comment       string         `json:"comment,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// ApprovalDelegation lets a user decide the approvals asked of another user
+// for a window of time, e.g. while they are on vacation
+type ApprovalDelegation struct {
+	ID          uuid.UUID `json:"id"`
+	DelegatorID uuid.UUID `json:"delegator_id"`
+	DelegateID  uuid.UUID `json:"delegate_id"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	Reason      string    `json:"reason,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -3,11 +3,29 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Labels applied to Kubernetes workloads managed by the platform
+const (
+	LabelServiceID     = "openpaas.io/service-id"
+	LabelProjectID     = "openpaas.io/project-id"
+	LabelEnvironmentID = "openpaas.io/environment-id"
+	LabelIngressID     = "openpaas.io/ingress-id"
+	LabelManagedBy     = "app.kubernetes.io/managed-by"
+	ManagedByValue     = "openpaas"
+)
+
+// Labels attached to service metrics by the Prometheus scrape configuration
+const (
+	MetricLabelServiceID = "service_id"
+	MetricLabelProjectID = "project_id"
+	MetricLabelClusterID = "cluster_id"
+)
+
 // ProjectStatus represents the current state of a project
 type ProjectStatus string
 
@@ -20,17 +38,55 @@ const (
 
 // Project represents a collection of services and resources
 type Project struct {
-	ID          uuid.UUID              `json:"id"`
-	Name        string                 `json:"name"`
-	Slug        string                 `json:"slug"`
-	Description string                 `json:"description,omitempty"`
-	Status      ProjectStatus          `json:"status"`
-	OwnerID     uuid.UUID              `json:"owner_id"`
-	TeamID      *uuid.UUID             `json:"team_id,omitempty"`
-	Labels      map[string]string      `json:"labels,omitempty"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
+	ID            uuid.UUID              `json:"id"`
+	Name          string                 `json:"name"`
+	Slug          string                 `json:"slug"`
+	Description   string                 `json:"description,omitempty"`
+	Status        ProjectStatus          `json:"status"`
+	OwnerID       uuid.UUID              `json:"owner_id"`
+	TeamID        *uuid.UUID             `json:"team_id,omitempty"`
+	Labels        map[string]string      `json:"labels,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	DataResidency string                 `json:"data_residency,omitempty"` // Residency region workloads, databases and backups must stay in
+	Slack         *SlackSettings         `json:"slack,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+// SlackSettings routes a project's build, deploy and alert notifications to
+// Slack channels
+type SlackSettings struct {
+	Channel string       `json:"channel,omitempty"` // Receives the events no route takes; none when empty
+	Routes  []SlackRoute `json:"routes,omitempty"`
+}
+
+// SlackRoute sends a project's events of the given types to a channel
+type SlackRoute struct {
+	Events     []string `json:"events"`               // e.g. deploy.failed, or * for every type
+	Severities []string `json:"severities,omitempty"` // Alert severities the route takes; every one when empty
+	Channel    string   `json:"channel"`
+}
+
+// Matches reports whether the route takes events of the given type and
+// severity. Routes that list severities only take alerts of those severities.
+func (r *SlackRoute) Matches(eventType, severity string) bool {
+	if len(r.Severities) > 0 {
+		found := false
+		for _, s := range r.Severities {
+			if s == severity {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, t := range r.Events {
+		if t == "*" || t == eventType {
+			return true
+		}
+	}
+	return false
 }
 
 // ServiceType represents the type of service being deployed
@@ -85,6 +141,63 @@ type ScalingConfig struct {
 	TargetMemory         int32 `json:"target_memory,omitempty"`
 	ScaleDownDelay       int32 `json:"scale_down_delay,omitempty"`
 	ScaleUpStabilization int32 `json:"scale_up_stabilization,omitempty"`
+
+	Schedules   []ScalingSchedule `json:"schedules,omitempty"`
+	Triggers    []ScalingTrigger  `json:"triggers,omitempty"`
+	WarmStandby *WarmStandby      `json:"warm_standby,omitempty"`
+}
+
+// Autoscalers driving the replica count of a service
+const (
+	AutoscalerHPA  = "hpa"
+	AutoscalerKEDA = "keda"
+)
+
+// ServiceReplicas is the replica count of a service's workload as last
+// observed in its target cluster
+type ServiceReplicas struct {
+	Desired       int32      `json:"desired"` // Set by the autoscaler, or the workload's own count
+	Current       int32      `json:"current"`
+	Ready         int32      `json:"ready"`
+	Autoscaler    string     `json:"autoscaler,omitempty"` // Empty when the service is not autoscaled
+	LastScaleTime *time.Time `json:"last_scale_time,omitempty"`
+	ObservedAt    time.Time  `json:"observed_at"`
+}
+
+// WarmStandby keeps spare capacity ready so scale-ups don't wait on cold starts
+type WarmStandby struct {
+	Replicas     int32 `json:"replicas"`                // Idle replicas kept above current demand
+	PrewarmImage bool  `json:"prewarm_image,omitempty"` // Keep the image pulled on every eligible node
+}
+
+// Scaling trigger types beyond CPU and memory targets
+const (
+	ScalingTriggerHTTPRPS       = "http_rps"
+	ScalingTriggerNATSJetStream = "nats_jetstream"
+	ScalingTriggerKafka         = "kafka"
+)
+
+// ScalingTrigger is an additional autoscaling signal, rendered as a KEDA scaler
+type ScalingTrigger struct {
+	Type          string  `json:"type"`
+	Target        float64 `json:"target"`                   // Per replica: requests per second or pending messages
+	Endpoint      string  `json:"endpoint,omitempty"`       // NATS monitoring endpoint or Kafka bootstrap servers
+	Stream        string  `json:"stream,omitempty"`         // NATS JetStream stream
+	Consumer      string  `json:"consumer,omitempty"`       // NATS JetStream durable consumer
+	Topic         string  `json:"topic,omitempty"`          // Kafka topic
+	ConsumerGroup string  `json:"consumer_group,omitempty"` // Kafka consumer group
+}
+
+// ScalingSchedule overrides the replica range during a recurring time window.
+// Metric-based autoscaling keeps operating within the overridden range.
+type ScalingSchedule struct {
+	Name        string   `json:"name"`
+	Days        []string `json:"days,omitempty"`     // mon..sun; empty means every day
+	Start       string   `json:"start"`              // HH:MM
+	End         string   `json:"end"`                // HH:MM; earlier than Start for windows spanning midnight
+	Timezone    string   `json:"timezone,omitempty"` // IANA name; defaults to UTC
+	MinReplicas int32    `json:"min_replicas"`
+	MaxReplicas int32    `json:"max_replicas"`
 }
 
 // HealthCheck defines the health check configuration
@@ -121,10 +234,234 @@ type Service struct {
 	CurrentBuildID  *uuid.UUID             `json:"current_build_id,omitempty"`
 	CurrentVersion  string                 `json:"current_version,omitempty"`
 	TargetClusterID *uuid.UUID             `json:"target_cluster_id,omitempty"`
+	Catalog         ServiceCatalog         `json:"catalog"`
+	Networking      *ServiceNetworking     `json:"networking,omitempty"`
+	Placement       *ServicePlacement      `json:"placement,omitempty"`
+	CronJob         *CronJobConfig         `json:"cron_job,omitempty"`
+	Availability    *ServiceAvailability   `json:"availability,omitempty"`
+	Volumes         []Volume               `json:"volumes,omitempty"`
+	Replicas        *ServiceReplicas       `json:"replicas,omitempty"` // Observed in the cluster, not configured
 	CreatedAt       time.Time              `json:"created_at"`
 	UpdatedAt       time.Time              `json:"updated_at"`
 }
 
+// ServiceTier ranks how critical a service is, tier-1 being the most critical
+type ServiceTier string
+
+const (
+	ServiceTier1 ServiceTier = "tier-1"
+	ServiceTier2 ServiceTier = "tier-2"
+	ServiceTier3 ServiceTier = "tier-3"
+)
+
+// ServiceLifecycle is the lifecycle stage of a service in the catalog
+type ServiceLifecycle string
+
+const (
+	ServiceLifecycleExperimental ServiceLifecycle = "experimental"
+	ServiceLifecycleProduction   ServiceLifecycle = "production"
+	ServiceLifecycleDeprecated   ServiceLifecycle = "deprecated"
+)
+
+// ServiceCatalog is the ownership and documentation metadata of a service
+// shown in the service catalog
+type ServiceCatalog struct {
+	Owner     string           `json:"owner,omitempty"` // Owning team or contact
+	Tier      ServiceTier      `json:"tier,omitempty"`
+	Lifecycle ServiceLifecycle `json:"lifecycle,omitempty"`
+	DocsURL   string           `json:"docs_url,omitempty"`
+	SLOLinks  []string         `json:"slo_links,omitempty"`
+}
+
+// IPFamily is an IP address family of Kubernetes service networking
+type IPFamily string
+
+const (
+	IPFamilyIPv4 IPFamily = "IPv4"
+	IPFamilyIPv6 IPFamily = "IPv6"
+)
+
+// IPFamilyPolicy is how many IP families a Kubernetes service is given
+type IPFamilyPolicy string
+
+const (
+	IPFamilyPolicySingleStack      IPFamilyPolicy = "SingleStack"
+	IPFamilyPolicyPreferDualStack  IPFamilyPolicy = "PreferDualStack"
+	IPFamilyPolicyRequireDualStack IPFamilyPolicy = "RequireDualStack"
+)
+
+// ServiceExposure is who can reach a service
+type ServiceExposure string
+
+const (
+	ServiceExposurePublic   ServiceExposure = "public"   // Through ingresses
+	ServiceExposureInternal ServiceExposure = "internal" // Only from inside its cluster, under an internal DNS name
+)
+
+// ServiceNetworking sets the IP families and exposure of a service's
+// Kubernetes Service. Families are listed in order of preference; the first
+// is the primary one.
+type ServiceNetworking struct {
+	IPFamilyPolicy IPFamilyPolicy  `json:"ip_family_policy,omitempty"`
+	IPFamilies     []IPFamily      `json:"ip_families,omitempty"`
+	Exposure       ServiceExposure `json:"exposure,omitempty"` // public when empty
+	// Isolated internal services only accept connections from the services
+	// of their project listed by slug in AllowFrom
+	Isolated  bool     `json:"isolated,omitempty"`
+	AllowFrom []string `json:"allow_from,omitempty"`
+	DNSName   string   `json:"dns_name,omitempty"` // Internal name; set by the platform
+}
+
+// ServicePlacement constrains the cluster a service is scheduled on when no
+// target cluster is set
+type ServicePlacement struct {
+	Regions       []string          `json:"regions,omitempty"`        // Preferred cluster regions, most preferred first
+	ClusterLabels map[string]string `json:"cluster_labels,omitempty"` // Labels the cluster must have
+}
+
+// TopologyDomain is a kind of failure domain a service's replicas are spread
+// across
+type TopologyDomain string
+
+const (
+	TopologyZone TopologyDomain = "zone"
+	TopologyNode TopologyDomain = "node"
+)
+
+// ServiceAvailability keeps a service's replicas serving through voluntary
+// disruptions such as node drains, and spreads them across failure domains.
+// Budgets are a count or a percentage of replicas, such as "1" or "50%"; at
+// most one of MinAvailable and MaxUnavailable is set.
+type ServiceAvailability struct {
+	MinAvailable   string           `json:"min_available,omitempty"`   // Replicas kept running through evictions
+	MaxUnavailable string           `json:"max_unavailable,omitempty"` // Replicas evictions may take down at once
+	Spread         []TopologySpread `json:"spread,omitempty"`
+}
+
+// TopologySpread spreads a service's replicas evenly across the zones or
+// nodes of its cluster
+type TopologySpread struct {
+	Topology TopologyDomain `json:"topology"`
+	MaxSkew  int32          `json:"max_skew,omitempty"` // Largest difference in replicas between two domains; 1 when unset
+	Required bool           `json:"required,omitempty"` // Leaves replicas pending rather than exceed the skew; best effort otherwise
+}
+
+// VolumeAccessMode is how many nodes may mount a volume at once
+type VolumeAccessMode string
+
+const (
+	VolumeReadWriteOnce VolumeAccessMode = "ReadWriteOnce" // One node; replicas of the service share it
+	VolumeReadWriteMany VolumeAccessMode = "ReadWriteMany" // Any number of nodes; needs a storage class that supports it
+)
+
+// Volume is a persistent volume of a service, a PersistentVolumeClaim in its
+// target cluster mounted into the service's container. Its name identifies it
+// within the service.
+type Volume struct {
+	Name         string           `json:"name"`
+	Size         string           `json:"size"`                    // Requested capacity, such as "10Gi"; can only grow
+	StorageClass string           `json:"storage_class,omitempty"` // The cluster's default when empty
+	MountPath    string           `json:"mount_path"`
+	AccessMode   VolumeAccessMode `json:"access_mode,omitempty"` // ReadWriteOnce when empty
+}
+
+// VolumeState is the state of a volume's claim in its cluster
+type VolumeState string
+
+const (
+	VolumeNotDeployed VolumeState = "not_deployed" // The service has no target cluster or the claim is not applied yet
+	VolumePending     VolumeState = "pending"      // Waiting for storage to be provisioned
+	VolumeBound       VolumeState = "bound"
+	VolumeResizing    VolumeState = "resizing" // Expanding to the requested size
+	VolumeLost        VolumeState = "lost"     // The storage behind the claim is gone
+)
+
+// VolumeStatus is a volume with the state of its claim as last read from
+// the cluster
+type VolumeStatus struct {
+	Volume
+	Claim    string      `json:"claim"` // Name of the PersistentVolumeClaim
+	State    VolumeState `json:"state"`
+	Capacity string      `json:"capacity,omitempty"` // Provisioned, which trails Size while resizing
+}
+
+// VolumeSnapshot is a point-in-time copy of a volume, taken by the CSI
+// driver of its storage
+type VolumeSnapshot struct {
+	Name        string     `json:"name"`
+	Volume      string     `json:"volume"`
+	ReadyToUse  bool       `json:"ready_to_use"`
+	RestoreSize string     `json:"restore_size,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+// CronConcurrencyPolicy is what a cron job does when a run is due while the
+// previous one is still running
+type CronConcurrencyPolicy string
+
+const (
+	CronConcurrencyAllow   CronConcurrencyPolicy = "Allow"
+	CronConcurrencyForbid  CronConcurrencyPolicy = "Forbid"  // Skips the new run
+	CronConcurrencyReplace CronConcurrencyPolicy = "Replace" // Stops the running one
+)
+
+// CronJobConfig is the schedule of a cron job service and how its runs behave
+type CronJobConfig struct {
+	Schedule                string                `json:"schedule"`                            // Standard five-field cron expression
+	TimeZone                string                `json:"time_zone,omitempty"`                 // IANA zone the schedule is read in; UTC when empty
+	ConcurrencyPolicy       CronConcurrencyPolicy `json:"concurrency_policy,omitempty"`        // Forbid when empty
+	StartingDeadlineSeconds int64                 `json:"starting_deadline_seconds,omitempty"` // How late a missed run may still start
+	ActiveDeadlineSeconds   int64                 `json:"active_deadline_seconds,omitempty"`   // How long a run may take before it is stopped
+	BackoffLimit            int32                 `json:"backoff_limit"`                       // Retries of a failed run
+	Command                 []string              `json:"command,omitempty"`                   // Overrides the image's entrypoint
+	SuccessfulRunsKept      int32                 `json:"successful_runs_kept,omitempty"`      // Completed runs kept with their logs
+	FailedRunsKept          int32                 `json:"failed_runs_kept,omitempty"`          // Failed runs kept with their logs
+	Suspend                 bool                  `json:"suspend"`                             // Stops scheduling new runs
+}
+
+// JobStatus is the state of a run of a Kubernetes Job
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// CronJobRun is a run of a cron job service, scheduled or started on demand
+type CronJobRun struct {
+	Name        string     `json:"name"` // Name of the Kubernetes Job
+	Manual      bool       `json:"manual"`
+	Status      JobStatus  `json:"status"`
+	Pod         string     `json:"pod,omitempty"` // Pod of the latest attempt
+	Attempts    int32      `json:"attempts"`
+	ExitCode    *int32     `json:"exit_code,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// JobRun is a one-off command run against a service's image as a Kubernetes
+// Job, such as a database migration
+type JobRun struct {
+	Name           string     `json:"name"` // Name of the Kubernetes Job
+	ServiceID      uuid.UUID  `json:"service_id"`
+	Command        []string   `json:"command"`
+	TimeoutSeconds int64      `json:"timeout_seconds"`
+	Retries        int32      `json:"retries"`
+	TriggeredBy    string     `json:"triggered_by,omitempty"`
+	Status         JobStatus  `json:"status"`
+	Pod            string     `json:"pod,omitempty"` // Pod of the latest attempt
+	Attempts       int32      `json:"attempts"`
+	ExitCode       *int32     `json:"exit_code,omitempty"`
+	Reason         string     `json:"reason,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
 // ServicePort defines a port exposed by a service
 type ServicePort struct {
 	Name       string `json:"name"`
@@ -155,7 +492,8 @@ type Build struct {
 	ImageTag     string                 `json:"image_tag,omitempty"`
 	ImageDigest  string                 `json:"image_digest,omitempty"`
 	BuildLogs    string                 `json:"build_logs,omitempty"`
-	Duration     int64                  `json:"duration,omitempty"`
+	Duration     int64                  `json:"duration,omitempty"` // Seconds
+	Stages       []BuildStage           `json:"stages,omitempty"`
 	TriggeredBy  string                 `json:"triggered_by"`
 	ErrorMessage string                 `json:"error_message,omitempty"`
 	Metadata     map[string]interface{} `json:"metadata,omitempty"`
@@ -164,6 +502,20 @@ type Build struct {
 	CreatedAt    time.Time              `json:"created_at"`
 }
 
+// Build stage names reported by CI adapters
+const (
+	BuildStageClone        = "clone"
+	BuildStageDependencies = "dependencies"
+	BuildStageCompile      = "compile"
+	BuildStageImagePush    = "image_push"
+)
+
+// BuildStage is the timing of a single build stage
+type BuildStage struct {
+	Name     string `json:"name"`
+	Duration int64  `json:"duration"` // Seconds
+}
+
 // DeploymentStatus represents the current state of a deployment
 type DeploymentStatus string
 
@@ -200,12 +552,57 @@ type Deployment struct {
 	ReadyReplicas   int32                  `json:"ready_replicas"`
 	TriggeredBy     string                 `json:"triggered_by"`
 	ErrorMessage    string                 `json:"error_message,omitempty"`
+	Health          *ReleaseHealth         `json:"health,omitempty"`
+	PrePull         *PrePullStatus         `json:"pre_pull,omitempty"`
 	Metadata        map[string]interface{} `json:"metadata,omitempty"`
 	StartedAt       *time.Time             `json:"started_at,omitempty"`
 	CompletedAt     *time.Time             `json:"completed_at,omitempty"`
 	CreatedAt       time.Time              `json:"created_at"`
 }
 
+// PrePullPhase is the state of pulling a release's image ahead of its rollout
+type PrePullPhase string
+
+const (
+	PrePullPulling   PrePullPhase = "pulling"
+	PrePullCompleted PrePullPhase = "completed"
+	PrePullFailed    PrePullPhase = "failed"
+	PrePullTimedOut  PrePullPhase = "timed_out" // The rollout proceeds; remaining nodes pull on demand
+	PrePullSkipped   PrePullPhase = "skipped"   // First deploy, nothing to learn node placement from
+)
+
+// PrePullStatus is the progress of pulling a release's image onto the target nodes
+type PrePullStatus struct {
+	Phase       PrePullPhase `json:"phase"`
+	Image       string       `json:"image"`
+	NodesReady  int32        `json:"nodes_ready"`
+	NodesTotal  int32        `json:"nodes_total"`
+	Message     string       `json:"message,omitempty"`
+	StartedAt   time.Time    `json:"started_at"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+}
+
+// ReleaseHealthGrade summarizes a release health score
+type ReleaseHealthGrade string
+
+const (
+	ReleaseHealthHealthy   ReleaseHealthGrade = "healthy"
+	ReleaseHealthDegraded  ReleaseHealthGrade = "degraded"
+	ReleaseHealthUnhealthy ReleaseHealthGrade = "unhealthy"
+)
+
+// ReleaseHealth is the health score of a deployment, from 0 (worst) to 100
+type ReleaseHealth struct {
+	Score           float64            `json:"score"`
+	Grade           ReleaseHealthGrade `json:"grade"`
+	Restarts        int64              `json:"restarts"`
+	Instances       int                `json:"instances"`
+	ErrorBudgetBurn float64            `json:"error_budget_burn"`
+	Alerts          int                `json:"alerts"`
+	RolledBack      bool               `json:"rolled_back"`
+	EvaluatedAt     time.Time          `json:"evaluated_at"`
+}
+
 // ClusterProvider represents the cloud provider for a cluster
 type ClusterProvider string
 
@@ -217,6 +614,7 @@ const (
 	ClusterProviderLinode       ClusterProvider = "linode"
 	ClusterProviderOnPrem       ClusterProvider = "on_prem"
 	ClusterProviderK3s          ClusterProvider = "k3s"
+	ClusterProviderRKE2         ClusterProvider = "rke2"
 )
 
 // ClusterStatus represents the current state of a cluster
@@ -248,6 +646,54 @@ type Cluster struct {
 	UpdatedAt        time.Time              `json:"updated_at"`
 }
 
+// NodePool is a group of identically sized nodes of a managed cluster
+type NodePool struct {
+	Name         string `json:"name"`
+	InstanceType string `json:"instance_type,omitempty"` // Machine type of the cloud provider, e.g. m6i.large
+	NodeCount    int32  `json:"node_count"`
+	MinCount     int32  `json:"min_count,omitempty"`
+	MaxCount     int32  `json:"max_count,omitempty"`
+	AutoScaling  bool   `json:"autoscaling"`
+	Version      string `json:"version,omitempty"` // Kubernetes version of the pool's nodes
+}
+
+// ClusterCapacity is how much of a cluster's allocatable resources the pods
+// running on it request
+type ClusterCapacity struct {
+	CPU        ResourceCapacity      `json:"cpu"`    // Cores
+	Memory     ResourceCapacity      `json:"memory"` // Bytes
+	Pods       ResourceCapacity      `json:"pods"`
+	Namespaces []NamespaceAllocation `json:"namespaces"`
+}
+
+// ResourceCapacity is the allocatable and requested amount of a resource
+type ResourceCapacity struct {
+	Allocatable float64 `json:"allocatable"`
+	Requested   float64 `json:"requested"`
+}
+
+// NamespaceAllocation is what the running pods of a namespace request
+type NamespaceAllocation struct {
+	Namespace     string  `json:"namespace"`
+	Pods          int     `json:"pods"`
+	CPURequest    float64 `json:"cpu_request"`    // Cores
+	MemoryRequest float64 `json:"memory_request"` // Bytes
+}
+
+// KubeConfigGrant is the access a short-lived kubeconfig carries
+type KubeConfigGrant struct {
+	Subject     string        `json:"subject"`      // Service account the kubeconfig authenticates as
+	ClusterRole string        `json:"cluster_role"` // Bound in each namespace, or cluster-wide without namespaces
+	Namespaces  []string      `json:"namespaces,omitempty"`
+	TTL         time.Duration `json:"ttl"`
+}
+
+// IssuedKubeConfig is a kubeconfig with short-lived credentials
+type IssuedKubeConfig struct {
+	KubeConfig []byte    `json:"kubeconfig"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
 // EnvironmentType represents the type of environment
 type EnvironmentType string
 
@@ -268,12 +714,57 @@ type Environment struct {
 	Type      EnvironmentType        `json:"type"`
 	Namespace string                 `json:"namespace"`
 	IsDefault bool                   `json:"is_default"`
+	Egress    *Egress                `json:"egress,omitempty"`
+	Lifecycle *EnvironmentLifecycle  `json:"lifecycle,omitempty"`
 	Labels    map[string]string      `json:"labels,omitempty"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 	CreatedAt time.Time              `json:"created_at"`
 	UpdatedAt time.Time              `json:"updated_at"`
 }
 
+// EgressMode is how an environment's outbound traffic gets stable source IPs
+type EgressMode string
+
+const (
+	// EgressModeGateway routes egress through dedicated gateway nodes (Cilium egress gateway)
+	EgressModeGateway EgressMode = "gateway"
+	// EgressModeClusterNAT relies on the cloud NAT gateway in front of the cluster
+	EgressModeClusterNAT EgressMode = "cluster_nat"
+)
+
+// EgressStatus represents the state of an environment's egress configuration
+type EgressStatus string
+
+const (
+	EgressStatusPending EgressStatus = "pending"
+	EgressStatusReady   EgressStatus = "ready"
+	EgressStatusFailed  EgressStatus = "failed"
+)
+
+// Egress gives an environment's outbound traffic static IPs that third
+// parties can allow-list
+type Egress struct {
+	Mode      EgressMode   `json:"mode"`
+	Pool      string       `json:"pool,omitempty"` // Gateway node pool; gateway mode only
+	Status    EgressStatus `json:"status"`
+	IPs       []string     `json:"ips"`
+	Message   string       `json:"message,omitempty"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// EnvironmentLifecycle expires short-lived environments and hibernates them,
+// scaling their workloads to zero, while they get no HTTP traffic
+type EnvironmentLifecycle struct {
+	TTLHours      int  `json:"ttl_hours,omitempty"`       // Deleted this long after creation; 0 for no limit
+	IdleHours     int  `json:"idle_hours,omitempty"`      // Hibernated after this long without requests; 0 never
+	WakeOnRequest bool `json:"wake_on_request,omitempty"` // Requests to a hibernated environment scale it back up
+
+	// Set by the platform
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	HibernatedAt *time.Time `json:"hibernated_at,omitempty"`
+	WokenAt      *time.Time `json:"woken_at,omitempty"`
+}
+
 // SecretType represents the type of secret
 type SecretType string
 
@@ -299,6 +790,90 @@ type Secret struct {
 	UpdatedAt time.Time         `json:"updated_at"`
 }
 
+// SecretReplicationStatus is the state of a secret's copy in one cluster
+type SecretReplicationStatus string
+
+const (
+	SecretReplicationPending SecretReplicationStatus = "pending" // Applied, the cluster has not caught up yet
+	SecretReplicationSynced  SecretReplicationStatus = "synced"
+	SecretReplicationFailed  SecretReplicationStatus = "failed"
+	SecretReplicationStale   SecretReplicationStatus = "stale" // Behind Vault for longer than the grace period
+)
+
+// SecretReplication tracks the copy of a Vault secret that External Secrets
+// Operator keeps in one namespace of a cluster
+type SecretReplication struct {
+	ID             uuid.UUID               `json:"id"`
+	SecretID       uuid.UUID               `json:"secret_id"`
+	ProjectID      uuid.UUID               `json:"project_id"`
+	ClusterID      uuid.UUID               `json:"cluster_id"`
+	Namespace      string                  `json:"namespace"`
+	SecretName     string                  `json:"secret_name"`
+	DesiredVersion int                     `json:"desired_version"` // Current version in Vault
+	SyncedVersion  int                     `json:"synced_version"`  // Version of the copy in the cluster
+	Status         SecretReplicationStatus `json:"status"`
+	Message        string                  `json:"message,omitempty"`
+	DesiredSince   time.Time               `json:"desired_since"` // When Vault moved to DesiredVersion
+	LastSyncedAt   *time.Time              `json:"last_synced_at,omitempty"`
+	CheckedAt      time.Time               `json:"checked_at"`
+	CreatedAt      time.Time               `json:"created_at"`
+	UpdatedAt      time.Time               `json:"updated_at"`
+}
+
+// WebhookSubscription delivers a project's platform events of the given
+// types to an HTTPS endpoint, signed with a per-subscription HMAC secret
+type WebhookSubscription struct {
+	ID          uuid.UUID `json:"id"`
+	ProjectID   uuid.UUID `json:"project_id"`
+	URL         string    `json:"url"`
+	EventTypes  []string  `json:"event_types"` // e.g. deploy.completed, or * for every type
+	Secret      string    `json:"-"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedBy   uuid.UUID `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Matches reports whether the subscription receives events of the given type
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	for _, t := range s.EventTypes {
+		if t == "*" || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is the outcome of one delivery attempt
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending" // Queued, sent once due
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"  // Retried later
+	WebhookDeliveryGaveUp    WebhookDeliveryStatus = "gave_up" // Failed on the last attempt
+)
+
+// WebhookDelivery logs one attempt to deliver an event to a webhook
+// subscription. Attempts are queued as pending, with the request body, until
+// they are sent.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id"`
+	SubscriptionID uuid.UUID             `json:"subscription_id"`
+	EventID        string                `json:"event_id"`
+	EventType      string                `json:"event_type"`
+	Attempt        int                   `json:"attempt"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	ResponseStatus int                   `json:"response_status,omitempty"`
+	Error          string                `json:"error,omitempty"`
+	DurationMS     int64                 `json:"duration_ms"`
+	Test           bool                  `json:"test"`
+	NextAttemptAt  *time.Time            `json:"next_attempt_at,omitempty"` // When a pending attempt is due
+	Payload        []byte                `json:"-"`                         // Body of a pending attempt
+	CreatedAt      time.Time             `json:"created_at"`
+}
+
 // IngressType represents the type of ingress
 type IngressType string
 
@@ -313,6 +888,39 @@ type TLSConfig struct {
 	Enabled    bool   `json:"enabled"`
 	SecretName string `json:"secret_name,omitempty"`
 	AutoTLS    bool   `json:"auto_tls"` // Let's Encrypt via cert-manager
+	// Challenge proves control of the domain to the ACME CA: http01 or
+	// dns01; the platform default when empty
+	Challenge   ACMEChallenge `json:"challenge,omitempty"`
+	Certificate *Certificate  `json:"certificate,omitempty"` // Of auto TLS; set by the platform
+}
+
+// ACMEChallenge is how an ACME CA verifies control of a domain
+type ACMEChallenge string
+
+const (
+	ACMEChallengeHTTP01 ACMEChallenge = "http01" // A token served by the ingress controller
+	ACMEChallengeDNS01  ACMEChallenge = "dns01"  // A TXT record in the domain's zone
+)
+
+// CertificateStatus represents the state of an auto TLS certificate
+type CertificateStatus string
+
+const (
+	CertificateStatusPending CertificateStatus = "pending" // Being issued or renewed
+	CertificateStatusReady   CertificateStatus = "ready"
+	CertificateStatusFailed  CertificateStatus = "failed" // Retried by cert-manager with backoff
+)
+
+// Certificate tracks the certificate cert-manager issues for an ingress
+type Certificate struct {
+	Status      CertificateStatus `json:"status"`
+	Message     string            `json:"message,omitempty"`
+	Challenge   ACMEChallenge     `json:"challenge"`
+	ClusterID   *uuid.UUID        `json:"cluster_id,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	NotAfter    *time.Time        `json:"not_after,omitempty"`
+	RenewalTime *time.Time        `json:"renewal_time,omitempty"`
+	UpdatedAt   time.Time         `json:"updated_at"`
 }
 
 // Ingress represents an ingress route for a service
@@ -326,10 +934,125 @@ type Ingress struct {
 	TLS         TLSConfig         `json:"tls"`
 	Annotations map[string]string `json:"annotations,omitempty"`
 	Labels      map[string]string `json:"labels,omitempty"`
+	IPFamilies  []IPFamily        `json:"ip_families,omitempty"` // Address families published in DNS; IPv4 when empty
+	Routing     IngressRouting    `json:"routing"`
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
 }
 
+// IngressRouting holds the routing options of an ingress, which are rendered
+// in the annotation dialect of the ingress controller of the cluster
+type IngressRouting struct {
+	// RewritePath replaces the matched path prefix before requests reach the
+	// service; "/" strips it
+	RewritePath    string          `json:"rewrite_path,omitempty"`
+	MaxBodySizeMB  int             `json:"max_body_size_mb,omitempty"` // 0 for the controller default
+	StickySessions *StickySessions `json:"sticky_sessions,omitempty"`
+	RateLimit      *RateLimit      `json:"rate_limit,omitempty"`
+	// AllowCIDRs admits only clients from these ranges; DenyCIDRs rejects
+	// clients from these ranges. Entries are CIDRs or single addresses.
+	AllowCIDRs []string `json:"allow_cidrs,omitempty"`
+	DenyCIDRs  []string `json:"deny_cidrs,omitempty"`
+	// Rules redirect, rewrite and add headers, applied in order after the
+	// options above
+	Rules []IngressRule `json:"rules,omitempty"`
+}
+
+// IngressRuleType is what an ingress routing rule does
+type IngressRuleType string
+
+const (
+	IngressRuleRedirectHost    IngressRuleType = "redirect_host"    // Redirects another host, such as www, to the ingress domain
+	IngressRuleForceHTTPS      IngressRuleType = "force_https"      // Redirects plain HTTP requests to HTTPS
+	IngressRuleRewrite         IngressRuleType = "rewrite"          // Rewrites the request path with a regular expression
+	IngressRuleResponseHeaders IngressRuleType = "response_headers" // Sets headers on responses, such as HSTS or CSP
+)
+
+// IngressRule is a redirect, rewrite or header rule of an ingress. Only the
+// fields of its type are set.
+type IngressRule struct {
+	Type        IngressRuleType   `json:"type"`
+	Host        string            `json:"host,omitempty"`        // redirect_host
+	Permanent   bool              `json:"permanent,omitempty"`   // redirect_host, force_https: 308 instead of 307
+	Regex       string            `json:"regex,omitempty"`       // rewrite
+	Replacement string            `json:"replacement,omitempty"` // rewrite; $1 refers to the first group
+	Headers     map[string]string `json:"headers,omitempty"`     // response_headers
+}
+
+// Hosts returns the domain of an ingress followed by the hosts its rules
+// redirect to it
+func (i *Ingress) Hosts() []string {
+	hosts := []string{i.Domain}
+	for _, rule := range i.Routing.Rules {
+		if rule.Type == IngressRuleRedirectHost {
+			hosts = append(hosts, strings.ToLower(rule.Host))
+		}
+	}
+	return hosts
+}
+
+// RateLimit caps the request rate of each client address at the edge
+type RateLimit struct {
+	RequestsPerSecond int `json:"requests_per_second"`
+	Burst             int `json:"burst,omitempty"` // Requests allowed above the rate at once
+}
+
+// StickySessions pins a client to one replica with a cookie
+type StickySessions struct {
+	CookieName    string `json:"cookie_name,omitempty"`     // northstack-affinity when empty
+	MaxAgeSeconds int    `json:"max_age_seconds,omitempty"` // 0 for a session cookie
+}
+
+// DomainVerificationMethod is the DNS record that proves control of a
+// custom domain
+type DomainVerificationMethod string
+
+const (
+	DomainVerificationTXT   DomainVerificationMethod = "txt"
+	DomainVerificationCNAME DomainVerificationMethod = "cname"
+)
+
+// DomainStatus represents the verification state of a custom domain
+type DomainStatus string
+
+const (
+	DomainStatusPending  DomainStatus = "pending"
+	DomainStatusVerified DomainStatus = "verified"
+	DomainStatusFailed   DomainStatus = "failed" // Not verified before the claim expired; it can still be verified on request
+)
+
+// CustomDomain is a domain a project has claimed for its ingresses. Until its
+// DNS carries the project's challenge record, no ingress may route it. A
+// verified domain covers its subdomains, and is verified by one project at a
+// time.
+type CustomDomain struct {
+	ID         uuid.UUID                `json:"id"`
+	ProjectID  uuid.UUID                `json:"project_id"`
+	Name       string                   `json:"name"`
+	Method     DomainVerificationMethod `json:"method"`
+	Token      string                   `json:"token"`
+	Status     DomainStatus             `json:"status"`
+	Message    string                   `json:"message,omitempty"` // Why the last check failed
+	Record     *DNSRecord               `json:"record,omitempty"`  // The challenge to publish; set by the API
+	VerifiedAt *time.Time               `json:"verified_at,omitempty"`
+	CheckedAt  *time.Time               `json:"checked_at,omitempty"`
+	CreatedBy  uuid.UUID                `json:"created_by"`
+	CreatedAt  time.Time                `json:"created_at"`
+	UpdatedAt  time.Time                `json:"updated_at"`
+}
+
+// Covers reports whether a verified domain allows routing a host
+func (d *CustomDomain) Covers(host string) bool {
+	return d.Status == DomainStatusVerified && (host == d.Name || strings.HasSuffix(host, "."+d.Name))
+}
+
+// DNSRecord is a DNS record a user must publish
+type DNSRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
 // PipelineStatus represents the status of a pipeline
 type PipelineStatus string
 
@@ -425,6 +1148,35 @@ type TeamMembership struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Template is a pre-configured project: a northstack.yaml manifest whose
+// ${VARIABLE} placeholders are filled in when the template is instantiated.
+// Built-in templates ship with the platform; users add their own.
+type Template struct {
+	ID          uuid.UUID          `json:"id"`
+	Slug        string             `json:"slug"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Category    string             `json:"category,omitempty"`
+	Tags        []string           `json:"tags"`
+	Manifest    string             `json:"manifest"` // YAML
+	Variables   []TemplateVariable `json:"variables"`
+	Builtin     bool               `json:"builtin"`
+	CreatedBy   *uuid.UUID         `json:"created_by,omitempty"` // Nil for built-in templates
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// TemplateVariable is a placeholder of a template's manifest
+type TemplateVariable struct {
+	Name        string `json:"name"` // Referenced as ${NAME}
+	Description string `json:"description,omitempty"`
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Generate fills the variable with a random password when no value is
+	// given, e.g. for database credentials
+	Generate bool `json:"generate,omitempty"`
+}
+
 // AuditAction represents an auditable action
 type AuditAction string
 
@@ -438,6 +1190,14 @@ const (
 	AuditActionRestart AuditAction = "restart"
 	AuditActionLogin   AuditAction = "login"
 	AuditActionLogout  AuditAction = "logout"
+
+	AuditActionPortForward AuditAction = "port_forward"
+	AuditActionIssueCredentials AuditAction = "issue_credentials"
+
+	AuditActionApprove  AuditAction = "approve"
+	AuditActionReject   AuditAction = "reject"
+	AuditActionDelegate AuditAction = "delegate"
+	AuditActionEscalate AuditAction = "escalate"
 )
 
 // AuditLog represents an audit log entry
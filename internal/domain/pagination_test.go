@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	c := Cursor{CreatedAt: time.Date(2026, 3, 1, 12, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+
+	decoded, err := DecodeCursor(c.Encode())
+	require.NoError(t, err)
+	assert.True(t, c.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, c.ID, decoded.ID)
+
+	_, err = DecodeCursor("not a cursor!")
+	assert.Error(t, err)
+	_, err = DecodeCursor("bm90IGpzb24")
+	assert.Error(t, err)
+}
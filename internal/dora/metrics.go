@@ -0,0 +1,282 @@
+package dora
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tier is a DORA performance tier
+type Tier string
+
+const (
+	TierElite  Tier = "elite"
+	TierHigh   Tier = "high"
+	TierMedium Tier = "medium"
+	TierLow    Tier = "low"
+	// TierNoData is given to metrics with nothing to measure in the window
+	TierNoData Tier = "no_data"
+)
+
+// Direction describes how a metric moved relative to the previous window
+type Direction string
+
+const (
+	DirectionImproving Direction = "improving"
+	DirectionStable    Direction = "stable"
+	DirectionDeclining Direction = "declining"
+)
+
+// trendThreshold is the relative change that counts as a trend
+const trendThreshold = 0.1
+
+// Change is a single production change derived from a deployment and its build
+type Change struct {
+	ServiceID    uuid.UUID
+	DeploymentID uuid.UUID
+	CommittedAt  time.Time // Zero when the originating commit is unknown
+	DeployedAt   time.Time
+	Failed       bool
+	Author       string
+}
+
+// Metrics are the four DORA keys plus developer activity for a window
+type Metrics struct {
+	Deployments         int     `json:"deployments"`
+	DeploymentFrequency float64 `json:"deployment_frequency_per_day"`
+	LeadTimeHours       float64 `json:"lead_time_hours"`
+	LeadTimeSamples     int     `json:"lead_time_samples"` // Deployments with a known commit time
+	ChangeFailureRate   float64 `json:"change_failure_rate"`
+	Failures            int     `json:"failures"`
+	MTTRHours           float64 `json:"mttr_hours"`
+	Restores            int     `json:"restores"` // Failures restored by a later change
+	OpenIncidents       int     `json:"open_incidents"`
+	Contributors        int     `json:"contributors"`
+}
+
+// Tiers classifies each metric against the DORA benchmarks
+type Tiers struct {
+	DeploymentFrequency Tier `json:"deployment_frequency"`
+	LeadTime            Tier `json:"lead_time"`
+	ChangeFailureRate   Tier `json:"change_failure_rate"`
+	MTTR                Tier `json:"mttr"`
+}
+
+// Trends compares each metric with the previous window
+type Trends struct {
+	DeploymentFrequency Direction `json:"deployment_frequency"`
+	LeadTime            Direction `json:"lead_time"`
+	ChangeFailureRate   Direction `json:"change_failure_rate"`
+	MTTR                Direction `json:"mttr"`
+}
+
+// Bucket is a weekly slice of the window
+type Bucket struct {
+	Start         time.Time `json:"start"`
+	Deployments   int       `json:"deployments"`
+	Failures      int       `json:"failures"`
+	LeadTimeHours float64   `json:"lead_time_hours"`
+}
+
+// Compute derives the metrics for changes deployed in [start, end)
+func Compute(changes []Change, start, end time.Time) Metrics {
+	var m Metrics
+	var leadTimes, restoreTimes []float64
+	authors := make(map[string]struct{})
+
+	byService := make(map[uuid.UUID][]Change)
+	for _, c := range changes {
+		byService[c.ServiceID] = append(byService[c.ServiceID], c)
+	}
+	for _, list := range byService {
+		sort.Slice(list, func(i, j int) bool { return list[i].DeployedAt.Before(list[j].DeployedAt) })
+	}
+
+	for _, list := range byService {
+		for i, c := range list {
+			if c.DeployedAt.Before(start) || !c.DeployedAt.Before(end) {
+				continue
+			}
+
+			if c.Author != "" {
+				authors[c.Author] = struct{}{}
+			}
+
+			if !c.Failed {
+				m.Deployments++
+				if !c.CommittedAt.IsZero() && c.DeployedAt.After(c.CommittedAt) {
+					leadTimes = append(leadTimes, c.DeployedAt.Sub(c.CommittedAt).Hours())
+				}
+				continue
+			}
+
+			// A failure is restored by the next successful change to the same service
+			m.Failures++
+			restored := false
+			for _, next := range list[i+1:] {
+				if !next.Failed {
+					restoreTimes = append(restoreTimes, next.DeployedAt.Sub(c.DeployedAt).Hours())
+					restored = true
+					break
+				}
+			}
+			if !restored {
+				m.OpenIncidents++
+			}
+		}
+	}
+
+	if days := end.Sub(start).Hours() / 24; days > 0 {
+		m.DeploymentFrequency = round(float64(m.Deployments) / days)
+	}
+	if total := m.Deployments + m.Failures; total > 0 {
+		m.ChangeFailureRate = round(float64(m.Failures) / float64(total))
+	}
+	m.LeadTimeHours = round(median(leadTimes))
+	m.LeadTimeSamples = len(leadTimes)
+	m.MTTRHours = round(median(restoreTimes))
+	m.Restores = len(restoreTimes)
+	m.Contributors = len(authors)
+
+	return m
+}
+
+// Weekly splits the window into seven-day buckets, oldest first
+func Weekly(changes []Change, start, end time.Time) []Bucket {
+	const week = 7 * 24 * time.Hour
+
+	var buckets []Bucket
+	for from := start; from.Before(end); from = from.Add(week) {
+		to := from.Add(week)
+		if to.After(end) {
+			to = end
+		}
+
+		b := Bucket{Start: from}
+		var leadTimes []float64
+		for _, c := range changes {
+			if c.DeployedAt.Before(from) || !c.DeployedAt.Before(to) {
+				continue
+			}
+			if c.Failed {
+				b.Failures++
+				continue
+			}
+			b.Deployments++
+			if !c.CommittedAt.IsZero() && c.DeployedAt.After(c.CommittedAt) {
+				leadTimes = append(leadTimes, c.DeployedAt.Sub(c.CommittedAt).Hours())
+			}
+		}
+		b.LeadTimeHours = round(median(leadTimes))
+		buckets = append(buckets, b)
+	}
+	return buckets
+}
+
+// Classify maps metrics to performance tiers
+func Classify(m Metrics) Tiers {
+	return Tiers{
+		DeploymentFrequency: frequencyTier(m.DeploymentFrequency),
+		LeadTime:            durationTier(m.LeadTimeSamples, m.LeadTimeHours, 24, 7*24, 30*24),
+		ChangeFailureRate:   rateTier(m.Deployments+m.Failures, m.ChangeFailureRate),
+		MTTR:                durationTier(m.Restores, m.MTTRHours, 1, 24, 7*24),
+	}
+}
+
+// Compare computes the direction of each metric relative to the previous window
+func Compare(current, previous Metrics) Trends {
+	return Trends{
+		DeploymentFrequency: direction(current.DeploymentFrequency, previous.DeploymentFrequency, true),
+		LeadTime:            direction(current.LeadTimeHours, previous.LeadTimeHours, false),
+		ChangeFailureRate:   direction(current.ChangeFailureRate, previous.ChangeFailureRate, false),
+		MTTR:                direction(current.MTTRHours, previous.MTTRHours, false),
+	}
+}
+
+func frequencyTier(perDay float64) Tier {
+	switch {
+	case perDay >= 1:
+		return TierElite
+	case perDay >= 1.0/7:
+		return TierHigh
+	case perDay >= 1.0/30:
+		return TierMedium
+	default:
+		return TierLow
+	}
+}
+
+// durationTier classifies a median duration in hours taken over samples values
+func durationTier(samples int, hours, elite, high, medium float64) Tier {
+	switch {
+	case samples == 0:
+		return TierNoData
+	case hours < elite:
+		return TierElite
+	case hours < high:
+		return TierHigh
+	case hours < medium:
+		return TierMedium
+	default:
+		return TierLow
+	}
+}
+
+// rateTier classifies a failure rate taken over samples changes
+func rateTier(samples int, rate float64) Tier {
+	switch {
+	case samples == 0:
+		return TierNoData
+	case rate <= 0.05:
+		return TierElite
+	case rate <= 0.10:
+		return TierHigh
+	case rate <= 0.15:
+		return TierMedium
+	default:
+		return TierLow
+	}
+}
+
+func direction(current, previous float64, higherIsBetter bool) Direction {
+	if previous == 0 {
+		// Without a baseline only new activity counts as movement
+		if higherIsBetter && current > 0 {
+			return DirectionImproving
+		}
+		return DirectionStable
+	}
+
+	change := (current - previous) / previous
+	if !higherIsBetter {
+		change = -change
+	}
+
+	switch {
+	case change >= trendThreshold:
+		return DirectionImproving
+	case change <= -trendThreshold:
+		return DirectionDeclining
+	default:
+		return DirectionStable
+	}
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,81 @@
+package dora
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompute(t *testing.T) {
+	end := time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)
+	start := end.Add(-10 * 24 * time.Hour)
+	svc := uuid.New()
+
+	at := func(day, hour int) time.Time { return start.Add(time.Duration(day*24+hour) * time.Hour) }
+
+	changes := []Change{
+		{ServiceID: svc, DeployedAt: at(1, 0), CommittedAt: at(0, 22), Author: "alice"},
+		{ServiceID: svc, DeployedAt: at(2, 0), Failed: true, Author: "bob"},
+		{ServiceID: svc, DeployedAt: at(2, 3), CommittedAt: at(2, 1), Author: "bob"},
+		{ServiceID: svc, DeployedAt: at(5, 0), CommittedAt: at(4, 20), Author: "alice"},
+		{ServiceID: svc, DeployedAt: at(9, 0), Failed: true},
+		// Outside the window
+		{ServiceID: svc, DeployedAt: start.Add(-time.Hour)},
+	}
+
+	m := Compute(changes, start, end)
+	assert.Equal(t, 3, m.Deployments)
+	assert.Equal(t, 2, m.Failures)
+	assert.Equal(t, 0.3, m.DeploymentFrequency)
+	assert.Equal(t, 2.0, m.LeadTimeHours)
+	assert.Equal(t, 0.4, m.ChangeFailureRate)
+	assert.Equal(t, 3.0, m.MTTRHours)
+	assert.Equal(t, 3, m.LeadTimeSamples)
+	assert.Equal(t, 1, m.Restores)
+	assert.Equal(t, 1, m.OpenIncidents)
+	assert.Equal(t, 2, m.Contributors)
+
+	weekly := Weekly(changes, start, end)
+	assert.Len(t, weekly, 2)
+	assert.Equal(t, 3, weekly[0].Deployments)
+	assert.Equal(t, 1, weekly[1].Failures)
+}
+
+func TestClassifyAndCompare(t *testing.T) {
+	tiers := Classify(Metrics{
+		Deployments: 8, DeploymentFrequency: 2, LeadTimeHours: 30, LeadTimeSamples: 8,
+		Failures: 2, ChangeFailureRate: 0.2, MTTRHours: 0.5, Restores: 2,
+	})
+	assert.Equal(t, TierElite, tiers.DeploymentFrequency)
+	assert.Equal(t, TierHigh, tiers.LeadTime)
+	assert.Equal(t, TierLow, tiers.ChangeFailureRate)
+	assert.Equal(t, TierElite, tiers.MTTR)
+
+	trends := Compare(
+		Metrics{DeploymentFrequency: 2, LeadTimeHours: 10, ChangeFailureRate: 0.1, MTTRHours: 1},
+		Metrics{DeploymentFrequency: 1, LeadTimeHours: 20, ChangeFailureRate: 0.1, MTTRHours: 0},
+	)
+	assert.Equal(t, DirectionImproving, trends.DeploymentFrequency)
+	assert.Equal(t, DirectionImproving, trends.LeadTime)
+	assert.Equal(t, DirectionStable, trends.ChangeFailureRate)
+	assert.Equal(t, DirectionStable, trends.MTTR)
+}
+
+func TestClassifyWithoutData(t *testing.T) {
+	end := time.Date(2026, 1, 11, 0, 0, 0, 0, time.UTC)
+	tiers := Classify(Compute(nil, end.Add(-30*24*time.Hour), end))
+	assert.Equal(t, TierLow, tiers.DeploymentFrequency)
+	assert.Equal(t, TierNoData, tiers.LeadTime)
+	assert.Equal(t, TierNoData, tiers.ChangeFailureRate)
+	assert.Equal(t, TierNoData, tiers.MTTR)
+
+	// Deploys without known commits or failures leave those metrics unmeasured
+	svc := uuid.New()
+	tiers = Classify(Compute([]Change{{ServiceID: svc, DeployedAt: end.Add(-time.Hour)}}, end.Add(-24*time.Hour), end))
+	assert.Equal(t, TierElite, tiers.DeploymentFrequency)
+	assert.Equal(t, TierNoData, tiers.LeadTime)
+	assert.Equal(t, TierElite, tiers.ChangeFailureRate)
+	assert.Equal(t, TierNoData, tiers.MTTR)
+}
@@ -0,0 +1,172 @@
+// Package dora derives the DORA delivery metrics (deployment frequency, lead
+// time for changes, change failure rate and time to restore) for a project from
+// its builds and deployments.
+package dora
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// CommitTimestampKey is the build (or external deployment) metadata key
+// holding the RFC3339 commit time.
+// Builds without it fall back to their creation time for lead time.
+const CommitTimestampKey = "commit_timestamp"
+
+// maxDeploymentsPerService bounds the history loaded for a single service
+const maxDeploymentsPerService = 1000
+
+// Report is the DORA report for a project
+type Report struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Window    string    `json:"window"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Current   Metrics   `json:"current"`
+	Previous  Metrics   `json:"previous"`
+	Tiers     Tiers     `json:"tiers"`
+	Trends    Trends    `json:"trends"`
+	Weekly    []Bucket  `json:"weekly"`
+}
+
+// Reporter builds DORA reports
+type Reporter struct {
+	serviceRepo domain.ServiceRepository
+	deployRepo  domain.DeploymentRepository
+	buildRepo   domain.BuildRepository
+	logger      *logger.Logger
+}
+
+// NewReporter creates a new Reporter. buildRepo may be nil, in which case lead
+// time is not reported.
+func NewReporter(serviceRepo domain.ServiceRepository, deployRepo domain.DeploymentRepository, buildRepo domain.BuildRepository, log *logger.Logger) *Reporter {
+	return &Reporter{
+		serviceRepo: serviceRepo,
+		deployRepo:  deployRepo,
+		buildRepo:   buildRepo,
+		logger:      log,
+	}
+}
+
+// Report computes the metrics for the window ending now and compares them with
+// the window before it
+func (r *Reporter) Report(ctx context.Context, projectID uuid.UUID, window time.Duration, label string) (*Report, error) {
+	end := time.Now().UTC()
+	start := end.Add(-window)
+	previousStart := start.Add(-window)
+
+	changes, err := r.changes(ctx, projectID, previousStart)
+	if err != nil {
+		return nil, err
+	}
+
+	current := Compute(changes, start, end)
+	previous := Compute(changes, previousStart, start)
+
+	return &Report{
+		ProjectID: projectID,
+		Window:    label,
+		Start:     start,
+		End:       end,
+		Current:   current,
+		Previous:  previous,
+		Tiers:     Classify(current),
+		Trends:    Compare(current, previous),
+		Weekly:    Weekly(changes, start, end),
+	}, nil
+}
+
+// changes loads every finished deployment in the project since the given time
+func (r *Reporter) changes(ctx context.Context, projectID uuid.UUID, since time.Time) ([]Change, error) {
+	services, err := r.serviceRepo.ListByProject(ctx, projectID, domain.ServiceFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	builds, err := r.builds(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, svc := range services {
+		deployments, err := r.deployRepo.ListByService(ctx, svc.ID, maxDeploymentsPerService)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range deployments {
+			change, ok := toChange(d, builds[d.BuildID])
+			if !ok || change.DeployedAt.Before(since) {
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+func (r *Reporter) builds(ctx context.Context, projectID uuid.UUID) (map[uuid.UUID]*domain.Build, error) {
+	builds := make(map[uuid.UUID]*domain.Build)
+	if r.buildRepo == nil {
+		return builds, nil
+	}
+
+	list, err := r.buildRepo.ListByProject(ctx, projectID, maxDeploymentsPerService)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range list {
+		builds[b.ID] = b
+	}
+	return builds, nil
+}
+
+// toChange converts a finished deployment; in-flight deployments are skipped.
+// Failed and rolled back deployments, and releases graded unhealthy, count as
+// failed changes.
+func toChange(d *domain.Deployment, build *domain.Build) (Change, bool) {
+	var failed bool
+	switch d.Status {
+	case domain.DeploymentStatusSucceeded:
+		failed = d.Health != nil && d.Health.Grade == domain.ReleaseHealthUnhealthy
+	case domain.DeploymentStatusFailed, domain.DeploymentStatusRolledBack:
+		failed = true
+	default:
+		return Change{}, false
+	}
+
+	deployedAt := d.CreatedAt
+	if d.CompletedAt != nil {
+		deployedAt = *d.CompletedAt
+	}
+
+	change := Change{
+		ServiceID:    d.ServiceID,
+		DeploymentID: d.ID,
+		DeployedAt:   deployedAt,
+		Failed:       failed,
+		Author:       d.TriggeredBy,
+	}
+
+	if build != nil {
+		change.CommittedAt = build.CreatedAt
+		if ts, ok := build.Metadata[CommitTimestampKey].(string); ok {
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				change.CommittedAt = t
+			}
+		}
+	} else if ts, ok := d.Metadata[CommitTimestampKey].(string); ok {
+		// Deployments registered by external CD tools carry the commit time themselves
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			change.CommittedAt = t
+		}
+	}
+
+	return change, true
+}
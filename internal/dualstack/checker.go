@@ -0,0 +1,100 @@
+package dualstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Checker validates networking against the clusters a service deploys to
+type Checker struct {
+	clusterRepo    domain.ClusterRepository
+	envRepo        domain.EnvironmentRepository
+	clusterManager domain.ClusterManagerAdapter
+	logger         *logger.Logger
+}
+
+// NewChecker creates a new Checker. Without a cluster manager, cluster
+// capabilities are unknown and only the options themselves are validated.
+func NewChecker(
+	clusterRepo domain.ClusterRepository,
+	envRepo domain.EnvironmentRepository,
+	clusterManager domain.ClusterManagerAdapter,
+	log *logger.Logger,
+) *Checker {
+	return &Checker{
+		clusterRepo:    clusterRepo,
+		envRepo:        envRepo,
+		clusterManager: clusterManager,
+		logger:         log,
+	}
+}
+
+// Check validates n and rejects it when a cluster the service deploys to
+// cannot provide its IP families. Clusters whose health cannot be read are
+// skipped rather than blocking the change.
+func (c *Checker) Check(ctx context.Context, service *domain.Service, n *domain.ServiceNetworking) error {
+	if err := Validate(n); err != nil {
+		return err
+	}
+	if n == nil || c == nil || c.clusterManager == nil {
+		return nil
+	}
+
+	clusters, err := c.clusters(ctx, service)
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		if cluster.RancherClusterID == "" {
+			continue
+		}
+		health, err := c.clusterManager.GetClusterHealth(ctx, cluster.RancherClusterID)
+		if err != nil {
+			c.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to read cluster IP families")
+			continue
+		}
+		if reason := Unsupported(n, health.IPFamilies); reason != "" {
+			return errors.BadRequest(fmt.Sprintf("cluster %s %s (requested %s)", cluster.Slug, reason, Describe(n)))
+		}
+	}
+	return nil
+}
+
+// clusters returns the service's target cluster, or else the clusters of its
+// project's environments
+func (c *Checker) clusters(ctx context.Context, service *domain.Service) ([]*domain.Cluster, error) {
+	ids := []uuid.UUID{}
+	if service.TargetClusterID != nil {
+		ids = append(ids, *service.TargetClusterID)
+	} else {
+		environments, err := c.envRepo.ListByProject(ctx, service.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[uuid.UUID]bool)
+		for _, env := range environments {
+			if !seen[env.ClusterID] {
+				seen[env.ClusterID] = true
+				ids = append(ids, env.ClusterID)
+			}
+		}
+	}
+
+	clusters := make([]*domain.Cluster, 0, len(ids))
+	for _, id := range ids {
+		cluster, err := c.clusterRepo.GetByID(ctx, id)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
@@ -0,0 +1,147 @@
+// Package dualstack validates the IPv4/IPv6 options of services and ingresses
+// and renders them into the manifests and DNS records generated for them.
+// Clusters report the service IP families they were set up with through the
+// cluster adapter; a cluster reporting none is taken to be IPv4 only.
+package dualstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ValidateFamilies checks that families holds at most one of each family
+func ValidateFamilies(families []domain.IPFamily) error {
+	seen := make(map[domain.IPFamily]bool)
+	for _, family := range families {
+		if family != domain.IPFamilyIPv4 && family != domain.IPFamilyIPv6 {
+			return errors.BadRequest(fmt.Sprintf("ip family must be %s or %s", domain.IPFamilyIPv4, domain.IPFamilyIPv6))
+		}
+		if seen[family] {
+			return errors.BadRequest("ip family " + string(family) + " is listed twice")
+		}
+		seen[family] = true
+	}
+	return nil
+}
+
+// Validate checks the networking options of a service
+func Validate(n *domain.ServiceNetworking) error {
+	if n == nil {
+		return nil
+	}
+	switch n.IPFamilyPolicy {
+	case "", domain.IPFamilyPolicySingleStack, domain.IPFamilyPolicyPreferDualStack, domain.IPFamilyPolicyRequireDualStack:
+	default:
+		return errors.BadRequest(fmt.Sprintf("ip_family_policy must be %s, %s or %s",
+			domain.IPFamilyPolicySingleStack, domain.IPFamilyPolicyPreferDualStack, domain.IPFamilyPolicyRequireDualStack))
+	}
+	if err := ValidateFamilies(n.IPFamilies); err != nil {
+		return err
+	}
+	if len(n.IPFamilies) > 1 && (n.IPFamilyPolicy == "" || n.IPFamilyPolicy == domain.IPFamilyPolicySingleStack) {
+		return errors.BadRequest("two ip families need a PreferDualStack or RequireDualStack ip_family_policy")
+	}
+	return nil
+}
+
+// Unsupported returns why a cluster with the given service IP families cannot
+// run a service with networking n, or "" when it can. With PreferDualStack
+// only the primary family has to be available; Kubernetes drops the other.
+func Unsupported(n *domain.ServiceNetworking, cluster []domain.IPFamily) string {
+	if n == nil {
+		return ""
+	}
+	if len(cluster) == 0 {
+		cluster = []domain.IPFamily{domain.IPFamilyIPv4}
+	}
+	if n.IPFamilyPolicy == domain.IPFamilyPolicyRequireDualStack && len(cluster) < 2 {
+		return "is not dual-stack"
+	}
+
+	required := n.IPFamilies
+	if n.IPFamilyPolicy == domain.IPFamilyPolicyPreferDualStack && len(required) > 1 {
+		required = required[:1]
+	}
+	for _, family := range required {
+		if !contains(cluster, family) {
+			return "does not support " + string(family)
+		}
+	}
+	return ""
+}
+
+// ForIngress returns the networking an ingress's families ask of the cluster
+// serving it: every listed family must be available
+func ForIngress(families []domain.IPFamily) *domain.ServiceNetworking {
+	if len(families) == 0 {
+		return nil
+	}
+	n := &domain.ServiceNetworking{IPFamilyPolicy: domain.IPFamilyPolicySingleStack, IPFamilies: families}
+	if len(families) > 1 {
+		n.IPFamilyPolicy = domain.IPFamilyPolicyRequireDualStack
+	}
+	return n
+}
+
+// RecordTypes returns the DNS record types published for an ingress with the
+// given families: A for IPv4 and AAAA for IPv6, A alone when none are set
+func RecordTypes(families []domain.IPFamily) []string {
+	if len(families) == 0 {
+		return []string{"A"}
+	}
+	types := make([]string, 0, len(families))
+	for _, family := range families {
+		if family == domain.IPFamilyIPv6 {
+			types = append(types, "AAAA")
+		} else {
+			types = append(types, "A")
+		}
+	}
+	return types
+}
+
+// Patch renders the networking of a service as a JSON patch of its
+// Kubernetes Service, or "" when it leaves the cluster defaults alone.
+// Internal services are kept to a ClusterIP Service.
+func Patch(n *domain.ServiceNetworking) string {
+	if n == nil || (n.IPFamilyPolicy == "" && len(n.IPFamilies) == 0 && n.Exposure != domain.ServiceExposureInternal) {
+		return ""
+	}
+	var ops []map[string]interface{}
+	if n.Exposure == domain.ServiceExposureInternal {
+		ops = append(ops, map[string]interface{}{"op": "add", "path": "/spec/type", "value": "ClusterIP"})
+	}
+	if n.IPFamilyPolicy != "" {
+		ops = append(ops, map[string]interface{}{"op": "add", "path": "/spec/ipFamilyPolicy", "value": n.IPFamilyPolicy})
+	}
+	if len(n.IPFamilies) > 0 {
+		ops = append(ops, map[string]interface{}{"op": "add", "path": "/spec/ipFamilies", "value": n.IPFamilies})
+	}
+	out, _ := json.Marshal(ops)
+	return string(out)
+}
+
+// Describe formats networking for log lines and error messages
+func Describe(n *domain.ServiceNetworking) string {
+	if n == nil {
+		return ""
+	}
+	families := make([]string, len(n.IPFamilies))
+	for i, family := range n.IPFamilies {
+		families[i] = string(family)
+	}
+	return strings.TrimSpace(string(n.IPFamilyPolicy) + " " + strings.Join(families, ","))
+}
+
+func contains(families []domain.IPFamily, family domain.IPFamily) bool {
+	for _, f := range families {
+		if f == family {
+			return true
+		}
+	}
+	return false
+}
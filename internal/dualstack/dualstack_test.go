@@ -0,0 +1,69 @@
+package dualstack
+
+import (
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	v4   = domain.IPFamilyIPv4
+	v6   = domain.IPFamilyIPv6
+	dual = []domain.IPFamily{v4, v6}
+)
+
+func TestValidate(t *testing.T) {
+	valid := []*domain.ServiceNetworking{
+		nil,
+		{IPFamilyPolicy: domain.IPFamilyPolicySingleStack, IPFamilies: []domain.IPFamily{v6}},
+		{IPFamilyPolicy: domain.IPFamilyPolicyPreferDualStack, IPFamilies: []domain.IPFamily{v6, v4}},
+		{IPFamilyPolicy: domain.IPFamilyPolicyRequireDualStack},
+	}
+	for _, n := range valid {
+		assert.NoError(t, Validate(n), Describe(n))
+	}
+
+	invalid := []*domain.ServiceNetworking{
+		{IPFamilyPolicy: "DualStack"},
+		{IPFamilies: []domain.IPFamily{"IPv5"}},
+		{IPFamilyPolicy: domain.IPFamilyPolicyPreferDualStack, IPFamilies: []domain.IPFamily{v4, v4}},
+		{IPFamilyPolicy: domain.IPFamilyPolicySingleStack, IPFamilies: dual},
+		{IPFamilies: dual},
+	}
+	for _, n := range invalid {
+		assert.Error(t, Validate(n), Describe(n))
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	requireDual := &domain.ServiceNetworking{IPFamilyPolicy: domain.IPFamilyPolicyRequireDualStack}
+	prefer := &domain.ServiceNetworking{IPFamilyPolicy: domain.IPFamilyPolicyPreferDualStack, IPFamilies: dual}
+	ipv6 := &domain.ServiceNetworking{IPFamilyPolicy: domain.IPFamilyPolicySingleStack, IPFamilies: []domain.IPFamily{v6}}
+
+	// A cluster reporting no families is IPv4 only
+	assert.Equal(t, "is not dual-stack", Unsupported(requireDual, nil))
+	assert.Equal(t, "does not support IPv6", Unsupported(ipv6, nil))
+	assert.Empty(t, Unsupported(prefer, nil))
+
+	assert.Empty(t, Unsupported(requireDual, dual))
+	assert.Empty(t, Unsupported(ipv6, dual))
+	assert.Equal(t, "does not support IPv4", Unsupported(prefer, []domain.IPFamily{v6}))
+}
+
+func TestRecordTypes(t *testing.T) {
+	assert.Equal(t, []string{"A"}, RecordTypes(nil))
+	assert.Equal(t, []string{"AAAA"}, RecordTypes([]domain.IPFamily{v6}))
+	assert.Equal(t, []string{"A", "AAAA"}, RecordTypes(dual))
+}
+
+func TestPatch(t *testing.T) {
+	assert.Empty(t, Patch(nil))
+	assert.Empty(t, Patch(&domain.ServiceNetworking{}))
+	assert.JSONEq(t,
+		`[{"op":"add","path":"/spec/ipFamilyPolicy","value":"PreferDualStack"},{"op":"add","path":"/spec/ipFamilies","value":["IPv6","IPv4"]}]`,
+		Patch(&domain.ServiceNetworking{IPFamilyPolicy: domain.IPFamilyPolicyPreferDualStack, IPFamilies: []domain.IPFamily{v6, v4}}))
+	assert.JSONEq(t,
+		`[{"op":"add","path":"/spec/type","value":"ClusterIP"}]`,
+		Patch(&domain.ServiceNetworking{Exposure: domain.ServiceExposureInternal}))
+}
@@ -0,0 +1,300 @@
+// Package egress gives an environment's outbound traffic static source IPs
+// that customers can hand to upstreams which allow-list by IP. In gateway
+// mode the environment's namespace leaves the cluster through a pool of
+// Cilium egress gateway nodes; in cluster NAT mode it inherits the public IPs
+// of the cloud NAT gateway an admin recorded on the cluster. The assigned IPs
+// are surfaced on the environment and refreshed as gateway nodes change.
+package egress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Manager provisions and tracks the egress IPs of environments
+type Manager struct {
+	config      *config.EgressConfig
+	kube        domain.KubernetesClient
+	clusterRepo domain.ClusterRepository
+	envRepo     domain.EnvironmentRepository
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewManager creates a new Manager. Without a Kubernetes client, gateway
+// mode stays pending; cluster NAT mode needs none.
+func NewManager(
+	cfg *config.EgressConfig,
+	kube domain.KubernetesClient,
+	clusterRepo domain.ClusterRepository,
+	envRepo domain.EnvironmentRepository,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *Manager {
+	return &Manager{
+		config:      cfg,
+		kube:        kube,
+		clusterRepo: clusterRepo,
+		envRepo:     envRepo,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Configure sets how an environment gets its egress IPs and provisions them
+func (m *Manager) Configure(ctx context.Context, env *domain.Environment, mode domain.EgressMode, pool string) (*domain.Egress, error) {
+	switch mode {
+	case domain.EgressModeGateway:
+		if pool == "" {
+			pool = m.config.DefaultPool
+		}
+	case domain.EgressModeClusterNAT:
+		if pool != "" {
+			return nil, errors.BadRequest("pool applies to gateway mode only")
+		}
+	default:
+		return nil, errors.BadRequest(fmt.Sprintf("egress mode must be %s or %s", domain.EgressModeGateway, domain.EgressModeClusterNAT))
+	}
+
+	// Switching away from a gateway leaves no policy behind
+	if env.Egress != nil && env.Egress.Mode == domain.EgressModeGateway && mode != domain.EgressModeGateway {
+		if err := m.deletePolicy(ctx, env.ClusterID, env.Namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	previous := env.Egress
+	env.Egress = &domain.Egress{Mode: mode, Pool: pool}
+	if err := m.provision(ctx, env); err != nil {
+		return nil, err
+	}
+	if err := m.save(ctx, env, previous); err != nil {
+		return nil, err
+	}
+	return env.Egress, nil
+}
+
+// Disable removes an environment's egress configuration; its traffic leaves
+// through whichever node it runs on again
+func (m *Manager) Disable(ctx context.Context, env *domain.Environment) error {
+	if env.Egress == nil {
+		return errors.NotFound("egress configuration", env.ID.String())
+	}
+	if env.Egress.Mode == domain.EgressModeGateway {
+		if err := m.deletePolicy(ctx, env.ClusterID, env.Namespace); err != nil {
+			return err
+		}
+	}
+
+	previous := env.Egress
+	env.Egress = nil
+	return m.save(ctx, env, previous)
+}
+
+// Refresh re-provisions an environment's egress, picking up gateway nodes
+// that were added or replaced
+func (m *Manager) Refresh(ctx context.Context, env *domain.Environment) error {
+	if env.Egress == nil {
+		return nil
+	}
+	previous := *env.Egress
+	current := previous
+	env.Egress = &current
+	if err := m.provision(ctx, env); err != nil {
+		return err
+	}
+	if unchanged(&previous, env.Egress) {
+		return nil
+	}
+	return m.save(ctx, env, &previous)
+}
+
+// Run refreshes every environment with egress IPs until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.refreshAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Watch removes the gateway policy of deleted environments, which would
+// otherwise outlive the namespace since the policy is cluster-scoped. Events
+// go through a durable consumer, so one replica handles each and failures
+// are retried; removing a policy that is gone is a no-op.
+func (m *Manager) Watch(ctx context.Context, bus domain.EventBus) error {
+	_, err := eventbus.SubscribeDurable(ctx, bus, "project.environment.deleted", eventbus.DurableName("egress", "project.environment.deleted"), func(event *domain.Event) error {
+		raw, _ := event.Data["cluster_id"].(string)
+		clusterID, err := uuid.Parse(raw)
+		namespace, _ := event.Data["namespace"].(string)
+		if err != nil || namespace == "" || m.kube == nil {
+			return nil
+		}
+		if err := m.deletePolicy(ctx, clusterID, namespace); err != nil {
+			m.logger.Warn().Err(err).Str("namespace", namespace).Msg("Failed to remove egress gateway policy")
+			return err
+		}
+		return nil
+	})
+	return err
+}
+
+func (m *Manager) refreshAll(ctx context.Context) {
+	clusters, err := m.clusterRepo.List(ctx, domain.ClusterFilter{})
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list clusters for egress refresh")
+		return
+	}
+	for _, cluster := range clusters {
+		environments, err := m.envRepo.ListByCluster(ctx, cluster.ID)
+		if err != nil {
+			m.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to list environments for egress refresh")
+			continue
+		}
+		for _, env := range environments {
+			if err := m.Refresh(ctx, env); err != nil {
+				m.logger.Warn().Err(err).Str("environment_id", env.ID.String()).Msg("Failed to refresh egress IPs")
+			}
+		}
+	}
+}
+
+// provision applies the environment's egress configuration and records the
+// resulting IPs and status on env.Egress
+func (m *Manager) provision(ctx context.Context, env *domain.Environment) error {
+	egress := env.Egress
+	egress.UpdatedAt = time.Now()
+	egress.IPs = []string{}
+	egress.Message = ""
+
+	cluster, err := m.clusterRepo.GetByID(ctx, env.ClusterID)
+	if err != nil {
+		return err
+	}
+
+	if egress.Mode == domain.EgressModeClusterNAT {
+		egress.IPs = ClusterNATIPs(cluster)
+		if len(egress.IPs) == 0 {
+			egress.Status = domain.EgressStatusPending
+			egress.Message = fmt.Sprintf("cluster %s has no NAT egress IPs recorded", cluster.Slug)
+			return nil
+		}
+		egress.Status = domain.EgressStatusReady
+		return nil
+	}
+
+	if m.kube == nil {
+		egress.Status = domain.EgressStatusPending
+		egress.Message = "no Kubernetes client for workload clusters; the egress gateway policy was not applied"
+		return nil
+	}
+	if obj, err := m.kube.GetResource(ctx, cluster.ID, "CustomResourceDefinition", "", crdName); err != nil || obj == nil {
+		egress.Status = domain.EgressStatusFailed
+		egress.Message = fmt.Sprintf("Cilium egress gateway is not installed in cluster %s", cluster.Slug)
+		return nil
+	}
+
+	manifest, err := json.Marshal(Policy(m.config, env, egress.Pool).Object)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode egress gateway policy")
+	}
+	if err := m.kube.ApplyManifest(ctx, cluster.ID, manifest); err != nil {
+		egress.Status = domain.EgressStatusFailed
+		egress.Message = "failed to apply egress gateway policy: " + err.Error()
+		return nil
+	}
+
+	nodes, err := m.kube.ListResources(ctx, cluster.ID, "Node", "", map[string]string{m.config.PoolLabel: egress.Pool})
+	if err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	egress.IPs = nodeIPs(nodes, m.config.IPAnnotation)
+	if len(egress.IPs) == 0 {
+		egress.Status = domain.EgressStatusFailed
+		egress.Message = fmt.Sprintf("no gateway node with a public IP is labelled %s=%s", m.config.PoolLabel, egress.Pool)
+		return nil
+	}
+	egress.Status = domain.EgressStatusReady
+	return nil
+}
+
+// save stores the environment and announces a change of its egress IPs, which
+// users may need to pass on to third parties
+func (m *Manager) save(ctx context.Context, env *domain.Environment, previous *domain.Egress) error {
+	if err := m.envRepo.Update(ctx, env); err != nil {
+		return err
+	}
+
+	var before, after []string
+	if previous != nil {
+		before = previous.IPs
+	}
+	if env.Egress != nil {
+		after = env.Egress.IPs
+	}
+	if (len(before) == 0 && len(after) == 0) || reflect.DeepEqual(before, after) {
+		return nil
+	}
+
+	m.logger.Info().
+		Str("environment_id", env.ID.String()).
+		Str("previous_ips", strings.Join(before, ",")).
+		Str("ips", strings.Join(after, ",")).
+		Msg("Environment egress IPs changed")
+
+	if m.eventBus == nil {
+		return nil
+	}
+	event := &domain.Event{
+		Type:   "project.environment.egress_changed",
+		Source: "egress",
+		Data: map[string]interface{}{
+			"environment_id": env.ID.String(),
+			"project_id":     env.ProjectID.String(),
+			"previous_ips":   before,
+			"ips":            after,
+		},
+	}
+	if err := m.eventBus.Publish(ctx, event.Type, event); err != nil {
+		m.logger.Warn().Err(err).Str("event", event.Type).Msg("Failed to publish event")
+	}
+	return nil
+}
+
+func (m *Manager) deletePolicy(ctx context.Context, clusterID uuid.UUID, namespace string) error {
+	if m.kube == nil {
+		return nil
+	}
+	name := PolicyName(namespace)
+	if obj, err := m.kube.GetResource(ctx, clusterID, "CiliumEgressGatewayPolicy", "", name); err != nil || obj == nil {
+		return nil
+	}
+	if err := m.kube.DeleteResource(ctx, clusterID, "CiliumEgressGatewayPolicy", "", name); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+// unchanged reports whether a refresh left the egress as it was, ignoring
+// the refresh time
+func unchanged(a, b *domain.Egress) bool {
+	return a.Status == b.Status && a.Message == b.Message && reflect.DeepEqual(a.IPs, b.IPs)
+}
@@ -0,0 +1,132 @@
+package egress
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// crdName is the CRD that shows Cilium's egress gateway is installed
+	crdName = "ciliumegressgatewaypolicies.cilium.io"
+	// policyPrefix prefixes the cluster-scoped policy of each namespace
+	policyPrefix = "northstack-egress-"
+	// MetadataNATIPs is the cluster metadata key holding the public IPs of its cloud NAT
+	MetadataNATIPs = "egress_ips"
+)
+
+// PolicyName returns the name of the egress gateway policy of a namespace
+func PolicyName(namespace string) string {
+	return policyPrefix + namespace
+}
+
+// Policy renders the CiliumEgressGatewayPolicy that sends every pod of the
+// environment's namespace out through a node of the gateway pool
+func Policy(cfg *config.EgressConfig, env *domain.Environment, pool string) *unstructured.Unstructured {
+	destinations := []interface{}{"0.0.0.0/0"}
+	excluded := make([]interface{}, 0, len(cfg.ExcludedCIDRs))
+	for _, cidr := range cfg.ExcludedCIDRs {
+		excluded = append(excluded, cidr)
+	}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "cilium.io/v2",
+		"kind":       "CiliumEgressGatewayPolicy",
+		"metadata": map[string]interface{}{
+			"name": PolicyName(env.Namespace),
+			"labels": map[string]interface{}{
+				"app.kubernetes.io/managed-by": "northstack",
+				"northstack.io/environment-id": env.ID.String(),
+			},
+		},
+		"spec": map[string]interface{}{
+			"selectors": []interface{}{
+				map[string]interface{}{
+					"podSelector": map[string]interface{}{
+						"matchLabels": map[string]interface{}{
+							"io.kubernetes.pod.namespace": env.Namespace,
+						},
+					},
+				},
+			},
+			"destinationCIDRs": destinations,
+			"excludedCIDRs":    excluded,
+			"egressGateway": map[string]interface{}{
+				"nodeSelector": map[string]interface{}{
+					"matchLabels": map[string]interface{}{cfg.PoolLabel: pool},
+				},
+			},
+		},
+	}}
+}
+
+// nodeIPs returns the public IPs traffic leaving through the gateway nodes
+// comes from: the static IP annotated on a node, or else its external address
+func nodeIPs(nodes []map[string]interface{}, annotation string) []string {
+	seen := make(map[string]bool)
+	for _, node := range nodes {
+		obj := unstructured.Unstructured{Object: node}
+		if value := obj.GetAnnotations()[annotation]; value != "" {
+			for _, ip := range strings.Split(value, ",") {
+				if ip = strings.TrimSpace(ip); net.ParseIP(ip) != nil {
+					seen[ip] = true
+				}
+			}
+			continue
+		}
+
+		addresses, _, _ := unstructured.NestedSlice(node, "status", "addresses")
+		for _, a := range addresses {
+			address, _ := a.(map[string]interface{})
+			if address["type"] == "ExternalIP" {
+				if ip, _ := address["address"].(string); net.ParseIP(ip) != nil {
+					seen[ip] = true
+				}
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// ClusterNATIPs returns the public IPs of the cloud NAT in front of a cluster
+func ClusterNATIPs(cluster *domain.Cluster) []string {
+	seen := make(map[string]bool)
+	switch ips := cluster.Metadata[MetadataNATIPs].(type) {
+	case []string:
+		for _, ip := range ips {
+			seen[ip] = true
+		}
+	case []interface{}:
+		for _, ip := range ips {
+			if s, ok := ip.(string); ok {
+				seen[s] = true
+			}
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// SetClusterNATIPs records the public IPs of the cloud NAT in front of a cluster
+func SetClusterNATIPs(cluster *domain.Cluster, ips []string) {
+	if cluster.Metadata == nil {
+		cluster.Metadata = make(map[string]interface{})
+	}
+	if len(ips) == 0 {
+		delete(cluster.Metadata, MetadataNATIPs)
+		return
+	}
+	cluster.Metadata[MetadataNATIPs] = ips
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
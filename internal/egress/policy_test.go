@@ -0,0 +1,77 @@
+package egress
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func node(annotations map[string]interface{}, addresses ...map[string]interface{}) map[string]interface{} {
+	list := make([]interface{}, len(addresses))
+	for i, a := range addresses {
+		list[i] = a
+	}
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+		"status":   map[string]interface{}{"addresses": list},
+	}
+}
+
+func TestNodeIPs(t *testing.T) {
+	const annotation = "egress.northstack.io/public-ip"
+	nodes := []map[string]interface{}{
+		// The annotated static IP wins over the node's own address
+		node(map[string]interface{}{annotation: "203.0.113.10, 203.0.113.11"},
+			map[string]interface{}{"type": "ExternalIP", "address": "198.51.100.1"}),
+		node(nil,
+			map[string]interface{}{"type": "InternalIP", "address": "10.0.0.5"},
+			map[string]interface{}{"type": "ExternalIP", "address": "198.51.100.2"}),
+		node(nil, map[string]interface{}{"type": "InternalIP", "address": "10.0.0.6"}),
+		node(map[string]interface{}{annotation: "not-an-ip"}),
+	}
+
+	assert.Equal(t, []string{"198.51.100.2", "203.0.113.10", "203.0.113.11"}, nodeIPs(nodes, annotation))
+	assert.Empty(t, nodeIPs(nil, annotation))
+}
+
+func TestClusterNATIPs(t *testing.T) {
+	cluster := &domain.Cluster{}
+	assert.Empty(t, ClusterNATIPs(cluster))
+
+	SetClusterNATIPs(cluster, []string{"203.0.113.2", "203.0.113.1"})
+	assert.Equal(t, []string{"203.0.113.1", "203.0.113.2"}, ClusterNATIPs(cluster))
+
+	// As read back from the metadata column
+	data, err := json.Marshal(cluster.Metadata)
+	require.NoError(t, err)
+	stored := &domain.Cluster{}
+	require.NoError(t, json.Unmarshal(data, &stored.Metadata))
+	assert.Equal(t, []string{"203.0.113.1", "203.0.113.2"}, ClusterNATIPs(stored))
+
+	SetClusterNATIPs(stored, nil)
+	assert.NotContains(t, stored.Metadata, MetadataNATIPs)
+}
+
+func TestPolicy(t *testing.T) {
+	cfg := &config.EgressConfig{PoolLabel: "egress.northstack.io/pool", ExcludedCIDRs: []string{"10.0.0.0/8"}}
+	env := &domain.Environment{ID: uuid.New(), Namespace: "shop-prod"}
+
+	policy := Policy(cfg, env, "eu")
+	assert.Equal(t, "northstack-egress-shop-prod", policy.GetName())
+
+	selectors, _, _ := unstructured.NestedSlice(policy.Object, "spec", "selectors")
+	require.Len(t, selectors, 1)
+	namespace, _, _ := unstructured.NestedString(selectors[0].(map[string]interface{}), "podSelector", "matchLabels", "io.kubernetes.pod.namespace")
+	assert.Equal(t, "shop-prod", namespace)
+
+	pool, _, _ := unstructured.NestedString(policy.Object, "spec", "egressGateway", "nodeSelector", "matchLabels", "egress.northstack.io/pool")
+	assert.Equal(t, "eu", pool)
+	excluded, _, _ := unstructured.NestedStringSlice(policy.Object, "spec", "excludedCIDRs")
+	assert.Equal(t, []string{"10.0.0.0/8"}, excluded)
+}
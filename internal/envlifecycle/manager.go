@@ -0,0 +1,391 @@
+// Package envlifecycle keeps short-lived environments, such as previews, from
+// piling up in workload clusters. An environment's lifecycle deletes it a
+// number of hours after it was created and hibernates it, scaling its
+// workloads to zero, once its ingresses have served no requests for a number
+// of hours. Hibernated environments are woken on demand or, with wake on
+// request, as soon as requests for them reach the ingress controller again.
+package envlifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// workloadKinds are the workloads hibernation scales to zero
+var workloadKinds = []string{"Deployment", "StatefulSet"}
+
+// Querier runs instant PromQL queries
+type Querier interface {
+	Query(ctx context.Context, expr string) (float64, error)
+}
+
+// Manager expires, hibernates and wakes environments
+type Manager struct {
+	config         *config.EnvLifecycleConfig
+	kube           domain.KubernetesClient
+	envRepo        domain.EnvironmentRepository
+	projectRepo    domain.ProjectRepository
+	clusterRepo    domain.ClusterRepository
+	clusterManager domain.ClusterManagerAdapter
+	metrics        Querier
+	eventBus       domain.EventBus
+	logger         *logger.Logger
+}
+
+// NewManager creates a new Manager. Without a Querier, environments still
+// expire and can be hibernated by hand, but idle ones are not detected and
+// requests do not wake them.
+func NewManager(
+	cfg *config.EnvLifecycleConfig,
+	kube domain.KubernetesClient,
+	envRepo domain.EnvironmentRepository,
+	projectRepo domain.ProjectRepository,
+	clusterRepo domain.ClusterRepository,
+	clusterManager domain.ClusterManagerAdapter,
+	metrics Querier,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *Manager {
+	return &Manager{
+		config:         cfg,
+		kube:           kube,
+		envRepo:        envRepo,
+		projectRepo:    projectRepo,
+		clusterRepo:    clusterRepo,
+		clusterManager: clusterManager,
+		metrics:        metrics,
+		eventBus:       eventBus,
+		logger:         log,
+	}
+}
+
+// Effective returns the lifecycle an environment follows
+func (m *Manager) Effective(env *domain.Environment) *domain.EnvironmentLifecycle {
+	return Effective(m.config, env)
+}
+
+// Configure sets the lifecycle of an environment, keeping its hibernation state
+func (m *Manager) Configure(ctx context.Context, env *domain.Environment, l domain.EnvironmentLifecycle) (*domain.EnvironmentLifecycle, error) {
+	if err := Validate(env, &l); err != nil {
+		return nil, err
+	}
+	if m.metrics == nil && (l.IdleHours > 0 || l.WakeOnRequest) {
+		return nil, errors.BadRequest("idle_hours and wake_on_request need request metrics from Prometheus, which is not configured")
+	}
+
+	l.ExpiresAt = ExpiresAt(env, &l)
+	l.HibernatedAt, l.WokenAt = nil, nil
+	if current := Effective(m.config, env); current != nil {
+		l.HibernatedAt, l.WokenAt = current.HibernatedAt, current.WokenAt
+	}
+	env.Lifecycle = &l
+	if err := m.envRepo.Update(ctx, env); err != nil {
+		return nil, err
+	}
+	return env.Lifecycle, nil
+}
+
+// Disable removes the lifecycle of an environment, waking it first when it
+// is hibernated. Preview environments fall back to the configured defaults.
+func (m *Manager) Disable(ctx context.Context, env *domain.Environment) error {
+	if env.Lifecycle == nil {
+		return errors.NotFound("environment lifecycle", env.ID.String())
+	}
+	if env.Lifecycle.HibernatedAt != nil {
+		if err := m.Wake(ctx, env); err != nil {
+			return err
+		}
+	}
+	env.Lifecycle = nil
+	return m.envRepo.Update(ctx, env)
+}
+
+// Hibernate scales the workloads of an environment to zero, recording the
+// replicas each one is woken with. KEDA ScaledObjects are paused so they do
+// not scale the workloads back up; HorizontalPodAutoscalers leave workloads
+// at zero replicas alone.
+func (m *Manager) Hibernate(ctx context.Context, env *domain.Environment) error {
+	if m.kube == nil {
+		return errors.BadRequest("no Kubernetes client for workload clusters; environments cannot be hibernated")
+	}
+	l := Effective(m.config, env)
+	if l == nil {
+		l = &domain.EnvironmentLifecycle{}
+	}
+	if l.HibernatedAt != nil {
+		return nil
+	}
+
+	scaledObjects, err := m.kube.ListResources(ctx, env.ClusterID, "ScaledObject", env.Namespace, nil)
+	if err != nil {
+		scaledObjects = nil // KEDA is not installed
+	}
+	for _, obj := range scaledObjects {
+		annotations, _, _ := unstructured.NestedStringMap(obj, "metadata", "annotations")
+		if _, paused := annotations[pausedAnnotation]; paused {
+			continue // Paused by its owner
+		}
+		if err := m.annotate(ctx, env, obj, map[string]string{pausedAnnotation: "0", AnnotationHibernated: "true"}, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, kind := range workloadKinds {
+		workloads, err := m.kube.ListResources(ctx, env.ClusterID, kind, env.Namespace, nil)
+		if err != nil {
+			return errors.DependencyFailed("kubernetes", err)
+		}
+		for _, obj := range workloads {
+			annotations, _, _ := unstructured.NestedStringMap(obj, "metadata", "annotations")
+			replicas, found, _ := unstructured.NestedInt64(obj, "spec", "replicas")
+			if !found {
+				replicas = 1
+			}
+			if _, hibernated := annotations[AnnotationReplicas]; hibernated || replicas == 0 {
+				continue
+			}
+			zero := int64(0)
+			if err := m.annotate(ctx, env, obj, map[string]string{AnnotationReplicas: strconv.FormatInt(replicas, 10)}, &zero); err != nil {
+				return err
+			}
+		}
+	}
+
+	now := time.Now()
+	hibernated := *l
+	hibernated.HibernatedAt = &now
+	env.Lifecycle = &hibernated
+	if err := m.envRepo.Update(ctx, env); err != nil {
+		return err
+	}
+
+	m.logger.Info().
+		Str("environment_id", env.ID.String()).
+		Str("namespace", env.Namespace).
+		Msg("Environment hibernated")
+	m.publish(ctx, "project.environment.hibernated", env, "")
+	return nil
+}
+
+// Wake scales the workloads of a hibernated environment back to the replicas
+// they had and resumes their ScaledObjects
+func (m *Manager) Wake(ctx context.Context, env *domain.Environment) error {
+	if m.kube == nil {
+		return errors.BadRequest("no Kubernetes client for workload clusters; environments cannot be woken")
+	}
+	if env.Lifecycle == nil || env.Lifecycle.HibernatedAt == nil {
+		return nil
+	}
+
+	for _, kind := range workloadKinds {
+		workloads, err := m.kube.ListResources(ctx, env.ClusterID, kind, env.Namespace, nil)
+		if err != nil {
+			return errors.DependencyFailed("kubernetes", err)
+		}
+		for _, obj := range workloads {
+			annotations, _, _ := unstructured.NestedStringMap(obj, "metadata", "annotations")
+			value, hibernated := annotations[AnnotationReplicas]
+			if !hibernated {
+				continue
+			}
+			replicas, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || replicas < 1 {
+				replicas = 1
+			}
+			if err := m.annotate(ctx, env, obj, nil, &replicas, AnnotationReplicas); err != nil {
+				return err
+			}
+		}
+	}
+
+	scaledObjects, err := m.kube.ListResources(ctx, env.ClusterID, "ScaledObject", env.Namespace, nil)
+	if err != nil {
+		scaledObjects = nil
+	}
+	for _, obj := range scaledObjects {
+		annotations, _, _ := unstructured.NestedStringMap(obj, "metadata", "annotations")
+		if annotations[AnnotationHibernated] == "" {
+			continue
+		}
+		if err := m.annotate(ctx, env, obj, nil, nil, pausedAnnotation, AnnotationHibernated); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	env.Lifecycle.HibernatedAt = nil
+	env.Lifecycle.WokenAt = &now
+	if err := m.envRepo.Update(ctx, env); err != nil {
+		return err
+	}
+
+	m.logger.Info().
+		Str("environment_id", env.ID.String()).
+		Str("namespace", env.Namespace).
+		Msg("Environment woken")
+	m.publish(ctx, "project.environment.woken", env, "")
+	return nil
+}
+
+// Run expires, hibernates and wakes environments every interval until ctx is
+// cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.reconcileAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) reconcileAll(ctx context.Context) {
+	projects, err := m.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list projects for environment lifecycles")
+		return
+	}
+	now := time.Now()
+	for _, project := range projects {
+		environments, err := m.envRepo.ListByProject(ctx, project.ID)
+		if err != nil {
+			m.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list environments for lifecycles")
+			continue
+		}
+		for _, env := range environments {
+			if err := m.reconcile(ctx, env, now); err != nil {
+				m.logger.Warn().Err(err).Str("environment_id", env.ID.String()).Msg("Failed to apply environment lifecycle")
+			}
+		}
+	}
+}
+
+// reconcile deletes an expired environment, wakes a hibernated one that is
+// receiving requests again, or hibernates an idle one
+func (m *Manager) reconcile(ctx context.Context, env *domain.Environment, now time.Time) error {
+	l := Effective(m.config, env)
+	if l == nil {
+		return nil
+	}
+	if Expired(env, l, now) {
+		return m.expire(ctx, env)
+	}
+	if m.metrics == nil || m.kube == nil {
+		return nil
+	}
+
+	if l.HibernatedAt != nil {
+		if !l.WakeOnRequest {
+			return nil
+		}
+		// Requests since the previous pass; the ingress controller answers
+		// them with 503 while the environment has no pods
+		requests, err := m.metrics.Query(ctx, RequestsQuery(env.Namespace, 2*m.config.Interval))
+		if err != nil || requests == 0 {
+			return err
+		}
+		return m.Wake(ctx, env)
+	}
+
+	window := IdleWindow(env, l, now)
+	if window == 0 {
+		return nil
+	}
+	requests, err := m.metrics.Query(ctx, RequestsQuery(env.Namespace, window))
+	if err != nil || requests > 0 {
+		return err
+	}
+	return m.Hibernate(ctx, env)
+}
+
+// expire deletes an environment that outlived its TTL along with its
+// namespace, as deleting it through the API does
+func (m *Manager) expire(ctx context.Context, env *domain.Environment) error {
+	if m.clusterManager != nil {
+		cluster, err := m.clusterRepo.GetByID(ctx, env.ClusterID)
+		if err != nil {
+			return err
+		}
+		if cluster.RancherClusterID != "" {
+			if err := m.clusterManager.DeleteNamespace(ctx, cluster.RancherClusterID, env.Namespace); err != nil {
+				return err
+			}
+		}
+	}
+	if err := m.envRepo.Delete(ctx, env.ID); err != nil {
+		return err
+	}
+
+	m.logger.Info().
+		Str("environment_id", env.ID.String()).
+		Str("namespace", env.Namespace).
+		Msg("Expired environment deleted")
+	m.publish(ctx, "project.environment.deleted", env, "expired")
+	return nil
+}
+
+// annotate sets and removes annotations of an object, and its replicas when
+// given, then applies it
+func (m *Manager) annotate(ctx context.Context, env *domain.Environment, obj map[string]interface{}, set map[string]string, replicas *int64, remove ...string) error {
+	annotations, _, _ := unstructured.NestedStringMap(obj, "metadata", "annotations")
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	for k, v := range set {
+		annotations[k] = v
+	}
+	for _, k := range remove {
+		delete(annotations, k)
+	}
+	unstructured.SetNestedStringMap(obj, annotations, "metadata", "annotations")
+	if replicas != nil {
+		unstructured.SetNestedField(obj, *replicas, "spec", "replicas")
+	}
+	unstructured.RemoveNestedField(obj, "metadata", "managedFields")
+	unstructured.RemoveNestedField(obj, "status")
+
+	manifest, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode workload")
+	}
+	if err := m.kube.ApplyManifest(ctx, env.ClusterID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+func (m *Manager) publish(ctx context.Context, eventType string, env *domain.Environment, reason string) {
+	if m.eventBus == nil {
+		return
+	}
+	data := map[string]interface{}{
+		"environment_id": env.ID.String(),
+		"project_id":     env.ProjectID.String(),
+		"cluster_id":     env.ClusterID.String(),
+		"namespace":      env.Namespace,
+	}
+	if reason != "" {
+		data["reason"] = reason
+	}
+	event := &domain.Event{
+		Type:   eventType,
+		Source: "environment-lifecycle",
+		Data:   data,
+	}
+	if err := m.eventBus.Publish(ctx, eventType, event); err != nil {
+		m.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
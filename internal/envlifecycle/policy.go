@@ -0,0 +1,103 @@
+package envlifecycle
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+const (
+	// AnnotationReplicas records on a hibernated workload the replicas it is
+	// woken with
+	AnnotationReplicas = "openpaas.io/hibernated-replicas"
+	// AnnotationHibernated marks the KEDA ScaledObjects paused by hibernation
+	AnnotationHibernated = "openpaas.io/hibernated"
+
+	// pausedAnnotation holds a KEDA ScaledObject at a replica count
+	pausedAnnotation = "autoscaling.keda.sh/paused-replicas"
+
+	// maxHours bounds the TTL and idle time of an environment (90 days)
+	maxHours = 90 * 24
+)
+
+// requestsQuery counts the requests the ingress controllers of a cluster
+// routed into a namespace over a window: ingress-nginx labels them with the
+// namespace, Traefik with its <namespace>-<service> service names
+const requestsQuery = `sum(increase(nginx_ingress_controller_requests{exported_namespace=%[1]q}[%[3]s]) or increase(traefik_service_requests_total{exported_service=~%[2]q}[%[3]s]))`
+
+// Validate checks the lifecycle of an environment. Only preview and
+// development environments that are not the project default can expire or
+// hibernate.
+func Validate(env *domain.Environment, l *domain.EnvironmentLifecycle) error {
+	if env.IsDefault || (env.Type != domain.EnvironmentTypePreview && env.Type != domain.EnvironmentTypeDevelopment) {
+		return errors.BadRequest("lifecycle policies only apply to preview and development environments that are not the project default")
+	}
+	if l.TTLHours < 0 || l.TTLHours > maxHours {
+		return errors.BadRequest(fmt.Sprintf("ttl_hours must be between 0 and %d", maxHours))
+	}
+	if l.IdleHours < 0 || l.IdleHours > maxHours {
+		return errors.BadRequest(fmt.Sprintf("idle_hours must be between 0 and %d", maxHours))
+	}
+	return nil
+}
+
+// Effective returns the lifecycle an environment follows: its own or, for
+// preview environments without one, the configured preview defaults. Nil
+// means it neither expires nor hibernates.
+func Effective(cfg *config.EnvLifecycleConfig, env *domain.Environment) *domain.EnvironmentLifecycle {
+	if env.Lifecycle != nil {
+		return env.Lifecycle
+	}
+	if env.Type != domain.EnvironmentTypePreview || env.IsDefault || (cfg.PreviewTTL <= 0 && cfg.PreviewIdle <= 0) {
+		return nil
+	}
+	l := &domain.EnvironmentLifecycle{
+		TTLHours:  int(cfg.PreviewTTL.Hours()),
+		IdleHours: int(cfg.PreviewIdle.Hours()),
+	}
+	l.ExpiresAt = ExpiresAt(env, l)
+	return l
+}
+
+// ExpiresAt is when an environment is deleted, or nil when it lives on
+func ExpiresAt(env *domain.Environment, l *domain.EnvironmentLifecycle) *time.Time {
+	if l.TTLHours == 0 {
+		return nil
+	}
+	t := env.CreatedAt.Add(time.Duration(l.TTLHours) * time.Hour)
+	return &t
+}
+
+// Expired reports whether an environment outlived its TTL
+func Expired(env *domain.Environment, l *domain.EnvironmentLifecycle, now time.Time) bool {
+	expires := ExpiresAt(env, l)
+	return expires != nil && !now.Before(*expires)
+}
+
+// IdleWindow is the window without requests after which an awake environment
+// hibernates, or 0 when it is not due for the check yet: it must have been up
+// for the whole window since it was created or last woken.
+func IdleWindow(env *domain.Environment, l *domain.EnvironmentLifecycle, now time.Time) time.Duration {
+	if l.IdleHours == 0 || l.HibernatedAt != nil {
+		return 0
+	}
+	since := env.CreatedAt
+	if l.WokenAt != nil && l.WokenAt.After(since) {
+		since = *l.WokenAt
+	}
+	window := time.Duration(l.IdleHours) * time.Hour
+	if now.Sub(since) < window {
+		return 0
+	}
+	return window
+}
+
+// RequestsQuery renders the PromQL counting the requests routed into a
+// namespace over a window
+func RequestsQuery(namespace string, window time.Duration) string {
+	return fmt.Sprintf(requestsQuery, namespace, namespace+"-.*", strconv.Itoa(int(window.Seconds()))+"s")
+}
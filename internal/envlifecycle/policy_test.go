@@ -0,0 +1,72 @@
+package envlifecycle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	preview := &domain.Environment{Type: domain.EnvironmentTypePreview}
+	assert.NoError(t, Validate(preview, &domain.EnvironmentLifecycle{TTLHours: 72, IdleHours: 4, WakeOnRequest: true}))
+	assert.Error(t, Validate(preview, &domain.EnvironmentLifecycle{TTLHours: -1}))
+	assert.Error(t, Validate(preview, &domain.EnvironmentLifecycle{IdleHours: maxHours + 1}))
+
+	assert.Error(t, Validate(&domain.Environment{Type: domain.EnvironmentTypeProduction}, &domain.EnvironmentLifecycle{TTLHours: 1}))
+	assert.Error(t, Validate(&domain.Environment{Type: domain.EnvironmentTypeDevelopment, IsDefault: true}, &domain.EnvironmentLifecycle{TTLHours: 1}))
+}
+
+func TestEffective(t *testing.T) {
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	cfg := &config.EnvLifecycleConfig{PreviewTTL: 72 * time.Hour, PreviewIdle: 2 * time.Hour}
+
+	preview := &domain.Environment{Type: domain.EnvironmentTypePreview, CreatedAt: created}
+	l := Effective(cfg, preview)
+	require.NotNil(t, l)
+	assert.Equal(t, 72, l.TTLHours)
+	assert.Equal(t, created.Add(72*time.Hour), *l.ExpiresAt)
+
+	assert.Nil(t, Effective(cfg, &domain.Environment{Type: domain.EnvironmentTypeDevelopment}))
+	assert.Nil(t, Effective(&config.EnvLifecycleConfig{}, preview))
+
+	own := &domain.EnvironmentLifecycle{TTLHours: 1}
+	preview.Lifecycle = own
+	assert.Same(t, own, Effective(cfg, preview))
+}
+
+func TestExpired(t *testing.T) {
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	env := &domain.Environment{CreatedAt: created}
+
+	assert.False(t, Expired(env, &domain.EnvironmentLifecycle{}, created.Add(1000*time.Hour)))
+	l := &domain.EnvironmentLifecycle{TTLHours: 24}
+	assert.False(t, Expired(env, l, created.Add(23*time.Hour)))
+	assert.True(t, Expired(env, l, created.Add(24*time.Hour)))
+}
+
+func TestIdleWindow(t *testing.T) {
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	env := &domain.Environment{CreatedAt: created}
+	l := &domain.EnvironmentLifecycle{IdleHours: 4}
+
+	assert.Zero(t, IdleWindow(env, l, created.Add(3*time.Hour))) // Not up for the whole window yet
+	assert.Equal(t, 4*time.Hour, IdleWindow(env, l, created.Add(5*time.Hour)))
+
+	woken := created.Add(10 * time.Hour)
+	l.WokenAt = &woken
+	assert.Zero(t, IdleWindow(env, l, created.Add(12*time.Hour)))
+	assert.Equal(t, 4*time.Hour, IdleWindow(env, l, created.Add(14*time.Hour)))
+
+	l.HibernatedAt = &woken
+	assert.Zero(t, IdleWindow(env, l, created.Add(100*time.Hour)))
+}
+
+func TestRequestsQuery(t *testing.T) {
+	assert.Equal(t,
+		`sum(increase(nginx_ingress_controller_requests{exported_namespace="shop-pr-42"}[7200s]) or increase(traefik_service_requests_total{exported_service=~"shop-pr-42-.*"}[7200s]))`,
+		RequestsQuery("shop-pr-42", 2*time.Hour))
+}
@@ -0,0 +1,222 @@
+// Package escalation pages on-call engineers through incident services such
+// as PagerDuty or Opsgenie. Only critical alerts and failed production
+// deploys open incidents; each has a dedup key, so repeats update the open
+// incident, and the incident resolves with the alert or with the next
+// successful production deploy of the service.
+package escalation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// SeverityCritical is the only alert severity that opens incidents
+const SeverityCritical = "critical"
+
+// Incident is an incident to open, or update when its dedup key is open
+type Incident struct {
+	DedupKey string
+	Summary  string
+	Source   string // What is affected, such as a service or cluster
+	Details  map[string]string
+	Link     string // Console page of what is affected; none when empty
+}
+
+// Backend opens and resolves incidents in an incident service
+type Backend interface {
+	Trigger(ctx context.Context, incident *Incident) error
+	Resolve(ctx context.Context, dedupKey string) error
+}
+
+// Notifier is a domain.Notifier that opens and resolves incidents through a
+// backend
+type Notifier struct {
+	backend     Backend
+	envRepo     domain.EnvironmentRepository
+	serviceRepo domain.ServiceRepository
+	consoleURL  string
+	logger      *logger.Logger
+}
+
+// NewNotifier creates a new Notifier. serviceRepo may be nil, in which case
+// incidents name services by ID.
+func NewNotifier(backend Backend, envRepo domain.EnvironmentRepository, serviceRepo domain.ServiceRepository, consoleURL string, log *logger.Logger) *Notifier {
+	return &Notifier{
+		backend:     backend,
+		envRepo:     envRepo,
+		serviceRepo: serviceRepo,
+		consoleURL:  consoleURL,
+		logger:      log,
+	}
+}
+
+var _ domain.Notifier = (*Notifier)(nil)
+
+// SendNotification does nothing; plain notifications do not page anyone
+func (n *Notifier) SendNotification(ctx context.Context, notification *domain.Notification) error {
+	return nil
+}
+
+// SendBuildNotification does nothing; builds do not page anyone
+func (n *Notifier) SendBuildNotification(ctx context.Context, build *domain.Build) error {
+	return nil
+}
+
+// SendDeploymentNotification opens an incident when a deploy to a production
+// environment fails, and resolves it when a later one of the service to the
+// same cluster succeeds. Deploys to other environments are ignored.
+func (n *Notifier) SendDeploymentNotification(ctx context.Context, deployment *domain.Deployment) error {
+	if deployment.Status != domain.DeploymentStatusFailed && deployment.Status != domain.DeploymentStatusSucceeded {
+		return nil
+	}
+	env, err := n.productionEnvironment(ctx, deployment)
+	if err != nil || env == nil {
+		return err
+	}
+
+	key := DeployDedupKey(deployment.ServiceID, deployment.ClusterID)
+	if deployment.Status == domain.DeploymentStatusSucceeded {
+		return n.backend.Resolve(ctx, key)
+	}
+	return n.trigger(ctx, deployIncident(key, n.serviceName(ctx, deployment.ServiceID), env, deployment, n.consoleURL))
+}
+
+// SendAlertNotification opens an incident when a critical alert fires and
+// resolves it when the alert does
+func (n *Notifier) SendAlertNotification(ctx context.Context, alert *domain.Alert) error {
+	if alert.Severity != SeverityCritical {
+		return nil
+	}
+
+	key := AlertDedupKey(alert)
+	if alert.Status == domain.AlertStatusResolved {
+		return n.backend.Resolve(ctx, key)
+	}
+	return n.trigger(ctx, alertIncident(key, alert, n.consoleURL))
+}
+
+func (n *Notifier) trigger(ctx context.Context, incident *Incident) error {
+	if err := n.backend.Trigger(ctx, incident); err != nil {
+		return err
+	}
+	n.logger.Info().
+		Str("dedup_key", incident.DedupKey).
+		Str("summary", incident.Summary).
+		Msg("Incident triggered")
+	return nil
+}
+
+// AlertDedupKey is the dedup key of an alert's incident. Alerts share a
+// fingerprint for as long as the condition lasts, so refiring updates the
+// same incident.
+func AlertDedupKey(alert *domain.Alert) string {
+	if alert.Fingerprint != "" {
+		return "alert/" + alert.Fingerprint
+	}
+	return "alert/" + alert.ID
+}
+
+// DeployDedupKey is the dedup key of the incident of a service's failed
+// production deploys to a cluster
+func DeployDedupKey(serviceID, clusterID uuid.UUID) string {
+	return "deploy/" + serviceID.String() + "/" + clusterID.String()
+}
+
+// productionEnvironment returns the production environment of the
+// deployment's project on its cluster, or nil when it has none
+func (n *Notifier) productionEnvironment(ctx context.Context, deployment *domain.Deployment) (*domain.Environment, error) {
+	if deployment.ClusterID == uuid.Nil {
+		return nil, nil
+	}
+	environments, err := n.envRepo.ListByProject(ctx, deployment.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	for _, env := range environments {
+		if env.ClusterID == deployment.ClusterID && env.Type == domain.EnvironmentTypeProduction {
+			return env, nil
+		}
+	}
+	return nil, nil
+}
+
+// serviceName names a service in incidents, by its ID when it cannot be loaded
+func (n *Notifier) serviceName(ctx context.Context, serviceID uuid.UUID) string {
+	if n.serviceRepo == nil {
+		return serviceID.String()
+	}
+	service, err := n.serviceRepo.GetByID(ctx, serviceID)
+	if err != nil {
+		return serviceID.String()
+	}
+	return service.Name
+}
+
+func deployIncident(key, serviceName string, env *domain.Environment, deployment *domain.Deployment, consoleURL string) *Incident {
+	incident := &Incident{
+		DedupKey: key,
+		Summary:  fmt.Sprintf("Production deploy of %s failed", serviceName),
+		Source:   serviceName,
+		Details: map[string]string{
+			"environment": env.Name,
+			"namespace":   env.Namespace,
+			"service_id":  deployment.ServiceID.String(),
+			"cluster_id":  deployment.ClusterID.String(),
+		},
+		Link: link(consoleURL, "/services/"+deployment.ServiceID.String()),
+	}
+	if deployment.ID != uuid.Nil {
+		incident.Details["deployment_id"] = deployment.ID.String()
+	}
+	if deployment.Version != "" {
+		incident.Summary = fmt.Sprintf("Production deploy of %s %s failed", serviceName, deployment.Version)
+		incident.Details["version"] = deployment.Version
+	}
+	if deployment.ErrorMessage != "" {
+		incident.Details["error"] = deployment.ErrorMessage
+	}
+	if deployment.TriggeredBy != "" {
+		incident.Details["triggered_by"] = deployment.TriggeredBy
+	}
+	return incident
+}
+
+func alertIncident(key string, alert *domain.Alert, consoleURL string) *Incident {
+	incident := &Incident{
+		DedupKey: key,
+		Summary:  alert.Name,
+		Source:   alert.Source,
+		Details:  map[string]string{"alert_id": alert.ID},
+	}
+	if alert.Message != "" {
+		incident.Summary = alert.Name + ": " + alert.Message
+	}
+	for name, value := range alert.Labels {
+		incident.Details[name] = value
+	}
+	if alert.ClusterID != nil {
+		incident.Details["cluster_id"] = alert.ClusterID.String()
+		incident.Link = link(consoleURL, "/clusters/"+alert.ClusterID.String())
+	}
+	if alert.ServiceID != nil {
+		incident.Details["service_id"] = alert.ServiceID.String()
+		incident.Link = link(consoleURL, "/services/"+alert.ServiceID.String())
+	}
+	if alert.ProjectID != nil {
+		incident.Details["project_id"] = alert.ProjectID.String()
+	}
+	return incident
+}
+
+// link joins the console URL and a path, or returns nothing without a console URL
+func link(consoleURL, path string) string {
+	if consoleURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(consoleURL, "/") + path
+}
@@ -0,0 +1,106 @@
+package escalation
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingBackend struct {
+	triggered []*Incident
+	resolved  []string
+}
+
+func (b *recordingBackend) Trigger(_ context.Context, incident *Incident) error {
+	b.triggered = append(b.triggered, incident)
+	return nil
+}
+
+func (b *recordingBackend) Resolve(_ context.Context, dedupKey string) error {
+	b.resolved = append(b.resolved, dedupKey)
+	return nil
+}
+
+type fakeEnvironments struct {
+	domain.EnvironmentRepository
+	environments []*domain.Environment
+}
+
+func (f fakeEnvironments) ListByProject(context.Context, uuid.UUID) ([]*domain.Environment, error) {
+	return f.environments, nil
+}
+
+func TestSendAlertNotification(t *testing.T) {
+	backend := &recordingBackend{}
+	n := NewNotifier(backend, fakeEnvironments{}, nil, "https://console.example.com", logger.New("error", "json", io.Discard))
+
+	clusterID := uuid.New()
+	alert := &domain.Alert{
+		ID:          "a1",
+		Fingerprint: "ClusterUnhealthy/" + clusterID.String(),
+		Name:        "ClusterUnhealthy",
+		Severity:    "critical",
+		Status:      domain.AlertStatusFiring,
+		Source:      "platform",
+		Message:     "API server unreachable",
+		ClusterID:   &clusterID,
+	}
+	require.NoError(t, n.SendAlertNotification(context.Background(), alert))
+	require.Len(t, backend.triggered, 1)
+	incident := backend.triggered[0]
+	assert.Equal(t, "alert/ClusterUnhealthy/"+clusterID.String(), incident.DedupKey)
+	assert.Equal(t, "ClusterUnhealthy: API server unreachable", incident.Summary)
+	assert.Equal(t, "https://console.example.com/clusters/"+clusterID.String(), incident.Link)
+
+	// The resolved alert resolves the same incident
+	alert.Status = domain.AlertStatusResolved
+	require.NoError(t, n.SendAlertNotification(context.Background(), alert))
+	assert.Equal(t, []string{incident.DedupKey}, backend.resolved)
+
+	// Other severities do not page
+	alert.Severity = "warning"
+	alert.Status = domain.AlertStatusFiring
+	require.NoError(t, n.SendAlertNotification(context.Background(), alert))
+	assert.Len(t, backend.triggered, 1)
+}
+
+func TestSendDeploymentNotification(t *testing.T) {
+	backend := &recordingBackend{}
+	production := &domain.Environment{Name: "production", Type: domain.EnvironmentTypeProduction, ClusterID: uuid.New(), Namespace: "shop-prod"}
+	staging := &domain.Environment{Name: "staging", Type: domain.EnvironmentTypeStaging, ClusterID: uuid.New()}
+	n := NewNotifier(backend, fakeEnvironments{environments: []*domain.Environment{staging, production}}, nil, "", logger.New("error", "json", io.Discard))
+
+	deployment := &domain.Deployment{
+		ID:           uuid.New(),
+		ServiceID:    uuid.New(),
+		ProjectID:    uuid.New(),
+		ClusterID:    staging.ClusterID,
+		Status:       domain.DeploymentStatusFailed,
+		Version:      "v7",
+		ErrorMessage: "rollout timed out",
+	}
+	// Failed deploys outside production do not page
+	require.NoError(t, n.SendDeploymentNotification(context.Background(), deployment))
+	assert.Empty(t, backend.triggered)
+
+	deployment.ClusterID = production.ClusterID
+	require.NoError(t, n.SendDeploymentNotification(context.Background(), deployment))
+	require.Len(t, backend.triggered, 1)
+	incident := backend.triggered[0]
+	assert.Equal(t, DeployDedupKey(deployment.ServiceID, production.ClusterID), incident.DedupKey)
+	assert.Equal(t, "Production deploy of "+deployment.ServiceID.String()+" v7 failed", incident.Summary)
+	assert.Equal(t, "rollout timed out", incident.Details["error"])
+	assert.Equal(t, "shop-prod", incident.Details["namespace"])
+	assert.Empty(t, incident.Link)
+
+	// The next successful production deploy resolves it
+	deployment.Status = domain.DeploymentStatusSucceeded
+	require.NoError(t, n.SendDeploymentNotification(context.Background(), deployment))
+	assert.Equal(t, []string{incident.DedupKey}, backend.resolved)
+}
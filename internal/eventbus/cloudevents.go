@@ -0,0 +1,177 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+// CloudEvents structured-mode encoding, see
+// https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/formats/json-format.md
+const (
+	CloudEventsSpecVersion = "1.0"
+	ContentTypeCloudEvents = "application/cloudevents+json"
+
+	// CloudEventTypePrefix namespaces event types, e.g. io.northstack.deploy.completed
+	CloudEventTypePrefix = "io.northstack."
+
+	// defaultCloudEventSource is the source of events published without one
+	defaultCloudEventSource = "/northstack/orchestrator"
+
+	// metadataExtension carries the metadata keys that are not valid
+	// extension attribute names, as a JSON object
+	metadataExtension = "northstackmetadata"
+)
+
+// cloudEventAttributes are the context attributes and data members defined
+// by the spec; any other member of an envelope is an extension
+var cloudEventAttributes = map[string]bool{
+	"specversion":     true,
+	"id":              true,
+	"source":          true,
+	"type":            true,
+	"subject":         true,
+	"time":            true,
+	"datacontenttype": true,
+	"dataschema":      true,
+	"data":            true,
+	"data_base64":     true,
+}
+
+var extensionName = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+
+// encodeEvent serializes an event, as a CloudEvent when cloudEvents is set
+// and in the legacy platform format otherwise
+func encodeEvent(event *domain.Event, cloudEvents bool) ([]byte, error) {
+	if !cloudEvents {
+		return json.Marshal(event)
+	}
+
+	source := event.Source
+	if source == "" {
+		source = defaultCloudEventSource
+	}
+	envelope := map[string]interface{}{
+		"specversion":     CloudEventsSpecVersion,
+		"id":              event.ID,
+		"source":          source,
+		"type":            CloudEventTypePrefix + event.Type,
+		"datacontenttype": "application/json",
+		"data":            event.Data,
+	}
+	if event.Subject != "" {
+		envelope["subject"] = event.Subject
+	}
+	if event.Timestamp != 0 {
+		envelope["time"] = time.Unix(0, event.Timestamp).UTC().Format(time.RFC3339Nano)
+	}
+
+	// Metadata such as traceparent becomes extension attributes
+	rest := make(map[string]string)
+	for key, value := range event.Metadata {
+		if extensionName.MatchString(key) && !cloudEventAttributes[key] && key != metadataExtension {
+			envelope[key] = value
+		} else {
+			rest[key] = value
+		}
+	}
+	if len(rest) > 0 {
+		encoded, err := json.Marshal(rest)
+		if err != nil {
+			return nil, err
+		}
+		envelope[metadataExtension] = string(encoded)
+	}
+
+	return json.Marshal(envelope)
+}
+
+// decodeEvent parses an event in either the CloudEvents or the legacy
+// format, so that consumers keep working while publishers migrate
+func decodeEvent(data []byte) (*domain.Event, error) {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	if _, ok := envelope["specversion"]; !ok {
+		var event domain.Event
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, err
+		}
+		return &event, nil
+	}
+
+	var attrs struct {
+		SpecVersion     string `json:"specversion"`
+		ID              string `json:"id"`
+		Source          string `json:"source"`
+		Type            string `json:"type"`
+		Subject         string `json:"subject"`
+		Time            string `json:"time"`
+		DataContentType string `json:"datacontenttype"`
+	}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(attrs.SpecVersion, "1.") {
+		return nil, fmt.Errorf("unsupported CloudEvents specversion %q", attrs.SpecVersion)
+	}
+	if _, ok := envelope["data_base64"]; ok {
+		return nil, fmt.Errorf("CloudEvents with binary data are not supported")
+	}
+	if ct := attrs.DataContentType; ct != "" && !strings.Contains(ct, "json") {
+		return nil, fmt.Errorf("unsupported CloudEvents datacontenttype %q", ct)
+	}
+
+	event := &domain.Event{
+		ID:      attrs.ID,
+		Source:  attrs.Source,
+		Type:    strings.TrimPrefix(attrs.Type, CloudEventTypePrefix),
+		Subject: attrs.Subject,
+	}
+	if attrs.Time != "" {
+		t, err := time.Parse(time.RFC3339Nano, attrs.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CloudEvents time: %w", err)
+		}
+		event.Timestamp = t.UnixNano()
+	}
+	if raw, ok := envelope["data"]; ok {
+		if err := json.Unmarshal(raw, &event.Data); err != nil {
+			return nil, fmt.Errorf("CloudEvents data must be a JSON object: %w", err)
+		}
+	}
+
+	for name, raw := range envelope {
+		if cloudEventAttributes[name] {
+			continue
+		}
+		if event.Metadata == nil {
+			event.Metadata = make(map[string]string)
+		}
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		if name == metadataExtension {
+			var rest map[string]string
+			if s, ok := value.(string); ok && json.Unmarshal([]byte(s), &rest) == nil {
+				for key, v := range rest {
+					event.Metadata[key] = v
+				}
+				continue
+			}
+		}
+		if s, ok := value.(string); ok {
+			event.Metadata[name] = s
+		} else {
+			event.Metadata[name] = string(raw)
+		}
+	}
+
+	return event, nil
+}
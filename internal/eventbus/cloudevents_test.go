@@ -0,0 +1,57 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudEventRoundTrip(t *testing.T) {
+	event := &domain.Event{
+		ID:        "evt-1",
+		Type:      "deploy.completed",
+		Source:    "github",
+		Subject:   "deploy.completed",
+		Data:      map[string]interface{}{"deployment_id": "d-1"},
+		Timestamp: time.Date(2026, 3, 1, 12, 0, 0, 123, time.UTC).UnixNano(),
+		Metadata: map[string]string{
+			"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+			"source":      "github",
+			"delivery_id": "abc",
+		},
+	}
+
+	data, err := encodeEvent(event, true)
+	require.NoError(t, err)
+
+	var envelope map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &envelope))
+	assert.Equal(t, "1.0", envelope["specversion"])
+	assert.Equal(t, "io.northstack.deploy.completed", envelope["type"])
+	assert.Equal(t, "2026-03-01T12:00:00.000000123Z", envelope["time"])
+	assert.Equal(t, event.Metadata["traceparent"], envelope["traceparent"], "traceparent is a CloudEvents extension")
+
+	decoded, err := decodeEvent(data)
+	require.NoError(t, err)
+	assert.Equal(t, event, decoded)
+}
+
+func TestDecodeLegacyEvent(t *testing.T) {
+	data, err := encodeEvent(&domain.Event{ID: "evt-2", Type: "build.started", Source: "orchestrator", Timestamp: 42}, false)
+	require.NoError(t, err)
+
+	decoded, err := decodeEvent(data)
+	require.NoError(t, err)
+	assert.Equal(t, "evt-2", decoded.ID)
+	assert.Equal(t, "build.started", decoded.Type)
+	assert.Equal(t, int64(42), decoded.Timestamp)
+}
+
+func TestDecodeCloudEventRejectsBinaryData(t *testing.T) {
+	_, err := decodeEvent([]byte(`{"specversion":"1.0","id":"1","source":"/x","type":"t","data_base64":"AAE="}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,246 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/northstack/platform/internal/domain"
+	apperrors "github.com/northstack/platform/pkg/errors"
+)
+
+// StreamDeadLetters holds events whose handlers failed on every attempt
+const StreamDeadLetters = "DLQ"
+
+// Headers of dead letters, recording where the event came from and why it failed
+const (
+	HeaderDLQSubject   = "Northstack-DLQ-Subject"
+	HeaderDLQStream    = "Northstack-DLQ-Stream"
+	HeaderDLQStreamSeq = "Northstack-DLQ-Stream-Seq"
+	HeaderDLQConsumer  = "Northstack-DLQ-Consumer"
+	HeaderDLQAttempts  = "Northstack-DLQ-Attempts"
+	HeaderDLQError     = "Northstack-DLQ-Error"
+
+	// HeaderReplayedFrom marks a re-published event with its stream and sequence
+	HeaderReplayedFrom = "Northstack-Replayed-From"
+)
+
+// maxDeadLetters caps a page of ListDeadLetters
+const maxDeadLetters = 500
+
+// deadLetter parks a message in the dead-letter stream under dlq.<consumer>
+func (b *NATSEventBus) deadLetter(msg *nats.Msg, durable string, attempts uint64, cause error) error {
+	dl := nats.NewMsg("dlq." + durable)
+	dl.Data = msg.Data
+	for key, values := range msg.Header {
+		dl.Header[key] = values
+	}
+	dl.Header.Set(HeaderDLQSubject, msg.Subject)
+	dl.Header.Set(HeaderDLQConsumer, durable)
+	dl.Header.Set(HeaderDLQAttempts, strconv.FormatUint(attempts, 10))
+	dl.Header.Set(HeaderDLQError, cause.Error())
+	if meta, err := msg.Metadata(); err == nil {
+		dl.Header.Set(HeaderDLQStream, meta.Stream)
+		dl.Header.Set(HeaderDLQStreamSeq, strconv.FormatUint(meta.Sequence.Stream, 10))
+	}
+
+	_, err := b.js.PublishMsg(dl)
+	return err
+}
+
+// ListDeadLetters returns up to limit dead letters with sequences after the
+// given one, oldest first
+func (b *NATSEventBus) ListDeadLetters(ctx context.Context, after uint64, limit int) ([]*domain.DeadLetter, error) {
+	if b.js == nil {
+		return nil, apperrors.NotImplemented("JetStream is not enabled")
+	}
+	if limit <= 0 || limit > maxDeadLetters {
+		limit = maxDeadLetters
+	}
+
+	info, err := b.js.StreamInfo(StreamDeadLetters, nats.Context(ctx))
+	if err != nil {
+		return nil, apperrors.DependencyFailed("nats", err)
+	}
+	if info.State.Msgs == 0 || after >= info.State.LastSeq {
+		return []*domain.DeadLetter{}, nil
+	}
+
+	sub, err := b.js.SubscribeSync("", nats.BindStream(StreamDeadLetters), nats.OrderedConsumer(), nats.StartSequence(after+1))
+	if err != nil {
+		return nil, apperrors.DependencyFailed("nats", err)
+	}
+	defer sub.Unsubscribe()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	letters := make([]*domain.DeadLetter, 0)
+	for len(letters) < limit {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return nil, apperrors.DependencyFailed("nats", err)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return nil, apperrors.DependencyFailed("nats", err)
+		}
+		letters = append(letters, decodeDeadLetter(meta.Sequence.Stream, meta.Timestamp, msg.Header, msg.Data))
+		if meta.NumPending == 0 {
+			break
+		}
+	}
+	return letters, nil
+}
+
+// GetDeadLetter returns one dead letter
+func (b *NATSEventBus) GetDeadLetter(ctx context.Context, sequence uint64) (*domain.DeadLetter, error) {
+	raw, err := b.getDeadLetter(ctx, sequence)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDeadLetter(raw.Sequence, raw.Time, raw.Header, raw.Data), nil
+}
+
+// Redrive publishes a dead letter on its original subject again, with its
+// original headers, and removes it from the dead-letter stream. Every consumer
+// of the subject receives it again, so handlers must be idempotent.
+func (b *NATSEventBus) Redrive(ctx context.Context, sequence uint64) error {
+	raw, err := b.getDeadLetter(ctx, sequence)
+	if err != nil {
+		return err
+	}
+	subject := raw.Header.Get(HeaderDLQSubject)
+	if subject == "" {
+		return apperrors.BadRequest("dead letter has no original subject")
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = raw.Data
+	for key, values := range raw.Header {
+		msg.Header[key] = values
+	}
+	for _, key := range []string{HeaderDLQSubject, HeaderDLQStream, HeaderDLQStreamSeq, HeaderDLQConsumer, HeaderDLQAttempts, HeaderDLQError} {
+		msg.Header.Del(key)
+	}
+
+	if _, err := b.js.PublishMsg(msg, nats.Context(ctx)); err != nil {
+		return apperrors.DependencyFailed("nats", err)
+	}
+	if err := b.js.DeleteMsg(StreamDeadLetters, sequence, nats.Context(ctx)); err != nil {
+		// Already re-driven; a second attempt would publish it twice
+		b.logger.Warn().Err(err).Int64("sequence", int64(sequence)).Msg("Failed to remove re-driven dead letter")
+	}
+
+	b.logger.Info().Str("subject", subject).Int64("sequence", int64(sequence)).Msg("Dead letter re-driven")
+	return nil
+}
+
+// DiscardDeadLetter removes a dead letter without re-driving it
+func (b *NATSEventBus) DiscardDeadLetter(ctx context.Context, sequence uint64) error {
+	if _, err := b.getDeadLetter(ctx, sequence); err != nil {
+		return err
+	}
+	if err := b.js.DeleteMsg(StreamDeadLetters, sequence, nats.Context(ctx)); err != nil {
+		return apperrors.DependencyFailed("nats", err)
+	}
+	return nil
+}
+
+func (b *NATSEventBus) getDeadLetter(ctx context.Context, sequence uint64) (*nats.RawStreamMsg, error) {
+	if b.js == nil {
+		return nil, apperrors.NotImplemented("JetStream is not enabled")
+	}
+	raw, err := b.js.GetMsg(StreamDeadLetters, sequence, nats.Context(ctx))
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return nil, apperrors.NotFound("dead letter", strconv.FormatUint(sequence, 10))
+	}
+	if err != nil {
+		return nil, apperrors.DependencyFailed("nats", err)
+	}
+	return raw, nil
+}
+
+func decodeDeadLetter(sequence uint64, failedAt time.Time, header nats.Header, data []byte) *domain.DeadLetter {
+	letter := &domain.DeadLetter{
+		Sequence: sequence,
+		Subject:  header.Get(HeaderDLQSubject),
+		Stream:   header.Get(HeaderDLQStream),
+		Consumer: header.Get(HeaderDLQConsumer),
+		Error:    header.Get(HeaderDLQError),
+		FailedAt: failedAt,
+	}
+	letter.StreamSequence, _ = strconv.ParseUint(header.Get(HeaderDLQStreamSeq), 10, 64)
+	letter.Attempts, _ = strconv.Atoi(header.Get(HeaderDLQAttempts))
+
+	if event, err := decodeEvent(data); err == nil {
+		letter.Event = event
+	}
+	return letter
+}
+
+// Replay re-publishes the events a stream stored between from and to,
+// optionally only those matching subject, on their original subjects. Each
+// carries HeaderReplayedFrom. It returns the number of events re-published.
+func (b *NATSEventBus) Replay(ctx context.Context, stream, subject string, from, to time.Time) (int, error) {
+	if b.js == nil {
+		return 0, fmt.Errorf("JetStream is not enabled")
+	}
+	// Events re-published by the replay land after now; stop before them
+	if now := time.Now(); to.IsZero() || to.After(now) {
+		to = now
+	}
+
+	info, err := b.js.StreamInfo(stream, nats.Context(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get stream info: %w", err)
+	}
+	if info.State.Msgs == 0 {
+		return 0, nil
+	}
+
+	sub, err := b.js.SubscribeSync(subject, nats.BindStream(stream), nats.OrderedConsumer(), nats.StartTime(from))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read stream: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	replayed := 0
+	for {
+		readCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		msg, err := sub.NextMsgWithContext(readCtx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			// Nothing stored after from matches subject
+			return replayed, nil
+		}
+		if err != nil {
+			return replayed, fmt.Errorf("failed to read stream: %w", err)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return replayed, fmt.Errorf("failed to read message metadata: %w", err)
+		}
+		if meta.Timestamp.After(to) {
+			return replayed, nil
+		}
+
+		out := nats.NewMsg(msg.Subject)
+		out.Data = msg.Data
+		for key, values := range msg.Header {
+			out.Header[key] = values
+		}
+		out.Header.Set(HeaderReplayedFrom, stream+":"+strconv.FormatUint(meta.Sequence.Stream, 10))
+		if _, err := b.js.PublishMsg(out, nats.Context(ctx)); err != nil {
+			return replayed, fmt.Errorf("failed to re-publish %s:%d: %w", stream, meta.Sequence.Stream, err)
+		}
+		replayed++
+
+		if meta.NumPending == 0 {
+			return replayed, nil
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package eventbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeDeadLetter(t *testing.T) {
+	header := nats.Header{}
+	header.Set(HeaderDLQSubject, "deploy.completed")
+	header.Set(HeaderDLQStream, "DEPLOYMENTS")
+	header.Set(HeaderDLQStreamSeq, "42")
+	header.Set(HeaderDLQConsumer, "orchestrator-deploys")
+	header.Set(HeaderDLQAttempts, "5")
+	header.Set(HeaderDLQError, "deployment not found")
+	failedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	letter := decodeDeadLetter(7, failedAt, header, []byte(`{"id":"evt-1","type":"deploy.completed"}`))
+
+	assert.Equal(t, uint64(7), letter.Sequence)
+	assert.Equal(t, "deploy.completed", letter.Subject)
+	assert.Equal(t, "DEPLOYMENTS", letter.Stream)
+	assert.Equal(t, uint64(42), letter.StreamSequence)
+	assert.Equal(t, "orchestrator-deploys", letter.Consumer)
+	assert.Equal(t, 5, letter.Attempts)
+	assert.Equal(t, "deployment not found", letter.Error)
+	assert.Equal(t, failedAt, letter.FailedAt)
+	require.NotNil(t, letter.Event)
+	assert.Equal(t, "evt-1", letter.Event.ID)
+
+	assert.Nil(t, decodeDeadLetter(8, failedAt, header, []byte("not json")).Event)
+}
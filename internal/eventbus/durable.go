@@ -0,0 +1,223 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+)
+
+// fetchWait is how long a pull waits for events before polling again
+const fetchWait = 5 * time.Second
+
+// DurableSubscriber is an event bus with durable consumers; both drivers are
+type DurableSubscriber interface {
+	DurableSubscribe(ctx context.Context, subject, durable string, handler domain.EventHandler) (domain.Subscription, error)
+}
+
+// SubscribeDurable subscribes handler to subject through the durable consumer
+// named durable, so that one orchestrator replica handles each event, and
+// events published while none runs are not lost. Events are delivered at
+// least once: handlers must be idempotent, and return an error to have the
+// event redelivered. Buses without durable consumers get a queue
+// subscription of the same name.
+func SubscribeDurable(ctx context.Context, bus domain.EventBus, subject, durable string, handler domain.EventHandler) (domain.Subscription, error) {
+	if d, ok := bus.(DurableSubscriber); ok {
+		return d.DurableSubscribe(ctx, subject, durable, handler)
+	}
+	return bus.QueueSubscribe(ctx, subject, durable, handler)
+}
+
+// DurableName is the name of the durable consumer through which component
+// handles subject, e.g. cronjobs-service-created
+func DurableName(component, subject string) string {
+	return component + "-" + strings.ReplaceAll(subject, ".", "-")
+}
+
+// DurableSubscribe delivers the events on subject at least once to the durable
+// JetStream consumer named durable. Orchestrator replicas that use the same
+// name share the work, and events published while none is running are
+// delivered once one starts. A handler error redelivers the event after the
+// configured backoff, up to MaxDeliver attempts, after which the event is
+// parked in the dead-letter stream. A new consumer starts with the events
+// published after it is created.
+//
+// Without JetStream it falls back to a core NATS queue subscription, which
+// delivers at most once.
+func (b *NATSEventBus) DurableSubscribe(ctx context.Context, subject, durable string, handler domain.EventHandler) (domain.Subscription, error) {
+	if b.js == nil {
+		b.logger.Warn().Str("subject", subject).Msg("JetStream is not enabled, events published while the orchestrator is down will be lost")
+		return b.QueueSubscribe(ctx, subject, durable, handler)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, fmt.Errorf("event bus is closed")
+	}
+
+	stream, err := b.js.StreamNameBySubject(subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stream for %s: %w", subject, err)
+	}
+	if err := b.ensureConsumer(stream, subject, durable); err != nil {
+		return nil, err
+	}
+
+	// Bound to the consumer so that unsubscribing, e.g. on shutdown, keeps it
+	sub, err := b.js.PullSubscribe(subject, durable, nats.Bind(stream, durable))
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to consumer %s: %w", durable, err)
+	}
+
+	b.subs = append(b.subs, sub)
+	go b.consume(ctx, sub, subject, durable, handler)
+
+	b.logger.Info().
+		Str("subject", subject).
+		Str("stream", stream).
+		Str("consumer", durable).
+		Msg("Durable consumer subscribed")
+
+	return &natsSubscription{sub: sub}, nil
+}
+
+// ensureConsumer creates the durable pull consumer, or updates it to the
+// configured delivery limits
+func (b *NATSEventBus) ensureConsumer(stream, subject, durable string) error {
+	cfg := &nats.ConsumerConfig{
+		Durable:       durable,
+		FilterSubject: subject,
+		DeliverPolicy: nats.DeliverNewPolicy,
+		AckPolicy:     nats.AckExplicitPolicy,
+		AckWait:       b.config.Consumers.AckWait,
+		MaxDeliver:    b.config.Consumers.MaxDeliver,
+	}
+
+	if _, err := b.js.AddConsumer(stream, cfg); err != nil {
+		if _, err := b.js.UpdateConsumer(stream, cfg); err != nil {
+			return fmt.Errorf("failed to create consumer %s: %w", durable, err)
+		}
+	}
+	return nil
+}
+
+// consume pulls events for a durable subscription until ctx is cancelled or
+// the subscription is closed
+func (b *NATSEventBus) consume(ctx context.Context, sub *nats.Subscription, subject, durable string, handler domain.EventHandler) {
+	batch := b.config.Consumers.BatchSize
+	if batch <= 0 {
+		batch = 1
+	}
+
+	for ctx.Err() == nil && sub.IsValid() {
+		fetchCtx, cancel := context.WithTimeout(ctx, fetchWait)
+		msgs, err := sub.Fetch(batch, nats.Context(fetchCtx))
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, nats.ErrTimeout) || ctx.Err() != nil {
+				continue
+			}
+			if errors.Is(err, nats.ErrBadSubscription) || errors.Is(err, nats.ErrConnectionClosed) {
+				return
+			}
+			b.logger.Warn().Err(err).Str("subject", subject).Msg("Failed to fetch events")
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for _, msg := range msgs {
+			b.handleDurable(msg, subject, durable, handler)
+		}
+	}
+}
+
+// handleDurable runs the handler for one message and acknowledges it, or
+// schedules its redelivery when the handler fails
+func (b *NATSEventBus) handleDurable(msg *nats.Msg, subject, durable string, handler domain.EventHandler) {
+	event, err := decodeEvent(msg.Data)
+	if err != nil {
+		// Redelivering a message that cannot be decoded cannot help
+		b.logger.Error().Err(err).Str("subject", subject).Msg("Failed to unmarshal event, moving it to the dead-letter queue")
+		metrics.ObserveConsume(subject, err)
+		b.giveUp(msg, durable, 1, err)
+		return
+	}
+	if err := b.checkSchema(msg.Subject, event); err != nil {
+		// Neither can redelivering it to a consumer that is too old
+		b.logger.Error().Err(err).Str("subject", subject).Str("event_id", event.ID).Msg("Moving event to the dead-letter queue")
+		metrics.ObserveConsume(subject, err)
+		b.giveUp(msg, durable, 1, err)
+		return
+	}
+
+	_, span := tracing.StartConsume(msg.Subject, event)
+	err = handler(event)
+	metrics.ObserveConsume(subject, err)
+	tracing.End(span, err)
+	if err == nil {
+		if err := msg.Ack(); err != nil {
+			b.logger.Warn().Err(err).Str("event_id", event.ID).Msg("Failed to acknowledge event")
+		}
+		return
+	}
+
+	var attempt uint64 = 1
+	if meta, metaErr := msg.Metadata(); metaErr == nil {
+		attempt = meta.NumDelivered
+	}
+	if maxDeliver := b.config.Consumers.MaxDeliver; maxDeliver > 0 && attempt >= uint64(maxDeliver) {
+		b.logger.Error().
+			Err(err).
+			Str("subject", subject).
+			Str("event_id", event.ID).
+			Int64("attempts", int64(attempt)).
+			Msg("Event handler failed on every attempt, moving it to the dead-letter queue")
+		b.giveUp(msg, durable, attempt, err)
+		return
+	}
+
+	delay := retryDelay(b.config.Consumers.Backoff, attempt)
+	b.logger.Warn().
+		Err(err).
+		Str("subject", subject).
+		Str("event_id", event.ID).
+		Int64("attempt", int64(attempt)).
+		Dur("retry_in", delay).
+		Msg("Event handler error, retrying")
+	msg.NakWithDelay(delay)
+}
+
+// giveUp dead-letters a message and stops its redelivery
+func (b *NATSEventBus) giveUp(msg *nats.Msg, durable string, attempts uint64, cause error) {
+	if err := b.deadLetter(msg, durable, attempts, cause); err != nil {
+		// It remains in its own stream, from where it can be replayed
+		b.logger.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to dead-letter event")
+	}
+	msg.Term()
+}
+
+// retryDelay is the backoff before the retry that follows the given attempt;
+// the last delay repeats
+func retryDelay(backoff []time.Duration, attempt uint64) time.Duration {
+	if len(backoff) == 0 {
+		return 0
+	}
+	if attempt == 0 {
+		attempt = 1
+	}
+	if attempt > uint64(len(backoff)) {
+		return backoff[len(backoff)-1]
+	}
+	return backoff[attempt-1]
+}
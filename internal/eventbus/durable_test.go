@@ -0,0 +1,60 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDelay(t *testing.T) {
+	backoff := []time.Duration{time.Second, 10 * time.Second, time.Minute}
+
+	assert.Equal(t, time.Second, retryDelay(backoff, 1))
+	assert.Equal(t, 10*time.Second, retryDelay(backoff, 2))
+	assert.Equal(t, time.Minute, retryDelay(backoff, 3))
+	assert.Equal(t, time.Minute, retryDelay(backoff, 7), "the last delay repeats")
+	assert.Equal(t, time.Duration(0), retryDelay(nil, 2))
+}
+
+// durableBus records the durable consumers subscribed to each subject
+type durableBus struct {
+	domain.EventBus
+	durables map[string]string
+}
+
+func (b *durableBus) DurableSubscribe(_ context.Context, subject, durable string, _ domain.EventHandler) (domain.Subscription, error) {
+	b.durables[subject] = durable
+	return nil, nil
+}
+
+// queueBus records the queue groups subscribed to each subject
+type queueBus struct {
+	domain.EventBus
+	queues map[string]string
+}
+
+func (b *queueBus) QueueSubscribe(_ context.Context, subject, queue string, _ domain.EventHandler) (domain.Subscription, error) {
+	b.queues[subject] = queue
+	return nil, nil
+}
+
+func TestSubscribeDurable(t *testing.T) {
+	handler := func(*domain.Event) error { return nil }
+	durable := DurableName("cronjobs", "service.deleted")
+	assert.Equal(t, "cronjobs-service-deleted", durable)
+
+	durables := &durableBus{durables: make(map[string]string)}
+	_, err := SubscribeDurable(context.Background(), durables, "service.deleted", durable, handler)
+	require.NoError(t, err)
+	assert.Equal(t, durable, durables.durables["service.deleted"])
+
+	// Buses without durable consumers still deliver each event to one replica
+	queues := &queueBus{queues: make(map[string]string)}
+	_, err = SubscribeDurable(context.Background(), queues, "service.deleted", durable, handler)
+	require.NoError(t, err)
+	assert.Equal(t, durable, queues.queues["service.deleted"])
+}
@@ -4,8 +4,9 @@ package eventbus
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -13,6 +14,9 @@ import (
 	"github.com/nats-io/nats.go"
 	"github.com/northstack/platform/internal/config"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	apperrors "github.com/northstack/platform/pkg/errors"
 	"github.com/northstack/platform/pkg/logger"
 )
 
@@ -29,6 +33,7 @@ const (
 	SubjectServiceDeleted   = "service.deleted"
 	SubjectServiceScaled    = "service.scaled"
 	SubjectProjectCreated   = "project.created"
+	SubjectProjectUpdated   = "project.updated"
 	SubjectProjectDeleted   = "project.deleted"
 	SubjectClusterCreated   = "cluster.created"
 	SubjectClusterUpdated   = "cluster.updated"
@@ -42,15 +47,26 @@ const (
 	SubjectAuditLog         = "audit.log"
 )
 
+// HeaderSignature carries the detached JWS of a signed event's data
+const HeaderSignature = "Northstack-Signature"
+
+// EventSigner signs the encoded form of an event
+type EventSigner interface {
+	SignEvent(ctx context.Context, event *domain.Event, payload []byte) (string, error)
+}
+
 // NATSEventBus implements the EventBus interface using NATS
 type NATSEventBus struct {
-	conn   *nats.Conn
-	js     nats.JetStreamContext
-	config *config.NATSConfig
-	logger *logger.Logger
-	subs   []*nats.Subscription
-	mu     sync.RWMutex
-	closed bool
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	config  *config.NATSConfig
+	logger  *logger.Logger
+	subs    []*nats.Subscription
+	signer  EventSigner
+	schemas *SchemaRegistry
+	tenants *tenantMirror
+	mu      sync.RWMutex
+	closed  bool
 }
 
 // natsSubscription wraps a NATS subscription
@@ -83,7 +99,9 @@ func NewNATSEventBus(cfg *config.NATSConfig, log *logger.Logger) (*NATSEventBus,
 	}
 
 	// Add authentication if configured
-	if cfg.Token != "" {
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, nats.UserCredentials(cfg.CredentialsFile))
+	} else if cfg.Token != "" {
 		opts = append(opts, nats.Token(cfg.Token))
 	} else if cfg.Username != "" && cfg.Password != "" {
 		opts = append(opts, nats.UserInfo(cfg.Username, cfg.Password))
@@ -100,16 +118,22 @@ func NewNATSEventBus(cfg *config.NATSConfig, log *logger.Logger) (*NATSEventBus,
 		}
 	}
 
+	schemas, err := NewSchemaRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event schemas: %w", err)
+	}
+
 	conn, err := nats.Connect(cfg.URL, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
 
 	bus := &NATSEventBus{
-		conn:   conn,
-		config: cfg,
-		logger: log,
-		subs:   make([]*nats.Subscription, 0),
+		conn:    conn,
+		config:  cfg,
+		logger:  log,
+		subs:    make([]*nats.Subscription, 0),
+		schemas: schemas,
 	}
 
 	// Initialize JetStream if enabled
@@ -138,6 +162,7 @@ func (b *NATSEventBus) createStreams() error {
 	streams := []struct {
 		name     string
 		subjects []string
+		maxAge   time.Duration // Seven days when zero
 	}{
 		{
 			name:     "BUILDS",
@@ -175,14 +200,28 @@ func (b *NATSEventBus) createStreams() error {
 			name:     "AUDIT",
 			subjects: []string{"audit.>"},
 		},
+		{
+			name:     "NOTIFICATIONS",
+			subjects: []string{"notification.>"},
+		},
+		{
+			// Kept longer so that failures can be investigated before re-driving
+			name:     StreamDeadLetters,
+			subjects: []string{"dlq.>"},
+			maxAge:   30 * 24 * time.Hour,
+		},
 	}
 
 	for _, stream := range streams {
+		maxAge := stream.maxAge
+		if maxAge == 0 {
+			maxAge = 7 * 24 * time.Hour // Keep events for 7 days
+		}
 		_, err := b.js.AddStream(&nats.StreamConfig{
 			Name:       stream.name,
 			Subjects:   stream.subjects,
 			Retention:  nats.LimitsPolicy,
-			MaxAge:     7 * 24 * time.Hour, // Keep events for 7 days
+			MaxAge:     maxAge,
 			MaxBytes:   1024 * 1024 * 1024, // 1GB max
 			Discard:    nats.DiscardOld,
 			Storage:    nats.FileStorage,
@@ -194,7 +233,7 @@ func (b *NATSEventBus) createStreams() error {
 				Name:       stream.name,
 				Subjects:   stream.subjects,
 				Retention:  nats.LimitsPolicy,
-				MaxAge:     7 * 24 * time.Hour,
+				MaxAge:     maxAge,
 				MaxBytes:   1024 * 1024 * 1024,
 				Discard:    nats.DiscardOld,
 				Storage:    nats.FileStorage,
@@ -216,6 +255,8 @@ func (b *NATSEventBus) Publish(ctx context.Context, subject string, event *domai
 		b.mu.RUnlock()
 		return fmt.Errorf("event bus is closed")
 	}
+	signer := b.signer
+	tenants := b.tenants
 	b.mu.RUnlock()
 
 	// Set event ID and timestamp if not set
@@ -227,21 +268,43 @@ func (b *NATSEventBus) Publish(ctx context.Context, subject string, event *domai
 	}
 	event.Subject = subject
 
-	data, err := json.Marshal(event)
+	if err := b.applySchema(subject, event); err != nil {
+		metrics.ObservePublish(subject, err)
+		return err
+	}
+
+	_, span := tracing.StartPublish(ctx, subject, event)
+	data, err := encodeEvent(event, b.config.CloudEvents)
 	if err != nil {
+		tracing.End(span, err)
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
+	msg := b.newMsg(subject, data)
+	if signer != nil {
+		// Consumers that require signatures drop the event; the platform keeps running
+		if signature, err := signer.SignEvent(ctx, event, data); err != nil {
+			b.logger.Warn().Err(err).Str("subject", subject).Msg("Failed to sign event, publishing unsigned")
+		} else {
+			msg.Header.Set(HeaderSignature, signature)
+		}
+	}
+
 	// Use JetStream if available for durability
 	if b.js != nil {
-		_, err = b.js.Publish(subject, data)
+		_, err = b.js.PublishMsg(msg)
 	} else {
-		err = b.conn.Publish(subject, data)
+		err = b.conn.PublishMsg(msg)
 	}
+	metrics.ObservePublish(subject, err)
+	tracing.End(span, err)
 
 	if err != nil {
 		return fmt.Errorf("failed to publish event: %w", err)
 	}
+	if tenants != nil {
+		b.mirror(ctx, tenants, msg, event)
+	}
 
 	b.logger.Debug().
 		Str("subject", subject).
@@ -252,6 +315,58 @@ func (b *NATSEventBus) Publish(ctx context.Context, subject string, event *domai
 	return nil
 }
 
+// UseSigner signs every event published from now on
+func (b *NATSEventBus) UseSigner(signer EventSigner) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.signer = signer
+}
+
+// applySchema validates an event's payload against the latest schema of its
+// subject and records that schema's version in the event metadata
+func (b *NATSEventBus) applySchema(subject string, event *domain.Event) error {
+	mode := b.config.SchemaValidation
+	if mode == SchemaValidationOff || b.schemas == nil {
+		return nil
+	}
+
+	version, err := b.schemas.Validate(subject, event.Data)
+	if err != nil {
+		metrics.ObserveSchemaMismatch(subject, "publish")
+		if mode == SchemaValidationEnforce {
+			return err
+		}
+		b.logger.Warn().Err(err).Str("subject", subject).Str("event_type", event.Type).Msg("Event payload does not match its schema")
+	}
+	if version > 0 {
+		if event.Metadata == nil {
+			event.Metadata = make(map[string]string)
+		}
+		event.Metadata[MetadataSchemaVersion] = strconv.Itoa(version)
+	}
+	return nil
+}
+
+// checkSchema flags a consumed event published with a schema version this
+// build does not know; it is only rejected in enforce mode
+func (b *NATSEventBus) checkSchema(subject string, event *domain.Event) error {
+	mode := b.config.SchemaValidation
+	if mode == SchemaValidationOff || b.schemas == nil {
+		return nil
+	}
+
+	err := b.schemas.Check(subject, event)
+	if err == nil {
+		return nil
+	}
+	metrics.ObserveSchemaMismatch(subject, "consume")
+	if mode == SchemaValidationEnforce {
+		return err
+	}
+	b.logger.Warn().Err(err).Str("subject", subject).Str("event_id", event.ID).Msg("Consumed event has an unknown schema version")
+	return nil
+}
+
 // Subscribe subscribes to events on a subject
 func (b *NATSEventBus) Subscribe(ctx context.Context, subject string, handler domain.EventHandler) (domain.Subscription, error) {
 	b.mu.Lock()
@@ -261,17 +376,7 @@ func (b *NATSEventBus) Subscribe(ctx context.Context, subject string, handler do
 		return nil, fmt.Errorf("event bus is closed")
 	}
 
-	sub, err := b.conn.Subscribe(subject, func(msg *nats.Msg) {
-		var event domain.Event
-		if err := json.Unmarshal(msg.Data, &event); err != nil {
-			b.logger.Error().Err(err).Str("subject", subject).Msg("Failed to unmarshal event")
-			return
-		}
-
-		if err := handler(&event); err != nil {
-			b.logger.Error().Err(err).Str("subject", subject).Str("event_id", event.ID).Msg("Event handler error")
-		}
-	})
+	sub, err := b.conn.Subscribe(subject, b.dispatch(subject, handler))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to subscribe: %w", err)
@@ -292,17 +397,7 @@ func (b *NATSEventBus) QueueSubscribe(ctx context.Context, subject string, queue
 		return nil, fmt.Errorf("event bus is closed")
 	}
 
-	sub, err := b.conn.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
-		var event domain.Event
-		if err := json.Unmarshal(msg.Data, &event); err != nil {
-			b.logger.Error().Err(err).Str("subject", subject).Msg("Failed to unmarshal event")
-			return
-		}
-
-		if err := handler(&event); err != nil {
-			b.logger.Error().Err(err).Str("subject", subject).Str("event_id", event.ID).Msg("Event handler error")
-		}
-	})
+	sub, err := b.conn.QueueSubscribe(subject, queue, b.dispatch(subject, handler))
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to queue subscribe: %w", err)
@@ -314,6 +409,32 @@ func (b *NATSEventBus) QueueSubscribe(ctx context.Context, subject string, queue
 	return &natsSubscription{sub: sub}, nil
 }
 
+// dispatch decodes messages for a subscription and runs the handler inside a
+// consumer span that continues the publisher's trace
+func (b *NATSEventBus) dispatch(subject string, handler domain.EventHandler) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		event, err := decodeEvent(msg.Data)
+		if err != nil {
+			b.logger.Error().Err(err).Str("subject", subject).Msg("Failed to unmarshal event")
+			metrics.ObserveConsume(subject, err)
+			return
+		}
+		if err := b.checkSchema(msg.Subject, event); err != nil {
+			b.logger.Error().Err(err).Str("subject", subject).Str("event_id", event.ID).Msg("Dropping event")
+			metrics.ObserveConsume(subject, err)
+			return
+		}
+
+		_, span := tracing.StartConsume(msg.Subject, event)
+		err = handler(event)
+		metrics.ObserveConsume(subject, err)
+		tracing.End(span, err)
+		if err != nil {
+			b.logger.Error().Err(err).Str("subject", subject).Str("event_id", event.ID).Msg("Event handler error")
+		}
+	}
+}
+
 // Request sends a request and waits for a response
 func (b *NATSEventBus) Request(ctx context.Context, subject string, event *domain.Event) (*domain.Event, error) {
 	b.mu.RLock()
@@ -331,7 +452,7 @@ func (b *NATSEventBus) Request(ctx context.Context, subject string, event *domai
 		event.Timestamp = time.Now().UnixNano()
 	}
 
-	data, err := json.Marshal(event)
+	data, err := encodeEvent(event, b.config.CloudEvents)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal event: %w", err)
 	}
@@ -342,17 +463,113 @@ func (b *NATSEventBus) Request(ctx context.Context, subject string, event *domai
 		timeout = time.Until(deadline)
 	}
 
-	msg, err := b.conn.Request(subject, data, timeout)
+	msg, err := b.conn.RequestMsg(b.newMsg(subject, data), timeout)
 	if err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return nil, apperrors.DependencyFailed("worker", fmt.Errorf("no worker answers %s", subject))
+		}
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 
-	var response domain.Event
-	if err := json.Unmarshal(msg.Data, &response); err != nil {
+	response, err := decodeEvent(msg.Data)
+	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	if id := response.Metadata[MetadataCorrelationID]; id != "" && id != event.ID {
+		return nil, fmt.Errorf("reply to request %s answers request %s", event.ID, id)
+	}
+	if response.Type == EventTypeRPCError {
+		return nil, replyError(response)
+	}
+
+	return response, nil
+}
+
+// newMsg creates a message carrying an encoded event, labelled with its
+// content type when it is a CloudEvent
+func (b *NATSEventBus) newMsg(subject string, data []byte) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if b.config.CloudEvents {
+		msg.Header.Set("Content-Type", ContentTypeCloudEvents)
+	}
+	return msg
+}
+
+// StreamBounds returns the first and last sequence held by a stream
+func (b *NATSEventBus) StreamBounds(ctx context.Context, stream string) (uint64, uint64, error) {
+	if b.js == nil {
+		return 0, 0, fmt.Errorf("JetStream is not enabled")
+	}
+
+	info, err := b.js.StreamInfo(stream, nats.Context(ctx))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get stream info: %w", err)
+	}
+	return info.State.FirstSeq, info.State.LastSeq, nil
+}
+
+// ReadStream returns the events with sequences in [from, to], oldest first,
+// using a short-lived ordered consumer
+func (b *NATSEventBus) ReadStream(ctx context.Context, stream string, from, to uint64) ([]*domain.StoredEvent, error) {
+	if b.js == nil {
+		return nil, fmt.Errorf("JetStream is not enabled")
+	}
+	if from == 0 {
+		from = 1
+	}
+	if to < from {
+		return nil, nil
+	}
+
+	sub, err := b.js.SubscribeSync("", nats.BindStream(stream), nats.OrderedConsumer(), nats.StartSequence(from))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Guards against waiting on sequences that were removed while reading
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	events := make([]*domain.StoredEvent, 0, to-from+1)
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream: %w", err)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read message metadata: %w", err)
+		}
+		if meta.Sequence.Stream > to {
+			break
+		}
+
+		stored := &domain.StoredEvent{
+			Stream:   stream,
+			Sequence: meta.Sequence.Stream,
+			Stored:   meta.Timestamp,
+		}
+		if event, err := decodeEvent(msg.Data); err == nil {
+			stored.Event = event
+		}
+		events = append(events, stored)
+
+		if meta.Sequence.Stream == to || meta.NumPending == 0 {
+			break
+		}
+	}
+
+	return events, nil
+}
 
-	return &response, nil
+// Health reports whether the connection to NATS is up
+func (b *NATSEventBus) Health(ctx context.Context) error {
+	if !b.conn.IsConnected() {
+		return fmt.Errorf("nats connection is %s", b.conn.Status())
+	}
+	return nil
 }
 
 // Close closes the event bus connection
@@ -0,0 +1,153 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	apperrors "github.com/northstack/platform/pkg/errors"
+)
+
+// Request/reply between the API and adapter workers. A reply carries the ID of
+// its request in the correlationid metadata key; a failed call is answered
+// with an rpc.error event describing the error.
+const (
+	MetadataCorrelationID = "correlationid"
+
+	EventTypeRPCReply = "rpc.reply"
+	EventTypeRPCError = "rpc.error"
+)
+
+// Respond answers the requests sent on subject, each by one of the
+// responders sharing the queue name, until ctx is cancelled
+func (b *NATSEventBus) Respond(ctx context.Context, subject, queue string, handler domain.RequestHandler) (domain.Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, fmt.Errorf("event bus is closed")
+	}
+
+	sub, err := b.conn.QueueSubscribe(subject, queue, func(msg *nats.Msg) {
+		b.respond(ctx, msg, subject, handler)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe responder: %w", err)
+	}
+
+	b.subs = append(b.subs, sub)
+	b.logger.Debug().Str("subject", subject).Str("queue", queue).Msg("Responding to requests")
+
+	return &natsSubscription{sub: sub}, nil
+}
+
+// respond runs the handler for one request and sends its reply
+func (b *NATSEventBus) respond(ctx context.Context, msg *nats.Msg, subject string, handler domain.RequestHandler) {
+	if msg.Reply == "" {
+		b.logger.Warn().Str("subject", msg.Subject).Msg("Dropping request without a reply subject")
+		return
+	}
+
+	var reply *domain.Event
+	request, err := decodeEvent(msg.Data)
+	if err != nil {
+		metrics.ObserveConsume(subject, err)
+		reply = errorReply(apperrors.BadRequest("invalid request: " + err.Error()))
+		request = &domain.Event{}
+	} else {
+		_, span := tracing.StartConsume(msg.Subject, request)
+		start := time.Now()
+		reply, err = handler(ctx, request)
+		metrics.ObserveConsume(subject, err)
+		tracing.End(span, err)
+
+		log := b.logger.Debug()
+		if err != nil {
+			log = b.logger.Warn().Err(err)
+			reply = errorReply(err)
+		}
+		log.Str("subject", msg.Subject).
+			Str("correlation_id", request.ID).
+			Dur("duration", time.Since(start)).
+			Msg("Request handled")
+	}
+
+	if reply == nil {
+		reply = &domain.Event{}
+	}
+	if reply.ID == "" {
+		reply.ID = uuid.New().String()
+	}
+	if reply.Type == "" {
+		reply.Type = EventTypeRPCReply
+	}
+	if reply.Timestamp == 0 {
+		reply.Timestamp = time.Now().UnixNano()
+	}
+	if reply.Metadata == nil {
+		reply.Metadata = make(map[string]string)
+	}
+	reply.Metadata[MetadataCorrelationID] = request.ID
+
+	data, err := encodeEvent(reply, b.config.CloudEvents)
+	if err != nil {
+		data, _ = encodeEvent(errorReply(fmt.Errorf("failed to marshal reply: %w", err)), b.config.CloudEvents)
+	}
+	if err := msg.RespondMsg(b.newMsg(msg.Reply, data)); err != nil {
+		b.logger.Error().Err(err).Str("subject", msg.Subject).Str("correlation_id", request.ID).Msg("Failed to send reply")
+	}
+}
+
+// errorReply describes an error so that the requester gets back an
+// equivalent one from Request
+func errorReply(err error) *domain.Event {
+	data := map[string]interface{}{
+		"code":    string(apperrors.CodeInternalError),
+		"message": err.Error(),
+		"status":  http.StatusInternalServerError,
+	}
+
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		data["code"] = string(appErr.Code)
+		data["message"] = appErr.Message
+		data["status"] = appErr.HTTPStatus
+		if appErr.Details != nil {
+			data["details"] = appErr.Details
+		}
+		if appErr.Err != nil {
+			data["error"] = appErr.Err.Error()
+		}
+	}
+
+	return &domain.Event{Type: EventTypeRPCError, Data: data}
+}
+
+// replyError rebuilds the error described by an rpc.error reply
+func replyError(reply *domain.Event) error {
+	code, _ := reply.Data["code"].(string)
+	message, _ := reply.Data["message"].(string)
+	status, _ := reply.Data["status"].(float64)
+	if code == "" {
+		code = string(apperrors.CodeInternalError)
+	}
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	appErr := apperrors.NewError(apperrors.Code(code), message, int(status))
+	if details, ok := reply.Data["details"]; ok {
+		appErr.WithDetails(details)
+	}
+	if cause, ok := reply.Data["error"].(string); ok {
+		appErr.WithError(errors.New(cause))
+	}
+	return appErr
+}
@@ -0,0 +1,38 @@
+package eventbus
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	apperrors "github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplyErrorRoundTrip(t *testing.T) {
+	err := replyError(roundTrip(t, errorReply(apperrors.NotFound("cluster", "c-123"))))
+	assert.True(t, apperrors.IsNotFound(err))
+
+	err = replyError(roundTrip(t, errorReply(apperrors.DependencyFailed("rancher", errors.New("connection refused")))))
+	var appErr *apperrors.AppError
+	require.True(t, errors.As(err, &appErr))
+	assert.Equal(t, apperrors.CodeDependencyFailed, appErr.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, appErr.HTTPStatus)
+	assert.EqualError(t, appErr.Err, "connection refused")
+
+	err = replyError(roundTrip(t, errorReply(errors.New("boom"))))
+	require.True(t, errors.As(err, &appErr))
+	assert.Equal(t, apperrors.CodeInternalError, appErr.Code)
+	assert.Equal(t, "boom", appErr.Message)
+}
+
+// roundTrip sends an event through the wire format
+func roundTrip(t *testing.T, event *domain.Event) *domain.Event {
+	data, err := encodeEvent(event, false)
+	require.NoError(t, err)
+	decoded, err := decodeEvent(data)
+	require.NoError(t, err)
+	return decoded
+}
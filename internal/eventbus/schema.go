@@ -0,0 +1,333 @@
+package eventbus
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+)
+
+// MetadataSchemaVersion is the event metadata key carrying the version of the
+// schema an event's payload was published with
+const MetadataSchemaVersion = "schemaversion"
+
+// Schema validation modes
+const (
+	SchemaValidationOff     = "off"
+	SchemaValidationWarn    = "warn"    // Log and count mismatches, deliver anyway
+	SchemaValidationEnforce = "enforce" // Reject mismatching publishes, dead-letter unknown versions
+)
+
+// ErrUnknownSchemaVersion is returned for events published with a schema
+// version this build does not know, usually by a newer publisher
+var ErrUnknownSchemaVersion = errors.New("unknown event schema version")
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// SchemaError lists how a payload differs from its subject's schema
+type SchemaError struct {
+	Subject  string
+	Version  int
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Version == 0 {
+		return fmt.Sprintf("no event schema registered for subject %s", e.Subject)
+	}
+	return fmt.Sprintf("event on %s does not match schema v%d: %s", e.Subject, e.Version, strings.Join(e.Problems, "; "))
+}
+
+// SchemaRegistry holds the versioned JSON schemas of the payloads (Event.Data)
+// of every published subject. The schemas live in the schemas directory; a
+// breaking change to a payload gets a new file with the next x-version.
+type SchemaRegistry struct {
+	schemas  map[string][]*schema // By subject pattern, oldest version first
+	patterns []string             // Most specific first
+}
+
+type schema struct {
+	domain.EventSchema
+	root *jsonSchema
+}
+
+// NewSchemaRegistry loads the schemas built into the binary
+func NewSchemaRegistry() (*SchemaRegistry, error) {
+	files, err := schemaFiles.ReadDir("schemas")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &SchemaRegistry{schemas: make(map[string][]*schema)}
+	for _, file := range files {
+		raw, err := schemaFiles.ReadFile(path.Join("schemas", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		root := &jsonSchema{}
+		if err := json.Unmarshal(raw, root); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", file.Name(), err)
+		}
+		if root.Subject == "" || root.Version < 1 {
+			return nil, fmt.Errorf("schema %s: x-subject and x-version are required", file.Name())
+		}
+
+		if _, ok := r.schemas[root.Subject]; !ok {
+			r.patterns = append(r.patterns, root.Subject)
+		}
+		r.schemas[root.Subject] = append(r.schemas[root.Subject], &schema{
+			EventSchema: domain.EventSchema{Subject: root.Subject, Version: root.Version, Schema: raw},
+			root:        root,
+		})
+	}
+
+	for pattern, versions := range r.schemas {
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+		for i := 1; i < len(versions); i++ {
+			if versions[i].Version == versions[i-1].Version {
+				return nil, fmt.Errorf("schema %s v%d is defined twice", pattern, versions[i].Version)
+			}
+		}
+	}
+	sort.Slice(r.patterns, func(i, j int) bool {
+		return moreSpecific(r.patterns[i], r.patterns[j])
+	})
+
+	return r, nil
+}
+
+// Schemas returns every version of the schemas that apply to subject, or of
+// all schemas when subject is empty
+func (r *SchemaRegistry) Schemas(subject string) []*domain.EventSchema {
+	patterns := r.patterns
+	if subject != "" {
+		patterns = nil
+		if pattern, ok := r.pattern(subject); ok {
+			patterns = []string{pattern}
+		}
+	}
+
+	result := []*domain.EventSchema{}
+	for _, pattern := range patterns {
+		for _, s := range r.schemas[pattern] {
+			result = append(result, &s.EventSchema)
+		}
+	}
+	return result
+}
+
+// Validate checks a payload against the latest schema of its subject and
+// returns that schema's version
+func (r *SchemaRegistry) Validate(subject string, data map[string]interface{}) (int, error) {
+	pattern, ok := r.pattern(subject)
+	if !ok {
+		return 0, &SchemaError{Subject: subject}
+	}
+	versions := r.schemas[pattern]
+	latest := versions[len(versions)-1]
+
+	// Validate the payload as consumers will see it
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return latest.Version, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(encoded, &value); err != nil {
+		return latest.Version, err
+	}
+
+	var problems []string
+	latest.root.validate("data", value, &problems)
+	if len(problems) > 0 {
+		return latest.Version, &SchemaError{Subject: subject, Version: latest.Version, Problems: problems}
+	}
+	return latest.Version, nil
+}
+
+// Check returns ErrUnknownSchemaVersion when a consumed event was published
+// with a schema version the subject does not have. Events published without
+// a version, before the registry existed, pass.
+func (r *SchemaRegistry) Check(subject string, event *domain.Event) error {
+	raw := event.Metadata[MetadataSchemaVersion]
+	if raw == "" {
+		return nil
+	}
+	version, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("%w %q on %s", ErrUnknownSchemaVersion, raw, subject)
+	}
+
+	pattern, ok := r.pattern(subject)
+	if !ok {
+		return fmt.Errorf("%w %d on %s: the subject has no schema", ErrUnknownSchemaVersion, version, subject)
+	}
+	for _, s := range r.schemas[pattern] {
+		if s.Version == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %d on %s", ErrUnknownSchemaVersion, version, subject)
+}
+
+// pattern returns the most specific schema pattern matching a subject
+func (r *SchemaRegistry) pattern(subject string) (string, bool) {
+	for _, pattern := range r.patterns {
+		if matchSubject(pattern, subject) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// moreSpecific orders patterns with fewer wildcards, then more tokens, first
+func moreSpecific(a, b string) bool {
+	wildcards := func(p string) int {
+		n := 0
+		for _, token := range strings.Split(p, ".") {
+			if token == "*" || token == ">" {
+				n++
+			}
+		}
+		return n
+	}
+	if wa, wb := wildcards(a), wildcards(b); wa != wb {
+		return wa < wb
+	}
+	if ta, tb := strings.Count(a, "."), strings.Count(b, "."); ta != tb {
+		return ta > tb
+	}
+	return a < b
+}
+
+// jsonSchema is the subset of JSON Schema the event schemas use
+type jsonSchema struct {
+	Subject              string                 `json:"x-subject"`
+	Version              int                    `json:"x-version"`
+	Type                 schemaTypes            `json:"type"`
+	Format               string                 `json:"format"`
+	Enum                 []interface{}          `json:"enum"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+}
+
+// schemaTypes is a JSON Schema type, a name or a list of names
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*t = schemaTypes{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return err
+	}
+	*t = names
+	return nil
+}
+
+func (t schemaTypes) allow(value interface{}) bool {
+	if len(t) == 0 {
+		return true
+	}
+	for _, name := range t {
+		switch v := value.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case float64:
+			if name == "number" || (name == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validate appends to problems how value, found at path, breaks the schema
+func (s *jsonSchema) validate(at string, value interface{}, problems *[]string) {
+	if !s.Type.allow(value) {
+		*problems = append(*problems, fmt.Sprintf("%s must be %s", at, strings.Join(s.Type, " or ")))
+		return
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, allowed := range s.Enum {
+			if allowed == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			*problems = append(*problems, fmt.Sprintf("%s is not an allowed value", at))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		switch s.Format {
+		case "uuid":
+			if _, err := uuid.Parse(v); err != nil {
+				*problems = append(*problems, fmt.Sprintf("%s must be a UUID", at))
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				*problems = append(*problems, fmt.Sprintf("%s must be an RFC 3339 time", at))
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s.%s is required", at, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				property.validate(at+"."+name, v[name], problems)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*problems = append(*problems, fmt.Sprintf("%s.%s is not allowed", at, name))
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", at, i), item, problems)
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package eventbus
+
+import (
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaRegistryCoversPublishedSubjects(t *testing.T) {
+	registry, err := NewSchemaRegistry()
+	require.NoError(t, err)
+
+	subjects := publishedSubjects(t, "../../cmd", "../../internal")
+	require.NotEmpty(t, subjects)
+	for subject, at := range subjects {
+		assert.NotEmpty(t, registry.Schemas(subject), "%s published at %s has no schema", subject, at)
+	}
+}
+
+// subjectLiteral matches string literals shaped like event subjects
+var subjectLiteral = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)+$`)
+
+// publishedSubjects scans the Go sources under dirs for the subjects events
+// are published on: literals passed to Publish and the publish helpers,
+// literals assigned to eventType, and the Type of events published on
+// event.Type. It returns where each subject is first published.
+func publishedSubjects(t *testing.T, dirs ...string) map[string]string {
+	t.Helper()
+
+	subjects := make(map[string]string)
+	fset := token.NewFileSet()
+	add := func(expr ast.Expr) {
+		lit, ok := expr.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return
+		}
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil || !subjectLiteral.MatchString(value) {
+			return
+		}
+		if _, ok := subjects[value]; !ok {
+			subjects[value] = fset.Position(lit.Pos()).String()
+		}
+	}
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return err
+			}
+			file, err := parser.ParseFile(fset, path, nil, 0)
+			if err != nil {
+				return err
+			}
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok || fn.Body == nil {
+					continue
+				}
+				publishesType := false
+				var types []ast.Expr
+				ast.Inspect(fn.Body, func(n ast.Node) bool {
+					switch n := n.(type) {
+					case *ast.CallExpr:
+						if !strings.Contains(strings.ToLower(calleeName(n)), "publish") {
+							break
+						}
+						for _, arg := range n.Args {
+							add(arg)
+							if sel, ok := arg.(*ast.SelectorExpr); ok && sel.Sel.Name == "Type" {
+								publishesType = true
+							}
+						}
+					case *ast.AssignStmt:
+						for i, lhs := range n.Lhs {
+							if id, ok := lhs.(*ast.Ident); ok && id.Name == "eventType" && i < len(n.Rhs) {
+								add(n.Rhs[i])
+							}
+						}
+					case *ast.KeyValueExpr:
+						if key, ok := n.Key.(*ast.Ident); ok && key.Name == "Type" {
+							types = append(types, n.Value)
+						}
+					}
+					return true
+				})
+				if publishesType {
+					for _, expr := range types {
+						add(expr)
+					}
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	return subjects
+}
+
+func calleeName(call *ast.CallExpr) string {
+	switch fn := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		return fn.Sel.Name
+	case *ast.Ident:
+		return fn.Name
+	}
+	return ""
+}
+
+func TestSchemaRegistryPrefersExactSubjects(t *testing.T) {
+	registry, err := NewSchemaRegistry()
+	require.NoError(t, err)
+
+	schemas := registry.Schemas("build.duration_regressed")
+	require.Len(t, schemas, 1)
+	assert.Equal(t, "build.duration_regressed", schemas[0].Subject)
+
+	schemas = registry.Schemas("build.started")
+	require.Len(t, schemas, 1)
+	assert.Equal(t, "build.*", schemas[0].Subject)
+}
+
+func TestSchemaRegistryValidate(t *testing.T) {
+	registry, err := NewSchemaRegistry()
+	require.NoError(t, err)
+
+	version, err := registry.Validate("service.scaled", map[string]interface{}{
+		"service_id": uuid.New().String(),
+		"replicas":   3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	_, err = registry.Validate("service.scaled", map[string]interface{}{
+		"service_id": "not-a-uuid",
+		"replicas":   1.5,
+	})
+	var schemaErr *SchemaError
+	require.True(t, errors.As(err, &schemaErr))
+	assert.ElementsMatch(t, []string{"data.service_id must be a UUID", "data.replicas must be integer"}, schemaErr.Problems)
+
+	_, err = registry.Validate("project.created", map[string]interface{}{"name": "web"})
+	assert.ErrorContains(t, err, "data.project_id is required")
+
+	version, err = registry.Validate("unknown.subject", map[string]interface{}{})
+	assert.Error(t, err)
+	assert.Equal(t, 0, version)
+}
+
+func TestSchemaRegistryCheck(t *testing.T) {
+	registry, err := NewSchemaRegistry()
+	require.NoError(t, err)
+
+	event := &domain.Event{Metadata: map[string]string{}}
+	assert.NoError(t, registry.Check("deploy.completed", event), "unversioned events pass")
+
+	event.Metadata[MetadataSchemaVersion] = "1"
+	assert.NoError(t, registry.Check("deploy.completed", event))
+
+	event.Metadata[MetadataSchemaVersion] = "2"
+	assert.ErrorIs(t, registry.Check("deploy.completed", event), ErrUnknownSchemaVersion)
+
+	event.Metadata[MetadataSchemaVersion] = "1"
+	assert.ErrorIs(t, registry.Check("unknown.subject", event), ErrUnknownSchemaVersion)
+}
@@ -0,0 +1,398 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/metrics"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// streamsBlock is how long a read waits for events before polling again
+const streamsBlock = 5 * time.Second
+
+// StreamsEventBus implements the EventBus interface on DragonflyDB (or Redis)
+// streams, so that small installs can run without NATS. Events are appended
+// to one stream per subject prefix, e.g. <prefix>:events:build for
+// build.started, and subscribers filter on the full subject, which may use the
+// NATS wildcards * and >.
+//
+// Subscribe delivers to every subscriber from the time it subscribes.
+// QueueSubscribe and DurableSubscribe use consumer groups, so events published
+// while no replica is running are processed once one starts.
+type StreamsEventBus struct {
+	client   redis.UniversalClient
+	prefix   string
+	config   *config.RedisStreamsConfig
+	consumer string // This replica's name in consumer groups
+	logger   *logger.Logger
+	signer   EventSigner
+	ctx      context.Context // Cancelled on Close to stop every reader
+	cancel   context.CancelFunc
+	mu       sync.RWMutex
+	closed   bool
+}
+
+// NewStreamsEventBus creates an event bus on the streams of a DragonflyDB
+// client. keyPrefix prefixes the stream keys.
+func NewStreamsEventBus(client redis.UniversalClient, keyPrefix string, cfg *config.RedisStreamsConfig, log *logger.Logger) *StreamsEventBus {
+	host, _ := os.Hostname()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &StreamsEventBus{
+		client:   client,
+		prefix:   keyPrefix,
+		config:   cfg,
+		consumer: host + "-" + uuid.New().String()[:8],
+		logger:   log,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// streamsSubscription stops a stream reader
+type streamsSubscription struct {
+	cancel context.CancelFunc
+}
+
+func (s *streamsSubscription) Unsubscribe() error {
+	s.cancel()
+	return nil
+}
+
+// Publish appends an event to the stream of its subject
+func (b *StreamsEventBus) Publish(ctx context.Context, subject string, event *domain.Event) error {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return fmt.Errorf("event bus is closed")
+	}
+	signer := b.signer
+	b.mu.RUnlock()
+
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixNano()
+	}
+	event.Subject = subject
+
+	_, span := tracing.StartPublish(ctx, subject, event)
+	data, err := encodeEvent(event, false)
+	if err != nil {
+		tracing.End(span, err)
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	values := map[string]interface{}{
+		"subject": subject,
+		"data":    data,
+	}
+	if signer != nil {
+		if signature, err := signer.SignEvent(ctx, event, data); err != nil {
+			b.logger.Warn().Err(err).Str("subject", subject).Msg("Failed to sign event, publishing unsigned")
+		} else {
+			values["signature"] = signature
+		}
+	}
+
+	err = b.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: b.streamKey(subject),
+		MaxLen: b.config.MaxLen,
+		Approx: true,
+		Values: values,
+	}).Err()
+	metrics.ObservePublish(subject, err)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("failed to publish event: %w", err)
+	}
+
+	b.logger.Debug().
+		Str("subject", subject).
+		Str("event_id", event.ID).
+		Str("event_type", event.Type).
+		Msg("Event published")
+
+	return nil
+}
+
+// UseSigner signs every event published from now on
+func (b *StreamsEventBus) UseSigner(signer EventSigner) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.signer = signer
+}
+
+// Subscribe delivers the events published on subject from now on
+func (b *StreamsEventBus) Subscribe(ctx context.Context, subject string, handler domain.EventHandler) (domain.Subscription, error) {
+	readCtx, cancel, err := b.reader(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	key := b.streamKey(subject)
+	// Stream IDs start with the time in milliseconds
+	last := fmt.Sprintf("%d-0", time.Now().UnixMilli())
+	go func() {
+		for readCtx.Err() == nil {
+			streams, err := b.client.XRead(readCtx, &redis.XReadArgs{
+				Streams: []string{key, last},
+				Count:   100,
+				Block:   streamsBlock,
+			}).Result()
+			if !b.readOK(readCtx, err, subject) {
+				continue
+			}
+			for _, stream := range streams {
+				for _, msg := range stream.Messages {
+					last = msg.ID
+					if _, err := b.handle(msg, subject, handler); err != nil {
+						b.logger.Error().Err(err).Str("subject", subject).Msg("Event handler error")
+					}
+				}
+			}
+		}
+	}()
+
+	b.logger.Debug().Str("subject", subject).Msg("Subscribed to subject")
+	return &streamsSubscription{cancel: cancel}, nil
+}
+
+// QueueSubscribe delivers each event on subject to one of the subscribers
+// sharing the queue name. Like a NATS queue subscription it delivers at most
+// once: a handler error is logged and the event acknowledged.
+func (b *StreamsEventBus) QueueSubscribe(ctx context.Context, subject string, queue string, handler domain.EventHandler) (domain.Subscription, error) {
+	// Subscriptions of one queue to several subjects of a stream each need
+	// their own group, or they would acknowledge each other's events
+	return b.groupSubscribe(ctx, subject, queue+":"+subject, handler, false)
+}
+
+// DurableSubscribe delivers the events on subject at least once to the
+// consumer group named durable. An event whose handler fails is redelivered
+// after AckWait, up to MaxDeliver attempts.
+func (b *StreamsEventBus) DurableSubscribe(ctx context.Context, subject, durable string, handler domain.EventHandler) (domain.Subscription, error) {
+	return b.groupSubscribe(ctx, subject, durable, handler, true)
+}
+
+func (b *StreamsEventBus) groupSubscribe(ctx context.Context, subject, group string, handler domain.EventHandler, durable bool) (domain.Subscription, error) {
+	readCtx, cancel, err := b.reader(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	key := b.streamKey(subject)
+	// A new group starts with the events published after it is created
+	err = b.client.XGroupCreateMkStream(ctx, key, group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		cancel()
+		return nil, fmt.Errorf("failed to create consumer group %s: %w", group, err)
+	}
+
+	go func() {
+		for readCtx.Err() == nil {
+			if durable {
+				b.redeliver(readCtx, key, subject, group, handler)
+			}
+
+			streams, err := b.client.XReadGroup(readCtx, &redis.XReadGroupArgs{
+				Group:    group,
+				Consumer: b.consumer,
+				Streams:  []string{key, ">"},
+				Count:    10,
+				Block:    streamsBlock,
+			}).Result()
+			if !b.readOK(readCtx, err, subject) {
+				continue
+			}
+			for _, stream := range streams {
+				for _, msg := range stream.Messages {
+					b.handleGroup(readCtx, key, group, msg, subject, handler, durable)
+				}
+			}
+		}
+	}()
+
+	b.logger.Info().Str("subject", subject).Str("group", group).Msg("Consumer group subscribed")
+	return &streamsSubscription{cancel: cancel}, nil
+}
+
+// redeliver claims the group's events that were not acknowledged within
+// AckWait, by any replica, and runs the handler for them again
+func (b *StreamsEventBus) redeliver(ctx context.Context, key, subject, group string, handler domain.EventHandler) {
+	msgs, _, err := b.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   key,
+		Group:    group,
+		Consumer: b.consumer,
+		MinIdle:  b.config.AckWait,
+		Start:    "0-0",
+		Count:    10,
+	}).Result()
+	if err != nil {
+		if ctx.Err() == nil {
+			b.logger.Warn().Err(err).Str("subject", subject).Msg("Failed to claim pending events")
+		}
+		return
+	}
+
+	for _, msg := range msgs {
+		pending, err := b.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: key,
+			Group:  group,
+			Start:  msg.ID,
+			End:    msg.ID,
+			Count:  1,
+		}).Result()
+		if err == nil && len(pending) == 1 && b.config.MaxDeliver > 0 && pending[0].RetryCount > int64(b.config.MaxDeliver) {
+			b.logger.Error().
+				Str("subject", subject).
+				Str("group", group).
+				Str("stream_id", msg.ID).
+				Int64("attempts", pending[0].RetryCount-1).
+				Msg("Event handler failed on every attempt, giving up")
+			b.client.XAck(ctx, key, group, msg.ID)
+			continue
+		}
+		b.handleGroup(ctx, key, group, msg, subject, handler, true)
+	}
+}
+
+// handleGroup runs the handler for an event read through a consumer group and
+// acknowledges it, unless a durable handler failed
+func (b *StreamsEventBus) handleGroup(ctx context.Context, key, group string, msg redis.XMessage, subject string, handler domain.EventHandler, durable bool) {
+	_, err := b.handle(msg, subject, handler)
+	if err != nil {
+		if durable && !errors.Is(err, errUndecodable) {
+			b.logger.Warn().Err(err).Str("subject", subject).Str("stream_id", msg.ID).Dur("retry_in", b.config.AckWait).Msg("Event handler error, retrying")
+			return
+		}
+		b.logger.Error().Err(err).Str("subject", subject).Msg("Event handler error")
+	}
+	if err := b.client.XAck(ctx, key, group, msg.ID).Err(); err != nil {
+		b.logger.Warn().Err(err).Str("stream_id", msg.ID).Msg("Failed to acknowledge event")
+	}
+}
+
+// errUndecodable marks events that redelivering cannot help
+var errUndecodable = errors.New("failed to unmarshal event")
+
+// handle runs the handler for a stream entry if its subject matches pattern
+func (b *StreamsEventBus) handle(msg redis.XMessage, pattern string, handler domain.EventHandler) (bool, error) {
+	subject, _ := msg.Values["subject"].(string)
+	if !matchSubject(pattern, subject) {
+		return false, nil
+	}
+
+	data, _ := msg.Values["data"].(string)
+	event, err := decodeEvent([]byte(data))
+	if err != nil {
+		metrics.ObserveConsume(pattern, err)
+		return true, fmt.Errorf("%w: %v", errUndecodable, err)
+	}
+
+	_, span := tracing.StartConsume(subject, event)
+	err = handler(event)
+	metrics.ObserveConsume(pattern, err)
+	tracing.End(span, err)
+	return true, err
+}
+
+// reader checks that the bus is open and the subject can be read, and returns
+// the context of a new reader
+func (b *StreamsEventBus) reader(ctx context.Context, subject string) (context.Context, context.CancelFunc, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return nil, nil, fmt.Errorf("event bus is closed")
+	}
+	if prefix := strings.SplitN(subject, ".", 2)[0]; prefix == "*" || prefix == ">" {
+		return nil, nil, fmt.Errorf("subject %s: the first token cannot be a wildcard", subject)
+	}
+
+	readCtx, cancel := context.WithCancel(b.ctx)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-readCtx.Done():
+		}
+	}()
+	return readCtx, cancel, nil
+}
+
+// readOK reports whether a read returned events, backing off after errors
+func (b *StreamsEventBus) readOK(ctx context.Context, err error, subject string) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, redis.Nil) || ctx.Err() != nil {
+		return false
+	}
+	b.logger.Warn().Err(err).Str("subject", subject).Msg("Failed to read events")
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+	}
+	return false
+}
+
+// Request is not supported; streams have no reply subjects
+func (b *StreamsEventBus) Request(ctx context.Context, subject string, event *domain.Event) (*domain.Event, error) {
+	return nil, fmt.Errorf("request/reply is not supported by the streams event bus")
+}
+
+// Health reports whether DragonflyDB is reachable
+func (b *StreamsEventBus) Health(ctx context.Context) error {
+	return b.client.Ping(ctx).Err()
+}
+
+// Close stops every subscription. The client belongs to the cache and stays open.
+func (b *StreamsEventBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.cancel()
+	b.logger.Info().Msg("Streams event bus closed")
+	return nil
+}
+
+// streamKey returns the stream holding the events of a subject
+func (b *StreamsEventBus) streamKey(subject string) string {
+	return b.prefix + ":events:" + strings.SplitN(subject, ".", 2)[0]
+}
+
+// matchSubject reports whether a subject matches a NATS-style pattern, where
+// * matches one token and a trailing > one or more
+func matchSubject(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" {
+			return i == len(patternTokens)-1 && len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) {
+			return false
+		}
+		if token != "*" && token != subjectTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
@@ -0,0 +1,36 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		want    bool
+	}{
+		{"build.started", "build.started", true},
+		{"build.started", "build.failed", false},
+		{"build.*", "build.started", true},
+		{"build.*", "build.started.extra", false},
+		{"build.>", "build.started", true},
+		{"build.>", "build.started.extra", true},
+		{"build.>", "build", false},
+		{"tenant.*.deploy", "tenant.acme.deploy", true},
+		{"tenant.*.deploy", "tenant.acme.build", false},
+		{"build", "build.started", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, matchSubject(tt.pattern, tt.subject), "%s ~ %s", tt.pattern, tt.subject)
+	}
+}
+
+func TestStreamKey(t *testing.T) {
+	b := &StreamsEventBus{prefix: "northstack"}
+	assert.Equal(t, "northstack:events:build", b.streamKey("build.started"))
+	assert.Equal(t, "northstack:events:deploy", b.streamKey("deploy.>"))
+}
@@ -0,0 +1,147 @@
+package eventbus
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/northstack/platform/internal/domain"
+)
+
+// TenantResolver returns the tenant owning a project
+type TenantResolver interface {
+	ProjectTenant(ctx context.Context, projectID uuid.UUID) (uuid.UUID, error)
+}
+
+// tenantCacheTTL bounds how long a resolved tenant is trusted, should the
+// event that evicts it be missed
+const tenantCacheTTL = time.Minute
+
+// ProjectTenants resolves the tenant of a project from the project
+// repository: its team, or its owner when it belongs to no team. Answers are
+// cached until the project is updated or deleted, when Watch evicts them.
+type ProjectTenants struct {
+	projects domain.ProjectRepository
+	cache    sync.Map // Project ID to cachedTenant
+}
+
+type cachedTenant struct {
+	tenant  uuid.UUID
+	fetched time.Time
+}
+
+// NewProjectTenants creates a new ProjectTenants
+func NewProjectTenants(projects domain.ProjectRepository) *ProjectTenants {
+	return &ProjectTenants{projects: projects}
+}
+
+// ProjectTenant returns the tenant owning a project
+func (t *ProjectTenants) ProjectTenant(ctx context.Context, projectID uuid.UUID) (uuid.UUID, error) {
+	if cached, ok := t.cache.Load(projectID); ok && time.Since(cached.(cachedTenant).fetched) < tenantCacheTTL {
+		return cached.(cachedTenant).tenant, nil
+	}
+
+	project, err := t.projects.GetByID(ctx, projectID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	tenant := ProjectTenant(project)
+	t.cache.Store(projectID, cachedTenant{tenant: tenant, fetched: time.Now()})
+	return tenant, nil
+}
+
+// Forget evicts the cached tenant of a project
+func (t *ProjectTenants) Forget(projectID uuid.UUID) {
+	t.cache.Delete(projectID)
+}
+
+// Watch evicts the tenant of a project when it is updated, as moving it to
+// another team changes its tenant, or deleted. Every replica keeps its own
+// cache, so this is a plain subscription rather than a queue group.
+func (t *ProjectTenants) Watch(ctx context.Context, bus domain.EventBus) error {
+	return WatchProjectChanges(ctx, bus, t.Forget)
+}
+
+// WatchProjectChanges calls forget with the ID of every project updated or
+// deleted from now on
+func WatchProjectChanges(ctx context.Context, bus domain.EventBus, forget func(projectID uuid.UUID)) error {
+	for _, subject := range []string{SubjectProjectUpdated, SubjectProjectDeleted} {
+		if _, err := bus.Subscribe(ctx, subject, func(event *domain.Event) error {
+			raw, _ := event.Data["project_id"].(string)
+			if projectID, err := uuid.Parse(raw); err == nil {
+				forget(projectID)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProjectTenant returns the tenant of a project: its team, or its owner when
+// it belongs to no team
+func ProjectTenant(project *domain.Project) uuid.UUID {
+	if project.TeamID != nil {
+		return *project.TeamID
+	}
+	return project.OwnerID
+}
+
+// TenantSubject returns the subject an event is mirrored to for its tenant,
+// <prefix>.<tenant>.<project>.<subject>
+func TenantSubject(prefix string, tenant, project uuid.UUID, subject string) string {
+	return strings.Join([]string{prefix, tenant.String(), project.String(), subject}, ".")
+}
+
+// TenantSubjects returns the subjects a tenant may subscribe to: all of its
+// events, or those of one project when project is not nil
+func TenantSubjects(prefix string, tenant uuid.UUID, project *uuid.UUID) []string {
+	if project != nil {
+		return []string{TenantSubject(prefix, tenant, *project, ">")}
+	}
+	return []string{prefix + "." + tenant.String() + ".>"}
+}
+
+// tenantMirror copies published events to their tenant's subjects
+type tenantMirror struct {
+	prefix   string
+	resolver TenantResolver
+}
+
+// UseTenants mirrors every event about a project published from now on to
+// the subject of the project's tenant, so that tenants can subscribe to their
+// own events with credentials scoped to their prefix. The canonical subjects
+// are unchanged.
+func (b *NATSEventBus) UseTenants(prefix string, resolver TenantResolver) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tenants = &tenantMirror{prefix: prefix, resolver: resolver}
+}
+
+// mirror publishes a copy of a published message on its tenant's subject.
+// Events that are not about a project are platform-wide and not mirrored.
+func (b *NATSEventBus) mirror(ctx context.Context, tenants *tenantMirror, msg *nats.Msg, event *domain.Event) {
+	raw, _ := event.Data["project_id"].(string)
+	projectID, err := uuid.Parse(raw)
+	if err != nil {
+		return
+	}
+
+	tenant, err := tenants.resolver.ProjectTenant(ctx, projectID)
+	if err != nil {
+		b.logger.Warn().Err(err).Str("project_id", raw).Str("subject", msg.Subject).Msg("Failed to resolve event tenant, not mirroring")
+		return
+	}
+
+	// Tenant subjects are a live feed; the canonical subject is the durable copy
+	copied := nats.NewMsg(TenantSubject(tenants.prefix, tenant, projectID, msg.Subject))
+	copied.Data = msg.Data
+	copied.Header = msg.Header
+	if err := b.conn.PublishMsg(copied); err != nil {
+		b.logger.Warn().Err(err).Str("subject", copied.Subject).Msg("Failed to mirror event to tenant")
+	}
+}
@@ -0,0 +1,80 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectTenant(t *testing.T) {
+	owner, team := uuid.New(), uuid.New()
+
+	assert.Equal(t, owner, ProjectTenant(&domain.Project{OwnerID: owner}))
+	assert.Equal(t, team, ProjectTenant(&domain.Project{OwnerID: owner, TeamID: &team}))
+}
+
+func TestTenantSubjects(t *testing.T) {
+	tenant, project := uuid.New(), uuid.New()
+	subject := TenantSubject("tenant", tenant, project, SubjectDeployCompleted)
+
+	assert.Equal(t, "tenant."+tenant.String()+"."+project.String()+".deploy.completed", subject)
+	for _, allowed := range [][]string{
+		TenantSubjects("tenant", tenant, nil),
+		TenantSubjects("tenant", tenant, &project),
+	} {
+		assert.Len(t, allowed, 1)
+		assert.True(t, matchSubject(allowed[0], subject))
+	}
+
+	other := TenantSubject("tenant", uuid.New(), project, SubjectDeployCompleted)
+	assert.False(t, matchSubject(TenantSubjects("tenant", tenant, nil)[0], other))
+	assert.False(t, matchSubject(TenantSubjects("tenant", tenant, &project)[0], TenantSubject("tenant", tenant, uuid.New(), SubjectDeployCompleted)))
+}
+
+type movableProjects struct {
+	domain.ProjectRepository
+	project *domain.Project
+}
+
+func (f *movableProjects) GetByID(context.Context, uuid.UUID) (*domain.Project, error) {
+	moved := *f.project
+	return &moved, nil
+}
+
+// handlerBus records the handlers subscribed to each subject
+type handlerBus struct {
+	domain.EventBus
+	handlers map[string]domain.EventHandler
+}
+
+func (b *handlerBus) Subscribe(_ context.Context, subject string, handler domain.EventHandler) (domain.Subscription, error) {
+	b.handlers[subject] = handler
+	return nil, nil
+}
+
+func TestProjectTenantsForgetMovedProjects(t *testing.T) {
+	oldTeam, newTeam := uuid.New(), uuid.New()
+	project := &domain.Project{ID: uuid.New(), OwnerID: uuid.New(), TeamID: &oldTeam}
+	repo := &movableProjects{project: project}
+	bus := &handlerBus{handlers: make(map[string]domain.EventHandler)}
+
+	tenants := NewProjectTenants(repo)
+	require.NoError(t, tenants.Watch(context.Background(), bus))
+
+	tenant, err := tenants.ProjectTenant(context.Background(), project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, oldTeam, tenant)
+
+	// Moving the project to another team publishes project.updated
+	project.TeamID = &newTeam
+	require.Contains(t, bus.handlers, SubjectProjectUpdated)
+	require.NoError(t, bus.handlers[SubjectProjectUpdated](&domain.Event{Data: map[string]interface{}{"project_id": project.ID.String()}}))
+
+	tenant, err = tenants.ProjectTenant(context.Background(), project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, newTeam, tenant)
+}
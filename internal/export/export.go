@@ -0,0 +1,331 @@
+// Package export ejects a project to plain Kubernetes configuration: a Helm
+// chart or a Kustomize directory holding its services, ingresses and
+// configuration, which deploy without the platform, e.g. from a GitOps
+// repository.
+package export
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/manifest"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"sigs.k8s.io/yaml"
+)
+
+// Format is the layout of an export
+type Format string
+
+const (
+	FormatHelm      Format = "helm"
+	FormatKustomize Format = "kustomize"
+)
+
+// chartVersion is the version of exported charts
+const chartVersion = "0.1.0"
+
+// helmValuePattern matches the stand-ins of Helm template expressions in
+// marshalled YAML
+var helmValuePattern = regexp.MustCompile(`__helm_value_(\d+)__`)
+
+// Bundle is an exported project
+type Bundle struct {
+	Format      Format                 `json:"format"`
+	Name        string                 `json:"name"` // Directory the files are in
+	Files       []File                 `json:"files"`
+	Unsupported []manifest.Unsupported `json:"unsupported"` // Settings that were not exported
+}
+
+// File is a file of a bundle, by its path in the bundle's directory
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Exporter exports projects
+type Exporter struct {
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	ingressRepo domain.IngressRepository
+	secretRepo  domain.SecretRepository
+	logger      *logger.Logger
+}
+
+// NewExporter creates a new Exporter. Without an ingress repository no
+// Ingresses are exported; without a secret repository the README names the
+// Secrets to create without their keys.
+func NewExporter(projectRepo domain.ProjectRepository, serviceRepo domain.ServiceRepository, ingressRepo domain.IngressRepository, secretRepo domain.SecretRepository, log *logger.Logger) *Exporter {
+	return &Exporter{
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		ingressRepo: ingressRepo,
+		secretRepo:  secretRepo,
+		logger:      log,
+	}
+}
+
+// Export renders a project in the given format. Secret values stay in Vault:
+// services reference Secrets of the same names, which the README lists.
+func (e *Exporter) Export(ctx context.Context, projectID uuid.UUID, format Format) (*Bundle, error) {
+	if format != FormatHelm && format != FormatKustomize {
+		return nil, errors.BadRequest(fmt.Sprintf("format must be %s or %s", FormatHelm, FormatKustomize))
+	}
+
+	project, err := e.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	services, err := e.serviceRepo.ListByProject(ctx, projectID, domain.ServiceFilter{})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Slug < services[j].Slug })
+
+	ingresses := map[uuid.UUID][]*domain.Ingress{}
+	if e.ingressRepo != nil {
+		list, err := e.ingressRepo.ListByProject(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ing := range list {
+			ingresses[ing.ServiceID] = append(ingresses[ing.ServiceID], ing)
+		}
+	}
+
+	secrets := map[string]*domain.Secret{}
+	if e.secretRepo != nil {
+		list, err := e.secretRepo.ListByProject(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		for _, secret := range list {
+			secrets[secret.Name] = secret
+		}
+	}
+
+	bundle := &Bundle{Format: format, Name: project.Slug, Unsupported: []manifest.Unsupported{}}
+	var all []*rendered
+	for _, service := range services {
+		r, unsupported := render(project, service, ingresses[service.ID])
+		all = append(all, r)
+		bundle.Unsupported = append(bundle.Unsupported, unsupported...)
+	}
+
+	if format == FormatHelm {
+		err = bundle.helm(project, all)
+	} else {
+		err = bundle.kustomize(all)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render the export")
+	}
+	bundle.add("README.md", readme(project, bundle, all, secrets))
+	sort.Slice(bundle.Files, func(i, j int) bool { return bundle.Files[i].Path < bundle.Files[j].Path })
+
+	e.logger.Info().Str("project_id", projectID.String()).Str("format", string(format)).Int("services", len(services)).Msg("Project exported")
+	return bundle, nil
+}
+
+// helm lays the objects out as a chart. Each service's image and replicas are
+// values; everything else is written out as it is.
+func (b *Bundle) helm(project *domain.Project, services []*rendered) error {
+	chart, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion":  "v2",
+		"name":        project.Slug,
+		"description": firstNonEmpty(project.Description, project.Name),
+		"type":        "application",
+		"version":     chartVersion,
+	})
+	if err != nil {
+		return err
+	}
+	b.add("Chart.yaml", string(chart))
+
+	values := map[string]interface{}{}
+	for _, r := range services {
+		slug := r.service.Slug
+		value := func(key string) string {
+			return fmt.Sprintf(`(index .Values.services %q).%s`, slug, key)
+		}
+		var expressions []string
+		param := func(expression string) string {
+			expressions = append(expressions, expression)
+			return fmt.Sprintf("__helm_value_%d__", len(expressions)-1)
+		}
+
+		settings := map[string]interface{}{}
+		pod := podSpec(r.workload)
+		container := pod["containers"].([]interface{})[0].(object)
+		settings["image"] = container["image"]
+		container["image"] = param("{{ " + value("image") + " | quote }}")
+		if spec := r.workload["spec"].(object); spec["replicas"] != nil {
+			settings["replicas"] = spec["replicas"]
+			spec["replicas"] = param("{{ " + value("replicas") + " }}")
+		}
+		values[slug] = settings
+
+		doc, err := marshal(r.objects)
+		if err != nil {
+			return err
+		}
+		doc = helmValuePattern.ReplaceAllStringFunc(doc, func(m string) string {
+			i, _ := strconv.Atoi(helmValuePattern.FindStringSubmatch(m)[1])
+			return expressions[i]
+		})
+		b.add("templates/"+slug+".yaml", doc)
+	}
+
+	doc, err := yaml.Marshal(map[string]interface{}{"services": values})
+	if err != nil {
+		return err
+	}
+	b.add("values.yaml", "# Image and replicas of each service\n"+string(doc))
+	return nil
+}
+
+// kustomize lays the objects out as a Kustomize directory, one file per
+// service
+func (b *Bundle) kustomize(services []*rendered) error {
+	resources := []interface{}{}
+	for _, r := range services {
+		doc, err := marshal(r.objects)
+		if err != nil {
+			return err
+		}
+		b.add(r.service.Slug+".yaml", doc)
+		resources = append(resources, r.service.Slug+".yaml")
+	}
+
+	doc, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"resources":  resources,
+	})
+	if err != nil {
+		return err
+	}
+	b.add("kustomization.yaml", string(doc))
+	return nil
+}
+
+// WriteArchive writes the bundle as a gzipped tarball of its directory
+func (b *Bundle) WriteArchive(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, f := range b.Files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    b.Name + "/" + f.Path,
+			Mode:    0o644,
+			Size:    int64(len(f.Content)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(tw, f.Content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func (b *Bundle) add(path, content string) {
+	b.Files = append(b.Files, File{Path: path, Content: content})
+}
+
+// readme explains how to deploy a bundle and what it leaves out
+func readme(project *domain.Project, b *Bundle, services []*rendered, secrets map[string]*domain.Secret) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", project.Name)
+	fmt.Fprintf(&sb, "Plain Kubernetes configuration of the %s project, exported on %s.\n\n", project.Slug, time.Now().UTC().Format("2006-01-02"))
+	if b.Format == FormatHelm {
+		fmt.Fprintf(&sb, "Deploy it with:\n\n    helm install %s ./%s --namespace <namespace>\n\n", project.Slug, b.Name)
+		sb.WriteString("Each service's image and replicas are set in values.yaml.\n")
+	} else {
+		fmt.Fprintf(&sb, "Deploy it with:\n\n    kubectl apply -k %s --namespace <namespace>\n\n", b.Name)
+		sb.WriteString("Add a namespace, images or patches to kustomization.yaml, or use this directory as a base.\n")
+	}
+
+	var refs []string
+	seen := map[string]bool{}
+	for _, r := range services {
+		for _, ref := range r.service.SecretRefs {
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	sort.Strings(refs)
+	if len(refs) > 0 {
+		sb.WriteString("\n## Secrets\n\nSecret values are not exported. Create these Secrets in the namespace first:\n\n")
+		for _, ref := range refs {
+			if secret, ok := secrets[ref]; ok && len(secret.Keys) > 0 {
+				fmt.Fprintf(&sb, "- `%s`, with the keys %s\n", ref, "`"+strings.Join(secret.Keys, "`, `")+"`")
+			} else {
+				fmt.Fprintf(&sb, "- `%s`\n", ref)
+			}
+		}
+	}
+
+	if len(b.Unsupported) > 0 {
+		sb.WriteString("\n## Not exported\n\n")
+		for _, u := range b.Unsupported {
+			key := u.Key
+			if u.Service != "" {
+				key = u.Service + ": " + key
+			}
+			fmt.Fprintf(&sb, "- %s: %s\n", key, u.Reason)
+		}
+	}
+	return sb.String()
+}
+
+// podSpec returns the pod spec of a workload
+func podSpec(workload object) object {
+	spec := workload["spec"].(object)
+	if workload["kind"] == "CronJob" {
+		spec = spec["jobTemplate"].(object)["spec"].(object)
+	}
+	return spec["template"].(object)["spec"].(object)
+}
+
+// marshal renders objects as a multi-document YAML stream
+func marshal(objects []object) (string, error) {
+	var buf bytes.Buffer
+	for i, obj := range objects {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		doc, err := yaml.Marshal(obj)
+		if err != nil {
+			return "", err
+		}
+		buf.Write(doc)
+	}
+	return buf.String(), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+package export
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func testService() (*domain.Project, *domain.Service, []*domain.Ingress) {
+	project := &domain.Project{ID: uuid.New(), Name: "Shop", Slug: "shop"}
+	service := &domain.Service{
+		ID:             uuid.New(),
+		ProjectID:      project.ID,
+		Name:           "Web",
+		Slug:           "web",
+		Type:           domain.ServiceTypeWebApp,
+		BuildSource:    domain.BuildSource{Type: "docker", Image: "ghcr.io/acme/web"},
+		CurrentVersion: "1.4.0",
+		Resources:      domain.ResourceLimits{CPURequest: "250m", MemoryLimit: "512Mi"},
+		Scaling:        domain.ScalingConfig{MinReplicas: 2, MaxReplicas: 5, TargetCPU: 70},
+		HealthCheck:    &domain.HealthCheck{Type: "http", Path: "/healthz", Port: 8080, PeriodSeconds: 10},
+		EnvVars:        map[string]string{"LOG_LEVEL": "info"},
+		SecretRefs:     []string{"web-secrets"},
+		Ports:          []domain.ServicePort{{Name: "http", Port: 80, TargetPort: 8080, Protocol: "TCP", Public: true}},
+	}
+	ingresses := []*domain.Ingress{
+		{ServiceID: service.ID, Domain: "shop.example.com", Type: domain.IngressTypeHTTP, TLS: domain.TLSConfig{Enabled: true, AutoTLS: true}},
+		{ServiceID: service.ID, Domain: "tcp.example.com", Type: domain.IngressTypeTCP},
+	}
+	return project, service, ingresses
+}
+
+func kinds(objects []object) []string {
+	var out []string
+	for _, obj := range objects {
+		out = append(out, obj["kind"].(string))
+	}
+	return out
+}
+
+func TestRender(t *testing.T) {
+	project, service, ingresses := testService()
+	r, unsupported := render(project, service, ingresses)
+
+	assert.Equal(t, []string{"ConfigMap", "Deployment", "Service", "HorizontalPodAutoscaler", "Ingress"}, kinds(r.objects))
+	require.Len(t, unsupported, 1)
+	assert.Equal(t, "ingresses.tcp.example.com", unsupported[0].Key)
+
+	container := podSpec(r.workload)["containers"].([]interface{})[0].(object)
+	assert.Equal(t, "ghcr.io/acme/web:1.4.0", container["image"])
+	assert.Equal(t, []interface{}{
+		object{"configMapRef": object{"name": "web-env"}},
+		object{"secretRef": object{"name": "web-secrets"}},
+	}, container["envFrom"])
+	assert.Equal(t, object{"httpGet": object{"path": "/healthz", "port": int32(8080)}, "periodSeconds": int32(10)}, container["livenessProbe"])
+
+	ing := r.objects[4]
+	assert.Equal(t, "web-shop-example-com", ing["metadata"].(object)["name"])
+	assert.Equal(t, "letsencrypt", ing["metadata"].(object)["annotations"].(map[string]interface{})["cert-manager.io/cluster-issuer"])
+}
+
+func TestRenderAvailability(t *testing.T) {
+	project, service, _ := testService()
+	service.Availability = &domain.ServiceAvailability{
+		MinAvailable: "1",
+		Spread:       []domain.TopologySpread{{Topology: domain.TopologyZone}},
+	}
+	r, _ := render(project, service, nil)
+
+	assert.Equal(t, []string{"ConfigMap", "Deployment", "Service", "HorizontalPodAutoscaler", "PodDisruptionBudget"}, kinds(r.objects))
+	pdb := r.objects[4]["spec"].(object)
+	assert.Equal(t, int64(1), pdb["minAvailable"])
+	assert.Equal(t, object{"matchLabels": map[string]interface{}{labelName: "web"}}, pdb["selector"])
+
+	constraints := podSpec(r.workload)["topologySpreadConstraints"].([]interface{})
+	require.Len(t, constraints, 1)
+	assert.Equal(t, "topology.kubernetes.io/zone", constraints[0].(object)["topologyKey"])
+	assert.Equal(t, object{"matchLabels": map[string]interface{}{labelName: "web"}}, constraints[0].(object)["labelSelector"])
+}
+
+func TestRenderVolumes(t *testing.T) {
+	project, service, _ := testService()
+	service.Volumes = []domain.Volume{{Name: "uploads", Size: "5Gi", StorageClass: "fast", MountPath: "/srv/uploads", AccessMode: domain.VolumeReadWriteMany}}
+	r, _ := render(project, service, nil)
+
+	assert.Contains(t, kinds(r.objects), "PersistentVolumeClaim")
+	var claim object
+	for _, obj := range r.objects {
+		if obj["kind"] == "PersistentVolumeClaim" {
+			claim = obj
+		}
+	}
+	assert.Equal(t, "web-uploads", claim["metadata"].(object)["name"])
+	assert.Equal(t, "fast", claim["spec"].(object)["storageClassName"])
+	assert.Equal(t, []interface{}{"ReadWriteMany"}, claim["spec"].(object)["accessModes"])
+
+	spec := podSpec(r.workload)
+	assert.Equal(t, []interface{}{object{"name": "uploads", "persistentVolumeClaim": object{"claimName": "web-uploads"}}}, spec["volumes"])
+	container := spec["containers"].([]interface{})[0].(object)
+	assert.Equal(t, []interface{}{object{"name": "uploads", "mountPath": "/srv/uploads"}}, container["volumeMounts"])
+
+	project = &domain.Project{Slug: "shop"}
+	db, unsupported := render(project, &domain.Service{
+		Slug:        "postgres",
+		Type:        domain.ServiceTypeStatefulDB,
+		BuildSource: domain.BuildSource{Image: "postgres:16"},
+		Volumes:     []domain.Volume{{Name: "pgdata", Size: "20Gi", MountPath: "/var/lib/postgresql/data"}},
+	}, nil)
+	assert.Empty(t, unsupported)
+	templates := db.workload["spec"].(object)["volumeClaimTemplates"].([]interface{})
+	require.Len(t, templates, 1)
+	assert.Equal(t, object{"name": "pgdata"}, templates[0].(object)["metadata"])
+	assert.Equal(t, object{"requests": object{"storage": "20Gi"}}, templates[0].(object)["spec"].(object)["resources"])
+	container = podSpec(db.workload)["containers"].([]interface{})[0].(object)
+	assert.Equal(t, []interface{}{object{"name": "pgdata", "mountPath": "/var/lib/postgresql/data"}}, container["volumeMounts"])
+}
+
+func TestRenderStatefulAndCron(t *testing.T) {
+	project := &domain.Project{Slug: "shop"}
+	db, unsupported := render(project, &domain.Service{
+		Slug:        "postgres",
+		Type:        domain.ServiceTypeStatefulDB,
+		BuildSource: domain.BuildSource{Image: "postgres:16"},
+		Resources:   domain.ResourceLimits{StorageSize: "10Gi"},
+	}, nil)
+	assert.Equal(t, "StatefulSet", db.workload["kind"])
+	assert.Equal(t, "postgres:16", podSpec(db.workload)["containers"].([]interface{})[0].(object)["image"])
+	assert.Len(t, unsupported, 1)
+
+	cron, unsupported := render(project, &domain.Service{
+		Slug:        "report",
+		Type:        domain.ServiceTypeCronJob,
+		BuildSource: domain.BuildSource{Type: "buildpack", Repository: "https://github.com/acme/shop", Registry: "registry.example.com/shop"},
+		Metadata:    map[string]interface{}{"schedule": "*/5 * * * *"},
+	}, nil)
+	assert.Equal(t, "CronJob", cron.workload["kind"])
+	assert.Equal(t, "*/5 * * * *", cron.workload["spec"].(object)["schedule"])
+	assert.Equal(t, "registry.example.com/shop/report", podSpec(cron.workload)["containers"].([]interface{})[0].(object)["image"])
+	require.Len(t, unsupported, 1)
+	assert.Equal(t, "build_source", unsupported[0].Key)
+}
+
+func TestHelm(t *testing.T) {
+	project, service, ingresses := testService()
+	r, _ := render(project, service, ingresses)
+	b := &Bundle{Format: FormatHelm, Name: "shop"}
+	require.NoError(t, b.helm(project, []*rendered{r}))
+
+	files := map[string]string{}
+	for _, f := range b.Files {
+		files[f.Path] = f.Content
+	}
+	require.Contains(t, files, "Chart.yaml")
+	assert.Contains(t, files["templates/web.yaml"], `image: {{ (index .Values.services "web").image | quote }}`)
+	assert.Contains(t, files["templates/web.yaml"], `replicas: {{ (index .Values.services "web").replicas }}`)
+	assert.NotContains(t, files["templates/web.yaml"], "__helm_value")
+
+	var values struct {
+		Services map[string]struct {
+			Image    string `json:"image"`
+			Replicas int    `json:"replicas"`
+		} `json:"services"`
+	}
+	require.NoError(t, yaml.Unmarshal([]byte(files["values.yaml"]), &values))
+	assert.Equal(t, "ghcr.io/acme/web:1.4.0", values.Services["web"].Image)
+	assert.Equal(t, 2, values.Services["web"].Replicas)
+}
+
+func TestKustomizeArchive(t *testing.T) {
+	project, service, ingresses := testService()
+	r, _ := render(project, service, ingresses)
+	b := &Bundle{Format: FormatKustomize, Name: "shop"}
+	require.NoError(t, b.kustomize([]*rendered{r}))
+
+	var buf bytes.Buffer
+	require.NoError(t, b.WriteArchive(&buf))
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	contents := map[string]string{}
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, _ := io.ReadAll(tr)
+		contents[h.Name] = string(data)
+	}
+
+	assert.Contains(t, contents["shop/kustomization.yaml"], "- web.yaml")
+	assert.Equal(t, 4, strings.Count(contents["shop/web.yaml"], "\n---\n"))
+}
@@ -0,0 +1,448 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/northstack/platform/internal/availability"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/manifest"
+	"github.com/northstack/platform/internal/volumes"
+)
+
+// Kubernetes labels every exported object carries
+const (
+	labelName   = "app.kubernetes.io/name"
+	labelPartOf = "app.kubernetes.io/part-of"
+)
+
+// defaultSchedule is the schedule of cron jobs that do not record one
+const defaultSchedule = "0 0 * * *"
+
+// dataMountPath is where a stateful service's volume is mounted
+const dataMountPath = "/data"
+
+var nonNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// object is a Kubernetes object, as it is written out
+type object = map[string]interface{}
+
+// rendered are the objects of one service
+type rendered struct {
+	service  *domain.Service
+	objects  []object
+	workload object // Deployment, StatefulSet or CronJob
+}
+
+// render translates a service and its ingresses into plain Kubernetes
+// objects, reporting the settings they cannot express
+func render(project *domain.Project, service *domain.Service, ingresses []*domain.Ingress) (*rendered, []manifest.Unsupported) {
+	var unsupported []manifest.Unsupported
+	drop := func(key, reason string) {
+		unsupported = append(unsupported, manifest.Unsupported{Service: service.Slug, Key: key, Reason: reason})
+	}
+
+	labels := map[string]interface{}{labelName: service.Slug, labelPartOf: project.Slug}
+	selector := map[string]interface{}{labelName: service.Slug}
+	out := &rendered{service: service}
+
+	container := object{"name": service.Slug, "image": image(service)}
+	if service.BuildSource.Image == "" {
+		drop("build_source", "the service is built by the platform; set its image to one pushed to your own registry")
+	}
+
+	if len(service.EnvVars) > 0 {
+		data := map[string]interface{}{}
+		for k, v := range service.EnvVars {
+			data[k] = v
+		}
+		out.objects = append(out.objects, object{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   metadata(service.Slug+"-env", labels, nil),
+			"data":       data,
+		})
+	}
+	// Variables come from the ConfigMap, secrets from Secrets of the same name
+	var envFrom []interface{}
+	if len(service.EnvVars) > 0 {
+		envFrom = append(envFrom, object{"configMapRef": object{"name": service.Slug + "-env"}})
+	}
+	for _, ref := range service.SecretRefs {
+		envFrom = append(envFrom, object{"secretRef": object{"name": ref}})
+	}
+	if len(envFrom) > 0 {
+		container["envFrom"] = envFrom
+	}
+
+	var containerPorts, servicePorts []interface{}
+	for _, p := range service.Ports {
+		target := p.TargetPort
+		if target == 0 {
+			target = p.Port
+		}
+		protocol := strings.ToUpper(p.Protocol)
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		name := p.Name
+		if name == "" {
+			name = fmt.Sprintf("port-%d", p.Port)
+		}
+		containerPorts = append(containerPorts, object{"name": name, "containerPort": target, "protocol": protocol})
+		servicePorts = append(servicePorts, object{"name": name, "port": p.Port, "targetPort": target, "protocol": protocol})
+	}
+	if len(containerPorts) > 0 {
+		container["ports"] = containerPorts
+	}
+
+	if r := resources(service.Resources); len(r) > 0 {
+		container["resources"] = r
+	}
+	if probe := probe(service.HealthCheck); probe != nil {
+		container["readinessProbe"] = probe
+		container["livenessProbe"] = probe
+	}
+	if mounts := volumes.Mounts(service); len(mounts) > 0 {
+		container["volumeMounts"] = mounts
+	}
+
+	podTemplate := object{
+		"metadata": object{"labels": labels},
+		"spec":     object{"containers": []interface{}{container}},
+	}
+	if constraints := availability.Constraints(service.Availability, selector); len(constraints) > 0 {
+		podTemplate["spec"].(object)["topologySpreadConstraints"] = constraints
+	}
+	replicas := service.Scaling.MinReplicas
+	if replicas < 1 {
+		replicas = 1
+	}
+
+	switch service.Type {
+	case domain.ServiceTypeCronJob:
+		schedule, _ := service.Metadata["schedule"].(string)
+		if service.CronJob != nil {
+			schedule = service.CronJob.Schedule
+		}
+		if schedule == "" {
+			schedule = defaultSchedule
+			drop("schedule", "the service records no schedule; it was exported as "+defaultSchedule)
+		}
+		podTemplate["spec"].(object)["restartPolicy"] = "OnFailure"
+		delete(container, "readinessProbe")
+		delete(container, "livenessProbe")
+		out.workload = object{
+			"apiVersion": "batch/v1",
+			"kind":       "CronJob",
+			"metadata":   metadata(service.Slug, labels, service.Annotations),
+			"spec": object{
+				"schedule":    schedule,
+				"jobTemplate": object{"spec": object{"template": podTemplate}},
+			},
+		}
+		if cj := service.CronJob; cj != nil {
+			spec := out.workload["spec"].(object)
+			if cj.TimeZone != "" {
+				spec["timeZone"] = cj.TimeZone
+			}
+			if cj.ConcurrencyPolicy != "" {
+				spec["concurrencyPolicy"] = string(cj.ConcurrencyPolicy)
+			}
+			if cj.StartingDeadlineSeconds > 0 {
+				spec["startingDeadlineSeconds"] = cj.StartingDeadlineSeconds
+			}
+			if cj.Suspend {
+				spec["suspend"] = true
+			}
+			if len(cj.Command) > 0 {
+				container["command"] = cj.Command
+			}
+		}
+	case domain.ServiceTypeStatefulDB:
+		// Each replica gets its own claims, from the service's volumes or else
+		// one of its storage size
+		var claimTemplates []interface{}
+		for _, v := range service.Volumes {
+			claimTemplates = append(claimTemplates, object{"metadata": object{"name": v.Name}, "spec": volumes.ClaimSpec(v)})
+		}
+		if len(service.Volumes) == 0 {
+			size := service.Resources.StorageSize
+			if size == "" {
+				size = "1Gi"
+			}
+			container["volumeMounts"] = []interface{}{object{"name": "data", "mountPath": dataMountPath}}
+			drop("volume", "the volume is mounted at "+dataMountPath+"; change the mount path to where the image keeps its data")
+			claimTemplates = []interface{}{object{
+				"metadata": object{"name": "data"},
+				"spec":     volumes.ClaimSpec(domain.Volume{Name: "data", Size: size}),
+			}}
+		}
+		out.workload = object{
+			"apiVersion": "apps/v1",
+			"kind":       "StatefulSet",
+			"metadata":   metadata(service.Slug, labels, service.Annotations),
+			"spec": object{
+				"serviceName":          service.Slug,
+				"replicas":             replicas,
+				"selector":             object{"matchLabels": selector},
+				"template":             podTemplate,
+				"volumeClaimTemplates": claimTemplates,
+			},
+		}
+	default:
+		// Replicas share a claim per volume
+		if len(service.Volumes) > 0 {
+			podTemplate["spec"].(object)["volumes"] = volumes.PodVolumes(service)
+		}
+		for _, v := range service.Volumes {
+			out.objects = append(out.objects, object{
+				"apiVersion": "v1",
+				"kind":       "PersistentVolumeClaim",
+				"metadata":   metadata(volumes.ClaimName(service, v), labels, nil),
+				"spec":       volumes.ClaimSpec(v),
+			})
+		}
+		out.workload = object{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   metadata(service.Slug, labels, service.Annotations),
+			"spec": object{
+				"replicas": replicas,
+				"selector": object{"matchLabels": selector},
+				"template": podTemplate,
+			},
+		}
+	}
+	out.objects = append(out.objects, out.workload)
+
+	if len(servicePorts) > 0 {
+		spec := object{"selector": selector, "ports": servicePorts}
+		if n := service.Networking; n != nil {
+			if n.IPFamilyPolicy != "" {
+				spec["ipFamilyPolicy"] = n.IPFamilyPolicy
+			}
+			if len(n.IPFamilies) > 0 {
+				spec["ipFamilies"] = n.IPFamilies
+			}
+		}
+		out.objects = append(out.objects, object{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   metadata(service.Slug, labels, nil),
+			"spec":       spec,
+		})
+	}
+
+	s := service.Scaling
+	if service.Type != domain.ServiceTypeCronJob && s.MaxReplicas > replicas && (s.TargetCPU > 0 || s.TargetMemory > 0) {
+		var metrics []interface{}
+		for _, m := range []struct {
+			resource string
+			target   int32
+		}{{"cpu", s.TargetCPU}, {"memory", s.TargetMemory}} {
+			if m.target > 0 {
+				metrics = append(metrics, object{
+					"type": "Resource",
+					"resource": object{
+						"name":   m.resource,
+						"target": object{"type": "Utilization", "averageUtilization": m.target},
+					},
+				})
+			}
+		}
+		out.objects = append(out.objects, object{
+			"apiVersion": "autoscaling/v2",
+			"kind":       "HorizontalPodAutoscaler",
+			"metadata":   metadata(service.Slug, labels, nil),
+			"spec": object{
+				"scaleTargetRef": object{"apiVersion": "apps/v1", "kind": out.workload["kind"], "name": service.Slug},
+				"minReplicas":    replicas,
+				"maxReplicas":    s.MaxReplicas,
+				"metrics":        metrics,
+			},
+		})
+	}
+	if budget := availability.Budget(service.Availability); budget != nil && service.Type != domain.ServiceTypeCronJob {
+		budget["selector"] = object{"matchLabels": selector}
+		out.objects = append(out.objects, object{
+			"apiVersion": "policy/v1",
+			"kind":       "PodDisruptionBudget",
+			"metadata":   metadata(service.Slug, labels, nil),
+			"spec":       budget,
+		})
+	}
+	if len(s.Triggers) > 0 {
+		drop("scaling.triggers", "event-driven scaling needs KEDA; only CPU and memory targets were exported")
+	}
+	if len(s.Schedules) > 0 {
+		drop("scaling.schedules", "scheduled scaling is run by the platform and was not exported")
+	}
+	if s.WarmStandby != nil {
+		drop("scaling.warm_standby", "warm standby is run by the platform and was not exported")
+	}
+
+	for _, ing := range ingresses {
+		obj, reason := ingress(service, ing, labels)
+		if obj == nil {
+			drop("ingresses."+ing.Domain, reason)
+			continue
+		}
+		out.objects = append(out.objects, obj)
+	}
+	return out, unsupported
+}
+
+// ingress renders an Ingress routing a domain to a service, or why it cannot
+func ingress(service *domain.Service, ing *domain.Ingress, labels map[string]interface{}) (object, string) {
+	if ing.Type == domain.IngressTypeTCP {
+		return nil, "TCP routes need a controller-specific resource and were not exported"
+	}
+	if len(service.Ports) == 0 {
+		return nil, "the service has no port to route to"
+	}
+	port := service.Ports[0]
+	for _, p := range service.Ports {
+		if p.Public {
+			port = p
+			break
+		}
+	}
+
+	path := ing.Path
+	if path == "" {
+		path = "/"
+	}
+	annotations := map[string]string{}
+	for k, v := range ing.Annotations {
+		annotations[k] = v
+	}
+	if ing.Type == domain.IngressTypeGRPC {
+		annotations["nginx.ingress.kubernetes.io/backend-protocol"] = "GRPC"
+	}
+
+	spec := object{
+		"rules": []interface{}{object{
+			"host": ing.Domain,
+			"http": object{"paths": []interface{}{object{
+				"path":     path,
+				"pathType": "Prefix",
+				"backend": object{"service": object{
+					"name": service.Slug,
+					"port": object{"number": port.Port},
+				}},
+			}}},
+		}},
+	}
+	if ing.TLS.Enabled || ing.TLS.AutoTLS {
+		secretName := ing.TLS.SecretName
+		if secretName == "" {
+			secretName = name(service.Slug, ing.Domain) + "-tls"
+		}
+		spec["tls"] = []interface{}{object{"hosts": []interface{}{ing.Domain}, "secretName": secretName}}
+		if ing.TLS.AutoTLS {
+			if _, ok := annotations["cert-manager.io/cluster-issuer"]; !ok {
+				annotations["cert-manager.io/cluster-issuer"] = "letsencrypt"
+			}
+		}
+	}
+
+	return object{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "Ingress",
+		"metadata":   metadata(name(service.Slug, ing.Domain), labels, annotations),
+		"spec":       spec,
+	}, ""
+}
+
+// image returns the image a service runs, tagged with its current version
+func image(service *domain.Service) string {
+	b := service.BuildSource
+	ref := b.Image
+	if ref == "" {
+		ref = service.Slug
+		if b.Registry != "" {
+			ref = strings.TrimRight(b.Registry, "/") + "/" + ref
+		}
+	}
+	if service.CurrentVersion != "" && !strings.Contains(ref[strings.LastIndex(ref, "/")+1:], ":") && !strings.Contains(ref, "@") {
+		ref += ":" + service.CurrentVersion
+	}
+	return ref
+}
+
+// resources renders a service's requests and limits
+func resources(r domain.ResourceLimits) object {
+	out := object{}
+	set := func(kind, resource, value string) {
+		if value == "" {
+			return
+		}
+		if out[kind] == nil {
+			out[kind] = object{}
+		}
+		out[kind].(object)[resource] = value
+	}
+	set("requests", "cpu", r.CPURequest)
+	set("requests", "memory", r.MemoryRequest)
+	set("limits", "cpu", r.CPULimit)
+	set("limits", "memory", r.MemoryLimit)
+	return out
+}
+
+// probe renders a health check as a probe. The success threshold is left
+// out, as liveness probes only accept 1.
+func probe(h *domain.HealthCheck) object {
+	if h == nil {
+		return nil
+	}
+	p := object{}
+	switch h.Type {
+	case "http":
+		path := h.Path
+		if path == "" {
+			path = "/"
+		}
+		p["httpGet"] = object{"path": path, "port": h.Port}
+	case "tcp":
+		p["tcpSocket"] = object{"port": h.Port}
+	case "exec":
+		p["exec"] = object{"command": []interface{}{"sh", "-c", h.Command}}
+	default:
+		return nil
+	}
+	for field, value := range map[string]int32{
+		"initialDelaySeconds": h.InitialDelaySeconds,
+		"periodSeconds":       h.PeriodSeconds,
+		"timeoutSeconds":      h.TimeoutSeconds,
+		"failureThreshold":    h.FailureThreshold,
+	} {
+		if value > 0 {
+			p[field] = value
+		}
+	}
+	return p
+}
+
+// metadata renders object metadata
+func metadata(name string, labels map[string]interface{}, annotations map[string]string) object {
+	m := object{"name": name, "labels": labels}
+	if len(annotations) > 0 {
+		a := map[string]interface{}{}
+		for k, v := range annotations {
+			a[k] = v
+		}
+		m["annotations"] = a
+	}
+	return m
+}
+
+// name derives an object name from a service and a domain
+func name(slug, host string) string {
+	n := strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(slug+"-"+host), "-"), "-")
+	if len(n) > 63 {
+		n = strings.TrimRight(n[:63], "-")
+	}
+	return n
+}
@@ -0,0 +1,285 @@
+// Package ingressroutes renders ingresses into the routing resources of the
+// ingress controller running in the workload cluster of their service:
+// networking.k8s.io Ingresses with annotations for NGINX, IngressRoutes and
+// Middlewares for Traefik. The controller is chosen per cluster. Path
+// rewrites, request body limits, sticky sessions, rate limits, client IP
+// allow and deny lists, and ordered redirect, rewrite and response header
+// rules are translated into the dialect of that controller.
+package ingressroutes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Manager applies the routing resources of ingresses to workload clusters
+type Manager struct {
+	config      *config.IngressRoutesConfig
+	kube        domain.KubernetesClient
+	ingressRepo domain.IngressRepository
+	serviceRepo domain.ServiceRepository
+	clusterRepo domain.ClusterRepository
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewManager creates a new Manager. Without a Kubernetes client, resources
+// are rendered but never applied.
+func NewManager(
+	cfg *config.IngressRoutesConfig,
+	kube domain.KubernetesClient,
+	ingressRepo domain.IngressRepository,
+	serviceRepo domain.ServiceRepository,
+	clusterRepo domain.ClusterRepository,
+	projectRepo domain.ProjectRepository,
+	log *logger.Logger,
+) *Manager {
+	return &Manager{
+		config:      cfg,
+		kube:        kube,
+		ingressRepo: ingressRepo,
+		serviceRepo: serviceRepo,
+		clusterRepo: clusterRepo,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// target is where the resources of an ingress go
+type target struct {
+	cluster    *domain.Cluster
+	controller Controller
+	backend    Backend
+}
+
+// Check fails when the ingress controller of the cluster serving an ingress
+// cannot render its routing options. Ingresses of services not deployed yet
+// pass.
+func (m *Manager) Check(ctx context.Context, ingress *domain.Ingress) error {
+	svc, err := m.serviceRepo.GetByID(ctx, ingress.ServiceID)
+	if err != nil || svc.TargetClusterID == nil {
+		return err
+	}
+	cluster, err := m.clusterRepo.GetByID(ctx, *svc.TargetClusterID)
+	if err != nil {
+		return err
+	}
+	_, err = Render(m.config, ClusterController(cluster, m.config.Controller), ingress, Backend{})
+	return err
+}
+
+// Manifests renders the resources of an ingress for the cluster its service
+// is deployed to
+func (m *Manager) Manifests(ctx context.Context, ingress *domain.Ingress) ([]map[string]interface{}, error) {
+	t, err := m.target(ctx, ingress)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, errors.BadRequest("the service is not deployed to a cluster yet")
+	}
+	return Render(m.config, t.controller, ingress, t.backend)
+}
+
+// Apply renders the resources of an ingress and applies them, removing those
+// the ingress no longer needs. Ingresses of services that are not running yet
+// are skipped; the reconcile loop applies them later.
+func (m *Manager) Apply(ctx context.Context, ingress *domain.Ingress) error {
+	if m.kube == nil || ingress.Type == domain.IngressTypeTCP {
+		return nil
+	}
+	t, err := m.target(ctx, ingress)
+	if err != nil || t == nil {
+		return err
+	}
+	objects, err := Render(m.config, t.controller, ingress, t.backend)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		manifest, err := json.Marshal(obj)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode ingress resources")
+		}
+		if err := m.kube.ApplyManifest(ctx, t.cluster.ID, manifest); err != nil {
+			return errors.DependencyFailed("kubernetes", fmt.Errorf("failed to apply %s: %w", obj["kind"], err))
+		}
+		name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+		applied[obj["kind"].(string)+"/"+name] = true
+	}
+
+	// Leftovers of the other controller or of routing options turned off
+	for _, res := range m.resources(ctx, t.cluster.ID, t.backend.Namespace, ingress) {
+		if !applied[res.kind+"/"+res.name] {
+			m.delete(ctx, t.cluster.ID, res.kind, t.backend.Namespace, res.name)
+		}
+	}
+
+	m.logger.Info().
+		Str("ingress_id", ingress.ID.String()).
+		Str("domain", ingress.Domain).
+		Str("controller", string(t.controller)).
+		Msg("Applied ingress resources")
+	return nil
+}
+
+// Remove deletes every routing resource of an ingress
+func (m *Manager) Remove(ctx context.Context, ingress *domain.Ingress) error {
+	if m.kube == nil {
+		return nil
+	}
+	t, err := m.target(ctx, ingress)
+	if err != nil || t == nil {
+		return err
+	}
+	for _, res := range m.resources(ctx, t.cluster.ID, t.backend.Namespace, ingress) {
+		m.delete(ctx, t.cluster.ID, res.kind, t.backend.Namespace, res.name)
+	}
+	return nil
+}
+
+// Run applies the resources of every ingress until ctx is cancelled, so
+// ingresses created before their service was deployed, or whose service
+// moved, are routed
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.applyAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) applyAll(ctx context.Context) {
+	projects, err := m.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list projects for ingress reconciliation")
+		return
+	}
+	for _, project := range projects {
+		ingresses, err := m.ingressRepo.ListByProject(ctx, project.ID)
+		if err != nil {
+			m.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list ingresses for reconciliation")
+			continue
+		}
+		for _, ingress := range ingresses {
+			if err := m.Apply(ctx, ingress); err != nil {
+				m.logger.Warn().Err(err).Str("ingress_id", ingress.ID.String()).Msg("Failed to apply ingress resources")
+			}
+		}
+	}
+}
+
+// target finds the cluster, controller and backend of an ingress, or nil when
+// its service has no Kubernetes Service yet
+func (m *Manager) target(ctx context.Context, ingress *domain.Ingress) (*target, error) {
+	svc, err := m.serviceRepo.GetByID(ctx, ingress.ServiceID)
+	if err != nil {
+		return nil, err
+	}
+	if svc.TargetClusterID == nil {
+		return nil, nil
+	}
+	cluster, err := m.clusterRepo.GetByID(ctx, *svc.TargetClusterID)
+	if err != nil {
+		return nil, err
+	}
+	port := servicePort(svc)
+	if port == 0 {
+		return nil, errors.BadRequest("the service exposes no ports to route to")
+	}
+
+	t := &target{
+		cluster:    cluster,
+		controller: ClusterController(cluster, m.config.Controller),
+		backend:    Backend{Name: svc.Slug, Port: port},
+	}
+	if m.kube == nil {
+		return t, nil
+	}
+	objects, err := m.kube.ListResources(ctx, cluster.ID, "Service", "", map[string]string{
+		domain.LabelServiceID: svc.ID.String(),
+	})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+	t.backend.Name, _, _ = unstructured.NestedString(objects[0], "metadata", "name")
+	t.backend.Namespace, _, _ = unstructured.NestedString(objects[0], "metadata", "namespace")
+	return t, nil
+}
+
+func (m *Manager) delete(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) {
+	// Kinds of a controller that is not installed fail discovery; nothing to delete
+	if err := m.kube.DeleteResource(ctx, clusterID, kind, namespace, name); err != nil && !errors.IsNotFound(err) {
+		m.logger.Debug().Err(err).Str("kind", kind).Str("name", name).Msg("Skipped ingress resource cleanup")
+	}
+}
+
+type resource struct {
+	kind string
+	name string
+}
+
+// resourceKinds are the kinds an ingress may be rendered to
+var resourceKinds = []string{"Ingress", "IngressRoute", "Middleware", "ConfigMap"}
+
+// resources lists every resource an ingress may have been rendered to: those
+// labelled with its ID, and the fixed names of resources rendered before
+// ingresses were labelled
+func (m *Manager) resources(ctx context.Context, clusterID uuid.UUID, namespace string, ingress *domain.Ingress) []resource {
+	out := []resource{
+		{"Ingress", Name(ingress)},
+		{"IngressRoute", Name(ingress)},
+		{"Middleware", AllowListMiddlewareName(ingress)},
+		{"Middleware", RateLimitMiddlewareName(ingress)},
+		{"Middleware", RewriteMiddlewareName(ingress)},
+		{"Middleware", BodyMiddlewareName(ingress)},
+	}
+	for _, kind := range resourceKinds {
+		objects, err := m.kube.ListResources(ctx, clusterID, kind, namespace, map[string]string{
+			domain.LabelIngressID: ingress.ID.String(),
+		})
+		if err != nil {
+			continue // Kinds of a controller that is not installed
+		}
+		for _, obj := range objects {
+			name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+			out = append(out, resource{kind, name})
+		}
+	}
+	return out
+}
+
+// servicePort is the port ingresses reach a service on: its first public
+// port, or its first port
+func servicePort(svc *domain.Service) int32 {
+	for _, p := range svc.Ports {
+		if p.Public {
+			return p.Port
+		}
+	}
+	if len(svc.Ports) > 0 {
+		return svc.Ports[0].Port
+	}
+	return 0
+}
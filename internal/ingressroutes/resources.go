@@ -0,0 +1,407 @@
+package ingressroutes
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/northstack/platform/internal/autotls"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// Controller is the ingress controller of a cluster, which decides the kind
+// of resources an ingress is rendered to
+type Controller string
+
+const (
+	ControllerNGINX   Controller = "nginx"   // networking.k8s.io Ingress with nginx.ingress.kubernetes.io annotations
+	ControllerTraefik Controller = "traefik" // IngressRoute and Middleware custom resources
+)
+
+const (
+	// MetadataController is the cluster metadata key holding its ingress controller
+	MetadataController = "ingress_controller"
+
+	// DefaultCookieName names the affinity cookie of sticky sessions
+	DefaultCookieName = "northstack-affinity"
+
+	traefikAPIVersion = "traefik.io/v1alpha1"
+	nginxPrefix       = "nginx.ingress.kubernetes.io/"
+)
+
+// Backend is the Kubernetes Service an ingress routes to
+type Backend struct {
+	Name      string
+	Namespace string
+	Port      int32
+}
+
+// ClusterController returns the ingress controller of a cluster, or fallback
+// when it records none
+func ClusterController(cluster *domain.Cluster, fallback string) Controller {
+	if controller, ok := cluster.Metadata[MetadataController].(string); ok && controller != "" {
+		return Controller(controller)
+	}
+	return Controller(fallback)
+}
+
+// SetClusterController records the ingress controller of a cluster; empty
+// falls back to the platform default
+func SetClusterController(cluster *domain.Cluster, controller string) {
+	if cluster.Metadata == nil {
+		cluster.Metadata = make(map[string]interface{})
+	}
+	if controller == "" {
+		delete(cluster.Metadata, MetadataController)
+		return
+	}
+	cluster.Metadata[MetadataController] = controller
+}
+
+var cookieName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Validate checks the routing options of an ingress
+func Validate(ingress *domain.Ingress) error {
+	r := ingress.Routing
+	if r.RewritePath != "" && (!strings.HasPrefix(r.RewritePath, "/") || strings.ContainsAny(r.RewritePath, " ?#$")) {
+		return errors.BadRequest("routing.rewrite_path must start with / and must not contain spaces, queries, fragments or $")
+	}
+	if r.MaxBodySizeMB < 0 {
+		return errors.BadRequest("routing.max_body_size_mb must not be negative")
+	}
+	if s := r.StickySessions; s != nil {
+		if s.MaxAgeSeconds < 0 {
+			return errors.BadRequest("routing.sticky_sessions.max_age_seconds must not be negative")
+		}
+		if s.CookieName != "" && !cookieName.MatchString(s.CookieName) {
+			return errors.BadRequest("routing.sticky_sessions.cookie_name may only contain letters, digits, - and _")
+		}
+	}
+	if l := r.RateLimit; l != nil && (l.RequestsPerSecond < 1 || l.Burst < 0) {
+		return errors.BadRequest("routing.rate_limit needs requests_per_second of at least 1 and a burst that is not negative")
+	}
+	for _, entry := range append(append([]string{}, r.AllowCIDRs...), r.DenyCIDRs...) {
+		if !validRange(entry) {
+			return errors.BadRequest(fmt.Sprintf("%q in routing.allow_cidrs or routing.deny_cidrs is not a CIDR or IP address", entry))
+		}
+	}
+	if ingress.Type == domain.IngressTypeTCP && (r.RewritePath != "" || r.MaxBodySizeMB > 0 || r.StickySessions != nil ||
+		r.RateLimit != nil || len(r.AllowCIDRs) > 0 || len(r.DenyCIDRs) > 0 || len(r.Rules) > 0) {
+		return errors.BadRequest("routing options only apply to http and grpc ingresses")
+	}
+	return validateRules(ingress)
+}
+
+func validRange(entry string) bool {
+	if _, _, err := net.ParseCIDR(entry); err == nil {
+		return true
+	}
+	return net.ParseIP(entry) != nil
+}
+
+// Name is the name of the main routing resource of an ingress
+func Name(ingress *domain.Ingress) string {
+	return "ingress-" + ingress.ID.String()
+}
+
+// Render renders the resources routing an ingress to its backend with the
+// given controller
+func Render(cfg *config.IngressRoutesConfig, controller Controller, ingress *domain.Ingress, backend Backend) ([]map[string]interface{}, error) {
+	switch controller {
+	case ControllerNGINX:
+		return nginxResources(cfg, ingress, backend), nil
+	case ControllerTraefik:
+		if len(ingress.Routing.DenyCIDRs) > 0 {
+			return nil, errors.BadRequest("traefik has no deny lists; use routing.allow_cidrs on traefik clusters")
+		}
+		return traefikResources(cfg, ingress, backend), nil
+	}
+	return nil, errors.BadRequest(fmt.Sprintf("unsupported ingress controller %q; use %s or %s", controller, ControllerNGINX, ControllerTraefik))
+}
+
+func nginxResources(cfg *config.IngressRoutesConfig, ingress *domain.Ingress, backend Backend) []map[string]interface{} {
+	annotations := userAnnotations(ingress)
+	path := nginxPath(ingress.Path, "Prefix", backend)
+
+	r := ingress.Routing
+	if r.RewritePath != "" {
+		// The remainder of the path after the matched prefix is the last group
+		base := strings.TrimSuffix(r.RewritePath, "/")
+		prefix := strings.TrimSuffix(ingress.Path, "/")
+		if prefix == "" {
+			path["path"] = "/(.*)"
+			annotations[nginxPrefix+"rewrite-target"] = base + "/$1"
+		} else {
+			path["path"] = regexp.QuoteMeta(prefix) + "(/|$)(.*)"
+			annotations[nginxPrefix+"rewrite-target"] = base + "/$2"
+		}
+		path["pathType"] = "ImplementationSpecific"
+		annotations[nginxPrefix+"use-regex"] = "true"
+	}
+	if r.MaxBodySizeMB > 0 {
+		annotations[nginxPrefix+"proxy-body-size"] = strconv.Itoa(r.MaxBodySizeMB) + "m"
+	}
+	if s := r.StickySessions; s != nil {
+		annotations[nginxPrefix+"affinity"] = "cookie"
+		annotations[nginxPrefix+"session-cookie-name"] = stickyCookie(s)
+		if s.MaxAgeSeconds > 0 {
+			annotations[nginxPrefix+"session-cookie-max-age"] = strconv.Itoa(s.MaxAgeSeconds)
+		}
+	}
+	if l := r.RateLimit; l != nil {
+		annotations[nginxPrefix+"limit-rps"] = strconv.Itoa(l.RequestsPerSecond)
+		// NGINX sizes the burst as a multiple of the rate
+		multiplier := (l.Burst + l.RequestsPerSecond - 1) / l.RequestsPerSecond
+		if multiplier < 1 {
+			multiplier = 1
+		}
+		annotations[nginxPrefix+"limit-burst-multiplier"] = strconv.Itoa(multiplier)
+	}
+	if len(r.AllowCIDRs) > 0 {
+		annotations[nginxPrefix+"allowlist-source-range"] = strings.Join(r.AllowCIDRs, ",")
+	}
+	if len(r.DenyCIDRs) > 0 {
+		annotations[nginxPrefix+"denylist-source-range"] = strings.Join(r.DenyCIDRs, ",")
+	}
+	if ingress.Type == domain.IngressTypeGRPC {
+		annotations[nginxPrefix+"backend-protocol"] = "GRPC"
+	}
+
+	// Rules add annotations, so they go last
+	extra := nginxRules(cfg, ingress, backend, annotations)
+	main := nginxIngress(cfg, Name(ingress), ingress.Domain, ingress, backend, path, annotations)
+	return append([]map[string]interface{}{main}, extra...)
+}
+
+func nginxPath(path, pathType string, backend Backend) map[string]interface{} {
+	return map[string]interface{}{
+		"path":     path,
+		"pathType": pathType,
+		"backend": map[string]interface{}{
+			"service": map[string]interface{}{
+				"name": backend.Name,
+				"port": map[string]interface{}{"number": int64(backend.Port)},
+			},
+		},
+	}
+}
+
+// nginxIngress renders an Ingress routing one path of a host to the backend,
+// served with the certificate of the ingress when it has TLS
+func nginxIngress(cfg *config.IngressRoutesConfig, name, host string, ingress *domain.Ingress, backend Backend, path, annotations map[string]interface{}) map[string]interface{} {
+	spec := map[string]interface{}{
+		"ingressClassName": cfg.NGINXClass,
+		"rules": []interface{}{
+			map[string]interface{}{
+				"host": host,
+				"http": map[string]interface{}{"paths": []interface{}{path}},
+			},
+		},
+	}
+	if ingress.TLS.Enabled {
+		spec["tls"] = []interface{}{
+			map[string]interface{}{
+				"hosts":      []interface{}{host},
+				"secretName": autotls.SecretName(ingress),
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "Ingress",
+		"metadata":   metadata(name, backend.Namespace, ingress, annotations),
+		"spec":       spec,
+	}
+}
+
+func traefikResources(cfg *config.IngressRoutesConfig, ingress *domain.Ingress, backend Backend) []map[string]interface{} {
+	var objects []map[string]interface{}
+	var middlewares []interface{}
+	service := map[string]interface{}{
+		"name": backend.Name,
+		"port": int64(backend.Port),
+	}
+
+	// Middlewares run in order: rejected clients never count against the limit
+	r := ingress.Routing
+	if len(r.AllowCIDRs) > 0 {
+		name := AllowListMiddlewareName(ingress)
+		objects = append(objects, middleware(name, backend.Namespace, ingress, map[string]interface{}{
+			"ipAllowList": map[string]interface{}{"sourceRange": stringSlice(r.AllowCIDRs)},
+		}))
+		middlewares = append(middlewares, map[string]interface{}{"name": name, "namespace": backend.Namespace})
+	}
+	if l := r.RateLimit; l != nil {
+		limit := map[string]interface{}{"average": int64(l.RequestsPerSecond), "period": "1s"}
+		if l.Burst > 0 {
+			limit["burst"] = int64(l.Burst)
+		}
+		name := RateLimitMiddlewareName(ingress)
+		objects = append(objects, middleware(name, backend.Namespace, ingress, map[string]interface{}{"rateLimit": limit}))
+		middlewares = append(middlewares, map[string]interface{}{"name": name, "namespace": backend.Namespace})
+	}
+	if r.RewritePath != "" {
+		base := strings.TrimSuffix(r.RewritePath, "/")
+		prefix := strings.TrimSuffix(ingress.Path, "/")
+		regex, replacement := "^/(.*)", base+"/$1"
+		if prefix != "" {
+			regex, replacement = "^"+regexp.QuoteMeta(prefix)+"(/|$)(.*)", base+"/$2"
+		}
+		name := RewriteMiddlewareName(ingress)
+		objects = append(objects, middleware(name, backend.Namespace, ingress, map[string]interface{}{
+			"replacePathRegex": map[string]interface{}{"regex": regex, "replacement": replacement},
+		}))
+		middlewares = append(middlewares, map[string]interface{}{"name": name, "namespace": backend.Namespace})
+	}
+	if r.MaxBodySizeMB > 0 {
+		name := BodyMiddlewareName(ingress)
+		objects = append(objects, middleware(name, backend.Namespace, ingress, map[string]interface{}{
+			"buffering": map[string]interface{}{"maxRequestBodyBytes": int64(r.MaxBodySizeMB) << 20},
+		}))
+		middlewares = append(middlewares, map[string]interface{}{"name": name, "namespace": backend.Namespace})
+	}
+	if s := r.StickySessions; s != nil {
+		cookie := map[string]interface{}{"name": stickyCookie(s)}
+		if s.MaxAgeSeconds > 0 {
+			cookie["maxAge"] = int64(s.MaxAgeSeconds)
+		}
+		if ingress.TLS.Enabled {
+			cookie["secure"] = true
+		}
+		service["sticky"] = map[string]interface{}{"cookie": cookie}
+	}
+	if ingress.Type == domain.IngressTypeGRPC {
+		service["scheme"] = "h2c"
+	}
+
+	// Routing rules run last, in the order they are listed
+	ruleObjects, ruleMiddlewares, httpsRedirect := traefikRules(ingress, backend.Namespace)
+	objects = append(objects, ruleObjects...)
+	middlewares = append(middlewares, ruleMiddlewares...)
+
+	route := map[string]interface{}{
+		"kind":     "Rule",
+		"match":    traefikMatch(ingress),
+		"services": []interface{}{service},
+	}
+	if len(middlewares) > 0 {
+		route["middlewares"] = middlewares
+	}
+
+	spec := map[string]interface{}{
+		"entryPoints": []interface{}{cfg.TraefikEntryPoint},
+		"routes":      []interface{}{route},
+	}
+	if ingress.TLS.Enabled {
+		spec["entryPoints"] = []interface{}{cfg.TraefikTLSEntry}
+		spec["tls"] = map[string]interface{}{"secretName": autotls.SecretName(ingress)}
+	}
+
+	objects = append(objects, map[string]interface{}{
+		"apiVersion": traefikAPIVersion,
+		"kind":       "IngressRoute",
+		"metadata":   metadata(Name(ingress), backend.Namespace, ingress, userAnnotations(ingress)),
+		"spec":       spec,
+	})
+	if httpsRedirect != nil {
+		// Plain HTTP requests only reach the redirect
+		objects = append(objects, map[string]interface{}{
+			"apiVersion": traefikAPIVersion,
+			"kind":       "IngressRoute",
+			"metadata":   metadata(HTTPRouteName(ingress), backend.Namespace, ingress, nil),
+			"spec": map[string]interface{}{
+				"entryPoints": []interface{}{cfg.TraefikEntryPoint},
+				"routes": []interface{}{
+					map[string]interface{}{
+						"kind":        "Rule",
+						"match":       traefikMatch(ingress),
+						"middlewares": []interface{}{httpsRedirect},
+						"services":    []interface{}{service},
+					},
+				},
+			},
+		})
+	}
+	return objects
+}
+
+// RewriteMiddlewareName is the Traefik Middleware rewriting the path of an ingress
+func RewriteMiddlewareName(ingress *domain.Ingress) string {
+	return Name(ingress) + "-rewrite"
+}
+
+// AllowListMiddlewareName is the Traefik Middleware admitting the allowed
+// client ranges of an ingress
+func AllowListMiddlewareName(ingress *domain.Ingress) string {
+	return Name(ingress) + "-allow"
+}
+
+// RateLimitMiddlewareName is the Traefik Middleware rate limiting an ingress
+func RateLimitMiddlewareName(ingress *domain.Ingress) string {
+	return Name(ingress) + "-ratelimit"
+}
+
+// BodyMiddlewareName is the Traefik Middleware limiting the request body of an ingress
+func BodyMiddlewareName(ingress *domain.Ingress) string {
+	return Name(ingress) + "-body"
+}
+
+func middleware(name, namespace string, ingress *domain.Ingress, spec map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": traefikAPIVersion,
+		"kind":       "Middleware",
+		"metadata":   metadata(name, namespace, ingress, nil),
+		"spec":       spec,
+	}
+}
+
+func metadata(name, namespace string, ingress *domain.Ingress, annotations map[string]interface{}) map[string]interface{} {
+	labels := make(map[string]interface{}, len(ingress.Labels)+4)
+	for k, v := range ingress.Labels {
+		labels[k] = v
+	}
+	labels[domain.LabelIngressID] = ingress.ID.String()
+	labels[domain.LabelServiceID] = ingress.ServiceID.String()
+	labels[domain.LabelProjectID] = ingress.ProjectID.String()
+	labels[domain.LabelManagedBy] = domain.ManagedByValue
+
+	meta := map[string]interface{}{
+		"name":      name,
+		"namespace": namespace,
+		"labels":    labels,
+	}
+	if len(annotations) > 0 {
+		meta["annotations"] = annotations
+	}
+	return meta
+}
+
+// userAnnotations copies the annotations set on an ingress; routing options
+// override them
+func userAnnotations(ingress *domain.Ingress) map[string]interface{} {
+	annotations := make(map[string]interface{}, len(ingress.Annotations))
+	for k, v := range ingress.Annotations {
+		annotations[k] = v
+	}
+	return annotations
+}
+
+func stringSlice(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func stickyCookie(s *domain.StickySessions) string {
+	if s.CookieName != "" {
+		return s.CookieName
+	}
+	return DefaultCookieName
+}
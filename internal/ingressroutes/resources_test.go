@@ -0,0 +1,254 @@
+package ingressroutes
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var testConfig = &config.IngressRoutesConfig{
+	Controller:        "nginx",
+	NGINXClass:        "nginx",
+	TraefikEntryPoint: "web",
+	TraefikTLSEntry:   "websecure",
+}
+
+func testIngress() *domain.Ingress {
+	return &domain.Ingress{
+		ID:          uuid.New(),
+		ServiceID:   uuid.New(),
+		ProjectID:   uuid.New(),
+		Domain:      "app.example.com",
+		Path:        "/api",
+		Type:        domain.IngressTypeHTTP,
+		Annotations: map[string]string{"example.com/team": "a"},
+		Routing: domain.IngressRouting{
+			RewritePath:    "/",
+			MaxBodySizeMB:  25,
+			StickySessions: &domain.StickySessions{MaxAgeSeconds: 3600},
+		},
+	}
+}
+
+func TestRenderNGINX(t *testing.T) {
+	ingress := testIngress()
+	ingress.TLS.Enabled = true
+	objects, err := Render(testConfig, ControllerNGINX, ingress, Backend{Name: "web", Namespace: "team-a", Port: 8080})
+	require.NoError(t, err)
+	require.Len(t, objects, 1)
+
+	obj := objects[0]
+	assert.Equal(t, "Ingress", obj["kind"])
+	annotations, _, _ := unstructured.NestedStringMap(obj, "metadata", "annotations")
+	assert.Equal(t, "/$2", annotations[nginxPrefix+"rewrite-target"])
+	assert.Equal(t, "25m", annotations[nginxPrefix+"proxy-body-size"])
+	assert.Equal(t, "cookie", annotations[nginxPrefix+"affinity"])
+	assert.Equal(t, DefaultCookieName, annotations[nginxPrefix+"session-cookie-name"])
+	assert.Equal(t, "3600", annotations[nginxPrefix+"session-cookie-max-age"])
+	assert.Equal(t, "a", annotations["example.com/team"])
+
+	rules, _, _ := unstructured.NestedSlice(obj, "spec", "rules")
+	paths, _, _ := unstructured.NestedSlice(rules[0].(map[string]interface{}), "http", "paths")
+	path := paths[0].(map[string]interface{})
+	assert.Equal(t, "/api(/|$)(.*)", path["path"])
+	assert.Equal(t, "ImplementationSpecific", path["pathType"])
+
+	tls, _, _ := unstructured.NestedSlice(obj, "spec", "tls")
+	assert.Equal(t, "ingress-"+ingress.ID.String()+"-tls", tls[0].(map[string]interface{})["secretName"])
+
+	// Without routing options the path is a plain prefix
+	ingress.Routing = domain.IngressRouting{}
+	ingress.Path = "/"
+	objects, err = Render(testConfig, ControllerNGINX, ingress, Backend{Name: "web", Namespace: "team-a", Port: 8080})
+	require.NoError(t, err)
+	annotations, _, _ = unstructured.NestedStringMap(objects[0], "metadata", "annotations")
+	assert.NotContains(t, annotations, nginxPrefix+"use-regex")
+}
+
+func TestRenderTraefik(t *testing.T) {
+	ingress := testIngress()
+	ingress.Routing.RewritePath = "/v1"
+	objects, err := Render(testConfig, ControllerTraefik, ingress, Backend{Name: "web", Namespace: "team-a", Port: 8080})
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+
+	rewrite := objects[0]
+	assert.Equal(t, "Middleware", rewrite["kind"])
+	replacement, _, _ := unstructured.NestedString(rewrite, "spec", "replacePathRegex", "replacement")
+	assert.Equal(t, "/v1/$2", replacement)
+	limit, _, _ := unstructured.NestedFieldNoCopy(objects[1], "spec", "buffering", "maxRequestBodyBytes")
+	assert.Equal(t, int64(25<<20), limit)
+
+	route := objects[2]
+	assert.Equal(t, "IngressRoute", route["kind"])
+	entryPoints, _, _ := unstructured.NestedStringSlice(route, "spec", "entryPoints")
+	assert.Equal(t, []string{"web"}, entryPoints)
+	routes, _, _ := unstructured.NestedSlice(route, "spec", "routes")
+	rule := routes[0].(map[string]interface{})
+	assert.Equal(t, "Host(`app.example.com`) && PathPrefix(`/api`)", rule["match"])
+	assert.Len(t, rule["middlewares"], 2)
+	cookie, _, _ := unstructured.NestedString(rule["services"].([]interface{})[0].(map[string]interface{}), "sticky", "cookie", "name")
+	assert.Equal(t, DefaultCookieName, cookie)
+}
+
+func TestValidate(t *testing.T) {
+	ingress := testIngress()
+	assert.NoError(t, Validate(ingress))
+
+	ingress.Routing.RewritePath = "v1"
+	assert.Error(t, Validate(ingress))
+
+	ingress = testIngress()
+	ingress.Routing.StickySessions.CookieName = "bad cookie"
+	assert.Error(t, Validate(ingress))
+
+	ingress = testIngress()
+	ingress.Routing.AllowCIDRs = []string{"10.0.0.0/33"}
+	assert.Error(t, Validate(ingress))
+
+	ingress = testIngress()
+	ingress.Routing.RateLimit = &domain.RateLimit{}
+	assert.Error(t, Validate(ingress))
+
+	ingress = testIngress()
+	ingress.Type = domain.IngressTypeTCP
+	assert.Error(t, Validate(ingress))
+	ingress.Routing = domain.IngressRouting{}
+	assert.NoError(t, Validate(ingress))
+}
+
+func TestClusterController(t *testing.T) {
+	cluster := &domain.Cluster{}
+	assert.Equal(t, ControllerNGINX, ClusterController(cluster, "nginx"))
+	SetClusterController(cluster, "traefik")
+	assert.Equal(t, ControllerTraefik, ClusterController(cluster, "nginx"))
+	SetClusterController(cluster, "")
+	assert.NotContains(t, cluster.Metadata, MetadataController)
+}
+
+func TestRenderAccess(t *testing.T) {
+	ingress := testIngress()
+	ingress.Routing = domain.IngressRouting{
+		RateLimit:  &domain.RateLimit{RequestsPerSecond: 10, Burst: 25},
+		AllowCIDRs: []string{"10.0.0.0/8", "203.0.113.7"},
+		DenyCIDRs:  []string{"10.1.0.0/16"},
+	}
+	backend := Backend{Name: "web", Namespace: "team-a", Port: 8080}
+
+	objects, err := Render(testConfig, ControllerNGINX, ingress, backend)
+	require.NoError(t, err)
+	annotations, _, _ := unstructured.NestedStringMap(objects[0], "metadata", "annotations")
+	assert.Equal(t, "10", annotations[nginxPrefix+"limit-rps"])
+	assert.Equal(t, "3", annotations[nginxPrefix+"limit-burst-multiplier"])
+	assert.Equal(t, "10.0.0.0/8,203.0.113.7", annotations[nginxPrefix+"allowlist-source-range"])
+	assert.Equal(t, "10.1.0.0/16", annotations[nginxPrefix+"denylist-source-range"])
+
+	_, err = Render(testConfig, ControllerTraefik, ingress, backend)
+	assert.Error(t, err) // No deny lists
+
+	ingress.Routing.DenyCIDRs = nil
+	objects, err = Render(testConfig, ControllerTraefik, ingress, backend)
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+	ranges, _, _ := unstructured.NestedStringSlice(objects[0], "spec", "ipAllowList", "sourceRange")
+	assert.Equal(t, []string{"10.0.0.0/8", "203.0.113.7"}, ranges)
+	burst, _, _ := unstructured.NestedInt64(objects[1], "spec", "rateLimit", "burst")
+	assert.Equal(t, int64(25), burst)
+}
+
+func testRules() []domain.IngressRule {
+	return []domain.IngressRule{
+		{Type: domain.IngressRuleRedirectHost, Host: "www.example.com", Permanent: true},
+		{Type: domain.IngressRuleForceHTTPS},
+		{Type: domain.IngressRuleRewrite, Regex: "^/old/(.*)", Replacement: "/new/$1"},
+		{Type: domain.IngressRuleResponseHeaders, Headers: map[string]string{"Strict-Transport-Security": "max-age=31536000"}},
+	}
+}
+
+func TestRenderRulesNGINX(t *testing.T) {
+	ingress := testIngress()
+	ingress.TLS.Enabled = true
+	ingress.Routing = domain.IngressRouting{MaxBodySizeMB: 5, Rules: testRules()}
+	objects, err := Render(testConfig, ControllerNGINX, ingress, Backend{Name: "web", Namespace: "team-a", Port: 8080})
+	require.NoError(t, err)
+	require.Len(t, objects, 4)
+
+	annotations, _, _ := unstructured.NestedStringMap(objects[0], "metadata", "annotations")
+	assert.Equal(t, "true", annotations[nginxPrefix+"force-ssl-redirect"])
+	assert.Equal(t, "team-a/"+HeadersName(ingress), annotations[nginxPrefix+"custom-headers"])
+	labels, _, _ := unstructured.NestedStringMap(objects[0], "metadata", "labels")
+	assert.Equal(t, ingress.ID.String(), labels[domain.LabelIngressID])
+
+	headers := objects[1]
+	assert.Equal(t, "ConfigMap", headers["kind"])
+	hsts, _, _ := unstructured.NestedString(headers, "data", "Strict-Transport-Security")
+	assert.Equal(t, "max-age=31536000", hsts)
+
+	redirect := objects[2]
+	annotations, _, _ = unstructured.NestedStringMap(redirect, "metadata", "annotations")
+	assert.Equal(t, "https://app.example.com$request_uri", annotations[nginxPrefix+"permanent-redirect"])
+	assert.Equal(t, "308", annotations[nginxPrefix+"permanent-redirect-code"])
+	rules, _, _ := unstructured.NestedSlice(redirect, "spec", "rules")
+	assert.Equal(t, "www.example.com", rules[0].(map[string]interface{})["host"])
+
+	rewrite := objects[3]
+	annotations, _, _ = unstructured.NestedStringMap(rewrite, "metadata", "annotations")
+	assert.Equal(t, "/new/$1", annotations[nginxPrefix+"rewrite-target"])
+	assert.Equal(t, "5m", annotations[nginxPrefix+"proxy-body-size"])
+	rules, _, _ = unstructured.NestedSlice(rewrite, "spec", "rules")
+	paths, _, _ := unstructured.NestedSlice(rules[0].(map[string]interface{}), "http", "paths")
+	assert.Equal(t, "/old/(.*)", paths[0].(map[string]interface{})["path"])
+}
+
+func TestRenderRulesTraefik(t *testing.T) {
+	ingress := testIngress()
+	ingress.TLS.Enabled = true
+	ingress.Routing = domain.IngressRouting{Rules: testRules()}
+	objects, err := Render(testConfig, ControllerTraefik, ingress, Backend{Name: "web", Namespace: "team-a", Port: 8080})
+	require.NoError(t, err)
+	require.Len(t, objects, 6) // Four middlewares and two routes
+
+	regex, _, _ := unstructured.NestedString(objects[0], "spec", "redirectRegex", "regex")
+	assert.Equal(t, `^https?://www\.example\.com(:\d+)?/(.*)`, regex)
+
+	route := objects[4]
+	routes, _, _ := unstructured.NestedSlice(route, "spec", "routes")
+	rule := routes[0].(map[string]interface{})
+	assert.Equal(t, "(Host(`app.example.com`) || Host(`www.example.com`)) && PathPrefix(`/api`)", rule["match"])
+	assert.Len(t, rule["middlewares"], 3)
+
+	http := objects[5]
+	assert.Equal(t, HTTPRouteName(ingress), http["metadata"].(map[string]interface{})["name"])
+	entryPoints, _, _ := unstructured.NestedStringSlice(http, "spec", "entryPoints")
+	assert.Equal(t, []string{"web"}, entryPoints)
+}
+
+func TestValidateRules(t *testing.T) {
+	ingress := testIngress()
+	ingress.TLS.Enabled = true
+	ingress.Routing.Rules = testRules()
+	assert.NoError(t, Validate(ingress))
+
+	for _, rule := range []domain.IngressRule{
+		{Type: "drop"},
+		{Type: domain.IngressRuleRedirectHost, Host: "app.example.com"},
+		{Type: domain.IngressRuleRedirectHost, Host: "*.example.com"},
+		{Type: domain.IngressRuleRewrite, Regex: "old/(.*)", Replacement: "/new/$1"},
+		{Type: domain.IngressRuleRewrite, Regex: "^/(", Replacement: "/"},
+		{Type: domain.IngressRuleRewrite, Regex: "^/old", Replacement: "new"},
+		{Type: domain.IngressRuleResponseHeaders},
+		{Type: domain.IngressRuleResponseHeaders, Headers: map[string]string{"X-Bad": "a\r\nSet-Cookie: b"}},
+	} {
+		ingress.Routing.Rules = []domain.IngressRule{rule}
+		assert.Error(t, Validate(ingress), rule)
+	}
+
+	ingress.TLS.Enabled = false
+	ingress.Routing.Rules = []domain.IngressRule{{Type: domain.IngressRuleForceHTTPS}}
+	assert.Error(t, Validate(ingress))
+}
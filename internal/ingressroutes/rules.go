@@ -0,0 +1,213 @@
+package ingressroutes
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// maxRules bounds the routing rules of an ingress
+const maxRules = 20
+
+// headerName matches the token characters allowed in an HTTP header name
+var headerName = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// hostname matches a DNS hostname, wildcards excluded
+var hostname = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// validateRules checks the routing rules of an ingress
+func validateRules(ingress *domain.Ingress) error {
+	rules := ingress.Routing.Rules
+	if len(rules) > maxRules {
+		return errors.BadRequest(fmt.Sprintf("an ingress has at most %d routing rules", maxRules))
+	}
+	hosts := map[string]bool{ingress.Domain: true}
+	for i, rule := range rules {
+		field := fmt.Sprintf("routing.rules[%d]", i)
+		switch rule.Type {
+		case domain.IngressRuleRedirectHost:
+			host := strings.ToLower(rule.Host)
+			if !hostname.MatchString(host) {
+				return errors.BadRequest(field + ".host must be a hostname")
+			}
+			if hosts[host] {
+				return errors.BadRequest(field + ".host must differ from the ingress domain and the other redirected hosts")
+			}
+			hosts[host] = true
+		case domain.IngressRuleForceHTTPS:
+			if !ingress.TLS.Enabled {
+				return errors.BadRequest(field + ": force_https needs tls.enabled")
+			}
+		case domain.IngressRuleRewrite:
+			if _, err := regexp.Compile(rule.Regex); err != nil || !strings.HasPrefix(rule.Regex, "^/") {
+				return errors.BadRequest(field + ".regex must be a regular expression anchored at the start of the path (^/)")
+			}
+			if !strings.HasPrefix(rule.Replacement, "/") || strings.ContainsAny(rule.Replacement, " ?#") {
+				return errors.BadRequest(field + ".replacement must be a path starting with /")
+			}
+		case domain.IngressRuleResponseHeaders:
+			if len(rule.Headers) == 0 {
+				return errors.BadRequest(field + ".headers must not be empty")
+			}
+			for name, value := range rule.Headers {
+				if !headerName.MatchString(name) || strings.ContainsAny(value, "\r\n") {
+					return errors.BadRequest(fmt.Sprintf("%s.headers: %q is not a valid header", field, name))
+				}
+			}
+		default:
+			return errors.BadRequest(fmt.Sprintf("%s.type must be %s, %s, %s or %s", field,
+				domain.IngressRuleRedirectHost, domain.IngressRuleForceHTTPS, domain.IngressRuleRewrite, domain.IngressRuleResponseHeaders))
+		}
+	}
+	return nil
+}
+
+// HeadersName is the ConfigMap holding the response headers NGINX adds for
+// an ingress
+func HeadersName(ingress *domain.Ingress) string {
+	return Name(ingress) + "-headers"
+}
+
+// RuleName is the NGINX Ingress, or Traefik Middleware, of the routing rule
+// at index i of an ingress
+func RuleName(ingress *domain.Ingress, i int) string {
+	return Name(ingress) + "-rule-" + strconv.Itoa(i)
+}
+
+// HTTPRouteName is the Traefik IngressRoute redirecting plain HTTP requests
+// of a TLS ingress to HTTPS
+func HTTPRouteName(ingress *domain.Ingress) string {
+	return Name(ingress) + "-http"
+}
+
+// nginxRules applies the routing rules of an ingress to the annotations of
+// its NGINX Ingress and renders the other objects they need: an Ingress per
+// redirected host, an Ingress per rewritten path and a ConfigMap of response
+// headers. When rules set the same header the later one wins.
+func nginxRules(cfg *config.IngressRoutesConfig, ingress *domain.Ingress, backend Backend, annotations map[string]interface{}) []map[string]interface{} {
+	var objects []map[string]interface{}
+	headers := map[string]interface{}{}
+
+	for _, rule := range ingress.Routing.Rules {
+		switch rule.Type {
+		case domain.IngressRuleForceHTTPS:
+			// NGINX answers with 308 whether or not the rule is permanent
+			annotations[nginxPrefix+"force-ssl-redirect"] = "true"
+		case domain.IngressRuleResponseHeaders:
+			for name, value := range rule.Headers {
+				headers[name] = value
+			}
+		}
+	}
+	if len(headers) > 0 {
+		annotations[nginxPrefix+"custom-headers"] = backend.Namespace + "/" + HeadersName(ingress)
+		objects = append(objects, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   metadata(HeadersName(ingress), backend.Namespace, ingress, nil),
+			"data":       headers,
+		})
+	}
+
+	for i, rule := range ingress.Routing.Rules {
+		switch rule.Type {
+		case domain.IngressRuleRedirectHost:
+			code := "307"
+			if rule.Permanent {
+				code = "308"
+			}
+			redirect := map[string]interface{}{
+				nginxPrefix + "permanent-redirect":      scheme(ingress) + "://" + ingress.Domain + "$request_uri",
+				nginxPrefix + "permanent-redirect-code": code,
+			}
+			objects = append(objects, nginxIngress(cfg, RuleName(ingress, i), strings.ToLower(rule.Host), ingress, backend,
+				nginxPath("/", "Prefix", backend), redirect))
+		case domain.IngressRuleRewrite:
+			// A location of its own, sharing the options of the ingress;
+			// ingress-nginx anchors regex paths itself
+			rewrite := make(map[string]interface{}, len(annotations)+2)
+			for k, v := range annotations {
+				rewrite[k] = v
+			}
+			rewrite[nginxPrefix+"use-regex"] = "true"
+			rewrite[nginxPrefix+"rewrite-target"] = rule.Replacement
+			objects = append(objects, nginxIngress(cfg, RuleName(ingress, i), ingress.Domain, ingress, backend,
+				nginxPath(strings.TrimPrefix(rule.Regex, "^"), "ImplementationSpecific", backend), rewrite))
+		}
+	}
+	return objects
+}
+
+// traefikRules renders a Middleware per routing rule of an ingress, in rule
+// order. The HTTPS redirect is returned separately: it belongs on the plain
+// HTTP route.
+func traefikRules(ingress *domain.Ingress, namespace string) (objects []map[string]interface{}, middlewares []interface{}, httpsRedirect map[string]interface{}) {
+	for i, rule := range ingress.Routing.Rules {
+		var spec map[string]interface{}
+		switch rule.Type {
+		case domain.IngressRuleRedirectHost:
+			spec = map[string]interface{}{"redirectRegex": map[string]interface{}{
+				"regex":       `^https?://` + regexp.QuoteMeta(strings.ToLower(rule.Host)) + `(:\d+)?/(.*)`,
+				"replacement": scheme(ingress) + "://" + ingress.Domain + "/${2}",
+				"permanent":   rule.Permanent,
+			}}
+		case domain.IngressRuleForceHTTPS:
+			spec = map[string]interface{}{"redirectScheme": map[string]interface{}{
+				"scheme":    "https",
+				"permanent": rule.Permanent,
+			}}
+		case domain.IngressRuleRewrite:
+			spec = map[string]interface{}{"replacePathRegex": map[string]interface{}{
+				"regex":       rule.Regex,
+				"replacement": rule.Replacement,
+			}}
+		case domain.IngressRuleResponseHeaders:
+			headers := make(map[string]interface{}, len(rule.Headers))
+			for name, value := range rule.Headers {
+				headers[name] = value
+			}
+			spec = map[string]interface{}{"headers": map[string]interface{}{"customResponseHeaders": headers}}
+		default:
+			continue
+		}
+
+		name := RuleName(ingress, i)
+		objects = append(objects, middleware(name, namespace, ingress, spec))
+		ref := map[string]interface{}{"name": name, "namespace": namespace}
+		if rule.Type == domain.IngressRuleForceHTTPS {
+			httpsRedirect = ref
+			continue
+		}
+		middlewares = append(middlewares, ref)
+	}
+	return objects, middlewares, httpsRedirect
+}
+
+// traefikMatch matches the hosts of an ingress under its path
+func traefikMatch(ingress *domain.Ingress) string {
+	hosts := ingress.Hosts()
+	matches := make([]string, len(hosts))
+	for i, host := range hosts {
+		matches[i] = fmt.Sprintf("Host(`%s`)", strings.ToLower(host))
+	}
+	match := strings.Join(matches, " || ")
+	if len(hosts) > 1 {
+		match = "(" + match + ")"
+	}
+	if ingress.Path != "/" {
+		match += fmt.Sprintf(" && PathPrefix(`%s`)", ingress.Path)
+	}
+	return match
+}
+
+func scheme(ingress *domain.Ingress) string {
+	if ingress.TLS.Enabled {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,278 @@
+// Package internalnet gives services with internal exposure a stable DNS name,
+// <service>.<project>.internal, resolvable from every pod of the cluster they
+// run in, and optionally isolates them with a NetworkPolicy that admits only
+// the services of their project they list. Names are served by a CoreDNS
+// server block the platform writes into the CoreDNS custom ConfigMap of each
+// workload cluster.
+package internalnet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Manager maintains the internal names and network policies of services
+type Manager struct {
+	config      *config.InternalNetworkConfig
+	kube        domain.KubernetesClient
+	serviceRepo domain.ServiceRepository
+	projectRepo domain.ProjectRepository
+	ingressRepo domain.IngressRepository
+	logger      *logger.Logger
+}
+
+// NewManager creates a new Manager. Without a Kubernetes client, internal
+// names are recorded but not served.
+func NewManager(
+	cfg *config.InternalNetworkConfig,
+	kube domain.KubernetesClient,
+	serviceRepo domain.ServiceRepository,
+	projectRepo domain.ProjectRepository,
+	ingressRepo domain.IngressRepository,
+	log *logger.Logger,
+) *Manager {
+	return &Manager{
+		config:      cfg,
+		kube:        kube,
+		serviceRepo: serviceRepo,
+		projectRepo: projectRepo,
+		ingressRepo: ingressRepo,
+		logger:      log,
+	}
+}
+
+// Prepare checks the internal networking of a service against its project
+// and records its internal DNS name. Services routed by ingresses cannot be
+// made internal.
+func (m *Manager) Prepare(ctx context.Context, svc *domain.Service) error {
+	n := svc.Networking
+	if n == nil {
+		return nil
+	}
+	n.DNSName = ""
+	if !Internal(svc) {
+		return nil
+	}
+
+	for _, slug := range n.AllowFrom {
+		if _, err := m.serviceRepo.GetBySlug(ctx, svc.ProjectID, slug); err != nil {
+			if errors.IsNotFound(err) {
+				return errors.BadRequest(fmt.Sprintf("allow_from: service %s is not in the project", slug))
+			}
+			return err
+		}
+	}
+	if m.ingressRepo != nil {
+		ingresses, err := m.ingressRepo.ListByService(ctx, svc.ID)
+		if err != nil {
+			return err
+		}
+		if len(ingresses) > 0 {
+			return errors.NewError(errors.CodeConflict, "the service is routed by ingresses; delete them before making it internal", http.StatusConflict)
+		}
+	}
+
+	project, err := m.projectRepo.GetByID(ctx, svc.ProjectID)
+	if err != nil {
+		return err
+	}
+	n.DNSName = DNSName(m.config, project, svc)
+	return nil
+}
+
+// Apply isolates a service as its networking asks and refreshes the internal
+// names of its cluster. Services not deployed yet are picked up by the
+// reconcile loop.
+func (m *Manager) Apply(ctx context.Context, svc *domain.Service) error {
+	if m.kube == nil || svc.TargetClusterID == nil {
+		return nil
+	}
+	if err := m.applyPolicy(ctx, svc); err != nil {
+		return err
+	}
+	return m.syncCluster(ctx, *svc.TargetClusterID)
+}
+
+// Remove deletes the network policy of a deleted service and drops its name
+func (m *Manager) Remove(ctx context.Context, svc *domain.Service) error {
+	if m.kube == nil || svc.TargetClusterID == nil {
+		return nil
+	}
+	target, err := m.kubeService(ctx, svc)
+	if err != nil {
+		return err
+	}
+	if target != nil {
+		if err := m.deletePolicy(ctx, svc, target.namespace); err != nil {
+			return err
+		}
+	}
+	return m.syncCluster(ctx, *svc.TargetClusterID)
+}
+
+// Run reconciles the policies and names of every cluster until ctx is
+// cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.reconcile(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Manager) reconcile(ctx context.Context) {
+	if m.kube == nil {
+		return
+	}
+	byCluster, err := m.servicesByCluster(ctx)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list services for internal networking")
+		return
+	}
+	for clusterID, services := range byCluster {
+		for _, svc := range services {
+			if err := m.applyPolicy(ctx, svc); err != nil {
+				m.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Failed to apply service network policy")
+			}
+		}
+		if err := m.syncDNS(ctx, clusterID, services); err != nil {
+			m.logger.Warn().Err(err).Str("cluster_id", clusterID.String()).Msg("Failed to sync internal DNS names")
+		}
+	}
+}
+
+// applyPolicy applies the NetworkPolicy of an isolated internal service and
+// deletes it from any other
+func (m *Manager) applyPolicy(ctx context.Context, svc *domain.Service) error {
+	target, err := m.kubeService(ctx, svc)
+	if err != nil || target == nil {
+		return err
+	}
+	if !Internal(svc) || !svc.Networking.Isolated {
+		return m.deletePolicy(ctx, svc, target.namespace)
+	}
+
+	var allowed []string
+	for _, slug := range svc.Networking.AllowFrom {
+		peer, err := m.serviceRepo.GetBySlug(ctx, svc.ProjectID, slug)
+		if errors.IsNotFound(err) {
+			continue // Deleted since; admits nothing
+		}
+		if err != nil {
+			return err
+		}
+		allowed = append(allowed, peer.ID.String())
+	}
+
+	manifest, err := json.Marshal(Policy(svc, target.namespace, allowed))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode NetworkPolicy")
+	}
+	if err := m.kube.ApplyManifest(ctx, *svc.TargetClusterID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+func (m *Manager) deletePolicy(ctx context.Context, svc *domain.Service, namespace string) error {
+	if err := m.kube.DeleteResource(ctx, *svc.TargetClusterID, "NetworkPolicy", namespace, PolicyName(svc)); err != nil && !errors.IsNotFound(err) {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+func (m *Manager) syncCluster(ctx context.Context, clusterID uuid.UUID) error {
+	byCluster, err := m.servicesByCluster(ctx)
+	if err != nil {
+		return err
+	}
+	return m.syncDNS(ctx, clusterID, byCluster[clusterID])
+}
+
+// syncDNS writes the internal names of the services running in a cluster
+func (m *Manager) syncDNS(ctx context.Context, clusterID uuid.UUID, services []*domain.Service) error {
+	var records []Record
+	for _, svc := range services {
+		if !Internal(svc) || svc.Networking.DNSName == "" {
+			continue
+		}
+		target, err := m.kubeService(ctx, svc)
+		if err != nil {
+			return err
+		}
+		if target != nil {
+			records = append(records, Record{Name: svc.Networking.DNSName, Service: target.name, Namespace: target.namespace})
+		}
+	}
+
+	manifest, err := json.Marshal(ConfigMap(m.config, records))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode CoreDNS ConfigMap")
+	}
+	if err := m.kube.ApplyManifest(ctx, clusterID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+// servicesByCluster groups the services of every project by the cluster they
+// are deployed to
+func (m *Manager) servicesByCluster(ctx context.Context) (map[uuid.UUID][]*domain.Service, error) {
+	projects, err := m.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		return nil, err
+	}
+	byCluster := make(map[uuid.UUID][]*domain.Service)
+	for _, project := range projects {
+		services, err := m.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			return nil, err
+		}
+		for _, svc := range services {
+			if svc.TargetClusterID != nil {
+				byCluster[*svc.TargetClusterID] = append(byCluster[*svc.TargetClusterID], svc)
+			}
+		}
+	}
+	return byCluster, nil
+}
+
+type kubeService struct {
+	name      string
+	namespace string
+}
+
+// kubeService finds the Kubernetes Service of a service, or nil when it has
+// none yet
+func (m *Manager) kubeService(ctx context.Context, svc *domain.Service) (*kubeService, error) {
+	objects, err := m.kube.ListResources(ctx, *svc.TargetClusterID, "Service", "", map[string]string{
+		domain.LabelServiceID: svc.ID.String(),
+	})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+	out := &kubeService{}
+	out.name, _, _ = unstructured.NestedString(objects[0], "metadata", "name")
+	out.namespace, _, _ = unstructured.NestedString(objects[0], "metadata", "namespace")
+	return out, nil
+}
@@ -0,0 +1,137 @@
+package internalnet
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// serverFile is the key of the CoreDNS custom ConfigMap holding the server
+// block of internal names
+const serverFile = "northstack.server"
+
+// Validate checks the exposure options of a service's networking
+func Validate(n *domain.ServiceNetworking) error {
+	if n == nil {
+		return nil
+	}
+	switch n.Exposure {
+	case "", domain.ServiceExposurePublic, domain.ServiceExposureInternal:
+	default:
+		return errors.BadRequest(fmt.Sprintf("exposure must be %s or %s", domain.ServiceExposurePublic, domain.ServiceExposureInternal))
+	}
+	if n.Exposure != domain.ServiceExposureInternal && (n.Isolated || len(n.AllowFrom) > 0) {
+		return errors.BadRequest("isolated and allow_from only apply to internal services")
+	}
+	if len(n.AllowFrom) > 0 && !n.Isolated {
+		return errors.BadRequest("allow_from needs isolated; without it every service may connect")
+	}
+	return nil
+}
+
+// Internal reports whether a service is only reachable inside its cluster
+func Internal(svc *domain.Service) bool {
+	return svc.Networking != nil && svc.Networking.Exposure == domain.ServiceExposureInternal
+}
+
+// DNSName is the internal name of a service: <service>.<project>.<domain>
+func DNSName(cfg *config.InternalNetworkConfig, project *domain.Project, svc *domain.Service) string {
+	return strings.ToLower(svc.Slug + "." + project.Slug + "." + strings.Trim(cfg.Domain, "."))
+}
+
+// PolicyName is the name of the NetworkPolicy isolating a service
+func PolicyName(svc *domain.Service) string {
+	return "internal-" + svc.ID.String()
+}
+
+// Policy renders the NetworkPolicy admitting only the pods of the allowed
+// services, in any namespace, to the pods of a service. No allowed services
+// isolates it completely.
+func Policy(svc *domain.Service, namespace string, allowed []string) map[string]interface{} {
+	ingress := []interface{}{}
+	if len(allowed) > 0 {
+		values := make([]interface{}, len(allowed))
+		for i, id := range allowed {
+			values[i] = id
+		}
+		ingress = append(ingress, map[string]interface{}{
+			"from": []interface{}{
+				map[string]interface{}{
+					"namespaceSelector": map[string]interface{}{},
+					"podSelector": map[string]interface{}{
+						"matchExpressions": []interface{}{
+							map[string]interface{}{"key": domain.LabelServiceID, "operator": "In", "values": values},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "NetworkPolicy",
+		"metadata": map[string]interface{}{
+			"name":      PolicyName(svc),
+			"namespace": namespace,
+			"labels": map[string]interface{}{
+				domain.LabelServiceID: svc.ID.String(),
+				domain.LabelProjectID: svc.ProjectID.String(),
+				domain.LabelManagedBy: domain.ManagedByValue,
+			},
+		},
+		"spec": map[string]interface{}{
+			"podSelector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{domain.LabelServiceID: svc.ID.String()},
+			},
+			"policyTypes": []interface{}{"Ingress"},
+			"ingress":     ingress,
+		},
+	}
+}
+
+// Record maps an internal name to the Kubernetes Service answering it
+type Record struct {
+	Name      string // api.shop.internal
+	Service   string
+	Namespace string
+}
+
+// ServerBlock renders the CoreDNS server block answering the internal zone.
+// Each name is rewritten to its Service's cluster name and back in answers;
+// the regex form works with every CoreDNS release.
+func ServerBlock(cfg *config.InternalNetworkConfig, records []Record) string {
+	sorted := append([]Record(nil), records...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:53 {\n    errors\n    cache 30\n", strings.Trim(cfg.Domain, "."))
+	for _, r := range sorted {
+		target := fmt.Sprintf("%s.%s.svc.%s.", r.Service, r.Namespace, strings.Trim(cfg.ClusterDomain, "."))
+		fmt.Fprintf(&b, "    rewrite stop {\n        name regex ^%s\\.$ %s\n        answer name ^%s$ %s.\n    }\n",
+			regexp.QuoteMeta(r.Name), target, regexp.QuoteMeta(target), r.Name)
+	}
+	fmt.Fprintf(&b, "    kubernetes %s\n}\n", strings.Trim(cfg.ClusterDomain, "."))
+	return b.String()
+}
+
+// ConfigMap renders the CoreDNS custom ConfigMap entry holding the server
+// block. Server-side apply leaves the other entries of the ConfigMap alone.
+func ConfigMap(cfg *config.InternalNetworkConfig, records []Record) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      cfg.CoreDNSConfigMap,
+			"namespace": cfg.CoreDNSNamespace,
+		},
+		"data": map[string]interface{}{
+			serverFile: ServerBlock(cfg, records),
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package internalnet
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var testConfig = &config.InternalNetworkConfig{
+	Domain:           "internal",
+	ClusterDomain:    "cluster.local",
+	CoreDNSNamespace: "kube-system",
+	CoreDNSConfigMap: "coredns-custom",
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(nil))
+	assert.NoError(t, Validate(&domain.ServiceNetworking{Exposure: domain.ServiceExposureInternal, Isolated: true, AllowFrom: []string{"web"}}))
+	assert.NoError(t, Validate(&domain.ServiceNetworking{Exposure: domain.ServiceExposureInternal, Isolated: true}))
+	assert.Error(t, Validate(&domain.ServiceNetworking{Exposure: "private"}))
+	assert.Error(t, Validate(&domain.ServiceNetworking{Isolated: true}))
+	assert.Error(t, Validate(&domain.ServiceNetworking{Exposure: domain.ServiceExposureInternal, AllowFrom: []string{"web"}}))
+}
+
+func TestDNSName(t *testing.T) {
+	project := &domain.Project{Slug: "shop"}
+	svc := &domain.Service{Slug: "API"}
+	assert.Equal(t, "api.shop.internal", DNSName(testConfig, project, svc))
+}
+
+func TestPolicy(t *testing.T) {
+	svc := &domain.Service{ID: uuid.New(), ProjectID: uuid.New()}
+	peer := uuid.New().String()
+
+	policy := Policy(svc, "team-a", []string{peer})
+	rules, _, _ := unstructured.NestedSlice(policy, "spec", "ingress")
+	from := rules[0].(map[string]interface{})["from"].([]interface{})
+	expressions, _, _ := unstructured.NestedSlice(from[0].(map[string]interface{}), "podSelector", "matchExpressions")
+	assert.Equal(t, []interface{}{peer}, expressions[0].(map[string]interface{})["values"])
+
+	policy = Policy(svc, "team-a", nil)
+	rules, found, _ := unstructured.NestedSlice(policy, "spec", "ingress")
+	assert.True(t, found)
+	assert.Empty(t, rules) // Denies all ingress
+}
+
+func TestServerBlock(t *testing.T) {
+	block := ServerBlock(testConfig, []Record{
+		{Name: "web.shop.internal", Service: "web", Namespace: "shop-prod"},
+		{Name: "api.shop.internal", Service: "api", Namespace: "shop-prod"},
+	})
+	assert.Equal(t, `internal:53 {
+    errors
+    cache 30
+    rewrite stop {
+        name regex ^api\.shop\.internal\.$ api.shop-prod.svc.cluster.local.
+        answer name ^api\.shop-prod\.svc\.cluster\.local\.$ api.shop.internal.
+    }
+    rewrite stop {
+        name regex ^web\.shop\.internal\.$ web.shop-prod.svc.cluster.local.
+        answer name ^web\.shop-prod\.svc\.cluster\.local\.$ web.shop.internal.
+    }
+    kubernetes cluster.local
+}
+`, block)
+}
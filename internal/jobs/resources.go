@@ -0,0 +1,181 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// LabelOneOff marks one-off Jobs and their pods, setting them apart from
+	// the runs of cron jobs
+	LabelOneOff = "openpaas.io/one-off-job"
+
+	annotationCommand     = "openpaas.io/command"
+	annotationTriggeredBy = "openpaas.io/triggered-by"
+
+	// maxNameLength bounds the names of Jobs, which label their pods
+	maxNameLength = 63
+)
+
+type object = map[string]interface{}
+
+// containerFields are the settings of the workload's container a job keeps;
+// probes, ports and lifecycle hooks make no sense for a command
+var containerFields = []string{"image", "imagePullPolicy", "env", "envFrom", "resources", "volumeMounts", "workingDir", "securityContext"}
+
+// podFields are the settings of the workload's pods a job keeps
+var podFields = []string{"volumes", "serviceAccountName", "imagePullSecrets", "securityContext", "nodeSelector", "tolerations"}
+
+// Workload is the Deployment, StatefulSet or CronJob running a service, whose
+// pods jobs are modelled on
+type Workload struct {
+	ClusterID uuid.UUID
+	Namespace string
+	PodSpec   map[string]interface{}
+	Container map[string]interface{} // The service's container in PodSpec
+}
+
+// Name names a job of a service started at now
+func Name(svc *domain.Service, now time.Time) string {
+	suffix := fmt.Sprintf("-job-%d-%s", now.Unix(), uuid.NewString()[:4])
+	name := svc.Slug
+	if len(name)+len(suffix) > maxNameLength {
+		name = strings.TrimRight(name[:maxNameLength-len(suffix)], "-")
+	}
+	return name + suffix
+}
+
+// Job renders a Job running a command with the image, environment, secrets
+// and volumes of the service's workload. Its pods carry none of the
+// workload's labels, so no Service routes traffic to them.
+func Job(svc *domain.Service, w *Workload, name string, req *Request, retention time.Duration) object {
+	container := object{"name": containerName(w.Container), "command": req.Command}
+	for _, key := range containerFields {
+		if v, ok := w.Container[key]; ok {
+			container[key] = v
+		}
+	}
+
+	podSpec := object{"restartPolicy": "Never"}
+	for _, key := range podFields {
+		if v, ok := w.PodSpec[key]; ok {
+			podSpec[key] = v
+		}
+	}
+	container["volumeMounts"] = mounts(container["volumeMounts"], podSpec["volumes"])
+	if container["volumeMounts"] == nil {
+		delete(container, "volumeMounts")
+	}
+	podSpec["containers"] = []interface{}{container}
+
+	command, _ := json.Marshal(req.Command)
+	annotations := object{annotationCommand: string(command)}
+	if req.TriggeredBy != "" {
+		annotations[annotationTriggeredBy] = req.TriggeredBy
+	}
+
+	spec := object{
+		"backoffLimit":          int64(req.Retries),
+		"activeDeadlineSeconds": req.TimeoutSeconds,
+		"template": object{
+			"metadata": object{"labels": labels(svc)},
+			"spec":     podSpec,
+		},
+	}
+	if retention > 0 {
+		spec["ttlSecondsAfterFinished"] = int64(retention.Seconds())
+	}
+
+	return object{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata": object{
+			"name":        name,
+			"namespace":   w.Namespace,
+			"labels":      labels(svc),
+			"annotations": annotations,
+		},
+		"spec": spec,
+	}
+}
+
+// Run reads a one-off job from its Job and pods
+func Run(svc *domain.Service, job map[string]interface{}, pods []map[string]interface{}) *domain.JobRun {
+	state := Read(job, pods, jobContainer(job))
+
+	run := &domain.JobRun{
+		Name:        state.Name,
+		ServiceID:   svc.ID,
+		Status:      state.Status,
+		Pod:         state.Pod,
+		Attempts:    state.Attempts,
+		ExitCode:    state.ExitCode,
+		Reason:      state.Reason,
+		CreatedAt:   state.CreatedAt,
+		StartedAt:   state.StartedAt,
+		CompletedAt: state.CompletedAt,
+	}
+	if raw, _, _ := unstructured.NestedString(job, "metadata", "annotations", annotationCommand); raw != "" {
+		json.Unmarshal([]byte(raw), &run.Command)
+	}
+	run.TriggeredBy, _, _ = unstructured.NestedString(job, "metadata", "annotations", annotationTriggeredBy)
+	run.TimeoutSeconds, _, _ = unstructured.NestedInt64(job, "spec", "activeDeadlineSeconds")
+	retries, _, _ := unstructured.NestedInt64(job, "spec", "backoffLimit")
+	run.Retries = int32(retries)
+	return run
+}
+
+// mounts keeps the volume mounts whose volume the job's pods have; the
+// volume claim templates of StatefulSets are left behind
+func mounts(volumeMounts, volumes interface{}) interface{} {
+	mountList, _ := volumeMounts.([]interface{})
+	volumeList, _ := volumes.([]interface{})
+	names := make(map[interface{}]bool, len(volumeList))
+	for _, v := range volumeList {
+		if volume, ok := v.(map[string]interface{}); ok {
+			names[volume["name"]] = true
+		}
+	}
+
+	var kept []interface{}
+	for _, m := range mountList {
+		if mount, ok := m.(map[string]interface{}); ok && names[mount["name"]] {
+			kept = append(kept, mount)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// jobContainer is the name of the container of a job's pods
+func jobContainer(job map[string]interface{}) string {
+	containers, _, _ := unstructured.NestedSlice(job, "spec", "template", "spec", "containers")
+	if len(containers) == 0 {
+		return ""
+	}
+	container, _ := containers[0].(map[string]interface{})
+	return containerName(container)
+}
+
+func containerName(container map[string]interface{}) string {
+	name, _ := container["name"].(string)
+	return name
+}
+
+// labels identify the one-off jobs of a service and their pods
+func labels(svc *domain.Service) object {
+	return object{
+		domain.LabelServiceID: svc.ID.String(),
+		domain.LabelProjectID: svc.ProjectID.String(),
+		domain.LabelManagedBy: domain.ManagedByValue,
+		LabelOneOff:           "true",
+	}
+}
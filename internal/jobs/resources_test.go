@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestName(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	name := Name(&domain.Service{Slug: "api"}, now)
+	assert.True(t, strings.HasPrefix(name, "api-job-1700000000-"), name)
+
+	long := Name(&domain.Service{Slug: strings.Repeat("a", 70)}, now)
+	assert.LessOrEqual(t, len(long), maxNameLength)
+}
+
+func TestJob(t *testing.T) {
+	svc := &domain.Service{ID: uuid.New(), ProjectID: uuid.New(), Slug: "api"}
+	w := &Workload{
+		Namespace: "shop-production",
+		PodSpec: object{
+			"serviceAccountName": "api",
+			"volumes":            []interface{}{object{"name": "config", "configMap": object{"name": "api"}}},
+			"containers":         []interface{}{},
+		},
+		Container: object{
+			"name":           "api",
+			"image":          "registry.example.com/shop/api:v3",
+			"args":           []interface{}{"serve"},
+			"envFrom":        []interface{}{object{"secretRef": object{"name": "api-db"}}},
+			"readinessProbe": object{"httpGet": object{"path": "/healthz"}},
+			"volumeMounts": []interface{}{
+				object{"name": "config", "mountPath": "/etc/api"},
+				object{"name": "data", "mountPath": "/data"}, // From a volume claim template
+			},
+		},
+	}
+	req := &Request{Command: []string{"rails", "db:migrate"}, TimeoutSeconds: 600, Retries: 2, TriggeredBy: "user-1"}
+
+	obj := Job(svc, w, "api-job-1", req, 24*time.Hour)
+	metadata := obj["metadata"].(object)
+	assert.Equal(t, "api-job-1", metadata["name"])
+	assert.Equal(t, "shop-production", metadata["namespace"])
+	assert.Equal(t, `["rails","db:migrate"]`, metadata["annotations"].(object)[annotationCommand])
+	assert.Equal(t, "true", metadata["labels"].(object)[LabelOneOff])
+
+	spec := obj["spec"].(object)
+	assert.Equal(t, int64(2), spec["backoffLimit"])
+	assert.Equal(t, int64(600), spec["activeDeadlineSeconds"])
+	assert.Equal(t, int64(86400), spec["ttlSecondsAfterFinished"])
+
+	template := spec["template"].(object)
+	assert.Equal(t, labels(svc), template["metadata"].(object)["labels"])
+	podSpec := template["spec"].(object)
+	assert.Equal(t, "Never", podSpec["restartPolicy"])
+	assert.Equal(t, "api", podSpec["serviceAccountName"])
+
+	container := podSpec["containers"].([]interface{})[0].(object)
+	assert.Equal(t, "registry.example.com/shop/api:v3", container["image"])
+	assert.Equal(t, []string{"rails", "db:migrate"}, container["command"])
+	assert.NotContains(t, container, "args")
+	assert.NotContains(t, container, "readinessProbe")
+	assert.Equal(t, []interface{}{object{"name": "config", "mountPath": "/etc/api"}}, container["volumeMounts"])
+}
+
+func TestRun(t *testing.T) {
+	svc := &domain.Service{ID: uuid.New(), Slug: "api"}
+	job := object{
+		"metadata": object{
+			"name":              "api-job-1",
+			"creationTimestamp": "2024-05-01T10:00:00Z",
+			"annotations": object{
+				annotationCommand:     `["rails","db:migrate"]`,
+				annotationTriggeredBy: "user-1",
+			},
+		},
+		"spec": object{
+			"backoffLimit":          int64(1),
+			"activeDeadlineSeconds": int64(600),
+			"template": object{"spec": object{
+				"containers": []interface{}{object{"name": "api"}},
+			}},
+		},
+		"status": object{
+			"failed":    int64(2),
+			"startTime": "2024-05-01T10:00:01Z",
+			"conditions": []interface{}{
+				object{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded", "lastTransitionTime": "2024-05-01T10:02:00Z"},
+			},
+		},
+	}
+	pods := []map[string]interface{}{{
+		"metadata": object{"name": "api-job-1-b", "creationTimestamp": "2024-05-01T10:01:00Z"},
+		"status": object{"containerStatuses": []interface{}{
+			object{"name": "api", "state": object{"terminated": object{"exitCode": int64(1), "reason": "Error"}}},
+		}},
+	}}
+
+	run := Run(svc, job, pods)
+	assert.Equal(t, "api-job-1", run.Name)
+	assert.Equal(t, svc.ID, run.ServiceID)
+	assert.Equal(t, []string{"rails", "db:migrate"}, run.Command)
+	assert.Equal(t, "user-1", run.TriggeredBy)
+	assert.Equal(t, int64(600), run.TimeoutSeconds)
+	assert.Equal(t, int32(1), run.Retries)
+	assert.Equal(t, domain.JobFailed, run.Status)
+	assert.Equal(t, "BackoffLimitExceeded", run.Reason)
+	assert.Equal(t, int32(2), run.Attempts)
+	assert.Equal(t, "api-job-1-b", run.Pod)
+	require.NotNil(t, run.ExitCode)
+	assert.Equal(t, int32(1), *run.ExitCode)
+	require.NotNil(t, run.CompletedAt)
+	assert.Equal(t, "2024-05-01T10:02:00Z", run.CompletedAt.Format(time.RFC3339))
+}
@@ -0,0 +1,309 @@
+// Package jobs runs one-off commands against a service's image as Kubernetes
+// Jobs, such as database migrations, and reads back their status and logs.
+// Jobs run next to the service's workload with its image, environment,
+// secrets and volumes, and are cleaned up after a retention period. It also
+// reads the state of the Jobs of cron job services.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxRetries bounds the retries of a job, as it does those of cron job runs
+const maxRetries = 10
+
+// Request describes a one-off job
+type Request struct {
+	Command        []string
+	TimeoutSeconds int64 // 0 uses the configured default
+	Retries        int32
+	TriggeredBy    string
+}
+
+// Runner starts one-off jobs of services and reads their status and logs
+type Runner struct {
+	config *config.JobsConfig
+	kube   domain.KubernetesClient
+	logger *logger.Logger
+}
+
+// NewRunner creates a new Runner. kube may be nil, in which case jobs are
+// unavailable.
+func NewRunner(cfg *config.JobsConfig, kube domain.KubernetesClient, log *logger.Logger) *Runner {
+	return &Runner{
+		config: cfg,
+		kube:   kube,
+		logger: log,
+	}
+}
+
+// Validate checks a request and sets its default timeout
+func (r *Runner) Validate(req *Request) error {
+	if len(req.Command) == 0 || strings.TrimSpace(req.Command[0]) == "" {
+		return errors.BadRequest("command is required")
+	}
+	max := int64(r.config.MaxTimeout.Seconds())
+	if req.TimeoutSeconds < 0 || req.TimeoutSeconds > max {
+		return errors.BadRequest(fmt.Sprintf("timeout_seconds must be between 0 and %d", max))
+	}
+	if req.TimeoutSeconds == 0 {
+		req.TimeoutSeconds = int64(r.config.DefaultTimeout.Seconds())
+	}
+	if req.Retries < 0 || req.Retries > maxRetries {
+		return errors.BadRequest(fmt.Sprintf("retries must be between 0 and %d", maxRetries))
+	}
+	return nil
+}
+
+// Run starts a one-off job of a service. The service must be deployed: the
+// job copies the pod template of its workload.
+func (r *Runner) Run(ctx context.Context, svc *domain.Service, req *Request) (*domain.JobRun, error) {
+	if err := r.Validate(req); err != nil {
+		return nil, err
+	}
+	if r.kube == nil {
+		return nil, errors.ErrNoKubernetes
+	}
+	workload, err := r.workload(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	name := Name(svc, time.Now())
+	manifest, err := json.Marshal(Job(svc, workload, name, req, r.config.Retention))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode Job")
+	}
+	if err := r.kube.ApplyManifest(ctx, workload.ClusterID, manifest); err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	r.logger.Info().
+		Str("service_id", svc.ID.String()).
+		Str("job", name).
+		Str("command", strings.Join(req.Command, " ")).
+		Str("triggered_by", req.TriggeredBy).
+		Msg("Started one-off job")
+
+	return &domain.JobRun{
+		Name:           name,
+		ServiceID:      svc.ID,
+		Command:        req.Command,
+		TimeoutSeconds: req.TimeoutSeconds,
+		Retries:        req.Retries,
+		TriggeredBy:    req.TriggeredBy,
+		Status:         domain.JobPending,
+		CreatedAt:      time.Now().UTC(),
+	}, nil
+}
+
+// List lists the one-off jobs of a service the cluster still keeps, newest
+// first
+func (r *Runner) List(ctx context.Context, svc *domain.Service) ([]*domain.JobRun, error) {
+	if svc.TargetClusterID == nil {
+		return []*domain.JobRun{}, nil
+	}
+	if r.kube == nil {
+		return nil, errors.ErrNoKubernetes
+	}
+	jobList, err := r.kube.ListResources(ctx, *svc.TargetClusterID, "Job", "", r.selector(svc))
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	pods, err := r.kube.ListResources(ctx, *svc.TargetClusterID, "Pod", "", r.selector(svc))
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	podsByJob := PodsByJob(pods)
+	runs := make([]*domain.JobRun, 0, len(jobList))
+	for _, job := range jobList {
+		name, _, _ := unstructured.NestedString(job, "metadata", "name")
+		runs = append(runs, Run(svc, job, podsByJob[name]))
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+	return runs, nil
+}
+
+// Get returns a one-off job of a service
+func (r *Runner) Get(ctx context.Context, svc *domain.Service, name string) (*domain.JobRun, error) {
+	job, err := r.job(ctx, svc, name)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := r.pods(ctx, svc, name)
+	if err != nil {
+		return nil, err
+	}
+	return Run(svc, job, pods), nil
+}
+
+// Logs returns the last lines logged by the latest attempt of a job, or the
+// configured number of lines when tailLines is 0
+func (r *Runner) Logs(ctx context.Context, svc *domain.Service, name string, tailLines int64) (string, error) {
+	job, err := r.job(ctx, svc, name)
+	if err != nil {
+		return "", err
+	}
+	pods, err := r.pods(ctx, svc, name)
+	if err != nil {
+		return "", err
+	}
+	pod := LatestPod(pods)
+	if pod == nil {
+		return "", nil // Not scheduled yet
+	}
+	podName, _, _ := unstructured.NestedString(pod, "metadata", "name")
+	namespace, _, _ := unstructured.NestedString(job, "metadata", "namespace")
+
+	if tailLines <= 0 {
+		tailLines = r.config.LogTailLines
+	}
+	logs, err := r.kube.GetPodLogs(ctx, *svc.TargetClusterID, namespace, podName, jobContainer(job), tailLines)
+	if err != nil {
+		return "", errors.DependencyFailed("kubernetes", err)
+	}
+	return logs, nil
+}
+
+// Cancel stops a running job. Its deadline is moved into the past, so the Job
+// controller terminates its pods and marks it failed; the job and its logs
+// are kept until the retention period ends.
+func (r *Runner) Cancel(ctx context.Context, svc *domain.Service, name string) (*domain.JobRun, error) {
+	job, err := r.job(ctx, svc, name)
+	if err != nil {
+		return nil, err
+	}
+	if status := Read(job, nil, "").Status; status == domain.JobSucceeded || status == domain.JobFailed {
+		return nil, errors.NewError(errors.CodeConflict, "the job has already finished", http.StatusConflict)
+	}
+
+	unstructured.SetNestedField(job, int64(1), "spec", "activeDeadlineSeconds")
+	unstructured.RemoveNestedField(job, "metadata", "managedFields")
+	unstructured.RemoveNestedField(job, "status")
+	manifest, err := json.Marshal(job)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode Job")
+	}
+	if err := r.kube.ApplyManifest(ctx, *svc.TargetClusterID, manifest); err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	r.logger.Info().Str("service_id", svc.ID.String()).Str("job", name).Msg("Cancelled one-off job")
+	return r.Get(ctx, svc, name)
+}
+
+// job finds a one-off job of a service by name in any namespace of its
+// target cluster
+func (r *Runner) job(ctx context.Context, svc *domain.Service, name string) (map[string]interface{}, error) {
+	if svc.TargetClusterID == nil {
+		return nil, errors.NotFound("job", name)
+	}
+	if r.kube == nil {
+		return nil, errors.ErrNoKubernetes
+	}
+	jobList, err := r.kube.ListResources(ctx, *svc.TargetClusterID, "Job", "", r.selector(svc))
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	for _, job := range jobList {
+		if jobName, _, _ := unstructured.NestedString(job, "metadata", "name"); jobName == name {
+			return job, nil
+		}
+	}
+	return nil, errors.NotFound("job", name)
+}
+
+func (r *Runner) pods(ctx context.Context, svc *domain.Service, name string) ([]map[string]interface{}, error) {
+	selector := r.selector(svc)
+	selector[LabelJobName] = name
+	pods, err := r.kube.ListResources(ctx, *svc.TargetClusterID, "Pod", "", selector)
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	return pods, nil
+}
+
+func (r *Runner) selector(svc *domain.Service) map[string]string {
+	return map[string]string{
+		domain.LabelServiceID: svc.ID.String(),
+		LabelOneOff:           "true",
+	}
+}
+
+// workloadKinds are the kinds of workloads jobs are modelled on, with the path
+// to their pod spec
+var workloadKinds = []struct {
+	kind string
+	path []string
+}{
+	{"Deployment", []string{"spec", "template", "spec"}},
+	{"StatefulSet", []string{"spec", "template", "spec"}},
+	{"CronJob", []string{"spec", "jobTemplate", "spec", "template", "spec"}},
+}
+
+// workload finds the workload running a service on its target cluster
+func (r *Runner) workload(ctx context.Context, svc *domain.Service) (*Workload, error) {
+	if svc.TargetClusterID == nil {
+		return nil, errors.BadRequest("the service is not deployed yet: it has no target cluster")
+	}
+	selector := map[string]string{domain.LabelServiceID: svc.ID.String()}
+	for _, w := range workloadKinds {
+		objects, err := r.kube.ListResources(ctx, *svc.TargetClusterID, w.kind, "", selector)
+		if err != nil {
+			return nil, errors.DependencyFailed("kubernetes", err)
+		}
+		for _, obj := range objects {
+			podSpec, ok, _ := unstructured.NestedMap(obj, w.path...)
+			if !ok {
+				continue
+			}
+			container := serviceContainer(svc, podSpec)
+			if container == nil {
+				continue
+			}
+			namespace, _, _ := unstructured.NestedString(obj, "metadata", "namespace")
+			return &Workload{
+				ClusterID: *svc.TargetClusterID,
+				Namespace: namespace,
+				PodSpec:   podSpec,
+				Container: container,
+			}, nil
+		}
+	}
+	return nil, errors.BadRequest("the service is not deployed yet: no workload runs it on its target cluster")
+}
+
+// serviceContainer is the container named after the service, or the first
+// one; sidecars are not what a command runs in
+func serviceContainer(svc *domain.Service, podSpec map[string]interface{}) map[string]interface{} {
+	containers, _, _ := unstructured.NestedSlice(podSpec, "containers")
+	var first map[string]interface{}
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if containerName(container) == svc.Slug {
+			return container
+		}
+		if first == nil {
+			first = container
+		}
+	}
+	return first
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerWithoutKubernetes(t *testing.T) {
+	clusterID := uuid.New()
+	svc := &domain.Service{ID: uuid.New(), TargetClusterID: &clusterID}
+	r := NewRunner(&config.JobsConfig{MaxTimeout: time.Hour, DefaultTimeout: time.Minute}, nil, logger.New("error", "json", io.Discard))
+	ctx := context.Background()
+
+	for _, err := range []error{
+		func() error { _, err := r.Run(ctx, svc, &Request{Command: []string{"migrate"}}); return err }(),
+		func() error { _, err := r.List(ctx, svc); return err }(),
+		func() error { _, err := r.Get(ctx, svc, "migrate-1"); return err }(),
+		func() error { _, err := r.Logs(ctx, svc, "migrate-1", 0); return err }(),
+		func() error { _, err := r.Cancel(ctx, svc, "migrate-1"); return err }(),
+	} {
+		assert.ErrorIs(t, err, errors.ErrNoKubernetes)
+	}
+}
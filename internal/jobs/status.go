@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// LabelJobName is set by the Job controller on the pods of a Job
+const LabelJobName = "job-name"
+
+// State is what a Kubernetes Job and its pods tell about a run
+type State struct {
+	Name        string
+	Status      domain.JobStatus
+	Pod         string // Pod of the latest attempt
+	Attempts    int32
+	ExitCode    *int32
+	Reason      string
+	CreatedAt   time.Time
+	StartedAt   *time.Time
+	CompletedAt *time.Time
+}
+
+// PodsByJob groups pods by the Job that created them
+func PodsByJob(pods []map[string]interface{}) map[string][]map[string]interface{} {
+	byJob := make(map[string][]map[string]interface{})
+	for _, pod := range pods {
+		job, _, _ := unstructured.NestedString(pod, "metadata", "labels", LabelJobName)
+		byJob[job] = append(byJob[job], pod)
+	}
+	return byJob
+}
+
+// Read reads the state of a Job from its status and the exit code of
+// container in the pod of its latest attempt
+func Read(job map[string]interface{}, pods []map[string]interface{}, container string) State {
+	name, _, _ := unstructured.NestedString(job, "metadata", "name")
+	active, _, _ := unstructured.NestedInt64(job, "status", "active")
+	succeeded, _, _ := unstructured.NestedInt64(job, "status", "succeeded")
+	failed, _, _ := unstructured.NestedInt64(job, "status", "failed")
+
+	state := State{
+		Name:      name,
+		Status:    domain.JobPending,
+		Attempts:  int32(active + succeeded + failed),
+		CreatedAt: Timestamp(job, "metadata", "creationTimestamp"),
+	}
+	if t := Timestamp(job, "status", "startTime"); !t.IsZero() {
+		state.StartedAt = &t
+	}
+	if t := Timestamp(job, "status", "completionTime"); !t.IsZero() {
+		state.CompletedAt = &t
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(job, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["status"] != "True" {
+			continue
+		}
+		switch cond["type"] {
+		case "Complete":
+			state.Status = domain.JobSucceeded
+		case "Failed":
+			state.Status = domain.JobFailed
+			state.Reason, _ = cond["reason"].(string)
+			if state.CompletedAt == nil {
+				if t := Timestamp(cond, "lastTransitionTime"); !t.IsZero() {
+					state.CompletedAt = &t
+				}
+			}
+		}
+	}
+	if state.Status == domain.JobPending && active > 0 {
+		state.Status = domain.JobRunning
+	}
+
+	pod := LatestPod(pods)
+	if pod == nil {
+		return state
+	}
+	state.Pod, _, _ = unstructured.NestedString(pod, "metadata", "name")
+	statuses, _, _ := unstructured.NestedSlice(pod, "status", "containerStatuses")
+	for _, s := range statuses {
+		status, ok := s.(map[string]interface{})
+		if !ok || status["name"] != container {
+			continue
+		}
+		if terminated, ok, _ := unstructured.NestedMap(status, "state", "terminated"); ok {
+			if code, ok, _ := unstructured.NestedInt64(terminated, "exitCode"); ok {
+				exit := int32(code)
+				state.ExitCode = &exit
+			}
+			if reason, _ := terminated["reason"].(string); reason != "" && state.Reason == "" && state.Status != domain.JobSucceeded {
+				state.Reason = reason
+			}
+		}
+	}
+	return state
+}
+
+// LatestPod returns the most recently created pod
+func LatestPod(pods []map[string]interface{}) map[string]interface{} {
+	var latest map[string]interface{}
+	var latestAt time.Time
+	for _, pod := range pods {
+		if t := Timestamp(pod, "metadata", "creationTimestamp"); latest == nil || t.After(latestAt) {
+			latest, latestAt = pod, t
+		}
+	}
+	return latest
+}
+
+// Timestamp reads an RFC 3339 time at path, zero when absent
+func Timestamp(obj map[string]interface{}, path ...string) time.Time {
+	raw, _, _ := unstructured.NestedString(obj, path...)
+	t, _ := time.Parse(time.RFC3339, raw)
+	return t
+}
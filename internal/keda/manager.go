@@ -0,0 +1,156 @@
+// Package keda renders a service's autoscaling triggers beyond CPU and memory
+// (HTTP request rate from ingress metrics, NATS JetStream or Kafka consumer lag)
+// as a KEDA ScaledObject, after checking that KEDA and each metric source are
+// actually available in the target cluster.
+package keda
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/scheduledscaling"
+	"github.com/northstack/platform/internal/warmpool"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// workload is the Deployment backing a service
+type workload struct {
+	clusterID uuid.UUID
+	name      string
+	namespace string
+}
+
+// Manager applies ScaledObjects to workload clusters
+type Manager struct {
+	kube     domain.KubernetesClient
+	config   *config.KEDAConfig
+	warmPool *warmpool.Pool
+	logger   *logger.Logger
+}
+
+// NewManager creates a new Manager. warmPool may be nil, in which case warm
+// standby replicas do not raise the minimum.
+func NewManager(kube domain.KubernetesClient, cfg *config.KEDAConfig, warmPool *warmpool.Pool, log *logger.Logger) *Manager {
+	return &Manager{
+		kube:     kube,
+		config:   cfg,
+		warmPool: warmPool,
+		logger:   log,
+	}
+}
+
+// CheckAvailability verifies that KEDA and the metric source of every trigger
+// exist in the cluster
+func (m *Manager) CheckAvailability(ctx context.Context, clusterID uuid.UUID, triggers []domain.ScalingTrigger) error {
+	if len(triggers) == 0 {
+		return nil
+	}
+
+	if !m.exists(ctx, clusterID, "CustomResourceDefinition", "", crdName) {
+		return errors.BadRequest("KEDA is not installed in the target cluster")
+	}
+
+	for i, t := range triggers {
+		switch t.Type {
+		case domain.ScalingTriggerHTTPRPS:
+			if !m.exists(ctx, clusterID, "IngressClass", "", m.config.IngressClass) {
+				return errors.BadRequest(fmt.Sprintf("trigger %d: ingress class %q not found in the target cluster", i, m.config.IngressClass))
+			}
+			if err := m.checkService(ctx, clusterID, m.config.PrometheusAddress); err != nil {
+				return errors.BadRequest(fmt.Sprintf("trigger %d: Prometheus %s", i, err.Error()))
+			}
+		case domain.ScalingTriggerNATSJetStream, domain.ScalingTriggerKafka:
+			if err := m.checkService(ctx, clusterID, t.Endpoint); err != nil {
+				return errors.BadRequest(fmt.Sprintf("trigger %d: %s %s", i, t.Type, err.Error()))
+			}
+		}
+	}
+	return nil
+}
+
+// Apply installs or updates the ScaledObject for the service, or removes it
+// when the service no longer has triggers. The replica range honours any
+// active scaling schedule and the warm standby floor.
+func (m *Manager) Apply(ctx context.Context, svc *domain.Service) error {
+	if svc.TargetClusterID == nil {
+		return nil
+	}
+
+	w, err := m.findWorkload(ctx, svc)
+	if err != nil {
+		return err
+	}
+
+	if len(svc.Scaling.Triggers) == 0 {
+		if !m.exists(ctx, w.clusterID, "ScaledObject", w.namespace, w.name) {
+			return nil
+		}
+		if err := m.kube.DeleteResource(ctx, w.clusterID, "ScaledObject", w.namespace, w.name); err != nil {
+			return errors.DependencyFailed("kubernetes", err)
+		}
+		m.logger.Info().Str("service_id", svc.ID.String()).Msg("Removed KEDA ScaledObject")
+		return nil
+	}
+
+	r := scheduledscaling.Resolve(ctx, svc, time.Now(), m.warmPool)
+	manifest, err := json.Marshal(scaledObject(svc, w, r, m.config))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode ScaledObject")
+	}
+	if err := m.kube.ApplyManifest(ctx, w.clusterID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+
+	m.logger.Info().
+		Str("service_id", svc.ID.String()).
+		Int("triggers", len(svc.Scaling.Triggers)).
+		Msg("Applied KEDA ScaledObject")
+	return nil
+}
+
+// checkService verifies that a cluster-local address points at an existing
+// Service. External addresses cannot be checked from the cluster API and are accepted.
+func (m *Manager) checkService(ctx context.Context, clusterID uuid.UUID, address string) error {
+	name, namespace, ok := clusterService(address)
+	if !ok {
+		return nil
+	}
+	if !m.exists(ctx, clusterID, "Service", namespace, name) {
+		return fmt.Errorf("service %s/%s not found in the target cluster", namespace, name)
+	}
+	return nil
+}
+
+func (m *Manager) exists(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) bool {
+	obj, err := m.kube.GetResource(ctx, clusterID, kind, namespace, name)
+	return err == nil && obj != nil
+}
+
+// findWorkload locates the Deployment running the service on its target cluster
+func (m *Manager) findWorkload(ctx context.Context, svc *domain.Service) (*workload, error) {
+	objects, err := m.kube.ListResources(ctx, *svc.TargetClusterID, "Deployment", "", map[string]string{
+		domain.LabelServiceID: svc.ID.String(),
+	})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	if len(objects) == 0 {
+		return nil, errors.NotFound("workload for service", svc.ID.String())
+	}
+
+	name, _, _ := unstructured.NestedString(objects[0], "metadata", "name")
+	namespace, _, _ := unstructured.NestedString(objects[0], "metadata", "namespace")
+
+	return &workload{
+		clusterID: *svc.TargetClusterID,
+		name:      name,
+		namespace: namespace,
+	}, nil
+}
@@ -0,0 +1,159 @@
+package keda
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/scheduledscaling"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+const (
+	apiVersion = "keda.sh/v1alpha1"
+	// crdName is the CustomResourceDefinition that indicates KEDA is installed
+	crdName = "scaledobjects.keda.sh"
+	// maxTriggers bounds the extra triggers per service
+	maxTriggers = 5
+)
+
+// ValidateTriggers checks that each trigger is complete
+func ValidateTriggers(triggers []domain.ScalingTrigger) error {
+	if len(triggers) > maxTriggers {
+		return errors.BadRequest(fmt.Sprintf("at most %d scaling triggers are allowed", maxTriggers))
+	}
+
+	for i, t := range triggers {
+		if t.Target <= 0 {
+			return errors.BadRequest(fmt.Sprintf("trigger %d: target must be positive", i))
+		}
+		switch t.Type {
+		case domain.ScalingTriggerHTTPRPS:
+		case domain.ScalingTriggerNATSJetStream:
+			if t.Endpoint == "" || t.Stream == "" || t.Consumer == "" {
+				return errors.BadRequest(fmt.Sprintf("trigger %d: nats_jetstream requires endpoint, stream and consumer", i))
+			}
+		case domain.ScalingTriggerKafka:
+			if t.Endpoint == "" || t.Topic == "" || t.ConsumerGroup == "" {
+				return errors.BadRequest(fmt.Sprintf("trigger %d: kafka requires endpoint, topic and consumer_group", i))
+			}
+		default:
+			return errors.BadRequest(fmt.Sprintf("trigger %d: unsupported type %q", i, t.Type))
+		}
+	}
+	return nil
+}
+
+// scaledObject renders the KEDA ScaledObject for a service's workload. CPU and
+// memory targets are kept as KEDA resource triggers so they keep working once
+// KEDA takes over the HorizontalPodAutoscaler.
+func scaledObject(svc *domain.Service, w *workload, r scheduledscaling.Range, cfg *config.KEDAConfig) map[string]interface{} {
+	triggers := []interface{}{}
+	if svc.Scaling.TargetCPU > 0 {
+		triggers = append(triggers, resourceTrigger("cpu", svc.Scaling.TargetCPU))
+	}
+	if svc.Scaling.TargetMemory > 0 {
+		triggers = append(triggers, resourceTrigger("memory", svc.Scaling.TargetMemory))
+	}
+	for _, t := range svc.Scaling.Triggers {
+		triggers = append(triggers, trigger(t, w, cfg))
+	}
+
+	metadata := map[string]interface{}{
+		"name":      w.name,
+		"namespace": w.namespace,
+		"labels": map[string]interface{}{
+			domain.LabelServiceID: svc.ID.String(),
+			domain.LabelProjectID: svc.ProjectID.String(),
+			domain.LabelManagedBy: domain.ManagedByValue,
+		},
+	}
+	if annotations := r.Annotations(); annotations != nil {
+		metadata["annotations"] = annotations
+	}
+
+	return map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       "ScaledObject",
+		"metadata":   metadata,
+		"spec": map[string]interface{}{
+			"scaleTargetRef":  map[string]interface{}{"name": w.name},
+			"minReplicaCount": int64(r.Min),
+			"maxReplicaCount": int64(r.Max),
+			"triggers":        triggers,
+		},
+	}
+}
+
+func resourceTrigger(resource string, utilization int32) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       resource,
+		"metricType": "Utilization",
+		"metadata":   map[string]interface{}{"value": strconv.Itoa(int(utilization))},
+	}
+}
+
+func trigger(t domain.ScalingTrigger, w *workload, cfg *config.KEDAConfig) map[string]interface{} {
+	target := strconv.FormatFloat(t.Target, 'f', -1, 64)
+
+	switch t.Type {
+	case domain.ScalingTriggerHTTPRPS:
+		return map[string]interface{}{
+			"type": "prometheus",
+			"metadata": map[string]interface{}{
+				"serverAddress": cfg.PrometheusAddress,
+				"query":         rpsQuery(w),
+				"threshold":     target,
+			},
+		}
+	case domain.ScalingTriggerNATSJetStream:
+		return map[string]interface{}{
+			"type": "nats-jetstream",
+			"metadata": map[string]interface{}{
+				"natsServerMonitoringEndpoint": t.Endpoint,
+				"account":                      "$G",
+				"stream":                       t.Stream,
+				"consumer":                     t.Consumer,
+				"lagThreshold":                 target,
+			},
+		}
+	default: // Kafka
+		return map[string]interface{}{
+			"type": "kafka",
+			"metadata": map[string]interface{}{
+				"bootstrapServers": t.Endpoint,
+				"topic":            t.Topic,
+				"consumerGroup":    t.ConsumerGroup,
+				"lagThreshold":     target,
+			},
+		}
+	}
+}
+
+// rpsQuery is the request rate of the workload's Service as seen by the ingress controller
+func rpsQuery(w *workload) string {
+	return fmt.Sprintf(`sum(rate(nginx_ingress_controller_requests{exported_namespace=%q,service=%q}[2m]))`, w.namespace, w.name)
+}
+
+// clusterService extracts the Service referenced by a cluster-local address
+// such as nats.messaging.svc:8222 or http://prometheus.monitoring.svc.cluster.local
+func clusterService(address string) (name, namespace string, ok bool) {
+	host := address
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	if i := strings.IndexAny(host, ",/"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) < 3 || parts[2] != "svc" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
@@ -0,0 +1,65 @@
+package keda
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/scheduledscaling"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestValidateTriggers(t *testing.T) {
+	assert.NoError(t, ValidateTriggers([]domain.ScalingTrigger{
+		{Type: domain.ScalingTriggerHTTPRPS, Target: 50},
+		{Type: domain.ScalingTriggerKafka, Target: 100, Endpoint: "kafka.data.svc:9092", Topic: "orders", ConsumerGroup: "billing"},
+	}))
+	assert.Error(t, ValidateTriggers([]domain.ScalingTrigger{{Type: domain.ScalingTriggerHTTPRPS}}))
+	assert.Error(t, ValidateTriggers([]domain.ScalingTrigger{{Type: domain.ScalingTriggerNATSJetStream, Target: 10, Stream: "jobs"}}))
+	assert.Error(t, ValidateTriggers([]domain.ScalingTrigger{{Type: "sqs", Target: 10}}))
+}
+
+func TestClusterService(t *testing.T) {
+	name, ns, ok := clusterService("http://prometheus-server.monitoring.svc.cluster.local:80")
+	assert.True(t, ok)
+	assert.Equal(t, "prometheus-server", name)
+	assert.Equal(t, "monitoring", ns)
+
+	_, _, ok = clusterService("kafka-0.kafka.data.svc:9092,kafka-1.kafka.data.svc:9092")
+	assert.False(t, ok) // Pod DNS names are not Service addresses
+
+	name, ns, ok = clusterService("nats.messaging.svc:8222")
+	assert.True(t, ok)
+	assert.Equal(t, "nats", name)
+	assert.Equal(t, "messaging", ns)
+
+	_, _, ok = clusterService("broker.example.com:9092")
+	assert.False(t, ok)
+}
+
+func TestScaledObject(t *testing.T) {
+	svc := &domain.Service{
+		ID: uuid.New(),
+		Scaling: domain.ScalingConfig{
+			TargetCPU: 70,
+			Triggers:  []domain.ScalingTrigger{{Type: domain.ScalingTriggerHTTPRPS, Target: 25.5}},
+		},
+	}
+	w := &workload{name: "api", namespace: "shop"}
+
+	obj := scaledObject(svc, w, scheduledscaling.Range{Min: 2, Max: 10, Warm: "2"}, &config.KEDAConfig{PrometheusAddress: "http://prom.monitoring.svc"})
+
+	min, _, _ := unstructured.NestedInt64(obj, "spec", "minReplicaCount")
+	assert.Equal(t, int64(2), min)
+	warm, _, _ := unstructured.NestedString(obj, "metadata", "annotations", scheduledscaling.AnnotationWarmFloor)
+	assert.Equal(t, "2", warm)
+	triggers, _, _ := unstructured.NestedSlice(obj, "spec", "triggers")
+	if assert.Len(t, triggers, 2) {
+		rps := triggers[1].(map[string]interface{})
+		assert.Equal(t, "prometheus", rps["type"])
+		threshold, _, _ := unstructured.NestedString(rps, "metadata", "threshold")
+		assert.Equal(t, "25.5", threshold)
+	}
+}
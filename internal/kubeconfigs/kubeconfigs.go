@@ -0,0 +1,359 @@
+// Package kubeconfigs manages access to managed clusters. The admin
+// kubeconfig Rancher generates for a cluster is cached encrypted with a Vault
+// transit key and replaced, revoking the old credentials, before it expires.
+// Users get short-lived kubeconfigs limited to the namespaces of their
+// environments on the cluster instead of the admin kubeconfig.
+package kubeconfigs
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"sigs.k8s.io/yaml"
+)
+
+// MetadataCache is the cluster metadata key holding the cached admin kubeconfig
+const MetadataCache = "kubeconfig"
+
+// Cipher is the Vault transit engine encrypting cached kubeconfigs
+type Cipher interface {
+	CreateEncryptionKey(ctx context.Context, name string) error
+	TransitEncrypt(ctx context.Context, name string, plaintext []byte) (string, error)
+	TransitDecrypt(ctx context.Context, name, ciphertext string) ([]byte, error)
+}
+
+// cache is a cluster's admin kubeconfig as stored in its metadata
+type cache struct {
+	Ciphertext string    `json:"ciphertext"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Manager caches admin kubeconfigs and issues user kubeconfigs
+type Manager struct {
+	config      *config.KubeconfigsConfig
+	clusters    domain.ClusterManagerAdapter
+	cipher      Cipher
+	clusterRepo domain.ClusterRepository
+	envRepo     domain.EnvironmentRepository
+	projectRepo domain.ProjectRepository
+	teamRepo    domain.TeamRepository
+	logger      *logger.Logger
+
+	mu        sync.Mutex // Serializes rotations
+	keyExists bool
+}
+
+// NewManager creates a new Manager. teamRepo may be nil, in which case only
+// project owners have access to the namespaces of their environments.
+func NewManager(
+	cfg *config.KubeconfigsConfig,
+	clusters domain.ClusterManagerAdapter,
+	cipher Cipher,
+	clusterRepo domain.ClusterRepository,
+	envRepo domain.EnvironmentRepository,
+	projectRepo domain.ProjectRepository,
+	teamRepo domain.TeamRepository,
+	log *logger.Logger,
+) *Manager {
+	return &Manager{
+		config:      cfg,
+		clusters:    clusters,
+		cipher:      cipher,
+		clusterRepo: clusterRepo,
+		envRepo:     envRepo,
+		projectRepo: projectRepo,
+		teamRepo:    teamRepo,
+		logger:      log,
+	}
+}
+
+// Admin returns a cluster's admin kubeconfig, from the cache unless it is
+// due for rotation
+func (m *Manager) Admin(ctx context.Context, cluster *domain.Cluster) ([]byte, error) {
+	if cluster.RancherClusterID == "" {
+		return nil, errors.BadRequest("cluster is not managed by the platform")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cached := getCache(cluster)
+	if cached != nil && !m.due(cached, time.Now()) {
+		kubeconfig, err := m.cipher.TransitDecrypt(ctx, m.config.EncryptionKey, cached.Ciphertext)
+		if err == nil {
+			return kubeconfig, nil
+		}
+		m.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to decrypt cached kubeconfig")
+	}
+	return m.rotate(ctx, cluster.ID)
+}
+
+// RotateDue replaces the cached kubeconfigs that are about to expire
+func (m *Manager) RotateDue(ctx context.Context) error {
+	clusters, err := m.clusterRepo.List(ctx, domain.ClusterFilter{})
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, cluster := range clusters {
+		cached := getCache(cluster)
+		if cached == nil || !m.due(cached, now) || cluster.Status == domain.ClusterStatusDeleting {
+			continue
+		}
+		if _, err := m.rotate(ctx, cluster.ID); err != nil {
+			m.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to rotate kubeconfig")
+		}
+	}
+	return nil
+}
+
+// Run rotates cached kubeconfigs on the configured interval until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.RotateDue(ctx); err != nil {
+				m.logger.Warn().Err(err).Msg("Failed to check kubeconfigs for rotation")
+			}
+		}
+	}
+}
+
+func (m *Manager) due(cached *cache, now time.Time) bool {
+	return !now.Before(cached.ExpiresAt.Add(-m.config.RotateBefore))
+}
+
+// rotate fetches a new admin kubeconfig, caches it and revokes the one it
+// replaces. The caller holds m.mu.
+func (m *Manager) rotate(ctx context.Context, clusterID uuid.UUID) ([]byte, error) {
+	cluster, err := m.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	previous := getCache(cluster)
+
+	kubeconfig, err := m.clusters.GetKubeConfig(ctx, cluster.RancherClusterID)
+	if err != nil {
+		return nil, err
+	}
+	if !m.keyExists {
+		if err := m.cipher.CreateEncryptionKey(ctx, m.config.EncryptionKey); err != nil {
+			return nil, err
+		}
+		m.keyExists = true
+	}
+	ciphertext, err := m.cipher.TransitEncrypt(ctx, m.config.EncryptionKey, kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := Expiry(kubeconfig, now, m.config.MaxAge)
+	if cluster.Metadata == nil {
+		cluster.Metadata = make(map[string]interface{})
+	}
+	cluster.Metadata[MetadataCache] = &cache{Ciphertext: ciphertext, FetchedAt: now, ExpiresAt: expiresAt}
+	cluster.UpdatedAt = now
+	if err := m.clusterRepo.Update(ctx, cluster); err != nil {
+		return nil, err
+	}
+
+	if previous != nil {
+		if old, err := m.cipher.TransitDecrypt(ctx, m.config.EncryptionKey, previous.Ciphertext); err == nil {
+			if err := m.clusters.RevokeKubeConfig(ctx, cluster.RancherClusterID, old); err != nil {
+				m.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to revoke replaced kubeconfig")
+			}
+		}
+	}
+
+	m.logger.Info().
+		Str("cluster_id", cluster.ID.String()).
+		Time("expires_at", expiresAt).
+		Bool("replaced", previous != nil).
+		Msg("Cached cluster kubeconfig")
+	return kubeconfig, nil
+}
+
+// Issue returns a short-lived kubeconfig for a user. Admins get the admin
+// cluster role cluster-wide; other users get the user cluster role in the
+// namespaces of the environments on the cluster whose project they own or
+// whose team they belong to.
+func (m *Manager) Issue(ctx context.Context, cluster *domain.Cluster, userID uuid.UUID, admin bool, ttl time.Duration) (*domain.IssuedKubeConfig, *domain.KubeConfigGrant, error) {
+	if cluster.RancherClusterID == "" {
+		return nil, nil, errors.BadRequest("cluster is not managed by the platform")
+	}
+	if cluster.Status != domain.ClusterStatusActive {
+		return nil, nil, errors.BadRequest("cluster is not ready")
+	}
+	if ttl <= 0 {
+		ttl = m.config.UserTTL
+	}
+	if ttl > m.config.MaxUserTTL {
+		return nil, nil, errors.BadRequest("ttl exceeds the maximum of " + m.config.MaxUserTTL.String())
+	}
+
+	grant := &domain.KubeConfigGrant{Subject: "user-" + userID.String(), ClusterRole: m.config.AdminClusterRole, TTL: ttl}
+	if !admin {
+		namespaces, err := m.namespaces(ctx, cluster.ID, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(namespaces) == 0 {
+			return nil, nil, errors.Forbidden("no access to cluster " + cluster.ID.String())
+		}
+		grant.ClusterRole = m.config.UserClusterRole
+		grant.Namespaces = namespaces
+	}
+
+	issued, err := m.clusters.IssueKubeConfig(ctx, cluster.RancherClusterID, grant)
+	if err != nil {
+		return nil, nil, err
+	}
+	return issued, grant, nil
+}
+
+// namespaces lists the namespaces on a cluster a user has access to
+func (m *Manager) namespaces(ctx context.Context, clusterID, userID uuid.UUID) ([]string, error) {
+	envs, err := m.envRepo.ListByCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[uuid.UUID]bool)
+	var namespaces []string
+	for _, env := range envs {
+		ok, checked := allowed[env.ProjectID]
+		if !checked {
+			if ok, err = m.hasProjectAccess(ctx, env.ProjectID, userID); err != nil {
+				return nil, err
+			}
+			allowed[env.ProjectID] = ok
+		}
+		if ok && env.Namespace != "" {
+			namespaces = append(namespaces, env.Namespace)
+		}
+	}
+	return namespaces, nil
+}
+
+func (m *Manager) hasProjectAccess(ctx context.Context, projectID, userID uuid.UUID) (bool, error) {
+	project, err := m.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return false, err
+	}
+	if project.OwnerID == userID {
+		return true, nil
+	}
+	if project.TeamID == nil || m.teamRepo == nil {
+		return false, nil
+	}
+	members, err := m.teamRepo.GetMembers(ctx, *project.TeamID)
+	if err != nil {
+		return false, err
+	}
+	for _, member := range members {
+		if member.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Expiry returns when the credentials of a kubeconfig fetched at fetched
+// expire: the earliest expiry of its client certificates and JWT tokens, and
+// at most maxAge after it was fetched, as Rancher tokens carry no expiry
+func Expiry(kubeconfig []byte, fetched time.Time, maxAge time.Duration) time.Time {
+	expiry := fetched.Add(maxAge)
+	earlier := func(t time.Time) {
+		if !t.IsZero() && t.Before(expiry) {
+			expiry = t
+		}
+	}
+
+	var config struct {
+		Users []struct {
+			User struct {
+				Token                 string `json:"token"`
+				ClientCertificateData string `json:"client-certificate-data"`
+			} `json:"user"`
+		} `json:"users"`
+	}
+	if err := yaml.Unmarshal(kubeconfig, &config); err != nil {
+		return expiry
+	}
+	for _, user := range config.Users {
+		earlier(certificateExpiry(user.User.ClientCertificateData))
+		earlier(tokenExpiry(user.User.Token))
+	}
+	return expiry
+}
+
+func certificateExpiry(data string) time.Time {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return time.Time{}
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return time.Time{}
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}
+	}
+	return cert.NotAfter
+}
+
+func tokenExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Exp, 0)
+}
+
+// getCache returns a cluster's cached admin kubeconfig, or nil
+func getCache(cluster *domain.Cluster) *cache {
+	raw, ok := cluster.Metadata[MetadataCache]
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var cached cache
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Ciphertext == "" {
+		return nil
+	}
+	return &cached
+}
@@ -0,0 +1,100 @@
+package kubeconfigs
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCipher struct{}
+
+func (fakeCipher) CreateEncryptionKey(context.Context, string) error { return nil }
+
+func (fakeCipher) TransitEncrypt(_ context.Context, _ string, plaintext []byte) (string, error) {
+	return "vault:v1:" + base64.StdEncoding.EncodeToString(plaintext), nil
+}
+
+func (fakeCipher) TransitDecrypt(_ context.Context, _ string, ciphertext string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(ciphertext[len("vault:v1:"):])
+}
+
+type fakeClusterRepo struct {
+	domain.ClusterRepository
+	clusters map[uuid.UUID]*domain.Cluster
+}
+
+func (r *fakeClusterRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Cluster, error) {
+	c := *r.clusters[id]
+	return &c, nil
+}
+
+func (r *fakeClusterRepo) Update(_ context.Context, cluster *domain.Cluster) error {
+	r.clusters[cluster.ID] = cluster
+	return nil
+}
+
+// fakeManager hands out a new token on every fetch and records revocations
+type fakeManager struct {
+	domain.ClusterManagerAdapter
+	fetched int
+	revoked []string
+}
+
+func (m *fakeManager) GetKubeConfig(context.Context, string) ([]byte, error) {
+	m.fetched++
+	return []byte(fmt.Sprintf("users:\n- name: admin\n  user:\n    token: kubeconfig-%d:secret\n", m.fetched)), nil
+}
+
+func (m *fakeManager) RevokeKubeConfig(_ context.Context, _ string, kubeconfig []byte) error {
+	m.revoked = append(m.revoked, string(kubeconfig))
+	return nil
+}
+
+func TestExpiry(t *testing.T) {
+	fetched := time.Unix(1700000000, 0)
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"exp":1700003600}`))
+	jwt := []byte("users:\n- user:\n    token: header." + claims + ".signature\n")
+
+	assert.Equal(t, time.Unix(1700003600, 0), Expiry(jwt, fetched, 24*time.Hour))
+	assert.Equal(t, fetched.Add(time.Minute), Expiry(jwt, fetched, time.Minute))
+	assert.Equal(t, fetched.Add(time.Hour), Expiry([]byte("users:\n- user:\n    token: kubeconfig-1:secret\n"), fetched, time.Hour))
+}
+
+func TestAdminRotation(t *testing.T) {
+	cluster := &domain.Cluster{ID: uuid.New(), RancherClusterID: "c-1", Status: domain.ClusterStatusActive}
+	repo := &fakeClusterRepo{clusters: map[uuid.UUID]*domain.Cluster{cluster.ID: cluster}}
+	clusters := &fakeManager{}
+	cfg := &config.KubeconfigsConfig{EncryptionKey: "kubeconfigs", MaxAge: time.Hour, RotateBefore: 10 * time.Minute}
+	m := NewManager(cfg, clusters, fakeCipher{}, repo, nil, nil, nil, logger.New("error", "json", io.Discard))
+	ctx := context.Background()
+
+	first, err := m.Admin(ctx, cluster)
+	require.NoError(t, err)
+	stored, _ := repo.GetByID(ctx, cluster.ID)
+	cached := getCache(stored)
+	require.NotNil(t, cached)
+	assert.NotContains(t, cached.Ciphertext, "secret", "cached encrypted")
+
+	again, err := m.Admin(ctx, stored)
+	require.NoError(t, err)
+	assert.Equal(t, first, again)
+	assert.Equal(t, 1, clusters.fetched)
+
+	// Within RotateBefore of expiry the kubeconfig is replaced and revoked
+	cfg.RotateBefore = 2 * time.Hour
+	stored, _ = repo.GetByID(ctx, cluster.ID)
+	rotated, err := m.Admin(ctx, stored)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, rotated)
+	assert.Equal(t, []string{string(first)}, clusters.revoked)
+}
@@ -0,0 +1,102 @@
+package kubeevents
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// maxEventsPerService bounds memory per service; the oldest events are dropped first
+	maxEventsPerService = 200
+	// retention matches the default Kubernetes event TTL with some headroom
+	retention = 6 * time.Hour
+)
+
+// Categories for the failure modes users most often need to debug
+const (
+	CategoryOOMKilled        = "oom_killed"
+	CategoryFailedScheduling = "failed_scheduling"
+	CategoryImagePull        = "image_pull"
+	CategoryCrashLoop        = "crash_loop"
+	CategoryOther            = "other"
+)
+
+// Event is a Kubernetes event attributed to a service
+type Event struct {
+	ID        string    `json:"id"`
+	ServiceID uuid.UUID `json:"service_id"`
+	ClusterID uuid.UUID `json:"cluster_id"`
+	Namespace string    `json:"namespace"`
+	Object    string    `json:"object"` // Kind/name of the involved object
+	Type      string    `json:"type"`   // Normal or Warning
+	Reason    string    `json:"reason"`
+	Category  string    `json:"category"`
+	Message   string    `json:"message"`
+	Count     int64     `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Store keeps recent events per service in memory
+type Store struct {
+	mu     sync.RWMutex
+	events map[uuid.UUID][]*Event
+}
+
+// NewStore creates a new Store
+func NewStore() *Store {
+	return &Store{events: make(map[uuid.UUID][]*Event)}
+}
+
+// Add records an event, merging it with an existing event with the same ID
+func (s *Store) Add(e *Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.events[e.ServiceID]
+	for i, existing := range list {
+		if existing.ID == e.ID {
+			list[i] = e
+			return
+		}
+	}
+
+	cutoff := time.Now().Add(-retention)
+	kept := list[:0]
+	for _, existing := range list {
+		if existing.LastSeen.After(cutoff) {
+			kept = append(kept, existing)
+		}
+	}
+	kept = append(kept, e)
+	if len(kept) > maxEventsPerService {
+		sort.Slice(kept, func(i, j int) bool { return kept[i].LastSeen.Before(kept[j].LastSeen) })
+		kept = kept[len(kept)-maxEventsPerService:]
+	}
+	s.events[e.ServiceID] = kept
+}
+
+// List returns a service's events, most recent first, optionally filtered by category
+func (s *Store) List(serviceID uuid.UUID, category string, limit int) []*Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cutoff := time.Now().Add(-retention)
+	result := []*Event{}
+	for _, e := range s.events[serviceID] {
+		if e.LastSeen.Before(cutoff) || (category != "" && e.Category != category) {
+			continue
+		}
+		copied := *e
+		result = append(result, &copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].LastSeen.After(result[j].LastSeen) })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
@@ -0,0 +1,258 @@
+// Package kubeevents ingests Kubernetes events (scheduling failures, image pull
+// errors, crash loops) and OOM kills for platform-managed workloads and keeps
+// them per service, so failed deploys can be debugged without kubectl.
+package kubeevents
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// clusterRefreshInterval is how often new clusters are picked up
+	clusterRefreshInterval = 5 * time.Minute
+	// rewatchDelay is the pause before re-establishing a dropped watch
+	rewatchDelay = 10 * time.Second
+	// maxOwnerCache bounds the involved-object to service cache
+	maxOwnerCache = 10000
+)
+
+// Watcher watches events on every registered cluster
+type Watcher struct {
+	kube        domain.KubernetesClient
+	clusterRepo domain.ClusterRepository
+	store       *Store
+	logger      *logger.Logger
+
+	mu      sync.Mutex
+	watched map[uuid.UUID]bool
+	owners  map[string]uuid.UUID // Involved object UID -> service ID (uuid.Nil if unmanaged)
+}
+
+// NewWatcher creates a new Watcher
+func NewWatcher(kube domain.KubernetesClient, clusterRepo domain.ClusterRepository, store *Store, log *logger.Logger) *Watcher {
+	return &Watcher{
+		kube:        kube,
+		clusterRepo: clusterRepo,
+		store:       store,
+		logger:      log,
+		watched:     make(map[uuid.UUID]bool),
+		owners:      make(map[string]uuid.UUID),
+	}
+}
+
+// Run starts watching all clusters and picks up new ones until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(clusterRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		w.refreshClusters(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) refreshClusters(ctx context.Context) {
+	clusters, err := w.clusterRepo.List(ctx, domain.ClusterFilter{})
+	if err != nil {
+		w.logger.Warn().Err(err).Msg("Failed to list clusters for event ingestion")
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, cluster := range clusters {
+		if w.watched[cluster.ID] || cluster.Status == domain.ClusterStatusDeleting {
+			continue
+		}
+		w.watched[cluster.ID] = true
+		go w.watch(ctx, cluster.ID, "Event", w.handleEvent)
+		go w.watch(ctx, cluster.ID, "Pod", w.handlePod)
+	}
+}
+
+// watch keeps a watch open, re-establishing it when it drops
+func (w *Watcher) watch(ctx context.Context, clusterID uuid.UUID, kind string, handle func(uuid.UUID, map[string]interface{})) {
+	for ctx.Err() == nil {
+		err := w.kube.WatchResource(ctx, clusterID, kind, "", func(eventType string, obj map[string]interface{}) {
+			if eventType == "ADDED" || eventType == "MODIFIED" {
+				handle(clusterID, obj)
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			w.logger.Warn().Err(err).Str("cluster_id", clusterID.String()).Str("kind", kind).Msg("Watch ended, retrying")
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(rewatchDelay):
+		}
+	}
+}
+
+// handleEvent records Warning events whose involved object belongs to a service
+func (w *Watcher) handleEvent(clusterID uuid.UUID, obj map[string]interface{}) {
+	eventType, _, _ := unstructured.NestedString(obj, "type")
+	if eventType != "Warning" {
+		return
+	}
+
+	kind, _, _ := unstructured.NestedString(obj, "involvedObject", "kind")
+	name, _, _ := unstructured.NestedString(obj, "involvedObject", "name")
+	namespace, _, _ := unstructured.NestedString(obj, "involvedObject", "namespace")
+	uid, _, _ := unstructured.NestedString(obj, "involvedObject", "uid")
+
+	serviceID := w.resolveService(clusterID, kind, namespace, name, uid)
+	if serviceID == uuid.Nil {
+		return
+	}
+
+	id, _, _ := unstructured.NestedString(obj, "metadata", "uid")
+	reason, _, _ := unstructured.NestedString(obj, "reason")
+	message, _, _ := unstructured.NestedString(obj, "message")
+	count, _, _ := unstructured.NestedInt64(obj, "count")
+	if count == 0 {
+		count = 1
+	}
+
+	e := &Event{
+		ID:        id,
+		ServiceID: serviceID,
+		ClusterID: clusterID,
+		Namespace: namespace,
+		Object:    kind + "/" + name,
+		Type:      eventType,
+		Reason:    reason,
+		Category:  Categorize(reason, message),
+		Message:   message,
+		Count:     count,
+		FirstSeen: timestamp(obj, "firstTimestamp", "eventTime"),
+		LastSeen:  timestamp(obj, "lastTimestamp", "eventTime"),
+	}
+	if e.LastSeen.IsZero() {
+		e.LastSeen = time.Now().UTC()
+	}
+	if e.FirstSeen.IsZero() {
+		e.FirstSeen = e.LastSeen
+	}
+
+	w.store.Add(e)
+}
+
+// handlePod records OOM kills, which surface in container status rather than as events
+func (w *Watcher) handlePod(clusterID uuid.UUID, obj map[string]interface{}) {
+	raw, _, _ := unstructured.NestedString(obj, "metadata", "labels", domain.LabelServiceID)
+	serviceID, err := uuid.Parse(raw)
+	if err != nil {
+		return
+	}
+
+	podName, _, _ := unstructured.NestedString(obj, "metadata", "name")
+	namespace, _, _ := unstructured.NestedString(obj, "metadata", "namespace")
+	podUID, _, _ := unstructured.NestedString(obj, "metadata", "uid")
+
+	statuses, _, _ := unstructured.NestedSlice(obj, "status", "containerStatuses")
+	for _, st := range statuses {
+		status, ok := st.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		reason, _, _ := unstructured.NestedString(status, "lastState", "terminated", "reason")
+		if reason != "OOMKilled" {
+			continue
+		}
+
+		container, _, _ := unstructured.NestedString(status, "name")
+		finished, _, _ := unstructured.NestedString(status, "lastState", "terminated", "finishedAt")
+		restarts, _, _ := unstructured.NestedInt64(status, "restartCount")
+		at, err := time.Parse(time.RFC3339, finished)
+		if err != nil {
+			at = time.Now().UTC()
+		}
+
+		w.store.Add(&Event{
+			ID:        podUID + "/" + container + "/oom",
+			ServiceID: serviceID,
+			ClusterID: clusterID,
+			Namespace: namespace,
+			Object:    "Pod/" + podName,
+			Type:      "Warning",
+			Reason:    "OOMKilled",
+			Category:  CategoryOOMKilled,
+			Message:   "Container " + container + " was killed after exceeding its memory limit",
+			Count:     restarts,
+			FirstSeen: at,
+			LastSeen:  at,
+		})
+	}
+}
+
+// resolveService finds the service owning an involved object via its labels
+func (w *Watcher) resolveService(clusterID uuid.UUID, kind, namespace, name, uid string) uuid.UUID {
+	w.mu.Lock()
+	if id, ok := w.owners[uid]; ok && uid != "" {
+		w.mu.Unlock()
+		return id
+	}
+	w.mu.Unlock()
+
+	serviceID := uuid.Nil
+	obj, err := w.kube.GetResource(context.Background(), clusterID, kind, namespace, name)
+	if err == nil {
+		raw, _, _ := unstructured.NestedString(obj, "metadata", "labels", domain.LabelServiceID)
+		if id, err := uuid.Parse(raw); err == nil {
+			serviceID = id
+		}
+	}
+
+	if uid != "" && err == nil {
+		w.mu.Lock()
+		if len(w.owners) >= maxOwnerCache {
+			w.owners = make(map[string]uuid.UUID)
+		}
+		w.owners[uid] = serviceID
+		w.mu.Unlock()
+	}
+	return serviceID
+}
+
+// Categorize maps an event reason and message to a category
+func Categorize(reason, message string) string {
+	msg := strings.ToLower(message)
+	switch {
+	case reason == "FailedScheduling":
+		return CategoryFailedScheduling
+	case reason == "OOMKilling" || reason == "OOMKilled":
+		return CategoryOOMKilled
+	case reason == "ErrImagePull" || reason == "ImagePullBackOff" ||
+		((reason == "Failed" || reason == "BackOff") && strings.Contains(msg, "image")):
+		return CategoryImagePull
+	case reason == "BackOff" && strings.Contains(msg, "restarting failed container"):
+		return CategoryCrashLoop
+	default:
+		return CategoryOther
+	}
+}
+
+// timestamp returns the first of the given fields that holds a valid time
+func timestamp(obj map[string]interface{}, fields ...string) time.Time {
+	for _, f := range fields {
+		v, _, _ := unstructured.NestedString(obj, f)
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
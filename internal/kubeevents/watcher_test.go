@@ -0,0 +1,37 @@
+package kubeevents
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategorize(t *testing.T) {
+	assert.Equal(t, CategoryFailedScheduling, Categorize("FailedScheduling", "0/3 nodes are available"))
+	assert.Equal(t, CategoryImagePull, Categorize("Failed", "Failed to pull image \"app:v2\""))
+	assert.Equal(t, CategoryImagePull, Categorize("BackOff", "Back-off pulling image \"app:v2\""))
+	assert.Equal(t, CategoryCrashLoop, Categorize("BackOff", "Back-off restarting failed container app"))
+	assert.Equal(t, CategoryOOMKilled, Categorize("OOMKilling", "Memory cgroup out of memory"))
+	assert.Equal(t, CategoryOther, Categorize("Unhealthy", "Readiness probe failed"))
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore()
+	svc := uuid.New()
+	now := time.Now()
+
+	store.Add(&Event{ID: "a", ServiceID: svc, Category: CategoryImagePull, Count: 1, LastSeen: now.Add(-time.Minute)})
+	store.Add(&Event{ID: "b", ServiceID: svc, Category: CategoryOther, LastSeen: now})
+	store.Add(&Event{ID: "a", ServiceID: svc, Category: CategoryImagePull, Count: 4, LastSeen: now.Add(-30 * time.Second)})
+	store.Add(&Event{ID: "old", ServiceID: svc, LastSeen: now.Add(-2 * retention)})
+
+	events := store.List(svc, "", 0)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, "b", events[0].ID)
+		assert.Equal(t, int64(4), events[1].Count)
+	}
+	assert.Len(t, store.List(svc, CategoryImagePull, 0), 1)
+	assert.Empty(t, store.List(uuid.New(), "", 0))
+}
@@ -0,0 +1,114 @@
+// Package livefeed pushes build, deploy and service events to dashboard
+// sessions as they happen. Each replica subscribes to the event bus once and
+// fans events out to the sessions connected to it, filtered by project.
+package livefeed
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Subjects are the event bus subjects forwarded to sessions
+var Subjects = []string{"build.>", "deploy.>", "deployment.>", "service.>"}
+
+// sessionBuffer is how many events a slow session may fall behind before
+// further events are dropped; clients resync from the list endpoints
+const sessionBuffer = 64
+
+// session is one connected client and the projects it may see; a nil
+// project set sees every project
+type session struct {
+	events   chan *domain.Event
+	projects map[uuid.UUID]bool
+}
+
+// Hub fans events out to the sessions connected to this replica
+type Hub struct {
+	eventBus domain.EventBus
+	logger   *logger.Logger
+
+	mu       sync.RWMutex
+	sessions map[*session]struct{}
+}
+
+// NewHub creates a new Hub
+func NewHub(eventBus domain.EventBus, log *logger.Logger) *Hub {
+	return &Hub{
+		eventBus: eventBus,
+		logger:   log,
+		sessions: make(map[*session]struct{}),
+	}
+}
+
+// Watch subscribes to the forwarded subjects. Every replica receives every
+// event, as its sessions are only connected to it.
+func (h *Hub) Watch(ctx context.Context) error {
+	if h.eventBus == nil {
+		return nil
+	}
+	for _, subject := range Subjects {
+		if _, err := h.eventBus.Subscribe(ctx, subject, func(event *domain.Event) error {
+			h.Publish(event)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a session for the events of the given projects, or of
+// every project when projects is nil. The returned function must be called
+// when the session ends.
+func (h *Hub) Subscribe(projects []uuid.UUID) (<-chan *domain.Event, func()) {
+	s := &session{events: make(chan *domain.Event, sessionBuffer)}
+	if projects != nil {
+		s.projects = make(map[uuid.UUID]bool, len(projects))
+		for _, id := range projects {
+			s.projects[id] = true
+		}
+	}
+
+	h.mu.Lock()
+	h.sessions[s] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return s.events, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.sessions, s)
+			h.mu.Unlock()
+		})
+	}
+}
+
+// Publish delivers an event to the sessions that may see its project. Events
+// without a project only reach sessions that see every project.
+func (h *Hub) Publish(event *domain.Event) {
+	projectID, _ := ProjectID(event)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.sessions {
+		if s.projects != nil && !s.projects[projectID] {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			h.logger.Debug().Str("event", event.Type).Msg("Dropped live event for slow session")
+		}
+	}
+}
+
+// ProjectID returns the project an event belongs to
+func ProjectID(event *domain.Event) (uuid.UUID, bool) {
+	value, _ := event.Data["project_id"].(string)
+	id, err := uuid.Parse(value)
+	return id, err == nil
+}
@@ -0,0 +1,49 @@
+package livefeed
+
+import (
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func event(projectID uuid.UUID) *domain.Event {
+	return &domain.Event{Type: "deploy.completed", Data: map[string]interface{}{"project_id": projectID.String()}}
+}
+
+func TestHubFiltersByProject(t *testing.T) {
+	hub := NewHub(nil, logger.New("error", "json", io.Discard))
+	mine, other := uuid.New(), uuid.New()
+
+	scoped, cancelScoped := hub.Subscribe([]uuid.UUID{mine})
+	defer cancelScoped()
+	all, cancelAll := hub.Subscribe(nil)
+	defer cancelAll()
+
+	hub.Publish(event(other))
+	hub.Publish(event(mine))
+	hub.Publish(&domain.Event{Type: "service.updated", Data: map[string]interface{}{}})
+
+	assert.Len(t, scoped, 1)
+	assert.Equal(t, mine.String(), (<-scoped).Data["project_id"])
+	assert.Len(t, all, 3)
+}
+
+func TestHubDropsForSlowSessions(t *testing.T) {
+	hub := NewHub(nil, logger.New("error", "json", io.Discard))
+	projectID := uuid.New()
+
+	events, cancel := hub.Subscribe([]uuid.UUID{projectID})
+	for i := 0; i < sessionBuffer+10; i++ {
+		hub.Publish(event(projectID))
+	}
+	assert.Len(t, events, sessionBuffer)
+
+	cancel()
+	cancel()
+	hub.Publish(event(projectID))
+	assert.Len(t, events, sessionBuffer)
+}
@@ -0,0 +1,245 @@
+// Package logs provides access to service runtime logs.
+// Live logs are read from pods through the KubernetesClient; since it only
+// returns log snapshots, following is implemented by polling for the lines
+// logged since the last one seen, told apart by their timestamps.
+package logs
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	defaultTail  = 100
+	maxTail      = 5000
+	followWindow = 500 // lines fetched per poll while following
+)
+
+// Line is a single log line from a service instance
+type Line struct {
+	Instance  string    `json:"instance"`
+	Container string    `json:"container,omitempty"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"` // When the line was logged
+	Received  time.Time `json:"received"`
+}
+
+// StreamOptions controls which logs are returned
+type StreamOptions struct {
+	Follow    bool
+	Tail      int64
+	Container string
+	Instance  string // Pod name; empty means all instances
+}
+
+// Instance is a pod backing a service
+type Instance struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Phase     string `json:"phase"`
+}
+
+// Streamer reads logs from service pods
+type Streamer struct {
+	kube         domain.KubernetesClient
+	logger       *logger.Logger
+	pollInterval time.Duration
+}
+
+// NewStreamer creates a new Streamer
+func NewStreamer(kube domain.KubernetesClient, log *logger.Logger) *Streamer {
+	return &Streamer{
+		kube:         kube,
+		logger:       log,
+		pollInterval: 2 * time.Second,
+	}
+}
+
+// Instances lists the pods currently backing a service
+func (s *Streamer) Instances(ctx context.Context, service *domain.Service) ([]Instance, error) {
+	if service.TargetClusterID == nil {
+		return nil, errors.BadRequest("service has not been deployed to a cluster")
+	}
+
+	objects, err := s.kube.ListResources(ctx, *service.TargetClusterID, "Pod", "", map[string]string{
+		domain.LabelServiceID: service.ID.String(),
+	})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	instances := make([]Instance, 0, len(objects))
+	for _, obj := range objects {
+		name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+		namespace, _, _ := unstructured.NestedString(obj, "metadata", "namespace")
+		phase, _, _ := unstructured.NestedString(obj, "status", "phase")
+		instances = append(instances, Instance{Name: name, Namespace: namespace, Phase: phase})
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+	return instances, nil
+}
+
+// Stream sends log lines for the service to out. Without Follow it returns after
+// the initial tail; with Follow it polls until ctx is cancelled, picking up new instances.
+func (s *Streamer) Stream(ctx context.Context, service *domain.Service, opts StreamOptions, out func(Line) error) error {
+	tail := opts.Tail
+	if tail <= 0 {
+		tail = defaultTail
+	}
+	if tail > maxTail {
+		tail = maxTail
+	}
+
+	instances, err := s.selectInstances(ctx, service, opts.Instance)
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 && !opts.Follow {
+		return errors.NotFound("running instances for service", service.ID.String())
+	}
+
+	clusterID := *service.TargetClusterID
+	cursors := make(map[string]*cursor)
+	for _, inst := range instances {
+		raw, err := s.kube.GetTimestampedPodLogs(ctx, clusterID, inst.Namespace, inst.Name, opts.Container, time.Time{}, tail)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("pod", inst.Name).Msg("Failed to read pod logs")
+			continue
+		}
+		c := &cursor{}
+		cursors[inst.Name] = c
+		if err := emit(inst.Name, opts.Container, c.advance(parseLines(raw)), out); err != nil {
+			return err
+		}
+	}
+
+	if !opts.Follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		instances, err := s.selectInstances(ctx, service, opts.Instance)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("service_id", service.ID.String()).Msg("Failed to list service instances")
+			continue
+		}
+
+		for _, inst := range instances {
+			c, seen := cursors[inst.Name]
+			if !seen {
+				c = &cursor{}
+			}
+			raw, err := s.kube.GetTimestampedPodLogs(ctx, clusterID, inst.Namespace, inst.Name, opts.Container, c.last, followWindow)
+			if err != nil {
+				continue // Pod may still be starting or already gone
+			}
+			cursors[inst.Name] = c
+			if err := emit(inst.Name, opts.Container, c.advance(parseLines(raw)), out); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Streamer) selectInstances(ctx context.Context, service *domain.Service, name string) ([]Instance, error) {
+	instances, err := s.Instances(ctx, service)
+	if err != nil || name == "" {
+		return instances, err
+	}
+	for _, inst := range instances {
+		if inst.Name == name {
+			return []Instance{inst}, nil
+		}
+	}
+	return nil, errors.NotFound("instance", name)
+}
+
+func emit(instance, container string, lines []timedLine, out func(Line) error) error {
+	now := time.Now().UTC()
+	for _, l := range lines {
+		if err := out(Line{Instance: instance, Container: container, Message: l.message, Timestamp: l.at, Received: now}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timedLine is a log line with the time it was logged at
+type timedLine struct {
+	at      time.Time
+	message string
+}
+
+// parseLines splits timestamped logs into lines. A line without a timestamp
+// takes that of the line before it.
+func parseLines(raw string) []timedLine {
+	raw = strings.TrimRight(raw, "\n")
+	if raw == "" {
+		return nil
+	}
+
+	var lines []timedLine
+	var last time.Time
+	for _, l := range strings.Split(raw, "\n") {
+		stamp, message, _ := strings.Cut(l, " ")
+		at, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil {
+			at, message = last, l
+		}
+		last = at
+		lines = append(lines, timedLine{at: at.UTC(), message: message})
+	}
+	return lines
+}
+
+// cursor marks the last line of a pod's logs already sent: its timestamp,
+// and how many lines carrying that very timestamp were sent
+type cursor struct {
+	last time.Time
+	n    int
+}
+
+// advance returns the lines logged after the cursor and moves it past them.
+// Lines are told apart by their timestamps, not their content, so the same
+// message logged again is sent again.
+func (c *cursor) advance(lines []timedLine) []timedLine {
+	var fresh []timedLine
+	atLast := 0
+	for _, l := range lines {
+		if l.at.Before(c.last) {
+			continue
+		}
+		if l.at.Equal(c.last) {
+			atLast++
+			if atLast <= c.n {
+				continue
+			}
+		}
+		fresh = append(fresh, l)
+	}
+
+	for _, l := range fresh {
+		if l.at.Equal(c.last) {
+			c.n++
+		} else {
+			c.last, c.n = l.at, 1
+		}
+	}
+	return fresh
+}
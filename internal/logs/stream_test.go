@@ -0,0 +1,50 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func messages(lines []timedLine) []string {
+	out := []string{}
+	for _, l := range lines {
+		out = append(out, l.message)
+	}
+	return out
+}
+
+func TestParseLines(t *testing.T) {
+	lines := parseLines("2026-03-01T10:00:00.000000001Z GET /health\n  at main.go:10\n2026-03-01T10:00:01Z done\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "GET /health", lines[0].message)
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 0, 0, 1, time.UTC), lines[0].at)
+	assert.Equal(t, "  at main.go:10", lines[1].message, "lines without a timestamp are kept whole")
+	assert.Equal(t, lines[0].at, lines[1].at)
+	assert.Equal(t, "done", lines[2].message)
+
+	assert.Empty(t, parseLines(""))
+}
+
+func TestCursorAdvance(t *testing.T) {
+	c := &cursor{}
+	first := parseLines("2026-03-01T10:00:00.1Z ping\n2026-03-01T10:00:00.2Z ping\n2026-03-01T10:00:00.3Z ping")
+	assert.Equal(t, []string{"ping", "ping", "ping"}, messages(c.advance(first)), "repeated lines are all sent")
+
+	// The next poll starts at the second of the cursor, so it sees the same lines again
+	next := parseLines("2026-03-01T10:00:00.1Z ping\n2026-03-01T10:00:00.2Z ping\n2026-03-01T10:00:00.3Z ping\n2026-03-01T10:00:00.4Z ping")
+	assert.Equal(t, []string{"ping"}, messages(c.advance(next)), "a line identical to the last one is new when logged later")
+
+	assert.Empty(t, c.advance(next), "lines already sent are not sent again")
+}
+
+func TestCursorAdvanceSameTimestamp(t *testing.T) {
+	c := &cursor{}
+	assert.Len(t, c.advance(parseLines("2026-03-01T10:00:00Z a\n2026-03-01T10:00:00Z a")), 2)
+
+	// A third line logged within the same instant follows the two already sent
+	fresh := c.advance(parseLines("2026-03-01T10:00:00Z a\n2026-03-01T10:00:00Z a\n2026-03-01T10:00:00Z a\n2026-03-01T10:00:01Z b"))
+	assert.Equal(t, []string{"a", "b"}, messages(fresh))
+}
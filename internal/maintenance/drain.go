@@ -0,0 +1,588 @@
+// Package maintenance provides node maintenance workflows for managed clusters.
+// The drain assistant cordons a node, moves platform services off it while
+// honouring PodDisruptionBudgets, and reports progress to operators.
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// DrainPhase represents the current phase of a drain operation
+type DrainPhase string
+
+const (
+	DrainPhasePlanned      DrainPhase = "planned"
+	DrainPhaseCordoning    DrainPhase = "cordoning"
+	DrainPhaseRescheduling DrainPhase = "rescheduling_singletons"
+	DrainPhaseEvicting     DrainPhase = "evicting"
+	DrainPhaseCompleted    DrainPhase = "completed"
+	DrainPhaseFailed       DrainPhase = "failed"
+)
+
+// DisruptedService describes a platform service with pods on the drained node
+type DisruptedService struct {
+	ServiceID          uuid.UUID `json:"service_id"`
+	ProjectID          uuid.UUID `json:"project_id"`
+	Name               string    `json:"name"`
+	Namespace          string    `json:"namespace"`
+	Pods               []string  `json:"pods"`
+	Singleton          bool      `json:"singleton"`
+	PDB                string    `json:"pdb,omitempty"`
+	DisruptionsAllowed *int64    `json:"disruptions_allowed,omitempty"`
+}
+
+// DrainOptions controls how a drain is performed
+type DrainOptions struct {
+	DryRun  bool
+	Timeout time.Duration
+}
+
+// DrainOperation tracks the progress of a node drain
+type DrainOperation struct {
+	ID            uuid.UUID          `json:"id"`
+	ClusterID     uuid.UUID          `json:"cluster_id"`
+	Node          string             `json:"node"`
+	Phase         DrainPhase         `json:"phase"`
+	DryRun        bool               `json:"dry_run"`
+	Services      []DisruptedService `json:"services"`
+	UnmanagedPods []string           `json:"unmanaged_pods,omitempty"`
+	TotalPods     int                `json:"total_pods"`
+	EvictedPods   int                `json:"evicted_pods"`
+	BlockedPods   []string           `json:"blocked_pods,omitempty"`
+	Error         string             `json:"error,omitempty"`
+	StartedAt     time.Time          `json:"started_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+	CompletedAt   *time.Time         `json:"completed_at,omitempty"`
+}
+
+// nodePod is a pod scheduled on the node being drained
+type nodePod struct {
+	name      string
+	namespace string
+	labels    map[string]string
+	serviceID *uuid.UUID
+}
+
+// pdb is a PodDisruptionBudget relevant to the drain
+type pdb struct {
+	name      string
+	namespace string
+	selector  map[string]string
+	allowed   int64
+}
+
+// Drainer coordinates node drains across managed clusters
+type Drainer struct {
+	kube         domain.KubernetesClient
+	serviceRepo  domain.ServiceRepository
+	eventBus     domain.EventBus
+	logger       *logger.Logger
+	pollInterval time.Duration
+
+	mu         sync.RWMutex
+	operations map[string]*DrainOperation
+}
+
+// NewDrainer creates a new Drainer
+func NewDrainer(
+	kube domain.KubernetesClient,
+	serviceRepo domain.ServiceRepository,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *Drainer {
+	return &Drainer{
+		kube:         kube,
+		serviceRepo:  serviceRepo,
+		eventBus:     eventBus,
+		logger:       log,
+		pollInterval: 5 * time.Second,
+		operations:   make(map[string]*DrainOperation),
+	}
+}
+
+// Start plans a drain and, unless it is a dry run, executes it in the background
+func (d *Drainer) Start(ctx context.Context, clusterID uuid.UUID, node string, opts DrainOptions) (*DrainOperation, error) {
+	key := operationKey(clusterID, node)
+
+	d.mu.RLock()
+	if existing, ok := d.operations[key]; ok && !existing.finished() && !existing.DryRun {
+		d.mu.RUnlock()
+		return nil, errors.Conflict(fmt.Sprintf("drain of node %s", node))
+	}
+	d.mu.RUnlock()
+
+	op, pods, err := d.plan(ctx, clusterID, node)
+	if err != nil {
+		return nil, err
+	}
+	op.DryRun = opts.DryRun
+
+	d.mu.Lock()
+	d.operations[key] = op
+	d.mu.Unlock()
+
+	if opts.DryRun {
+		return op.snapshot(&d.mu), nil
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+
+	go d.run(op, pods, timeout)
+
+	return op.snapshot(&d.mu), nil
+}
+
+// Status returns the latest known state of a drain operation
+func (d *Drainer) Status(clusterID uuid.UUID, node string) (*DrainOperation, error) {
+	d.mu.RLock()
+	op, ok := d.operations[operationKey(clusterID, node)]
+	d.mu.RUnlock()
+	if !ok {
+		return nil, errors.NotFound("drain operation", node)
+	}
+	return op.snapshot(&d.mu), nil
+}
+
+// plan inspects the node and determines which services will be disrupted
+func (d *Drainer) plan(ctx context.Context, clusterID uuid.UUID, node string) (*DrainOperation, []nodePod, error) {
+	objects, err := d.kube.ListResources(ctx, clusterID, "Pod", "", nil)
+	if err != nil {
+		return nil, nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	var pods []nodePod
+	namespaces := make(map[string]bool)
+	for _, obj := range objects {
+		nodeName, _, _ := unstructured.NestedString(obj, "spec", "nodeName")
+		if nodeName != node || isDaemonSetPod(obj) || isMirrorPod(obj) {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+		namespace, _, _ := unstructured.NestedString(obj, "metadata", "namespace")
+		labels, _, _ := unstructured.NestedStringMap(obj, "metadata", "labels")
+
+		pod := nodePod{name: name, namespace: namespace, labels: labels}
+		if id, err := uuid.Parse(labels[domain.LabelServiceID]); err == nil {
+			pod.serviceID = &id
+		}
+		pods = append(pods, pod)
+		namespaces[namespace] = true
+	}
+
+	budgets, err := d.listPDBs(ctx, clusterID, namespaces)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	op := &DrainOperation{
+		ID:        uuid.New(),
+		ClusterID: clusterID,
+		Node:      node,
+		Phase:     DrainPhasePlanned,
+		TotalPods: len(pods),
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+
+	byService := make(map[uuid.UUID]*DisruptedService)
+	for _, pod := range pods {
+		if pod.serviceID == nil {
+			op.UnmanagedPods = append(op.UnmanagedPods, pod.namespace+"/"+pod.name)
+			continue
+		}
+
+		ds, ok := byService[*pod.serviceID]
+		if !ok {
+			ds = &DisruptedService{ServiceID: *pod.serviceID, Namespace: pod.namespace}
+			if svc, err := d.serviceRepo.GetByID(ctx, *pod.serviceID); err == nil {
+				ds.Name = svc.Name
+				ds.ProjectID = svc.ProjectID
+				ds.Singleton = svc.Scaling.MaxReplicas <= 1
+			}
+			if budget := matchPDB(budgets, pod); budget != nil {
+				allowed := budget.allowed
+				ds.PDB = budget.name
+				ds.DisruptionsAllowed = &allowed
+			}
+			byService[*pod.serviceID] = ds
+		}
+		ds.Pods = append(ds.Pods, pod.name)
+	}
+
+	for _, ds := range byService {
+		op.Services = append(op.Services, *ds)
+	}
+	// Singletons first so operators see the riskiest disruptions at the top
+	sort.Slice(op.Services, func(i, j int) bool {
+		if op.Services[i].Singleton != op.Services[j].Singleton {
+			return op.Services[i].Singleton
+		}
+		return op.Services[i].Name < op.Services[j].Name
+	})
+
+	return op, pods, nil
+}
+
+// run executes the drain: cordon, reschedule singletons, then evict the rest
+func (d *Drainer) run(op *DrainOperation, pods []nodePod, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	d.publishEvent(ctx, "cluster.node.drain_started", op)
+
+	d.setPhase(op, DrainPhaseCordoning)
+	if err := d.cordon(ctx, op.ClusterID, op.Node); err != nil {
+		d.fail(op, err)
+		return
+	}
+
+	singletons := make(map[uuid.UUID]bool)
+	for _, ds := range op.Services {
+		if ds.Singleton {
+			singletons[ds.ServiceID] = true
+		}
+	}
+
+	singletonPods := make(map[uuid.UUID][]nodePod)
+	var remaining []nodePod
+	for _, pod := range pods {
+		if pod.serviceID != nil && singletons[*pod.serviceID] {
+			singletonPods[*pod.serviceID] = append(singletonPods[*pod.serviceID], pod)
+			continue
+		}
+		remaining = append(remaining, pod)
+	}
+
+	d.setPhase(op, DrainPhaseRescheduling)
+	for _, ds := range op.Services {
+		if !ds.Singleton {
+			continue
+		}
+		if err := d.rescheduleSingleton(ctx, op, ds, singletonPods[ds.ServiceID]); err != nil {
+			d.fail(op, err)
+			return
+		}
+	}
+
+	d.setPhase(op, DrainPhaseEvicting)
+	if err := d.evict(ctx, op, remaining); err != nil {
+		d.fail(op, err)
+		return
+	}
+
+	d.mu.Lock()
+	now := time.Now()
+	op.Phase = DrainPhaseCompleted
+	op.BlockedPods = nil
+	op.UpdatedAt = now
+	op.CompletedAt = &now
+	d.mu.Unlock()
+
+	d.logger.Info().
+		Str("cluster_id", op.ClusterID.String()).
+		Str("node", op.Node).
+		Int("evicted", op.EvictedPods).
+		Msg("Node drain completed")
+
+	d.publishEvent(ctx, "cluster.node.drain_completed", op)
+}
+
+// cordon marks the node unschedulable. It patches spec.unschedulable alone,
+// leaving the rest of the node's spec to its other owners.
+func (d *Drainer) cordon(ctx context.Context, clusterID uuid.UUID, node string) error {
+	patch, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{"unschedulable": true},
+	})
+	if err := d.kube.PatchResource(ctx, clusterID, "Node", "", node, patch); err != nil {
+		return fmt.Errorf("failed to cordon node: %w", err)
+	}
+	return nil
+}
+
+// rescheduleSingleton starts a replacement pod elsewhere before evicting the old one
+func (d *Drainer) rescheduleSingleton(ctx context.Context, op *DrainOperation, ds DisruptedService, pods []nodePod) error {
+	svc, err := d.serviceRepo.GetByID(ctx, ds.ServiceID)
+	if err != nil {
+		return fmt.Errorf("failed to load service %s: %w", ds.ServiceID, err)
+	}
+
+	// Restart the rollout as kubectl rollout restart does; the node is
+	// cordoned, so the new pod lands elsewhere
+	restart, _ := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						"kubectl.kubernetes.io/restartedAt": time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err := d.kube.PatchResource(ctx, op.ClusterID, "Deployment", ds.Namespace, svc.Slug, restart); err != nil {
+		return fmt.Errorf("failed to restart %s: %w", svc.Name, err)
+	}
+
+	selector := map[string]string{domain.LabelServiceID: ds.ServiceID.String()}
+	for {
+		ready, err := d.hasReadyPodElsewhere(ctx, op.ClusterID, ds.Namespace, op.Node, selector)
+		if err != nil {
+			return err
+		}
+		if ready {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s to reschedule", svc.Name)
+		case <-time.After(d.pollInterval):
+		}
+	}
+
+	return d.evict(ctx, op, pods)
+}
+
+// evict evicts pods from the node through the Eviction API, waiting whenever
+// a PDB blocks a disruption. Each eviction uses up budget, so the budget of a
+// pod is re-read just before it is evicted.
+func (d *Drainer) evict(ctx context.Context, op *DrainOperation, pods []nodePod) error {
+	for len(pods) > 0 {
+		var blocked []nodePod
+		for _, pod := range pods {
+			evicted, err := d.evictPod(ctx, op.ClusterID, pod)
+			if err != nil {
+				return err
+			}
+			if !evicted {
+				blocked = append(blocked, pod)
+				continue
+			}
+			d.mu.Lock()
+			op.EvictedPods++
+			op.UpdatedAt = time.Now()
+			d.mu.Unlock()
+		}
+
+		names := make([]string, len(blocked))
+		for i, pod := range blocked {
+			names[i] = pod.namespace + "/" + pod.name
+		}
+		d.mu.Lock()
+		op.BlockedPods = names
+		op.UpdatedAt = time.Now()
+		d.mu.Unlock()
+
+		pods = blocked
+		if len(pods) == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for disruption budgets: %d pods still blocked", len(pods))
+		case <-time.After(d.pollInterval):
+		}
+	}
+
+	return nil
+}
+
+// evictPod evicts a pod unless its disruption budget, read afresh, allows no
+// disruption. It reports false when a budget blocks the eviction, including
+// when the API server refuses it on a budget that changed since it was read.
+func (d *Drainer) evictPod(ctx context.Context, clusterID uuid.UUID, pod nodePod) (bool, error) {
+	budgets, err := d.listPDBs(ctx, clusterID, map[string]bool{pod.namespace: true})
+	if err != nil {
+		return false, err
+	}
+	if budget := matchPDB(budgets, pod); budget != nil && budget.allowed <= 0 {
+		return false, nil
+	}
+
+	err = d.kube.EvictPod(ctx, clusterID, pod.namespace, pod.name)
+	switch {
+	case err == nil, errors.IsNotFound(err):
+		return true, nil
+	case evictionBlocked(err):
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to evict pod %s/%s: %w", pod.namespace, pod.name, err)
+}
+
+// hasReadyPodElsewhere checks for a ready replacement pod on another node
+func (d *Drainer) hasReadyPodElsewhere(ctx context.Context, clusterID uuid.UUID, namespace, node string, selector map[string]string) (bool, error) {
+	objects, err := d.kube.ListResources(ctx, clusterID, "Pod", namespace, selector)
+	if err != nil {
+		return false, errors.DependencyFailed("kubernetes", err)
+	}
+
+	for _, obj := range objects {
+		nodeName, _, _ := unstructured.NestedString(obj, "spec", "nodeName")
+		if nodeName == "" || nodeName == node {
+			continue
+		}
+		conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions")
+		for _, c := range conditions {
+			cond, ok := c.(map[string]interface{})
+			if ok && cond["type"] == "Ready" && cond["status"] == "True" {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// listPDBs loads the disruption budgets for the given namespaces
+func (d *Drainer) listPDBs(ctx context.Context, clusterID uuid.UUID, namespaces map[string]bool) ([]*pdb, error) {
+	var budgets []*pdb
+	for namespace := range namespaces {
+		objects, err := d.kube.ListResources(ctx, clusterID, "PodDisruptionBudget", namespace, nil)
+		if err != nil {
+			return nil, errors.DependencyFailed("kubernetes", err)
+		}
+		for _, obj := range objects {
+			name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+			selector, _, _ := unstructured.NestedStringMap(obj, "spec", "selector", "matchLabels")
+			allowed, _, _ := unstructured.NestedInt64(obj, "status", "disruptionsAllowed")
+			budgets = append(budgets, &pdb{
+				name:      name,
+				namespace: namespace,
+				selector:  selector,
+				allowed:   allowed,
+			})
+		}
+	}
+	return budgets, nil
+}
+
+func (d *Drainer) setPhase(op *DrainOperation, phase DrainPhase) {
+	d.mu.Lock()
+	op.Phase = phase
+	op.UpdatedAt = time.Now()
+	d.mu.Unlock()
+}
+
+func (d *Drainer) fail(op *DrainOperation, err error) {
+	d.mu.Lock()
+	now := time.Now()
+	op.Phase = DrainPhaseFailed
+	op.Error = err.Error()
+	op.UpdatedAt = now
+	op.CompletedAt = &now
+	d.mu.Unlock()
+
+	d.logger.Error().
+		Err(err).
+		Str("cluster_id", op.ClusterID.String()).
+		Str("node", op.Node).
+		Msg("Node drain failed")
+
+	d.publishEvent(context.Background(), "cluster.node.drain_failed", op)
+}
+
+func (d *Drainer) publishEvent(ctx context.Context, eventType string, op *DrainOperation) {
+	if d.eventBus == nil {
+		return
+	}
+	d.mu.RLock()
+	data := map[string]interface{}{
+		"operation_id": op.ID.String(),
+		"cluster_id":   op.ClusterID.String(),
+		"node":         op.Node,
+		"phase":        string(op.Phase),
+		"total_pods":   op.TotalPods,
+		"evicted_pods": op.EvictedPods,
+	}
+	if op.Error != "" {
+		data["error"] = op.Error
+	}
+	d.mu.RUnlock()
+
+	event := &domain.Event{
+		Type:   eventType,
+		Source: "maintenance",
+		Data:   data,
+	}
+	if err := d.eventBus.Publish(ctx, eventType, event); err != nil {
+		d.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+func (op *DrainOperation) finished() bool {
+	return op.Phase == DrainPhaseCompleted || op.Phase == DrainPhaseFailed
+}
+
+// snapshot returns a copy of the operation that is safe to serialize
+func (op *DrainOperation) snapshot(mu *sync.RWMutex) *DrainOperation {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	cp := *op
+	cp.Services = append([]DisruptedService(nil), op.Services...)
+	cp.UnmanagedPods = append([]string(nil), op.UnmanagedPods...)
+	cp.BlockedPods = append([]string(nil), op.BlockedPods...)
+	return &cp
+}
+
+func operationKey(clusterID uuid.UUID, node string) string {
+	return clusterID.String() + "/" + node
+}
+
+func matchPDB(budgets []*pdb, pod nodePod) *pdb {
+	for _, budget := range budgets {
+		if budget.namespace != pod.namespace || len(budget.selector) == 0 {
+			continue
+		}
+		matches := true
+		for k, v := range budget.selector {
+			if pod.labels[k] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return budget
+		}
+	}
+	return nil
+}
+
+// evictionBlocked reports whether the API server refused an eviction because
+// a disruption budget does not allow it
+func evictionBlocked(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Code == errors.CodeRateLimited
+}
+
+func isDaemonSetPod(obj map[string]interface{}) bool {
+	owners, _, _ := unstructured.NestedSlice(obj, "metadata", "ownerReferences")
+	for _, o := range owners {
+		if owner, ok := o.(map[string]interface{}); ok && owner["kind"] == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+func isMirrorPod(obj map[string]interface{}) bool {
+	annotations, _, _ := unstructured.NestedStringMap(obj, "metadata", "annotations")
+	_, ok := annotations["kubernetes.io/config.mirror"]
+	return ok
+}
@@ -0,0 +1,216 @@
+package maintenance
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKube holds the pods of node-1 and one disruption budget covering the
+// web pods. Evictions use up the budget the way the API server does, and a
+// replacement becomes ready by the next time a spent budget is read.
+type fakeKube struct {
+	domain.KubernetesClient
+
+	mu        sync.Mutex
+	pods      []map[string]interface{}
+	allowed   int64
+	pdbReads  int
+	patches   []string
+	evictions []string
+	refuse    map[string]bool // Pods whose next eviction the API server refuses
+}
+
+func (f *fakeKube) ListResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string, labels map[string]string) ([]map[string]interface{}, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch kind {
+	case "PodDisruptionBudget":
+		f.pdbReads++
+		budget := map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "web", "namespace": namespace},
+			"spec":     map[string]interface{}{"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}}},
+			"status":   map[string]interface{}{"disruptionsAllowed": f.allowed},
+		}
+		if f.allowed == 0 {
+			f.allowed = 1
+		}
+		return []map[string]interface{}{budget}, nil
+	case "Pod":
+		if labels != nil {
+			// The singleton's replacement, ready on another node
+			return []map[string]interface{}{pod("db-1", "node-2", labels, true)}, nil
+		}
+		return f.pods, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeKube) PatchResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string, patch []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.patches = append(f.patches, kind+" "+name+" "+string(patch))
+	return nil
+}
+
+func (f *fakeKube) EvictPod(ctx context.Context, clusterID uuid.UUID, namespace, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.refuse[name] {
+		delete(f.refuse, name)
+		return errors.NewError(errors.CodeRateLimited, "eviction is blocked by a disruption budget", http.StatusTooManyRequests)
+	}
+	if name == "web-1" || name == "web-2" {
+		if f.allowed <= 0 {
+			return errors.NewError(errors.CodeRateLimited, "eviction is blocked by a disruption budget", http.StatusTooManyRequests)
+		}
+		f.allowed--
+	}
+	f.evictions = append(f.evictions, namespace+"/"+name)
+	return nil
+}
+
+func (f *fakeKube) DeleteResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) error {
+	panic("drains must evict, not delete")
+}
+
+// fakeServices holds the services by ID
+type fakeServices struct {
+	domain.ServiceRepository
+	services map[uuid.UUID]*domain.Service
+}
+
+func (f *fakeServices) GetByID(ctx context.Context, id uuid.UUID) (*domain.Service, error) {
+	if svc, ok := f.services[id]; ok {
+		return svc, nil
+	}
+	return nil, errors.NotFound("service", id.String())
+}
+
+func pod(name, node string, labels map[string]string, ready bool) map[string]interface{} {
+	podLabels := map[string]interface{}{}
+	for k, v := range labels {
+		podLabels[k] = v
+	}
+	status := "False"
+	if ready {
+		status = "True"
+	}
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"name": name, "namespace": "shop", "labels": podLabels},
+		"spec":     map[string]interface{}{"nodeName": node},
+		"status": map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": status},
+		}},
+	}
+}
+
+func newTestDrainer(t *testing.T) (*Drainer, *fakeKube, uuid.UUID, uuid.UUID) {
+	t.Helper()
+
+	web := &domain.Service{ID: uuid.New(), Name: "web", Slug: "web", Scaling: domain.ScalingConfig{MaxReplicas: 3}}
+	db := &domain.Service{ID: uuid.New(), Name: "db", Slug: "db", Scaling: domain.ScalingConfig{MaxReplicas: 1}}
+	webLabels := map[string]string{"app": "web", domain.LabelServiceID: web.ID.String()}
+
+	daemon := pod("logs-1", "node-1", nil, true)
+	daemon["metadata"].(map[string]interface{})["ownerReferences"] = []interface{}{map[string]interface{}{"kind": "DaemonSet"}}
+
+	kube := &fakeKube{
+		pods: []map[string]interface{}{
+			pod("web-1", "node-1", webLabels, true),
+			pod("web-2", "node-1", webLabels, true),
+			pod("web-3", "node-2", webLabels, true),
+			pod("db-0", "node-1", map[string]string{domain.LabelServiceID: db.ID.String()}, true),
+			pod("debug", "node-1", nil, true),
+			daemon,
+		},
+		allowed: 1,
+		refuse:  map[string]bool{},
+	}
+	services := &fakeServices{services: map[uuid.UUID]*domain.Service{web.ID: web, db.ID: db}}
+
+	drainer := NewDrainer(kube, services, nil, logger.New("error", "json", io.Discard))
+	drainer.pollInterval = time.Millisecond
+	return drainer, kube, web.ID, db.ID
+}
+
+func waitForDrain(t *testing.T, drainer *Drainer, clusterID uuid.UUID) *DrainOperation {
+	t.Helper()
+
+	var op *DrainOperation
+	require.Eventually(t, func() bool {
+		var err error
+		op, err = drainer.Status(clusterID, "node-1")
+		require.NoError(t, err)
+		return op.finished()
+	}, 5*time.Second, time.Millisecond)
+	return op
+}
+
+func TestDrainerPlansDryRun(t *testing.T) {
+	drainer, kube, webID, dbID := newTestDrainer(t)
+
+	op, err := drainer.Start(context.Background(), uuid.New(), "node-1", DrainOptions{DryRun: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, DrainPhasePlanned, op.Phase)
+	assert.Equal(t, 4, op.TotalPods, "daemon set pods stay on the node")
+	require.Len(t, op.Services, 2)
+	assert.Equal(t, dbID, op.Services[0].ServiceID, "singletons come first")
+	assert.True(t, op.Services[0].Singleton)
+	assert.Equal(t, webID, op.Services[1].ServiceID)
+	assert.Equal(t, []string{"web-1", "web-2"}, op.Services[1].Pods)
+	assert.Equal(t, "web", op.Services[1].PDB)
+	assert.Equal(t, []string{"shop/debug"}, op.UnmanagedPods)
+
+	assert.Empty(t, kube.patches, "dry runs change nothing")
+	assert.Empty(t, kube.evictions)
+}
+
+func TestDrainerEvictsWithinDisruptionBudgets(t *testing.T) {
+	drainer, kube, _, _ := newTestDrainer(t)
+	kube.refuse["debug"] = true
+	clusterID := uuid.New()
+
+	_, err := drainer.Start(context.Background(), clusterID, "node-1", DrainOptions{})
+	require.NoError(t, err)
+	op := waitForDrain(t, drainer, clusterID)
+
+	require.Equal(t, DrainPhaseCompleted, op.Phase, op.Error)
+	assert.Equal(t, 4, op.EvictedPods)
+	assert.Empty(t, op.BlockedPods)
+
+	kube.mu.Lock()
+	defer kube.mu.Unlock()
+
+	require.Len(t, kube.patches, 2)
+	assert.Equal(t, `Node node-1 {"spec":{"unschedulable":true}}`, kube.patches[0], "cordoning patches spec.unschedulable alone")
+	assert.Contains(t, kube.patches[1], `Deployment db {"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":`)
+
+	assert.Equal(t, "shop/db-0", kube.evictions[0], "singletons move before the rest")
+	assert.ElementsMatch(t, []string{"shop/db-0", "shop/web-1", "shop/web-2", "shop/debug"}, kube.evictions)
+	assert.GreaterOrEqual(t, kube.pdbReads, 5, "the budget is re-read before each eviction")
+}
+
+func TestDrainerRejectsConcurrentDrains(t *testing.T) {
+	drainer, _, _, _ := newTestDrainer(t)
+	clusterID := uuid.New()
+	drainer.operations[operationKey(clusterID, "node-1")] = &DrainOperation{Phase: DrainPhaseEvicting}
+
+	_, err := drainer.Start(context.Background(), clusterID, "node-1", DrainOptions{})
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code)
+}
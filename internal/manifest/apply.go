@@ -0,0 +1,388 @@
+package manifest
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/dualstack"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Actions of the changes in a plan
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionDelete    = "delete"
+	ActionUnchanged = "unchanged"
+)
+
+// Kinds of the objects a plan changes
+const (
+	KindService = "service"
+	KindIngress = "ingress"
+)
+
+// Change is one step of converging a project to its manifest
+type Change struct {
+	Action  string     `json:"action"`
+	Kind    string     `json:"kind"`
+	Name    string     `json:"name"`              // Slug of a service, domain and path of an ingress
+	Service string     `json:"service,omitempty"` // Slug of an ingress's service
+	ID      *uuid.UUID `json:"id,omitempty"`
+	Fields  []string   `json:"fields,omitempty"` // Settings an update changes
+}
+
+// Result lists the changes an apply made, or would make on a dry run
+type Result struct {
+	DryRun  bool     `json:"dry_run"`
+	Changes []Change `json:"changes"`
+}
+
+// Options tune an apply
+type Options struct {
+	DryRun bool // Plan only
+	Prune  bool // Delete the services a manifest created that it no longer declares
+}
+
+// step is a change with the object it writes
+type step struct {
+	change  Change
+	service *domain.Service
+	ingress *domain.Ingress
+}
+
+// Applier converges projects to their manifests, like kubectl apply
+type Applier struct {
+	services  domain.ServiceRepository
+	ingresses domain.IngressRepository
+	eventBus  domain.EventBus
+	logger    *logger.Logger
+}
+
+// NewApplier creates a new Applier. ingresses may be nil, in which case
+// manifests that declare ingress routes are rejected.
+func NewApplier(services domain.ServiceRepository, ingresses domain.IngressRepository, eventBus domain.EventBus, log *logger.Logger) *Applier {
+	return &Applier{
+		services:  services,
+		ingresses: ingresses,
+		eventBus:  eventBus,
+		logger:    log,
+	}
+}
+
+// Apply diffs a project's services against a manifest and, unless it is a
+// dry run, converges them. Changes are not transactional: after a failure,
+// applying again picks up where the last apply stopped.
+func (a *Applier) Apply(ctx context.Context, projectID uuid.UUID, m *Manifest, opts Options) (*Result, error) {
+	steps, err := a.plan(ctx, projectID, m, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{DryRun: opts.DryRun, Changes: make([]Change, 0, len(steps))}
+	for _, s := range steps {
+		if !opts.DryRun && s.change.Action != ActionUnchanged {
+			if err := a.execute(ctx, s); err != nil {
+				return nil, err
+			}
+		}
+		result.Changes = append(result.Changes, s.change)
+	}
+
+	if !opts.DryRun {
+		a.logger.Info().
+			Str("project_id", projectID.String()).
+			Int("changes", countChanged(result.Changes)).
+			Msg("Manifest applied")
+	}
+	return result, nil
+}
+
+// plan returns the steps that converge a project: services to create or
+// update, then their ingress routes, then the services to prune
+func (a *Applier) plan(ctx context.Context, projectID uuid.UUID, m *Manifest, opts Options) ([]step, error) {
+	existing, err := a.services.ListByProject(ctx, projectID, domain.ServiceFilter{})
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]*domain.Service, len(existing))
+	for _, service := range existing {
+		bySlug[service.Slug] = service
+	}
+
+	var serviceSteps, ingressSteps, pruneSteps []step
+	declared := make(map[string]bool, len(m.Services))
+	for i := range m.Services {
+		spec := &m.Services[i]
+		declared[spec.Slug] = true
+		desired := spec.Service(projectID)
+
+		current, ok := bySlug[spec.Slug]
+		if !ok {
+			now := time.Now()
+			desired.ID = uuid.New()
+			desired.Status = domain.ServiceStatusPending
+			desired.CreatedAt = now
+			desired.UpdatedAt = now
+			serviceSteps = append(serviceSteps, step{
+				change:  Change{Action: ActionCreate, Kind: KindService, Name: spec.Slug, ID: &desired.ID},
+				service: desired,
+			})
+		} else {
+			change := Change{Action: ActionUnchanged, Kind: KindService, Name: spec.Slug, ID: &current.ID}
+			if change.Fields = diffService(current, desired); len(change.Fields) > 0 {
+				change.Action = ActionUpdate
+				merge(current, desired)
+			}
+			serviceSteps = append(serviceSteps, step{change: change, service: current})
+			desired = current
+		}
+
+		if spec.Ingress != nil {
+			steps, err := a.planIngresses(ctx, desired, *spec.Ingress, ok)
+			if err != nil {
+				return nil, err
+			}
+			ingressSteps = append(ingressSteps, steps...)
+		}
+	}
+
+	if opts.Prune {
+		for _, service := range existing {
+			if declared[service.Slug] || service.Labels[LabelManagedBy] != ManagedByApply {
+				continue
+			}
+			id := service.ID
+			pruneSteps = append(pruneSteps, step{
+				change:  Change{Action: ActionDelete, Kind: KindService, Name: service.Slug, ID: &id},
+				service: service,
+			})
+		}
+	}
+
+	return append(append(serviceSteps, ingressSteps...), pruneSteps...), nil
+}
+
+// planIngresses returns the steps that converge the ingress routes of a service
+func (a *Applier) planIngresses(ctx context.Context, service *domain.Service, specs []IngressSpec, exists bool) ([]step, error) {
+	if a.ingresses == nil {
+		return nil, errors.BadRequest("ingress routes are not enabled on this platform")
+	}
+
+	var current []*domain.Ingress
+	if exists {
+		var err error
+		if current, err = a.ingresses.ListByService(ctx, service.ID); err != nil {
+			return nil, err
+		}
+	}
+	byRoute := make(map[string]*domain.Ingress, len(current))
+	for _, ingress := range current {
+		byRoute[ingress.Domain+ingress.Path] = ingress
+	}
+
+	var steps []step
+	for _, spec := range specs {
+		route := spec.Domain + spec.Path
+		ingress, ok := byRoute[route]
+		delete(byRoute, route)
+
+		if !ok {
+			now := time.Now()
+			ingress = &domain.Ingress{
+				ID:        uuid.New(),
+				ServiceID: service.ID,
+				ProjectID: service.ProjectID,
+				Domain:    spec.Domain,
+				Path:      spec.Path,
+				Type:      domain.IngressType(spec.Type),
+				TLS:       domain.TLSConfig{Enabled: spec.TLS, AutoTLS: spec.TLS},
+				CreatedAt: now,
+				UpdatedAt: now,
+			}
+			steps = append(steps, step{
+				change:  Change{Action: ActionCreate, Kind: KindIngress, Name: route, Service: service.Slug, ID: &ingress.ID},
+				ingress: ingress,
+			})
+			continue
+		}
+
+		change := Change{Action: ActionUnchanged, Kind: KindIngress, Name: route, Service: service.Slug, ID: &ingress.ID}
+		if ingress.Type != domain.IngressType(spec.Type) {
+			change.Fields = append(change.Fields, "type")
+			ingress.Type = domain.IngressType(spec.Type)
+		}
+		if ingress.TLS.Enabled != spec.TLS {
+			change.Fields = append(change.Fields, "tls")
+			// A certificate from a secret is kept; otherwise cert-manager issues one
+			ingress.TLS.Enabled = spec.TLS
+			ingress.TLS.AutoTLS = spec.TLS && ingress.TLS.SecretName == ""
+		}
+		if len(change.Fields) > 0 {
+			change.Action = ActionUpdate
+		}
+		steps = append(steps, step{change: change, ingress: ingress})
+	}
+
+	// Routes the manifest no longer declares
+	for _, ingress := range current {
+		if _, ok := byRoute[ingress.Domain+ingress.Path]; !ok {
+			continue
+		}
+		id := ingress.ID
+		steps = append(steps, step{
+			change:  Change{Action: ActionDelete, Kind: KindIngress, Name: ingress.Domain + ingress.Path, Service: service.Slug, ID: &id},
+			ingress: ingress,
+		})
+	}
+	return steps, nil
+}
+
+// execute writes one change and publishes its event
+func (a *Applier) execute(ctx context.Context, s step) error {
+	switch {
+	case s.change.Kind == KindService && s.change.Action == ActionCreate:
+		if err := a.services.Create(ctx, s.service); err != nil {
+			return err
+		}
+		a.publish(ctx, "service.created", map[string]interface{}{
+			"service_id": s.service.ID.String(),
+			"project_id": s.service.ProjectID.String(),
+			"name":       s.service.Name,
+			"type":       string(s.service.Type),
+		})
+	case s.change.Kind == KindService && s.change.Action == ActionUpdate:
+		if err := a.services.Update(ctx, s.service); err != nil {
+			return err
+		}
+		a.publish(ctx, "service.updated", map[string]interface{}{
+			"service_id": s.service.ID.String(),
+			"project_id": s.service.ProjectID.String(),
+		})
+	case s.change.Kind == KindService && s.change.Action == ActionDelete:
+		if err := a.services.Delete(ctx, s.service.ID); err != nil {
+			return err
+		}
+		a.publish(ctx, "service.deleted", map[string]interface{}{
+			"service_id": s.service.ID.String(),
+			"project_id": s.service.ProjectID.String(),
+			"name":       s.service.Name,
+		})
+	case s.change.Kind == KindIngress:
+		var err error
+		eventType := "service.ingress.updated"
+		switch s.change.Action {
+		case ActionCreate:
+			err = a.ingresses.Create(ctx, s.ingress)
+			eventType = "service.ingress.created"
+		case ActionUpdate:
+			err = a.ingresses.Update(ctx, s.ingress)
+		case ActionDelete:
+			err = a.ingresses.Delete(ctx, s.ingress.ID)
+			eventType = "service.ingress.deleted"
+		}
+		if err != nil {
+			return err
+		}
+		a.publish(ctx, eventType, map[string]interface{}{
+			"ingress_id":   s.ingress.ID.String(),
+			"service_id":   s.ingress.ServiceID.String(),
+			"project_id":   s.ingress.ProjectID.String(),
+			"domain":       s.ingress.Domain,
+			"path":         s.ingress.Path,
+			"record_types": dualstack.RecordTypes(s.ingress.IPFamilies),
+		})
+	}
+	return nil
+}
+
+func (a *Applier) publish(ctx context.Context, eventType string, data map[string]interface{}) {
+	if a.eventBus == nil {
+		return
+	}
+	event := &domain.Event{Type: eventType, Source: "apply", Data: data}
+	if err := a.eventBus.Publish(ctx, eventType, event); err != nil {
+		a.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// diffService returns the settings a manifest changes on a service
+func diffService(current, desired *domain.Service) []string {
+	build := current.BuildSource
+	build.CommitSHA = ""
+
+	var fields []string
+	for _, f := range []struct {
+		name          string
+		current, want interface{}
+	}{
+		{"name", current.Name, desired.Name},
+		{"type", current.Type, desired.Type},
+		{"build", build, desired.BuildSource},
+		{"env", nonEmptyMap(current.EnvVars), nonEmptyMap(desired.EnvVars)},
+		{"secrets", nonEmptySlice(current.SecretRefs), nonEmptySlice(desired.SecretRefs)},
+		{"ports", nonEmptySlice(current.Ports), nonEmptySlice(desired.Ports)},
+		{"resources", current.Resources, desired.Resources},
+		{"scaling", replicaBounds(current.Scaling), replicaBounds(desired.Scaling)},
+		{"health_check", current.HealthCheck, desired.HealthCheck},
+		{"labels", nonEmptyMap(current.Labels), nonEmptyMap(desired.Labels)},
+	} {
+		if !reflect.DeepEqual(f.current, f.want) {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+// merge copies the settings a manifest declares onto a service
+func merge(current, desired *domain.Service) {
+	commit := current.BuildSource.CommitSHA
+	current.Name = desired.Name
+	current.Type = desired.Type
+	current.BuildSource = desired.BuildSource
+	current.BuildSource.CommitSHA = commit
+	current.EnvVars = desired.EnvVars
+	current.SecretRefs = desired.SecretRefs
+	current.Ports = desired.Ports
+	current.Resources = desired.Resources
+	current.Scaling.MinReplicas = desired.Scaling.MinReplicas
+	current.Scaling.MaxReplicas = desired.Scaling.MaxReplicas
+	current.Scaling.TargetCPU = desired.Scaling.TargetCPU
+	current.Scaling.TargetMemory = desired.Scaling.TargetMemory
+	current.HealthCheck = desired.HealthCheck
+	current.Labels = desired.Labels
+	current.UpdatedAt = time.Now()
+}
+
+// replicaBounds are the scaling settings a manifest declares
+func replicaBounds(s domain.ScalingConfig) [4]int32 {
+	return [4]int32{s.MinReplicas, s.MaxReplicas, s.TargetCPU, s.TargetMemory}
+}
+
+func nonEmptyMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+func nonEmptySlice[T any](s []T) []T {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
+
+func countChanged(changes []Change) int {
+	n := 0
+	for _, c := range changes {
+		if c.Action != ActionUnchanged {
+			n++
+		}
+	}
+	return n
+}
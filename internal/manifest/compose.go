@@ -0,0 +1,667 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultComposeStorage is the size of the persistent storage a service with
+// a named compose volume gets
+const DefaultComposeStorage = "1Gi"
+
+// ComposeOptions tune a compose import
+type ComposeOptions struct {
+	// Repository and Branch hold the code of services that compose builds
+	// from a local context. Without a repository such services are skipped.
+	Repository string
+	Branch     string
+	// StorageSize of services with a named volume; DefaultComposeStorage when empty
+	StorageSize string
+}
+
+// Unsupported is a compose setting an import could not carry over
+type Unsupported struct {
+	Service string `json:"service,omitempty"` // Empty for top-level keys
+	Key     string `json:"key"`
+	Reason  string `json:"reason"`
+}
+
+// ComposeImport is a manifest converted from a docker-compose.yml
+type ComposeImport struct {
+	Manifest    *Manifest     `json:"manifest"`
+	Unsupported []Unsupported `json:"unsupported"`
+}
+
+// composeReasons explain why known compose keys are not carried over
+var composeReasons = map[string]string{
+	"command":        "overriding the image's command is not supported; set it in the image",
+	"entrypoint":     "overriding the image's entrypoint is not supported; set it in the image",
+	"depends_on":     "services start independently and must retry their dependencies",
+	"links":          "services share the project network and reach each other by slug",
+	"networks":       "services share the project network and reach each other by slug",
+	"network_mode":   "services share the project network and reach each other by slug",
+	"container_name": "containers are named after the service slug",
+	"hostname":       "services are reached by their slug",
+	"env_file":       "env files are not uploaded; move the variables to environment",
+	"configs":        "configs are not supported; bake them into the image or use secrets",
+	"privileged":     "privileged containers are not allowed",
+	"cap_add":        "extra capabilities are not allowed",
+	"devices":        "host devices are not available",
+	"extra_hosts":    "host entries are not supported",
+	"pid":            "sharing the host's process namespace is not allowed",
+	"ipc":            "sharing the host's IPC namespace is not allowed",
+}
+
+var (
+	traefikHost = regexp.MustCompile("Host\\(([^)]*)\\)")
+	traefikPath = regexp.MustCompile("PathPrefix\\(`([^`]*)`\\)")
+	backticked  = regexp.MustCompile("`([^`]*)`")
+	labelKey    = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?[A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?$`)
+	labelValue  = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]{0,61}[A-Za-z0-9])?)?$`)
+	byteSize    = regexp.MustCompile(`^([0-9.]+)\s*([a-zA-Z]*)$`)
+)
+
+// FromCompose converts a docker-compose.yml into a manifest. Services map to
+// services, environment to env, named volumes to persistent storage and
+// Traefik Host rules or VIRTUAL_HOST to ingress routes; every other setting is
+// listed as unsupported.
+func FromCompose(data []byte, opts ComposeOptions) (*ComposeImport, error) {
+	if len(data) > maxManifestBytes {
+		return nil, errors.BadRequest(fmt.Sprintf("compose file is larger than %d bytes", maxManifestBytes))
+	}
+	if opts.StorageSize == "" {
+		opts.StorageSize = DefaultComposeStorage
+	}
+
+	var file map[string]json.RawMessage
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.BadRequest("invalid compose file: " + err.Error())
+	}
+	var services map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(file["services"], &services); err != nil || len(services) == 0 {
+		return nil, errors.BadRequest("invalid compose file: services are required")
+	}
+
+	result := &ComposeImport{Manifest: &Manifest{Version: Version}, Unsupported: []Unsupported{}}
+	for key := range file {
+		switch {
+		case key == "services", key == "version", key == "name", key == "volumes", strings.HasPrefix(key, "x-"):
+		case key == "secrets":
+			result.unsupported("", key, "secret values are not imported; register them as project secrets with the same names")
+		default:
+			result.unsupported("", key, composeReason(key))
+		}
+	}
+
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if spec, ok := result.convert(name, services[name], opts); ok {
+			result.Manifest.Services = append(result.Manifest.Services, *spec)
+		}
+	}
+
+	sort.SliceStable(result.Unsupported, func(i, j int) bool {
+		a, b := result.Unsupported[i], result.Unsupported[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Key < b.Key
+	})
+	if err := result.Manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *ComposeImport) unsupported(service, key, reason string) {
+	r.Unsupported = append(r.Unsupported, Unsupported{Service: service, Key: key, Reason: reason})
+}
+
+func composeReason(key string) string {
+	if reason, ok := composeReasons[key]; ok {
+		return reason
+	}
+	return "not supported"
+}
+
+// composeService is the part of a compose service an import reads
+type composeService struct {
+	Image       string          `json:"image"`
+	Build       json.RawMessage `json:"build"`
+	Environment json.RawMessage `json:"environment"`
+	Ports       []interface{}   `json:"ports"`
+	Expose      []interface{}   `json:"expose"`
+	Volumes     []interface{}   `json:"volumes"`
+	Labels      json.RawMessage `json:"labels"`
+	Secrets     []interface{}   `json:"secrets"`
+	Restart     string          `json:"restart"`
+	HealthCheck *struct {
+		Test        interface{} `json:"test"`
+		Interval    string      `json:"interval"`
+		Timeout     string      `json:"timeout"`
+		Retries     int32       `json:"retries"`
+		StartPeriod string      `json:"start_period"`
+		Disable     bool        `json:"disable"`
+	} `json:"healthcheck"`
+	Deploy *struct {
+		Replicas  *int32 `json:"replicas"`
+		Resources struct {
+			Limits       composeResources `json:"limits"`
+			Reservations composeResources `json:"reservations"`
+		} `json:"resources"`
+	} `json:"deploy"`
+	CPUs     interface{} `json:"cpus"`
+	MemLimit interface{} `json:"mem_limit"`
+}
+
+type composeResources struct {
+	CPUs   interface{} `json:"cpus"`
+	Memory interface{} `json:"memory"`
+}
+
+// convert returns the spec of one compose service, or false when it cannot
+// be imported
+func (r *ComposeImport) convert(name string, raw map[string]json.RawMessage, opts ComposeOptions) (*ServiceSpec, bool) {
+	known := map[string]bool{
+		"image": true, "build": true, "environment": true, "ports": true, "expose": true,
+		"volumes": true, "labels": true, "secrets": true, "restart": true, "healthcheck": true,
+		"deploy": true, "cpus": true, "mem_limit": true,
+	}
+	for key := range raw {
+		if !known[key] && !strings.HasPrefix(key, "x-") {
+			r.unsupported(name, key, composeReason(key))
+		}
+	}
+
+	encoded, _ := json.Marshal(raw)
+	var c composeService
+	if err := json.Unmarshal(encoded, &c); err != nil {
+		r.unsupported(name, "", "the service could not be read: "+err.Error())
+		return nil, false
+	}
+
+	spec := &ServiceSpec{Name: name, Type: string(domain.ServiceTypeWorker)}
+	if !r.build(spec, c, opts) {
+		return nil, false
+	}
+
+	spec.Env = r.environment(name, c.Environment)
+	r.ports(spec, c)
+	if len(spec.Ports) > 0 {
+		spec.Type = string(domain.ServiceTypeWebApp)
+	}
+	r.volumes(spec, c.Volumes, opts.StorageSize)
+	r.labels(spec, c.Labels)
+	r.resources(spec, c)
+
+	for _, s := range c.Secrets {
+		switch v := s.(type) {
+		case string:
+			spec.Secrets = append(spec.Secrets, v)
+		case map[string]interface{}:
+			if source, ok := v["source"].(string); ok {
+				spec.Secrets = append(spec.Secrets, source)
+			}
+		}
+	}
+
+	switch c.Restart {
+	case "", "always", "unless-stopped":
+	default:
+		r.unsupported(name, "restart", "services are always restarted")
+	}
+
+	if c.HealthCheck != nil && !c.HealthCheck.Disable {
+		r.healthCheck(spec, c)
+	}
+	return spec, true
+}
+
+// build sets where a service's image comes from
+func (r *ComposeImport) build(spec *ServiceSpec, c composeService, opts ComposeOptions) bool {
+	var context, dockerfile string
+	if len(c.Build) > 0 {
+		var build struct {
+			Context    string          `json:"context"`
+			Dockerfile string          `json:"dockerfile"`
+			Args       json.RawMessage `json:"args"`
+		}
+		if json.Unmarshal(c.Build, &context) != nil {
+			if err := json.Unmarshal(c.Build, &build); err != nil {
+				r.unsupported(spec.Name, "build", "the build could not be read")
+			}
+			context, dockerfile = build.Context, build.Dockerfile
+			if len(build.Args) > 0 {
+				r.unsupported(spec.Name, "build.args", "build arguments are not supported")
+			}
+		}
+	}
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+
+	remote := strings.HasPrefix(context, "https://") || strings.HasPrefix(context, "git@")
+	switch {
+	case remote:
+		repository, branch, _ := strings.Cut(context, "#")
+		spec.Build = domain.BuildSource{Type: "docker", Repository: repository, Branch: branch, Dockerfile: dockerfile}
+	case len(c.Build) > 0 && opts.Repository != "":
+		spec.Build = domain.BuildSource{
+			Type:       "docker",
+			Repository: opts.Repository,
+			Branch:     opts.Branch,
+			Dockerfile: strings.TrimPrefix(path.Join(context, dockerfile), "/"),
+		}
+	case c.Image != "":
+		spec.Build = domain.BuildSource{Type: "docker", Image: c.Image}
+	case len(c.Build) > 0:
+		r.unsupported(spec.Name, "build", "local build contexts need the repository holding them; the service was skipped")
+		return false
+	default:
+		r.unsupported(spec.Name, "image", "the service has no image or build; it was skipped")
+		return false
+	}
+	return true
+}
+
+// environment returns a service's variables. Variables without a value are
+// taken from the shell running compose, which an import cannot see.
+func (r *ComposeImport) environment(service string, raw json.RawMessage) map[string]string {
+	if len(raw) == 0 {
+		return nil
+	}
+	env := map[string]string{}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		for _, entry := range list {
+			key, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				r.unsupported(service, "environment."+key, "the value comes from the shell running compose; set it explicitly")
+				continue
+			}
+			env[key] = value
+		}
+		return env
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		r.unsupported(service, "environment", "the environment could not be read")
+		return nil
+	}
+	for key, value := range values {
+		if value == nil {
+			r.unsupported(service, "environment."+key, "the value comes from the shell running compose; set it explicitly")
+			continue
+		}
+		env[key] = fmt.Sprint(value)
+	}
+	return env
+}
+
+// ports converts published ports to public ports, and other ports to ports
+// reachable inside the project
+func (r *ComposeImport) ports(spec *ServiceSpec, c composeService) {
+	add := func(port, target int32, protocol string, public bool) {
+		for i, p := range spec.Ports {
+			if p.Port == port && p.Protocol == protocol {
+				spec.Ports[i].Public = p.Public || public
+				return
+			}
+		}
+		name := fmt.Sprintf("%s-%d", strings.ToLower(protocol), port)
+		if len(spec.Ports) == 0 && protocol == "TCP" {
+			name = "http"
+		}
+		spec.Ports = append(spec.Ports, PortSpec{Name: name, Port: port, TargetPort: target, Protocol: protocol, Public: public})
+	}
+
+	for _, p := range c.Ports {
+		published, target, protocol, ok := composePort(p)
+		switch {
+		case !ok:
+			r.unsupported(spec.Name, "ports", fmt.Sprintf("port %v is not supported; port ranges and host IPs cannot be published", p))
+		case published == 0:
+			add(target, target, protocol, false)
+		default:
+			add(published, target, protocol, true)
+		}
+	}
+	for _, p := range c.Expose {
+		_, target, protocol, ok := composePort(fmt.Sprint(p))
+		if !ok {
+			r.unsupported(spec.Name, "expose", fmt.Sprintf("port %v is not supported", p))
+			continue
+		}
+		add(target, target, protocol, false)
+	}
+}
+
+// composePort reads a port in the short ("8080:80/udp") or long syntax. The
+// published port is 0 when it is not published.
+func composePort(p interface{}) (published, target int32, protocol string, ok bool) {
+	protocol = "TCP"
+	switch v := p.(type) {
+	case float64:
+		return 0, int32(v), protocol, v >= 1 && v <= 65535
+	case string:
+		spec := v
+		if i := strings.LastIndex(spec, "/"); i >= 0 {
+			protocol = strings.ToUpper(spec[i+1:])
+			spec = spec[:i]
+		}
+		parts := strings.Split(spec, ":")
+		if len(parts) > 3 || strings.HasPrefix(spec, "[") {
+			return 0, 0, "", false
+		}
+		if len(parts) == 3 && parts[0] != "" && parts[0] != "0.0.0.0" {
+			return 0, 0, "", false
+		}
+		t, err := parsePort(parts[len(parts)-1])
+		if err != nil {
+			return 0, 0, "", false
+		}
+		target = t
+		if len(parts) > 1 && parts[len(parts)-2] != "" {
+			if published, err = parsePort(parts[len(parts)-2]); err != nil {
+				return 0, 0, "", false
+			}
+		}
+	case map[string]interface{}:
+		t, err := parsePort(fmt.Sprint(v["target"]))
+		if err != nil {
+			return 0, 0, "", false
+		}
+		target = t
+		if raw, ok := v["published"]; ok && raw != nil && fmt.Sprint(raw) != "" {
+			if published, err = parsePort(fmt.Sprint(raw)); err != nil {
+				return 0, 0, "", false
+			}
+		}
+		if s, ok := v["protocol"].(string); ok {
+			protocol = strings.ToUpper(s)
+		}
+		if ip, ok := v["host_ip"].(string); ok && ip != "" && ip != "0.0.0.0" {
+			return 0, 0, "", false
+		}
+	default:
+		return 0, 0, "", false
+	}
+	return published, target, protocol, protocol == "TCP" || protocol == "UDP"
+}
+
+func parsePort(s string) (int32, error) {
+	n, err := strconv.ParseInt(s, 10, 32)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return int32(n), nil
+}
+
+// volumes gives a service with a named volume persistent storage. Bind mounts,
+// anonymous volumes and further volumes cannot be carried over.
+func (r *ComposeImport) volumes(spec *ServiceSpec, volumes []interface{}, size string) {
+	mounted := ""
+	for _, v := range volumes {
+		var kind, source, target string
+		switch v := v.(type) {
+		case string:
+			parts := strings.Split(v, ":")
+			if len(parts) == 1 {
+				kind, target = "volume", parts[0]
+			} else {
+				source, target = parts[0], parts[1]
+				kind = "volume"
+				if strings.HasPrefix(source, ".") || strings.HasPrefix(source, "/") || strings.HasPrefix(source, "~") {
+					kind = "bind"
+				}
+			}
+		case map[string]interface{}:
+			kind, _ = v["type"].(string)
+			source, _ = v["source"].(string)
+			target, _ = v["target"].(string)
+		}
+
+		switch {
+		case kind == "bind":
+			r.unsupported(spec.Name, "volumes", fmt.Sprintf("bind mount %s is not supported; copy the files into the image", target))
+		case kind != "volume" || source == "":
+			r.unsupported(spec.Name, "volumes", fmt.Sprintf("%s at %s is not persisted; only named volumes are", orDefault(kind, "volume"), target))
+		case mounted != "":
+			r.unsupported(spec.Name, "volumes", fmt.Sprintf("volume %s is not supported; a service has one persistent volume, %s", source, mounted))
+		default:
+			mounted = source
+			spec.Type = string(domain.ServiceTypeStatefulDB)
+			if spec.Resources == nil {
+				spec.Resources = &ResourcesSpec{}
+			}
+			spec.Resources.StorageSize = size
+		}
+	}
+}
+
+// labels copies labels that are valid Kubernetes labels and turns Traefik
+// Host rules into ingress routes
+func (r *ComposeImport) labels(spec *ServiceSpec, raw json.RawMessage) {
+	labels := map[string]string{}
+	if len(raw) > 0 {
+		var list []string
+		if json.Unmarshal(raw, &list) == nil {
+			for _, entry := range list {
+				key, value, _ := strings.Cut(entry, "=")
+				labels[key] = value
+			}
+		} else {
+			var values map[string]interface{}
+			if err := json.Unmarshal(raw, &values); err != nil {
+				r.unsupported(spec.Name, "labels", "the labels could not be read")
+			}
+			for key, value := range values {
+				labels[key] = fmt.Sprint(value)
+			}
+		}
+	}
+
+	var ingress []IngressSpec
+	routeTLS := map[string]bool{}
+	for key, value := range labels {
+		if strings.HasPrefix(key, "traefik.") {
+			// traefik.http.routers.<router>.tls and .tls.certresolver enable TLS
+			if parts := strings.Split(key, "."); len(parts) >= 5 && parts[1] == "http" && parts[2] == "routers" && parts[4] == "tls" {
+				routeTLS[parts[3]] = routeTLS[parts[3]] || value != "false"
+			}
+			continue
+		}
+		if !labelKey.MatchString(key) || !labelValue.MatchString(value) {
+			r.unsupported(spec.Name, "labels."+key, "not a valid Kubernetes label")
+			continue
+		}
+		if spec.Labels == nil {
+			spec.Labels = map[string]string{}
+		}
+		spec.Labels[key] = value
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts := strings.Split(key, ".")
+		if len(parts) != 5 || parts[0] != "traefik" || parts[2] != "routers" || parts[4] != "rule" {
+			continue
+		}
+		routePath := "/"
+		if m := traefikPath.FindStringSubmatch(labels[key]); m != nil {
+			routePath = m[1]
+		}
+		for _, host := range traefikHost.FindAllStringSubmatch(labels[key], -1) {
+			for _, name := range backticked.FindAllStringSubmatch(host[1], -1) {
+				ingress = append(ingress, IngressSpec{Domain: name[1], Path: routePath, TLS: routeTLS[parts[3]]})
+			}
+		}
+	}
+
+	if hosts := spec.Env["VIRTUAL_HOST"]; hosts != "" {
+		tls := spec.Env["LETSENCRYPT_HOST"] != ""
+		routePath := orDefault(spec.Env["VIRTUAL_PATH"], "/")
+		for _, host := range strings.Split(hosts, ",") {
+			ingress = append(ingress, IngressSpec{Domain: strings.TrimSpace(host), Path: routePath, TLS: tls})
+		}
+	}
+
+	if len(ingress) > 0 {
+		spec.Ingress = &ingress
+	}
+}
+
+// resources converts deploy resources and replicas
+func (r *ComposeImport) resources(spec *ServiceSpec, c composeService) {
+	limits, reservations := composeResources{CPUs: c.CPUs, Memory: c.MemLimit}, composeResources{}
+	if c.Deploy != nil {
+		if c.Deploy.Resources.Limits.CPUs != nil || c.Deploy.Resources.Limits.Memory != nil {
+			limits = c.Deploy.Resources.Limits
+		}
+		reservations = c.Deploy.Resources.Reservations
+		if c.Deploy.Replicas != nil {
+			spec.Scaling = &ScalingSpec{MinReplicas: *c.Deploy.Replicas, MaxReplicas: *c.Deploy.Replicas}
+		}
+	}
+
+	quantity := func(key string, value interface{}, convert func(interface{}) (string, bool)) string {
+		if value == nil {
+			return ""
+		}
+		converted, ok := convert(value)
+		if !ok {
+			r.unsupported(spec.Name, key, fmt.Sprintf("%v is not a valid quantity", value))
+		}
+		return converted
+	}
+	res := ResourcesSpec{
+		CPULimit:      quantity("deploy.resources.limits.cpus", limits.CPUs, millicores),
+		MemoryLimit:   quantity("deploy.resources.limits.memory", limits.Memory, mebibytes),
+		CPURequest:    quantity("deploy.resources.reservations.cpus", reservations.CPUs, millicores),
+		MemoryRequest: quantity("deploy.resources.reservations.memory", reservations.Memory, mebibytes),
+	}
+	if res == (ResourcesSpec{}) && spec.Resources == nil {
+		return
+	}
+
+	// The API's defaults fill in what compose leaves out; requests default to
+	// the limits when only those are set
+	if spec.Resources == nil {
+		spec.Resources = &ResourcesSpec{}
+	}
+	spec.Resources.CPURequest = orDefault(res.CPURequest, orDefault(res.CPULimit, "100m"))
+	spec.Resources.CPULimit = orDefault(res.CPULimit, "500m")
+	spec.Resources.MemoryRequest = orDefault(res.MemoryRequest, orDefault(res.MemoryLimit, "128Mi"))
+	spec.Resources.MemoryLimit = orDefault(res.MemoryLimit, "512Mi")
+}
+
+// millicores converts compose CPUs ("0.5") to a Kubernetes quantity ("500m")
+func millicores(v interface{}) (string, bool) {
+	cpus, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+	if err != nil || cpus <= 0 {
+		return "", false
+	}
+	return fmt.Sprintf("%dm", int64(math.Ceil(cpus*1000))), true
+}
+
+// mebibytes converts a compose byte size ("512m", "1gb") to a Kubernetes
+// quantity ("512Mi")
+func mebibytes(v interface{}) (string, bool) {
+	if n, ok := v.(float64); ok {
+		v = strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	m := byteSize.FindStringSubmatch(strings.TrimSpace(fmt.Sprint(v)))
+	if m == nil {
+		return "", false
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil || n <= 0 {
+		return "", false
+	}
+	units := map[string]float64{"": 1, "b": 1, "k": 1 << 10, "kb": 1 << 10, "m": 1 << 20, "mb": 1 << 20, "g": 1 << 30, "gb": 1 << 30}
+	unit, ok := units[strings.ToLower(m[2])]
+	if !ok {
+		return "", false
+	}
+	mi := int64(math.Ceil(n * unit / (1 << 20)))
+	if mi%1024 == 0 {
+		return fmt.Sprintf("%dGi", mi/1024), true
+	}
+	return fmt.Sprintf("%dMi", mi), true
+}
+
+// healthCheck converts a compose health check to an exec check
+func (r *ComposeImport) healthCheck(spec *ServiceSpec, c composeService) {
+	h := c.HealthCheck
+	var command string
+	switch test := h.Test.(type) {
+	case string:
+		command = strings.TrimPrefix(test, "CMD-SHELL ")
+	case []interface{}:
+		args := make([]string, 0, len(test))
+		for _, arg := range test {
+			args = append(args, fmt.Sprint(arg))
+		}
+		if len(args) > 0 && (args[0] == "CMD" || args[0] == "CMD-SHELL") {
+			args = args[1:]
+		} else if len(args) > 0 && args[0] == "NONE" {
+			return
+		}
+		command = strings.Join(args, " ")
+	}
+	if command == "" {
+		r.unsupported(spec.Name, "healthcheck", "the health check has no test")
+		return
+	}
+
+	seconds := func(key, value string, fallback int32) int32 {
+		if value == "" {
+			return fallback
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			r.unsupported(spec.Name, "healthcheck."+key, fmt.Sprintf("%s is not a duration", value))
+			return fallback
+		}
+		return int32(math.Ceil(d.Seconds()))
+	}
+	spec.HealthCheck = &domain.HealthCheck{
+		Type:                "exec",
+		Command:             command,
+		InitialDelaySeconds: seconds("start_period", h.StartPeriod, 0),
+		PeriodSeconds:       seconds("interval", h.Interval, 30),
+		TimeoutSeconds:      seconds("timeout", h.Timeout, 30),
+		FailureThreshold:    h.Retries,
+		SuccessThreshold:    1,
+	}
+	if spec.HealthCheck.FailureThreshold == 0 {
+		spec.HealthCheck.FailureThreshold = 3
+	}
+}
+
+func orDefault(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
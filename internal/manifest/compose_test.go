@@ -0,0 +1,127 @@
+package manifest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCompose = `
+version: "3.8"
+services:
+  web:
+    build:
+      context: ./web
+      dockerfile: Dockerfile.prod
+    ports:
+      - "8080:3000"
+      - "9229"
+    environment:
+      - NODE_ENV=production
+      - API_TOKEN
+    depends_on: [db]
+    labels:
+      traefik.http.routers.web.rule: Host(` + "`shop.example.com`" + `) && PathPrefix(` + "`/app`" + `)
+      traefik.http.routers.web.tls.certresolver: le
+      com.example.tier: frontend
+      com.example.description: The storefront
+  db:
+    image: postgres:16
+    environment:
+      POSTGRES_DB: shop
+    volumes:
+      - pgdata:/var/lib/postgresql/data
+      - ./init.sql:/docker-entrypoint-initdb.d/init.sql
+    deploy:
+      resources:
+        limits:
+          cpus: "0.5"
+          memory: 1g
+    healthcheck:
+      test: ["CMD", "pg_isready", "-U", "postgres"]
+      interval: 10s
+      retries: 5
+  cache:
+    image: redis:7
+    expose: ["6379"]
+    deploy:
+      replicas: 2
+networks:
+  default: {}
+volumes:
+  pgdata: {}
+`
+
+func TestFromCompose(t *testing.T) {
+	imported, err := FromCompose([]byte(testCompose), ComposeOptions{Repository: "https://github.com/acme/shop", Branch: "main"})
+	require.NoError(t, err)
+	require.Len(t, imported.Manifest.Services, 3)
+	services := map[string]ServiceSpec{}
+	for _, s := range imported.Manifest.Services {
+		services[s.Name] = s
+	}
+
+	web := services["web"]
+	assert.Equal(t, "webapp", web.Type)
+	assert.Equal(t, "https://github.com/acme/shop", web.Build.Repository)
+	assert.Equal(t, "web/Dockerfile.prod", web.Build.Dockerfile)
+	assert.Equal(t, map[string]string{"NODE_ENV": "production"}, web.Env)
+	assert.Equal(t, []PortSpec{
+		{Name: "http", Port: 8080, TargetPort: 3000, Protocol: "TCP", Public: true},
+		{Name: "tcp-9229", Port: 9229, TargetPort: 9229, Protocol: "TCP"},
+	}, web.Ports)
+	assert.Equal(t, map[string]string{"com.example.tier": "frontend"}, web.Labels)
+	require.NotNil(t, web.Ingress)
+	assert.Equal(t, []IngressSpec{{Domain: "shop.example.com", Path: "/app", Type: "http", TLS: true}}, *web.Ingress)
+
+	db := services["db"]
+	assert.Equal(t, "stateful_db", db.Type)
+	assert.Equal(t, "postgres:16", db.Build.Image)
+	assert.Equal(t, &ResourcesSpec{CPURequest: "500m", CPULimit: "500m", MemoryRequest: "1Gi", MemoryLimit: "1Gi", StorageSize: "1Gi"}, db.Resources)
+	require.NotNil(t, db.HealthCheck)
+	assert.Equal(t, "pg_isready -U postgres", db.HealthCheck.Command)
+	assert.Equal(t, int32(10), db.HealthCheck.PeriodSeconds)
+	assert.Equal(t, int32(5), db.HealthCheck.FailureThreshold)
+
+	cache := services["cache"]
+	assert.Equal(t, "webapp", cache.Type)
+	assert.False(t, cache.Ports[0].Public)
+	assert.Equal(t, &ScalingSpec{MinReplicas: 2, MaxReplicas: 2}, cache.Scaling)
+
+	keys := map[string]bool{}
+	for _, u := range imported.Unsupported {
+		keys[u.Service+"/"+u.Key] = true
+	}
+	assert.True(t, keys["/networks"])
+	assert.True(t, keys["db/volumes"], "bind mounts are reported")
+	assert.True(t, keys["web/depends_on"])
+	assert.True(t, keys["web/environment.API_TOKEN"])
+	assert.True(t, keys["web/labels.com.example.description"])
+}
+
+func TestFromComposeSkipsLocalBuildsWithoutRepository(t *testing.T) {
+	imported, err := FromCompose([]byte("services:\n  api:\n    build: .\n  proxy:\n    image: nginx\n"), ComposeOptions{})
+	require.NoError(t, err)
+	require.Len(t, imported.Manifest.Services, 1)
+	assert.Equal(t, "proxy", imported.Manifest.Services[0].Name)
+	assert.Equal(t, "worker", imported.Manifest.Services[0].Type)
+	assert.Equal(t, []Unsupported{{Service: "api", Key: "build", Reason: "local build contexts need the repository holding them; the service was skipped"}}, imported.Unsupported)
+
+	_, err = FromCompose([]byte("version: '3'\n"), ComposeOptions{})
+	assert.Error(t, err)
+}
+
+func TestComposeQuantities(t *testing.T) {
+	for in, want := range map[interface{}]string{"512m": "512Mi", "1g": "1Gi", "1.5gb": "1536Mi", float64(1048576): "1Mi"} {
+		got, ok := mebibytes(in)
+		assert.True(t, ok)
+		assert.Equal(t, want, got, in)
+	}
+	_, ok := mebibytes("12 parsecs")
+	assert.False(t, ok)
+
+	cpus, ok := millicores("0.25")
+	assert.True(t, ok)
+	assert.Equal(t, "250m", cpus)
+}
@@ -0,0 +1,303 @@
+// Package manifest reads northstack.yaml, a file checked into a repository
+// that declares the services of a project, and converges the project to it.
+package manifest
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// FileName is the conventional name of a manifest
+const FileName = "northstack.yaml"
+
+// Version is the manifest format this build reads
+const Version = 1
+
+// LabelManagedBy marks the services a manifest declares, which are the only
+// ones pruning deletes
+const (
+	LabelManagedBy = "northstack.io/managed-by"
+	ManagedByApply = "manifest"
+)
+
+// maxManifestBytes bounds the size of a manifest
+const maxManifestBytes = 1 << 20
+
+// Manifest is the desired state of a project's services
+type Manifest struct {
+	Version  int           `json:"version"`
+	Services []ServiceSpec `json:"services"`
+}
+
+// ServiceSpec declares one service. Omitted settings take the defaults of
+// services created through the API.
+type ServiceSpec struct {
+	Name        string              `json:"name"`
+	Slug        string              `json:"slug,omitempty"` // Defaults to the name as a DNS label
+	Type        string              `json:"type,omitempty"` // Defaults to webapp
+	Build       domain.BuildSource  `json:"build"`
+	Env         map[string]string   `json:"env,omitempty"`
+	Secrets     []string            `json:"secrets,omitempty"`
+	Ports       []PortSpec          `json:"ports,omitempty"`
+	Resources   *ResourcesSpec      `json:"resources,omitempty"`
+	Scaling     *ScalingSpec        `json:"scaling,omitempty"`
+	HealthCheck *domain.HealthCheck `json:"health_check,omitempty"`
+	Labels      map[string]string   `json:"labels,omitempty"`
+	// Ingress routes of the service. Omitted leaves existing routes alone; an
+	// empty list removes them.
+	Ingress *[]IngressSpec `json:"ingress,omitempty"`
+}
+
+// PortSpec is a port a service listens on
+type PortSpec struct {
+	Name       string `json:"name"`
+	Port       int32  `json:"port"`
+	TargetPort int32  `json:"target_port,omitempty"`
+	Protocol   string `json:"protocol,omitempty"` // TCP or UDP; defaults to TCP
+	Public     bool   `json:"public,omitempty"`
+}
+
+// ResourcesSpec are a service's compute requests and limits
+type ResourcesSpec struct {
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+	StorageSize   string `json:"storage_size,omitempty"`
+}
+
+// ScalingSpec bounds a service's replicas. Triggers, schedules and warm
+// standby are managed through the API and kept as they are.
+type ScalingSpec struct {
+	MinReplicas  int32 `json:"min_replicas"`
+	MaxReplicas  int32 `json:"max_replicas"`
+	TargetCPU    int32 `json:"target_cpu,omitempty"`
+	TargetMemory int32 `json:"target_memory,omitempty"`
+}
+
+// IngressSpec routes a domain and path to a service
+type IngressSpec struct {
+	Domain string `json:"domain"`
+	Path   string `json:"path,omitempty"` // Defaults to /
+	Type   string `json:"type,omitempty"` // http, grpc or tcp; defaults to http
+	TLS    bool   `json:"tls,omitempty"`  // Certificate from cert-manager
+}
+
+var (
+	slugPattern   = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	domainPattern = regexp.MustCompile(`^([a-z0-9]([-a-z0-9]*[a-z0-9])?\.)+[a-z]{2,}$`)
+	nonSlugChars  = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Parse decodes and validates a manifest, in YAML or JSON
+func Parse(data []byte) (*Manifest, error) {
+	if len(data) > maxManifestBytes {
+		return nil, errors.BadRequest(fmt.Sprintf("manifest is larger than %d bytes", maxManifestBytes))
+	}
+
+	m := &Manifest{}
+	if err := yaml.UnmarshalStrict(data, m); err != nil {
+		return nil, errors.BadRequest("invalid manifest: " + err.Error())
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate fills in defaults and checks the manifest
+func (m *Manifest) Validate() error {
+	var fields []errors.FieldError
+	invalid := func(field, message string) {
+		fields = append(fields, errors.FieldError{Field: field, Message: message})
+	}
+
+	if m.Version != Version {
+		invalid("version", fmt.Sprintf("must be %d", Version))
+	}
+
+	slugs := make(map[string]bool)
+	domains := make(map[string]bool)
+	for i := range m.Services {
+		s := &m.Services[i]
+		at := fmt.Sprintf("services[%d]", i)
+
+		if s.Name == "" {
+			invalid(at+".name", "is required")
+		}
+		if s.Slug == "" {
+			s.Slug = strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(s.Name), "-"), "-")
+		}
+		if !slugPattern.MatchString(s.Slug) || len(s.Slug) > 63 {
+			invalid(at+".slug", "must be a DNS label")
+		} else if slugs[s.Slug] {
+			invalid(at+".slug", "is declared twice")
+		}
+		slugs[s.Slug] = true
+
+		if s.Type == "" {
+			s.Type = string(domain.ServiceTypeWebApp)
+		}
+		switch domain.ServiceType(s.Type) {
+		case domain.ServiceTypeWebApp, domain.ServiceTypeWorker, domain.ServiceTypeCronJob, domain.ServiceTypeStatefulDB, domain.ServiceTypeStateless:
+		default:
+			invalid(at+".type", "must be one of webapp, worker, cronjob, stateful_db, stateless")
+		}
+
+		switch s.Build.Type {
+		case "git", "buildpack":
+			if s.Build.Repository == "" {
+				invalid(at+".build.repository", "is required for "+s.Build.Type+" builds")
+			}
+		case "docker":
+			if s.Build.Image == "" && s.Build.Repository == "" {
+				invalid(at+".build.image", "an image or a repository is required for docker builds")
+			}
+		default:
+			invalid(at+".build.type", "must be one of git, docker, buildpack")
+		}
+
+		ports := make(map[string]bool)
+		for j, p := range s.Ports {
+			pat := fmt.Sprintf("%s.ports[%d]", at, j)
+			if p.Name == "" {
+				invalid(pat+".name", "is required")
+			} else if ports[p.Name] {
+				invalid(pat+".name", "is declared twice")
+			}
+			ports[p.Name] = true
+			if p.Port < 1 || p.Port > 65535 {
+				invalid(pat+".port", "must be between 1 and 65535")
+			}
+			if p.TargetPort < 0 || p.TargetPort > 65535 {
+				invalid(pat+".target_port", "must be between 1 and 65535")
+			}
+			if p.Protocol != "" && p.Protocol != "TCP" && p.Protocol != "UDP" {
+				invalid(pat+".protocol", "must be TCP or UDP")
+			}
+		}
+
+		if s.Scaling != nil {
+			if s.Scaling.MinReplicas < 0 || s.Scaling.MaxReplicas < s.Scaling.MinReplicas {
+				invalid(at+".scaling", "needs 0 <= min_replicas <= max_replicas")
+			}
+		}
+
+		if s.HealthCheck != nil {
+			switch s.HealthCheck.Type {
+			case "http", "tcp", "exec":
+			default:
+				invalid(at+".health_check.type", "must be one of http, tcp, exec")
+			}
+		}
+
+		if s.Ingress != nil {
+			for j := range *s.Ingress {
+				in := &(*s.Ingress)[j]
+				iat := fmt.Sprintf("%s.ingress[%d]", at, j)
+				in.Domain = strings.ToLower(in.Domain)
+				if !domainPattern.MatchString(in.Domain) {
+					invalid(iat+".domain", "must be a hostname")
+				}
+				if in.Path == "" {
+					in.Path = "/"
+				}
+				if !strings.HasPrefix(in.Path, "/") || strings.ContainsAny(in.Path, " ?#") {
+					invalid(iat+".path", "must start with / and must not contain spaces, queries or fragments")
+				}
+				if in.Type == "" {
+					in.Type = string(domain.IngressTypeHTTP)
+				}
+				switch in.Type {
+				case "http", "grpc", "tcp":
+				default:
+					invalid(iat+".type", "must be one of http, grpc, tcp")
+				}
+				if domains[in.Domain+in.Path] {
+					invalid(iat+".domain", "the domain and path are routed twice")
+				}
+				domains[in.Domain+in.Path] = true
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return errors.Validation(fields)
+	}
+	return nil
+}
+
+// Service returns the service a spec declares, with the API's defaults
+func (s *ServiceSpec) Service(projectID uuid.UUID) *domain.Service {
+	service := &domain.Service{
+		ProjectID:   projectID,
+		Name:        s.Name,
+		Slug:        s.Slug,
+		Type:        domain.ServiceType(s.Type),
+		BuildSource: s.Build,
+		EnvVars:     s.Env,
+		SecretRefs:  s.Secrets,
+		HealthCheck: s.HealthCheck,
+		Labels:      map[string]string{},
+		Scaling: domain.ScalingConfig{
+			MinReplicas: 1,
+			MaxReplicas: 3,
+			TargetCPU:   80,
+		},
+		Resources: domain.ResourceLimits{
+			CPURequest:    "100m",
+			CPULimit:      "500m",
+			MemoryRequest: "128Mi",
+			MemoryLimit:   "512Mi",
+		},
+	}
+	// The commit is set by builds, not declared
+	service.BuildSource.CommitSHA = ""
+
+	for k, v := range s.Labels {
+		service.Labels[k] = v
+	}
+	service.Labels[LabelManagedBy] = ManagedByApply
+
+	if s.Scaling != nil {
+		service.Scaling = domain.ScalingConfig{
+			MinReplicas:  s.Scaling.MinReplicas,
+			MaxReplicas:  s.Scaling.MaxReplicas,
+			TargetCPU:    s.Scaling.TargetCPU,
+			TargetMemory: s.Scaling.TargetMemory,
+		}
+	}
+	if s.Resources != nil {
+		service.Resources = domain.ResourceLimits(*s.Resources)
+	}
+	if s.HealthCheck != nil && s.HealthCheck.SuccessThreshold == 0 {
+		check := *s.HealthCheck
+		check.SuccessThreshold = 1
+		service.HealthCheck = &check
+	}
+
+	for _, p := range s.Ports {
+		port := domain.ServicePort{
+			Name:       p.Name,
+			Port:       p.Port,
+			TargetPort: p.TargetPort,
+			Protocol:   p.Protocol,
+			Public:     p.Public,
+		}
+		if port.TargetPort == 0 {
+			port.TargetPort = port.Port
+		}
+		if port.Protocol == "" {
+			port.Protocol = "TCP"
+		}
+		service.Ports = append(service.Ports, port)
+	}
+
+	return service
+}
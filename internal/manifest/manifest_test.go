@@ -0,0 +1,159 @@
+package manifest
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	apperrors "github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `
+version: 1
+services:
+  - name: API Server
+    build:
+      type: git
+      repository: https://github.com/acme/api
+      branch: main
+    env:
+      LOG_LEVEL: info
+    ports:
+      - name: http
+        port: 8080
+        public: true
+    scaling:
+      min_replicas: 2
+      max_replicas: 4
+    ingress:
+      - domain: API.example.com
+        tls: true
+  - name: worker
+    type: worker
+    build:
+      type: docker
+      image: ghcr.io/acme/worker:1.0
+`
+
+func TestParseDefaults(t *testing.T) {
+	m, err := Parse([]byte(testManifest))
+	require.NoError(t, err)
+	require.Len(t, m.Services, 2)
+
+	api := m.Services[0]
+	assert.Equal(t, "api-server", api.Slug)
+	assert.Equal(t, "webapp", api.Type)
+	require.NotNil(t, api.Ingress)
+	assert.Equal(t, IngressSpec{Domain: "api.example.com", Path: "/", Type: "http", TLS: true}, (*api.Ingress)[0])
+
+	service := api.Service(uuid.New())
+	assert.Equal(t, int32(8080), service.Ports[0].TargetPort)
+	assert.Equal(t, "TCP", service.Ports[0].Protocol)
+	assert.Equal(t, ManagedByApply, service.Labels[LabelManagedBy])
+	assert.Equal(t, "128Mi", service.Resources.MemoryRequest)
+}
+
+func TestParseRejectsInvalidManifests(t *testing.T) {
+	_, err := Parse([]byte("version: 1\nservices:\n  - name: a\n    colour: blue\n"))
+	assert.Error(t, err, "unknown fields are rejected")
+
+	_, err = Parse([]byte(`
+version: 2
+services:
+  - name: api
+    build: {type: git}
+    ports: [{name: http, port: 70000}]
+  - name: api
+    build: {type: docker, image: nginx}
+`))
+	var appErr *apperrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	fields := map[string]bool{}
+	for _, f := range appErr.Details.([]apperrors.FieldError) {
+		fields[f.Field] = true
+	}
+	assert.True(t, fields["version"])
+	assert.True(t, fields["services[0].build.repository"])
+	assert.True(t, fields["services[0].ports[0].port"])
+	assert.True(t, fields["services[1].slug"])
+}
+
+// memoryServices is a ServiceRepository over a map
+type memoryServices struct {
+	domain.ServiceRepository
+	services map[uuid.UUID]*domain.Service
+}
+
+func (r *memoryServices) ListByProject(ctx context.Context, projectID uuid.UUID, filter domain.ServiceFilter) ([]*domain.Service, error) {
+	var result []*domain.Service
+	for _, s := range r.services {
+		if s.ProjectID == projectID {
+			copied := *s
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryServices) Create(ctx context.Context, s *domain.Service) error {
+	r.services[s.ID] = s
+	return nil
+}
+
+func (r *memoryServices) Update(ctx context.Context, s *domain.Service) error {
+	r.services[s.ID] = s
+	return nil
+}
+
+func (r *memoryServices) Delete(ctx context.Context, id uuid.UUID) error {
+	delete(r.services, id)
+	return nil
+}
+
+func actions(result *Result) map[string]string {
+	byName := map[string]string{}
+	for _, c := range result.Changes {
+		byName[c.Name] = c.Action
+	}
+	return byName
+}
+
+func TestApply(t *testing.T) {
+	projectID := uuid.New()
+	repo := &memoryServices{services: map[uuid.UUID]*domain.Service{}}
+	applier := NewApplier(repo, nil, nil, logger.New("error", "json", io.Discard))
+
+	m, err := Parse([]byte("version: 1\nservices:\n  - name: api\n    build: {type: docker, image: nginx}\n  - name: old\n    build: {type: docker, image: nginx}\n"))
+	require.NoError(t, err)
+
+	result, err := applier.Apply(context.Background(), projectID, m, Options{DryRun: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"api": ActionCreate, "old": ActionCreate}, actions(result))
+	assert.Empty(t, repo.services, "a dry run writes nothing")
+
+	_, err = applier.Apply(context.Background(), projectID, m, Options{})
+	require.NoError(t, err)
+	assert.Len(t, repo.services, 2)
+
+	result, err = applier.Apply(context.Background(), projectID, m, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"api": ActionUnchanged, "old": ActionUnchanged}, actions(result), "applying twice changes nothing")
+
+	// A service created through the API is never pruned
+	manual := &domain.Service{ID: uuid.New(), ProjectID: projectID, Slug: "manual"}
+	repo.services[manual.ID] = manual
+
+	m.Services = m.Services[:1]
+	m.Services[0].Env = map[string]string{"A": "1"}
+	result, err = applier.Apply(context.Background(), projectID, m, Options{Prune: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"api": ActionUpdate, "old": ActionDelete}, actions(result))
+	assert.Equal(t, []string{"env"}, result.Changes[0].Fields)
+	assert.Len(t, repo.services, 2)
+	assert.Contains(t, repo.services, manual.ID)
+}
@@ -0,0 +1,163 @@
+// Package metering rolls per-service CPU, memory and network egress samples
+// from the MetricsCollector up into hourly usage records and reports usage
+// per project, as the basis for chargeback and billing.
+package metering
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// checkInterval is how often the meter looks for completed hours to roll up
+	checkInterval = 5 * time.Minute
+	// sampleStep is the resolution, in seconds, of the samples that are integrated
+	sampleStep = 60
+)
+
+// Meter produces hourly usage records
+type Meter struct {
+	config      *config.MeteringConfig
+	usageRepo   domain.UsageRepository
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	metrics     domain.MetricsCollector
+	logger      *logger.Logger
+}
+
+// NewMeter creates a new Meter
+func NewMeter(
+	cfg *config.MeteringConfig,
+	usageRepo domain.UsageRepository,
+	projectRepo domain.ProjectRepository,
+	serviceRepo domain.ServiceRepository,
+	metrics domain.MetricsCollector,
+	log *logger.Logger,
+) *Meter {
+	return &Meter{
+		config:      cfg,
+		usageRepo:   usageRepo,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		metrics:     metrics,
+		logger:      log,
+	}
+}
+
+// Run rolls up each hour once it has completed, until ctx is cancelled
+func (m *Meter) Run(ctx context.Context) {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		m.rollupPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rollupPending rolls up every completed hour since the last rollup, going
+// back at most Backfill
+func (m *Meter) rollupPending(ctx context.Context) {
+	last := time.Now().UTC().Truncate(time.Hour).Add(-time.Hour)
+	start := last.Add(-m.config.Backfill).Add(time.Hour)
+
+	latest, err := m.usageRepo.LatestHour(ctx)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to read usage rollup progress")
+		return
+	}
+	if latest != nil && latest.UTC().Add(time.Hour).After(start) {
+		start = latest.UTC().Add(time.Hour)
+	}
+
+	for hour := start; !hour.After(last); hour = hour.Add(time.Hour) {
+		if err := m.rollupHour(ctx, hour); err != nil {
+			m.logger.Warn().Err(err).Time("hour", hour).Msg("Usage rollup failed, will retry")
+			return
+		}
+	}
+}
+
+// rollupHour records the usage of every service during the hour starting at hour
+func (m *Meter) rollupHour(ctx context.Context, hour time.Time) error {
+	projects, err := m.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		return err
+	}
+
+	window := domain.TimeRange{
+		Start: hour.Unix(),
+		End:   hour.Add(time.Hour).Unix() - sampleStep,
+		Step:  sampleStep,
+	}
+
+	recorded := 0
+	for _, project := range projects {
+		services, err := m.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			return err
+		}
+
+		for _, svc := range services {
+			samples, err := m.metrics.GetServiceMetrics(ctx, svc.ID, window)
+			if err != nil {
+				return err
+			}
+
+			cpu, memory, egress := rollup(samples, sampleStep)
+			if cpu == 0 && memory == 0 && egress == 0 {
+				continue
+			}
+
+			err = m.usageRepo.Upsert(ctx, &domain.UsageRecord{
+				ServiceID:      svc.ID,
+				ProjectID:      project.ID,
+				Hour:           hour,
+				CPUCoreHours:   cpu,
+				MemoryGiBHours: memory,
+				EgressBytes:    egress,
+			})
+			if err != nil {
+				return err
+			}
+			recorded++
+		}
+	}
+
+	m.logger.Info().Time("hour", hour).Int("services", recorded).Msg("Usage rolled up")
+	return nil
+}
+
+// Report summarizes a project's usage for hours in [from, to)
+func (m *Meter) Report(ctx context.Context, projectID uuid.UUID, from, to time.Time) (*Report, error) {
+	records, err := m.usageRepo.ListByProject(ctx, projectID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := aggregate(projectID, from, to, records)
+
+	services, err := m.serviceRepo.ListByProject(ctx, projectID, domain.ServiceFilter{})
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(services))
+	for _, svc := range services {
+		names[svc.ID] = svc.Name
+	}
+	for _, s := range report.Services {
+		s.Name = names[s.ServiceID] // Empty for deleted services
+	}
+
+	return report, nil
+}
@@ -0,0 +1,122 @@
+package metering
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+)
+
+const gib = 1 << 30
+
+// Usage is an amount of consumed resources
+type Usage struct {
+	CPUCoreHours   float64 `json:"cpu_core_hours"`
+	MemoryGiBHours float64 `json:"memory_gib_hours"`
+	EgressGB       float64 `json:"egress_gb"`
+}
+
+// ServiceUsage is the usage of one service over a report period
+type ServiceUsage struct {
+	ServiceID uuid.UUID `json:"service_id"`
+	Name      string    `json:"name,omitempty"`
+	Usage
+}
+
+// DailyUsage is the usage of a project during one UTC day
+type DailyUsage struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Usage
+}
+
+// Report is the usage of a project over a period
+type Report struct {
+	ProjectID uuid.UUID       `json:"project_id"`
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Total     Usage           `json:"total"`
+	Services  []*ServiceUsage `json:"services"`
+	Daily     []*DailyUsage   `json:"daily"`
+}
+
+// rollup integrates a service's metric samples over an hour. Each sample
+// stands for step seconds, so hours in which the service only ran part of
+// the time are charged for that part only.
+func rollup(m *domain.ServiceMetrics, step int64) (cpuCoreHours, memoryGiBHours, egressBytes float64) {
+	hours := float64(step) / 3600
+	for _, p := range m.CPUUsage {
+		cpuCoreHours += p.Value * hours
+	}
+	for _, p := range m.MemoryUsage {
+		memoryGiBHours += p.Value / gib * hours
+	}
+	for _, p := range m.NetworkEgress {
+		egressBytes += p.Value * float64(step)
+	}
+	return cpuCoreHours, memoryGiBHours, egressBytes
+}
+
+// aggregate builds a report from hourly records
+func aggregate(projectID uuid.UUID, from, to time.Time, records []*domain.UsageRecord) *Report {
+	report := &Report{ProjectID: projectID, From: from, To: to, Services: []*ServiceUsage{}, Daily: []*DailyUsage{}}
+
+	services := make(map[uuid.UUID]*ServiceUsage)
+	days := make(map[string]*DailyUsage)
+	for _, r := range records {
+		svc, ok := services[r.ServiceID]
+		if !ok {
+			svc = &ServiceUsage{ServiceID: r.ServiceID}
+			services[r.ServiceID] = svc
+			report.Services = append(report.Services, svc)
+		}
+		date := r.Hour.UTC().Format("2006-01-02")
+		day, ok := days[date]
+		if !ok {
+			day = &DailyUsage{Date: date}
+			days[date] = day
+			report.Daily = append(report.Daily, day)
+		}
+
+		for _, u := range []*Usage{&report.Total, &svc.Usage, &day.Usage} {
+			u.add(r)
+		}
+	}
+
+	for _, u := range append([]*Usage{&report.Total}, usages(report)...) {
+		u.round()
+	}
+	sort.Slice(report.Services, func(i, j int) bool {
+		return report.Services[i].CPUCoreHours > report.Services[j].CPUCoreHours
+	})
+	sort.Slice(report.Daily, func(i, j int) bool { return report.Daily[i].Date < report.Daily[j].Date })
+	return report
+}
+
+func usages(report *Report) []*Usage {
+	var all []*Usage
+	for _, s := range report.Services {
+		all = append(all, &s.Usage)
+	}
+	for _, d := range report.Daily {
+		all = append(all, &d.Usage)
+	}
+	return all
+}
+
+func (u *Usage) add(r *domain.UsageRecord) {
+	u.CPUCoreHours += r.CPUCoreHours
+	u.MemoryGiBHours += r.MemoryGiBHours
+	u.EgressGB += r.EgressBytes / 1e9
+}
+
+func (u *Usage) round() {
+	u.CPUCoreHours = round(u.CPUCoreHours)
+	u.MemoryGiBHours = round(u.MemoryGiBHours)
+	u.EgressGB = round(u.EgressGB)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
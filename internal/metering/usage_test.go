@@ -0,0 +1,47 @@
+package metering
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollup(t *testing.T) {
+	// Half an hour of samples at 2 cores, 1 GiB and 1 MB/s egress
+	m := &domain.ServiceMetrics{}
+	for i := 0; i < 30; i++ {
+		m.CPUUsage = append(m.CPUUsage, domain.MetricPoint{Value: 2})
+		m.MemoryUsage = append(m.MemoryUsage, domain.MetricPoint{Value: gib})
+		m.NetworkEgress = append(m.NetworkEgress, domain.MetricPoint{Value: 1e6})
+	}
+
+	cpu, memory, egress := rollup(m, 60)
+	assert.InDelta(t, 1.0, cpu, 1e-9)
+	assert.InDelta(t, 0.5, memory, 1e-9)
+	assert.InDelta(t, 1.8e9, egress, 1e-3)
+}
+
+func TestAggregate(t *testing.T) {
+	project, api, worker := uuid.New(), uuid.New(), uuid.New()
+	day := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	records := []*domain.UsageRecord{
+		{ServiceID: worker, Hour: day, CPUCoreHours: 0.5, EgressBytes: 2e9},
+		{ServiceID: api, Hour: day.Add(time.Hour), CPUCoreHours: 1, MemoryGiBHours: 2},
+		{ServiceID: api, Hour: day.Add(25 * time.Hour), CPUCoreHours: 1, MemoryGiBHours: 2},
+	}
+
+	report := aggregate(project, day, day.Add(48*time.Hour), records)
+	assert.Equal(t, Usage{CPUCoreHours: 2.5, MemoryGiBHours: 4, EgressGB: 2}, report.Total)
+	if assert.Len(t, report.Services, 2) {
+		assert.Equal(t, api, report.Services[0].ServiceID)
+		assert.Equal(t, 2.0, report.Services[0].CPUCoreHours)
+	}
+	if assert.Len(t, report.Daily, 2) {
+		assert.Equal(t, "2026-06-01", report.Daily[0].Date)
+		assert.Equal(t, 1.5, report.Daily[0].CPUCoreHours)
+	}
+}
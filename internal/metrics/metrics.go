@@ -0,0 +1,181 @@
+// Package metrics provides Prometheus instrumentation for the orchestrator.
+// Collectors are package-level so any component can record against them;
+// Init registers them under the configured namespace and subsystem.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/northstack/platform/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// Registry holds every orchestrator collector
+	Registry = prometheus.NewRegistry()
+
+	initOnce sync.Once
+)
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Total number of HTTP requests",
+		},
+		[]string{"route", "method", "status"},
+	)
+	HTTPDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of HTTP requests in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route", "method"},
+	)
+	AdapterCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "adapter_calls_total",
+			Help: "Total number of calls to external systems",
+		},
+		[]string{"system", "method", "result"},
+	)
+	AdapterDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "adapter_call_duration_seconds",
+			Help:    "Duration of calls to external systems in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"system", "method"},
+	)
+	EventsPublished = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "events_published_total",
+			Help: "Total number of events published to the event bus",
+		},
+		[]string{"subject", "result"},
+	)
+	EventsConsumed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "events_consumed_total",
+			Help: "Total number of events consumed from the event bus",
+		},
+		[]string{"subject", "result"},
+	)
+	EventSchemaMismatches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "event_schema_mismatches_total",
+			Help: "Total number of events that did not match their subject's schema",
+		},
+		[]string{"subject", "stage"},
+	)
+)
+
+// Init registers the collectors, prefixed with the configured namespace and subsystem
+func Init(cfg *config.MetricsConfig) {
+	initOnce.Do(func() {
+		Registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+
+		var registerer prometheus.Registerer = Registry
+		if prefix := prefix(cfg); prefix != "" {
+			registerer = prometheus.WrapRegistererWithPrefix(prefix, Registry)
+		}
+		registerer.MustRegister(
+			HTTPRequests,
+			HTTPDuration,
+			AdapterCalls,
+			AdapterDuration,
+			EventsPublished,
+			EventsConsumed,
+			EventSchemaMismatches,
+		)
+	})
+}
+
+// Register adds additional collectors to the orchestrator registry
+func Register(cs ...prometheus.Collector) {
+	Registry.MustRegister(cs...)
+}
+
+// Handler returns the HTTP handler exposing the registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+}
+
+// HTTPMiddleware records request count and latency by route
+func HTTPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched" // 404s would otherwise explode cardinality
+		}
+
+		HTTPRequests.WithLabelValues(route, c.Request.Method, fmt.Sprintf("%d", c.Writer.Status())).Inc()
+		HTTPDuration.WithLabelValues(route, c.Request.Method).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveAdapterCall records a call to an external system
+func ObserveAdapterCall(system, method string, start time.Time, resp *http.Response, err error) {
+	AdapterCalls.WithLabelValues(system, method, result(resp, err)).Inc()
+	AdapterDuration.WithLabelValues(system, method).Observe(time.Since(start).Seconds())
+}
+
+// ObservePublish records an event publish attempt
+func ObservePublish(subject string, err error) {
+	EventsPublished.WithLabelValues(subject, outcome(err)).Inc()
+}
+
+// ObserveConsume records the outcome of handling a consumed event
+func ObserveConsume(subject string, err error) {
+	EventsConsumed.WithLabelValues(subject, outcome(err)).Inc()
+}
+
+// ObserveSchemaMismatch records an event that did not match its schema, at
+// the publish or consume stage
+func ObserveSchemaMismatch(subject, stage string) {
+	EventSchemaMismatches.WithLabelValues(subject, stage).Inc()
+}
+
+func prefix(cfg *config.MetricsConfig) string {
+	var parts []string
+	if cfg.Namespace != "" {
+		parts = append(parts, cfg.Namespace)
+	}
+	if cfg.Subsystem != "" {
+		parts = append(parts, cfg.Subsystem)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, "_") + "_"
+}
+
+func result(resp *http.Response, err error) string {
+	if err != nil || resp == nil {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", resp.StatusCode/100)
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,195 @@
+// Package natsauth issues NATS user credentials with scoped permissions, for
+// tenants to subscribe to their own events and for cluster agents to answer
+// the requests of their own cluster. Users are JWTs signed by an account key,
+// as used by NATS servers in operator mode.
+package natsauth
+
+import (
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nkeys"
+)
+
+// jwtHeader is the header of every NATS JWT
+const jwtHeader = `{"typ":"JWT","alg":"ed25519-nkey"}`
+
+// Credentials are a NATS user JWT and the seed of its key
+type Credentials struct {
+	JWT       string
+	Seed      string
+	ExpiresAt time.Time
+}
+
+// File returns the credentials in the .creds format NATS clients load
+func (c *Credentials) File() string {
+	return fmt.Sprintf(`-----BEGIN NATS USER JWT-----
+%s
+------END NATS USER JWT------
+
+************************* IMPORTANT *************************
+NKEY Seed printed below can be used to sign and prove identity.
+NKEYs are sensitive and should be treated as secrets.
+
+-----BEGIN USER NKEY SEED-----
+%s
+------END USER NKEY SEED------
+
+*************************************************************
+`, c.JWT, c.Seed)
+}
+
+// Issuer signs user JWTs with an account key
+type Issuer struct {
+	signer  nkeys.KeyPair
+	issuer  string // Public key of the signer
+	account string // Public key of the account, when the signer is one of its signing keys
+}
+
+// NewIssuer creates an Issuer from the seed of an account or of one of its
+// signing keys; account is the account's public key in the latter case
+func NewIssuer(seed, account string) (*Issuer, error) {
+	signer, err := nkeys.FromSeed([]byte(seed))
+	if err != nil {
+		return nil, fmt.Errorf("invalid account seed: %w", err)
+	}
+	issuer, err := signer.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if !nkeys.IsValidPublicAccountKey(issuer) {
+		return nil, fmt.Errorf("the account seed must be an account key (SA...)")
+	}
+	if account == issuer {
+		account = ""
+	}
+	if account != "" && !nkeys.IsValidPublicAccountKey(account) {
+		return nil, fmt.Errorf("invalid account public key %q", account)
+	}
+
+	return &Issuer{signer: signer, issuer: issuer, account: account}, nil
+}
+
+// userClaims are the claims of a NATS user JWT (version 2)
+type userClaims struct {
+	ID        string    `json:"jti,omitempty"`
+	IssuedAt  int64     `json:"iat"`
+	Issuer    string    `json:"iss"`
+	Name      string    `json:"name"`
+	Subject   string    `json:"sub"`
+	ExpiresAt int64     `json:"exp,omitempty"`
+	NATS      natsClaim `json:"nats"`
+}
+
+type natsClaim struct {
+	Pub           permission `json:"pub"`
+	Sub           permission `json:"sub"`
+	Subs          int64      `json:"subs"`
+	Data          int64      `json:"data"`
+	Payload       int64      `json:"payload"`
+	Resp          *responses `json:"resp,omitempty"`
+	IssuerAccount string     `json:"issuer_account,omitempty"`
+	Type          string     `json:"type"`
+	Version       int        `json:"version"`
+}
+
+type permission struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// responses lets a user publish to the reply subjects of the requests it
+// received, up to Max replies each
+type responses struct {
+	Max int   `json:"max"`
+	TTL int64 `json:"ttl"` // Nanoseconds a reply subject stays open; zero for no limit
+}
+
+// Subscriber issues credentials for a new user that may subscribe to the
+// given subjects and publish nothing
+func (i *Issuer) Subscriber(name string, subjects []string, ttl time.Duration) (*Credentials, error) {
+	return i.user(name, natsClaim{
+		Pub: permission{Deny: []string{">"}},
+		Sub: permission{Allow: subjects},
+	}, ttl)
+}
+
+// Responder issues credentials for a new user that may subscribe and publish
+// to the given subjects only, and reply once to each request it receives.
+// Replies go to the requester's inbox without granting the user the inboxes
+// of other requesters.
+func (i *Issuer) Responder(name string, subjects []string, ttl time.Duration) (*Credentials, error) {
+	return i.user(name, natsClaim{
+		Pub:  permission{Allow: subjects},
+		Sub:  permission{Allow: subjects},
+		Resp: &responses{Max: 1},
+	}, ttl)
+}
+
+// user issues credentials for a new user with the permissions of claim
+func (i *Issuer) user(name string, claim natsClaim, ttl time.Duration) (*Credentials, error) {
+	user, err := nkeys.CreateUser()
+	if err != nil {
+		return nil, err
+	}
+	userKey, err := user.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	seed, err := user.Seed()
+	if err != nil {
+		return nil, err
+	}
+
+	claim.Subs = -1
+	claim.Data = -1
+	claim.Payload = -1
+	claim.IssuerAccount = i.account
+	claim.Type = "user"
+	claim.Version = 2
+
+	now := time.Now()
+	claims := userClaims{
+		IssuedAt: now.Unix(),
+		Issuer:   i.issuer,
+		Name:     name,
+		Subject:  userKey,
+		NATS:     claim,
+	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = now.Add(ttl).Truncate(time.Second)
+		claims.ExpiresAt = expiresAt.Unix()
+	}
+
+	token, err := i.sign(&claims)
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{JWT: token, Seed: string(seed), ExpiresAt: expiresAt}, nil
+}
+
+// sign encodes claims as a JWT; the ID is the hash of the claims without it
+func (i *Issuer) sign(claims *userClaims) (string, error) {
+	claims.ID = ""
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	hash := sha512.Sum512_256(payload)
+	claims.ID = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(hash[:])
+
+	if payload, err = json.Marshal(claims); err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(jwtHeader)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := i.signer.Sign([]byte(unsigned))
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,91 @@
+package natsauth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nkeys"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscriberCredentials(t *testing.T) {
+	account, err := nkeys.CreateAccount()
+	require.NoError(t, err)
+	seed, err := account.Seed()
+	require.NoError(t, err)
+	accountKey, err := account.PublicKey()
+	require.NoError(t, err)
+
+	issuer, err := NewIssuer(string(seed), "")
+	require.NoError(t, err)
+
+	creds, err := issuer.Subscriber("team-a", []string{"tenant.a.>"}, time.Hour)
+	require.NoError(t, err)
+
+	// The file parses like one written by nsc
+	token, err := nkeys.ParseDecoratedJWT([]byte(creds.File()))
+	require.NoError(t, err)
+	assert.Equal(t, creds.JWT, token)
+	user, err := nkeys.ParseDecoratedUserNKey([]byte(creds.File()))
+	require.NoError(t, err)
+	userKey, err := user.PublicKey()
+	require.NoError(t, err)
+
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	require.NoError(t, err)
+	assert.NoError(t, account.Verify([]byte(parts[0]+"."+parts[1]), signature))
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims userClaims
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, accountKey, claims.Issuer)
+	assert.Equal(t, userKey, claims.Subject)
+	assert.Equal(t, []string{"tenant.a.>"}, claims.NATS.Sub.Allow)
+	assert.Equal(t, []string{">"}, claims.NATS.Pub.Deny)
+	assert.Equal(t, creds.ExpiresAt.Unix(), claims.ExpiresAt)
+	assert.NotEmpty(t, claims.ID)
+}
+
+func TestResponderCredentials(t *testing.T) {
+	account, err := nkeys.CreateAccount()
+	require.NoError(t, err)
+	seed, err := account.Seed()
+	require.NoError(t, err)
+
+	issuer, err := NewIssuer(string(seed), "")
+	require.NoError(t, err)
+
+	creds, err := issuer.Responder("agent-a", []string{"agent.a.>"}, 0)
+	require.NoError(t, err)
+	assert.True(t, creds.ExpiresAt.IsZero())
+
+	parts := strings.Split(creds.JWT, ".")
+	require.Len(t, parts, 3)
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var claims userClaims
+	require.NoError(t, json.Unmarshal(payload, &claims))
+	assert.Equal(t, []string{"agent.a.>"}, claims.NATS.Pub.Allow)
+	assert.Equal(t, []string{"agent.a.>"}, claims.NATS.Sub.Allow)
+	assert.Empty(t, claims.NATS.Pub.Deny)
+	require.NotNil(t, claims.NATS.Resp, "replies to requests are allowed")
+	assert.Equal(t, 1, claims.NATS.Resp.Max)
+	assert.Zero(t, claims.ExpiresAt)
+}
+
+func TestNewIssuerRejectsUserSeeds(t *testing.T) {
+	user, err := nkeys.CreateUser()
+	require.NoError(t, err)
+	seed, err := user.Seed()
+	require.NoError(t, err)
+
+	_, err = NewIssuer(string(seed), "")
+	assert.Error(t, err)
+}
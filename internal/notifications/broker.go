@@ -0,0 +1,79 @@
+package notifications
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Stream event names
+const (
+	EventNotification = "notification"
+	EventRead         = "read"
+)
+
+// streamBuffer is how many messages a slow stream may fall behind before
+// further messages are dropped; clients resync from the list endpoint
+const streamBuffer = 16
+
+// Message is an inbox change sent to a user's streams
+type Message struct {
+	Event string
+	Data  interface{}
+}
+
+// ReadUpdate reports notifications marked read; ID is nil when all were
+type ReadUpdate struct {
+	ID          *uuid.UUID `json:"id,omitempty"`
+	UnreadCount int64      `json:"unread_count"`
+}
+
+// broker fans messages out to the streams connected to this replica
+type broker struct {
+	mu      sync.RWMutex
+	streams map[uuid.UUID]map[chan Message]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{streams: make(map[uuid.UUID]map[chan Message]struct{})}
+}
+
+func (b *broker) subscribe(userID uuid.UUID) (<-chan Message, func()) {
+	ch := make(chan Message, streamBuffer)
+
+	b.mu.Lock()
+	if b.streams[userID] == nil {
+		b.streams[userID] = make(map[chan Message]struct{})
+	}
+	b.streams[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.streams[userID], ch)
+			if len(b.streams[userID]) == 0 {
+				delete(b.streams, userID)
+			}
+			b.mu.Unlock()
+		})
+	}
+}
+
+func (b *broker) has(userID uuid.UUID) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.streams[userID]) > 0
+}
+
+func (b *broker) publish(userID uuid.UUID, msg Message) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.streams[userID] {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package notifications keeps a per-user in-product inbox. Notifications are
+// persisted so they survive reconnects, and pushed to the user's open SSE
+// streams on every replica through the event bus. External channels such as
+// Slack or email are separate; the inbox is always on.
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Subjects announcing inbox changes to every replica
+const (
+	SubjectCreated = "notification.created"
+	SubjectRead    = "notification.read"
+)
+
+// Center creates notifications and delivers them to connected users
+type Center struct {
+	repo        domain.NotificationRepository
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	deployRepo  domain.DeploymentRepository
+	eventBus    domain.EventBus
+	policy      domain.NotificationPolicy
+	broker      *broker
+	logger      *logger.Logger
+}
+
+// NewCenter creates a new Center. serviceRepo and deployRepo may be nil, in
+// which case deploy notifications name services by ID and only reach the
+// project owner. Without an event bus, streams only see changes made on this
+// replica.
+func NewCenter(
+	repo domain.NotificationRepository,
+	projectRepo domain.ProjectRepository,
+	serviceRepo domain.ServiceRepository,
+	deployRepo domain.DeploymentRepository,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *Center {
+	return &Center{
+		repo:        repo,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		deployRepo:  deployRepo,
+		eventBus:    eventBus,
+		broker:      newBroker(),
+		logger:      log,
+	}
+}
+
+// UsePolicy makes the center only notify users of the events their
+// notification preferences let reach the inbox
+func (c *Center) UsePolicy(policy domain.NotificationPolicy) {
+	c.policy = policy
+}
+
+// Notify stores a notification in the user's inbox and pushes it to their open streams
+func (c *Center) Notify(ctx context.Context, n *domain.UserNotification) error {
+	if n.UserID == uuid.Nil {
+		return errors.BadRequest("notification has no recipient")
+	}
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now().UTC()
+	}
+
+	if err := c.repo.Create(ctx, n); err != nil {
+		return err
+	}
+
+	c.announce(ctx, SubjectCreated, n.UserID, n.ID)
+	return nil
+}
+
+// List returns a page of the user's notifications and their unread count
+func (c *Center) List(ctx context.Context, userID uuid.UUID, filter domain.NotificationFilter) ([]*domain.UserNotification, int64, error) {
+	notifications, err := c.repo.ListByUser(ctx, userID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	unread, err := c.repo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, unread, nil
+}
+
+// UnreadCount returns the number of unread notifications of a user
+func (c *Center) UnreadCount(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return c.repo.CountUnread(ctx, userID)
+}
+
+// MarkRead marks one of the user's notifications as read
+func (c *Center) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	if err := c.repo.MarkRead(ctx, userID, id); err != nil {
+		return err
+	}
+
+	c.announce(ctx, SubjectRead, userID, id)
+	return nil
+}
+
+// MarkAllRead marks all of the user's notifications as read and returns how many were unread
+func (c *Center) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	n, err := c.repo.MarkAllRead(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if n > 0 {
+		c.announce(ctx, SubjectRead, userID, uuid.Nil)
+	}
+	return n, nil
+}
+
+// Subscribe registers a stream for the user's inbox changes. The returned
+// function must be called when the stream ends.
+func (c *Center) Subscribe(userID uuid.UUID) (<-chan Message, func()) {
+	return c.broker.subscribe(userID)
+}
+
+// Watch notifies project owners and deployers when deployments finish, and
+// forwards inbox changes made on any replica to the streams connected here
+func (c *Center) Watch(ctx context.Context) error {
+	if c.eventBus == nil {
+		return nil
+	}
+
+	for _, subject := range []string{"deploy.completed", "deploy.failed"} {
+		// A durable consumer so that each deployment is notified once across
+		// replicas, including those finished while none was running
+		if _, err := eventbus.SubscribeDurable(ctx, c.eventBus, subject, eventbus.DurableName("notifications", subject), func(event *domain.Event) error {
+			return c.notifyDeploy(ctx, event)
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Every replica pushes to the streams connected to it, so this one is not durable
+	_, err := c.eventBus.Subscribe(ctx, "notification.>", func(event *domain.Event) error {
+		userID, err := uuid.Parse(stringField(event.Data, "user_id"))
+		if err != nil {
+			return nil
+		}
+		id, _ := uuid.Parse(stringField(event.Data, "notification_id"))
+		c.deliver(ctx, event.Type, userID, id)
+		return nil
+	})
+	return err
+}
+
+// announce tells every replica about an inbox change, or only this one when
+// there is no event bus
+func (c *Center) announce(ctx context.Context, subject string, userID, id uuid.UUID) {
+	if c.eventBus == nil {
+		c.deliver(ctx, subject, userID, id)
+		return
+	}
+
+	data := map[string]interface{}{"user_id": userID.String()}
+	if id != uuid.Nil {
+		data["notification_id"] = id.String()
+	}
+	if err := c.eventBus.Publish(ctx, subject, &domain.Event{
+		Type:   subject,
+		Source: "notification-center",
+		Data:   data,
+	}); err != nil {
+		c.logger.Warn().Err(err).Str("event", subject).Msg("Failed to publish notification event")
+	}
+}
+
+// deliver pushes an inbox change to the user's streams on this replica
+func (c *Center) deliver(ctx context.Context, subject string, userID, id uuid.UUID) {
+	if !c.broker.has(userID) {
+		return
+	}
+
+	switch subject {
+	case SubjectCreated:
+		n, err := c.repo.GetByID(ctx, userID, id)
+		if err != nil {
+			c.logger.Warn().Err(err).Str("notification_id", id.String()).Msg("Failed to load notification for delivery")
+			return
+		}
+		c.broker.publish(userID, Message{Event: EventNotification, Data: n})
+	case SubjectRead:
+		unread, err := c.repo.CountUnread(ctx, userID)
+		if err != nil {
+			c.logger.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to count unread notifications")
+			return
+		}
+		read := ReadUpdate{UnreadCount: unread}
+		if id != uuid.Nil {
+			read.ID = &id
+		}
+		c.broker.publish(userID, Message{Event: EventRead, Data: read})
+	}
+}
+
+func stringField(data map[string]interface{}, key string) string {
+	s, _ := data[key].(string)
+	return s
+}
@@ -0,0 +1,127 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// notifyDeploy tells the project owner, and whoever triggered the deployment,
+// that a deployment finished. A redelivered event does not notify anyone
+// twice: the notifications it creates have IDs derived from the event.
+func (c *Center) notifyDeploy(ctx context.Context, event *domain.Event) error {
+	projectID, err := uuid.Parse(stringField(event.Data, "project_id"))
+	if err != nil {
+		return nil
+	}
+	project, err := c.projectRepo.GetByID(ctx, projectID)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		c.logger.Warn().Err(err).Str("project_id", projectID.String()).Msg("Failed to load project for deploy notification")
+		return err
+	}
+
+	recipients := []uuid.UUID{project.OwnerID}
+	if deployer := c.deployer(ctx, event); deployer != uuid.Nil && deployer != project.OwnerID {
+		recipients = append(recipients, deployer)
+	}
+
+	serviceName := stringField(event.Data, "service_id")
+	if serviceID, err := uuid.Parse(serviceName); err == nil && c.serviceRepo != nil {
+		if service, err := c.serviceRepo.GetByID(ctx, serviceID); err == nil {
+			serviceName = service.Name
+		}
+	}
+
+	var failed error
+	for _, userID := range recipients {
+		if c.policy != nil && !c.policy.Allows(ctx, domain.NotificationChannelInbox, &userID, &projectID, event.Type, "") {
+			continue
+		}
+		n := deployNotification(event, serviceName)
+		if n == nil {
+			return nil
+		}
+		n.UserID = userID
+		if event.ID != "" {
+			n.ID = notificationID(event.ID, userID)
+			if _, err := c.repo.GetByID(ctx, userID, n.ID); err == nil {
+				continue // Created before the event was redelivered
+			}
+		}
+		if err := c.Notify(ctx, n); err != nil {
+			c.logger.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to create deploy notification")
+			failed = err
+		}
+	}
+	return failed
+}
+
+// notificationID is the ID of the notification an event creates for a user
+func notificationID(eventID string, userID uuid.UUID) uuid.UUID {
+	return uuid.NewSHA1(userID, []byte(eventID))
+}
+
+// deployer returns the user who triggered the deployment, if it was a user
+func (c *Center) deployer(ctx context.Context, event *domain.Event) uuid.UUID {
+	if c.deployRepo == nil {
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(stringField(event.Data, "deployment_id"))
+	if err != nil {
+		return uuid.Nil
+	}
+	deployment, err := c.deployRepo.GetByID(ctx, id)
+	if err != nil {
+		return uuid.Nil
+	}
+	userID, err := uuid.Parse(deployment.TriggeredBy)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
+
+// deployNotification builds the notification for a deploy.completed or
+// deploy.failed event, without a recipient
+func deployNotification(event *domain.Event, serviceName string) *domain.UserNotification {
+	n := &domain.UserNotification{
+		Data: map[string]interface{}{},
+	}
+
+	switch event.Type {
+	case "deploy.completed":
+		n.Type = domain.NotificationDeploySucceeded
+		n.Title = fmt.Sprintf("%s deployed", serviceName)
+		if version := stringField(event.Data, "version"); version != "" {
+			n.Body = fmt.Sprintf("Version %s is live.", version)
+		}
+	case "deploy.failed":
+		n.Type = domain.NotificationDeployFailed
+		n.Title = fmt.Sprintf("%s deployment failed", serviceName)
+		n.Body = stringField(event.Data, "error")
+	default:
+		return nil
+	}
+
+	if id, err := uuid.Parse(stringField(event.Data, "project_id")); err == nil {
+		n.ProjectID = &id
+	}
+	if id, err := uuid.Parse(stringField(event.Data, "service_id")); err == nil {
+		n.ServiceID = &id
+	}
+	if id := stringField(event.Data, "deployment_id"); id != "" {
+		n.Link = "/deployments/" + id
+		n.Data["deployment_id"] = id
+	}
+	if version := stringField(event.Data, "version"); version != "" {
+		n.Data["version"] = version
+	}
+
+	return n
+}
@@ -0,0 +1,78 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeployNotification(t *testing.T) {
+	serviceID := uuid.New()
+	data := map[string]interface{}{
+		"deployment_id": "d1",
+		"service_id":    serviceID.String(),
+		"project_id":    uuid.New().String(),
+		"version":       "v1.4.0",
+	}
+
+	t.Run("completed", func(t *testing.T) {
+		n := deployNotification(&domain.Event{Type: "deploy.completed", Data: data}, "api")
+		require.NotNil(t, n)
+		assert.Equal(t, domain.NotificationDeploySucceeded, n.Type)
+		assert.Equal(t, "api deployed", n.Title)
+		assert.Equal(t, "Version v1.4.0 is live.", n.Body)
+		assert.Equal(t, "/deployments/d1", n.Link)
+		assert.Equal(t, serviceID, *n.ServiceID)
+	})
+
+	t.Run("failed carries the error", func(t *testing.T) {
+		failed := map[string]interface{}{"error": "image pull backoff"}
+		for k, v := range data {
+			failed[k] = v
+		}
+		n := deployNotification(&domain.Event{Type: "deploy.failed", Data: failed}, "api")
+		require.NotNil(t, n)
+		assert.Equal(t, domain.NotificationDeployFailed, n.Type)
+		assert.Equal(t, "image pull backoff", n.Body)
+	})
+
+	t.Run("other events are ignored", func(t *testing.T) {
+		assert.Nil(t, deployNotification(&domain.Event{Type: "deploy.started", Data: data}, "api"))
+	})
+}
+
+func TestBroker(t *testing.T) {
+	b := newBroker()
+	alice, bob := uuid.New(), uuid.New()
+
+	ch, cancel := b.subscribe(alice)
+	assert.True(t, b.has(alice))
+	assert.False(t, b.has(bob))
+
+	b.publish(bob, Message{Event: EventNotification})
+	b.publish(alice, Message{Event: EventRead})
+	assert.Equal(t, EventRead, (<-ch).Event)
+	assert.Empty(t, ch)
+
+	// A full stream drops messages rather than blocking the publisher
+	for i := 0; i < streamBuffer+5; i++ {
+		b.publish(alice, Message{Event: EventNotification})
+	}
+	assert.Len(t, ch, streamBuffer)
+
+	cancel()
+	cancel()
+	assert.False(t, b.has(alice))
+}
+
+func TestNotificationID(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+
+	// A redelivered event maps to the notifications it already created
+	assert.Equal(t, notificationID("evt-1", alice), notificationID("evt-1", alice))
+	assert.NotEqual(t, notificationID("evt-1", alice), notificationID("evt-1", bob))
+	assert.NotEqual(t, notificationID("evt-1", alice), notificationID("evt-2", alice))
+}
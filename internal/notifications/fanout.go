@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"context"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+// Fanout is a domain.Notifier sending through each of several notifiers,
+// such as Slack and email. Every notifier is tried; the first failure is
+// returned.
+type Fanout []domain.Notifier
+
+var _ domain.Notifier = Fanout(nil)
+
+// SendNotification sends a notification through every notifier
+func (f Fanout) SendNotification(ctx context.Context, notification *domain.Notification) error {
+	return f.each(func(n domain.Notifier) error { return n.SendNotification(ctx, notification) })
+}
+
+// SendBuildNotification sends a build status through every notifier
+func (f Fanout) SendBuildNotification(ctx context.Context, build *domain.Build) error {
+	return f.each(func(n domain.Notifier) error { return n.SendBuildNotification(ctx, build) })
+}
+
+// SendDeploymentNotification sends a deployment status through every notifier
+func (f Fanout) SendDeploymentNotification(ctx context.Context, deployment *domain.Deployment) error {
+	return f.each(func(n domain.Notifier) error { return n.SendDeploymentNotification(ctx, deployment) })
+}
+
+// SendAlertNotification sends an alert through every notifier
+func (f Fanout) SendAlertNotification(ctx context.Context, alert *domain.Alert) error {
+	return f.each(func(n domain.Notifier) error { return n.SendAlertNotification(ctx, alert) })
+}
+
+func (f Fanout) each(send func(domain.Notifier) error) error {
+	var firstErr error
+	for _, n := range f {
+		if err := send(n); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
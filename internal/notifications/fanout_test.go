@@ -0,0 +1,32 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingNotifier struct {
+	domain.Notifier
+	alerts int
+	err    error
+}
+
+func (n *countingNotifier) SendAlertNotification(context.Context, *domain.Alert) error {
+	n.alerts++
+	return n.err
+}
+
+func TestFanout(t *testing.T) {
+	failing := &countingNotifier{err: errors.New("smtp down")}
+	ok := &countingNotifier{}
+
+	err := Fanout{failing, ok}.SendAlertNotification(context.Background(), &domain.Alert{Name: "HighErrorRate"})
+	assert.EqualError(t, err, "smtp down")
+	// A failing notifier does not keep the others from sending
+	assert.Equal(t, 1, failing.alerts)
+	assert.Equal(t, 1, ok.alerts)
+}
@@ -0,0 +1,93 @@
+package notifications
+
+import (
+	"context"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+// Gate is a domain.Notifier passing builds, deploys and alerts on to the
+// notifier of a channel only when the policy lets them reach it. Plain
+// notifications, which name their recipient, always pass.
+type Gate struct {
+	channel  string
+	notifier domain.Notifier
+	policy   domain.NotificationPolicy
+}
+
+// NewGate creates a new Gate
+func NewGate(channel string, notifier domain.Notifier, policy domain.NotificationPolicy) *Gate {
+	return &Gate{
+		channel:  channel,
+		notifier: notifier,
+		policy:   policy,
+	}
+}
+
+var _ domain.Notifier = (*Gate)(nil)
+
+// SendNotification passes a notification on
+func (g *Gate) SendNotification(ctx context.Context, notification *domain.Notification) error {
+	return g.notifier.SendNotification(ctx, notification)
+}
+
+// SendBuildNotification passes a finished build on if its project lets it
+// reach the channel
+func (g *Gate) SendBuildNotification(ctx context.Context, build *domain.Build) error {
+	eventType := BuildEvent(build.Status)
+	if eventType != "" && !g.policy.Allows(ctx, g.channel, nil, &build.ProjectID, eventType, "") {
+		return nil
+	}
+	return g.notifier.SendBuildNotification(ctx, build)
+}
+
+// SendDeploymentNotification passes a finished deployment on if its project
+// lets it reach the channel
+func (g *Gate) SendDeploymentNotification(ctx context.Context, deployment *domain.Deployment) error {
+	eventType := DeploymentEvent(deployment.Status)
+	if eventType != "" && !g.policy.Allows(ctx, g.channel, nil, &deployment.ProjectID, eventType, "") {
+		return nil
+	}
+	return g.notifier.SendDeploymentNotification(ctx, deployment)
+}
+
+// SendAlertNotification passes an alert on if its project lets it reach the
+// channel. Alerts of no project always pass.
+func (g *Gate) SendAlertNotification(ctx context.Context, alert *domain.Alert) error {
+	if alert.ProjectID != nil && !g.policy.Allows(ctx, g.channel, nil, alert.ProjectID, AlertEvent(alert.Status), alert.Severity) {
+		return nil
+	}
+	return g.notifier.SendAlertNotification(ctx, alert)
+}
+
+// BuildEvent is the event type of a finished build, empty while it runs
+func BuildEvent(status domain.BuildStatus) string {
+	switch status {
+	case domain.BuildStatusSucceeded:
+		return domain.NotificationEventBuildCompleted
+	case domain.BuildStatusFailed:
+		return domain.NotificationEventBuildFailed
+	}
+	return ""
+}
+
+// DeploymentEvent is the event type of a finished deployment, empty while it runs
+func DeploymentEvent(status domain.DeploymentStatus) string {
+	switch status {
+	case domain.DeploymentStatusSucceeded:
+		return domain.NotificationEventDeployCompleted
+	case domain.DeploymentStatusFailed:
+		return domain.NotificationEventDeployFailed
+	case domain.DeploymentStatusRolledBack:
+		return domain.NotificationEventRollbackCompleted
+	}
+	return ""
+}
+
+// AlertEvent is the event type of an alert in a status
+func AlertEvent(status string) string {
+	if status == domain.AlertStatusResolved {
+		return domain.NotificationEventAlertResolved
+	}
+	return domain.NotificationEventAlertFired
+}
@@ -0,0 +1,52 @@
+package notifications
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	domain.Notifier
+	deployments int
+	alerts      int
+}
+
+func (n *recordingNotifier) SendDeploymentNotification(context.Context, *domain.Deployment) error {
+	n.deployments++
+	return nil
+}
+
+func (n *recordingNotifier) SendAlertNotification(context.Context, *domain.Alert) error {
+	n.alerts++
+	return nil
+}
+
+func TestGate(t *testing.T) {
+	projectID := uuid.New()
+	pref := &domain.NotificationPreference{ProjectID: &projectID, Channel: domain.NotificationChannelSlack, Events: []string{domain.NotificationEventDeployFailed, domain.NotificationEventAlertFired}}
+	policy := testPreferences(fakePreferences{preferences: []*domain.NotificationPreference{pref}}, time.Now())
+	notifier := &recordingNotifier{}
+	gate := NewGate(domain.NotificationChannelSlack, notifier, policy)
+	ctx := context.Background()
+
+	require.NoError(t, gate.SendDeploymentNotification(ctx, &domain.Deployment{ProjectID: projectID, Status: domain.DeploymentStatusSucceeded}))
+	assert.Equal(t, 0, notifier.deployments)
+	require.NoError(t, gate.SendDeploymentNotification(ctx, &domain.Deployment{ProjectID: projectID, Status: domain.DeploymentStatusFailed}))
+	assert.Equal(t, 1, notifier.deployments)
+	// Unfinished deployments are left to the notifier
+	require.NoError(t, gate.SendDeploymentNotification(ctx, &domain.Deployment{ProjectID: projectID, Status: domain.DeploymentStatusInProgress}))
+	assert.Equal(t, 2, notifier.deployments)
+
+	require.NoError(t, gate.SendAlertNotification(ctx, &domain.Alert{ProjectID: &projectID, Status: domain.AlertStatusResolved}))
+	assert.Equal(t, 0, notifier.alerts)
+	require.NoError(t, gate.SendAlertNotification(ctx, &domain.Alert{ProjectID: &projectID, Status: domain.AlertStatusFiring}))
+	// Alerts of no project always pass
+	require.NoError(t, gate.SendAlertNotification(ctx, &domain.Alert{Status: domain.AlertStatusResolved}))
+	assert.Equal(t, 2, notifier.alerts)
+}
@@ -0,0 +1,179 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/timewindow"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+var channels = map[string]bool{
+	"*":                                 true,
+	domain.NotificationChannelInbox:     true,
+	domain.NotificationChannelEmail:     true,
+	domain.NotificationChannelSlack:     true,
+	domain.NotificationChannelPagerDuty: true,
+	domain.NotificationChannelOpsgenie:  true,
+}
+
+var eventTypes = map[string]bool{
+	"*":                                       true,
+	domain.NotificationEventBuildCompleted:    true,
+	domain.NotificationEventBuildFailed:       true,
+	domain.NotificationEventDeployCompleted:   true,
+	domain.NotificationEventDeployFailed:      true,
+	domain.NotificationEventRollbackCompleted: true,
+	domain.NotificationEventAlertFired:        true,
+	domain.NotificationEventAlertResolved:     true,
+	domain.NotificationEventDigest:            true,
+}
+
+// Preferences is the domain.NotificationPolicy of the stored notification
+// preferences. The most specific preference for a channel decides: a user's
+// for the project, then the user's own, then the project's. A preference
+// naming the channel beats one for every channel of the same scope, and
+// without any preference every event reaches every channel.
+type Preferences struct {
+	repo   domain.NotificationPreferenceRepository
+	logger *logger.Logger
+	now    func() time.Time
+}
+
+// NewPreferences creates a new Preferences
+func NewPreferences(repo domain.NotificationPreferenceRepository, log *logger.Logger) *Preferences {
+	return &Preferences{
+		repo:   repo,
+		logger: log,
+		now:    time.Now,
+	}
+}
+
+var _ domain.NotificationPolicy = (*Preferences)(nil)
+
+// Allows reports whether an event reaches a channel. Preferences that cannot
+// be loaded allow it, so an outage of the database does not silence pages.
+func (p *Preferences) Allows(ctx context.Context, channel string, userID, projectID *uuid.UUID, eventType, severity string) bool {
+	var candidates []*domain.NotificationPreference
+	if userID != nil {
+		prefs, err := p.repo.ListByUser(ctx, *userID)
+		if err != nil {
+			p.logger.Warn().Err(err).Str("user_id", userID.String()).Msg("Failed to load notification preferences")
+			return true
+		}
+		candidates = append(candidates, prefs...)
+	}
+	if projectID != nil {
+		prefs, err := p.repo.ListByProject(ctx, *projectID)
+		if err != nil {
+			p.logger.Warn().Err(err).Str("project_id", projectID.String()).Msg("Failed to load notification preferences")
+			return true
+		}
+		candidates = append(candidates, prefs...)
+	}
+
+	pref := choose(candidates, channel, userID, projectID)
+	if pref == nil {
+		return true
+	}
+	return allows(pref, eventType, severity, p.now())
+}
+
+// choose returns the preference that decides for a channel, or nil
+func choose(prefs []*domain.NotificationPreference, channel string, userID, projectID *uuid.UUID) *domain.NotificationPreference {
+	var best *domain.NotificationPreference
+	bestRank := 0
+	for _, pref := range prefs {
+		if pref.Channel != channel && pref.Channel != "*" {
+			continue
+		}
+		rank := scopeRank(pref, userID, projectID)
+		if rank == 0 {
+			continue
+		}
+		// Scopes rank by tens, so an exact channel only breaks ties within one
+		rank *= 10
+		if pref.Channel == channel {
+			rank++
+		}
+		if rank > bestRank {
+			best, bestRank = pref, rank
+		}
+	}
+	return best
+}
+
+// scopeRank ranks how specifically a preference applies to the recipient and
+// project, 0 when it does not apply
+func scopeRank(pref *domain.NotificationPreference, userID, projectID *uuid.UUID) int {
+	sameUser := pref.UserID != nil && userID != nil && *pref.UserID == *userID
+	sameProject := pref.ProjectID != nil && projectID != nil && *pref.ProjectID == *projectID
+	switch {
+	case sameUser && sameProject:
+		return 3
+	case sameUser && pref.ProjectID == nil:
+		return 2
+	case pref.UserID == nil && sameProject:
+		return 1
+	}
+	return 0
+}
+
+// allows reports whether a preference lets an event through at now
+func allows(pref *domain.NotificationPreference, eventType, severity string, now time.Time) bool {
+	if pref.Muted {
+		return false
+	}
+	for _, w := range pref.Mute {
+		if timewindow.Window(w).Open(now) {
+			return false
+		}
+	}
+	if len(pref.Events) > 0 && !contains(pref.Events, eventType) && !contains(pref.Events, "*") {
+		return false
+	}
+	// Severities only filter alerts
+	if len(pref.Severities) > 0 && severity != "" && !contains(pref.Severities, severity) {
+		return false
+	}
+	return true
+}
+
+// ValidatePreference checks a notification preference
+func ValidatePreference(pref *domain.NotificationPreference) error {
+	if pref.UserID == nil && pref.ProjectID == nil {
+		return errors.BadRequest("notification preference needs a user or a project")
+	}
+	if !channels[pref.Channel] {
+		return errors.BadRequest(fmt.Sprintf("unknown notification channel %q", pref.Channel))
+	}
+	for _, t := range pref.Events {
+		if !eventTypes[t] {
+			return errors.BadRequest(fmt.Sprintf("unknown event type %q", t))
+		}
+	}
+	for _, severity := range pref.Severities {
+		if severity == "" {
+			return errors.BadRequest("severities must not be empty")
+		}
+	}
+	for _, w := range pref.Mute {
+		if err := timewindow.Window(w).Validate(); err != nil {
+			return errors.BadRequest("mute window: " + err.Error())
+		}
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
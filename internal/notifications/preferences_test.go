@@ -0,0 +1,153 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePreferences struct {
+	domain.NotificationPreferenceRepository
+	preferences []*domain.NotificationPreference
+	err         error
+}
+
+func (f fakePreferences) ListByUser(_ context.Context, userID uuid.UUID) ([]*domain.NotificationPreference, error) {
+	var prefs []*domain.NotificationPreference
+	for _, p := range f.preferences {
+		if p.UserID != nil && *p.UserID == userID {
+			prefs = append(prefs, p)
+		}
+	}
+	return prefs, f.err
+}
+
+func (f fakePreferences) ListByProject(_ context.Context, projectID uuid.UUID) ([]*domain.NotificationPreference, error) {
+	var prefs []*domain.NotificationPreference
+	for _, p := range f.preferences {
+		if p.UserID == nil && p.ProjectID != nil && *p.ProjectID == projectID {
+			prefs = append(prefs, p)
+		}
+	}
+	return prefs, f.err
+}
+
+func testPreferences(repo domain.NotificationPreferenceRepository, now time.Time) *Preferences {
+	p := NewPreferences(repo, logger.New("error", "json", io.Discard))
+	p.now = func() time.Time { return now }
+	return p
+}
+
+func TestPreferencesAllows(t *testing.T) {
+	userID, projectID := uuid.New(), uuid.New()
+	ctx := context.Background()
+	now := time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC) // A Wednesday
+
+	// The project only posts failures to Slack and pages for critical alerts
+	projectSlack := &domain.NotificationPreference{ProjectID: &projectID, Channel: domain.NotificationChannelSlack, Events: []string{domain.NotificationEventDeployFailed}}
+	projectPaging := &domain.NotificationPreference{ProjectID: &projectID, Channel: "*", Severities: []string{"critical"}}
+	// The user wants no email, except failed deploys of this project
+	userEmail := &domain.NotificationPreference{UserID: &userID, Channel: domain.NotificationChannelEmail, Muted: true}
+	userProjectEmail := &domain.NotificationPreference{UserID: &userID, ProjectID: &projectID, Channel: "*", Events: []string{domain.NotificationEventDeployFailed}}
+
+	p := testPreferences(fakePreferences{preferences: []*domain.NotificationPreference{projectSlack, projectPaging, userEmail, userProjectEmail}}, now)
+
+	assert.True(t, p.Allows(ctx, domain.NotificationChannelSlack, nil, &projectID, domain.NotificationEventDeployFailed, ""))
+	assert.False(t, p.Allows(ctx, domain.NotificationChannelSlack, nil, &projectID, domain.NotificationEventDeployCompleted, ""))
+
+	// The exact channel beats * for the same scope
+	assert.True(t, p.Allows(ctx, domain.NotificationChannelSlack, nil, &projectID, domain.NotificationEventDeployFailed, "warning"))
+	assert.False(t, p.Allows(ctx, domain.NotificationChannelPagerDuty, nil, &projectID, domain.NotificationEventAlertFired, "warning"))
+	assert.True(t, p.Allows(ctx, domain.NotificationChannelPagerDuty, nil, &projectID, domain.NotificationEventAlertFired, "critical"))
+
+	// The user's preference for the project beats their own
+	assert.True(t, p.Allows(ctx, domain.NotificationChannelEmail, &userID, &projectID, domain.NotificationEventDeployFailed, ""))
+	assert.False(t, p.Allows(ctx, domain.NotificationChannelEmail, &userID, &projectID, domain.NotificationEventDeployCompleted, ""))
+	assert.False(t, p.Allows(ctx, domain.NotificationChannelEmail, &userID, nil, domain.NotificationEventDeployFailed, ""))
+
+	// Without a preference everything passes
+	other := uuid.New()
+	assert.True(t, p.Allows(ctx, domain.NotificationChannelEmail, &other, &other, domain.NotificationEventDeployCompleted, ""))
+	assert.True(t, p.Allows(ctx, domain.NotificationChannelInbox, nil, nil, domain.NotificationEventDigest, ""))
+}
+
+func TestPreferencesFailOpen(t *testing.T) {
+	userID := uuid.New()
+	muted := &domain.NotificationPreference{UserID: &userID, Channel: "*", Muted: true}
+	p := testPreferences(fakePreferences{preferences: []*domain.NotificationPreference{muted}, err: errors.New("connection refused")}, time.Now())
+
+	assert.True(t, p.Allows(context.Background(), domain.NotificationChannelEmail, &userID, nil, domain.NotificationEventDeployFailed, ""))
+}
+
+func TestPreferencesMuteWindow(t *testing.T) {
+	userID := uuid.New()
+	// Weeknights from 22:00 to 07:00 in Berlin
+	night := &domain.NotificationPreference{UserID: &userID, Channel: "*", Mute: []domain.MuteWindow{
+		{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "22:00", End: "07:00", Timezone: "Europe/Berlin"},
+	}}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"friday night", at(6, 23, 0), false},
+		{"early saturday belongs to friday", at(7, 6, 59), false},
+		{"saturday night", at(7, 23, 0), true},
+		{"weekday morning", at(4, 7, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testPreferences(fakePreferences{preferences: []*domain.NotificationPreference{night}}, tt.now)
+			assert.Equal(t, tt.want, p.Allows(context.Background(), domain.NotificationChannelEmail, &userID, nil, domain.NotificationEventDeployFailed, ""))
+		})
+	}
+}
+
+func TestValidatePreference(t *testing.T) {
+	projectID := uuid.New()
+	valid := func() *domain.NotificationPreference {
+		return &domain.NotificationPreference{
+			ProjectID: &projectID,
+			Channel:   domain.NotificationChannelSlack,
+			Events:    []string{domain.NotificationEventDeployFailed},
+			Mute:      []domain.MuteWindow{{Days: []string{"Sat", "sun"}, Start: "00:00", End: "23:59", Timezone: "America/New_York"}},
+		}
+	}
+	require.NoError(t, ValidatePreference(valid()))
+
+	tests := []struct {
+		name   string
+		mutate func(*domain.NotificationPreference)
+	}{
+		{"no scope", func(p *domain.NotificationPreference) { p.ProjectID = nil }},
+		{"unknown channel", func(p *domain.NotificationPreference) { p.Channel = "sms" }},
+		{"unknown event", func(p *domain.NotificationPreference) { p.Events = []string{"deploy.started"} }},
+		{"empty severity", func(p *domain.NotificationPreference) { p.Severities = []string{""} }},
+		{"bad start", func(p *domain.NotificationPreference) { p.Mute[0].Start = "7am" }},
+		{"empty window", func(p *domain.NotificationPreference) { p.Mute[0].End = p.Mute[0].Start }},
+		{"bad day", func(p *domain.NotificationPreference) { p.Mute[0].Days = []string{"weekend"} }},
+		{"bad timezone", func(p *domain.NotificationPreference) { p.Mute[0].Timezone = "Mars/Olympus" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pref := valid()
+			tt.mutate(pref)
+			assert.Error(t, ValidatePreference(pref))
+		})
+	}
+}
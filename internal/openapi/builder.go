@@ -0,0 +1,305 @@
+package openapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Access is who may call a route
+type Access int
+
+const (
+	// Authenticated routes need a bearer token; the default
+	Authenticated Access = iota
+	// Public routes need no token, or authenticate by their own means
+	Public
+	// Admin routes need a token of an admin user
+	Admin
+)
+
+// securityScheme is the name of the bearer token scheme
+const securityScheme = "bearerAuth"
+
+// Annotation documents the bodies of one route
+type Annotation struct {
+	Method  string
+	Path    string // Gin syntax, e.g. /api/v1/projects/:id
+	Summary string
+	// Request is a value of the JSON request body type
+	Request interface{}
+	// Response is a value of the JSON response body type
+	Response interface{}
+	// List wraps Response in the {"data": [...], "count": n} list envelope
+	List bool
+	// Status is the success status code, http.StatusOK when zero
+	Status int
+}
+
+// Builder builds the document of a Gin engine's routes
+type Builder struct {
+	info        Info
+	errorSchema interface{}
+
+	mu          sync.Mutex
+	annotations map[string]Annotation
+	access      map[string]Access
+}
+
+// NewBuilder creates a new Builder. errorResponse is a value of the body
+// type every failed request responds with.
+func NewBuilder(info Info, errorResponse interface{}) *Builder {
+	return &Builder{
+		info:        info,
+		errorSchema: errorResponse,
+		annotations: make(map[string]Annotation),
+		access:      make(map[string]Access),
+	}
+}
+
+// Annotate adds request and response bodies to routes
+func (b *Builder) Annotate(annotations ...Annotation) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, a := range annotations {
+		b.annotations[routeKey(a.Method, a.Path)] = a
+	}
+}
+
+// Mark records the access of every route not marked yet. Called with the
+// engine's routes after each group is registered, it tells the groups apart
+// without the builder knowing the middleware involved.
+func (b *Builder) Mark(routes gin.RoutesInfo, access Access) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, route := range routes {
+		key := routeKey(route.Method, route.Path)
+		if _, ok := b.access[key]; !ok {
+			b.access[key] = access
+		}
+	}
+}
+
+// Build returns the document of routes
+func (b *Builder) Build(routes gin.RoutesInfo) *Document {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := newSchemas()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    b.info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: s.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				securityScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	errorSchema := s.of(b.errorSchema)
+
+	// Sorted so that operation IDs and component names are stable
+	sorted := append(gin.RoutesInfo(nil), routes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path != sorted[j].Path {
+			return sorted[i].Path < sorted[j].Path
+		}
+		return sorted[i].Method < sorted[j].Method
+	})
+
+	tags := make(map[string]bool)
+	operationIDs := make(map[string]bool)
+	for _, route := range sorted {
+		if route.Method == http.MethodHead || route.Method == http.MethodOptions {
+			continue
+		}
+		key := routeKey(route.Method, route.Path)
+		op := &Operation{
+			OperationID: operationID(route, operationIDs),
+			Tags:        []string{tag(route.Path)},
+			Parameters:  pathParameters(route.Path),
+			Responses:   make(map[string]Response),
+		}
+		tags[op.Tags[0]] = true
+
+		switch b.access[key] {
+		case Public:
+			op.Security = []map[string][]string{}
+		case Admin:
+			op.Description = "Requires the admin role."
+			op.Security = []map[string][]string{{securityScheme: {}}}
+		default:
+			op.Security = []map[string][]string{{securityScheme: {}}}
+		}
+
+		a, annotated := b.annotations[key]
+		op.Summary = a.Summary
+		status := a.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := Response{Description: http.StatusText(status)}
+		if annotated && a.Response != nil {
+			schema := s.of(a.Response)
+			if a.List {
+				schema = &Schema{
+					Type: "object",
+					Properties: map[string]*Schema{
+						"data":  {Type: "array", Items: schema},
+						"count": {Type: "integer", Format: "int32"},
+					},
+					Required: []string{"data", "count"},
+				}
+			}
+			response.Content = jsonContent(schema)
+		}
+		op.Responses[strconv.Itoa(status)] = response
+		op.Responses["default"] = Response{Description: "Error", Content: map[string]MediaType{problemContentType: {Schema: errorSchema}}}
+
+		if annotated && a.Request != nil {
+			op.RequestBody = &RequestBody{Required: true, Content: jsonContent(s.of(a.Request))}
+		}
+
+		path := openAPIPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = PathItem{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	for name := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: name})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	return doc
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
+
+// problemContentType is the media type of error responses (RFC 7807)
+const problemContentType = "application/problem+json"
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
+
+// openAPIPath converts Gin path parameters (:id, *path) to {id}
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func pathParameters(path string) []Parameter {
+	var params []Parameter
+	for _, segment := range strings.Split(path, "/") {
+		if !strings.HasPrefix(segment, ":") && !strings.HasPrefix(segment, "*") {
+			continue
+		}
+		name := segment[1:]
+		schema := &Schema{Type: "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			schema.Format = "uuid"
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return params
+}
+
+// tag groups a route by the first segment after the API version
+func tag(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) >= 3 && segments[0] == "api" && strings.HasPrefix(segments[1], "v") {
+		return segments[2]
+	}
+	if segments[0] == "" || segments[0] == "api" {
+		return "default"
+	}
+	return strings.TrimPrefix(segments[0], ".")
+}
+
+// operationID names an operation after its handler, e.g. ProjectHandler.Create
+// becomes projectCreate, falling back to the method and path when a handler
+// serves several routes
+func operationID(route gin.RouteInfo, taken map[string]bool) string {
+	id := handlerName(route.Handler)
+	if id == "" || taken[id] {
+		id = strings.ToLower(route.Method) + camel(openAPIPath(route.Path))
+	}
+	for base, n := id, 2; taken[id]; n++ {
+		id = base + strconv.Itoa(n)
+	}
+	taken[id] = true
+	return id
+}
+
+// handlerName turns a Gin handler name such as
+// github.com/x/handlers.(*ProjectHandler).Create-fm into projectCreate
+func handlerName(handler string) string {
+	handler = strings.TrimSuffix(handler, "-fm")
+	if i := strings.LastIndex(handler, "/"); i >= 0 {
+		handler = handler[i+1:]
+	}
+	// Drop the package name
+	_, handler, _ = strings.Cut(handler, ".")
+
+	var receiver, method string
+	if strings.HasPrefix(handler, "(*") {
+		receiver, method, _ = strings.Cut(strings.TrimPrefix(handler, "(*"), ").")
+	} else {
+		receiver, method, _ = strings.Cut(handler, ".")
+	}
+	// Closures and plain functions have no usable name
+	if receiver == "" || method == "" || strings.Contains(method, ".") || strings.HasPrefix(method, "func") {
+		return ""
+	}
+
+	receiver = strings.TrimSuffix(receiver, "Handler")
+	if receiver == "Router" {
+		// Built-in stubs such as handleListClusters
+		return lowerFirst(strings.TrimPrefix(method, "handle"))
+	}
+	return lowerFirst(receiver) + method
+}
+
+func camel(path string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range path {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// lowerFirst lower-cases a leading word, including acronyms: DORA becomes dora
+// and URLMap becomes urlMap
+func lowerFirst(s string) string {
+	runes := []rune(s)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) || (i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
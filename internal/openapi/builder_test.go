@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type widgetHandler struct{}
+
+func (widgetHandler) Create(*gin.Context) {}
+func (widgetHandler) Get(*gin.Context)    {}
+func (widgetHandler) Purge(*gin.Context)  {}
+
+type widget struct {
+	ID        uuid.UUID         `json:"id"`
+	Name      string            `json:"name" binding:"required,min=1,max=63"`
+	Size      string            `json:"size,omitempty" binding:"omitempty,oneof=small large"`
+	Parent    *widget           `json:"parent,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	internal  string
+}
+
+func TestBuild(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &widgetHandler{}
+	engine := gin.New()
+	b := NewBuilder(Info{Title: "Test", Version: "1"}, struct {
+		Message string `json:"message"`
+	}{})
+	b.Annotate(Annotation{Method: http.MethodPost, Path: "/api/v1/widgets", Request: widget{}, Response: widget{}, Status: http.StatusCreated})
+
+	engine.GET("/health", h.Get)
+	b.Mark(engine.Routes(), Public)
+	engine.POST("/api/v1/widgets", h.Create)
+	engine.GET("/api/v1/widgets/:id", h.Get)
+	b.Mark(engine.Routes(), Authenticated)
+	engine.DELETE("/api/v1/widgets/:id/*path", h.Purge)
+	b.Mark(engine.Routes(), Admin)
+
+	doc := b.Build(engine.Routes())
+	assert.Equal(t, Version, doc.OpenAPI)
+
+	create := doc.Paths["/api/v1/widgets"]["post"]
+	require.NotNil(t, create)
+	assert.Equal(t, "widgetCreate", create.OperationID)
+	assert.Equal(t, []string{"widgets"}, create.Tags)
+	assert.Contains(t, create.Responses, "201")
+	assert.Equal(t, "#/components/schemas/widget", create.RequestBody.Content["application/json"].Schema.Ref)
+	assert.NotEmpty(t, create.Security)
+
+	// Get serves two routes; the second falls back to method and path
+	assert.Equal(t, "getHealth", doc.Paths["/health"]["get"].OperationID)
+	assert.Empty(t, doc.Paths["/health"]["get"].Security)
+	get := doc.Paths["/api/v1/widgets/{id}"]["get"]
+	require.NotNil(t, get)
+	assert.Equal(t, "widgetGet", get.OperationID)
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, "uuid", get.Parameters[0].Schema.Format)
+
+	purge := doc.Paths["/api/v1/widgets/{id}/{path}"]["delete"]
+	require.NotNil(t, purge)
+	assert.Equal(t, "Requires the admin role.", purge.Description)
+	assert.Len(t, purge.Parameters, 2)
+
+	schema := doc.Components.Schemas["widget"]
+	require.NotNil(t, schema)
+	assert.Equal(t, []string{"name"}, schema.Required)
+	assert.Equal(t, 63, *schema.Properties["name"].MaxLength)
+	assert.Equal(t, []string{"small", "large"}, schema.Properties["size"].Enum)
+	assert.Equal(t, "#/components/schemas/widget", schema.Properties["parent"].Ref)
+	assert.Equal(t, "date-time", schema.Properties["created_at"].Format)
+	assert.Equal(t, "string", schema.Properties["labels"].AdditionalProperties.Type)
+	assert.NotContains(t, schema.Properties, "internal")
+}
+
+func TestHandlerName(t *testing.T) {
+	assert.Equal(t, "projectCreate", handlerName("github.com/northstack/platform/internal/api/handlers.(*ProjectHandler).Create-fm"))
+	assert.Equal(t, "doraGet", handlerName("github.com/northstack/platform/internal/api/handlers.(*DORAHandler).Get-fm"))
+	assert.Equal(t, "listClusters", handlerName("github.com/northstack/platform/internal/api.(*Router).handleListClusters-fm"))
+	assert.Empty(t, handlerName("github.com/gin-gonic/gin.WrapH.func1"))
+}
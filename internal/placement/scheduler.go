@@ -0,0 +1,252 @@
+// Package placement schedules services onto clusters. A service without a
+// target cluster is placed on one of the clusters of its project's
+// environments: the cluster must be active, carry the labels the service
+// requires, keep to the project's data residency and have capacity left.
+// Among those, clusters in the service's preferred regions win, then the
+// cluster with the most headroom.
+package placement
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/residency"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Candidate is a cluster considered for a service
+type Candidate struct {
+	ClusterID uuid.UUID `json:"cluster_id"`
+	Slug      string    `json:"slug"`
+	Region    string    `json:"region"`
+	Eligible  bool      `json:"eligible"`
+	Reason    string    `json:"reason,omitempty"`   // Why the cluster was ruled out
+	Headroom  *float64  `json:"headroom,omitempty"` // Unrequested fraction of the scarcer of CPU and memory; unknown without cluster health
+
+	regionRank int
+}
+
+// Decision is the cluster a service was placed on and the clusters considered
+type Decision struct {
+	ClusterID  uuid.UUID   `json:"cluster_id"`
+	Candidates []Candidate `json:"candidates"` // Best first; ruled out clusters last
+}
+
+// Scheduler picks the clusters of services
+type Scheduler struct {
+	config         *config.PlacementConfig
+	clusterRepo    domain.ClusterRepository
+	envRepo        domain.EnvironmentRepository
+	projectRepo    domain.ProjectRepository
+	clusterManager domain.ClusterManagerAdapter
+	residency      *residency.Checker
+	logger         *logger.Logger
+}
+
+// NewScheduler creates a new Scheduler. Without a cluster manager the
+// capacity of clusters is unknown and does not rule any out; residency may
+// be nil, in which case clusters are not checked against data residency.
+func NewScheduler(
+	cfg *config.PlacementConfig,
+	clusterRepo domain.ClusterRepository,
+	envRepo domain.EnvironmentRepository,
+	projectRepo domain.ProjectRepository,
+	clusterManager domain.ClusterManagerAdapter,
+	residency *residency.Checker,
+	log *logger.Logger,
+) *Scheduler {
+	return &Scheduler{
+		config:         cfg,
+		clusterRepo:    clusterRepo,
+		envRepo:        envRepo,
+		projectRepo:    projectRepo,
+		clusterManager: clusterManager,
+		residency:      residency,
+		logger:         log,
+	}
+}
+
+// Place picks the cluster for a service among the clusters of its project's
+// environments. It returns nil when the project has no environments yet, and
+// a conflict listing why each cluster was ruled out when none fits.
+func (s *Scheduler) Place(ctx context.Context, service *domain.Service) (*Decision, error) {
+	if err := Validate(service.Placement); err != nil {
+		return nil, err
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, service.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := s.clusters(ctx, service.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters) == 0 {
+		return nil, nil
+	}
+
+	candidates := make([]Candidate, len(clusters))
+	for i, cluster := range clusters {
+		candidates[i] = s.evaluate(ctx, project, cluster, service.Placement)
+	}
+	rank(candidates)
+
+	if !candidates[0].Eligible {
+		return nil, errors.NewError(errors.CodeConflict,
+			fmt.Sprintf("no cluster of project %s can run service %s", project.Slug, service.Slug),
+			http.StatusConflict,
+		).WithDetails(map[string]interface{}{"candidates": candidates})
+	}
+
+	s.logger.Info().
+		Str("service_id", service.ID.String()).
+		Str("cluster", candidates[0].Slug).
+		Int("candidates", len(candidates)).
+		Msg("Service placed")
+	return &Decision{ClusterID: candidates[0].ClusterID, Candidates: candidates}, nil
+}
+
+// evaluate rules a cluster in or out for a service and measures its headroom
+func (s *Scheduler) evaluate(ctx context.Context, project *domain.Project, cluster *domain.Cluster, p *domain.ServicePlacement) Candidate {
+	candidate := Candidate{
+		ClusterID:  cluster.ID,
+		Slug:       cluster.Slug,
+		Region:     cluster.Region,
+		regionRank: regionRank(p, cluster.Region),
+	}
+
+	if cluster.Status != domain.ClusterStatusActive {
+		candidate.Reason = fmt.Sprintf("cluster is %s", cluster.Status)
+		return candidate
+	}
+	if p != nil {
+		for key, value := range p.ClusterLabels {
+			if cluster.Labels[key] != value {
+				candidate.Reason = fmt.Sprintf("cluster lacks label %s=%s", key, value)
+				return candidate
+			}
+		}
+	}
+	if err := s.residency.CheckCluster(project, cluster); err != nil {
+		candidate.Reason = "cluster is outside the project's data residency"
+		return candidate
+	}
+
+	if s.clusterManager != nil && cluster.RancherClusterID != "" {
+		health, err := s.clusterManager.GetClusterHealth(ctx, cluster.RancherClusterID)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to read cluster capacity")
+		} else {
+			if reason := s.full(health); reason != "" {
+				candidate.Reason = reason
+				return candidate
+			}
+			headroom := 1 - math.Max(health.CPUUsage, health.MemoryUsage)
+			candidate.Headroom = &headroom
+		}
+	}
+
+	candidate.Eligible = true
+	return candidate
+}
+
+// full returns why a cluster has no capacity left, or ""
+func (s *Scheduler) full(health *domain.ClusterHealth) string {
+	switch {
+	case health.ReadyNodes == 0:
+		return "cluster has no ready nodes"
+	case s.config.MaxCPUUsage > 0 && health.CPUUsage >= s.config.MaxCPUUsage:
+		return fmt.Sprintf("%.0f%% of the cluster's CPU is requested", health.CPUUsage*100)
+	case s.config.MaxMemoryUsage > 0 && health.MemoryUsage >= s.config.MaxMemoryUsage:
+		return fmt.Sprintf("%.0f%% of the cluster's memory is requested", health.MemoryUsage*100)
+	}
+	return ""
+}
+
+// clusters returns the distinct clusters of a project's environments
+func (s *Scheduler) clusters(ctx context.Context, projectID uuid.UUID) ([]*domain.Cluster, error) {
+	environments, err := s.envRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	clusters := []*domain.Cluster{}
+	for _, env := range environments {
+		if seen[env.ClusterID] {
+			continue
+		}
+		seen[env.ClusterID] = true
+		cluster, err := s.clusterRepo.GetByID(ctx, env.ClusterID)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters, nil
+}
+
+// rank orders candidates best first: eligible clusters, then preferred
+// regions in order, then known headroom, most first
+func rank(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Eligible != b.Eligible {
+			return a.Eligible
+		}
+		if a.regionRank != b.regionRank {
+			return a.regionRank < b.regionRank
+		}
+		if (a.Headroom == nil) != (b.Headroom == nil) {
+			return a.Headroom != nil
+		}
+		if a.Headroom != nil && *a.Headroom != *b.Headroom {
+			return *a.Headroom > *b.Headroom
+		}
+		return a.Slug < b.Slug
+	})
+}
+
+// regionRank is the position of region among the preferred regions, or
+// their count when it is not preferred
+func regionRank(p *domain.ServicePlacement, region string) int {
+	if p == nil {
+		return 0
+	}
+	for i, preferred := range p.Regions {
+		if strings.EqualFold(preferred, region) {
+			return i
+		}
+	}
+	return len(p.Regions)
+}
+
+// Validate checks the placement constraints of a service
+func Validate(p *domain.ServicePlacement) error {
+	if p == nil {
+		return nil
+	}
+	for _, region := range p.Regions {
+		if strings.TrimSpace(region) == "" {
+			return errors.BadRequest("placement regions must not be empty")
+		}
+	}
+	for key := range p.ClusterLabels {
+		if strings.TrimSpace(key) == "" {
+			return errors.BadRequest("placement cluster label keys must not be empty")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package placement
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/residency"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClusters struct {
+	domain.ClusterRepository
+	clusters []*domain.Cluster
+}
+
+func (f fakeClusters) GetByID(_ context.Context, id uuid.UUID) (*domain.Cluster, error) {
+	for _, c := range f.clusters {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return nil, errors.NotFound("cluster", id.String())
+}
+
+// fakeEnvironments has one environment on each cluster
+type fakeEnvironments struct {
+	domain.EnvironmentRepository
+	clusters []*domain.Cluster
+}
+
+func (f fakeEnvironments) ListByProject(context.Context, uuid.UUID) ([]*domain.Environment, error) {
+	envs := make([]*domain.Environment, 0, len(f.clusters))
+	for _, c := range f.clusters {
+		envs = append(envs, &domain.Environment{ClusterID: c.ID})
+	}
+	return envs, nil
+}
+
+type fakeProjects struct {
+	domain.ProjectRepository
+	project *domain.Project
+}
+
+func (f fakeProjects) GetByID(context.Context, uuid.UUID) (*domain.Project, error) {
+	return f.project, nil
+}
+
+// fakeHealth reports the capacity of clusters by external ID
+type fakeHealth struct {
+	domain.ClusterManagerAdapter
+	health map[string]*domain.ClusterHealth
+}
+
+func (f fakeHealth) GetClusterHealth(_ context.Context, externalID string) (*domain.ClusterHealth, error) {
+	if h, ok := f.health[externalID]; ok {
+		return h, nil
+	}
+	return nil, errors.NotFound("cluster", externalID)
+}
+
+func newCluster(slug, region string, labels map[string]string) *domain.Cluster {
+	return &domain.Cluster{ID: uuid.New(), Slug: slug, Region: region, Labels: labels, Status: domain.ClusterStatusActive, RancherClusterID: "c-" + slug}
+}
+
+func newScheduler(project *domain.Project, clusters []*domain.Cluster, health map[string]*domain.ClusterHealth, checker *residency.Checker) *Scheduler {
+	cfg := &config.PlacementConfig{MaxCPUUsage: 0.85, MaxMemoryUsage: 0.85}
+	return NewScheduler(cfg, fakeClusters{clusters: clusters}, fakeEnvironments{clusters: clusters}, fakeProjects{project: project}, fakeHealth{health: health}, checker, logger.New("error", "json", io.Discard))
+}
+
+func TestPlace(t *testing.T) {
+	project := &domain.Project{ID: uuid.New(), Slug: "shop"}
+	fra := newCluster("fra", "eu-central-1", map[string]string{"gpu": "true"})
+	dub := newCluster("dub", "eu-west-1", nil)
+	iad := newCluster("iad", "us-east-1", nil)
+	health := map[string]*domain.ClusterHealth{
+		"c-fra": {ReadyNodes: 3, CPUUsage: 0.5, MemoryUsage: 0.4},
+		"c-dub": {ReadyNodes: 3, CPUUsage: 0.7, MemoryUsage: 0.2},
+		"c-iad": {ReadyNodes: 3, CPUUsage: 0.1, MemoryUsage: 0.1},
+	}
+	scheduler := newScheduler(project, []*domain.Cluster{fra, dub, iad}, health, nil)
+	ctx := context.Background()
+
+	// Without constraints the cluster with the most headroom wins
+	decision, err := scheduler.Place(ctx, &domain.Service{ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Equal(t, iad.ID, decision.ClusterID)
+
+	// Preferred regions come before headroom
+	decision, err = scheduler.Place(ctx, &domain.Service{ProjectID: project.ID, Placement: &domain.ServicePlacement{Regions: []string{"EU-WEST-1", "eu-central-1"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dub", "fra", "iad"}, slugs(decision.Candidates))
+
+	// Required labels rule clusters out
+	decision, err = scheduler.Place(ctx, &domain.Service{ProjectID: project.ID, Placement: &domain.ServicePlacement{ClusterLabels: map[string]string{"gpu": "true"}}})
+	require.NoError(t, err)
+	assert.Equal(t, fra.ID, decision.ClusterID)
+	assert.False(t, decision.Candidates[1].Eligible)
+	assert.Equal(t, "cluster lacks label gpu=true", decision.Candidates[1].Reason)
+
+	// Full clusters are ruled out, and unknown capacity ranks last
+	health["c-iad"].MemoryUsage = 0.9
+	delete(health, "c-dub")
+	decision, err = scheduler.Place(ctx, &domain.Service{ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fra", "dub", "iad"}, slugs(decision.Candidates))
+	assert.Nil(t, decision.Candidates[1].Headroom)
+	assert.Equal(t, "90% of the cluster's memory is requested", decision.Candidates[2].Reason)
+}
+
+func TestPlaceResidency(t *testing.T) {
+	project := &domain.Project{ID: uuid.New(), Slug: "shop", DataResidency: "eu"}
+	iad := newCluster("iad", "us-east-1", nil)
+	checker := residency.NewChecker(&config.ResidencyConfig{Regions: map[string][]string{"eu": {"eu-west-1"}}}, nil, nil, nil, nil)
+	scheduler := newScheduler(project, []*domain.Cluster{iad}, nil, checker)
+
+	_, err := scheduler.Place(context.Background(), &domain.Service{ProjectID: project.ID, Slug: "api"})
+	require.Error(t, err)
+	appErr, ok := err.(*errors.AppError)
+	require.True(t, ok)
+	assert.Equal(t, errors.CodeConflict, appErr.Code)
+	assert.Equal(t, "no cluster of project shop can run service api", appErr.Message)
+
+	// A project without environments leaves the service unplaced
+	decision, err := newScheduler(project, nil, nil, checker).Place(context.Background(), &domain.Service{ProjectID: project.ID})
+	require.NoError(t, err)
+	assert.Nil(t, decision)
+}
+
+func slugs(candidates []Candidate) []string {
+	out := make([]string, len(candidates))
+	for i, c := range candidates {
+		out[i] = c.Slug
+	}
+	return out
+}
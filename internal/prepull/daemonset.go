@@ -0,0 +1,156 @@
+package prepull
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// busyboxImage provides a static binary that can run inside any image,
+	// so images without a shell can be pulled by running it
+	busyboxImage = "busybox:1.36"
+	pauseImage   = "registry.k8s.io/pause:3.9"
+
+	// LabelPull selects the pods of a pull DaemonSet
+	LabelPull = "openpaas.io/image-pull"
+)
+
+// Target is the Deployment whose nodes images are pulled onto
+type Target struct {
+	ClusterID uuid.UUID
+	Name      string
+	Namespace string
+	UID       string
+	PodSpec   map[string]interface{}
+}
+
+// FindTarget locates the Deployment running the service on its target cluster
+func FindTarget(ctx context.Context, kube domain.KubernetesClient, svc *domain.Service) (*Target, error) {
+	if svc.TargetClusterID == nil {
+		return nil, errors.BadRequest(fmt.Sprintf("service %s has no target cluster", svc.Name))
+	}
+
+	objects, err := kube.ListResources(ctx, *svc.TargetClusterID, "Deployment", "", map[string]string{
+		domain.LabelServiceID: svc.ID.String(),
+	})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	if len(objects) == 0 {
+		return nil, errors.NotFound("workload for service", svc.ID.String())
+	}
+
+	name, _, _ := unstructured.NestedString(objects[0], "metadata", "name")
+	namespace, _, _ := unstructured.NestedString(objects[0], "metadata", "namespace")
+	uid, _, _ := unstructured.NestedString(objects[0], "metadata", "uid")
+	podSpec, _, _ := unstructured.NestedMap(objects[0], "spec", "template", "spec")
+	if podSpec == nil {
+		return nil, errors.Internal(fmt.Sprintf("deployment %s/%s has no pod template", namespace, name))
+	}
+
+	return &Target{
+		ClusterID: *svc.TargetClusterID,
+		Name:      name,
+		Namespace: namespace,
+		UID:       uid,
+		PodSpec:   podSpec,
+	}, nil
+}
+
+// Images returns the container images of the target's pods
+func (t *Target) Images() []string {
+	var images []string
+	containers, _, _ := unstructured.NestedSlice(t.PodSpec, "containers")
+	for _, c := range containers {
+		if image, _, _ := unstructured.NestedString(c.(map[string]interface{}), "image"); image != "" {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// DaemonSet renders a DaemonSet named name that pulls images onto every node
+// the target's pods may be scheduled on, then idles on the pause image. A pod
+// becomes ready once its node has all images. The DaemonSet is owned by the
+// Deployment so it is garbage collected with it.
+func DaemonSet(svc *domain.Service, t *Target, name string, images []string) map[string]interface{} {
+	volumeMount := []interface{}{map[string]interface{}{"name": "pull", "mountPath": "/pull"}}
+
+	initContainers := []interface{}{
+		map[string]interface{}{
+			"name":         "busybox",
+			"image":        busyboxImage,
+			"command":      []interface{}{"cp", "/bin/busybox", "/pull/busybox"},
+			"volumeMounts": volumeMount,
+		},
+	}
+	for i, image := range images {
+		initContainers = append(initContainers, map[string]interface{}{
+			"name":            fmt.Sprintf("pull-%d", i),
+			"image":           image,
+			"imagePullPolicy": "IfNotPresent",
+			"command":         []interface{}{"/pull/busybox", "true"},
+			"volumeMounts":    volumeMount,
+		})
+	}
+
+	podSpec := map[string]interface{}{
+		"initContainers": initContainers,
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name":  "pause",
+				"image": pauseImage,
+				"resources": map[string]interface{}{
+					"requests": map[string]interface{}{"cpu": "1m", "memory": "8Mi"},
+				},
+			},
+		},
+		"volumes": []interface{}{
+			map[string]interface{}{"name": "pull", "emptyDir": map[string]interface{}{}},
+		},
+	}
+	// Only pull onto nodes the service's pods can land on
+	for _, field := range []string{"nodeSelector", "tolerations", "affinity", "imagePullSecrets"} {
+		if v, ok := t.PodSpec[field]; ok {
+			podSpec[field] = v
+		}
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "DaemonSet",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": t.Namespace,
+			"labels": map[string]interface{}{
+				domain.LabelServiceID: svc.ID.String(),
+				domain.LabelProjectID: svc.ProjectID.String(),
+				domain.LabelManagedBy: domain.ManagedByValue,
+			},
+			"ownerReferences": []interface{}{
+				map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"name":       t.Name,
+					"uid":        t.UID,
+				},
+			},
+		},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{LabelPull: name},
+			},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels": map[string]interface{}{LabelPull: name},
+				},
+				"spec": podSpec,
+			},
+		},
+	}
+}
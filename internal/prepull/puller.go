@@ -0,0 +1,150 @@
+// Package prepull pulls container images onto the nodes a service's pods can
+// be scheduled on, using a short-lived DaemonSet, so that rollouts of large
+// images don't wait on pulls while old and new replicas are both serving.
+package prepull
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// pullFailures are container waiting reasons that mean the image cannot be pulled
+var pullFailures = map[string]bool{
+	"ImagePullBackOff": true,
+	"InvalidImageName": true,
+}
+
+// Puller pulls release images ahead of rollouts
+type Puller struct {
+	kube   domain.KubernetesClient
+	config *config.PrePullConfig
+	logger *logger.Logger
+}
+
+// NewPuller creates a new Puller
+func NewPuller(kube domain.KubernetesClient, cfg *config.PrePullConfig, log *logger.Logger) *Puller {
+	return &Puller{
+		kube:   kube,
+		config: cfg,
+		logger: log,
+	}
+}
+
+// PrePull pulls image onto every node the service's pods may run on and waits
+// until all have it, reporting progress as nodes finish. It returns an error
+// only when the image cannot be pulled, since the rollout would fail as well;
+// on timeout the rollout may proceed and remaining nodes pull on demand.
+func (p *Puller) PrePull(ctx context.Context, svc *domain.Service, image string, progress func(*domain.PrePullStatus)) (*domain.PrePullStatus, error) {
+	status := &domain.PrePullStatus{Phase: domain.PrePullPulling, Image: image, StartedAt: time.Now().UTC()}
+
+	target, err := FindTarget(ctx, p.kube, svc)
+	if errors.IsNotFound(err) {
+		status.Phase = domain.PrePullSkipped
+		status.Message = "service has no running workload yet"
+		return finish(status), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := target.Name + "-prepull"
+	manifest, err := json.Marshal(DaemonSet(svc, target, name, []string{image}))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode DaemonSet")
+	}
+	if err := p.kube.ApplyManifest(ctx, target.ClusterID, manifest); err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	defer func() {
+		// Also clean up when the deploy was cancelled
+		if err := p.kube.DeleteResource(context.WithoutCancel(ctx), target.ClusterID, "DaemonSet", target.Namespace, name); err != nil {
+			p.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Failed to remove pre-pull DaemonSet")
+		}
+	}()
+
+	progress(status)
+
+	deadline := time.NewTimer(p.config.Timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(p.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-deadline.C:
+			status.Phase = domain.PrePullTimedOut
+			status.Message = fmt.Sprintf("%d of %d nodes pulled within %s", status.NodesReady, status.NodesTotal, p.config.Timeout)
+			return finish(status), nil
+		case <-ticker.C:
+		}
+
+		ds, err := p.kube.GetResource(ctx, target.ClusterID, "DaemonSet", target.Namespace, name)
+		if err != nil {
+			p.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Failed to read pre-pull progress")
+			continue
+		}
+		pods, err := p.kube.ListResources(ctx, target.ClusterID, "Pod", target.Namespace, map[string]string{LabelPull: name})
+		if err != nil {
+			p.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Failed to read pre-pull progress")
+			continue
+		}
+
+		ready, total, done, failure := evaluate(ds, pods)
+		if failure != "" {
+			status.Phase = domain.PrePullFailed
+			status.Message = failure
+			return finish(status), errors.DeploymentFailed("image pre-pull failed: " + failure)
+		}
+		if ready != status.NodesReady || total != status.NodesTotal {
+			status.NodesReady, status.NodesTotal = ready, total
+			if !done {
+				progress(status)
+			}
+		}
+		if done {
+			status.Phase = domain.PrePullCompleted
+			return finish(status), nil
+		}
+	}
+}
+
+// evaluate reads pull progress from the DaemonSet and its pods. The pull is
+// done once the DaemonSet status reflects its spec and every scheduled pod is
+// ready; it fails as soon as any node cannot pull the image.
+func evaluate(ds map[string]interface{}, pods []map[string]interface{}) (ready, total int32, done bool, failure string) {
+	desired, _, _ := unstructured.NestedInt64(ds, "status", "desiredNumberScheduled")
+	numberReady, _, _ := unstructured.NestedInt64(ds, "status", "numberReady")
+	generation, _, _ := unstructured.NestedInt64(ds, "metadata", "generation")
+	observed, _, _ := unstructured.NestedInt64(ds, "status", "observedGeneration")
+
+	for _, pod := range pods {
+		statuses, _, _ := unstructured.NestedSlice(pod, "status", "initContainerStatuses")
+		for _, s := range statuses {
+			reason, _, _ := unstructured.NestedString(s.(map[string]interface{}), "state", "waiting", "reason")
+			if pullFailures[reason] {
+				message, _, _ := unstructured.NestedString(s.(map[string]interface{}), "state", "waiting", "message")
+				node, _, _ := unstructured.NestedString(pod, "spec", "nodeName")
+				return int32(numberReady), int32(desired), false, fmt.Sprintf("%s on node %s: %s", reason, node, message)
+			}
+		}
+	}
+
+	done = generation > 0 && observed >= generation && numberReady >= desired
+	return int32(numberReady), int32(desired), done, ""
+}
+
+func finish(status *domain.PrePullStatus) *domain.PrePullStatus {
+	now := time.Now().UTC()
+	status.CompletedAt = &now
+	return status
+}
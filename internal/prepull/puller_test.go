@@ -0,0 +1,69 @@
+package prepull
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func daemonSet(generation, observed, desired, ready int64) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{"generation": generation},
+		"status": map[string]interface{}{
+			"observedGeneration":     observed,
+			"desiredNumberScheduled": desired,
+			"numberReady":            ready,
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ready, total, done, failure := evaluate(daemonSet(1, 1, 5, 3), nil)
+	assert.Equal(t, int32(3), ready)
+	assert.Equal(t, int32(5), total)
+	assert.False(t, done)
+	assert.Empty(t, failure)
+
+	_, _, done, _ = evaluate(daemonSet(1, 1, 5, 5), nil)
+	assert.True(t, done)
+
+	// A status from before the spec was applied says nothing about this pull
+	_, _, done, _ = evaluate(daemonSet(2, 1, 5, 5), nil)
+	assert.False(t, done)
+
+	pod := map[string]interface{}{
+		"spec": map[string]interface{}{"nodeName": "worker-3"},
+		"status": map[string]interface{}{
+			"initContainerStatuses": []interface{}{
+				map[string]interface{}{"state": map[string]interface{}{"terminated": map[string]interface{}{"exitCode": int64(0)}}},
+				map[string]interface{}{"state": map[string]interface{}{"waiting": map[string]interface{}{
+					"reason":  "ImagePullBackOff",
+					"message": "manifest unknown",
+				}}},
+			},
+		},
+	}
+	_, _, done, failure = evaluate(daemonSet(1, 1, 5, 4), []map[string]interface{}{pod})
+	assert.False(t, done)
+	assert.Equal(t, "ImagePullBackOff on node worker-3: manifest unknown", failure)
+}
+
+func TestDaemonSet(t *testing.T) {
+	target := &Target{Name: "api", Namespace: "shop", PodSpec: map[string]interface{}{
+		"containers":   []interface{}{map[string]interface{}{"name": "app", "image": "registry.example.com/api:v1"}},
+		"nodeSelector": map[string]interface{}{"pool": "web"},
+	}}
+
+	obj := DaemonSet(&domain.Service{ID: uuid.New()}, target, "api-prepull", []string{"registry.example.com/api:v2"})
+
+	inits, _, _ := unstructured.NestedSlice(obj, "spec", "template", "spec", "initContainers")
+	if assert.Len(t, inits, 2) {
+		assert.Equal(t, "registry.example.com/api:v2", inits[1].(map[string]interface{})["image"])
+	}
+	selector, _, _ := unstructured.NestedStringMap(obj, "spec", "template", "spec", "nodeSelector")
+	assert.Equal(t, "web", selector["pool"])
+	assert.Equal(t, []string{"registry.example.com/api:v1"}, target.Images())
+}
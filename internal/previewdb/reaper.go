@@ -0,0 +1,189 @@
+// Package previewdb tears down the databases cloned into environments, such
+// as the copies of production data given to preview environments, once their
+// environment is deleted. Deletions are picked up from environment events,
+// and a periodic sweep catches the ones missed while the orchestrator was
+// down.
+package previewdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/northstack/platform/pkg/postgres"
+	"github.com/northstack/platform/pkg/yugabytedb"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// sweepInterval is how often databases of deleted environments are looked for
+const sweepInterval = time.Hour
+
+// Reaper deletes the databases of deleted environments
+type Reaper struct {
+	yugabyte *yugabytedb.DatabaseService
+	postgres *postgres.Service
+	envRepo  domain.EnvironmentRepository
+	eventBus domain.EventBus
+	logger   *logger.Logger
+}
+
+// NewReaper creates a new Reaper. Without a PostgreSQL service, only
+// YugabyteDB databases are deleted.
+func NewReaper(yugabyte *yugabytedb.DatabaseService, pg *postgres.Service, envRepo domain.EnvironmentRepository, eventBus domain.EventBus, log *logger.Logger) *Reaper {
+	return &Reaper{
+		yugabyte: yugabyte,
+		postgres: pg,
+		envRepo:  envRepo,
+		eventBus: eventBus,
+		logger:   log,
+	}
+}
+
+// Run deletes the databases of environments as they are deleted, and sweeps
+// for leftovers every sweepInterval, until ctx is cancelled
+func (r *Reaper) Run(ctx context.Context) {
+	// One replica tears each environment down, retrying what failed; databases
+	// that are already gone are no longer listed
+	_, err := eventbus.SubscribeDurable(ctx, r.eventBus, "project.environment.deleted", eventbus.DurableName("previewdb", "project.environment.deleted"), func(event *domain.Event) error {
+		environmentID, _ := event.Data["environment_id"].(string)
+		if environmentID == "" {
+			return nil
+		}
+		return r.teardown(ctx, map[string]bool{environmentID: true})
+	})
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Failed to subscribe to environment deletions, sweeping only")
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		r.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep deletes the databases of environments that no longer exist
+func (r *Reaper) sweep(ctx context.Context) {
+	databases, err := r.databases(ctx)
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Failed to list environment databases")
+		return
+	}
+
+	deleted := map[string]bool{}
+	for _, db := range databases {
+		if _, seen := deleted[db.environmentID]; seen {
+			continue
+		}
+		id, err := uuid.Parse(db.environmentID)
+		if err != nil {
+			continue
+		}
+		_, err = r.envRepo.GetByID(ctx, id)
+		if err != nil && !errors.IsNotFound(err) {
+			r.logger.Warn().Err(err).Str("environment_id", db.environmentID).Msg("Failed to get environment")
+			continue
+		}
+		deleted[db.environmentID] = err != nil
+	}
+	r.teardown(ctx, deleted)
+}
+
+// teardown deletes the databases of the environments marked true. It returns
+// the last failure, after trying every database.
+func (r *Reaper) teardown(ctx context.Context, environments map[string]bool) error {
+	databases, err := r.databases(ctx)
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Failed to list environment databases")
+		return err
+	}
+
+	var failed error
+
+	for _, db := range databases {
+		if !environments[db.environmentID] {
+			continue
+		}
+		if err := db.delete(ctx); err != nil && !apierrors.IsNotFound(err) {
+			r.logger.Error().Err(err).Str("database_id", db.id).Msg("Failed to delete database of deleted environment")
+			failed = err
+			continue
+		}
+		r.logger.Info().
+			Str("database_id", db.id).
+			Str("environment_id", db.environmentID).
+			Msg("Deleted database of deleted environment")
+		r.publish(ctx, db)
+	}
+	return failed
+}
+
+// database is a database of an environment, of either engine
+type database struct {
+	id            string
+	environmentID string
+	delete        func(context.Context) error
+}
+
+// databases lists the databases that belong to an environment
+func (r *Reaper) databases(ctx context.Context) ([]database, error) {
+	var databases []database
+	clusters, err := r.yugabyte.ListDatabases(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, db := range clusters {
+		if db.EnvironmentID == "" {
+			continue
+		}
+		id := db.ID
+		databases = append(databases, database{id: id, environmentID: db.EnvironmentID, delete: func(ctx context.Context) error {
+			return r.yugabyte.DeleteDatabase(ctx, id)
+		}})
+	}
+
+	if r.postgres == nil {
+		return databases, nil
+	}
+	pgClusters, err := r.postgres.ListDatabases(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, db := range pgClusters {
+		if db.EnvironmentID == "" {
+			continue
+		}
+		id := db.ID
+		databases = append(databases, database{id: id, environmentID: db.EnvironmentID, delete: func(ctx context.Context) error {
+			return r.postgres.DeleteDatabase(ctx, id)
+		}})
+	}
+	return databases, nil
+}
+
+func (r *Reaper) publish(ctx context.Context, db database) {
+	event := &domain.Event{
+		ID:        uuid.New().String(),
+		Type:      "database.deleted",
+		Source:    "platform-orchestrator",
+		Timestamp: time.Now().Unix(),
+		Data: map[string]interface{}{
+			"database_id":    db.id,
+			"environment_id": db.environmentID,
+		},
+	}
+	if err := r.eventBus.Publish(ctx, event.Type, event); err != nil {
+		r.logger.Warn().Err(err).Str("database_id", db.id).Msg("Failed to publish database event")
+	}
+}
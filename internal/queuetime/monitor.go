@@ -0,0 +1,229 @@
+// Package queuetime tracks how long builds and deployments wait in queue
+// before they start, reports percentiles per project and alerts platform
+// operators when queue times across the platform exceed the configured SLAs.
+package queuetime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/alerting"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// maxItems bounds the builds loaded per project and deployments per service
+	maxItems = 500
+	// RuleQueueTimeSLA is the name of the alert raised on SLA breaches
+	RuleQueueTimeSLA = "QueueTimeSLA"
+)
+
+// Report is the queue time breakdown for a project
+type Report struct {
+	ProjectID   uuid.UUID `json:"project_id"`
+	Window      string    `json:"window"`
+	Builds      *Stats    `json:"builds,omitempty"`
+	Deployments *Stats    `json:"deployments,omitempty"`
+}
+
+// Monitor computes queue time statistics and raises SLA alerts
+type Monitor struct {
+	config      *config.QueueSLAConfig
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	buildRepo   domain.BuildRepository
+	deployRepo  domain.DeploymentRepository
+	alerts      *alerting.Manager
+	logger      *logger.Logger
+}
+
+// NewMonitor creates a new Monitor. buildRepo or deployRepo may be nil, in
+// which case that kind of work is not tracked; alerts may be nil to only report.
+func NewMonitor(
+	cfg *config.QueueSLAConfig,
+	projectRepo domain.ProjectRepository,
+	serviceRepo domain.ServiceRepository,
+	buildRepo domain.BuildRepository,
+	deployRepo domain.DeploymentRepository,
+	alerts *alerting.Manager,
+	log *logger.Logger,
+) *Monitor {
+	return &Monitor{
+		config:      cfg,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		buildRepo:   buildRepo,
+		deployRepo:  deployRepo,
+		alerts:      alerts,
+		logger:      log,
+	}
+}
+
+// ProjectReport computes queue time percentiles for a project over the window
+func (m *Monitor) ProjectReport(ctx context.Context, projectID uuid.UUID, window time.Duration, label string) (*Report, error) {
+	now := time.Now().UTC()
+	since := now.Add(-window)
+	report := &Report{ProjectID: projectID, Window: label}
+
+	if m.buildRepo != nil {
+		builds, err := m.projectBuilds(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		report.Builds = compute(KindBuild, builds, since, now, m.config.BuildSLA)
+	}
+
+	if m.deployRepo != nil {
+		deployments, err := m.projectDeployments(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		report.Deployments = compute(KindDeployment, deployments, since, now, m.config.DeploymentSLA)
+	}
+
+	return report, nil
+}
+
+// Run checks platform-wide queue times every EvaluationInterval until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	if m.alerts == nil || (m.buildRepo == nil && m.deployRepo == nil) {
+		m.logger.Info().Msg("Queue time SLA monitoring has no repositories or alert manager configured, idle")
+		return
+	}
+
+	ticker := time.NewTicker(m.config.EvaluationInterval)
+	defer ticker.Stop()
+
+	for {
+		m.evaluate(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PlatformStats computes queue times across all projects over the configured
+// window, one entry per tracked kind of work. Kinds that fail to load are
+// logged and left out.
+func (m *Monitor) PlatformStats(ctx context.Context) ([]*Stats, error) {
+	projects, err := m.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	since := now.Add(-m.config.Window)
+
+	var stats []*Stats
+	if m.buildRepo != nil {
+		if items, err := collect(ctx, projects, m.projectBuilds); err != nil {
+			m.logger.Warn().Err(err).Msg("Failed to load builds for queue time evaluation")
+		} else {
+			stats = append(stats, compute(KindBuild, items, since, now, m.config.BuildSLA))
+		}
+	}
+
+	if m.deployRepo != nil {
+		if items, err := collect(ctx, projects, m.projectDeployments); err != nil {
+			m.logger.Warn().Err(err).Msg("Failed to load deployments for queue time evaluation")
+		} else {
+			stats = append(stats, compute(KindDeployment, items, since, now, m.config.DeploymentSLA))
+		}
+	}
+
+	return stats, nil
+}
+
+// evaluate aggregates queue times across all projects and fires or resolves
+// one alert per kind of work
+func (m *Monitor) evaluate(ctx context.Context) {
+	stats, err := m.PlatformStats(ctx)
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list projects for queue time evaluation")
+		return
+	}
+	for _, s := range stats {
+		m.check(ctx, s)
+	}
+}
+
+// check fires an alert for stats that breach the SLA and resolves it otherwise
+func (m *Monitor) check(ctx context.Context, stats *Stats) {
+	fingerprint := RuleQueueTimeSLA + "/" + stats.Kind
+
+	if !stats.Breached && !stats.Starved {
+		if _, err := m.alerts.Resolve(ctx, fingerprint, time.Now()); err != nil {
+			m.logger.Error().Err(err).Str("kind", stats.Kind).Msg("Failed to resolve queue time alert")
+		}
+		return
+	}
+
+	severity := "warning"
+	message := fmt.Sprintf("p95 %s queue time is %.0fs over the last %s (SLA %.0fs)", stats.Kind, stats.P95, m.config.Window, stats.SLA)
+	if stats.Starved {
+		severity = "critical"
+		message = fmt.Sprintf("%d %ss queued, oldest waiting %.0fs (SLA %.0fs)", stats.Waiting, stats.Kind, stats.OldestWaiting, stats.SLA)
+	}
+
+	_, err := m.alerts.Fire(ctx, &domain.Alert{
+		Fingerprint: fingerprint,
+		Name:        RuleQueueTimeSLA,
+		Severity:    severity,
+		Source:      alerting.SourcePlatform,
+		Message:     message,
+		Labels:      map[string]string{"kind": stats.Kind},
+		Annotations: map[string]string{
+			"p95_seconds":            fmt.Sprintf("%.1f", stats.P95),
+			"waiting":                fmt.Sprintf("%d", stats.Waiting),
+			"oldest_waiting_seconds": fmt.Sprintf("%.1f", stats.OldestWaiting),
+		},
+	})
+	if err != nil {
+		m.logger.Error().Err(err).Str("kind", stats.Kind).Msg("Failed to fire queue time alert")
+	}
+}
+
+func (m *Monitor) projectBuilds(ctx context.Context, projectID uuid.UUID) ([]item, error) {
+	builds, err := m.buildRepo.ListByProject(ctx, projectID, maxItems)
+	if err != nil {
+		return nil, err
+	}
+	return buildItems(builds), nil
+}
+
+func (m *Monitor) projectDeployments(ctx context.Context, projectID uuid.UUID) ([]item, error) {
+	services, err := m.serviceRepo.ListByProject(ctx, projectID, domain.ServiceFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	var items []item
+	for _, svc := range services {
+		deployments, err := m.deployRepo.ListByService(ctx, svc.ID, maxItems)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, deploymentItems(deployments)...)
+	}
+	return items, nil
+}
+
+// collect loads the items of every project
+func collect(ctx context.Context, projects []*domain.Project, load func(context.Context, uuid.UUID) ([]item, error)) ([]item, error) {
+	var items []item
+	for _, project := range projects {
+		loaded, err := load(ctx, project.ID)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, loaded...)
+	}
+	return items, nil
+}
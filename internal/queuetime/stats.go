@@ -0,0 +1,111 @@
+package queuetime
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+// Kinds of queued work
+const (
+	KindBuild      = "build"
+	KindDeployment = "deployment"
+)
+
+// minSamples is the number of started items needed before the p95 is checked against the SLA
+const minSamples = 5
+
+// item is a unit of work that waits in a queue before it starts
+type item struct {
+	createdAt time.Time
+	startedAt *time.Time
+	queued    bool // Still waiting to be picked up
+}
+
+// Stats are the queue time percentiles of one kind of work, in seconds
+type Stats struct {
+	Kind          string  `json:"kind"`
+	Started       int     `json:"started"` // Items picked up within the window
+	P50           float64 `json:"p50"`
+	P90           float64 `json:"p90"`
+	P95           float64 `json:"p95"`
+	Max           float64 `json:"max"`
+	Waiting       int     `json:"waiting"`        // Items currently queued
+	OldestWaiting float64 `json:"oldest_waiting"` // Time in queue of the oldest waiting item
+	SLA           float64 `json:"sla"`
+	Breached      bool    `json:"breached"`
+	Starved       bool    `json:"starved"` // A waiting item has exceeded the SLA
+}
+
+func buildItems(builds []*domain.Build) []item {
+	items := make([]item, 0, len(builds))
+	for _, b := range builds {
+		items = append(items, item{
+			createdAt: b.CreatedAt,
+			startedAt: b.StartedAt,
+			queued:    b.Status == domain.BuildStatusQueued && b.StartedAt == nil,
+		})
+	}
+	return items
+}
+
+func deploymentItems(deployments []*domain.Deployment) []item {
+	items := make([]item, 0, len(deployments))
+	for _, d := range deployments {
+		items = append(items, item{
+			createdAt: d.CreatedAt,
+			startedAt: d.StartedAt,
+			queued:    d.Status == domain.DeploymentStatusPending && d.StartedAt == nil,
+		})
+	}
+	return items
+}
+
+// compute summarizes the items started after since and those still waiting at now
+func compute(kind string, items []item, since, now time.Time, sla time.Duration) *Stats {
+	stats := &Stats{Kind: kind, SLA: sla.Seconds()}
+
+	var waits []float64
+	for _, it := range items {
+		switch {
+		case it.queued:
+			stats.Waiting++
+			stats.OldestWaiting = math.Max(stats.OldestWaiting, now.Sub(it.createdAt).Seconds())
+		case it.startedAt != nil && !it.startedAt.Before(since):
+			waits = append(waits, math.Max(0, it.startedAt.Sub(it.createdAt).Seconds()))
+		}
+	}
+
+	sort.Float64s(waits)
+	stats.Started = len(waits)
+	if len(waits) > 0 {
+		stats.P50 = round(percentile(waits, 50))
+		stats.P90 = round(percentile(waits, 90))
+		stats.P95 = round(percentile(waits, 95))
+		stats.Max = round(waits[len(waits)-1])
+	}
+	stats.OldestWaiting = round(stats.OldestWaiting)
+
+	if sla > 0 {
+		stats.Breached = stats.Started >= minSamples && stats.P95 > stats.SLA
+		stats.Starved = stats.OldestWaiting > stats.SLA
+	}
+	return stats
+}
+
+// percentile returns the p-th percentile of sorted values using linear interpolation
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo))
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,45 @@
+package queuetime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	build := func(createdAgo, waited time.Duration) *domain.Build {
+		created := now.Add(-createdAgo)
+		started := created.Add(waited)
+		return &domain.Build{Status: domain.BuildStatusSucceeded, CreatedAt: created, StartedAt: &started}
+	}
+
+	builds := []*domain.Build{
+		build(30*time.Minute, 10*time.Second),
+		build(25*time.Minute, 20*time.Second),
+		build(20*time.Minute, 30*time.Second),
+		build(15*time.Minute, 40*time.Second),
+		build(10*time.Minute, 400*time.Second),
+		build(3*time.Hour, time.Hour), // Started before the window
+		{Status: domain.BuildStatusQueued, CreatedAt: now.Add(-2 * time.Minute)},
+	}
+
+	stats := compute(KindBuild, buildItems(builds), now.Add(-time.Hour), now, 5*time.Minute)
+	assert.Equal(t, 5, stats.Started)
+	assert.Equal(t, 30.0, stats.P50)
+	assert.Equal(t, 328.0, stats.P95)
+	assert.Equal(t, 400.0, stats.Max)
+	assert.Equal(t, 1, stats.Waiting)
+	assert.Equal(t, 120.0, stats.OldestWaiting)
+	assert.True(t, stats.Breached)
+	assert.False(t, stats.Starved)
+
+	// A starved queue is flagged even without enough samples to compare the p95
+	stale := []*domain.Build{{Status: domain.BuildStatusQueued, CreatedAt: now.Add(-10 * time.Minute)}}
+	stats = compute(KindBuild, buildItems(stale), now.Add(-time.Hour), now, 5*time.Minute)
+	assert.False(t, stats.Breached)
+	assert.True(t, stats.Starved)
+}
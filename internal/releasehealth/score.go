@@ -0,0 +1,87 @@
+package releasehealth
+
+import (
+	"math"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+// Component weights; they sum to 1
+const (
+	weightCrashes     = 0.3
+	weightErrorBudget = 0.3
+	weightAlerts      = 0.2
+	weightRollback    = 0.2
+)
+
+const (
+	// restartsPerInstanceCeiling is the restart rate that counts as a total crash penalty
+	restartsPerInstanceCeiling = 5.0
+	// fastBurnRate is the error budget burn rate of a page-worthy incident (2% of a 30d budget in 1h)
+	fastBurnRate = 14.4
+	// alertsCeiling is the alert count that counts as a total alert penalty
+	alertsCeiling = 5.0
+
+	healthyScore  = 80
+	degradedScore = 50
+)
+
+// Inputs are the raw signals observed for a deployment
+type Inputs struct {
+	Restarts   int64
+	Instances  int
+	ErrorRate  float64 // Mean fraction of failed requests
+	SLOTarget  float64
+	Alerts     int
+	RolledBack bool
+}
+
+// Score combines the signals into a release health score
+func Score(in Inputs) *domain.ReleaseHealth {
+	crash := 0.0
+	if in.Instances > 0 {
+		crash = clamp(float64(in.Restarts) / float64(in.Instances) / restartsPerInstanceCeiling)
+	}
+
+	burn := 0.0
+	if budget := 1 - in.SLOTarget; budget > 0 {
+		burn = in.ErrorRate / budget
+	}
+
+	rollback := 0.0
+	if in.RolledBack {
+		rollback = 1
+	}
+
+	penalty := weightCrashes*crash +
+		weightErrorBudget*clamp(burn/fastBurnRate) +
+		weightAlerts*clamp(float64(in.Alerts)/alertsCeiling) +
+		weightRollback*rollback
+
+	score := math.Round((1-penalty)*1000) / 10
+
+	return &domain.ReleaseHealth{
+		Score:           score,
+		Grade:           grade(score),
+		Restarts:        in.Restarts,
+		Instances:       in.Instances,
+		ErrorBudgetBurn: math.Round(burn*100) / 100,
+		Alerts:          in.Alerts,
+		RolledBack:      in.RolledBack,
+	}
+}
+
+func grade(score float64) domain.ReleaseHealthGrade {
+	switch {
+	case score >= healthyScore:
+		return domain.ReleaseHealthHealthy
+	case score >= degradedScore:
+		return domain.ReleaseHealthDegraded
+	default:
+		return domain.ReleaseHealthUnhealthy
+	}
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
@@ -0,0 +1,36 @@
+package releasehealth
+
+import (
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	clean := Score(Inputs{Instances: 3, SLOTarget: 0.999})
+	assert.Equal(t, 100.0, clean.Score)
+	assert.Equal(t, domain.ReleaseHealthHealthy, clean.Grade)
+
+	rolledBack := Score(Inputs{Instances: 3, SLOTarget: 0.999, RolledBack: true})
+	assert.Equal(t, 80.0, rolledBack.Score)
+
+	bad := Score(Inputs{
+		Restarts:   30,
+		Instances:  3,
+		ErrorRate:  0.05,
+		SLOTarget:  0.999,
+		Alerts:     10,
+		RolledBack: true,
+	})
+	assert.Equal(t, 0.0, bad.Score)
+	assert.Equal(t, domain.ReleaseHealthUnhealthy, bad.Grade)
+	assert.Equal(t, 50.0, bad.ErrorBudgetBurn)
+}
+
+func TestTrend(t *testing.T) {
+	assert.Equal(t, TrendStable, trend([]float64{90, 40}))
+	assert.Equal(t, TrendImproving, trend([]float64{95, 90, 60, 55}))
+	assert.Equal(t, TrendDeclining, trend([]float64{50, 55, 90, 95}))
+	assert.Equal(t, TrendStable, trend([]float64{90, 88, 89, 91}))
+}
@@ -0,0 +1,393 @@
+// Package releasehealth scores every deployment on crash rate, error budget burn,
+// alert volume and rollback incidence, and aggregates scores per service so teams
+// can track whether delivery quality is improving.
+package releasehealth
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// alertPage is how many of a service's alerts are read at a time when counting
+const alertPage = 100
+
+// trendThreshold is the score change between halves of the history that counts as a trend
+const trendThreshold = 5.0
+
+// Trend describes the direction of release health over time
+type Trend string
+
+const (
+	TrendImproving Trend = "improving"
+	TrendStable    Trend = "stable"
+	TrendDeclining Trend = "declining"
+)
+
+// Release is a scored deployment
+type Release struct {
+	DeploymentID uuid.UUID               `json:"deployment_id"`
+	Version      string                  `json:"version"`
+	Status       domain.DeploymentStatus `json:"status"`
+	CreatedAt    time.Time               `json:"created_at"`
+	Health       *domain.ReleaseHealth   `json:"health,omitempty"`
+}
+
+// Summary aggregates release health for a service
+type Summary struct {
+	ServiceID    uuid.UUID `json:"service_id"`
+	Releases     []Release `json:"releases"`
+	Scored       int       `json:"scored"`
+	AverageScore float64   `json:"average_score"`
+	Trend        Trend     `json:"trend"`
+}
+
+// Scorer computes and stores release health scores
+type Scorer struct {
+	config     *config.ReleaseHealthConfig
+	kube       domain.KubernetesClient
+	metrics    domain.MetricsCollector
+	deployRepo domain.DeploymentRepository
+	alertRepo  domain.AlertRepository
+	eventBus   domain.EventBus
+	logger     *logger.Logger
+}
+
+// NewScorer creates a new Scorer. kube, metrics and alertRepo may be nil, in
+// which case the corresponding signals are treated as clean.
+func NewScorer(
+	cfg *config.ReleaseHealthConfig,
+	kube domain.KubernetesClient,
+	metrics domain.MetricsCollector,
+	deployRepo domain.DeploymentRepository,
+	alertRepo domain.AlertRepository,
+	eventBus domain.EventBus,
+	log *logger.Logger,
+) *Scorer {
+	return &Scorer{
+		config:     cfg,
+		kube:       kube,
+		metrics:    metrics,
+		deployRepo: deployRepo,
+		alertRepo:  alertRepo,
+		eventBus:   eventBus,
+		logger:     log,
+	}
+}
+
+// Watch scores deployments once their evaluation window has elapsed and
+// rescores rolled back deployments immediately. Events go through durable
+// consumers, so deployments finished while no replica runs are still scored;
+// those whose window elapsed meanwhile are scored on delivery.
+func (s *Scorer) Watch(ctx context.Context) error {
+	if _, err := eventbus.SubscribeDurable(ctx, s.eventBus, "deploy.completed", eventbus.DurableName("releasehealth", "deploy.completed"), func(event *domain.Event) error {
+		deploymentID, ok := eventID(event, "deployment_id")
+		if !ok {
+			return nil
+		}
+		return s.schedule(ctx, deploymentID)
+	}); err != nil {
+		return err
+	}
+
+	_, err := eventbus.SubscribeDurable(ctx, s.eventBus, "rollback.completed", eventbus.DurableName("releasehealth", "rollback.completed"), func(event *domain.Event) error {
+		deploymentID, ok := eventID(event, "deployment_id")
+		if !ok {
+			return nil
+		}
+		if _, err := s.Evaluate(ctx, deploymentID); err != nil && !errors.IsNotFound(err) {
+			s.logger.Error().Err(err).Str("deployment_id", deploymentID.String()).Msg("Failed to score release health")
+			return err
+		}
+		return nil
+	})
+	return err
+}
+
+// schedule scores a completed deployment once its evaluation window has
+// elapsed, right away when it already has
+func (s *Scorer) schedule(ctx context.Context, deploymentID uuid.UUID) error {
+	deployment, err := s.deployRepo.GetByID(ctx, deploymentID)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if deployment.Health != nil {
+		return nil // Redelivered after it was scored
+	}
+
+	wait := time.Until(s.due(deployment))
+	if wait <= 0 {
+		_, err := s.Evaluate(ctx, deploymentID)
+		if err != nil {
+			s.logger.Error().Err(err).Str("deployment_id", deploymentID.String()).Msg("Failed to score release health")
+		}
+		return err
+	}
+
+	time.AfterFunc(wait, func() {
+		if ctx.Err() == nil {
+			s.evaluateAndLog(ctx, deploymentID)
+		}
+	})
+	return nil
+}
+
+// due is when a deployment's evaluation window ends
+func (s *Scorer) due(deployment *domain.Deployment) time.Time {
+	since := deployment.CreatedAt
+	if deployment.CompletedAt != nil {
+		since = *deployment.CompletedAt
+	}
+	return since.Add(s.config.EvaluationWindow)
+}
+
+// Evaluate scores a deployment and stores the result on the deployment record
+func (s *Scorer) Evaluate(ctx context.Context, deploymentID uuid.UUID) (*domain.ReleaseHealth, error) {
+	deployment, err := s.deployRepo.GetByID(ctx, deploymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	until := s.due(deployment)
+	since := until.Add(-s.config.EvaluationWindow)
+	if now := time.Now(); until.After(now) {
+		until = now
+	}
+
+	alerts, err := s.alertCount(ctx, deployment.ServiceID, since, until)
+	if err != nil {
+		s.logger.Warn().Err(err).Str("deployment_id", deploymentID.String()).Msg("Failed to count alerts")
+	}
+	in := Inputs{
+		SLOTarget:  s.config.SLOTarget,
+		Alerts:     alerts,
+		RolledBack: deployment.Status == domain.DeploymentStatusRolledBack,
+	}
+
+	if s.kube != nil {
+		restarts, instances, err := s.restarts(ctx, deployment, since)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("deployment_id", deploymentID.String()).Msg("Failed to read pod restarts")
+		}
+		in.Restarts, in.Instances = restarts, instances
+	}
+
+	if s.metrics != nil {
+		m, err := s.metrics.GetServiceMetrics(ctx, deployment.ServiceID, domain.TimeRange{
+			Start: since.Unix(),
+			End:   until.Unix(),
+			Step:  60,
+		})
+		if err != nil {
+			s.logger.Warn().Err(err).Str("deployment_id", deploymentID.String()).Msg("Failed to read error rate")
+		} else {
+			in.ErrorRate = mean(m.ErrorRate)
+		}
+	}
+
+	health := Score(in)
+	health.EvaluatedAt = time.Now().UTC()
+
+	deployment.Health = health
+	if err := s.deployRepo.Update(ctx, deployment); err != nil {
+		return nil, errors.Wrap(err, "failed to store release health")
+	}
+
+	if err := s.eventBus.Publish(ctx, "deploy.health_scored", &domain.Event{
+		Type:   "deploy.health_scored",
+		Source: "release-health",
+		Data: map[string]interface{}{
+			"deployment_id": deployment.ID.String(),
+			"service_id":    deployment.ServiceID.String(),
+			"project_id":    deployment.ProjectID.String(),
+			"score":         health.Score,
+			"grade":         string(health.Grade),
+		},
+	}); err != nil {
+		s.logger.Error().Err(err).Msg("Failed to publish release health event")
+	}
+
+	return health, nil
+}
+
+// Summarize aggregates release health over the most recent deployments of a
+// service. Completed deployments past their evaluation window that were not
+// scored, because the replica waiting on them restarted, are scored first.
+func (s *Scorer) Summarize(ctx context.Context, serviceID uuid.UUID, limit int) (*Summary, error) {
+	deployments, err := s.deployRepo.ListByService(ctx, serviceID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, d := range deployments {
+		if d.Health != nil || d.CompletedAt == nil || now.Before(s.due(d)) {
+			continue
+		}
+		if health, err := s.Evaluate(ctx, d.ID); err == nil {
+			d.Health = health
+		} else {
+			s.logger.Warn().Err(err).Str("deployment_id", d.ID.String()).Msg("Failed to score release health")
+		}
+	}
+
+	summary := &Summary{
+		ServiceID: serviceID,
+		Releases:  make([]Release, 0, len(deployments)),
+		Trend:     TrendStable,
+	}
+
+	// Deployments are listed newest first
+	var scores []float64
+	for _, d := range deployments {
+		summary.Releases = append(summary.Releases, Release{
+			DeploymentID: d.ID,
+			Version:      d.Version,
+			Status:       d.Status,
+			CreatedAt:    d.CreatedAt,
+			Health:       d.Health,
+		})
+		if d.Health != nil {
+			scores = append(scores, d.Health.Score)
+		}
+	}
+
+	summary.Scored = len(scores)
+	if len(scores) == 0 {
+		return summary, nil
+	}
+
+	var total float64
+	for _, sc := range scores {
+		total += sc
+	}
+	summary.AverageScore = total / float64(len(scores))
+	summary.Trend = trend(scores)
+
+	return summary, nil
+}
+
+func (s *Scorer) evaluateAndLog(ctx context.Context, deploymentID uuid.UUID) {
+	health, err := s.Evaluate(ctx, deploymentID)
+	if err != nil {
+		s.logger.Error().Err(err).Str("deployment_id", deploymentID.String()).Msg("Failed to score release health")
+		return
+	}
+	s.logger.Info().
+		Str("deployment_id", deploymentID.String()).
+		Float64("score", health.Score).
+		Str("grade", string(health.Grade)).
+		Msg("Release health scored")
+}
+
+// restarts sums container restarts on pods created by the deployment
+func (s *Scorer) restarts(ctx context.Context, deployment *domain.Deployment, since time.Time) (int64, int, error) {
+	pods, err := s.kube.ListResources(ctx, deployment.ClusterID, "Pod", "", map[string]string{
+		domain.LabelServiceID: deployment.ServiceID.String(),
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var restarts int64
+	var instances int
+	for _, pod := range pods {
+		created, _, _ := unstructured.NestedString(pod, "metadata", "creationTimestamp")
+		if t, err := time.Parse(time.RFC3339, created); err == nil && t.Before(since.Add(-time.Minute)) {
+			continue // Pod predates this release
+		}
+		instances++
+
+		statuses, _, _ := unstructured.NestedSlice(pod, "status", "containerStatuses")
+		for _, st := range statuses {
+			if m, ok := st.(map[string]interface{}); ok {
+				n, _, _ := unstructured.NestedInt64(m, "restartCount")
+				restarts += n
+			}
+		}
+	}
+	return restarts, instances, nil
+}
+
+// alertCount counts the alerts of a service that started between since and
+// until. Alerts are read from their repository rather than the events, so
+// alerts fired on other replicas or before a restart are counted too.
+func (s *Scorer) alertCount(ctx context.Context, serviceID uuid.UUID, since, until time.Time) (int, error) {
+	if s.alertRepo == nil {
+		return 0, nil
+	}
+
+	count := 0
+	filter := domain.AlertFilter{ServiceID: &serviceID, Limit: alertPage}
+	for {
+		// Listed by start, newest first
+		alerts, err := s.alertRepo.List(ctx, filter)
+		if err != nil {
+			return count, err
+		}
+		for _, a := range alerts {
+			if a.StartsAt < since.Unix() {
+				return count, nil
+			}
+			if a.StartsAt <= until.Unix() {
+				count++
+			}
+		}
+		if len(alerts) < alertPage {
+			return count, nil
+		}
+		filter.Offset += alertPage
+	}
+}
+
+// trend compares the newer half of the scores (listed newest first) to the older half
+func trend(scores []float64) Trend {
+	if len(scores) < 4 {
+		return TrendStable
+	}
+	half := len(scores) / 2
+	delta := mean64(scores[:half]) - mean64(scores[len(scores)-half:])
+	switch {
+	case delta >= trendThreshold:
+		return TrendImproving
+	case delta <= -trendThreshold:
+		return TrendDeclining
+	default:
+		return TrendStable
+	}
+}
+
+func mean(points []domain.MetricPoint) float64 {
+	values := make([]float64, len(points))
+	for i, p := range points {
+		values[i] = p.Value
+	}
+	return mean64(values)
+}
+
+func mean64(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func eventID(event *domain.Event, key string) (uuid.UUID, bool) {
+	raw, _ := event.Data[key].(string)
+	id, err := uuid.Parse(raw)
+	return id, err == nil
+}
@@ -0,0 +1,68 @@
+package releasehealth
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAlertRepo lists alerts newest first, like the repositories
+type fakeAlertRepo struct {
+	domain.AlertRepository
+	alerts []*domain.Alert
+	calls  int
+}
+
+func (r *fakeAlertRepo) List(_ context.Context, filter domain.AlertFilter) ([]*domain.Alert, error) {
+	r.calls++
+	var matching []*domain.Alert
+	for _, a := range r.alerts {
+		if filter.ServiceID == nil || (a.ServiceID != nil && *a.ServiceID == *filter.ServiceID) {
+			matching = append(matching, a)
+		}
+	}
+	if filter.Offset >= len(matching) {
+		return nil, nil
+	}
+	matching = matching[filter.Offset:]
+	if filter.Limit > 0 && len(matching) > filter.Limit {
+		matching = matching[:filter.Limit]
+	}
+	return matching, nil
+}
+
+func TestAlertCount(t *testing.T) {
+	serviceID, other := uuid.New(), uuid.New()
+	until := time.Date(2026, 5, 4, 12, 0, 0, 0, time.UTC)
+	since := until.Add(-time.Hour)
+
+	repo := &fakeAlertRepo{}
+	// One alert after the window, then more than a page inside it, then older ones
+	repo.alerts = append(repo.alerts, &domain.Alert{ServiceID: &serviceID, StartsAt: until.Add(time.Minute).Unix()})
+	for i := 0; i < alertPage+5; i++ {
+		repo.alerts = append(repo.alerts, &domain.Alert{ServiceID: &serviceID, StartsAt: until.Add(-time.Duration(i) * time.Second).Unix()})
+	}
+	repo.alerts = append(repo.alerts, &domain.Alert{ServiceID: &other, StartsAt: since.Unix()})
+	for i := 0; i < alertPage; i++ {
+		repo.alerts = append(repo.alerts, &domain.Alert{ServiceID: &serviceID, StartsAt: since.Add(-time.Minute).Unix()})
+	}
+
+	s := NewScorer(&config.ReleaseHealthConfig{EvaluationWindow: time.Hour}, nil, nil, nil, repo, nil, logger.New("error", "json", io.Discard))
+	count, err := s.alertCount(context.Background(), serviceID, since, until)
+	require.NoError(t, err)
+	assert.Equal(t, alertPage+5, count)
+	assert.Equal(t, 2, repo.calls, "paging stops at the first alert before the window")
+
+	s = NewScorer(&config.ReleaseHealthConfig{}, nil, nil, nil, nil, nil, logger.New("error", "json", io.Discard))
+	count, err = s.alertCount(context.Background(), serviceID, since, until)
+	require.NoError(t, err)
+	assert.Zero(t, count, "without alerting there is nothing to count")
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// AlertRepository implements domain.AlertRepository using PostgreSQL
+type AlertRepository struct {
+	db *PostgresDB
+}
+
+// NewAlertRepository creates a new AlertRepository
+func NewAlertRepository(db *PostgresDB) *AlertRepository {
+	return &AlertRepository{db: db}
+}
+
+const alertColumns = `id, fingerprint, name, severity, status, source, message, labels, annotations, service_id, project_id, cluster_id, starts_at, ends_at`
+
+// Create creates a new alert
+func (r *AlertRepository) Create(ctx context.Context, alert *domain.Alert) error {
+	labels, _ := json.Marshal(alert.Labels)
+	annotations, _ := json.Marshal(alert.Annotations)
+
+	query := `
+		INSERT INTO alerts (` + alertColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		alert.ID,
+		alert.Fingerprint,
+		alert.Name,
+		alert.Severity,
+		alert.Status,
+		alert.Source,
+		alert.Message,
+		labels,
+		annotations,
+		alert.ServiceID,
+		alert.ProjectID,
+		alert.ClusterID,
+		alert.StartsAt,
+		alert.EndsAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create alert")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an alert by ID
+func (r *AlertRepository) GetByID(ctx context.Context, id string) (*domain.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE id = $1`
+
+	alert, err := scanAlert(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("alert", id)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get alert")
+	}
+
+	return alert, nil
+}
+
+// GetFiringByFingerprint retrieves the firing alert with the given fingerprint
+func (r *AlertRepository) GetFiringByFingerprint(ctx context.Context, fingerprint string) (*domain.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE fingerprint = $1 AND status = $2 ORDER BY starts_at DESC LIMIT 1`
+
+	alert, err := scanAlert(r.db.pool.QueryRow(ctx, query, fingerprint, domain.AlertStatusFiring))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("alert", fingerprint)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get alert")
+	}
+
+	return alert, nil
+}
+
+// List retrieves alerts with optional filtering
+func (r *AlertRepository) List(ctx context.Context, filter domain.AlertFilter) ([]*domain.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.Status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, filter.Status)
+		argIndex++
+	}
+
+	if filter.Source != "" {
+		query += fmt.Sprintf(" AND source = $%d", argIndex)
+		args = append(args, filter.Source)
+		argIndex++
+	}
+
+	if filter.ServiceID != nil {
+		query += fmt.Sprintf(" AND service_id = $%d", argIndex)
+		args = append(args, *filter.ServiceID)
+		argIndex++
+	}
+
+	if filter.ProjectID != nil {
+		query += fmt.Sprintf(" AND project_id = $%d", argIndex)
+		args = append(args, *filter.ProjectID)
+		argIndex++
+	}
+
+	if filter.ClusterID != nil {
+		query += fmt.Sprintf(" AND cluster_id = $%d", argIndex)
+		args = append(args, *filter.ClusterID)
+		argIndex++
+	}
+
+	query += " ORDER BY starts_at DESC"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argIndex)
+		args = append(args, filter.Limit)
+		argIndex++
+	}
+
+	if filter.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argIndex)
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list alerts")
+	}
+	defer rows.Close()
+
+	alerts := []*domain.Alert{}
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan alert")
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// Update updates an existing alert
+func (r *AlertRepository) Update(ctx context.Context, alert *domain.Alert) error {
+	labels, _ := json.Marshal(alert.Labels)
+	annotations, _ := json.Marshal(alert.Annotations)
+
+	query := `
+		UPDATE alerts
+		SET severity = $2, status = $3, message = $4, labels = $5, annotations = $6, ends_at = $7
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		alert.ID,
+		alert.Severity,
+		alert.Status,
+		alert.Message,
+		labels,
+		annotations,
+		alert.EndsAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update alert")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("alert", alert.ID)
+	}
+
+	return nil
+}
+
+func scanAlert(row pgx.Row) (*domain.Alert, error) {
+	alert := &domain.Alert{}
+	var labels, annotations []byte
+
+	err := row.Scan(
+		&alert.ID,
+		&alert.Fingerprint,
+		&alert.Name,
+		&alert.Severity,
+		&alert.Status,
+		&alert.Source,
+		&alert.Message,
+		&labels,
+		&annotations,
+		&alert.ServiceID,
+		&alert.ProjectID,
+		&alert.ClusterID,
+		&alert.StartsAt,
+		&alert.EndsAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(labels, &alert.Labels)
+	json.Unmarshal(annotations, &alert.Annotations)
+
+	return alert, nil
+}
@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ApprovalRepository implements domain.ApprovalRepository using PostgreSQL
+type ApprovalRepository struct {
+	db *PostgresDB
+}
+
+// NewApprovalRepository creates a new ApprovalRepository
+func NewApprovalRepository(db *PostgresDB) *ApprovalRepository {
+	return &ApprovalRepository{db: db}
+}
+
+const approvalColumns = `id, project_id, service_id, environment_id, workflow_id, version, status, approver_id,
+	escalated_at, decided_by, decided_at, comment, created_at, updated_at`
+
+// Create creates a new approval
+func (r *ApprovalRepository) Create(ctx context.Context, approval *domain.Approval) error {
+	query := `
+		INSERT INTO approvals (` + approvalColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		approval.ID,
+		approval.ProjectID,
+		approval.ServiceID,
+		approval.EnvironmentID,
+		approval.WorkflowID,
+		approval.Version,
+		approval.Status,
+		approval.ApproverID,
+		approval.EscalatedAt,
+		approval.DecidedBy,
+		approval.DecidedAt,
+		approval.Comment,
+		approval.CreatedAt,
+		approval.UpdatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create approval")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an approval by ID
+func (r *ApprovalRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Approval, error) {
+	query := `SELECT ` + approvalColumns + ` FROM approvals WHERE id = $1`
+
+	approval, err := scanApproval(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("approval", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get approval")
+	}
+
+	return approval, nil
+}
+
+// LatestByWorkflow retrieves the most recent approval of a deployment workflow
+func (r *ApprovalRepository) LatestByWorkflow(ctx context.Context, workflowID uuid.UUID) (*domain.Approval, error) {
+	query := `SELECT ` + approvalColumns + ` FROM approvals WHERE workflow_id = $1
+		ORDER BY created_at DESC, id DESC LIMIT 1`
+
+	approval, err := scanApproval(r.db.pool.QueryRow(ctx, query, workflowID))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("approval of workflow", workflowID.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get approval")
+	}
+
+	return approval, nil
+}
+
+// List retrieves approvals with filtering, newest first
+func (r *ApprovalRepository) List(ctx context.Context, filter domain.ApprovalFilter) ([]*domain.Approval, error) {
+	query := `SELECT ` + approvalColumns + ` FROM approvals WHERE 1=1`
+	args := []interface{}{}
+
+	if filter.ProjectID != nil {
+		args = append(args, *filter.ProjectID)
+		query += fmt.Sprintf(" AND project_id = $%d", len(args))
+	}
+
+	if filter.ApproverIDs != nil {
+		args = append(args, filter.ApproverIDs)
+		query += fmt.Sprintf(" AND approver_id = ANY($%d)", len(args))
+	}
+
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+
+	if filter.CreatedBefore != nil {
+		args = append(args, *filter.CreatedBefore)
+		query += fmt.Sprintf(" AND created_at < $%d", len(args))
+	}
+
+	if filter.Unescalated {
+		query += " AND escalated_at IS NULL"
+	}
+
+	query, args = keyset(query, args, filter.After, filter.Limit)
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list approvals")
+	}
+	defer rows.Close()
+
+	approvals := []*domain.Approval{}
+	for rows.Next() {
+		approval, err := scanApproval(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan approval")
+		}
+		approvals = append(approvals, approval)
+	}
+
+	return approvals, rows.Err()
+}
+
+// Update updates the state of an approval. The deploy it holds does not
+// change.
+func (r *ApprovalRepository) Update(ctx context.Context, approval *domain.Approval) error {
+	query := `
+		UPDATE approvals
+		SET status = $2, approver_id = $3, escalated_at = $4, decided_by = $5, decided_at = $6, comment = $7, updated_at = $8
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		approval.ID,
+		approval.Status,
+		approval.ApproverID,
+		approval.EscalatedAt,
+		approval.DecidedBy,
+		approval.DecidedAt,
+		approval.Comment,
+		approval.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update approval")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("approval", approval.ID.String())
+	}
+
+	return nil
+}
+
+func scanApproval(row pgx.Row) (*domain.Approval, error) {
+	approval := &domain.Approval{}
+
+	err := row.Scan(
+		&approval.ID,
+		&approval.ProjectID,
+		&approval.ServiceID,
+		&approval.EnvironmentID,
+		&approval.WorkflowID,
+		&approval.Version,
+		&approval.Status,
+		&approval.ApproverID,
+		&approval.EscalatedAt,
+		&approval.DecidedBy,
+		&approval.DecidedAt,
+		&approval.Comment,
+		&approval.CreatedAt,
+		&approval.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+// ApprovalDelegationRepository implements domain.ApprovalDelegationRepository using PostgreSQL
+type ApprovalDelegationRepository struct {
+	db *PostgresDB
+}
+
+// NewApprovalDelegationRepository creates a new ApprovalDelegationRepository
+func NewApprovalDelegationRepository(db *PostgresDB) *ApprovalDelegationRepository {
+	return &ApprovalDelegationRepository{db: db}
+}
+
+const approvalDelegationColumns = `id, delegator_id, delegate_id, starts_at, ends_at, reason, created_at`
+
+// Create creates a new approval delegation
+func (r *ApprovalDelegationRepository) Create(ctx context.Context, delegation *domain.ApprovalDelegation) error {
+	query := `
+		INSERT INTO approval_delegations (` + approvalDelegationColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		delegation.ID,
+		delegation.DelegatorID,
+		delegation.DelegateID,
+		delegation.StartsAt,
+		delegation.EndsAt,
+		delegation.Reason,
+		delegation.CreatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create approval delegation")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an approval delegation by ID
+func (r *ApprovalDelegationRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.ApprovalDelegation, error) {
+	query := `SELECT ` + approvalDelegationColumns + ` FROM approval_delegations WHERE id = $1`
+
+	delegation, err := scanApprovalDelegation(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("approval delegation", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get approval delegation")
+	}
+
+	return delegation, nil
+}
+
+// ListByUser retrieves the delegations a user gave or received, newest first
+func (r *ApprovalDelegationRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.ApprovalDelegation, error) {
+	query := `SELECT ` + approvalDelegationColumns + ` FROM approval_delegations
+		WHERE delegator_id = $1 OR delegate_id = $1 ORDER BY created_at DESC, id DESC`
+	return r.list(ctx, query, userID)
+}
+
+// ListActive retrieves the delegations a user received whose window holds at
+// the given time
+func (r *ApprovalDelegationRepository) ListActive(ctx context.Context, delegateID uuid.UUID, at time.Time) ([]*domain.ApprovalDelegation, error) {
+	query := `SELECT ` + approvalDelegationColumns + ` FROM approval_delegations
+		WHERE delegate_id = $1 AND starts_at <= $2 AND ends_at > $2 ORDER BY created_at DESC, id DESC`
+	return r.list(ctx, query, delegateID, at)
+}
+
+func (r *ApprovalDelegationRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.ApprovalDelegation, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list approval delegations")
+	}
+	defer rows.Close()
+
+	delegations := []*domain.ApprovalDelegation{}
+	for rows.Next() {
+		delegation, err := scanApprovalDelegation(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan approval delegation")
+		}
+		delegations = append(delegations, delegation)
+	}
+
+	return delegations, rows.Err()
+}
+
+// Delete deletes an approval delegation
+func (r *ApprovalDelegationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM approval_delegations WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete approval delegation")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("approval delegation", id.String())
+	}
+
+	return nil
+}
+
+func scanApprovalDelegation(row pgx.Row) (*domain.ApprovalDelegation, error) {
+	delegation := &domain.ApprovalDelegation{}
+
+	err := row.Scan(
+		&delegation.ID,
+		&delegation.DelegatorID,
+		&delegation.DelegateID,
+		&delegation.StartsAt,
+		&delegation.EndsAt,
+		&delegation.Reason,
+		&delegation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return delegation, nil
+}
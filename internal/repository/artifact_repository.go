@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// uniqueViolation is the PostgreSQL error code for a unique constraint violation
+const uniqueViolation = "23505"
+
+// ArtifactRepository implements domain.ArtifactRepository using PostgreSQL
+type ArtifactRepository struct {
+	db *PostgresDB
+}
+
+// NewArtifactRepository creates a new ArtifactRepository
+func NewArtifactRepository(db *PostgresDB) *ArtifactRepository {
+	return &ArtifactRepository{db: db}
+}
+
+const artifactColumns = `id, build_id, service_id, project_id, name, content_type, size, sha256, storage_key,
+	COALESCE(uploaded_by, ''), expires_at, created_at`
+
+// Create creates a new artifact
+func (r *ArtifactRepository) Create(ctx context.Context, artifact *domain.Artifact) error {
+	query := `
+		INSERT INTO artifacts (` + artifactColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		artifact.ID,
+		artifact.BuildID,
+		artifact.ServiceID,
+		artifact.ProjectID,
+		artifact.Name,
+		artifact.ContentType,
+		artifact.Size,
+		artifact.SHA256,
+		artifact.StorageKey,
+		artifact.UploadedBy,
+		artifact.ExpiresAt,
+		artifact.CreatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("artifact " + artifact.Name)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create artifact")
+	}
+
+	return nil
+}
+
+// GetByName retrieves the artifact of a build by name
+func (r *ArtifactRepository) GetByName(ctx context.Context, buildID uuid.UUID, name string) (*domain.Artifact, error) {
+	query := `SELECT ` + artifactColumns + ` FROM artifacts WHERE build_id = $1 AND name = $2`
+
+	artifact, err := scanArtifact(r.db.pool.QueryRow(ctx, query, buildID, name))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("artifact", name)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get artifact")
+	}
+
+	return artifact, nil
+}
+
+// ListByBuild retrieves the artifacts of a build by name
+func (r *ArtifactRepository) ListByBuild(ctx context.Context, buildID uuid.UUID) ([]*domain.Artifact, error) {
+	query := `SELECT ` + artifactColumns + ` FROM artifacts WHERE build_id = $1 ORDER BY name`
+	return r.list(ctx, query, buildID)
+}
+
+// ListByService retrieves the most recent artifacts of a service, newest first
+func (r *ArtifactRepository) ListByService(ctx context.Context, serviceID uuid.UUID, limit int) ([]*domain.Artifact, error) {
+	query := `SELECT ` + artifactColumns + ` FROM artifacts WHERE service_id = $1 ORDER BY created_at DESC LIMIT $2`
+	return r.list(ctx, query, serviceID, limit)
+}
+
+// ListByServiceAfter returns a page of a service's artifacts, newest first
+func (r *ArtifactRepository) ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Artifact, error) {
+	query, args := keyset(`SELECT `+artifactColumns+` FROM artifacts WHERE service_id = $1`, []interface{}{serviceID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+// ListExpired retrieves artifacts whose retention ended before the given time
+func (r *ArtifactRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]*domain.Artifact, error) {
+	query := `SELECT ` + artifactColumns + ` FROM artifacts WHERE expires_at < $1 ORDER BY expires_at LIMIT $2`
+	return r.list(ctx, query, before, limit)
+}
+
+func (r *ArtifactRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Artifact, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list artifacts")
+	}
+	defer rows.Close()
+
+	artifacts := []*domain.Artifact{}
+	for rows.Next() {
+		artifact, err := scanArtifact(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan artifact")
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts, nil
+}
+
+// Delete deletes an artifact
+func (r *ArtifactRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM artifacts WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete artifact")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("artifact", id.String())
+	}
+
+	return nil
+}
+
+func scanArtifact(row pgx.Row) (*domain.Artifact, error) {
+	artifact := &domain.Artifact{}
+
+	err := row.Scan(
+		&artifact.ID,
+		&artifact.BuildID,
+		&artifact.ServiceID,
+		&artifact.ProjectID,
+		&artifact.Name,
+		&artifact.ContentType,
+		&artifact.Size,
+		&artifact.SHA256,
+		&artifact.StorageKey,
+		&artifact.UploadedBy,
+		&artifact.ExpiresAt,
+		&artifact.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return artifact, nil
+}
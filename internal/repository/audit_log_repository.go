@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// AuditLogRepository implements domain.AuditLogRepository using PostgreSQL
+type AuditLogRepository struct {
+	db *PostgresDB
+}
+
+// NewAuditLogRepository creates a new AuditLogRepository
+func NewAuditLogRepository(db *PostgresDB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+const auditLogColumns = `id, user_id, action, resource_type, resource_id, resource_name, project_id,
+	ip_address, user_agent, old_value, new_value, metadata, created_at`
+
+// Create creates a new audit log entry
+func (r *AuditLogRepository) Create(ctx context.Context, log *domain.AuditLog) error {
+	metadata, _ := json.Marshal(log.Metadata)
+
+	query := `
+		INSERT INTO audit_logs (` + auditLogColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		log.ID,
+		log.UserID,
+		log.Action,
+		log.ResourceType,
+		log.ResourceID,
+		log.ResourceName,
+		log.ProjectID,
+		log.IPAddress,
+		log.UserAgent,
+		nullableJSON(log.OldValue),
+		nullableJSON(log.NewValue),
+		metadata,
+		log.CreatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create audit log")
+	}
+
+	return nil
+}
+
+// List retrieves audit log entries with filtering, newest first
+func (r *AuditLogRepository) List(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, error) {
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.UserID != nil {
+		query += fmt.Sprintf(" AND user_id = $%d", argIndex)
+		args = append(args, *filter.UserID)
+		argIndex++
+	}
+
+	if filter.ProjectID != nil {
+		query += fmt.Sprintf(" AND project_id = $%d", argIndex)
+		args = append(args, *filter.ProjectID)
+		argIndex++
+	}
+
+	if filter.ResourceType != "" {
+		query += fmt.Sprintf(" AND resource_type = $%d", argIndex)
+		args = append(args, filter.ResourceType)
+		argIndex++
+	}
+
+	if filter.ResourceID != nil {
+		query += fmt.Sprintf(" AND resource_id = $%d", argIndex)
+		args = append(args, *filter.ResourceID)
+		argIndex++
+	}
+
+	if filter.Action != nil {
+		query += fmt.Sprintf(" AND action = $%d", argIndex)
+		args = append(args, *filter.Action)
+		argIndex++
+	}
+
+	if filter.StartTime != nil {
+		query += fmt.Sprintf(" AND created_at >= $%d", argIndex)
+		args = append(args, time.Unix(*filter.StartTime, 0))
+		argIndex++
+	}
+
+	if filter.EndTime != nil {
+		query += fmt.Sprintf(" AND created_at < $%d", argIndex)
+		args = append(args, time.Unix(*filter.EndTime, 0))
+	}
+
+	query, args = keyset(query, args, filter.After, filter.Limit)
+	query, args = skip(query, args, filter.Offset)
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list audit logs")
+	}
+	defer rows.Close()
+
+	logs := []*domain.AuditLog{}
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan audit log")
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, rows.Err()
+}
+
+func scanAuditLog(row pgx.Row) (*domain.AuditLog, error) {
+	log := &domain.AuditLog{}
+	var resourceName, ipAddress, userAgent *string
+	var oldValue, newValue, metadata []byte
+
+	err := row.Scan(
+		&log.ID,
+		&log.UserID,
+		&log.Action,
+		&log.ResourceType,
+		&log.ResourceID,
+		&resourceName,
+		&log.ProjectID,
+		&ipAddress,
+		&userAgent,
+		&oldValue,
+		&newValue,
+		&metadata,
+		&log.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if resourceName != nil {
+		log.ResourceName = *resourceName
+	}
+	if ipAddress != nil {
+		log.IPAddress = *ipAddress
+	}
+	if userAgent != nil {
+		log.UserAgent = *userAgent
+	}
+	json.Unmarshal(oldValue, &log.OldValue)
+	json.Unmarshal(newValue, &log.NewValue)
+	json.Unmarshal(metadata, &log.Metadata)
+
+	return log, nil
+}
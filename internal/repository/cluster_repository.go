@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ClusterRepository implements domain.ClusterRepository using PostgreSQL
+type ClusterRepository struct {
+	db *PostgresDB
+}
+
+// NewClusterRepository creates a new ClusterRepository
+func NewClusterRepository(db *PostgresDB) *ClusterRepository {
+	return &ClusterRepository{db: db}
+}
+
+const clusterColumns = `id, name, slug, provider, region, status, COALESCE(kube_version, ''), COALESCE(api_endpoint, ''),
+	node_count, labels, metadata, COALESCE(rancher_cluster_id, ''), created_at, updated_at`
+
+// Create creates a new cluster
+func (r *ClusterRepository) Create(ctx context.Context, cluster *domain.Cluster) error {
+	labels, _ := json.Marshal(cluster.Labels)
+	metadata, _ := json.Marshal(cluster.Metadata)
+
+	query := `
+		INSERT INTO clusters (id, name, slug, provider, region, status, kube_version, api_endpoint,
+			node_count, labels, metadata, rancher_cluster_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		cluster.ID,
+		cluster.Name,
+		cluster.Slug,
+		cluster.Provider,
+		cluster.Region,
+		cluster.Status,
+		cluster.KubeVersion,
+		cluster.APIEndpoint,
+		cluster.NodeCount,
+		labels,
+		metadata,
+		cluster.RancherClusterID,
+		cluster.CreatedAt,
+		cluster.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("cluster " + cluster.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create cluster")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a cluster by ID
+func (r *ClusterRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Cluster, error) {
+	query := `SELECT ` + clusterColumns + ` FROM clusters WHERE id = $1`
+
+	cluster, err := scanCluster(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("cluster", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster")
+	}
+
+	return cluster, nil
+}
+
+// GetBySlug retrieves a cluster by slug
+func (r *ClusterRepository) GetBySlug(ctx context.Context, slug string) (*domain.Cluster, error) {
+	query := `SELECT ` + clusterColumns + ` FROM clusters WHERE slug = $1`
+
+	cluster, err := scanCluster(r.db.pool.QueryRow(ctx, query, slug))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("cluster", slug)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster")
+	}
+
+	return cluster, nil
+}
+
+// List retrieves clusters with optional filtering
+func (r *ClusterRepository) List(ctx context.Context, filter domain.ClusterFilter) ([]*domain.Cluster, error) {
+	query := `SELECT ` + clusterColumns + ` FROM clusters WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.Provider != nil {
+		query += fmt.Sprintf(" AND provider = $%d", argIndex)
+		args = append(args, *filter.Provider)
+		argIndex++
+	}
+
+	if filter.Status != nil {
+		query += fmt.Sprintf(" AND status = $%d", argIndex)
+		args = append(args, *filter.Status)
+		argIndex++
+	}
+
+	if filter.Region != "" {
+		query += fmt.Sprintf(" AND region = $%d", argIndex)
+		args = append(args, filter.Region)
+		argIndex++
+	}
+
+	if len(filter.Labels) > 0 {
+		labels, _ := json.Marshal(filter.Labels)
+		query += fmt.Sprintf(" AND labels @> $%d", argIndex)
+		args = append(args, labels)
+	}
+
+	query, args = keyset(query, args, filter.After, filter.Limit)
+	query, args = skip(query, args, filter.Offset)
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list clusters")
+	}
+	defer rows.Close()
+
+	clusters := []*domain.Cluster{}
+	for rows.Next() {
+		cluster, err := scanCluster(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan cluster")
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, nil
+}
+
+// Update updates an existing cluster
+func (r *ClusterRepository) Update(ctx context.Context, cluster *domain.Cluster) error {
+	labels, _ := json.Marshal(cluster.Labels)
+	metadata, _ := json.Marshal(cluster.Metadata)
+	cluster.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE clusters
+		SET name = $2, region = $3, status = $4, kube_version = $5, api_endpoint = $6, node_count = $7,
+			labels = $8, metadata = $9, rancher_cluster_id = $10, updated_at = $11
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		cluster.ID,
+		cluster.Name,
+		cluster.Region,
+		cluster.Status,
+		cluster.KubeVersion,
+		cluster.APIEndpoint,
+		cluster.NodeCount,
+		labels,
+		metadata,
+		cluster.RancherClusterID,
+		cluster.UpdatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update cluster")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("cluster", cluster.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a cluster
+func (r *ClusterRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM clusters WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete cluster")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("cluster", id.String())
+	}
+
+	return nil
+}
+
+func scanCluster(row pgx.Row) (*domain.Cluster, error) {
+	cluster := &domain.Cluster{}
+	var labels, metadata []byte
+
+	err := row.Scan(
+		&cluster.ID,
+		&cluster.Name,
+		&cluster.Slug,
+		&cluster.Provider,
+		&cluster.Region,
+		&cluster.Status,
+		&cluster.KubeVersion,
+		&cluster.APIEndpoint,
+		&cluster.NodeCount,
+		&labels,
+		&metadata,
+		&cluster.RancherClusterID,
+		&cluster.CreatedAt,
+		&cluster.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(labels, &cluster.Labels)
+	json.Unmarshal(metadata, &cluster.Metadata)
+
+	return cluster, nil
+}
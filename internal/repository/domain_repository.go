@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// DomainRepository implements domain.DomainRepository using PostgreSQL
+type DomainRepository struct {
+	db *PostgresDB
+}
+
+// NewDomainRepository creates a new DomainRepository
+func NewDomainRepository(db *PostgresDB) *DomainRepository {
+	return &DomainRepository{db: db}
+}
+
+const domainColumns = `id, project_id, name, method, token, status, message, verified_at, checked_at, created_by, created_at, updated_at`
+
+// Create creates a new custom domain
+func (r *DomainRepository) Create(ctx context.Context, d *domain.CustomDomain) error {
+	query := `
+		INSERT INTO custom_domains (` + domainColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		d.ID,
+		d.ProjectID,
+		d.Name,
+		d.Method,
+		d.Token,
+		d.Status,
+		d.Message,
+		d.VerifiedAt,
+		d.CheckedAt,
+		d.CreatedBy,
+		d.CreatedAt,
+		d.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("domain " + d.Name)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create custom domain")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a custom domain by ID
+func (r *DomainRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.CustomDomain, error) {
+	query := `SELECT ` + domainColumns + ` FROM custom_domains WHERE id = $1`
+
+	d, err := scanDomain(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("domain", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get custom domain")
+	}
+
+	return d, nil
+}
+
+// ListByProject retrieves the custom domains of a project
+func (r *DomainRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.CustomDomain, error) {
+	return r.list(ctx, `SELECT `+domainColumns+` FROM custom_domains WHERE project_id = $1 ORDER BY name`, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's custom domains, newest first
+func (r *DomainRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.CustomDomain, error) {
+	query, args := keyset(`SELECT `+domainColumns+` FROM custom_domains WHERE project_id = $1`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+// ListByName retrieves every project's claim on a domain name
+func (r *DomainRepository) ListByName(ctx context.Context, name string) ([]*domain.CustomDomain, error) {
+	return r.list(ctx, `SELECT `+domainColumns+` FROM custom_domains WHERE name = $1 ORDER BY created_at`, name)
+}
+
+// ListByStatus retrieves the custom domains in a verification state
+func (r *DomainRepository) ListByStatus(ctx context.Context, status domain.DomainStatus) ([]*domain.CustomDomain, error) {
+	return r.list(ctx, `SELECT `+domainColumns+` FROM custom_domains WHERE status = $1 ORDER BY created_at`, status)
+}
+
+// Update updates the verification state of a custom domain
+func (r *DomainRepository) Update(ctx context.Context, d *domain.CustomDomain) error {
+	d.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE custom_domains
+		SET method = $2, token = $3, status = $4, message = $5, verified_at = $6, checked_at = $7, updated_at = $8
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		d.ID,
+		d.Method,
+		d.Token,
+		d.Status,
+		d.Message,
+		d.VerifiedAt,
+		d.CheckedAt,
+		d.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("domain " + d.Name)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to update custom domain")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("domain", d.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a custom domain
+func (r *DomainRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM custom_domains WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete custom domain")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("domain", id.String())
+	}
+
+	return nil
+}
+
+func (r *DomainRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.CustomDomain, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list custom domains")
+	}
+	defer rows.Close()
+
+	domains := []*domain.CustomDomain{}
+	for rows.Next() {
+		d, err := scanDomain(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan custom domain")
+		}
+		domains = append(domains, d)
+	}
+
+	return domains, nil
+}
+
+func scanDomain(row pgx.Row) (*domain.CustomDomain, error) {
+	d := &domain.CustomDomain{}
+
+	err := row.Scan(
+		&d.ID,
+		&d.ProjectID,
+		&d.Name,
+		&d.Method,
+		&d.Token,
+		&d.Status,
+		&d.Message,
+		&d.VerifiedAt,
+		&d.CheckedAt,
+		&d.CreatedBy,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
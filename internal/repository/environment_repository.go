@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// EnvironmentRepository implements domain.EnvironmentRepository using PostgreSQL
+type EnvironmentRepository struct {
+	db *PostgresDB
+}
+
+// NewEnvironmentRepository creates a new EnvironmentRepository
+func NewEnvironmentRepository(db *PostgresDB) *EnvironmentRepository {
+	return &EnvironmentRepository{db: db}
+}
+
+const environmentColumns = `id, project_id, cluster_id, name, slug, type, namespace, is_default, egress, lifecycle, labels, metadata, created_at, updated_at`
+
+// Create creates a new environment
+func (r *EnvironmentRepository) Create(ctx context.Context, environment *domain.Environment) error {
+	labels, _ := json.Marshal(environment.Labels)
+	metadata, _ := json.Marshal(environment.Metadata)
+
+	query := `
+		INSERT INTO environments (` + environmentColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		environment.ID,
+		environment.ProjectID,
+		environment.ClusterID,
+		environment.Name,
+		environment.Slug,
+		environment.Type,
+		environment.Namespace,
+		environment.IsDefault,
+		nullableJSON(environment.Egress),
+		nullableJSON(environment.Lifecycle),
+		labels,
+		metadata,
+		environment.CreatedAt,
+		environment.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("environment " + environment.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create environment")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an environment by ID
+func (r *EnvironmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Environment, error) {
+	query := `SELECT ` + environmentColumns + ` FROM environments WHERE id = $1`
+
+	environment, err := scanEnvironment(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("environment", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get environment")
+	}
+
+	return environment, nil
+}
+
+// GetBySlug retrieves an environment of a project by slug
+func (r *EnvironmentRepository) GetBySlug(ctx context.Context, projectID uuid.UUID, slug string) (*domain.Environment, error) {
+	query := `SELECT ` + environmentColumns + ` FROM environments WHERE project_id = $1 AND slug = $2`
+
+	environment, err := scanEnvironment(r.db.pool.QueryRow(ctx, query, projectID, slug))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("environment", slug)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get environment")
+	}
+
+	return environment, nil
+}
+
+// ListByProject retrieves the environments of a project
+func (r *EnvironmentRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.Environment, error) {
+	query := `SELECT ` + environmentColumns + ` FROM environments WHERE project_id = $1 ORDER BY is_default DESC, name`
+	return r.list(ctx, query, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's environments, newest first
+func (r *EnvironmentRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Environment, error) {
+	query, args := keyset(`SELECT `+environmentColumns+` FROM environments WHERE project_id = $1`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+// ListByCluster retrieves the environments hosted on a cluster
+func (r *EnvironmentRepository) ListByCluster(ctx context.Context, clusterID uuid.UUID) ([]*domain.Environment, error) {
+	query := `SELECT ` + environmentColumns + ` FROM environments WHERE cluster_id = $1 ORDER BY namespace`
+	return r.list(ctx, query, clusterID)
+}
+
+func (r *EnvironmentRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Environment, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list environments")
+	}
+	defer rows.Close()
+
+	environments := []*domain.Environment{}
+	for rows.Next() {
+		environment, err := scanEnvironment(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan environment")
+		}
+		environments = append(environments, environment)
+	}
+
+	return environments, nil
+}
+
+// Update updates an existing environment
+func (r *EnvironmentRepository) Update(ctx context.Context, environment *domain.Environment) error {
+	labels, _ := json.Marshal(environment.Labels)
+	metadata, _ := json.Marshal(environment.Metadata)
+	environment.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE environments
+		SET name = $2, type = $3, is_default = $4, egress = $5, lifecycle = $6, labels = $7, metadata = $8, updated_at = $9
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		environment.ID,
+		environment.Name,
+		environment.Type,
+		environment.IsDefault,
+		nullableJSON(environment.Egress),
+		nullableJSON(environment.Lifecycle),
+		labels,
+		metadata,
+		environment.UpdatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update environment")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("environment", environment.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes an environment
+func (r *EnvironmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM environments WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete environment")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("environment", id.String())
+	}
+
+	return nil
+}
+
+func scanEnvironment(row pgx.Row) (*domain.Environment, error) {
+	environment := &domain.Environment{}
+	var egress, lifecycle, labels, metadata []byte
+
+	err := row.Scan(
+		&environment.ID,
+		&environment.ProjectID,
+		&environment.ClusterID,
+		&environment.Name,
+		&environment.Slug,
+		&environment.Type,
+		&environment.Namespace,
+		&environment.IsDefault,
+		&egress,
+		&lifecycle,
+		&labels,
+		&metadata,
+		&environment.CreatedAt,
+		&environment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(egress, &environment.Egress)
+	json.Unmarshal(lifecycle, &environment.Lifecycle)
+	json.Unmarshal(labels, &environment.Labels)
+	json.Unmarshal(metadata, &environment.Metadata)
+
+	return environment, nil
+}
@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// IngressRepository implements domain.IngressRepository using PostgreSQL
+type IngressRepository struct {
+	db *PostgresDB
+}
+
+// NewIngressRepository creates a new IngressRepository
+func NewIngressRepository(db *PostgresDB) *IngressRepository {
+	return &IngressRepository{db: db}
+}
+
+const ingressColumns = `id, service_id, project_id, domain, path, type, tls, annotations, labels, ip_families, routing, created_at, updated_at`
+
+// Create creates a new ingress
+func (r *IngressRepository) Create(ctx context.Context, ingress *domain.Ingress) error {
+	tls, _ := json.Marshal(ingress.TLS)
+	annotations, _ := json.Marshal(ingress.Annotations)
+	labels, _ := json.Marshal(ingress.Labels)
+	routing, _ := json.Marshal(ingress.Routing)
+
+	query := `
+		INSERT INTO ingresses (` + ingressColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		ingress.ID,
+		ingress.ServiceID,
+		ingress.ProjectID,
+		ingress.Domain,
+		ingress.Path,
+		ingress.Type,
+		tls,
+		annotations,
+		labels,
+		nullableJSON(ingress.IPFamilies),
+		routing,
+		ingress.CreatedAt,
+		ingress.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("ingress " + ingress.Domain + ingress.Path)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create ingress")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an ingress by ID
+func (r *IngressRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Ingress, error) {
+	query := `SELECT ` + ingressColumns + ` FROM ingresses WHERE id = $1`
+
+	ingress, err := scanIngress(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("ingress", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ingress")
+	}
+
+	return ingress, nil
+}
+
+// GetByDomain retrieves the root ingress of a domain, or its first path when
+// the domain has no root route
+func (r *IngressRepository) GetByDomain(ctx context.Context, domainName string) (*domain.Ingress, error) {
+	query := `SELECT ` + ingressColumns + ` FROM ingresses WHERE domain = $1 ORDER BY path = '/' DESC, path LIMIT 1`
+
+	ingress, err := scanIngress(r.db.pool.QueryRow(ctx, query, domainName))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("ingress", domainName)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ingress")
+	}
+
+	return ingress, nil
+}
+
+// ListByService retrieves the ingresses of a service
+func (r *IngressRepository) ListByService(ctx context.Context, serviceID uuid.UUID) ([]*domain.Ingress, error) {
+	query := `SELECT ` + ingressColumns + ` FROM ingresses WHERE service_id = $1 ORDER BY domain, path`
+	return r.list(ctx, query, serviceID)
+}
+
+// ListByServiceAfter retrieves a page of a service's ingresses, newest first
+func (r *IngressRepository) ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Ingress, error) {
+	query, args := keyset(`SELECT `+ingressColumns+` FROM ingresses WHERE service_id = $1`, []interface{}{serviceID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+// ListByProject retrieves the ingresses of a project
+func (r *IngressRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.Ingress, error) {
+	query := `SELECT ` + ingressColumns + ` FROM ingresses WHERE project_id = $1 ORDER BY domain, path`
+	return r.list(ctx, query, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's ingresses, newest first
+func (r *IngressRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Ingress, error) {
+	query, args := keyset(`SELECT `+ingressColumns+` FROM ingresses WHERE project_id = $1`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+func (r *IngressRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Ingress, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list ingresses")
+	}
+	defer rows.Close()
+
+	ingresses := []*domain.Ingress{}
+	for rows.Next() {
+		ingress, err := scanIngress(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan ingress")
+		}
+		ingresses = append(ingresses, ingress)
+	}
+
+	return ingresses, nil
+}
+
+// Update updates an existing ingress
+func (r *IngressRepository) Update(ctx context.Context, ingress *domain.Ingress) error {
+	tls, _ := json.Marshal(ingress.TLS)
+	annotations, _ := json.Marshal(ingress.Annotations)
+	labels, _ := json.Marshal(ingress.Labels)
+	routing, _ := json.Marshal(ingress.Routing)
+	ingress.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE ingresses
+		SET domain = $2, path = $3, type = $4, tls = $5, annotations = $6, labels = $7, ip_families = $8, routing = $9, updated_at = $10
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		ingress.ID,
+		ingress.Domain,
+		ingress.Path,
+		ingress.Type,
+		tls,
+		annotations,
+		labels,
+		nullableJSON(ingress.IPFamilies),
+		routing,
+		ingress.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("ingress " + ingress.Domain + ingress.Path)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to update ingress")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("ingress", ingress.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes an ingress
+func (r *IngressRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM ingresses WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete ingress")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("ingress", id.String())
+	}
+
+	return nil
+}
+
+func scanIngress(row pgx.Row) (*domain.Ingress, error) {
+	ingress := &domain.Ingress{}
+	var tls, annotations, labels, ipFamilies, routing []byte
+
+	err := row.Scan(
+		&ingress.ID,
+		&ingress.ServiceID,
+		&ingress.ProjectID,
+		&ingress.Domain,
+		&ingress.Path,
+		&ingress.Type,
+		&tls,
+		&annotations,
+		&labels,
+		&ipFamilies,
+		&routing,
+		&ingress.CreatedAt,
+		&ingress.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(tls, &ingress.TLS)
+	json.Unmarshal(annotations, &ingress.Annotations)
+	json.Unmarshal(labels, &ingress.Labels)
+	json.Unmarshal(ipFamilies, &ingress.IPFamilies)
+	json.Unmarshal(routing, &ingress.Routing)
+
+	return ingress, nil
+}
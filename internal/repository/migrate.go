@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	pgxmigrate "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// migrationFiles holds the numbered schema migrations. Each version has an
+// .up.sql and a .down.sql file; applied versions are tracked in the
+// schema_migrations table. Add new versions at the end, never edit applied ones.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrate applies all pending migrations
+func (db *PostgresDB) Migrate(ctx context.Context) error {
+	return db.migrate(ctx, func(m *migrate.Migrate) error { return m.Up() })
+}
+
+// MigrateTo migrates the schema up or down to the given version; version 0
+// reverts every migration
+func (db *PostgresDB) MigrateTo(ctx context.Context, version uint) error {
+	return db.migrate(ctx, func(m *migrate.Migrate) error {
+		if version == 0 {
+			return m.Down()
+		}
+		return m.Migrate(version)
+	})
+}
+
+func (db *PostgresDB) migrate(ctx context.Context, run func(m *migrate.Migrate) error) error {
+	src, err := iofs.New(migrationFiles, "migrations")
+	if err != nil {
+		return fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	// The driver takes its own connection from the pool for the advisory
+	// lock that keeps replicas from migrating concurrently
+	driver, err := pgxmigrate.WithInstance(stdlib.OpenDBFromPool(db.pool), &pgxmigrate.Config{})
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "pgx5", driver)
+	if err != nil {
+		return fmt.Errorf("failed to initialize migrations: %w", err)
+	}
+	defer m.Close()
+	m.Log = migrateLogger{db.logger}
+
+	// Stop after the current migration when the context is cancelled
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			m.GracefulStop <- true
+		case <-done:
+		}
+	}()
+
+	if err := run(m); err != nil && !stderrors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil && !stderrors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	db.logger.Info().Int("version", int(version)).Bool("dirty", dirty).Msg("Database migrations completed")
+	return nil
+}
+
+// migrateLogger forwards golang-migrate's progress messages to the logger
+type migrateLogger struct {
+	logger *logger.Logger
+}
+
+func (l migrateLogger) Printf(format string, v ...interface{}) {
+	l.logger.Info().Msg(strings.TrimSpace(fmt.Sprintf(format, v...)))
+}
+
+func (l migrateLogger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationFiles(t *testing.T) {
+	src, err := iofs.New(migrationFiles, "migrations")
+	require.NoError(t, err)
+	defer src.Close()
+
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	require.NoError(t, err)
+
+	// Versions are numbered without gaps and each can be reverted
+	version, err := src.First()
+	require.NoError(t, err)
+	count := 0
+	for {
+		count++
+		assert.Equal(t, uint(count), version, "migration versions must be sequential")
+
+		up, _, err := src.ReadUp(version)
+		require.NoError(t, err, "missing up migration %d", version)
+		up.Close()
+		down, _, err := src.ReadDown(version)
+		require.NoError(t, err, "missing down migration %d", version)
+		down.Close()
+
+		if version, err = src.Next(version); err != nil {
+			break
+		}
+	}
+	assert.Len(t, files, 2*count, fmt.Sprintf("unexpected files in migrations: %v", files))
+}
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS services (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    build_source JSONB NOT NULL DEFAULT '{}',
+    resources JSONB NOT NULL DEFAULT '{}',
+    scaling JSONB NOT NULL DEFAULT '{}',
+    health_check JSONB,
+    env_vars JSONB DEFAULT '{}',
+    secret_refs JSONB DEFAULT '[]',
+    ports JSONB DEFAULT '[]',
+    labels JSONB DEFAULT '{}',
+    annotations JSONB DEFAULT '{}',
+    metadata JSONB DEFAULT '{}',
+    current_build_id UUID,
+    current_version VARCHAR(255),
+    target_cluster_id UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(project_id, slug)
+);
+
+CREATE INDEX IF NOT EXISTS idx_services_project_id ON services(project_id);
+CREATE INDEX IF NOT EXISTS idx_services_status ON services(status);
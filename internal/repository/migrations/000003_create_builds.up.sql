@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS builds (
+    id UUID PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    status VARCHAR(50) NOT NULL DEFAULT 'queued',
+    source JSONB NOT NULL DEFAULT '{}',
+    image_tag VARCHAR(512),
+    image_digest VARCHAR(255),
+    build_logs TEXT,
+    duration BIGINT,
+    triggered_by VARCHAR(255) NOT NULL,
+    error_message TEXT,
+    metadata JSONB DEFAULT '{}',
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_builds_service_id ON builds(service_id);
+CREATE INDEX IF NOT EXISTS idx_builds_project_id ON builds(project_id);
+CREATE INDEX IF NOT EXISTS idx_builds_status ON builds(status);
+CREATE INDEX IF NOT EXISTS idx_builds_created_at ON builds(created_at DESC);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS deployments (
+    id UUID PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    build_id UUID NOT NULL REFERENCES builds(id),
+    cluster_id UUID NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    strategy VARCHAR(50) NOT NULL DEFAULT 'rolling_update',
+    version VARCHAR(255) NOT NULL,
+    previous_version VARCHAR(255),
+    replicas INTEGER NOT NULL DEFAULT 1,
+    ready_replicas INTEGER NOT NULL DEFAULT 0,
+    triggered_by VARCHAR(255) NOT NULL,
+    error_message TEXT,
+    metadata JSONB DEFAULT '{}',
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_deployments_service_id ON deployments(service_id);
+CREATE INDEX IF NOT EXISTS idx_deployments_cluster_id ON deployments(cluster_id);
+CREATE INDEX IF NOT EXISTS idx_deployments_status ON deployments(status);
+CREATE INDEX IF NOT EXISTS idx_deployments_created_at ON deployments(created_at DESC);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS clusters (
+    id UUID PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL UNIQUE,
+    provider VARCHAR(50) NOT NULL,
+    region VARCHAR(100) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'provisioning',
+    kube_version VARCHAR(50),
+    api_endpoint VARCHAR(512),
+    node_count INTEGER NOT NULL DEFAULT 0,
+    labels JSONB DEFAULT '{}',
+    metadata JSONB DEFAULT '{}',
+    rancher_cluster_id VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_clusters_provider ON clusters(provider);
+CREATE INDEX IF NOT EXISTS idx_clusters_status ON clusters(status);
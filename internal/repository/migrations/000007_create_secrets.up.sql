@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS secrets (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(50) NOT NULL DEFAULT 'opaque',
+    keys JSONB NOT NULL DEFAULT '[]',
+    vault_path VARCHAR(512) NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    labels JSONB DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(project_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_secrets_project_id ON secrets(project_id);
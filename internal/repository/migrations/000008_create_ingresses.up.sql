@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS ingresses (
+    id UUID PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    domain VARCHAR(512) NOT NULL,
+    path VARCHAR(255) NOT NULL DEFAULT '/',
+    type VARCHAR(50) NOT NULL DEFAULT 'http',
+    tls JSONB NOT NULL DEFAULT '{"enabled": false}',
+    annotations JSONB DEFAULT '{}',
+    labels JSONB DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(domain, path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingresses_service_id ON ingresses(service_id);
+CREATE INDEX IF NOT EXISTS idx_ingresses_domain ON ingresses(domain);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS pipelines (
+    id UUID PRIMARY KEY,
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    trigger VARCHAR(50) NOT NULL,
+    branch VARCHAR(255),
+    commit_sha VARCHAR(255),
+    stages JSONB NOT NULL DEFAULT '[]',
+    build_id UUID REFERENCES builds(id),
+    deployment_id UUID REFERENCES deployments(id),
+    metadata JSONB DEFAULT '{}',
+    started_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_pipelines_service_id ON pipelines(service_id);
+CREATE INDEX IF NOT EXISTS idx_pipelines_status ON pipelines(status);
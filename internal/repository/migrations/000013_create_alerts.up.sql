@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS alerts (
+    id VARCHAR(64) PRIMARY KEY,
+    fingerprint VARCHAR(128) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    severity VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    source VARCHAR(100) NOT NULL,
+    message TEXT,
+    labels JSONB DEFAULT '{}',
+    annotations JSONB DEFAULT '{}',
+    service_id UUID,
+    project_id UUID,
+    cluster_id UUID,
+    starts_at BIGINT NOT NULL,
+    ends_at BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_fingerprint_status ON alerts(fingerprint, status);
+CREATE INDEX IF NOT EXISTS idx_alerts_service_id ON alerts(service_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_starts_at ON alerts(starts_at DESC);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS probe_results (
+    id UUID PRIMARY KEY,
+    ingress_id UUID NOT NULL,
+    service_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_time BIGINT NOT NULL DEFAULT 0,
+    success BOOLEAN NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_probe_results_ingress_checked_at ON probe_results(ingress_id, checked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_probe_results_checked_at ON probe_results(checked_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS usage_records (
+    service_id UUID NOT NULL,
+    project_id UUID NOT NULL,
+    hour TIMESTAMPTZ NOT NULL,
+    cpu_core_hours DOUBLE PRECISION NOT NULL DEFAULT 0,
+    memory_gib_hours DOUBLE PRECISION NOT NULL DEFAULT 0,
+    egress_bytes DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (service_id, hour)
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_records_project_hour ON usage_records(project_id, hour);
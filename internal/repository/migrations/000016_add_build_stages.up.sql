@@ -0,0 +1 @@
+ALTER TABLE builds ADD COLUMN IF NOT EXISTS stages JSONB NOT NULL DEFAULT '[]';
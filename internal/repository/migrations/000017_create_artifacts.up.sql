@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS artifacts (
+    id UUID PRIMARY KEY,
+    build_id UUID NOT NULL REFERENCES builds(id) ON DELETE CASCADE,
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    storage_key TEXT NOT NULL,
+    uploaded_by VARCHAR(255),
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (build_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_artifacts_service_created_at ON artifacts(service_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_artifacts_expires_at ON artifacts(expires_at);
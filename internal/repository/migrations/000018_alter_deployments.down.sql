@@ -0,0 +1,4 @@
+-- Fails while deployments without a build exist
+ALTER TABLE deployments ALTER COLUMN build_id SET NOT NULL;
+ALTER TABLE deployments DROP COLUMN IF EXISTS health;
+ALTER TABLE deployments DROP COLUMN IF EXISTS pre_pull;
@@ -0,0 +1,5 @@
+-- Deploys of images not built by the platform have no build; health and
+-- pre-pull results are recorded as the workflow engine progresses
+ALTER TABLE deployments ALTER COLUMN build_id DROP NOT NULL;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS health JSONB;
+ALTER TABLE deployments ADD COLUMN IF NOT EXISTS pre_pull JSONB;
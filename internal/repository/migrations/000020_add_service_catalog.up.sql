@@ -0,0 +1 @@
+ALTER TABLE services ADD COLUMN IF NOT EXISTS catalog JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_clusters_labels;
+DROP INDEX IF EXISTS idx_services_labels;
+DROP INDEX IF EXISTS idx_projects_labels;
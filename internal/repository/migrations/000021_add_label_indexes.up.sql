@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_projects_labels ON projects USING GIN (labels jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_services_labels ON services USING GIN (labels jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS idx_clusters_labels ON clusters USING GIN (labels jsonb_path_ops);
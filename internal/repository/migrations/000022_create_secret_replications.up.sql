@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS secret_replications (
+    id UUID PRIMARY KEY,
+    secret_id UUID NOT NULL REFERENCES secrets(id) ON DELETE CASCADE,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    cluster_id UUID NOT NULL REFERENCES clusters(id) ON DELETE CASCADE,
+    namespace VARCHAR(255) NOT NULL,
+    secret_name VARCHAR(255) NOT NULL,
+    desired_version INTEGER NOT NULL DEFAULT 0,
+    synced_version INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    desired_since TIMESTAMPTZ NOT NULL,
+    last_synced_at TIMESTAMPTZ,
+    checked_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(secret_id, cluster_id, namespace)
+);
+
+CREATE INDEX IF NOT EXISTS idx_secret_replications_project_id ON secret_replications(project_id);
@@ -0,0 +1,2 @@
+-- Static egress IPs of an environment and how they are provided
+ALTER TABLE environments ADD COLUMN IF NOT EXISTS egress JSONB;
@@ -0,0 +1,2 @@
+ALTER TABLE ingresses DROP COLUMN IF EXISTS ip_families;
+ALTER TABLE services DROP COLUMN IF EXISTS networking;
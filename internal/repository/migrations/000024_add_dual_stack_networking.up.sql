@@ -0,0 +1,3 @@
+-- IP families of a service's Kubernetes Service and of an ingress's DNS records
+ALTER TABLE services ADD COLUMN IF NOT EXISTS networking JSONB;
+ALTER TABLE ingresses ADD COLUMN IF NOT EXISTS ip_families JSONB;
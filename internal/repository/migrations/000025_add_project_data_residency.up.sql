@@ -0,0 +1,2 @@
+-- Region a project's workloads, databases and backups must stay in
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS data_residency VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS templates (
+    id UUID PRIMARY KEY,
+    slug VARCHAR(63) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    category VARCHAR(63) NOT NULL DEFAULT '',
+    tags JSONB NOT NULL DEFAULT '[]',
+    manifest TEXT NOT NULL,
+    variables JSONB NOT NULL DEFAULT '[]',
+    created_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
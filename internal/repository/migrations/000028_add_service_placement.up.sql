@@ -0,0 +1,2 @@
+-- Region and cluster label constraints the placement scheduler honours for a service
+ALTER TABLE services ADD COLUMN IF NOT EXISTS placement JSONB;
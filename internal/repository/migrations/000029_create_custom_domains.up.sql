@@ -0,0 +1,22 @@
+-- Domains projects claim for their ingresses, routable once their DNS is verified
+CREATE TABLE IF NOT EXISTS custom_domains (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name VARCHAR(253) NOT NULL,
+    method VARCHAR(20) NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    verified_at TIMESTAMPTZ,
+    checked_at TIMESTAMPTZ,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(project_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_custom_domains_name ON custom_domains(name);
+CREATE INDEX IF NOT EXISTS idx_custom_domains_status ON custom_domains(status);
+
+-- Only one project can hold a verified domain
+CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_domains_verified_name ON custom_domains(name) WHERE status = 'verified';
@@ -0,0 +1 @@
+ALTER TABLE ingresses DROP COLUMN IF EXISTS routing;
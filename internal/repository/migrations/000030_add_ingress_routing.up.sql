@@ -0,0 +1,2 @@
+-- Routing options of an ingress: path rewrite, body size limit and sticky sessions
+ALTER TABLE ingresses ADD COLUMN IF NOT EXISTS routing JSONB;
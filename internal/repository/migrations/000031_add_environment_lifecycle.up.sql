@@ -0,0 +1,2 @@
+-- Expiry and idle hibernation policy of an environment, with its hibernation state
+ALTER TABLE environments ADD COLUMN IF NOT EXISTS lifecycle JSONB;
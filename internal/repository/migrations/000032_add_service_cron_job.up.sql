@@ -0,0 +1,2 @@
+-- Schedule and job settings of cron job services
+ALTER TABLE services ADD COLUMN IF NOT EXISTS cron_job JSONB;
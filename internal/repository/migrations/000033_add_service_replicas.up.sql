@@ -0,0 +1,2 @@
+-- Replica counts of services as last observed in their clusters
+ALTER TABLE services ADD COLUMN IF NOT EXISTS replicas JSONB;
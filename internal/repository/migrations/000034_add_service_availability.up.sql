@@ -0,0 +1,2 @@
+-- Disruption budgets and topology spread of services
+ALTER TABLE services ADD COLUMN IF NOT EXISTS availability JSONB;
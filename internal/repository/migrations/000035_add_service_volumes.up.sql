@@ -0,0 +1,2 @@
+-- Persistent volumes of services
+ALTER TABLE services ADD COLUMN IF NOT EXISTS volumes JSONB;
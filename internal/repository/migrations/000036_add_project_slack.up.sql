@@ -0,0 +1,2 @@
+-- Slack channel routing of projects' notifications
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS slack JSONB;
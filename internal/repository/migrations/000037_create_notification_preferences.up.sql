@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    id UUID PRIMARY KEY,
+    user_id UUID,
+    project_id UUID REFERENCES projects(id) ON DELETE CASCADE,
+    channel VARCHAR(32) NOT NULL,
+    events JSONB NOT NULL DEFAULT '[]',
+    severities JSONB NOT NULL DEFAULT '[]',
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    mute JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (user_id IS NOT NULL OR project_id IS NOT NULL)
+);
+
+-- One preference per scope and channel; NULL scopes compare equal
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_preferences_scope_channel ON notification_preferences (
+    COALESCE(user_id, '00000000-0000-0000-0000-000000000000'),
+    COALESCE(project_id, '00000000-0000-0000-0000-000000000000'),
+    channel
+);
+CREATE INDEX IF NOT EXISTS idx_notification_preferences_user_id ON notification_preferences(user_id);
+CREATE INDEX IF NOT EXISTS idx_notification_preferences_project_id ON notification_preferences(project_id) WHERE user_id IS NULL;
@@ -0,0 +1,2 @@
+-- Endpoint responses are no longer logged; they could hold whatever an endpoint reached
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS response_body;
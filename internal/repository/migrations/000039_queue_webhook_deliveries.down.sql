@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_pending;
+DELETE FROM webhook_deliveries WHERE status = 'pending';
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS payload;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS next_attempt_at;
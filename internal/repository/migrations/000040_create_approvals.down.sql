@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS approval_delegations;
+DROP TABLE IF EXISTS approvals;
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS approvals (
+    id UUID PRIMARY KEY,
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    service_id UUID NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    environment_id UUID NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    workflow_id UUID NOT NULL,
+    version VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(32) NOT NULL DEFAULT 'pending',
+    approver_id UUID NOT NULL,
+    escalated_at TIMESTAMPTZ,
+    decided_by UUID,
+    decided_at TIMESTAMPTZ,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_approvals_workflow_id ON approvals(workflow_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_approvals_approver_id ON approvals(approver_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_approvals_project_id ON approvals(project_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_approvals_pending ON approvals(created_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS approval_delegations (
+    id UUID PRIMARY KEY,
+    delegator_id UUID NOT NULL,
+    delegate_id UUID NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at),
+    CHECK (delegate_id <> delegator_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegator_id ON approval_delegations(delegator_id);
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegate_id ON approval_delegations(delegate_id, ends_at);
@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// NotificationPreferenceRepository implements domain.NotificationPreferenceRepository using PostgreSQL
+type NotificationPreferenceRepository struct {
+	db *PostgresDB
+}
+
+// NewNotificationPreferenceRepository creates a new NotificationPreferenceRepository
+func NewNotificationPreferenceRepository(db *PostgresDB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db}
+}
+
+const notificationPreferenceColumns = `id, user_id, project_id, channel, events, severities, muted, mute, created_at, updated_at`
+
+// Create creates a new notification preference
+func (r *NotificationPreferenceRepository) Create(ctx context.Context, preference *domain.NotificationPreference) error {
+	events, severities, mute := marshalPreference(preference)
+
+	query := `
+		INSERT INTO notification_preferences (` + notificationPreferenceColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		preference.ID,
+		preference.UserID,
+		preference.ProjectID,
+		preference.Channel,
+		events,
+		severities,
+		preference.Muted,
+		mute,
+		preference.CreatedAt,
+		preference.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("notification preference for channel " + preference.Channel)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create notification preference")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a notification preference by ID
+func (r *NotificationPreferenceRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.NotificationPreference, error) {
+	query := `SELECT ` + notificationPreferenceColumns + ` FROM notification_preferences WHERE id = $1`
+
+	preference, err := scanNotificationPreference(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("notification preference", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get notification preference")
+	}
+
+	return preference, nil
+}
+
+// ListByUser retrieves the preferences of a user, for every project and none
+func (r *NotificationPreferenceRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.NotificationPreference, error) {
+	query := `SELECT ` + notificationPreferenceColumns + ` FROM notification_preferences
+		WHERE user_id = $1 ORDER BY project_id NULLS FIRST, channel`
+	return r.list(ctx, query, userID)
+}
+
+// ListByProject retrieves the preferences of a project, not those of its users
+func (r *NotificationPreferenceRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.NotificationPreference, error) {
+	query := `SELECT ` + notificationPreferenceColumns + ` FROM notification_preferences
+		WHERE project_id = $1 AND user_id IS NULL ORDER BY channel`
+	return r.list(ctx, query, projectID)
+}
+
+func (r *NotificationPreferenceRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.NotificationPreference, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list notification preferences")
+	}
+	defer rows.Close()
+
+	preferences := []*domain.NotificationPreference{}
+	for rows.Next() {
+		preference, err := scanNotificationPreference(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan notification preference")
+		}
+		preferences = append(preferences, preference)
+	}
+
+	return preferences, nil
+}
+
+// Update updates what a notification preference lets through. Its scope and
+// channel do not change.
+func (r *NotificationPreferenceRepository) Update(ctx context.Context, preference *domain.NotificationPreference) error {
+	events, severities, mute := marshalPreference(preference)
+
+	query := `
+		UPDATE notification_preferences
+		SET events = $2, severities = $3, muted = $4, mute = $5, updated_at = $6
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		preference.ID,
+		events,
+		severities,
+		preference.Muted,
+		mute,
+		preference.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update notification preference")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("notification preference", preference.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a notification preference
+func (r *NotificationPreferenceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM notification_preferences WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete notification preference")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("notification preference", id.String())
+	}
+
+	return nil
+}
+
+// marshalPreference encodes the lists of a preference, empty ones as []
+func marshalPreference(preference *domain.NotificationPreference) (events, severities, mute []byte) {
+	events, _ = json.Marshal(nonNil(preference.Events))
+	severities, _ = json.Marshal(nonNil(preference.Severities))
+	mute = []byte("[]")
+	if len(preference.Mute) > 0 {
+		mute, _ = json.Marshal(preference.Mute)
+	}
+	return events, severities, mute
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func scanNotificationPreference(row pgx.Row) (*domain.NotificationPreference, error) {
+	preference := &domain.NotificationPreference{}
+	var events, severities, mute []byte
+
+	err := row.Scan(
+		&preference.ID,
+		&preference.UserID,
+		&preference.ProjectID,
+		&preference.Channel,
+		&events,
+		&severities,
+		&preference.Muted,
+		&mute,
+		&preference.CreatedAt,
+		&preference.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(events, &preference.Events)
+	json.Unmarshal(severities, &preference.Severities)
+	json.Unmarshal(mute, &preference.Mute)
+
+	return preference, nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// NotificationRepository implements domain.NotificationRepository using PostgreSQL
+type NotificationRepository struct {
+	db *PostgresDB
+}
+
+// NewNotificationRepository creates a new NotificationRepository
+func NewNotificationRepository(db *PostgresDB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+const notificationColumns = `id, user_id, type, title, body, link, project_id, service_id, data, read_at, created_at`
+
+// Create creates a new notification
+func (r *NotificationRepository) Create(ctx context.Context, notification *domain.UserNotification) error {
+	data, _ := json.Marshal(notification.Data)
+
+	query := `
+		INSERT INTO notifications (` + notificationColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		notification.ID,
+		notification.UserID,
+		notification.Type,
+		notification.Title,
+		notification.Body,
+		notification.Link,
+		notification.ProjectID,
+		notification.ServiceID,
+		data,
+		notification.ReadAt,
+		notification.CreatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create notification")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a notification of a user by ID
+func (r *NotificationRepository) GetByID(ctx context.Context, userID, id uuid.UUID) (*domain.UserNotification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE id = $1 AND user_id = $2`
+
+	notification, err := scanNotification(r.db.pool.QueryRow(ctx, query, id, userID))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("notification", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get notification")
+	}
+
+	return notification, nil
+}
+
+// ListByUser retrieves the notifications of a user, newest first
+func (r *NotificationRepository) ListByUser(ctx context.Context, userID uuid.UUID, filter domain.NotificationFilter) ([]*domain.UserNotification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	if filter.UnreadOnly {
+		query += " AND read_at IS NULL"
+	}
+
+	query, args = keyset(query, args, filter.After, filter.Limit)
+	query, args = skip(query, args, filter.Offset)
+
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list notifications")
+	}
+	defer rows.Close()
+
+	notifications := []*domain.UserNotification{}
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan notification")
+		}
+		notifications = append(notifications, notification)
+	}
+
+	return notifications, rows.Err()
+}
+
+// CountUnread returns the number of unread notifications of a user
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to count unread notifications")
+	}
+
+	return count, nil
+}
+
+// MarkRead marks a notification of a user as read; marking it again keeps the first read time
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx,
+		`UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2`,
+		id, userID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark notification read")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("notification", id.String())
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of a user as read and returns how many were
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := r.db.pool.Exec(ctx,
+		`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`,
+		userID,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to mark notifications read")
+	}
+
+	return result.RowsAffected(), nil
+}
+
+func scanNotification(row pgx.Row) (*domain.UserNotification, error) {
+	notification := &domain.UserNotification{}
+	var data []byte
+
+	err := row.Scan(
+		&notification.ID,
+		&notification.UserID,
+		&notification.Type,
+		&notification.Title,
+		&notification.Body,
+		&notification.Link,
+		&notification.ProjectID,
+		&notification.ServiceID,
+		&data,
+		&notification.ReadAt,
+		&notification.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(data, &notification.Data)
+
+	return notification, nil
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+// keyset completes a query listing rows newest first: it restricts the rows
+// to those after the cursor, if any, then orders and limits them; a limit of
+// zero keeps every row. The query must end in a WHERE clause whose
+// parameters are args.
+func keyset(query string, args []interface{}, after *domain.Cursor, limit int) (string, []interface{}) {
+	if after != nil {
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", len(args)+1, len(args)+2)
+		args = append(args, after.CreatedAt, after.ID)
+	}
+
+	query += " ORDER BY created_at DESC, id DESC"
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", len(args)+1)
+		args = append(args, limit)
+	}
+	return query, args
+}
+
+// skip drops the first offset rows of a query completed by keyset, for
+// clients still paging by offset
+func skip(query string, args []interface{}, offset int) (string, []interface{}) {
+	if offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", len(args)+1)
+		args = append(args, offset)
+	}
+	return query, args
+}
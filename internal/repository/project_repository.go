@@ -28,8 +28,8 @@ func (r *ProjectRepository) Create(ctx context.Context, project *domain.Project)
 	metadata, _ := json.Marshal(project.Metadata)
 
 	query := `
-		INSERT INTO projects (id, name, slug, description, status, owner_id, team_id, labels, metadata, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO projects (id, name, slug, description, status, owner_id, team_id, labels, metadata, data_residency, slack, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
 	_, err := r.db.pool.Exec(ctx, query,
@@ -42,6 +42,8 @@ func (r *ProjectRepository) Create(ctx context.Context, project *domain.Project)
 		project.TeamID,
 		labels,
 		metadata,
+		project.DataResidency,
+		nullableJSON(project.Slack),
 		project.CreatedAt,
 		project.UpdatedAt,
 	)
@@ -56,13 +58,13 @@ func (r *ProjectRepository) Create(ctx context.Context, project *domain.Project)
 // GetByID retrieves a project by ID
 func (r *ProjectRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Project, error) {
 	query := `
-		SELECT id, name, slug, description, status, owner_id, team_id, labels, metadata, created_at, updated_at
+		SELECT id, name, slug, description, status, owner_id, team_id, labels, metadata, data_residency, slack, created_at, updated_at
 		FROM projects
 		WHERE id = $1
 	`
 
 	project := &domain.Project{}
-	var labels, metadata []byte
+	var labels, metadata, slack []byte
 
 	err := r.db.pool.QueryRow(ctx, query, id).Scan(
 		&project.ID,
@@ -74,6 +76,8 @@ func (r *ProjectRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 		&project.TeamID,
 		&labels,
 		&metadata,
+		&project.DataResidency,
+		&slack,
 		&project.CreatedAt,
 		&project.UpdatedAt,
 	)
@@ -87,6 +91,7 @@ func (r *ProjectRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 
 	json.Unmarshal(labels, &project.Labels)
 	json.Unmarshal(metadata, &project.Metadata)
+	json.Unmarshal(slack, &project.Slack)
 
 	return project, nil
 }
@@ -94,13 +99,13 @@ func (r *ProjectRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 // GetBySlug retrieves a project by slug
 func (r *ProjectRepository) GetBySlug(ctx context.Context, slug string) (*domain.Project, error) {
 	query := `
-		SELECT id, name, slug, description, status, owner_id, team_id, labels, metadata, created_at, updated_at
+		SELECT id, name, slug, description, status, owner_id, team_id, labels, metadata, data_residency, slack, created_at, updated_at
 		FROM projects
 		WHERE slug = $1
 	`
 
 	project := &domain.Project{}
-	var labels, metadata []byte
+	var labels, metadata, slack []byte
 
 	err := r.db.pool.QueryRow(ctx, query, slug).Scan(
 		&project.ID,
@@ -112,6 +117,8 @@ func (r *ProjectRepository) GetBySlug(ctx context.Context, slug string) (*domain
 		&project.TeamID,
 		&labels,
 		&metadata,
+		&project.DataResidency,
+		&slack,
 		&project.CreatedAt,
 		&project.UpdatedAt,
 	)
@@ -125,6 +132,7 @@ func (r *ProjectRepository) GetBySlug(ctx context.Context, slug string) (*domain
 
 	json.Unmarshal(labels, &project.Labels)
 	json.Unmarshal(metadata, &project.Metadata)
+	json.Unmarshal(slack, &project.Slack)
 
 	return project, nil
 }
@@ -132,7 +140,7 @@ func (r *ProjectRepository) GetBySlug(ctx context.Context, slug string) (*domain
 // List retrieves projects with optional filtering
 func (r *ProjectRepository) List(ctx context.Context, filter domain.ProjectFilter) ([]*domain.Project, error) {
 	query := `
-		SELECT id, name, slug, description, status, owner_id, team_id, labels, metadata, created_at, updated_at
+		SELECT id, name, slug, description, status, owner_id, team_id, labels, metadata, data_residency, slack, created_at, updated_at
 		FROM projects
 		WHERE 1=1
 	`
@@ -163,18 +171,14 @@ func (r *ProjectRepository) List(ctx context.Context, filter domain.ProjectFilte
 		argIndex++
 	}
 
-	query += " ORDER BY created_at DESC"
-
-	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argIndex)
-		args = append(args, filter.Limit)
-		argIndex++
+	if len(filter.Labels) > 0 {
+		labels, _ := json.Marshal(filter.Labels)
+		query += fmt.Sprintf(" AND labels @> $%d", argIndex)
+		args = append(args, labels)
 	}
 
-	if filter.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argIndex)
-		args = append(args, filter.Offset)
-	}
+	query, args = keyset(query, args, filter.After, filter.Limit)
+	query, args = skip(query, args, filter.Offset)
 
 	rows, err := r.db.pool.Query(ctx, query, args...)
 	if err != nil {
@@ -185,7 +189,7 @@ func (r *ProjectRepository) List(ctx context.Context, filter domain.ProjectFilte
 	projects := []*domain.Project{}
 	for rows.Next() {
 		project := &domain.Project{}
-		var labels, metadata []byte
+		var labels, metadata, slack []byte
 
 		err := rows.Scan(
 			&project.ID,
@@ -197,6 +201,8 @@ func (r *ProjectRepository) List(ctx context.Context, filter domain.ProjectFilte
 			&project.TeamID,
 			&labels,
 			&metadata,
+			&project.DataResidency,
+			&slack,
 			&project.CreatedAt,
 			&project.UpdatedAt,
 		)
@@ -206,6 +212,8 @@ func (r *ProjectRepository) List(ctx context.Context, filter domain.ProjectFilte
 
 		json.Unmarshal(labels, &project.Labels)
 		json.Unmarshal(metadata, &project.Metadata)
+		json.Unmarshal(slack, &project.Slack)
+	json.Unmarshal(slack, &project.Slack)
 
 		projects = append(projects, project)
 	}
@@ -221,7 +229,7 @@ func (r *ProjectRepository) Update(ctx context.Context, project *domain.Project)
 
 	query := `
 		UPDATE projects
-		SET name = $2, slug = $3, description = $4, status = $5, team_id = $6, labels = $7, metadata = $8, updated_at = $9
+		SET name = $2, slug = $3, description = $4, status = $5, team_id = $6, labels = $7, metadata = $8, data_residency = $9, slack = $10, updated_at = $11
 		WHERE id = $1
 	`
 
@@ -234,6 +242,8 @@ func (r *ProjectRepository) Update(ctx context.Context, project *domain.Project)
 		project.TeamID,
 		labels,
 		metadata,
+		project.DataResidency,
+		nullableJSON(project.Slack),
 		project.UpdatedAt,
 	)
 
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// SecretReplicationRepository implements domain.SecretReplicationRepository using PostgreSQL
+type SecretReplicationRepository struct {
+	db *PostgresDB
+}
+
+// NewSecretReplicationRepository creates a new SecretReplicationRepository
+func NewSecretReplicationRepository(db *PostgresDB) *SecretReplicationRepository {
+	return &SecretReplicationRepository{db: db}
+}
+
+const secretReplicationColumns = `id, secret_id, project_id, cluster_id, namespace, secret_name, desired_version, synced_version,
+	status, message, desired_since, last_synced_at, checked_at, created_at, updated_at`
+
+// Upsert records the replication of a secret into a cluster namespace. The
+// row is keyed by secret, cluster and namespace; ID and CreatedAt of an
+// existing row are kept and copied back into replication.
+func (r *SecretReplicationRepository) Upsert(ctx context.Context, replication *domain.SecretReplication) error {
+	query := `
+		INSERT INTO secret_replications (` + secretReplicationColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (secret_id, cluster_id, namespace) DO UPDATE
+		SET secret_name = EXCLUDED.secret_name,
+		    desired_version = EXCLUDED.desired_version,
+		    synced_version = EXCLUDED.synced_version,
+		    status = EXCLUDED.status,
+		    message = EXCLUDED.message,
+		    desired_since = EXCLUDED.desired_since,
+		    last_synced_at = EXCLUDED.last_synced_at,
+		    checked_at = EXCLUDED.checked_at,
+		    updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+
+	err := r.db.pool.QueryRow(ctx, query,
+		replication.ID,
+		replication.SecretID,
+		replication.ProjectID,
+		replication.ClusterID,
+		replication.Namespace,
+		replication.SecretName,
+		replication.DesiredVersion,
+		replication.SyncedVersion,
+		replication.Status,
+		replication.Message,
+		replication.DesiredSince,
+		replication.LastSyncedAt,
+		replication.CheckedAt,
+		replication.CreatedAt,
+		replication.UpdatedAt,
+	).Scan(&replication.ID, &replication.CreatedAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to record secret replication")
+	}
+
+	return nil
+}
+
+// ListByProject retrieves the replications of a project's secrets
+func (r *SecretReplicationRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.SecretReplication, error) {
+	query := `SELECT ` + secretReplicationColumns + ` FROM secret_replications
+		WHERE project_id = $1 ORDER BY secret_name, cluster_id, namespace`
+	return r.list(ctx, query, projectID)
+}
+
+// ListBySecret retrieves the replications of a secret
+func (r *SecretReplicationRepository) ListBySecret(ctx context.Context, secretID uuid.UUID) ([]*domain.SecretReplication, error) {
+	query := `SELECT ` + secretReplicationColumns + ` FROM secret_replications
+		WHERE secret_id = $1 ORDER BY cluster_id, namespace`
+	return r.list(ctx, query, secretID)
+}
+
+func (r *SecretReplicationRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.SecretReplication, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list secret replications")
+	}
+	defer rows.Close()
+
+	replications := []*domain.SecretReplication{}
+	for rows.Next() {
+		replication, err := scanSecretReplication(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan secret replication")
+		}
+		replications = append(replications, replication)
+	}
+
+	return replications, nil
+}
+
+func scanSecretReplication(row pgx.Row) (*domain.SecretReplication, error) {
+	replication := &domain.SecretReplication{}
+
+	err := row.Scan(
+		&replication.ID,
+		&replication.SecretID,
+		&replication.ProjectID,
+		&replication.ClusterID,
+		&replication.Namespace,
+		&replication.SecretName,
+		&replication.DesiredVersion,
+		&replication.SyncedVersion,
+		&replication.Status,
+		&replication.Message,
+		&replication.DesiredSince,
+		&replication.LastSyncedAt,
+		&replication.CheckedAt,
+		&replication.CreatedAt,
+		&replication.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return replication, nil
+}
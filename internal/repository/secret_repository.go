@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// SecretRepository implements domain.SecretRepository using PostgreSQL
+type SecretRepository struct {
+	db *PostgresDB
+}
+
+// NewSecretRepository creates a new SecretRepository
+func NewSecretRepository(db *PostgresDB) *SecretRepository {
+	return &SecretRepository{db: db}
+}
+
+const secretColumns = `id, project_id, name, type, keys, vault_path, version, labels, created_at, updated_at`
+
+// Create creates a new secret
+func (r *SecretRepository) Create(ctx context.Context, secret *domain.Secret) error {
+	keys, _ := json.Marshal(secret.Keys)
+	labels, _ := json.Marshal(secret.Labels)
+
+	query := `
+		INSERT INTO secrets (` + secretColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		secret.ID,
+		secret.ProjectID,
+		secret.Name,
+		secret.Type,
+		keys,
+		secret.VaultPath,
+		secret.Version,
+		labels,
+		secret.CreatedAt,
+		secret.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("secret " + secret.Name)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create secret")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a secret by ID
+func (r *SecretRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Secret, error) {
+	query := `SELECT ` + secretColumns + ` FROM secrets WHERE id = $1`
+
+	secret, err := scanSecret(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("secret", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get secret")
+	}
+
+	return secret, nil
+}
+
+// GetByName retrieves a project's secret by name
+func (r *SecretRepository) GetByName(ctx context.Context, projectID uuid.UUID, name string) (*domain.Secret, error) {
+	query := `SELECT ` + secretColumns + ` FROM secrets WHERE project_id = $1 AND name = $2`
+
+	secret, err := scanSecret(r.db.pool.QueryRow(ctx, query, projectID, name))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("secret", name)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get secret")
+	}
+
+	return secret, nil
+}
+
+// ListByProject retrieves the secrets of a project
+func (r *SecretRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.Secret, error) {
+	return r.list(ctx, `SELECT `+secretColumns+` FROM secrets WHERE project_id = $1 ORDER BY name`, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's secrets, newest first
+func (r *SecretRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Secret, error) {
+	query, args := keyset(`SELECT `+secretColumns+` FROM secrets WHERE project_id = $1`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+func (r *SecretRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Secret, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list secrets")
+	}
+	defer rows.Close()
+
+	secrets := []*domain.Secret{}
+	for rows.Next() {
+		secret, err := scanSecret(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan secret")
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, nil
+}
+
+// Update updates an existing secret
+func (r *SecretRepository) Update(ctx context.Context, secret *domain.Secret) error {
+	keys, _ := json.Marshal(secret.Keys)
+	labels, _ := json.Marshal(secret.Labels)
+	secret.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE secrets
+		SET type = $2, keys = $3, vault_path = $4, version = $5, labels = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		secret.ID,
+		secret.Type,
+		keys,
+		secret.VaultPath,
+		secret.Version,
+		labels,
+		secret.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update secret")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("secret", secret.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a secret
+func (r *SecretRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM secrets WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete secret")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("secret", id.String())
+	}
+
+	return nil
+}
+
+func scanSecret(row pgx.Row) (*domain.Secret, error) {
+	secret := &domain.Secret{}
+	var keys, labels []byte
+
+	err := row.Scan(
+		&secret.ID,
+		&secret.ProjectID,
+		&secret.Name,
+		&secret.Type,
+		&keys,
+		&secret.VaultPath,
+		&secret.Version,
+		&labels,
+		&secret.CreatedAt,
+		&secret.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(keys, &secret.Keys)
+	json.Unmarshal(labels, &secret.Labels)
+
+	return secret, nil
+}
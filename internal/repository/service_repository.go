@@ -34,14 +34,15 @@ func (r *ServiceRepository) Create(ctx context.Context, service *domain.Service)
 	labels, _ := json.Marshal(service.Labels)
 	annotations, _ := json.Marshal(service.Annotations)
 	metadata, _ := json.Marshal(service.Metadata)
+	catalog, _ := json.Marshal(service.Catalog)
 
 	query := `
 		INSERT INTO services (
 			id, project_id, name, slug, type, status, build_source, resources, scaling,
 			health_check, env_vars, secret_refs, ports, labels, annotations, metadata,
-			current_build_id, current_version, target_cluster_id, created_at, updated_at
+			current_build_id, current_version, target_cluster_id, catalog, networking, placement, cron_job, availability, volumes, created_at, updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27)
 	`
 
 	_, err := r.db.pool.Exec(ctx, query,
@@ -64,6 +65,12 @@ func (r *ServiceRepository) Create(ctx context.Context, service *domain.Service)
 		service.CurrentBuildID,
 		service.CurrentVersion,
 		service.TargetClusterID,
+		catalog,
+		nullableJSON(service.Networking),
+		nullableJSON(service.Placement),
+		nullableJSON(service.CronJob),
+		nullableJSON(service.Availability),
+		nullableJSON(service.Volumes),
 		service.CreatedAt,
 		service.UpdatedAt,
 	)
@@ -80,7 +87,7 @@ func (r *ServiceRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.
 	query := `
 		SELECT id, project_id, name, slug, type, status, build_source, resources, scaling,
 			health_check, env_vars, secret_refs, ports, labels, annotations, metadata,
-			current_build_id, current_version, target_cluster_id, created_at, updated_at
+			current_build_id, current_version, target_cluster_id, catalog, networking, placement, cron_job, availability, volumes, replicas, created_at, updated_at
 		FROM services
 		WHERE id = $1
 	`
@@ -93,7 +100,7 @@ func (r *ServiceRepository) GetBySlug(ctx context.Context, projectID uuid.UUID,
 	query := `
 		SELECT id, project_id, name, slug, type, status, build_source, resources, scaling,
 			health_check, env_vars, secret_refs, ports, labels, annotations, metadata,
-			current_build_id, current_version, target_cluster_id, created_at, updated_at
+			current_build_id, current_version, target_cluster_id, catalog, networking, placement, cron_job, availability, volumes, replicas, created_at, updated_at
 		FROM services
 		WHERE project_id = $1 AND slug = $2
 	`
@@ -103,7 +110,7 @@ func (r *ServiceRepository) GetBySlug(ctx context.Context, projectID uuid.UUID,
 
 func (r *ServiceRepository) scanService(ctx context.Context, query string, args ...interface{}) (*domain.Service, error) {
 	service := &domain.Service{}
-	var buildSource, resources, scaling, healthCheck, envVars, secretRefs, ports, labels, annotations, metadata []byte
+	var buildSource, resources, scaling, healthCheck, envVars, secretRefs, ports, labels, annotations, metadata, catalog, networking, placement, cronJob, availability, volumes, replicas []byte
 
 	err := r.db.pool.QueryRow(ctx, query, args...).Scan(
 		&service.ID,
@@ -125,6 +132,13 @@ func (r *ServiceRepository) scanService(ctx context.Context, query string, args
 		&service.CurrentBuildID,
 		&service.CurrentVersion,
 		&service.TargetClusterID,
+		&catalog,
+		&networking,
+		&placement,
+		&cronJob,
+		&availability,
+		&volumes,
+		&replicas,
 		&service.CreatedAt,
 		&service.UpdatedAt,
 	)
@@ -146,6 +160,13 @@ func (r *ServiceRepository) scanService(ctx context.Context, query string, args
 	json.Unmarshal(labels, &service.Labels)
 	json.Unmarshal(annotations, &service.Annotations)
 	json.Unmarshal(metadata, &service.Metadata)
+	json.Unmarshal(catalog, &service.Catalog)
+	json.Unmarshal(networking, &service.Networking)
+	json.Unmarshal(placement, &service.Placement)
+	json.Unmarshal(cronJob, &service.CronJob)
+	json.Unmarshal(availability, &service.Availability)
+	json.Unmarshal(volumes, &service.Volumes)
+	json.Unmarshal(replicas, &service.Replicas)
 
 	return service, nil
 }
@@ -155,7 +176,7 @@ func (r *ServiceRepository) ListByProject(ctx context.Context, projectID uuid.UU
 	query := `
 		SELECT id, project_id, name, slug, type, status, build_source, resources, scaling,
 			health_check, env_vars, secret_refs, ports, labels, annotations, metadata,
-			current_build_id, current_version, target_cluster_id, created_at, updated_at
+			current_build_id, current_version, target_cluster_id, catalog, networking, placement, cron_job, availability, volumes, replicas, created_at, updated_at
 		FROM services
 		WHERE project_id = $1
 	`
@@ -180,18 +201,14 @@ func (r *ServiceRepository) ListByProject(ctx context.Context, projectID uuid.UU
 		argIndex++
 	}
 
-	query += " ORDER BY created_at DESC"
-
-	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argIndex)
-		args = append(args, filter.Limit)
-		argIndex++
+	if len(filter.Labels) > 0 {
+		labels, _ := json.Marshal(filter.Labels)
+		query += fmt.Sprintf(" AND labels @> $%d", argIndex)
+		args = append(args, labels)
 	}
 
-	if filter.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argIndex)
-		args = append(args, filter.Offset)
-	}
+	query, args = keyset(query, args, filter.After, filter.Limit)
+	query, args = skip(query, args, filter.Offset)
 
 	rows, err := r.db.pool.Query(ctx, query, args...)
 	if err != nil {
@@ -202,7 +219,7 @@ func (r *ServiceRepository) ListByProject(ctx context.Context, projectID uuid.UU
 	services := []*domain.Service{}
 	for rows.Next() {
 		service := &domain.Service{}
-		var buildSource, resources, scaling, healthCheck, envVars, secretRefs, ports, labels, annotations, metadata []byte
+		var buildSource, resources, scaling, healthCheck, envVars, secretRefs, ports, labels, annotations, metadata, catalog, networking, placement, cronJob, availability, volumes, replicas []byte
 
 		err := rows.Scan(
 			&service.ID,
@@ -224,6 +241,13 @@ func (r *ServiceRepository) ListByProject(ctx context.Context, projectID uuid.UU
 			&service.CurrentBuildID,
 			&service.CurrentVersion,
 			&service.TargetClusterID,
+			&catalog,
+			&networking,
+			&placement,
+			&cronJob,
+			&availability,
+			&volumes,
+			&replicas,
 			&service.CreatedAt,
 			&service.UpdatedAt,
 		)
@@ -241,6 +265,13 @@ func (r *ServiceRepository) ListByProject(ctx context.Context, projectID uuid.UU
 		json.Unmarshal(labels, &service.Labels)
 		json.Unmarshal(annotations, &service.Annotations)
 		json.Unmarshal(metadata, &service.Metadata)
+		json.Unmarshal(catalog, &service.Catalog)
+		json.Unmarshal(networking, &service.Networking)
+		json.Unmarshal(placement, &service.Placement)
+		json.Unmarshal(cronJob, &service.CronJob)
+		json.Unmarshal(availability, &service.Availability)
+		json.Unmarshal(volumes, &service.Volumes)
+		json.Unmarshal(replicas, &service.Replicas)
 
 		services = append(services, service)
 	}
@@ -260,6 +291,7 @@ func (r *ServiceRepository) Update(ctx context.Context, service *domain.Service)
 	labels, _ := json.Marshal(service.Labels)
 	annotations, _ := json.Marshal(service.Annotations)
 	metadata, _ := json.Marshal(service.Metadata)
+	catalog, _ := json.Marshal(service.Catalog)
 	service.UpdatedAt = time.Now()
 
 	query := `
@@ -267,7 +299,7 @@ func (r *ServiceRepository) Update(ctx context.Context, service *domain.Service)
 		SET name = $2, slug = $3, type = $4, status = $5, build_source = $6, resources = $7,
 			scaling = $8, health_check = $9, env_vars = $10, secret_refs = $11, ports = $12,
 			labels = $13, annotations = $14, metadata = $15, current_build_id = $16,
-			current_version = $17, target_cluster_id = $18, updated_at = $19
+			current_version = $17, target_cluster_id = $18, catalog = $19, networking = $20, placement = $21, cron_job = $22, availability = $23, volumes = $24, updated_at = $25
 		WHERE id = $1
 	`
 
@@ -290,6 +322,12 @@ func (r *ServiceRepository) Update(ctx context.Context, service *domain.Service)
 		service.CurrentBuildID,
 		service.CurrentVersion,
 		service.TargetClusterID,
+		catalog,
+		nullableJSON(service.Networking),
+		nullableJSON(service.Placement),
+		nullableJSON(service.CronJob),
+		nullableJSON(service.Availability),
+		nullableJSON(service.Volumes),
 		service.UpdatedAt,
 	)
 
@@ -335,3 +373,20 @@ func (r *ServiceRepository) UpdateStatus(ctx context.Context, id uuid.UUID, stat
 
 	return nil
 }
+
+// UpdateReplicas records the replica counts observed for a service. They are
+// not a change to the service, so updated_at is left alone.
+func (r *ServiceRepository) UpdateReplicas(ctx context.Context, id uuid.UUID, replicas *domain.ServiceReplicas) error {
+	query := `UPDATE services SET replicas = $2 WHERE id = $1`
+
+	result, err := r.db.pool.Exec(ctx, query, id, nullableJSON(replicas))
+	if err != nil {
+		return errors.Wrap(err, "failed to update service replicas")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("service", id.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// AlertRepository implements domain.AlertRepository using SQLite
+type AlertRepository struct {
+	db *DB
+}
+
+// NewAlertRepository creates a new AlertRepository
+func NewAlertRepository(db *DB) *AlertRepository {
+	return &AlertRepository{db: db}
+}
+
+const alertColumns = `id, fingerprint, name, severity, status, source, COALESCE(message, ''), labels, annotations, service_id, project_id, cluster_id, starts_at, ends_at`
+
+// Create creates a new alert
+func (r *AlertRepository) Create(ctx context.Context, alert *domain.Alert) error {
+	query := `
+		INSERT INTO alerts (id, fingerprint, name, severity, status, source, message, labels, annotations,
+			service_id, project_id, cluster_id, starts_at, ends_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		alert.ID,
+		alert.Fingerprint,
+		alert.Name,
+		alert.Severity,
+		alert.Status,
+		alert.Source,
+		alert.Message,
+		jsonText(alert.Labels),
+		jsonText(alert.Annotations),
+		alert.ServiceID,
+		alert.ProjectID,
+		alert.ClusterID,
+		alert.StartsAt,
+		alert.EndsAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create alert")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an alert by ID
+func (r *AlertRepository) GetByID(ctx context.Context, id string) (*domain.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE id = ?`
+
+	alert, err := scanAlert(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("alert", id)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get alert")
+	}
+
+	return alert, nil
+}
+
+// GetFiringByFingerprint retrieves the firing alert with the given fingerprint
+func (r *AlertRepository) GetFiringByFingerprint(ctx context.Context, fingerprint string) (*domain.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE fingerprint = ? AND status = ? ORDER BY starts_at DESC LIMIT 1`
+
+	alert, err := scanAlert(r.db.queryRow(ctx, query, fingerprint, domain.AlertStatusFiring))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("alert", fingerprint)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get alert")
+	}
+
+	return alert, nil
+}
+
+// List retrieves alerts with optional filtering
+func (r *AlertRepository) List(ctx context.Context, filter domain.AlertFilter) ([]*domain.Alert, error) {
+	query := `SELECT ` + alertColumns + ` FROM alerts WHERE 1=1`
+	args := []interface{}{}
+
+	if filter.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+
+	if filter.Source != "" {
+		query += " AND source = ?"
+		args = append(args, filter.Source)
+	}
+
+	if filter.ServiceID != nil {
+		query += " AND service_id = ?"
+		args = append(args, *filter.ServiceID)
+	}
+
+	if filter.ProjectID != nil {
+		query += " AND project_id = ?"
+		args = append(args, *filter.ProjectID)
+	}
+
+	if filter.ClusterID != nil {
+		query += " AND cluster_id = ?"
+		args = append(args, *filter.ClusterID)
+	}
+
+	query += " ORDER BY starts_at DESC"
+	query, args = paginate(query, args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list alerts")
+	}
+	defer rows.Close()
+
+	alerts := []*domain.Alert{}
+	for rows.Next() {
+		alert, err := scanAlert(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan alert")
+		}
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+// Update updates an existing alert
+func (r *AlertRepository) Update(ctx context.Context, alert *domain.Alert) error {
+	query := `
+		UPDATE alerts
+		SET severity = ?, status = ?, message = ?, labels = ?, annotations = ?, ends_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		alert.Severity,
+		alert.Status,
+		alert.Message,
+		jsonText(alert.Labels),
+		jsonText(alert.Annotations),
+		alert.EndsAt,
+		alert.ID,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update alert")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("alert", alert.ID)
+	}
+
+	return nil
+}
+
+func scanAlert(row scanner) (*domain.Alert, error) {
+	alert := &domain.Alert{}
+	var labels, annotations []byte
+
+	err := row.Scan(
+		&alert.ID,
+		&alert.Fingerprint,
+		&alert.Name,
+		&alert.Severity,
+		&alert.Status,
+		&alert.Source,
+		&alert.Message,
+		&labels,
+		&annotations,
+		&alert.ServiceID,
+		&alert.ProjectID,
+		&alert.ClusterID,
+		&alert.StartsAt,
+		&alert.EndsAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(labels, &alert.Labels)
+	json.Unmarshal(annotations, &alert.Annotations)
+
+	return alert, nil
+}
@@ -0,0 +1,98 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewAlertRepository(db)
+	ctx := context.Background()
+
+	serviceID, projectID := uuid.New(), uuid.New()
+	resolved := &domain.Alert{
+		ID:          "alert-1",
+		Fingerprint: "cpu-high",
+		Name:        "HighCPU",
+		Severity:    "warning",
+		Status:      domain.AlertStatusResolved,
+		Source:      "prometheus",
+		Labels:      map[string]string{"service": "api"},
+		Annotations: map[string]string{},
+		ServiceID:   &serviceID,
+		ProjectID:   &projectID,
+		StartsAt:    100,
+		EndsAt:      200,
+	}
+	firing := &domain.Alert{
+		ID:          "alert-2",
+		Fingerprint: "cpu-high",
+		Name:        "HighCPU",
+		Severity:    "critical",
+		Status:      domain.AlertStatusFiring,
+		Source:      "prometheus",
+		Message:     "CPU above 90%",
+		Labels:      map[string]string{"service": "api"},
+		Annotations: map[string]string{"runbook": "https://runbooks.acme.dev/cpu"},
+		ServiceID:   &serviceID,
+		ProjectID:   &projectID,
+		StartsAt:    300,
+	}
+	other := &domain.Alert{ID: "alert-3", Fingerprint: "disk", Name: "DiskFull", Severity: "critical", Status: domain.AlertStatusFiring, Source: "platform", StartsAt: 250}
+	for _, alert := range []*domain.Alert{resolved, firing, other} {
+		require.NoError(t, repo.Create(ctx, alert))
+	}
+
+	got, err := repo.GetByID(ctx, "alert-2")
+	require.NoError(t, err)
+	assert.Equal(t, firing, got)
+	_, err = repo.GetByID(ctx, "missing")
+	assert.True(t, errors.IsNotFound(err))
+
+	got, err = repo.GetFiringByFingerprint(ctx, "cpu-high")
+	require.NoError(t, err)
+	assert.Equal(t, "alert-2", got.ID)
+
+	all, err := repo.List(ctx, domain.AlertFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alert-2", "alert-3", "alert-1"}, alertIDs(all), "newest first")
+
+	byService, err := repo.List(ctx, domain.AlertFilter{Status: domain.AlertStatusFiring, ServiceID: &serviceID})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alert-2"}, alertIDs(byService))
+
+	bySource, err := repo.List(ctx, domain.AlertFilter{Source: "platform"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alert-3"}, alertIDs(bySource))
+
+	page, err := repo.List(ctx, domain.AlertFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alert-3"}, alertIDs(page))
+
+	firing.Status = domain.AlertStatusResolved
+	firing.EndsAt = 400
+	require.NoError(t, repo.Update(ctx, firing))
+	_, err = repo.GetFiringByFingerprint(ctx, "cpu-high")
+	assert.True(t, errors.IsNotFound(err), "resolved alerts no longer fire")
+	got, err = repo.GetByID(ctx, "alert-2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(400), got.EndsAt)
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.Alert{ID: "missing"})))
+}
+
+func alertIDs(alerts []*domain.Alert) []string {
+	ids := []string{}
+	for _, a := range alerts {
+		ids = append(ids, a.ID)
+	}
+	return ids
+}
@@ -0,0 +1,317 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ApprovalRepository implements domain.ApprovalRepository using SQLite
+type ApprovalRepository struct {
+	db *DB
+}
+
+// NewApprovalRepository creates a new ApprovalRepository
+func NewApprovalRepository(db *DB) *ApprovalRepository {
+	return &ApprovalRepository{db: db}
+}
+
+const approvalColumns = `id, project_id, service_id, environment_id, workflow_id, version, status, approver_id,
+	escalated_at, decided_by, decided_at, comment, created_at, updated_at`
+
+// Create creates a new approval
+func (r *ApprovalRepository) Create(ctx context.Context, approval *domain.Approval) error {
+	query := `
+		INSERT INTO approvals (` + approvalColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		approval.ID,
+		approval.ProjectID,
+		approval.ServiceID,
+		approval.EnvironmentID,
+		approval.WorkflowID,
+		approval.Version,
+		approval.Status,
+		approval.ApproverID,
+		approval.EscalatedAt,
+		approval.DecidedBy,
+		approval.DecidedAt,
+		approval.Comment,
+		approval.CreatedAt,
+		approval.UpdatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create approval")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an approval by ID
+func (r *ApprovalRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Approval, error) {
+	query := `SELECT ` + approvalColumns + ` FROM approvals WHERE id = ?`
+
+	approval, err := scanApproval(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("approval", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get approval")
+	}
+
+	return approval, nil
+}
+
+// LatestByWorkflow retrieves the most recent approval of a deployment workflow
+func (r *ApprovalRepository) LatestByWorkflow(ctx context.Context, workflowID uuid.UUID) (*domain.Approval, error) {
+	query := `SELECT ` + approvalColumns + ` FROM approvals WHERE workflow_id = ?
+		ORDER BY created_at DESC, id DESC LIMIT 1`
+
+	approval, err := scanApproval(r.db.queryRow(ctx, query, workflowID))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("approval of workflow", workflowID.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get approval")
+	}
+
+	return approval, nil
+}
+
+// List retrieves approvals with filtering, newest first
+func (r *ApprovalRepository) List(ctx context.Context, filter domain.ApprovalFilter) ([]*domain.Approval, error) {
+	query := `SELECT ` + approvalColumns + ` FROM approvals WHERE 1=1`
+	args := []interface{}{}
+
+	if filter.ProjectID != nil {
+		query += " AND project_id = ?"
+		args = append(args, *filter.ProjectID)
+	}
+
+	if filter.ApproverIDs != nil {
+		if len(filter.ApproverIDs) == 0 {
+			return []*domain.Approval{}, nil
+		}
+		query += " AND approver_id IN (?" + strings.Repeat(", ?", len(filter.ApproverIDs)-1) + ")"
+		for _, id := range filter.ApproverIDs {
+			args = append(args, id)
+		}
+	}
+
+	if filter.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+
+	if filter.CreatedBefore != nil {
+		query += " AND created_at < ?"
+		args = append(args, *filter.CreatedBefore)
+	}
+
+	if filter.Unescalated {
+		query += " AND escalated_at IS NULL"
+	}
+
+	query, args = keyset(query, args, filter.After, filter.Limit)
+
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list approvals")
+	}
+	defer rows.Close()
+
+	approvals := []*domain.Approval{}
+	for rows.Next() {
+		approval, err := scanApproval(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan approval")
+		}
+		approvals = append(approvals, approval)
+	}
+
+	return approvals, rows.Err()
+}
+
+// Update updates the state of an approval. The deploy it holds does not
+// change.
+func (r *ApprovalRepository) Update(ctx context.Context, approval *domain.Approval) error {
+	query := `
+		UPDATE approvals
+		SET status = ?, approver_id = ?, escalated_at = ?, decided_by = ?, decided_at = ?, comment = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		approval.Status,
+		approval.ApproverID,
+		approval.EscalatedAt,
+		approval.DecidedBy,
+		approval.DecidedAt,
+		approval.Comment,
+		approval.UpdatedAt,
+		approval.ID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update approval")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("approval", approval.ID.String())
+	}
+
+	return nil
+}
+
+func scanApproval(row scanner) (*domain.Approval, error) {
+	approval := &domain.Approval{}
+
+	err := row.Scan(
+		&approval.ID,
+		&approval.ProjectID,
+		&approval.ServiceID,
+		&approval.EnvironmentID,
+		&approval.WorkflowID,
+		&approval.Version,
+		&approval.Status,
+		&approval.ApproverID,
+		&approval.EscalatedAt,
+		&approval.DecidedBy,
+		&approval.DecidedAt,
+		&approval.Comment,
+		&approval.CreatedAt,
+		&approval.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+// ApprovalDelegationRepository implements domain.ApprovalDelegationRepository using SQLite
+type ApprovalDelegationRepository struct {
+	db *DB
+}
+
+// NewApprovalDelegationRepository creates a new ApprovalDelegationRepository
+func NewApprovalDelegationRepository(db *DB) *ApprovalDelegationRepository {
+	return &ApprovalDelegationRepository{db: db}
+}
+
+const approvalDelegationColumns = `id, delegator_id, delegate_id, starts_at, ends_at, reason, created_at`
+
+// Create creates a new approval delegation
+func (r *ApprovalDelegationRepository) Create(ctx context.Context, delegation *domain.ApprovalDelegation) error {
+	query := `
+		INSERT INTO approval_delegations (` + approvalDelegationColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		delegation.ID,
+		delegation.DelegatorID,
+		delegation.DelegateID,
+		delegation.StartsAt,
+		delegation.EndsAt,
+		delegation.Reason,
+		delegation.CreatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create approval delegation")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an approval delegation by ID
+func (r *ApprovalDelegationRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.ApprovalDelegation, error) {
+	query := `SELECT ` + approvalDelegationColumns + ` FROM approval_delegations WHERE id = ?`
+
+	delegation, err := scanApprovalDelegation(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("approval delegation", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get approval delegation")
+	}
+
+	return delegation, nil
+}
+
+// ListByUser retrieves the delegations a user gave or received, newest first
+func (r *ApprovalDelegationRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.ApprovalDelegation, error) {
+	query := `SELECT ` + approvalDelegationColumns + ` FROM approval_delegations
+		WHERE delegator_id = ? OR delegate_id = ? ORDER BY created_at DESC, id DESC`
+	return r.list(ctx, query, userID, userID)
+}
+
+// ListActive retrieves the delegations a user received whose window holds at
+// the given time
+func (r *ApprovalDelegationRepository) ListActive(ctx context.Context, delegateID uuid.UUID, at time.Time) ([]*domain.ApprovalDelegation, error) {
+	query := `SELECT ` + approvalDelegationColumns + ` FROM approval_delegations
+		WHERE delegate_id = ? AND starts_at <= ? AND ends_at > ? ORDER BY created_at DESC, id DESC`
+	return r.list(ctx, query, delegateID, at, at)
+}
+
+func (r *ApprovalDelegationRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.ApprovalDelegation, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list approval delegations")
+	}
+	defer rows.Close()
+
+	delegations := []*domain.ApprovalDelegation{}
+	for rows.Next() {
+		delegation, err := scanApprovalDelegation(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan approval delegation")
+		}
+		delegations = append(delegations, delegation)
+	}
+
+	return delegations, rows.Err()
+}
+
+// Delete deletes an approval delegation
+func (r *ApprovalDelegationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM approval_delegations WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete approval delegation")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("approval delegation", id.String())
+	}
+
+	return nil
+}
+
+func scanApprovalDelegation(row scanner) (*domain.ApprovalDelegation, error) {
+	delegation := &domain.ApprovalDelegation{}
+
+	err := row.Scan(
+		&delegation.ID,
+		&delegation.DelegatorID,
+		&delegation.DelegateID,
+		&delegation.StartsAt,
+		&delegation.EndsAt,
+		&delegation.Reason,
+		&delegation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return delegation, nil
+}
@@ -0,0 +1,171 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApprovalRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewApprovalRepository(db)
+	ctx := context.Background()
+
+	project := testProject(t, db)
+	service := testService(t, db, project.ID)
+	cluster := testCluster(t, db)
+	now := testNow()
+	env := &domain.Environment{ID: uuid.New(), ProjectID: project.ID, ClusterID: cluster.ID, Name: "Production", Slug: "production", Type: domain.EnvironmentTypeProduction, Namespace: "shop", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, NewEnvironmentRepository(db).Create(ctx, env))
+
+	workflowID := uuid.New()
+	first := &domain.Approval{
+		ID:            uuid.New(),
+		ProjectID:     project.ID,
+		ServiceID:     service.ID,
+		EnvironmentID: env.ID,
+		WorkflowID:    workflowID,
+		Version:       "v1",
+		Status:        domain.ApprovalStatusPending,
+		ApproverID:    project.OwnerID,
+		CreatedAt:     now.Add(-5 * time.Hour),
+		UpdatedAt:     now.Add(-5 * time.Hour),
+	}
+	require.NoError(t, repo.Create(ctx, first))
+
+	got, err := repo.GetByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first, got)
+	_, err = repo.GetByID(ctx, uuid.New())
+	assert.True(t, errors.IsNotFound(err))
+
+	// The approver decides
+	decider := uuid.New()
+	first.Status = domain.ApprovalStatusRejected
+	first.DecidedBy = &decider
+	first.DecidedAt = &now
+	first.Comment = "not during the sale"
+	first.UpdatedAt = now
+	require.NoError(t, repo.Update(ctx, first))
+	got, err = repo.GetByID(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first, got)
+
+	missing := *first
+	missing.ID = uuid.New()
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &missing)))
+
+	second := *first
+	second.ID = uuid.New()
+	second.Version = "v2"
+	second.Status = domain.ApprovalStatusPending
+	second.DecidedBy, second.DecidedAt, second.Comment = nil, nil, ""
+	second.CreatedAt = now.Add(-time.Hour)
+	require.NoError(t, repo.Create(ctx, &second))
+
+	latest, err := repo.LatestByWorkflow(ctx, workflowID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, latest.ID)
+	_, err = repo.LatestByWorkflow(ctx, uuid.New())
+	assert.True(t, errors.IsNotFound(err))
+
+	teamOwner := uuid.New()
+	escalated := second
+	escalated.ID = uuid.New()
+	escalated.WorkflowID = uuid.New()
+	escalated.ApproverID = teamOwner
+	escalated.EscalatedAt = &now
+	escalated.CreatedAt = now.Add(-10 * time.Hour)
+	require.NoError(t, repo.Create(ctx, &escalated))
+
+	list, err := repo.List(ctx, domain.ApprovalFilter{ProjectID: &project.ID})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, []uuid.UUID{second.ID, first.ID, escalated.ID}, []uuid.UUID{list[0].ID, list[1].ID, list[2].ID}, "newest first")
+
+	list, err = repo.List(ctx, domain.ApprovalFilter{ApproverIDs: []uuid.UUID{teamOwner, uuid.New()}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, escalated.ID, list[0].ID)
+	list, err = repo.List(ctx, domain.ApprovalFilter{ApproverIDs: []uuid.UUID{}})
+	require.NoError(t, err)
+	assert.Empty(t, list, "no approvers match no approvals")
+
+	// Pending approvals older than the cutoff that were not escalated yet
+	cutoff := now.Add(-30 * time.Minute)
+	list, err = repo.List(ctx, domain.ApprovalFilter{Status: domain.ApprovalStatusPending, CreatedBefore: &cutoff, Unescalated: true})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, second.ID, list[0].ID)
+
+	page, err := repo.List(ctx, domain.ApprovalFilter{ProjectID: &project.ID, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	page, err = repo.List(ctx, domain.ApprovalFilter{ProjectID: &project.ID, Limit: 2, After: &domain.Cursor{CreatedAt: page[1].CreatedAt, ID: page[1].ID}})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, escalated.ID, page[0].ID)
+}
+
+func TestApprovalDelegationRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewApprovalDelegationRepository(db)
+	ctx := context.Background()
+
+	owner, deputy, other := uuid.New(), uuid.New(), uuid.New()
+	now := testNow()
+	vacation := &domain.ApprovalDelegation{
+		ID:          uuid.New(),
+		DelegatorID: owner,
+		DelegateID:  deputy,
+		StartsAt:    now.Add(-time.Hour),
+		EndsAt:      now.Add(7 * 24 * time.Hour),
+		Reason:      "vacation",
+		CreatedAt:   now,
+	}
+	require.NoError(t, repo.Create(ctx, vacation))
+
+	got, err := repo.GetByID(ctx, vacation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, vacation, got)
+	_, err = repo.GetByID(ctx, uuid.New())
+	assert.True(t, errors.IsNotFound(err))
+
+	self := *vacation
+	self.ID = uuid.New()
+	self.DelegateID = owner
+	assert.Error(t, repo.Create(ctx, &self), "users cannot delegate to themselves")
+
+	later := &domain.ApprovalDelegation{ID: uuid.New(), DelegatorID: other, DelegateID: deputy, StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(48 * time.Hour), CreatedAt: now.Add(time.Second)}
+	require.NoError(t, repo.Create(ctx, later))
+
+	byUser, err := repo.ListByUser(ctx, deputy)
+	require.NoError(t, err)
+	require.Len(t, byUser, 2)
+	assert.Equal(t, later.ID, byUser[0].ID)
+	byUser, err = repo.ListByUser(ctx, owner)
+	require.NoError(t, err)
+	require.Len(t, byUser, 1)
+
+	active, err := repo.ListActive(ctx, deputy, now)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, vacation.ID, active[0].ID)
+	active, err = repo.ListActive(ctx, deputy, now.Add(36*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, active, 2)
+	active, err = repo.ListActive(ctx, deputy, now.Add(30*24*time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, active, "windows end")
+
+	require.NoError(t, repo.Delete(ctx, vacation.ID))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, vacation.ID)))
+}
@@ -0,0 +1,87 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewArtifactRepository(db)
+	ctx := context.Background()
+
+	service := testService(t, db, testProject(t, db).ID)
+	build := testBuild(t, db, service)
+	now := testNow()
+	newArtifact := func(name string, createdAt time.Time, expiresAt *time.Time) *domain.Artifact {
+		return &domain.Artifact{
+			ID:          uuid.New(),
+			BuildID:     build.ID,
+			ServiceID:   service.ID,
+			ProjectID:   service.ProjectID,
+			Name:        name,
+			ContentType: "application/octet-stream",
+			Size:        2048,
+			SHA256:      "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			StorageKey:  "artifacts/" + build.ID.String() + "/" + name,
+			UploadedBy:  "ci",
+			ExpiresAt:   expiresAt,
+			CreatedAt:   createdAt,
+		}
+	}
+
+	expired := now.Add(-time.Hour)
+	cli := newArtifact("cli", now, &expired)
+	require.NoError(t, repo.Create(ctx, cli))
+	report := newArtifact("report.html", now.Add(time.Minute), nil)
+	require.NoError(t, repo.Create(ctx, report))
+
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, newArtifact("cli", now, nil)), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "names are unique within a build")
+
+	got, err := repo.GetByName(ctx, build.ID, "cli")
+	require.NoError(t, err)
+	assert.Equal(t, cli, got)
+	_, err = repo.GetByName(ctx, build.ID, "missing")
+	assert.True(t, errors.IsNotFound(err))
+
+	byBuild, err := repo.ListByBuild(ctx, build.ID)
+	require.NoError(t, err)
+	require.Len(t, byBuild, 2)
+	assert.Equal(t, "cli", byBuild[0].Name)
+
+	byService, err := repo.ListByService(ctx, service.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, byService, 1)
+	assert.Equal(t, "report.html", byService[0].Name)
+
+	rest, err := repo.ListByServiceAfter(ctx, service.ID, cursorOf(report.CreatedAt, report.ID), 10)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, cli.ID, rest[0].ID)
+
+	due, err := repo.ListExpired(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1, "artifacts without an expiry are kept")
+	assert.Equal(t, cli.ID, due[0].ID)
+
+	require.NoError(t, repo.Delete(ctx, cli.ID))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, cli.ID)))
+
+	// Artifacts go with their build
+	_, err = db.exec(ctx, `DELETE FROM builds WHERE id = ?`, build.ID)
+	require.NoError(t, err)
+	byBuild, err = repo.ListByBuild(ctx, build.ID)
+	require.NoError(t, err)
+	assert.Empty(t, byBuild)
+}
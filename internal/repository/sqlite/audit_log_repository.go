@@ -0,0 +1,146 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// AuditLogRepository implements domain.AuditLogRepository using SQLite
+type AuditLogRepository struct {
+	db *DB
+}
+
+// NewAuditLogRepository creates a new AuditLogRepository
+func NewAuditLogRepository(db *DB) *AuditLogRepository {
+	return &AuditLogRepository{db: db}
+}
+
+const auditLogColumns = `id, user_id, action, resource_type, resource_id, resource_name, project_id,
+	ip_address, user_agent, old_value, new_value, metadata, created_at`
+
+// Create creates a new audit log entry
+func (r *AuditLogRepository) Create(ctx context.Context, log *domain.AuditLog) error {
+	query := `
+		INSERT INTO audit_logs (` + auditLogColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		log.ID,
+		log.UserID,
+		log.Action,
+		log.ResourceType,
+		log.ResourceID,
+		log.ResourceName,
+		log.ProjectID,
+		log.IPAddress,
+		log.UserAgent,
+		nullableJSON(log.OldValue),
+		nullableJSON(log.NewValue),
+		jsonText(log.Metadata),
+		log.CreatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create audit log")
+	}
+
+	return nil
+}
+
+// List retrieves audit log entries with filtering, newest first
+func (r *AuditLogRepository) List(ctx context.Context, filter domain.AuditLogFilter) ([]*domain.AuditLog, error) {
+	query := `SELECT ` + auditLogColumns + ` FROM audit_logs WHERE 1=1`
+	args := []interface{}{}
+
+	if filter.UserID != nil {
+		query += " AND user_id = ?"
+		args = append(args, *filter.UserID)
+	}
+
+	if filter.ProjectID != nil {
+		query += " AND project_id = ?"
+		args = append(args, *filter.ProjectID)
+	}
+
+	if filter.ResourceType != "" {
+		query += " AND resource_type = ?"
+		args = append(args, filter.ResourceType)
+	}
+
+	if filter.ResourceID != nil {
+		query += " AND resource_id = ?"
+		args = append(args, *filter.ResourceID)
+	}
+
+	if filter.Action != nil {
+		query += " AND action = ?"
+		args = append(args, *filter.Action)
+	}
+
+	if filter.StartTime != nil {
+		query += " AND created_at >= ?"
+		args = append(args, time.Unix(*filter.StartTime, 0))
+	}
+
+	if filter.EndTime != nil {
+		query += " AND created_at < ?"
+		args = append(args, time.Unix(*filter.EndTime, 0))
+	}
+
+	query, args = keyset(query, args, filter.After, filter.Limit)
+	query, args = skip(query, args, filter.Offset)
+
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list audit logs")
+	}
+	defer rows.Close()
+
+	logs := []*domain.AuditLog{}
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan audit log")
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, rows.Err()
+}
+
+func scanAuditLog(row scanner) (*domain.AuditLog, error) {
+	log := &domain.AuditLog{}
+	var oldValue, newValue, metadata []byte
+
+	err := row.Scan(
+		&log.ID,
+		&log.UserID,
+		&log.Action,
+		&log.ResourceType,
+		&log.ResourceID,
+		&log.ResourceName,
+		&log.ProjectID,
+		&log.IPAddress,
+		&log.UserAgent,
+		&oldValue,
+		&newValue,
+		&metadata,
+		&log.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(oldValue, &log.OldValue)
+	json.Unmarshal(newValue, &log.NewValue)
+	json.Unmarshal(metadata, &log.Metadata)
+
+	return log, nil
+}
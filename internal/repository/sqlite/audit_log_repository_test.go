@@ -0,0 +1,77 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewAuditLogRepository(db)
+	ctx := context.Background()
+
+	user, projectID, serviceID := uuid.New(), uuid.New(), uuid.New()
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	var logs []*domain.AuditLog
+	for i, action := range []domain.AuditAction{domain.AuditActionCreate, domain.AuditActionUpdate, domain.AuditActionDeploy, domain.AuditActionUpdate} {
+		log := &domain.AuditLog{
+			ID:           uuid.New(),
+			UserID:       user,
+			Action:       action,
+			ResourceType: "service",
+			ResourceID:   serviceID,
+			ResourceName: "api",
+			ProjectID:    &projectID,
+			IPAddress:    "203.0.113.7",
+			UserAgent:    "cli/1.0",
+			CreatedAt:    start.Add(time.Duration(i) * time.Hour),
+		}
+		if action == domain.AuditActionUpdate {
+			log.OldValue = map[string]interface{}{"replicas": float64(i)}
+			log.NewValue = map[string]interface{}{"replicas": float64(i + 1)}
+		}
+		require.NoError(t, repo.Create(ctx, log))
+		logs = append(logs, log)
+	}
+	require.NoError(t, repo.Create(ctx, &domain.AuditLog{ID: uuid.New(), UserID: uuid.New(), Action: domain.AuditActionCreate, ResourceType: "project", ResourceID: projectID, CreatedAt: start}))
+
+	byResource, err := repo.List(ctx, domain.AuditLogFilter{ResourceType: "service", ResourceID: &serviceID})
+	require.NoError(t, err)
+	require.Len(t, byResource, 4)
+	assert.Equal(t, logs[3], byResource[0], "newest first")
+	assert.Nil(t, byResource[3].OldValue)
+
+	update := domain.AuditActionUpdate
+	byAction, err := repo.List(ctx, domain.AuditLogFilter{UserID: &user, Action: &update})
+	require.NoError(t, err)
+	assert.Len(t, byAction, 2)
+
+	from, to := start.Add(time.Hour).Unix(), start.Add(3*time.Hour).Unix()
+	window, err := repo.List(ctx, domain.AuditLogFilter{ProjectID: &projectID, StartTime: &from, EndTime: &to})
+	require.NoError(t, err)
+	require.Len(t, window, 2)
+	assert.Equal(t, logs[2].ID, window[0].ID)
+	assert.Equal(t, logs[1].ID, window[1].ID)
+
+	first, err := repo.List(ctx, domain.AuditLogFilter{UserID: &user, Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, first, 3)
+	last := first[2]
+	next, err := repo.List(ctx, domain.AuditLogFilter{UserID: &user, Limit: 3, After: cursorOf(last.CreatedAt, last.ID)})
+	require.NoError(t, err)
+	require.Len(t, next, 1)
+	assert.Equal(t, logs[0].ID, next[0].ID)
+
+	offset, err := repo.List(ctx, domain.AuditLogFilter{UserID: &user, Offset: 3})
+	require.NoError(t, err)
+	require.Len(t, offset, 1)
+	assert.Equal(t, logs[0].ID, offset[0].ID)
+}
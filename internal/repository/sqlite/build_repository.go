@@ -0,0 +1,222 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// BuildRepository implements domain.BuildRepository using SQLite
+type BuildRepository struct {
+	db *DB
+}
+
+// NewBuildRepository creates a new BuildRepository
+func NewBuildRepository(db *DB) *BuildRepository {
+	return &BuildRepository{db: db}
+}
+
+const buildColumns = `id, service_id, project_id, status, source, COALESCE(image_tag, ''), COALESCE(image_digest, ''),
+	COALESCE(build_logs, ''), COALESCE(duration, 0), stages, triggered_by, COALESCE(error_message, ''), metadata,
+	started_at, completed_at, created_at`
+
+// Create creates a new build
+func (r *BuildRepository) Create(ctx context.Context, build *domain.Build) error {
+	query := `
+		INSERT INTO builds (id, service_id, project_id, status, source, image_tag, image_digest, build_logs,
+			duration, stages, triggered_by, error_message, metadata, started_at, completed_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		build.ID,
+		build.ServiceID,
+		build.ProjectID,
+		build.Status,
+		jsonText(build.Source),
+		build.ImageTag,
+		build.ImageDigest,
+		build.BuildLogs,
+		build.Duration,
+		jsonText(buildStages(build.Stages)),
+		build.TriggeredBy,
+		build.ErrorMessage,
+		jsonText(build.Metadata),
+		build.StartedAt,
+		build.CompletedAt,
+		build.CreatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create build")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a build by ID
+func (r *BuildRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Build, error) {
+	query := `SELECT ` + buildColumns + ` FROM builds WHERE id = ?`
+
+	build, err := scanBuild(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("build", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get build")
+	}
+
+	return build, nil
+}
+
+// ListByService retrieves the most recent builds of a service, newest first
+func (r *BuildRepository) ListByService(ctx context.Context, serviceID uuid.UUID, limit int) ([]*domain.Build, error) {
+	return r.ListByServiceAfter(ctx, serviceID, nil, limit)
+}
+
+// ListByServiceAfter retrieves the builds of a service older than the cursor, newest first
+func (r *BuildRepository) ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Build, error) {
+	query, args := keyset(`SELECT `+buildColumns+` FROM builds WHERE service_id = ?`, []interface{}{serviceID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+// ListByProject retrieves the most recent builds of a project, newest first
+func (r *BuildRepository) ListByProject(ctx context.Context, projectID uuid.UUID, limit int) ([]*domain.Build, error) {
+	query := `SELECT ` + buildColumns + ` FROM builds WHERE project_id = ? ORDER BY created_at DESC LIMIT ?`
+	return r.list(ctx, query, projectID, limit)
+}
+
+// ListActive retrieves queued and running builds, oldest first
+func (r *BuildRepository) ListActive(ctx context.Context, limit int) ([]*domain.Build, error) {
+	query := `SELECT ` + buildColumns + ` FROM builds WHERE status IN (?, ?) ORDER BY created_at ASC LIMIT ?`
+	return r.list(ctx, query, domain.BuildStatusQueued, domain.BuildStatusRunning, limit)
+}
+
+func (r *BuildRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Build, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list builds")
+	}
+	defer rows.Close()
+
+	builds := []*domain.Build{}
+	for rows.Next() {
+		build, err := scanBuild(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan build")
+		}
+		builds = append(builds, build)
+	}
+
+	return builds, rows.Err()
+}
+
+// Update updates an existing build
+func (r *BuildRepository) Update(ctx context.Context, build *domain.Build) error {
+	query := `
+		UPDATE builds
+		SET status = ?, image_tag = ?, image_digest = ?, build_logs = ?, duration = ?, stages = ?,
+			error_message = ?, metadata = ?, started_at = ?, completed_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		build.Status,
+		build.ImageTag,
+		build.ImageDigest,
+		build.BuildLogs,
+		build.Duration,
+		jsonText(buildStages(build.Stages)),
+		build.ErrorMessage,
+		jsonText(build.Metadata),
+		build.StartedAt,
+		build.CompletedAt,
+		build.ID,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update build")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("build", build.ID.String())
+	}
+
+	return nil
+}
+
+// UpdateStatus updates the status of a build, stamping completed_at once it
+// reaches a terminal status
+func (r *BuildRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.BuildStatus, errorMsg string) error {
+	var completedAt *time.Time
+	switch status {
+	case domain.BuildStatusSucceeded, domain.BuildStatusFailed, domain.BuildStatusCanceled:
+		now := time.Now()
+		completedAt = &now
+	}
+
+	query := `
+		UPDATE builds
+		SET status = ?, error_message = ?, completed_at = COALESCE(completed_at, ?)
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query, status, errorMsg, completedAt, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to update build status")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("build", id.String())
+	}
+
+	return nil
+}
+
+// buildStages keeps a build without stages from being stored as JSON null
+func buildStages(stages []domain.BuildStage) []domain.BuildStage {
+	if stages == nil {
+		return []domain.BuildStage{}
+	}
+	return stages
+}
+
+func scanBuild(row scanner) (*domain.Build, error) {
+	build := &domain.Build{}
+	var source, stages, metadata []byte
+
+	err := row.Scan(
+		&build.ID,
+		&build.ServiceID,
+		&build.ProjectID,
+		&build.Status,
+		&source,
+		&build.ImageTag,
+		&build.ImageDigest,
+		&build.BuildLogs,
+		&build.Duration,
+		&stages,
+		&build.TriggeredBy,
+		&build.ErrorMessage,
+		&metadata,
+		&build.StartedAt,
+		&build.CompletedAt,
+		&build.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(source, &build.Source)
+	json.Unmarshal(stages, &build.Stages)
+	json.Unmarshal(metadata, &build.Metadata)
+
+	return build, nil
+}
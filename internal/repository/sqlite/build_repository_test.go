@@ -0,0 +1,123 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewBuildRepository(db)
+	ctx := context.Background()
+
+	service := testService(t, db, testProject(t, db).ID)
+	now := testNow()
+	started := now.Add(time.Second)
+	build := &domain.Build{
+		ID:          uuid.New(),
+		ServiceID:   service.ID,
+		ProjectID:   service.ProjectID,
+		Status:      domain.BuildStatusRunning,
+		Source:      domain.BuildSource{Type: "git", Repository: "https://github.com/acme/api", CommitSHA: "abc123"},
+		Stages:      []domain.BuildStage{{Name: domain.BuildStageClone, Duration: 3}},
+		TriggeredBy: "push",
+		Metadata:    map[string]interface{}{"runner": "linux-amd64"},
+		StartedAt:   &started,
+		CreatedAt:   now,
+	}
+	require.NoError(t, repo.Create(ctx, build))
+
+	got, err := repo.GetByID(ctx, build.ID)
+	require.NoError(t, err)
+	assert.Equal(t, build, got)
+	_, err = repo.GetByID(ctx, uuid.New())
+	assert.True(t, errors.IsNotFound(err))
+
+	active, err := repo.ListActive(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+
+	build.ImageTag = "registry.acme.dev/api:abc123"
+	build.Duration = 42
+	build.Stages = append(build.Stages, domain.BuildStage{Name: domain.BuildStageCompile, Duration: 39})
+	require.NoError(t, repo.Update(ctx, build))
+	got, err = repo.GetByID(ctx, build.ID)
+	require.NoError(t, err)
+	assert.Equal(t, build, got)
+
+	require.NoError(t, repo.UpdateStatus(ctx, build.ID, domain.BuildStatusFailed, "compile error"))
+	got, err = repo.GetByID(ctx, build.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.BuildStatusFailed, got.Status)
+	assert.Equal(t, "compile error", got.ErrorMessage)
+	require.NotNil(t, got.CompletedAt, "terminal statuses stamp completion")
+	completedAt := *got.CompletedAt
+
+	require.NoError(t, repo.UpdateStatus(ctx, build.ID, domain.BuildStatusCanceled, ""))
+	got, err = repo.GetByID(ctx, build.ID)
+	require.NoError(t, err)
+	assert.Equal(t, completedAt, *got.CompletedAt, "completion is stamped once")
+
+	active, err = repo.ListActive(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, active)
+
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.Build{ID: uuid.New()})))
+	assert.True(t, errors.IsNotFound(repo.UpdateStatus(ctx, uuid.New(), domain.BuildStatusFailed, "")))
+}
+
+func TestBuildRepositoryListByService(t *testing.T) {
+	db := testDB(t)
+	repo := NewBuildRepository(db)
+	ctx := context.Background()
+
+	service := testService(t, db, testProject(t, db).ID)
+	other := testService(t, db, service.ProjectID)
+	testBuild(t, db, other)
+
+	// Two builds share a creation time, so the ID breaks the tie
+	start := testNow()
+	var builds []*domain.Build
+	for _, at := range []time.Time{start, start.Add(time.Minute), start.Add(time.Minute), start.Add(2 * time.Minute)} {
+		build := &domain.Build{ID: uuid.New(), ServiceID: service.ID, ProjectID: service.ProjectID, Status: domain.BuildStatusSucceeded, TriggeredBy: "push", CreatedAt: at}
+		require.NoError(t, repo.Create(ctx, build))
+		builds = append(builds, build)
+	}
+
+	var seen []uuid.UUID
+	var after *domain.Cursor
+	for {
+		page, err := repo.ListByServiceAfter(ctx, service.ID, after, 2)
+		require.NoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		for _, b := range page {
+			seen = append(seen, b.ID)
+		}
+		last := page[len(page)-1]
+		after = cursorOf(last.CreatedAt, last.ID)
+	}
+	require.Len(t, seen, 4, "every build is listed once")
+	assert.Equal(t, builds[3].ID, seen[0])
+	assert.Equal(t, builds[0].ID, seen[3])
+	assert.ElementsMatch(t, []uuid.UUID{builds[1].ID, builds[2].ID}, seen[1:3])
+
+	latest, err := repo.ListByService(ctx, service.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, latest, 1)
+	assert.Equal(t, builds[3].ID, latest[0].ID)
+
+	byProject, err := repo.ListByProject(ctx, service.ProjectID, 10)
+	require.NoError(t, err)
+	assert.Len(t, byProject, 5)
+}
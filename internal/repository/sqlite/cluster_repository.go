@@ -0,0 +1,219 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ClusterRepository implements domain.ClusterRepository using SQLite
+type ClusterRepository struct {
+	db *DB
+}
+
+// NewClusterRepository creates a new ClusterRepository
+func NewClusterRepository(db *DB) *ClusterRepository {
+	return &ClusterRepository{db: db}
+}
+
+const clusterColumns = `id, name, slug, provider, region, status, COALESCE(kube_version, ''), COALESCE(api_endpoint, ''),
+	node_count, labels, metadata, COALESCE(rancher_cluster_id, ''), created_at, updated_at`
+
+// Create creates a new cluster
+func (r *ClusterRepository) Create(ctx context.Context, cluster *domain.Cluster) error {
+	query := `
+		INSERT INTO clusters (id, name, slug, provider, region, status, kube_version, api_endpoint,
+			node_count, labels, metadata, rancher_cluster_id, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		cluster.ID,
+		cluster.Name,
+		cluster.Slug,
+		cluster.Provider,
+		cluster.Region,
+		cluster.Status,
+		cluster.KubeVersion,
+		cluster.APIEndpoint,
+		cluster.NodeCount,
+		jsonText(cluster.Labels),
+		jsonText(cluster.Metadata),
+		cluster.RancherClusterID,
+		cluster.CreatedAt,
+		cluster.UpdatedAt,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("cluster " + cluster.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create cluster")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a cluster by ID
+func (r *ClusterRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Cluster, error) {
+	query := `SELECT ` + clusterColumns + ` FROM clusters WHERE id = ?`
+
+	cluster, err := scanCluster(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("cluster", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster")
+	}
+
+	return cluster, nil
+}
+
+// GetBySlug retrieves a cluster by slug
+func (r *ClusterRepository) GetBySlug(ctx context.Context, slug string) (*domain.Cluster, error) {
+	query := `SELECT ` + clusterColumns + ` FROM clusters WHERE slug = ?`
+
+	cluster, err := scanCluster(r.db.queryRow(ctx, query, slug))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("cluster", slug)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster")
+	}
+
+	return cluster, nil
+}
+
+// List retrieves clusters with optional filtering
+func (r *ClusterRepository) List(ctx context.Context, filter domain.ClusterFilter) ([]*domain.Cluster, error) {
+	query := `SELECT ` + clusterColumns + ` FROM clusters WHERE 1=1`
+	args := []interface{}{}
+
+	if filter.Provider != nil {
+		query += " AND provider = ?"
+		args = append(args, *filter.Provider)
+	}
+
+	if filter.Status != nil {
+		query += " AND status = ?"
+		args = append(args, *filter.Status)
+	}
+
+	if filter.Region != "" {
+		query += " AND region = ?"
+		args = append(args, filter.Region)
+	}
+
+	if len(filter.Labels) > 0 {
+		cond, labelArgs := labelsMatch(filter.Labels)
+		query += cond
+		args = append(args, labelArgs...)
+	}
+
+	query, args = keyset(query, args, filter.After, filter.Limit)
+	query, args = skip(query, args, filter.Offset)
+
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list clusters")
+	}
+	defer rows.Close()
+
+	clusters := []*domain.Cluster{}
+	for rows.Next() {
+		cluster, err := scanCluster(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan cluster")
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, rows.Err()
+}
+
+// Update updates an existing cluster
+func (r *ClusterRepository) Update(ctx context.Context, cluster *domain.Cluster) error {
+	cluster.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE clusters
+		SET name = ?, region = ?, status = ?, kube_version = ?, api_endpoint = ?, node_count = ?,
+			labels = ?, metadata = ?, rancher_cluster_id = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		cluster.Name,
+		cluster.Region,
+		cluster.Status,
+		cluster.KubeVersion,
+		cluster.APIEndpoint,
+		cluster.NodeCount,
+		jsonText(cluster.Labels),
+		jsonText(cluster.Metadata),
+		cluster.RancherClusterID,
+		cluster.UpdatedAt,
+		cluster.ID,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update cluster")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("cluster", cluster.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a cluster
+func (r *ClusterRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM clusters WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete cluster")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("cluster", id.String())
+	}
+
+	return nil
+}
+
+func scanCluster(row scanner) (*domain.Cluster, error) {
+	cluster := &domain.Cluster{}
+	var labels, metadata []byte
+
+	err := row.Scan(
+		&cluster.ID,
+		&cluster.Name,
+		&cluster.Slug,
+		&cluster.Provider,
+		&cluster.Region,
+		&cluster.Status,
+		&cluster.KubeVersion,
+		&cluster.APIEndpoint,
+		&cluster.NodeCount,
+		&labels,
+		&metadata,
+		&cluster.RancherClusterID,
+		&cluster.CreatedAt,
+		&cluster.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(labels, &cluster.Labels)
+	json.Unmarshal(metadata, &cluster.Metadata)
+
+	return cluster, nil
+}
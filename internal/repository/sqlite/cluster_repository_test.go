@@ -0,0 +1,130 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewClusterRepository(db)
+	ctx := context.Background()
+
+	now := testNow()
+	cluster := &domain.Cluster{
+		ID:               uuid.New(),
+		Name:             "Production",
+		Slug:             "production",
+		Provider:         domain.ClusterProviderGCP,
+		Region:           "europe-west1",
+		Status:           domain.ClusterStatusProvisioning,
+		KubeVersion:      "1.31",
+		APIEndpoint:      "https://10.0.0.1",
+		NodeCount:        3,
+		Labels:           map[string]string{"env": "prod"},
+		Metadata:         map[string]interface{}{"project": "acme"},
+		RancherClusterID: "c-m-abc",
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	require.NoError(t, repo.Create(ctx, cluster))
+
+	duplicate := *cluster
+	duplicate.ID = uuid.New()
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, &duplicate), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "slugs are unique")
+
+	got, err := repo.GetByID(ctx, cluster.ID)
+	require.NoError(t, err)
+	assert.Equal(t, cluster, got)
+
+	got, err = repo.GetBySlug(ctx, "production")
+	require.NoError(t, err)
+	assert.Equal(t, cluster.ID, got.ID)
+	_, err = repo.GetBySlug(ctx, "missing")
+	assert.True(t, errors.IsNotFound(err))
+
+	cluster.Status = domain.ClusterStatusActive
+	cluster.NodeCount = 5
+	require.NoError(t, repo.Update(ctx, cluster))
+	got, err = repo.GetByID(ctx, cluster.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ClusterStatusActive, got.Status)
+	assert.Equal(t, int32(5), got.NodeCount)
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.Cluster{ID: uuid.New()})))
+
+	require.NoError(t, repo.Delete(ctx, cluster.ID))
+	_, err = repo.GetByID(ctx, cluster.ID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, cluster.ID)))
+}
+
+func TestClusterRepositoryList(t *testing.T) {
+	db := testDB(t)
+	repo := NewClusterRepository(db)
+	ctx := context.Background()
+
+	now := testNow()
+	for _, c := range []struct {
+		name     string
+		provider domain.ClusterProvider
+		region   string
+		env      string
+	}{
+		{"charlie", domain.ClusterProviderAWS, "us-east-1", "prod"},
+		{"alpha", domain.ClusterProviderAWS, "eu-west-1", "prod"},
+		{"bravo", domain.ClusterProviderK3s, "eu-west-1", "dev"},
+	} {
+		require.NoError(t, repo.Create(ctx, &domain.Cluster{
+			ID:        uuid.New(),
+			Name:      c.name,
+			Slug:      c.name,
+			Provider:  c.provider,
+			Region:    c.region,
+			Status:    domain.ClusterStatusActive,
+			Labels:    map[string]string{"env": c.env},
+			CreatedAt: now,
+			UpdatedAt: now,
+		}))
+		now = now.Add(time.Second)
+	}
+
+	all, err := repo.List(ctx, domain.ClusterFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bravo", "alpha", "charlie"}, clusterNames(all), "clusters are listed newest first")
+
+	aws := domain.ClusterProviderAWS
+	byProvider, err := repo.List(ctx, domain.ClusterFilter{Provider: &aws})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha", "charlie"}, clusterNames(byProvider))
+
+	byRegion, err := repo.List(ctx, domain.ClusterFilter{Region: "eu-west-1", Labels: map[string]string{"env": "prod"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha"}, clusterNames(byRegion))
+
+	page, err := repo.List(ctx, domain.ClusterFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpha"}, clusterNames(page))
+
+	page, err = repo.List(ctx, domain.ClusterFilter{Limit: 2, After: cursorOf(page[0].CreatedAt, page[0].ID)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"charlie"}, clusterNames(page))
+}
+
+func clusterNames(clusters []*domain.Cluster) []string {
+	names := []string{}
+	for _, c := range clusters {
+		names = append(names, c.Name)
+	}
+	return names
+}
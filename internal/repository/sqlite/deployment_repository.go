@@ -0,0 +1,252 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// DeploymentRepository implements domain.DeploymentRepository using SQLite
+type DeploymentRepository struct {
+	db *DB
+}
+
+// NewDeploymentRepository creates a new DeploymentRepository
+func NewDeploymentRepository(db *DB) *DeploymentRepository {
+	return &DeploymentRepository{db: db}
+}
+
+const deploymentColumns = `id, service_id, project_id, build_id, cluster_id, status, strategy, version,
+	COALESCE(previous_version, ''), replicas, ready_replicas, triggered_by, COALESCE(error_message, ''),
+	health, pre_pull, metadata, started_at, completed_at, created_at`
+
+// Create creates a new deployment
+func (r *DeploymentRepository) Create(ctx context.Context, deployment *domain.Deployment) error {
+	query := `
+		INSERT INTO deployments (id, service_id, project_id, build_id, cluster_id, status, strategy, version,
+			previous_version, replicas, ready_replicas, triggered_by, error_message, health, pre_pull, metadata,
+			started_at, completed_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		deployment.ID,
+		deployment.ServiceID,
+		deployment.ProjectID,
+		nullableUUID(deployment.BuildID),
+		deployment.ClusterID,
+		deployment.Status,
+		deployment.Strategy,
+		deployment.Version,
+		deployment.PreviousVersion,
+		deployment.Replicas,
+		deployment.ReadyReplicas,
+		deployment.TriggeredBy,
+		deployment.ErrorMessage,
+		nullableJSON(deployment.Health),
+		nullableJSON(deployment.PrePull),
+		jsonText(deployment.Metadata),
+		deployment.StartedAt,
+		deployment.CompletedAt,
+		deployment.CreatedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create deployment")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a deployment by ID
+func (r *DeploymentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Deployment, error) {
+	query := `SELECT ` + deploymentColumns + ` FROM deployments WHERE id = ?`
+
+	deployment, err := scanDeployment(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("deployment", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get deployment")
+	}
+
+	return deployment, nil
+}
+
+// GetLatestByService retrieves the most recent deployment of a service
+func (r *DeploymentRepository) GetLatestByService(ctx context.Context, serviceID uuid.UUID) (*domain.Deployment, error) {
+	query := `SELECT ` + deploymentColumns + ` FROM deployments WHERE service_id = ? ORDER BY created_at DESC LIMIT 1`
+
+	deployment, err := scanDeployment(r.db.queryRow(ctx, query, serviceID))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("deployment for service", serviceID.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get latest deployment")
+	}
+
+	return deployment, nil
+}
+
+// ListByService retrieves the most recent deployments of a service, newest first
+func (r *DeploymentRepository) ListByService(ctx context.Context, serviceID uuid.UUID, limit int) ([]*domain.Deployment, error) {
+	return r.ListByServiceAfter(ctx, serviceID, nil, limit)
+}
+
+// ListByServiceAfter retrieves the deployments of a service older than the cursor, newest first
+func (r *DeploymentRepository) ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Deployment, error) {
+	query, args := keyset(`SELECT `+deploymentColumns+` FROM deployments WHERE service_id = ?`, []interface{}{serviceID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+// ListByCluster retrieves the most recent deployments to a cluster, newest first
+func (r *DeploymentRepository) ListByCluster(ctx context.Context, clusterID uuid.UUID, limit int) ([]*domain.Deployment, error) {
+	return r.ListByClusterAfter(ctx, clusterID, nil, limit)
+}
+
+// ListByClusterAfter retrieves the deployments to a cluster older than the cursor, newest first
+func (r *DeploymentRepository) ListByClusterAfter(ctx context.Context, clusterID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Deployment, error) {
+	query, args := keyset(`SELECT `+deploymentColumns+` FROM deployments WHERE cluster_id = ?`, []interface{}{clusterID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+func (r *DeploymentRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Deployment, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list deployments")
+	}
+	defer rows.Close()
+
+	deployments := []*domain.Deployment{}
+	for rows.Next() {
+		deployment, err := scanDeployment(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan deployment")
+		}
+		deployments = append(deployments, deployment)
+	}
+
+	return deployments, rows.Err()
+}
+
+// Update updates an existing deployment
+func (r *DeploymentRepository) Update(ctx context.Context, deployment *domain.Deployment) error {
+	query := `
+		UPDATE deployments
+		SET status = ?, version = ?, previous_version = ?, replicas = ?, ready_replicas = ?,
+			error_message = ?, health = ?, pre_pull = ?, metadata = ?, started_at = ?, completed_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		deployment.Status,
+		deployment.Version,
+		deployment.PreviousVersion,
+		deployment.Replicas,
+		deployment.ReadyReplicas,
+		deployment.ErrorMessage,
+		nullableJSON(deployment.Health),
+		nullableJSON(deployment.PrePull),
+		jsonText(deployment.Metadata),
+		deployment.StartedAt,
+		deployment.CompletedAt,
+		deployment.ID,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update deployment")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("deployment", deployment.ID.String())
+	}
+
+	return nil
+}
+
+// UpdateStatus updates the status of a deployment, stamping completed_at once
+// it reaches a terminal status
+func (r *DeploymentRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.DeploymentStatus, errorMsg string) error {
+	var completedAt *time.Time
+	switch status {
+	case domain.DeploymentStatusSucceeded, domain.DeploymentStatusFailed, domain.DeploymentStatusRolledBack:
+		now := time.Now()
+		completedAt = &now
+	}
+
+	query := `
+		UPDATE deployments
+		SET status = ?, error_message = ?, completed_at = COALESCE(completed_at, ?)
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query, status, errorMsg, completedAt, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to update deployment status")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("deployment", id.String())
+	}
+
+	return nil
+}
+
+// nullableUUID stores uuid.Nil as NULL, for deploys of images not built by the platform
+func nullableUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+func scanDeployment(row scanner) (*domain.Deployment, error) {
+	deployment := &domain.Deployment{}
+	var buildID *uuid.UUID
+	var health, prePull, metadata []byte
+
+	err := row.Scan(
+		&deployment.ID,
+		&deployment.ServiceID,
+		&deployment.ProjectID,
+		&buildID,
+		&deployment.ClusterID,
+		&deployment.Status,
+		&deployment.Strategy,
+		&deployment.Version,
+		&deployment.PreviousVersion,
+		&deployment.Replicas,
+		&deployment.ReadyReplicas,
+		&deployment.TriggeredBy,
+		&deployment.ErrorMessage,
+		&health,
+		&prePull,
+		&metadata,
+		&deployment.StartedAt,
+		&deployment.CompletedAt,
+		&deployment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if buildID != nil {
+		deployment.BuildID = *buildID
+	}
+	if len(health) > 0 {
+		json.Unmarshal(health, &deployment.Health)
+	}
+	if len(prePull) > 0 {
+		json.Unmarshal(prePull, &deployment.PrePull)
+	}
+	json.Unmarshal(metadata, &deployment.Metadata)
+
+	return deployment, nil
+}
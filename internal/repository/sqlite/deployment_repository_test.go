@@ -0,0 +1,143 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeploymentRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewDeploymentRepository(db)
+	ctx := context.Background()
+
+	service := testService(t, db, testProject(t, db).ID)
+	build := testBuild(t, db, service)
+	cluster := testCluster(t, db)
+	now := testNow()
+	deployment := &domain.Deployment{
+		ID:          uuid.New(),
+		ServiceID:   service.ID,
+		ProjectID:   service.ProjectID,
+		BuildID:     build.ID,
+		ClusterID:   cluster.ID,
+		Status:      domain.DeploymentStatusInProgress,
+		Strategy:    domain.DeploymentStrategyRollingUpdate,
+		Version:     "v2",
+		Replicas:    3,
+		TriggeredBy: "push",
+		PrePull:     &domain.PrePullStatus{Phase: domain.PrePullPulling, Image: "registry.acme.dev/api:v2", NodesTotal: 4, StartedAt: now},
+		Metadata:    map[string]interface{}{"commit": "abc123"},
+		StartedAt:   &now,
+		CreatedAt:   now,
+	}
+	require.NoError(t, repo.Create(ctx, deployment))
+
+	got, err := repo.GetByID(ctx, deployment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, deployment, got)
+	_, err = repo.GetByID(ctx, uuid.New())
+	assert.True(t, errors.IsNotFound(err))
+
+	// Deploys of images the platform did not build have no build
+	image := &domain.Deployment{
+		ID:          uuid.New(),
+		ServiceID:   service.ID,
+		ProjectID:   service.ProjectID,
+		ClusterID:   cluster.ID,
+		Status:      domain.DeploymentStatusPending,
+		Strategy:    domain.DeploymentStrategyRecreate,
+		Version:     "nginx:1.27",
+		TriggeredBy: "api",
+		CreatedAt:   now.Add(time.Second),
+	}
+	require.NoError(t, repo.Create(ctx, image))
+	latest, err := repo.GetLatestByService(ctx, service.ID)
+	require.NoError(t, err)
+	assert.Equal(t, image.ID, latest.ID)
+	assert.Equal(t, uuid.Nil, latest.BuildID)
+	_, err = repo.GetLatestByService(ctx, uuid.New())
+	assert.True(t, errors.IsNotFound(err))
+
+	deployment.ReadyReplicas = 3
+	deployment.PreviousVersion = "v1"
+	deployment.PrePull = nil
+	require.NoError(t, repo.Update(ctx, deployment))
+	got, err = repo.GetByID(ctx, deployment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, deployment, got)
+
+	require.NoError(t, repo.UpdateStatus(ctx, deployment.ID, domain.DeploymentStatusSucceeded, ""))
+	got, err = repo.GetByID(ctx, deployment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeploymentStatusSucceeded, got.Status)
+	assert.NotNil(t, got.CompletedAt)
+
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.Deployment{ID: uuid.New()})))
+	assert.True(t, errors.IsNotFound(repo.UpdateStatus(ctx, uuid.New(), domain.DeploymentStatusFailed, "")))
+}
+
+func TestDeploymentRepositoryKeyset(t *testing.T) {
+	db := testDB(t)
+	repo := NewDeploymentRepository(db)
+	ctx := context.Background()
+
+	service := testService(t, db, testProject(t, db).ID)
+	cluster, other := testCluster(t, db), testCluster(t, db)
+
+	start := testNow()
+	var ids []uuid.UUID
+	for i := 0; i < 5; i++ {
+		clusterID := cluster.ID
+		if i == 4 {
+			clusterID = other.ID
+		}
+		deployment := &domain.Deployment{
+			ID:          uuid.New(),
+			ServiceID:   service.ID,
+			ProjectID:   service.ProjectID,
+			ClusterID:   clusterID,
+			Status:      domain.DeploymentStatusSucceeded,
+			Strategy:    domain.DeploymentStrategyRollingUpdate,
+			Version:     "v1",
+			TriggeredBy: "push",
+			CreatedAt:   start.Add(time.Duration(i) * time.Minute),
+		}
+		require.NoError(t, repo.Create(ctx, deployment))
+		ids = append(ids, deployment.ID)
+	}
+
+	first, err := repo.ListByService(ctx, service.ID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{ids[4], ids[3], ids[2]}, deploymentIDs(first))
+
+	last := first[2]
+	rest, err := repo.ListByServiceAfter(ctx, service.ID, cursorOf(last.CreatedAt, last.ID), 3)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{ids[1], ids[0]}, deploymentIDs(rest))
+
+	onCluster, err := repo.ListByCluster(ctx, cluster.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{ids[3], ids[2]}, deploymentIDs(onCluster))
+
+	last = onCluster[1]
+	rest, err = repo.ListByClusterAfter(ctx, cluster.ID, cursorOf(last.CreatedAt, last.ID), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{ids[1], ids[0]}, deploymentIDs(rest))
+}
+
+func deploymentIDs(deployments []*domain.Deployment) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for _, d := range deployments {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
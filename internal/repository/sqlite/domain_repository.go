@@ -0,0 +1,185 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// DomainRepository implements domain.DomainRepository using SQLite
+type DomainRepository struct {
+	db *DB
+}
+
+// NewDomainRepository creates a new DomainRepository
+func NewDomainRepository(db *DB) *DomainRepository {
+	return &DomainRepository{db: db}
+}
+
+const domainColumns = `id, project_id, name, method, token, status, message, verified_at, checked_at, created_by, created_at, updated_at`
+
+// Create creates a new custom domain
+func (r *DomainRepository) Create(ctx context.Context, d *domain.CustomDomain) error {
+	query := `
+		INSERT INTO custom_domains (` + domainColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		d.ID,
+		d.ProjectID,
+		d.Name,
+		d.Method,
+		d.Token,
+		d.Status,
+		d.Message,
+		d.VerifiedAt,
+		d.CheckedAt,
+		d.CreatedBy,
+		d.CreatedAt,
+		d.UpdatedAt,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("domain " + d.Name)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create custom domain")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a custom domain by ID
+func (r *DomainRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.CustomDomain, error) {
+	query := `SELECT ` + domainColumns + ` FROM custom_domains WHERE id = ?`
+
+	d, err := scanDomain(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("domain", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get custom domain")
+	}
+
+	return d, nil
+}
+
+// ListByProject retrieves the custom domains of a project
+func (r *DomainRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.CustomDomain, error) {
+	return r.list(ctx, `SELECT `+domainColumns+` FROM custom_domains WHERE project_id = ? ORDER BY name`, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's custom domains, newest first
+func (r *DomainRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.CustomDomain, error) {
+	query, args := keyset(`SELECT `+domainColumns+` FROM custom_domains WHERE project_id = ?`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+// ListByName retrieves every project's claim on a domain name
+func (r *DomainRepository) ListByName(ctx context.Context, name string) ([]*domain.CustomDomain, error) {
+	return r.list(ctx, `SELECT `+domainColumns+` FROM custom_domains WHERE name = ? ORDER BY created_at`, name)
+}
+
+// ListByStatus retrieves the custom domains in a verification state
+func (r *DomainRepository) ListByStatus(ctx context.Context, status domain.DomainStatus) ([]*domain.CustomDomain, error) {
+	return r.list(ctx, `SELECT `+domainColumns+` FROM custom_domains WHERE status = ? ORDER BY created_at`, status)
+}
+
+// Update updates the verification state of a custom domain
+func (r *DomainRepository) Update(ctx context.Context, d *domain.CustomDomain) error {
+	d.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE custom_domains
+		SET method = ?, token = ?, status = ?, message = ?, verified_at = ?, checked_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		d.Method,
+		d.Token,
+		d.Status,
+		d.Message,
+		d.VerifiedAt,
+		d.CheckedAt,
+		d.UpdatedAt,
+		d.ID,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("domain " + d.Name)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to update custom domain")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("domain", d.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a custom domain
+func (r *DomainRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM custom_domains WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete custom domain")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("domain", id.String())
+	}
+
+	return nil
+}
+
+func (r *DomainRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.CustomDomain, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list custom domains")
+	}
+	defer rows.Close()
+
+	domains := []*domain.CustomDomain{}
+	for rows.Next() {
+		d, err := scanDomain(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan custom domain")
+		}
+		domains = append(domains, d)
+	}
+
+	return domains, rows.Err()
+}
+
+func scanDomain(row scanner) (*domain.CustomDomain, error) {
+	d := &domain.CustomDomain{}
+
+	err := row.Scan(
+		&d.ID,
+		&d.ProjectID,
+		&d.Name,
+		&d.Method,
+		&d.Token,
+		&d.Status,
+		&d.Message,
+		&d.VerifiedAt,
+		&d.CheckedAt,
+		&d.CreatedBy,
+		&d.CreatedAt,
+		&d.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
@@ -0,0 +1,97 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewDomainRepository(db)
+	ctx := context.Background()
+
+	shop, other := testProject(t, db), testProject(t, db)
+	now := testNow()
+	newDomain := func(projectID uuid.UUID, name string, createdAt time.Time) *domain.CustomDomain {
+		return &domain.CustomDomain{
+			ID:        uuid.New(),
+			ProjectID: projectID,
+			Name:      name,
+			Method:    domain.DomainVerificationTXT,
+			Token:     "northstack-verify=" + uuid.NewString(),
+			Status:    domain.DomainStatusPending,
+			CreatedBy: uuid.New(),
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		}
+	}
+
+	claim := newDomain(shop.ID, "shop.example.com", now)
+	require.NoError(t, repo.Create(ctx, claim))
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, newDomain(shop.ID, "shop.example.com", now)), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "a project claims a name once")
+
+	got, err := repo.GetByID(ctx, claim.ID)
+	require.NoError(t, err)
+	assert.Equal(t, claim, got)
+	_, err = repo.GetByID(ctx, uuid.New())
+	assert.True(t, errors.IsNotFound(err))
+
+	// Another project may claim the same name until one of them verifies it
+	rival := newDomain(other.ID, "shop.example.com", now.Add(time.Second))
+	require.NoError(t, repo.Create(ctx, rival))
+	require.NoError(t, repo.Create(ctx, newDomain(shop.ID, "api.example.com", now.Add(2*time.Second))))
+
+	byName, err := repo.ListByName(ctx, "shop.example.com")
+	require.NoError(t, err)
+	require.Len(t, byName, 2)
+	assert.Equal(t, claim.ID, byName[0].ID, "oldest claim first")
+
+	byProject, err := repo.ListByProject(ctx, shop.ID)
+	require.NoError(t, err)
+	require.Len(t, byProject, 2)
+	assert.Equal(t, "api.example.com", byProject[0].Name, "domains are listed by name")
+
+	page, err := repo.ListByProjectAfter(ctx, shop.ID, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, "api.example.com", page[0].Name, "pages are newest first")
+	page, err = repo.ListByProjectAfter(ctx, shop.ID, cursorOf(page[0].CreatedAt, page[0].ID), 10)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, claim.ID, page[0].ID)
+
+	verified := now.Add(time.Minute)
+	claim.Status = domain.DomainStatusVerified
+	claim.VerifiedAt = &verified
+	claim.CheckedAt = &verified
+	require.NoError(t, repo.Update(ctx, claim))
+	got, err = repo.GetByID(ctx, claim.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DomainStatusVerified, got.Status)
+	require.NotNil(t, got.VerifiedAt)
+	assert.Equal(t, verified, *got.VerifiedAt)
+
+	rival.Status = domain.DomainStatusVerified
+	require.ErrorAs(t, repo.Update(ctx, rival), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "only one project can verify a name")
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.CustomDomain{ID: uuid.New()})))
+
+	pending, err := repo.ListByStatus(ctx, domain.DomainStatusPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, rival.ID, pending[0].ID)
+
+	require.NoError(t, repo.Delete(ctx, claim.ID))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, claim.ID)))
+}
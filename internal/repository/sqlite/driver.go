@@ -0,0 +1,6 @@
+//go:build sqlite
+
+package sqlite
+
+// Registers the "sqlite3" database/sql driver
+import _ "github.com/mattn/go-sqlite3"
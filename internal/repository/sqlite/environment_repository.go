@@ -0,0 +1,206 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// EnvironmentRepository implements domain.EnvironmentRepository using SQLite
+type EnvironmentRepository struct {
+	db *DB
+}
+
+// NewEnvironmentRepository creates a new EnvironmentRepository
+func NewEnvironmentRepository(db *DB) *EnvironmentRepository {
+	return &EnvironmentRepository{db: db}
+}
+
+const environmentColumns = `id, project_id, cluster_id, name, slug, type, namespace, is_default, egress, lifecycle, labels, metadata, created_at, updated_at`
+
+// Create creates a new environment
+func (r *EnvironmentRepository) Create(ctx context.Context, environment *domain.Environment) error {
+	query := `
+		INSERT INTO environments (` + environmentColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		environment.ID,
+		environment.ProjectID,
+		environment.ClusterID,
+		environment.Name,
+		environment.Slug,
+		environment.Type,
+		environment.Namespace,
+		environment.IsDefault,
+		nullableJSON(environment.Egress),
+		nullableJSON(environment.Lifecycle),
+		jsonText(environment.Labels),
+		jsonText(environment.Metadata),
+		environment.CreatedAt,
+		environment.UpdatedAt,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("environment " + environment.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create environment")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an environment by ID
+func (r *EnvironmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Environment, error) {
+	query := `SELECT ` + environmentColumns + ` FROM environments WHERE id = ?`
+
+	environment, err := scanEnvironment(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("environment", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get environment")
+	}
+
+	return environment, nil
+}
+
+// GetBySlug retrieves an environment of a project by slug
+func (r *EnvironmentRepository) GetBySlug(ctx context.Context, projectID uuid.UUID, slug string) (*domain.Environment, error) {
+	query := `SELECT ` + environmentColumns + ` FROM environments WHERE project_id = ? AND slug = ?`
+
+	environment, err := scanEnvironment(r.db.queryRow(ctx, query, projectID, slug))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("environment", slug)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get environment")
+	}
+
+	return environment, nil
+}
+
+// ListByProject retrieves the environments of a project
+func (r *EnvironmentRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.Environment, error) {
+	query := `SELECT ` + environmentColumns + ` FROM environments WHERE project_id = ? ORDER BY is_default DESC, name`
+	return r.list(ctx, query, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's environments, newest first
+func (r *EnvironmentRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Environment, error) {
+	query, args := keyset(`SELECT `+environmentColumns+` FROM environments WHERE project_id = ?`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+// ListByCluster retrieves the environments hosted on a cluster
+func (r *EnvironmentRepository) ListByCluster(ctx context.Context, clusterID uuid.UUID) ([]*domain.Environment, error) {
+	query := `SELECT ` + environmentColumns + ` FROM environments WHERE cluster_id = ? ORDER BY namespace`
+	return r.list(ctx, query, clusterID)
+}
+
+func (r *EnvironmentRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Environment, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list environments")
+	}
+	defer rows.Close()
+
+	environments := []*domain.Environment{}
+	for rows.Next() {
+		environment, err := scanEnvironment(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan environment")
+		}
+		environments = append(environments, environment)
+	}
+
+	return environments, rows.Err()
+}
+
+// Update updates an existing environment
+func (r *EnvironmentRepository) Update(ctx context.Context, environment *domain.Environment) error {
+	environment.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE environments
+		SET name = ?, type = ?, is_default = ?, egress = ?, lifecycle = ?, labels = ?, metadata = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		environment.Name,
+		environment.Type,
+		environment.IsDefault,
+		nullableJSON(environment.Egress),
+		nullableJSON(environment.Lifecycle),
+		jsonText(environment.Labels),
+		jsonText(environment.Metadata),
+		environment.UpdatedAt,
+		environment.ID,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to update environment")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("environment", environment.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes an environment
+func (r *EnvironmentRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM environments WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete environment")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("environment", id.String())
+	}
+
+	return nil
+}
+
+func scanEnvironment(row scanner) (*domain.Environment, error) {
+	environment := &domain.Environment{}
+	var egress, lifecycle, labels, metadata []byte
+
+	err := row.Scan(
+		&environment.ID,
+		&environment.ProjectID,
+		&environment.ClusterID,
+		&environment.Name,
+		&environment.Slug,
+		&environment.Type,
+		&environment.Namespace,
+		&environment.IsDefault,
+		&egress,
+		&lifecycle,
+		&labels,
+		&metadata,
+		&environment.CreatedAt,
+		&environment.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(egress, &environment.Egress)
+	json.Unmarshal(lifecycle, &environment.Lifecycle)
+	json.Unmarshal(labels, &environment.Labels)
+	json.Unmarshal(metadata, &environment.Metadata)
+
+	return environment, nil
+}
@@ -0,0 +1,103 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironmentRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewEnvironmentRepository(db)
+	ctx := context.Background()
+
+	project := testProject(t, db)
+	cluster := testCluster(t, db)
+	now := testNow()
+	expires := now.Add(72 * time.Hour)
+	preview := &domain.Environment{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		ClusterID: cluster.ID,
+		Name:      "PR 42",
+		Slug:      "pr-42",
+		Type:      domain.EnvironmentTypePreview,
+		Namespace: "shop-pr-42",
+		Lifecycle: &domain.EnvironmentLifecycle{TTLHours: 72, ExpiresAt: &expires},
+		Labels:    map[string]string{"pr": "42"},
+		Metadata:  map[string]interface{}{"branch": "feature"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, repo.Create(ctx, preview))
+
+	duplicate := *preview
+	duplicate.ID = uuid.New()
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, &duplicate), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "slugs are unique within a project")
+
+	got, err := repo.GetByID(ctx, preview.ID)
+	require.NoError(t, err)
+	assert.Equal(t, preview, got)
+
+	got, err = repo.GetBySlug(ctx, project.ID, "pr-42")
+	require.NoError(t, err)
+	assert.Equal(t, preview.ID, got.ID)
+	_, err = repo.GetBySlug(ctx, project.ID, "missing")
+	assert.True(t, errors.IsNotFound(err))
+
+	production := &domain.Environment{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		ClusterID: cluster.ID,
+		Name:      "Production",
+		Slug:      "production",
+		Type:      domain.EnvironmentTypeProduction,
+		Namespace: "shop-production",
+		IsDefault: true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, repo.Create(ctx, production))
+
+	byProject, err := repo.ListByProject(ctx, project.ID)
+	require.NoError(t, err)
+	require.Len(t, byProject, 2)
+	assert.Equal(t, production.ID, byProject[0].ID, "the default environment comes first")
+
+	first, err := repo.ListByProjectAfter(ctx, project.ID, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	rest, err := repo.ListByProjectAfter(ctx, project.ID, cursorOf(first[0].CreatedAt, first[0].ID), 10)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.ElementsMatch(t, []uuid.UUID{preview.ID, production.ID}, []uuid.UUID{first[0].ID, rest[0].ID}, "pages do not overlap")
+
+	byCluster, err := repo.ListByCluster(ctx, cluster.ID)
+	require.NoError(t, err)
+	require.Len(t, byCluster, 2)
+	assert.Equal(t, "shop-pr-42", byCluster[0].Namespace, "environments on a cluster are listed by namespace")
+
+	hibernated := now.Add(time.Hour)
+	preview.Lifecycle.HibernatedAt = &hibernated
+	require.NoError(t, repo.Update(ctx, preview))
+	got, err = repo.GetByID(ctx, preview.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.Lifecycle.HibernatedAt)
+	assert.True(t, hibernated.Equal(*got.Lifecycle.HibernatedAt))
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.Environment{ID: uuid.New()})))
+
+	require.NoError(t, repo.Delete(ctx, preview.ID))
+	_, err = repo.GetByID(ctx, preview.ID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, preview.ID)))
+}
@@ -0,0 +1,216 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// IngressRepository implements domain.IngressRepository using SQLite
+type IngressRepository struct {
+	db *DB
+}
+
+// NewIngressRepository creates a new IngressRepository
+func NewIngressRepository(db *DB) *IngressRepository {
+	return &IngressRepository{db: db}
+}
+
+const ingressColumns = `id, service_id, project_id, domain, path, type, tls, annotations, labels, ip_families, routing, created_at, updated_at`
+
+// Create creates a new ingress
+func (r *IngressRepository) Create(ctx context.Context, ingress *domain.Ingress) error {
+	query := `
+		INSERT INTO ingresses (` + ingressColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		ingress.ID,
+		ingress.ServiceID,
+		ingress.ProjectID,
+		ingress.Domain,
+		ingress.Path,
+		ingress.Type,
+		jsonText(ingress.TLS),
+		jsonText(ingress.Annotations),
+		jsonText(ingress.Labels),
+		nullableJSON(ingress.IPFamilies),
+		jsonText(ingress.Routing),
+		ingress.CreatedAt,
+		ingress.UpdatedAt,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("ingress " + ingress.Domain + ingress.Path)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create ingress")
+	}
+
+	return nil
+}
+
+// GetByID retrieves an ingress by ID
+func (r *IngressRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Ingress, error) {
+	query := `SELECT ` + ingressColumns + ` FROM ingresses WHERE id = ?`
+
+	ingress, err := scanIngress(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("ingress", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ingress")
+	}
+
+	return ingress, nil
+}
+
+// GetByDomain retrieves the root ingress of a domain, or its first path when
+// the domain has no root route
+func (r *IngressRepository) GetByDomain(ctx context.Context, domainName string) (*domain.Ingress, error) {
+	query := `SELECT ` + ingressColumns + ` FROM ingresses WHERE domain = ? ORDER BY path = '/' DESC, path LIMIT 1`
+
+	ingress, err := scanIngress(r.db.queryRow(ctx, query, domainName))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("ingress", domainName)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ingress")
+	}
+
+	return ingress, nil
+}
+
+// ListByService retrieves the ingresses of a service
+func (r *IngressRepository) ListByService(ctx context.Context, serviceID uuid.UUID) ([]*domain.Ingress, error) {
+	query := `SELECT ` + ingressColumns + ` FROM ingresses WHERE service_id = ? ORDER BY domain, path`
+	return r.list(ctx, query, serviceID)
+}
+
+// ListByServiceAfter retrieves a page of a service's ingresses, newest first
+func (r *IngressRepository) ListByServiceAfter(ctx context.Context, serviceID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Ingress, error) {
+	query, args := keyset(`SELECT `+ingressColumns+` FROM ingresses WHERE service_id = ?`, []interface{}{serviceID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+// ListByProject retrieves the ingresses of a project
+func (r *IngressRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.Ingress, error) {
+	query := `SELECT ` + ingressColumns + ` FROM ingresses WHERE project_id = ? ORDER BY domain, path`
+	return r.list(ctx, query, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's ingresses, newest first
+func (r *IngressRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Ingress, error) {
+	query, args := keyset(`SELECT `+ingressColumns+` FROM ingresses WHERE project_id = ?`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+func (r *IngressRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Ingress, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list ingresses")
+	}
+	defer rows.Close()
+
+	ingresses := []*domain.Ingress{}
+	for rows.Next() {
+		ingress, err := scanIngress(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan ingress")
+		}
+		ingresses = append(ingresses, ingress)
+	}
+
+	return ingresses, rows.Err()
+}
+
+// Update updates an existing ingress
+func (r *IngressRepository) Update(ctx context.Context, ingress *domain.Ingress) error {
+	ingress.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE ingresses
+		SET domain = ?, path = ?, type = ?, tls = ?, annotations = ?, labels = ?, ip_families = ?, routing = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		ingress.Domain,
+		ingress.Path,
+		ingress.Type,
+		jsonText(ingress.TLS),
+		jsonText(ingress.Annotations),
+		jsonText(ingress.Labels),
+		nullableJSON(ingress.IPFamilies),
+		jsonText(ingress.Routing),
+		ingress.UpdatedAt,
+		ingress.ID,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("ingress " + ingress.Domain + ingress.Path)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to update ingress")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("ingress", ingress.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes an ingress
+func (r *IngressRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM ingresses WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete ingress")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("ingress", id.String())
+	}
+
+	return nil
+}
+
+func scanIngress(row scanner) (*domain.Ingress, error) {
+	ingress := &domain.Ingress{}
+	var tls, annotations, labels, ipFamilies, routing []byte
+
+	err := row.Scan(
+		&ingress.ID,
+		&ingress.ServiceID,
+		&ingress.ProjectID,
+		&ingress.Domain,
+		&ingress.Path,
+		&ingress.Type,
+		&tls,
+		&annotations,
+		&labels,
+		&ipFamilies,
+		&routing,
+		&ingress.CreatedAt,
+		&ingress.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(tls, &ingress.TLS)
+	json.Unmarshal(annotations, &ingress.Annotations)
+	json.Unmarshal(labels, &ingress.Labels)
+	json.Unmarshal(ipFamilies, &ingress.IPFamilies)
+	json.Unmarshal(routing, &ingress.Routing)
+
+	return ingress, nil
+}
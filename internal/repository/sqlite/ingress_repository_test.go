@@ -0,0 +1,99 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngressRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewIngressRepository(db)
+	ctx := context.Background()
+
+	service := testService(t, db, testProject(t, db).ID)
+	now := testNow()
+	newIngress := func(path string) *domain.Ingress {
+		return &domain.Ingress{
+			ID:          uuid.New(),
+			ServiceID:   service.ID,
+			ProjectID:   service.ProjectID,
+			Domain:      "shop.acme.dev",
+			Path:        path,
+			Type:        domain.IngressTypeHTTP,
+			TLS:         domain.TLSConfig{Enabled: true, AutoTLS: true, Challenge: domain.ACMEChallengeHTTP01},
+			Annotations: map[string]string{"team": "shop"},
+			Labels:      map[string]string{"env": "prod"},
+			IPFamilies:  []domain.IPFamily{domain.IPFamilyIPv4},
+			Routing:     domain.IngressRouting{MaxBodySizeMB: 10, AllowCIDRs: []string{"10.0.0.0/8"}},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+	}
+
+	api := newIngress("/api")
+	require.NoError(t, repo.Create(ctx, api))
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, newIngress("/api")), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "a domain routes each path once")
+
+	got, err := repo.GetByID(ctx, api.ID)
+	require.NoError(t, err)
+	assert.Equal(t, api, got)
+
+	got, err = repo.GetByDomain(ctx, "shop.acme.dev")
+	require.NoError(t, err)
+	assert.Equal(t, api.ID, got.ID, "the first path stands in for a missing root route")
+
+	root := newIngress("/")
+	root.IPFamilies = nil
+	require.NoError(t, repo.Create(ctx, root))
+	got, err = repo.GetByDomain(ctx, "shop.acme.dev")
+	require.NoError(t, err)
+	assert.Equal(t, root.ID, got.ID, "the root route is preferred")
+	assert.Nil(t, got.IPFamilies)
+	_, err = repo.GetByDomain(ctx, "missing.acme.dev")
+	assert.True(t, errors.IsNotFound(err))
+
+	byService, err := repo.ListByService(ctx, service.ID)
+	require.NoError(t, err)
+	require.Len(t, byService, 2)
+	assert.Equal(t, "/", byService[0].Path)
+
+	byProject, err := repo.ListByProject(ctx, service.ProjectID)
+	require.NoError(t, err)
+	assert.Len(t, byProject, 2)
+
+	first, err := repo.ListByServiceAfter(ctx, service.ID, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	rest, err := repo.ListByProjectAfter(ctx, service.ProjectID, cursorOf(first[0].CreatedAt, first[0].ID), 10)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.ElementsMatch(t, []uuid.UUID{api.ID, root.ID}, []uuid.UUID{first[0].ID, rest[0].ID}, "pages do not overlap")
+
+	api.Path = "/"
+	require.ErrorAs(t, repo.Update(ctx, api), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code)
+
+	api.Path = "/v2"
+	api.Routing.RewritePath = "/"
+	require.NoError(t, repo.Update(ctx, api))
+	got, err = repo.GetByID(ctx, api.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "/v2", got.Path)
+	assert.Equal(t, "/", got.Routing.RewritePath)
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.Ingress{ID: uuid.New(), Domain: "other.acme.dev"})))
+
+	require.NoError(t, repo.Delete(ctx, api.ID))
+	_, err = repo.GetByID(ctx, api.ID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, api.ID)))
+}
@@ -0,0 +1,187 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// NotificationPreferenceRepository implements domain.NotificationPreferenceRepository using SQLite
+type NotificationPreferenceRepository struct {
+	db *DB
+}
+
+// NewNotificationPreferenceRepository creates a new NotificationPreferenceRepository
+func NewNotificationPreferenceRepository(db *DB) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db}
+}
+
+const notificationPreferenceColumns = `id, user_id, project_id, channel, events, severities, muted, mute, created_at, updated_at`
+
+// Create creates a new notification preference
+func (r *NotificationPreferenceRepository) Create(ctx context.Context, preference *domain.NotificationPreference) error {
+	query := `
+		INSERT INTO notification_preferences (` + notificationPreferenceColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		preference.ID,
+		preference.UserID,
+		preference.ProjectID,
+		preference.Channel,
+		jsonList(preference.Events),
+		jsonList(preference.Severities),
+		preference.Muted,
+		muteText(preference.Mute),
+		preference.CreatedAt,
+		preference.UpdatedAt,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("notification preference for channel " + preference.Channel)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create notification preference")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a notification preference by ID
+func (r *NotificationPreferenceRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.NotificationPreference, error) {
+	query := `SELECT ` + notificationPreferenceColumns + ` FROM notification_preferences WHERE id = ?`
+
+	preference, err := scanNotificationPreference(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("notification preference", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get notification preference")
+	}
+
+	return preference, nil
+}
+
+// ListByUser retrieves the preferences of a user, for every project and none
+func (r *NotificationPreferenceRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]*domain.NotificationPreference, error) {
+	query := `SELECT ` + notificationPreferenceColumns + ` FROM notification_preferences
+		WHERE user_id = ? ORDER BY project_id IS NOT NULL, project_id, channel`
+	return r.list(ctx, query, userID)
+}
+
+// ListByProject retrieves the preferences of a project, not those of its users
+func (r *NotificationPreferenceRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.NotificationPreference, error) {
+	query := `SELECT ` + notificationPreferenceColumns + ` FROM notification_preferences
+		WHERE project_id = ? AND user_id IS NULL ORDER BY channel`
+	return r.list(ctx, query, projectID)
+}
+
+func (r *NotificationPreferenceRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.NotificationPreference, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list notification preferences")
+	}
+	defer rows.Close()
+
+	preferences := []*domain.NotificationPreference{}
+	for rows.Next() {
+		preference, err := scanNotificationPreference(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan notification preference")
+		}
+		preferences = append(preferences, preference)
+	}
+
+	return preferences, rows.Err()
+}
+
+// Update updates what a notification preference lets through. Its scope and
+// channel do not change.
+func (r *NotificationPreferenceRepository) Update(ctx context.Context, preference *domain.NotificationPreference) error {
+	query := `
+		UPDATE notification_preferences
+		SET events = ?, severities = ?, muted = ?, mute = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		jsonList(preference.Events),
+		jsonList(preference.Severities),
+		preference.Muted,
+		muteText(preference.Mute),
+		preference.UpdatedAt,
+		preference.ID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update notification preference")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("notification preference", preference.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a notification preference
+func (r *NotificationPreferenceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM notification_preferences WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete notification preference")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("notification preference", id.String())
+	}
+
+	return nil
+}
+
+// jsonList stores a nil list as [] rather than JSON null
+func jsonList(values []string) string {
+	if values == nil {
+		return "[]"
+	}
+	return jsonText(values)
+}
+
+func muteText(windows []domain.MuteWindow) string {
+	if windows == nil {
+		return "[]"
+	}
+	return jsonText(windows)
+}
+
+func scanNotificationPreference(row scanner) (*domain.NotificationPreference, error) {
+	preference := &domain.NotificationPreference{}
+	var events, severities, mute []byte
+
+	err := row.Scan(
+		&preference.ID,
+		&preference.UserID,
+		&preference.ProjectID,
+		&preference.Channel,
+		&events,
+		&severities,
+		&preference.Muted,
+		&mute,
+		&preference.CreatedAt,
+		&preference.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(events, &preference.Events)
+	json.Unmarshal(severities, &preference.Severities)
+	json.Unmarshal(mute, &preference.Mute)
+
+	return preference, nil
+}
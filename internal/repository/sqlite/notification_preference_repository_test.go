@@ -0,0 +1,88 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationPreferenceRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewNotificationPreferenceRepository(db)
+	ctx := context.Background()
+
+	project := testProject(t, db)
+	user := uuid.New()
+	now := testNow()
+
+	global := &domain.NotificationPreference{
+		ID:         uuid.New(),
+		UserID:     &user,
+		Channel:    "email",
+		Events:     []string{"deploy.failed"},
+		Severities: []string{"critical"},
+		Mute:       []domain.MuteWindow{{Days: []string{"sat", "sun"}, Start: "00:00", End: "23:59", Timezone: "Europe/Berlin"}},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	require.NoError(t, repo.Create(ctx, global))
+
+	got, err := repo.GetByID(ctx, global.ID)
+	require.NoError(t, err)
+	assert.Equal(t, global, got)
+	_, err = repo.GetByID(ctx, uuid.New())
+	assert.True(t, errors.IsNotFound(err))
+
+	duplicate := *global
+	duplicate.ID = uuid.New()
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, &duplicate), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "a scope has one preference per channel")
+
+	perProject := &domain.NotificationPreference{ID: uuid.New(), UserID: &user, ProjectID: &project.ID, Channel: "email", Muted: true, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.Create(ctx, perProject))
+	projectWide := &domain.NotificationPreference{ID: uuid.New(), ProjectID: &project.ID, Channel: "slack", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.Create(ctx, projectWide))
+
+	got, err = repo.GetByID(ctx, projectWide.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, got.Events, "nil lists are stored empty")
+	assert.Equal(t, []domain.MuteWindow{}, got.Mute)
+
+	byUser, err := repo.ListByUser(ctx, user)
+	require.NoError(t, err)
+	require.Len(t, byUser, 2)
+	assert.Equal(t, global.ID, byUser[0].ID, "preferences for every project come first")
+	assert.True(t, byUser[1].Muted)
+
+	byProject, err := repo.ListByProject(ctx, project.ID)
+	require.NoError(t, err)
+	require.Len(t, byProject, 1, "users' preferences in the project are not the project's")
+	assert.Equal(t, projectWide.ID, byProject[0].ID)
+
+	global.Events = []string{"*"}
+	global.Mute = nil
+	global.UpdatedAt = now.Add(time.Second)
+	require.NoError(t, repo.Update(ctx, global))
+	got, err = repo.GetByID(ctx, global.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, got.Events)
+	assert.Empty(t, got.Mute)
+	assert.Equal(t, global.UpdatedAt, got.UpdatedAt)
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.NotificationPreference{ID: uuid.New()})))
+
+	// A preference needs a user or a project
+	_, err = db.exec(ctx, `INSERT INTO notification_preferences (id, channel, created_at, updated_at) VALUES (?, 'email', ?, ?)`, uuid.New(), now, now)
+	assert.Error(t, err)
+
+	require.NoError(t, repo.Delete(ctx, global.ID))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, global.ID)))
+}
@@ -0,0 +1,88 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewNotificationRepository(db)
+	ctx := context.Background()
+
+	service := testService(t, db, testProject(t, db).ID)
+	user, other := uuid.New(), uuid.New()
+	start := testNow()
+	var notifications []*domain.UserNotification
+	for i, kind := range []string{domain.NotificationDeploySucceeded, domain.NotificationDeployFailed, domain.NotificationQuotaNearing} {
+		notification := &domain.UserNotification{
+			ID:        uuid.New(),
+			UserID:    user,
+			Type:      kind,
+			Title:     "Deploy of api",
+			Body:      "v2 is live",
+			Link:      "/api/v1/services/" + service.ID.String(),
+			ProjectID: &service.ProjectID,
+			ServiceID: &service.ID,
+			Data:      map[string]interface{}{"version": "v2"},
+			CreatedAt: start.Add(time.Duration(i) * time.Minute),
+		}
+		require.NoError(t, repo.Create(ctx, notification))
+		notifications = append(notifications, notification)
+	}
+	require.NoError(t, repo.Create(ctx, &domain.UserNotification{ID: uuid.New(), UserID: other, Type: domain.NotificationDeployFailed, Title: "Deploy failed", CreatedAt: start}))
+
+	got, err := repo.GetByID(ctx, user, notifications[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, notifications[0], got)
+	_, err = repo.GetByID(ctx, other, notifications[0].ID)
+	assert.True(t, errors.IsNotFound(err), "users only see their own notifications")
+
+	unread, err := repo.CountUnread(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), unread)
+
+	require.NoError(t, repo.MarkRead(ctx, user, notifications[1].ID))
+	read, err := repo.GetByID(ctx, user, notifications[1].ID)
+	require.NoError(t, err)
+	require.NotNil(t, read.ReadAt)
+	require.NoError(t, repo.MarkRead(ctx, user, notifications[1].ID))
+	again, err := repo.GetByID(ctx, user, notifications[1].ID)
+	require.NoError(t, err)
+	assert.Equal(t, read.ReadAt, again.ReadAt, "the first read time is kept")
+	assert.True(t, errors.IsNotFound(repo.MarkRead(ctx, other, notifications[0].ID)))
+
+	onlyUnread, err := repo.ListByUser(ctx, user, domain.NotificationFilter{UnreadOnly: true})
+	require.NoError(t, err)
+	require.Len(t, onlyUnread, 2)
+	assert.Equal(t, notifications[2].ID, onlyUnread[0].ID, "newest first")
+
+	page, err := repo.ListByUser(ctx, user, domain.NotificationFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, notifications[1].ID, page[0].ID)
+
+	page, err = repo.ListByUser(ctx, user, domain.NotificationFilter{Limit: 5, After: cursorOf(page[0].CreatedAt, page[0].ID)})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, notifications[0].ID, page[0].ID)
+
+	marked, err := repo.MarkAllRead(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), marked)
+	unread, err = repo.CountUnread(ctx, user)
+	require.NoError(t, err)
+	assert.Zero(t, unread)
+	unread, err = repo.CountUnread(ctx, other)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), unread)
+}
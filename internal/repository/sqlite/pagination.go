@@ -0,0 +1,32 @@
+//go:build sqlite
+
+package sqlite
+
+import "github.com/northstack/platform/internal/domain"
+
+// keyset completes a query listing rows newest first: it restricts the rows
+// to those after the cursor, if any, then orders and limits them; a limit of
+// zero keeps every row. The query must end in a WHERE clause whose
+// parameters are args.
+func keyset(query string, args []interface{}, after *domain.Cursor, limit int) (string, []interface{}) {
+	if after != nil {
+		query += " AND (created_at, id) < (?, ?)"
+		args = append(args, after.CreatedAt, after.ID)
+	}
+
+	if limit <= 0 {
+		limit = -1 // SQLite has no OFFSET without a LIMIT
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	return query, append(args, limit)
+}
+
+// skip drops the first offset rows of a query completed by keyset, for
+// clients still paging by offset
+func skip(query string, args []interface{}, offset int) (string, []interface{}) {
+	if offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, offset)
+	}
+	return query, args
+}
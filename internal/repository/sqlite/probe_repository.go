@@ -0,0 +1,116 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ProbeRepository implements domain.ProbeRepository using SQLite
+type ProbeRepository struct {
+	db *DB
+}
+
+// NewProbeRepository creates a new ProbeRepository
+func NewProbeRepository(db *DB) *ProbeRepository {
+	return &ProbeRepository{db: db}
+}
+
+// Create records a probe result
+func (r *ProbeRepository) Create(ctx context.Context, result *domain.ProbeResult) error {
+	query := `
+		INSERT INTO probe_results (id, ingress_id, service_id, url, status_code, response_time, success, error, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		result.ID,
+		result.IngressID,
+		result.ServiceID,
+		result.URL,
+		result.StatusCode,
+		result.ResponseTime,
+		result.Success,
+		result.Error,
+		result.CheckedAt,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to create probe result")
+	}
+
+	return nil
+}
+
+// ListByIngress retrieves the most recent probe results of an ingress
+func (r *ProbeRepository) ListByIngress(ctx context.Context, ingressID uuid.UUID, limit int) ([]*domain.ProbeResult, error) {
+	query := `
+		SELECT id, ingress_id, service_id, url, status_code, response_time, success, error, checked_at
+		FROM probe_results
+		WHERE ingress_id = ?
+		ORDER BY checked_at DESC
+		LIMIT ?
+	`
+
+	rows, err := r.db.query(ctx, query, ingressID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list probe results")
+	}
+	defer rows.Close()
+
+	results := []*domain.ProbeResult{}
+	for rows.Next() {
+		result := &domain.ProbeResult{}
+		err := rows.Scan(
+			&result.ID,
+			&result.IngressID,
+			&result.ServiceID,
+			&result.URL,
+			&result.StatusCode,
+			&result.ResponseTime,
+			&result.Success,
+			&result.Error,
+			&result.CheckedAt,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan probe result")
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// Stats aggregates the probe results of an ingress since the given time
+func (r *ProbeRepository) Stats(ctx context.Context, ingressID uuid.UUID, since time.Time) (*domain.ProbeStats, error) {
+	query := `
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE success),
+		       COALESCE(AVG(response_time) FILTER (WHERE success), 0)
+		FROM probe_results
+		WHERE ingress_id = ? AND checked_at >= ?
+	`
+
+	stats := &domain.ProbeStats{}
+	err := r.db.queryRow(ctx, query, ingressID, since).Scan(&stats.Total, &stats.Successful, &stats.AvgResponseTime)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to aggregate probe results")
+	}
+
+	return stats, nil
+}
+
+// DeleteBefore removes probe results older than the given time
+func (r *ProbeRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.exec(ctx, `DELETE FROM probe_results WHERE checked_at < ?`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete probe results")
+	}
+
+	return result.RowsAffected()
+}
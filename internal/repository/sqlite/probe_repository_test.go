@@ -0,0 +1,75 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewProbeRepository(db)
+	ctx := context.Background()
+
+	ingressID, serviceID := uuid.New(), uuid.New()
+	// Whole and fractional seconds are stored with different precision and
+	// must still compare in time order
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	checks := []struct {
+		at      time.Time
+		success bool
+		ms      int64
+	}{
+		{start, true, 100},
+		{start.Add(500 * time.Millisecond), false, 0},
+		{start.Add(time.Second), true, 300},
+		{start.Add(1500 * time.Millisecond), true, 200},
+	}
+	for _, c := range checks {
+		result := &domain.ProbeResult{
+			ID:           uuid.New(),
+			IngressID:    ingressID,
+			ServiceID:    serviceID,
+			URL:          "https://shop.acme.dev/healthz",
+			StatusCode:   200,
+			ResponseTime: c.ms,
+			Success:      c.success,
+			CheckedAt:    c.at,
+		}
+		if !c.success {
+			result.StatusCode, result.Error = 502, "bad gateway"
+		}
+		require.NoError(t, repo.Create(ctx, result))
+	}
+	require.NoError(t, repo.Create(ctx, &domain.ProbeResult{ID: uuid.New(), IngressID: uuid.New(), ServiceID: serviceID, URL: "https://other.acme.dev", Success: true, CheckedAt: start}))
+
+	recent, err := repo.ListByIngress(ctx, ingressID, 2)
+	require.NoError(t, err)
+	require.Len(t, recent, 2)
+	assert.Equal(t, checks[3].at, recent[0].CheckedAt)
+	assert.Equal(t, checks[2].at, recent[1].CheckedAt)
+
+	failed, err := repo.ListByIngress(ctx, ingressID, 4)
+	require.NoError(t, err)
+	assert.Equal(t, "bad gateway", failed[2].Error)
+	assert.False(t, failed[2].Success)
+
+	stats, err := repo.Stats(ctx, ingressID, start.Add(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, &domain.ProbeStats{Total: 3, Successful: 2, AvgResponseTime: 250}, stats)
+
+	deleted, err := repo.DeleteBefore(ctx, start.Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted, "both ingresses' older results are removed")
+
+	stats, err = repo.Stats(ctx, ingressID, start)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Total)
+}
@@ -0,0 +1,244 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ProjectRepository implements domain.ProjectRepository using SQLite
+type ProjectRepository struct {
+	db *DB
+}
+
+// NewProjectRepository creates a new ProjectRepository
+func NewProjectRepository(db *DB) *ProjectRepository {
+	return &ProjectRepository{db: db}
+}
+
+const projectColumns = `id, name, slug, COALESCE(description, ''), status, owner_id, team_id, labels, metadata, data_residency, slack, created_at, updated_at`
+
+// Create creates a new project
+func (r *ProjectRepository) Create(ctx context.Context, project *domain.Project) error {
+	labels := jsonText(project.Labels)
+	metadata := jsonText(project.Metadata)
+
+	query := `
+		INSERT INTO projects (id, name, slug, description, status, owner_id, team_id, labels, metadata, data_residency, slack, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		project.ID,
+		project.Name,
+		project.Slug,
+		project.Description,
+		project.Status,
+		project.OwnerID,
+		project.TeamID,
+		labels,
+		metadata,
+		project.DataResidency,
+		nullableJSON(project.Slack),
+		project.CreatedAt,
+		project.UpdatedAt,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("project " + project.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create project")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a project by ID
+func (r *ProjectRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE id = ?`
+
+	project, err := scanProject(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("project", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get project")
+	}
+
+	return project, nil
+}
+
+// GetBySlug retrieves a project by slug
+func (r *ProjectRepository) GetBySlug(ctx context.Context, slug string) (*domain.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE slug = ?`
+
+	project, err := scanProject(r.db.queryRow(ctx, query, slug))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("project", slug)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get project")
+	}
+
+	return project, nil
+}
+
+// List retrieves projects with optional filtering
+func (r *ProjectRepository) List(ctx context.Context, filter domain.ProjectFilter) ([]*domain.Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE 1=1`
+	args := []interface{}{}
+
+	if filter.OwnerID != nil {
+		query += " AND owner_id = ?"
+		args = append(args, *filter.OwnerID)
+	}
+
+	if filter.TeamID != nil {
+		query += " AND team_id = ?"
+		args = append(args, *filter.TeamID)
+	}
+
+	if filter.Status != nil {
+		query += " AND status = ?"
+		args = append(args, *filter.Status)
+	}
+
+	if filter.Search != "" {
+		query += " AND (name LIKE ? OR slug LIKE ?)"
+		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+
+	if len(filter.Labels) > 0 {
+		cond, labelArgs := labelsMatch(filter.Labels)
+		query += cond
+		args = append(args, labelArgs...)
+	}
+
+	query, args = keyset(query, args, filter.After, filter.Limit)
+	query, args = skip(query, args, filter.Offset)
+
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list projects")
+	}
+	defer rows.Close()
+
+	projects := []*domain.Project{}
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan project")
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+// Update updates an existing project
+func (r *ProjectRepository) Update(ctx context.Context, project *domain.Project) error {
+	labels := jsonText(project.Labels)
+	metadata := jsonText(project.Metadata)
+	project.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE projects
+		SET name = ?, slug = ?, description = ?, status = ?, team_id = ?, labels = ?, metadata = ?, data_residency = ?, slack = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		project.Name,
+		project.Slug,
+		project.Description,
+		project.Status,
+		project.TeamID,
+		labels,
+		metadata,
+		project.DataResidency,
+		nullableJSON(project.Slack),
+		project.UpdatedAt,
+		project.ID,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("project " + project.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to update project")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("project", project.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a project
+func (r *ProjectRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM projects WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete project")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("project", id.String())
+	}
+
+	return nil
+}
+
+// paginate appends LIMIT and OFFSET clauses; SQLite requires a LIMIT for an OFFSET
+func paginate(query string, args []interface{}, limit, offset int) (string, []interface{}) {
+	if limit > 0 || offset > 0 {
+		if limit <= 0 {
+			limit = -1
+		}
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	if offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, offset)
+	}
+	return query, args
+}
+
+func scanProject(row scanner) (*domain.Project, error) {
+	project := &domain.Project{}
+	var labels, metadata, slack []byte
+
+	err := row.Scan(
+		&project.ID,
+		&project.Name,
+		&project.Slug,
+		&project.Description,
+		&project.Status,
+		&project.OwnerID,
+		&project.TeamID,
+		&labels,
+		&metadata,
+		&project.DataResidency,
+		&slack,
+		&project.CreatedAt,
+		&project.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(labels, &project.Labels)
+	json.Unmarshal(metadata, &project.Metadata)
+	json.Unmarshal(slack, &project.Slack)
+
+	return project, nil
+}
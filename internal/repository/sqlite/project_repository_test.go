@@ -0,0 +1,140 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewProjectRepository(db)
+	ctx := context.Background()
+
+	owner, team := uuid.New(), uuid.New()
+	now := testNow()
+	project := &domain.Project{
+		ID:            uuid.New(),
+		Name:          "Shop",
+		Slug:          "shop",
+		Status:        domain.ProjectStatusActive,
+		OwnerID:       owner,
+		TeamID:        &team,
+		Labels:        map[string]string{"tier": "gold"},
+		Metadata:      map[string]interface{}{"source": "import"},
+		DataResidency: "eu",
+		Slack:         &domain.SlackSettings{Channel: "#shop"},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	require.NoError(t, repo.Create(ctx, project))
+
+	duplicate := *project
+	duplicate.ID = uuid.New()
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, &duplicate), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "slugs are unique")
+
+	got, err := repo.GetByID(ctx, project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, project, got)
+
+	got, err = repo.GetBySlug(ctx, "shop")
+	require.NoError(t, err)
+	assert.Equal(t, project.ID, got.ID)
+	_, err = repo.GetBySlug(ctx, "missing")
+	assert.True(t, errors.IsNotFound(err))
+
+	project.Description = "Storefront"
+	project.Slack = nil
+	require.NoError(t, repo.Update(ctx, project))
+	got, err = repo.GetByID(ctx, project.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Storefront", got.Description)
+	assert.Nil(t, got.Slack)
+	assert.True(t, got.UpdatedAt.After(now))
+
+	missing := &domain.Project{ID: uuid.New(), Slug: "missing"}
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, missing)))
+
+	// Deleting a project removes its services with it
+	service := testService(t, db, project.ID)
+	require.NoError(t, repo.Delete(ctx, project.ID))
+	_, err = repo.GetByID(ctx, project.ID)
+	assert.True(t, errors.IsNotFound(err))
+	_, err = NewServiceRepository(db).GetByID(ctx, service.ID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, project.ID)))
+}
+
+func TestProjectRepositoryList(t *testing.T) {
+	db := testDB(t)
+	repo := NewProjectRepository(db)
+	ctx := context.Background()
+
+	owner := uuid.New()
+	start := testNow()
+	var projects []*domain.Project
+	for i, slug := range []string{"alpha", "beta", "gamma", "delta"} {
+		at := start.Add(time.Duration(i) * time.Minute)
+		project := &domain.Project{
+			ID:        uuid.New(),
+			Name:      slug,
+			Slug:      slug,
+			Status:    domain.ProjectStatusActive,
+			OwnerID:   owner,
+			Labels:    map[string]string{"env": "prod"},
+			CreatedAt: at,
+			UpdatedAt: at,
+		}
+		if slug == "delta" {
+			project.OwnerID = uuid.New()
+			project.Labels = map[string]string{"env": "dev"}
+		}
+		require.NoError(t, repo.Create(ctx, project))
+		projects = append(projects, project)
+	}
+
+	all, err := repo.List(ctx, domain.ProjectFilter{})
+	require.NoError(t, err)
+	require.Len(t, all, 4)
+	assert.Equal(t, projects[3].ID, all[0].ID, "newest first")
+
+	mine, err := repo.List(ctx, domain.ProjectFilter{OwnerID: &owner})
+	require.NoError(t, err)
+	assert.Len(t, mine, 3)
+
+	dev, err := repo.List(ctx, domain.ProjectFilter{Labels: map[string]string{"env": "dev"}})
+	require.NoError(t, err)
+	require.Len(t, dev, 1)
+	assert.Equal(t, "delta", dev[0].Slug)
+
+	search, err := repo.List(ctx, domain.ProjectFilter{Search: "amm"})
+	require.NoError(t, err)
+	require.Len(t, search, 1)
+	assert.Equal(t, "gamma", search[0].Slug)
+
+	offset, err := repo.List(ctx, domain.ProjectFilter{Offset: 3})
+	require.NoError(t, err)
+	require.Len(t, offset, 1, "an offset without a limit returns the rest")
+	assert.Equal(t, "alpha", offset[0].Slug)
+
+	first, err := repo.List(ctx, domain.ProjectFilter{OwnerID: &owner, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, []string{"gamma", "beta"}, []string{first[0].Slug, first[1].Slug})
+
+	last := first[1]
+	next, err := repo.List(ctx, domain.ProjectFilter{OwnerID: &owner, Limit: 2, After: cursorOf(last.CreatedAt, last.ID)})
+	require.NoError(t, err)
+	require.Len(t, next, 1)
+	assert.Equal(t, "alpha", next[0].Slug)
+}
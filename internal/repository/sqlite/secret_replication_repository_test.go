@@ -0,0 +1,87 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretReplicationRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewSecretReplicationRepository(db)
+	ctx := context.Background()
+
+	project := testProject(t, db)
+	cluster := testCluster(t, db)
+	now := testNow()
+	secrets := NewSecretRepository(db)
+	newSecret := func(name string) *domain.Secret {
+		secret := &domain.Secret{ID: uuid.New(), ProjectID: project.ID, Name: name, Type: domain.SecretTypeOpaque, VaultPath: "secret/data/" + name, Version: 2, CreatedAt: now, UpdatedAt: now}
+		require.NoError(t, secrets.Create(ctx, secret))
+		return secret
+	}
+	stripe, database := newSecret("stripe"), newSecret("database")
+
+	replication := &domain.SecretReplication{
+		ID:             uuid.New(),
+		SecretID:       stripe.ID,
+		ProjectID:      project.ID,
+		ClusterID:      cluster.ID,
+		Namespace:      "shop-production",
+		SecretName:     "stripe",
+		DesiredVersion: 2,
+		SyncedVersion:  1,
+		Status:         domain.SecretReplicationPending,
+		DesiredSince:   now,
+		CheckedAt:      now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	require.NoError(t, repo.Upsert(ctx, replication))
+
+	bySecret, err := repo.ListBySecret(ctx, stripe.ID)
+	require.NoError(t, err)
+	require.Len(t, bySecret, 1)
+	assert.Equal(t, replication, bySecret[0])
+
+	// A later check of the same namespace updates the row in place
+	synced := now.Add(time.Minute)
+	again := *replication
+	again.ID = uuid.New()
+	again.SyncedVersion = 2
+	again.Status = domain.SecretReplicationSynced
+	again.LastSyncedAt = &synced
+	again.CheckedAt = synced
+	again.CreatedAt = synced
+	again.UpdatedAt = synced
+	require.NoError(t, repo.Upsert(ctx, &again))
+	assert.Equal(t, replication.ID, again.ID, "the existing ID is kept")
+	assert.Equal(t, now, again.CreatedAt.UTC())
+
+	bySecret, err = repo.ListBySecret(ctx, stripe.ID)
+	require.NoError(t, err)
+	require.Len(t, bySecret, 1)
+	assert.Equal(t, domain.SecretReplicationSynced, bySecret[0].Status)
+	assert.Equal(t, 2, bySecret[0].SyncedVersion)
+	require.NotNil(t, bySecret[0].LastSyncedAt)
+	assert.Equal(t, synced, *bySecret[0].LastSyncedAt)
+
+	require.NoError(t, repo.Upsert(ctx, &domain.SecretReplication{ID: uuid.New(), SecretID: database.ID, ProjectID: project.ID, ClusterID: cluster.ID, Namespace: "shop-production", SecretName: "database", Status: domain.SecretReplicationFailed, Message: "forbidden", DesiredSince: now, CheckedAt: now, CreatedAt: now, UpdatedAt: now}))
+	byProject, err := repo.ListByProject(ctx, project.ID)
+	require.NoError(t, err)
+	require.Len(t, byProject, 2)
+	assert.Equal(t, "database", byProject[0].SecretName, "replications are listed by secret name")
+
+	// Replications go with their secret
+	require.NoError(t, secrets.Delete(ctx, stripe.ID))
+	bySecret, err = repo.ListBySecret(ctx, stripe.ID)
+	require.NoError(t, err)
+	assert.Empty(t, bySecret)
+}
@@ -0,0 +1,186 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// SecretRepository implements domain.SecretRepository using SQLite
+type SecretRepository struct {
+	db *DB
+}
+
+// NewSecretRepository creates a new SecretRepository
+func NewSecretRepository(db *DB) *SecretRepository {
+	return &SecretRepository{db: db}
+}
+
+const secretColumns = `id, project_id, name, type, keys, vault_path, version, labels, created_at, updated_at`
+
+// Create creates a new secret
+func (r *SecretRepository) Create(ctx context.Context, secret *domain.Secret) error {
+	query := `
+		INSERT INTO secrets (` + secretColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		secret.ID,
+		secret.ProjectID,
+		secret.Name,
+		secret.Type,
+		jsonText(secret.Keys),
+		secret.VaultPath,
+		secret.Version,
+		jsonText(secret.Labels),
+		secret.CreatedAt,
+		secret.UpdatedAt,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("secret " + secret.Name)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create secret")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a secret by ID
+func (r *SecretRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Secret, error) {
+	query := `SELECT ` + secretColumns + ` FROM secrets WHERE id = ?`
+
+	secret, err := scanSecret(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("secret", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get secret")
+	}
+
+	return secret, nil
+}
+
+// GetByName retrieves a project's secret by name
+func (r *SecretRepository) GetByName(ctx context.Context, projectID uuid.UUID, name string) (*domain.Secret, error) {
+	query := `SELECT ` + secretColumns + ` FROM secrets WHERE project_id = ? AND name = ?`
+
+	secret, err := scanSecret(r.db.queryRow(ctx, query, projectID, name))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("secret", name)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get secret")
+	}
+
+	return secret, nil
+}
+
+// ListByProject retrieves the secrets of a project
+func (r *SecretRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.Secret, error) {
+	return r.list(ctx, `SELECT `+secretColumns+` FROM secrets WHERE project_id = ? ORDER BY name`, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's secrets, newest first
+func (r *SecretRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.Secret, error) {
+	query, args := keyset(`SELECT `+secretColumns+` FROM secrets WHERE project_id = ?`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+func (r *SecretRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Secret, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list secrets")
+	}
+	defer rows.Close()
+
+	secrets := []*domain.Secret{}
+	for rows.Next() {
+		secret, err := scanSecret(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan secret")
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, rows.Err()
+}
+
+// Update updates an existing secret
+func (r *SecretRepository) Update(ctx context.Context, secret *domain.Secret) error {
+	secret.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE secrets
+		SET type = ?, keys = ?, vault_path = ?, version = ?, labels = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		secret.Type,
+		jsonText(secret.Keys),
+		secret.VaultPath,
+		secret.Version,
+		jsonText(secret.Labels),
+		secret.UpdatedAt,
+		secret.ID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update secret")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("secret", secret.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a secret
+func (r *SecretRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM secrets WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete secret")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("secret", id.String())
+	}
+
+	return nil
+}
+
+func scanSecret(row scanner) (*domain.Secret, error) {
+	secret := &domain.Secret{}
+	var keys, labels []byte
+
+	err := row.Scan(
+		&secret.ID,
+		&secret.ProjectID,
+		&secret.Name,
+		&secret.Type,
+		&keys,
+		&secret.VaultPath,
+		&secret.Version,
+		&labels,
+		&secret.CreatedAt,
+		&secret.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(keys, &secret.Keys)
+	json.Unmarshal(labels, &secret.Labels)
+
+	return secret, nil
+}
@@ -0,0 +1,81 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewSecretRepository(db)
+	ctx := context.Background()
+
+	project := testProject(t, db)
+	now := testNow()
+	secret := &domain.Secret{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		Name:      "stripe",
+		Type:      domain.SecretTypeOpaque,
+		Keys:      []string{"api_key", "webhook_secret"},
+		VaultPath: "secret/data/projects/" + project.ID.String() + "/stripe",
+		Version:   1,
+		Labels:    map[string]string{"vendor": "stripe"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, repo.Create(ctx, secret))
+
+	duplicate := *secret
+	duplicate.ID = uuid.New()
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, &duplicate), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "names are unique within a project")
+
+	got, err := repo.GetByID(ctx, secret.ID)
+	require.NoError(t, err)
+	assert.Equal(t, secret, got)
+
+	got, err = repo.GetByName(ctx, project.ID, "stripe")
+	require.NoError(t, err)
+	assert.Equal(t, secret.ID, got.ID)
+	_, err = repo.GetByName(ctx, testProject(t, db).ID, "stripe")
+	assert.True(t, errors.IsNotFound(err))
+
+	tls := &domain.Secret{ID: uuid.New(), ProjectID: project.ID, Name: "certificate", Type: domain.SecretTypeTLS, Keys: []string{"tls.crt", "tls.key"}, VaultPath: "secret/data/certificate", Version: 1, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.Create(ctx, tls))
+	secrets, err := repo.ListByProject(ctx, project.ID)
+	require.NoError(t, err)
+	require.Len(t, secrets, 2)
+	assert.Equal(t, "certificate", secrets[0].Name, "secrets are listed by name")
+
+	first, err := repo.ListByProjectAfter(ctx, project.ID, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	rest, err := repo.ListByProjectAfter(ctx, project.ID, cursorOf(first[0].CreatedAt, first[0].ID), 10)
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.ElementsMatch(t, []uuid.UUID{secret.ID, tls.ID}, []uuid.UUID{first[0].ID, rest[0].ID}, "pages do not overlap")
+
+	secret.Keys = append(secret.Keys, "publishable_key")
+	secret.Version = 2
+	require.NoError(t, repo.Update(ctx, secret))
+	got, err = repo.GetByID(ctx, secret.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.Version)
+	assert.Equal(t, secret.Keys, got.Keys)
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.Secret{ID: uuid.New()})))
+
+	require.NoError(t, repo.Delete(ctx, secret.ID))
+	_, err = repo.GetByID(ctx, secret.ID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, secret.ID)))
+}
@@ -0,0 +1,315 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// ServiceRepository implements domain.ServiceRepository using SQLite
+type ServiceRepository struct {
+	db *DB
+}
+
+// NewServiceRepository creates a new ServiceRepository
+func NewServiceRepository(db *DB) *ServiceRepository {
+	return &ServiceRepository{db: db}
+}
+
+const serviceColumns = `id, project_id, name, slug, type, status, build_source, resources, scaling,
+	health_check, env_vars, secret_refs, ports, labels, annotations, metadata,
+	current_build_id, COALESCE(current_version, ''), target_cluster_id, catalog, networking, placement, cron_job, availability, volumes, replicas, created_at, updated_at`
+
+// Create creates a new service
+func (r *ServiceRepository) Create(ctx context.Context, service *domain.Service) error {
+	query := `
+		INSERT INTO services (
+			id, project_id, name, slug, type, status, build_source, resources, scaling,
+			health_check, env_vars, secret_refs, ports, labels, annotations, metadata,
+			current_build_id, current_version, target_cluster_id, catalog, networking, placement, cron_job, availability, volumes, created_at, updated_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		service.ID,
+		service.ProjectID,
+		service.Name,
+		service.Slug,
+		service.Type,
+		service.Status,
+		jsonText(service.BuildSource),
+		jsonText(service.Resources),
+		jsonText(service.Scaling),
+		nullableJSON(service.HealthCheck),
+		jsonText(service.EnvVars),
+		jsonText(service.SecretRefs),
+		jsonText(service.Ports),
+		jsonText(service.Labels),
+		jsonText(service.Annotations),
+		jsonText(service.Metadata),
+		service.CurrentBuildID,
+		service.CurrentVersion,
+		service.TargetClusterID,
+		jsonText(service.Catalog),
+		nullableJSON(service.Networking),
+		nullableJSON(service.Placement),
+		nullableJSON(service.CronJob),
+		nullableJSON(service.Availability),
+		nullableJSON(service.Volumes),
+		service.CreatedAt,
+		service.UpdatedAt,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("service " + service.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create service")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a service by ID
+func (r *ServiceRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM services WHERE id = ?`
+
+	service, err := scanService(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("service", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get service")
+	}
+
+	return service, nil
+}
+
+// GetBySlug retrieves a service by project ID and slug
+func (r *ServiceRepository) GetBySlug(ctx context.Context, projectID uuid.UUID, slug string) (*domain.Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM services WHERE project_id = ? AND slug = ?`
+
+	service, err := scanService(r.db.queryRow(ctx, query, projectID, slug))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("service", slug)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get service")
+	}
+
+	return service, nil
+}
+
+// ListByProject retrieves services for a project
+func (r *ServiceRepository) ListByProject(ctx context.Context, projectID uuid.UUID, filter domain.ServiceFilter) ([]*domain.Service, error) {
+	query := `SELECT ` + serviceColumns + ` FROM services WHERE project_id = ?`
+	args := []interface{}{projectID}
+
+	if filter.Type != nil {
+		query += " AND type = ?"
+		args = append(args, *filter.Type)
+	}
+
+	if filter.Status != nil {
+		query += " AND status = ?"
+		args = append(args, *filter.Status)
+	}
+
+	if filter.Search != "" {
+		query += " AND (name LIKE ? OR slug LIKE ?)"
+		args = append(args, "%"+filter.Search+"%", "%"+filter.Search+"%")
+	}
+
+	if len(filter.Labels) > 0 {
+		cond, labelArgs := labelsMatch(filter.Labels)
+		query += cond
+		args = append(args, labelArgs...)
+	}
+
+	query, args = keyset(query, args, filter.After, filter.Limit)
+	query, args = skip(query, args, filter.Offset)
+
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list services")
+	}
+	defer rows.Close()
+
+	services := []*domain.Service{}
+	for rows.Next() {
+		service, err := scanService(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan service")
+		}
+		services = append(services, service)
+	}
+
+	return services, rows.Err()
+}
+
+// Update updates an existing service
+func (r *ServiceRepository) Update(ctx context.Context, service *domain.Service) error {
+	service.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE services
+		SET name = ?, slug = ?, type = ?, status = ?, build_source = ?, resources = ?,
+			scaling = ?, health_check = ?, env_vars = ?, secret_refs = ?, ports = ?,
+			labels = ?, annotations = ?, metadata = ?, current_build_id = ?,
+			current_version = ?, target_cluster_id = ?, catalog = ?, networking = ?, placement = ?, cron_job = ?, availability = ?, volumes = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		service.Name,
+		service.Slug,
+		service.Type,
+		service.Status,
+		jsonText(service.BuildSource),
+		jsonText(service.Resources),
+		jsonText(service.Scaling),
+		nullableJSON(service.HealthCheck),
+		jsonText(service.EnvVars),
+		jsonText(service.SecretRefs),
+		jsonText(service.Ports),
+		jsonText(service.Labels),
+		jsonText(service.Annotations),
+		jsonText(service.Metadata),
+		service.CurrentBuildID,
+		service.CurrentVersion,
+		service.TargetClusterID,
+		jsonText(service.Catalog),
+		nullableJSON(service.Networking),
+		nullableJSON(service.Placement),
+		nullableJSON(service.CronJob),
+		nullableJSON(service.Availability),
+		nullableJSON(service.Volumes),
+		service.UpdatedAt,
+		service.ID,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("service " + service.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to update service")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("service", service.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a service
+func (r *ServiceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM services WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete service")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("service", id.String())
+	}
+
+	return nil
+}
+
+// UpdateStatus updates only the status of a service
+func (r *ServiceRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status domain.ServiceStatus) error {
+	result, err := r.db.exec(ctx, `UPDATE services SET status = ?, updated_at = ? WHERE id = ?`, status, time.Now(), id)
+	if err != nil {
+		return errors.Wrap(err, "failed to update service status")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("service", id.String())
+	}
+
+	return nil
+}
+
+// UpdateReplicas records the replica counts observed for a service. They are
+// not a change to the service, so updated_at is left alone.
+func (r *ServiceRepository) UpdateReplicas(ctx context.Context, id uuid.UUID, replicas *domain.ServiceReplicas) error {
+	result, err := r.db.exec(ctx, `UPDATE services SET replicas = ? WHERE id = ?`, nullableJSON(replicas), id)
+	if err != nil {
+		return errors.Wrap(err, "failed to update service replicas")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("service", id.String())
+	}
+
+	return nil
+}
+
+func scanService(row scanner) (*domain.Service, error) {
+	service := &domain.Service{}
+	var buildSource, resources, scaling, healthCheck, envVars, secretRefs, ports, labels, annotations, metadata, catalog, networking, placement, cronJob, availability, volumes, replicas []byte
+
+	err := row.Scan(
+		&service.ID,
+		&service.ProjectID,
+		&service.Name,
+		&service.Slug,
+		&service.Type,
+		&service.Status,
+		&buildSource,
+		&resources,
+		&scaling,
+		&healthCheck,
+		&envVars,
+		&secretRefs,
+		&ports,
+		&labels,
+		&annotations,
+		&metadata,
+		&service.CurrentBuildID,
+		&service.CurrentVersion,
+		&service.TargetClusterID,
+		&catalog,
+		&networking,
+		&placement,
+		&cronJob,
+		&availability,
+		&volumes,
+		&replicas,
+		&service.CreatedAt,
+		&service.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(buildSource, &service.BuildSource)
+	json.Unmarshal(resources, &service.Resources)
+	json.Unmarshal(scaling, &service.Scaling)
+	if len(healthCheck) > 0 {
+		json.Unmarshal(healthCheck, &service.HealthCheck)
+	}
+	json.Unmarshal(envVars, &service.EnvVars)
+	json.Unmarshal(secretRefs, &service.SecretRefs)
+	json.Unmarshal(ports, &service.Ports)
+	json.Unmarshal(labels, &service.Labels)
+	json.Unmarshal(annotations, &service.Annotations)
+	json.Unmarshal(metadata, &service.Metadata)
+	json.Unmarshal(catalog, &service.Catalog)
+	json.Unmarshal(networking, &service.Networking)
+	json.Unmarshal(placement, &service.Placement)
+	json.Unmarshal(cronJob, &service.CronJob)
+	json.Unmarshal(availability, &service.Availability)
+	json.Unmarshal(volumes, &service.Volumes)
+	json.Unmarshal(replicas, &service.Replicas)
+
+	return service, nil
+}
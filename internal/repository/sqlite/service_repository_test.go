@@ -0,0 +1,160 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewServiceRepository(db)
+	ctx := context.Background()
+
+	project := testProject(t, db)
+	cluster := testCluster(t, db)
+	now := testNow()
+	service := &domain.Service{
+		ID:          uuid.New(),
+		ProjectID:   project.ID,
+		Name:        "API",
+		Slug:        "api",
+		Type:        domain.ServiceTypeWebApp,
+		Status:      domain.ServiceStatusPending,
+		BuildSource: domain.BuildSource{Type: "git", Repository: "https://github.com/acme/api", Branch: "main"},
+		Resources:   domain.ResourceLimits{CPURequest: "250m", MemoryRequest: "256Mi"},
+		Scaling: domain.ScalingConfig{
+			MinReplicas: 2,
+			MaxReplicas: 6,
+			TargetCPU:   70,
+			WarmStandby: &domain.WarmStandby{Replicas: 1},
+		},
+		HealthCheck:     &domain.HealthCheck{Type: "http", Path: "/healthz", Port: 8080, PeriodSeconds: 10},
+		EnvVars:         map[string]string{"LOG_LEVEL": "info"},
+		SecretRefs:      []string{"db-credentials"},
+		Ports:           []domain.ServicePort{{Name: "http", Port: 80, TargetPort: 8080, Protocol: "TCP", Public: true}},
+		Labels:          map[string]string{"team": "payments"},
+		Annotations:     map[string]string{"owner": "payments@acme.dev"},
+		Metadata:        map[string]interface{}{"imported": true},
+		CurrentVersion:  "v1",
+		TargetClusterID: &cluster.ID,
+		Catalog:         domain.ServiceCatalog{Owner: "payments", Tier: domain.ServiceTier1},
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	require.NoError(t, repo.Create(ctx, service))
+
+	duplicate := *service
+	duplicate.ID = uuid.New()
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, &duplicate), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "slugs are unique within a project")
+
+	got, err := repo.GetByID(ctx, service.ID)
+	require.NoError(t, err)
+	assert.Equal(t, service, got)
+
+	got, err = repo.GetBySlug(ctx, project.ID, "api")
+	require.NoError(t, err)
+	assert.Equal(t, service.ID, got.ID)
+	_, err = repo.GetBySlug(ctx, uuid.New(), "api")
+	assert.True(t, errors.IsNotFound(err))
+
+	service.HealthCheck = nil
+	service.Scaling.MaxReplicas = 10
+	require.NoError(t, repo.Update(ctx, service))
+	got, err = repo.GetByID(ctx, service.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.HealthCheck)
+	assert.Equal(t, int32(10), got.Scaling.MaxReplicas)
+
+	require.NoError(t, repo.UpdateStatus(ctx, service.ID, domain.ServiceStatusRunning))
+	assert.True(t, errors.IsNotFound(repo.UpdateStatus(ctx, uuid.New(), domain.ServiceStatusRunning)))
+
+	got, err = repo.GetByID(ctx, service.ID)
+	require.NoError(t, err)
+	updatedAt := got.UpdatedAt
+	replicas := &domain.ServiceReplicas{Desired: 3, Current: 3, Ready: 2, Autoscaler: domain.AutoscalerHPA, ObservedAt: testNow()}
+	require.NoError(t, repo.UpdateReplicas(ctx, service.ID, replicas))
+	got, err = repo.GetByID(ctx, service.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.ServiceStatusRunning, got.Status)
+	assert.Equal(t, replicas, got.Replicas)
+	assert.Equal(t, updatedAt, got.UpdatedAt, "observed replicas are not a change to the service")
+
+	require.NoError(t, repo.Delete(ctx, service.ID))
+	_, err = repo.GetByID(ctx, service.ID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, service.ID)))
+}
+
+func TestServiceRepositoryListByProject(t *testing.T) {
+	db := testDB(t)
+	repo := NewServiceRepository(db)
+	ctx := context.Background()
+
+	project := testProject(t, db)
+	testService(t, db, testProject(t, db).ID)
+
+	start := testNow()
+	for i, slug := range []string{"web", "worker", "cron", "queue"} {
+		at := start.Add(time.Duration(i) * time.Minute)
+		service := &domain.Service{
+			ID:        uuid.New(),
+			ProjectID: project.ID,
+			Name:      slug,
+			Slug:      slug,
+			Type:      domain.ServiceTypeWorker,
+			Status:    domain.ServiceStatusRunning,
+			Labels:    map[string]string{"tier": "backend"},
+			CreatedAt: at,
+			UpdatedAt: at,
+		}
+		if slug == "web" {
+			service.Type = domain.ServiceTypeWebApp
+			service.Labels = map[string]string{"tier": "frontend"}
+		}
+		require.NoError(t, repo.Create(ctx, service))
+	}
+
+	all, err := repo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"queue", "cron", "worker", "web"}, serviceSlugs(all), "only the project's services, newest first")
+
+	web := domain.ServiceTypeWebApp
+	byType, err := repo.ListByProject(ctx, project.ID, domain.ServiceFilter{Type: &web})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, serviceSlugs(byType))
+
+	byLabel, err := repo.ListByProject(ctx, project.ID, domain.ServiceFilter{Labels: map[string]string{"tier": "backend"}})
+	require.NoError(t, err)
+	assert.Len(t, byLabel, 3)
+
+	search, err := repo.ListByProject(ctx, project.ID, domain.ServiceFilter{Search: "work"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"worker"}, serviceSlugs(search))
+
+	first, err := repo.ListByProject(ctx, project.ID, domain.ServiceFilter{Limit: 3})
+	require.NoError(t, err)
+	require.Len(t, first, 3)
+	last := first[2]
+	next, err := repo.ListByProject(ctx, project.ID, domain.ServiceFilter{Limit: 3, After: cursorOf(last.CreatedAt, last.ID)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, serviceSlugs(next))
+}
+
+func serviceSlugs(services []*domain.Service) []string {
+	slugs := []string{}
+	for _, s := range services {
+		slugs = append(slugs, s.Slug)
+	}
+	return slugs
+}
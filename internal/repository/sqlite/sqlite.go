@@ -0,0 +1,584 @@
+//go:build sqlite
+
+// Package sqlite implements the repository interfaces from the domain package
+// on an embedded SQLite database, so the orchestrator can run as a single
+// binary without PostgreSQL. It is only compiled with the sqlite build tag,
+// and the driver needs cgo:
+//
+//	CGO_ENABLED=1 go build -tags sqlite ./cmd/orchestrator
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// DB wraps a SQLite database handle
+type DB struct {
+	db     *sql.DB
+	logger *logger.Logger
+}
+
+// Open opens (creating if needed) the SQLite database at cfg.Path
+func Open(ctx context.Context, cfg *config.DatabaseConfig, log *logger.Logger) (*DB, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=1&_journal_mode=WAL&_busy_timeout=5000", cfg.Path)
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite serializes writers; a single connection avoids SQLITE_BUSY under
+	// load and keeps an in-memory database from being split across connections
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	log.Info().Str("path", cfg.Path).Msg("Opened SQLite database")
+
+	return &DB{db: db, logger: log}, nil
+}
+
+// Close closes the database
+func (db *DB) Close() {
+	db.db.Close()
+	db.logger.Info().Msg("SQLite database closed")
+}
+
+// Health pings the database
+func (db *DB) Health(ctx context.Context) error {
+	return db.db.PingContext(ctx)
+}
+
+// Migrate creates the schema
+func (db *DB) Migrate(ctx context.Context) error {
+	if _, err := db.db.ExecContext(ctx, schema); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	db.logger.Info().Msg("Database migrations completed")
+	return nil
+}
+
+func (db *DB) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.db.ExecContext(ctx, query, utc(args)...)
+}
+
+func (db *DB) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.db.QueryRowContext(ctx, query, utc(args)...)
+}
+
+func (db *DB) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.db.QueryContext(ctx, query, utc(args)...)
+}
+
+// utc normalizes timestamp arguments so that stored timestamps compare and
+// sort correctly as text regardless of the caller's time zone
+func utc(args []interface{}) []interface{} {
+	for i, arg := range args {
+		switch t := arg.(type) {
+		case time.Time:
+			args[i] = t.UTC()
+		case *time.Time:
+			if t != nil {
+				args[i] = t.UTC()
+			}
+		}
+	}
+	return args
+}
+
+// jsonText encodes v for a TEXT column; a BLOB would not be valid input to
+// SQLite's JSON functions
+func jsonText(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// nullableJSON stores a nil value as NULL rather than JSON null
+func nullableJSON(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil || string(data) == "null" {
+		return nil
+	}
+	return string(data)
+}
+
+// rowsAffected reports whether a statement changed any row
+func rowsAffected(result sql.Result) bool {
+	n, err := result.RowsAffected()
+	return err == nil && n > 0
+}
+
+// isUniqueViolation reports whether err is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// labelsMatch returns the condition that the labels column holds every given
+// label, one json_extract per key in a stable order
+func labelsMatch(labels map[string]string) (string, []interface{}) {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var cond string
+	var args []interface{}
+	for _, key := range keys {
+		cond += " AND json_extract(labels, ?) = ?"
+		args = append(args, jsonPath(key), labels[key])
+	}
+	return cond, args
+}
+
+// jsonPath returns the JSON path of a top-level object key
+func jsonPath(key string) string {
+	quoted, _ := json.Marshal(key)
+	return "$." + string(quoted)
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Timestamps are declared TIMESTAMP so the driver parses them back into
+// time.Time; JSON documents are stored as TEXT
+const schema = `
+CREATE TABLE IF NOT EXISTS projects (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    description TEXT,
+    status TEXT NOT NULL DEFAULT 'pending',
+    owner_id TEXT NOT NULL,
+    team_id TEXT,
+    labels TEXT DEFAULT '{}',
+    metadata TEXT DEFAULT '{}',
+    data_residency TEXT NOT NULL DEFAULT '',
+    slack TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS services (
+    id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    type TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    build_source TEXT NOT NULL DEFAULT '{}',
+    resources TEXT NOT NULL DEFAULT '{}',
+    scaling TEXT NOT NULL DEFAULT '{}',
+    health_check TEXT,
+    env_vars TEXT DEFAULT '{}',
+    secret_refs TEXT DEFAULT '[]',
+    ports TEXT DEFAULT '[]',
+    labels TEXT DEFAULT '{}',
+    annotations TEXT DEFAULT '{}',
+    metadata TEXT DEFAULT '{}',
+    current_build_id TEXT,
+    current_version TEXT,
+    target_cluster_id TEXT,
+    catalog TEXT NOT NULL DEFAULT '{}',
+    networking TEXT,
+    placement TEXT,
+    cron_job TEXT,
+    availability TEXT,
+    volumes TEXT,
+    replicas TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(project_id, slug)
+);
+
+CREATE TABLE IF NOT EXISTS builds (
+    id TEXT PRIMARY KEY,
+    service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'queued',
+    source TEXT NOT NULL DEFAULT '{}',
+    image_tag TEXT,
+    image_digest TEXT,
+    build_logs TEXT,
+    duration INTEGER,
+    stages TEXT NOT NULL DEFAULT '[]',
+    triggered_by TEXT NOT NULL,
+    error_message TEXT,
+    metadata TEXT DEFAULT '{}',
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS deployments (
+    id TEXT PRIMARY KEY,
+    service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    build_id TEXT REFERENCES builds(id),
+    cluster_id TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    strategy TEXT NOT NULL DEFAULT 'rolling_update',
+    version TEXT NOT NULL,
+    previous_version TEXT,
+    replicas INTEGER NOT NULL DEFAULT 1,
+    ready_replicas INTEGER NOT NULL DEFAULT 0,
+    triggered_by TEXT NOT NULL,
+    error_message TEXT,
+    health TEXT,
+    pre_pull TEXT,
+    metadata TEXT DEFAULT '{}',
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS clusters (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    provider TEXT NOT NULL,
+    region TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'provisioning',
+    kube_version TEXT,
+    api_endpoint TEXT,
+    node_count INTEGER NOT NULL DEFAULT 0,
+    labels TEXT DEFAULT '{}',
+    metadata TEXT DEFAULT '{}',
+    rancher_cluster_id TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS environments (
+    id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    cluster_id TEXT NOT NULL REFERENCES clusters(id),
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL,
+    type TEXT NOT NULL,
+    namespace TEXT NOT NULL,
+    is_default INTEGER NOT NULL DEFAULT 0,
+    egress TEXT,
+    lifecycle TEXT,
+    labels TEXT DEFAULT '{}',
+    metadata TEXT DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(project_id, slug)
+);
+
+CREATE TABLE IF NOT EXISTS ingresses (
+    id TEXT PRIMARY KEY,
+    service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    path TEXT NOT NULL DEFAULT '/',
+    type TEXT NOT NULL DEFAULT 'http',
+    tls TEXT NOT NULL DEFAULT '{"enabled": false}',
+    annotations TEXT DEFAULT '{}',
+    labels TEXT DEFAULT '{}',
+    ip_families TEXT,
+    routing TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(domain, path)
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id TEXT PRIMARY KEY,
+    fingerprint TEXT NOT NULL,
+    name TEXT NOT NULL,
+    severity TEXT NOT NULL,
+    status TEXT NOT NULL,
+    source TEXT NOT NULL,
+    message TEXT,
+    labels TEXT DEFAULT '{}',
+    annotations TEXT DEFAULT '{}',
+    service_id TEXT,
+    project_id TEXT,
+    cluster_id TEXT,
+    starts_at INTEGER NOT NULL,
+    ends_at INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS probe_results (
+    id TEXT PRIMARY KEY,
+    ingress_id TEXT NOT NULL,
+    service_id TEXT NOT NULL,
+    url TEXT NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_time INTEGER NOT NULL DEFAULT 0,
+    success INTEGER NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    checked_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS usage_records (
+    service_id TEXT NOT NULL,
+    project_id TEXT NOT NULL,
+    hour TIMESTAMP NOT NULL,
+    cpu_core_hours REAL NOT NULL DEFAULT 0,
+    memory_gib_hours REAL NOT NULL DEFAULT 0,
+    egress_bytes REAL NOT NULL DEFAULT 0,
+    PRIMARY KEY (service_id, hour)
+);
+
+CREATE TABLE IF NOT EXISTS artifacts (
+    id TEXT PRIMARY KEY,
+    build_id TEXT NOT NULL REFERENCES builds(id) ON DELETE CASCADE,
+    service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    content_type TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    sha256 TEXT NOT NULL,
+    storage_key TEXT NOT NULL,
+    uploaded_by TEXT,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (build_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    link TEXT NOT NULL DEFAULT '',
+    project_id TEXT REFERENCES projects(id) ON DELETE CASCADE,
+    service_id TEXT REFERENCES services(id) ON DELETE CASCADE,
+    data TEXT NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    id TEXT PRIMARY KEY,
+    user_id TEXT,
+    project_id TEXT REFERENCES projects(id) ON DELETE CASCADE,
+    channel TEXT NOT NULL,
+    events TEXT NOT NULL DEFAULT '[]',
+    severities TEXT NOT NULL DEFAULT '[]',
+    muted INTEGER NOT NULL DEFAULT 0,
+    mute TEXT NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    CHECK (user_id IS NOT NULL OR project_id IS NOT NULL)
+);
+
+CREATE TABLE IF NOT EXISTS approvals (
+    id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    service_id TEXT NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    environment_id TEXT NOT NULL REFERENCES environments(id) ON DELETE CASCADE,
+    workflow_id TEXT NOT NULL,
+    version TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'pending',
+    approver_id TEXT NOT NULL,
+    escalated_at TIMESTAMP,
+    decided_by TEXT,
+    decided_at TIMESTAMP,
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS approval_delegations (
+    id TEXT PRIMARY KEY,
+    delegator_id TEXT NOT NULL,
+    delegate_id TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    CHECK (ends_at > starts_at),
+    CHECK (delegate_id <> delegator_id)
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    resource_type TEXT NOT NULL,
+    resource_id TEXT NOT NULL,
+    resource_name TEXT NOT NULL DEFAULT '',
+    project_id TEXT,
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    old_value TEXT,
+    new_value TEXT,
+    metadata TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS secrets (
+    id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL DEFAULT 'opaque',
+    keys TEXT NOT NULL DEFAULT '[]',
+    vault_path TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    labels TEXT DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(project_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS secret_replications (
+    id TEXT PRIMARY KEY,
+    secret_id TEXT NOT NULL REFERENCES secrets(id) ON DELETE CASCADE,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    cluster_id TEXT NOT NULL REFERENCES clusters(id) ON DELETE CASCADE,
+    namespace TEXT NOT NULL,
+    secret_name TEXT NOT NULL,
+    desired_version INTEGER NOT NULL DEFAULT 0,
+    synced_version INTEGER NOT NULL DEFAULT 0,
+    status TEXT NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    desired_since TIMESTAMP NOT NULL,
+    last_synced_at TIMESTAMP,
+    checked_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(secret_id, cluster_id, namespace)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    event_types TEXT NOT NULL DEFAULT '[]',
+    secret TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    active INTEGER NOT NULL DEFAULT 1,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id TEXT PRIMARY KEY,
+    subscription_id TEXT NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status TEXT NOT NULL,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    test INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    payload BLOB,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS templates (
+    id TEXT PRIMARY KEY,
+    slug TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    category TEXT NOT NULL DEFAULT '',
+    tags TEXT NOT NULL DEFAULT '[]',
+    manifest TEXT NOT NULL,
+    variables TEXT NOT NULL DEFAULT '[]',
+    created_by TEXT,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS teams (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    slug TEXT NOT NULL UNIQUE,
+    description TEXT,
+    owner_id TEXT NOT NULL,
+    labels TEXT DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS team_memberships (
+    id TEXT PRIMARY KEY,
+    team_id TEXT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'member',
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE(team_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS custom_domains (
+    id TEXT PRIMARY KEY,
+    project_id TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    method TEXT NOT NULL,
+    token TEXT NOT NULL,
+    status TEXT NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    verified_at TIMESTAMP,
+    checked_at TIMESTAMP,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE(project_id, name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_projects_owner_id ON projects(owner_id);
+CREATE INDEX IF NOT EXISTS idx_services_project_id ON services(project_id);
+CREATE INDEX IF NOT EXISTS idx_builds_service_created_at ON builds(service_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_project_created_at ON builds(project_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_builds_status ON builds(status);
+CREATE INDEX IF NOT EXISTS idx_deployments_service_created_at ON deployments(service_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_deployments_cluster_created_at ON deployments(cluster_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_environments_project_id ON environments(project_id);
+CREATE INDEX IF NOT EXISTS idx_environments_cluster_id ON environments(cluster_id);
+CREATE INDEX IF NOT EXISTS idx_ingresses_service_id ON ingresses(service_id);
+CREATE INDEX IF NOT EXISTS idx_ingresses_project_id ON ingresses(project_id);
+CREATE INDEX IF NOT EXISTS idx_alerts_fingerprint_status ON alerts(fingerprint, status);
+CREATE INDEX IF NOT EXISTS idx_alerts_starts_at ON alerts(starts_at DESC);
+CREATE INDEX IF NOT EXISTS idx_probe_results_ingress_checked_at ON probe_results(ingress_id, checked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_probe_results_checked_at ON probe_results(checked_at);
+CREATE INDEX IF NOT EXISTS idx_usage_records_project_hour ON usage_records(project_id, hour);
+CREATE INDEX IF NOT EXISTS idx_artifacts_service_created_at ON artifacts(service_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_artifacts_expires_at ON artifacts(expires_at);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_created_at ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_preferences_scope_channel ON notification_preferences(IFNULL(user_id, ''), IFNULL(project_id, ''), channel);
+CREATE INDEX IF NOT EXISTS idx_notification_preferences_user_id ON notification_preferences(user_id);
+CREATE INDEX IF NOT EXISTS idx_notification_preferences_project_id ON notification_preferences(project_id) WHERE user_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_approvals_workflow_id ON approvals(workflow_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_approvals_approver_id ON approvals(approver_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_approvals_project_id ON approvals(project_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_approvals_pending ON approvals(created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegator_id ON approval_delegations(delegator_id);
+CREATE INDEX IF NOT EXISTS idx_approval_delegations_delegate_id ON approval_delegations(delegate_id, ends_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs(user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_resource_type_id ON audit_logs(resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_project_id ON audit_logs(project_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_secrets_project_id ON secrets(project_id);
+CREATE INDEX IF NOT EXISTS idx_secret_replications_project_id ON secret_replications(project_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_project_id ON webhook_subscriptions(project_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_created_at ON webhook_deliveries(subscription_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_pending ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_team_memberships_user_id ON team_memberships(user_id);
+CREATE INDEX IF NOT EXISTS idx_custom_domains_name ON custom_domains(name);
+CREATE INDEX IF NOT EXISTS idx_custom_domains_status ON custom_domains(status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_domains_verified_name ON custom_domains(name) WHERE status = 'verified';
+`
@@ -0,0 +1,130 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDB opens a migrated in-memory database
+func testDB(t *testing.T) *DB {
+	ctx := context.Background()
+	db, err := Open(ctx, &config.DatabaseConfig{Path: ":memory:"}, logger.New("error", "json", io.Discard))
+	require.NoError(t, err)
+	t.Cleanup(db.Close)
+	require.NoError(t, db.Migrate(ctx))
+	return db
+}
+
+// testNow is the current time at the precision timestamps round-trip with
+func testNow() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func testProject(t *testing.T, db *DB) *domain.Project {
+	t.Helper()
+	now := testNow()
+	project := &domain.Project{
+		ID:        uuid.New(),
+		Name:      "Shop",
+		Slug:      "shop-" + uuid.NewString()[:8],
+		Status:    domain.ProjectStatusActive,
+		OwnerID:   uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, NewProjectRepository(db).Create(context.Background(), project))
+	return project
+}
+
+func testService(t *testing.T, db *DB, projectID uuid.UUID) *domain.Service {
+	t.Helper()
+	now := testNow()
+	service := &domain.Service{
+		ID:        uuid.New(),
+		ProjectID: projectID,
+		Name:      "API",
+		Slug:      "api-" + uuid.NewString()[:8],
+		Type:      domain.ServiceTypeWebApp,
+		Status:    domain.ServiceStatusRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, NewServiceRepository(db).Create(context.Background(), service))
+	return service
+}
+
+func testCluster(t *testing.T, db *DB) *domain.Cluster {
+	t.Helper()
+	now := testNow()
+	cluster := &domain.Cluster{
+		ID:        uuid.New(),
+		Name:      "Primary",
+		Slug:      "primary-" + uuid.NewString()[:8],
+		Provider:  domain.ClusterProviderAWS,
+		Region:    "eu-west-1",
+		Status:    domain.ClusterStatusActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, NewClusterRepository(db).Create(context.Background(), cluster))
+	return cluster
+}
+
+func testBuild(t *testing.T, db *DB, service *domain.Service) *domain.Build {
+	t.Helper()
+	build := &domain.Build{
+		ID:          uuid.New(),
+		ServiceID:   service.ID,
+		ProjectID:   service.ProjectID,
+		Status:      domain.BuildStatusSucceeded,
+		TriggeredBy: "push",
+		CreatedAt:   testNow(),
+	}
+	require.NoError(t, NewBuildRepository(db).Create(context.Background(), build))
+	return build
+}
+
+// cursorOf is the keyset cursor that continues a listing after the given row
+func cursorOf(createdAt time.Time, id uuid.UUID) *domain.Cursor {
+	return &domain.Cursor{CreatedAt: createdAt, ID: id}
+}
+
+func TestMigrate(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+
+	require.NoError(t, db.Migrate(ctx), "migrations can be run again")
+	require.NoError(t, db.Health(ctx))
+
+	rows, err := db.query(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name`)
+	require.NoError(t, err)
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		tables = append(tables, name)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{
+		"alerts", "approval_delegations", "approvals", "artifacts", "audit_logs", "builds", "clusters", "custom_domains", "deployments",
+		"environments", "ingresses", "notification_preferences", "notifications", "probe_results",
+		"projects", "secret_replications", "secrets", "services", "team_memberships", "teams",
+		"templates", "usage_records", "webhook_deliveries", "webhook_subscriptions",
+	}, tables)
+
+	// Foreign keys are enforced, so rows cannot outlive their project
+	orphan := &domain.Service{ID: uuid.New(), ProjectID: uuid.New(), Name: "orphan", Slug: "orphan", Type: domain.ServiceTypeWorker, CreatedAt: testNow(), UpdatedAt: testNow()}
+	assert.Error(t, NewServiceRepository(db).Create(ctx, orphan))
+}
@@ -0,0 +1,80 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewTeamRepository(db)
+	ctx := context.Background()
+
+	owner, member := uuid.New(), uuid.New()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	team := &domain.Team{
+		ID:        uuid.New(),
+		Name:      "Payments",
+		Slug:      "payments",
+		OwnerID:   owner,
+		Labels:    map[string]string{"cost-center": "42"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, repo.Create(ctx, team))
+
+	duplicate := *team
+	duplicate.ID = uuid.New()
+	assert.Error(t, repo.Create(ctx, &duplicate), "slugs are unique")
+
+	got, err := repo.GetBySlug(ctx, team.Slug)
+	require.NoError(t, err)
+	assert.Equal(t, team.ID, got.ID)
+	assert.Equal(t, team.Labels, got.Labels)
+	assert.Empty(t, got.Description)
+
+	analytics := &domain.Team{ID: uuid.New(), Name: "Analytics", Slug: "analytics", OwnerID: owner, CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, repo.Create(ctx, analytics))
+	page, err := repo.List(ctx, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, team.ID, page[0].ID, "teams are listed by name")
+
+	team.Description = "Card processing"
+	require.NoError(t, repo.Update(ctx, team))
+	got, err = repo.GetByID(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Card processing", got.Description)
+
+	membership := &domain.TeamMembership{ID: uuid.New(), TeamID: team.ID, UserID: member, Role: domain.UserRoleMember, CreatedAt: now}
+	require.NoError(t, repo.AddMember(ctx, membership))
+	membership.ID, membership.Role = uuid.New(), domain.UserRoleAdmin
+	require.NoError(t, repo.AddMember(ctx, membership), "adding a member again changes their role")
+
+	members, err := repo.GetMembers(ctx, team.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, member, members[0].UserID)
+	assert.Equal(t, domain.UserRoleAdmin, members[0].Role)
+
+	teams, err := repo.GetUserTeams(ctx, member)
+	require.NoError(t, err)
+	require.Len(t, teams, 1)
+	assert.Equal(t, team.ID, teams[0].ID)
+
+	require.NoError(t, repo.RemoveMember(ctx, team.ID, member))
+	assert.True(t, errors.IsNotFound(repo.RemoveMember(ctx, team.ID, member)))
+
+	require.NoError(t, repo.Delete(ctx, team.ID))
+	_, err = repo.GetByID(ctx, team.ID)
+	assert.True(t, errors.IsNotFound(err))
+}
@@ -0,0 +1,182 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// TemplateRepository implements domain.TemplateRepository using SQLite
+type TemplateRepository struct {
+	db *DB
+}
+
+// NewTemplateRepository creates a new TemplateRepository
+func NewTemplateRepository(db *DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+const templateColumns = `id, slug, name, description, category, tags, manifest, variables, created_by, created_at, updated_at`
+
+// Create creates a new template
+func (r *TemplateRepository) Create(ctx context.Context, template *domain.Template) error {
+
+	query := `
+		INSERT INTO templates (` + templateColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		template.ID,
+		template.Slug,
+		template.Name,
+		template.Description,
+		template.Category,
+		jsonText(template.Tags),
+		template.Manifest,
+		jsonText(template.Variables),
+		template.CreatedBy,
+		template.CreatedAt,
+		template.UpdatedAt,
+	)
+
+	if isUniqueViolation(err) {
+		return errors.Conflict("template " + template.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create template")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a template by ID
+func (r *TemplateRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates WHERE id = ?`
+
+	template, err := scanTemplate(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("template", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get template")
+	}
+
+	return template, nil
+}
+
+// GetBySlug retrieves a template by slug
+func (r *TemplateRepository) GetBySlug(ctx context.Context, slug string) (*domain.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates WHERE slug = ?`
+
+	template, err := scanTemplate(r.db.queryRow(ctx, query, slug))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("template", slug)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get template")
+	}
+
+	return template, nil
+}
+
+// List retrieves all templates by name
+func (r *TemplateRepository) List(ctx context.Context) ([]*domain.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates ORDER BY name`
+
+	rows, err := r.db.query(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list templates")
+	}
+	defer rows.Close()
+
+	templates := []*domain.Template{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan template")
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, rows.Err()
+}
+
+// Update updates an existing template
+func (r *TemplateRepository) Update(ctx context.Context, template *domain.Template) error {
+	template.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE templates
+		SET name = ?, description = ?, category = ?, tags = ?, manifest = ?, variables = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		template.Name,
+		template.Description,
+		template.Category,
+		jsonText(template.Tags),
+		template.Manifest,
+		jsonText(template.Variables),
+		template.UpdatedAt,
+		template.ID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update template")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("template", template.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a template
+func (r *TemplateRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM templates WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete template")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("template", id.String())
+	}
+
+	return nil
+}
+
+func scanTemplate(row scanner) (*domain.Template, error) {
+	template := &domain.Template{}
+	var tags, variables []byte
+
+	err := row.Scan(
+		&template.ID,
+		&template.Slug,
+		&template.Name,
+		&template.Description,
+		&template.Category,
+		&tags,
+		&template.Manifest,
+		&variables,
+		&template.CreatedBy,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(tags, &template.Tags)
+	json.Unmarshal(variables, &template.Variables)
+
+	return template, nil
+}
@@ -0,0 +1,75 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewTemplateRepository(db)
+	ctx := context.Background()
+
+	author := uuid.New()
+	now := testNow()
+	template := &domain.Template{
+		ID:          uuid.New(),
+		Slug:        "wordpress",
+		Name:        "WordPress",
+		Description: "WordPress with MySQL",
+		Category:    "cms",
+		Tags:        []string{"php", "mysql"},
+		Manifest:    "services:\n  - name: wordpress\n",
+		Variables:   []domain.TemplateVariable{{Name: "DB_PASSWORD", Required: true, Generate: true}, {Name: "SITE_NAME", Default: "blog"}},
+		CreatedBy:   &author,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	require.NoError(t, repo.Create(ctx, template))
+
+	duplicate := *template
+	duplicate.ID = uuid.New()
+	var appErr *errors.AppError
+	require.ErrorAs(t, repo.Create(ctx, &duplicate), &appErr)
+	assert.Equal(t, errors.CodeConflict, appErr.Code, "slugs are unique")
+
+	got, err := repo.GetByID(ctx, template.ID)
+	require.NoError(t, err)
+	assert.Equal(t, template, got)
+
+	got, err = repo.GetBySlug(ctx, "wordpress")
+	require.NoError(t, err)
+	assert.Equal(t, template.ID, got.ID)
+	_, err = repo.GetBySlug(ctx, "missing")
+	assert.True(t, errors.IsNotFound(err))
+
+	require.NoError(t, repo.Create(ctx, &domain.Template{ID: uuid.New(), Slug: "ghost", Name: "Ghost", Manifest: "services: []\n", CreatedAt: now, UpdatedAt: now}))
+	templates, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, templates, 2)
+	assert.Equal(t, "Ghost", templates[0].Name, "templates are listed by name")
+	assert.Nil(t, templates[0].CreatedBy)
+	assert.Nil(t, templates[0].Tags, "nil lists round-trip as nil, as in Postgres")
+
+	template.Tags = []string{"php"}
+	template.Variables = nil
+	require.NoError(t, repo.Update(ctx, template))
+	got, err = repo.GetByID(ctx, template.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"php"}, got.Tags)
+	assert.Empty(t, got.Variables)
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.Template{ID: uuid.New()})))
+
+	require.NoError(t, repo.Delete(ctx, template.ID))
+	_, err = repo.GetByID(ctx, template.ID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, template.ID)))
+}
@@ -0,0 +1,107 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// sqliteTimeLayout is the layout the driver writes timestamps in; aggregates
+// such as MAX lose the column type and come back as text
+const sqliteTimeLayout = "2006-01-02 15:04:05.999999999-07:00"
+
+// UsageRepository implements domain.UsageRepository using SQLite
+type UsageRepository struct {
+	db *DB
+}
+
+// NewUsageRepository creates a new UsageRepository
+func NewUsageRepository(db *DB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// Upsert records the usage of a service for an hour, replacing any previous rollup
+func (r *UsageRepository) Upsert(ctx context.Context, record *domain.UsageRecord) error {
+	query := `
+		INSERT INTO usage_records (service_id, project_id, hour, cpu_core_hours, memory_gib_hours, egress_bytes)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (service_id, hour) DO UPDATE
+		SET cpu_core_hours = excluded.cpu_core_hours,
+		    memory_gib_hours = excluded.memory_gib_hours,
+		    egress_bytes = excluded.egress_bytes
+	`
+
+	_, err := r.db.exec(ctx, query,
+		record.ServiceID,
+		record.ProjectID,
+		record.Hour,
+		record.CPUCoreHours,
+		record.MemoryGiBHours,
+		record.EgressBytes,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert usage record")
+	}
+
+	return nil
+}
+
+// ListByProject retrieves the usage records of a project with hours in [from, to)
+func (r *UsageRepository) ListByProject(ctx context.Context, projectID uuid.UUID, from, to time.Time) ([]*domain.UsageRecord, error) {
+	query := `
+		SELECT service_id, project_id, hour, cpu_core_hours, memory_gib_hours, egress_bytes
+		FROM usage_records
+		WHERE project_id = ? AND hour >= ? AND hour < ?
+		ORDER BY hour
+	`
+
+	rows, err := r.db.query(ctx, query, projectID, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list usage records")
+	}
+	defer rows.Close()
+
+	records := []*domain.UsageRecord{}
+	for rows.Next() {
+		record := &domain.UsageRecord{}
+		err := rows.Scan(
+			&record.ServiceID,
+			&record.ProjectID,
+			&record.Hour,
+			&record.CPUCoreHours,
+			&record.MemoryGiBHours,
+			&record.EgressBytes,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan usage record")
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// LatestHour returns the most recent hour that has been rolled up, or nil if none
+func (r *UsageRepository) LatestHour(ctx context.Context) (*time.Time, error) {
+	var latest sql.NullString
+	if err := r.db.queryRow(ctx, `SELECT MAX(hour) FROM usage_records`).Scan(&latest); err != nil {
+		return nil, errors.Wrap(err, "failed to get latest usage hour")
+	}
+	if !latest.Valid {
+		return nil, nil
+	}
+
+	hour, err := time.Parse(sqliteTimeLayout, latest.String)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse latest usage hour")
+	}
+
+	return &hour, nil
+}
@@ -0,0 +1,71 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewUsageRepository(db)
+	ctx := context.Background()
+
+	latest, err := repo.LatestHour(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, latest, "nothing has been rolled up yet")
+
+	projectID, serviceID := uuid.New(), uuid.New()
+	hour := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, repo.Upsert(ctx, &domain.UsageRecord{
+			ServiceID:      serviceID,
+			ProjectID:      projectID,
+			Hour:           hour.Add(time.Duration(i) * time.Hour),
+			CPUCoreHours:   0.5,
+			MemoryGiBHours: 1,
+		}))
+	}
+	require.NoError(t, repo.Upsert(ctx, &domain.UsageRecord{ServiceID: uuid.New(), ProjectID: uuid.New(), Hour: hour, CPUCoreHours: 2}))
+
+	// A later rollup of the same hour replaces the earlier one
+	require.NoError(t, repo.Upsert(ctx, &domain.UsageRecord{
+		ServiceID:      serviceID,
+		ProjectID:      projectID,
+		Hour:           hour.Add(time.Hour),
+		CPUCoreHours:   0.75,
+		MemoryGiBHours: 1.5,
+		EgressBytes:    1024,
+	}))
+
+	records, err := repo.ListByProject(ctx, projectID, hour, hour.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, records, 2, "the end of the range is exclusive")
+	assert.Equal(t, hour, records[0].Hour)
+	assert.Equal(t, &domain.UsageRecord{
+		ServiceID:      serviceID,
+		ProjectID:      projectID,
+		Hour:           hour.Add(time.Hour),
+		CPUCoreHours:   0.75,
+		MemoryGiBHours: 1.5,
+		EgressBytes:    1024,
+	}, records[1])
+
+	// Hours are stored in UTC whatever the caller's zone
+	local := hour.In(time.FixedZone("CET", 3600))
+	records, err = repo.ListByProject(ctx, projectID, local, local.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	latest, err = repo.LatestHour(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	assert.True(t, hour.Add(2*time.Hour).Equal(*latest))
+}
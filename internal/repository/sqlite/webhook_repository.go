@@ -0,0 +1,292 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// WebhookRepository implements domain.WebhookRepository using SQLite
+type WebhookRepository struct {
+	db *DB
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookColumns = `id, project_id, url, event_types, secret, description, active, created_by, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, attempt, status, response_status, error,
+	duration_ms, test, next_attempt_at, created_at`
+
+// Create creates a new webhook subscription
+func (r *WebhookRepository) Create(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (` + webhookColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := r.db.exec(ctx, query,
+		subscription.ID,
+		subscription.ProjectID,
+		subscription.URL,
+		jsonText(subscription.EventTypes),
+		subscription.Secret,
+		subscription.Description,
+		subscription.Active,
+		subscription.CreatedBy,
+		subscription.CreatedAt,
+		subscription.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create webhook subscription")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a webhook subscription by ID
+func (r *WebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE id = ?`
+
+	subscription, err := scanWebhook(r.db.queryRow(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.NotFound("webhook", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook subscription")
+	}
+
+	return subscription, nil
+}
+
+// ListByProject retrieves the webhook subscriptions of a project
+func (r *WebhookRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.WebhookSubscription, error) {
+	return r.list(ctx, `SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE project_id = ? ORDER BY created_at`, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's webhook subscriptions, newest first
+func (r *WebhookRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.WebhookSubscription, error) {
+	query, args := keyset(`SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE project_id = ?`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+func (r *WebhookRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.WebhookSubscription, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook subscriptions")
+	}
+	defer rows.Close()
+
+	subscriptions := []*domain.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhook(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan webhook subscription")
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, rows.Err()
+}
+
+// Update updates an existing webhook subscription
+func (r *WebhookRepository) Update(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	subscription.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = ?, event_types = ?, secret = ?, description = ?, active = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		subscription.URL,
+		jsonText(subscription.EventTypes),
+		subscription.Secret,
+		subscription.Description,
+		subscription.Active,
+		subscription.UpdatedAt,
+		subscription.ID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update webhook subscription")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("webhook", subscription.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a webhook subscription and its delivery log
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = ?`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete webhook subscription")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("webhook", id.String())
+	}
+
+	return nil
+}
+
+// CreateDelivery logs or queues a delivery attempt, leaving an existing attempt
+// with the same ID as it is
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (` + webhookDeliveryColumns + `, payload)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO NOTHING
+	`
+
+	_, err := r.db.exec(ctx, query,
+		delivery.ID,
+		delivery.SubscriptionID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Attempt,
+		delivery.Status,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.DurationMS,
+		delivery.Test,
+		delivery.NextAttemptAt,
+		delivery.CreatedAt,
+		delivery.Payload,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to log webhook delivery")
+	}
+
+	return nil
+}
+
+// UpdateDelivery records the outcome of a queued attempt; its body is dropped
+// once it is sent
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = ?, response_status = ?, error = ?, duration_ms = ?, next_attempt_at = ?, payload = NULL
+		WHERE id = ?
+	`
+
+	result, err := r.db.exec(ctx, query,
+		delivery.Status,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.DurationMS,
+		delivery.NextAttemptAt,
+		delivery.ID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update webhook delivery")
+	}
+
+	if !rowsAffected(result) {
+		return errors.NotFound("webhook delivery", delivery.ID.String())
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the latest delivery attempts of a subscription, newest first
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]*domain.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `, NULL FROM webhook_deliveries
+		WHERE subscription_id = ? ORDER BY created_at DESC LIMIT ?`
+	return r.listDeliveries(ctx, query, subscriptionID, limit)
+}
+
+// ListDueDeliveries returns queued attempts due at now, with their bodies, oldest first
+func (r *WebhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `, payload FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= ? ORDER BY next_attempt_at LIMIT ?`
+	return r.listDeliveries(ctx, query, now, limit)
+}
+
+// ClaimDelivery postpones a queued attempt due at now to until, and reports
+// whether this caller claimed it rather than another replica
+func (r *WebhookRepository) ClaimDelivery(ctx context.Context, id uuid.UUID, now, until time.Time) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id = ? AND status = 'pending' AND next_attempt_at <= ?
+	`
+
+	result, err := r.db.exec(ctx, query, until, id, now)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to claim webhook delivery")
+	}
+
+	return rowsAffected(result), nil
+}
+
+func (r *WebhookRepository) listDeliveries(ctx context.Context, query string, args ...interface{}) ([]*domain.WebhookDelivery, error) {
+	rows, err := r.db.query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook deliveries")
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery := &domain.WebhookDelivery{}
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Attempt,
+			&delivery.Status,
+			&delivery.ResponseStatus,
+			&delivery.Error,
+			&delivery.DurationMS,
+			&delivery.Test,
+			&delivery.NextAttemptAt,
+			&delivery.CreatedAt,
+			&delivery.Payload,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan webhook delivery")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(row scanner) (*domain.WebhookSubscription, error) {
+	subscription := &domain.WebhookSubscription{}
+	var eventTypes []byte
+
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.ProjectID,
+		&subscription.URL,
+		&eventTypes,
+		&subscription.Secret,
+		&subscription.Description,
+		&subscription.Active,
+		&subscription.CreatedBy,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(eventTypes, &subscription.EventTypes)
+
+	return subscription, nil
+}
@@ -0,0 +1,141 @@
+//go:build sqlite
+
+package sqlite
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewWebhookRepository(db)
+	ctx := context.Background()
+
+	project := testProject(t, db)
+	now := testNow()
+	subscription := &domain.WebhookSubscription{
+		ID:          uuid.New(),
+		ProjectID:   project.ID,
+		URL:         "https://hooks.example.com/northstack",
+		EventTypes:  []string{"deploy.completed", "deploy.failed"},
+		Secret:      "whsec_0123456789",
+		Description: "Deploy notifications",
+		Active:      true,
+		CreatedBy:   uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	require.NoError(t, repo.Create(ctx, subscription))
+
+	got, err := repo.GetByID(ctx, subscription.ID)
+	require.NoError(t, err)
+	assert.Equal(t, subscription, got)
+	_, err = repo.GetByID(ctx, uuid.New())
+	assert.True(t, errors.IsNotFound(err))
+
+	catchAll := &domain.WebhookSubscription{ID: uuid.New(), ProjectID: project.ID, URL: "https://audit.example.com", EventTypes: []string{"*"}, Secret: "whsec_abc", CreatedBy: uuid.New(), CreatedAt: now.Add(time.Second), UpdatedAt: now}
+	require.NoError(t, repo.Create(ctx, catchAll))
+	subscriptions, err := repo.ListByProject(ctx, project.ID)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+	assert.Equal(t, subscription.ID, subscriptions[0].ID, "oldest first")
+	assert.False(t, subscriptions[1].Active)
+
+	page, err := repo.ListByProjectAfter(ctx, project.ID, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, catchAll.ID, page[0].ID, "pages are newest first")
+	page, err = repo.ListByProjectAfter(ctx, project.ID, cursorOf(page[0].CreatedAt, page[0].ID), 10)
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, subscription.ID, page[0].ID)
+
+	subscription.EventTypes = []string{"*"}
+	subscription.Active = false
+	require.NoError(t, repo.Update(ctx, subscription))
+	got, err = repo.GetByID(ctx, subscription.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, got.EventTypes)
+	assert.False(t, got.Active)
+	assert.True(t, errors.IsNotFound(repo.Update(ctx, &domain.WebhookSubscription{ID: uuid.New()})))
+
+	var deliveries []*domain.WebhookDelivery
+	for i, status := range []domain.WebhookDeliveryStatus{domain.WebhookDeliveryFailed, domain.WebhookDeliveryFailed, domain.WebhookDeliverySucceeded} {
+		delivery := &domain.WebhookDelivery{
+			ID:             uuid.New(),
+			SubscriptionID: subscription.ID,
+			EventID:        "evt_1",
+			EventType:      "deploy.completed",
+			Attempt:        i + 1,
+			Status:         status,
+			ResponseStatus: 502,
+			Error:          "bad gateway",
+			DurationMS:     120,
+			CreatedAt:      now.Add(time.Duration(i) * time.Minute),
+		}
+		if status == domain.WebhookDeliverySucceeded {
+			delivery.ResponseStatus, delivery.Error = 200, ""
+		}
+		require.NoError(t, repo.CreateDelivery(ctx, delivery))
+		deliveries = append(deliveries, delivery)
+	}
+	require.NoError(t, repo.CreateDelivery(ctx, &domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: catchAll.ID, EventID: "evt_2", EventType: "ping", Attempt: 1, Status: domain.WebhookDeliverySucceeded, Test: true, CreatedAt: now}))
+
+	latest, err := repo.ListDeliveries(ctx, subscription.ID, 2)
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+	assert.Equal(t, deliveries[2], latest[0], "newest first")
+	assert.Equal(t, deliveries[1].ID, latest[1].ID)
+
+	// Pending attempts wait in the queue until due and are claimed once
+	due := now.Add(time.Minute)
+	pending := &domain.WebhookDelivery{ID: uuid.New(), SubscriptionID: catchAll.ID, EventID: "evt_3", EventType: "deploy.failed", Attempt: 1, Status: domain.WebhookDeliveryPending, NextAttemptAt: &due, Payload: []byte(`{"id":"evt_3"}`), CreatedAt: now.Add(time.Second)}
+	require.NoError(t, repo.CreateDelivery(ctx, pending))
+	duplicate := *pending
+	duplicate.EventID = "evt_4"
+	require.NoError(t, repo.CreateDelivery(ctx, &duplicate), "attempts are created once")
+
+	queued, err := repo.ListDueDeliveries(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, queued, "not due yet")
+	queued, err = repo.ListDueDeliveries(ctx, due, 10)
+	require.NoError(t, err)
+	require.Len(t, queued, 1)
+	assert.Equal(t, pending, queued[0])
+
+	claimed, err := repo.ClaimDelivery(ctx, pending.ID, due, due.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = repo.ClaimDelivery(ctx, pending.ID, due, due.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed, "a claimed attempt is not due until its lease ends")
+
+	pending.Status = domain.WebhookDeliverySucceeded
+	pending.ResponseStatus = 204
+	pending.NextAttemptAt = nil
+	require.NoError(t, repo.UpdateDelivery(ctx, pending))
+	sent, err := repo.ListDeliveries(ctx, catchAll.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, sent, 1)
+	assert.Equal(t, domain.WebhookDeliverySucceeded, sent[0].Status)
+	assert.Nil(t, sent[0].Payload)
+	queued, err = repo.ListDueDeliveries(ctx, due.Add(time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, queued)
+	assert.True(t, errors.IsNotFound(repo.UpdateDelivery(ctx, &domain.WebhookDelivery{ID: uuid.New()})))
+
+	// Deleting a subscription drops its delivery log
+	require.NoError(t, repo.Delete(ctx, subscription.ID))
+	assert.True(t, errors.IsNotFound(repo.Delete(ctx, subscription.ID)))
+	latest, err = repo.ListDeliveries(ctx, subscription.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, latest)
+}
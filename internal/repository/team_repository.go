@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// TeamRepository implements domain.TeamRepository using PostgreSQL
+type TeamRepository struct {
+	db *PostgresDB
+}
+
+// NewTeamRepository creates a new TeamRepository
+func NewTeamRepository(db *PostgresDB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+const teamColumns = `id, name, slug, COALESCE(description, ''), owner_id, labels, created_at, updated_at`
+
+// Create creates a new team
+func (r *TeamRepository) Create(ctx context.Context, team *domain.Team) error {
+	labels, _ := json.Marshal(team.Labels)
+
+	query := `
+		INSERT INTO teams (id, name, slug, description, owner_id, labels, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		team.ID,
+		team.Name,
+		team.Slug,
+		team.Description,
+		team.OwnerID,
+		labels,
+		team.CreatedAt,
+		team.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("team " + team.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create team")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a team by ID
+func (r *TeamRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Team, error) {
+	query := `SELECT ` + teamColumns + ` FROM teams WHERE id = $1`
+
+	team, err := scanTeam(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("team", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get team")
+	}
+
+	return team, nil
+}
+
+// GetBySlug retrieves a team by slug
+func (r *TeamRepository) GetBySlug(ctx context.Context, slug string) (*domain.Team, error) {
+	query := `SELECT ` + teamColumns + ` FROM teams WHERE slug = $1`
+
+	team, err := scanTeam(r.db.pool.QueryRow(ctx, query, slug))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("team", slug)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get team")
+	}
+
+	return team, nil
+}
+
+// List retrieves teams by name
+func (r *TeamRepository) List(ctx context.Context, limit, offset int) ([]*domain.Team, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `SELECT ` + teamColumns + ` FROM teams ORDER BY name, id LIMIT $1 OFFSET $2`
+
+	return r.list(ctx, query, limit, offset)
+}
+
+// Update updates an existing team
+func (r *TeamRepository) Update(ctx context.Context, team *domain.Team) error {
+	labels, _ := json.Marshal(team.Labels)
+	team.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE teams
+		SET name = $2, slug = $3, description = $4, owner_id = $5, labels = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		team.ID,
+		team.Name,
+		team.Slug,
+		team.Description,
+		team.OwnerID,
+		labels,
+		team.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("team " + team.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to update team")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("team", team.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a team with its memberships
+func (r *TeamRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM teams WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete team")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("team", id.String())
+	}
+
+	return nil
+}
+
+// AddMember adds a user to a team, or changes their role if they already
+// are a member
+func (r *TeamRepository) AddMember(ctx context.Context, membership *domain.TeamMembership) error {
+	query := `
+		INSERT INTO team_memberships (id, team_id, user_id, role, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		membership.ID,
+		membership.TeamID,
+		membership.UserID,
+		membership.Role,
+		membership.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to add team member")
+	}
+
+	return nil
+}
+
+// RemoveMember removes a user from a team
+func (r *TeamRepository) RemoveMember(ctx context.Context, teamID, userID uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM team_memberships WHERE team_id = $1 AND user_id = $2`, teamID, userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove team member")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("team member", userID.String())
+	}
+
+	return nil
+}
+
+// GetMembers retrieves the memberships of a team, oldest first
+func (r *TeamRepository) GetMembers(ctx context.Context, teamID uuid.UUID) ([]*domain.TeamMembership, error) {
+	query := `
+		SELECT id, team_id, user_id, role, created_at
+		FROM team_memberships
+		WHERE team_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.pool.Query(ctx, query, teamID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list team members")
+	}
+	defer rows.Close()
+
+	memberships := []*domain.TeamMembership{}
+	for rows.Next() {
+		membership := &domain.TeamMembership{}
+		if err := rows.Scan(
+			&membership.ID,
+			&membership.TeamID,
+			&membership.UserID,
+			&membership.Role,
+			&membership.CreatedAt,
+		); err != nil {
+			return nil, errors.Wrap(err, "failed to scan team member")
+		}
+		memberships = append(memberships, membership)
+	}
+
+	return memberships, nil
+}
+
+// GetUserTeams retrieves the teams a user is a member of, by name
+func (r *TeamRepository) GetUserTeams(ctx context.Context, userID uuid.UUID) ([]*domain.Team, error) {
+	query := `
+		SELECT t.id, t.name, t.slug, COALESCE(t.description, ''), t.owner_id, t.labels, t.created_at, t.updated_at
+		FROM teams t
+		JOIN team_memberships m ON m.team_id = t.id
+		WHERE m.user_id = $1
+		ORDER BY t.name, t.id
+	`
+
+	return r.list(ctx, query, userID)
+}
+
+func (r *TeamRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.Team, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list teams")
+	}
+	defer rows.Close()
+
+	teams := []*domain.Team{}
+	for rows.Next() {
+		team, err := scanTeam(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan team")
+		}
+		teams = append(teams, team)
+	}
+
+	return teams, nil
+}
+
+func scanTeam(row pgx.Row) (*domain.Team, error) {
+	team := &domain.Team{}
+	var labels []byte
+
+	err := row.Scan(
+		&team.ID,
+		&team.Name,
+		&team.Slug,
+		&team.Description,
+		&team.OwnerID,
+		&labels,
+		&team.CreatedAt,
+		&team.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(labels, &team.Labels)
+
+	return team, nil
+}
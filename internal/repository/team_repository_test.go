@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDB connects to the PostgreSQL database named by TEST_DATABASE_URL and
+// migrates it, skipping the test when there is none
+func testDB(t *testing.T) *PostgresDB {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, url)
+	require.NoError(t, err)
+	db := &PostgresDB{pool: pool, logger: logger.New("error", "json", io.Discard)}
+	t.Cleanup(db.Close)
+	require.NoError(t, db.Migrate(ctx))
+	return db
+}
+
+// testUser inserts a user for teams to reference
+func testUser(t *testing.T, db *PostgresDB) uuid.UUID {
+	id := uuid.New()
+	_, err := db.pool.Exec(context.Background(), `INSERT INTO users (id, email, name) VALUES ($1, $2, $3)`, id, id.String()+"@example.com", "Test")
+	require.NoError(t, err)
+	return id
+}
+
+func TestTeamRepository(t *testing.T) {
+	db := testDB(t)
+	repo := NewTeamRepository(db)
+	ctx := context.Background()
+
+	owner, member := testUser(t, db), testUser(t, db)
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	team := &domain.Team{
+		ID:        uuid.New(),
+		Name:      "Payments",
+		Slug:      "payments-" + uuid.NewString()[:8],
+		OwnerID:   owner,
+		Labels:    map[string]string{"cost-center": "42"},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	require.NoError(t, repo.Create(ctx, team))
+	t.Cleanup(func() { repo.Delete(ctx, team.ID) })
+
+	duplicate := *team
+	duplicate.ID = uuid.New()
+	assert.Error(t, repo.Create(ctx, &duplicate), "slugs are unique")
+
+	got, err := repo.GetBySlug(ctx, team.Slug)
+	require.NoError(t, err)
+	assert.Equal(t, team.ID, got.ID)
+	assert.Equal(t, team.Labels, got.Labels)
+	assert.Empty(t, got.Description)
+
+	team.Description = "Card processing"
+	require.NoError(t, repo.Update(ctx, team))
+	got, err = repo.GetByID(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Card processing", got.Description)
+
+	membership := &domain.TeamMembership{ID: uuid.New(), TeamID: team.ID, UserID: member, Role: domain.UserRoleMember, CreatedAt: now}
+	require.NoError(t, repo.AddMember(ctx, membership))
+	membership.ID, membership.Role = uuid.New(), domain.UserRoleAdmin
+	require.NoError(t, repo.AddMember(ctx, membership), "adding a member again changes their role")
+
+	members, err := repo.GetMembers(ctx, team.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, member, members[0].UserID)
+	assert.Equal(t, domain.UserRoleAdmin, members[0].Role)
+
+	teams, err := repo.GetUserTeams(ctx, member)
+	require.NoError(t, err)
+	require.Len(t, teams, 1)
+	assert.Equal(t, team.ID, teams[0].ID)
+
+	require.NoError(t, repo.RemoveMember(ctx, team.ID, member))
+	assert.True(t, errors.IsNotFound(repo.RemoveMember(ctx, team.ID, member)))
+
+	require.NoError(t, repo.Delete(ctx, team.ID))
+	_, err = repo.GetByID(ctx, team.ID)
+	assert.True(t, errors.IsNotFound(err))
+}
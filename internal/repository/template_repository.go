@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// TemplateRepository implements domain.TemplateRepository using PostgreSQL
+type TemplateRepository struct {
+	db *PostgresDB
+}
+
+// NewTemplateRepository creates a new TemplateRepository
+func NewTemplateRepository(db *PostgresDB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+const templateColumns = `id, slug, name, description, category, tags, manifest, variables, created_by, created_at, updated_at`
+
+// Create creates a new template
+func (r *TemplateRepository) Create(ctx context.Context, template *domain.Template) error {
+	tags, _ := json.Marshal(template.Tags)
+	variables, _ := json.Marshal(template.Variables)
+
+	query := `
+		INSERT INTO templates (` + templateColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		template.ID,
+		template.Slug,
+		template.Name,
+		template.Description,
+		template.Category,
+		tags,
+		template.Manifest,
+		variables,
+		template.CreatedBy,
+		template.CreatedAt,
+		template.UpdatedAt,
+	)
+
+	var pgErr *pgconn.PgError
+	if stderrors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict("template " + template.Slug)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create template")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a template by ID
+func (r *TemplateRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates WHERE id = $1`
+
+	template, err := scanTemplate(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("template", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get template")
+	}
+
+	return template, nil
+}
+
+// GetBySlug retrieves a template by slug
+func (r *TemplateRepository) GetBySlug(ctx context.Context, slug string) (*domain.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates WHERE slug = $1`
+
+	template, err := scanTemplate(r.db.pool.QueryRow(ctx, query, slug))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("template", slug)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get template")
+	}
+
+	return template, nil
+}
+
+// List retrieves all templates by name
+func (r *TemplateRepository) List(ctx context.Context) ([]*domain.Template, error) {
+	query := `SELECT ` + templateColumns + ` FROM templates ORDER BY name`
+
+	rows, err := r.db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list templates")
+	}
+	defer rows.Close()
+
+	templates := []*domain.Template{}
+	for rows.Next() {
+		template, err := scanTemplate(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan template")
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, nil
+}
+
+// Update updates an existing template
+func (r *TemplateRepository) Update(ctx context.Context, template *domain.Template) error {
+	tags, _ := json.Marshal(template.Tags)
+	variables, _ := json.Marshal(template.Variables)
+	template.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE templates
+		SET name = $2, description = $3, category = $4, tags = $5, manifest = $6, variables = $7, updated_at = $8
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		template.ID,
+		template.Name,
+		template.Description,
+		template.Category,
+		tags,
+		template.Manifest,
+		variables,
+		template.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update template")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("template", template.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a template
+func (r *TemplateRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM templates WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete template")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("template", id.String())
+	}
+
+	return nil
+}
+
+func scanTemplate(row pgx.Row) (*domain.Template, error) {
+	template := &domain.Template{}
+	var tags, variables []byte
+
+	err := row.Scan(
+		&template.ID,
+		&template.Slug,
+		&template.Name,
+		&template.Description,
+		&template.Category,
+		&tags,
+		&template.Manifest,
+		&variables,
+		&template.CreatedBy,
+		&template.CreatedAt,
+		&template.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(tags, &template.Tags)
+	json.Unmarshal(variables, &template.Variables)
+
+	return template, nil
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// UsageRepository implements domain.UsageRepository using PostgreSQL
+type UsageRepository struct {
+	db *PostgresDB
+}
+
+// NewUsageRepository creates a new UsageRepository
+func NewUsageRepository(db *PostgresDB) *UsageRepository {
+	return &UsageRepository{db: db}
+}
+
+// Upsert records the usage of a service for an hour, replacing any previous rollup
+func (r *UsageRepository) Upsert(ctx context.Context, record *domain.UsageRecord) error {
+	query := `
+		INSERT INTO usage_records (service_id, project_id, hour, cpu_core_hours, memory_gib_hours, egress_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (service_id, hour) DO UPDATE
+		SET cpu_core_hours = EXCLUDED.cpu_core_hours,
+		    memory_gib_hours = EXCLUDED.memory_gib_hours,
+		    egress_bytes = EXCLUDED.egress_bytes
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		record.ServiceID,
+		record.ProjectID,
+		record.Hour,
+		record.CPUCoreHours,
+		record.MemoryGiBHours,
+		record.EgressBytes,
+	)
+
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert usage record")
+	}
+
+	return nil
+}
+
+// ListByProject retrieves the usage records of a project with hours in [from, to)
+func (r *UsageRepository) ListByProject(ctx context.Context, projectID uuid.UUID, from, to time.Time) ([]*domain.UsageRecord, error) {
+	query := `
+		SELECT service_id, project_id, hour, cpu_core_hours, memory_gib_hours, egress_bytes
+		FROM usage_records
+		WHERE project_id = $1 AND hour >= $2 AND hour < $3
+		ORDER BY hour
+	`
+
+	rows, err := r.db.pool.Query(ctx, query, projectID, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list usage records")
+	}
+	defer rows.Close()
+
+	records := []*domain.UsageRecord{}
+	for rows.Next() {
+		record := &domain.UsageRecord{}
+		err := rows.Scan(
+			&record.ServiceID,
+			&record.ProjectID,
+			&record.Hour,
+			&record.CPUCoreHours,
+			&record.MemoryGiBHours,
+			&record.EgressBytes,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan usage record")
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// LatestHour returns the most recent hour that has been rolled up, or nil if none
+func (r *UsageRepository) LatestHour(ctx context.Context) (*time.Time, error) {
+	var hour *time.Time
+	if err := r.db.pool.QueryRow(ctx, `SELECT MAX(hour) FROM usage_records`).Scan(&hour); err != nil {
+		return nil, errors.Wrap(err, "failed to get latest usage hour")
+	}
+
+	return hour, nil
+}
@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// WebhookRepository implements domain.WebhookRepository using PostgreSQL
+type WebhookRepository struct {
+	db *PostgresDB
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *PostgresDB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+const webhookColumns = `id, project_id, url, event_types, secret, description, active, created_by, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, attempt, status, response_status, error,
+	duration_ms, test, next_attempt_at, created_at`
+
+// Create creates a new webhook subscription
+func (r *WebhookRepository) Create(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	eventTypes, _ := json.Marshal(subscription.EventTypes)
+
+	query := `
+		INSERT INTO webhook_subscriptions (` + webhookColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		subscription.ID,
+		subscription.ProjectID,
+		subscription.URL,
+		eventTypes,
+		subscription.Secret,
+		subscription.Description,
+		subscription.Active,
+		subscription.CreatedBy,
+		subscription.CreatedAt,
+		subscription.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create webhook subscription")
+	}
+
+	return nil
+}
+
+// GetByID retrieves a webhook subscription by ID
+func (r *WebhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhook_subscriptions WHERE id = $1`
+
+	subscription, err := scanWebhook(r.db.pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, errors.NotFound("webhook", id.String())
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get webhook subscription")
+	}
+
+	return subscription, nil
+}
+
+// ListByProject retrieves the webhook subscriptions of a project
+func (r *WebhookRepository) ListByProject(ctx context.Context, projectID uuid.UUID) ([]*domain.WebhookSubscription, error) {
+	return r.list(ctx, `SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE project_id = $1 ORDER BY created_at`, projectID)
+}
+
+// ListByProjectAfter retrieves a page of a project's webhook subscriptions, newest first
+func (r *WebhookRepository) ListByProjectAfter(ctx context.Context, projectID uuid.UUID, after *domain.Cursor, limit int) ([]*domain.WebhookSubscription, error) {
+	query, args := keyset(`SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE project_id = $1`, []interface{}{projectID}, after, limit)
+	return r.list(ctx, query, args...)
+}
+
+func (r *WebhookRepository) list(ctx context.Context, query string, args ...interface{}) ([]*domain.WebhookSubscription, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook subscriptions")
+	}
+	defer rows.Close()
+
+	subscriptions := []*domain.WebhookSubscription{}
+	for rows.Next() {
+		subscription, err := scanWebhook(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan webhook subscription")
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
+
+// Update updates an existing webhook subscription
+func (r *WebhookRepository) Update(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	eventTypes, _ := json.Marshal(subscription.EventTypes)
+	subscription.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE webhook_subscriptions
+		SET url = $2, event_types = $3, secret = $4, description = $5, active = $6, updated_at = $7
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		subscription.ID,
+		subscription.URL,
+		eventTypes,
+		subscription.Secret,
+		subscription.Description,
+		subscription.Active,
+		subscription.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update webhook subscription")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("webhook", subscription.ID.String())
+	}
+
+	return nil
+}
+
+// Delete deletes a webhook subscription and its delivery log
+func (r *WebhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.db.pool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete webhook subscription")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("webhook", id.String())
+	}
+
+	return nil
+}
+
+// CreateDelivery logs or queues a delivery attempt, leaving an existing attempt
+// with the same ID as it is
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (` + webhookDeliveryColumns + `, payload)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (id) DO NOTHING
+	`
+
+	_, err := r.db.pool.Exec(ctx, query,
+		delivery.ID,
+		delivery.SubscriptionID,
+		delivery.EventID,
+		delivery.EventType,
+		delivery.Attempt,
+		delivery.Status,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.DurationMS,
+		delivery.Test,
+		delivery.NextAttemptAt,
+		delivery.CreatedAt,
+		delivery.Payload,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to log webhook delivery")
+	}
+
+	return nil
+}
+
+// UpdateDelivery records the outcome of a queued attempt; its body is dropped
+// once it is sent
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, response_status = $3, error = $4, duration_ms = $5, next_attempt_at = $6, payload = NULL
+		WHERE id = $1
+	`
+
+	result, err := r.db.pool.Exec(ctx, query,
+		delivery.ID,
+		delivery.Status,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.DurationMS,
+		delivery.NextAttemptAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update webhook delivery")
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.NotFound("webhook delivery", delivery.ID.String())
+	}
+
+	return nil
+}
+
+// ListDeliveries returns the latest delivery attempts of a subscription, newest first
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]*domain.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `, NULL::bytea FROM webhook_deliveries
+		WHERE subscription_id = $1 ORDER BY created_at DESC LIMIT $2`
+	return r.listDeliveries(ctx, query, subscriptionID, limit)
+}
+
+// ListDueDeliveries returns queued attempts due at now, with their bodies, oldest first
+func (r *WebhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	query := `SELECT ` + webhookDeliveryColumns + `, payload FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1 ORDER BY next_attempt_at LIMIT $2`
+	return r.listDeliveries(ctx, query, now, limit)
+}
+
+// ClaimDelivery postpones a queued attempt due at now to until, and reports
+// whether this caller claimed it rather than another replica
+func (r *WebhookRepository) ClaimDelivery(ctx context.Context, id uuid.UUID, now, until time.Time) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries SET next_attempt_at = $3
+		WHERE id = $1 AND status = 'pending' AND next_attempt_at <= $2
+	`
+
+	result, err := r.db.pool.Exec(ctx, query, id, now, until)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to claim webhook delivery")
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *WebhookRepository) listDeliveries(ctx context.Context, query string, args ...interface{}) ([]*domain.WebhookDelivery, error) {
+	rows, err := r.db.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook deliveries")
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for rows.Next() {
+		delivery := &domain.WebhookDelivery{}
+		err := rows.Scan(
+			&delivery.ID,
+			&delivery.SubscriptionID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&delivery.Attempt,
+			&delivery.Status,
+			&delivery.ResponseStatus,
+			&delivery.Error,
+			&delivery.DurationMS,
+			&delivery.Test,
+			&delivery.NextAttemptAt,
+			&delivery.CreatedAt,
+			&delivery.Payload,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan webhook delivery")
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(row pgx.Row) (*domain.WebhookSubscription, error) {
+	subscription := &domain.WebhookSubscription{}
+	var eventTypes []byte
+
+	err := row.Scan(
+		&subscription.ID,
+		&subscription.ProjectID,
+		&subscription.URL,
+		&eventTypes,
+		&subscription.Secret,
+		&subscription.Description,
+		&subscription.Active,
+		&subscription.CreatedBy,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(eventTypes, &subscription.EventTypes)
+
+	return subscription, nil
+}
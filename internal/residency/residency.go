@@ -0,0 +1,210 @@
+// Package residency enforces the data residency projects declare. A project
+// in a residency region may only place services, databases and backups in the
+// cluster and storage regions configured for it; anything else is rejected
+// with a violation naming the resource, its region and the allowed regions.
+package residency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// Checker validates placements against the data residency of projects
+type Checker struct {
+	config      *config.ResidencyConfig
+	projectRepo domain.ProjectRepository
+	clusterRepo domain.ClusterRepository
+	envRepo     domain.EnvironmentRepository
+	serviceRepo domain.ServiceRepository
+}
+
+// NewChecker creates a new Checker. A nil Checker allows every placement.
+func NewChecker(
+	cfg *config.ResidencyConfig,
+	projectRepo domain.ProjectRepository,
+	clusterRepo domain.ClusterRepository,
+	envRepo domain.EnvironmentRepository,
+	serviceRepo domain.ServiceRepository,
+) *Checker {
+	return &Checker{
+		config:      cfg,
+		projectRepo: projectRepo,
+		clusterRepo: clusterRepo,
+		envRepo:     envRepo,
+		serviceRepo: serviceRepo,
+	}
+}
+
+// Validate checks that a project can declare the residency region. With no
+// regions configured, any region is accepted and allows only itself.
+func (c *Checker) Validate(residency string) error {
+	if c == nil || residency == "" || len(c.config.Regions) == 0 {
+		return nil
+	}
+	if _, ok := c.lookup(residency); ok {
+		return nil
+	}
+	names := make([]string, 0, len(c.config.Regions))
+	for name := range c.config.Regions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return errors.BadRequest(fmt.Sprintf("unknown data residency region %q; configured regions are %s", residency, strings.Join(names, ", ")))
+}
+
+// AllowedRegions returns the regions data of a residency region may live in
+func (c *Checker) AllowedRegions(residency string) []string {
+	if regions, ok := c.lookup(residency); ok {
+		return regions
+	}
+	return []string{residency}
+}
+
+// Allowed reports whether data of a residency region may live in region
+func (c *Checker) Allowed(residency, region string) bool {
+	if residency == "" {
+		return true
+	}
+	for _, allowed := range c.AllowedRegions(residency) {
+		if strings.EqualFold(allowed, region) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckCluster rejects running the project's workloads on cluster
+func (c *Checker) CheckCluster(project *domain.Project, cluster *domain.Cluster) error {
+	return c.check(project, "cluster", cluster.Slug, cluster.Region)
+}
+
+// CheckDatabase rejects creating a managed database for the project
+func (c *Checker) CheckDatabase(project *domain.Project, name string) error {
+	if c == nil {
+		return nil
+	}
+	return c.check(project, "database", name, c.config.DatabaseRegion)
+}
+
+// CheckBackups rejects storing the project's database backups
+func (c *Checker) CheckBackups(project *domain.Project, name string) error {
+	if c == nil {
+		return nil
+	}
+	return c.check(project, "backups of database", name, c.config.BackupRegion)
+}
+
+// CheckDeploy rejects deploying a service of the project to a cluster
+func (c *Checker) CheckDeploy(ctx context.Context, projectID, clusterID uuid.UUID) error {
+	if c == nil {
+		return nil
+	}
+	project, err := c.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if project.DataResidency == "" {
+		return nil
+	}
+	cluster, err := c.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return err
+	}
+	return c.CheckCluster(project, cluster)
+}
+
+// CheckProject rejects a residency the project's existing environments and
+// service targets already break, before it is declared
+func (c *Checker) CheckProject(ctx context.Context, project *domain.Project) error {
+	if c == nil || project.DataResidency == "" {
+		return nil
+	}
+
+	clusterIDs := []uuid.UUID{}
+	seen := make(map[uuid.UUID]bool)
+	add := func(id uuid.UUID) {
+		if !seen[id] {
+			seen[id] = true
+			clusterIDs = append(clusterIDs, id)
+		}
+	}
+
+	if c.envRepo != nil {
+		environments, err := c.envRepo.ListByProject(ctx, project.ID)
+		if err != nil {
+			return err
+		}
+		for _, env := range environments {
+			add(env.ClusterID)
+		}
+	}
+	services, err := c.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+	if err != nil {
+		return err
+	}
+	for _, svc := range services {
+		if svc.TargetClusterID != nil {
+			add(*svc.TargetClusterID)
+		}
+	}
+
+	for _, id := range clusterIDs {
+		cluster, err := c.clusterRepo.GetByID(ctx, id)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := c.CheckCluster(project, cluster); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns a violation when the named resource's region is outside the
+// project's residency. An unknown region is a violation too, as compliance
+// cannot be shown for it.
+func (c *Checker) check(project *domain.Project, kind, name, region string) error {
+	if c == nil || project.DataResidency == "" || (region != "" && c.Allowed(project.DataResidency, region)) {
+		return nil
+	}
+
+	allowed := c.AllowedRegions(project.DataResidency)
+	where := "in region " + region
+	if region == "" {
+		where = "in no known region"
+	}
+	return errors.ResidencyViolation(
+		fmt.Sprintf("data residency violation: project %s must keep its data in %s (%s), but %s %s is %s",
+			project.Slug, project.DataResidency, strings.Join(allowed, ", "), kind, name, where),
+		map[string]interface{}{
+			"project_id":      project.ID.String(),
+			"data_residency":  project.DataResidency,
+			"allowed_regions": allowed,
+			"resource":        kind,
+			"name":            name,
+			"region":          region,
+		},
+	)
+}
+
+func (c *Checker) lookup(residency string) ([]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	for name, regions := range c.config.Regions {
+		if strings.EqualFold(name, residency) {
+			return regions, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,65 @@
+package residency
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newChecker(cfg config.ResidencyConfig) *Checker {
+	return NewChecker(&cfg, nil, nil, nil, nil)
+}
+
+func TestValidate(t *testing.T) {
+	checker := newChecker(config.ResidencyConfig{Regions: map[string][]string{
+		"eu": {"eu-west-1", "eu-central-1"},
+		"us": {"us-east-1"},
+	}})
+	assert.NoError(t, checker.Validate(""))
+	assert.NoError(t, checker.Validate("EU"))
+	assert.EqualError(t, checker.Validate("apac"), `INVALID_INPUT: unknown data residency region "apac"; configured regions are eu, us`)
+
+	// Without configured regions a residency allows only itself
+	open := newChecker(config.ResidencyConfig{})
+	assert.NoError(t, open.Validate("eu-west-1"))
+	assert.True(t, open.Allowed("eu-west-1", "EU-WEST-1"))
+	assert.False(t, open.Allowed("eu-west-1", "eu-central-1"))
+}
+
+func TestCheckCluster(t *testing.T) {
+	checker := newChecker(config.ResidencyConfig{Regions: map[string][]string{"eu": {"eu-west-1", "eu-central-1"}}})
+	project := &domain.Project{ID: uuid.New(), Slug: "shop", DataResidency: "eu"}
+
+	assert.NoError(t, checker.CheckCluster(project, &domain.Cluster{Slug: "fra", Region: "eu-central-1"}))
+	assert.NoError(t, checker.CheckCluster(&domain.Project{Slug: "free"}, &domain.Cluster{Slug: "iad", Region: "us-east-1"}))
+
+	err := checker.CheckCluster(project, &domain.Cluster{Slug: "iad", Region: "us-east-1"})
+	require.Error(t, err)
+	appErr, ok := err.(*errors.AppError)
+	require.True(t, ok)
+	assert.Equal(t, errors.CodeResidencyViolation, appErr.Code)
+	assert.Equal(t, "data residency violation: project shop must keep its data in eu (eu-west-1, eu-central-1), but cluster iad is in region us-east-1", appErr.Message)
+	assert.Equal(t, "us-east-1", appErr.Details.(map[string]interface{})["region"])
+
+	// A resource in an unknown region cannot be shown to comply
+	assert.Error(t, checker.CheckCluster(project, &domain.Cluster{Slug: "onprem"}))
+}
+
+func TestCheckDatabase(t *testing.T) {
+	project := &domain.Project{ID: uuid.New(), Slug: "shop", DataResidency: "eu"}
+	checker := newChecker(config.ResidencyConfig{
+		Regions:        map[string][]string{"eu": {"eu-west-1"}},
+		DatabaseRegion: "eu-west-1",
+		BackupRegion:   "us-east-1",
+	})
+	assert.NoError(t, checker.CheckDatabase(project, "orders"))
+	assert.Error(t, checker.CheckBackups(project, "orders"))
+
+	var none *Checker
+	assert.NoError(t, none.CheckBackups(project, "orders"))
+}
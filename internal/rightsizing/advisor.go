@@ -0,0 +1,295 @@
+// Package rightsizing recommends CPU and memory requests for platform services.
+// Recommendations combine the platform's own usage metrics with the
+// Vertical Pod Autoscaler, which runs in recommendation-only mode.
+package rightsizing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/internal/warmpool"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Confidence describes how much a recommendation can be trusted
+type Confidence string
+
+const (
+	ConfidenceLow    Confidence = "low"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceHigh   Confidence = "high"
+)
+
+const (
+	// lookback is the usage window considered for metrics-based advice
+	lookback = 7 * 24 * time.Hour
+	// minSamples is the number of usage samples needed for medium confidence
+	minSamples = 100
+	// agreement is the relative difference under which sources are considered to agree
+	agreement = 0.25
+
+	cpuHeadroom    = 1.15
+	memoryHeadroom = 1.20
+)
+
+// Resources is a per-replica CPU and memory amount
+type Resources struct {
+	CPUCores    float64 `json:"cpu_cores"`
+	MemoryBytes float64 `json:"memory_bytes"`
+}
+
+// Recommendation is the right-sizing advice for a service
+type Recommendation struct {
+	ServiceID   uuid.UUID             `json:"service_id"`
+	Current     domain.ResourceLimits `json:"current"`
+	Recommended domain.ResourceLimits `json:"recommended"`
+	Metrics     *Resources            `json:"metrics,omitempty"`
+	VPA         *Resources            `json:"vpa,omitempty"`
+	Confidence  Confidence            `json:"confidence"`
+	Sources     []string              `json:"sources"`
+	WarmStandby *warmpool.Cost        `json:"warm_standby,omitempty"` // Cost of idle standby replicas at the recommended requests
+	GeneratedAt time.Time             `json:"generated_at"`
+}
+
+// Advisor produces right-sizing recommendations
+type Advisor struct {
+	kube        domain.KubernetesClient
+	metrics     domain.MetricsCollector
+	serviceRepo domain.ServiceRepository
+	prices      *config.MeteringConfig
+	logger      *logger.Logger
+}
+
+// NewAdvisor creates a new Advisor. metrics may be nil, in which case only VPA advice is used;
+// kube may be nil, in which case only metrics advice is used;
+// prices may be nil, in which case no costs are estimated.
+func NewAdvisor(
+	kube domain.KubernetesClient,
+	metrics domain.MetricsCollector,
+	serviceRepo domain.ServiceRepository,
+	prices *config.MeteringConfig,
+	log *logger.Logger,
+) *Advisor {
+	return &Advisor{
+		kube:        kube,
+		metrics:     metrics,
+		serviceRepo: serviceRepo,
+		prices:      prices,
+		logger:      log,
+	}
+}
+
+// Recommend builds a recommendation for the service from all available sources
+func (a *Advisor) Recommend(ctx context.Context, service *domain.Service) (*Recommendation, error) {
+	rec := &Recommendation{
+		ServiceID:   service.ID,
+		Current:     service.Resources,
+		Sources:     []string{},
+		GeneratedAt: time.Now().UTC(),
+	}
+
+	var samples int
+	if a.metrics != nil {
+		usage, n, err := a.metricsRecommendation(ctx, service)
+		if err != nil {
+			a.logger.Warn().Err(err).Str("service_id", service.ID.String()).Msg("Failed to compute metrics recommendation")
+		} else if usage != nil {
+			rec.Metrics = usage
+			rec.Sources = append(rec.Sources, "metrics")
+			samples = n
+		}
+	}
+
+	var upperBound *Resources
+	if a.kube != nil {
+		vpa, err := a.vpaRecommendation(ctx, service)
+		if err != nil {
+			a.logger.Warn().Err(err).Str("service_id", service.ID.String()).Msg("Failed to read VPA recommendation")
+		} else if vpa != nil {
+			rec.VPA = &vpa.target
+			rec.Sources = append(rec.Sources, "vpa")
+			upperBound = &vpa.upperBound
+		}
+	}
+
+	merged, confidence := merge(rec.Metrics, rec.VPA, upperBound, samples)
+	if merged == nil {
+		return nil, errors.NotFound("rightsizing recommendation", service.ID.String())
+	}
+
+	rec.Confidence = confidence
+	rec.Recommended = domain.ResourceLimits{
+		CPURequest:    formatCPU(merged.CPUCores),
+		MemoryRequest: formatMemory(merged.MemoryBytes),
+		CPULimit:      service.Resources.CPULimit,
+		MemoryLimit:   service.Resources.MemoryLimit,
+		StorageSize:   service.Resources.StorageSize,
+	}
+	if a.prices != nil {
+		rec.WarmStandby = warmpool.Estimate(service.Scaling.WarmStandby, rec.Recommended, a.prices)
+	}
+	return rec, nil
+}
+
+// Watch installs VPA objects for services as their deployments complete,
+// through a durable consumer so that deployments completed while the
+// orchestrator is down are still picked up. Without a Kubernetes client it
+// does nothing.
+func (a *Advisor) Watch(ctx context.Context, bus domain.EventBus) error {
+	if a.kube == nil {
+		return nil
+	}
+	subject := "deploy.completed"
+	_, err := eventbus.SubscribeDurable(ctx, bus, subject, eventbus.DurableName("rightsizing", subject), func(event *domain.Event) error {
+		raw, _ := event.Data["service_id"].(string)
+		serviceID, err := uuid.Parse(raw)
+		if err != nil {
+			return nil
+		}
+
+		service, err := a.serviceRepo.GetByID(ctx, serviceID)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			a.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to load service for VPA")
+			return err
+		}
+
+		if service.TargetClusterID == nil {
+			return nil
+		}
+
+		// Applying the VPA is idempotent, so redeliveries are harmless
+		err = a.EnsureVPA(ctx, service)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			a.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to ensure VPA")
+		}
+		return err
+	})
+	return err
+}
+
+// metricsRecommendation derives per-replica requests from observed usage
+func (a *Advisor) metricsRecommendation(ctx context.Context, service *domain.Service) (*Resources, int, error) {
+	now := time.Now()
+	metrics, err := a.metrics.GetServiceMetrics(ctx, service.ID, domain.TimeRange{
+		Start: now.Add(-lookback).Unix(),
+		End:   now.Unix(),
+		Step:  int64((5 * time.Minute).Seconds()),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(metrics.CPUUsage) == 0 && len(metrics.MemoryUsage) == 0 {
+		return nil, 0, nil
+	}
+
+	replicas := mean(metrics.Replicas)
+	if replicas < 1 {
+		replicas = 1
+	}
+
+	return &Resources{
+		CPUCores:    percentile(metrics.CPUUsage, 0.95) / replicas * cpuHeadroom,
+		MemoryBytes: percentile(metrics.MemoryUsage, 1.0) / replicas * memoryHeadroom,
+	}, min(len(metrics.CPUUsage), len(metrics.MemoryUsage)), nil
+}
+
+// merge combines metrics and VPA advice into a single recommendation.
+// When both sources are present the larger value wins, bounded by the VPA upper bound.
+func merge(metrics, vpa, upperBound *Resources, samples int) (*Resources, Confidence) {
+	switch {
+	case metrics == nil && vpa == nil:
+		return nil, ""
+	case vpa == nil:
+		if samples < minSamples {
+			return metrics, ConfidenceLow
+		}
+		return metrics, ConfidenceMedium
+	case metrics == nil:
+		return vpa, ConfidenceMedium
+	}
+
+	merged := &Resources{
+		CPUCores:    math.Max(metrics.CPUCores, vpa.CPUCores),
+		MemoryBytes: math.Max(metrics.MemoryBytes, vpa.MemoryBytes),
+	}
+	if upperBound != nil {
+		if upperBound.CPUCores > 0 {
+			merged.CPUCores = math.Min(merged.CPUCores, upperBound.CPUCores)
+		}
+		if upperBound.MemoryBytes > 0 {
+			merged.MemoryBytes = math.Min(merged.MemoryBytes, upperBound.MemoryBytes)
+		}
+	}
+
+	if relDiff(metrics.CPUCores, vpa.CPUCores) <= agreement && relDiff(metrics.MemoryBytes, vpa.MemoryBytes) <= agreement {
+		return merged, ConfidenceHigh
+	}
+	return merged, ConfidenceMedium
+}
+
+func relDiff(a, b float64) float64 {
+	hi := math.Max(a, b)
+	if hi == 0 {
+		return 0
+	}
+	return math.Abs(a-b) / hi
+}
+
+func mean(points []domain.MetricPoint) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, p := range points {
+		sum += p.Value
+	}
+	return sum / float64(len(points))
+}
+
+func percentile(points []domain.MetricPoint, p float64) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+	values := make([]float64, len(points))
+	for i, pt := range points {
+		values[i] = pt.Value
+	}
+	sort.Float64s(values)
+	idx := int(math.Ceil(p*float64(len(values)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return values[idx]
+}
+
+// formatCPU renders cores as a Kubernetes millicore quantity
+func formatCPU(cores float64) string {
+	milli := int64(math.Ceil(cores * 1000))
+	if milli < 10 {
+		milli = 10
+	}
+	return fmt.Sprintf("%dm", milli)
+}
+
+// formatMemory renders bytes as a Kubernetes Mi quantity
+func formatMemory(bytes float64) string {
+	mi := int64(math.Ceil(bytes / (1 << 20)))
+	if mi < 16 {
+		mi = 16
+	}
+	return fmt.Sprintf("%dMi", mi)
+}
@@ -0,0 +1,82 @@
+package rightsizing
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMetrics reports steady usage of half a core and 256Mi
+type fakeMetrics struct {
+	domain.MetricsCollector
+}
+
+func (fakeMetrics) GetServiceMetrics(ctx context.Context, serviceID uuid.UUID, timeRange domain.TimeRange) (*domain.ServiceMetrics, error) {
+	metrics := &domain.ServiceMetrics{ServiceID: serviceID}
+	for i := int64(0); i < 100; i++ {
+		metrics.CPUUsage = append(metrics.CPUUsage, domain.MetricPoint{Timestamp: timeRange.Start + i, Value: 0.5})
+		metrics.MemoryUsage = append(metrics.MemoryUsage, domain.MetricPoint{Timestamp: timeRange.Start + i, Value: 256 << 20})
+		metrics.Replicas = append(metrics.Replicas, domain.MetricPoint{Timestamp: timeRange.Start + i, Value: 1})
+	}
+	return metrics, nil
+}
+
+func TestAdvisorWithoutKubernetes(t *testing.T) {
+	advisor := NewAdvisor(nil, fakeMetrics{}, nil, nil, logger.New("error", "json", io.Discard))
+	clusterID := uuid.New()
+	service := &domain.Service{ID: uuid.New(), Name: "web", TargetClusterID: &clusterID}
+
+	rec, err := advisor.Recommend(context.Background(), service)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"metrics"}, rec.Sources, "only metrics advice is used")
+
+	assert.ErrorIs(t, advisor.EnsureVPA(context.Background(), service), errors.ErrNoKubernetes)
+	assert.NoError(t, advisor.Watch(context.Background(), nil))
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("no sources", func(t *testing.T) {
+		merged, _ := merge(nil, nil, nil, 0)
+		assert.Nil(t, merged)
+	})
+
+	t.Run("metrics only with few samples", func(t *testing.T) {
+		metrics := &Resources{CPUCores: 0.5, MemoryBytes: 256 << 20}
+		merged, confidence := merge(metrics, nil, nil, 10)
+		assert.Equal(t, metrics, merged)
+		assert.Equal(t, ConfidenceLow, confidence)
+	})
+
+	t.Run("agreeing sources", func(t *testing.T) {
+		metrics := &Resources{CPUCores: 0.5, MemoryBytes: 256 << 20}
+		vpa := &Resources{CPUCores: 0.45, MemoryBytes: 240 << 20}
+		merged, confidence := merge(metrics, vpa, nil, 500)
+		assert.Equal(t, ConfidenceHigh, confidence)
+		assert.Equal(t, 0.5, merged.CPUCores)
+		assert.Equal(t, float64(256<<20), merged.MemoryBytes)
+	})
+
+	t.Run("disagreeing sources capped by upper bound", func(t *testing.T) {
+		metrics := &Resources{CPUCores: 2, MemoryBytes: 1 << 30}
+		vpa := &Resources{CPUCores: 0.5, MemoryBytes: 512 << 20}
+		upper := &Resources{CPUCores: 1, MemoryBytes: 2 << 30}
+		merged, confidence := merge(metrics, vpa, upper, 500)
+		assert.Equal(t, ConfidenceMedium, confidence)
+		assert.Equal(t, 1.0, merged.CPUCores)
+		assert.Equal(t, float64(1<<30), merged.MemoryBytes)
+	})
+}
+
+func TestFormatQuantities(t *testing.T) {
+	assert.Equal(t, "250m", formatCPU(0.25))
+	assert.Equal(t, "10m", formatCPU(0.001))
+	assert.Equal(t, "256Mi", formatMemory(256<<20))
+	assert.Equal(t, "16Mi", formatMemory(1024))
+}
@@ -0,0 +1,152 @@
+package rightsizing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const vpaAPIVersion = "autoscaling.k8s.io/v1"
+
+// workload is the Deployment backing a service
+type workload struct {
+	clusterID uuid.UUID
+	name      string
+	namespace string
+	uid       string
+}
+
+// vpaRecommendation is the summed VPA recommendation across containers
+type vpaRecommendation struct {
+	target     Resources
+	upperBound Resources
+}
+
+// EnsureVPA installs a recommendation-only VerticalPodAutoscaler for the service.
+// The VPA is owned by the service's Deployment so it is garbage collected with it.
+func (a *Advisor) EnsureVPA(ctx context.Context, service *domain.Service) error {
+	if a.kube == nil {
+		return errors.ErrNoKubernetes
+	}
+	w, err := a.findWorkload(ctx, service)
+	if err != nil {
+		return err
+	}
+
+	manifest, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": vpaAPIVersion,
+		"kind":       "VerticalPodAutoscaler",
+		"metadata": map[string]interface{}{
+			"name":      w.name,
+			"namespace": w.namespace,
+			"labels": map[string]interface{}{
+				domain.LabelServiceID: service.ID.String(),
+				domain.LabelProjectID: service.ProjectID.String(),
+				domain.LabelManagedBy: domain.ManagedByValue,
+			},
+			"ownerReferences": []interface{}{
+				map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"name":       w.name,
+					"uid":        w.uid,
+				},
+			},
+		},
+		"spec": map[string]interface{}{
+			"targetRef": map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"name":       w.name,
+			},
+			// Recommendation only: the platform owns resource requests
+			"updatePolicy": map[string]interface{}{"updateMode": "Off"},
+		},
+	})
+
+	if err := a.kube.ApplyManifest(ctx, w.clusterID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+// vpaRecommendation reads the current VPA recommendation for the service, if any
+func (a *Advisor) vpaRecommendation(ctx context.Context, service *domain.Service) (*vpaRecommendation, error) {
+	w, err := a.findWorkload(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := a.kube.GetResource(ctx, w.clusterID, "VerticalPodAutoscaler", w.namespace, w.name)
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	if obj == nil {
+		return nil, nil
+	}
+
+	containers, found, _ := unstructured.NestedSlice(obj, "status", "recommendation", "containerRecommendations")
+	if !found || len(containers) == 0 {
+		return nil, nil
+	}
+
+	rec := &vpaRecommendation{}
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		target, _, _ := unstructured.NestedStringMap(container, "target")
+		upper, _, _ := unstructured.NestedStringMap(container, "upperBound")
+		rec.target.add(target)
+		rec.upperBound.add(upper)
+	}
+	if rec.target.CPUCores == 0 && rec.target.MemoryBytes == 0 {
+		return nil, nil
+	}
+	return rec, nil
+}
+
+// findWorkload locates the Deployment running the service on its target cluster
+func (a *Advisor) findWorkload(ctx context.Context, service *domain.Service) (*workload, error) {
+	if service.TargetClusterID == nil {
+		return nil, errors.BadRequest(fmt.Sprintf("service %s has no target cluster", service.Name))
+	}
+
+	objects, err := a.kube.ListResources(ctx, *service.TargetClusterID, "Deployment", "", map[string]string{
+		domain.LabelServiceID: service.ID.String(),
+	})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	if len(objects) == 0 {
+		return nil, errors.NotFound("workload for service", service.ID.String())
+	}
+
+	name, _, _ := unstructured.NestedString(objects[0], "metadata", "name")
+	namespace, _, _ := unstructured.NestedString(objects[0], "metadata", "namespace")
+	uid, _, _ := unstructured.NestedString(objects[0], "metadata", "uid")
+
+	return &workload{
+		clusterID: *service.TargetClusterID,
+		name:      name,
+		namespace: namespace,
+		uid:       uid,
+	}, nil
+}
+
+// add accumulates a Kubernetes resource list into r
+func (r *Resources) add(list map[string]string) {
+	if q, err := resource.ParseQuantity(list["cpu"]); err == nil {
+		r.CPUCores += q.AsApproximateFloat64()
+	}
+	if q, err := resource.ParseQuantity(list["memory"]); err == nil {
+		r.MemoryBytes += q.AsApproximateFloat64()
+	}
+}
@@ -0,0 +1,128 @@
+package rke2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// MetadataProgress is the cluster metadata key holding the provisioning progress
+const MetadataProgress = "rke2"
+
+// Node roles
+const (
+	RoleServer = "server" // Control plane and etcd
+	RoleAgent  = "agent"  // Workloads only
+)
+
+// NodeStatus is how far a node's installation got
+type NodeStatus string
+
+const (
+	NodePending    NodeStatus = "pending"
+	NodeInstalling NodeStatus = "installing"
+	NodeReady      NodeStatus = "ready"
+	NodeFailed     NodeStatus = "failed"
+)
+
+var (
+	hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*$`)
+	nodeNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	versionPattern  = regexp.MustCompile(`^v\d+\.\d+\.\d+\+rke2r\d+$`)
+)
+
+// Node is a machine RKE2 is installed on, and how far it got
+type Node struct {
+	Address   string     `json:"address" binding:"required"` // IP or hostname reachable over SSH
+	SSHPort   int        `json:"ssh_port,omitempty"`         // 22 when unset
+	Role      string     `json:"role" binding:"required,oneof=server agent"`
+	Name      string     `json:"name,omitempty"` // Kubernetes node name; the hostname when unset
+	Status    NodeStatus `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Progress is the provisioning state of a cluster's nodes
+type Progress struct {
+	Nodes []Node `json:"nodes"`
+	Error string `json:"error,omitempty"` // Why the cluster could not be completed, beyond node failures
+}
+
+// ValidateNodes checks the nodes of a new cluster. The first node must be a
+// server: it bootstraps the cluster and the others join it.
+func ValidateNodes(nodes []Node) error {
+	var fields []errors.FieldError
+	invalid := func(i int, field, message string) {
+		fields = append(fields, errors.FieldError{Field: fmt.Sprintf("nodes[%d].%s", i, field), Rule: "invalid", Message: message})
+	}
+
+	if len(nodes) == 0 {
+		return errors.Validation([]errors.FieldError{{Field: "nodes", Rule: "required", Message: "at least one server is required"}})
+	}
+	seen := map[string]bool{}
+	for i, n := range nodes {
+		if net.ParseIP(n.Address) == nil && !hostnamePattern.MatchString(n.Address) {
+			invalid(i, "address", "must be an IP address or hostname")
+		}
+		if seen[n.Address] {
+			invalid(i, "address", "is listed twice")
+		}
+		seen[n.Address] = true
+		if n.SSHPort < 0 || n.SSHPort > 65535 {
+			invalid(i, "ssh_port", "must be a port number")
+		}
+		if n.Role != RoleServer && n.Role != RoleAgent {
+			invalid(i, "role", "must be server or agent")
+		}
+		if n.Name != "" && !nodeNamePattern.MatchString(n.Name) {
+			invalid(i, "name", "must be a lowercase DNS label")
+		}
+	}
+	if nodes[0].Role != RoleServer {
+		invalid(0, "role", "the first node bootstraps the cluster and must be a server")
+	}
+	if len(fields) > 0 {
+		return errors.Validation(fields)
+	}
+	return nil
+}
+
+// ValidateVersion checks an RKE2 release name, e.g. v1.28.5+rke2r1
+func ValidateVersion(version string) error {
+	if !versionPattern.MatchString(version) {
+		return errors.BadRequest("kube_version must be an RKE2 release, e.g. v1.28.5+rke2r1")
+	}
+	return nil
+}
+
+// GetProgress returns the provisioning progress of a cluster, or nil for
+// clusters not provisioned by RKE2
+func GetProgress(cluster *domain.Cluster) *Progress {
+	raw, ok := cluster.Metadata[MetadataProgress]
+	if !ok {
+		return nil
+	}
+	// Metadata read back from the store is decoded JSON
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var progress Progress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil
+	}
+	return &progress
+}
+
+// SetProgress records the provisioning progress of a cluster
+func SetProgress(cluster *domain.Cluster, progress *Progress) {
+	if cluster.Metadata == nil {
+		cluster.Metadata = make(map[string]interface{})
+	}
+	cluster.Metadata[MetadataProgress] = progress
+}
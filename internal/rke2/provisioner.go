@@ -0,0 +1,447 @@
+// Package rke2 provisions RKE2 clusters on bare-metal machines: it installs
+// RKE2 servers and agents over SSH, registers the cluster in Rancher and
+// tracks the progress of each node on the cluster.
+package rke2
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"sigs.k8s.io/yaml"
+)
+
+// Paths on the nodes
+const (
+	configPath     = "/etc/rancher/rke2/config.yaml"
+	kubeconfigPath = "/etc/rancher/rke2/rke2.yaml"
+	kubectlPath    = "/var/lib/rancher/rke2/bin/kubectl"
+)
+
+// Ports of the first server other nodes and clients connect to
+const (
+	supervisorPort = 9345
+	apiServerPort  = 6443
+)
+
+// maxMessage bounds the command output kept in a failed node's message
+const maxMessage = 2000
+
+// Registrar registers clusters provisioned outside Rancher
+type Registrar interface {
+	// RegisterCluster returns the Rancher cluster ID and the URL of the agent
+	// manifest that connects the cluster to Rancher
+	RegisterCluster(ctx context.Context, cluster *domain.Cluster) (string, string, error)
+}
+
+// Provisioner installs RKE2 clusters on machines reachable over SSH
+type Provisioner struct {
+	cfg         *config.RKE2Config
+	clusterRepo domain.ClusterRepository
+	runner      Runner
+	registrar   Registrar
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+
+	mu sync.Mutex // Serializes progress updates of concurrently installed nodes
+}
+
+// NewProvisioner creates a new Provisioner. Without a registrar clusters are
+// not registered in Rancher; without an event bus no events are published.
+func NewProvisioner(cfg *config.RKE2Config, clusterRepo domain.ClusterRepository, runner Runner, registrar Registrar, eventBus domain.EventBus, log *logger.Logger) *Provisioner {
+	return &Provisioner{
+		cfg:         cfg,
+		clusterRepo: clusterRepo,
+		runner:      runner,
+		registrar:   registrar,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Prepare readies a new cluster for provisioning: it defaults the Kubernetes
+// version, marks the cluster provisioning and every node pending. The caller
+// stores the cluster, then calls Start.
+func (p *Provisioner) Prepare(cluster *domain.Cluster, nodes []Node) error {
+	if err := ValidateNodes(nodes); err != nil {
+		return err
+	}
+	if cluster.KubeVersion == "" {
+		cluster.KubeVersion = p.cfg.KubernetesVersion
+	}
+	if err := ValidateVersion(cluster.KubeVersion); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	progress := &Progress{Nodes: make([]Node, len(nodes))}
+	for i, n := range nodes {
+		n.Status = NodePending
+		n.Message = ""
+		n.UpdatedAt = now
+		progress.Nodes[i] = n
+	}
+	cluster.Status = domain.ClusterStatusProvisioning
+	cluster.NodeCount = int32(len(nodes))
+	SetProgress(cluster, progress)
+	return nil
+}
+
+// Start provisions a prepared cluster in the background. Each node gets the
+// install timeout; the first server goes first, then the other servers one at
+// a time, then the agents together.
+func (p *Provisioner) Start(cluster *domain.Cluster) {
+	go func() {
+		progress := GetProgress(cluster)
+		if progress == nil {
+			return
+		}
+		ctx := context.Background()
+		if err := p.provision(ctx, cluster.ID, cluster.KubeVersion, progress.Nodes); err != nil {
+			p.logger.Error().Err(err).Str("cluster_id", cluster.ID.String()).Msg("RKE2 provisioning failed")
+		}
+	}()
+}
+
+func (p *Provisioner) provision(ctx context.Context, clusterID uuid.UUID, version string, nodes []Node) error {
+	token, err := newToken()
+	if err != nil {
+		return p.fail(ctx, clusterID, fmt.Sprintf("failed to generate the cluster token: %v", err))
+	}
+
+	first := nodes[0]
+	if !p.install(ctx, clusterID, first, version, token, "") {
+		// Nothing can join a cluster that does not exist
+		for i := range nodes[1:] {
+			p.setNode(ctx, clusterID, nodes[i+1].Address, NodeFailed, "not installed: the first server failed")
+		}
+		return p.fail(ctx, clusterID, "the first server failed to install")
+	}
+
+	failed := 0
+	for _, n := range nodes[1:] {
+		if n.Role == RoleServer && !p.install(ctx, clusterID, n, version, token, first.Address) {
+			failed++
+		}
+	}
+	var wg sync.WaitGroup
+	var failedAgents int
+	var countMu sync.Mutex
+	for _, n := range nodes[1:] {
+		if n.Role != RoleAgent {
+			continue
+		}
+		wg.Add(1)
+		go func(n Node) {
+			defer wg.Done()
+			if !p.install(ctx, clusterID, n, version, token, first.Address) {
+				countMu.Lock()
+				failedAgents++
+				countMu.Unlock()
+			}
+		}(n)
+	}
+	wg.Wait()
+	failed += failedAgents
+
+	var rancherID, problem string
+	if p.registrar != nil {
+		rancherID, problem = p.register(ctx, clusterID, first)
+	}
+	if problem == "" && failed > 0 {
+		problem = fmt.Sprintf("%d of %d nodes failed to install", failed, len(nodes))
+	}
+
+	cluster, err := p.update(ctx, clusterID, func(c *domain.Cluster, progress *Progress) {
+		c.APIEndpoint = "https://" + net.JoinHostPort(first.Address, strconv.Itoa(apiServerPort))
+		c.NodeCount = int32(len(nodes) - failed)
+		if rancherID != "" {
+			c.RancherClusterID = rancherID
+		}
+		c.Status = domain.ClusterStatusActive
+		if problem != "" {
+			c.Status = domain.ClusterStatusUnhealthy
+		}
+		progress.Error = problem
+	})
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info().
+		Str("cluster_id", clusterID.String()).
+		Str("status", string(cluster.Status)).
+		Int("nodes", len(nodes)).
+		Int("failed", failed).
+		Msg("RKE2 cluster provisioned")
+	p.publish(ctx, cluster, problem)
+	return nil
+}
+
+// install installs RKE2 on a node and reports whether it succeeded. An empty
+// join address bootstraps a new cluster.
+func (p *Provisioner) install(ctx context.Context, clusterID uuid.UUID, node Node, version, token, join string) bool {
+	p.setNode(ctx, clusterID, node.Address, NodeInstalling, "")
+
+	script, err := p.script(node, version, token, join)
+	if err != nil {
+		p.setNode(ctx, clusterID, node.Address, NodeFailed, err.Error())
+		return false
+	}
+
+	timeout := p.cfg.InstallTimeout
+	if timeout <= 0 {
+		timeout = 20 * time.Minute
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := p.runner.Run(runCtx, node, script)
+	if err != nil {
+		p.logger.Warn().Err(err).
+			Str("cluster_id", clusterID.String()).
+			Str("node", node.Address).
+			Msg("RKE2 install failed")
+		p.setNode(ctx, clusterID, node.Address, NodeFailed, FailureMessage(err, output))
+		return false
+	}
+	p.setNode(ctx, clusterID, node.Address, NodeReady, "")
+	return true
+}
+
+// register registers the cluster in Rancher and applies the agent manifest on
+// the first server. It returns the Rancher cluster ID and what went wrong.
+func (p *Provisioner) register(ctx context.Context, clusterID uuid.UUID, first Node) (string, string) {
+	cluster, err := p.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return "", fmt.Sprintf("failed to load the cluster: %v", err)
+	}
+	rancherID, manifestURL, err := p.registrar.RegisterCluster(ctx, cluster)
+	if err != nil {
+		return "", fmt.Sprintf("failed to register the cluster in Rancher: %v", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	script := fmt.Sprintf("set -eu\ncurl -sfL %s | %s --kubeconfig %s apply -f -\n", Quote(manifestURL), kubectlPath, kubeconfigPath)
+	if output, err := p.runner.Run(runCtx, first, script); err != nil {
+		return rancherID, "failed to apply the Rancher agent: " + FailureMessage(err, output)
+	}
+	return rancherID, ""
+}
+
+// script renders the install script of a node: it writes the RKE2 config,
+// runs the install script and starts the service
+func (p *Provisioner) script(node Node, version, token, join string) (string, error) {
+	cfg, err := p.nodeConfig(node, token, join)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("set -eu\n")
+	fmt.Fprintf(&sb, "mkdir -p %s\n", "/etc/rancher/rke2")
+	fmt.Fprintf(&sb, "cat > %s <<'RKE2_CONFIG'\n%sRKE2_CONFIG\n", configPath, cfg)
+	fmt.Fprintf(&sb, "chmod 600 %s\n", configPath)
+
+	cis := p.cfg.Profile != ""
+	if cis && node.Role == RoleServer {
+		// The CIS profile runs etcd as its own user
+		sb.WriteString("id etcd >/dev/null 2>&1 || useradd -r -c 'etcd user' -s /sbin/nologin -M etcd -U\n")
+	}
+	fmt.Fprintf(&sb, "curl -sfL %s | INSTALL_RKE2_VERSION=%s INSTALL_RKE2_TYPE=%s sh -\n", Quote(p.installURL()), Quote(version), node.Role)
+	if cis {
+		// Kernel parameters the CIS profile requires; the path depends on
+		// whether RKE2 was installed from RPMs or a tarball
+		sb.WriteString("for f in /usr/share/rke2/rke2-cis-sysctl.conf /usr/local/share/rke2/rke2-cis-sysctl.conf; do\n")
+		sb.WriteString("  if [ -f \"$f\" ]; then cp -f \"$f\" /etc/sysctl.d/60-rke2-cis.conf; fi\n")
+		sb.WriteString("done\n")
+		sb.WriteString("systemctl restart systemd-sysctl\n")
+	}
+	fmt.Fprintf(&sb, "systemctl enable rke2-%s.service\n", node.Role)
+	fmt.Fprintf(&sb, "systemctl start rke2-%s.service\n", node.Role)
+	return sb.String(), nil
+}
+
+// nodeConfig renders the RKE2 config.yaml of a node
+func (p *Provisioner) nodeConfig(node Node, token, join string) (string, error) {
+	cfg := map[string]interface{}{"token": token}
+	if join != "" {
+		cfg["server"] = "https://" + net.JoinHostPort(join, strconv.Itoa(supervisorPort))
+	}
+	if node.Name != "" {
+		cfg["node-name"] = node.Name
+	}
+	if p.cfg.Profile != "" {
+		cfg["profile"] = p.cfg.Profile
+	}
+	if p.cfg.SELinux {
+		cfg["selinux"] = true
+	}
+	if p.cfg.CloudProvider != "" && p.cfg.CloudProvider != "none" {
+		cfg["cloud-provider-name"] = p.cfg.CloudProvider
+	}
+	if node.Role == RoleServer {
+		cfg["tls-san"] = []string{node.Address}
+		if p.cfg.CNI != "" {
+			cfg["cni"] = p.cfg.CNI
+		}
+		cfg["write-kubeconfig-mode"] = "0600"
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (p *Provisioner) installURL() string {
+	if p.cfg.InstallURL != "" {
+		return p.cfg.InstallURL
+	}
+	return "https://get.rke2.io"
+}
+
+// Recover marks clusters whose provisioning was interrupted by a restart as
+// unhealthy, with their unfinished nodes failed
+func (p *Provisioner) Recover(ctx context.Context) error {
+	provider := domain.ClusterProviderRKE2
+	status := domain.ClusterStatusProvisioning
+	clusters, err := p.clusterRepo.List(ctx, domain.ClusterFilter{Provider: &provider, Status: &status})
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		if GetProgress(cluster) == nil {
+			continue
+		}
+		updated, err := p.update(ctx, cluster.ID, func(c *domain.Cluster, progress *Progress) {
+			for i := range progress.Nodes {
+				if progress.Nodes[i].Status == NodePending || progress.Nodes[i].Status == NodeInstalling {
+					progress.Nodes[i].Status = NodeFailed
+					progress.Nodes[i].Message = "interrupted by an orchestrator restart"
+					progress.Nodes[i].UpdatedAt = time.Now()
+				}
+			}
+			progress.Error = "provisioning was interrupted"
+			c.Status = domain.ClusterStatusUnhealthy
+		})
+		if err != nil {
+			return err
+		}
+		p.logger.Warn().Str("cluster_id", cluster.ID.String()).Msg("Interrupted RKE2 provisioning marked failed")
+		p.publish(ctx, updated, "provisioning was interrupted")
+	}
+	return nil
+}
+
+// fail marks a cluster unhealthy
+func (p *Provisioner) fail(ctx context.Context, clusterID uuid.UUID, problem string) error {
+	cluster, err := p.update(ctx, clusterID, func(c *domain.Cluster, progress *Progress) {
+		c.Status = domain.ClusterStatusUnhealthy
+		progress.Error = problem
+	})
+	if err != nil {
+		return err
+	}
+	p.publish(ctx, cluster, problem)
+	return fmt.Errorf("%s", problem)
+}
+
+// setNode records the status of one node
+func (p *Provisioner) setNode(ctx context.Context, clusterID uuid.UUID, address string, status NodeStatus, message string) {
+	_, err := p.update(ctx, clusterID, func(_ *domain.Cluster, progress *Progress) {
+		for i := range progress.Nodes {
+			if progress.Nodes[i].Address == address {
+				progress.Nodes[i].Status = status
+				progress.Nodes[i].Message = message
+				progress.Nodes[i].UpdatedAt = time.Now()
+			}
+		}
+	})
+	if err != nil {
+		p.logger.Warn().Err(err).Str("cluster_id", clusterID.String()).Str("node", address).Msg("Failed to record RKE2 node status")
+	}
+}
+
+// update applies a change to the stored cluster and its progress. The
+// cluster is read back first so that concurrent edits are kept.
+func (p *Provisioner) update(ctx context.Context, clusterID uuid.UUID, apply func(*domain.Cluster, *Progress)) (*domain.Cluster, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cluster, err := p.clusterRepo.GetByID(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	progress := GetProgress(cluster)
+	if progress == nil {
+		progress = &Progress{}
+	}
+	apply(cluster, progress)
+	SetProgress(cluster, progress)
+	cluster.UpdatedAt = time.Now()
+	if err := p.clusterRepo.Update(ctx, cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+func (p *Provisioner) publish(ctx context.Context, cluster *domain.Cluster, problem string) {
+	if p.eventBus == nil {
+		return
+	}
+	eventType := "cluster.provisioned"
+	if problem != "" {
+		eventType = "cluster.provisioning_failed"
+	}
+	err := p.eventBus.Publish(ctx, eventType, &domain.Event{
+		Type:   eventType,
+		Source: "platform-orchestrator",
+		Data: map[string]interface{}{
+			"cluster_id": cluster.ID.String(),
+			"name":       cluster.Name,
+			"status":     string(cluster.Status),
+			"error":      problem,
+		},
+	})
+	if err != nil {
+		p.logger.Warn().Err(err).Str("cluster_id", cluster.ID.String()).Msg("Failed to publish cluster event")
+	}
+}
+
+// newToken generates the shared secret nodes join the cluster with
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Quote quotes a string for the shell
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// FailureMessage describes a failed command by its error and the tail of its
+// output
+func FailureMessage(err error, output string) string {
+	output = strings.TrimSpace(output)
+	if len(output) > maxMessage {
+		output = "..." + output[len(output)-maxMessage:]
+	}
+	if output == "" {
+		return err.Error()
+	}
+	return err.Error() + ": " + output
+}
@@ -0,0 +1,132 @@
+package rke2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClusterRepo struct {
+	domain.ClusterRepository
+	mu       sync.Mutex
+	clusters map[uuid.UUID]*domain.Cluster
+}
+
+func (r *fakeClusterRepo) GetByID(_ context.Context, id uuid.UUID) (*domain.Cluster, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *r.clusters[id]
+	return &c, nil
+}
+
+func (r *fakeClusterRepo) Update(_ context.Context, cluster *domain.Cluster) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *cluster
+	r.clusters[cluster.ID] = &c
+	return nil
+}
+
+type fakeRunner struct {
+	mu      sync.Mutex
+	scripts map[string][]string
+	fail    map[string]bool
+}
+
+func (r *fakeRunner) Run(_ context.Context, node Node, script string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scripts[node.Address] = append(r.scripts[node.Address], script)
+	if r.fail[node.Address] {
+		return "curl: (6) Could not resolve host", fmt.Errorf("exit status 1")
+	}
+	return "", nil
+}
+
+type fakeRegistrar struct{}
+
+func (fakeRegistrar) RegisterCluster(context.Context, *domain.Cluster) (string, string, error) {
+	return "c-m-abc", "https://rancher.example.com/v3/import/x.yaml", nil
+}
+
+func testConfig() *config.RKE2Config {
+	return &config.RKE2Config{KubernetesVersion: "v1.28.5+rke2r1", CNI: "cilium", Profile: "cis", CloudProvider: "none"}
+}
+
+func TestValidateNodes(t *testing.T) {
+	assert.NoError(t, ValidateNodes([]Node{{Address: "10.0.0.1", Role: RoleServer}, {Address: "node-2.example.com", Role: RoleAgent}}))
+	assert.Error(t, ValidateNodes(nil))
+	assert.Error(t, ValidateNodes([]Node{{Address: "10.0.0.1", Role: RoleAgent}}))
+	assert.Error(t, ValidateNodes([]Node{{Address: "10.0.0.1; rm -rf /", Role: RoleServer}}))
+	assert.Error(t, ValidateNodes([]Node{{Address: "10.0.0.1", Role: RoleServer}, {Address: "10.0.0.1", Role: RoleAgent}}))
+	assert.Error(t, ValidateVersion("v1.28.5'; reboot"))
+}
+
+func TestScript(t *testing.T) {
+	p := NewProvisioner(testConfig(), nil, nil, nil, nil, logger.New("error", "json", io.Discard))
+
+	server, err := p.script(Node{Address: "10.0.0.1", Role: RoleServer}, "v1.28.5+rke2r1", "secret", "")
+	require.NoError(t, err)
+	assert.Contains(t, server, "cni: cilium\n")
+	assert.Contains(t, server, "profile: cis\n")
+	assert.Contains(t, server, "- 10.0.0.1\n")
+	assert.NotContains(t, server, "server: https://")
+	assert.NotContains(t, server, "cloud-provider-name")
+	assert.Contains(t, server, "useradd -r -c 'etcd user'")
+	assert.Contains(t, server, "INSTALL_RKE2_VERSION='v1.28.5+rke2r1' INSTALL_RKE2_TYPE=server sh -")
+	assert.Contains(t, server, "systemctl start rke2-server.service")
+
+	agent, err := p.script(Node{Address: "10.0.0.2", Role: RoleAgent, Name: "worker-1"}, "v1.28.5+rke2r1", "secret", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Contains(t, agent, "server: https://10.0.0.1:9345\n")
+	assert.Contains(t, agent, "node-name: worker-1\n")
+	assert.NotContains(t, agent, "cni:")
+	assert.NotContains(t, agent, "useradd")
+	assert.Contains(t, agent, "systemctl start rke2-agent.service")
+}
+
+func TestProvision(t *testing.T) {
+	repo := &fakeClusterRepo{clusters: map[uuid.UUID]*domain.Cluster{}}
+	runner := &fakeRunner{scripts: map[string][]string{}, fail: map[string]bool{"10.0.0.3": true}}
+	p := NewProvisioner(testConfig(), repo, runner, fakeRegistrar{}, nil, logger.New("error", "json", io.Discard))
+
+	cluster := &domain.Cluster{ID: uuid.New(), Name: "Metal", Slug: "metal", Provider: domain.ClusterProviderRKE2}
+	nodes := []Node{
+		{Address: "10.0.0.1", Role: RoleServer},
+		{Address: "10.0.0.2", Role: RoleAgent},
+		{Address: "10.0.0.3", Role: RoleAgent},
+	}
+	require.NoError(t, p.Prepare(cluster, nodes))
+	assert.Equal(t, domain.ClusterStatusProvisioning, cluster.Status)
+	assert.Equal(t, "v1.28.5+rke2r1", cluster.KubeVersion)
+	repo.clusters[cluster.ID] = cluster
+
+	require.NoError(t, p.provision(context.Background(), cluster.ID, cluster.KubeVersion, GetProgress(cluster).Nodes))
+
+	stored := repo.clusters[cluster.ID]
+	assert.Equal(t, domain.ClusterStatusUnhealthy, stored.Status)
+	assert.Equal(t, "c-m-abc", stored.RancherClusterID)
+	assert.Equal(t, "https://10.0.0.1:6443", stored.APIEndpoint)
+	assert.Equal(t, int32(2), stored.NodeCount)
+
+	progress := GetProgress(stored)
+	require.Len(t, progress.Nodes, 3)
+	assert.Equal(t, NodeReady, progress.Nodes[0].Status)
+	assert.Equal(t, NodeReady, progress.Nodes[1].Status)
+	assert.Equal(t, NodeFailed, progress.Nodes[2].Status)
+	assert.Contains(t, progress.Nodes[2].Message, "Could not resolve host")
+	assert.Equal(t, "1 of 3 nodes failed to install", progress.Error)
+
+	// The first server also applies the Rancher agent
+	require.Len(t, runner.scripts["10.0.0.1"], 2)
+	assert.Contains(t, runner.scripts["10.0.0.1"][1], "curl -sfL 'https://rancher.example.com/v3/import/x.yaml' | ")
+}
@@ -0,0 +1,125 @@
+package rke2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/pkg/logger"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Runner runs shell scripts on nodes
+type Runner interface {
+	// Run runs a script as root on a node and returns its combined output
+	Run(ctx context.Context, node Node, script string) (string, error)
+}
+
+// sshRunner runs scripts over SSH with the configured user and key
+type sshRunner struct {
+	cfg    *config.RKE2Config
+	logger *logger.Logger
+}
+
+// NewSSHRunner creates a Runner connecting over SSH. Without a known_hosts
+// file any host key is accepted.
+func NewSSHRunner(cfg *config.RKE2Config, log *logger.Logger) Runner {
+	if cfg.SSHKeyPath != "" && cfg.KnownHostsPath == "" {
+		log.Warn().Msg("integrations.rke2.known_hosts_path is not set; node host keys are not verified")
+	}
+	return &sshRunner{cfg: cfg, logger: log}
+}
+
+func (r *sshRunner) clientConfig() (*ssh.ClientConfig, error) {
+	if r.cfg.SSHKeyPath == "" {
+		return nil, fmt.Errorf("integrations.rke2.ssh_key_path is not set")
+	}
+	key, err := os.ReadFile(r.cfg.SSHKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse SSH key: %w", err)
+	}
+
+	hostKeys := ssh.InsecureIgnoreHostKey()
+	if r.cfg.KnownHostsPath != "" {
+		if hostKeys, err = knownhosts.New(r.cfg.KnownHostsPath); err != nil {
+			return nil, fmt.Errorf("failed to read known hosts: %w", err)
+		}
+	}
+
+	return &ssh.ClientConfig{
+		User:            r.cfg.SSHUser,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeys,
+		Timeout:         r.cfg.SSHTimeout,
+	}, nil
+}
+
+// Run pipes the script to sh on the node, through sudo unless the SSH user is
+// root. Cancelling ctx closes the connection.
+func (r *sshRunner) Run(ctx context.Context, node Node, script string) (string, error) {
+	clientConfig, err := r.clientConfig()
+	if err != nil {
+		return "", err
+	}
+
+	port := node.SSHPort
+	if port == 0 {
+		port = 22
+	}
+	addr := net.JoinHostPort(node.Address, strconv.Itoa(port))
+	dialer := net.Dialer{Timeout: r.cfg.SSHTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("SSH handshake with %s failed: %w", addr, err)
+	}
+	client := ssh.NewClient(sshConn, chans, reqs)
+	defer client.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close()
+		case <-done:
+		}
+	}()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer session.Close()
+
+	var output bytes.Buffer
+	session.Stdin = strings.NewReader(script)
+	session.Stdout = &output
+	session.Stderr = &output
+
+	command := "sh -s"
+	if r.cfg.SSHUser != "root" {
+		command = "sudo -n sh -s"
+	}
+	if err := session.Run(command); err != nil {
+		if ctx.Err() != nil {
+			return output.String(), ctx.Err()
+		}
+		return output.String(), err
+	}
+	return output.String(), nil
+}
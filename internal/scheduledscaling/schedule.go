@@ -0,0 +1,75 @@
+package scheduledscaling
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/timewindow"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// maxSchedules bounds the schedules per service
+const maxSchedules = 20
+
+// Validate checks a service's schedules
+func Validate(schedules []domain.ScalingSchedule) error {
+	if len(schedules) > maxSchedules {
+		return errors.BadRequest(fmt.Sprintf("at most %d schedules are allowed", maxSchedules))
+	}
+
+	names := make(map[string]bool)
+	for _, s := range schedules {
+		if s.Name == "" {
+			return errors.BadRequest("schedule name is required")
+		}
+		if names[s.Name] {
+			return errors.BadRequest(fmt.Sprintf("duplicate schedule %q", s.Name))
+		}
+		names[s.Name] = true
+
+		if err := window(&s).Validate(); err != nil {
+			return errors.BadRequest(fmt.Sprintf("schedule %q: %s", s.Name, err))
+		}
+		if s.MinReplicas < 0 || s.MaxReplicas < 1 || s.MaxReplicas < s.MinReplicas {
+			return errors.BadRequest(fmt.Sprintf("schedule %q: replicas must satisfy 0 <= min <= max and max >= 1", s.Name))
+		}
+	}
+	return nil
+}
+
+// Active returns the schedule in effect at now, or nil. When schedules
+// overlap the one with the highest minimum (then maximum) wins, so an overlap
+// never scales a service below what either schedule asks for.
+func Active(schedules []domain.ScalingSchedule, now time.Time) *domain.ScalingSchedule {
+	var active *domain.ScalingSchedule
+	for i := range schedules {
+		s := &schedules[i]
+		if !inWindow(s, now) {
+			continue
+		}
+		if active == nil || s.MinReplicas > active.MinReplicas ||
+			(s.MinReplicas == active.MinReplicas && s.MaxReplicas > active.MaxReplicas) {
+			active = s
+		}
+	}
+	return active
+}
+
+// Desired returns the replica range a service should have at now and the
+// schedule responsible for it, if any
+func Desired(scaling domain.ScalingConfig, now time.Time) (min, max int32, schedule *domain.ScalingSchedule) {
+	if s := Active(scaling.Schedules, now); s != nil {
+		return s.MinReplicas, s.MaxReplicas, s
+	}
+	return scaling.MinReplicas, scaling.MaxReplicas, nil
+}
+
+// inWindow reports whether now falls within the schedule
+func inWindow(s *domain.ScalingSchedule, now time.Time) bool {
+	return window(s).Open(now)
+}
+
+func window(s *domain.ScalingSchedule) timewindow.Window {
+	return timewindow.Window{Days: s.Days, Start: s.Start, End: s.End, Timezone: s.Timezone}
+}
@@ -0,0 +1,49 @@
+package scheduledscaling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActive(t *testing.T) {
+	schedules := []domain.ScalingSchedule{
+		{Name: "business-hours", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "20:00", Timezone: "Europe/Berlin", MinReplicas: 10, MaxReplicas: 20},
+		{Name: "nightly-batch", Start: "23:00", End: "02:00", MinReplicas: 4, MaxReplicas: 8},
+		{Name: "launch", Days: []string{"wed"}, Start: "09:00", End: "12:00", Timezone: "Europe/Berlin", MinReplicas: 15, MaxReplicas: 30},
+	}
+
+	// Wednesday 2026-06-03 07:30 UTC is 09:30 in Berlin: both daytime schedules match, the larger wins
+	assert.Equal(t, "launch", Active(schedules, time.Date(2026, 6, 3, 7, 30, 0, 0, time.UTC)).Name)
+	// Wednesday 12:30 UTC
+	assert.Equal(t, "business-hours", Active(schedules, time.Date(2026, 6, 3, 12, 30, 0, 0, time.UTC)).Name)
+	// Saturday 10:00 UTC
+	assert.Nil(t, Active(schedules, time.Date(2026, 6, 6, 10, 0, 0, 0, time.UTC)))
+	// Sunday 01:00 UTC belongs to Saturday's overnight window
+	assert.Equal(t, "nightly-batch", Active(schedules, time.Date(2026, 6, 7, 1, 0, 0, 0, time.UTC)).Name)
+
+	min, max, active := Desired(domain.ScalingConfig{MinReplicas: 2, MaxReplicas: 5, Schedules: schedules}, time.Date(2026, 6, 6, 10, 0, 0, 0, time.UTC))
+	assert.Equal(t, int32(2), min)
+	assert.Equal(t, int32(5), max)
+	assert.Nil(t, active)
+}
+
+func TestValidate(t *testing.T) {
+	valid := domain.ScalingSchedule{Name: "day", Start: "08:00", End: "20:00", MinReplicas: 2, MaxReplicas: 4}
+	assert.NoError(t, Validate([]domain.ScalingSchedule{valid}))
+	assert.Error(t, Validate([]domain.ScalingSchedule{valid, valid}))
+
+	invalid := []domain.ScalingSchedule{
+		{Name: "", Start: "08:00", End: "20:00", MaxReplicas: 1},
+		{Name: "a", Start: "8am", End: "20:00", MaxReplicas: 1},
+		{Name: "a", Start: "08:00", End: "08:00", MaxReplicas: 1},
+		{Name: "a", Start: "08:00", End: "20:00", Days: []string{"someday"}, MaxReplicas: 1},
+		{Name: "a", Start: "08:00", End: "20:00", Timezone: "Mars/Olympus", MaxReplicas: 1},
+		{Name: "a", Start: "08:00", End: "20:00", MinReplicas: 5, MaxReplicas: 2},
+	}
+	for _, s := range invalid {
+		assert.Error(t, Validate([]domain.ScalingSchedule{s}), s)
+	}
+}
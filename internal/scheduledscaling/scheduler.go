@@ -0,0 +1,271 @@
+// Package scheduledscaling applies time-based scaling schedules. A schedule
+// overrides the min/max replicas of a service's HorizontalPodAutoscaler (or
+// KEDA ScaledObject) during a recurring window, so metric-based autoscaling
+// keeps working within the scheduled range; services without either have
+// their replica count clamped. Warm standby replicas raise the minimum on top.
+package scheduledscaling
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/envlifecycle"
+	"github.com/northstack/platform/internal/warmpool"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// reconcileInterval is how often schedules are re-evaluated
+	reconcileInterval = time.Minute
+	// AnnotationSchedule records the schedule currently applied to a workload
+	AnnotationSchedule = "openpaas.io/scaling-schedule"
+	// AnnotationWarmFloor records the minimum replicas raised for warm standby
+	AnnotationWarmFloor = "openpaas.io/warm-standby-floor"
+)
+
+// Scheduler reconciles scaling schedules onto workloads
+type Scheduler struct {
+	kube        domain.KubernetesClient
+	projectRepo domain.ProjectRepository
+	serviceRepo domain.ServiceRepository
+	eventBus    domain.EventBus
+	warmPool    *warmpool.Pool
+	logger      *logger.Logger
+}
+
+// NewScheduler creates a new Scheduler. warmPool may be nil, in which case
+// warm standby replicas are not maintained.
+func NewScheduler(kube domain.KubernetesClient, projectRepo domain.ProjectRepository, serviceRepo domain.ServiceRepository, eventBus domain.EventBus, warmPool *warmpool.Pool, log *logger.Logger) *Scheduler {
+	return &Scheduler{
+		kube:        kube,
+		projectRepo: projectRepo,
+		serviceRepo: serviceRepo,
+		eventBus:    eventBus,
+		warmPool:    warmPool,
+		logger:      log,
+	}
+}
+
+// Run reconciles every service each minute until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		s.reconcileAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) reconcileAll(ctx context.Context) {
+	projects, err := s.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("Failed to list projects for scheduled scaling")
+		return
+	}
+
+	now := time.Now()
+	for _, project := range projects {
+		services, err := s.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			s.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list services for scheduled scaling")
+			continue
+		}
+		for _, svc := range services {
+			if svc.TargetClusterID == nil {
+				continue
+			}
+			if err := s.Reconcile(ctx, svc, now); err != nil {
+				s.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Scheduled scaling failed")
+			}
+		}
+	}
+}
+
+// Range is the replica range a service should have at a point in time
+type Range struct {
+	Min      int32
+	Max      int32
+	Schedule string // Schedule in effect, empty outside every window
+	Warm     string // Minimum raised for warm standby, empty when not raised
+}
+
+// Resolve returns the replica range a service should have at now: the range of
+// its schedule in effect, with the minimum raised to the warm standby floor.
+// The autoscaling reconciler and KEDA apply under the same field manager as
+// the Scheduler, so they render this range too rather than undo it. warmPool
+// may be nil.
+func Resolve(ctx context.Context, svc *domain.Service, now time.Time, warmPool *warmpool.Pool) Range {
+	min, max, schedule := Desired(svc.Scaling, now)
+	r := Range{Min: min, Max: max}
+	if schedule != nil {
+		r.Schedule = schedule.Name
+	}
+	if warmPool != nil {
+		if floor := warmPool.Floor(ctx, svc, min, max); floor > min {
+			r.Min = floor
+			r.Warm = strconv.Itoa(int(floor))
+		}
+	}
+	return r
+}
+
+// Annotations records the schedule and warm standby floor on the object the
+// range is applied to, nil when neither applies
+func (r Range) Annotations() map[string]interface{} {
+	annotations := map[string]interface{}{}
+	if r.Schedule != "" {
+		annotations[AnnotationSchedule] = r.Schedule
+	}
+	if r.Warm != "" {
+		annotations[AnnotationWarmFloor] = r.Warm
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	return annotations
+}
+
+// Reconcile applies the replica range the service should have at now. Workloads
+// that were never touched by a schedule or warm standby are left alone when
+// neither applies.
+func (s *Scheduler) Reconcile(ctx context.Context, svc *domain.Service, now time.Time) error {
+	r := Resolve(ctx, svc, now, s.warmPool)
+	min, max, name, warm := r.Min, r.Max, r.Schedule, r.Warm
+
+	selector := map[string]string{domain.LabelServiceID: svc.ID.String()}
+
+	// KEDA owns the HPA of services with extra triggers, so its ScaledObject is patched instead
+	scaledObjects, err := s.kube.ListResources(ctx, *svc.TargetClusterID, "ScaledObject", "", selector)
+	if err == nil && len(scaledObjects) > 0 {
+		return s.apply(ctx, svc, scaledObjects[0], name, warm, func(obj map[string]interface{}) bool {
+			return setRange(obj, "minReplicaCount", "maxReplicaCount", min, max)
+		})
+	}
+
+	hpas, err := s.kube.ListResources(ctx, *svc.TargetClusterID, "HorizontalPodAutoscaler", "", selector)
+	if err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+
+	if len(hpas) > 0 {
+		return s.apply(ctx, svc, hpas[0], name, warm, func(obj map[string]interface{}) bool {
+			return setRange(obj, "minReplicas", "maxReplicas", min, max)
+		})
+	}
+
+	deployments, err := s.kube.ListResources(ctx, *svc.TargetClusterID, "Deployment", "", selector)
+	if err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	if len(deployments) == 0 {
+		return nil
+	}
+	if annotations, _, _ := unstructured.NestedStringMap(deployments[0], "metadata", "annotations"); annotations[envlifecycle.AnnotationReplicas] != "" {
+		return nil // Hibernated with its environment; waking restores its replicas
+	}
+	return s.apply(ctx, svc, deployments[0], name, warm, func(obj map[string]interface{}) bool {
+		replicas, _, _ := unstructured.NestedInt64(obj, "spec", "replicas")
+		clamped := clamp(replicas, int64(min), int64(max))
+		if clamped == replicas {
+			return false
+		}
+		unstructured.SetNestedField(obj, clamped, "spec", "replicas")
+		return true
+	})
+}
+
+// apply updates the object when the schedule or warm standby floor changed or
+// mutate reports a change
+func (s *Scheduler) apply(ctx context.Context, svc *domain.Service, obj map[string]interface{}, schedule, warm string, mutate func(map[string]interface{}) bool) error {
+	annotations, _, _ := unstructured.NestedStringMap(obj, "metadata", "annotations")
+	previous := annotations[AnnotationSchedule]
+	previousWarm := annotations[AnnotationWarmFloor]
+	if schedule == "" && previous == "" && warm == "" && previousWarm == "" {
+		return nil // Never scheduled; the base range is managed by deploys
+	}
+
+	changed := mutate(obj)
+	if !changed && schedule == previous && warm == previousWarm {
+		return nil
+	}
+
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	setAnnotation(annotations, AnnotationSchedule, schedule)
+	setAnnotation(annotations, AnnotationWarmFloor, warm)
+	unstructured.SetNestedStringMap(obj, annotations, "metadata", "annotations")
+	unstructured.RemoveNestedField(obj, "metadata", "managedFields")
+
+	manifest, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode workload")
+	}
+	if err := s.kube.ApplyManifest(ctx, *svc.TargetClusterID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+
+	s.logger.Info().
+		Str("service_id", svc.ID.String()).
+		Str("schedule", schedule).
+		Str("previous_schedule", previous).
+		Str("warm_standby_floor", warm).
+		Msg("Applied scaling schedule")
+
+	if schedule == previous {
+		return nil // Only the warm standby floor moved with demand
+	}
+
+	s.eventBus.Publish(ctx, "service.schedule_applied", &domain.Event{
+		Type:   "service.schedule_applied",
+		Source: "scheduled-scaling",
+		Data: map[string]interface{}{
+			"service_id":        svc.ID.String(),
+			"project_id":        svc.ProjectID.String(),
+			"schedule":          schedule,
+			"previous_schedule": previous,
+		},
+	})
+	return nil
+}
+
+// setRange sets the replica bounds under spec, reporting whether they changed
+func setRange(obj map[string]interface{}, minField, maxField string, min, max int32) bool {
+	curMin, _, _ := unstructured.NestedInt64(obj, "spec", minField)
+	curMax, _, _ := unstructured.NestedInt64(obj, "spec", maxField)
+	if curMin == int64(min) && curMax == int64(max) {
+		return false
+	}
+	unstructured.SetNestedField(obj, int64(min), "spec", minField)
+	unstructured.SetNestedField(obj, int64(max), "spec", maxField)
+	return true
+}
+
+func setAnnotation(annotations map[string]string, key, value string) {
+	if value == "" {
+		delete(annotations, key)
+	} else {
+		annotations[key] = value
+	}
+}
+
+func clamp(v, min, max int64) int64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,158 @@
+package secretsync
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// AnnotationVersion records the Vault version an ExternalSecret requests
+	// and the Secret it writes holds
+	AnnotationVersion = "openpaas.io/secret-version"
+
+	externalSecretsAPIVersion = "external-secrets.io/v1beta1"
+)
+
+// secretTypes maps platform secret types to Kubernetes Secret types
+var secretTypes = map[domain.SecretType]string{
+	domain.SecretTypeOpaque:       "Opaque",
+	domain.SecretTypeTLS:          "kubernetes.io/tls",
+	domain.SecretTypeDockerConfig: "kubernetes.io/dockerconfigjson",
+	domain.SecretTypeSSHAuth:      "kubernetes.io/ssh-auth",
+	domain.SecretTypeBasicAuth:    "kubernetes.io/basic-auth",
+}
+
+// ClusterSecretStore renders the store through which External Secrets
+// Operator in cluster reads Vault. Each cluster logs in through its own
+// Kubernetes auth mount, so Vault can tell clusters apart.
+func ClusterSecretStore(cfg *config.SecretReplicationConfig, vault *config.VaultConfig, cluster *domain.Cluster) map[string]interface{} {
+	saNamespace, saName, found := strings.Cut(cfg.ServiceAccount, "/")
+	if !found {
+		saNamespace, saName = "external-secrets", cfg.ServiceAccount
+	}
+
+	return map[string]interface{}{
+		"apiVersion": externalSecretsAPIVersion,
+		"kind":       "ClusterSecretStore",
+		"metadata": map[string]interface{}{
+			"name": cfg.StoreName,
+			"labels": map[string]interface{}{
+				domain.LabelManagedBy: domain.ManagedByValue,
+			},
+		},
+		"spec": map[string]interface{}{
+			"provider": map[string]interface{}{
+				"vault": map[string]interface{}{
+					"server":  vault.Address,
+					"path":    vault.MountPath,
+					"version": "v2",
+					"auth": map[string]interface{}{
+						"kubernetes": map[string]interface{}{
+							"mountPath": cfg.AuthMountPrefix + cluster.Slug,
+							"role":      vault.K8sRole,
+							"serviceAccountRef": map[string]interface{}{
+								"name":      saName,
+								"namespace": saNamespace,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// ExternalSecret renders the ExternalSecret that keeps namespace's copy of
+// secret at the given Vault version. Pinning the version means a rotation
+// reaches a cluster only once the platform re-applies it, so the annotation
+// on the written Secret tells which version the cluster really holds.
+func ExternalSecret(cfg *config.SecretReplicationConfig, secret *domain.Secret, namespace string, version int) map[string]interface{} {
+	v := strconv.Itoa(version)
+	labels := map[string]interface{}{
+		domain.LabelProjectID: secret.ProjectID.String(),
+		domain.LabelManagedBy: domain.ManagedByValue,
+	}
+	annotations := map[string]interface{}{AnnotationVersion: v}
+
+	secretType := secretTypes[secret.Type]
+	if secretType == "" {
+		secretType = "Opaque"
+	}
+
+	spec := map[string]interface{}{
+		"refreshInterval": cfg.RefreshInterval.String(),
+		"secretStoreRef": map[string]interface{}{
+			"kind": "ClusterSecretStore",
+			"name": cfg.StoreName,
+		},
+		"target": map[string]interface{}{
+			"name":           secret.Name,
+			"creationPolicy": "Owner",
+			"template": map[string]interface{}{
+				"type": secretType,
+				"metadata": map[string]interface{}{
+					"labels":      labels,
+					"annotations": annotations,
+				},
+			},
+		},
+	}
+
+	// Only copy the declared keys; without any, copy the whole secret
+	if len(secret.Keys) > 0 {
+		data := make([]interface{}, 0, len(secret.Keys))
+		for _, key := range secret.Keys {
+			data = append(data, map[string]interface{}{
+				"secretKey": key,
+				"remoteRef": map[string]interface{}{
+					"key":      secret.VaultPath,
+					"property": key,
+					"version":  v,
+				},
+			})
+		}
+		spec["data"] = data
+	} else {
+		spec["dataFrom"] = []interface{}{
+			map[string]interface{}{
+				"extract": map[string]interface{}{
+					"key":     secret.VaultPath,
+					"version": v,
+				},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"apiVersion": externalSecretsAPIVersion,
+		"kind":       "ExternalSecret",
+		"metadata": map[string]interface{}{
+			"name":        secret.Name,
+			"namespace":   namespace,
+			"labels":      labels,
+			"annotations": annotations,
+		},
+		"spec": spec,
+	}
+}
+
+// MarshalYAML renders manifests as a multi-document YAML stream
+func MarshalYAML(manifests []map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, manifest := range manifests {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		doc, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(doc)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,526 @@
+// Package secretsync replicates the Vault secrets bound to a service into
+// every cluster the service is deployed to. External Secrets Operator
+// delivers the values; the platform pins each cluster's copy to the current
+// Vault version, records which version every cluster holds and raises a
+// drift alert when a copy stays behind.
+package secretsync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/alerting"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// RuleSecretDrift is the name of the alert raised for stale cluster copies
+	RuleSecretDrift = "SecretReplicationDrift"
+	// deploymentHistory bounds the deployments searched for a service's clusters
+	deploymentHistory = 50
+)
+
+// VersionSource reports the current version of a secret in Vault
+type VersionSource interface {
+	CurrentVersion(ctx context.Context, path string) (int, error)
+}
+
+// Replicator keeps cluster copies of bound secrets at the Vault version
+type Replicator struct {
+	config          *config.SecretReplicationConfig
+	vaultConfig     *config.VaultConfig
+	vault           VersionSource
+	projectRepo     domain.ProjectRepository
+	serviceRepo     domain.ServiceRepository
+	secretRepo      domain.SecretRepository
+	deployRepo      domain.DeploymentRepository
+	clusterRepo     domain.ClusterRepository
+	environmentRepo domain.EnvironmentRepository
+	replicationRepo domain.SecretReplicationRepository
+	kube            domain.KubernetesClient
+	alerts          *alerting.Manager
+	logger          *logger.Logger
+}
+
+// target is a namespace a service runs in
+type target struct {
+	cluster   *domain.Cluster
+	namespace string
+}
+
+// NewReplicator creates a new Replicator. kube may be nil to only render
+// manifests for GitOps, in which case copies are never confirmed; alerts may
+// be nil to only record status.
+func NewReplicator(
+	cfg *config.SecretReplicationConfig,
+	vaultCfg *config.VaultConfig,
+	vault VersionSource,
+	projectRepo domain.ProjectRepository,
+	serviceRepo domain.ServiceRepository,
+	secretRepo domain.SecretRepository,
+	deployRepo domain.DeploymentRepository,
+	clusterRepo domain.ClusterRepository,
+	environmentRepo domain.EnvironmentRepository,
+	replicationRepo domain.SecretReplicationRepository,
+	kube domain.KubernetesClient,
+	alerts *alerting.Manager,
+	log *logger.Logger,
+) *Replicator {
+	return &Replicator{
+		config:          cfg,
+		vaultConfig:     vaultCfg,
+		vault:           vault,
+		projectRepo:     projectRepo,
+		serviceRepo:     serviceRepo,
+		secretRepo:      secretRepo,
+		deployRepo:      deployRepo,
+		clusterRepo:     clusterRepo,
+		environmentRepo: environmentRepo,
+		replicationRepo: replicationRepo,
+		kube:            kube,
+		alerts:          alerts,
+		logger:          log,
+	}
+}
+
+// Run reconciles every service with bound secrets each Interval until ctx is cancelled
+func (r *Replicator) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		r.reconcileAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Watch reconciles a service's secrets as soon as a deployment completes,
+// which is when it may have reached a new cluster. Events go through a
+// durable consumer, so one replica handles each and failures are retried;
+// reconciling is idempotent.
+func (r *Replicator) Watch(ctx context.Context, bus domain.EventBus) error {
+	_, err := eventbus.SubscribeDurable(ctx, bus, "deploy.completed", eventbus.DurableName("secretsync", "deploy.completed"), func(event *domain.Event) error {
+		raw, _ := event.Data["service_id"].(string)
+		serviceID, err := uuid.Parse(raw)
+		if err != nil {
+			return nil
+		}
+
+		svc, err := r.serviceRepo.GetByID(ctx, serviceID)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			r.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to load service for secret replication")
+			return err
+		}
+		if len(svc.SecretRefs) == 0 {
+			return nil
+		}
+
+		if _, err := r.Reconcile(ctx, svc); err != nil {
+			r.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to replicate secrets")
+			return err
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *Replicator) reconcileAll(ctx context.Context) {
+	projects, err := r.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		r.logger.Warn().Err(err).Msg("Failed to list projects for secret replication")
+		return
+	}
+
+	for _, project := range projects {
+		services, err := r.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			r.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list services for secret replication")
+			continue
+		}
+		for _, svc := range services {
+			if len(svc.SecretRefs) == 0 {
+				continue
+			}
+			if _, err := r.Reconcile(ctx, svc); err != nil {
+				r.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Failed to replicate secrets")
+			}
+		}
+	}
+}
+
+// Reconcile brings every cluster copy of the service's secrets to the
+// current Vault version and records where each copy stands
+func (r *Replicator) Reconcile(ctx context.Context, svc *domain.Service) ([]*domain.SecretReplication, error) {
+	targets, err := r.targets(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := r.boundSecrets(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	stores := map[uuid.UUID]bool{}
+	replications := []*domain.SecretReplication{}
+	for _, secret := range secrets {
+		existing, err := r.replicationRepo.ListBySecret(ctx, secret.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			replication, err := r.replicate(ctx, secret, t, find(existing, t), stores)
+			if err != nil {
+				return nil, err
+			}
+			replications = append(replications, replication)
+		}
+	}
+
+	return replications, nil
+}
+
+// Status returns where each cluster copy of the service's secrets stood at
+// the last check
+func (r *Replicator) Status(ctx context.Context, svc *domain.Service) ([]*domain.SecretReplication, error) {
+	targets, err := r.targets(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	all, err := r.replicationRepo.ListByProject(ctx, svc.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	bound := make(map[string]bool, len(svc.SecretRefs))
+	for _, name := range svc.SecretRefs {
+		bound[name] = true
+	}
+
+	replications := []*domain.SecretReplication{}
+	for _, replication := range all {
+		if !bound[replication.SecretName] {
+			continue
+		}
+		for _, t := range targets {
+			if t.cluster.ID == replication.ClusterID && t.namespace == replication.Namespace {
+				replications = append(replications, replication)
+				break
+			}
+		}
+	}
+	return replications, nil
+}
+
+// Manifests renders the ClusterSecretStore and ExternalSecrets that carry
+// the service's secrets into a cluster, for clusters managed through GitOps
+func (r *Replicator) Manifests(ctx context.Context, svc *domain.Service, clusterID uuid.UUID) ([]map[string]interface{}, error) {
+	targets, err := r.targets(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	var namespaces []string
+	var cluster *domain.Cluster
+	for _, t := range targets {
+		if t.cluster.ID == clusterID {
+			cluster = t.cluster
+			namespaces = append(namespaces, t.namespace)
+		}
+	}
+	if cluster == nil {
+		return nil, errors.NotFound("deployment of service to cluster", clusterID.String())
+	}
+
+	secrets, err := r.boundSecrets(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := []map[string]interface{}{ClusterSecretStore(r.config, r.vaultConfig, cluster)}
+	for _, secret := range secrets {
+		for _, namespace := range namespaces {
+			manifests = append(manifests, ExternalSecret(r.config, secret, namespace, secret.Version))
+		}
+	}
+	return manifests, nil
+}
+
+// replicate applies one secret to one namespace and records the outcome
+func (r *Replicator) replicate(ctx context.Context, secret *domain.Secret, t target, replication *domain.SecretReplication, stores map[uuid.UUID]bool) (*domain.SecretReplication, error) {
+	now := time.Now().UTC()
+	if replication == nil {
+		replication = &domain.SecretReplication{
+			ID:           uuid.New(),
+			SecretID:     secret.ID,
+			ProjectID:    secret.ProjectID,
+			ClusterID:    t.cluster.ID,
+			Namespace:    t.namespace,
+			DesiredSince: now,
+			CreatedAt:    now,
+		}
+	}
+	previous := replication.Status
+	replication.SecretName = secret.Name
+	replication.UpdatedAt = now
+	if replication.DesiredVersion != secret.Version {
+		replication.DesiredVersion = secret.Version
+		replication.DesiredSince = now
+	}
+
+	if r.kube == nil {
+		replication.Status = domain.SecretReplicationPending
+		replication.Message = "no Kubernetes client for workload clusters; apply the manifests for this cluster"
+		replication.CheckedAt = now
+	} else {
+		var applyErr error
+		if replication.Status != domain.SecretReplicationSynced || replication.SyncedVersion != replication.DesiredVersion {
+			applyErr = r.apply(ctx, secret, t, stores)
+		}
+
+		copied, reason := r.read(ctx, secret, t)
+		if applyErr != nil {
+			reason = applyErr.Error()
+		}
+		observe(replication, copied, reason, now, r.config.StaleAfter)
+	}
+
+	if err := r.replicationRepo.Upsert(ctx, replication); err != nil {
+		return nil, err
+	}
+	r.alert(ctx, replication, previous, t.cluster)
+	return replication, nil
+}
+
+// apply writes the cluster's store, once per reconcile, and the ExternalSecret
+func (r *Replicator) apply(ctx context.Context, secret *domain.Secret, t target, stores map[uuid.UUID]bool) error {
+	if !stores[t.cluster.ID] {
+		manifest, err := json.Marshal(ClusterSecretStore(r.config, r.vaultConfig, t.cluster))
+		if err != nil {
+			return errors.Wrap(err, "failed to encode ClusterSecretStore")
+		}
+		if err := r.kube.ApplyManifest(ctx, t.cluster.ID, manifest); err != nil {
+			return errors.DependencyFailed("kubernetes", err)
+		}
+		stores[t.cluster.ID] = true
+	}
+
+	manifest, err := json.Marshal(ExternalSecret(r.config, secret, t.namespace, secret.Version))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode ExternalSecret")
+	}
+	if err := r.kube.ApplyManifest(ctx, t.cluster.ID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+// read returns the Secret written into the namespace, if any, and why the
+// ExternalSecret is not ready
+func (r *Replicator) read(ctx context.Context, secret *domain.Secret, t target) (map[string]interface{}, string) {
+	externalSecret, err := r.kube.GetResource(ctx, t.cluster.ID, "ExternalSecret", t.namespace, secret.Name)
+	if err != nil {
+		return nil, "failed to read ExternalSecret: " + err.Error()
+	}
+	reason := notReadyReason(externalSecret)
+
+	copied, err := r.kube.GetResource(ctx, t.cluster.ID, "Secret", t.namespace, secret.Name)
+	if err != nil {
+		// Not written yet
+		return nil, reason
+	}
+	return copied, reason
+}
+
+// observe records the version held by the cluster's copy. A copy behind
+// Vault is pending, or failed when ESO reports an error, until it has been
+// behind for staleAfter; from then on it is stale.
+func observe(replication *domain.SecretReplication, copied map[string]interface{}, reason string, now time.Time, staleAfter time.Duration) {
+	replication.CheckedAt = now
+	replication.SyncedVersion = 0
+	if copied != nil {
+		value, _, _ := unstructured.NestedString(copied, "metadata", "annotations", AnnotationVersion)
+		replication.SyncedVersion, _ = strconv.Atoi(value)
+	}
+
+	switch {
+	case replication.DesiredVersion > 0 && replication.SyncedVersion >= replication.DesiredVersion:
+		replication.Status = domain.SecretReplicationSynced
+		replication.Message = ""
+		replication.LastSyncedAt = &now
+	case now.Sub(replication.DesiredSince) >= staleAfter:
+		replication.Status = domain.SecretReplicationStale
+		replication.Message = fmt.Sprintf("cluster holds version %d, Vault is at version %d", replication.SyncedVersion, replication.DesiredVersion)
+		if reason != "" {
+			replication.Message += ": " + reason
+		}
+	case reason != "":
+		replication.Status = domain.SecretReplicationFailed
+		replication.Message = reason
+	default:
+		replication.Status = domain.SecretReplicationPending
+		replication.Message = "waiting for External Secrets Operator"
+	}
+}
+
+// notReadyReason returns the message of a false Ready condition
+func notReadyReason(externalSecret map[string]interface{}) string {
+	conditions, _, _ := unstructured.NestedSlice(externalSecret, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" || condition["status"] != "False" {
+			continue
+		}
+		message, _ := condition["message"].(string)
+		if message == "" {
+			message, _ = condition["reason"].(string)
+		}
+		return message
+	}
+	return ""
+}
+
+// alert fires a drift alert while a copy is stale and resolves it once the
+// copy is no longer stale
+func (r *Replicator) alert(ctx context.Context, replication *domain.SecretReplication, previous domain.SecretReplicationStatus, cluster *domain.Cluster) {
+	if r.alerts == nil {
+		return
+	}
+
+	fingerprint := RuleSecretDrift + "/" + replication.SecretID.String() + "/" + replication.ClusterID.String() + "/" + replication.Namespace
+	switch {
+	case replication.Status == domain.SecretReplicationStale:
+		projectID, clusterID := replication.ProjectID, replication.ClusterID
+		_, err := r.alerts.Fire(ctx, &domain.Alert{
+			Fingerprint: fingerprint,
+			Name:        RuleSecretDrift,
+			Severity:    "warning",
+			Source:      alerting.SourcePlatform,
+			Message:     fmt.Sprintf("Secret %s in %s/%s is stale: %s", replication.SecretName, cluster.Name, replication.Namespace, replication.Message),
+			Labels: map[string]string{
+				"secret":    replication.SecretName,
+				"cluster":   cluster.Name,
+				"namespace": replication.Namespace,
+			},
+			ProjectID: &projectID,
+			ClusterID: &clusterID,
+		})
+		if err != nil {
+			r.logger.Error().Err(err).Str("secret_id", replication.SecretID.String()).Msg("Failed to fire secret drift alert")
+		}
+	case previous == domain.SecretReplicationStale:
+		if _, err := r.alerts.Resolve(ctx, fingerprint, replication.CheckedAt); err != nil {
+			r.logger.Error().Err(err).Str("secret_id", replication.SecretID.String()).Msg("Failed to resolve secret drift alert")
+		}
+	}
+}
+
+// boundSecrets resolves the service's secret references, refreshing each
+// secret's version from Vault. Unknown references are skipped.
+func (r *Replicator) boundSecrets(ctx context.Context, svc *domain.Service) ([]*domain.Secret, error) {
+	var secrets []*domain.Secret
+	for _, name := range svc.SecretRefs {
+		secret, err := r.secretRepo.GetByName(ctx, svc.ProjectID, name)
+		if errors.IsNotFound(err) {
+			r.logger.Warn().Str("service_id", svc.ID.String()).Str("secret", name).Msg("Service references an unknown secret")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		version, err := r.vault.CurrentVersion(ctx, secret.VaultPath)
+		if err != nil {
+			// Keep replicating the last known version
+			r.logger.Warn().Err(err).Str("secret", name).Msg("Failed to read secret version from Vault")
+		} else if version != secret.Version {
+			secret.Version = version
+			if err := r.secretRepo.Update(ctx, secret); err != nil {
+				return nil, err
+			}
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// targets returns the namespaces the service runs in: those of the project's
+// environments on each cluster the service is targeted or was deployed to
+func (r *Replicator) targets(ctx context.Context, svc *domain.Service) ([]target, error) {
+	var clusterIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	add := func(id uuid.UUID) {
+		if id != uuid.Nil && !seen[id] {
+			seen[id] = true
+			clusterIDs = append(clusterIDs, id)
+		}
+	}
+
+	if svc.TargetClusterID != nil {
+		add(*svc.TargetClusterID)
+	}
+	deployments, err := r.deployRepo.ListByService(ctx, svc.ID, deploymentHistory)
+	if err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments {
+		if deployment.Status == domain.DeploymentStatusSucceeded {
+			add(deployment.ClusterID)
+		}
+	}
+
+	environments, err := r.environmentRepo.ListByProject(ctx, svc.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []target
+	for _, id := range clusterIDs {
+		cluster, err := r.clusterRepo.GetByID(ctx, id)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		found := false
+		for _, env := range environments {
+			if env.ClusterID == id && env.Namespace != "" {
+				targets = append(targets, target{cluster: cluster, namespace: env.Namespace})
+				found = true
+			}
+		}
+		if !found {
+			r.logger.Warn().
+				Str("service_id", svc.ID.String()).
+				Str("cluster_id", id.String()).
+				Msg("Service runs on a cluster without a project environment; secrets are not replicated there")
+		}
+	}
+	return targets, nil
+}
+
+func find(replications []*domain.SecretReplication, t target) *domain.SecretReplication {
+	for _, replication := range replications {
+		if replication.ClusterID == t.cluster.ID && replication.Namespace == t.namespace {
+			return replication
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package secretsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func copyAt(version string) map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{AnnotationVersion: version},
+		},
+	}
+}
+
+func TestObserve(t *testing.T) {
+	now := time.Now()
+	replication := &domain.SecretReplication{DesiredVersion: 3, DesiredSince: now.Add(-time.Minute)}
+
+	observe(replication, copyAt("3"), "", now, 10*time.Minute)
+	assert.Equal(t, domain.SecretReplicationSynced, replication.Status)
+	assert.Equal(t, 3, replication.SyncedVersion)
+	assert.NotNil(t, replication.LastSyncedAt)
+
+	observe(replication, copyAt("2"), "", now, 10*time.Minute)
+	assert.Equal(t, domain.SecretReplicationPending, replication.Status)
+
+	observe(replication, nil, "could not get secret data from provider", now, 10*time.Minute)
+	assert.Equal(t, domain.SecretReplicationFailed, replication.Status)
+	assert.Equal(t, 0, replication.SyncedVersion)
+
+	// Behind for longer than the grace period
+	replication.DesiredSince = now.Add(-time.Hour)
+	observe(replication, copyAt("2"), "", now, 10*time.Minute)
+	assert.Equal(t, domain.SecretReplicationStale, replication.Status)
+	assert.Equal(t, "cluster holds version 2, Vault is at version 3", replication.Message)
+}
+
+func TestNotReadyReason(t *testing.T) {
+	externalSecret := map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "False", "reason": "SecretSyncedError", "message": "permission denied"},
+			},
+		},
+	}
+	assert.Equal(t, "permission denied", notReadyReason(externalSecret))
+
+	externalSecret["status"] = map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+	}
+	assert.Empty(t, notReadyReason(externalSecret))
+}
+
+func TestExternalSecret(t *testing.T) {
+	cfg := &config.SecretReplicationConfig{StoreName: "northstack-vault", RefreshInterval: time.Hour}
+	secret := &domain.Secret{
+		ProjectID: uuid.New(),
+		Name:      "db-credentials",
+		Type:      domain.SecretTypeBasicAuth,
+		Keys:      []string{"username", "password"},
+		VaultPath: "payments/db",
+	}
+
+	manifest := ExternalSecret(cfg, secret, "payments-prod", 4)
+	version, _, _ := unstructured.NestedString(manifest, "metadata", "annotations", AnnotationVersion)
+	assert.Equal(t, "4", version)
+	secretType, _, _ := unstructured.NestedString(manifest, "spec", "target", "template", "type")
+	assert.Equal(t, "kubernetes.io/basic-auth", secretType)
+
+	data, _, _ := unstructured.NestedSlice(manifest, "spec", "data")
+	assert.Len(t, data, 2)
+	property, _, _ := unstructured.NestedString(data[1].(map[string]interface{}), "remoteRef", "property")
+	assert.Equal(t, "password", property)
+
+	// Without declared keys the whole secret is copied
+	secret.Keys = nil
+	manifest = ExternalSecret(cfg, secret, "payments-prod", 4)
+	_, found, _ := unstructured.NestedSlice(manifest, "spec", "dataFrom")
+	assert.True(t, found)
+}
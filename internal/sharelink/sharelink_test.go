@@ -0,0 +1,58 @@
+package sharelink
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	cfg := &config.ShareLinksConfig{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour}
+	signer := NewSigner(cfg, "jwt-secret")
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	claims := Claims{
+		Resource:    ResourceDeployment,
+		ID:          uuid.New(),
+		IncludeLogs: true,
+		ExpiresAt:   now.Add(time.Hour).Unix(),
+	}
+	token, err := signer.Sign(claims)
+	require.NoError(t, err)
+
+	got, err := signer.Verify(token, now)
+	require.NoError(t, err)
+	assert.Equal(t, claims, *got)
+
+	_, err = signer.Verify(token, now.Add(time.Hour))
+	assert.Error(t, err, "expired")
+
+	_, err = NewSigner(cfg, "other-secret").Verify(token, now)
+	assert.Error(t, err, "signed with another key")
+
+	// Flipping IncludeLogs in the payload invalidates the signature
+	claims.IncludeLogs = false
+	forged, _ := signer.Sign(claims)
+	_, sig, _ := strings.Cut(token, ".")
+	payload, _, _ := strings.Cut(forged, ".")
+	_, err = signer.Verify(payload+"."+sig, now)
+	assert.Error(t, err, "tampered")
+}
+
+func TestTTL(t *testing.T) {
+	signer := NewSigner(&config.ShareLinksConfig{DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour}, "s")
+
+	ttl, err := signer.TTL(0)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	_, err = signer.TTL(48 * time.Hour)
+	assert.Error(t, err)
+	_, err = signer.TTL(time.Second)
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package signing
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/adapters/vault"
+)
+
+// JWK is the public half of one key version
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// KeySet is a JSON Web Key Set
+type KeySet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type cachedKeySet struct {
+	set     *KeySet
+	fetched time.Time
+}
+
+// JWKS returns the public keys consumers verify signatures with: the
+// current version of every organization's key, plus versions replaced less
+// than Overlap ago so that messages signed just before a rotation still verify
+func (s *Signer) JWKS(ctx context.Context) (*KeySet, error) {
+	s.mu.Lock()
+	cached := s.jwks
+	s.mu.Unlock()
+	if cached != nil && time.Since(cached.fetched) < keyCacheTTL {
+		return cached.set, nil
+	}
+
+	names, err := s.keyNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &KeySet{Keys: []JWK{}}
+	now := time.Now()
+	for _, name := range names {
+		key, err := s.transit.TransitKey(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, version := range publishedVersions(key, s.config.Overlap, now) {
+			jwk, err := publicJWK(KeyID(name, version), key.Versions[version].PublicKey)
+			if err != nil {
+				s.logger.Warn().Err(err).Str("key", name).Int("version", version).Msg("Skipping unusable signing key version")
+				continue
+			}
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	s.mu.Lock()
+	s.jwks = &cachedKeySet{set: set, fetched: time.Now()}
+	s.mu.Unlock()
+	return set, nil
+}
+
+// keyNames returns the transit keys that belong to the signer
+func (s *Signer) keyNames(ctx context.Context) ([]string, error) {
+	all, err := s.transit.ListTransitKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range all {
+		if strings.HasPrefix(name, s.config.KeyPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// publishedVersions returns the latest version of a key and every version
+// whose successor was created less than overlap before now, newest first
+func publishedVersions(key *vault.TransitKey, overlap time.Duration, now time.Time) []int {
+	var versions []int
+	for version := key.LatestVersion; version > 0; version-- {
+		if _, ok := key.Versions[version]; !ok {
+			continue
+		}
+		if version < key.LatestVersion {
+			successor, ok := key.Versions[version+1]
+			if !ok || now.Sub(successor.CreatedAt) >= overlap {
+				break
+			}
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// publicJWK converts a PEM encoded P-256 public key to a JWK
+func publicJWK(kid, publicKey string) (JWK, error) {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return JWK{}, fmt.Errorf("key %s has no PEM public key", kid)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return JWK{}, fmt.Errorf("failed to parse key %s: %w", kid, err)
+	}
+	ecKey, ok := parsed.(*ecdsa.PublicKey)
+	if !ok {
+		return JWK{}, fmt.Errorf("key %s is not an ECDSA key", kid)
+	}
+	ecdhKey, err := ecKey.ECDH()
+	if err != nil || ecKey.Curve.Params().Name != "P-256" {
+		return JWK{}, fmt.Errorf("key %s is not a P-256 key", kid)
+	}
+
+	// Uncompressed point: 0x04 || X || Y
+	point := ecdhKey.Bytes()
+	size := (len(point) - 1) / 2
+	return JWK{
+		KeyType:   "EC",
+		Curve:     "P-256",
+		X:         base64.RawURLEncoding.EncodeToString(point[1 : 1+size]),
+		Y:         base64.RawURLEncoding.EncodeToString(point[1+size:]),
+		KeyID:     kid,
+		Algorithm: Algorithm,
+		Use:       "sig",
+	}, nil
+}
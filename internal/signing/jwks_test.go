@@ -0,0 +1,59 @@
+package signing
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/adapters/vault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishedVersions(t *testing.T) {
+	now := time.Now()
+	key := &vault.TransitKey{
+		LatestVersion: 3,
+		Versions: map[int]vault.TransitKeyVersion{
+			1: {CreatedAt: now.Add(-60 * 24 * time.Hour)},
+			2: {CreatedAt: now.Add(-30 * 24 * time.Hour)},
+			3: {CreatedAt: now.Add(-24 * time.Hour)},
+		},
+	}
+
+	// Version 2 was replaced a day ago, version 1 a month ago
+	assert.Equal(t, []int{3, 2}, publishedVersions(key, 7*24*time.Hour, now))
+	assert.Equal(t, []int{3}, publishedVersions(key, time.Hour, now))
+	assert.Equal(t, []int{3, 2, 1}, publishedVersions(key, 45*24*time.Hour, now))
+}
+
+func TestPublicJWK(t *testing.T) {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	require.NoError(t, err)
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	jwk, err := publicJWK("northstack-org-default.v1", string(encoded))
+	require.NoError(t, err)
+	assert.Equal(t, "EC", jwk.KeyType)
+	assert.Equal(t, "P-256", jwk.Curve)
+	assert.Equal(t, "ES256", jwk.Algorithm)
+	assert.Equal(t, "northstack-org-default.v1", jwk.KeyID)
+
+	x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+	require.NoError(t, err)
+	y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+	require.NoError(t, err)
+	assert.Zero(t, private.PublicKey.X.Cmp(new(big.Int).SetBytes(x)))
+	assert.Zero(t, private.PublicKey.Y.Cmp(new(big.Int).SetBytes(y)))
+
+	_, err = publicJWK("bad", "not a key")
+	assert.Error(t, err)
+}
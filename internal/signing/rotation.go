@@ -0,0 +1,61 @@
+package signing
+
+import (
+	"context"
+	"time"
+)
+
+// rotationCheckInterval is how often keys are checked for their age
+const rotationCheckInterval = time.Hour
+
+// Run rotates keys older than RotationPeriod until ctx is cancelled
+func (s *Signer) Run(ctx context.Context) {
+	ticker := time.NewTicker(rotationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.rotateDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Signer) rotateDue(ctx context.Context) {
+	if s.config.RotationPeriod <= 0 {
+		return
+	}
+
+	names, err := s.keyNames(ctx)
+	if err != nil {
+		s.logger.Warn().Err(err).Msg("Failed to list signing keys for rotation")
+		return
+	}
+
+	for _, name := range names {
+		key, err := s.transit.TransitKey(ctx, name)
+		if err != nil {
+			s.logger.Warn().Err(err).Str("key", name).Msg("Failed to read signing key for rotation")
+			continue
+		}
+		latest, ok := key.Versions[key.LatestVersion]
+		if !ok || time.Since(latest.CreatedAt) < s.config.RotationPeriod {
+			continue
+		}
+
+		if err := s.transit.RotateTransitKey(ctx, name); err != nil {
+			s.logger.Error().Err(err).Str("key", name).Msg("Failed to rotate signing key")
+			continue
+		}
+		s.forget(name)
+
+		s.logger.Info().
+			Str("key", name).
+			Int("replaced_version", key.LatestVersion).
+			Dur("overlap", s.config.Overlap).
+			Msg("Rotated signing key")
+	}
+}
@@ -0,0 +1,202 @@
+// Package signing signs outbound webhooks and published events with a key
+// per organization (team) held in Vault transit, so consumers can verify
+// where a message came from without any shared secret. Signatures are
+// detached JWS (RFC 7515, appendix F) using ES256; the public keys are
+// served as a JWKS, and keys are rotated on a schedule while the replaced
+// version stays published for an overlap window.
+package signing
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/adapters/vault"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// DefaultOrg signs messages that belong to no team
+	DefaultOrg = "default"
+	// Algorithm is the JWS algorithm of every signature
+	Algorithm = "ES256"
+	// keyCacheTTL bounds how long a key's latest version is trusted before re-reading it
+	keyCacheTTL = time.Minute
+	// orgCacheTTL bounds how long a project's organization is trusted, should
+	// the event that evicts it be missed
+	orgCacheTTL = time.Minute
+)
+
+// Transit is the Vault transit engine holding the signing keys
+type Transit interface {
+	CreateTransitKey(ctx context.Context, name string) error
+	RotateTransitKey(ctx context.Context, name string) error
+	TransitKey(ctx context.Context, name string) (*vault.TransitKey, error)
+	ListTransitKeys(ctx context.Context) ([]string, error)
+	TransitSign(ctx context.Context, name string, version int, input []byte) (string, error)
+}
+
+// Signer signs payloads with the key of the organization they belong to
+type Signer struct {
+	config      *config.SigningConfig
+	transit     Transit
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+
+	mu   sync.Mutex
+	keys map[string]cachedKey
+	orgs map[uuid.UUID]cachedOrg // Project ID to organization
+	jwks *cachedKeySet
+}
+
+type cachedKey struct {
+	key     *vault.TransitKey
+	fetched time.Time
+}
+
+type cachedOrg struct {
+	org     string
+	fetched time.Time
+}
+
+// NewSigner creates a new Signer. projectRepo may be nil, in which case every
+// event is signed with the default organization's key.
+func NewSigner(cfg *config.SigningConfig, transit Transit, projectRepo domain.ProjectRepository, log *logger.Logger) *Signer {
+	return &Signer{
+		config:      cfg,
+		transit:     transit,
+		projectRepo: projectRepo,
+		logger:      log,
+		keys:        make(map[string]cachedKey),
+		orgs:        make(map[uuid.UUID]cachedOrg),
+	}
+}
+
+// KeyName returns the transit key of an organization
+func (s *Signer) KeyName(org string) string {
+	return s.config.KeyPrefix + org
+}
+
+// Sign returns a detached JWS over payload made with the organization's
+// current key, creating the key on first use
+func (s *Signer) Sign(ctx context.Context, org string, payload []byte) (string, error) {
+	name := s.KeyName(org)
+	key, err := s.key(ctx, name)
+	if err != nil {
+		return "", err
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": Algorithm, "kid": KeyID(name, key.LatestVersion)})
+	encodedHeader := base64.RawURLEncoding.EncodeToString(header)
+	input := encodedHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	signature, err := s.transit.TransitSign(ctx, name, key.LatestVersion, []byte(input))
+	if err != nil {
+		return "", err
+	}
+	return encodedHeader + ".." + strings.TrimRight(signature, "="), nil
+}
+
+// SignEvent signs an encoded event with the key of the team owning the
+// event's project
+func (s *Signer) SignEvent(ctx context.Context, event *domain.Event, payload []byte) (string, error) {
+	return s.Sign(ctx, s.eventOrg(ctx, event), payload)
+}
+
+// OrgForProject returns the organization a project belongs to
+func OrgForProject(project *domain.Project) string {
+	if project.TeamID == nil {
+		return DefaultOrg
+	}
+	return project.TeamID.String()
+}
+
+// KeyID returns the JWS key ID of a key version
+func KeyID(name string, version int) string {
+	return fmt.Sprintf("%s.v%d", name, version)
+}
+
+func (s *Signer) eventOrg(ctx context.Context, event *domain.Event) string {
+	raw, _ := event.Data["project_id"].(string)
+	projectID, err := uuid.Parse(raw)
+	if err != nil {
+		return DefaultOrg
+	}
+	return s.ProjectOrg(ctx, projectID)
+}
+
+// ProjectOrg returns the organization whose key signs a project's messages
+func (s *Signer) ProjectOrg(ctx context.Context, projectID uuid.UUID) string {
+	if s.projectRepo == nil {
+		return DefaultOrg
+	}
+
+	s.mu.Lock()
+	cached, ok := s.orgs[projectID]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetched) < orgCacheTTL {
+		return cached.org
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		// A deleted project's last events still get signed
+		return DefaultOrg
+	}
+	org := OrgForProject(project)
+
+	s.mu.Lock()
+	s.orgs[projectID] = cachedOrg{org: org, fetched: time.Now()}
+	s.mu.Unlock()
+	return org
+}
+
+// Forget evicts the cached organization of a project
+func (s *Signer) Forget(projectID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.orgs, projectID)
+	s.mu.Unlock()
+}
+
+// key returns a transit key, creating it when it does not exist yet
+func (s *Signer) key(ctx context.Context, name string) (*vault.TransitKey, error) {
+	s.mu.Lock()
+	cached, ok := s.keys[name]
+	s.mu.Unlock()
+	if ok && time.Since(cached.fetched) < keyCacheTTL {
+		return cached.key, nil
+	}
+
+	key, err := s.transit.TransitKey(ctx, name)
+	if errors.IsNotFound(err) {
+		if err := s.transit.CreateTransitKey(ctx, name); err != nil {
+			return nil, err
+		}
+		s.logger.Info().Str("key", name).Msg("Created signing key")
+		key, err = s.transit.TransitKey(ctx, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.keys[name] = cachedKey{key: key, fetched: time.Now()}
+	s.mu.Unlock()
+	return key, nil
+}
+
+// forget drops a cached key so that its next use reads the latest version
+func (s *Signer) forget(name string) {
+	s.mu.Lock()
+	delete(s.keys, name)
+	s.jwks = nil
+	s.mu.Unlock()
+}
@@ -0,0 +1,38 @@
+package signing
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+type movableProjects struct {
+	domain.ProjectRepository
+	project *domain.Project
+}
+
+func (f *movableProjects) GetByID(context.Context, uuid.UUID) (*domain.Project, error) {
+	moved := *f.project
+	return &moved, nil
+}
+
+func TestProjectOrgFollowsMovedProjects(t *testing.T) {
+	team := uuid.New()
+	project := &domain.Project{ID: uuid.New(), OwnerID: uuid.New()}
+	s := NewSigner(&config.SigningConfig{}, nil, &movableProjects{project: project}, logger.New("error", "json", io.Discard))
+	ctx := context.Background()
+
+	assert.Equal(t, DefaultOrg, s.ProjectOrg(ctx, project.ID))
+
+	project.TeamID = &team
+	// Cached until the project's update evicts it
+	assert.Equal(t, DefaultOrg, s.ProjectOrg(ctx, project.ID))
+	s.Forget(project.ID)
+	assert.Equal(t, team.String(), s.ProjectOrg(ctx, project.ID))
+}
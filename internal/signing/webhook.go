@@ -0,0 +1,20 @@
+package signing
+
+import (
+	"context"
+	"net/http"
+)
+
+// HeaderSignature carries the detached JWS of an outbound webhook body
+const HeaderSignature = "X-Northstack-Signature"
+
+// SignRequest signs an outbound webhook request with the organization's key.
+// body must be exactly the bytes sent as the request body.
+func (s *Signer) SignRequest(ctx context.Context, req *http.Request, org string, body []byte) error {
+	signature, err := s.Sign(ctx, org, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(HeaderSignature, signature)
+	return nil
+}
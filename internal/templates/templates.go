@@ -0,0 +1,490 @@
+// Package templates is the catalog of project templates: northstack.yaml
+// manifests with ${VARIABLE} placeholders that are instantiated into a project
+// and its services in one call. A few templates are built in; users add their
+// own.
+package templates
+
+import (
+	"context"
+	"crypto/rand"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/manifest"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"sigs.k8s.io/yaml"
+)
+
+// LabelTemplate marks the projects created from a template
+const LabelTemplate = "northstack.io/template"
+
+// Variables every template can use without declaring them
+const (
+	VariableProjectName = "PROJECT_NAME"
+	VariableProjectSlug = "PROJECT_SLUG"
+)
+
+// passwordLength is the length of generated variable values
+const passwordLength = 32
+
+const passwordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+var (
+	slugPattern        = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	variablePattern    = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+)
+
+//go:embed builtin/*.yaml
+var builtinFiles embed.FS
+
+// builtins are the templates that ship with the platform, by slug
+var builtins = mustLoadBuiltins()
+
+// InstantiateRequest describes the project to create from a template
+type InstantiateRequest struct {
+	ProjectID   *uuid.UUID // Add the services to this project instead of creating one
+	Name        string     // Name of the created project
+	Slug        string     // Slug of the created project
+	Description string
+	TeamID      *uuid.UUID
+	OwnerID     uuid.UUID
+	Variables   map[string]string
+	DryRun      bool // Report what would be created without changing anything
+}
+
+// Instance is the outcome of instantiating a template
+type Instance struct {
+	DryRun        bool              `json:"dry_run"`
+	Template      string            `json:"template"`
+	ProjectAction string            `json:"project_action"` // create or existing
+	Project       *domain.Project   `json:"project"`
+	Variables     map[string]string `json:"variables"` // Values used, without generated ones
+	Generated     []string          `json:"generated"` // Variables given random values
+	Services      *manifest.Result  `json:"services"`
+}
+
+// Catalog lists templates and instantiates them
+type Catalog struct {
+	repo        domain.TemplateRepository
+	projectRepo domain.ProjectRepository
+	applier     *manifest.Applier
+	eventBus    domain.EventBus
+	logger      *logger.Logger
+}
+
+// NewCatalog creates a new Catalog. repo may be nil, in which case only the
+// built-in templates are available.
+func NewCatalog(repo domain.TemplateRepository, projectRepo domain.ProjectRepository, applier *manifest.Applier, eventBus domain.EventBus, log *logger.Logger) *Catalog {
+	return &Catalog{
+		repo:        repo,
+		projectRepo: projectRepo,
+		applier:     applier,
+		eventBus:    eventBus,
+		logger:      log,
+	}
+}
+
+// Editable reports whether users can add templates
+func (c *Catalog) Editable() bool {
+	return c.repo != nil
+}
+
+// List returns the built-in templates followed by user-defined ones, by name
+func (c *Catalog) List(ctx context.Context) ([]*domain.Template, error) {
+	templates := make([]*domain.Template, 0, len(builtins))
+	for _, t := range builtins {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	if c.repo == nil {
+		return templates, nil
+	}
+	custom, err := c.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return append(templates, custom...), nil
+}
+
+// Get returns a template by slug
+func (c *Catalog) Get(ctx context.Context, slug string) (*domain.Template, error) {
+	if t, ok := builtins[slug]; ok {
+		return t, nil
+	}
+	if c.repo == nil {
+		return nil, errors.NotFound("template", slug)
+	}
+	return c.repo.GetBySlug(ctx, slug)
+}
+
+// Create validates and stores a user-defined template
+func (c *Catalog) Create(ctx context.Context, t *domain.Template) error {
+	if c.repo == nil {
+		return errors.BadRequest("user-defined templates are not enabled")
+	}
+	if _, ok := builtins[t.Slug]; ok {
+		return errors.Conflict("template " + t.Slug)
+	}
+	if err := Validate(t); err != nil {
+		return err
+	}
+	normalize(t)
+
+	now := time.Now()
+	t.ID = uuid.New()
+	t.Builtin = false
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	return c.repo.Create(ctx, t)
+}
+
+// Update validates and stores changes to a user-defined template
+func (c *Catalog) Update(ctx context.Context, t *domain.Template) error {
+	if t.Builtin {
+		return errors.Forbidden("built-in templates cannot be changed")
+	}
+	if err := Validate(t); err != nil {
+		return err
+	}
+	normalize(t)
+	return c.repo.Update(ctx, t)
+}
+
+// Delete deletes a user-defined template
+func (c *Catalog) Delete(ctx context.Context, t *domain.Template) error {
+	if t.Builtin {
+		return errors.Forbidden("built-in templates cannot be deleted")
+	}
+	return c.repo.Delete(ctx, t.ID)
+}
+
+// Instantiate creates a project from a template, or adds the template's
+// services to an existing project. A created project is deleted again if its
+// services cannot be created.
+func (c *Catalog) Instantiate(ctx context.Context, slug string, req InstantiateRequest) (*Instance, error) {
+	t, err := c.Get(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+	return c.InstantiateTemplate(ctx, t, req)
+}
+
+// InstantiateTemplate is Instantiate for a template that need not be in the
+// catalog, such as one made of a repository's northstack.yaml
+func (c *Catalog) InstantiateTemplate(ctx context.Context, t *domain.Template, req InstantiateRequest) (*Instance, error) {
+	instance := &Instance{DryRun: req.DryRun, Template: t.Slug}
+	project, err := c.project(ctx, t, req, instance)
+	if err != nil {
+		return nil, err
+	}
+	instance.Project = project
+
+	values, generated, err := resolve(t, req.Variables, project)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Render(t, values)
+	if err != nil {
+		return nil, err
+	}
+	instance.Generated = generated
+	instance.Variables = make(map[string]string, len(values))
+	for name, value := range values {
+		instance.Variables[name] = value
+	}
+	for _, name := range generated {
+		delete(instance.Variables, name)
+	}
+
+	created := instance.ProjectAction == "create" && !req.DryRun
+	if created {
+		if err := c.projectRepo.Create(ctx, project); err != nil {
+			return nil, err
+		}
+	}
+
+	instance.Services, err = c.applier.Apply(ctx, project.ID, m, manifest.Options{DryRun: req.DryRun})
+	if err != nil {
+		if created {
+			if delErr := c.projectRepo.Delete(ctx, project.ID); delErr != nil {
+				c.logger.Warn().Err(delErr).Str("project_id", project.ID.String()).Msg("Failed to delete the project of a failed template")
+			}
+		}
+		return nil, err
+	}
+
+	if created {
+		c.publish(ctx, "project.created", map[string]interface{}{
+			"project_id": project.ID.String(),
+			"name":       project.Name,
+			"owner_id":   project.OwnerID.String(),
+			"template":   t.Slug,
+		})
+	}
+	if !req.DryRun {
+		c.logger.Info().
+			Str("template", t.Slug).
+			Str("project_id", project.ID.String()).
+			Int("services", len(m.Services)).
+			Msg("Template instantiated")
+	}
+	return instance, nil
+}
+
+// project returns the project to instantiate into, which is created by
+// Instantiate unless the request names an existing one
+func (c *Catalog) project(ctx context.Context, t *domain.Template, req InstantiateRequest, instance *Instance) (*domain.Project, error) {
+	if req.ProjectID != nil {
+		instance.ProjectAction = "existing"
+		return c.projectRepo.GetByID(ctx, *req.ProjectID)
+	}
+
+	if req.Name == "" || req.Slug == "" {
+		return nil, errors.BadRequest("a project name and slug are required to create a project")
+	}
+	if existing, err := c.projectRepo.GetBySlug(ctx, req.Slug); err == nil && existing != nil {
+		return nil, errors.Conflict("project " + req.Slug)
+	}
+
+	now := time.Now()
+	instance.ProjectAction = "create"
+	return &domain.Project{
+		ID:          uuid.New(),
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		Status:      domain.ProjectStatusActive,
+		OwnerID:     req.OwnerID,
+		TeamID:      req.TeamID,
+		Labels:      map[string]string{LabelTemplate: t.Slug},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// Validate checks a template: its slug and variables, that its manifest only
+// uses declared variables, and that the manifest is valid once rendered by
+// Preview
+func Validate(t *domain.Template) error {
+	var fields []errors.FieldError
+	invalid := func(field, message string) {
+		fields = append(fields, errors.FieldError{Field: field, Message: message})
+	}
+
+	if !slugPattern.MatchString(t.Slug) || len(t.Slug) > 63 {
+		invalid("slug", "must be a DNS label")
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		invalid("name", "is required")
+	}
+
+	declared := map[string]bool{VariableProjectName: true, VariableProjectSlug: true}
+	for i, v := range t.Variables {
+		at := fmt.Sprintf("variables[%d].name", i)
+		switch {
+		case !variablePattern.MatchString(v.Name):
+			invalid(at, "must be upper case letters, digits and underscores")
+		case declared[v.Name]:
+			invalid(at, "is declared twice or reserved")
+		}
+		declared[v.Name] = true
+	}
+
+	for _, match := range placeholderPattern.FindAllStringSubmatch(t.Manifest, -1) {
+		if !declared[match[1]] {
+			invalid("manifest", "uses the undeclared variable "+match[1])
+			declared[match[1]] = true
+		}
+	}
+	if len(fields) > 0 {
+		return errors.Validation(fields)
+	}
+
+	_, err := Preview(t)
+	return err
+}
+
+// Preview renders a template with its variables set to their defaults or,
+// lacking one, a hostname, which every string setting accepts
+func Preview(t *domain.Template) (*manifest.Manifest, error) {
+	samples := map[string]string{VariableProjectName: "Example", VariableProjectSlug: "example"}
+	for _, v := range t.Variables {
+		samples[v.Name] = v.Default
+		if v.Default == "" {
+			samples[v.Name] = "example.com"
+		}
+	}
+	return Render(t, samples)
+}
+
+// Render fills in a template's placeholders and returns its manifest.
+// Placeholders are replaced in string values only, so a value cannot change
+// the structure of the manifest.
+func Render(t *domain.Template, values map[string]string) (*manifest.Manifest, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal([]byte(t.Manifest), &doc); err != nil {
+		return nil, errors.BadRequest("invalid template manifest: " + err.Error())
+	}
+
+	data, err := json.Marshal(substitute(doc, values))
+	if err != nil {
+		return nil, err
+	}
+	return manifest.Parse(data)
+}
+
+// substitute replaces the placeholders in the strings of a decoded document
+func substitute(v interface{}, values map[string]string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return placeholderPattern.ReplaceAllStringFunc(v, func(placeholder string) string {
+			return values[placeholder[2:len(placeholder)-1]]
+		})
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = substitute(item, values)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = substitute(item, values)
+		}
+	}
+	return v
+}
+
+// resolve returns the value of each variable of a template: the given one,
+// its default or a generated password. It also returns the names of the
+// generated ones.
+func resolve(t *domain.Template, given map[string]string, project *domain.Project) (map[string]string, []string, error) {
+	var fields []errors.FieldError
+	values := map[string]string{
+		VariableProjectName: project.Name,
+		VariableProjectSlug: project.Slug,
+	}
+	var generated []string
+
+	known := make(map[string]bool, len(t.Variables))
+	for _, v := range t.Variables {
+		known[v.Name] = true
+		value, ok := given[v.Name]
+		switch {
+		case ok && value != "":
+		case v.Default != "":
+			value = v.Default
+		case v.Generate:
+			password, err := generatePassword()
+			if err != nil {
+				return nil, nil, err
+			}
+			value = password
+			generated = append(generated, v.Name)
+		case v.Required:
+			fields = append(fields, errors.FieldError{Field: "variables." + v.Name, Rule: "required", Message: "is required"})
+		}
+		values[v.Name] = value
+	}
+	for name := range given {
+		if !known[name] {
+			fields = append(fields, errors.FieldError{Field: "variables." + name, Message: "is not a variable of the template"})
+		}
+	}
+
+	if len(fields) > 0 {
+		sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+		return nil, nil, errors.Validation(fields)
+	}
+	return values, generated, nil
+}
+
+// normalize lists a template's tags and variables as empty rather than null
+func normalize(t *domain.Template) {
+	if t.Tags == nil {
+		t.Tags = []string{}
+	}
+	if t.Variables == nil {
+		t.Variables = []domain.TemplateVariable{}
+	}
+}
+
+func generatePassword() (string, error) {
+	b := make([]byte, passwordLength)
+	limit := big.NewInt(int64(len(passwordChars)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordChars[n.Int64()]
+	}
+	return string(b), nil
+}
+
+func (c *Catalog) publish(ctx context.Context, eventType string, data map[string]interface{}) {
+	if c.eventBus == nil {
+		return
+	}
+	event := &domain.Event{Type: eventType, Source: "templates", Data: data}
+	if err := c.eventBus.Publish(ctx, eventType, event); err != nil {
+		c.logger.Warn().Err(err).Str("event", eventType).Msg("Failed to publish event")
+	}
+}
+
+// builtinFile is the layout of a built-in template, with its manifest inline
+type builtinFile struct {
+	Slug        string                    `json:"slug"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Category    string                    `json:"category"`
+	Tags        []string                  `json:"tags"`
+	Variables   []domain.TemplateVariable `json:"variables"`
+	Manifest    json.RawMessage           `json:"manifest"`
+}
+
+func mustLoadBuiltins() map[string]*domain.Template {
+	files, err := builtinFiles.ReadDir("builtin")
+	if err != nil {
+		panic(err)
+	}
+
+	templates := make(map[string]*domain.Template, len(files))
+	for _, file := range files {
+		data, err := builtinFiles.ReadFile(path.Join("builtin", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var f builtinFile
+		if err := yaml.UnmarshalStrict(data, &f); err != nil {
+			panic(fmt.Sprintf("built-in template %s: %v", file.Name(), err))
+		}
+		m, err := yaml.JSONToYAML(f.Manifest)
+		if err != nil {
+			panic(fmt.Sprintf("built-in template %s: %v", file.Name(), err))
+		}
+
+		templates[f.Slug] = &domain.Template{
+			// Stable across restarts so clients can keep referring to them
+			ID:          uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://northstack.io/templates/"+f.Slug)),
+			Slug:        f.Slug,
+			Name:        f.Name,
+			Description: f.Description,
+			Category:    f.Category,
+			Tags:        f.Tags,
+			Manifest:    string(m),
+			Variables:   f.Variables,
+			Builtin:     true,
+		}
+	}
+	return templates
+}
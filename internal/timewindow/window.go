@@ -0,0 +1,112 @@
+// Package timewindow implements the recurring windows of the week shared by
+// maintenance windows, scaling schedules and notification quiet hours.
+package timewindow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a recurring window of the week
+type Window struct {
+	Days     []string `json:"days,omitempty"`     // mon..sun; empty means every day
+	Start    string   `json:"start"`              // HH:MM
+	End      string   `json:"end"`                // HH:MM; earlier than Start for windows spanning midnight
+	Timezone string   `json:"timezone,omitempty"` // IANA name; defaults to UTC
+}
+
+// Validate checks a window. Its errors name the offending field and leave
+// the caller to say which window it was.
+func (w Window) Validate() error {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return errors.New("start must be HH:MM")
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return errors.New("end must be HH:MM")
+	}
+	if start == end {
+		return errors.New("start and end must differ")
+	}
+	for _, d := range w.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return fmt.Errorf("invalid day %q", d)
+		}
+	}
+	if _, err := location(w.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", w.Timezone)
+	}
+	return nil
+}
+
+// Open reports whether now falls within the window. A window spanning
+// midnight belongs to the day it starts on. An invalid window is never open.
+func (w Window) Open(now time.Time) bool {
+	loc, err := location(w.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+
+	local := now.In(loc)
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+
+	if start < end {
+		return clock >= start && clock < end && onDay(w.Days, local.Weekday())
+	}
+	if clock >= start {
+		return onDay(w.Days, local.Weekday())
+	}
+	if clock < end {
+		return onDay(w.Days, (local.Weekday()+6)%7)
+	}
+	return false
+}
+
+func onDay(days []string, day time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses HH:MM into an offset from midnight
+func parseClock(v string) (time.Duration, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
@@ -0,0 +1,61 @@
+package timewindow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowOpen(t *testing.T) {
+	// Weeknights from 22:00 to 07:00 in Berlin
+	night := Window{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "22:00", End: "07:00", Timezone: "Europe/Berlin"}
+	lunch := Window{Start: "12:00", End: "13:00"}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 3, day, hour, minute, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		name   string
+		window Window
+		now    time.Time
+		want   bool
+	}{
+		{"friday night", night, at(6, 23, 0), true},
+		{"early saturday belongs to friday", night, at(7, 6, 59), true},
+		{"saturday night", night, at(7, 23, 0), false},
+		{"early monday belongs to sunday", night, at(9, 3, 0), false},
+		{"weekday morning", night, at(4, 7, 0), false},
+		{"lunch in UTC", lunch, time.Date(2026, 3, 4, 12, 30, 0, 0, time.UTC), true},
+		{"lunch ends", lunch, time.Date(2026, 3, 4, 13, 0, 0, 0, time.UTC), false},
+		{"days are case-insensitive", Window{Days: []string{"Wed"}, Start: "12:00", End: "13:00"}, time.Date(2026, 3, 4, 12, 30, 0, 0, time.UTC), true},
+		{"invalid window", Window{Start: "noon", End: "13:00"}, time.Date(2026, 3, 4, 12, 30, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.window.Open(tt.now))
+		})
+	}
+}
+
+func TestWindowValidate(t *testing.T) {
+	assert.NoError(t, Window{Days: []string{"Sat", "sun"}, Start: "22:00", End: "02:00", Timezone: "America/New_York"}.Validate())
+
+	tests := []struct {
+		window Window
+		want   string
+	}{
+		{Window{Start: "2am", End: "04:00"}, "start must be HH:MM"},
+		{Window{Start: "02:00", End: "24:00"}, "end must be HH:MM"},
+		{Window{Start: "02:00", End: "02:00"}, "start and end must differ"},
+		{Window{Start: "02:00", End: "04:00", Days: []string{"someday"}}, `invalid day "someday"`},
+		{Window{Start: "02:00", End: "04:00", Timezone: "Mars/Olympus"}, `unknown timezone "Mars/Olympus"`},
+	}
+	for _, tt := range tests {
+		assert.EqualError(t, tt.window.Validate(), tt.want)
+	}
+}
@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestEventPropagation(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer provider.Shutdown(context.Background())
+
+	event := &domain.Event{ID: "evt-1", Type: "deploy.completed"}
+	ctx, span := StartPublish(context.Background(), "deploy.completed", event)
+	span.End()
+	assert.NotEmpty(t, event.Metadata["traceparent"])
+
+	_, consumer := StartConsume("deploy.completed", event)
+	defer consumer.End()
+
+	publisher := trace.SpanContextFromContext(ctx)
+	assert.Equal(t, publisher.TraceID(), consumer.SpanContext().TraceID())
+	assert.NotEqual(t, publisher.SpanID(), consumer.SpanContext().SpanID())
+}
+
+func TestOperationName(t *testing.T) {
+	assert.Equal(t, "SELECT", operationName("  select id from projects"))
+	assert.Equal(t, "QUERY", operationName(""))
+}
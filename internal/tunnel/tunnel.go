@@ -0,0 +1,277 @@
+// Package tunnel opens port-forward tunnels to the ports of private services,
+// so developers can reach internal databases and admin ports without a VPN.
+// A tunnel forwards to one running pod of the service, as kubectl
+// port-forward svc/<name> does. Users hold a limited number of tunnels at
+// once, and every tunnel is recorded in the audit log when it opens and when
+// it closes.
+package tunnel
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Request identifies who opens a tunnel, for limits and auditing
+type Request struct {
+	UserID    uuid.UUID
+	Port      int32 // Service port; forwarded to its target port
+	IPAddress string
+	UserAgent string
+}
+
+// Session is an open tunnel
+type Session struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ServiceID uuid.UUID `json:"service_id"`
+	Port      int32     `json:"port"`
+	Pod       string    `json:"pod"`
+	Namespace string    `json:"namespace"`
+	StartedAt time.Time `json:"started_at"`
+
+	stream   io.ReadWriteCloser
+	service  *domain.Service
+	request  Request
+	bytesIn  atomic.Int64 // From the client to the pod
+	bytesOut atomic.Int64 // From the pod to the client
+	manager  *Manager
+	once     sync.Once
+}
+
+// Manager opens tunnels and enforces their limits
+type Manager struct {
+	config *config.TunnelConfig
+	kube   domain.KubernetesClient
+	audit  *audit.Logger
+	logger *logger.Logger
+
+	mu       sync.Mutex
+	sessions map[uuid.UUID]map[*Session]struct{} // By user
+}
+
+// NewManager creates a new Manager. Without an audit logger tunnels are only
+// logged.
+func NewManager(cfg *config.TunnelConfig, kube domain.KubernetesClient, auditLogger *audit.Logger, log *logger.Logger) *Manager {
+	return &Manager{
+		config:   cfg,
+		kube:     kube,
+		audit:    auditLogger,
+		logger:   log,
+		sessions: make(map[uuid.UUID]map[*Session]struct{}),
+	}
+}
+
+// IdleTimeout is how long a tunnel may go without traffic
+func (m *Manager) IdleTimeout() time.Duration {
+	return m.config.IdleTimeout
+}
+
+// MaxDuration is how long a tunnel may stay open
+func (m *Manager) MaxDuration() time.Duration {
+	return m.config.MaxDuration
+}
+
+// Open opens a tunnel to a TCP port of the service. The session must be
+// closed when the client goes away.
+func (m *Manager) Open(ctx context.Context, service *domain.Service, req Request) (*Session, error) {
+	targetPort, err := TargetPort(service, req.Port)
+	if err != nil {
+		return nil, err
+	}
+	if service.TargetClusterID == nil {
+		return nil, errors.BadRequest("service has not been deployed to a cluster")
+	}
+	if m.kube == nil {
+		return nil, errors.NewError(errors.CodeServiceUnavailable, "no Kubernetes client for workload clusters; tunnels are unavailable", http.StatusServiceUnavailable)
+	}
+
+	session := &Session{
+		ID:        uuid.New(),
+		UserID:    req.UserID,
+		ServiceID: service.ID,
+		Port:      req.Port,
+		StartedAt: time.Now(),
+		service:   service,
+		request:   req,
+		manager:   m,
+	}
+	// Claim the slot first so concurrent opens cannot exceed the limit
+	if err := m.claim(session); err != nil {
+		return nil, err
+	}
+
+	pod, namespace, err := m.pod(ctx, service)
+	if err == nil {
+		session.Pod, session.Namespace = pod, namespace
+		session.stream, err = m.kube.PortForward(ctx, *service.TargetClusterID, namespace, pod, targetPort)
+		if err != nil {
+			err = errors.DependencyFailed("kubernetes", err)
+		}
+	}
+	if err != nil {
+		m.release(session)
+		return nil, err
+	}
+
+	m.record(ctx, session, "opened", nil)
+	m.logger.Info().
+		Str("session_id", session.ID.String()).
+		Str("user_id", req.UserID.String()).
+		Str("service_id", service.ID.String()).
+		Str("pod", pod).
+		Int("port", int(req.Port)).
+		Msg("Tunnel opened")
+	return session, nil
+}
+
+// Sessions returns the open tunnels of a user
+func (m *Manager) Sessions(userID uuid.UUID) []*Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := make([]*Session, 0, len(m.sessions[userID]))
+	for s := range m.sessions[userID] {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// Read reads from the pod
+func (s *Session) Read(p []byte) (int, error) {
+	n, err := s.stream.Read(p)
+	s.bytesOut.Add(int64(n))
+	return n, err
+}
+
+// Write writes to the pod
+func (s *Session) Write(p []byte) (int, error) {
+	n, err := s.stream.Write(p)
+	s.bytesIn.Add(int64(n))
+	return n, err
+}
+
+// Close closes the tunnel and records it in the audit log. It is safe to
+// call more than once.
+func (s *Session) Close() error {
+	var err error
+	s.once.Do(func() {
+		err = s.stream.Close()
+		s.manager.release(s)
+
+		duration := time.Since(s.StartedAt)
+		s.manager.record(context.Background(), s, "closed", map[string]interface{}{
+			"bytes_in":         s.bytesIn.Load(),
+			"bytes_out":        s.bytesOut.Load(),
+			"duration_seconds": int64(duration.Seconds()),
+		})
+		s.manager.logger.Info().
+			Str("session_id", s.ID.String()).
+			Int64("bytes_in", s.bytesIn.Load()).
+			Int64("bytes_out", s.bytesOut.Load()).
+			Dur("duration", duration).
+			Msg("Tunnel closed")
+	})
+	return err
+}
+
+// TargetPort returns the container port a TCP service port forwards to
+func TargetPort(service *domain.Service, port int32) (int32, error) {
+	for _, p := range service.Ports {
+		if p.Port != port {
+			continue
+		}
+		if p.Protocol != "" && p.Protocol != "TCP" {
+			return 0, errors.BadRequest(fmt.Sprintf("port %d is %s; only TCP ports can be tunnelled", port, p.Protocol))
+		}
+		if p.TargetPort != 0 {
+			return p.TargetPort, nil
+		}
+		return p.Port, nil
+	}
+	return 0, errors.BadRequest(fmt.Sprintf("service %s does not expose port %d", service.Slug, port))
+}
+
+// claim registers a session, failing when the user is at the limit
+func (m *Manager) claim(s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if limit := m.config.MaxSessionsPerUser; limit > 0 && len(m.sessions[s.UserID]) >= limit {
+		return errors.NewError(errors.CodeRateLimitExceeded,
+			fmt.Sprintf("at most %d tunnels may be open at once; close one first", limit), http.StatusTooManyRequests)
+	}
+	if m.sessions[s.UserID] == nil {
+		m.sessions[s.UserID] = make(map[*Session]struct{})
+	}
+	m.sessions[s.UserID][s] = struct{}{}
+	return nil
+}
+
+func (m *Manager) release(s *Session) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions[s.UserID], s)
+	if len(m.sessions[s.UserID]) == 0 {
+		delete(m.sessions, s.UserID)
+	}
+}
+
+// pod picks a running pod of the service
+func (m *Manager) pod(ctx context.Context, service *domain.Service) (string, string, error) {
+	objects, err := m.kube.ListResources(ctx, *service.TargetClusterID, "Pod", "", map[string]string{
+		domain.LabelServiceID: service.ID.String(),
+	})
+	if err != nil {
+		return "", "", errors.DependencyFailed("kubernetes", err)
+	}
+	for _, obj := range objects {
+		phase, _, _ := unstructured.NestedString(obj, "status", "phase")
+		if phase != "Running" {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+		namespace, _, _ := unstructured.NestedString(obj, "metadata", "namespace")
+		return name, namespace, nil
+	}
+	return "", "", errors.BadRequest(fmt.Sprintf("service %s has no running instances", service.Slug))
+}
+
+// record writes an audit entry for a tunnel
+func (m *Manager) record(ctx context.Context, s *Session, event string, metadata map[string]interface{}) {
+	if m.audit == nil {
+		return
+	}
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["event"] = event
+	metadata["session_id"] = s.ID.String()
+	metadata["port"] = s.Port
+	metadata["pod"] = s.Pod
+	metadata["namespace"] = s.Namespace
+
+	projectID := s.service.ProjectID
+	// Audit failures are logged by the audit logger and must not break tunnels
+	_ = m.audit.Log(ctx, audit.LogOptions{
+		UserID:       s.UserID,
+		Action:       domain.AuditActionPortForward,
+		ResourceType: "service",
+		ResourceID:   s.ServiceID,
+		ResourceName: s.service.Name,
+		ProjectID:    &projectID,
+		IPAddress:    s.request.IPAddress,
+		UserAgent:    s.request.UserAgent,
+		Metadata:     metadata,
+	})
+}
@@ -0,0 +1,120 @@
+package tunnel
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/audit"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeKube struct {
+	domain.KubernetesClient
+	forwarded []int32
+}
+
+func (k *fakeKube) ListResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string, labels map[string]string) ([]map[string]interface{}, error) {
+	return []map[string]interface{}{
+		{"metadata": map[string]interface{}{"name": "api-0", "namespace": "shop"}, "status": map[string]interface{}{"phase": "Pending"}},
+		{"metadata": map[string]interface{}{"name": "api-1", "namespace": "shop"}, "status": map[string]interface{}{"phase": "Running"}},
+	}, nil
+}
+
+func (k *fakeKube) PortForward(ctx context.Context, clusterID uuid.UUID, namespace, podName string, port int32) (io.ReadWriteCloser, error) {
+	k.forwarded = append(k.forwarded, port)
+	local, remote := net.Pipe()
+	go io.Copy(remote, remote) // Echo
+	return local, nil
+}
+
+type auditRepo struct {
+	domain.AuditLogRepository
+	entries []*domain.AuditLog
+}
+
+func (r *auditRepo) Create(ctx context.Context, log *domain.AuditLog) error {
+	r.entries = append(r.entries, log)
+	return nil
+}
+
+func service() *domain.Service {
+	clusterID := uuid.New()
+	return &domain.Service{
+		ID:              uuid.New(),
+		ProjectID:       uuid.New(),
+		Name:            "api",
+		Slug:            "api",
+		TargetClusterID: &clusterID,
+		Ports: []domain.ServicePort{
+			{Name: "postgres", Port: 5432, TargetPort: 15432, Protocol: "TCP"},
+			{Name: "admin", Port: 9000},
+			{Name: "dns", Port: 53, Protocol: "UDP"},
+		},
+	}
+}
+
+func TestTargetPort(t *testing.T) {
+	svc := service()
+
+	port, err := TargetPort(svc, 5432)
+	require.NoError(t, err)
+	assert.Equal(t, int32(15432), port)
+
+	port, err = TargetPort(svc, 9000)
+	require.NoError(t, err)
+	assert.Equal(t, int32(9000), port)
+
+	_, err = TargetPort(svc, 53)
+	assert.Error(t, err)
+	_, err = TargetPort(svc, 8080)
+	assert.Error(t, err)
+}
+
+func TestOpenLimitsAndAuditsSessions(t *testing.T) {
+	log := logger.New("error", "json", io.Discard)
+	kube := &fakeKube{}
+	repo := &auditRepo{}
+	manager := NewManager(&config.TunnelConfig{MaxSessionsPerUser: 1, IdleTimeout: time.Minute}, kube, audit.NewLogger(repo, nil, log), log)
+	svc := service()
+	userID := uuid.New()
+
+	session, err := manager.Open(context.Background(), svc, Request{UserID: userID, Port: 5432})
+	require.NoError(t, err)
+	assert.Equal(t, "api-1", session.Pod)
+	assert.Equal(t, []int32{15432}, kube.forwarded)
+
+	_, err = manager.Open(context.Background(), svc, Request{UserID: userID, Port: 5432})
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusTooManyRequests, appErr.HTTPStatus)
+	_, err = manager.Open(context.Background(), svc, Request{UserID: uuid.New(), Port: 5432})
+	assert.NoError(t, err)
+
+	_, err = session.Write([]byte("ping"))
+	require.NoError(t, err)
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(session, buf)
+	require.NoError(t, err)
+	assert.Equal(t, "ping", string(buf))
+
+	require.NoError(t, session.Close())
+	assert.NoError(t, session.Close())
+	assert.Empty(t, manager.Sessions(userID))
+
+	require.Len(t, repo.entries, 3)
+	closed := repo.entries[2]
+	assert.Equal(t, domain.AuditActionPortForward, closed.Action)
+	assert.Equal(t, "closed", closed.Metadata["event"])
+	assert.Equal(t, int64(4), closed.Metadata["bytes_in"])
+	assert.Equal(t, int64(4), closed.Metadata["bytes_out"])
+}
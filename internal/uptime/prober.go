@@ -0,0 +1,209 @@
+// Package uptime probes the public domains of HTTP ingresses, records
+// response times and status history, reports uptime percentages and raises
+// an alert when an ingress fails several consecutive checks.
+package uptime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/alerting"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/tracing"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+const (
+	// RuleIngressDown is the name of the alert raised for failing ingresses
+	RuleIngressDown = "IngressDown"
+	// maxConcurrentProbes bounds the checks in flight at once
+	maxConcurrentProbes = 16
+	// pruneInterval is how often results past the retention are deleted
+	pruneInterval = time.Hour
+)
+
+// Prober periodically checks every public HTTP ingress
+type Prober struct {
+	config      *config.UptimeConfig
+	projectRepo domain.ProjectRepository
+	ingressRepo domain.IngressRepository
+	probeRepo   domain.ProbeRepository
+	alerts      *alerting.Manager
+	client      *http.Client
+	logger      *logger.Logger
+
+	tracker   *failureTracker
+	lastPrune time.Time
+}
+
+// NewProber creates a new Prober. alerts may be nil to only record results.
+func NewProber(
+	cfg *config.UptimeConfig,
+	projectRepo domain.ProjectRepository,
+	ingressRepo domain.IngressRepository,
+	probeRepo domain.ProbeRepository,
+	alerts *alerting.Manager,
+	log *logger.Logger,
+) *Prober {
+	return &Prober{
+		config:      cfg,
+		projectRepo: projectRepo,
+		ingressRepo: ingressRepo,
+		probeRepo:   probeRepo,
+		alerts:      alerts,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: tracing.Transport(nil),
+		},
+		logger:  log,
+		tracker: newFailureTracker(cfg.FailureThreshold),
+	}
+}
+
+// Run probes all ingresses every Interval until ctx is cancelled
+func (p *Prober) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.probeAll(ctx)
+		p.prune(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Prober) probeAll(ctx context.Context) {
+	projects, err := p.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to list projects for uptime probing")
+		return
+	}
+
+	sem := make(chan struct{}, maxConcurrentProbes)
+	var wg sync.WaitGroup
+	for _, project := range projects {
+		ingresses, err := p.ingressRepo.ListByProject(ctx, project.ID)
+		if err != nil {
+			p.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list ingresses for uptime probing")
+			continue
+		}
+
+		for _, ingress := range ingresses {
+			if !probeable(ingress) {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(ingress *domain.Ingress) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				p.check(ctx, ingress)
+			}(ingress)
+		}
+	}
+	wg.Wait()
+}
+
+// check probes one ingress, stores the result and updates its alert
+func (p *Prober) check(ctx context.Context, ingress *domain.Ingress) {
+	result := p.probe(ctx, ingress)
+	if err := p.probeRepo.Create(ctx, result); err != nil {
+		p.logger.Error().Err(err).Str("ingress_id", ingress.ID.String()).Msg("Failed to record probe result")
+	}
+
+	transition := p.tracker.observe(ingress.ID, result.Success)
+	if p.alerts == nil {
+		return
+	}
+
+	fingerprint := RuleIngressDown + "/" + ingress.ID.String()
+	switch transition {
+	case transitionDown:
+		serviceID, projectID := ingress.ServiceID, ingress.ProjectID
+		_, err := p.alerts.Fire(ctx, &domain.Alert{
+			Fingerprint: fingerprint,
+			Name:        RuleIngressDown,
+			Severity:    "critical",
+			Source:      alerting.SourcePlatform,
+			Message:     fmt.Sprintf("%s failed %d consecutive checks: %s", result.URL, p.config.FailureThreshold, describe(result)),
+			Labels:      map[string]string{"domain": ingress.Domain},
+			Annotations: map[string]string{"url": result.URL},
+			ServiceID:   &serviceID,
+			ProjectID:   &projectID,
+		})
+		if err != nil {
+			p.logger.Error().Err(err).Str("ingress_id", ingress.ID.String()).Msg("Failed to fire uptime alert")
+		}
+	case transitionUp:
+		if _, err := p.alerts.Resolve(ctx, fingerprint, result.CheckedAt); err != nil {
+			p.logger.Error().Err(err).Str("ingress_id", ingress.ID.String()).Msg("Failed to resolve uptime alert")
+		}
+	}
+}
+
+// probe issues a GET against the ingress URL. Any response below 500 counts
+// as up: the ingress routed the request and the service answered.
+func (p *Prober) probe(ctx context.Context, ingress *domain.Ingress) *domain.ProbeResult {
+	result := &domain.ProbeResult{
+		ID:        uuid.New(),
+		IngressID: ingress.ID,
+		ServiceID: ingress.ServiceID,
+		URL:       probeURL(ingress),
+		CheckedAt: time.Now().UTC(),
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, result.URL, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.Header.Set("User-Agent", "northstack-uptime-probe")
+
+	start := time.Now()
+	resp, err := p.client.Do(req)
+	result.ResponseTime = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	result.StatusCode = resp.StatusCode
+	result.Success = resp.StatusCode < http.StatusInternalServerError
+	return result
+}
+
+func (p *Prober) prune(ctx context.Context) {
+	if p.config.Retention <= 0 || time.Since(p.lastPrune) < pruneInterval {
+		return
+	}
+	p.lastPrune = time.Now()
+
+	deleted, err := p.probeRepo.DeleteBefore(ctx, time.Now().Add(-p.config.Retention))
+	if err != nil {
+		p.logger.Warn().Err(err).Msg("Failed to prune probe results")
+		return
+	}
+	if deleted > 0 {
+		p.logger.Debug().Int64("deleted", deleted).Msg("Pruned probe results")
+	}
+}
+
+func describe(result *domain.ProbeResult) string {
+	if result.Error != "" {
+		return result.Error
+	}
+	return fmt.Sprintf("HTTP %d", result.StatusCode)
+}
@@ -0,0 +1,180 @@
+package uptime
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// Reporting periods for uptime percentages
+var periods = []struct {
+	label  string
+	window time.Duration
+}{
+	{"24h", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+	{"30d", 30 * 24 * time.Hour},
+}
+
+// IngressUptime is the uptime summary of an ingress
+type IngressUptime struct {
+	IngressID uuid.UUID           `json:"ingress_id"`
+	Domain    string              `json:"domain"`
+	URL       string              `json:"url"`
+	Status    string              `json:"status"` // up, down or unknown
+	Uptime    map[string]*float64 `json:"uptime"` // Percentage per period; null without checks
+	AvgMs     map[string]float64  `json:"avg_response_time"`
+	LastCheck *domain.ProbeResult `json:"last_check,omitempty"`
+}
+
+// ServiceUptime summarizes the uptime of each probed ingress of a service
+func (p *Prober) ServiceUptime(ctx context.Context, serviceID uuid.UUID) ([]*IngressUptime, error) {
+	ingresses, err := p.ingressRepo.ListByService(ctx, serviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	summaries := []*IngressUptime{}
+	for _, ingress := range ingresses {
+		if !probeable(ingress) {
+			continue
+		}
+
+		summary := &IngressUptime{
+			IngressID: ingress.ID,
+			Domain:    ingress.Domain,
+			URL:       probeURL(ingress),
+			Status:    "unknown",
+			Uptime:    make(map[string]*float64),
+			AvgMs:     make(map[string]float64),
+		}
+
+		for _, period := range periods {
+			stats, err := p.probeRepo.Stats(ctx, ingress.ID, now.Add(-period.window))
+			if err != nil {
+				return nil, err
+			}
+			summary.Uptime[period.label] = percentage(stats)
+			summary.AvgMs[period.label] = math.Round(stats.AvgResponseTime)
+		}
+
+		last, err := p.probeRepo.ListByIngress(ctx, ingress.ID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(last) > 0 {
+			summary.LastCheck = last[0]
+			summary.Status = "down"
+			if last[0].Success {
+				summary.Status = "up"
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// History returns the most recent probe results of one of a service's ingresses
+func (p *Prober) History(ctx context.Context, serviceID, ingressID uuid.UUID, limit int) ([]*domain.ProbeResult, error) {
+	ingress, err := p.ingressRepo.GetByID(ctx, ingressID)
+	if err != nil {
+		return nil, err
+	}
+	if ingress.ServiceID != serviceID {
+		return nil, errors.NotFound("ingress", ingressID.String())
+	}
+	return p.probeRepo.ListByIngress(ctx, ingressID, limit)
+}
+
+// probeable reports whether an ingress is a public HTTP endpoint
+func probeable(ingress *domain.Ingress) bool {
+	return ingress.Type == domain.IngressTypeHTTP && ingress.Domain != ""
+}
+
+func probeURL(ingress *domain.Ingress) string {
+	scheme := "http"
+	if ingress.TLS.Enabled || ingress.TLS.AutoTLS {
+		scheme = "https"
+	}
+	path := ingress.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return scheme + "://" + ingress.Domain + path
+}
+
+// percentage returns the share of successful checks, rounded to three decimals
+func percentage(stats *domain.ProbeStats) *float64 {
+	if stats.Total == 0 {
+		return nil
+	}
+	v := math.Round(float64(stats.Successful)/float64(stats.Total)*100*1000) / 1000
+	return &v
+}
+
+// transition is a change in an ingress's alerting state
+type transition int
+
+const (
+	transitionNone transition = iota
+	transitionDown            // Failure threshold reached
+	transitionUp              // Recovered, or first success since startup
+)
+
+type ingressState struct {
+	failures int
+	down     bool
+}
+
+// failureTracker counts consecutive failures per ingress
+type failureTracker struct {
+	threshold int
+
+	mu     sync.Mutex
+	states map[uuid.UUID]*ingressState
+}
+
+func newFailureTracker(threshold int) *failureTracker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &failureTracker{threshold: threshold, states: make(map[uuid.UUID]*ingressState)}
+}
+
+// observe records a check result. The first success after startup reports
+// transitionUp so alerts left firing by a previous process get resolved.
+func (t *failureTracker) observe(id uuid.UUID, success bool) transition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, seen := t.states[id]
+	if !seen {
+		state = &ingressState{}
+		t.states[id] = state
+	}
+
+	if success {
+		wasDown := state.down
+		state.failures = 0
+		state.down = false
+		if wasDown || !seen {
+			return transitionUp
+		}
+		return transitionNone
+	}
+
+	state.failures++
+	if !state.down && state.failures >= t.threshold {
+		state.down = true
+		return transitionDown
+	}
+	return transitionNone
+}
@@ -0,0 +1,33 @@
+package uptime
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFailureTracker(t *testing.T) {
+	tracker := newFailureTracker(3)
+	id := uuid.New()
+
+	assert.Equal(t, transitionUp, tracker.observe(id, true)) // First success resolves stale alerts
+	assert.Equal(t, transitionNone, tracker.observe(id, true))
+	assert.Equal(t, transitionNone, tracker.observe(id, false))
+	assert.Equal(t, transitionNone, tracker.observe(id, false))
+	assert.Equal(t, transitionDown, tracker.observe(id, false))
+	assert.Equal(t, transitionNone, tracker.observe(id, false))
+	assert.Equal(t, transitionUp, tracker.observe(id, true))
+	assert.Equal(t, transitionNone, tracker.observe(id, false))
+}
+
+func TestProbeURL(t *testing.T) {
+	assert.Equal(t, "https://app.example.com/", probeURL(&domain.Ingress{Domain: "app.example.com", TLS: domain.TLSConfig{AutoTLS: true}}))
+	assert.Equal(t, "http://api.example.com/health", probeURL(&domain.Ingress{Domain: "api.example.com", Path: "/health"}))
+}
+
+func TestPercentage(t *testing.T) {
+	assert.Nil(t, percentage(&domain.ProbeStats{}))
+	assert.Equal(t, 99.931, *percentage(&domain.ProbeStats{Total: 1440, Successful: 1439}))
+}
@@ -0,0 +1,442 @@
+// Package volumes gives services persistent volumes: a PersistentVolumeClaim
+// per volume in the namespace of their project's environment on their target
+// cluster, mounted into the service's container by the manifests generated
+// for it. Claims grow in place when their storage class allows expansion,
+// and CSI VolumeSnapshots can be taken of them; a volume is not deleted while
+// snapshots of it remain.
+package volumes
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Manager applies the claims of service volumes and takes their snapshots
+type Manager struct {
+	config      *config.VolumesConfig
+	kube        domain.KubernetesClient
+	serviceRepo domain.ServiceRepository
+	envRepo     domain.EnvironmentRepository
+	projectRepo domain.ProjectRepository
+	logger      *logger.Logger
+}
+
+// NewManager creates a new Manager. kube may be nil, in which case volumes
+// are unavailable.
+func NewManager(
+	cfg *config.VolumesConfig,
+	kube domain.KubernetesClient,
+	serviceRepo domain.ServiceRepository,
+	envRepo domain.EnvironmentRepository,
+	projectRepo domain.ProjectRepository,
+	log *logger.Logger,
+) *Manager {
+	return &Manager{
+		config:      cfg,
+		kube:        kube,
+		serviceRepo: serviceRepo,
+		envRepo:     envRepo,
+		projectRepo: projectRepo,
+		logger:      log,
+	}
+}
+
+// Apply applies the claims of a service's volumes. Services without a target
+// cluster are skipped.
+func (m *Manager) Apply(ctx context.Context, svc *domain.Service) error {
+	if svc.TargetClusterID == nil || len(svc.Volumes) == 0 {
+		return nil
+	}
+	if m.kube == nil {
+		return errors.ErrNoKubernetes
+	}
+	namespace, err := m.namespace(ctx, svc)
+	if err != nil {
+		return err
+	}
+	for _, v := range svc.Volumes {
+		manifest, err := json.Marshal(Claim(svc, v, namespace))
+		if err != nil {
+			return errors.Wrap(err, "failed to encode PersistentVolumeClaim")
+		}
+		if err := m.kube.ApplyManifest(ctx, *svc.TargetClusterID, manifest); err != nil {
+			return errors.DependencyFailed("kubernetes", err)
+		}
+	}
+	return nil
+}
+
+// CheckResize rejects growing a deployed volume whose storage class does not
+// allow expansion. Volumes not deployed yet are created at the new size.
+func (m *Manager) CheckResize(ctx context.Context, svc *domain.Service, old, v domain.Volume) error {
+	if Grows(old, v) <= 0 || svc.TargetClusterID == nil {
+		return nil
+	}
+	claim, namespace, err := m.claim(ctx, svc, v)
+	if err != nil || claim == nil {
+		return err
+	}
+
+	class, _, _ := unstructured.NestedString(claim, "spec", "storageClassName")
+	if class == "" {
+		return errors.BadRequest(fmt.Sprintf("claim %s/%s has no storage class, so it cannot be expanded", namespace, ClaimName(svc, v)))
+	}
+	storageClass, err := m.kube.GetResource(ctx, *svc.TargetClusterID, "StorageClass", "", class)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return errors.BadRequest(fmt.Sprintf("storage class %s of volume %s no longer exists", class, v.Name))
+		}
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	if allowed, _, _ := unstructured.NestedBool(storageClass, "allowVolumeExpansion"); !allowed {
+		return errors.BadRequest(fmt.Sprintf("storage class %s does not allow volume expansion", class))
+	}
+	return nil
+}
+
+// Status reads the state of each of a service's volumes from its claim
+func (m *Manager) Status(ctx context.Context, svc *domain.Service) ([]domain.VolumeStatus, error) {
+	statuses := make([]domain.VolumeStatus, 0, len(svc.Volumes))
+	for _, v := range svc.Volumes {
+		status, err := m.VolumeStatus(ctx, svc, v)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// VolumeStatus reads the state of a volume from its claim
+func (m *Manager) VolumeStatus(ctx context.Context, svc *domain.Service, v domain.Volume) (domain.VolumeStatus, error) {
+	claim, _, err := m.claim(ctx, svc, v)
+	if err != nil {
+		return domain.VolumeStatus{}, err
+	}
+	return Status(svc, v, claim), nil
+}
+
+// Delete deletes the claim of a volume, and with it the volume's data. It is
+// refused while snapshots of the volume remain. Kubernetes keeps the claim
+// until no pod mounts it.
+func (m *Manager) Delete(ctx context.Context, svc *domain.Service, v domain.Volume) error {
+	if svc.TargetClusterID == nil {
+		return nil
+	}
+	if m.kube == nil {
+		return errors.ErrNoKubernetes
+	}
+	namespace, err := m.namespace(ctx, svc)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := m.snapshots(ctx, svc, v, namespace)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) > 0 {
+		return errors.NewError(errors.CodeConflict,
+			fmt.Sprintf("volume %s has %d snapshots; delete them before the volume", v.Name, len(snapshots)),
+			http.StatusConflict)
+	}
+
+	if err := m.kube.DeleteResource(ctx, *svc.TargetClusterID, "PersistentVolumeClaim", namespace, ClaimName(svc, v)); err != nil && !errors.IsNotFound(err) {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	m.logger.Info().Str("service_id", svc.ID.String()).Str("volume", v.Name).Msg("Deleted volume claim")
+	return nil
+}
+
+// Snapshots lists the snapshots of a volume, newest first
+func (m *Manager) Snapshots(ctx context.Context, svc *domain.Service, v domain.Volume) ([]domain.VolumeSnapshot, error) {
+	if svc.TargetClusterID == nil {
+		return []domain.VolumeSnapshot{}, nil
+	}
+	if m.kube == nil {
+		return nil, errors.ErrNoKubernetes
+	}
+	namespace, err := m.namespace(ctx, svc)
+	if errors.IsNotFound(err) {
+		return []domain.VolumeSnapshot{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.snapshots(ctx, svc, v, namespace)
+}
+
+// CreateSnapshot takes a snapshot of a deployed volume
+func (m *Manager) CreateSnapshot(ctx context.Context, svc *domain.Service, v domain.Volume) (*domain.VolumeSnapshot, error) {
+	claim, namespace, err := m.claim(ctx, svc, v)
+	if err != nil {
+		return nil, err
+	}
+	if claim == nil {
+		return nil, errors.BadRequest(fmt.Sprintf("volume %s is not deployed yet", v.Name))
+	}
+
+	name := SnapshotName(svc, v, time.Now())
+	manifest, err := json.Marshal(Snapshot(svc, v, namespace, name, m.config.SnapshotClass))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode VolumeSnapshot")
+	}
+	if err := m.kube.ApplyManifest(ctx, *svc.TargetClusterID, manifest); err != nil {
+		if snapshotsUnsupported(err) {
+			return nil, errors.BadRequest("the cluster does not support volume snapshots")
+		}
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	m.logger.Info().Str("service_id", svc.ID.String()).Str("volume", v.Name).Str("snapshot", name).Msg("Started volume snapshot")
+	return &domain.VolumeSnapshot{Name: name, Volume: v.Name}, nil
+}
+
+// DeleteSnapshot deletes a snapshot of a volume
+func (m *Manager) DeleteSnapshot(ctx context.Context, svc *domain.Service, v domain.Volume, name string) error {
+	snapshots, err := m.Snapshots(ctx, svc, v)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Name != name {
+			continue
+		}
+		namespace, err := m.namespace(ctx, svc)
+		if err != nil {
+			return err
+		}
+		if err := m.kube.DeleteResource(ctx, *svc.TargetClusterID, "VolumeSnapshot", namespace, name); err != nil && !errors.IsNotFound(err) {
+			return errors.DependencyFailed("kubernetes", err)
+		}
+		return nil
+	}
+	return errors.NotFound("snapshot", name)
+}
+
+// snapshots lists the snapshots of a volume's claim, whoever took them. A
+// cluster without the snapshot API has none.
+func (m *Manager) snapshots(ctx context.Context, svc *domain.Service, v domain.Volume, namespace string) ([]domain.VolumeSnapshot, error) {
+	if m.kube == nil {
+		return nil, errors.ErrNoKubernetes
+	}
+	objects, err := m.kube.ListResources(ctx, *svc.TargetClusterID, "VolumeSnapshot", namespace, nil)
+	if snapshotsUnsupported(err) {
+		return []domain.VolumeSnapshot{}, nil
+	}
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	return Snapshots(svc, v, objects), nil
+}
+
+// claim reads the claim of a volume, nil when it does not exist yet
+func (m *Manager) claim(ctx context.Context, svc *domain.Service, v domain.Volume) (map[string]interface{}, string, error) {
+	if svc.TargetClusterID == nil {
+		return nil, "", nil
+	}
+	if m.kube == nil {
+		return nil, "", errors.ErrNoKubernetes
+	}
+	namespace, err := m.namespace(ctx, svc)
+	if errors.IsNotFound(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	claim, err := m.kube.GetResource(ctx, *svc.TargetClusterID, "PersistentVolumeClaim", namespace, ClaimName(svc, v))
+	if errors.IsNotFound(err) {
+		return nil, namespace, nil
+	}
+	if err != nil {
+		return nil, "", errors.DependencyFailed("kubernetes", err)
+	}
+	return claim, namespace, nil
+}
+
+// snapshotsUnsupported reports whether the cluster has no VolumeSnapshot kind,
+// the snapshot CRDs not being installed
+func snapshotsUnsupported(err error) bool {
+	var appErr *errors.AppError
+	return stderrors.As(err, &appErr) && appErr.Code == errors.CodeInvalidInput && strings.HasPrefix(appErr.Message, "unknown kind")
+}
+
+// Run applies the claims of every service's volumes until ctx is cancelled
+func (m *Manager) Run(ctx context.Context) {
+	if m.kube == nil {
+		m.logger.Warn().Msg("No Kubernetes client for workload clusters, volume claims are not applied")
+		return
+	}
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.applyAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Watch applies a service's claims as it is created, changed or deployed, and
+// removes them once it is deleted. Events go through durable consumers, so
+// one replica handles each and failures are retried; applying and removing
+// are idempotent.
+func (m *Manager) Watch(ctx context.Context, bus domain.EventBus) error {
+	if m.kube == nil {
+		return nil
+	}
+	for _, subject := range []string{"service.created", "service.updated", "deploy.completed"} {
+		if _, err := eventbus.SubscribeDurable(ctx, bus, subject, eventbus.DurableName("volumes", subject), func(event *domain.Event) error {
+			return m.applyEvent(ctx, event)
+		}); err != nil {
+			return err
+		}
+	}
+	_, err := eventbus.SubscribeDurable(ctx, bus, "service.deleted", eventbus.DurableName("volumes", "service.deleted"), func(event *domain.Event) error {
+		return m.removeEvent(ctx, event)
+	})
+	return err
+}
+
+func (m *Manager) applyEvent(ctx context.Context, event *domain.Event) error {
+	raw, _ := event.Data["service_id"].(string)
+	serviceID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil
+	}
+	svc, err := m.serviceRepo.GetByID(ctx, serviceID)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		m.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to load service for its volumes")
+		return err
+	}
+	if err := m.Apply(ctx, svc); err != nil {
+		m.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to apply volume claims")
+		return err
+	}
+	return nil
+}
+
+// removeEvent deletes the claims of a deleted service from the clusters of
+// its project's environments. Claims with snapshots are kept, so the
+// snapshots can still be restored.
+func (m *Manager) removeEvent(ctx context.Context, event *domain.Event) error {
+	serviceID, _ := event.Data["service_id"].(string)
+	raw, _ := event.Data["project_id"].(string)
+	projectID, err := uuid.Parse(raw)
+	if err != nil || serviceID == "" {
+		return nil
+	}
+	environments, err := m.envRepo.ListByProject(ctx, projectID)
+	if err != nil {
+		m.logger.Warn().Err(err).Str("service_id", serviceID).Msg("Failed to list environments to remove volume claims")
+		return err
+	}
+	var failed error
+	for _, env := range environments {
+		claims, err := m.kube.ListResources(ctx, env.ClusterID, "PersistentVolumeClaim", env.Namespace, map[string]string{
+			domain.LabelServiceID: serviceID,
+			domain.LabelManagedBy: domain.ManagedByValue,
+		})
+		if err != nil {
+			m.logger.Warn().Err(err).Str("service_id", serviceID).Msg("Failed to list volume claims of deleted service")
+			failed = err
+			continue
+		}
+		if len(claims) == 0 {
+			continue
+		}
+		snapshots, err := m.kube.ListResources(ctx, env.ClusterID, "VolumeSnapshot", env.Namespace, nil)
+		if err != nil && !snapshotsUnsupported(err) {
+			m.logger.Warn().Err(err).Str("service_id", serviceID).Msg("Failed to list snapshots of deleted service")
+			failed = err
+			continue
+		}
+		snapshotted := make(map[string]bool)
+		for _, obj := range snapshots {
+			source, _, _ := unstructured.NestedString(obj, "spec", "source", "persistentVolumeClaimName")
+			snapshotted[source] = true
+		}
+
+		for _, obj := range claims {
+			name, _, _ := unstructured.NestedString(obj, "metadata", "name")
+			if snapshotted[name] {
+				m.logger.Info().Str("service_id", serviceID).Str("claim", name).Msg("Kept volume claim of deleted service with snapshots")
+				continue
+			}
+			if err := m.kube.DeleteResource(ctx, env.ClusterID, "PersistentVolumeClaim", env.Namespace, name); err != nil && !errors.IsNotFound(err) {
+				m.logger.Warn().Err(err).Str("service_id", serviceID).Msg("Failed to remove volume claim")
+				failed = err
+			}
+		}
+	}
+	return failed
+}
+
+func (m *Manager) applyAll(ctx context.Context) {
+	projects, err := m.projectRepo.List(ctx, domain.ProjectFilter{})
+	if err != nil {
+		m.logger.Warn().Err(err).Msg("Failed to list projects for volumes")
+		return
+	}
+	for _, project := range projects {
+		services, err := m.serviceRepo.ListByProject(ctx, project.ID, domain.ServiceFilter{})
+		if err != nil {
+			m.logger.Warn().Err(err).Str("project_id", project.ID.String()).Msg("Failed to list services for volumes")
+			continue
+		}
+		for _, svc := range services {
+			if err := m.Apply(ctx, svc); err != nil {
+				m.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Failed to apply volume claims")
+			}
+		}
+	}
+}
+
+// namespace is the namespace of the environment of the service's project on
+// its target cluster, the project default first
+func (m *Manager) namespace(ctx context.Context, svc *domain.Service) (string, error) {
+	environments, err := m.envRepo.ListByProject(ctx, svc.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	namespace := ""
+	for _, env := range environments {
+		if env.ClusterID != *svc.TargetClusterID {
+			continue
+		}
+		if env.IsDefault {
+			return env.Namespace, nil
+		}
+		if namespace == "" {
+			namespace = env.Namespace
+		}
+	}
+	if namespace == "" {
+		return "", errors.NotFound("environment on the target cluster of service", svc.ID.String())
+	}
+	return namespace, nil
+}
@@ -0,0 +1,40 @@
+package volumes
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagerWithoutKubernetes(t *testing.T) {
+	clusterID := uuid.New()
+	data := domain.Volume{Name: "data", Size: "10Gi", MountPath: "/data"}
+	bigger := domain.Volume{Name: "data", Size: "20Gi", MountPath: "/data"}
+	svc := &domain.Service{ID: uuid.New(), TargetClusterID: &clusterID, Volumes: []domain.Volume{data}}
+	m := NewManager(&config.VolumesConfig{}, nil, nil, nil, nil, logger.New("error", "json", io.Discard))
+	ctx := context.Background()
+
+	// Agents are disabled: the API reports volumes as unavailable
+	for _, err := range []error{
+		m.Apply(ctx, svc),
+		m.CheckResize(ctx, svc, data, bigger),
+		func() error { _, err := m.Status(ctx, svc); return err }(),
+		m.Delete(ctx, svc, data),
+		func() error { _, err := m.Snapshots(ctx, svc, data); return err }(),
+		func() error { _, err := m.CreateSnapshot(ctx, svc, data); return err }(),
+		m.DeleteSnapshot(ctx, svc, data, "data-1"),
+	} {
+		assert.ErrorIs(t, err, errors.ErrNoKubernetes)
+	}
+
+	// and nothing is applied in the background
+	assert.NoError(t, m.Watch(ctx, nil))
+	m.Run(ctx)
+}
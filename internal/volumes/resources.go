@@ -0,0 +1,267 @@
+package volumes
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// LabelVolume names the volume a PersistentVolumeClaim or VolumeSnapshot
+// belongs to
+const LabelVolume = "openpaas.io/volume"
+
+type object = map[string]interface{}
+
+var volumeName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Validate checks a volume before it is added to a service or changed on it,
+// against the service's other volumes
+func Validate(svc *domain.Service, v domain.Volume) error {
+	if svc.Type == domain.ServiceTypeCronJob {
+		return errors.BadRequest("volumes are not supported for cron job services")
+	}
+	if len(v.Name) > 63 || !volumeName.MatchString(v.Name) {
+		return errors.BadRequest("volume name must be a DNS label: lowercase letters, digits and '-'")
+	}
+	size, err := resource.ParseQuantity(v.Size)
+	if err != nil || size.Sign() <= 0 {
+		return errors.BadRequest(fmt.Sprintf("invalid volume size %q: use a quantity such as 10Gi", v.Size))
+	}
+	if !path.IsAbs(v.MountPath) || path.Clean(v.MountPath) == "/" || strings.Contains(v.MountPath, ":") {
+		return errors.BadRequest("volume mount_path must be an absolute path other than /")
+	}
+	switch v.AccessMode {
+	case "", domain.VolumeReadWriteOnce, domain.VolumeReadWriteMany:
+	default:
+		return errors.BadRequest(fmt.Sprintf("unknown volume access_mode %q: use ReadWriteOnce or ReadWriteMany", v.AccessMode))
+	}
+
+	for _, other := range svc.Volumes {
+		if other.Name == v.Name {
+			continue
+		}
+		if path.Clean(other.MountPath) == path.Clean(v.MountPath) {
+			return errors.BadRequest(fmt.Sprintf("volume %s is already mounted at %s", other.Name, other.MountPath))
+		}
+	}
+	return nil
+}
+
+// ValidateChange checks a change to a volume. Claims can grow but not
+// shrink, and their storage class and access mode are fixed once created.
+func ValidateChange(old, v domain.Volume) error {
+	if v.StorageClass != old.StorageClass {
+		return errors.BadRequest("the storage class of a volume cannot be changed")
+	}
+	if accessMode(v) != accessMode(old) {
+		return errors.BadRequest("the access mode of a volume cannot be changed")
+	}
+	if Grows(old, v) < 0 {
+		return errors.BadRequest(fmt.Sprintf("volume %s cannot shrink from %s to %s", v.Name, old.Size, v.Size))
+	}
+	return nil
+}
+
+// Grows compares the size of a changed volume with its old size: positive
+// when it grows, negative when it shrinks
+func Grows(old, v domain.Volume) int {
+	oldSize, err := resource.ParseQuantity(old.Size)
+	if err != nil {
+		return 1
+	}
+	size, err := resource.ParseQuantity(v.Size)
+	if err != nil {
+		return 0
+	}
+	return size.Cmp(oldSize)
+}
+
+// Find returns the volume of a service with a name
+func Find(svc *domain.Service, name string) (domain.Volume, bool) {
+	for _, v := range svc.Volumes {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return domain.Volume{}, false
+}
+
+// ClaimName is the name of a volume's PersistentVolumeClaim
+func ClaimName(svc *domain.Service, v domain.Volume) string {
+	name := svc.Slug + "-" + v.Name
+	if len(name) > 253 {
+		name = strings.TrimRight(name[:253], "-")
+	}
+	return name
+}
+
+// Claim renders the PersistentVolumeClaim of a volume. It is not owned by
+// the service's workload, so its data outlives redeploys.
+func Claim(svc *domain.Service, v domain.Volume, namespace string) object {
+	return object{
+		"apiVersion": "v1",
+		"kind":       "PersistentVolumeClaim",
+		"metadata": object{
+			"name":      ClaimName(svc, v),
+			"namespace": namespace,
+			"labels":    labels(svc, v),
+		},
+		"spec": ClaimSpec(v),
+	}
+}
+
+// ClaimSpec renders the spec of a volume's claim, which is also that of the
+// claim templates of StatefulSets
+func ClaimSpec(v domain.Volume) object {
+	spec := object{
+		"accessModes": []interface{}{string(accessMode(v))},
+		"resources":   object{"requests": object{"storage": v.Size}},
+	}
+	if v.StorageClass != "" {
+		spec["storageClassName"] = v.StorageClass
+	}
+	return spec
+}
+
+// PodVolumes renders the pod volumes of a service, each backed by its claim
+func PodVolumes(svc *domain.Service) []interface{} {
+	var out []interface{}
+	for _, v := range svc.Volumes {
+		out = append(out, object{
+			"name":                  v.Name,
+			"persistentVolumeClaim": object{"claimName": ClaimName(svc, v)},
+		})
+	}
+	return out
+}
+
+// Mounts renders the volume mounts of a service's container
+func Mounts(svc *domain.Service) []interface{} {
+	var out []interface{}
+	for _, v := range svc.Volumes {
+		out = append(out, object{"name": v.Name, "mountPath": v.MountPath})
+	}
+	return out
+}
+
+// SnapshotName names a new snapshot of a volume
+func SnapshotName(svc *domain.Service, v domain.Volume, now time.Time) string {
+	suffix := fmt.Sprintf("-%d", now.Unix())
+	claim := ClaimName(svc, v)
+	if len(claim)+len(suffix) > 253 {
+		claim = strings.TrimRight(claim[:253-len(suffix)], "-")
+	}
+	return claim + suffix
+}
+
+// Snapshot renders a VolumeSnapshot of a volume's claim, of the given
+// VolumeSnapshotClass or else the cluster's default
+func Snapshot(svc *domain.Service, v domain.Volume, namespace, name, class string) object {
+	spec := object{"source": object{"persistentVolumeClaimName": ClaimName(svc, v)}}
+	if class != "" {
+		spec["volumeSnapshotClassName"] = class
+	}
+	return object{
+		"apiVersion": "snapshot.storage.k8s.io/v1",
+		"kind":       "VolumeSnapshot",
+		"metadata": object{
+			"name":      name,
+			"namespace": namespace,
+			"labels":    labels(svc, v),
+		},
+		"spec": spec,
+	}
+}
+
+// Status reads the state of a volume from its claim, which is nil when it
+// does not exist
+func Status(svc *domain.Service, v domain.Volume, claim map[string]interface{}) domain.VolumeStatus {
+	status := domain.VolumeStatus{Volume: v, Claim: ClaimName(svc, v), State: domain.VolumeNotDeployed}
+	if claim == nil {
+		return status
+	}
+	status.Capacity, _, _ = unstructured.NestedString(claim, "status", "capacity", "storage")
+
+	phase, _, _ := unstructured.NestedString(claim, "status", "phase")
+	switch phase {
+	case "Bound":
+		status.State = domain.VolumeBound
+		requested, _, _ := unstructured.NestedString(claim, "spec", "resources", "requests", "storage")
+		if smaller(status.Capacity, requested) {
+			status.State = domain.VolumeResizing
+		}
+	case "Lost":
+		status.State = domain.VolumeLost
+	default:
+		status.State = domain.VolumePending
+	}
+	return status
+}
+
+// Snapshots reads the VolumeSnapshots taken of a volume's claim, newest first
+func Snapshots(svc *domain.Service, v domain.Volume, objects []map[string]interface{}) []domain.VolumeSnapshot {
+	claim := ClaimName(svc, v)
+	snapshots := []domain.VolumeSnapshot{}
+	for _, obj := range objects {
+		if source, _, _ := unstructured.NestedString(obj, "spec", "source", "persistentVolumeClaimName"); source != claim {
+			continue
+		}
+		snapshot := domain.VolumeSnapshot{Volume: v.Name}
+		snapshot.Name, _, _ = unstructured.NestedString(obj, "metadata", "name")
+		snapshot.ReadyToUse, _, _ = unstructured.NestedBool(obj, "status", "readyToUse")
+		snapshot.RestoreSize, _, _ = unstructured.NestedString(obj, "status", "restoreSize")
+		snapshot.Error, _, _ = unstructured.NestedString(obj, "status", "error", "message")
+		if raw, _, _ := unstructured.NestedString(obj, "metadata", "creationTimestamp"); raw != "" {
+			if t, err := time.Parse(time.RFC3339, raw); err == nil {
+				snapshot.CreatedAt = &t
+			}
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		a, b := snapshots[i], snapshots[j]
+		if a.CreatedAt == nil || b.CreatedAt == nil {
+			return a.Name > b.Name
+		}
+		return a.CreatedAt.After(*b.CreatedAt)
+	})
+	return snapshots
+}
+
+// smaller reports whether a capacity is below the requested size
+func smaller(capacity, requested string) bool {
+	c, err := resource.ParseQuantity(capacity)
+	if err != nil {
+		return false
+	}
+	r, err := resource.ParseQuantity(requested)
+	if err != nil {
+		return false
+	}
+	return c.Cmp(r) < 0
+}
+
+func accessMode(v domain.Volume) domain.VolumeAccessMode {
+	if v.AccessMode == "" {
+		return domain.VolumeReadWriteOnce
+	}
+	return v.AccessMode
+}
+
+func labels(svc *domain.Service, v domain.Volume) object {
+	return object{
+		domain.LabelServiceID: svc.ID.String(),
+		domain.LabelProjectID: svc.ProjectID.String(),
+		domain.LabelManagedBy: domain.ManagedByValue,
+		LabelVolume:           v.Name,
+	}
+}
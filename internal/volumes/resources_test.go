@@ -0,0 +1,154 @@
+package volumes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testService() *domain.Service {
+	return &domain.Service{
+		ID:        uuid.New(),
+		ProjectID: uuid.New(),
+		Slug:      "db",
+		Type:      domain.ServiceTypeStatefulDB,
+		Volumes:   []domain.Volume{{Name: "data", Size: "10Gi", MountPath: "/var/lib/data"}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	svc := testService()
+
+	tests := []struct {
+		name    string
+		v       domain.Volume
+		wantErr bool
+	}{
+		{"valid", domain.Volume{Name: "logs", Size: "1Gi", MountPath: "/var/log/app"}, false},
+		{"read write many", domain.Volume{Name: "shared", Size: "500Mi", MountPath: "/shared", AccessMode: domain.VolumeReadWriteMany}, false},
+		{"existing volume", domain.Volume{Name: "data", Size: "20Gi", MountPath: "/var/lib/data"}, false},
+		{"uppercase name", domain.Volume{Name: "Logs", Size: "1Gi", MountPath: "/logs"}, true},
+		{"bad size", domain.Volume{Name: "logs", Size: "lots", MountPath: "/logs"}, true},
+		{"zero size", domain.Volume{Name: "logs", Size: "0", MountPath: "/logs"}, true},
+		{"relative path", domain.Volume{Name: "logs", Size: "1Gi", MountPath: "logs"}, true},
+		{"root path", domain.Volume{Name: "logs", Size: "1Gi", MountPath: "/"}, true},
+		{"unknown access mode", domain.Volume{Name: "logs", Size: "1Gi", MountPath: "/logs", AccessMode: "ReadOnlyMany"}, true},
+		{"mount path taken", domain.Volume{Name: "logs", Size: "1Gi", MountPath: "/var/lib/data/"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(svc, tt.v)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	cronJob := &domain.Service{Type: domain.ServiceTypeCronJob}
+	assert.Error(t, Validate(cronJob, domain.Volume{Name: "logs", Size: "1Gi", MountPath: "/logs"}))
+}
+
+func TestValidateChange(t *testing.T) {
+	old := domain.Volume{Name: "data", Size: "10Gi", StorageClass: "fast", MountPath: "/data"}
+
+	grown := old
+	grown.Size = "20Gi"
+	assert.NoError(t, ValidateChange(old, grown))
+	assert.Positive(t, Grows(old, grown))
+
+	same := old
+	same.Size = "10240Mi"
+	assert.NoError(t, ValidateChange(old, same))
+	assert.Zero(t, Grows(old, same))
+
+	shrunk := old
+	shrunk.Size = "5Gi"
+	assert.Error(t, ValidateChange(old, shrunk))
+
+	reclassed := old
+	reclassed.StorageClass = "slow"
+	assert.Error(t, ValidateChange(old, reclassed))
+
+	shared := old
+	shared.AccessMode = domain.VolumeReadWriteMany
+	assert.Error(t, ValidateChange(old, shared))
+	explicit := old
+	explicit.AccessMode = domain.VolumeReadWriteOnce
+	assert.NoError(t, ValidateChange(old, explicit))
+}
+
+func TestClaim(t *testing.T) {
+	svc := testService()
+	v := svc.Volumes[0]
+
+	obj := Claim(svc, v, "shop-production")
+	assert.Equal(t, "PersistentVolumeClaim", obj["kind"])
+	metadata := obj["metadata"].(object)
+	assert.Equal(t, "db-data", metadata["name"])
+	assert.Equal(t, "shop-production", metadata["namespace"])
+	assert.Equal(t, "data", metadata["labels"].(object)[LabelVolume])
+	assert.NotContains(t, metadata, "ownerReferences")
+
+	spec := obj["spec"].(object)
+	assert.Equal(t, []interface{}{"ReadWriteOnce"}, spec["accessModes"])
+	assert.Equal(t, object{"requests": object{"storage": "10Gi"}}, spec["resources"])
+	assert.NotContains(t, spec, "storageClassName")
+
+	v.StorageClass = "fast"
+	assert.Equal(t, "fast", ClaimSpec(v)["storageClassName"])
+}
+
+func TestStatus(t *testing.T) {
+	svc := testService()
+	v := svc.Volumes[0]
+
+	assert.Equal(t, domain.VolumeNotDeployed, Status(svc, v, nil).State)
+
+	claim := func(phase, requested, capacity string) map[string]interface{} {
+		return map[string]interface{}{
+			"spec":   map[string]interface{}{"resources": map[string]interface{}{"requests": map[string]interface{}{"storage": requested}}},
+			"status": map[string]interface{}{"phase": phase, "capacity": map[string]interface{}{"storage": capacity}},
+		}
+	}
+	bound := Status(svc, v, claim("Bound", "10Gi", "10Gi"))
+	assert.Equal(t, domain.VolumeBound, bound.State)
+	assert.Equal(t, "10Gi", bound.Capacity)
+	assert.Equal(t, "db-data", bound.Claim)
+	assert.Equal(t, domain.VolumeResizing, Status(svc, v, claim("Bound", "20Gi", "10Gi")).State)
+	assert.Equal(t, domain.VolumePending, Status(svc, v, claim("Pending", "10Gi", "")).State)
+	assert.Equal(t, domain.VolumeLost, Status(svc, v, claim("Lost", "10Gi", "10Gi")).State)
+}
+
+func TestSnapshots(t *testing.T) {
+	svc := testService()
+	v := svc.Volumes[0]
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	snapshot := func(name, claim string, created time.Time) map[string]interface{} {
+		return map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "creationTimestamp": created.Format(time.RFC3339)},
+			"spec":     map[string]interface{}{"source": map[string]interface{}{"persistentVolumeClaimName": claim}},
+			"status":   map[string]interface{}{"readyToUse": true, "restoreSize": "10Gi"},
+		}
+	}
+	snapshots := Snapshots(svc, v, []map[string]interface{}{
+		snapshot("db-data-1", "db-data", now.Add(-time.Hour)),
+		snapshot("other-1", "other-data", now),
+		snapshot("db-data-2", "db-data", now),
+	})
+	require.Len(t, snapshots, 2)
+	assert.Equal(t, "db-data-2", snapshots[0].Name)
+	assert.Equal(t, "db-data-1", snapshots[1].Name)
+	assert.True(t, snapshots[0].ReadyToUse)
+	assert.Equal(t, "data", snapshots[0].Volume)
+
+	assert.Equal(t, "db-data-1777636800", SnapshotName(svc, v, now))
+	obj := Snapshot(svc, v, "shop-production", "db-data-1777636800", "csi-snapclass")
+	assert.Equal(t, object{"source": object{"persistentVolumeClaimName": "db-data"}, "volumeSnapshotClassName": "csi-snapclass"}, obj["spec"])
+}
@@ -0,0 +1,154 @@
+// Package warmpool keeps warm standby capacity for latency-sensitive
+// services: a number of idle replicas above current demand, so scale-ups are
+// served by running pods, and optionally the service's images pre-pulled on
+// every eligible node by a DaemonSet, so new pods start without a pull.
+package warmpool
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/internal/prepull"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// demandWindow is the recent usage window current demand is estimated from
+const demandWindow = 5 * time.Minute
+
+// Pool maintains warm standby capacity
+type Pool struct {
+	kube        domain.KubernetesClient
+	metrics     domain.MetricsCollector
+	serviceRepo domain.ServiceRepository
+	prices      *config.MeteringConfig
+	logger      *logger.Logger
+}
+
+// NewPool creates a new Pool. metrics may be nil, in which case standby
+// replicas are kept on top of the minimum replica count.
+func NewPool(
+	kube domain.KubernetesClient,
+	metrics domain.MetricsCollector,
+	serviceRepo domain.ServiceRepository,
+	prices *config.MeteringConfig,
+	log *logger.Logger,
+) *Pool {
+	return &Pool{
+		kube:        kube,
+		metrics:     metrics,
+		serviceRepo: serviceRepo,
+		prices:      prices,
+		logger:      log,
+	}
+}
+
+// Floor raises the minimum replica count so the service keeps its standby
+// replicas idle above current demand, within [min, max]
+func (p *Pool) Floor(ctx context.Context, svc *domain.Service, min, max int32) int32 {
+	standby := svc.Scaling.WarmStandby
+	if standby == nil || standby.Replicas == 0 {
+		return min
+	}
+
+	var current int32
+	if p.metrics != nil {
+		now := time.Now()
+		m, err := p.metrics.GetServiceMetrics(ctx, svc.ID, domain.TimeRange{
+			Start: now.Add(-demandWindow).Unix(),
+			End:   now.Unix(),
+			Step:  60,
+		})
+		if err != nil {
+			p.logger.Warn().Err(err).Str("service_id", svc.ID.String()).Msg("Failed to read demand for warm standby")
+		} else {
+			current = demand(last(m.CPUUsage), last(m.MemoryUsage), svc.Resources, svc.Scaling)
+		}
+	}
+
+	return floor(current, standby.Replicas, min, max)
+}
+
+// Estimate prices the service's idle standby replicas at the configured unit prices
+func (p *Pool) Estimate(svc *domain.Service, resources domain.ResourceLimits) *Cost {
+	return Estimate(svc.Scaling.WarmStandby, resources, p.prices)
+}
+
+// EnsurePrewarm installs, updates or removes the image pre-warming DaemonSet
+// of the service to match its warm standby configuration
+func (p *Pool) EnsurePrewarm(ctx context.Context, svc *domain.Service) error {
+	if svc.TargetClusterID == nil {
+		return nil
+	}
+
+	target, err := prepull.FindTarget(ctx, p.kube, svc)
+	if err != nil {
+		return err
+	}
+
+	if standby := svc.Scaling.WarmStandby; standby == nil || !standby.PrewarmImage {
+		existing, err := p.kube.GetResource(ctx, target.ClusterID, "DaemonSet", target.Namespace, prewarmName(target))
+		if err != nil || existing == nil {
+			return nil
+		}
+		if err := p.kube.DeleteResource(ctx, target.ClusterID, "DaemonSet", target.Namespace, prewarmName(target)); err != nil {
+			return errors.DependencyFailed("kubernetes", err)
+		}
+		p.logger.Info().Str("service_id", svc.ID.String()).Msg("Removed image pre-warming DaemonSet")
+		return nil
+	}
+
+	manifest, err := json.Marshal(prewarmDaemonSet(svc, target))
+	if err != nil {
+		return errors.Wrap(err, "failed to encode DaemonSet")
+	}
+	if err := p.kube.ApplyManifest(ctx, target.ClusterID, manifest); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+
+	p.logger.Info().Str("service_id", svc.ID.String()).Msg("Applied image pre-warming DaemonSet")
+	return nil
+}
+
+// Watch refreshes pre-warmed images as deployments of new versions complete,
+// through a durable consumer so that failures are retried
+func (p *Pool) Watch(ctx context.Context, bus domain.EventBus) error {
+	_, err := eventbus.SubscribeDurable(ctx, bus, "deploy.completed", eventbus.DurableName("warmpool", "deploy.completed"), func(event *domain.Event) error {
+		raw, _ := event.Data["service_id"].(string)
+		serviceID, err := uuid.Parse(raw)
+		if err != nil {
+			return nil
+		}
+
+		svc, err := p.serviceRepo.GetByID(ctx, serviceID)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			p.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to load service for image pre-warming")
+			return err
+		}
+		if svc.Scaling.WarmStandby == nil || !svc.Scaling.WarmStandby.PrewarmImage {
+			return nil
+		}
+
+		if err := p.EnsurePrewarm(ctx, svc); err != nil {
+			p.logger.Warn().Err(err).Str("service_id", raw).Msg("Failed to pre-warm image")
+			return err
+		}
+		return nil
+	})
+	return err
+}
+
+func last(points []domain.MetricPoint) float64 {
+	if len(points) == 0 {
+		return 0
+	}
+	return points[len(points)-1].Value
+}
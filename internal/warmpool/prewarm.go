@@ -0,0 +1,16 @@
+package warmpool
+
+import (
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/prepull"
+)
+
+func prewarmName(t *prepull.Target) string {
+	return t.Name + "-prewarm"
+}
+
+// prewarmDaemonSet renders a DaemonSet that keeps the images of the service's
+// current pods pulled on every node they may be scheduled on
+func prewarmDaemonSet(svc *domain.Service, t *prepull.Target) map[string]interface{} {
+	return prepull.DaemonSet(svc, t, prewarmName(t), t.Images())
+}
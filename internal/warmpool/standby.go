@@ -0,0 +1,106 @@
+package warmpool
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// maxStandbyReplicas bounds the idle replicas a service may keep
+	maxStandbyReplicas = 20
+	// hoursPerMonth is the average number of hours in a month
+	hoursPerMonth = 730
+	gib           = 1 << 30
+)
+
+// Cost is the estimated monthly cost of keeping the standby replicas idle
+type Cost struct {
+	Replicas    int32   `json:"replicas"`
+	CPUCores    float64 `json:"cpu_cores"`  // Requested by the idle replicas
+	MemoryGiB   float64 `json:"memory_gib"` // Requested by the idle replicas
+	MonthlyCost float64 `json:"monthly_cost"`
+	Currency    string  `json:"currency"`
+}
+
+// Validate checks a warm standby configuration against the service's replica range
+func Validate(standby *domain.WarmStandby, scaling domain.ScalingConfig) error {
+	if standby == nil {
+		return nil
+	}
+	if standby.Replicas < 0 || standby.Replicas > maxStandbyReplicas {
+		return errors.BadRequest(fmt.Sprintf("warm standby replicas must be between 0 and %d", maxStandbyReplicas))
+	}
+	if standby.Replicas == 0 && !standby.PrewarmImage {
+		return errors.BadRequest("warm standby needs replicas or prewarm_image")
+	}
+	if standby.Replicas > 0 && scaling.MaxReplicas <= scaling.MinReplicas {
+		return errors.BadRequest("warm standby replicas need max_replicas above min_replicas")
+	}
+	return nil
+}
+
+// Estimate prices the requests of the idle replicas at the given unit prices.
+// It returns nil when the service keeps no idle replicas.
+func Estimate(standby *domain.WarmStandby, resources domain.ResourceLimits, prices *config.MeteringConfig) *Cost {
+	if standby == nil || standby.Replicas == 0 {
+		return nil
+	}
+
+	replicas := float64(standby.Replicas)
+	cost := &Cost{
+		Replicas:  standby.Replicas,
+		CPUCores:  round(quantity(resources.CPURequest) * replicas),
+		MemoryGiB: round(quantity(resources.MemoryRequest) / gib * replicas),
+		Currency:  prices.Currency,
+	}
+	cost.MonthlyCost = round((cost.CPUCores*prices.CPUCoreHourPrice + cost.MemoryGiB*prices.MemoryGiBHourPrice) * hoursPerMonth)
+	return cost
+}
+
+// demand estimates the replicas needed for the current load without any
+// standby, from the autoscaling targets. It returns 0 when the service has
+// no resource target to derive it from.
+func demand(cpuCores, memoryBytes float64, resources domain.ResourceLimits, scaling domain.ScalingConfig) int32 {
+	var needed float64
+	if request := quantity(resources.CPURequest); scaling.TargetCPU > 0 && request > 0 {
+		needed = math.Max(needed, cpuCores/(request*float64(scaling.TargetCPU)/100))
+	}
+	if request := quantity(resources.MemoryRequest); scaling.TargetMemory > 0 && request > 0 {
+		needed = math.Max(needed, memoryBytes/(request*float64(scaling.TargetMemory)/100))
+	}
+	return int32(math.Ceil(needed))
+}
+
+// floor is the minimum replica count that leaves standby idle replicas on top
+// of demand, within the service's replica range
+func floor(demand, standby, min, max int32) int32 {
+	f := demand + standby
+	if f < min {
+		f = min
+	}
+	if f > max {
+		f = max
+	}
+	return f
+}
+
+// quantity parses a Kubernetes quantity, returning 0 when unset or invalid
+func quantity(v string) float64 {
+	if v == "" {
+		return 0
+	}
+	q, err := resource.ParseQuantity(v)
+	if err != nil {
+		return 0
+	}
+	return q.AsApproximateFloat64()
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,47 @@
+package warmpool
+
+import (
+	"testing"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	scaling := domain.ScalingConfig{MinReplicas: 2, MaxReplicas: 10}
+	assert.NoError(t, Validate(nil, scaling))
+	assert.NoError(t, Validate(&domain.WarmStandby{Replicas: 2}, scaling))
+	assert.NoError(t, Validate(&domain.WarmStandby{PrewarmImage: true}, domain.ScalingConfig{MinReplicas: 1, MaxReplicas: 1}))
+	assert.Error(t, Validate(&domain.WarmStandby{}, scaling))
+	assert.Error(t, Validate(&domain.WarmStandby{Replicas: 21}, scaling))
+	assert.Error(t, Validate(&domain.WarmStandby{Replicas: 1}, domain.ScalingConfig{MinReplicas: 3, MaxReplicas: 3}))
+}
+
+func TestFloor(t *testing.T) {
+	resources := domain.ResourceLimits{CPURequest: "500m", MemoryRequest: "512Mi"}
+	scaling := domain.ScalingConfig{MinReplicas: 2, MaxReplicas: 10, TargetCPU: 50}
+
+	// 1.5 cores at 0.25 cores per replica needs 6 replicas
+	d := demand(1.5, 0, resources, scaling)
+	assert.Equal(t, int32(6), d)
+	assert.Equal(t, int32(8), floor(d, 2, 2, 10))
+	assert.Equal(t, int32(10), floor(d, 5, 2, 10))
+
+	// No target to derive demand from: standby sits on top of nothing, but min still holds
+	assert.Equal(t, int32(0), demand(1.5, 0, resources, domain.ScalingConfig{}))
+	assert.Equal(t, int32(3), floor(0, 3, 2, 10))
+	assert.Equal(t, int32(2), floor(0, 1, 2, 10))
+}
+
+func TestEstimate(t *testing.T) {
+	prices := &config.MeteringConfig{Currency: "USD", CPUCoreHourPrice: 0.04, MemoryGiBHourPrice: 0.005}
+	resources := domain.ResourceLimits{CPURequest: "250m", MemoryRequest: "1Gi"}
+
+	cost := Estimate(&domain.WarmStandby{Replicas: 4}, resources, prices)
+	assert.Equal(t, 1.0, cost.CPUCores)
+	assert.Equal(t, 4.0, cost.MemoryGiB)
+	assert.Equal(t, 43.8, cost.MonthlyCost) // (1 * 0.04 + 4 * 0.005) * 730
+
+	assert.Nil(t, Estimate(&domain.WarmStandby{PrewarmImage: true}, resources, prices))
+}
@@ -0,0 +1,434 @@
+// Package webhooks delivers platform events to the HTTPS endpoints projects
+// subscribe. Each request is signed with the subscription's HMAC secret and
+// retried with exponential backoff; every attempt is logged so that users can
+// see why an endpoint missed an event. Attempts are queued in the delivery
+// log before they are made, so none is lost when the orchestrator restarts.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/eventbus"
+	"github.com/northstack/platform/internal/signing"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Headers of webhook requests
+const (
+	HeaderSignature = "X-Northstack-Webhook-Signature" // t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+	HeaderEvent     = "X-Northstack-Event"
+	HeaderDelivery  = "X-Northstack-Delivery" // ID of the attempt in the delivery log
+)
+
+// TestEventType is the type of the events sent by test deliveries
+const TestEventType = "webhook.test"
+
+// maxResponseBody is how much of an endpoint's response is read, so that the
+// connection can be reused; it is discarded, not logged
+const maxResponseBody = 1024
+
+const (
+	// pollInterval is how often queued deliveries are checked for due attempts
+	pollInterval = 5 * time.Second
+	// dueBatch bounds the attempts picked up per poll
+	dueBatch = 100
+	// maxConcurrentDeliveries bounds the requests in flight per replica
+	maxConcurrentDeliveries = 10
+	// claimMargin is added to the request timeout for how long a claimed
+	// attempt is left to the replica sending it
+	claimMargin = time.Minute
+)
+
+// EventTypes are the event types subscriptions can receive
+var EventTypes = []string{
+	"build.started",
+	"build.completed",
+	"build.failed",
+	"deploy.started",
+	"deploy.completed",
+	"deploy.failed",
+	"service.created",
+	"service.updated",
+	"service.deleted",
+	"service.scaled",
+	"alert.fired",
+	"alert.resolved",
+}
+
+// Payload is the body of a webhook request
+type Payload struct {
+	ID        string                 `json:"id"` // Event ID, the same on every retry
+	Type      string                 `json:"type"`
+	ProjectID uuid.UUID              `json:"project_id"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Dispatcher delivers events to webhook subscriptions
+type Dispatcher struct {
+	repo     domain.WebhookRepository
+	eventBus domain.EventBus
+	signer   *signing.Signer
+	client   *http.Client
+	cfg      *config.WebhooksConfig
+	logger   *logger.Logger
+}
+
+// NewDispatcher creates a new Dispatcher. signer may be nil; when set,
+// requests also carry the X-Northstack-Signature JWS of the project's team.
+func NewDispatcher(repo domain.WebhookRepository, eventBus domain.EventBus, signer *signing.Signer, cfg *config.WebhooksConfig, log *logger.Logger) *Dispatcher {
+	return &Dispatcher{
+		repo:     repo,
+		eventBus: eventBus,
+		signer:   signer,
+		client:   newClient(cfg.Timeout, publicOnly),
+		cfg:      cfg,
+		logger:   log,
+	}
+}
+
+// Watch queues deliveries of the events subscriptions are interested in until
+// ctx is cancelled. Run sends them.
+func (d *Dispatcher) Watch(ctx context.Context) error {
+	if d.eventBus == nil {
+		return nil
+	}
+
+	for _, eventType := range EventTypes {
+		// A durable consumer so that each event is queued once across
+		// replicas, including those published while none was running
+		if _, err := eventbus.SubscribeDurable(ctx, d.eventBus, eventType, eventbus.DurableName("webhooks", eventType), func(event *domain.Event) error {
+			return d.Enqueue(ctx, event)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Enqueue queues a delivery of an event to each active subscription of its
+// project that receives its type. An error leaves the event unacknowledged;
+// queueing a redelivered event again changes nothing, as attempts have IDs
+// derived from the event.
+func (d *Dispatcher) Enqueue(ctx context.Context, event *domain.Event) error {
+	raw, _ := event.Data["project_id"].(string)
+	projectID, err := uuid.Parse(raw)
+	if err != nil {
+		return nil
+	}
+
+	subscriptions, err := d.repo.ListByProject(ctx, projectID)
+	if err != nil {
+		d.logger.Warn().Err(err).Str("project_id", raw).Msg("Failed to list webhook subscriptions")
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		if !subscription.Active || !subscription.Matches(event.Type) {
+			continue
+		}
+		body, err := encodePayload(subscription, event)
+		if err != nil {
+			d.logger.Error().Err(err).Str("event_id", event.ID).Msg("Failed to encode webhook payload")
+			continue
+		}
+
+		now := time.Now().UTC()
+		delivery := &domain.WebhookDelivery{
+			ID:             uuid.NewSHA1(subscription.ID, []byte(event.ID)),
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Attempt:        1,
+			Status:         domain.WebhookDeliveryPending,
+			NextAttemptAt:  &now,
+			Payload:        body,
+			CreatedAt:      now,
+		}
+		if err := d.repo.CreateDelivery(ctx, delivery); err != nil {
+			d.logger.Warn().Err(err).Str("subscription_id", subscription.ID.String()).Msg("Failed to queue webhook delivery")
+			return err
+		}
+	}
+	return nil
+}
+
+// Run sends queued deliveries as they fall due until ctx is cancelled. Every
+// replica runs it: an attempt is claimed before it is sent, so one replica
+// sends each, and an attempt claimed by a replica that stopped is sent again
+// once its claim expires.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue sends the attempts due now, a few at a time
+func (d *Dispatcher) sendDue(ctx context.Context) {
+	now := time.Now().UTC()
+	due, err := d.repo.ListDueDeliveries(ctx, now, dueBatch)
+	if err != nil {
+		d.logger.Warn().Err(err).Msg("Failed to list due webhook deliveries")
+		return
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentDeliveries)
+	for _, delivery := range due {
+		// Claimed once a slot is free, so the claim covers the request
+		slots <- struct{}{}
+		claimedAt := time.Now().UTC()
+		claimed, err := d.repo.ClaimDelivery(ctx, delivery.ID, claimedAt, claimedAt.Add(d.cfg.Timeout+claimMargin))
+		if err != nil || !claimed {
+			if err != nil {
+				d.logger.Warn().Err(err).Str("delivery_id", delivery.ID.String()).Msg("Failed to claim webhook delivery")
+			}
+			<-slots
+			continue // Otherwise another replica is sending it
+		}
+
+		wg.Add(1)
+		go func(delivery *domain.WebhookDelivery) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			d.sendQueued(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+// sendQueued makes a queued attempt and records its outcome, queueing the
+// next attempt first when it failed and another is left
+func (d *Dispatcher) sendQueued(ctx context.Context, delivery *domain.WebhookDelivery) {
+	subscription, err := d.repo.GetByID(ctx, delivery.SubscriptionID)
+	if errors.IsNotFound(err) {
+		return // Deleted with its deliveries
+	}
+	if err != nil {
+		d.logger.Warn().Err(err).Str("subscription_id", delivery.SubscriptionID.String()).Msg("Failed to load webhook subscription")
+		return
+	}
+
+	attempts := d.cfg.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	final := delivery.Attempt >= attempts
+
+	body := delivery.Payload
+	if !subscription.Active {
+		delivery.Status, delivery.Error = domain.WebhookDeliveryGaveUp, "subscription is disabled"
+		delivery.NextAttemptAt = nil
+	} else {
+		d.attempt(ctx, subscription, delivery, body, final)
+	}
+
+	if delivery.Status == domain.WebhookDeliveryFailed {
+		due := time.Now().UTC().Add(Backoff(d.cfg.InitialBackoff, d.cfg.MaxBackoff, delivery.Attempt))
+		next := &domain.WebhookDelivery{
+			ID:             uuid.NewSHA1(delivery.ID, []byte("retry")),
+			SubscriptionID: delivery.SubscriptionID,
+			EventID:        delivery.EventID,
+			EventType:      delivery.EventType,
+			Attempt:        delivery.Attempt + 1,
+			Status:         domain.WebhookDeliveryPending,
+			NextAttemptAt:  &due,
+			Payload:        body,
+			CreatedAt:      time.Now().UTC(),
+		}
+		// Without it this attempt stays queued and is made again once its claim expires
+		if err := d.repo.CreateDelivery(context.WithoutCancel(ctx), next); err != nil {
+			d.logger.Warn().Err(err).Str("subscription_id", subscription.ID.String()).Msg("Failed to queue webhook retry")
+			return
+		}
+	}
+	if delivery.Status == domain.WebhookDeliveryGaveUp {
+		d.logger.Warn().
+			Str("subscription_id", subscription.ID.String()).
+			Str("event_id", delivery.EventID).
+			Int("attempts", delivery.Attempt).
+			Msg("Webhook delivery failed on every attempt, giving up")
+	}
+
+	// The log lives as long as the subscription, not the request that triggered it
+	if err := d.repo.UpdateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		d.logger.Warn().Err(err).Str("subscription_id", subscription.ID.String()).Msg("Failed to log webhook delivery")
+	}
+}
+
+// Test sends a webhook.test event to a subscription once and returns the
+// logged attempt
+func (d *Dispatcher) Test(ctx context.Context, subscription *domain.WebhookSubscription) (*domain.WebhookDelivery, error) {
+	event := &domain.Event{
+		ID:        uuid.New().String(),
+		Type:      TestEventType,
+		Source:    "webhooks",
+		Timestamp: time.Now().UnixNano(),
+		Data: map[string]interface{}{
+			"project_id":      subscription.ProjectID.String(),
+			"subscription_id": subscription.ID.String(),
+			"message":         "This is a test delivery",
+		},
+	}
+
+	body, err := encodePayload(subscription, event)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &domain.WebhookDelivery{
+		ID:             uuid.New(),
+		SubscriptionID: subscription.ID,
+		EventID:        event.ID,
+		EventType:      event.Type,
+		Attempt:        1,
+		Test:           true,
+		CreatedAt:      time.Now().UTC(),
+	}
+	d.attempt(ctx, subscription, delivery, body, true)
+	if err := d.repo.CreateDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		d.logger.Warn().Err(err).Str("subscription_id", subscription.ID.String()).Msg("Failed to log webhook delivery")
+	}
+	return delivery, nil
+}
+
+// attempt makes one request to the subscription's endpoint and records the
+// outcome on the delivery
+func (d *Dispatcher) attempt(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.WebhookDelivery, body []byte, final bool) {
+	start := time.Now()
+	status, err := d.send(ctx, subscription, delivery.EventType, delivery.ID, body)
+	delivery.DurationMS = time.Since(start).Milliseconds()
+	delivery.ResponseStatus = status
+	delivery.NextAttemptAt = nil
+	switch {
+	case err != nil:
+		delivery.Error = err.Error()
+	case status < 200 || status > 299:
+		delivery.Error = fmt.Sprintf("endpoint answered %d", status)
+	}
+
+	switch {
+	case delivery.Error == "":
+		delivery.Status = domain.WebhookDeliverySucceeded
+	case final:
+		delivery.Status = domain.WebhookDeliveryGaveUp
+	default:
+		delivery.Status = domain.WebhookDeliveryFailed
+	}
+}
+
+// send posts the payload and returns the response status. The response body
+// is not kept: it would hand whatever the endpoint reached back to the API.
+func (d *Dispatcher) send(ctx context.Context, subscription *domain.WebhookSubscription, eventType string, deliveryID uuid.UUID, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Northstack-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, eventType)
+	req.Header.Set(HeaderDelivery, deliveryID.String())
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, time.Now(), body))
+	if d.signer != nil {
+		if err := d.signer.SignRequest(ctx, req, d.signer.ProjectOrg(ctx, subscription.ProjectID), body); err != nil {
+			// The HMAC signature still authenticates the request
+			d.logger.Warn().Err(err).Str("subscription_id", subscription.ID.String()).Msg("Failed to sign webhook with team key")
+		}
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, nil
+}
+
+func encodePayload(subscription *domain.WebhookSubscription, event *domain.Event) ([]byte, error) {
+	createdAt := time.Now().UTC()
+	if event.Timestamp != 0 {
+		createdAt = time.Unix(0, event.Timestamp).UTC()
+	}
+	return json.Marshal(Payload{
+		ID:        event.ID,
+		Type:      event.Type,
+		ProjectID: subscription.ProjectID,
+		CreatedAt: createdAt,
+		Data:      event.Data,
+	})
+}
+
+// Sign returns the HeaderSignature value for a body sent at the given time
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff is the delay before the retry that follows the given attempt: the
+// initial delay, doubled after each attempt, up to limit
+func Backoff(initial, limit time.Duration, attempt int) time.Duration {
+	delay := initial
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if limit > 0 && delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// NewSecret generates the HMAC secret of a new subscription
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// ValidEventType reports whether subscriptions can receive an event type; *
+// stands for every type
+func ValidEventType(eventType string) bool {
+	if eventType == "*" {
+		return true
+	}
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
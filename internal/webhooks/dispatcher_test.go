@@ -0,0 +1,280 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"evt-1"}`)
+	at := time.Unix(1700000000, 0)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(body)))
+	assert.Equal(t, "t=1700000000,v1="+hex.EncodeToString(mac.Sum(nil)), Sign("whsec_test", at, body))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, Backoff(10*time.Second, time.Minute, 1))
+	assert.Equal(t, 20*time.Second, Backoff(10*time.Second, time.Minute, 2))
+	assert.Equal(t, 40*time.Second, Backoff(10*time.Second, time.Minute, 3))
+	assert.Equal(t, time.Minute, Backoff(10*time.Second, time.Minute, 4))
+	assert.Equal(t, time.Minute, Backoff(10*time.Second, time.Minute, 30))
+}
+
+// deliveryQueue keeps subscriptions and delivery attempts in memory
+type deliveryQueue struct {
+	domain.WebhookRepository
+	mu            sync.Mutex
+	subscriptions []*domain.WebhookSubscription
+	deliveries    []*domain.WebhookDelivery
+}
+
+func (q *deliveryQueue) GetByID(_ context.Context, id uuid.UUID) (*domain.WebhookSubscription, error) {
+	for _, s := range q.subscriptions {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, errors.NotFound("webhook", id.String())
+}
+
+func (q *deliveryQueue) ListByProject(_ context.Context, projectID uuid.UUID) ([]*domain.WebhookSubscription, error) {
+	var subscriptions []*domain.WebhookSubscription
+	for _, s := range q.subscriptions {
+		if s.ProjectID == projectID {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (q *deliveryQueue) CreateDelivery(_ context.Context, delivery *domain.WebhookDelivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, existing := range q.deliveries {
+		if existing.ID == delivery.ID {
+			return nil
+		}
+	}
+	stored := *delivery
+	q.deliveries = append(q.deliveries, &stored)
+	return nil
+}
+
+func (q *deliveryQueue) UpdateDelivery(_ context.Context, delivery *domain.WebhookDelivery) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i, existing := range q.deliveries {
+		if existing.ID == delivery.ID {
+			stored := *delivery
+			stored.Payload = nil
+			q.deliveries[i] = &stored
+			return nil
+		}
+	}
+	return errors.NotFound("webhook delivery", delivery.ID.String())
+}
+
+func (q *deliveryQueue) ListDueDeliveries(_ context.Context, now time.Time, limit int) ([]*domain.WebhookDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var due []*domain.WebhookDelivery
+	for _, d := range q.deliveries {
+		if d.Status == domain.WebhookDeliveryPending && !d.NextAttemptAt.After(now) && len(due) < limit {
+			copied := *d
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+func (q *deliveryQueue) ClaimDelivery(_ context.Context, id uuid.UUID, now, until time.Time) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, d := range q.deliveries {
+		if d.ID == id && d.Status == domain.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = &until
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// attempts returns the logged attempts other than pending ones
+func (q *deliveryQueue) attempts() []*domain.WebhookDelivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var attempts []*domain.WebhookDelivery
+	for _, d := range q.deliveries {
+		if d.Status != domain.WebhookDeliveryPending {
+			attempts = append(attempts, d)
+		}
+	}
+	return attempts
+}
+
+// deliver queues an event for the subscription and sends it until no attempt is left
+func deliver(t *testing.T, d *Dispatcher, q *deliveryQueue, subscription *domain.WebhookSubscription, event *domain.Event) {
+	t.Helper()
+	subscription.Active = true
+	subscription.EventTypes = []string{"*"}
+	q.subscriptions = append(q.subscriptions, subscription)
+	event.Data = map[string]interface{}{"project_id": subscription.ProjectID.String()}
+
+	ctx := context.Background()
+	require.NoError(t, d.Enqueue(ctx, event))
+	for i := 0; i < 20; i++ {
+		due, err := q.ListDueDeliveries(ctx, time.Now().Add(time.Hour), 10)
+		require.NoError(t, err)
+		if len(due) == 0 {
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+		d.sendDue(ctx)
+	}
+	t.Fatal("deliveries are still queued")
+}
+
+func TestDeliverRetriesUntilSuccess(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.True(t, strings.HasPrefix(r.Header.Get(HeaderSignature), "t="))
+		assert.Equal(t, "deploy.completed", r.Header.Get(HeaderEvent))
+		assert.Contains(t, string(body), `"id":"evt-1"`)
+
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	q := &deliveryQueue{}
+	d := NewDispatcher(q, nil, nil, &config.WebhooksConfig{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+		Timeout:        time.Second,
+	}, logger.New("error", "json", io.Discard))
+	// The test server listens on loopback, which deliveries otherwise refuse
+	d.client = newClient(time.Second, nil)
+
+	subscription := &domain.WebhookSubscription{ID: uuid.New(), ProjectID: uuid.New(), URL: server.URL, Secret: "whsec_test"}
+	event := &domain.Event{ID: "evt-1", Type: "deploy.completed"}
+	deliver(t, d, q, subscription, event)
+
+	attempts := q.attempts()
+	require.Len(t, attempts, 3)
+	assert.Equal(t, domain.WebhookDeliveryFailed, attempts[0].Status)
+	assert.Equal(t, http.StatusBadGateway, attempts[0].ResponseStatus)
+	assert.Equal(t, domain.WebhookDeliverySucceeded, attempts[2].Status)
+	assert.Equal(t, 3, attempts[2].Attempt)
+	assert.Nil(t, attempts[2].Payload, "bodies are not kept once sent")
+
+	// A redelivered event is not delivered again
+	require.NoError(t, d.Enqueue(context.Background(), event))
+	d.sendDue(context.Background())
+	assert.Equal(t, 3, calls)
+	assert.Len(t, q.attempts(), 3)
+}
+
+func TestEnqueueSkipsOtherSubscriptions(t *testing.T) {
+	q := &deliveryQueue{}
+	d := NewDispatcher(q, nil, nil, &config.WebhooksConfig{MaxAttempts: 1, Timeout: time.Second}, logger.New("error", "json", io.Discard))
+
+	projectID := uuid.New()
+	q.subscriptions = []*domain.WebhookSubscription{
+		{ID: uuid.New(), ProjectID: projectID, Active: true, EventTypes: []string{"build.failed"}},
+		{ID: uuid.New(), ProjectID: projectID, Active: false, EventTypes: []string{"*"}},
+		{ID: uuid.New(), ProjectID: uuid.New(), Active: true, EventTypes: []string{"*"}},
+	}
+	receiving := &domain.WebhookSubscription{ID: uuid.New(), ProjectID: projectID, Active: true, EventTypes: []string{"deploy.completed"}}
+	q.subscriptions = append(q.subscriptions, receiving)
+
+	require.NoError(t, d.Enqueue(context.Background(), &domain.Event{ID: "evt-1", Type: "deploy.completed", Data: map[string]interface{}{"project_id": projectID.String()}}))
+	require.Len(t, q.deliveries, 1)
+	queued := q.deliveries[0]
+	assert.Equal(t, receiving.ID, queued.SubscriptionID)
+	assert.Equal(t, domain.WebhookDeliveryPending, queued.Status)
+	assert.Contains(t, string(queued.Payload), `"id":"evt-1"`)
+	require.NotNil(t, queued.NextAttemptAt)
+}
+
+func TestPrivateAddress(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.100.100.200", "0.0.0.0", "::1", "fe80::1", "fd00:ec2::254", "::ffff:127.0.0.1"} {
+		assert.True(t, PrivateAddress(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:4700::1111"} {
+		assert.False(t, PrivateAddress(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestDeliveryRefusesPrivateAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	q := &deliveryQueue{}
+	d := NewDispatcher(q, nil, nil, &config.WebhooksConfig{MaxAttempts: 1, Timeout: time.Second}, logger.New("error", "json", io.Discard))
+
+	subscription := &domain.WebhookSubscription{ID: uuid.New(), ProjectID: uuid.New(), URL: server.URL, Secret: "whsec_test"}
+	deliver(t, d, q, subscription, &domain.Event{ID: "evt-1", Type: "deploy.completed"})
+
+	assert.False(t, called)
+	attempts := q.attempts()
+	require.Len(t, attempts, 1)
+	assert.Equal(t, domain.WebhookDeliveryGaveUp, attempts[0].Status)
+	assert.Contains(t, attempts[0].Error, "is not public")
+}
+
+func TestDeliveryDoesNotFollowRedirects(t *testing.T) {
+	internal := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			internal = true
+			return
+		}
+		http.Redirect(w, r, "/internal", http.StatusSeeOther)
+	}))
+	defer server.Close()
+
+	q := &deliveryQueue{}
+	d := NewDispatcher(q, nil, nil, &config.WebhooksConfig{MaxAttempts: 1, Timeout: time.Second}, logger.New("error", "json", io.Discard))
+	d.client = newClient(time.Second, nil)
+
+	subscription := &domain.WebhookSubscription{ID: uuid.New(), ProjectID: uuid.New(), URL: server.URL, Secret: "whsec_test"}
+	deliver(t, d, q, subscription, &domain.Event{ID: "evt-1", Type: "deploy.completed"})
+
+	assert.False(t, internal)
+	attempts := q.attempts()
+	require.Len(t, attempts, 1)
+	assert.Equal(t, http.StatusSeeOther, attempts[0].ResponseStatus)
+	assert.Equal(t, "endpoint answered 303", attempts[0].Error)
+}
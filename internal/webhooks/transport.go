@@ -0,0 +1,85 @@
+package webhooks
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// blockedPrefixes are the ranges outside the loopback, private, link-local
+// and multicast ones that webhooks must not reach
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),  // Carrier-grade NAT, also Alibaba Cloud metadata
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // Reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64 of IPv4 addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"), // Local NAT64
+	netip.MustParsePrefix("2001:db8::/32"),  // Documentation
+}
+
+// PrivateAddress reports whether webhooks must not reach an address: a
+// loopback, private, link-local (including cloud metadata services at
+// 169.254.169.254), multicast or otherwise reserved one
+func PrivateAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// publicOnly is a net.Dialer Control refusing connections to private
+// addresses. It sees the address after name resolution, so hostnames that
+// resolve, or are later rebound, to internal addresses are refused too.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if PrivateAddress(addr) {
+		return fmt.Errorf("webhook endpoint address %s is not public", addr)
+	}
+	return nil
+}
+
+// newClient returns the HTTP client of deliveries. It does not follow
+// redirects, which could point it anywhere, so a redirect counts as a failed
+// delivery. With control set it only connects to the addresses it allows.
+func newClient(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}
+	return &http.Client{
+		Timeout: timeout,
+		// No proxy: it would make the connection the dialer checks
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
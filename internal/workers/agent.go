@@ -0,0 +1,168 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Operations of the Kubernetes client answered by cluster agents. Each agent
+// answers the subjects of its own cluster, agent.<cluster ID>.kube.<operation>,
+// so that the orchestrator reaches clusters behind firewalls over the
+// connection the agent opened.
+const (
+	AgentOpApply           = "apply"
+	AgentOpPatch           = "patch"
+	AgentOpDelete          = "delete"
+	AgentOpEvict           = "evict"
+	AgentOpGet             = "get"
+	AgentOpList            = "list"
+	AgentOpLogs            = "logs"
+	AgentOpTimestampedLogs = "timestamped_logs"
+	AgentOpExec            = "exec"
+	AgentOpWatch           = "watch"
+)
+
+// Event types of the changes a watch forwards to its inbox
+const (
+	EventTypeAgentWatchEvent = "agent.watch.event"
+	EventTypeAgentWatchEnd   = "agent.watch.end"
+)
+
+// AgentWatchLease bounds how long an agent keeps a watch open. Agents cannot
+// tell when the orchestrator stops listening, so watches end after the lease
+// and are re-established by the caller, as when the API server ends them.
+const AgentWatchLease = 10 * time.Minute
+
+// AgentSubject returns the subject an agent answers an operation on
+func AgentSubject(clusterID uuid.UUID, op string) string {
+	return fmt.Sprintf("agent.%s.kube.%s", clusterID, op)
+}
+
+// AgentSubjects returns the subjects of an agent's operations and watches,
+// the only ones its NATS credentials may use
+func AgentSubjects(clusterID uuid.UUID) []string {
+	return []string{fmt.Sprintf("agent.%s.>", clusterID)}
+}
+
+// AgentBus answers the orchestrator's requests and publishes watch events
+type AgentBus interface {
+	Responder
+	Publish(ctx context.Context, subject string, event *domain.Event) error
+}
+
+// Agent executes Kubernetes operations sent by the orchestrator against the
+// cluster it runs in
+type Agent struct {
+	bus       AgentBus
+	kube      domain.KubernetesClient
+	clusterID uuid.UUID
+	logger    *logger.Logger
+}
+
+// NewAgent creates a new Agent for the cluster registered as clusterID
+func NewAgent(bus AgentBus, kube domain.KubernetesClient, clusterID uuid.UUID, log *logger.Logger) *Agent {
+	return &Agent{
+		bus:       bus,
+		kube:      kube,
+		clusterID: clusterID,
+		logger:    log,
+	}
+}
+
+// Run answers operations until ctx is cancelled. Agent replicas of a cluster
+// share a queue group, so each operation runs once.
+func (a *Agent) Run(ctx context.Context) error {
+	queue := fmt.Sprintf("agent.%s", a.clusterID)
+	for op, handler := range a.handlers(ctx) {
+		if _, err := a.bus.Respond(ctx, AgentSubject(a.clusterID, op), queue, handler); err != nil {
+			return err
+		}
+	}
+
+	a.logger.Info().Str("cluster_id", a.clusterID.String()).Msg("Cluster agent started")
+	return nil
+}
+
+func (a *Agent) handlers(ctx context.Context) map[string]domain.RequestHandler {
+	kube := a.kube
+	return map[string]domain.RequestHandler{
+		AgentOpApply: handle(func(ctx context.Context, req kubeRequest) (struct{}, error) {
+			return struct{}{}, kube.ApplyManifest(ctx, a.clusterID, req.Manifest)
+		}),
+		AgentOpPatch: handle(func(ctx context.Context, req kubeRequest) (struct{}, error) {
+			return struct{}{}, kube.PatchResource(ctx, a.clusterID, req.Kind, req.Namespace, req.Name, req.Patch)
+		}),
+		AgentOpDelete: handle(func(ctx context.Context, req kubeRequest) (struct{}, error) {
+			return struct{}{}, kube.DeleteResource(ctx, a.clusterID, req.Kind, req.Namespace, req.Name)
+		}),
+		AgentOpEvict: handle(func(ctx context.Context, req kubeRequest) (struct{}, error) {
+			return struct{}{}, kube.EvictPod(ctx, a.clusterID, req.Namespace, req.Name)
+		}),
+		AgentOpGet: handle(func(ctx context.Context, req kubeRequest) (map[string]interface{}, error) {
+			return kube.GetResource(ctx, a.clusterID, req.Kind, req.Namespace, req.Name)
+		}),
+		AgentOpList: handle(func(ctx context.Context, req kubeRequest) ([]map[string]interface{}, error) {
+			return kube.ListResources(ctx, a.clusterID, req.Kind, req.Namespace, req.Labels)
+		}),
+		AgentOpLogs: handle(func(ctx context.Context, req kubeRequest) (string, error) {
+			return kube.GetPodLogs(ctx, a.clusterID, req.Namespace, req.Name, req.Container, req.TailLines)
+		}),
+		AgentOpTimestampedLogs: handle(func(ctx context.Context, req kubeRequest) (string, error) {
+			return kube.GetTimestampedPodLogs(ctx, a.clusterID, req.Namespace, req.Name, req.Container, req.Since, req.TailLines)
+		}),
+		AgentOpExec: handle(func(ctx context.Context, req kubeRequest) (string, error) {
+			return kube.ExecInPod(ctx, a.clusterID, req.Namespace, req.Name, req.Container, req.Command)
+		}),
+		// The watch outlives its request; it is bound to the agent's context
+		AgentOpWatch: handle(func(_ context.Context, req kubeRequest) (struct{}, error) {
+			go a.watch(ctx, req)
+			return struct{}{}, nil
+		}),
+	}
+}
+
+// watch forwards the changes of a watch to its inbox until the lease ends,
+// then tells the orchestrator that it ended
+func (a *Agent) watch(ctx context.Context, req kubeRequest) {
+	ctx, cancel := context.WithTimeout(ctx, AgentWatchLease)
+	defer cancel()
+
+	err := a.kube.WatchResource(ctx, a.clusterID, req.Kind, req.Namespace, func(eventType string, obj map[string]interface{}) {
+		if err := a.bus.Publish(ctx, req.Inbox, &domain.Event{
+			Type:   EventTypeAgentWatchEvent,
+			Source: "agent",
+			Data:   map[string]interface{}{"type": eventType, "object": obj},
+		}); err != nil {
+			a.logger.Warn().Err(err).Str("kind", req.Kind).Msg("Failed to forward watch event")
+		}
+	})
+
+	end := &domain.Event{Type: EventTypeAgentWatchEnd, Source: "agent", Data: map[string]interface{}{}}
+	if err != nil {
+		end.Data["error"] = err.Error()
+	}
+	// The agent's context may be done; the end is sent regardless
+	if err := a.bus.Publish(context.Background(), req.Inbox, end); err != nil {
+		a.logger.Warn().Err(err).Str("kind", req.Kind).Msg("Failed to end watch")
+	}
+}
+
+// kubeRequest holds the arguments of Kubernetes client calls
+type kubeRequest struct {
+	Manifest  []byte            `json:"manifest,omitempty"`
+	Patch     []byte            `json:"patch,omitempty"`
+	Kind      string            `json:"kind,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Container string            `json:"container,omitempty"`
+	TailLines int64             `json:"tail_lines,omitempty"`
+	Since     time.Time         `json:"since,omitempty"`
+	Command   []string          `json:"command,omitempty"`
+	Inbox     string            `json:"inbox,omitempty"`
+}
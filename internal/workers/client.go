@@ -0,0 +1,289 @@
+package workers
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// client sends adapter calls to the workers
+type client struct {
+	bus     domain.EventBus
+	timeout time.Duration
+}
+
+// call sends a request and decodes the result of its reply into resp,
+// unless resp is nil
+func (c *client) call(ctx context.Context, subject string, req, resp interface{}) error {
+	data, err := encode(req)
+	if err != nil {
+		return err
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	reply, err := c.bus.Request(ctx, subject, &domain.Event{
+		Type:   subject,
+		Source: "api",
+		Data:   data,
+	})
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return nil
+	}
+	return decode(reply.Data["result"], resp)
+}
+
+// ClusterManager implements domain.ClusterManagerAdapter by calling workers
+type ClusterManager struct {
+	client
+}
+
+// NewClusterManager creates a new ClusterManager
+func NewClusterManager(bus domain.EventBus, cfg *config.WorkersConfig) *ClusterManager {
+	return &ClusterManager{client{bus: bus, timeout: cfg.Timeout}}
+}
+
+func (m *ClusterManager) CreateCluster(ctx context.Context, cluster *domain.Cluster) (string, error) {
+	var externalID string
+	err := m.call(ctx, SubjectClusterCreate, clusterRequest{Cluster: cluster}, &externalID)
+	return externalID, err
+}
+
+func (m *ClusterManager) GetCluster(ctx context.Context, externalID string) (*domain.Cluster, error) {
+	var cluster *domain.Cluster
+	err := m.call(ctx, SubjectClusterGet, clusterRequest{ExternalID: externalID}, &cluster)
+	return cluster, err
+}
+
+func (m *ClusterManager) UpdateCluster(ctx context.Context, cluster *domain.Cluster) error {
+	return m.call(ctx, SubjectClusterUpdate, clusterRequest{Cluster: cluster}, nil)
+}
+
+func (m *ClusterManager) DeleteCluster(ctx context.Context, externalID string) error {
+	return m.call(ctx, SubjectClusterDelete, clusterRequest{ExternalID: externalID}, nil)
+}
+
+func (m *ClusterManager) GetKubeConfig(ctx context.Context, externalID string) ([]byte, error) {
+	var kubeconfig []byte
+	err := m.call(ctx, SubjectClusterKubeConfig, clusterRequest{ExternalID: externalID}, &kubeconfig)
+	return kubeconfig, err
+}
+
+func (m *ClusterManager) ListClusters(ctx context.Context) ([]*domain.Cluster, error) {
+	var clusters []*domain.Cluster
+	err := m.call(ctx, SubjectClusterList, clusterRequest{}, &clusters)
+	return clusters, err
+}
+
+func (m *ClusterManager) GetClusterHealth(ctx context.Context, externalID string) (*domain.ClusterHealth, error) {
+	var health *domain.ClusterHealth
+	err := m.call(ctx, SubjectClusterHealth, clusterRequest{ExternalID: externalID}, &health)
+	return health, err
+}
+
+func (m *ClusterManager) CreateNamespace(ctx context.Context, externalID, namespace string, labels map[string]string) error {
+	return m.call(ctx, SubjectNamespaceCreate, clusterRequest{ExternalID: externalID, Namespace: namespace, Labels: labels}, nil)
+}
+
+func (m *ClusterManager) DeleteNamespace(ctx context.Context, externalID, namespace string) error {
+	return m.call(ctx, SubjectNamespaceDelete, clusterRequest{ExternalID: externalID, Namespace: namespace}, nil)
+}
+
+func (m *ClusterManager) ListNodePools(ctx context.Context, externalID string) ([]*domain.NodePool, error) {
+	var pools []*domain.NodePool
+	err := m.call(ctx, SubjectNodePoolList, clusterRequest{ExternalID: externalID}, &pools)
+	return pools, err
+}
+
+func (m *ClusterManager) CreateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	return m.call(ctx, SubjectNodePoolCreate, clusterRequest{ExternalID: externalID, NodePool: pool}, nil)
+}
+
+func (m *ClusterManager) UpdateNodePool(ctx context.Context, externalID string, pool *domain.NodePool) error {
+	return m.call(ctx, SubjectNodePoolUpdate, clusterRequest{ExternalID: externalID, NodePool: pool}, nil)
+}
+
+func (m *ClusterManager) DeleteNodePool(ctx context.Context, externalID, name string) error {
+	return m.call(ctx, SubjectNodePoolDelete, clusterRequest{ExternalID: externalID, PoolName: name}, nil)
+}
+
+func (m *ClusterManager) ListKubernetesVersions(ctx context.Context, externalID string) ([]string, error) {
+	var versions []string
+	err := m.call(ctx, SubjectClusterVersions, clusterRequest{ExternalID: externalID}, &versions)
+	return versions, err
+}
+
+func (m *ClusterManager) GetClusterCapacity(ctx context.Context, externalID string) (*domain.ClusterCapacity, error) {
+	var capacity *domain.ClusterCapacity
+	err := m.call(ctx, SubjectClusterCapacity, clusterRequest{ExternalID: externalID}, &capacity)
+	return capacity, err
+}
+
+func (m *ClusterManager) IssueKubeConfig(ctx context.Context, externalID string, grant *domain.KubeConfigGrant) (*domain.IssuedKubeConfig, error) {
+	var issued *domain.IssuedKubeConfig
+	err := m.call(ctx, SubjectKubeConfigIssue, clusterRequest{ExternalID: externalID, Grant: grant}, &issued)
+	return issued, err
+}
+
+func (m *ClusterManager) RevokeKubeConfig(ctx context.Context, externalID string, kubeconfig []byte) error {
+	return m.call(ctx, SubjectKubeConfigRevoke, clusterRequest{ExternalID: externalID, KubeConfig: kubeconfig}, nil)
+}
+
+// CIAdapter implements domain.CIAdapter by calling workers
+type CIAdapter struct {
+	client
+}
+
+// NewCIAdapter creates a new CIAdapter
+func NewCIAdapter(bus domain.EventBus, cfg *config.WorkersConfig) *CIAdapter {
+	return &CIAdapter{client{bus: bus, timeout: cfg.Timeout}}
+}
+
+func (a *CIAdapter) TriggerBuild(ctx context.Context, service *domain.Service, source domain.BuildSource) (*domain.Build, error) {
+	var build *domain.Build
+	err := a.call(ctx, SubjectBuildTrigger, ciRequest{Service: service, Source: source}, &build)
+	return build, err
+}
+
+func (a *CIAdapter) GetBuildStatus(ctx context.Context, buildID string) (*domain.Build, error) {
+	var build *domain.Build
+	err := a.call(ctx, SubjectBuildStatus, ciRequest{BuildID: buildID}, &build)
+	return build, err
+}
+
+func (a *CIAdapter) CancelBuild(ctx context.Context, buildID string) error {
+	return a.call(ctx, SubjectBuildCancel, ciRequest{BuildID: buildID}, nil)
+}
+
+func (a *CIAdapter) GetBuildLogs(ctx context.Context, buildID string) (string, error) {
+	var logs string
+	err := a.call(ctx, SubjectBuildLogs, ciRequest{BuildID: buildID}, &logs)
+	return logs, err
+}
+
+func (a *CIAdapter) CreateProject(ctx context.Context, project *domain.Project) (string, error) {
+	var externalID string
+	err := a.call(ctx, SubjectCIProjectCreate, ciRequest{Project: project}, &externalID)
+	return externalID, err
+}
+
+func (a *CIAdapter) DeleteProject(ctx context.Context, externalID string) error {
+	return a.call(ctx, SubjectCIProjectDelete, ciRequest{ExternalID: externalID}, nil)
+}
+
+// KubernetesClient implements domain.KubernetesClient by calling the agent of
+// each cluster
+type KubernetesClient struct {
+	client
+}
+
+// NewKubernetesClient creates a new KubernetesClient
+func NewKubernetesClient(bus domain.EventBus, cfg *config.AgentsConfig) *KubernetesClient {
+	return &KubernetesClient{client{bus: bus, timeout: cfg.Timeout}}
+}
+
+func (k *KubernetesClient) ApplyManifest(ctx context.Context, clusterID uuid.UUID, manifest []byte) error {
+	return k.call(ctx, AgentSubject(clusterID, AgentOpApply), kubeRequest{Manifest: manifest}, nil)
+}
+
+func (k *KubernetesClient) PatchResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string, patch []byte) error {
+	return k.call(ctx, AgentSubject(clusterID, AgentOpPatch), kubeRequest{Kind: kind, Namespace: namespace, Name: name, Patch: patch}, nil)
+}
+
+func (k *KubernetesClient) DeleteResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) error {
+	return k.call(ctx, AgentSubject(clusterID, AgentOpDelete), kubeRequest{Kind: kind, Namespace: namespace, Name: name}, nil)
+}
+
+func (k *KubernetesClient) EvictPod(ctx context.Context, clusterID uuid.UUID, namespace, name string) error {
+	return k.call(ctx, AgentSubject(clusterID, AgentOpEvict), kubeRequest{Namespace: namespace, Name: name}, nil)
+}
+
+func (k *KubernetesClient) GetResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	err := k.call(ctx, AgentSubject(clusterID, AgentOpGet), kubeRequest{Kind: kind, Namespace: namespace, Name: name}, &obj)
+	return obj, err
+}
+
+func (k *KubernetesClient) ListResources(ctx context.Context, clusterID uuid.UUID, kind, namespace string, labels map[string]string) ([]map[string]interface{}, error) {
+	var items []map[string]interface{}
+	err := k.call(ctx, AgentSubject(clusterID, AgentOpList), kubeRequest{Kind: kind, Namespace: namespace, Labels: labels}, &items)
+	return items, err
+}
+
+func (k *KubernetesClient) GetPodLogs(ctx context.Context, clusterID uuid.UUID, namespace, podName, container string, tailLines int64) (string, error) {
+	var logs string
+	err := k.call(ctx, AgentSubject(clusterID, AgentOpLogs), kubeRequest{Namespace: namespace, Name: podName, Container: container, TailLines: tailLines}, &logs)
+	return logs, err
+}
+
+func (k *KubernetesClient) GetTimestampedPodLogs(ctx context.Context, clusterID uuid.UUID, namespace, podName, container string, since time.Time, tailLines int64) (string, error) {
+	var logs string
+	err := k.call(ctx, AgentSubject(clusterID, AgentOpTimestampedLogs), kubeRequest{Namespace: namespace, Name: podName, Container: container, Since: since, TailLines: tailLines}, &logs)
+	return logs, err
+}
+
+func (k *KubernetesClient) ExecInPod(ctx context.Context, clusterID uuid.UUID, namespace, podName, container string, command []string) (string, error) {
+	var output string
+	err := k.call(ctx, AgentSubject(clusterID, AgentOpExec), kubeRequest{Namespace: namespace, Name: podName, Container: container, Command: command}, &output)
+	return output, err
+}
+
+// WatchResource subscribes to an inbox, asks the agent to forward the changes
+// of a watch to it, and calls handler with them until ctx is cancelled or the
+// agent ends the watch
+func (k *KubernetesClient) WatchResource(ctx context.Context, clusterID uuid.UUID, kind, namespace string, handler func(eventType string, obj map[string]interface{})) error {
+	inbox := fmt.Sprintf("agent.%s.watch.%s", clusterID, uuid.New())
+	events := make(chan *domain.Event, 64)
+	sub, err := k.bus.Subscribe(ctx, inbox, func(event *domain.Event) error {
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	if err := k.call(ctx, AgentSubject(clusterID, AgentOpWatch), kubeRequest{Kind: kind, Namespace: namespace, Inbox: inbox}, nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-events:
+			if event.Type == EventTypeAgentWatchEnd {
+				if message, ok := event.Data["error"].(string); ok {
+					return errors.DependencyFailed("agent", stderrors.New(message))
+				}
+				return nil
+			}
+			eventType, _ := event.Data["type"].(string)
+			obj, _ := event.Data["object"].(map[string]interface{})
+			handler(eventType, obj)
+		}
+	}
+}
+
+// PortForward is not supported through agents; a stream does not fit a
+// request and its reply
+func (k *KubernetesClient) PortForward(ctx context.Context, clusterID uuid.UUID, namespace, podName string, port int32) (io.ReadWriteCloser, error) {
+	return nil, errors.NotImplemented("port forwarding is not supported through the cluster agent")
+}
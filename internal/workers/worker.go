@@ -0,0 +1,238 @@
+// Package workers runs heavyweight adapter operations, cluster provisioning
+// and builds, in separate worker processes. API replicas send each call as a
+// request on the event bus; one of the workers sharing the queue group runs
+// it against the real adapter and replies, with the request's ID as the
+// correlation ID. This lets the API scale independently of slow adapters.
+package workers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+)
+
+// Subjects of adapter calls
+const (
+	SubjectClusterCreate     = "rpc.cluster.create"
+	SubjectClusterGet        = "rpc.cluster.get"
+	SubjectClusterUpdate     = "rpc.cluster.update"
+	SubjectClusterDelete     = "rpc.cluster.delete"
+	SubjectClusterKubeConfig = "rpc.cluster.kubeconfig"
+	SubjectClusterList       = "rpc.cluster.list"
+	SubjectClusterHealth     = "rpc.cluster.health"
+	SubjectClusterVersions   = "rpc.cluster.versions"
+	SubjectClusterCapacity   = "rpc.cluster.capacity"
+	SubjectKubeConfigIssue   = "rpc.cluster.kubeconfig.issue"
+	SubjectKubeConfigRevoke  = "rpc.cluster.kubeconfig.revoke"
+	SubjectNamespaceCreate   = "rpc.cluster.namespace.create"
+	SubjectNamespaceDelete   = "rpc.cluster.namespace.delete"
+	SubjectNodePoolList      = "rpc.cluster.nodepool.list"
+	SubjectNodePoolCreate    = "rpc.cluster.nodepool.create"
+	SubjectNodePoolUpdate    = "rpc.cluster.nodepool.update"
+	SubjectNodePoolDelete    = "rpc.cluster.nodepool.delete"
+
+	SubjectBuildTrigger    = "rpc.build.trigger"
+	SubjectBuildStatus     = "rpc.build.status"
+	SubjectBuildCancel     = "rpc.build.cancel"
+	SubjectBuildLogs       = "rpc.build.logs"
+	SubjectCIProjectCreate = "rpc.build.project.create"
+	SubjectCIProjectDelete = "rpc.build.project.delete"
+)
+
+// Responder answers requests sent on the event bus
+type Responder interface {
+	Respond(ctx context.Context, subject, queue string, handler domain.RequestHandler) (domain.Subscription, error)
+}
+
+// Worker executes the adapter calls sent by API replicas
+type Worker struct {
+	bus      Responder
+	ci       domain.CIAdapter
+	clusters domain.ClusterManagerAdapter
+	cfg      *config.WorkersConfig
+	logger   *logger.Logger
+}
+
+// NewWorker creates a new Worker. ci or clusters may be nil, in which case
+// this worker does not answer their calls.
+func NewWorker(bus Responder, ci domain.CIAdapter, clusters domain.ClusterManagerAdapter, cfg *config.WorkersConfig, log *logger.Logger) *Worker {
+	return &Worker{
+		bus:      bus,
+		ci:       ci,
+		clusters: clusters,
+		cfg:      cfg,
+		logger:   log,
+	}
+}
+
+// Run answers calls until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) error {
+	handlers := make(map[string]domain.RequestHandler)
+	if w.clusters != nil {
+		for subject, handler := range clusterHandlers(w.clusters) {
+			handlers[subject] = handler
+		}
+	}
+	if w.ci != nil {
+		for subject, handler := range ciHandlers(w.ci) {
+			handlers[subject] = handler
+		}
+	}
+
+	for subject, handler := range handlers {
+		if _, err := w.bus.Respond(ctx, subject, w.cfg.Queue, handler); err != nil {
+			return err
+		}
+	}
+
+	w.logger.Info().Int("operations", len(handlers)).Str("queue", w.cfg.Queue).Msg("Adapter worker started")
+	return nil
+}
+
+func clusterHandlers(clusters domain.ClusterManagerAdapter) map[string]domain.RequestHandler {
+	return map[string]domain.RequestHandler{
+		SubjectClusterCreate: handle(func(ctx context.Context, req clusterRequest) (string, error) {
+			return clusters.CreateCluster(ctx, req.Cluster)
+		}),
+		SubjectClusterGet: handle(func(ctx context.Context, req clusterRequest) (*domain.Cluster, error) {
+			return clusters.GetCluster(ctx, req.ExternalID)
+		}),
+		SubjectClusterUpdate: handle(func(ctx context.Context, req clusterRequest) (struct{}, error) {
+			return struct{}{}, clusters.UpdateCluster(ctx, req.Cluster)
+		}),
+		SubjectClusterDelete: handle(func(ctx context.Context, req clusterRequest) (struct{}, error) {
+			return struct{}{}, clusters.DeleteCluster(ctx, req.ExternalID)
+		}),
+		SubjectClusterKubeConfig: handle(func(ctx context.Context, req clusterRequest) ([]byte, error) {
+			return clusters.GetKubeConfig(ctx, req.ExternalID)
+		}),
+		SubjectClusterList: handle(func(ctx context.Context, req clusterRequest) ([]*domain.Cluster, error) {
+			return clusters.ListClusters(ctx)
+		}),
+		SubjectClusterHealth: handle(func(ctx context.Context, req clusterRequest) (*domain.ClusterHealth, error) {
+			return clusters.GetClusterHealth(ctx, req.ExternalID)
+		}),
+		SubjectClusterVersions: handle(func(ctx context.Context, req clusterRequest) ([]string, error) {
+			return clusters.ListKubernetesVersions(ctx, req.ExternalID)
+		}),
+		SubjectClusterCapacity: handle(func(ctx context.Context, req clusterRequest) (*domain.ClusterCapacity, error) {
+			return clusters.GetClusterCapacity(ctx, req.ExternalID)
+		}),
+		SubjectKubeConfigIssue: handle(func(ctx context.Context, req clusterRequest) (*domain.IssuedKubeConfig, error) {
+			return clusters.IssueKubeConfig(ctx, req.ExternalID, req.Grant)
+		}),
+		SubjectKubeConfigRevoke: handle(func(ctx context.Context, req clusterRequest) (struct{}, error) {
+			return struct{}{}, clusters.RevokeKubeConfig(ctx, req.ExternalID, req.KubeConfig)
+		}),
+		SubjectNamespaceCreate: handle(func(ctx context.Context, req clusterRequest) (struct{}, error) {
+			return struct{}{}, clusters.CreateNamespace(ctx, req.ExternalID, req.Namespace, req.Labels)
+		}),
+		SubjectNamespaceDelete: handle(func(ctx context.Context, req clusterRequest) (struct{}, error) {
+			return struct{}{}, clusters.DeleteNamespace(ctx, req.ExternalID, req.Namespace)
+		}),
+		SubjectNodePoolList: handle(func(ctx context.Context, req clusterRequest) ([]*domain.NodePool, error) {
+			return clusters.ListNodePools(ctx, req.ExternalID)
+		}),
+		SubjectNodePoolCreate: handle(func(ctx context.Context, req clusterRequest) (struct{}, error) {
+			return struct{}{}, clusters.CreateNodePool(ctx, req.ExternalID, req.NodePool)
+		}),
+		SubjectNodePoolUpdate: handle(func(ctx context.Context, req clusterRequest) (struct{}, error) {
+			return struct{}{}, clusters.UpdateNodePool(ctx, req.ExternalID, req.NodePool)
+		}),
+		SubjectNodePoolDelete: handle(func(ctx context.Context, req clusterRequest) (struct{}, error) {
+			return struct{}{}, clusters.DeleteNodePool(ctx, req.ExternalID, req.PoolName)
+		}),
+	}
+}
+
+func ciHandlers(ci domain.CIAdapter) map[string]domain.RequestHandler {
+	return map[string]domain.RequestHandler{
+		SubjectBuildTrigger: handle(func(ctx context.Context, req ciRequest) (*domain.Build, error) {
+			return ci.TriggerBuild(ctx, req.Service, req.Source)
+		}),
+		SubjectBuildStatus: handle(func(ctx context.Context, req ciRequest) (*domain.Build, error) {
+			return ci.GetBuildStatus(ctx, req.BuildID)
+		}),
+		SubjectBuildCancel: handle(func(ctx context.Context, req ciRequest) (struct{}, error) {
+			return struct{}{}, ci.CancelBuild(ctx, req.BuildID)
+		}),
+		SubjectBuildLogs: handle(func(ctx context.Context, req ciRequest) (string, error) {
+			return ci.GetBuildLogs(ctx, req.BuildID)
+		}),
+		SubjectCIProjectCreate: handle(func(ctx context.Context, req ciRequest) (string, error) {
+			return ci.CreateProject(ctx, req.Project)
+		}),
+		SubjectCIProjectDelete: handle(func(ctx context.Context, req ciRequest) (struct{}, error) {
+			return struct{}{}, ci.DeleteProject(ctx, req.ExternalID)
+		}),
+	}
+}
+
+// clusterRequest holds the arguments of cluster manager calls
+type clusterRequest struct {
+	Cluster    *domain.Cluster         `json:"cluster,omitempty"`
+	ExternalID string                  `json:"external_id,omitempty"`
+	Namespace  string                  `json:"namespace,omitempty"`
+	Labels     map[string]string       `json:"labels,omitempty"`
+	NodePool   *domain.NodePool        `json:"node_pool,omitempty"`
+	PoolName   string                  `json:"pool_name,omitempty"`
+	Grant      *domain.KubeConfigGrant `json:"grant,omitempty"`
+	KubeConfig []byte                  `json:"kubeconfig,omitempty"`
+}
+
+// ciRequest holds the arguments of CI calls
+type ciRequest struct {
+	Service    *domain.Service    `json:"service,omitempty"`
+	Source     domain.BuildSource `json:"source"`
+	BuildID    string             `json:"build_id,omitempty"`
+	Project    *domain.Project    `json:"project,omitempty"`
+	ExternalID string             `json:"external_id,omitempty"`
+}
+
+// handle adapts a typed call to a request handler. The reply's data holds
+// the call's result under "result".
+func handle[Req, Resp any](call func(ctx context.Context, req Req) (Resp, error)) domain.RequestHandler {
+	return func(ctx context.Context, request *domain.Event) (*domain.Event, error) {
+		var req Req
+		if err := decode(request.Data, &req); err != nil {
+			return nil, errors.BadRequest("invalid adapter call: " + err.Error())
+		}
+
+		resp, err := call(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := encode(map[string]interface{}{"result": resp})
+		if err != nil {
+			return nil, err
+		}
+		return &domain.Event{Data: data}, nil
+	}
+}
+
+// encode converts a value to event data
+func encode(v interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// decode converts event data, or a part of it, back to a value
+func decode(data interface{}, v interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,157 @@
+package workers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loopbackBus answers requests with the handlers registered on it
+type loopbackBus struct {
+	domain.EventBus
+	handlers    map[string]domain.RequestHandler
+	subscribers map[string]domain.EventHandler
+}
+
+func (b *loopbackBus) Respond(ctx context.Context, subject, queue string, handler domain.RequestHandler) (domain.Subscription, error) {
+	b.handlers[subject] = handler
+	return nil, nil
+}
+
+func (b *loopbackBus) Request(ctx context.Context, subject string, event *domain.Event) (*domain.Event, error) {
+	handler, ok := b.handlers[subject]
+	if !ok {
+		return nil, errors.DependencyFailed("worker", nil)
+	}
+	return handler(ctx, event)
+}
+
+// fakeClusters provisions clusters in memory
+type fakeClusters struct {
+	domain.ClusterManagerAdapter
+	created []*domain.Cluster
+}
+
+func (f *fakeClusters) CreateCluster(ctx context.Context, cluster *domain.Cluster) (string, error) {
+	f.created = append(f.created, cluster)
+	return "c-" + cluster.Slug, nil
+}
+
+func (f *fakeClusters) GetKubeConfig(ctx context.Context, externalID string) ([]byte, error) {
+	if externalID != "c-prod" {
+		return nil, errors.NotFound("cluster", externalID)
+	}
+	return []byte("apiVersion: v1\nkind: Config\n"), nil
+}
+
+func TestClusterManagerCallsWorker(t *testing.T) {
+	bus := &loopbackBus{handlers: make(map[string]domain.RequestHandler)}
+	clusters := &fakeClusters{}
+	cfg := &config.WorkersConfig{Queue: "adapter-workers"}
+	require.NoError(t, NewWorker(bus, nil, clusters, cfg, logger.New("error", "json", io.Discard)).Run(context.Background()))
+
+	manager := NewClusterManager(bus, cfg)
+	cluster := &domain.Cluster{ID: uuid.New(), Slug: "prod", NodeCount: 3, Labels: map[string]string{"tier": "gold"}}
+
+	externalID, err := manager.CreateCluster(context.Background(), cluster)
+	require.NoError(t, err)
+	assert.Equal(t, "c-prod", externalID)
+	require.Len(t, clusters.created, 1)
+	assert.Equal(t, cluster.ID, clusters.created[0].ID)
+	assert.Equal(t, cluster.Labels, clusters.created[0].Labels)
+
+	kubeconfig, err := manager.GetKubeConfig(context.Background(), "c-prod")
+	require.NoError(t, err)
+	assert.Equal(t, "apiVersion: v1\nkind: Config\n", string(kubeconfig))
+
+	_, err = manager.GetKubeConfig(context.Background(), "c-missing")
+	assert.True(t, errors.IsNotFound(err))
+
+	_, err = NewCIAdapter(bus, cfg).GetBuildLogs(context.Background(), "b-1")
+	assert.Error(t, err, "no worker answers CI calls")
+}
+
+func (b *loopbackBus) Subscribe(ctx context.Context, subject string, handler domain.EventHandler) (domain.Subscription, error) {
+	b.subscribers[subject] = handler
+	return loopbackSubscription{}, nil
+}
+
+func (b *loopbackBus) Publish(ctx context.Context, subject string, event *domain.Event) error {
+	if handler, ok := b.subscribers[subject]; ok {
+		return handler(event)
+	}
+	return nil
+}
+
+type loopbackSubscription struct{}
+
+func (loopbackSubscription) Unsubscribe() error { return nil }
+
+// fakeKube holds one deployment and reports one change to watches
+type fakeKube struct {
+	domain.KubernetesClient
+}
+
+func (fakeKube) GetResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string) (map[string]interface{}, error) {
+	if kind != "Deployment" || name != "web" {
+		return nil, errors.NotFound(kind, name)
+	}
+	return map[string]interface{}{"kind": kind, "metadata": map[string]interface{}{"name": name, "namespace": namespace}}, nil
+}
+
+func (fakeKube) PatchResource(ctx context.Context, clusterID uuid.UUID, kind, namespace, name string, patch []byte) error {
+	if string(patch) != `{"spec":{"unschedulable":true}}` {
+		return errors.BadRequest("unexpected patch " + string(patch))
+	}
+	return nil
+}
+
+func (fakeKube) EvictPod(ctx context.Context, clusterID uuid.UUID, namespace, name string) error {
+	if name == "web-2" {
+		return errors.NewError(errors.CodeRateLimited, "eviction is blocked by a disruption budget", http.StatusTooManyRequests)
+	}
+	return nil
+}
+
+func (fakeKube) WatchResource(ctx context.Context, clusterID uuid.UUID, kind, namespace string, handler func(eventType string, obj map[string]interface{})) error {
+	handler("ADDED", map[string]interface{}{"kind": kind})
+	return nil
+}
+
+func TestKubernetesClientCallsAgent(t *testing.T) {
+	bus := &loopbackBus{handlers: make(map[string]domain.RequestHandler), subscribers: make(map[string]domain.EventHandler)}
+	clusterID := uuid.New()
+	require.NoError(t, NewAgent(bus, fakeKube{}, clusterID, logger.New("error", "json", io.Discard)).Run(context.Background()))
+
+	kube := NewKubernetesClient(bus, &config.AgentsConfig{})
+	obj, err := kube.GetResource(context.Background(), clusterID, "Deployment", "shop", "web")
+	require.NoError(t, err)
+	assert.Equal(t, "shop", obj["metadata"].(map[string]interface{})["namespace"])
+
+	_, err = kube.GetResource(context.Background(), clusterID, "Deployment", "shop", "api")
+	assert.True(t, errors.IsNotFound(err))
+
+	_, err = kube.GetResource(context.Background(), uuid.New(), "Deployment", "shop", "web")
+	assert.Error(t, err, "no agent answers for other clusters")
+
+	assert.NoError(t, kube.PatchResource(context.Background(), clusterID, "Node", "", "node-1", []byte(`{"spec":{"unschedulable":true}}`)))
+	assert.NoError(t, kube.EvictPod(context.Background(), clusterID, "shop", "web-1"))
+	var appErr *errors.AppError
+	require.ErrorAs(t, kube.EvictPod(context.Background(), clusterID, "shop", "web-2"), &appErr)
+	assert.Equal(t, errors.CodeRateLimited, appErr.Code)
+
+	var events []string
+	require.NoError(t, kube.WatchResource(context.Background(), clusterID, "Pod", "", func(eventType string, obj map[string]interface{}) {
+		events = append(events, eventType+" "+obj["kind"].(string))
+	}))
+	assert.Equal(t, []string{"ADDED Pod"}, events)
+}
@@ -0,0 +1,158 @@
+package workflow
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// previousVersionLookback bounds the history searched for the last successful release
+const previousVersionLookback = 20
+
+// recordDeployment persists the deployment of a workflow as it moves through
+// the deploy states. Writes are serialized and always reflect the workflow's
+// current state, so a side effect that runs late cannot roll the record back.
+func (sm *StateMachine) recordDeployment(ctx context.Context, workflow *DeploymentWorkflow) {
+	if sm.deployRepo == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	sm.recordMu.Lock()
+	defer sm.recordMu.Unlock()
+
+	sm.mu.Lock()
+	status, ok := deploymentStatus(workflow.State)
+	if ok && workflow.DeploymentID == nil {
+		id := uuid.New()
+		workflow.DeploymentID = &id
+	}
+	snapshot := *workflow
+	sm.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	deployment, err := sm.deployRepo.GetByID(ctx, *snapshot.DeploymentID)
+	created := errors.IsNotFound(err)
+	if created {
+		deployment = sm.newDeployment(ctx, &snapshot)
+	} else if err != nil {
+		sm.logger.Error().Err(err).Str("workflow_id", snapshot.ID.String()).Msg("Failed to load deployment record")
+		return
+	}
+
+	applyState(deployment, &snapshot, status, time.Now())
+
+	if created {
+		err = sm.deployRepo.Create(ctx, deployment)
+	} else {
+		err = sm.deployRepo.Update(ctx, deployment)
+	}
+	if err != nil {
+		sm.logger.Error().Err(err).Str("deployment_id", deployment.ID.String()).Msg("Failed to record deployment")
+	}
+}
+
+// newDeployment creates the record of the workflow's deploy
+func (sm *StateMachine) newDeployment(ctx context.Context, workflow *DeploymentWorkflow) *domain.Deployment {
+	deployment := &domain.Deployment{
+		ID:          *workflow.DeploymentID,
+		ServiceID:   workflow.ServiceID,
+		ProjectID:   workflow.ProjectID,
+		ClusterID:   workflow.ClusterID,
+		Strategy:    domain.DeploymentStrategyRollingUpdate,
+		TriggeredBy: "workflow-engine",
+		Metadata: map[string]interface{}{
+			"workflow_id": workflow.ID.String(),
+		},
+		CreatedAt: time.Now(),
+	}
+	if workflow.BuildID != nil {
+		deployment.BuildID = *workflow.BuildID
+	}
+	if workflow.Image != "" {
+		deployment.Metadata["image"] = workflow.Image
+	}
+	if strategy, ok := workflow.Metadata["strategy"].(string); ok && strategy != "" {
+		deployment.Strategy = domain.DeploymentStrategy(strategy)
+	}
+	if triggeredBy, ok := workflow.Metadata["triggered_by"].(string); ok && triggeredBy != "" {
+		deployment.TriggeredBy = triggeredBy
+	}
+
+	if service, err := sm.serviceRepo.GetByID(ctx, workflow.ServiceID); err == nil {
+		deployment.Replicas = service.Scaling.MinReplicas
+	}
+
+	deployment.PreviousVersion = workflow.PrevVersion
+	if history, err := sm.deployRepo.ListByService(ctx, workflow.ServiceID, previousVersionLookback); err == nil {
+		for _, d := range history {
+			if d.Status == domain.DeploymentStatusSucceeded {
+				deployment.PreviousVersion = d.Version
+				break
+			}
+		}
+	}
+
+	return deployment
+}
+
+// deploymentStatus maps a workflow state to the status of its deployment.
+// States outside the deploy lifecycle, and rolling back, leave it unchanged.
+func deploymentStatus(state DeploymentState) (domain.DeploymentStatus, bool) {
+	switch state {
+	case StateDeployQueued, StatePrePulling:
+		return domain.DeploymentStatusPending, true
+	case StateDeploying:
+		return domain.DeploymentStatusInProgress, true
+	case StateDeployComplete:
+		return domain.DeploymentStatusSucceeded, true
+	case StateDeployFailed:
+		return domain.DeploymentStatusFailed, true
+	case StateRollbackComplete:
+		return domain.DeploymentStatusRolledBack, true
+	}
+	return "", false
+}
+
+// applyState brings a deployment record in line with its workflow
+func applyState(deployment *domain.Deployment, workflow *DeploymentWorkflow, status domain.DeploymentStatus, now time.Time) {
+	deployment.Status = status
+
+	switch {
+	case workflow.Version != "":
+		deployment.Version = workflow.Version
+	case deployment.Version == "":
+		deployment.Version = workflow.Image
+	}
+	if workflow.PrePull != nil {
+		prePull := *workflow.PrePull
+		deployment.PrePull = &prePull
+	}
+
+	switch status {
+	case domain.DeploymentStatusInProgress:
+		if deployment.StartedAt == nil {
+			deployment.StartedAt = &now
+		}
+	case domain.DeploymentStatusSucceeded:
+		deployment.ReadyReplicas = deployment.Replicas
+	case domain.DeploymentStatusFailed:
+		deployment.ErrorMessage = workflow.Error
+	}
+
+	switch status {
+	case domain.DeploymentStatusSucceeded, domain.DeploymentStatusFailed, domain.DeploymentStatusRolledBack:
+		if deployment.StartedAt == nil {
+			deployment.StartedAt = &now
+		}
+		if deployment.CompletedAt == nil {
+			deployment.CompletedAt = &now
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package workflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyState(t *testing.T) {
+	now := time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+	deployment := &domain.Deployment{Replicas: 3}
+	wf := &DeploymentWorkflow{Image: "registry.example.com/api:v2"}
+
+	status, _ := deploymentStatus(StateDeployQueued)
+	applyState(deployment, wf, status, now)
+	assert.Equal(t, domain.DeploymentStatusPending, deployment.Status)
+	assert.Equal(t, "registry.example.com/api:v2", deployment.Version)
+	assert.Nil(t, deployment.StartedAt)
+
+	status, _ = deploymentStatus(StateDeploying)
+	applyState(deployment, wf, status, now)
+	assert.Equal(t, now, *deployment.StartedAt)
+
+	wf.Version = "v2"
+	status, _ = deploymentStatus(StateDeployComplete)
+	applyState(deployment, wf, status, now.Add(time.Minute))
+	assert.Equal(t, domain.DeploymentStatusSucceeded, deployment.Status)
+	assert.Equal(t, "v2", deployment.Version)
+	assert.Equal(t, int32(3), deployment.ReadyReplicas)
+	assert.Equal(t, now, *deployment.StartedAt)
+	assert.Equal(t, now.Add(time.Minute), *deployment.CompletedAt)
+
+	// Rolling back does not touch the record until the rollback completes
+	_, ok := deploymentStatus(StateRollingBack)
+	assert.False(t, ok)
+}
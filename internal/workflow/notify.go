@@ -0,0 +1,76 @@
+package workflow
+
+import (
+	"context"
+	"time"
+
+	"github.com/northstack/platform/internal/domain"
+)
+
+// UseNotifier makes workflows send the outcome of their builds and deploys
+// to notifier, such as Slack
+func (sm *StateMachine) UseNotifier(notifier domain.Notifier) {
+	sm.notifier = notifier
+}
+
+// notifyBuild sends the outcome of a workflow's build. The build record may
+// not reflect it yet, so its status and error are taken from the workflow.
+func (sm *StateMachine) notifyBuild(ctx context.Context, workflow *DeploymentWorkflow, status domain.BuildStatus) {
+	if sm.notifier == nil {
+		return
+	}
+	snapshot := sm.snapshot(workflow)
+
+	var build *domain.Build
+	if sm.buildRepo != nil && snapshot.BuildID != nil {
+		build, _ = sm.buildRepo.GetByID(ctx, *snapshot.BuildID)
+	}
+	if build == nil {
+		build = &domain.Build{ServiceID: snapshot.ServiceID, ProjectID: snapshot.ProjectID, ImageTag: snapshot.Version}
+	}
+	build.Status = status
+	if snapshot.Error != "" {
+		build.ErrorMessage = snapshot.Error
+	}
+
+	if err := sm.notifier.SendBuildNotification(ctx, build); err != nil {
+		sm.logger.Warn().Err(err).Str("workflow_id", snapshot.ID.String()).Msg("Failed to send build notification")
+	}
+}
+
+// notifyDeployment sends the outcome of a workflow's deploy, as recorded
+func (sm *StateMachine) notifyDeployment(ctx context.Context, workflow *DeploymentWorkflow) {
+	if sm.notifier == nil {
+		return
+	}
+	snapshot := sm.snapshot(workflow)
+	status, ok := deploymentStatus(snapshot.State)
+	if !ok {
+		return
+	}
+
+	var deployment *domain.Deployment
+	if sm.deployRepo != nil && snapshot.DeploymentID != nil {
+		deployment, _ = sm.deployRepo.GetByID(ctx, *snapshot.DeploymentID)
+	}
+	if deployment == nil {
+		deployment = &domain.Deployment{
+			ServiceID:       snapshot.ServiceID,
+			ProjectID:       snapshot.ProjectID,
+			ClusterID:       snapshot.ClusterID,
+			PreviousVersion: snapshot.PrevVersion,
+			TriggeredBy:     "workflow-engine",
+		}
+		applyState(deployment, &snapshot, status, time.Now())
+	}
+
+	if err := sm.notifier.SendDeploymentNotification(ctx, deployment); err != nil {
+		sm.logger.Warn().Err(err).Str("workflow_id", snapshot.ID.String()).Msg("Failed to send deployment notification")
+	}
+}
+
+func (sm *StateMachine) snapshot(workflow *DeploymentWorkflow) DeploymentWorkflow {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return *workflow
+}
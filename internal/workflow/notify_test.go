@@ -0,0 +1,73 @@
+package workflow
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	builds      []*domain.Build
+	deployments []*domain.Deployment
+}
+
+func (n *recordingNotifier) SendNotification(ctx context.Context, notification *domain.Notification) error {
+	return nil
+}
+
+func (n *recordingNotifier) SendBuildNotification(ctx context.Context, build *domain.Build) error {
+	n.builds = append(n.builds, build)
+	return nil
+}
+
+func (n *recordingNotifier) SendDeploymentNotification(ctx context.Context, deployment *domain.Deployment) error {
+	n.deployments = append(n.deployments, deployment)
+	return nil
+}
+
+func (n *recordingNotifier) SendAlertNotification(ctx context.Context, alert *domain.Alert) error {
+	return nil
+}
+
+func TestNotify(t *testing.T) {
+	notifier := &recordingNotifier{}
+	sm := &StateMachine{logger: logger.New("error", "json", io.Discard)}
+
+	wf := &DeploymentWorkflow{
+		ID:        uuid.New(),
+		ServiceID: uuid.New(),
+		ProjectID: uuid.New(),
+		State:     StateBuildFailed,
+		Error:     "npm ci exited with 1",
+	}
+	// Without a notifier nothing is sent
+	sm.notifyBuild(context.Background(), wf, domain.BuildStatusFailed)
+
+	sm.UseNotifier(notifier)
+	sm.notifyBuild(context.Background(), wf, domain.BuildStatusFailed)
+	require.Len(t, notifier.builds, 1)
+	assert.Equal(t, domain.BuildStatusFailed, notifier.builds[0].Status)
+	assert.Equal(t, wf.ProjectID, notifier.builds[0].ProjectID)
+	assert.Equal(t, "npm ci exited with 1", notifier.builds[0].ErrorMessage)
+
+	wf.State = StateDeployComplete
+	wf.Version = "v7"
+	wf.PrevVersion = "v6"
+	wf.Error = ""
+	sm.notifyDeployment(context.Background(), wf)
+	require.Len(t, notifier.deployments, 1)
+	assert.Equal(t, domain.DeploymentStatusSucceeded, notifier.deployments[0].Status)
+	assert.Equal(t, "v7", notifier.deployments[0].Version)
+	assert.Equal(t, "v6", notifier.deployments[0].PreviousVersion)
+
+	// States outside the deploy lifecycle are not reported
+	wf.State = StateRollingBack
+	sm.notifyDeployment(context.Background(), wf)
+	assert.Len(t, notifier.deployments, 1)
+}
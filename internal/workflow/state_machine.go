@@ -10,7 +10,11 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/northstack/platform/internal/approvals"
+	"github.com/northstack/platform/internal/clusterupgrade"
 	"github.com/northstack/platform/internal/domain"
+	"github.com/northstack/platform/internal/prepull"
+	"github.com/northstack/platform/internal/residency"
 	"github.com/northstack/platform/pkg/logger"
 )
 
@@ -24,6 +28,7 @@ const (
 	StateBuildComplete  DeploymentState = "build_complete"
 	StateBuildFailed    DeploymentState = "build_failed"
 	StateDeployQueued   DeploymentState = "deploy_queued"
+	StatePrePulling     DeploymentState = "pre_pulling"
 	StateDeploying      DeploymentState = "deploying"
 	StateDeployComplete DeploymentState = "deploy_complete"
 	StateDeployFailed   DeploymentState = "deploy_failed"
@@ -40,6 +45,7 @@ const (
 	EventBuildSucceeded   DeploymentEvent = "build_succeeded"
 	EventBuildFailed      DeploymentEvent = "build_failed"
 	EventTriggerDeploy    DeploymentEvent = "trigger_deploy"
+	EventPrePullStarted   DeploymentEvent = "pre_pull_started"
 	EventDeployStarted    DeploymentEvent = "deploy_started"
 	EventDeploySucceeded  DeploymentEvent = "deploy_succeeded"
 	EventDeployFailed     DeploymentEvent = "deploy_failed"
@@ -59,6 +65,8 @@ type DeploymentWorkflow struct {
 	DeploymentID *uuid.UUID
 	Version      string
 	PrevVersion  string
+	Image        string
+	PrePull      *domain.PrePullStatus
 	Error        string
 	StartedAt    time.Time
 	UpdatedAt    time.Time
@@ -73,16 +81,32 @@ type StateMachine struct {
 	gitOps     domain.GitOpsAdapter
 	eventBus   domain.EventBus
 	serviceRepo domain.ServiceRepository
+	buildRepo  domain.BuildRepository
+	deployRepo domain.DeploymentRepository
+	prePuller  *prepull.Puller
+	residency  *residency.Checker
+	upgrades   *clusterupgrade.Upgrader
+	approvals  *approvals.Gate
+	notifier   domain.Notifier
 	logger     *logger.Logger
 	transitions map[DeploymentState]map[DeploymentEvent]DeploymentState
+
+	// recordMu serializes writes of deployment records
+	recordMu sync.Mutex
 }
 
-// NewStateMachine creates a new state machine
+// NewStateMachine creates a new state machine. buildRepo and deployRepo may be
+// nil, in which case builds and deployments are not persisted. prePuller may
+// be nil, in which case rollouts start without pulling the image onto the
+// target nodes first.
 func NewStateMachine(
 	ciAdapter domain.CIAdapter,
 	gitOps domain.GitOpsAdapter,
 	eventBus domain.EventBus,
 	serviceRepo domain.ServiceRepository,
+	buildRepo domain.BuildRepository,
+	deployRepo domain.DeploymentRepository,
+	prePuller *prepull.Puller,
 	log *logger.Logger,
 ) *StateMachine {
 	sm := &StateMachine{
@@ -91,6 +115,9 @@ func NewStateMachine(
 		gitOps:      gitOps,
 		eventBus:    eventBus,
 		serviceRepo: serviceRepo,
+		buildRepo:   buildRepo,
+		deployRepo:  deployRepo,
+		prePuller:   prePuller,
 		logger:      log,
 	}
 
@@ -122,7 +149,13 @@ func (sm *StateMachine) initTransitions() {
 			EventTriggerBuild: StateBuildQueued,
 		},
 		StateDeployQueued: {
+			EventPrePullStarted: StatePrePulling,
+			EventDeployStarted:  StateDeploying,
+			EventCancel:         StateBuildComplete,
+		},
+		StatePrePulling: {
 			EventDeployStarted: StateDeploying,
+			EventDeployFailed:  StateDeployFailed,
 			EventCancel:        StateBuildComplete,
 		},
 		StateDeploying: {
@@ -151,8 +184,41 @@ func (sm *StateMachine) initTransitions() {
 	}
 }
 
+// UseResidency makes new workflows check the target cluster against the data
+// residency of the service's project
+func (sm *StateMachine) UseResidency(checker *residency.Checker) {
+	sm.residency = checker
+}
+
+// UseUpgrades makes workflows refuse to deploy to clusters that are being
+// upgraded
+func (sm *StateMachine) UseUpgrades(upgrader *clusterupgrade.Upgrader) {
+	sm.upgrades = upgrader
+}
+
+// UseApprovals makes deploys to gated environments wait until they are
+// approved. The gate resumes them through EventTriggerDeploy.
+func (sm *StateMachine) UseApprovals(gate *approvals.Gate) {
+	sm.approvals = gate
+}
+
 // CreateWorkflow creates a new deployment workflow
 func (sm *StateMachine) CreateWorkflow(ctx context.Context, serviceID, projectID, clusterID uuid.UUID) (*DeploymentWorkflow, error) {
+	if err := sm.upgrades.CheckDeploy(ctx, clusterID); err != nil {
+		sm.logger.Warn().Err(err).
+			Str("service_id", serviceID.String()).
+			Str("cluster_id", clusterID.String()).
+			Msg("Deployment rejected during cluster upgrade")
+		return nil, err
+	}
+	if err := sm.residency.CheckDeploy(ctx, projectID, clusterID); err != nil {
+		sm.logger.Warn().Err(err).
+			Str("service_id", serviceID.String()).
+			Str("cluster_id", clusterID.String()).
+			Msg("Deployment rejected by data residency")
+		return nil, err
+	}
+
 	workflow := &DeploymentWorkflow{
 		ID:        uuid.New(),
 		ServiceID: serviceID,
@@ -184,6 +250,30 @@ func (sm *StateMachine) GetWorkflow(id uuid.UUID) (*DeploymentWorkflow, bool) {
 	return wf, exists
 }
 
+// LatestWorkflowByService returns a copy of the most recently updated workflow
+// of a service, including finished ones that were not cleaned up yet
+func (sm *StateMachine) LatestWorkflowByService(serviceID uuid.UUID) (*DeploymentWorkflow, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	var latest *DeploymentWorkflow
+	for _, wf := range sm.workflows {
+		if wf.ServiceID == serviceID && (latest == nil || wf.UpdatedAt.After(latest.UpdatedAt)) {
+			latest = wf
+		}
+	}
+	if latest == nil {
+		return nil, false
+	}
+
+	snapshot := *latest
+	if latest.PrePull != nil {
+		prePull := *latest.PrePull
+		snapshot.PrePull = &prePull
+	}
+	return &snapshot, true
+}
+
 // GetWorkflowByService retrieves the active workflow for a service
 func (sm *StateMachine) GetWorkflowByService(serviceID uuid.UUID) (*DeploymentWorkflow, bool) {
 	sm.mu.RLock()
@@ -198,6 +288,37 @@ func (sm *StateMachine) GetWorkflowByService(serviceID uuid.UUID) (*DeploymentWo
 
 // ProcessEvent processes an event and transitions the workflow state
 func (sm *StateMachine) ProcessEvent(ctx context.Context, workflowID uuid.UUID, event DeploymentEvent, data map[string]interface{}) error {
+	// The project's residency may have changed since the workflow was
+	// created, the cluster may have started an upgrade, and the environment
+	// may need the deploy approved
+	if event == EventTriggerDeploy && (sm.residency != nil || sm.upgrades != nil || sm.approvals != nil) {
+		sm.mu.RLock()
+		workflow, exists := sm.workflows[workflowID]
+		sm.mu.RUnlock()
+		if exists {
+			if err := sm.residency.CheckDeploy(ctx, workflow.ProjectID, workflow.ClusterID); err != nil {
+				return err
+			}
+			if err := sm.upgrades.CheckDeploy(ctx, workflow.ClusterID); err != nil {
+				return err
+			}
+
+			version := workflow.Version
+			if v, ok := data["version"].(string); ok {
+				version = v
+			}
+			if err := sm.approvals.CheckDeploy(ctx, approvals.Deploy{
+				WorkflowID: workflow.ID,
+				ServiceID:  workflow.ServiceID,
+				ProjectID:  workflow.ProjectID,
+				ClusterID:  workflow.ClusterID,
+				Version:    version,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
@@ -221,6 +342,13 @@ func (sm *StateMachine) ProcessEvent(ctx context.Context, workflowID uuid.UUID,
 	workflow.State = newState
 	workflow.UpdatedAt = time.Now()
 
+	// Every deploy is recorded as a new deployment
+	if newState == StateDeployQueued {
+		workflow.DeploymentID = nil
+		workflow.PrePull = nil
+		workflow.Error = ""
+	}
+
 	// Update workflow data based on event
 	if data != nil {
 		if buildID, ok := data["build_id"].(uuid.UUID); ok {
@@ -232,6 +360,9 @@ func (sm *StateMachine) ProcessEvent(ctx context.Context, workflowID uuid.UUID,
 		if version, ok := data["version"].(string); ok {
 			workflow.Version = version
 		}
+		if image, ok := data["image"].(string); ok {
+			workflow.Image = image
+		}
 		if errMsg, ok := data["error"].(string); ok {
 			workflow.Error = errMsg
 		}
@@ -252,16 +383,32 @@ func (sm *StateMachine) ProcessEvent(ctx context.Context, workflowID uuid.UUID,
 
 // executeSideEffects performs actions based on state transitions
 func (sm *StateMachine) executeSideEffects(ctx context.Context, workflow *DeploymentWorkflow, oldState, newState DeploymentState) {
+	// Recorded first so deploy events carry the deployment ID
+	sm.recordDeployment(ctx, workflow)
+
 	switch newState {
 	case StateBuilding:
 		sm.updateServiceStatus(ctx, workflow.ServiceID, domain.ServiceStatusBuilding)
 
 	case StateBuildComplete:
 		sm.publishEvent(ctx, "build.completed", workflow)
+		sm.notifyBuild(ctx, workflow, domain.BuildStatusSucceeded)
 
 	case StateBuildFailed:
 		sm.updateServiceStatus(ctx, workflow.ServiceID, domain.ServiceStatusFailed)
 		sm.publishEvent(ctx, "build.failed", workflow)
+		sm.notifyBuild(ctx, workflow, domain.BuildStatusFailed)
+
+	case StateDeployQueued:
+		// Pull the image onto the target nodes before the rollout starts
+		if sm.prePuller != nil && workflow.Image != "" {
+			if err := sm.ProcessEvent(ctx, workflow.ID, EventPrePullStarted, nil); err != nil {
+				sm.logger.Warn().Err(err).Str("workflow_id", workflow.ID.String()).Msg("Failed to start image pre-pull")
+			}
+		}
+
+	case StatePrePulling:
+		sm.prePull(context.WithoutCancel(ctx), workflow)
 
 	case StateDeploying:
 		sm.updateServiceStatus(ctx, workflow.ServiceID, domain.ServiceStatusDeploying)
@@ -270,14 +417,74 @@ func (sm *StateMachine) executeSideEffects(ctx context.Context, workflow *Deploy
 	case StateDeployComplete:
 		sm.updateServiceStatus(ctx, workflow.ServiceID, domain.ServiceStatusRunning)
 		sm.publishEvent(ctx, "deploy.completed", workflow)
+		sm.notifyDeployment(ctx, workflow)
 
 	case StateDeployFailed:
 		sm.updateServiceStatus(ctx, workflow.ServiceID, domain.ServiceStatusFailed)
 		sm.publishEvent(ctx, "deploy.failed", workflow)
+		sm.notifyDeployment(ctx, workflow)
 
 	case StateRollbackComplete:
 		sm.updateServiceStatus(ctx, workflow.ServiceID, domain.ServiceStatusRunning)
 		sm.publishEvent(ctx, "rollback.completed", workflow)
+		sm.notifyDeployment(ctx, workflow)
+	}
+}
+
+// prePull pulls the workflow's image onto the service's nodes, then starts
+// the rollout. The deploy fails when the image cannot be pulled.
+func (sm *StateMachine) prePull(ctx context.Context, workflow *DeploymentWorkflow) {
+	service, err := sm.serviceRepo.GetByID(ctx, workflow.ServiceID)
+	if err != nil {
+		sm.ProcessEvent(ctx, workflow.ID, EventDeployFailed, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	status, err := sm.prePuller.PrePull(ctx, service, workflow.Image, func(status *domain.PrePullStatus) {
+		sm.setPrePull(ctx, workflow, status)
+	})
+	if status != nil {
+		sm.setPrePull(ctx, workflow, status)
+	}
+	if err != nil {
+		sm.ProcessEvent(ctx, workflow.ID, EventDeployFailed, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	if err := sm.ProcessEvent(ctx, workflow.ID, EventDeployStarted, nil); err != nil {
+		sm.logger.Warn().Err(err).Str("workflow_id", workflow.ID.String()).Msg("Failed to start rollout after image pre-pull")
+	}
+}
+
+// setPrePull records pre-pull progress on the workflow and publishes it
+func (sm *StateMachine) setPrePull(ctx context.Context, workflow *DeploymentWorkflow, status *domain.PrePullStatus) {
+	snapshot := *status
+
+	sm.mu.Lock()
+	workflow.PrePull = &snapshot
+	workflow.UpdatedAt = time.Now()
+	sm.mu.Unlock()
+
+	sm.recordDeployment(ctx, workflow)
+
+	event := &domain.Event{
+		Type:   "deploy.prepull",
+		Source: "workflow-engine",
+		Data: map[string]interface{}{
+			"workflow_id": workflow.ID.String(),
+			"service_id":  workflow.ServiceID.String(),
+			"project_id":  workflow.ProjectID.String(),
+			"image":       snapshot.Image,
+			"phase":       string(snapshot.Phase),
+			"nodes_ready": snapshot.NodesReady,
+			"nodes_total": snapshot.NodesTotal,
+		},
+	}
+	if snapshot.Message != "" {
+		event.Data["message"] = snapshot.Message
+	}
+	if err := sm.eventBus.Publish(ctx, "deploy.prepull", event); err != nil {
+		sm.logger.Error().Err(err).Str("event_type", "deploy.prepull").Msg("Failed to publish event")
 	}
 }
 
@@ -336,6 +543,12 @@ func (sm *StateMachine) TriggerBuildAndDeploy(ctx context.Context, service *doma
 		return workflow, err
 	}
 
+	if sm.buildRepo != nil {
+		if err := sm.buildRepo.Create(ctx, build); err != nil {
+			sm.logger.Error().Err(err).Str("build_id", build.ID.String()).Msg("Failed to record build")
+		}
+	}
+
 	// Update workflow with build info
 	sm.ProcessEvent(ctx, workflow.ID, EventBuildStarted, map[string]interface{}{"build_id": build.ID})
 
@@ -0,0 +1,478 @@
+// Package dragonfly provisions single-instance, Redis-compatible caches in a
+// Kubernetes namespace: Dragonfly by default, or Redis. Each cache is a
+// StatefulSet with a Service in front and a connection secret; the Service,
+// the secret and the TLS certificate are owned by the StatefulSet, so
+// deleting it removes them all.
+package dragonfly
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/pkg/password"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Kubernetes GVRs of the objects of a cache
+var (
+	StatefulSetGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	ServiceGVR     = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	SecretGVR      = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	CertificateGVR = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+)
+
+// Cache engines
+const (
+	EngineDragonfly = "dragonfly"
+	EngineRedis     = "redis"
+)
+
+// Port is the port caches listen on, with or without TLS
+const Port = 6379
+
+// Default engine versions
+const (
+	DefaultDragonflyVersion = "v1.21.2"
+	DefaultRedisVersion     = "7.2"
+)
+
+// CacheService manages Dragonfly and Redis caches
+type CacheService struct {
+	dynamic   dynamic.Interface
+	namespace string
+	tlsIssuer string // cert-manager ClusterIssuer of cache certificates; empty disables TLS
+}
+
+// CreateCacheInput holds parameters for creating a cache
+type CreateCacheInput struct {
+	Name        string
+	ProjectID   string
+	TeamID      string
+	Engine      string // dragonfly or redis
+	Size        string // small, medium, large, xlarge
+	Persistence bool   // Snapshot to a volume and reload on restart
+	StorageGB   int    // Size of the volume with persistence
+	TLSEnabled  bool
+	Version     string
+}
+
+// CacheInfo describes a cache
+type CacheInfo struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	ProjectID   string    `json:"project_id"`
+	Engine      string    `json:"engine"`
+	Status      string    `json:"status"`
+	Endpoint    string    `json:"endpoint"`
+	Port        int       `json:"port"`
+	Size        string    `json:"size"`
+	Persistence bool      `json:"persistence"`
+	TLSEnabled  bool      `json:"tls_enabled"`
+	SecretName  string    `json:"secret_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ResourceConfig holds the resources of a cache for a size
+type ResourceConfig struct {
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+	MaxMemory     string // Engine memory limit, leaving headroom for the process itself
+}
+
+// NewCacheService creates a new cache service. Without a TLS issuer, caches
+// cannot be created with TLS.
+func NewCacheService(dynClient dynamic.Interface, namespace, tlsIssuer string) *CacheService {
+	return &CacheService{
+		dynamic:   dynClient,
+		namespace: namespace,
+		tlsIssuer: tlsIssuer,
+	}
+}
+
+// TLSAvailable reports whether caches can be created with TLS
+func (s *CacheService) TLSAvailable() bool {
+	return s.tlsIssuer != ""
+}
+
+// CreateCache creates a cache and its connection secret
+func (s *CacheService) CreateCache(ctx context.Context, input *CreateCacheInput) (*CacheInfo, error) {
+	if input.TLSEnabled && !s.TLSAvailable() {
+		return nil, fmt.Errorf("TLS is not available for caches")
+	}
+	name := fmt.Sprintf("%s-%s", input.ProjectID, input.Name)
+	engine := input.Engine
+	if engine == "" {
+		engine = EngineDragonfly
+	}
+	size := input.Size
+	if _, ok := sizes[size]; !ok {
+		size = "small"
+	}
+
+	password, err := password.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]interface{}{
+		"northstack.io/project": input.ProjectID,
+		"northstack.io/team":    input.TeamID,
+		"northstack.io/type":    "cache",
+		"northstack.io/engine":  engine,
+		"northstack.io/cache":   name,
+	}
+	statefulSet := s.statefulSet(name, engine, size, input, labels)
+	created, err := s.dynamic.Resource(StatefulSetGVR).Namespace(s.namespace).Create(ctx, statefulSet, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache: %w", err)
+	}
+	owner := []interface{}{map[string]interface{}{
+		"apiVersion":         "apps/v1",
+		"kind":               "StatefulSet",
+		"name":               created.GetName(),
+		"uid":                string(created.GetUID()),
+		"controller":         true,
+		"blockOwnerDeletion": true,
+	}}
+
+	scheme := "redis"
+	if input.TLSEnabled {
+		scheme = "rediss"
+	}
+	host := fmt.Sprintf("%s.%s.svc", name, s.namespace)
+	type owned struct {
+		gvr schema.GroupVersionResource
+		obj *unstructured.Unstructured
+	}
+	objects := []owned{
+		{SecretGVR, s.object("v1", "Secret", name+"-connection", labels, owner, map[string]interface{}{
+			"type": "Opaque",
+			"stringData": map[string]interface{}{
+				"REDIS_HOST":     host,
+				"REDIS_PORT":     strconv.Itoa(Port),
+				"REDIS_PASSWORD": password,
+				"REDIS_URL":      fmt.Sprintf("%s://:%s@%s:%d", scheme, password, host, Port),
+			},
+		})},
+		{ServiceGVR, s.object("v1", "Service", name, labels, owner, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{"northstack.io/cache": name},
+				"ports": []interface{}{map[string]interface{}{
+					"name":       "redis",
+					"port":       int64(Port),
+					"targetPort": int64(Port),
+				}},
+			},
+		})},
+	}
+	if input.TLSEnabled {
+		objects = append(objects, owned{CertificateGVR, s.object("cert-manager.io/v1", "Certificate", name, labels, owner, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"secretName": name + "-tls",
+				"dnsNames":   []interface{}{name, host, host + ".cluster.local"},
+				"issuerRef": map[string]interface{}{
+					"kind": "ClusterIssuer",
+					"name": s.tlsIssuer,
+				},
+			},
+		})})
+	}
+	for _, o := range objects {
+		if _, err := s.dynamic.Resource(o.gvr).Namespace(s.namespace).Create(ctx, o.obj, metav1.CreateOptions{}); err != nil {
+			// The objects created so far go with the StatefulSet
+			_ = s.DeleteCache(ctx, name)
+			return nil, fmt.Errorf("failed to create cache %s: %w", o.obj.GetKind(), err)
+		}
+	}
+
+	info := s.extractCacheInfo(created)
+	info.Name = input.Name
+	info.Status = "creating"
+	return info, nil
+}
+
+// GetCache retrieves cache information
+func (s *CacheService) GetCache(ctx context.Context, name string) (*CacheInfo, error) {
+	statefulSet, err := s.get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.extractCacheInfo(statefulSet), nil
+}
+
+// ListCaches lists the caches of a project, or all of them
+func (s *CacheService) ListCaches(ctx context.Context, projectID string) ([]*CacheInfo, error) {
+	labelSelector := "northstack.io/type=cache"
+	if projectID != "" {
+		labelSelector += fmt.Sprintf(",northstack.io/project=%s", projectID)
+	}
+
+	statefulSets, err := s.dynamic.Resource(StatefulSetGVR).Namespace(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var caches []*CacheInfo
+	for i := range statefulSets.Items {
+		caches = append(caches, s.extractCacheInfo(&statefulSets.Items[i]))
+	}
+
+	return caches, nil
+}
+
+// DeleteCache deletes a cache with its Service, secrets, certificate and
+// volume
+func (s *CacheService) DeleteCache(ctx context.Context, name string) error {
+	propagation := metav1.DeletePropagationBackground
+	return s.dynamic.Resource(StatefulSetGVR).Namespace(s.namespace).Delete(ctx, name, metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+}
+
+// Connection returns the values of a cache's connection secret: REDIS_HOST,
+// REDIS_PORT, REDIS_PASSWORD and REDIS_URL
+func (s *CacheService) Connection(ctx context.Context, name string) (map[string]string, error) {
+	if _, err := s.get(ctx, name); err != nil {
+		return nil, err
+	}
+	secret, err := s.dynamic.Resource(SecretGVR).Namespace(s.namespace).Get(ctx, name+"-connection", metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, _ := unstructured.NestedStringMap(secret.Object, "data")
+	values := make(map[string]string, len(data))
+	for key, encoded := range data {
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid connection secret: %w", err)
+		}
+		values[key] = string(value)
+	}
+	return values, nil
+}
+
+// get returns the StatefulSet of a cache, ignoring other StatefulSets
+func (s *CacheService) get(ctx context.Context, name string) (*unstructured.Unstructured, error) {
+	statefulSet, err := s.dynamic.Resource(StatefulSetGVR).Namespace(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if statefulSet.GetLabels()["northstack.io/type"] != "cache" {
+		return nil, apierrors.NewNotFound(StatefulSetGVR.GroupResource(), name)
+	}
+	return statefulSet, nil
+}
+
+func (s *CacheService) statefulSet(name, engine, size string, input *CreateCacheInput, labels map[string]interface{}) *unstructured.Unstructured {
+	resources := sizes[size]
+
+	image, args := engineCommand(engine, input.Version, resources, input.Persistence, input.TLSEnabled)
+	container := map[string]interface{}{
+		"name":  engine,
+		"image": image,
+		"args":  args,
+		"env": []interface{}{map[string]interface{}{
+			"name": "REDIS_PASSWORD",
+			"valueFrom": map[string]interface{}{
+				"secretKeyRef": map[string]interface{}{"name": name + "-connection", "key": "REDIS_PASSWORD"},
+			},
+		}},
+		"ports": []interface{}{map[string]interface{}{"name": "redis", "containerPort": int64(Port)}},
+		"resources": map[string]interface{}{
+			"requests": map[string]interface{}{"cpu": resources.CPURequest, "memory": resources.MemoryRequest},
+			"limits":   map[string]interface{}{"cpu": resources.CPULimit, "memory": resources.MemoryLimit},
+		},
+		"readinessProbe": map[string]interface{}{
+			"tcpSocket":     map[string]interface{}{"port": int64(Port)},
+			"periodSeconds": int64(5),
+		},
+	}
+
+	var mounts, volumes, claims []interface{}
+	if input.Persistence {
+		mounts = append(mounts, map[string]interface{}{"name": "data", "mountPath": "/data"})
+		claims = append(claims, map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "data"},
+			"spec": map[string]interface{}{
+				"accessModes": []interface{}{"ReadWriteOnce"},
+				"resources": map[string]interface{}{
+					"requests": map[string]interface{}{"storage": fmt.Sprintf("%dGi", input.StorageGB)},
+				},
+			},
+		})
+	}
+	if input.TLSEnabled {
+		mounts = append(mounts, map[string]interface{}{"name": "tls", "mountPath": "/etc/tls", "readOnly": true})
+		volumes = append(volumes, map[string]interface{}{
+			"name":   "tls",
+			"secret": map[string]interface{}{"secretName": name + "-tls"},
+		})
+	}
+	if len(mounts) > 0 {
+		container["volumeMounts"] = mounts
+	}
+
+	podSpec := map[string]interface{}{
+		"containers": []interface{}{container},
+	}
+	if len(volumes) > 0 {
+		podSpec["volumes"] = volumes
+	}
+	spec := map[string]interface{}{
+		"serviceName": name,
+		"replicas":    int64(1),
+		"selector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{"northstack.io/cache": name},
+		},
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{"labels": labels},
+			"spec":     podSpec,
+		},
+	}
+	if len(claims) > 0 {
+		spec["volumeClaimTemplates"] = claims
+		spec["persistentVolumeClaimRetentionPolicy"] = map[string]interface{}{"whenDeleted": "Delete"}
+	}
+
+	statefulSet := s.object("apps/v1", "StatefulSet", name, labels, nil, map[string]interface{}{"spec": spec})
+	statefulSet.SetAnnotations(map[string]string{"northstack.io/size": size})
+	return statefulSet
+}
+
+// engineCommand returns the image and arguments of an engine. The password
+// comes from the REDIS_PASSWORD variable, which Kubernetes expands in them.
+func engineCommand(engine, version string, resources ResourceConfig, persistence, tls bool) (string, []interface{}) {
+	if engine == EngineRedis {
+		if version == "" {
+			version = DefaultRedisVersion
+		}
+		args := []interface{}{"redis-server", "--requirepass", "$(REDIS_PASSWORD)", "--maxmemory", resources.MaxMemory}
+		if persistence {
+			args = append(args, "--dir", "/data", "--appendonly", "yes")
+		} else {
+			args = append(args, "--save", "", "--appendonly", "no")
+		}
+		if tls {
+			args = append(args, "--port", "0", "--tls-port", strconv.Itoa(Port),
+				"--tls-cert-file", "/etc/tls/tls.crt", "--tls-key-file", "/etc/tls/tls.key", "--tls-auth-clients", "no")
+		}
+		return "redis:" + version, args
+	}
+
+	if version == "" {
+		version = DefaultDragonflyVersion
+	}
+	args := []interface{}{"--requirepass=$(REDIS_PASSWORD)", "--maxmemory=" + resources.MaxMemory, fmt.Sprintf("--port=%d", Port)}
+	if persistence {
+		args = append(args, "--dir=/data", "--dbfilename=dump", "--snapshot_cron=*/5 * * * *")
+	} else {
+		args = append(args, "--dbfilename=")
+	}
+	if tls {
+		args = append(args, "--tls", "--tls_cert_file=/etc/tls/tls.crt", "--tls_key_file=/etc/tls/tls.key")
+	}
+	return "docker.dragonflydb.io/dragonflydb/dragonfly:" + version, args
+}
+
+func (s *CacheService) object(apiVersion, kind, name string, labels map[string]interface{}, owner []interface{}, fields map[string]interface{}) *unstructured.Unstructured {
+	metadata := map[string]interface{}{
+		"name":      name,
+		"namespace": s.namespace,
+		"labels":    labels,
+	}
+	if owner != nil {
+		metadata["ownerReferences"] = owner
+	}
+	obj := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   metadata,
+	}
+	for key, value := range fields {
+		obj[key] = value
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+// sizes are the resources of each size preset
+var sizes = map[string]ResourceConfig{
+	"small": {
+		CPURequest:    "250m",
+		CPULimit:      "500m",
+		MemoryRequest: "1Gi",
+		MemoryLimit:   "1Gi",
+		MaxMemory:     "800mb",
+	},
+	"medium": {
+		CPURequest:    "500m",
+		CPULimit:      "1",
+		MemoryRequest: "2Gi",
+		MemoryLimit:   "2Gi",
+		MaxMemory:     "1600mb",
+	},
+	"large": {
+		CPURequest:    "1",
+		CPULimit:      "2",
+		MemoryRequest: "4Gi",
+		MemoryLimit:   "4Gi",
+		MaxMemory:     "3200mb",
+	},
+	"xlarge": {
+		CPURequest:    "2",
+		CPULimit:      "4",
+		MemoryRequest: "8Gi",
+		MemoryLimit:   "8Gi",
+		MaxMemory:     "6400mb",
+	},
+}
+
+func (s *CacheService) extractCacheInfo(statefulSet *unstructured.Unstructured) *CacheInfo {
+	name := statefulSet.GetName()
+	labels := statefulSet.GetLabels()
+	info := &CacheInfo{
+		ID:         name,
+		Name:       name,
+		ProjectID:  labels["northstack.io/project"],
+		Engine:     labels["northstack.io/engine"],
+		Endpoint:   fmt.Sprintf("%s.%s.svc:%d", name, s.namespace, Port),
+		Port:       Port,
+		Size:       statefulSet.GetAnnotations()["northstack.io/size"],
+		SecretName: name + "-connection",
+		CreatedAt:  statefulSet.GetCreationTimestamp().Time,
+	}
+	if strings.HasPrefix(name, info.ProjectID+"-") {
+		info.Name = strings.TrimPrefix(name, info.ProjectID+"-")
+	}
+
+	claims, _, _ := unstructured.NestedSlice(statefulSet.Object, "spec", "volumeClaimTemplates")
+	info.Persistence = len(claims) > 0
+	volumes, _, _ := unstructured.NestedSlice(statefulSet.Object, "spec", "template", "spec", "volumes")
+	for _, v := range volumes {
+		if volume, ok := v.(map[string]interface{}); ok && volume["name"] == "tls" {
+			info.TLSEnabled = true
+		}
+	}
+
+	ready, _, _ := unstructured.NestedInt64(statefulSet.Object, "status", "readyReplicas")
+	if ready > 0 {
+		info.Status = "ready"
+	} else {
+		info.Status = "creating"
+	}
+
+	return info
+}
@@ -0,0 +1,169 @@
+package dragonfly
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+const testNamespace = "caches"
+
+func newTestService(tlsIssuer string, objects ...runtime.Object) *CacheService {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		StatefulSetGVR: "StatefulSetList",
+		ServiceGVR:     "ServiceList",
+		SecretGVR:      "SecretList",
+		CertificateGVR: "CertificateList",
+	}, objects...)
+	return NewCacheService(client, testNamespace, tlsIssuer)
+}
+
+func get(t *testing.T, s *CacheService, gvr schema.GroupVersionResource, name string) *unstructured.Unstructured {
+	obj, err := s.dynamic.Resource(gvr).Namespace(testNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return obj
+}
+
+func TestCreateCache(t *testing.T) {
+	s := newTestService("")
+
+	info, err := s.CreateCache(context.Background(), &CreateCacheInput{Name: "sessions", ProjectID: "p1", Size: "huge"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "p1-sessions", info.ID)
+	assert.Equal(t, "sessions", info.Name)
+	assert.Equal(t, EngineDragonfly, info.Engine)
+	assert.Equal(t, "small", info.Size, "unknown sizes are small")
+	assert.Equal(t, "creating", info.Status)
+	assert.Equal(t, "p1-sessions.caches.svc:6379", info.Endpoint)
+	assert.Equal(t, "p1-sessions-connection", info.SecretName)
+	assert.False(t, info.Persistence)
+	assert.False(t, info.TLSEnabled)
+
+	secret := get(t, s, SecretGVR, "p1-sessions-connection")
+	values, _, _ := unstructured.NestedStringMap(secret.Object, "stringData")
+	assert.Len(t, values["REDIS_PASSWORD"], 48)
+	assert.Equal(t, "redis://:"+values["REDIS_PASSWORD"]+"@p1-sessions.caches.svc:6379", values["REDIS_URL"])
+	assert.Len(t, secret.GetOwnerReferences(), 1)
+
+	service := get(t, s, ServiceGVR, "p1-sessions")
+	selector, _, _ := unstructured.NestedStringMap(service.Object, "spec", "selector")
+	assert.Equal(t, map[string]string{"northstack.io/cache": "p1-sessions"}, selector)
+}
+
+func TestCreateCacheWithTLSAndPersistence(t *testing.T) {
+	_, err := newTestService("").CreateCache(context.Background(), &CreateCacheInput{Name: "sessions", ProjectID: "p1", TLSEnabled: true})
+	assert.Error(t, err, "TLS needs an issuer")
+
+	s := newTestService("internal-ca")
+	info, err := s.CreateCache(context.Background(), &CreateCacheInput{
+		Name:        "queue",
+		ProjectID:   "p1",
+		Engine:      EngineRedis,
+		Persistence: true,
+		StorageGB:   5,
+		TLSEnabled:  true,
+	})
+	require.NoError(t, err)
+	assert.True(t, info.Persistence)
+	assert.True(t, info.TLSEnabled)
+
+	secret := get(t, s, SecretGVR, "p1-queue-connection")
+	url, _, _ := unstructured.NestedString(secret.Object, "stringData", "REDIS_URL")
+	assert.Contains(t, url, "rediss://")
+
+	certificate := get(t, s, CertificateGVR, "p1-queue")
+	issuer, _, _ := unstructured.NestedString(certificate.Object, "spec", "issuerRef", "name")
+	assert.Equal(t, "internal-ca", issuer)
+
+	statefulSet := get(t, s, StatefulSetGVR, "p1-queue")
+	claims, _, _ := unstructured.NestedSlice(statefulSet.Object, "spec", "volumeClaimTemplates")
+	require.Len(t, claims, 1)
+	storage, _, _ := unstructured.NestedString(claims[0].(map[string]interface{}), "spec", "resources", "requests", "storage")
+	assert.Equal(t, "5Gi", storage)
+}
+
+func TestEngineCommand(t *testing.T) {
+	resources := sizes["small"]
+
+	image, args := engineCommand(EngineDragonfly, "", resources, false, false)
+	assert.Equal(t, "docker.dragonflydb.io/dragonflydb/dragonfly:"+DefaultDragonflyVersion, image)
+	assert.Equal(t, []interface{}{"--requirepass=$(REDIS_PASSWORD)", "--maxmemory=800mb", "--port=6379", "--dbfilename="}, args)
+
+	_, args = engineCommand(EngineDragonfly, "", resources, true, true)
+	assert.Contains(t, args, "--dir=/data")
+	assert.Contains(t, args, "--tls")
+
+	image, args = engineCommand(EngineRedis, "7.4", resources, false, false)
+	assert.Equal(t, "redis:7.4", image)
+	assert.Equal(t, []interface{}{"redis-server", "--requirepass", "$(REDIS_PASSWORD)", "--maxmemory", "800mb", "--save", "", "--appendonly", "no"}, args)
+
+	_, args = engineCommand(EngineRedis, "", resources, true, true)
+	assert.Contains(t, args, "--appendonly")
+	assert.Contains(t, args, "--tls-port")
+}
+
+func TestGetCacheIgnoresOtherStatefulSets(t *testing.T) {
+	other := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "StatefulSet",
+		"metadata":   map[string]interface{}{"name": "p1-db", "namespace": testNamespace},
+	}}
+	s := newTestService("", other)
+
+	_, err := s.GetCache(context.Background(), "p1-db")
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestListCaches(t *testing.T) {
+	s := newTestService("")
+	ctx := context.Background()
+	for _, input := range []*CreateCacheInput{
+		{Name: "sessions", ProjectID: "p1"},
+		{Name: "queue", ProjectID: "p1"},
+		{Name: "sessions", ProjectID: "p2"},
+	} {
+		_, err := s.CreateCache(ctx, input)
+		require.NoError(t, err)
+	}
+
+	caches, err := s.ListCaches(ctx, "p1")
+	require.NoError(t, err)
+	assert.Len(t, caches, 2)
+
+	caches, err = s.ListCaches(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, caches, 3)
+}
+
+func TestConnection(t *testing.T) {
+	s := newTestService("")
+	ctx := context.Background()
+	_, err := s.CreateCache(ctx, &CreateCacheInput{Name: "sessions", ProjectID: "p1"})
+	require.NoError(t, err)
+
+	// The API server moves stringData into data
+	secret := get(t, s, SecretGVR, "p1-sessions-connection")
+	require.NoError(t, unstructured.SetNestedStringMap(secret.Object, map[string]string{
+		"REDIS_HOST": base64.StdEncoding.EncodeToString([]byte("p1-sessions.caches.svc")),
+		"REDIS_PORT": base64.StdEncoding.EncodeToString([]byte("6379")),
+	}, "data"))
+	_, err = s.dynamic.Resource(SecretGVR).Namespace(testNamespace).Update(ctx, secret, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	values, err := s.Connection(ctx, "p1-sessions")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"REDIS_HOST": "p1-sessions.caches.svc", "REDIS_PORT": "6379"}, values)
+
+	_, err = s.Connection(ctx, "p1-missing")
+	assert.True(t, apierrors.IsNotFound(err))
+}
@@ -23,6 +23,9 @@ const (
 	CodeBuildFailed        Code = "BUILD_FAILED"
 	CodeDeploymentFailed   Code = "DEPLOYMENT_FAILED"
 	CodeDatabaseError      Code = "DATABASE_ERROR"
+	CodeResidencyViolation Code = "RESIDENCY_VIOLATION"
+	CodeDependencyFailed   Code = "DEPENDENCY_FAILED"
+	CodeNotImplemented     Code = "NOT_IMPLEMENTED"
 )
 
 // AppError represents an application error
@@ -110,6 +113,14 @@ var (
 		"Service temporarily unavailable",
 		http.StatusServiceUnavailable,
 	)
+	// ErrNoKubernetes is returned by the features that act on workload
+	// clusters, such as volumes, jobs and autoscaling, when the orchestrator
+	// runs without a Kubernetes client for them
+	ErrNoKubernetes = NewError(
+		CodeServiceUnavailable,
+		"No Kubernetes client for workload clusters",
+		http.StatusServiceUnavailable,
+	)
 )
 
 // NotFound creates a not found error
@@ -192,6 +203,16 @@ func DeploymentFailed(reason string) *AppError {
 	)
 }
 
+// ResidencyViolation creates an error for a placement that breaks a
+// project's data residency; details describe the violation
+func ResidencyViolation(message string, details map[string]interface{}) *AppError {
+	return NewError(
+		CodeResidencyViolation,
+		message,
+		http.StatusUnprocessableEntity,
+	).WithDetails(details)
+}
+
 // Unauthorized creates an unauthorized error
 func Unauthorized(message string) *AppError {
 	return NewError(
@@ -210,6 +231,15 @@ func BadRequest(message string) *AppError {
 	)
 }
 
+// NotImplemented creates an error for an endpoint that is not available
+func NotImplemented(message string) *AppError {
+	return NewError(
+		CodeNotImplemented,
+		message,
+		http.StatusNotImplemented,
+	)
+}
+
 // IsNotFound checks if error is a not found error
 func IsNotFound(err error) bool {
 	if appErr, ok := err.(*AppError); ok {
@@ -238,7 +268,7 @@ func Wrap(err error, message string) *AppError {
 // DependencyFailed creates a dependency failure error
 func DependencyFailed(service string, err error) *AppError {
 	return NewError(
-		CodeServiceUnavailable,
+		CodeDependencyFailed,
 		fmt.Sprintf("Dependency %s failed", service),
 		http.StatusServiceUnavailable,
 	).WithError(err)
@@ -259,11 +289,15 @@ type PlatformError struct {
 // GetPlatformError converts an error to PlatformError
 func GetPlatformError(err error) *PlatformError {
 	if appErr, ok := err.(*AppError); ok {
+		metadata, ok := appErr.Details.(map[string]interface{})
+		if !ok {
+			metadata = map[string]interface{}{}
+		}
 		return &PlatformError{
 			Code:       string(appErr.Code),
 			Message:    appErr.Message,
 			HTTPStatus: appErr.HTTPStatus,
-			Metadata:   map[string]interface{}{},
+			Metadata:   metadata,
 		}
 	}
 	return &PlatformError{
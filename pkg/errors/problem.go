@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBase prefixes the type URI of every problem; the code follows
+const ProblemTypeBase = "https://docs.northstack.io/errors/"
+
+// FieldError is a validation failure of one request field
+type FieldError struct {
+	Field   string `json:"field"`          // JSON path of the field, e.g. build_source.branch
+	Rule    string `json:"rule,omitempty"` // Failed binding rule, e.g. required
+	Message string `json:"message"`
+}
+
+// Problem is an RFC 7807 problem details body. Code is the stable,
+// machine-readable form of the error code clients should switch on.
+type Problem struct {
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	Status   int                    `json:"status"`
+	Detail   string                 `json:"detail,omitempty"`
+	Instance string                 `json:"instance,omitempty"`
+	Code     string                 `json:"code"`
+	Errors   []FieldError           `json:"errors,omitempty"`
+	Meta     map[string]interface{} `json:"meta,omitempty"`
+}
+
+// Slug returns the stable lowercase form of a code used in problem bodies,
+// e.g. not_found
+func (c Code) Slug() string {
+	return strings.ToLower(string(c))
+}
+
+// Validation creates a validation error for individual request fields
+func Validation(fields []FieldError) *AppError {
+	message := "Validation failed"
+	if len(fields) == 1 {
+		message = fields[0].Field + " " + fields[0].Message
+	}
+	return NewError(
+		CodeValidationFailed,
+		message,
+		http.StatusBadRequest,
+	).WithDetails(fields)
+}
+
+// NewProblem converts an error into problem details for the request path
+// instance. Errors that are not an AppError are internal errors.
+func NewProblem(err error, instance string) *Problem {
+	appErr, ok := err.(*AppError)
+	if !ok {
+		appErr = NewError(CodeInternalError, err.Error(), http.StatusInternalServerError)
+	}
+
+	p := &Problem{
+		Type:     ProblemTypeBase + strings.ReplaceAll(appErr.Code.Slug(), "_", "-"),
+		Title:    http.StatusText(appErr.HTTPStatus),
+		Status:   appErr.HTTPStatus,
+		Detail:   appErr.Message,
+		Instance: instance,
+		Code:     appErr.Code.Slug(),
+	}
+	switch details := appErr.Details.(type) {
+	case []FieldError:
+		p.Errors = details
+	case map[string]string:
+		// From ValidationFailed: field to message
+		for field, message := range details {
+			p.Errors = append(p.Errors, FieldError{Field: field, Message: message})
+		}
+		sort.Slice(p.Errors, func(i, j int) bool { return p.Errors[i].Field < p.Errors[j].Field })
+	case map[string]interface{}:
+		p.Meta = details
+	}
+	return p
+}
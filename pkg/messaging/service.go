@@ -0,0 +1,630 @@
+// Package messaging provisions project-scoped message brokers in a Kubernetes
+// namespace: Kafka through the Strimzi operator, or NATS with JetStream as a
+// StatefulSet. Each broker has one application user, whose credentials are in
+// a connection secret owned by the broker, and topics (Kafka) or streams
+// (JetStream) managed through this package.
+package messaging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/password"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// Kubernetes GVRs of the objects of a broker
+var (
+	KafkaGVR         = schema.GroupVersionResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkas"}
+	KafkaNodePoolGVR = schema.GroupVersionResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkanodepools"}
+	KafkaUserGVR     = schema.GroupVersionResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkausers"}
+	KafkaTopicGVR    = schema.GroupVersionResource{Group: "kafka.strimzi.io", Version: "v1beta2", Resource: "kafkatopics"}
+	StatefulSetGVR   = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+	ServiceGVR       = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	SecretGVR        = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+)
+
+// Broker engines
+const (
+	EngineKafka = "kafka"
+	EngineNATS  = "nats"
+)
+
+// Ports brokers listen on
+const (
+	KafkaPort = 9092
+	NATSPort  = 4222
+)
+
+// DefaultNATSVersion is the NATS server image tag. Kafka versions default to
+// the one of the installed Strimzi operator.
+const DefaultNATSVersion = "2.10.22-alpine"
+
+// natsUser is the user applications connect to NATS brokers as
+const natsUser = "app"
+
+// BrokerService manages Kafka and NATS brokers
+type BrokerService struct {
+	dynamic   dynamic.Interface
+	namespace string
+	kafka     bool // Strimzi is installed
+}
+
+// CreateBrokerInput holds parameters for creating a broker
+type CreateBrokerInput struct {
+	Name      string
+	ProjectID string
+	TeamID    string
+	Engine    string // kafka or nats
+	Size      string // small, medium, large
+	StorageGB int
+	Version   string
+}
+
+// BrokerInfo describes a broker
+type BrokerInfo struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	ProjectID  string    `json:"project_id"`
+	Engine     string    `json:"engine"`
+	Status     string    `json:"status"`
+	Endpoint   string    `json:"endpoint"`
+	Port       int       `json:"port"`
+	Size       string    `json:"size"`
+	SecretName string    `json:"secret_name"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ResourceConfig holds the resources of a broker for a size
+type ResourceConfig struct {
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+}
+
+// NewBrokerService creates a new broker service. Without Strimzi, only NATS
+// brokers can be created.
+func NewBrokerService(dynClient dynamic.Interface, namespace string, kafka bool) *BrokerService {
+	return &BrokerService{
+		dynamic:   dynClient,
+		namespace: namespace,
+		kafka:     kafka,
+	}
+}
+
+// KafkaAvailable reports whether Kafka brokers can be created
+func (s *BrokerService) KafkaAvailable() bool {
+	return s.kafka
+}
+
+// BrokerID returns the ID of a project's broker. Strimzi derives pod and
+// service names from it, so it is short and fixed-length rather than made of
+// the project ID and the name.
+func BrokerID(projectID, name string) string {
+	sum := sha256.Sum256([]byte(projectID + "/" + name))
+	return "brk-" + hex.EncodeToString(sum[:6])
+}
+
+// CreateBroker creates a broker, its application user and its connection
+// secret
+func (s *BrokerService) CreateBroker(ctx context.Context, input *CreateBrokerInput) (*BrokerInfo, error) {
+	engine := input.Engine
+	if engine == "" {
+		engine = EngineNATS
+	}
+	if engine == EngineKafka && !s.kafka {
+		return nil, errors.BadRequest("Kafka brokers are not available")
+	}
+	size := input.Size
+	if _, ok := sizes[size]; !ok {
+		size = "small"
+	}
+	if input.StorageGB <= 0 {
+		input.StorageGB = 10
+	}
+
+	password, err := password.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	// Kafka and NATS brokers of the same name would share the ID
+	id := BrokerID(input.ProjectID, input.Name)
+	if _, err := s.GetBroker(ctx, id); err == nil {
+		return nil, errors.Conflict("broker")
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	labels := map[string]interface{}{
+		"northstack.io/project": input.ProjectID,
+		"northstack.io/team":    input.TeamID,
+		"northstack.io/type":    "broker",
+		"northstack.io/engine":  engine,
+		"northstack.io/broker":  id,
+	}
+	if engine == EngineKafka {
+		err = s.createKafka(ctx, id, size, password, input, labels)
+	} else {
+		err = s.createNATS(ctx, id, size, password, input, labels)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.GetBroker(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	info.Status = "creating"
+	return info, nil
+}
+
+// createKafka creates a single-node KRaft Kafka cluster. The node pool, the
+// application user and the connection secret are owned by the Kafka resource.
+func (s *BrokerService) createKafka(ctx context.Context, id, size, password string, input *CreateBrokerInput, labels map[string]interface{}) error {
+	kafkaSpec := map[string]interface{}{
+		"listeners": []interface{}{map[string]interface{}{
+			"name":           "plain",
+			"port":           int64(KafkaPort),
+			"type":           "internal",
+			"tls":            false,
+			"authentication": map[string]interface{}{"type": "scram-sha-512"},
+		}},
+		"authorization": map[string]interface{}{"type": "simple"},
+		// A single node cannot replicate
+		"config": map[string]interface{}{
+			"offsets.topic.replication.factor":         int64(1),
+			"transaction.state.log.replication.factor": int64(1),
+			"transaction.state.log.min.isr":            int64(1),
+			"default.replication.factor":               int64(1),
+			"min.insync.replicas":                      int64(1),
+		},
+	}
+	if input.Version != "" {
+		kafkaSpec["version"] = input.Version
+	}
+	kafka := object("kafka.strimzi.io/v1beta2", "Kafka", id, s.namespace, labels, nil, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"kafka": kafkaSpec,
+			"entityOperator": map[string]interface{}{
+				"topicOperator": map[string]interface{}{},
+				"userOperator":  map[string]interface{}{},
+			},
+		},
+	})
+	kafka.SetAnnotations(map[string]string{
+		"strimzi.io/node-pools": "enabled",
+		"strimzi.io/kraft":      "enabled",
+		"northstack.io/name":    input.Name,
+		"northstack.io/size":    size,
+	})
+	created, err := s.dynamic.Resource(KafkaGVR).Namespace(s.namespace).Create(ctx, kafka, metav1.CreateOptions{})
+	if err != nil {
+		return kubeError(err, "broker", input.Name)
+	}
+	owner := ownerReference("kafka.strimzi.io/v1beta2", "Kafka", created)
+
+	resources := sizes[size]
+	clusterLabels := withLabel(labels, "strimzi.io/cluster", id)
+	host := fmt.Sprintf("%s-kafka-bootstrap.%s.svc", id, s.namespace)
+	username := id + "-app"
+	objects := []owned{
+		{SecretGVR, object("v1", "Secret", id+"-connection", s.namespace, labels, owner, map[string]interface{}{
+			"type": "Opaque",
+			"stringData": map[string]interface{}{
+				"KAFKA_BOOTSTRAP_SERVERS": fmt.Sprintf("%s:%d", host, KafkaPort),
+				"KAFKA_SECURITY_PROTOCOL": "SASL_PLAINTEXT",
+				"KAFKA_SASL_MECHANISM":    "SCRAM-SHA-512",
+				"KAFKA_USERNAME":          username,
+				"KAFKA_PASSWORD":          password,
+			},
+		})},
+		{KafkaNodePoolGVR, object("kafka.strimzi.io/v1beta2", "KafkaNodePool", id+"-nodes", s.namespace, clusterLabels, owner, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": int64(1),
+				"roles":    []interface{}{"controller", "broker"},
+				"storage": map[string]interface{}{
+					"type": "jbod",
+					"volumes": []interface{}{map[string]interface{}{
+						"id":            int64(0),
+						"type":          "persistent-claim",
+						"size":          fmt.Sprintf("%dGi", input.StorageGB),
+						"deleteClaim":   true,
+						"kraftMetadata": "shared",
+					}},
+				},
+				"resources": resources.object(),
+			},
+		})},
+		// The user may use every topic, consumer group and transaction of the
+		// broker; its password comes from the connection secret
+		{KafkaUserGVR, object("kafka.strimzi.io/v1beta2", "KafkaUser", username, s.namespace, clusterLabels, owner, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"authentication": map[string]interface{}{
+					"type": "scram-sha-512",
+					"password": map[string]interface{}{
+						"valueFrom": map[string]interface{}{
+							"secretKeyRef": map[string]interface{}{"name": id + "-connection", "key": "KAFKA_PASSWORD"},
+						},
+					},
+				},
+				"authorization": map[string]interface{}{
+					"type": "simple",
+					"acls": []interface{}{
+						allowAll("topic"),
+						allowAll("group"),
+						allowAll("transactionalId"),
+					},
+				},
+			},
+		})},
+	}
+	return s.createOwned(ctx, id, objects)
+}
+
+// createNATS creates a single NATS server with JetStream storing streams on a
+// volume
+func (s *BrokerService) createNATS(ctx context.Context, id, size, password string, input *CreateBrokerInput, labels map[string]interface{}) error {
+	version := input.Version
+	if version == "" {
+		version = DefaultNATSVersion
+	}
+	container := map[string]interface{}{
+		"name":  "nats",
+		"image": "nats:" + version,
+		"args": []interface{}{
+			"--jetstream", "--store_dir=/data",
+			fmt.Sprintf("--port=%d", NATSPort), "--http_port=8222",
+			"--user=" + natsUser, "--pass=$(NATS_PASSWORD)",
+		},
+		"env": []interface{}{map[string]interface{}{
+			"name": "NATS_PASSWORD",
+			"valueFrom": map[string]interface{}{
+				"secretKeyRef": map[string]interface{}{"name": id + "-connection", "key": "NATS_PASSWORD"},
+			},
+		}},
+		"ports": []interface{}{
+			map[string]interface{}{"name": "client", "containerPort": int64(NATSPort)},
+			map[string]interface{}{"name": "monitor", "containerPort": int64(8222)},
+		},
+		"resources": sizes[size].object(),
+		"readinessProbe": map[string]interface{}{
+			"httpGet": map[string]interface{}{
+				"path": "/healthz?js-enabled-only=true",
+				"port": int64(8222),
+			},
+			"periodSeconds": int64(5),
+		},
+		"volumeMounts": []interface{}{map[string]interface{}{"name": "data", "mountPath": "/data"}},
+	}
+	statefulSet := object("apps/v1", "StatefulSet", id, s.namespace, labels, nil, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"serviceName": id,
+			"replicas":    int64(1),
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"northstack.io/broker": id},
+			},
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec": map[string]interface{}{
+					"containers": []interface{}{container},
+				},
+			},
+			"volumeClaimTemplates": []interface{}{map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "data"},
+				"spec": map[string]interface{}{
+					"accessModes": []interface{}{"ReadWriteOnce"},
+					"resources": map[string]interface{}{
+						"requests": map[string]interface{}{"storage": fmt.Sprintf("%dGi", input.StorageGB)},
+					},
+				},
+			}},
+			"persistentVolumeClaimRetentionPolicy": map[string]interface{}{"whenDeleted": "Delete"},
+		},
+	})
+	statefulSet.SetAnnotations(map[string]string{
+		"northstack.io/name": input.Name,
+		"northstack.io/size": size,
+	})
+	created, err := s.dynamic.Resource(StatefulSetGVR).Namespace(s.namespace).Create(ctx, statefulSet, metav1.CreateOptions{})
+	if err != nil {
+		return kubeError(err, "broker", input.Name)
+	}
+	owner := ownerReference("apps/v1", "StatefulSet", created)
+
+	host := fmt.Sprintf("%s.%s.svc", id, s.namespace)
+	objects := []owned{
+		{SecretGVR, object("v1", "Secret", id+"-connection", s.namespace, labels, owner, map[string]interface{}{
+			"type": "Opaque",
+			"stringData": map[string]interface{}{
+				"NATS_URL":      fmt.Sprintf("nats://%s:%d", host, NATSPort),
+				"NATS_USER":     natsUser,
+				"NATS_PASSWORD": password,
+			},
+		})},
+		{ServiceGVR, object("v1", "Service", id, s.namespace, labels, owner, map[string]interface{}{
+			"spec": map[string]interface{}{
+				"selector": map[string]interface{}{"northstack.io/broker": id},
+				"ports": []interface{}{map[string]interface{}{
+					"name":       "client",
+					"port":       int64(NATSPort),
+					"targetPort": int64(NATSPort),
+				}},
+			},
+		})},
+	}
+	return s.createOwned(ctx, id, objects)
+}
+
+// owned is an object owned by a broker's main resource
+type owned struct {
+	gvr schema.GroupVersionResource
+	obj *unstructured.Unstructured
+}
+
+// createOwned creates the objects of a broker, deleting the broker if one
+// fails
+func (s *BrokerService) createOwned(ctx context.Context, id string, objects []owned) error {
+	for _, o := range objects {
+		if _, err := s.dynamic.Resource(o.gvr).Namespace(s.namespace).Create(ctx, o.obj, metav1.CreateOptions{}); err != nil {
+			// The objects created so far go with the broker
+			_ = s.DeleteBroker(ctx, id)
+			return errors.DependencyFailed("kubernetes", fmt.Errorf("failed to create broker %s: %w", o.obj.GetKind(), err))
+		}
+	}
+	return nil
+}
+
+// GetBroker retrieves broker information
+func (s *BrokerService) GetBroker(ctx context.Context, id string) (*BrokerInfo, error) {
+	statefulSet, err := s.dynamic.Resource(StatefulSetGVR).Namespace(s.namespace).Get(ctx, id, metav1.GetOptions{})
+	if err == nil && statefulSet.GetLabels()["northstack.io/type"] == "broker" {
+		return s.natsInfo(statefulSet), nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	if !s.kafka {
+		return nil, errors.NotFound("broker", id)
+	}
+
+	kafka, err := s.dynamic.Resource(KafkaGVR).Namespace(s.namespace).Get(ctx, id, metav1.GetOptions{})
+	if err != nil {
+		return nil, kubeError(err, "broker", id)
+	}
+	if kafka.GetLabels()["northstack.io/type"] != "broker" {
+		return nil, errors.NotFound("broker", id)
+	}
+	return s.kafkaInfo(kafka), nil
+}
+
+// ListBrokers lists the brokers of a project, or all of them
+func (s *BrokerService) ListBrokers(ctx context.Context, projectID string) ([]*BrokerInfo, error) {
+	opts := metav1.ListOptions{LabelSelector: "northstack.io/type=broker"}
+	if projectID != "" {
+		opts.LabelSelector += ",northstack.io/project=" + projectID
+	}
+
+	statefulSets, err := s.dynamic.Resource(StatefulSetGVR).Namespace(s.namespace).List(ctx, opts)
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	brokers := make([]*BrokerInfo, 0, len(statefulSets.Items))
+	for i := range statefulSets.Items {
+		brokers = append(brokers, s.natsInfo(&statefulSets.Items[i]))
+	}
+
+	if s.kafka {
+		kafkas, err := s.dynamic.Resource(KafkaGVR).Namespace(s.namespace).List(ctx, opts)
+		if err != nil {
+			return nil, errors.DependencyFailed("kubernetes", err)
+		}
+		for i := range kafkas.Items {
+			brokers = append(brokers, s.kafkaInfo(&kafkas.Items[i]))
+		}
+	}
+	return brokers, nil
+}
+
+// DeleteBroker deletes a broker with its topics, user, secrets and volumes
+func (s *BrokerService) DeleteBroker(ctx context.Context, id string) error {
+	broker, err := s.GetBroker(ctx, id)
+	if err != nil {
+		return err
+	}
+	propagation := metav1.DeletePropagationBackground
+	opts := metav1.DeleteOptions{PropagationPolicy: &propagation}
+
+	if broker.Engine != EngineKafka {
+		return kubeError(s.dynamic.Resource(StatefulSetGVR).Namespace(s.namespace).Delete(ctx, id, opts), "broker", id)
+	}
+	// Topics go first, while the topic operator that finalizes them still runs
+	err = s.dynamic.Resource(KafkaTopicGVR).Namespace(s.namespace).DeleteCollection(ctx, opts, metav1.ListOptions{
+		LabelSelector: "northstack.io/broker=" + id,
+	})
+	if err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return kubeError(s.dynamic.Resource(KafkaGVR).Namespace(s.namespace).Delete(ctx, id, opts), "broker", id)
+}
+
+// Connection returns the values of a broker's connection secret:
+// KAFKA_BOOTSTRAP_SERVERS, KAFKA_SECURITY_PROTOCOL, KAFKA_SASL_MECHANISM,
+// KAFKA_USERNAME and KAFKA_PASSWORD for Kafka, NATS_URL, NATS_USER and
+// NATS_PASSWORD for NATS
+func (s *BrokerService) Connection(ctx context.Context, id string) (map[string]string, error) {
+	if _, err := s.GetBroker(ctx, id); err != nil {
+		return nil, err
+	}
+	secret, err := s.dynamic.Resource(SecretGVR).Namespace(s.namespace).Get(ctx, id+"-connection", metav1.GetOptions{})
+	if err != nil {
+		return nil, kubeError(err, "broker connection", id)
+	}
+
+	data, _, _ := unstructured.NestedStringMap(secret.Object, "data")
+	values := make(map[string]string, len(data))
+	for key, encoded := range data {
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.Internal("invalid broker connection secret")
+		}
+		values[key] = string(value)
+	}
+	return values, nil
+}
+
+func (s *BrokerService) natsInfo(statefulSet *unstructured.Unstructured) *BrokerInfo {
+	info := s.info(statefulSet)
+	info.Endpoint = fmt.Sprintf("%s.%s.svc:%d", info.ID, s.namespace, NATSPort)
+	info.Port = NATSPort
+
+	ready, _, _ := unstructured.NestedInt64(statefulSet.Object, "status", "readyReplicas")
+	if ready > 0 {
+		info.Status = "ready"
+	}
+	return info
+}
+
+func (s *BrokerService) kafkaInfo(kafka *unstructured.Unstructured) *BrokerInfo {
+	info := s.info(kafka)
+	info.Endpoint = fmt.Sprintf("%s-kafka-bootstrap.%s.svc:%d", info.ID, s.namespace, KafkaPort)
+	info.Port = KafkaPort
+	if conditionTrue(kafka, "Ready") {
+		info.Status = "ready"
+	}
+	return info
+}
+
+func (s *BrokerService) info(obj *unstructured.Unstructured) *BrokerInfo {
+	labels := obj.GetLabels()
+	annotations := obj.GetAnnotations()
+	return &BrokerInfo{
+		ID:         obj.GetName(),
+		Name:       annotations["northstack.io/name"],
+		ProjectID:  labels["northstack.io/project"],
+		Engine:     labels["northstack.io/engine"],
+		Status:     "creating",
+		Size:       annotations["northstack.io/size"],
+		SecretName: obj.GetName() + "-connection",
+		CreatedAt:  obj.GetCreationTimestamp().Time,
+	}
+}
+
+// conditionTrue reports whether a Strimzi resource has a condition with
+// status True
+func conditionTrue(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == conditionType && condition["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+func object(apiVersion, kind, name, namespace string, labels map[string]interface{}, owner []interface{}, fields map[string]interface{}) *unstructured.Unstructured {
+	metadata := map[string]interface{}{
+		"name":      name,
+		"namespace": namespace,
+		"labels":    labels,
+	}
+	if owner != nil {
+		metadata["ownerReferences"] = owner
+	}
+	obj := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   metadata,
+	}
+	for key, value := range fields {
+		obj[key] = value
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+func ownerReference(apiVersion, kind string, owner *unstructured.Unstructured) []interface{} {
+	return []interface{}{map[string]interface{}{
+		"apiVersion":         apiVersion,
+		"kind":               kind,
+		"name":               owner.GetName(),
+		"uid":                string(owner.GetUID()),
+		"controller":         true,
+		"blockOwnerDeletion": true,
+	}}
+}
+
+// withLabel returns a copy of labels with one more
+func withLabel(labels map[string]interface{}, key, value string) map[string]interface{} {
+	copied := make(map[string]interface{}, len(labels)+1)
+	for k, v := range labels {
+		copied[k] = v
+	}
+	copied[key] = value
+	return copied
+}
+
+// allowAll is a Strimzi ACL rule allowing every operation on every resource
+// of a type
+func allowAll(resourceType string) interface{} {
+	return map[string]interface{}{
+		"resource": map[string]interface{}{
+			"type":        resourceType,
+			"name":        "*",
+			"patternType": "literal",
+		},
+		"operations": []interface{}{"All"},
+	}
+}
+
+// kubeError maps a Kubernetes API error to an AppError
+func kubeError(err error, resource, id string) error {
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsNotFound(err):
+		return errors.NotFound(resource, id)
+	case apierrors.IsAlreadyExists(err):
+		return errors.Conflict(resource)
+	}
+	return errors.DependencyFailed("kubernetes", err)
+}
+
+// sizes are the resources of each size preset
+var sizes = map[string]ResourceConfig{
+	"small": {
+		CPURequest:    "250m",
+		CPULimit:      "1",
+		MemoryRequest: "1Gi",
+		MemoryLimit:   "1Gi",
+	},
+	"medium": {
+		CPURequest:    "500m",
+		CPULimit:      "2",
+		MemoryRequest: "2Gi",
+		MemoryLimit:   "2Gi",
+	},
+	"large": {
+		CPURequest:    "1",
+		CPULimit:      "4",
+		MemoryRequest: "4Gi",
+		MemoryLimit:   "4Gi",
+	},
+}
+
+func (r ResourceConfig) object() map[string]interface{} {
+	return map[string]interface{}{
+		"requests": map[string]interface{}{"cpu": r.CPURequest, "memory": r.MemoryRequest},
+		"limits":   map[string]interface{}{"cpu": r.CPULimit, "memory": r.MemoryLimit},
+	}
+}
@@ -0,0 +1,199 @@
+package messaging
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+const testNamespace = "brokers"
+
+func newTestService(kafka bool) *BrokerService {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		KafkaGVR:         "KafkaList",
+		KafkaNodePoolGVR: "KafkaNodePoolList",
+		KafkaUserGVR:     "KafkaUserList",
+		KafkaTopicGVR:    "KafkaTopicList",
+		StatefulSetGVR:   "StatefulSetList",
+		ServiceGVR:       "ServiceList",
+		SecretGVR:        "SecretList",
+	})
+	return NewBrokerService(client, testNamespace, kafka)
+}
+
+func get(t *testing.T, s *BrokerService, gvr schema.GroupVersionResource, name string) *unstructured.Unstructured {
+	obj, err := s.dynamic.Resource(gvr).Namespace(testNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return obj
+}
+
+func TestBrokerID(t *testing.T) {
+	id := BrokerID("p1", "events")
+	assert.Regexp(t, `^brk-[0-9a-f]{12}$`, id)
+	assert.Equal(t, id, BrokerID("p1", "events"))
+	assert.NotEqual(t, id, BrokerID("p2", "events"))
+	// The separator keeps the parts apart
+	assert.NotEqual(t, BrokerID("p1", "1events"), BrokerID("p11", "events"))
+}
+
+func TestCreateNATSBroker(t *testing.T) {
+	s := newTestService(false)
+	ctx := context.Background()
+
+	info, err := s.CreateBroker(ctx, &CreateBrokerInput{Name: "events", ProjectID: "p1", Size: "medium"})
+	require.NoError(t, err)
+
+	id := BrokerID("p1", "events")
+	assert.Equal(t, id, info.ID)
+	assert.Equal(t, "events", info.Name)
+	assert.Equal(t, EngineNATS, info.Engine)
+	assert.Equal(t, "medium", info.Size)
+	assert.Equal(t, "creating", info.Status)
+	assert.Equal(t, id+".brokers.svc:4222", info.Endpoint)
+
+	secret := get(t, s, SecretGVR, id+"-connection")
+	values, _, _ := unstructured.NestedStringMap(secret.Object, "stringData")
+	assert.Equal(t, "nats://"+id+".brokers.svc:4222", values["NATS_URL"])
+	assert.Equal(t, natsUser, values["NATS_USER"])
+	assert.Len(t, values["NATS_PASSWORD"], 48)
+	assert.Len(t, secret.GetOwnerReferences(), 1)
+
+	statefulSet := get(t, s, StatefulSetGVR, id)
+	claims, _, _ := unstructured.NestedSlice(statefulSet.Object, "spec", "volumeClaimTemplates")
+	require.Len(t, claims, 1)
+	storage, _, _ := unstructured.NestedString(claims[0].(map[string]interface{}), "spec", "resources", "requests", "storage")
+	assert.Equal(t, "10Gi", storage, "storage defaults to 10 GB")
+
+	_, err = s.CreateBroker(ctx, &CreateBrokerInput{Name: "events", ProjectID: "p1"})
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusConflict, appErr.HTTPStatus)
+}
+
+func TestCreateKafkaBroker(t *testing.T) {
+	_, err := newTestService(false).CreateBroker(context.Background(), &CreateBrokerInput{Name: "events", ProjectID: "p1", Engine: EngineKafka})
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.HTTPStatus, "Kafka needs Strimzi")
+
+	s := newTestService(true)
+	info, err := s.CreateBroker(context.Background(), &CreateBrokerInput{Name: "events", ProjectID: "p1", Engine: EngineKafka, StorageGB: 50, Version: "3.8.0"})
+	require.NoError(t, err)
+
+	id := info.ID
+	assert.Equal(t, EngineKafka, info.Engine)
+	assert.Equal(t, id+"-kafka-bootstrap.brokers.svc:9092", info.Endpoint)
+
+	kafka := get(t, s, KafkaGVR, id)
+	version, _, _ := unstructured.NestedString(kafka.Object, "spec", "kafka", "version")
+	assert.Equal(t, "3.8.0", version)
+	assert.Equal(t, "enabled", kafka.GetAnnotations()["strimzi.io/kraft"])
+
+	pool := get(t, s, KafkaNodePoolGVR, id+"-nodes")
+	assert.Equal(t, id, pool.GetLabels()["strimzi.io/cluster"])
+	volumes, _, _ := unstructured.NestedSlice(pool.Object, "spec", "storage", "volumes")
+	require.Len(t, volumes, 1)
+	assert.Equal(t, "50Gi", volumes[0].(map[string]interface{})["size"])
+
+	user := get(t, s, KafkaUserGVR, id+"-app")
+	acls, _, _ := unstructured.NestedSlice(user.Object, "spec", "authorization", "acls")
+	assert.Equal(t, []interface{}{allowAll("topic"), allowAll("group"), allowAll("transactionalId")}, acls)
+	passwordKey, _, _ := unstructured.NestedString(user.Object, "spec", "authentication", "password", "valueFrom", "secretKeyRef", "key")
+	assert.Equal(t, "KAFKA_PASSWORD", passwordKey)
+
+	secret := get(t, s, SecretGVR, id+"-connection")
+	username, _, _ := unstructured.NestedString(secret.Object, "stringData", "KAFKA_USERNAME")
+	assert.Equal(t, id+"-app", username)
+}
+
+func TestListAndDeleteBrokers(t *testing.T) {
+	s := newTestService(true)
+	ctx := context.Background()
+	for _, input := range []*CreateBrokerInput{
+		{Name: "events", ProjectID: "p1"},
+		{Name: "stream", ProjectID: "p1", Engine: EngineKafka},
+		{Name: "events", ProjectID: "p2"},
+	} {
+		_, err := s.CreateBroker(ctx, input)
+		require.NoError(t, err)
+	}
+
+	brokers, err := s.ListBrokers(ctx, "p1")
+	require.NoError(t, err)
+	assert.Len(t, brokers, 2)
+	brokers, err = s.ListBrokers(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, brokers, 3)
+
+	require.NoError(t, s.DeleteBroker(ctx, BrokerID("p1", "events")))
+	require.NoError(t, s.DeleteBroker(ctx, BrokerID("p1", "stream")))
+	assert.True(t, errors.IsNotFound(s.DeleteBroker(ctx, BrokerID("p1", "stream"))))
+
+	brokers, err = s.ListBrokers(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, brokers, 1)
+}
+
+func TestConnection(t *testing.T) {
+	s := newTestService(false)
+	ctx := context.Background()
+	info, err := s.CreateBroker(ctx, &CreateBrokerInput{Name: "events", ProjectID: "p1"})
+	require.NoError(t, err)
+
+	// The API server moves stringData into data
+	secret := get(t, s, SecretGVR, info.SecretName)
+	require.NoError(t, unstructured.SetNestedStringMap(secret.Object, map[string]string{
+		"NATS_USER": base64.StdEncoding.EncodeToString([]byte(natsUser)),
+	}, "data"))
+	_, err = s.dynamic.Resource(SecretGVR).Namespace(testNamespace).Update(ctx, secret, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	values, err := s.Connection(ctx, info.ID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"NATS_USER": natsUser}, values)
+
+	_, err = s.Connection(ctx, "brk-000000000000")
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestBrokerStatus(t *testing.T) {
+	s := newTestService(true)
+
+	kafka := object("kafka.strimzi.io/v1beta2", "Kafka", "brk-1", testNamespace, nil, nil, map[string]interface{}{
+		"status": map[string]interface{}{"conditions": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "False"},
+		}},
+	})
+	assert.Equal(t, "creating", s.kafkaInfo(kafka).Status)
+	require.NoError(t, unstructured.SetNestedSlice(kafka.Object, []interface{}{
+		map[string]interface{}{"type": "Ready", "status": "True"},
+	}, "status", "conditions"))
+	assert.Equal(t, "ready", s.kafkaInfo(kafka).Status)
+
+	statefulSet := object("apps/v1", "StatefulSet", "brk-2", testNamespace, nil, nil, nil)
+	assert.Equal(t, "creating", s.natsInfo(statefulSet).Status)
+	require.NoError(t, unstructured.SetNestedField(statefulSet.Object, int64(1), "status", "readyReplicas"))
+	assert.Equal(t, "ready", s.natsInfo(statefulSet).Status)
+}
+
+func TestKubeError(t *testing.T) {
+	assert.NoError(t, kubeError(nil, "broker", "brk-1"))
+	assert.True(t, errors.IsNotFound(kubeError(apierrors.NewNotFound(KafkaGVR.GroupResource(), "brk-1"), "broker", "brk-1")))
+
+	var appErr *errors.AppError
+	require.ErrorAs(t, kubeError(apierrors.NewAlreadyExists(KafkaGVR.GroupResource(), "brk-1"), "broker", "brk-1"), &appErr)
+	assert.Equal(t, http.StatusConflict, appErr.HTTPStatus)
+	require.ErrorAs(t, kubeError(apierrors.NewServiceUnavailable("down"), "broker", "brk-1"), &appErr)
+	assert.Equal(t, errors.CodeDependencyFailed, appErr.Code)
+}
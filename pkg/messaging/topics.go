@@ -0,0 +1,246 @@
+package messaging
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/northstack/platform/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Topic is a Kafka topic or a JetStream stream of a broker
+type Topic struct {
+	Name           string   `json:"name"`
+	Partitions     int      `json:"partitions,omitempty"` // Kafka
+	Subjects       []string `json:"subjects,omitempty"`   // JetStream
+	RetentionHours int      `json:"retention_hours"`      // 0 for the engine default
+	Messages       uint64   `json:"messages,omitempty"`   // JetStream
+	Status         string   `json:"status"`
+}
+
+// CreateTopicInput holds parameters for creating a topic or stream
+type CreateTopicInput struct {
+	Name           string
+	Partitions     int      // Kafka; defaults to 1
+	Subjects       []string // JetStream; defaults to the name and name.>
+	RetentionHours int      // 0 keeps Kafka's 7 days and JetStream messages until deleted
+}
+
+// CreateTopic creates a Kafka topic through the Strimzi topic operator, or a
+// JetStream stream
+func (s *BrokerService) CreateTopic(ctx context.Context, brokerID string, input *CreateTopicInput) (*Topic, error) {
+	broker, err := s.GetBroker(ctx, brokerID)
+	if err != nil {
+		return nil, err
+	}
+	if broker.Engine == EngineKafka {
+		return s.createKafkaTopic(ctx, broker, input)
+	}
+	return s.createStream(ctx, broker, input)
+}
+
+// ListTopics lists the topics or streams of a broker
+func (s *BrokerService) ListTopics(ctx context.Context, brokerID string) ([]*Topic, error) {
+	broker, err := s.GetBroker(ctx, brokerID)
+	if err != nil {
+		return nil, err
+	}
+	if broker.Engine == EngineKafka {
+		return s.listKafkaTopics(ctx, broker)
+	}
+	return s.listStreams(ctx, broker)
+}
+
+// DeleteTopic deletes a topic or stream with its messages
+func (s *BrokerService) DeleteTopic(ctx context.Context, brokerID, name string) error {
+	broker, err := s.GetBroker(ctx, brokerID)
+	if err != nil {
+		return err
+	}
+	if broker.Engine == EngineKafka {
+		err = s.dynamic.Resource(KafkaTopicGVR).Namespace(s.namespace).Delete(ctx, kafkaTopicName(broker.ID, name), metav1.DeleteOptions{})
+		return kubeError(err, "topic", name)
+	}
+
+	nc, js, err := s.jetStream(ctx, broker)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+	return streamError(js.DeleteStream(name, nats.Context(ctx)), name)
+}
+
+func (s *BrokerService) createKafkaTopic(ctx context.Context, broker *BrokerInfo, input *CreateTopicInput) (*Topic, error) {
+	partitions := input.Partitions
+	if partitions <= 0 {
+		partitions = 1
+	}
+	config := map[string]interface{}{}
+	if input.RetentionHours > 0 {
+		config["retention.ms"] = strconv.FormatInt(int64(time.Duration(input.RetentionHours)*time.Hour/time.Millisecond), 10)
+	}
+
+	labels := map[string]interface{}{
+		"strimzi.io/cluster":    broker.ID,
+		"northstack.io/project": broker.ProjectID,
+		"northstack.io/broker":  broker.ID,
+	}
+	topic := object("kafka.strimzi.io/v1beta2", "KafkaTopic", kafkaTopicName(broker.ID, input.Name), s.namespace, labels, nil, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"topicName":  input.Name,
+			"partitions": int64(partitions),
+			"replicas":   int64(1),
+			"config":     config,
+		},
+	})
+	created, err := s.dynamic.Resource(KafkaTopicGVR).Namespace(s.namespace).Create(ctx, topic, metav1.CreateOptions{})
+	if err != nil {
+		return nil, kubeError(err, "topic", input.Name)
+	}
+
+	info := kafkaTopicInfo(created)
+	info.Status = "creating"
+	return info, nil
+}
+
+func (s *BrokerService) listKafkaTopics(ctx context.Context, broker *BrokerInfo) ([]*Topic, error) {
+	list, err := s.dynamic.Resource(KafkaTopicGVR).Namespace(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "northstack.io/broker=" + broker.ID,
+	})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	topics := make([]*Topic, 0, len(list.Items))
+	for i := range list.Items {
+		topics = append(topics, kafkaTopicInfo(&list.Items[i]))
+	}
+	return topics, nil
+}
+
+func (s *BrokerService) createStream(ctx context.Context, broker *BrokerInfo, input *CreateTopicInput) (*Topic, error) {
+	if strings.ContainsAny(input.Name, ".*> ") {
+		return nil, errors.BadRequest("stream names cannot contain '.', '*', '>' or spaces")
+	}
+	subjects := input.Subjects
+	if len(subjects) == 0 {
+		subjects = []string{input.Name, input.Name + ".>"}
+	}
+
+	nc, js, err := s.jetStream(ctx, broker)
+	if err != nil {
+		return nil, err
+	}
+	defer nc.Close()
+
+	// Adding a stream with the configuration of an existing one succeeds
+	if _, err := js.StreamInfo(input.Name, nats.Context(ctx)); err == nil {
+		return nil, errors.Conflict("topic")
+	}
+	info, err := js.AddStream(&nats.StreamConfig{
+		Name:     input.Name,
+		Subjects: subjects,
+		MaxAge:   time.Duration(input.RetentionHours) * time.Hour,
+		Storage:  nats.FileStorage,
+		Replicas: 1,
+	}, nats.Context(ctx))
+	if err != nil {
+		return nil, streamError(err, input.Name)
+	}
+	return streamInfo(info), nil
+}
+
+func (s *BrokerService) listStreams(ctx context.Context, broker *BrokerInfo) ([]*Topic, error) {
+	nc, js, err := s.jetStream(ctx, broker)
+	if err != nil {
+		return nil, err
+	}
+	defer nc.Close()
+
+	topics := []*Topic{}
+	for info := range js.Streams(nats.Context(ctx)) {
+		topics = append(topics, streamInfo(info))
+	}
+	return topics, nil
+}
+
+// jetStream connects to a NATS broker as its application user
+func (s *BrokerService) jetStream(ctx context.Context, broker *BrokerInfo) (*nats.Conn, nats.JetStreamContext, error) {
+	values, err := s.Connection(ctx, broker.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	nc, err := nats.Connect(values["NATS_URL"],
+		nats.UserInfo(values["NATS_USER"], values["NATS_PASSWORD"]),
+		nats.Name("northstack-orchestrator"),
+		nats.Timeout(5*time.Second),
+		nats.NoReconnect(),
+	)
+	if err != nil {
+		return nil, nil, errors.NewError(errors.CodeServiceUnavailable, "the broker is not ready yet", http.StatusServiceUnavailable).WithError(err)
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, nil, errors.DependencyFailed("nats", err)
+	}
+	return nc, js, nil
+}
+
+// streamError maps a JetStream API error to an AppError
+func streamError(err error, name string) error {
+	var apiErr *nats.APIError
+	switch {
+	case err == nil:
+		return nil
+	case stderrors.Is(err, nats.ErrStreamNotFound):
+		return errors.NotFound("topic", name)
+	case stderrors.Is(err, nats.ErrStreamNameAlreadyInUse):
+		return errors.Conflict("topic")
+	case stderrors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest:
+		return errors.BadRequest(apiErr.Description)
+	}
+	return errors.DependencyFailed("nats", err)
+}
+
+func kafkaTopicInfo(topic *unstructured.Unstructured) *Topic {
+	name, _, _ := unstructured.NestedString(topic.Object, "spec", "topicName")
+	partitions, _, _ := unstructured.NestedInt64(topic.Object, "spec", "partitions")
+	info := &Topic{
+		Name:       name,
+		Partitions: int(partitions),
+		Status:     "creating",
+	}
+	if retention, _, _ := unstructured.NestedString(topic.Object, "spec", "config", "retention.ms"); retention != "" {
+		if ms, err := strconv.ParseInt(retention, 10, 64); err == nil {
+			info.RetentionHours = int(time.Duration(ms) * time.Millisecond / time.Hour)
+		}
+	}
+	if conditionTrue(topic, "Ready") {
+		info.Status = "ready"
+	}
+	return info
+}
+
+func streamInfo(info *nats.StreamInfo) *Topic {
+	return &Topic{
+		Name:           info.Config.Name,
+		Subjects:       info.Config.Subjects,
+		RetentionHours: int(info.Config.MaxAge / time.Hour),
+		Messages:       info.State.Msgs,
+		Status:         "ready",
+	}
+}
+
+// kafkaTopicName is the name of the KafkaTopic resource of a topic. Topic
+// names are valid resource names, but not unique across brokers.
+func kafkaTopicName(brokerID, topic string) string {
+	return fmt.Sprintf("%s-%s", brokerID, topic)
+}
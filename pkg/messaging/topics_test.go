@@ -0,0 +1,76 @@
+package messaging
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestKafkaTopics(t *testing.T) {
+	s := newTestService(true)
+	ctx := context.Background()
+	broker, err := s.CreateBroker(ctx, &CreateBrokerInput{Name: "stream", ProjectID: "p1", Engine: EngineKafka})
+	require.NoError(t, err)
+
+	topic, err := s.CreateTopic(ctx, broker.ID, &CreateTopicInput{Name: "orders", RetentionHours: 48})
+	require.NoError(t, err)
+	assert.Equal(t, &Topic{Name: "orders", Partitions: 1, RetentionHours: 48, Status: "creating"}, topic)
+
+	resource := get(t, s, KafkaTopicGVR, kafkaTopicName(broker.ID, "orders"))
+	assert.Equal(t, broker.ID, resource.GetLabels()["strimzi.io/cluster"])
+	retention, _, _ := unstructured.NestedString(resource.Object, "spec", "config", "retention.ms")
+	assert.Equal(t, "172800000", retention)
+
+	_, err = s.CreateTopic(ctx, broker.ID, &CreateTopicInput{Name: "orders"})
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusConflict, appErr.HTTPStatus)
+
+	_, err = s.CreateTopic(ctx, broker.ID, &CreateTopicInput{Name: "payments", Partitions: 6})
+	require.NoError(t, err)
+	topics, err := s.ListTopics(ctx, broker.ID)
+	require.NoError(t, err)
+	assert.Len(t, topics, 2)
+
+	require.NoError(t, s.DeleteTopic(ctx, broker.ID, "orders"))
+	assert.True(t, errors.IsNotFound(s.DeleteTopic(ctx, broker.ID, "orders")))
+}
+
+func TestCreateStreamRejectsSubjectTokens(t *testing.T) {
+	s := newTestService(false)
+	for _, name := range []string{"orders.created", "orders*", "orders>", "new orders"} {
+		_, err := s.createStream(context.Background(), &BrokerInfo{ID: "brk-1", Engine: EngineNATS}, &CreateTopicInput{Name: name})
+		var appErr *errors.AppError
+		require.ErrorAs(t, err, &appErr, name)
+		assert.Equal(t, http.StatusBadRequest, appErr.HTTPStatus, name)
+	}
+}
+
+func TestStreamError(t *testing.T) {
+	assert.NoError(t, streamError(nil, "orders"))
+	assert.True(t, errors.IsNotFound(streamError(nats.ErrStreamNotFound, "orders")))
+
+	var appErr *errors.AppError
+	require.ErrorAs(t, streamError(nats.ErrStreamNameAlreadyInUse, "orders"), &appErr)
+	assert.Equal(t, http.StatusConflict, appErr.HTTPStatus)
+	require.ErrorAs(t, streamError(&nats.APIError{Code: http.StatusBadRequest, Description: "subjects overlap"}, "orders"), &appErr)
+	assert.Equal(t, http.StatusBadRequest, appErr.HTTPStatus)
+	assert.Equal(t, "subjects overlap", appErr.Message)
+	require.ErrorAs(t, streamError(nats.ErrTimeout, "orders"), &appErr)
+	assert.Equal(t, errors.CodeDependencyFailed, appErr.Code)
+}
+
+func TestStreamInfo(t *testing.T) {
+	info := &nats.StreamInfo{
+		Config: nats.StreamConfig{Name: "orders", Subjects: []string{"orders", "orders.>"}, MaxAge: 72 * time.Hour},
+		State:  nats.StreamState{Msgs: 12},
+	}
+	assert.Equal(t, &Topic{Name: "orders", Subjects: []string{"orders", "orders.>"}, RetentionHours: 72, Messages: 12, Status: "ready"}, streamInfo(info))
+}
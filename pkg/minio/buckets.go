@@ -0,0 +1,213 @@
+package minio
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/adapters/s3"
+	"github.com/northstack/platform/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Bucket is a bucket of a project's tenant
+type Bucket struct {
+	Name       string    `json:"name"`
+	QuotaBytes int64     `json:"quota_bytes"` // Hard quota; 0 for none
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreateBucket creates a bucket in a project's tenant
+func (s *TenantService) CreateBucket(ctx context.Context, projectID, name string) error {
+	return s.call(ctx, projectID, http.MethodPut, "/"+name, nil, nil, nil)
+}
+
+// DeleteBucket deletes an empty bucket of a project's tenant
+func (s *TenantService) DeleteBucket(ctx context.Context, projectID, name string) error {
+	return s.call(ctx, projectID, http.MethodDelete, "/"+name, nil, nil, nil)
+}
+
+// ListBuckets lists the buckets of a project's tenant with their quotas
+func (s *TenantService) ListBuckets(ctx context.Context, projectID string) ([]*Bucket, error) {
+	var result struct {
+		Buckets []struct {
+			Name         string    `xml:"Name"`
+			CreationDate time.Time `xml:"CreationDate"`
+		} `xml:"Buckets>Bucket"`
+	}
+	if err := s.call(ctx, projectID, http.MethodGet, "/", nil, nil, &result); err != nil {
+		return nil, err
+	}
+
+	buckets := make([]*Bucket, 0, len(result.Buckets))
+	for _, b := range result.Buckets {
+		quota, err := s.GetQuota(ctx, projectID, b.Name)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, &Bucket{Name: b.Name, QuotaBytes: quota, CreatedAt: b.CreationDate})
+	}
+	return buckets, nil
+}
+
+// GetQuota returns the hard quota of a bucket in bytes, 0 when it has none
+func (s *TenantService) GetQuota(ctx context.Context, projectID, bucket string) (int64, error) {
+	var quota struct {
+		Quota int64 `json:"quota"`
+		Size  int64 `json:"size"`
+	}
+	query := url.Values{"bucket": {bucket}}
+	err := s.call(ctx, projectID, http.MethodGet, "/minio/admin/v3/get-bucket-quota", query, nil, &quota)
+	if errors.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if quota.Size > 0 {
+		return quota.Size, nil
+	}
+	return quota.Quota, nil
+}
+
+// SetQuota sets the hard quota of a bucket in bytes; 0 removes it
+func (s *TenantService) SetQuota(ctx context.Context, projectID, bucket string, quota int64) error {
+	// Older releases read quota, newer ones size
+	body, err := json.Marshal(map[string]interface{}{"quota": quota, "size": quota, "quotatype": "hard"})
+	if err != nil {
+		return err
+	}
+	query := url.Values{"bucket": {bucket}}
+	return s.call(ctx, projectID, http.MethodPut, "/minio/admin/v3/set-bucket-quota", query, body, nil)
+}
+
+// removeUser removes a tenant user through the admin API
+func (s *TenantService) removeUser(ctx context.Context, projectID, accessKey string) error {
+	query := url.Values{"accessKey": {accessKey}}
+	return s.call(ctx, projectID, http.MethodDelete, "/minio/admin/v3/remove-user", query, nil, nil)
+}
+
+// call sends a request signed with the root credentials of a project's
+// tenant, decoding an XML or JSON response into out unless it is nil
+func (s *TenantService) call(ctx context.Context, projectID, method, path string, query url.Values, body []byte, out interface{}) error {
+	namespace := s.namespace(projectID)
+	user, password, err := s.rootCredentials(ctx, namespace)
+	if err != nil {
+		return err
+	}
+
+	u := endpoint(namespace) + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	s3.Sign(req, user, password, s.config.Region)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		// The tenant's Service only answers once the operator has started it
+		return errors.NewError(errors.CodeServiceUnavailable, "object storage is not ready yet", http.StatusServiceUnavailable).WithError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return apiError(resp, path)
+	}
+	if out == nil {
+		return nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.DependencyFailed("minio", err)
+	}
+	if strings.HasPrefix(path, "/minio/admin/") {
+		err = json.Unmarshal(data, out)
+	} else {
+		err = xml.Unmarshal(data, out)
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to decode MinIO response")
+	}
+	return nil
+}
+
+// rootCredentials reads the root credentials of a tenant from its
+// configuration secret
+func (s *TenantService) rootCredentials(ctx context.Context, namespace string) (string, string, error) {
+	secret, err := s.dynamic.Resource(SecretGVR).Namespace(namespace).Get(ctx, configSecret, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return "", "", errors.NotFound("object storage", namespace)
+	}
+	if err != nil {
+		return "", "", errors.DependencyFailed("kubernetes", err)
+	}
+	encoded, _, _ := unstructured.NestedString(secret.Object, "data", "config.env")
+	env, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", errors.Internal("invalid object storage credentials")
+	}
+
+	values := map[string]string{}
+	for _, line := range strings.Split(string(env), "\n") {
+		key, value, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "export "), "=")
+		if !ok {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		values[key] = value
+	}
+	if values["MINIO_ROOT_USER"] == "" || values["MINIO_ROOT_PASSWORD"] == "" {
+		return "", "", errors.Internal("invalid object storage credentials")
+	}
+	return values["MINIO_ROOT_USER"], values["MINIO_ROOT_PASSWORD"], nil
+}
+
+// apiError maps an S3 or admin API error response to an AppError
+func apiError(resp *http.Response, path string) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var s3Err struct {
+		Code    string `xml:"Code" json:"Code"`
+		Message string `xml:"Message" json:"Message"`
+	}
+	if xml.Unmarshal(data, &s3Err) != nil {
+		_ = json.Unmarshal(data, &s3Err)
+	}
+
+	switch {
+	case s3Err.Code == "BucketAlreadyOwnedByYou" || s3Err.Code == "BucketAlreadyExists":
+		return errors.Conflict("bucket")
+	case s3Err.Code == "BucketNotEmpty":
+		return errors.NewError(errors.CodeConflict, "the bucket is not empty", http.StatusConflict)
+	case s3Err.Code == "NoSuchBucket" || s3Err.Code == "XMinioAdminNoSuchBucket":
+		return errors.NotFound("bucket")
+	case s3Err.Code == "XMinioAdminNoSuchQuotaConfiguration":
+		return errors.NotFound("bucket quota")
+	case s3Err.Code == "XMinioAdminNoSuchUser":
+		return errors.NotFound("access key")
+	case resp.StatusCode == http.StatusNotFound:
+		return errors.NotFound(path)
+	case s3Err.Code == "InvalidBucketName":
+		return errors.BadRequest(s3Err.Message)
+	}
+	return errors.DependencyFailed("minio", fmt.Errorf("%s: %s %s", resp.Status, s3Err.Code, s3Err.Message))
+}
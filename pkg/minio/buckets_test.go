@@ -0,0 +1,141 @@
+package minio
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestTenant(t *testing.T) *TenantService {
+	s := newTestService()
+	_, err := s.EnsureTenant(context.Background(), "p1")
+	require.NoError(t, err)
+	applyStringData(t, s, "storage-p1", configSecret)
+	return s
+}
+
+func TestRootCredentials(t *testing.T) {
+	s := newTestService()
+	ctx := context.Background()
+
+	_, _, err := s.rootCredentials(ctx, "storage-p1")
+	assert.True(t, errors.IsNotFound(err))
+
+	secret := object("v1", "Secret", configSecret, "storage-p1", nil, map[string]interface{}{
+		"data": map[string]interface{}{
+			"config.env": base64.StdEncoding.EncodeToString([]byte("export MINIO_ROOT_USER=\"ROOT\"\n  export MINIO_ROOT_PASSWORD=s3cr3t\nexport MINIO_REGION=\"us-east-1\"\n")),
+		},
+	})
+	_, err = s.dynamic.Resource(SecretGVR).Namespace("storage-p1").Create(ctx, secret, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	user, password, err := s.rootCredentials(ctx, "storage-p1")
+	require.NoError(t, err)
+	assert.Equal(t, "ROOT", user)
+	assert.Equal(t, "s3cr3t", password)
+
+	require.NoError(t, unstructured.SetNestedField(secret.Object, base64.StdEncoding.EncodeToString([]byte("export MINIO_REGION=x\n")), "data", "config.env"))
+	_, err = s.dynamic.Resource(SecretGVR).Namespace("storage-p1").Update(ctx, secret, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	_, _, err = s.rootCredentials(ctx, "storage-p1")
+	assert.Error(t, err)
+}
+
+func TestListBuckets(t *testing.T) {
+	s := newTestTenant(t)
+	serve(t, s, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<ListAllMyBucketsResult><Buckets>
+				<Bucket><Name>assets</Name><CreationDate>2026-03-04T12:00:00.000Z</CreationDate></Bucket>
+				<Bucket><Name>logs</Name><CreationDate>2026-03-05T12:00:00.000Z</CreationDate></Bucket>
+			</Buckets></ListAllMyBucketsResult>`)
+		case "/minio/admin/v3/get-bucket-quota":
+			if r.URL.Query().Get("bucket") == "assets" {
+				fmt.Fprint(w, `{"quota":0,"size":1073741824,"quotatype":"hard"}`)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"Code":"XMinioAdminNoSuchQuotaConfiguration","Message":"no quota"}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	})
+
+	buckets, err := s.ListBuckets(context.Background(), "p1")
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	assert.Equal(t, "assets", buckets[0].Name)
+	assert.Equal(t, int64(1073741824), buckets[0].QuotaBytes)
+	assert.Equal(t, 2026, buckets[0].CreatedAt.Year())
+	assert.Equal(t, int64(0), buckets[1].QuotaBytes)
+}
+
+func TestSetQuota(t *testing.T) {
+	s := newTestTenant(t)
+	var body string
+	serve(t, s, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/minio/admin/v3/set-bucket-quota", r.URL.Path)
+		assert.Equal(t, "assets", r.URL.Query().Get("bucket"))
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	})
+
+	require.NoError(t, s.SetQuota(context.Background(), "p1", "assets", 1024))
+	assert.JSONEq(t, `{"quota":1024,"size":1024,"quotatype":"hard"}`, body)
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		code   string
+		status int
+		want   int
+	}{
+		{"BucketAlreadyOwnedByYou", http.StatusConflict, http.StatusConflict},
+		{"BucketNotEmpty", http.StatusConflict, http.StatusConflict},
+		{"NoSuchBucket", http.StatusNotFound, http.StatusNotFound},
+		{"XMinioAdminNoSuchUser", http.StatusNotFound, http.StatusNotFound},
+		{"InvalidBucketName", http.StatusBadRequest, http.StatusBadRequest},
+		{"", http.StatusNotFound, http.StatusNotFound},
+		{"InternalError", http.StatusInternalServerError, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			recorder.WriteHeader(tt.status)
+			if tt.code != "" {
+				fmt.Fprintf(recorder, "<Error><Code>%s</Code><Message>failed</Message></Error>", tt.code)
+			}
+			resp := recorder.Result()
+			resp.Status = fmt.Sprintf("%d %s", tt.status, http.StatusText(tt.status))
+
+			var appErr *errors.AppError
+			require.ErrorAs(t, apiError(resp, "/assets"), &appErr)
+			assert.Equal(t, tt.want, appErr.HTTPStatus)
+		})
+	}
+}
+
+func TestCallNotReady(t *testing.T) {
+	s := newTestTenant(t)
+	s.httpClient = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, io.ErrUnexpectedEOF
+	})}
+
+	var appErr *errors.AppError
+	require.ErrorAs(t, s.CreateBucket(context.Background(), "p1", "assets"), &appErr)
+	assert.Equal(t, http.StatusServiceUnavailable, appErr.HTTPStatus)
+	assert.True(t, strings.Contains(appErr.Message, "not ready"))
+}
@@ -0,0 +1,356 @@
+// Package minio provisions a MinIO tenant for each project with the MinIO
+// Operator and manages the buckets, quotas and access keys of the tenants.
+// Buckets and quotas go through a tenant's S3 and admin APIs with its root
+// credentials, which only live in the tenant's configuration secret; access
+// keys are tenant users the operator creates.
+package minio
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// MinIO Operator and Kubernetes GVRs
+var (
+	TenantGVR = schema.GroupVersionResource{
+		Group:    "minio.min.io",
+		Version:  "v2",
+		Resource: "tenants",
+	}
+	NamespaceGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	SecretGVR    = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+)
+
+const (
+	// TenantName is the name of every project's tenant, in its own namespace
+	TenantName = "storage"
+	// configSecret holds the root credentials of a tenant
+	configSecret = "storage-env-configuration"
+	// accessKeyLabel marks the secrets of issued access keys
+	accessKeyLabel = "northstack.io/access-key"
+)
+
+// TenantService manages per-project MinIO tenants
+type TenantService struct {
+	dynamic    dynamic.Interface
+	config     *config.ObjectStorageConfig
+	httpClient *http.Client
+}
+
+// TenantInfo describes the tenant of a project
+type TenantInfo struct {
+	ProjectID string    `json:"project_id"`
+	Namespace string    `json:"namespace"`
+	Status    string    `json:"status"` // The operator's state of the tenant
+	Ready     bool      `json:"ready"`
+	Endpoint  string    `json:"endpoint"` // S3 API, inside the cluster
+	Capacity  string    `json:"capacity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccessKey is a credential of a tenant. The secret key is only known when
+// the key is issued.
+type AccessKey struct {
+	AccessKey string    `json:"access_key"`
+	SecretKey string    `json:"secret_key,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewTenantService creates a new tenant service
+func NewTenantService(dynClient dynamic.Interface, cfg *config.ObjectStorageConfig) *TenantService {
+	return &TenantService{
+		dynamic:    dynClient,
+		config:     cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+// EnsureTenant returns the tenant of a project, creating it, its namespace
+// and its root credentials on first use. A new tenant is not ready until the
+// operator has started it.
+func (s *TenantService) EnsureTenant(ctx context.Context, projectID string) (*TenantInfo, error) {
+	info, err := s.GetTenant(ctx, projectID)
+	if !errors.IsNotFound(err) {
+		return info, err
+	}
+
+	namespace := s.namespace(projectID)
+	labels := map[string]interface{}{
+		"northstack.io/project": projectID,
+		"northstack.io/type":    "object-storage",
+	}
+	ns := object("v1", "Namespace", namespace, "", labels, nil)
+	if _, err := s.dynamic.Resource(NamespaceGVR).Create(ctx, ns, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create tenant namespace: %w", err)
+	}
+
+	rootUser, err := randomString(upperAlphanumeric, 20)
+	if err != nil {
+		return nil, err
+	}
+	rootPassword, err := randomString(alphanumeric, 40)
+	if err != nil {
+		return nil, err
+	}
+	secret := object("v1", "Secret", configSecret, namespace, labels, map[string]interface{}{
+		"type": "Opaque",
+		"stringData": map[string]interface{}{
+			"config.env": fmt.Sprintf("export MINIO_ROOT_USER=%q\nexport MINIO_ROOT_PASSWORD=%q\nexport MINIO_REGION=%q\n", rootUser, rootPassword, s.config.Region),
+		},
+	})
+	if _, err := s.dynamic.Resource(SecretGVR).Namespace(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create tenant credentials: %w", err)
+	}
+
+	claim := map[string]interface{}{
+		"accessModes": []interface{}{"ReadWriteOnce"},
+		"resources": map[string]interface{}{
+			"requests": map[string]interface{}{"storage": s.config.VolumeSize},
+		},
+	}
+	if s.config.StorageClass != "" {
+		claim["storageClassName"] = s.config.StorageClass
+	}
+	tenant := object("minio.min.io/v2", "Tenant", TenantName, namespace, labels, map[string]interface{}{
+		"spec": map[string]interface{}{
+			"image":           s.config.Image,
+			"configuration":   map[string]interface{}{"name": configSecret},
+			"requestAutoCert": false,
+			"pools": []interface{}{map[string]interface{}{
+				"name":             "pool-0",
+				"servers":          int64(1),
+				"volumesPerServer": int64(s.config.VolumesPerServer),
+				"volumeClaimTemplate": map[string]interface{}{
+					"metadata": map[string]interface{}{"name": "data"},
+					"spec":     claim,
+				},
+			}},
+		},
+	})
+	created, err := s.dynamic.Resource(TenantGVR).Namespace(namespace).Create(ctx, tenant, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+	return s.tenantInfo(projectID, created), nil
+}
+
+// GetTenant returns the tenant of a project
+func (s *TenantService) GetTenant(ctx context.Context, projectID string) (*TenantInfo, error) {
+	tenant, err := s.dynamic.Resource(TenantGVR).Namespace(s.namespace(projectID)).Get(ctx, TenantName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, errors.NotFound("object storage of project", projectID)
+	}
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	return s.tenantInfo(projectID, tenant), nil
+}
+
+// IssueAccessKey creates an access key to a project's tenant. The operator
+// creates the tenant user from the key's secret, with access to every bucket
+// of the tenant.
+func (s *TenantService) IssueAccessKey(ctx context.Context, projectID string) (*AccessKey, error) {
+	namespace := s.namespace(projectID)
+	tenant, err := s.dynamic.Resource(TenantGVR).Namespace(namespace).Get(ctx, TenantName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, errors.NotFound("object storage of project", projectID)
+	}
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	accessKey, err := randomString(upperAlphanumeric, 20)
+	if err != nil {
+		return nil, err
+	}
+	secretKey, err := randomString(alphanumeric, 40)
+	if err != nil {
+		return nil, err
+	}
+	secret := object("v1", "Secret", keySecretName(accessKey), namespace, map[string]interface{}{
+		"northstack.io/project": projectID,
+		accessKeyLabel:          accessKey,
+	}, map[string]interface{}{
+		"type": "Opaque",
+		"stringData": map[string]interface{}{
+			"CONSOLE_ACCESS_KEY": accessKey,
+			"CONSOLE_SECRET_KEY": secretKey,
+		},
+	})
+	created, err := s.dynamic.Resource(SecretGVR).Namespace(namespace).Create(ctx, secret, metav1.CreateOptions{})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	users, _, _ := unstructured.NestedSlice(tenant.Object, "spec", "users")
+	users = append(users, map[string]interface{}{"name": keySecretName(accessKey)})
+	if err := unstructured.SetNestedSlice(tenant.Object, users, "spec", "users"); err != nil {
+		return nil, err
+	}
+	if _, err := s.dynamic.Resource(TenantGVR).Namespace(namespace).Update(ctx, tenant, metav1.UpdateOptions{}); err != nil {
+		_ = s.dynamic.Resource(SecretGVR).Namespace(namespace).Delete(ctx, keySecretName(accessKey), metav1.DeleteOptions{})
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	return &AccessKey{
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		CreatedAt: created.GetCreationTimestamp().Time,
+	}, nil
+}
+
+// ListAccessKeys lists the access keys issued for a project's tenant, without
+// their secret keys
+func (s *TenantService) ListAccessKeys(ctx context.Context, projectID string) ([]*AccessKey, error) {
+	secrets, err := s.dynamic.Resource(SecretGVR).Namespace(s.namespace(projectID)).List(ctx, metav1.ListOptions{
+		LabelSelector: accessKeyLabel,
+	})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	keys := make([]*AccessKey, 0, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		keys = append(keys, &AccessKey{
+			AccessKey: secret.GetLabels()[accessKeyLabel],
+			CreatedAt: secret.GetCreationTimestamp().Time,
+		})
+	}
+	return keys, nil
+}
+
+// RevokeAccessKey removes the tenant user of an access key and its secret, so
+// the operator does not create it again
+func (s *TenantService) RevokeAccessKey(ctx context.Context, projectID, accessKey string) error {
+	namespace := s.namespace(projectID)
+	name := keySecretName(accessKey)
+	if _, err := s.dynamic.Resource(SecretGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		return errors.NotFound("access key", accessKey)
+	} else if err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+
+	tenant, err := s.dynamic.Resource(TenantGVR).Namespace(namespace).Get(ctx, TenantName, metav1.GetOptions{})
+	if err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	users, _, _ := unstructured.NestedSlice(tenant.Object, "spec", "users")
+	kept := make([]interface{}, 0, len(users))
+	for _, u := range users {
+		if user, ok := u.(map[string]interface{}); ok && user["name"] == name {
+			continue
+		}
+		kept = append(kept, u)
+	}
+	if err := unstructured.SetNestedSlice(tenant.Object, kept, "spec", "users"); err != nil {
+		return err
+	}
+	if _, err := s.dynamic.Resource(TenantGVR).Namespace(namespace).Update(ctx, tenant, metav1.UpdateOptions{}); err != nil {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+
+	if err := s.removeUser(ctx, projectID, accessKey); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err := s.dynamic.Resource(SecretGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+// namespace is the namespace of a project's tenant
+func (s *TenantService) namespace(projectID string) string {
+	return s.config.NamespacePrefix + projectID
+}
+
+// endpoint is the S3 API of the tenant in a namespace; the operator's minio
+// Service serves plain HTTP without auto certificates
+func endpoint(namespace string) string {
+	return fmt.Sprintf("http://minio.%s.svc.cluster.local", namespace)
+}
+
+func (s *TenantService) tenantInfo(projectID string, tenant *unstructured.Unstructured) *TenantInfo {
+	state, _, _ := unstructured.NestedString(tenant.Object, "status", "currentState")
+	if state == "" {
+		state = "Provisioning"
+	}
+	return &TenantInfo{
+		ProjectID: projectID,
+		Namespace: tenant.GetNamespace(),
+		Status:    state,
+		Ready:     state == "Initialized",
+		Endpoint:  endpoint(tenant.GetNamespace()),
+		Capacity:  capacity(s.config.VolumeSize, s.config.VolumesPerServer),
+		CreatedAt: tenant.GetCreationTimestamp().Time,
+	}
+}
+
+// capacity is the raw capacity of a tenant's volumes; erasure coding keeps
+// part of it for parity
+func capacity(volumeSize string, volumes int) string {
+	for _, unit := range []string{"Ti", "Gi", "Mi"} {
+		if n := strings.TrimSuffix(volumeSize, unit); n != volumeSize {
+			var size int
+			if _, err := fmt.Sscanf(n, "%d", &size); err == nil {
+				return fmt.Sprintf("%d%s", size*volumes, unit)
+			}
+		}
+	}
+	return fmt.Sprintf("%d x %s", volumes, volumeSize)
+}
+
+// keySecretName is the name of the secret of an access key
+func keySecretName(accessKey string) string {
+	return "access-key-" + strings.ToLower(accessKey)
+}
+
+func object(apiVersion, kind, name, namespace string, labels map[string]interface{}, fields map[string]interface{}) *unstructured.Unstructured {
+	metadata := map[string]interface{}{
+		"name":   name,
+		"labels": labels,
+	}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	obj := map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   metadata,
+	}
+	for key, value := range fields {
+		obj[key] = value
+	}
+	return &unstructured.Unstructured{Object: obj}
+}
+
+const (
+	upperAlphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	alphanumeric      = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// randomString returns n random characters of an alphabet
+func randomString(alphabet string, n int) (string, error) {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate credentials: %w", err)
+		}
+		b[i] = alphabet[index.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,169 @@
+package minio
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/internal/config"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func newTestService() *TenantService {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		TenantGVR:    "TenantList",
+		NamespaceGVR: "NamespaceList",
+		SecretGVR:    "SecretList",
+	})
+	return NewTenantService(client, &config.ObjectStorageConfig{
+		NamespacePrefix:  "storage-",
+		Image:            "quay.io/minio/minio:latest",
+		VolumesPerServer: 4,
+		VolumeSize:       "10Gi",
+		Region:           "us-east-1",
+		Timeout:          time.Second,
+	})
+}
+
+// serve sends the tenant API requests of s to handler
+func serve(t *testing.T, s *TenantService, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	s.httpClient = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		return http.DefaultTransport.RoundTrip(req)
+	})}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// applyStringData moves the string data of a secret into its data, as the
+// API server does
+func applyStringData(t *testing.T, s *TenantService, namespace, name string) {
+	ctx := context.Background()
+	secret, err := s.dynamic.Resource(SecretGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	require.NoError(t, err)
+	values, _, _ := unstructured.NestedStringMap(secret.Object, "stringData")
+	data := map[string]string{}
+	for key, value := range values {
+		data[key] = base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	require.NoError(t, unstructured.SetNestedStringMap(secret.Object, data, "data"))
+	_, err = s.dynamic.Resource(SecretGVR).Namespace(namespace).Update(ctx, secret, metav1.UpdateOptions{})
+	require.NoError(t, err)
+}
+
+func TestEnsureTenant(t *testing.T) {
+	s := newTestService()
+	ctx := context.Background()
+
+	_, err := s.GetTenant(ctx, "p1")
+	assert.True(t, errors.IsNotFound(err))
+
+	info, err := s.EnsureTenant(ctx, "p1")
+	require.NoError(t, err)
+	assert.Equal(t, "storage-p1", info.Namespace)
+	assert.Equal(t, "Provisioning", info.Status)
+	assert.False(t, info.Ready)
+	assert.Equal(t, "http://minio.storage-p1.svc.cluster.local", info.Endpoint)
+	assert.Equal(t, "40Gi", info.Capacity)
+
+	secret, err := s.dynamic.Resource(SecretGVR).Namespace("storage-p1").Get(ctx, configSecret, metav1.GetOptions{})
+	require.NoError(t, err)
+	env, _, _ := unstructured.NestedString(secret.Object, "stringData", "config.env")
+	assert.Contains(t, env, "export MINIO_ROOT_USER=")
+	assert.Contains(t, env, `export MINIO_REGION="us-east-1"`)
+
+	// A second call returns the tenant without creating another one
+	tenant, err := s.dynamic.Resource(TenantGVR).Namespace("storage-p1").Get(ctx, TenantName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NoError(t, unstructured.SetNestedField(tenant.Object, "Initialized", "status", "currentState"))
+	_, err = s.dynamic.Resource(TenantGVR).Namespace("storage-p1").Update(ctx, tenant, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	info, err = s.EnsureTenant(ctx, "p1")
+	require.NoError(t, err)
+	assert.True(t, info.Ready)
+}
+
+func TestAccessKeys(t *testing.T) {
+	s := newTestService()
+	ctx := context.Background()
+
+	_, err := s.IssueAccessKey(ctx, "p1")
+	assert.True(t, errors.IsNotFound(err), "a project without a tenant has no keys")
+
+	_, err = s.EnsureTenant(ctx, "p1")
+	require.NoError(t, err)
+	applyStringData(t, s, "storage-p1", configSecret)
+
+	first, err := s.IssueAccessKey(ctx, "p1")
+	require.NoError(t, err)
+	assert.Regexp(t, `^[A-Z0-9]{20}$`, first.AccessKey)
+	assert.Regexp(t, `^[A-Za-z0-9]{40}$`, first.SecretKey)
+	second, err := s.IssueAccessKey(ctx, "p1")
+	require.NoError(t, err)
+
+	keys, err := s.ListAccessKeys(ctx, "p1")
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	for _, key := range keys {
+		assert.Empty(t, key.SecretKey)
+	}
+
+	tenant, err := s.dynamic.Resource(TenantGVR).Namespace("storage-p1").Get(ctx, TenantName, metav1.GetOptions{})
+	require.NoError(t, err)
+	users, _, _ := unstructured.NestedSlice(tenant.Object, "spec", "users")
+	assert.Len(t, users, 2)
+
+	var removed string
+	serve(t, s, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/minio/admin/v3/remove-user", r.URL.Path)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "))
+		removed = r.URL.Query().Get("accessKey")
+	})
+	require.NoError(t, s.RevokeAccessKey(ctx, "p1", first.AccessKey))
+	assert.Equal(t, first.AccessKey, removed)
+
+	keys, err = s.ListAccessKeys(ctx, "p1")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, second.AccessKey, keys[0].AccessKey)
+
+	tenant, err = s.dynamic.Resource(TenantGVR).Namespace("storage-p1").Get(ctx, TenantName, metav1.GetOptions{})
+	require.NoError(t, err)
+	users, _, _ = unstructured.NestedSlice(tenant.Object, "spec", "users")
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": keySecretName(second.AccessKey)}}, users)
+
+	assert.True(t, errors.IsNotFound(s.RevokeAccessKey(ctx, "p1", first.AccessKey)))
+}
+
+func TestCapacity(t *testing.T) {
+	assert.Equal(t, "40Gi", capacity("10Gi", 4))
+	assert.Equal(t, "8Ti", capacity("2Ti", 4))
+	assert.Equal(t, "4 x 10G", capacity("10G", 4))
+}
+
+func TestRandomString(t *testing.T) {
+	value, err := randomString(upperAlphanumeric, 20)
+	require.NoError(t, err)
+	assert.Regexp(t, `^[A-Z0-9]{20}$`, value)
+}
@@ -0,0 +1,21 @@
+// Package password generates the passwords of the managed add-ons
+package password
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// size is the number of random bytes in a password
+const size = 24
+
+// Generate returns a random password of 48 hex characters. It needs no
+// escaping in a URL, a connection string or a SQL literal.
+func Generate() (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,19 @@
+package password
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	first, err := Generate()
+	require.NoError(t, err)
+	second, err := Generate()
+	require.NoError(t, err)
+
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{48}$`), first)
+	assert.NotEqual(t, first, second)
+}
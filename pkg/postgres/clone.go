@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/northstack/platform/pkg/errors"
+)
+
+// CloneDatabaseInput holds parameters for cloning a PostgreSQL cluster
+type CloneDatabaseInput struct {
+	SourceID      string
+	Name          string
+	TeamID        string
+	EnvironmentID string // Environment the clone is deleted with
+	Size          string
+	StorageGB     int
+}
+
+// CloneDatabase creates a cluster in the project of another one, seeded with
+// a physical copy of its data taken with pg_basebackup from the source's
+// primary. The clone keeps the database and owner of the source, but gets
+// its own owner password; the source is only read from.
+func (s *Service) CloneDatabase(ctx context.Context, input *CloneDatabaseInput) (*DatabaseInfo, error) {
+	source, err := s.GetDatabase(ctx, input.SourceID)
+	if err != nil {
+		return nil, err
+	}
+	if source.Status == "creating" {
+		return nil, errors.BadRequest("the source database is not ready yet")
+	}
+	if source.StorageGB > input.StorageGB {
+		return nil, errors.BadRequest(fmt.Sprintf("clones need at least the %d GB of storage of their source", source.StorageGB))
+	}
+
+	return s.CreateDatabase(ctx, &CreateDatabaseInput{
+		Name:          input.Name,
+		ProjectID:     source.ProjectID,
+		TeamID:        input.TeamID,
+		Size:          input.Size,
+		StorageGB:     input.StorageGB,
+		Version:       source.Version,
+		EnvironmentID: input.EnvironmentID,
+		source:        source,
+	})
+}
+
+// cloneBootstrap returns the bootstrap and external clusters of a clone of a
+// cluster. The operator connects to the source as its streaming replication
+// user, with the client certificate it issued for it.
+func (s *Service) cloneBootstrap(source *DatabaseInfo) (map[string]interface{}, []interface{}) {
+	bootstrap := map[string]interface{}{
+		"pg_basebackup": map[string]interface{}{
+			"source":   source.ID,
+			"database": source.Database,
+			"owner":    source.Username,
+		},
+	}
+	externalClusters := []interface{}{
+		map[string]interface{}{
+			"name": source.ID,
+			"connectionParameters": map[string]interface{}{
+				"host":    fmt.Sprintf("%s-rw.%s.svc", source.ID, s.namespace),
+				"user":    "streaming_replica",
+				"sslmode": "verify-full",
+			},
+			"sslKey":      map[string]interface{}{"name": source.ID + "-replication", "key": "tls.key"},
+			"sslCert":     map[string]interface{}{"name": source.ID + "-replication", "key": "tls.crt"},
+			"sslRootCert": map[string]interface{}{"name": source.ID + "-ca", "key": "ca.crt"},
+		},
+	}
+	return bootstrap, externalClusters
+}
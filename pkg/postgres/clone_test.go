@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestCloneDatabase(t *testing.T) {
+	s := newTestService()
+	ctx := context.Background()
+	_, err := s.CreateDatabase(ctx, &CreateDatabaseInput{Name: "orders", ProjectID: "p1", StorageGB: 20, Version: "15"})
+	require.NoError(t, err)
+
+	// A source whose first instance is not up cannot be copied
+	_, err = s.CloneDatabase(ctx, &CloneDatabaseInput{SourceID: "p1-orders", Name: "orders-pr-1", StorageGB: 20})
+	assert.Error(t, err)
+
+	source := get(t, s, ClusterGVR, "p1-orders")
+	require.NoError(t, unstructured.SetNestedField(source.Object, "Cluster in healthy state", "status", "phase"))
+	require.NoError(t, unstructured.SetNestedField(source.Object, int64(1), "status", "readyInstances"))
+	_, err = s.dynamic.Resource(ClusterGVR).Namespace(testNamespace).UpdateStatus(ctx, source, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	_, err = s.CloneDatabase(ctx, &CloneDatabaseInput{SourceID: "p1-orders", Name: "orders-pr-1", StorageGB: 10})
+	assert.Error(t, err, "a clone needs the storage of its source")
+
+	clone, err := s.CloneDatabase(ctx, &CloneDatabaseInput{SourceID: "p1-orders", Name: "orders-pr-1", StorageGB: 20, EnvironmentID: "e1"})
+	require.NoError(t, err)
+	assert.Equal(t, "p1-orders-pr-1", clone.ID)
+	assert.Equal(t, "p1-orders", clone.CloneOf)
+	assert.Equal(t, "15", clone.Version)
+	assert.Equal(t, "orders", clone.Database)
+	assert.Equal(t, "orders", clone.Username)
+
+	cluster := get(t, s, ClusterGVR, "p1-orders-pr-1")
+	from, _, _ := unstructured.NestedString(cluster.Object, "spec", "bootstrap", "pg_basebackup", "source")
+	assert.Equal(t, "p1-orders", from)
+	external, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "externalClusters")
+	require.Len(t, external, 1)
+	host, _, _ := unstructured.NestedString(external[0].(map[string]interface{}), "connectionParameters", "host")
+	assert.Equal(t, "p1-orders-rw.databases.svc", host)
+}
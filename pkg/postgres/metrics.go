@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"encoding/base64"
+	stderrors "errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// qpsInterval is how long transactions are counted for to compute the rate
+const qpsInterval = time.Second
+
+// DatabaseMetrics is a point-in-time view of the health and load of a
+// database, collected from the statistics views of its primary
+type DatabaseMetrics struct {
+	Healthy          bool      `json:"healthy"`
+	Connections      int       `json:"connections"`
+	MaxConnections   int       `json:"max_connections"`
+	QueriesPerSecond float64   `json:"queries_per_second"` // Committed and rolled back transactions
+	ReplicationLagMs int64     `json:"replication_lag_ms"` // Replay lag of the slowest replica
+	DiskUsedBytes    int64     `json:"disk_used_bytes"`    // Size of the database
+	DiskTotalBytes   int64     `json:"disk_total_bytes"`   // Size of the volume of each instance
+	Instances        int       `json:"instances"`
+	ReadyInstances   int       `json:"ready_instances"`
+	CollectedAt      time.Time `json:"collected_at"`
+}
+
+// SlowQuery is a statement from pg_stat_statements, with times in
+// milliseconds
+type SlowQuery struct {
+	Query     string  `json:"query"`
+	Calls     int64   `json:"calls"`
+	TotalTime float64 `json:"total_time_ms"`
+	MeanTime  float64 `json:"mean_time_ms"`
+	MaxTime   float64 `json:"max_time_ms"`
+	Rows      int64   `json:"rows"`
+}
+
+// GetMetrics collects the metrics of a database. It takes about a second, to
+// measure the transaction rate.
+func (s *Service) GetMetrics(ctx context.Context, name string) (*DatabaseMetrics, error) {
+	info, err := s.GetDatabase(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := s.connect(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	metrics := &DatabaseMetrics{
+		Healthy:        info.Status == "ready",
+		DiskTotalBytes: int64(info.StorageGB) << 30,
+		Instances:      info.Instances,
+		ReadyInstances: info.ReadyInstances,
+	}
+	err = conn.QueryRow(ctx, `
+		SELECT
+			(SELECT count(*) FROM pg_stat_activity
+				WHERE datname = current_database() AND backend_type = 'client backend'),
+			current_setting('max_connections')::int,
+			(SELECT coalesce(max(extract(epoch FROM replay_lag) * 1000), 0)::bigint FROM pg_stat_replication),
+			pg_database_size(current_database())`,
+	).Scan(&metrics.Connections, &metrics.MaxConnections, &metrics.ReplicationLagMs, &metrics.DiskUsedBytes)
+	if err != nil {
+		return nil, errors.DependencyFailed("postgres", err)
+	}
+
+	// Statistics are cached for the transaction, and each query is one
+	const transactions = `SELECT xact_commit + xact_rollback FROM pg_stat_database WHERE datname = current_database()`
+	var before, after int64
+	if err := conn.QueryRow(ctx, transactions).Scan(&before); err != nil {
+		return nil, errors.DependencyFailed("postgres", err)
+	}
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(qpsInterval):
+	}
+	if err := conn.QueryRow(ctx, transactions).Scan(&after); err != nil {
+		return nil, errors.DependencyFailed("postgres", err)
+	}
+	// The first sample is a transaction too
+	metrics.QueriesPerSecond = float64(after-before-1) / time.Since(start).Seconds()
+	if metrics.QueriesPerSecond < 0 {
+		metrics.QueriesPerSecond = 0
+	}
+	metrics.CollectedAt = time.Now()
+	return metrics, nil
+}
+
+// SlowQueries returns the statements of a database with a mean time of at
+// least minMeanTime milliseconds, slowest first
+func (s *Service) SlowQueries(ctx context.Context, name string, minMeanTime float64, limit int) ([]*SlowQuery, error) {
+	info, err := s.GetDatabase(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := s.connect(ctx, info)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := conn.Query(ctx, `
+		SELECT query, calls, total_exec_time, mean_exec_time, max_exec_time, rows
+		FROM pg_stat_statements
+		WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+			AND mean_exec_time >= $1
+		ORDER BY mean_exec_time DESC
+		LIMIT $2`, minMeanTime, limit)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if stderrors.As(err, &pgErr) && pgErr.Code == "42P01" { // undefined_table
+			return nil, errors.BadRequest("pg_stat_statements is not enabled on this database")
+		}
+		return nil, errors.DependencyFailed("postgres", err)
+	}
+	defer rows.Close()
+
+	queries := []*SlowQuery{}
+	for rows.Next() {
+		query := &SlowQuery{}
+		if err := rows.Scan(&query.Query, &query.Calls, &query.TotalTime, &query.MeanTime, &query.MaxTime, &query.Rows); err != nil {
+			return nil, errors.DependencyFailed("postgres", err)
+		}
+		queries = append(queries, query)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.DependencyFailed("postgres", err)
+	}
+	return queries, nil
+}
+
+// connect connects to the primary of a database as its owner, with the
+// credentials of the app secret
+func (s *Service) connect(ctx context.Context, info *DatabaseInfo) (*pgx.Conn, error) {
+	secret, err := s.dynamic.Resource(SecretGVR).Namespace(s.namespace).Get(ctx, info.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	username, password := secretValue(secret, "username"), secretValue(secret, "password")
+	if username == "" || password == "" {
+		return nil, errors.NewError(errors.CodeServiceUnavailable, "the database is not ready yet", http.StatusServiceUnavailable)
+	}
+
+	dsn := (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(username, password),
+		Host:     info.DirectEndpoint,
+		Path:     "/" + info.Database,
+		RawQuery: "sslmode=require&connect_timeout=5&application_name=northstack-orchestrator",
+	}).String()
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return nil, errors.NewError(errors.CodeServiceUnavailable, "the database is not ready yet", http.StatusServiceUnavailable).WithError(err)
+	}
+	return conn, nil
+}
+
+// secretValue returns a value of a secret read through the dynamic client
+func secretValue(secret *unstructured.Unstructured, key string) string {
+	if value, ok, _ := unstructured.NestedString(secret.Object, "data", key); ok {
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err == nil {
+			return string(decoded)
+		}
+	}
+	value, _, _ := unstructured.NestedString(secret.Object, "stringData", key)
+	return value
+}
+
+// statsParameters are the PostgreSQL parameters that enable
+// pg_stat_statements, which the operator then loads and creates
+func statsParameters() map[string]interface{} {
+	return map[string]interface{}{
+		"pg_stat_statements.max":   "5000",
+		"pg_stat_statements.track": "top",
+	}
+}
+
+// monitoringRole makes the owner of a database a member of pg_monitor, to
+// read replication lag and the statements of other roles. The operator leaves
+// the password of the role alone.
+func monitoringRole(owner string) map[string]interface{} {
+	return map[string]interface{}{
+		"name":    owner,
+		"ensure":  "present",
+		"login":   true,
+		"inRoles": []interface{}{"pg_monitor"},
+	}
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestConnectNotReady(t *testing.T) {
+	s := newTestService()
+	ctx := context.Background()
+	info, err := s.CreateDatabase(ctx, &CreateDatabaseInput{Name: "orders", ProjectID: "p1", StorageGB: 10})
+	require.NoError(t, err)
+
+	// The operator has not generated the app secret yet
+	var appErr *errors.AppError
+	_, err = s.connect(ctx, info)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, errors.CodeDependencyFailed, appErr.Code)
+
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": info.SecretName, "namespace": testNamespace},
+		"stringData": map[string]interface{}{"username": "orders"},
+	}}
+	_, err = s.dynamic.Resource(SecretGVR).Namespace(testNamespace).Create(ctx, secret, metav1.CreateOptions{})
+	require.NoError(t, err)
+	_, err = s.connect(ctx, info)
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusServiceUnavailable, appErr.HTTPStatus)
+}
+
+func TestSecretValue(t *testing.T) {
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"data":       map[string]interface{}{"password": "aHVudGVyMg==", "broken": "%%%"},
+		"stringData": map[string]interface{}{"username": "orders", "broken": "plain"},
+	}}
+	assert.Equal(t, "hunter2", secretValue(secret, "password"))
+	assert.Equal(t, "orders", secretValue(secret, "username"))
+	assert.Equal(t, "plain", secretValue(secret, "broken"))
+	assert.Equal(t, "", secretValue(secret, "uri"))
+}
+
+func TestMonitoringRole(t *testing.T) {
+	role := monitoringRole("orders")
+	assert.Equal(t, "orders", role["name"])
+	assert.Equal(t, []interface{}{"pg_monitor"}, role["inRoles"])
+	assert.Equal(t, "top", statsParameters()["pg_stat_statements.track"])
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/northstack/platform/pkg/password"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// PgBouncer pool modes
+const (
+	PoolModeSession     = "session"
+	PoolModeTransaction = "transaction"
+	PoolModeStatement   = "statement"
+)
+
+// withDefaults returns a copy of a pooler configuration with defaults for
+// the settings left out
+func (p *PoolerInput) withDefaults() *PoolerInput {
+	pooler := *p
+	if pooler.Mode == "" {
+		pooler.Mode = PoolModeTransaction
+	}
+	if pooler.PoolSize <= 0 {
+		pooler.PoolSize = 20
+	}
+	if pooler.MaxClientConnections <= 0 {
+		pooler.MaxClientConnections = 1000
+	}
+	return &pooler
+}
+
+// createPooler creates the app secret of a cluster with a pooler, and the
+// CloudNativePG Pooler in front of its primary. Both are owned by the cluster.
+func (s *Service) createPooler(ctx context.Context, cluster *unstructured.Unstructured, input *CreateDatabaseInput, pooler *PoolerInput) error {
+	name := cluster.GetName()
+	owner := []interface{}{map[string]interface{}{
+		"apiVersion":         "postgresql.cnpg.io/v1",
+		"kind":               "Cluster",
+		"name":               name,
+		"uid":                string(cluster.GetUID()),
+		"controller":         true,
+		"blockOwnerDeletion": true,
+	}}
+
+	password, err := password.Generate()
+	if err != nil {
+		return err
+	}
+	pooledHost := fmt.Sprintf("%s.%s.svc", poolerName(name), s.namespace)
+	directHost := fmt.Sprintf("%s-rw.%s.svc", name, s.namespace)
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":            name + "-app",
+				"namespace":       s.namespace,
+				"ownerReferences": owner,
+			},
+			"type": "kubernetes.io/basic-auth",
+			"stringData": map[string]interface{}{
+				"username":   input.Name,
+				"password":   password,
+				"dbname":     input.Name,
+				"host":       pooledHost,
+				"port":       strconv.Itoa(Port),
+				"uri":        fmt.Sprintf("postgresql://%s:%s@%s:%d/%s", input.Name, password, pooledHost, Port, input.Name),
+				"direct-uri": fmt.Sprintf("postgresql://%s:%s@%s:%d/%s", input.Name, password, directHost, Port, input.Name),
+			},
+		},
+	}
+	if _, err := s.dynamic.Resource(SecretGVR).Namespace(s.namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create PostgreSQL credentials: %w", err)
+	}
+
+	labels := map[string]interface{}{}
+	for k, v := range cluster.GetLabels() {
+		labels[k] = v
+	}
+	instances := int64(1)
+	if input.HighAvailability {
+		instances = 2
+	}
+	resource := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "postgresql.cnpg.io/v1",
+			"kind":       "Pooler",
+			"metadata": map[string]interface{}{
+				"name":            poolerName(name),
+				"namespace":       s.namespace,
+				"labels":          labels,
+				"ownerReferences": owner,
+			},
+			"spec": map[string]interface{}{
+				"cluster":   map[string]interface{}{"name": name},
+				"instances": instances,
+				"type":      "rw",
+				"pgbouncer": map[string]interface{}{
+					"poolMode": pooler.Mode,
+					"parameters": map[string]interface{}{
+						"default_pool_size": strconv.Itoa(pooler.PoolSize),
+						"max_client_conn":   strconv.Itoa(pooler.MaxClientConnections),
+					},
+				},
+			},
+		},
+	}
+	if _, err := s.dynamic.Resource(PoolerGVR).Namespace(s.namespace).Create(ctx, resource, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create PgBouncer pooler: %w", err)
+	}
+	return nil
+}
+
+// poolerName is the name of the Pooler of a cluster, and of its Service
+func poolerName(cluster string) string {
+	return cluster + "-pooler"
+}
@@ -0,0 +1,380 @@
+// Package postgres provisions single-purpose PostgreSQL clusters with the
+// CloudNativePG operator. Each cluster serves one database owned by one role;
+// the operator generates the role's password into the <cluster>-app secret,
+// unless the cluster has a PgBouncer pooler, whose connection string the
+// secret then holds.
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// CloudNativePG operator GVRs
+var (
+	ClusterGVR = schema.GroupVersionResource{
+		Group:    "postgresql.cnpg.io",
+		Version:  "v1",
+		Resource: "clusters",
+	}
+	PoolerGVR = schema.GroupVersionResource{
+		Group:    "postgresql.cnpg.io",
+		Version:  "v1",
+		Resource: "poolers",
+	}
+	SecretGVR = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+)
+
+// Port is the port of the read-write and read-only services of a cluster
+const Port = 5432
+
+// DefaultVersion is the PostgreSQL major version of new clusters
+const DefaultVersion = "16"
+
+// Service manages PostgreSQL clusters via the CloudNativePG operator
+type Service struct {
+	dynamic   dynamic.Interface
+	namespace string
+}
+
+// CreateDatabaseInput holds parameters for creating a PostgreSQL cluster
+type CreateDatabaseInput struct {
+	Name             string
+	ProjectID        string
+	TeamID           string
+	Size             string // small, medium, large, xlarge
+	StorageGB        int
+	HighAvailability bool // Three instances, one primary and two streaming replicas
+	Version          string
+	Pooler           *PoolerInput // nil for none
+	EnvironmentID    string       // Environment the database is deleted with; empty for none
+
+	// source is the cluster a clone copies, set by CloneDatabase
+	source *DatabaseInfo
+}
+
+// PoolerInput configures the PgBouncer pooler in front of a database
+type PoolerInput struct {
+	Mode                 string // session, transaction or statement; defaults to transaction
+	PoolSize             int    // Server connections per user and database; defaults to 20
+	MaxClientConnections int    // Defaults to 1000
+}
+
+// DatabaseInfo holds database connection information
+type DatabaseInfo struct {
+	ID               string    `json:"id"`
+	Name             string    `json:"name"`
+	Type             string    `json:"type"`
+	Status           string    `json:"status"`
+	Endpoint         string    `json:"endpoint"`           // Read-write, follows the primary; through the pooler when there is one
+	DirectEndpoint   string    `json:"direct_endpoint"`    // Read-write, bypassing the pooler
+	ReadOnlyEndpoint string    `json:"read_only_endpoint"` // Replicas
+	PoolMode         string    `json:"pool_mode,omitempty"`
+	Port             int       `json:"port"`
+	ProjectID        string    `json:"project_id"`
+	EnvironmentID    string    `json:"environment_id,omitempty"`
+	CloneOf          string    `json:"clone_of,omitempty"` // Source of a clone
+	Database         string    `json:"database"`
+	Username         string    `json:"username"`
+	SecretName       string    `json:"secret_name"`
+	Version          string    `json:"version"`
+	StorageGB        int       `json:"storage_gb"`
+	Instances        int       `json:"instances"`
+	ReadyInstances   int       `json:"ready_instances"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ResourceConfig holds the resources of each instance for a size
+type ResourceConfig struct {
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string
+}
+
+// NewService creates a new PostgreSQL service
+func NewService(dynClient dynamic.Interface, namespace string) *Service {
+	return &Service{
+		dynamic:   dynClient,
+		namespace: namespace,
+	}
+}
+
+// CreateDatabase creates a new PostgreSQL cluster
+func (s *Service) CreateDatabase(ctx context.Context, input *CreateDatabaseInput) (*DatabaseInfo, error) {
+	clusterName := fmt.Sprintf("%s-%s", input.ProjectID, input.Name)
+	resources := s.mapSizeToResources(input.Size)
+
+	instances := 1
+	if input.HighAvailability {
+		instances = 3
+	}
+	version := input.Version
+	if version == "" {
+		version = DefaultVersion
+	}
+
+	labels := map[string]interface{}{
+		"northstack.io/project": input.ProjectID,
+		"northstack.io/team":    input.TeamID,
+		"northstack.io/type":    "database",
+		"northstack.io/engine":  "postgres",
+	}
+	if input.EnvironmentID != "" {
+		labels["northstack.io/environment"] = input.EnvironmentID
+	}
+	initdb := map[string]interface{}{
+		"database": input.Name,
+		"owner":    input.Name,
+	}
+	bootstrap := map[string]interface{}{"initdb": initdb}
+	var externalClusters []interface{}
+	owner := input.Name
+	if input.source != nil {
+		labels["northstack.io/clone-of"] = input.source.ID
+		bootstrap, externalClusters = s.cloneBootstrap(input.source)
+		owner = input.source.Username
+	}
+	var pooler *PoolerInput
+	if input.Pooler != nil {
+		pooler = input.Pooler.withDefaults()
+		labels["northstack.io/pool-mode"] = pooler.Mode
+		// The app secret holds the pooled connection string, so it is not
+		// left to the operator
+		initdb["secret"] = map[string]interface{}{"name": clusterName + "-app"}
+	}
+
+	cluster := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "postgresql.cnpg.io/v1",
+			"kind":       "Cluster",
+			"metadata": map[string]interface{}{
+				"name":      clusterName,
+				"namespace": s.namespace,
+				"labels":    labels,
+			},
+			"spec": map[string]interface{}{
+				"instances": int64(instances),
+				"imageName": fmt.Sprintf("ghcr.io/cloudnative-pg/postgresql:%s", version),
+				"storage": map[string]interface{}{
+					"size": fmt.Sprintf("%dGi", input.StorageGB),
+				},
+				"resources": map[string]interface{}{
+					"requests": map[string]interface{}{
+						"cpu":    resources.CPURequest,
+						"memory": resources.MemoryRequest,
+					},
+					"limits": map[string]interface{}{
+						"cpu":    resources.CPULimit,
+						"memory": resources.MemoryLimit,
+					},
+				},
+				"bootstrap": bootstrap,
+				"postgresql": map[string]interface{}{
+					"parameters": statsParameters(),
+				},
+				"managed": map[string]interface{}{
+					"roles": []interface{}{monitoringRole(owner)},
+				},
+				// Spread the instances over nodes so a node failure leaves a
+				// replica to promote
+				"affinity": map[string]interface{}{
+					"enablePodAntiAffinity": true,
+					"topologyKey":           "kubernetes.io/hostname",
+				},
+			},
+		},
+	}
+
+	if externalClusters != nil {
+		cluster.Object["spec"].(map[string]interface{})["externalClusters"] = externalClusters
+	}
+
+	created, err := s.dynamic.Resource(ClusterGVR).Namespace(s.namespace).Create(ctx, cluster, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PostgreSQL cluster: %w", err)
+	}
+	if pooler != nil {
+		if err := s.createPooler(ctx, created, input, pooler); err != nil {
+			_ = s.DeleteDatabase(ctx, clusterName)
+			return nil, err
+		}
+	}
+
+	info := s.extractDatabaseInfo(created)
+	info.Status = "creating"
+	return info, nil
+}
+
+// GetDatabase retrieves database information
+func (s *Service) GetDatabase(ctx context.Context, name string) (*DatabaseInfo, error) {
+	cluster, err := s.dynamic.Resource(ClusterGVR).Namespace(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.extractDatabaseInfo(cluster), nil
+}
+
+// ListDatabases lists the PostgreSQL clusters of a project, or all of them
+func (s *Service) ListDatabases(ctx context.Context, projectID string) ([]*DatabaseInfo, error) {
+	labelSelector := ""
+	if projectID != "" {
+		labelSelector = fmt.Sprintf("northstack.io/project=%s", projectID)
+	}
+
+	clusters, err := s.dynamic.Resource(ClusterGVR).Namespace(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var databases []*DatabaseInfo
+	for i := range clusters.Items {
+		databases = append(databases, s.extractDatabaseInfo(&clusters.Items[i]))
+	}
+
+	return databases, nil
+}
+
+// DeleteDatabase deletes a PostgreSQL cluster; the operator removes its
+// instances, volumes and secrets
+func (s *Service) DeleteDatabase(ctx context.Context, name string) error {
+	return s.dynamic.Resource(ClusterGVR).Namespace(s.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+}
+
+// ScaleDatabase sets the number of instances; the operator adds or removes
+// replicas, never the primary
+func (s *Service) ScaleDatabase(ctx context.Context, name string, instances int) error {
+	cluster, err := s.dynamic.Resource(ClusterGVR).Namespace(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if err := unstructured.SetNestedField(cluster.Object, int64(instances), "spec", "instances"); err != nil {
+		return err
+	}
+
+	_, err = s.dynamic.Resource(ClusterGVR).Namespace(s.namespace).Update(ctx, cluster, metav1.UpdateOptions{})
+	return err
+}
+
+// mapSizeToResources returns the resources of each instance for a size preset;
+// unknown sizes are small
+func (s *Service) mapSizeToResources(size string) ResourceConfig {
+	configs := map[string]ResourceConfig{
+		"small": {
+			CPURequest:    "250m",
+			CPULimit:      "500m",
+			MemoryRequest: "512Mi",
+			MemoryLimit:   "1Gi",
+		},
+		"medium": {
+			CPURequest:    "500m",
+			CPULimit:      "1",
+			MemoryRequest: "1Gi",
+			MemoryLimit:   "2Gi",
+		},
+		"large": {
+			CPURequest:    "1",
+			CPULimit:      "2",
+			MemoryRequest: "2Gi",
+			MemoryLimit:   "4Gi",
+		},
+		"xlarge": {
+			CPURequest:    "2",
+			CPULimit:      "4",
+			MemoryRequest: "4Gi",
+			MemoryLimit:   "8Gi",
+		},
+	}
+
+	if config, ok := configs[size]; ok {
+		return config
+	}
+	return configs["small"]
+}
+
+func (s *Service) extractDatabaseInfo(cluster *unstructured.Unstructured) *DatabaseInfo {
+	name := cluster.GetName()
+	info := &DatabaseInfo{
+		ID:               name,
+		Name:             name,
+		Type:             "postgres",
+		Endpoint:         fmt.Sprintf("%s-rw.%s.svc:%d", name, s.namespace, Port),
+		DirectEndpoint:   fmt.Sprintf("%s-rw.%s.svc:%d", name, s.namespace, Port),
+		ReadOnlyEndpoint: fmt.Sprintf("%s-ro.%s.svc:%d", name, s.namespace, Port),
+		Port:             Port,
+		PoolMode:         cluster.GetLabels()["northstack.io/pool-mode"],
+		ProjectID:        cluster.GetLabels()["northstack.io/project"],
+		EnvironmentID:    cluster.GetLabels()["northstack.io/environment"],
+		CloneOf:          cluster.GetLabels()["northstack.io/clone-of"],
+		SecretName:       fmt.Sprintf("%s-app", name),
+		CreatedAt:        cluster.GetCreationTimestamp().Time,
+	}
+	if info.PoolMode != "" {
+		info.Endpoint = fmt.Sprintf("%s.%s.svc:%d", poolerName(name), s.namespace, Port)
+	}
+
+	if instances, ok, _ := unstructured.NestedInt64(cluster.Object, "spec", "instances"); ok {
+		info.Instances = int(instances)
+	}
+	if image, ok, _ := unstructured.NestedString(cluster.Object, "spec", "imageName"); ok {
+		info.Version = imageTag(image)
+	}
+	if size, ok, _ := unstructured.NestedString(cluster.Object, "spec", "storage", "size"); ok {
+		fmt.Sscanf(size, "%dGi", &info.StorageGB)
+	}
+	// Clones keep the database and owner of their source
+	for _, method := range []string{"initdb", "pg_basebackup"} {
+		if database, ok, _ := unstructured.NestedString(cluster.Object, "spec", "bootstrap", method, "database"); ok {
+			info.Name = database
+			info.Database = database
+		}
+		if owner, ok, _ := unstructured.NestedString(cluster.Object, "spec", "bootstrap", method, "owner"); ok {
+			info.Username = owner
+		}
+	}
+	if ready, ok, _ := unstructured.NestedInt64(cluster.Object, "status", "readyInstances"); ok {
+		info.ReadyInstances = int(ready)
+	}
+	info.Status = status(cluster, info)
+
+	return info
+}
+
+// status summarizes the phase the operator reports: "ready" once every
+// instance is, "creating" until the first instance is, "degraded" otherwise
+func status(cluster *unstructured.Unstructured, info *DatabaseInfo) string {
+	phase, _, _ := unstructured.NestedString(cluster.Object, "status", "phase")
+	switch {
+	case phase == "":
+		return "creating"
+	case info.Instances > 0 && info.ReadyInstances == info.Instances:
+		return "ready"
+	case info.ReadyInstances == 0:
+		return "creating"
+	default:
+		return "degraded"
+	}
+}
+
+// imageTag returns the tag of an image reference
+func imageTag(image string) string {
+	for i := len(image) - 1; i >= 0; i-- {
+		switch image[i] {
+		case ':':
+			return image[i+1:]
+		case '/':
+			return ""
+		}
+	}
+	return ""
+}
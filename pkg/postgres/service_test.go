@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+const testNamespace = "databases"
+
+func newTestService(objects ...runtime.Object) *Service {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ClusterGVR: "ClusterList",
+		PoolerGVR:  "PoolerList",
+		SecretGVR:  "SecretList",
+	}, objects...)
+	return NewService(client, testNamespace)
+}
+
+func get(t *testing.T, s *Service, gvr schema.GroupVersionResource, name string) *unstructured.Unstructured {
+	obj, err := s.dynamic.Resource(gvr).Namespace(testNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return obj
+}
+
+func TestCreateDatabase(t *testing.T) {
+	s := newTestService()
+
+	info, err := s.CreateDatabase(context.Background(), &CreateDatabaseInput{
+		Name:             "orders",
+		ProjectID:        "p1",
+		TeamID:           "t1",
+		Size:             "medium",
+		StorageGB:        20,
+		HighAvailability: true,
+		EnvironmentID:    "e1",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "p1-orders", info.ID)
+	assert.Equal(t, "creating", info.Status)
+	assert.Equal(t, "orders", info.Database)
+	assert.Equal(t, "orders", info.Username)
+	assert.Equal(t, DefaultVersion, info.Version)
+	assert.Equal(t, 3, info.Instances)
+	assert.Equal(t, 20, info.StorageGB)
+	assert.Equal(t, "e1", info.EnvironmentID)
+	assert.Equal(t, "p1-orders-rw.databases.svc:5432", info.Endpoint)
+	assert.Equal(t, info.Endpoint, info.DirectEndpoint)
+	assert.Equal(t, "p1-orders-ro.databases.svc:5432", info.ReadOnlyEndpoint)
+	assert.Equal(t, "p1-orders-app", info.SecretName)
+
+	cluster := get(t, s, ClusterGVR, "p1-orders")
+	cpu, _, _ := unstructured.NestedString(cluster.Object, "spec", "resources", "limits", "cpu")
+	assert.Equal(t, "1", cpu)
+	roles, _, _ := unstructured.NestedSlice(cluster.Object, "spec", "managed", "roles")
+	assert.Equal(t, []interface{}{monitoringRole("orders")}, roles)
+	_, hasSecret, _ := unstructured.NestedMap(cluster.Object, "spec", "bootstrap", "initdb", "secret")
+	assert.False(t, hasSecret, "the operator generates the app secret without a pooler")
+}
+
+func TestListDatabases(t *testing.T) {
+	s := newTestService()
+	ctx := context.Background()
+	for _, input := range []*CreateDatabaseInput{
+		{Name: "orders", ProjectID: "p1", StorageGB: 10},
+		{Name: "users", ProjectID: "p1", StorageGB: 10},
+		{Name: "orders", ProjectID: "p2", StorageGB: 10},
+	} {
+		_, err := s.CreateDatabase(ctx, input)
+		require.NoError(t, err)
+	}
+
+	databases, err := s.ListDatabases(ctx, "p1")
+	require.NoError(t, err)
+	assert.Len(t, databases, 2)
+
+	databases, err = s.ListDatabases(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, databases, 3)
+}
+
+func TestScaleDatabase(t *testing.T) {
+	s := newTestService()
+	ctx := context.Background()
+	_, err := s.CreateDatabase(ctx, &CreateDatabaseInput{Name: "orders", ProjectID: "p1", StorageGB: 10})
+	require.NoError(t, err)
+
+	require.NoError(t, s.ScaleDatabase(ctx, "p1-orders", 3))
+	info, err := s.GetDatabase(ctx, "p1-orders")
+	require.NoError(t, err)
+	assert.Equal(t, 3, info.Instances)
+}
+
+func TestMapSizeToResources(t *testing.T) {
+	s := newTestService()
+	assert.Equal(t, "4Gi", s.mapSizeToResources("large").MemoryLimit)
+	assert.Equal(t, s.mapSizeToResources("small"), s.mapSizeToResources("huge"))
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		phase     string
+		instances int
+		ready     int
+		want      string
+	}{
+		{"no phase yet", "", 3, 0, "creating"},
+		{"all ready", "Cluster in healthy state", 3, 3, "ready"},
+		{"none ready", "Setting up primary", 3, 0, "creating"},
+		{"replica down", "Waiting for the instances to become active", 3, 2, "degraded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			if tt.phase != "" {
+				require.NoError(t, unstructured.SetNestedField(cluster.Object, tt.phase, "status", "phase"))
+			}
+			assert.Equal(t, tt.want, status(cluster, &DatabaseInfo{Instances: tt.instances, ReadyInstances: tt.ready}))
+		})
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	assert.Equal(t, "16", imageTag("ghcr.io/cloudnative-pg/postgresql:16"))
+	assert.Equal(t, "16.4-27", imageTag("localhost:5000/postgresql:16.4-27"))
+	assert.Equal(t, "", imageTag("localhost:5000/postgresql"))
+	assert.Equal(t, "", imageTag(""))
+}
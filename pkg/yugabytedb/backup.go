@@ -0,0 +1,435 @@
+package yugabytedb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/northstack/platform/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// YugabyteDB operator backup GVRs. Backups run through the YugabyteDB
+// controller (ybc) of each cluster.
+var (
+	StorageConfigGVR = schema.GroupVersionResource{
+		Group:    "operator.yugabyte.io",
+		Version:  "v1alpha1",
+		Resource: "storageconfigs",
+	}
+	BackupGVR = schema.GroupVersionResource{
+		Group:    "operator.yugabyte.io",
+		Version:  "v1alpha1",
+		Resource: "backups",
+	}
+	BackupScheduleGVR = schema.GroupVersionResource{
+		Group:    "operator.yugabyte.io",
+		Version:  "v1alpha1",
+		Resource: "backupschedules",
+	}
+	RestoreJobGVR = schema.GroupVersionResource{
+		Group:    "operator.yugabyte.io",
+		Version:  "v1alpha1",
+		Resource: "restorejobs",
+	}
+)
+
+// storageConfigName is the operator StorageConfig every backup is stored with
+const storageConfigName = "northstack-backups"
+
+// Backup states
+const (
+	BackupRunning   = "running"
+	BackupCompleted = "completed"
+	BackupFailed    = "failed"
+)
+
+// BackupConfig holds the S3-compatible storage backups go to and how often
+// scheduled backups run
+type BackupConfig struct {
+	Endpoint        string // Empty for AWS S3
+	Bucket          string
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool
+
+	Schedule            string        // Cron expression of full backups
+	IncrementalInterval time.Duration // Incremental backups between full ones, and restore granularity; 0 disables them
+	Retention           time.Duration
+}
+
+// BackupInfo describes a backup of a database
+type BackupInfo struct {
+	ID         string     `json:"id"`
+	DatabaseID string     `json:"database_id"`
+	Scheduled  bool       `json:"scheduled"`
+	Status     string     `json:"status"`
+	Message    string     `json:"message,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// RestoreDatabaseInput holds parameters for restoring a backup into a new
+// database. Without a backup ID, the latest completed backup taken at or
+// before RestoreTime is restored, or the latest one without a time.
+type RestoreDatabaseInput struct {
+	SourceID    string
+	BackupID    string
+	RestoreTime *time.Time
+	Name        string
+	ProjectID   string
+	TeamID      string
+	Size        string
+	StorageGB   int
+
+	EnvironmentID string // Environment the new database is deleted with; empty for none
+}
+
+// BackupsEnabled reports whether databases can be backed up
+func (s *DatabaseService) BackupsEnabled() bool {
+	return s.backups != nil
+}
+
+// EnsureStorageConfig creates or updates the operator StorageConfig backups
+// are stored with, keeping its secret access key in a Secret
+func (s *DatabaseService) EnsureStorageConfig(ctx context.Context) error {
+	if s.backups == nil {
+		return nil
+	}
+
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":      storageConfigName,
+				"namespace": s.namespace,
+			},
+			"type": "Opaque",
+			"stringData": map[string]interface{}{
+				"awsSecretAccessKey": s.backups.SecretAccessKey,
+			},
+		},
+	}
+	if err := s.apply(ctx, secretGVR, secret); err != nil {
+		return fmt.Errorf("failed to store backup credentials: %w", err)
+	}
+
+	location := "s3://" + s.backups.Bucket
+	if prefix := strings.Trim(s.backups.Prefix, "/"); prefix != "" {
+		location += "/" + prefix
+	}
+	data := map[string]interface{}{
+		"AWS_ACCESS_KEY_ID": s.backups.AccessKeyID,
+		"BACKUP_LOCATION":   location,
+	}
+	if s.backups.Endpoint != "" {
+		host := s.backups.Endpoint
+		if u, err := url.Parse(host); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		data["AWS_HOST_BASE"] = host
+	}
+	if s.backups.PathStyle {
+		data["PATH_STYLE_ACCESS"] = "true"
+	}
+	storageConfig := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "operator.yugabyte.io/v1alpha1",
+			"kind":       "StorageConfig",
+			"metadata": map[string]interface{}{
+				"name":      storageConfigName,
+				"namespace": s.namespace,
+			},
+			"spec": map[string]interface{}{
+				"config_type": "STORAGE_S3",
+				"data":        data,
+				"awsSecretAccessKeySecret": map[string]interface{}{
+					"secretName": storageConfigName,
+					"key":        "awsSecretAccessKey",
+				},
+			},
+		},
+	}
+	if err := s.apply(ctx, StorageConfigGVR, storageConfig); err != nil {
+		return fmt.Errorf("failed to create backup storage config: %w", err)
+	}
+	return nil
+}
+
+// CreateBackup starts a full backup of a database
+func (s *DatabaseService) CreateBackup(ctx context.Context, databaseID string) (*BackupInfo, error) {
+	if s.backups == nil {
+		return nil, errors.BadRequest("database backups are not enabled")
+	}
+	db, err := s.GetDatabase(ctx, databaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "operator.yugabyte.io/v1alpha1",
+			"kind":       "Backup",
+			"metadata": map[string]interface{}{
+				"name":      fmt.Sprintf("%s-%d", db.ID, time.Now().Unix()),
+				"namespace": s.namespace,
+				"labels": map[string]interface{}{
+					"northstack.io/database": db.ID,
+				},
+			},
+			"spec": map[string]interface{}{
+				"backupType":         "PGSQL_TABLE_TYPE",
+				"storageConfig":      storageConfigName,
+				"universe":           db.ID,
+				"keyspace":           db.Database,
+				"tableByTableBackup": false,
+				"timeBeforeDelete":   s.backups.Retention.Milliseconds(),
+			},
+		},
+	}
+	created, err := s.dynamic.Resource(BackupGVR).Namespace(s.namespace).Create(ctx, backup, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+	return s.extractBackupInfo(created), nil
+}
+
+// ListBackups lists the backups of a database, scheduled and on-demand, newest
+// first
+func (s *DatabaseService) ListBackups(ctx context.Context, databaseID string) ([]*BackupInfo, error) {
+	if s.backups == nil {
+		return []*BackupInfo{}, nil
+	}
+	if _, err := s.GetDatabase(ctx, databaseID); err != nil {
+		return nil, err
+	}
+	all, err := s.listBackups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backups := all[databaseID]
+	if backups == nil {
+		backups = []*BackupInfo{}
+	}
+	return backups, nil
+}
+
+// RestoreDatabase creates a database from a backup of another one. The new
+// database has the replicas of the source and is empty until the restore job
+// completes.
+func (s *DatabaseService) RestoreDatabase(ctx context.Context, input *RestoreDatabaseInput) (*DatabaseInfo, *BackupInfo, error) {
+	if s.backups == nil {
+		return nil, nil, errors.BadRequest("database backups are not enabled")
+	}
+	source, err := s.GetDatabase(ctx, input.SourceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	backups, err := s.ListBackups(ctx, source.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	backup, err := pickBackup(backups, input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := s.CreateDatabase(ctx, &CreateDatabaseInput{
+		Name:            input.Name,
+		ProjectID:       input.ProjectID,
+		TeamID:          input.TeamID,
+		Size:            input.Size,
+		StorageGB:       input.StorageGB,
+		TServerReplicas: source.TServerReplicas,
+		MasterReplicas:  source.MasterReplicas,
+		Version:         source.Version,
+		EnvironmentID:   input.EnvironmentID,
+		CloneOf:         source.ID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	cluster, err := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).Get(ctx, db.ID, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	restore := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "operator.yugabyte.io/v1alpha1",
+			"kind":       "RestoreJob",
+			"metadata": map[string]interface{}{
+				"name":            db.ID + "-restore",
+				"namespace":       s.namespace,
+				"labels":          map[string]interface{}{"northstack.io/database": db.ID},
+				"ownerReferences": clusterOwner(cluster),
+			},
+			"spec": map[string]interface{}{
+				"actionType": "RESTORE",
+				"universe":   db.ID,
+				"backup":     backup.ID,
+				"keyspace":   db.Database,
+			},
+		},
+	}
+	if _, err := s.dynamic.Resource(RestoreJobGVR).Namespace(s.namespace).Create(ctx, restore, metav1.CreateOptions{}); err != nil {
+		_ = s.DeleteDatabase(ctx, db.ID)
+		return nil, nil, fmt.Errorf("failed to create restore job: %w", err)
+	}
+	return db, backup, nil
+}
+
+// pickBackup returns the completed backup a restore starts from
+func pickBackup(backups []*BackupInfo, input *RestoreDatabaseInput) (*BackupInfo, error) {
+	for _, backup := range backups {
+		if input.BackupID != "" && backup.ID != input.BackupID {
+			continue
+		}
+		if backup.Status != BackupCompleted {
+			if input.BackupID != "" {
+				return nil, errors.BadRequest(fmt.Sprintf("backup %s is %s", backup.ID, backup.Status))
+			}
+			continue
+		}
+		if input.BackupID == "" && input.RestoreTime != nil && backup.CreatedAt.After(*input.RestoreTime) {
+			continue
+		}
+		return backup, nil
+	}
+	if input.BackupID != "" {
+		return nil, errors.NotFound("backup", input.BackupID)
+	}
+	if input.RestoreTime != nil {
+		return nil, errors.BadRequest("no completed backup was taken at or before " + input.RestoreTime.Format(time.RFC3339))
+	}
+	return nil, errors.BadRequest("the database has no completed backup")
+}
+
+// createBackupSchedule schedules full and incremental backups of a new
+// database. The schedule is owned by the cluster; backups outlive it.
+func (s *DatabaseService) createBackupSchedule(ctx context.Context, cluster *unstructured.Unstructured, keyspace string) error {
+	spec := map[string]interface{}{
+		"backupType":               "PGSQL_TABLE_TYPE",
+		"storageConfig":            storageConfigName,
+		"universe":                 cluster.GetName(),
+		"keyspace":                 keyspace,
+		"cronExpression":           s.backups.Schedule,
+		"timeBeforeDelete":         s.backups.Retention.Milliseconds(),
+		"enablePointInTimeRestore": s.backups.IncrementalInterval > 0,
+	}
+	if s.backups.IncrementalInterval > 0 {
+		spec["incrementalBackupFrequency"] = s.backups.IncrementalInterval.Milliseconds()
+	}
+
+	schedule := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "operator.yugabyte.io/v1alpha1",
+			"kind":       "BackupSchedule",
+			"metadata": map[string]interface{}{
+				"name":            cluster.GetName() + "-backups",
+				"namespace":       s.namespace,
+				"labels":          map[string]interface{}{"northstack.io/database": cluster.GetName()},
+				"ownerReferences": clusterOwner(cluster),
+			},
+			"spec": spec,
+		},
+	}
+	_, err := s.dynamic.Resource(BackupScheduleGVR).Namespace(s.namespace).Create(ctx, schedule, metav1.CreateOptions{})
+	return err
+}
+
+// listBackups returns the backups of every database by database ID, newest
+// first
+func (s *DatabaseService) listBackups(ctx context.Context) (map[string][]*BackupInfo, error) {
+	list, err := s.dynamic.Resource(BackupGVR).Namespace(s.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	byDatabase := make(map[string][]*BackupInfo)
+	for i := range list.Items {
+		backup := s.extractBackupInfo(&list.Items[i])
+		byDatabase[backup.DatabaseID] = append(byDatabase[backup.DatabaseID], backup)
+	}
+	for _, backups := range byDatabase {
+		sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	}
+	return byDatabase, nil
+}
+
+// lastBackupTime returns the time of the latest completed backup
+func lastBackupTime(backups []*BackupInfo) *time.Time {
+	for _, backup := range backups {
+		if backup.Status == BackupCompleted {
+			t := backup.CreatedAt
+			return &t
+		}
+	}
+	return nil
+}
+
+func (s *DatabaseService) extractBackupInfo(backup *unstructured.Unstructured) *BackupInfo {
+	databaseID, _, _ := unstructured.NestedString(backup.Object, "spec", "universe")
+	message, _, _ := unstructured.NestedString(backup.Object, "status", "message")
+	info := &BackupInfo{
+		ID:         backup.GetName(),
+		DatabaseID: databaseID,
+		Scheduled:  backup.GetLabels()["northstack.io/database"] == "",
+		Status:     backupState(message),
+		Message:    message,
+		CreatedAt:  backup.GetCreationTimestamp().Time,
+	}
+	if ttl, ok, _ := unstructured.NestedInt64(backup.Object, "spec", "timeBeforeDelete"); ok && ttl > 0 {
+		expires := info.CreatedAt.Add(time.Duration(ttl) * time.Millisecond)
+		info.ExpiresAt = &expires
+	}
+	return info
+}
+
+// backupState maps the status message the operator reports for a backup to
+// a state
+func backupState(message string) string {
+	message = strings.ToLower(message)
+	switch {
+	case strings.Contains(message, "fail"):
+		return BackupFailed
+	case strings.Contains(message, "success") || strings.Contains(message, "complete"):
+		return BackupCompleted
+	}
+	return BackupRunning
+}
+
+// apply creates an object, or replaces the one that exists
+func (s *DatabaseService) apply(ctx context.Context, gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	client := s.dynamic.Resource(gvr).Namespace(s.namespace)
+	existing, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+	return err
+}
+
+func clusterOwner(cluster *unstructured.Unstructured) []interface{} {
+	return []interface{}{map[string]interface{}{
+		"apiVersion":         "yugabyte.com/v1alpha1",
+		"kind":               "YBCluster",
+		"name":               cluster.GetName(),
+		"uid":                string(cluster.GetUID()),
+		"controller":         true,
+		"blockOwnerDeletion": true,
+	}}
+}
@@ -0,0 +1,188 @@
+package yugabytedb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func testBackupConfig() *BackupConfig {
+	return &BackupConfig{
+		Endpoint:            "https://minio.example.com:9000",
+		Bucket:              "backups",
+		Prefix:              "/yugabyte/",
+		AccessKeyID:         "AKIA",
+		SecretAccessKey:     "secret",
+		PathStyle:           true,
+		Schedule:            "0 3 * * *",
+		IncrementalInterval: 15 * time.Minute,
+		Retention:           7 * 24 * time.Hour,
+	}
+}
+
+// addBackup stores a backup of a database as the operator reports it
+func addBackup(t *testing.T, s *DatabaseService, name, databaseID, message string, createdAt time.Time) {
+	backup := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "operator.yugabyte.io/v1alpha1",
+		"kind":       "Backup",
+		"metadata":   map[string]interface{}{"name": name, "namespace": testNamespace},
+		"spec":       map[string]interface{}{"universe": databaseID, "timeBeforeDelete": int64(time.Hour / time.Millisecond)},
+		"status":     map[string]interface{}{"message": message},
+	}}
+	backup.SetCreationTimestamp(metav1.NewTime(createdAt))
+	_, err := s.dynamic.Resource(BackupGVR).Namespace(testNamespace).Create(context.Background(), backup, metav1.CreateOptions{})
+	require.NoError(t, err)
+}
+
+func TestEnsureStorageConfig(t *testing.T) {
+	assert.NoError(t, newTestService(nil).EnsureStorageConfig(context.Background()))
+
+	s := newTestService(testBackupConfig())
+	require.NoError(t, s.EnsureStorageConfig(context.Background()))
+	// A second call replaces the existing objects
+	require.NoError(t, s.EnsureStorageConfig(context.Background()))
+
+	config := get(t, s, StorageConfigGVR, storageConfigName)
+	data, _, _ := unstructured.NestedStringMap(config.Object, "spec", "data")
+	assert.Equal(t, map[string]string{
+		"AWS_ACCESS_KEY_ID": "AKIA",
+		"BACKUP_LOCATION":   "s3://backups/yugabyte",
+		"AWS_HOST_BASE":     "minio.example.com:9000",
+		"PATH_STYLE_ACCESS": "true",
+	}, data)
+
+	secret := get(t, s, secretGVR, storageConfigName)
+	key, _, _ := unstructured.NestedString(secret.Object, "stringData", "awsSecretAccessKey")
+	assert.Equal(t, "secret", key)
+}
+
+func TestCreateDatabaseSchedulesBackups(t *testing.T) {
+	s := newTestService(testBackupConfig())
+
+	_, err := s.CreateDatabase(context.Background(), &CreateDatabaseInput{Name: "orders", ProjectID: "p1", BackupEnabled: true})
+	require.NoError(t, err)
+
+	schedule := get(t, s, BackupScheduleGVR, "p1-orders-backups")
+	spec, _, _ := unstructured.NestedMap(schedule.Object, "spec")
+	assert.Equal(t, "0 3 * * *", spec["cronExpression"])
+	assert.Equal(t, "orders", spec["keyspace"])
+	assert.Equal(t, true, spec["enablePointInTimeRestore"])
+	assert.Equal(t, int64(15*60*1000), spec["incrementalBackupFrequency"])
+	assert.Len(t, schedule.GetOwnerReferences(), 1)
+}
+
+func TestBackups(t *testing.T) {
+	ctx := context.Background()
+	_, err := newTestService(nil).CreateBackup(ctx, "p1-orders")
+	assert.Error(t, err, "backups are not enabled")
+
+	s := newTestService(testBackupConfig())
+	_, err = s.CreateDatabase(ctx, &CreateDatabaseInput{Name: "orders", ProjectID: "p1"})
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+	addBackup(t, s, "scheduled-1", "p1-orders", "Backup completed successfully", now.Add(-2*time.Hour))
+	addBackup(t, s, "scheduled-2", "p1-orders", "Backup failed: out of space", now.Add(-time.Hour))
+	addBackup(t, s, "other", "p1-users", "Backup completed successfully", now)
+
+	backup, err := s.CreateBackup(ctx, "p1-orders")
+	require.NoError(t, err)
+	assert.Equal(t, "p1-orders", backup.DatabaseID)
+	assert.False(t, backup.Scheduled)
+	assert.Equal(t, BackupRunning, backup.Status)
+
+	backups, err := s.ListBackups(ctx, "p1-orders")
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+	// Newest first; the fake API server sets no creation time on the new one
+	assert.Equal(t, []string{"scheduled-2", "scheduled-1", backup.ID}, []string{backups[0].ID, backups[1].ID, backups[2].ID})
+	assert.True(t, backups[0].Scheduled)
+	assert.Equal(t, BackupFailed, backups[0].Status)
+	assert.Equal(t, "Backup failed: out of space", backups[0].Message)
+	assert.Equal(t, now, *backups[0].ExpiresAt)
+	assert.Equal(t, BackupCompleted, backups[1].Status)
+
+	db, err := s.GetDatabase(ctx, "p1-orders")
+	require.NoError(t, err)
+	require.NotNil(t, db.LastBackupTime)
+	assert.Equal(t, now.Add(-2*time.Hour), *db.LastBackupTime, "the last completed backup")
+}
+
+func TestPickBackup(t *testing.T) {
+	now := time.Now()
+	// Newest first, as ListBackups returns them
+	backups := []*BackupInfo{
+		{ID: "running", Status: BackupRunning, CreatedAt: now},
+		{ID: "recent", Status: BackupCompleted, CreatedAt: now.Add(-time.Hour)},
+		{ID: "old", Status: BackupCompleted, CreatedAt: now.Add(-24 * time.Hour)},
+	}
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	tests := []struct {
+		name   string
+		input  *RestoreDatabaseInput
+		want   string
+		status int
+	}{
+		{"latest completed", &RestoreDatabaseInput{}, "recent", 0},
+		{"by ID", &RestoreDatabaseInput{BackupID: "old"}, "old", 0},
+		{"by time", &RestoreDatabaseInput{RestoreTime: at(-2 * time.Hour)}, "old", 0},
+		{"unfinished backup", &RestoreDatabaseInput{BackupID: "running"}, "", http.StatusBadRequest},
+		{"unknown backup", &RestoreDatabaseInput{BackupID: "missing"}, "", http.StatusNotFound},
+		{"before every backup", &RestoreDatabaseInput{RestoreTime: at(-48 * time.Hour)}, "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backup, err := pickBackup(backups, tt.input)
+			if tt.status == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, backup.ID)
+				return
+			}
+			var appErr *errors.AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, tt.status, appErr.HTTPStatus)
+		})
+	}
+
+	_, err := pickBackup(nil, &RestoreDatabaseInput{})
+	assert.Error(t, err)
+}
+
+func TestRestoreDatabase(t *testing.T) {
+	s := newTestService(testBackupConfig())
+	ctx := context.Background()
+	_, err := s.CreateDatabase(ctx, &CreateDatabaseInput{Name: "orders", ProjectID: "p1", HighAvailability: true, Version: "2.20.2.0-b145"})
+	require.NoError(t, err)
+	addBackup(t, s, "nightly", "p1-orders", "Backup completed successfully", time.Now().Add(-time.Hour))
+
+	db, backup, err := s.RestoreDatabase(ctx, &RestoreDatabaseInput{SourceID: "p1-orders", Name: "orders-pr-1", ProjectID: "p1", EnvironmentID: "e1"})
+	require.NoError(t, err)
+	assert.Equal(t, "nightly", backup.ID)
+	assert.Equal(t, "p1-orders-pr-1", db.ID)
+	assert.Equal(t, "p1-orders", db.CloneOf)
+	assert.Equal(t, 3, db.TServerReplicas)
+	assert.Equal(t, "2.20.2.0-b145", db.Version)
+
+	restore := get(t, s, RestoreJobGVR, "p1-orders-pr-1-restore")
+	spec, _, _ := unstructured.NestedStringMap(restore.Object, "spec")
+	assert.Equal(t, map[string]string{"actionType": "RESTORE", "universe": "p1-orders-pr-1", "backup": "nightly", "keyspace": "orders-pr-1"}, spec)
+}
+
+func TestBackupState(t *testing.T) {
+	assert.Equal(t, BackupCompleted, backupState("Backup completed successfully"))
+	assert.Equal(t, BackupCompleted, backupState("SUCCESS"))
+	assert.Equal(t, BackupFailed, backupState("Backup FAILED"))
+	assert.Equal(t, BackupRunning, backupState(""))
+	assert.Equal(t, BackupRunning, backupState("In progress"))
+}
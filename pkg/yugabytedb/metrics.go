@@ -0,0 +1,297 @@
+package yugabytedb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/northstack/platform/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// HTTP ports of YugabyteDB processes
+const (
+	masterHTTPPort  = 7000
+	tserverHTTPPort = 9000
+	ysqlHTTPPort    = 13000
+)
+
+// DatabaseMetrics is a point-in-time view of the health and load of a
+// database, collected from the HTTP endpoints of its masters and tablet
+// servers
+type DatabaseMetrics struct {
+	Healthy                bool           `json:"healthy"`
+	Connections            int            `json:"connections"`
+	MaxConnections         int            `json:"max_connections"`
+	QueriesPerSecond       float64        `json:"queries_per_second"`
+	ReplicationLagMs       int64          `json:"replication_lag_ms"` // Of the slowest tablet follower
+	DiskUsedBytes          int64          `json:"disk_used_bytes"`
+	DiskTotalBytes         int64          `json:"disk_total_bytes"`
+	UnderReplicatedTablets int            `json:"under_replicated_tablets"`
+	DeadNodes              []string       `json:"dead_nodes"`
+	Nodes                  []*NodeMetrics `json:"nodes"`
+	CollectedAt            time.Time      `json:"collected_at"`
+}
+
+// NodeMetrics are the metrics of one tablet server
+type NodeMetrics struct {
+	Name             string  `json:"name"`
+	Status           string  `json:"status"` // ALIVE or DEAD
+	Connections      int     `json:"connections"`
+	ReadOpsPerSec    float64 `json:"read_ops_per_sec"`
+	WriteOpsPerSec   float64 `json:"write_ops_per_sec"`
+	ReplicationLagMs int64   `json:"replication_lag_ms"`
+	DiskUsedBytes    int64   `json:"disk_used_bytes"`
+	DiskTotalBytes   int64   `json:"disk_total_bytes"`
+	UptimeSeconds    int64   `json:"uptime_seconds"`
+}
+
+// SlowQuery is a statement from pg_stat_statements, with times in
+// milliseconds
+type SlowQuery struct {
+	Query     string  `json:"query"`
+	Calls     int64   `json:"calls"`
+	TotalTime float64 `json:"total_time_ms"`
+	MeanTime  float64 `json:"mean_time_ms"`
+	MaxTime   float64 `json:"max_time_ms"`
+	Rows      int64   `json:"rows"`
+}
+
+// tabletServer is a tablet server in the response of the master's
+// /api/v1/tablet-servers endpoint
+type tabletServer struct {
+	Status         string  `json:"status"`
+	UptimeSeconds  int64   `json:"uptime_seconds"`
+	ReadOpsPerSec  float64 `json:"read_ops_per_sec"`
+	WriteOpsPerSec float64 `json:"write_ops_per_sec"`
+	PathMetrics    []struct {
+		SpaceUsed      int64 `json:"space_used"`
+		TotalSpaceSize int64 `json:"total_space_size"`
+	} `json:"path_metrics"`
+}
+
+// GetMetrics collects the metrics of a database. Unreachable tablet servers
+// are reported without their connections and replication lag.
+func (s *DatabaseService) GetMetrics(ctx context.Context, name string) (*DatabaseMetrics, error) {
+	cluster, err := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	info := s.extractDatabaseInfo(cluster)
+	master := s.masterURL(name)
+
+	var health struct {
+		DeadNodes              []string `json:"dead_nodes"`
+		UnderReplicatedTablets []string `json:"under_replicated_tablets"`
+	}
+	if err := s.getJSON(ctx, master+"/api/v1/health-check", &health); err != nil {
+		return nil, err
+	}
+	var servers map[string]map[string]*tabletServer
+	if err := s.getJSON(ctx, master+"/api/v1/tablet-servers", &servers); err != nil {
+		return nil, err
+	}
+
+	metrics := &DatabaseMetrics{
+		Healthy:                len(health.DeadNodes) == 0 && len(health.UnderReplicatedTablets) == 0,
+		UnderReplicatedTablets: len(health.UnderReplicatedTablets),
+		DeadNodes:              health.DeadNodes,
+		Nodes:                  []*NodeMetrics{},
+		CollectedAt:            time.Now(),
+	}
+	if metrics.DeadNodes == nil {
+		metrics.DeadNodes = []string{}
+	}
+	for _, group := range servers {
+		for address, server := range group {
+			node := &NodeMetrics{
+				Name:           address,
+				Status:         server.Status,
+				ReadOpsPerSec:  server.ReadOpsPerSec,
+				WriteOpsPerSec: server.WriteOpsPerSec,
+				UptimeSeconds:  server.UptimeSeconds,
+			}
+			for _, path := range server.PathMetrics {
+				node.DiskUsedBytes += path.SpaceUsed
+				node.DiskTotalBytes += path.TotalSpaceSize
+			}
+			if server.Status == "ALIVE" {
+				host := nodeHost(address)
+				node.Connections = s.connections(ctx, host, info.Database)
+				node.ReplicationLagMs = s.followerLag(ctx, host)
+			}
+			metrics.Nodes = append(metrics.Nodes, node)
+
+			metrics.Connections += node.Connections
+			metrics.QueriesPerSecond += node.ReadOpsPerSec + node.WriteOpsPerSec
+			metrics.DiskUsedBytes += node.DiskUsedBytes
+			metrics.DiskTotalBytes += node.DiskTotalBytes
+			if node.ReplicationLagMs > metrics.ReplicationLagMs {
+				metrics.ReplicationLagMs = node.ReplicationLagMs
+			}
+		}
+	}
+	sort.Slice(metrics.Nodes, func(i, j int) bool { return metrics.Nodes[i].Name < metrics.Nodes[j].Name })
+	metrics.MaxConnections = maxConnections(cluster) * len(metrics.Nodes)
+	return metrics, nil
+}
+
+// SlowQueries returns the statements of a database with a mean time of at
+// least minMeanTime milliseconds, slowest first. Statements run on several
+// tablet servers are merged.
+func (s *DatabaseService) SlowQueries(ctx context.Context, name string, minMeanTime float64, limit int) ([]*SlowQuery, error) {
+	if _, err := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).Get(ctx, name, metav1.GetOptions{}); err != nil {
+		return nil, err
+	}
+	var servers map[string]map[string]*tabletServer
+	if err := s.getJSON(ctx, s.masterURL(name)+"/api/v1/tablet-servers", &servers); err != nil {
+		return nil, err
+	}
+
+	merged := map[string]*SlowQuery{}
+	for _, group := range servers {
+		for address, server := range group {
+			if server.Status != "ALIVE" {
+				continue
+			}
+			var statements struct {
+				Statements []struct {
+					Query     string  `json:"query"`
+					Calls     int64   `json:"calls"`
+					TotalTime float64 `json:"total_time"`
+					MaxTime   float64 `json:"max_time"`
+					Rows      int64   `json:"rows"`
+				} `json:"statements"`
+			}
+			url := fmt.Sprintf("http://%s/statements", net.JoinHostPort(nodeHost(address), strconv.Itoa(ysqlHTTPPort)))
+			if err := s.getJSON(ctx, url, &statements); err != nil {
+				return nil, err
+			}
+			for _, st := range statements.Statements {
+				query, ok := merged[st.Query]
+				if !ok {
+					query = &SlowQuery{Query: st.Query}
+					merged[st.Query] = query
+				}
+				query.Calls += st.Calls
+				query.TotalTime += st.TotalTime
+				query.Rows += st.Rows
+				if st.MaxTime > query.MaxTime {
+					query.MaxTime = st.MaxTime
+				}
+			}
+		}
+	}
+
+	queries := []*SlowQuery{}
+	for _, query := range merged {
+		if query.Calls == 0 {
+			continue
+		}
+		query.MeanTime = query.TotalTime / float64(query.Calls)
+		if query.MeanTime >= minMeanTime {
+			queries = append(queries, query)
+		}
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].MeanTime > queries[j].MeanTime })
+	if limit > 0 && len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return queries, nil
+}
+
+// connections counts the client connections to a database on a tablet
+// server, or returns 0 when its YSQL webserver cannot be reached
+func (s *DatabaseService) connections(ctx context.Context, host, database string) int {
+	var rpcz struct {
+		Connections []struct {
+			BackendType string `json:"backend_type"`
+			DBName      string `json:"db_name"`
+		} `json:"connections"`
+	}
+	url := fmt.Sprintf("http://%s/rpcz", net.JoinHostPort(host, strconv.Itoa(ysqlHTTPPort)))
+	if err := s.getJSON(ctx, url, &rpcz); err != nil {
+		return 0
+	}
+	count := 0
+	for _, conn := range rpcz.Connections {
+		if conn.BackendType == "client backend" && conn.DBName == database {
+			count++
+		}
+	}
+	return count
+}
+
+// followerLag returns the largest follower lag of the tablets on a tablet
+// server, or 0 when it cannot be reached
+func (s *DatabaseService) followerLag(ctx context.Context, host string) int64 {
+	var entities []struct {
+		Metrics []struct {
+			Name  string `json:"name"`
+			Value int64  `json:"value"`
+		} `json:"metrics"`
+	}
+	url := fmt.Sprintf("http://%s/metrics?metrics=follower_lag_ms", net.JoinHostPort(host, strconv.Itoa(tserverHTTPPort)))
+	if err := s.getJSON(ctx, url, &entities); err != nil {
+		return 0
+	}
+	var lag int64
+	for _, entity := range entities {
+		for _, metric := range entity.Metrics {
+			if metric.Name == "follower_lag_ms" && metric.Value > lag {
+				lag = metric.Value
+			}
+		}
+	}
+	return lag
+}
+
+// getJSON decodes the JSON response of a YugabyteDB HTTP endpoint. A database
+// whose endpoints cannot be reached is reported as not ready.
+func (s *DatabaseService) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return errors.NewError(errors.CodeServiceUnavailable, "the database is not ready yet", http.StatusServiceUnavailable).WithError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.DependencyFailed("yugabytedb", fmt.Errorf("GET %s: %s", url, resp.Status))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.DependencyFailed("yugabytedb", err)
+	}
+	return nil
+}
+
+// masterURL is the base URL of the master HTTP API of a cluster
+func (s *DatabaseService) masterURL(cluster string) string {
+	return fmt.Sprintf("http://%s-yb-master-ui.%s.svc:%d", cluster, s.namespace, masterHTTPPort)
+}
+
+// nodeHost returns the host of a tablet server address
+func nodeHost(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
+
+// maxConnections returns the YSQL connection limit of each tablet server of a
+// cluster
+func maxConnections(cluster *unstructured.Unstructured) int {
+	value, _, _ := unstructured.NestedString(cluster.Object, "spec", "tserver", "gflags", "ysql_max_connections")
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+	return 300
+}
@@ -0,0 +1,106 @@
+package yugabytedb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// roundTripFunc answers the HTTP requests of a service without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// serve answers the HTTP requests of s with handler
+func serve(s *DatabaseService, handler http.HandlerFunc) {
+	s.http = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		recorder := httptest.NewRecorder()
+		handler(recorder, req)
+		return recorder.Result(), nil
+	})}
+}
+
+func TestGetMetrics(t *testing.T) {
+	s := newTestService(nil)
+	ctx := context.Background()
+	_, err := s.CreateDatabase(ctx, &CreateDatabaseInput{Name: "orders", ProjectID: "p1", HighAvailability: true})
+	require.NoError(t, err)
+
+	serve(s, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Host + r.URL.Path {
+		case "p1-orders-yb-master-ui.databases.svc:7000/api/v1/health-check":
+			fmt.Fprint(w, `{"dead_nodes":["node-c"],"under_replicated_tablets":["t1","t2"]}`)
+		case "p1-orders-yb-master-ui.databases.svc:7000/api/v1/tablet-servers":
+			fmt.Fprint(w, `{"uuid": {
+				"node-a:9100": {"status":"ALIVE","uptime_seconds":60,"read_ops_per_sec":10,"write_ops_per_sec":5,
+					"path_metrics":[{"space_used":100,"total_space_size":1000},{"space_used":50,"total_space_size":1000}]},
+				"node-b:9100": {"status":"ALIVE","read_ops_per_sec":1},
+				"node-c:9100": {"status":"DEAD"}
+			}}`)
+		case "node-a:13000/rpcz":
+			fmt.Fprint(w, `{"connections":[
+				{"backend_type":"client backend","db_name":"orders"},
+				{"backend_type":"client backend","db_name":"orders"},
+				{"backend_type":"client backend","db_name":"yugabyte"},
+				{"backend_type":"checkpointer","db_name":""}
+			]}`)
+		case "node-a:9000/metrics":
+			fmt.Fprint(w, `[{"metrics":[{"name":"follower_lag_ms","value":120}]},{"metrics":[{"name":"follower_lag_ms","value":40}]}]`)
+		default:
+			// node-b's webservers are unreachable
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+
+	metrics, err := s.GetMetrics(ctx, "p1-orders")
+	require.NoError(t, err)
+	assert.False(t, metrics.Healthy)
+	assert.Equal(t, []string{"node-c"}, metrics.DeadNodes)
+	assert.Equal(t, 2, metrics.UnderReplicatedTablets)
+	assert.Equal(t, 2, metrics.Connections)
+	assert.Equal(t, 900, metrics.MaxConnections)
+	assert.Equal(t, float64(16), metrics.QueriesPerSecond)
+	assert.Equal(t, int64(120), metrics.ReplicationLagMs)
+	assert.Equal(t, int64(150), metrics.DiskUsedBytes)
+	assert.Equal(t, int64(2000), metrics.DiskTotalBytes)
+	require.Len(t, metrics.Nodes, 3)
+	assert.Equal(t, "node-a:9100", metrics.Nodes[0].Name)
+	assert.Equal(t, 0, metrics.Nodes[1].Connections)
+}
+
+func TestGetMetricsNotReady(t *testing.T) {
+	s := newTestService(nil)
+	ctx := context.Background()
+	_, err := s.CreateDatabase(ctx, &CreateDatabaseInput{Name: "orders", ProjectID: "p1"})
+	require.NoError(t, err)
+	s.http = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("dial tcp: no such host")
+	})}
+
+	_, err = s.GetMetrics(ctx, "p1-orders")
+	var appErr *errors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, http.StatusServiceUnavailable, appErr.HTTPStatus)
+}
+
+func TestNodeHost(t *testing.T) {
+	assert.Equal(t, "node-a", nodeHost("node-a:9100"))
+	assert.Equal(t, "::1", nodeHost("[::1]:9100"))
+	assert.Equal(t, "node-a", nodeHost("node-a"))
+}
+
+func TestMaxConnections(t *testing.T) {
+	cluster := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	assert.Equal(t, 300, maxConnections(cluster))
+	require.NoError(t, unstructured.SetNestedField(cluster.Object, "500", "spec", "tserver", "gflags", "ysql_max_connections"))
+	assert.Equal(t, 500, maxConnections(cluster))
+}
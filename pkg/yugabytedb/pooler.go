@@ -0,0 +1,158 @@
+package yugabytedb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// PgBouncerImage is the image of the connection pooler of YugabyteDB
+// databases
+const PgBouncerImage = "edoburu/pgbouncer:v1.23.1-p2"
+
+// PgBouncer pool modes
+const (
+	PoolModeSession     = "session"
+	PoolModeTransaction = "transaction"
+	PoolModeStatement   = "statement"
+)
+
+var (
+	deploymentGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	serviceGVR    = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+)
+
+// PoolerInput configures the PgBouncer pooler in front of the YSQL API of a
+// database
+type PoolerInput struct {
+	Mode                 string // session, transaction or statement; defaults to transaction
+	PoolSize             int    // Server connections per user and database; defaults to 20
+	MaxClientConnections int    // Defaults to 1000
+}
+
+// withDefaults returns a copy of a pooler configuration with defaults for
+// the settings left out
+func (p *PoolerInput) withDefaults() *PoolerInput {
+	pooler := *p
+	if pooler.Mode == "" {
+		pooler.Mode = PoolModeTransaction
+	}
+	if pooler.PoolSize <= 0 {
+		pooler.PoolSize = 20
+	}
+	if pooler.MaxClientConnections <= 0 {
+		pooler.MaxClientConnections = 1000
+	}
+	return &pooler
+}
+
+// createPooler deploys PgBouncer in front of the tablet servers of a cluster,
+// with a Service of the same name. Both are owned by the cluster.
+func (s *DatabaseService) createPooler(ctx context.Context, cluster *unstructured.Unstructured, input *CreateDatabaseInput, pooler *PoolerInput) error {
+	name := poolerName(cluster.GetName())
+	secretName := cluster.GetName() + "-credentials"
+	labels := map[string]interface{}{
+		"northstack.io/project":  input.ProjectID,
+		"northstack.io/database": cluster.GetName(),
+		"northstack.io/role":     "pooler",
+	}
+	replicas := int64(1)
+	if input.HighAvailability {
+		replicas = 2
+	}
+	secretEnv := func(name, key string) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name,
+			"valueFrom": map[string]interface{}{
+				"secretKeyRef": map[string]interface{}{"name": secretName, "key": key},
+			},
+		}
+	}
+	env := func(name, value string) map[string]interface{} {
+		return map[string]interface{}{"name": name, "value": value}
+	}
+
+	deployment := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":            name,
+				"namespace":       s.namespace,
+				"labels":          labels,
+				"ownerReferences": clusterOwner(cluster),
+			},
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+				"selector": map[string]interface{}{"matchLabels": labels},
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{"labels": labels},
+					"spec": map[string]interface{}{
+						"containers": []interface{}{map[string]interface{}{
+							"name":  "pgbouncer",
+							"image": PgBouncerImage,
+							"ports": []interface{}{map[string]interface{}{"name": "postgres", "containerPort": int64(5432)}},
+							"env": []interface{}{
+								env("DB_HOST", fmt.Sprintf("%s-yb-tserver-service.%s.svc", cluster.GetName(), s.namespace)),
+								env("DB_PORT", "5433"),
+								secretEnv("DB_USER", "username"),
+								secretEnv("DB_PASSWORD", "password"),
+								secretEnv("DB_NAME", "database"),
+								env("LISTEN_PORT", "5432"),
+								env("AUTH_TYPE", "md5"),
+								env("POOL_MODE", pooler.Mode),
+								env("DEFAULT_POOL_SIZE", strconv.Itoa(pooler.PoolSize)),
+								env("MAX_CLIENT_CONN", strconv.Itoa(pooler.MaxClientConnections)),
+							},
+							"readinessProbe": map[string]interface{}{
+								"tcpSocket": map[string]interface{}{"port": int64(5432)},
+							},
+							"resources": map[string]interface{}{
+								"requests": map[string]interface{}{"cpu": "50m", "memory": "32Mi"},
+								"limits":   map[string]interface{}{"cpu": "500m", "memory": "128Mi"},
+							},
+						}},
+					},
+				},
+			},
+		},
+	}
+	if _, err := s.dynamic.Resource(deploymentGVR).Namespace(s.namespace).Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create PgBouncer deployment: %w", err)
+	}
+
+	service := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata": map[string]interface{}{
+				"name":            name,
+				"namespace":       s.namespace,
+				"labels":          labels,
+				"ownerReferences": clusterOwner(cluster),
+			},
+			"spec": map[string]interface{}{
+				"selector": labels,
+				"ports": []interface{}{map[string]interface{}{
+					"name":       "postgres",
+					"port":       int64(5432),
+					"targetPort": "postgres",
+				}},
+			},
+		},
+	}
+	if _, err := s.dynamic.Resource(serviceGVR).Namespace(s.namespace).Create(ctx, service, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create PgBouncer service: %w", err)
+	}
+	return nil
+}
+
+// poolerName is the name of the PgBouncer deployment of a cluster, and of its
+// Service
+func poolerName(cluster string) string {
+	return cluster + "-pooler"
+}
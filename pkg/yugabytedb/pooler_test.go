@@ -0,0 +1,61 @@
+package yugabytedb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPoolerWithDefaults(t *testing.T) {
+	assert.Equal(t, &PoolerInput{Mode: PoolModeTransaction, PoolSize: 20, MaxClientConnections: 1000}, (&PoolerInput{}).withDefaults())
+
+	custom := &PoolerInput{Mode: PoolModeSession, PoolSize: 5, MaxClientConnections: 50}
+	assert.Equal(t, custom, custom.withDefaults())
+	assert.NotSame(t, custom, custom.withDefaults())
+}
+
+func TestCreateDatabaseWithPooler(t *testing.T) {
+	s := newTestService(nil)
+
+	info, err := s.CreateDatabase(context.Background(), &CreateDatabaseInput{
+		Name:             "orders",
+		ProjectID:        "p1",
+		HighAvailability: true,
+		Pooler:           &PoolerInput{Mode: PoolModeSession},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, PoolModeSession, info.PoolMode)
+	assert.Equal(t, "p1-orders-pooler.databases.svc:5432", info.Endpoint)
+	assert.Equal(t, 5432, info.Port)
+	assert.Equal(t, "p1-orders-yb-tserver-service.databases.svc:5433", info.YSQLEndpoint)
+
+	// Services connect through the pooler
+	secret := get(t, s, secretGVR, "p1-orders-credentials")
+	uri, _, _ := unstructured.NestedString(secret.Object, "stringData", "uri")
+	assert.Contains(t, uri, "@p1-orders-pooler.databases.svc:5432/orders")
+
+	deployment := get(t, s, deploymentGVR, "p1-orders-pooler")
+	replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+	assert.Equal(t, int64(2), replicas)
+	containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+	require.Len(t, containers, 1)
+	env := map[string]interface{}{}
+	for _, e := range containers[0].(map[string]interface{})["env"].([]interface{}) {
+		variable := e.(map[string]interface{})
+		env[variable["name"].(string)] = variable["value"]
+	}
+	assert.Equal(t, "p1-orders-yb-tserver-service.databases.svc", env["DB_HOST"])
+	assert.Equal(t, PoolModeSession, env["POOL_MODE"])
+	assert.Equal(t, "20", env["DEFAULT_POOL_SIZE"])
+	assert.Len(t, deployment.GetOwnerReferences(), 1)
+
+	service := get(t, s, serviceGVR, "p1-orders-pooler")
+	assert.Equal(t, "p1-orders", service.GetLabels()["northstack.io/database"])
+
+	stored, err := s.GetDatabase(context.Background(), "p1-orders")
+	require.NoError(t, err)
+	assert.Equal(t, info.Endpoint, stored.Endpoint)
+}
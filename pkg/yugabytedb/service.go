@@ -3,9 +3,12 @@ package yugabytedb
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/northstack/platform/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -19,12 +22,16 @@ var (
 		Version:  "v1alpha1",
 		Resource: "ybclusters",
 	}
+	secretGVR = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
 )
 
 // DatabaseService manages YugabyteDB clusters via Kubernetes operator
 type DatabaseService struct {
 	dynamic   dynamic.Interface
 	namespace string
+	backups   *BackupConfig
+	http      *http.Client
+	adminMu   sync.Mutex // Serializes replacing the initial superuser passwords
 }
 
 // CreateDatabaseInput holds parameters for creating a database cluster
@@ -37,22 +44,32 @@ type CreateDatabaseInput struct {
 	TServerReplicas  int
 	MasterReplicas   int
 	HighAvailability bool
-	BackupEnabled    bool
+	BackupEnabled    bool // Schedule backups; needs backups to be enabled
 	TLSEnabled       bool
 	Version          string
+	Pooler           *PoolerInput // nil for none
+	EnvironmentID    string       // Environment the database is deleted with; empty for none
+	CloneOf          string       // Database the new one restores a backup of
 }
 
 // DatabaseInfo holds database connection information
 type DatabaseInfo struct {
 	ID              string     `json:"id"`
 	Name            string     `json:"name"`
+	Type            string     `json:"type"`
 	Status          string     `json:"status"`
+	Endpoint        string     `json:"endpoint"`      // YSQL, through the pooler when there is one
 	YSQLEndpoint    string     `json:"ysql_endpoint"` // PostgreSQL-compatible
 	YCQLEndpoint    string     `json:"ycql_endpoint"` // Cassandra-compatible
 	MasterUIURL     string     `json:"master_ui_url"`
-	Port            int        `json:"port"`
+	Port            int        `json:"port"` // Of the endpoint
+	PoolMode        string     `json:"pool_mode,omitempty"`
 	Database        string     `json:"database"`
 	Username        string     `json:"username"`
+	Version         string     `json:"version"`
+	ProjectID       string     `json:"project_id"`
+	EnvironmentID   string     `json:"environment_id,omitempty"`
+	CloneOf         string     `json:"clone_of,omitempty"` // Source of a restored or cloned database
 	SecretName      string     `json:"secret_name"`
 	TServerReplicas int        `json:"tserver_replicas"`
 	MasterReplicas  int        `json:"master_replicas"`
@@ -75,16 +92,22 @@ type ResourceConfig struct {
 	MasterMemoryLimit    string
 }
 
-// NewDatabaseService creates a new YugabyteDB service
-func NewDatabaseService(dynClient dynamic.Interface, namespace string) *DatabaseService {
+// NewDatabaseService creates a new YugabyteDB service. Without a backup
+// configuration, databases cannot be backed up.
+func NewDatabaseService(dynClient dynamic.Interface, namespace string, backups *BackupConfig) *DatabaseService {
 	return &DatabaseService{
 		dynamic:   dynClient,
 		namespace: namespace,
+		backups:   backups,
+		http:      &http.Client{Timeout: 5 * time.Second},
 	}
 }
 
 // CreateDatabase creates a new YugabyteDB cluster
 func (s *DatabaseService) CreateDatabase(ctx context.Context, input *CreateDatabaseInput) (*DatabaseInfo, error) {
+	if input.BackupEnabled && s.backups == nil {
+		return nil, errors.BadRequest("database backups are not enabled")
+	}
 	clusterName := fmt.Sprintf("%s-%s", input.ProjectID, input.Name)
 	secretName := fmt.Sprintf("%s-credentials", clusterName)
 
@@ -111,6 +134,26 @@ func (s *DatabaseService) CreateDatabase(ctx context.Context, input *CreateDatab
 		version = "2.20.1.0-b97"
 	}
 
+	labels := map[string]interface{}{
+		"northstack.io/project": input.ProjectID,
+		"northstack.io/team":    input.TeamID,
+		"northstack.io/type":    "database",
+		"northstack.io/name":    input.Name,
+	}
+	if input.EnvironmentID != "" {
+		labels["northstack.io/environment"] = input.EnvironmentID
+	}
+	if input.CloneOf != "" {
+		labels["northstack.io/clone-of"] = input.CloneOf
+	}
+	endpoint := fmt.Sprintf("%s-yb-tserver-service.%s.svc:5433", clusterName, s.namespace)
+	var pooler *PoolerInput
+	if input.Pooler != nil {
+		pooler = input.Pooler.withDefaults()
+		labels["northstack.io/pool-mode"] = pooler.Mode
+		endpoint = fmt.Sprintf("%s.%s.svc:5432", poolerName(clusterName), s.namespace)
+	}
+
 	// Build cluster spec
 	cluster := &unstructured.Unstructured{
 		Object: map[string]interface{}{
@@ -119,11 +162,7 @@ func (s *DatabaseService) CreateDatabase(ctx context.Context, input *CreateDatab
 			"metadata": map[string]interface{}{
 				"name":      clusterName,
 				"namespace": s.namespace,
-				"labels": map[string]interface{}{
-					"northstack.io/project": input.ProjectID,
-					"northstack.io/team":    input.TeamID,
-					"northstack.io/type":    "database",
-				},
+				"labels":    labels,
 			},
 			"spec": map[string]interface{}{
 				"image": map[string]interface{}{
@@ -132,9 +171,9 @@ func (s *DatabaseService) CreateDatabase(ctx context.Context, input *CreateDatab
 					"pullPolicy": "IfNotPresent",
 				},
 				"tserver": map[string]interface{}{
-					"replicas": tserverReplicas,
+					"replicas": int64(tserverReplicas),
 					"storage": map[string]interface{}{
-						"count": 1,
+						"count": int64(1),
 						"size":  fmt.Sprintf("%dGi", input.StorageGB),
 					},
 					"resource": map[string]interface{}{
@@ -154,9 +193,9 @@ func (s *DatabaseService) CreateDatabase(ctx context.Context, input *CreateDatab
 					},
 				},
 				"master": map[string]interface{}{
-					"replicas": masterReplicas,
+					"replicas": int64(masterReplicas),
 					"storage": map[string]interface{}{
-						"count": 1,
+						"count": int64(1),
 						"size":  "10Gi",
 					},
 					"resource": map[string]interface{}{
@@ -183,40 +222,72 @@ func (s *DatabaseService) CreateDatabase(ctx context.Context, input *CreateDatab
 	}
 
 	// Create credentials secret first
-	if err := s.createCredentialsSecret(ctx, secretName, input.Name); err != nil {
+	if err := s.createCredentialsSecret(ctx, secretName, input.Name, endpoint); err != nil {
 		return nil, fmt.Errorf("failed to create credentials secret: %w", err)
 	}
 
 	// Create the cluster
-	_, err := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).Create(ctx, cluster, metav1.CreateOptions{})
+	created, err := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).Create(ctx, cluster, metav1.CreateOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create YugabyteDB cluster: %w", err)
 	}
+	if input.BackupEnabled {
+		if err := s.createBackupSchedule(ctx, created, input.Name); err != nil {
+			_ = s.DeleteDatabase(ctx, clusterName)
+			return nil, fmt.Errorf("failed to schedule backups: %w", err)
+		}
+	}
+	if pooler != nil {
+		if err := s.createPooler(ctx, created, input, pooler); err != nil {
+			_ = s.DeleteDatabase(ctx, clusterName)
+			return nil, err
+		}
+	}
 
-	return &DatabaseInfo{
+	info := &DatabaseInfo{
 		ID:              clusterName,
 		Name:            input.Name,
+		Type:            "yugabytedb",
 		Status:          "creating",
+		Endpoint:        endpoint,
 		YSQLEndpoint:    fmt.Sprintf("%s-yb-tserver-service.%s.svc:5433", clusterName, s.namespace),
 		YCQLEndpoint:    fmt.Sprintf("%s-yb-tserver-service.%s.svc:9042", clusterName, s.namespace),
 		Port:            5433,
 		Database:        input.Name,
 		Username:        input.Name,
+		Version:         version,
+		ProjectID:       input.ProjectID,
+		EnvironmentID:   input.EnvironmentID,
+		CloneOf:         input.CloneOf,
 		SecretName:      secretName,
 		TServerReplicas: tserverReplicas,
 		MasterReplicas:  masterReplicas,
 		CreatedAt:       time.Now(),
-	}, nil
+	}
+	if pooler != nil {
+		info.PoolMode = pooler.Mode
+		info.Port = 5432
+	}
+	return info, nil
 }
 
-// GetDatabase retrieves database information
+// GetDatabase retrieves database information, with the time of its last
+// backup
 func (s *DatabaseService) GetDatabase(ctx context.Context, name string) (*DatabaseInfo, error) {
 	cluster, err := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).Get(ctx, name, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
 
-	return s.extractDatabaseInfo(cluster), nil
+	info := s.extractDatabaseInfo(cluster)
+	if s.backups != nil {
+		backups, err := s.listBackups(ctx)
+		if err != nil {
+			return nil, err
+		}
+		info.LastBackupTime = lastBackupTime(backups[info.ID])
+	}
+	return info, nil
 }
 
 // ListDatabases lists all YugabyteDB clusters
@@ -233,9 +304,18 @@ func (s *DatabaseService) ListDatabases(ctx context.Context, projectID string) (
 		return nil, err
 	}
 
+	var backups map[string][]*BackupInfo
+	if s.backups != nil {
+		if backups, err = s.listBackups(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	var databases []*DatabaseInfo
 	for _, cluster := range clusters.Items {
-		databases = append(databases, s.extractDatabaseInfo(&cluster))
+		info := s.extractDatabaseInfo(&cluster)
+		info.LastBackupTime = lastBackupTime(backups[info.ID])
+		databases = append(databases, info)
 	}
 
 	return databases, nil
@@ -255,7 +335,7 @@ func (s *DatabaseService) ScaleDatabase(ctx context.Context, name string, replic
 
 	spec := cluster.Object["spec"].(map[string]interface{})
 	tserver := spec["tserver"].(map[string]interface{})
-	tserver["replicas"] = replicas
+	tserver["replicas"] = int64(replicas)
 
 	_, err = s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).Update(ctx, cluster, metav1.UpdateOptions{})
 	return err
@@ -311,7 +391,9 @@ func (s *DatabaseService) mapSizeToResources(size string) ResourceConfig {
 	return configs["small"]
 }
 
-func (s *DatabaseService) createCredentialsSecret(ctx context.Context, name, username string) error {
+// createCredentialsSecret creates the credentials of a database. Its uri is
+// the connection string of the endpoint services connect to.
+func (s *DatabaseService) createCredentialsSecret(ctx context.Context, name, username, endpoint string) error {
 	// Generate random password
 	password := uuid.New().String()
 
@@ -329,20 +411,31 @@ func (s *DatabaseService) createCredentialsSecret(ctx context.Context, name, use
 				"password": password,
 				"database": username,
 				"ysql_uri": fmt.Sprintf("postgresql://%s:%s@yugabyte-ysql.%s.svc:5433/%s", username, password, s.namespace, username),
+				"uri":      fmt.Sprintf("postgresql://%s:%s@%s/%s", username, password, endpoint, username),
 			},
 		},
 	}
 
-	secretGVR := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
 	_, err := s.dynamic.Resource(secretGVR).Namespace(s.namespace).Create(ctx, secret, metav1.CreateOptions{})
 	return err
 }
 
 func (s *DatabaseService) extractDatabaseInfo(cluster *unstructured.Unstructured) *DatabaseInfo {
+	labels := cluster.GetLabels()
 	info := &DatabaseInfo{
-		ID:   cluster.GetName(),
-		Name: cluster.GetName(),
+		ID:            cluster.GetName(),
+		Name:          cluster.GetName(),
+		Type:          "yugabytedb",
+		Database:      labels["northstack.io/name"],
+		Username:      labels["northstack.io/name"],
+		ProjectID:     labels["northstack.io/project"],
+		PoolMode:      labels["northstack.io/pool-mode"],
+		EnvironmentID: labels["northstack.io/environment"],
+		CloneOf:       labels["northstack.io/clone-of"],
+		SecretName:    cluster.GetName() + "-credentials",
+		CreatedAt:     cluster.GetCreationTimestamp().Time,
 	}
+	info.Version, _, _ = unstructured.NestedString(cluster.Object, "spec", "image", "tag")
 
 	spec, ok := cluster.Object["spec"].(map[string]interface{})
 	if ok {
@@ -368,6 +461,12 @@ func (s *DatabaseService) extractDatabaseInfo(cluster *unstructured.Unstructured
 	info.YSQLEndpoint = fmt.Sprintf("%s-yb-tserver-service.%s.svc:5433", info.Name, s.namespace)
 	info.YCQLEndpoint = fmt.Sprintf("%s-yb-tserver-service.%s.svc:9042", info.Name, s.namespace)
 	info.Port = 5433
+	info.MasterUIURL = s.masterURL(info.Name)
+	info.Endpoint = info.YSQLEndpoint
+	if info.PoolMode != "" {
+		info.Endpoint = fmt.Sprintf("%s.%s.svc:5432", poolerName(info.Name), s.namespace)
+		info.Port = 5432
+	}
 
 	return info
 }
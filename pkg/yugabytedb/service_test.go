@@ -0,0 +1,104 @@
+package yugabytedb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+const testNamespace = "databases"
+
+func newTestService(backups *BackupConfig) *DatabaseService {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		YBClusterGVR:      "YBClusterList",
+		secretGVR:         "SecretList",
+		deploymentGVR:     "DeploymentList",
+		serviceGVR:        "ServiceList",
+		StorageConfigGVR:  "StorageConfigList",
+		BackupGVR:         "BackupList",
+		BackupScheduleGVR: "BackupScheduleList",
+		RestoreJobGVR:     "RestoreJobList",
+	})
+	return NewDatabaseService(client, testNamespace, backups)
+}
+
+func get(t *testing.T, s *DatabaseService, gvr schema.GroupVersionResource, name string) *unstructured.Unstructured {
+	obj, err := s.dynamic.Resource(gvr).Namespace(testNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	require.NoError(t, err)
+	return obj
+}
+
+func TestCreateDatabase(t *testing.T) {
+	s := newTestService(nil)
+	ctx := context.Background()
+
+	_, err := s.CreateDatabase(ctx, &CreateDatabaseInput{Name: "orders", ProjectID: "p1", BackupEnabled: true})
+	assert.Error(t, err, "backups need a backup configuration")
+
+	info, err := s.CreateDatabase(ctx, &CreateDatabaseInput{Name: "orders", ProjectID: "p1", StorageGB: 20, EnvironmentID: "e1"})
+	require.NoError(t, err)
+	assert.Equal(t, "p1-orders", info.ID)
+	assert.Equal(t, "creating", info.Status)
+	assert.Equal(t, 1, info.TServerReplicas)
+	assert.Equal(t, 1, info.MasterReplicas)
+	assert.Equal(t, "p1-orders-yb-tserver-service.databases.svc:5433", info.Endpoint)
+	assert.Equal(t, 5433, info.Port)
+	assert.Empty(t, info.PoolMode)
+
+	cluster := get(t, s, YBClusterGVR, "p1-orders")
+	assert.Equal(t, "e1", cluster.GetLabels()["northstack.io/environment"])
+	factor, _, _ := unstructured.NestedString(cluster.Object, "spec", "master", "gflags", "replication_factor")
+	assert.Equal(t, "1", factor)
+
+	secret := get(t, s, secretGVR, "p1-orders-credentials")
+	uri, _, _ := unstructured.NestedString(secret.Object, "stringData", "uri")
+	assert.Contains(t, uri, "@p1-orders-yb-tserver-service.databases.svc:5433/orders")
+
+	stored, err := s.GetDatabase(ctx, "p1-orders")
+	require.NoError(t, err)
+	assert.Equal(t, "orders", stored.Database)
+	assert.Equal(t, "p1", stored.ProjectID)
+	assert.Equal(t, "e1", stored.EnvironmentID)
+	assert.Equal(t, info.Endpoint, stored.Endpoint)
+}
+
+func TestCreateHighlyAvailableDatabase(t *testing.T) {
+	s := newTestService(nil)
+
+	info, err := s.CreateDatabase(context.Background(), &CreateDatabaseInput{Name: "orders", ProjectID: "p1", HighAvailability: true, TServerReplicas: 5})
+	require.NoError(t, err)
+	assert.Equal(t, 5, info.TServerReplicas)
+	assert.Equal(t, 3, info.MasterReplicas)
+
+	require.NoError(t, s.ScaleDatabase(context.Background(), "p1-orders", 7))
+	stored, err := s.GetDatabase(context.Background(), "p1-orders")
+	require.NoError(t, err)
+	assert.Equal(t, 7, stored.TServerReplicas)
+}
+
+func TestListDatabases(t *testing.T) {
+	s := newTestService(nil)
+	ctx := context.Background()
+	for _, input := range []*CreateDatabaseInput{
+		{Name: "orders", ProjectID: "p1"},
+		{Name: "users", ProjectID: "p1"},
+		{Name: "orders", ProjectID: "p2"},
+	} {
+		_, err := s.CreateDatabase(ctx, input)
+		require.NoError(t, err)
+	}
+
+	databases, err := s.ListDatabases(ctx, "p1")
+	require.NoError(t, err)
+	assert.Len(t, databases, 2)
+	databases, err = s.ListDatabases(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, databases, 3)
+}
@@ -0,0 +1,194 @@
+package yugabytedb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/northstack/platform/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// UpgradeAnnotation is the cluster annotation holding the state of its
+// latest upgrade
+const UpgradeAnnotation = "northstack.io/upgrade"
+
+// tagsURL lists the tags of the YugabyteDB image on Docker Hub
+const tagsURL = "https://hub.docker.com/v2/repositories/yugabytedb/yugabyte/tags"
+
+// Components of a cluster, rolled in this order on upgrades
+const (
+	ComponentMaster  = "master"
+	ComponentTServer = "tserver"
+)
+
+var statefulSetGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}
+
+// versionPattern matches release tags such as 2.20.1.0-b97
+var versionPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)\.(\d+)-b(\d+)$`)
+
+// UpgradeState returns the upgrade annotation of a cluster, empty when it
+// was never upgraded
+func (s *DatabaseService) UpgradeState(ctx context.Context, name string) (string, error) {
+	cluster, err := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return cluster.GetAnnotations()[UpgradeAnnotation], nil
+}
+
+// UpgradeStates returns the upgrade annotations of every cluster that has
+// one, by cluster name
+func (s *DatabaseService) UpgradeStates(ctx context.Context) (map[string]string, error) {
+	clusters, err := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	states := map[string]string{}
+	for _, cluster := range clusters.Items {
+		if state := cluster.GetAnnotations()[UpgradeAnnotation]; state != "" {
+			states[cluster.GetName()] = state
+		}
+	}
+	return states, nil
+}
+
+// SetUpgradeState stores the upgrade annotation of a cluster
+func (s *DatabaseService) SetUpgradeState(ctx context.Context, name, state string) error {
+	return s.updateCluster(ctx, name, func(cluster *unstructured.Unstructured) error {
+		annotations := cluster.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[UpgradeAnnotation] = state
+		cluster.SetAnnotations(annotations)
+		return nil
+	})
+}
+
+// SetVersion changes the YugabyteDB version of a cluster. The operator then
+// rolls its masters and tablet servers, one pod at a time.
+func (s *DatabaseService) SetVersion(ctx context.Context, name, version string) error {
+	return s.updateCluster(ctx, name, func(cluster *unstructured.Unstructured) error {
+		return unstructured.SetNestedField(cluster.Object, version, "spec", "image", "tag")
+	})
+}
+
+// RolledOut reports whether every pod of a component of a cluster runs a
+// version and is ready
+func (s *DatabaseService) RolledOut(ctx context.Context, name, component, version string) (bool, error) {
+	sts, err := s.dynamic.Resource(statefulSetGVR).Namespace(s.namespace).Get(ctx, fmt.Sprintf("%s-yb-%s", name, component), metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	containers, _, _ := unstructured.NestedSlice(sts.Object, "spec", "template", "spec", "containers")
+	for _, c := range containers {
+		container, _ := c.(map[string]interface{})
+		if image, _ := container["image"].(string); !strings.HasSuffix(image, ":"+version) {
+			return false, nil
+		}
+	}
+	generation := sts.GetGeneration()
+	observed, _, _ := unstructured.NestedInt64(sts.Object, "status", "observedGeneration")
+	replicas, _, _ := unstructured.NestedInt64(sts.Object, "spec", "replicas")
+	updated, _, _ := unstructured.NestedInt64(sts.Object, "status", "updatedReplicas")
+	ready, _, _ := unstructured.NestedInt64(sts.Object, "status", "readyReplicas")
+	current, _, _ := unstructured.NestedString(sts.Object, "status", "currentRevision")
+	update, _, _ := unstructured.NestedString(sts.Object, "status", "updateRevision")
+	return observed >= generation && updated == replicas && ready == replicas && current == update, nil
+}
+
+// ReleaseVersions discovers the published YugabyteDB versions of the release
+// series of a version, such as 2.20, from Docker Hub
+func (s *DatabaseService) ReleaseVersions(ctx context.Context, version string) ([]string, error) {
+	series := ReleaseSeries(version)
+	if series == "" {
+		return nil, errors.BadRequest(fmt.Sprintf("unrecognized YugabyteDB version %s", version))
+	}
+
+	next := tagsURL + "?" + url.Values{"page_size": {"100"}, "name": {series + "."}}.Encode()
+	var versions []string
+	for page := 0; next != "" && page < 10; page++ {
+		var tags struct {
+			Next    string `json:"next"`
+			Results []struct {
+				Name string `json:"name"`
+			} `json:"results"`
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, next, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.http.Do(req)
+		if err != nil {
+			return nil, errors.DependencyFailed("docker hub", err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&tags)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.DependencyFailed("docker hub", err)
+		}
+		for _, tag := range tags.Results {
+			if ReleaseSeries(tag.Name) == series {
+				versions = append(versions, tag.Name)
+			}
+		}
+		next = tags.Next
+	}
+	SortVersions(versions)
+	return versions, nil
+}
+
+// ReleaseSeries returns the release series of a version, or empty for an
+// unrecognized version
+func ReleaseSeries(version string) string {
+	m := versionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return ""
+	}
+	return m[1] + "." + m[2]
+}
+
+// CompareVersions orders two versions, -1 when a is older than b
+func CompareVersions(a, b string) int {
+	pa, pb := versionPattern.FindStringSubmatch(a), versionPattern.FindStringSubmatch(b)
+	for i := 1; i < 6 && pa != nil && pb != nil; i++ {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		switch {
+		case na < nb:
+			return -1
+		case na > nb:
+			return 1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// SortVersions sorts versions oldest first
+func SortVersions(versions []string) {
+	sort.Slice(versions, func(i, j int) bool { return CompareVersions(versions[i], versions[j]) < 0 })
+}
+
+// updateCluster applies a change to a cluster
+func (s *DatabaseService) updateCluster(ctx context.Context, name string, change func(*unstructured.Unstructured) error) error {
+	client := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace)
+	cluster, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if err := change(cluster); err != nil {
+		return err
+	}
+	_, err = client.Update(ctx, cluster, metav1.UpdateOptions{})
+	return err
+}
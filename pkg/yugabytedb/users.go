@@ -0,0 +1,690 @@
+package yugabytedb
+
+import (
+	"context"
+	"encoding/base64"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/northstack/platform/pkg/password"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// superuser is the YSQL superuser every cluster starts with, and its initial
+// password. The password is replaced the first time the orchestrator
+// connects, and kept in the admin_password key of the credentials secret.
+const (
+	superuser         = "yugabyte"
+	superuserPassword = "yugabyte"
+)
+
+// Access levels of a role to a logical database
+const (
+	AccessReadOnly  = "read_only"  // SELECT on tables and sequences
+	AccessReadWrite = "read_write" // Plus INSERT, UPDATE, DELETE and sequence use
+	AccessOwner     = "owner"      // Plus creating objects in the public schema
+)
+
+// identifierPattern restricts names of logical databases and roles to ones
+// that need no quoting and fit in a secret name
+var identifierPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// reservedNames are the databases and roles YugabyteDB creates
+var reservedNames = map[string]bool{
+	"yugabyte": true, "postgres": true, "template0": true, "template1": true,
+	"system_platform": true, "public": true,
+}
+
+// LogicalDatabase is a database of a cluster
+type LogicalDatabase struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+}
+
+// Grant is the access of a role to a logical database
+type Grant struct {
+	Database string `json:"database"`
+	Access   string `json:"access"`
+}
+
+// Role is a login role of a cluster
+type Role struct {
+	Name       string   `json:"name"`
+	Grants     []*Grant `json:"grants"`
+	SecretName string   `json:"secret_name"`
+}
+
+// RoleCredentials are the credentials of a role; the password is only
+// returned when the role is created or its password reset
+type RoleCredentials struct {
+	*Role
+	Password string `json:"password"`
+	URI      string `json:"uri"`
+}
+
+// CreateRoleInput holds parameters for creating a role
+type CreateRoleInput struct {
+	Name   string
+	Grants []*Grant // At least one
+}
+
+// ListLogicalDatabases lists the databases of a cluster
+func (s *DatabaseService) ListLogicalDatabases(ctx context.Context, cluster string) ([]*LogicalDatabase, error) {
+	conn, err := s.adminConn(ctx, cluster, "")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	rows, err := conn.Query(ctx, `
+		SELECT datname, pg_get_userbyid(datdba) FROM pg_database
+		WHERE NOT datistemplate AND datname <> ALL($1)
+		ORDER BY datname`, reservedList())
+	if err != nil {
+		return nil, sqlError(err, "database", "")
+	}
+	defer rows.Close()
+
+	databases := []*LogicalDatabase{}
+	for rows.Next() {
+		db := &LogicalDatabase{}
+		if err := rows.Scan(&db.Name, &db.Owner); err != nil {
+			return nil, sqlError(err, "database", "")
+		}
+		databases = append(databases, db)
+	}
+	return databases, sqlError(rows.Err(), "database", "")
+}
+
+// CreateLogicalDatabase creates a database in a cluster, owned by the owner
+// of the cluster
+func (s *DatabaseService) CreateLogicalDatabase(ctx context.Context, cluster, name string) (*LogicalDatabase, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+	info, err := s.GetDatabase(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := s.adminConn(ctx, cluster, "")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE DATABASE %s OWNER %s", ident(name), ident(info.Username))); err != nil {
+		return nil, sqlError(err, "database", name)
+	}
+	db := &LogicalDatabase{Name: name, Owner: info.Username}
+	if err := s.isolate(ctx, cluster, db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// DeleteLogicalDatabase drops a database of a cluster. The database the
+// cluster was created with cannot be dropped.
+func (s *DatabaseService) DeleteLogicalDatabase(ctx context.Context, cluster, name string) error {
+	info, err := s.GetDatabase(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	if name == info.Database {
+		return errors.BadRequest("the primary database of a cluster cannot be deleted")
+	}
+	if err := validateName(name); err != nil {
+		return errors.NotFound("database", name)
+	}
+	conn, err := s.adminConn(ctx, cluster, "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	_, err = conn.Exec(ctx, "DROP DATABASE "+ident(name))
+	return sqlError(err, "database", name)
+}
+
+// ListRoles lists the login roles of a cluster with their grants, including
+// the owner of the cluster, whose credentials are in the credentials secret
+func (s *DatabaseService) ListRoles(ctx context.Context, cluster string) ([]*Role, error) {
+	info, err := s.GetDatabase(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	databases, err := s.ListLogicalDatabases(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := s.adminConn(ctx, cluster, "")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	rows, err := conn.Query(ctx, `
+		SELECT rolname FROM pg_roles
+		WHERE rolcanlogin AND NOT rolsuper AND rolname <> ALL($1) AND rolname !~ '^(pg|yb)_'
+		ORDER BY rolname`, reservedList())
+	if err != nil {
+		return nil, sqlError(err, "role", "")
+	}
+	roles := []*Role{}
+	for rows.Next() {
+		role := &Role{Grants: []*Grant{}}
+		if err := rows.Scan(&role.Name); err != nil {
+			rows.Close()
+			return nil, sqlError(err, "role", "")
+		}
+		role.SecretName = roleSecretName(cluster, role.Name)
+		if role.Name == info.Username {
+			role.SecretName = info.SecretName
+		}
+		roles = append(roles, role)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, sqlError(err, "role", "")
+	}
+
+	for _, db := range databases {
+		dbConn, err := s.adminConn(ctx, cluster, db.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, role := range roles {
+			if grant := roleAccess(ctx, dbConn, db.Name, role.Name); grant != nil {
+				role.Grants = append(role.Grants, grant)
+			}
+		}
+		dbConn.Close(ctx)
+	}
+	return roles, nil
+}
+
+// CreateRole creates a login role with a generated password and grants it
+// access to logical databases. Its credentials are stored in a secret owned by
+// the cluster.
+func (s *DatabaseService) CreateRole(ctx context.Context, cluster string, input *CreateRoleInput) (*RoleCredentials, error) {
+	if err := validateName(input.Name); err != nil {
+		return nil, err
+	}
+	if len(input.Grants) == 0 {
+		return nil, errors.BadRequest("a role needs access to at least one database")
+	}
+	databases, err := s.ListLogicalDatabases(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]*LogicalDatabase{}
+	for _, db := range databases {
+		byName[db.Name] = db
+	}
+	for _, grant := range input.Grants {
+		if _, ok := byName[grant.Database]; !ok {
+			return nil, errors.BadRequest(fmt.Sprintf("database %s does not exist", grant.Database))
+		}
+		if grant.Access == "" {
+			grant.Access = AccessReadWrite
+		}
+		if grant.Access != AccessReadOnly && grant.Access != AccessReadWrite && grant.Access != AccessOwner {
+			return nil, errors.BadRequest("access must be read_only, read_write or owner")
+		}
+	}
+
+	password, err := password.Generate()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := s.adminConn(ctx, cluster, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD %s", ident(input.Name), literal(password))); err != nil {
+		conn.Close(ctx)
+		return nil, sqlError(err, "role", input.Name)
+	}
+	conn.Close(ctx)
+
+	for _, grant := range input.Grants {
+		if err := s.grant(ctx, cluster, input.Name, byName[grant.Database], grant); err != nil {
+			_ = s.DeleteRole(ctx, cluster, input.Name)
+			return nil, err
+		}
+	}
+
+	role := &Role{Name: input.Name, Grants: input.Grants, SecretName: roleSecretName(cluster, input.Name)}
+	creds, err := s.storeRoleCredentials(ctx, cluster, role, password)
+	if err != nil {
+		_ = s.DeleteRole(ctx, cluster, input.Name)
+		return nil, err
+	}
+	return creds, nil
+}
+
+// ResetRolePassword replaces the password of a role and its secret. The
+// pooler of the cluster is restarted when the owner's password changes.
+func (s *DatabaseService) ResetRolePassword(ctx context.Context, cluster, name string) (*RoleCredentials, error) {
+	roles, err := s.ListRoles(ctx, cluster)
+	if err != nil {
+		return nil, err
+	}
+	var role *Role
+	for _, r := range roles {
+		if r.Name == name {
+			role = r
+		}
+	}
+	if role == nil {
+		return nil, errors.NotFound("role", name)
+	}
+
+	password, err := password.Generate()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := s.adminConn(ctx, cluster, "")
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, fmt.Sprintf("ALTER ROLE %s PASSWORD %s", ident(name), literal(password))); err != nil {
+		return nil, sqlError(err, "role", name)
+	}
+	if role.SecretName == cluster+"-credentials" {
+		return s.storeOwnerPassword(ctx, cluster, role, password)
+	}
+	return s.storeRoleCredentials(ctx, cluster, role, password)
+}
+
+// DeleteRole drops a role with the objects it owns in every logical
+// database, and deletes its secret
+func (s *DatabaseService) DeleteRole(ctx context.Context, cluster, name string) error {
+	if err := validateName(name); err != nil {
+		return errors.NotFound("role", name)
+	}
+	info, err := s.GetDatabase(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	if name == info.Username {
+		return errors.BadRequest("the owner of a cluster cannot be deleted")
+	}
+	databases, err := s.ListLogicalDatabases(ctx, cluster)
+	if err != nil {
+		return err
+	}
+	for _, db := range databases {
+		conn, err := s.adminConn(ctx, cluster, db.Name)
+		if err != nil {
+			return err
+		}
+		_, err = conn.Exec(ctx, "DROP OWNED BY "+ident(name))
+		conn.Close(ctx)
+		if err != nil {
+			return sqlError(err, "role", name)
+		}
+	}
+
+	conn, err := s.adminConn(ctx, cluster, "")
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+	if _, err := conn.Exec(ctx, "DROP ROLE "+ident(name)); err != nil {
+		return sqlError(err, "role", name)
+	}
+	err = s.dynamic.Resource(secretGVR).Namespace(s.namespace).Delete(ctx, roleSecretName(cluster, name), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.DependencyFailed("kubernetes", err)
+	}
+	return nil
+}
+
+// isolate keeps the roles without a grant out of a logical database. By
+// default every role may connect to every database and create objects in its
+// public schema.
+func (s *DatabaseService) isolate(ctx context.Context, cluster string, db *LogicalDatabase) error {
+	conn, err := s.adminConn(ctx, cluster, db.Name)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	for _, statement := range isolateStatements(db) {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			return sqlError(err, "database", db.Name)
+		}
+	}
+	return nil
+}
+
+// grant gives a role access to a logical database, to its existing tables
+// and sequences and to the ones its owner creates later
+func (s *DatabaseService) grant(ctx context.Context, cluster, role string, db *LogicalDatabase, grant *Grant) error {
+	if err := s.isolate(ctx, cluster, db); err != nil {
+		return err
+	}
+	conn, err := s.adminConn(ctx, cluster, db.Name)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	for _, statement := range grantStatements(role, db, grant) {
+		if _, err := conn.Exec(ctx, statement); err != nil {
+			return sqlError(err, "role", role)
+		}
+	}
+	return nil
+}
+
+// isolateStatements revoke the default access of every role to a logical
+// database and leave its owner the public schema
+func isolateStatements(db *LogicalDatabase) []string {
+	return []string{
+		fmt.Sprintf("REVOKE CONNECT, TEMPORARY ON DATABASE %s FROM PUBLIC", ident(db.Name)),
+		"REVOKE CREATE ON SCHEMA public FROM PUBLIC",
+		fmt.Sprintf("GRANT USAGE, CREATE ON SCHEMA public TO %s", ident(db.Owner)),
+	}
+}
+
+// grantStatements give a role the access of a grant to a logical database
+func grantStatements(role string, db *LogicalDatabase, grant *Grant) []string {
+	tables, sequences := "SELECT", "SELECT"
+	if grant.Access != AccessReadOnly {
+		tables, sequences = "SELECT, INSERT, UPDATE, DELETE", "USAGE, SELECT, UPDATE"
+	}
+	schemaPrivileges := "USAGE"
+	if grant.Access == AccessOwner {
+		tables, schemaPrivileges = "ALL", "USAGE, CREATE"
+	}
+	r, o := ident(role), ident(db.Owner)
+	return []string{
+		fmt.Sprintf("GRANT CONNECT, TEMPORARY ON DATABASE %s TO %s", ident(db.Name), r),
+		fmt.Sprintf("GRANT %s ON SCHEMA public TO %s", schemaPrivileges, r),
+		fmt.Sprintf("GRANT %s ON ALL TABLES IN SCHEMA public TO %s", tables, r),
+		fmt.Sprintf("GRANT %s ON ALL SEQUENCES IN SCHEMA public TO %s", sequences, r),
+		fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA public GRANT %s ON TABLES TO %s", o, tables, r),
+		fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA public GRANT %s ON SEQUENCES TO %s", o, sequences, r),
+	}
+}
+
+// roleAccess returns the access of a role to the logical database of a
+// connection, or nil for none
+func roleAccess(ctx context.Context, conn *pgx.Conn, database, role string) *Grant {
+	var connect, create, write bool
+	err := conn.QueryRow(ctx, `
+		SELECT has_database_privilege($1, current_database(), 'CONNECT'),
+			has_schema_privilege($1, 'public', 'CREATE'),
+			EXISTS (SELECT 1 FROM pg_default_acl d, aclexplode(d.defaclacl) a
+				WHERE d.defaclobjtype = 'r' AND a.privilege_type = 'INSERT'
+					AND a.grantee = (SELECT oid FROM pg_roles WHERE rolname = $1))`, role).Scan(&connect, &create, &write)
+	if err != nil || !connect {
+		return nil
+	}
+	switch {
+	case create:
+		return &Grant{Database: database, Access: AccessOwner}
+	case write:
+		return &Grant{Database: database, Access: AccessReadWrite}
+	}
+	return &Grant{Database: database, Access: AccessReadOnly}
+}
+
+// storeRoleCredentials writes the credentials of a role into its secret
+func (s *DatabaseService) storeRoleCredentials(ctx context.Context, cluster string, role *Role, password string) (*RoleCredentials, error) {
+	clusterObj, err := s.dynamic.Resource(YBClusterGVR).Namespace(s.namespace).Get(ctx, cluster, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	database := ""
+	if len(role.Grants) > 0 {
+		database = role.Grants[0].Database
+	}
+	uri := fmt.Sprintf("postgresql://%s:%s@%s-yb-tserver-service.%s.svc:5433/%s", role.Name, password, cluster, s.namespace, database)
+
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":            role.SecretName,
+				"namespace":       s.namespace,
+				"ownerReferences": clusterOwner(clusterObj),
+			},
+			"type": "Opaque",
+			"stringData": map[string]interface{}{
+				"username": role.Name,
+				"password": password,
+				"database": database,
+				"uri":      uri,
+			},
+		},
+	}
+	if err := s.apply(ctx, secretGVR, secret); err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	return &RoleCredentials{Role: role, Password: password, URI: uri}, nil
+}
+
+// storeOwnerPassword replaces the password of the owner of a cluster in its
+// credentials secret, and restarts the pooler that connects with it
+func (s *DatabaseService) storeOwnerPassword(ctx context.Context, cluster string, role *Role, password string) (*RoleCredentials, error) {
+	secrets := s.dynamic.Resource(secretGVR).Namespace(s.namespace)
+	secret, err := secrets.Get(ctx, role.SecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	old := secretValue(secret, "password")
+	values := map[string]interface{}{"password": password}
+	for _, key := range []string{"uri", "ysql_uri"} {
+		values[key] = strings.Replace(secretValue(secret, key), ":"+old+"@", ":"+password+"@", 1)
+	}
+	if err := unstructured.SetNestedField(secret.Object, values, "stringData"); err != nil {
+		return nil, err
+	}
+	if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+
+	deployments := s.dynamic.Resource(deploymentGVR).Namespace(s.namespace)
+	pooler, err := deployments.Get(ctx, poolerName(cluster), metav1.GetOptions{})
+	switch {
+	case err == nil:
+		if err := unstructured.SetNestedField(pooler.Object, time.Now().UTC().Format(time.RFC3339), "spec", "template", "metadata", "annotations", "northstack.io/restarted-at"); err != nil {
+			return nil, err
+		}
+		if _, err := deployments.Update(ctx, pooler, metav1.UpdateOptions{}); err != nil {
+			return nil, errors.DependencyFailed("kubernetes", err)
+		}
+	case !apierrors.IsNotFound(err):
+		return nil, errors.DependencyFailed("kubernetes", err)
+	}
+	return &RoleCredentials{Role: role, Password: password, URI: values["uri"].(string)}, nil
+}
+
+// adminConn connects to a logical database of a cluster as the superuser, or
+// to the yugabyte database without one. The first connection bootstraps the
+// cluster.
+func (s *DatabaseService) adminConn(ctx context.Context, cluster, database string) (*pgx.Conn, error) {
+	secret, err := s.dynamic.Resource(secretGVR).Namespace(s.namespace).Get(ctx, cluster+"-credentials", metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if database == "" {
+		database = superuser
+	}
+
+	// A stored password the superuser does not have yet was stored before
+	// bootstrapping failed
+	password := secretValue(secret, "admin_password")
+	if password != "" {
+		conn, err := s.connect(ctx, cluster, database, password)
+		if !authFailed(err) {
+			return conn, connError(err, database)
+		}
+	}
+	if password, err = s.bootstrap(ctx, cluster, secret); err != nil {
+		return nil, err
+	}
+	conn, err := s.connect(ctx, cluster, database, password)
+	return conn, connError(err, database)
+}
+
+// bootstrap replaces the initial superuser password of a cluster, and creates
+// the role and database of its credentials secret. It returns the new
+// superuser password.
+func (s *DatabaseService) bootstrap(ctx context.Context, cluster string, secret *unstructured.Unstructured) (string, error) {
+	s.adminMu.Lock()
+	defer s.adminMu.Unlock()
+
+	conn, err := s.connect(ctx, cluster, superuser, superuserPassword)
+	if err != nil {
+		return "", connError(err, superuser)
+	}
+	defer conn.Close(ctx)
+
+	password, err := password.Generate()
+	if err != nil {
+		return "", err
+	}
+	// Store the password first: a password only the superuser has would lock
+	// the orchestrator out
+	if err := unstructured.SetNestedField(secret.Object, password, "stringData", "admin_password"); err != nil {
+		return "", err
+	}
+	if _, err := s.dynamic.Resource(secretGVR).Namespace(s.namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return "", errors.DependencyFailed("kubernetes", err)
+	}
+	if _, err := conn.Exec(ctx, fmt.Sprintf("ALTER ROLE %s PASSWORD %s", ident(superuser), literal(password))); err != nil {
+		return "", sqlError(err, "role", superuser)
+	}
+
+	owner, database := secretValue(secret, "username"), secretValue(secret, "database")
+	statements := []string{
+		fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD %s", ident(owner), literal(secretValue(secret, "password"))),
+		fmt.Sprintf("CREATE DATABASE %s OWNER %s", ident(database), ident(owner)),
+	}
+	for _, statement := range statements {
+		if _, err := conn.Exec(ctx, statement); err != nil && !exists(err) {
+			return "", sqlError(err, "database", database)
+		}
+	}
+	return password, nil
+}
+
+// connect connects to the YSQL API of a cluster as the superuser
+func (s *DatabaseService) connect(ctx context.Context, cluster, database, password string) (*pgx.Conn, error) {
+	dsn := (&url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(superuser, password),
+		Host:     fmt.Sprintf("%s-yb-tserver-service.%s.svc:5433", cluster, s.namespace),
+		Path:     "/" + database,
+		RawQuery: "sslmode=prefer&connect_timeout=5&application_name=northstack-orchestrator",
+	}).String()
+	return pgx.Connect(ctx, dsn)
+}
+
+// exists reports whether a statement failed because what it creates exists
+func exists(err error) bool {
+	var pgErr *pgconn.PgError
+	return stderrors.As(err, &pgErr) && (pgErr.Code == "42P04" || pgErr.Code == "42710") // duplicate_database, duplicate_object
+}
+
+// authFailed reports whether connecting failed because of the password
+func authFailed(err error) bool {
+	var pgErr *pgconn.PgError
+	return stderrors.As(err, &pgErr) && pgErr.Code == "28P01" // invalid_password
+}
+
+// connError maps an error connecting to a cluster to an AppError. A cluster
+// that cannot be reached is reported as not ready.
+func connError(err error, database string) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case err == nil:
+		return nil
+	case stderrors.As(err, &pgErr):
+		return sqlError(err, "database", database)
+	}
+	return errors.NewError(errors.CodeServiceUnavailable, "the database is not ready yet", http.StatusServiceUnavailable).WithError(err)
+}
+
+// sqlError maps a YSQL error to an AppError
+func sqlError(err error, resource, name string) error {
+	var pgErr *pgconn.PgError
+	if err == nil || !stderrors.As(err, &pgErr) {
+		if err != nil {
+			return errors.DependencyFailed("yugabytedb", err)
+		}
+		return nil
+	}
+	switch {
+	case exists(err):
+		return errors.Conflict(resource)
+	case pgErr.Code == "3D000" || pgErr.Code == "42704": // invalid_catalog_name, undefined_object
+		return errors.NotFound(resource, name)
+	case pgErr.Code == "55006": // object_in_use
+		return errors.NewError(errors.CodeConflict, fmt.Sprintf("%s %s is in use", resource, name), http.StatusConflict)
+	case pgErr.Code == "2BP01": // dependent_objects_still_exist
+		return errors.NewError(errors.CodeConflict, pgErr.Message, http.StatusConflict)
+	}
+	return errors.DependencyFailed("yugabytedb", err)
+}
+
+// validateName rejects names of databases and roles that are invalid or
+// reserved
+func validateName(name string) error {
+	if !identifierPattern.MatchString(name) {
+		return errors.BadRequest("names must start with a lowercase letter and contain only lowercase letters, digits and underscores")
+	}
+	if reservedNames[name] || strings.HasPrefix(name, "pg_") || strings.HasPrefix(name, "yb_") {
+		return errors.BadRequest(fmt.Sprintf("the name %s is reserved", name))
+	}
+	return nil
+}
+
+func reservedList() []string {
+	names := make([]string, 0, len(reservedNames))
+	for name := range reservedNames {
+		names = append(names, name)
+	}
+	return names
+}
+
+// roleSecretName is the name of the secret of a role
+func roleSecretName(cluster, role string) string {
+	return fmt.Sprintf("%s-role-%s", cluster, strings.ReplaceAll(role, "_", "-"))
+}
+
+// ident quotes an identifier
+func ident(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// literal quotes a string literal; DDL statements take no parameters
+func literal(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// secretValue returns a value of a secret read through the dynamic client
+func secretValue(secret *unstructured.Unstructured, key string) string {
+	if value, ok, _ := unstructured.NestedString(secret.Object, "data", key); ok {
+		if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+			return string(decoded)
+		}
+	}
+	value, _, _ := unstructured.NestedString(secret.Object, "stringData", key)
+	return value
+}
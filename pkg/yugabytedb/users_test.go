@@ -0,0 +1,165 @@
+package yugabytedb
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/northstack/platform/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"orders", true},
+		{"orders_2024", true},
+		{"a", true},
+		{"", false},
+		{"Orders", false},
+		{"2orders", false},
+		{"_orders", false},
+		{"order-items", false},
+		{`orders"; DROP DATABASE x; --`, false},
+		{"a123456789012345678901234567890123456789012345678901234567890123", false},
+		{"yugabyte", false},
+		{"postgres", false},
+		{"template1", false},
+		{"public", false},
+		{"pg_monitor", false},
+		{"yb_superuser", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateName(tt.name)
+			if tt.valid {
+				assert.NoError(t, err)
+				return
+			}
+			var appErr *errors.AppError
+			require.True(t, stderrors.As(err, &appErr))
+			assert.Equal(t, http.StatusBadRequest, appErr.HTTPStatus)
+		})
+	}
+}
+
+func TestIsolateStatements(t *testing.T) {
+	assert.Equal(t, []string{
+		`REVOKE CONNECT, TEMPORARY ON DATABASE "orders" FROM PUBLIC`,
+		"REVOKE CREATE ON SCHEMA public FROM PUBLIC",
+		`GRANT USAGE, CREATE ON SCHEMA public TO "app"`,
+	}, isolateStatements(&LogicalDatabase{Name: "orders", Owner: "app"}))
+}
+
+func TestGrantStatements(t *testing.T) {
+	db := &LogicalDatabase{Name: "orders", Owner: "app"}
+
+	tests := []struct {
+		access string
+		want   []string
+	}{
+		{AccessReadOnly, []string{
+			`GRANT CONNECT, TEMPORARY ON DATABASE "orders" TO "reporting"`,
+			`GRANT USAGE ON SCHEMA public TO "reporting"`,
+			`GRANT SELECT ON ALL TABLES IN SCHEMA public TO "reporting"`,
+			`GRANT SELECT ON ALL SEQUENCES IN SCHEMA public TO "reporting"`,
+			`ALTER DEFAULT PRIVILEGES FOR ROLE "app" IN SCHEMA public GRANT SELECT ON TABLES TO "reporting"`,
+			`ALTER DEFAULT PRIVILEGES FOR ROLE "app" IN SCHEMA public GRANT SELECT ON SEQUENCES TO "reporting"`,
+		}},
+		{AccessReadWrite, []string{
+			`GRANT CONNECT, TEMPORARY ON DATABASE "orders" TO "reporting"`,
+			`GRANT USAGE ON SCHEMA public TO "reporting"`,
+			`GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO "reporting"`,
+			`GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA public TO "reporting"`,
+			`ALTER DEFAULT PRIVILEGES FOR ROLE "app" IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO "reporting"`,
+			`ALTER DEFAULT PRIVILEGES FOR ROLE "app" IN SCHEMA public GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO "reporting"`,
+		}},
+		{AccessOwner, []string{
+			`GRANT CONNECT, TEMPORARY ON DATABASE "orders" TO "reporting"`,
+			`GRANT USAGE, CREATE ON SCHEMA public TO "reporting"`,
+			`GRANT ALL ON ALL TABLES IN SCHEMA public TO "reporting"`,
+			`GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA public TO "reporting"`,
+			`ALTER DEFAULT PRIVILEGES FOR ROLE "app" IN SCHEMA public GRANT ALL ON TABLES TO "reporting"`,
+			`ALTER DEFAULT PRIVILEGES FOR ROLE "app" IN SCHEMA public GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO "reporting"`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.access, func(t *testing.T) {
+			assert.Equal(t, tt.want, grantStatements("reporting", db, &Grant{Database: db.Name, Access: tt.access}))
+		})
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	assert.Equal(t, `"orders"`, ident("orders"))
+	assert.Equal(t, `"a""b"`, ident(`a"b`))
+	assert.Equal(t, `'secret'`, literal("secret"))
+	assert.Equal(t, `'it''s'`, literal("it's"))
+}
+
+func TestSQLError(t *testing.T) {
+	pgErr := func(code, message string) error {
+		return fmt.Errorf("exec: %w", &pgconn.PgError{Code: code, Message: message})
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		code    errors.Code
+		status  int
+		message string
+	}{
+		{"duplicate database", pgErr("42P04", ""), errors.CodeConflict, http.StatusConflict, "role already exists"},
+		{"duplicate object", pgErr("42710", ""), errors.CodeConflict, http.StatusConflict, "role already exists"},
+		{"unknown database", pgErr("3D000", ""), errors.CodeNotFound, http.StatusNotFound, ""},
+		{"unknown object", pgErr("42704", ""), errors.CodeNotFound, http.StatusNotFound, ""},
+		{"in use", pgErr("55006", ""), errors.CodeConflict, http.StatusConflict, "role reporting is in use"},
+		{"dependent objects", pgErr("2BP01", "role owns table"), errors.CodeConflict, http.StatusConflict, "role owns table"},
+		{"other SQL error", pgErr("42601", "syntax error"), errors.CodeDependencyFailed, http.StatusServiceUnavailable, ""},
+		{"not a SQL error", stderrors.New("connection reset"), errors.CodeDependencyFailed, http.StatusServiceUnavailable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var appErr *errors.AppError
+			require.True(t, stderrors.As(sqlError(tt.err, "role", "reporting"), &appErr))
+			assert.Equal(t, tt.code, appErr.Code)
+			assert.Equal(t, tt.status, appErr.HTTPStatus)
+			if tt.message != "" {
+				assert.Equal(t, tt.message, appErr.Message)
+			}
+		})
+	}
+
+	assert.NoError(t, sqlError(nil, "role", "reporting"))
+}
+
+func TestConnError(t *testing.T) {
+	assert.NoError(t, connError(nil, "orders"))
+
+	var appErr *errors.AppError
+	require.True(t, stderrors.As(connError(&pgconn.PgError{Code: "3D000"}, "orders"), &appErr))
+	assert.Equal(t, http.StatusNotFound, appErr.HTTPStatus)
+
+	require.True(t, stderrors.As(connError(stderrors.New("dial tcp: connection refused"), "orders"), &appErr))
+	assert.Equal(t, errors.CodeServiceUnavailable, appErr.Code)
+}
+
+func TestRoleSecretName(t *testing.T) {
+	assert.Equal(t, "prod-db-role-read-only", roleSecretName("prod-db", "read_only"))
+}
+
+func TestSecretValue(t *testing.T) {
+	secret := &unstructured.Unstructured{Object: map[string]interface{}{
+		"data":       map[string]interface{}{"password": "aHVudGVyMg=="},
+		"stringData": map[string]interface{}{"username": "app"},
+	}}
+	assert.Equal(t, "hunter2", secretValue(secret, "password"))
+	assert.Equal(t, "app", secretValue(secret, "username"))
+	assert.Equal(t, "", secretValue(secret, "admin_password"))
+}
@@ -0,0 +1,73 @@
+terraform {
+  required_providers {
+    northstack = {
+      source = "northstack/northstack"
+    }
+  }
+}
+
+provider "northstack" {}
+
+resource "northstack_project" "shop" {
+  name        = "Shop"
+  slug        = "shop"
+  description = "Storefront and order processing"
+  labels = {
+    team = "commerce"
+  }
+}
+
+resource "northstack_environment" "production" {
+  project_id = northstack_project.shop.id
+  cluster_id = var.cluster_id
+  name       = "Production"
+  type       = "production"
+  is_default = true
+}
+
+resource "northstack_secret" "database" {
+  project_id = northstack_project.shop.id
+  name       = "database"
+  vault_path = "secret/data/shop/database"
+  keys       = ["url"]
+}
+
+resource "northstack_service" "api" {
+  project_id = northstack_project.shop.id
+  name       = "API"
+  slug       = "api"
+
+  build_source = {
+    type       = "git"
+    repository = "https://github.com/example/shop"
+    branch     = "main"
+  }
+
+  ports = [{
+    name   = "http"
+    port   = 8080
+    public = true
+  }]
+
+  scaling = {
+    min_replicas = 2
+    max_replicas = 6
+    target_cpu   = 70
+  }
+
+  env_vars = {
+    LOG_LEVEL = "info"
+  }
+  secret_refs = [northstack_secret.database.name]
+}
+
+resource "northstack_domain" "api" {
+  service_id  = northstack_service.api.id
+  domain      = "api.shop.example.com"
+  tls_enabled = true
+  auto_tls    = true
+}
+
+variable "cluster_id" {
+  type = string
+}
@@ -0,0 +1,133 @@
+// Package client calls the platform API for the Terraform provider.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client calls the platform API with a JWT or an API key
+type Client struct {
+	endpoint  string
+	token     string
+	userAgent string
+	http      *http.Client
+}
+
+// New creates a Client for the API at endpoint, e.g. https://api.example.com
+func New(endpoint, token, version string) *Client {
+	return &Client{
+		endpoint:  strings.TrimRight(endpoint, "/") + "/api/v1",
+		token:     token,
+		userAgent: "terraform-provider-northstack/" + version,
+		http:      &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Error is an error response of the API
+type Error struct {
+	Status int
+	Detail string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("API answered %d: %s", e.Status, e.Detail)
+}
+
+// IsNotFound reports whether err is a 404 response
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// Get fetches path into out
+func (c *Client) Get(ctx context.Context, path string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, nil, out)
+}
+
+// Post sends in to path and decodes the response into out
+func (c *Client) Post(ctx context.Context, path string, in, out interface{}) error {
+	return c.do(ctx, http.MethodPost, path, in, out)
+}
+
+// Patch sends in to path and decodes the response into out
+func (c *Client) Patch(ctx context.Context, path string, in, out interface{}) error {
+	return c.do(ctx, http.MethodPatch, path, in, out)
+}
+
+// Delete deletes path; deleting what is already gone succeeds
+func (c *Client) Delete(ctx context.Context, path string) error {
+	if err := c.do(ctx, http.MethodDelete, path, nil, nil); err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return newError(resp.StatusCode, data)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// newError reads the problem details of an error response
+func newError(status int, data []byte) *Error {
+	var problem struct {
+		Detail string `json:"detail"`
+		Errors []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(data, &problem) != nil || problem.Detail == "" {
+		return &Error{Status: status, Detail: strings.TrimSpace(string(data))}
+	}
+
+	detail := problem.Detail
+	for _, e := range problem.Errors {
+		detail += "; " + e.Field + " " + e.Message
+	}
+	return &Error{Status: status, Detail: detail}
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer nfoss_key" {
+			t.Errorf("missing token: %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Path {
+		case "/api/v1/projects/1":
+			w.Write([]byte(`{"id":"1","name":"shop","slug":"shop"}`))
+		case "/api/v1/projects/2":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"status":404,"detail":"project not found"}`))
+		case "/api/v1/projects":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":400,"detail":"Validation failed","errors":[{"field":"slug","message":"is required"}]}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	c := New(server.URL+"/", "nfoss_key", "test")
+	ctx := context.Background()
+
+	var project Project
+	if err := c.Get(ctx, "/projects/1", &project); err != nil || project.Slug != "shop" {
+		t.Fatalf("Get = %+v, %v", project, err)
+	}
+
+	if err := c.Get(ctx, "/projects/2", &project); !IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if err := c.Delete(ctx, "/projects/2"); err != nil {
+		t.Errorf("deleting a missing project: %v", err)
+	}
+
+	err := c.Post(ctx, "/projects", Project{Name: "shop"}, &project)
+	if err == nil || err.Error() != "API answered 400: Validation failed; slug is required" {
+		t.Errorf("Post error = %v", err)
+	}
+}
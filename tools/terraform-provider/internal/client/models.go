@@ -0,0 +1,125 @@
+package client
+
+// Project is a project as the API returns it
+type Project struct {
+	ID            string            `json:"id,omitempty"`
+	Name          string            `json:"name"`
+	Slug          string            `json:"slug,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Status        string            `json:"status,omitempty"`
+	TeamID        *string           `json:"team_id,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	DataResidency *string           `json:"data_residency,omitempty"`
+}
+
+// Service is a service as the API returns it
+type Service struct {
+	ID          string            `json:"id,omitempty"`
+	ProjectID   string            `json:"project_id,omitempty"`
+	Name        string            `json:"name"`
+	Slug        string            `json:"slug,omitempty"`
+	Type        string            `json:"type,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	BuildSource *BuildSource      `json:"build_source,omitempty"`
+	Resources   *Resources        `json:"resources,omitempty"`
+	Scaling     *Scaling          `json:"scaling,omitempty"`
+	EnvVars     map[string]string `json:"env_vars"`
+	SecretRefs  []string          `json:"secret_refs,omitempty"`
+	Ports       []Port            `json:"ports,omitempty"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// BuildSource is where a service's image comes from
+type BuildSource struct {
+	Type       string `json:"type"`
+	Repository string `json:"repository,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Dockerfile string `json:"dockerfile,omitempty"`
+	Image      string `json:"image,omitempty"`
+	Registry   string `json:"registry,omitempty"`
+}
+
+// Resources are a service's compute requests and limits
+type Resources struct {
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+	StorageSize   string `json:"storage_size,omitempty"`
+}
+
+// Scaling bounds a service's replicas
+type Scaling struct {
+	MinReplicas  int64 `json:"min_replicas"`
+	MaxReplicas  int64 `json:"max_replicas"`
+	TargetCPU    int64 `json:"target_cpu,omitempty"`
+	TargetMemory int64 `json:"target_memory,omitempty"`
+}
+
+// Port is a port a service listens on
+type Port struct {
+	Name       string `json:"name"`
+	Port       int64  `json:"port"`
+	TargetPort int64  `json:"target_port,omitempty"`
+	Protocol   string `json:"protocol,omitempty"`
+	Public     bool   `json:"public"`
+}
+
+// Environment is a deployment target of a project on a cluster
+type Environment struct {
+	ID        string            `json:"id,omitempty"`
+	ProjectID string            `json:"project_id,omitempty"`
+	ClusterID string            `json:"cluster_id,omitempty"`
+	Name      string            `json:"name"`
+	Slug      string            `json:"slug,omitempty"`
+	Type      string            `json:"type"`
+	Namespace string            `json:"namespace,omitempty"`
+	IsDefault bool              `json:"is_default"`
+	Labels    map[string]string `json:"labels"`
+}
+
+// Secret is a reference to a secret stored in Vault; values never pass
+// through the API
+type Secret struct {
+	ID        string            `json:"id,omitempty"`
+	ProjectID string            `json:"project_id,omitempty"`
+	Name      string            `json:"name"`
+	Type      string            `json:"type,omitempty"`
+	Keys      []string          `json:"keys,omitempty"`
+	VaultPath string            `json:"vault_path"`
+	Version   int64             `json:"version,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// Ingress routes a domain and path to a service
+type Ingress struct {
+	ID        string            `json:"id,omitempty"`
+	ServiceID string            `json:"service_id,omitempty"`
+	Domain    string            `json:"domain"`
+	Path      string            `json:"path"`
+	Type      string            `json:"type,omitempty"`
+	TLS       TLS               `json:"tls"`
+	Labels    map[string]string `json:"labels"`
+}
+
+// TLS configures an ingress's certificate
+type TLS struct {
+	Enabled    bool   `json:"enabled"`
+	SecretName string `json:"secret_name,omitempty"`
+	AutoTLS    bool   `json:"auto_tls"`
+}
+
+// Cluster is a Kubernetes cluster services deploy to
+type Cluster struct {
+	ID          string            `json:"id,omitempty"`
+	Name        string            `json:"name"`
+	Slug        string            `json:"slug,omitempty"`
+	Provider    string            `json:"provider,omitempty"`
+	Region      string            `json:"region,omitempty"`
+	KubeVersion string            `json:"kube_version,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	Endpoint    string            `json:"endpoint,omitempty"`
+	NodeCount   int64             `json:"node_count"`
+	Labels      map[string]string `json:"labels"`
+	EgressIPs   []string          `json:"egress_ips"`
+}
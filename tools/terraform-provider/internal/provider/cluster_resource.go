@@ -0,0 +1,202 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/northstack/platform/tools/terraform-provider/internal/client"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &clusterResource{}
+	_ resource.ResourceWithImportState = &clusterResource{}
+)
+
+// clusterResource manages a Kubernetes cluster. The cluster API is only open
+// to platform administrators.
+type clusterResource struct {
+	client *client.Client
+}
+
+func newClusterResource() resource.Resource {
+	return &clusterResource{}
+}
+
+type clusterModel struct {
+	ID          types.String `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	Slug        types.String `tfsdk:"slug"`
+	Provider    types.String `tfsdk:"distribution"`
+	Region      types.String `tfsdk:"region"`
+	KubeVersion types.String `tfsdk:"kube_version"`
+	NodeCount   types.Int64  `tfsdk:"node_count"`
+	Labels      types.Map    `tfsdk:"labels"`
+	EgressIPs   types.List   `tfsdk:"egress_ips"`
+	Status      types.String `tfsdk:"status"`
+	Endpoint    types.String `tfsdk:"endpoint"`
+}
+
+func (r *clusterResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cluster"
+}
+
+func (r *clusterResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	replace := []planmodifier.String{stringplanmodifier.RequiresReplace()}
+	computed := []planmodifier.String{stringplanmodifier.UseStateForUnknown()}
+
+	resp.Schema = schema.Schema{
+		Description: "A Kubernetes cluster services deploy to. Requires an administrator token.",
+		Attributes: map[string]schema.Attribute{
+			"id":   schema.StringAttribute{Computed: true, PlanModifiers: computed},
+			"name": schema.StringAttribute{Required: true},
+			"slug": schema.StringAttribute{
+				Description:   "Defaults to the name as a DNS label.",
+				Optional:      true,
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown(), stringplanmodifier.RequiresReplace()},
+			},
+			// provider is a reserved attribute name in Terraform
+			"distribution": schema.StringAttribute{
+				Description:   "Kubernetes distribution or managed service: one of rancher, rke2, k3s, eks, gke and aks.",
+				Required:      true,
+				PlanModifiers: replace,
+			},
+			"region": schema.StringAttribute{Required: true, PlanModifiers: replace},
+			"kube_version": schema.StringAttribute{
+				Optional:      true,
+				Computed:      true,
+				PlanModifiers: computed,
+			},
+			"node_count": schema.Int64Attribute{Required: true},
+			"labels":     schema.MapAttribute{ElementType: types.StringType, Optional: true},
+			"egress_ips": schema.ListAttribute{
+				Description: "Public IPs of the NAT in front of the cluster, for allowlisting.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"status":   schema.StringAttribute{Computed: true},
+			"endpoint": schema.StringAttribute{Computed: true, PlanModifiers: computed},
+		},
+	}
+}
+
+func (r *clusterResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req, resp)
+}
+
+func (r *clusterResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan clusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := client.Cluster{
+		Name:      plan.Name.ValueString(),
+		Slug:      plan.Slug.ValueString(),
+		Provider:  plan.Provider.ValueString(),
+		Region:    plan.Region.ValueString(),
+		NodeCount: plan.NodeCount.ValueInt64(),
+		Labels:    stringMap(ctx, plan.Labels, &resp.Diagnostics),
+		EgressIPs: stringList(ctx, plan.EgressIPs, &resp.Diagnostics),
+	}
+	if !plan.KubeVersion.IsUnknown() {
+		body.KubeVersion = plan.KubeVersion.ValueString()
+	}
+	var cluster client.Cluster
+	if err := r.client.Post(ctx, "/clusters", body, &cluster); err != nil {
+		resp.Diagnostics.AddError("Failed to create cluster", err.Error())
+		return
+	}
+
+	plan.fromAPI(ctx, &cluster, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *clusterResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state clusterModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var cluster client.Cluster
+	if err := r.client.Get(ctx, "/clusters/"+state.ID.ValueString(), &cluster); err != nil {
+		if client.IsNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read cluster", err.Error())
+		return
+	}
+
+	state.fromAPI(ctx, &cluster, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *clusterResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state clusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := map[string]interface{}{
+		"name":       plan.Name.ValueString(),
+		"node_count": plan.NodeCount.ValueInt64(),
+		"labels":     stringMapOrEmpty(ctx, plan.Labels, &resp.Diagnostics),
+	}
+	if !plan.KubeVersion.IsUnknown() && !plan.KubeVersion.IsNull() {
+		body["kube_version"] = plan.KubeVersion.ValueString()
+	}
+	if egress := stringList(ctx, plan.EgressIPs, &resp.Diagnostics); egress != nil {
+		body["egress_ips"] = egress
+	}
+	var cluster client.Cluster
+	if err := r.client.Patch(ctx, "/clusters/"+state.ID.ValueString(), body, &cluster); err != nil {
+		resp.Diagnostics.AddError("Failed to update cluster", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.fromAPI(ctx, &cluster, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *clusterResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state clusterModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.Delete(ctx, "/clusters/"+state.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete cluster", err.Error())
+	}
+}
+
+func (r *clusterResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// fromAPI copies a cluster from the API into the model
+func (m *clusterModel) fromAPI(ctx context.Context, cluster *client.Cluster, diags *diag.Diagnostics) {
+	m.ID = types.StringValue(cluster.ID)
+	m.Name = types.StringValue(cluster.Name)
+	m.Slug = types.StringValue(cluster.Slug)
+	m.Provider = types.StringValue(cluster.Provider)
+	m.Region = types.StringValue(cluster.Region)
+	m.KubeVersion = types.StringValue(cluster.KubeVersion)
+	m.NodeCount = types.Int64Value(cluster.NodeCount)
+	m.Labels = mapValue(ctx, cluster.Labels, m.Labels, diags)
+	m.EgressIPs = listValue(ctx, cluster.EgressIPs, m.EgressIPs, diags)
+	m.Status = types.StringValue(cluster.Status)
+	m.Endpoint = types.StringValue(cluster.Endpoint)
+}
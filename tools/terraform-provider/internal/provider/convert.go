@@ -0,0 +1,85 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// The API omits empty values. Attributes that were not configured stay null
+// when the API returns them empty, so that plans stay clean.
+
+// stringMap returns the elements of a map attribute, nil when it is null
+func stringMap(ctx context.Context, m types.Map, diags *diag.Diagnostics) map[string]string {
+	if m.IsNull() || m.IsUnknown() {
+		return nil
+	}
+	result := map[string]string{}
+	diags.Append(m.ElementsAs(ctx, &result, false)...)
+	return result
+}
+
+// stringMapOrEmpty is stringMap for PATCH bodies, where an empty map clears
+// the attribute and a missing one keeps it
+func stringMapOrEmpty(ctx context.Context, m types.Map, diags *diag.Diagnostics) map[string]string {
+	if result := stringMap(ctx, m, diags); result != nil {
+		return result
+	}
+	return map[string]string{}
+}
+
+// mapValue returns a map attribute for a value from the API
+func mapValue(ctx context.Context, m map[string]string, prior types.Map, diags *diag.Diagnostics) types.Map {
+	if len(m) == 0 && prior.IsNull() {
+		return types.MapNull(types.StringType)
+	}
+	value, d := types.MapValueFrom(ctx, types.StringType, m)
+	diags.Append(d...)
+	return value
+}
+
+// stringList returns the elements of a list attribute, nil when it is null
+func stringList(ctx context.Context, l types.List, diags *diag.Diagnostics) []string {
+	if l.IsNull() || l.IsUnknown() {
+		return nil
+	}
+	var result []string
+	diags.Append(l.ElementsAs(ctx, &result, false)...)
+	return result
+}
+
+// listValue returns a list attribute for a value from the API
+func listValue(ctx context.Context, s []string, prior types.List, diags *diag.Diagnostics) types.List {
+	if len(s) == 0 && prior.IsNull() {
+		return types.ListNull(types.StringType)
+	}
+	value, d := types.ListValueFrom(ctx, types.StringType, s)
+	diags.Append(d...)
+	return value
+}
+
+// optionalString returns a string attribute for a value from the API
+func optionalString(s string, prior types.String) types.String {
+	if s == "" && prior.IsNull() {
+		return types.StringNull()
+	}
+	return types.StringValue(s)
+}
+
+// optionalInt returns a number attribute for a value from the API
+func optionalInt(n int64, prior types.Int64) types.Int64 {
+	if n == 0 && prior.IsNull() {
+		return types.Int64Null()
+	}
+	return types.Int64Value(n)
+}
+
+// stringPointer returns nil for a null string attribute
+func stringPointer(s types.String) *string {
+	if s.IsNull() || s.IsUnknown() {
+		return nil
+	}
+	value := s.ValueString()
+	return &value
+}
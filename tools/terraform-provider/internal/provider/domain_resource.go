@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/northstack/platform/tools/terraform-provider/internal/client"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &domainResource{}
+	_ resource.ResourceWithImportState = &domainResource{}
+)
+
+// domainResource routes a domain to a service through an ingress
+type domainResource struct {
+	client *client.Client
+}
+
+func newDomainResource() resource.Resource {
+	return &domainResource{}
+}
+
+type domainModel struct {
+	ID            types.String `tfsdk:"id"`
+	ServiceID     types.String `tfsdk:"service_id"`
+	Domain        types.String `tfsdk:"domain"`
+	Path          types.String `tfsdk:"path"`
+	Type          types.String `tfsdk:"type"`
+	TLSEnabled    types.Bool   `tfsdk:"tls_enabled"`
+	AutoTLS       types.Bool   `tfsdk:"auto_tls"`
+	TLSSecretName types.String `tfsdk:"tls_secret_name"`
+	Labels        types.Map    `tfsdk:"labels"`
+}
+
+func (r *domainResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_domain"
+}
+
+func (r *domainResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A domain and path routed to a service.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"service_id": schema.StringAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"domain": schema.StringAttribute{Required: true},
+			"path": schema.StringAttribute{
+				Optional: true,
+				Computed: true,
+				Default:  stringdefault.StaticString("/"),
+			},
+			"type": schema.StringAttribute{
+				Description: "One of http, grpc and tcp.",
+				Optional:    true,
+				Computed:    true,
+				Default:     stringdefault.StaticString("http"),
+			},
+			"tls_enabled": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+			},
+			"auto_tls": schema.BoolAttribute{
+				Description: "Issue the certificate with cert-manager.",
+				Optional:    true,
+				Computed:    true,
+				Default:     booldefault.StaticBool(false),
+			},
+			"tls_secret_name": schema.StringAttribute{
+				Description: "Kubernetes secret holding the certificate when auto_tls is off.",
+				Optional:    true,
+			},
+			"labels": schema.MapAttribute{ElementType: types.StringType, Optional: true},
+		},
+	}
+}
+
+func (r *domainResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req, resp)
+}
+
+func (r *domainResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan domainModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := plan.ingress(ctx, &resp.Diagnostics)
+	var ingress client.Ingress
+	if err := r.client.Post(ctx, "/services/"+plan.ServiceID.ValueString()+"/ingresses", body, &ingress); err != nil {
+		resp.Diagnostics.AddError("Failed to create domain", err.Error())
+		return
+	}
+
+	plan.fromAPI(ctx, &ingress, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *domainResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state domainModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var ingress client.Ingress
+	if err := r.client.Get(ctx, "/ingresses/"+state.ID.ValueString(), &ingress); err != nil {
+		if client.IsNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read domain", err.Error())
+		return
+	}
+
+	state.fromAPI(ctx, &ingress, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *domainResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state domainModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := plan.ingress(ctx, &resp.Diagnostics)
+	body.Labels = stringMapOrEmpty(ctx, plan.Labels, &resp.Diagnostics)
+	var ingress client.Ingress
+	if err := r.client.Patch(ctx, "/ingresses/"+state.ID.ValueString(), body, &ingress); err != nil {
+		resp.Diagnostics.AddError("Failed to update domain", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.fromAPI(ctx, &ingress, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *domainResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state domainModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.Delete(ctx, "/ingresses/"+state.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete domain", err.Error())
+	}
+}
+
+func (r *domainResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// ingress returns the API body for the model
+func (m *domainModel) ingress(ctx context.Context, diags *diag.Diagnostics) client.Ingress {
+	return client.Ingress{
+		Domain: m.Domain.ValueString(),
+		Path:   m.Path.ValueString(),
+		Type:   m.Type.ValueString(),
+		TLS: client.TLS{
+			Enabled:    m.TLSEnabled.ValueBool(),
+			AutoTLS:    m.AutoTLS.ValueBool(),
+			SecretName: m.TLSSecretName.ValueString(),
+		},
+		Labels: stringMap(ctx, m.Labels, diags),
+	}
+}
+
+// fromAPI copies an ingress from the API into the model
+func (m *domainModel) fromAPI(ctx context.Context, ingress *client.Ingress, diags *diag.Diagnostics) {
+	m.ID = types.StringValue(ingress.ID)
+	m.ServiceID = types.StringValue(ingress.ServiceID)
+	m.Domain = types.StringValue(ingress.Domain)
+	m.Path = types.StringValue(ingress.Path)
+	m.Type = types.StringValue(ingress.Type)
+	m.TLSEnabled = types.BoolValue(ingress.TLS.Enabled)
+	m.AutoTLS = types.BoolValue(ingress.TLS.AutoTLS)
+	m.TLSSecretName = optionalString(ingress.TLS.SecretName, m.TLSSecretName)
+	m.Labels = mapValue(ctx, ingress.Labels, m.Labels, diags)
+}
@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/northstack/platform/tools/terraform-provider/internal/client"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &environmentResource{}
+	_ resource.ResourceWithImportState = &environmentResource{}
+)
+
+// environmentResource manages a deployment target of a project
+type environmentResource struct {
+	client *client.Client
+}
+
+func newEnvironmentResource() resource.Resource {
+	return &environmentResource{}
+}
+
+type environmentModel struct {
+	ID        types.String `tfsdk:"id"`
+	ProjectID types.String `tfsdk:"project_id"`
+	ClusterID types.String `tfsdk:"cluster_id"`
+	Name      types.String `tfsdk:"name"`
+	Slug      types.String `tfsdk:"slug"`
+	Type      types.String `tfsdk:"type"`
+	Namespace types.String `tfsdk:"namespace"`
+	IsDefault types.Bool   `tfsdk:"is_default"`
+	Labels    types.Map    `tfsdk:"labels"`
+}
+
+func (r *environmentResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_environment"
+}
+
+func (r *environmentResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	replace := []planmodifier.String{stringplanmodifier.RequiresReplace()}
+	computedReplace := []planmodifier.String{stringplanmodifier.UseStateForUnknown(), stringplanmodifier.RequiresReplace()}
+
+	resp.Schema = schema.Schema{
+		Description: "An environment of a project, a namespace on a cluster that services deploy to.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"project_id": schema.StringAttribute{Required: true, PlanModifiers: replace},
+			"cluster_id": schema.StringAttribute{Required: true, PlanModifiers: replace},
+			"name":       schema.StringAttribute{Required: true},
+			"slug": schema.StringAttribute{
+				Description:   "Defaults to the name as a DNS label.",
+				Optional:      true,
+				Computed:      true,
+				PlanModifiers: computedReplace,
+			},
+			"type": schema.StringAttribute{
+				Description: "One of development, staging, production and preview.",
+				Required:    true,
+			},
+			"namespace": schema.StringAttribute{
+				Description:   "Kubernetes namespace; derived from the project and slug when omitted.",
+				Optional:      true,
+				Computed:      true,
+				PlanModifiers: computedReplace,
+			},
+			"is_default": schema.BoolAttribute{
+				Optional: true,
+				Computed: true,
+				Default:  booldefault.StaticBool(false),
+			},
+			"labels": schema.MapAttribute{ElementType: types.StringType, Optional: true},
+		},
+	}
+}
+
+func (r *environmentResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req, resp)
+}
+
+func (r *environmentResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan environmentModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := client.Environment{
+		ClusterID: plan.ClusterID.ValueString(),
+		Name:      plan.Name.ValueString(),
+		Slug:      plan.Slug.ValueString(),
+		Type:      plan.Type.ValueString(),
+		Namespace: plan.Namespace.ValueString(),
+		IsDefault: plan.IsDefault.ValueBool(),
+		Labels:    stringMap(ctx, plan.Labels, &resp.Diagnostics),
+	}
+	var environment client.Environment
+	if err := r.client.Post(ctx, "/projects/"+plan.ProjectID.ValueString()+"/environments", body, &environment); err != nil {
+		resp.Diagnostics.AddError("Failed to create environment", err.Error())
+		return
+	}
+
+	plan.fromAPI(ctx, &environment, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *environmentResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state environmentModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var environment client.Environment
+	if err := r.client.Get(ctx, "/environments/"+state.ID.ValueString(), &environment); err != nil {
+		if client.IsNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read environment", err.Error())
+		return
+	}
+
+	state.fromAPI(ctx, &environment, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *environmentResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state environmentModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := map[string]interface{}{
+		"name":       plan.Name.ValueString(),
+		"type":       plan.Type.ValueString(),
+		"is_default": plan.IsDefault.ValueBool(),
+		"labels":     stringMapOrEmpty(ctx, plan.Labels, &resp.Diagnostics),
+	}
+	var environment client.Environment
+	if err := r.client.Patch(ctx, "/environments/"+state.ID.ValueString(), body, &environment); err != nil {
+		resp.Diagnostics.AddError("Failed to update environment", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.fromAPI(ctx, &environment, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *environmentResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state environmentModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.Delete(ctx, "/environments/"+state.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete environment", err.Error())
+	}
+}
+
+func (r *environmentResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// fromAPI copies an environment from the API into the model
+func (m *environmentModel) fromAPI(ctx context.Context, environment *client.Environment, diags *diag.Diagnostics) {
+	m.ID = types.StringValue(environment.ID)
+	m.ProjectID = types.StringValue(environment.ProjectID)
+	m.ClusterID = types.StringValue(environment.ClusterID)
+	m.Name = types.StringValue(environment.Name)
+	m.Slug = types.StringValue(environment.Slug)
+	m.Type = types.StringValue(environment.Type)
+	m.Namespace = types.StringValue(environment.Namespace)
+	m.IsDefault = types.BoolValue(environment.IsDefault)
+	m.Labels = mapValue(ctx, environment.Labels, m.Labels, diags)
+}
@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/northstack/platform/tools/terraform-provider/internal/client"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &projectResource{}
+	_ resource.ResourceWithImportState = &projectResource{}
+)
+
+// projectResource manages a project
+type projectResource struct {
+	client *client.Client
+}
+
+func newProjectResource() resource.Resource {
+	return &projectResource{}
+}
+
+type projectModel struct {
+	ID            types.String `tfsdk:"id"`
+	Name          types.String `tfsdk:"name"`
+	Slug          types.String `tfsdk:"slug"`
+	Description   types.String `tfsdk:"description"`
+	TeamID        types.String `tfsdk:"team_id"`
+	Labels        types.Map    `tfsdk:"labels"`
+	DataResidency types.String `tfsdk:"data_residency"`
+	Status        types.String `tfsdk:"status"`
+}
+
+func (r *projectResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_project"
+}
+
+func (r *projectResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A project, which groups services, environments and secrets.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"name": schema.StringAttribute{Required: true},
+			"slug": schema.StringAttribute{
+				Description:   "Alphanumeric identifier; changing it replaces the project.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"description": schema.StringAttribute{Optional: true},
+			"team_id": schema.StringAttribute{
+				Description: "Team owning the project. Moving a project to another team is supported; removing the team is not.",
+				Optional:    true,
+			},
+			"labels": schema.MapAttribute{ElementType: types.StringType, Optional: true},
+			"data_residency": schema.StringAttribute{
+				Description: "Region the project's data must stay in, e.g. eu.",
+				Optional:    true,
+			},
+			"status": schema.StringAttribute{Computed: true},
+		},
+	}
+}
+
+func (r *projectResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req, resp)
+}
+
+func (r *projectResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan projectModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := client.Project{
+		Name:          plan.Name.ValueString(),
+		Slug:          plan.Slug.ValueString(),
+		Description:   plan.Description.ValueString(),
+		TeamID:        stringPointer(plan.TeamID),
+		Labels:        stringMap(ctx, plan.Labels, &resp.Diagnostics),
+		DataResidency: stringPointer(plan.DataResidency),
+	}
+	var project client.Project
+	if err := r.client.Post(ctx, "/projects", body, &project); err != nil {
+		resp.Diagnostics.AddError("Failed to create project", err.Error())
+		return
+	}
+
+	plan.fromAPI(ctx, &project, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *projectResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state projectModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var project client.Project
+	if err := r.client.Get(ctx, "/projects/"+state.ID.ValueString(), &project); err != nil {
+		if client.IsNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read project", err.Error())
+		return
+	}
+
+	state.fromAPI(ctx, &project, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *projectResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state projectModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := map[string]interface{}{
+		"name":           plan.Name.ValueString(),
+		"description":    plan.Description.ValueString(),
+		"labels":         stringMapOrEmpty(ctx, plan.Labels, &resp.Diagnostics),
+		"data_residency": plan.DataResidency.ValueString(),
+	}
+	if teamID := stringPointer(plan.TeamID); teamID != nil {
+		body["team_id"] = *teamID
+	}
+	var project client.Project
+	if err := r.client.Patch(ctx, "/projects/"+state.ID.ValueString(), body, &project); err != nil {
+		resp.Diagnostics.AddError("Failed to update project", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.fromAPI(ctx, &project, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *projectResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state projectModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.Delete(ctx, "/projects/"+state.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete project", err.Error())
+	}
+}
+
+func (r *projectResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// fromAPI copies a project from the API into the model
+func (m *projectModel) fromAPI(ctx context.Context, project *client.Project, diags *diag.Diagnostics) {
+	m.ID = types.StringValue(project.ID)
+	m.Name = types.StringValue(project.Name)
+	m.Slug = types.StringValue(project.Slug)
+	m.Description = optionalString(project.Description, m.Description)
+	m.TeamID = types.StringNull()
+	if project.TeamID != nil {
+		m.TeamID = types.StringValue(*project.TeamID)
+	}
+	m.Labels = mapValue(ctx, project.Labels, m.Labels, diags)
+	residency := ""
+	if project.DataResidency != nil {
+		residency = *project.DataResidency
+	}
+	m.DataResidency = optionalString(residency, m.DataResidency)
+	m.Status = types.StringValue(project.Status)
+}
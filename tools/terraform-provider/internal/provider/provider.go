@@ -0,0 +1,119 @@
+// Package provider implements the Terraform provider on the plugin framework.
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/northstack/platform/tools/terraform-provider/internal/client"
+)
+
+var _ provider.Provider = &northstackProvider{}
+
+// northstackProvider configures the API client shared by the resources
+type northstackProvider struct {
+	version string
+}
+
+// New returns the constructor of the provider
+func New(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &northstackProvider{version: version}
+	}
+}
+
+type providerModel struct {
+	Endpoint types.String `tfsdk:"endpoint"`
+	Token    types.String `tfsdk:"token"`
+}
+
+func (p *northstackProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "northstack"
+	resp.Version = p.version
+}
+
+func (p *northstackProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages projects, services, environments, secrets, domains and clusters of the platform.",
+		Attributes: map[string]schema.Attribute{
+			"endpoint": schema.StringAttribute{
+				Description: "URL of the API, e.g. https://api.example.com. Defaults to NFOSS_API_URL.",
+				Optional:    true,
+			},
+			"token": schema.StringAttribute{
+				Description: "JWT or API key. Defaults to NFOSS_TOKEN.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+		},
+	}
+}
+
+func (p *northstackProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config providerModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	endpoint := os.Getenv("NFOSS_API_URL")
+	if !config.Endpoint.IsNull() {
+		endpoint = config.Endpoint.ValueString()
+	}
+	token := os.Getenv("NFOSS_TOKEN")
+	if !config.Token.IsNull() {
+		token = config.Token.ValueString()
+	}
+
+	if endpoint == "" {
+		resp.Diagnostics.AddAttributeError(path.Root("endpoint"), "Missing API endpoint",
+			"Set endpoint in the provider configuration or the NFOSS_API_URL environment variable.")
+	}
+	if token == "" {
+		resp.Diagnostics.AddAttributeError(path.Root("token"), "Missing API token",
+			"Set token in the provider configuration or the NFOSS_TOKEN environment variable.")
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	c := client.New(endpoint, token, p.version)
+	resp.ResourceData = c
+	resp.DataSourceData = c
+}
+
+func (p *northstackProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newProjectResource,
+		newServiceResource,
+		newEnvironmentResource,
+		newSecretResource,
+		newDomainResource,
+		newClusterResource,
+	}
+}
+
+func (p *northstackProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return nil
+}
+
+// configureClient returns the API client the provider configured, nil before
+// the provider is configured
+func configureClient(req resource.ConfigureRequest, resp *resource.ConfigureResponse) *client.Client {
+	if req.ProviderData == nil {
+		return nil
+	}
+	c, ok := req.ProviderData.(*client.Client)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *client.Client, got %T.", req.ProviderData))
+		return nil
+	}
+	return c
+}
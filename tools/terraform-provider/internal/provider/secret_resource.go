@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/northstack/platform/tools/terraform-provider/internal/client"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &secretResource{}
+	_ resource.ResourceWithImportState = &secretResource{}
+)
+
+// secretResource registers a Vault secret with a project. Secret values are
+// write-only: they are written to Vault directly and never pass through the
+// API, so they never end up in Terraform state either.
+type secretResource struct {
+	client *client.Client
+}
+
+func newSecretResource() resource.Resource {
+	return &secretResource{}
+}
+
+type secretModel struct {
+	ID        types.String `tfsdk:"id"`
+	ProjectID types.String `tfsdk:"project_id"`
+	Name      types.String `tfsdk:"name"`
+	Type      types.String `tfsdk:"type"`
+	Keys      types.List   `tfsdk:"keys"`
+	VaultPath types.String `tfsdk:"vault_path"`
+	Labels    types.Map    `tfsdk:"labels"`
+	Version   types.Int64  `tfsdk:"version"`
+}
+
+func (r *secretResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_secret"
+}
+
+func (r *secretResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	replace := []planmodifier.String{stringplanmodifier.RequiresReplace()}
+
+	resp.Schema = schema.Schema{
+		Description: "A reference to a secret stored in Vault, replicated into the clusters the project's services run on. " +
+			"Values are never sent to the API; write them to vault_path, for example with the Vault provider. " +
+			"Secrets cannot be updated in place; any change replaces the reference.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"project_id": schema.StringAttribute{Required: true, PlanModifiers: replace},
+			"name": schema.StringAttribute{
+				Description:   "Name of the Kubernetes secret, a DNS name.",
+				Required:      true,
+				PlanModifiers: replace,
+			},
+			"type": schema.StringAttribute{
+				Description:   "One of opaque, tls, docker_config, ssh_auth and basic_auth.",
+				Optional:      true,
+				Computed:      true,
+				Default:       stringdefault.StaticString("opaque"),
+				PlanModifiers: replace,
+			},
+			"keys": schema.ListAttribute{
+				Description:   "Keys of the Vault secret to replicate; all of them when omitted.",
+				ElementType:   types.StringType,
+				Optional:      true,
+				PlanModifiers: []planmodifier.List{listplanmodifier.RequiresReplace()},
+			},
+			"vault_path": schema.StringAttribute{Required: true, PlanModifiers: replace},
+			"labels": schema.MapAttribute{
+				ElementType:   types.StringType,
+				Optional:      true,
+				PlanModifiers: []planmodifier.Map{mapplanmodifier.RequiresReplace()},
+			},
+			"version": schema.Int64Attribute{Computed: true},
+		},
+	}
+}
+
+func (r *secretResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req, resp)
+}
+
+func (r *secretResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan secretModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := client.Secret{
+		Name:      plan.Name.ValueString(),
+		Type:      plan.Type.ValueString(),
+		Keys:      stringList(ctx, plan.Keys, &resp.Diagnostics),
+		VaultPath: plan.VaultPath.ValueString(),
+		Labels:    stringMap(ctx, plan.Labels, &resp.Diagnostics),
+	}
+	var secret client.Secret
+	if err := r.client.Post(ctx, "/projects/"+plan.ProjectID.ValueString()+"/secrets", body, &secret); err != nil {
+		resp.Diagnostics.AddError("Failed to create secret", err.Error())
+		return
+	}
+
+	plan.fromAPI(ctx, &secret, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *secretResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state secretModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var secret client.Secret
+	if err := r.client.Get(ctx, "/secrets/"+state.ID.ValueString(), &secret); err != nil {
+		if client.IsNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read secret", err.Error())
+		return
+	}
+
+	state.fromAPI(ctx, &secret, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+// Update is never called with changes, since every attribute replaces the
+// secret
+func (r *secretResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan secretModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *secretResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state secretModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.Delete(ctx, "/secrets/"+state.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete secret", err.Error())
+	}
+}
+
+func (r *secretResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// fromAPI copies a secret reference from the API into the model
+func (m *secretModel) fromAPI(ctx context.Context, secret *client.Secret, diags *diag.Diagnostics) {
+	m.ID = types.StringValue(secret.ID)
+	m.ProjectID = types.StringValue(secret.ProjectID)
+	m.Name = types.StringValue(secret.Name)
+	m.Type = types.StringValue(secret.Type)
+	m.Keys = listValue(ctx, secret.Keys, m.Keys, diags)
+	m.VaultPath = types.StringValue(secret.VaultPath)
+	m.Labels = mapValue(ctx, secret.Labels, m.Labels, diags)
+	m.Version = types.Int64Value(secret.Version)
+}
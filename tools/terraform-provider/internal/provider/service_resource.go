@@ -0,0 +1,379 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/booldefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringdefault"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/northstack/platform/tools/terraform-provider/internal/client"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &serviceResource{}
+	_ resource.ResourceWithImportState = &serviceResource{}
+)
+
+// serviceResource manages a service of a project
+type serviceResource struct {
+	client *client.Client
+}
+
+func newServiceResource() resource.Resource {
+	return &serviceResource{}
+}
+
+type serviceModel struct {
+	ID          types.String     `tfsdk:"id"`
+	ProjectID   types.String     `tfsdk:"project_id"`
+	Name        types.String     `tfsdk:"name"`
+	Slug        types.String     `tfsdk:"slug"`
+	Type        types.String     `tfsdk:"type"`
+	BuildSource buildSourceModel `tfsdk:"build_source"`
+	Resources   *resourcesModel  `tfsdk:"resources"`
+	Scaling     *scalingModel    `tfsdk:"scaling"`
+	EnvVars     types.Map        `tfsdk:"env_vars"`
+	SecretRefs  types.List       `tfsdk:"secret_refs"`
+	Ports       []portModel      `tfsdk:"ports"`
+	Labels      types.Map        `tfsdk:"labels"`
+	Status      types.String     `tfsdk:"status"`
+}
+
+type buildSourceModel struct {
+	Type       types.String `tfsdk:"type"`
+	Repository types.String `tfsdk:"repository"`
+	Branch     types.String `tfsdk:"branch"`
+	Dockerfile types.String `tfsdk:"dockerfile"`
+	Image      types.String `tfsdk:"image"`
+	Registry   types.String `tfsdk:"registry"`
+}
+
+type resourcesModel struct {
+	CPURequest    types.String `tfsdk:"cpu_request"`
+	CPULimit      types.String `tfsdk:"cpu_limit"`
+	MemoryRequest types.String `tfsdk:"memory_request"`
+	MemoryLimit   types.String `tfsdk:"memory_limit"`
+	StorageSize   types.String `tfsdk:"storage_size"`
+}
+
+type scalingModel struct {
+	MinReplicas  types.Int64 `tfsdk:"min_replicas"`
+	MaxReplicas  types.Int64 `tfsdk:"max_replicas"`
+	TargetCPU    types.Int64 `tfsdk:"target_cpu"`
+	TargetMemory types.Int64 `tfsdk:"target_memory"`
+}
+
+type portModel struct {
+	Name       types.String `tfsdk:"name"`
+	Port       types.Int64  `tfsdk:"port"`
+	TargetPort types.Int64  `tfsdk:"target_port"`
+	Protocol   types.String `tfsdk:"protocol"`
+	Public     types.Bool   `tfsdk:"public"`
+}
+
+func (r *serviceResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_service"
+}
+
+func (r *serviceResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	replace := []planmodifier.String{stringplanmodifier.RequiresReplace()}
+	optionalString := func(description string) schema.StringAttribute {
+		return schema.StringAttribute{Description: description, Optional: true, PlanModifiers: replace}
+	}
+	optionalInt := func(description string) schema.Int64Attribute {
+		return schema.Int64Attribute{
+			Description:   description,
+			Optional:      true,
+			PlanModifiers: []planmodifier.Int64{int64planmodifier.RequiresReplace()},
+		}
+	}
+
+	resp.Schema = schema.Schema{
+		Description: "A service of a project. Only the name, environment variables and labels are updated in place; " +
+			"changing anything else replaces the service.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"project_id": schema.StringAttribute{Required: true, PlanModifiers: replace},
+			"name":       schema.StringAttribute{Required: true},
+			"slug":       schema.StringAttribute{Required: true, PlanModifiers: replace},
+			"type": schema.StringAttribute{
+				Description:   "One of webapp, worker, cronjob, stateful_db and stateless.",
+				Optional:      true,
+				Computed:      true,
+				Default:       stringdefault.StaticString("webapp"),
+				PlanModifiers: replace,
+			},
+			"build_source": schema.SingleNestedAttribute{
+				Required:      true,
+				PlanModifiers: []planmodifier.Object{objectplanmodifier.RequiresReplace()},
+				Attributes: map[string]schema.Attribute{
+					"type":       schema.StringAttribute{Description: "One of git, docker and buildpack.", Required: true},
+					"repository": schema.StringAttribute{Optional: true},
+					"branch":     schema.StringAttribute{Optional: true},
+					"dockerfile": schema.StringAttribute{Optional: true},
+					"image":      schema.StringAttribute{Optional: true},
+					"registry":   schema.StringAttribute{Optional: true},
+				},
+			},
+			"resources": schema.SingleNestedAttribute{
+				Description:   "Compute requests and limits. The API's defaults apply when omitted.",
+				Optional:      true,
+				PlanModifiers: []planmodifier.Object{objectplanmodifier.RequiresReplace()},
+				Attributes: map[string]schema.Attribute{
+					"cpu_request":    optionalString(""),
+					"cpu_limit":      optionalString(""),
+					"memory_request": optionalString(""),
+					"memory_limit":   optionalString(""),
+					"storage_size":   optionalString(""),
+				},
+			},
+			"scaling": schema.SingleNestedAttribute{
+				Description:   "Replica bounds. The API's defaults apply when omitted.",
+				Optional:      true,
+				PlanModifiers: []planmodifier.Object{objectplanmodifier.RequiresReplace()},
+				Attributes: map[string]schema.Attribute{
+					"min_replicas":  schema.Int64Attribute{Required: true},
+					"max_replicas":  schema.Int64Attribute{Required: true},
+					"target_cpu":    optionalInt("Target CPU utilization in percent."),
+					"target_memory": optionalInt("Target memory utilization in percent."),
+				},
+			},
+			"env_vars": schema.MapAttribute{ElementType: types.StringType, Optional: true},
+			"secret_refs": schema.ListAttribute{
+				Description:   "Names of the project's secrets mounted into the service.",
+				ElementType:   types.StringType,
+				Optional:      true,
+				PlanModifiers: []planmodifier.List{listplanmodifier.RequiresReplace()},
+			},
+			"ports": schema.ListNestedAttribute{
+				Optional:      true,
+				PlanModifiers: []planmodifier.List{listplanmodifier.RequiresReplace()},
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{Required: true},
+						"port": schema.Int64Attribute{Required: true},
+						"target_port": schema.Int64Attribute{
+							Description:   "Defaults to port.",
+							Optional:      true,
+							Computed:      true,
+							PlanModifiers: []planmodifier.Int64{int64planmodifier.UseStateForUnknown()},
+						},
+						"protocol": schema.StringAttribute{
+							Optional: true,
+							Computed: true,
+							Default:  stringdefault.StaticString("TCP"),
+						},
+						"public": schema.BoolAttribute{
+							Optional: true,
+							Computed: true,
+							Default:  booldefault.StaticBool(false),
+						},
+					},
+				},
+			},
+			"labels": schema.MapAttribute{ElementType: types.StringType, Optional: true},
+			"status": schema.StringAttribute{Computed: true},
+		},
+	}
+}
+
+func (r *serviceResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req, resp)
+}
+
+func (r *serviceResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan serviceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := client.Service{
+		Name: plan.Name.ValueString(),
+		Slug: plan.Slug.ValueString(),
+		Type: plan.Type.ValueString(),
+		BuildSource: &client.BuildSource{
+			Type:       plan.BuildSource.Type.ValueString(),
+			Repository: plan.BuildSource.Repository.ValueString(),
+			Branch:     plan.BuildSource.Branch.ValueString(),
+			Dockerfile: plan.BuildSource.Dockerfile.ValueString(),
+			Image:      plan.BuildSource.Image.ValueString(),
+			Registry:   plan.BuildSource.Registry.ValueString(),
+		},
+		EnvVars:    stringMap(ctx, plan.EnvVars, &resp.Diagnostics),
+		SecretRefs: stringList(ctx, plan.SecretRefs, &resp.Diagnostics),
+		Labels:     stringMap(ctx, plan.Labels, &resp.Diagnostics),
+	}
+	if plan.Resources != nil {
+		body.Resources = &client.Resources{
+			CPURequest:    plan.Resources.CPURequest.ValueString(),
+			CPULimit:      plan.Resources.CPULimit.ValueString(),
+			MemoryRequest: plan.Resources.MemoryRequest.ValueString(),
+			MemoryLimit:   plan.Resources.MemoryLimit.ValueString(),
+			StorageSize:   plan.Resources.StorageSize.ValueString(),
+		}
+	}
+	if plan.Scaling != nil {
+		body.Scaling = &client.Scaling{
+			MinReplicas:  plan.Scaling.MinReplicas.ValueInt64(),
+			MaxReplicas:  plan.Scaling.MaxReplicas.ValueInt64(),
+			TargetCPU:    plan.Scaling.TargetCPU.ValueInt64(),
+			TargetMemory: plan.Scaling.TargetMemory.ValueInt64(),
+		}
+	}
+	for _, p := range plan.Ports {
+		port := client.Port{
+			Name:     p.Name.ValueString(),
+			Port:     p.Port.ValueInt64(),
+			Protocol: p.Protocol.ValueString(),
+			Public:   p.Public.ValueBool(),
+		}
+		if !p.TargetPort.IsUnknown() {
+			port.TargetPort = p.TargetPort.ValueInt64()
+		}
+		body.Ports = append(body.Ports, port)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var service client.Service
+	if err := r.client.Post(ctx, "/projects/"+plan.ProjectID.ValueString()+"/services", body, &service); err != nil {
+		resp.Diagnostics.AddError("Failed to create service", err.Error())
+		return
+	}
+
+	plan.fromAPI(ctx, &service, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *serviceResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state serviceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var service client.Service
+	if err := r.client.Get(ctx, "/services/"+state.ID.ValueString(), &service); err != nil {
+		if client.IsNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("Failed to read service", err.Error())
+		return
+	}
+
+	state.fromAPI(ctx, &service, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *serviceResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state serviceModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	body := map[string]interface{}{
+		"name":     plan.Name.ValueString(),
+		"env_vars": stringMapOrEmpty(ctx, plan.EnvVars, &resp.Diagnostics),
+		"labels":   stringMapOrEmpty(ctx, plan.Labels, &resp.Diagnostics),
+	}
+	var service client.Service
+	if err := r.client.Patch(ctx, "/services/"+state.ID.ValueString(), body, &service); err != nil {
+		resp.Diagnostics.AddError("Failed to update service", err.Error())
+		return
+	}
+
+	plan.ID = state.ID
+	plan.fromAPI(ctx, &service, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *serviceResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state serviceModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if err := r.client.Delete(ctx, "/services/"+state.ID.ValueString()); err != nil {
+		resp.Diagnostics.AddError("Failed to delete service", err.Error())
+	}
+}
+
+func (r *serviceResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// fromAPI copies a service from the API into the model. Resources and
+// scaling are only tracked when configured, since the API fills in defaults.
+func (m *serviceModel) fromAPI(ctx context.Context, service *client.Service, diags *diag.Diagnostics) {
+	m.ID = types.StringValue(service.ID)
+	m.ProjectID = types.StringValue(service.ProjectID)
+	m.Name = types.StringValue(service.Name)
+	m.Slug = types.StringValue(service.Slug)
+	m.Type = types.StringValue(service.Type)
+	m.Status = types.StringValue(service.Status)
+
+	if b := service.BuildSource; b != nil {
+		m.BuildSource = buildSourceModel{
+			Type:       types.StringValue(b.Type),
+			Repository: optionalString(b.Repository, m.BuildSource.Repository),
+			Branch:     optionalString(b.Branch, m.BuildSource.Branch),
+			Dockerfile: optionalString(b.Dockerfile, m.BuildSource.Dockerfile),
+			Image:      optionalString(b.Image, m.BuildSource.Image),
+			Registry:   optionalString(b.Registry, m.BuildSource.Registry),
+		}
+	}
+	if m.Resources != nil && service.Resources != nil {
+		prior := m.Resources
+		m.Resources = &resourcesModel{
+			CPURequest:    optionalString(service.Resources.CPURequest, prior.CPURequest),
+			CPULimit:      optionalString(service.Resources.CPULimit, prior.CPULimit),
+			MemoryRequest: optionalString(service.Resources.MemoryRequest, prior.MemoryRequest),
+			MemoryLimit:   optionalString(service.Resources.MemoryLimit, prior.MemoryLimit),
+			StorageSize:   optionalString(service.Resources.StorageSize, prior.StorageSize),
+		}
+	}
+	if m.Scaling != nil && service.Scaling != nil {
+		prior := m.Scaling
+		m.Scaling = &scalingModel{
+			MinReplicas:  types.Int64Value(service.Scaling.MinReplicas),
+			MaxReplicas:  types.Int64Value(service.Scaling.MaxReplicas),
+			TargetCPU:    optionalInt(service.Scaling.TargetCPU, prior.TargetCPU),
+			TargetMemory: optionalInt(service.Scaling.TargetMemory, prior.TargetMemory),
+		}
+	}
+
+	m.EnvVars = mapValue(ctx, service.EnvVars, m.EnvVars, diags)
+	m.SecretRefs = listValue(ctx, service.SecretRefs, m.SecretRefs, diags)
+	m.Labels = mapValue(ctx, service.Labels, m.Labels, diags)
+
+	if len(service.Ports) > 0 || m.Ports != nil {
+		m.Ports = make([]portModel, 0, len(service.Ports))
+		for _, p := range service.Ports {
+			m.Ports = append(m.Ports, portModel{
+				Name:       types.StringValue(p.Name),
+				Port:       types.Int64Value(p.Port),
+				TargetPort: types.Int64Value(p.TargetPort),
+				Protocol:   types.StringValue(p.Protocol),
+				Public:     types.BoolValue(p.Public),
+			})
+		}
+	}
+}
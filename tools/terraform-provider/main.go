@@ -0,0 +1,29 @@
+// Command terraform-provider-northstack is the Terraform and OpenTofu
+// provider of the platform. It manages projects, services, environments,
+// secrets, domains and clusters through the API.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/northstack/platform/tools/terraform-provider/internal/provider"
+)
+
+// version is set by the release build
+var version = "dev"
+
+func main() {
+	debug := flag.Bool("debug", false, "Run with support for debuggers like delve")
+	flag.Parse()
+
+	err := providerserver.Serve(context.Background(), provider.New(version), providerserver.ServeOpts{
+		Address: "registry.terraform.io/northstack/northstack",
+		Debug:   *debug,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}